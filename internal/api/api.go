@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/pprof"
+	"os"
 	"runtime"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	yaml "gopkg.in/yaml.v3"
@@ -21,27 +23,45 @@ import (
 
 // Config contains the configuration fields for the Benthos API.
 type Config struct {
-	Address        string                     `json:"address" yaml:"address"`
-	Enabled        bool                       `json:"enabled" yaml:"enabled"`
-	RootPath       string                     `json:"root_path" yaml:"root_path"`
-	DebugEndpoints bool                       `json:"debug_endpoints" yaml:"debug_endpoints"`
-	CertFile       string                     `json:"cert_file" yaml:"cert_file"`
-	KeyFile        string                     `json:"key_file" yaml:"key_file"`
-	CORS           httpserver.CORSConfig      `json:"cors" yaml:"cors"`
-	BasicAuth      httpserver.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	Address           string                     `json:"address" yaml:"address"`
+	Network           string                     `json:"network" yaml:"network"`
+	Enabled           bool                       `json:"enabled" yaml:"enabled"`
+	RootPath          string                     `json:"root_path" yaml:"root_path"`
+	DebugEndpoints    bool                       `json:"debug_endpoints" yaml:"debug_endpoints"`
+	CertFile          string                     `json:"cert_file" yaml:"cert_file"`
+	KeyFile           string                     `json:"key_file" yaml:"key_file"`
+	ClientAuth        string                     `json:"client_auth" yaml:"client_auth"`
+	ClientCAFile      string                     `json:"client_ca_file" yaml:"client_ca_file"`
+	ReadTimeout       string                     `json:"read_timeout" yaml:"read_timeout"`
+	ReadHeaderTimeout string                     `json:"read_header_timeout" yaml:"read_header_timeout"`
+	WriteTimeout      string                     `json:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout       string                     `json:"idle_timeout" yaml:"idle_timeout"`
+	MaxHeaderBytes    int                        `json:"max_header_bytes" yaml:"max_header_bytes"`
+	CORS              httpserver.CORSConfig      `json:"cors" yaml:"cors"`
+	BasicAuth         httpserver.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	Auth              httpserver.AuthConfig      `json:"auth" yaml:"auth"`
 }
 
 // NewConfig creates a new API config with default values.
 func NewConfig() Config {
 	return Config{
-		Address:        "0.0.0.0:4195",
-		Enabled:        true,
-		RootPath:       "/benthos",
-		DebugEndpoints: false,
-		CertFile:       "",
-		KeyFile:        "",
-		CORS:           httpserver.NewServerCORSConfig(),
-		BasicAuth:      httpserver.NewBasicAuthConfig(),
+		Address:           "0.0.0.0:4195",
+		Network:           "tcp",
+		Enabled:           true,
+		RootPath:          "/benthos",
+		DebugEndpoints:    false,
+		CertFile:          "",
+		KeyFile:           "",
+		ClientAuth:        "none",
+		ClientCAFile:      "",
+		ReadTimeout:       "",
+		ReadHeaderTimeout: "",
+		WriteTimeout:      "",
+		IdleTimeout:       "",
+		MaxHeaderBytes:    0,
+		CORS:              httpserver.NewServerCORSConfig(),
+		BasicAuth:         httpserver.NewBasicAuthConfig(),
+		Auth:              httpserver.NewAuthConfig(),
 	}
 }
 
@@ -81,6 +101,7 @@ type Type struct {
 	log    log.Modular
 	mux    *mux.Router
 	server *http.Server
+	auth   *httpserver.Authenticator
 }
 
 // New creates a new Benthos HTTP API.
@@ -101,16 +122,44 @@ func New(
 		return nil, fmt.Errorf("bad CORS configuration: %w", err)
 	}
 
-	if conf.CertFile != "" || conf.KeyFile != "" {
-		if conf.CertFile == "" || conf.KeyFile == "" {
-			return nil, errors.New("both cert_file and key_file must be specified, or neither")
+	switch conf.Network {
+	case "tcp", "unix":
+	default:
+		return nil, fmt.Errorf("network type not recognised: %v", conf.Network)
+	}
+
+	if server.TLSConfig, err = newServerTLSConfig(conf); err != nil {
+		return nil, err
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"read_timeout", conf.ReadTimeout, &server.ReadTimeout},
+		{"read_header_timeout", conf.ReadHeaderTimeout, &server.ReadHeaderTimeout},
+		{"write_timeout", conf.WriteTimeout, &server.WriteTimeout},
+		{"idle_timeout", conf.IdleTimeout, &server.IdleTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.field, err = time.ParseDuration(d.value); err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", d.name, err)
 		}
 	}
+	server.MaxHeaderBytes = conf.MaxHeaderBytes
 
 	if err := conf.BasicAuth.Validate(); err != nil {
 		return nil, err
 	}
 
+	auth, err := httpserver.NewAuthenticator(conf.Auth)
+	if err != nil {
+		return nil, err
+	}
+
 	t := &Type{
 		conf:      conf,
 		endpoints: map[string]string{},
@@ -118,6 +167,7 @@ func New(
 		mux:       gMux,
 		server:    server,
 		log:       log,
+		auth:      auth,
 	}
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
@@ -194,19 +244,19 @@ func New(
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/heap", "DEBUG: Responds with a pprof-formatted heap profile.",
-			pprof.Index,
+			pprof.Handler("heap").ServeHTTP,
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/goroutine", "DEBUG: Responds with a pprof-formatted goroutine profile.",
-			pprof.Index,
+			pprof.Handler("goroutine").ServeHTTP,
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/block", "DEBUG: Responds with a pprof-formatted block profile.",
-			pprof.Index,
+			pprof.Handler("block").ServeHTTP,
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/mutex", "DEBUG: Responds with a pprof-formatted mutex profile.",
-			pprof.Index,
+			pprof.Handler("mutex").ServeHTTP,
 		)
 		t.RegisterEndpoint(
 			"/debug/pprof/symbol", "DEBUG: looks up the program counters listed"+
@@ -257,12 +307,12 @@ func (t *Type) RegisterEndpoint(path, desc string, handlerFunc http.HandlerFunc)
 	defer t.handlersMut.Unlock()
 
 	if _, exists := t.handlers[path]; !exists {
-		wrapHandler := t.conf.BasicAuth.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		wrapHandler := t.auth.WrapHandler(t.conf.BasicAuth.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
 			t.handlersMut.RLock()
 			h := t.handlers[path]
 			t.handlersMut.RUnlock()
 			h(w, r)
-		})
+		}))
 
 		t.mux.HandleFunc(path, wrapHandler)
 		t.mux.HandleFunc(t.conf.RootPath+path, wrapHandler)
@@ -276,17 +326,35 @@ func (t *Type) ListenAndServe() error {
 		<-t.ctx.Done()
 		return nil
 	}
+
+	if t.conf.Network == "unix" {
+		// Remove a socket left behind by a previous run, as otherwise the
+		// address cannot be bound.
+		if info, err := os.Stat(t.conf.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(t.conf.Address)
+		}
+	}
+
+	ln, err := net.Listen(t.conf.Network, t.conf.Address)
+	if err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if t.server.TLSConfig != nil {
+		scheme = "https"
+	}
+	if t.conf.Network == "unix" {
+		scheme += "+unix"
+	}
 	t.log.Infof(
 		"Listening for HTTP requests at: %v\n",
-		"http://"+t.conf.Address,
+		scheme+"://"+t.conf.Address,
 	)
 	if t.server.TLSConfig != nil {
-		return t.server.ListenAndServeTLS("", "")
-	}
-	if len(t.conf.CertFile) > 0 {
-		return t.server.ListenAndServeTLS(t.conf.CertFile, t.conf.KeyFile)
+		return t.server.ServeTLS(ln, "", "")
 	}
-	return t.server.ListenAndServe()
+	return t.server.Serve(ln)
 }
 
 // Shutdown attempts to close the http server.
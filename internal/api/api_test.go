@@ -1,9 +1,14 @@
 package api_test
 
 import (
+	"context"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -183,3 +188,42 @@ func TestAPIBasicAuth(t *testing.T) {
 		}(tc))
 	}
 }
+
+func TestAPIUnixSocket(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "benthos.sock")
+
+	conf := api.NewConfig()
+	conf.Network = "unix"
+	conf.Address = sockPath
+
+	s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	go func() {
+		_ = s.ListenAndServe()
+	}()
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		_ = s.Shutdown(ctx)
+	})
+
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", sockPath)
+			},
+		},
+	}
+
+	assert.Eventually(t, func() bool {
+		res, err := client.Get("http://benthos/ping")
+		if err != nil {
+			return false
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode == http.StatusOK && string(body) == "pong"
+	}, time.Second*5, time.Millisecond*50)
+}
@@ -14,17 +14,35 @@ import (
 func Spec() docs.FieldSpecs {
 	return docs.FieldSpecs{
 		docs.FieldBool("enabled", "Whether to enable to HTTP server.").HasDefault(true),
-		docs.FieldString("address", "The address to bind to.").HasDefault("0.0.0.0:4195"),
+		docs.FieldString("address", "The address to bind to. When `network` is `unix` this is the path of the socket.").HasDefault("0.0.0.0:4195"),
+		docs.FieldString("network", "The network type to listen on.").HasAnnotatedOptions(
+			"tcp", "Listen on a TCP address.",
+			"unix", "Listen on a unix domain socket.",
+		).Advanced().HasDefault("tcp").AtVersion("4.9.0"),
 		docs.FieldString(
 			"root_path", "Specifies a general prefix for all endpoints, this can help isolate the service endpoints when using a reverse proxy with other shared services. All endpoints will still be registered at the root as well as behind the prefix, e.g. with a root_path set to `/foo` the endpoint `/version` will be accessible from both `/version` and `/foo/version`.",
 		).HasDefault("/benthos"),
 		docs.FieldBool(
-			"debug_endpoints", "Whether to register a few extra endpoints that can be useful for debugging performance or behavioral problems.",
+			"debug_endpoints", "Whether to register a few extra endpoints that can be useful for debugging performance or behavioral problems. These include `pprof` profiling endpoints under `/debug/pprof`, which can be scraped by continuous profilers such as Parca and are protected by the `basic_auth` and `auth` settings of this section.",
 		).HasDefault(false),
 		docs.FieldString("cert_file", "An optional certificate file for enabling TLS.").Advanced().HasDefault(""),
 		docs.FieldString("key_file", "An optional key file for enabling TLS.").Advanced().HasDefault(""),
+		docs.FieldString("client_auth", "The policy for requesting and verifying client certificates when TLS is enabled.").HasAnnotatedOptions(
+			"none", "Client certificates are not requested.",
+			"request", "Client certificates are requested but not required or verified.",
+			"require", "Client certificates are required but not verified.",
+			"verify_if_given", "Client certificates are requested and verified against the `client_ca_file` when provided.",
+			"require_and_verify", "Client certificates are required and verified against the `client_ca_file`.",
+		).Advanced().HasDefault("none").AtVersion("4.9.0"),
+		docs.FieldString("client_ca_file", "An optional file of PEM encoded certificate authorities used to verify client certificates.").Advanced().HasDefault("").AtVersion("4.9.0"),
+		docs.FieldString("read_timeout", "The maximum duration for reading an entire request, including the body. Empty means no timeout.", "30s").Advanced().HasDefault("").AtVersion("4.9.0"),
+		docs.FieldString("read_header_timeout", "The maximum duration for reading the headers of a request. Empty means the `read_timeout` is used.", "5s").Advanced().HasDefault("").AtVersion("4.9.0"),
+		docs.FieldString("write_timeout", "The maximum duration before timing out the writing of a response. Empty means no timeout.", "30s").Advanced().HasDefault("").AtVersion("4.9.0"),
+		docs.FieldString("idle_timeout", "The maximum duration to wait for the next request on a keep-alive connection. Empty means the `read_timeout` is used.", "2m").Advanced().HasDefault("").AtVersion("4.9.0"),
+		docs.FieldInt("max_header_bytes", "The maximum number of bytes of request headers, where zero means the default of 1MB is used.").Advanced().HasDefault(0).AtVersion("4.9.0"),
 		httpserver.ServerCORSFieldSpec(),
 		httpserver.BasicAuthFieldSpec(),
+		httpserver.AuthFieldSpec(),
 	}
 }
 
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+)
+
+func clientAuthType(v string) (tls.ClientAuthType, error) {
+	switch v {
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("client_auth type not recognised: %v", v)
+}
+
+// newServerTLSConfig creates a TLS config for the API server from its config,
+// or returns nil if TLS is not enabled.
+func newServerTLSConfig(conf Config) (*tls.Config, error) {
+	if conf.CertFile != "" || conf.KeyFile != "" {
+		if conf.CertFile == "" || conf.KeyFile == "" {
+			return nil, errors.New("both cert_file and key_file must be specified, or neither")
+		}
+	}
+
+	clientAuth, err := clientAuthType(conf.ClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.CertFile == "" {
+		if clientAuth != tls.NoClientCert || conf.ClientCAFile != "" {
+			return nil, errors.New("a cert_file and key_file must be specified in order to authenticate clients")
+		}
+		return nil, nil
+	}
+
+	reloader, err := btls.NewCertReloader(conf.CertFile, conf.KeyFile, "")
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConf := reloader.ServerConfig()
+	tlsConf.ClientAuth = clientAuth
+
+	if conf.ClientCAFile != "" {
+		caPem, err := os.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, errors.New("failed to parse any certificates from client_ca_file")
+		}
+		tlsConf.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, errors.New("a client_ca_file must be specified in order to verify client certificates")
+	}
+	return tlsConf, nil
+}
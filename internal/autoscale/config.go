@@ -0,0 +1,38 @@
+package autoscale
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// Config contains configuration parameters for autoscaling workers.
+type Config struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Min      int    `json:"min" yaml:"min"`
+	Max      int    `json:"max" yaml:"max"`
+	Interval string `json:"interval" yaml:"interval"`
+}
+
+// NewConfig creates an autoscale config with default values, which disables
+// autoscaling.
+func NewConfig() Config {
+	return Config{
+		Enabled:  false,
+		Min:      1,
+		Max:      16,
+		Interval: "5s",
+	}
+}
+
+// FieldSpec returns a spec for a common autoscale field, where the description
+// explains which concurrency setting of the component is scaled.
+func FieldSpec(description string) docs.FieldSpec {
+	return docs.FieldObject("autoscale", description+`
+
+Every interval the utilisation of the active workers, which is the proportion of time spent performing work rather than waiting for it, is calculated along with the average latency of the work performed. When utilisation is high a worker is added, unless latency has degraded compared to the best latency observed, which indicates that the workers are contending for a shared resource such as CPU or a downstream service. When utilisation is low, or latency has degraded significantly, a worker is removed.
+
+The current number of workers is exposed with the gauge metric `+"`autoscale_workers`"+`.`,
+	).WithChildren(
+		docs.FieldBool("enabled", "Whether autoscaling is enabled.").HasDefault(false),
+		docs.FieldInt("min", "The minimum number of workers.").HasDefault(1),
+		docs.FieldInt("max", "The maximum number of workers.").HasDefault(16),
+		docs.FieldString("interval", "The period of time between each adjustment.").HasDefault("5s"),
+	).Advanced().AtVersion("4.9.0").ChildDefaultAndTypesFromStruct(NewConfig())
+}
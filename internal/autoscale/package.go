@@ -0,0 +1,4 @@
+// Package autoscale implements the adjustment of the number of concurrent
+// workers of a component within configured bounds, based on how saturated the
+// workers are and the latency of the work they perform.
+package autoscale
@@ -0,0 +1,202 @@
+package autoscale
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+)
+
+const (
+	// Utilisation above which a worker is added.
+	highUtilisation = 0.8
+
+	// Utilisation below which a worker is removed.
+	lowUtilisation = 0.3
+
+	// The ratio of average latency to the best observed latency above which
+	// workers are no longer added.
+	degradedLatency = 1.5
+
+	// The ratio of average latency to the best observed latency above which a
+	// worker is removed.
+	severelyDegradedLatency = 2.0
+
+	// The proportion by which the best observed latency drifts towards the
+	// current latency each interval, allowing it to adapt to a changing
+	// workload.
+	baselineDrift = 0.05
+)
+
+// Scaler limits the number of active workers of a component, where a fixed set
+// of workers is created up front and each waits until its index falls within
+// the current limit before taking on work.
+type Scaler struct {
+	min, max int
+	interval time.Duration
+
+	mWorkers metrics.StatGauge
+
+	mut      sync.Mutex
+	limit    int
+	changed  chan struct{}
+	released bool
+
+	busy     time.Duration
+	count    int64
+	baseline time.Duration
+}
+
+// New creates a scaler from a config, starting with an initial limit that is
+// clamped to the configured bounds.
+func New(conf Config, initial int, stats metrics.Type) (*Scaler, error) {
+	if conf.Min < 1 {
+		return nil, fmt.Errorf("autoscale min must be greater than zero, got %v", conf.Min)
+	}
+	if conf.Max < conf.Min {
+		return nil, fmt.Errorf("autoscale max (%v) must not be less than min (%v)", conf.Max, conf.Min)
+	}
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse autoscale interval: %w", err)
+	}
+	if interval <= 0 {
+		return nil, errors.New("autoscale interval must be greater than zero")
+	}
+
+	if initial < conf.Min {
+		initial = conf.Min
+	}
+	if initial > conf.Max {
+		initial = conf.Max
+	}
+
+	s := &Scaler{
+		min:      conf.Min,
+		max:      conf.Max,
+		interval: interval,
+		mWorkers: stats.GetGauge("autoscale_workers"),
+		limit:    initial,
+		changed:  make(chan struct{}),
+	}
+	s.mWorkers.Set(int64(initial))
+	return s, nil
+}
+
+// Max returns the maximum number of workers, which is the number of workers
+// that should be created.
+func (s *Scaler) Max() int {
+	return s.max
+}
+
+// Limit returns the current number of active workers.
+func (s *Scaler) Limit() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.limit
+}
+
+// WaitActive blocks until the worker of the given index is active, returning
+// false if the cancel channel is closed first. Once the scaler has been
+// released all workers are considered active.
+func (s *Scaler) WaitActive(index int, cancel <-chan struct{}) bool {
+	for {
+		s.mut.Lock()
+		active := s.released || index < s.limit
+		changed := s.changed
+		s.mut.Unlock()
+		if active {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-cancel:
+			return false
+		}
+	}
+}
+
+// Release marks all workers as active regardless of the current limit, which
+// allows each of them to observe the shutdown of the component.
+func (s *Scaler) Release() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if !s.released {
+		s.released = true
+		close(s.changed)
+		s.changed = make(chan struct{})
+	}
+}
+
+// Record the time a worker spent performing a unit of work.
+func (s *Scaler) Record(d time.Duration) {
+	s.mut.Lock()
+	s.busy += d
+	s.count++
+	s.mut.Unlock()
+}
+
+// Loop adjusts the limit of active workers each interval until the stop
+// channel is closed.
+func (s *Scaler) Loop(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.adjust(s.interval)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// adjust the limit of active workers based on the work recorded during the
+// elapsed period.
+func (s *Scaler) adjust(elapsed time.Duration) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	busy, count := s.busy, s.count
+	s.busy, s.count = 0, 0
+
+	newLimit := s.limit
+	if count == 0 {
+		newLimit--
+	} else {
+		utilisation := float64(busy) / float64(elapsed*time.Duration(s.limit))
+		latency := busy / time.Duration(count)
+
+		if s.baseline == 0 || latency < s.baseline {
+			s.baseline = latency
+		} else {
+			s.baseline += time.Duration(float64(latency-s.baseline) * baselineDrift)
+		}
+		latencyRatio := float64(latency) / float64(s.baseline)
+
+		switch {
+		case latencyRatio > severelyDegradedLatency, utilisation < lowUtilisation:
+			newLimit--
+		case utilisation >= highUtilisation && latencyRatio <= degradedLatency:
+			newLimit++
+		}
+	}
+
+	if newLimit < s.min {
+		newLimit = s.min
+	}
+	if newLimit > s.max {
+		newLimit = s.max
+	}
+	if newLimit == s.limit {
+		return
+	}
+
+	s.limit = newLimit
+	s.mWorkers.Set(int64(newLimit))
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
@@ -0,0 +1,125 @@
+package autoscale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+)
+
+func testConf(min, max int) Config {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Min = min
+	conf.Max = max
+	return conf
+}
+
+func TestScalerBadConfig(t *testing.T) {
+	conf := testConf(0, 10)
+	_, err := New(conf, 1, metrics.Noop())
+	require.Error(t, err)
+
+	conf = testConf(5, 4)
+	_, err = New(conf, 1, metrics.Noop())
+	require.Error(t, err)
+
+	conf = testConf(1, 4)
+	conf.Interval = "nope"
+	_, err = New(conf, 1, metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestScalerInitialClamped(t *testing.T) {
+	s, err := New(testConf(2, 4), 1, metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Limit())
+
+	s, err = New(testConf(2, 4), 10, metrics.Noop())
+	require.NoError(t, err)
+	assert.Equal(t, 4, s.Limit())
+	assert.Equal(t, 4, s.Max())
+}
+
+func TestScalerAdjust(t *testing.T) {
+	s, err := New(testConf(1, 4), 1, metrics.Noop())
+	require.NoError(t, err)
+
+	// Saturated with stable latency scales up until max.
+	for i := 2; i <= 4; i++ {
+		for j := 0; j < 10*s.Limit(); j++ {
+			s.Record(time.Millisecond * 100)
+		}
+		s.adjust(time.Second)
+		assert.Equal(t, i, s.Limit())
+	}
+	for j := 0; j < 40; j++ {
+		s.Record(time.Millisecond * 100)
+	}
+	s.adjust(time.Second)
+	assert.Equal(t, 4, s.Limit())
+
+	// Saturated with moderately degraded latency holds steady.
+	for j := 0; j < 25; j++ {
+		s.Record(time.Millisecond * 170)
+	}
+	s.adjust(time.Second)
+	assert.Equal(t, 4, s.Limit())
+
+	// Severely degraded latency scales down.
+	for j := 0; j < 10; j++ {
+		s.Record(time.Millisecond * 400)
+	}
+	s.adjust(time.Second)
+	assert.Equal(t, 3, s.Limit())
+
+	// Low utilisation scales down.
+	s.Record(time.Millisecond * 100)
+	s.adjust(time.Second)
+	assert.Equal(t, 2, s.Limit())
+
+	// No work at all scales down until min.
+	s.adjust(time.Second)
+	assert.Equal(t, 1, s.Limit())
+	s.adjust(time.Second)
+	assert.Equal(t, 1, s.Limit())
+}
+
+func TestScalerWaitActive(t *testing.T) {
+	s, err := New(testConf(1, 3), 1, metrics.Noop())
+	require.NoError(t, err)
+
+	assert.True(t, s.WaitActive(0, nil))
+
+	cancel := make(chan struct{})
+	close(cancel)
+	assert.False(t, s.WaitActive(1, cancel))
+
+	activeChan := make(chan bool)
+	go func() {
+		activeChan <- s.WaitActive(2, nil)
+	}()
+
+	for j := 0; j < 10; j++ {
+		s.Record(time.Millisecond * 100)
+	}
+	s.adjust(time.Second)
+	assert.Equal(t, 2, s.Limit())
+
+	select {
+	case <-activeChan:
+		t.Fatal("worker should not be active")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	s.Release()
+	select {
+	case active := <-activeChan:
+		assert.True(t, active)
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
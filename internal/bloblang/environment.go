@@ -1,6 +1,8 @@
 package bloblang
 
 import (
+	"fmt"
+
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
@@ -12,6 +14,20 @@ import (
 type Environment struct {
 	pCtx            parser.Context
 	maxMapRecursion int
+	fieldTimers     func() field.EvaluationTimer
+	fieldLimits     FieldLimits
+}
+
+// FieldLimits describes limits on the complexity of dynamic field expressions,
+// which are checked when a field is parsed. A limit of zero is disabled.
+type FieldLimits struct {
+	// MaxInterpolations is the maximum number of interpolation functions
+	// within a field.
+	MaxInterpolations int
+
+	// MaxLength is the maximum length in bytes of a field that contains
+	// interpolation functions.
+	MaxLength int
 }
 
 // GlobalEnvironment returns the global default environment. Modifying this
@@ -57,6 +73,17 @@ func (e *Environment) NewField(expr string) (*field.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	if n := f.NumDynamicExpressions(); n > 0 {
+		if l := e.fieldLimits.MaxInterpolations; l > 0 && n > l {
+			return nil, fmt.Errorf("field contains %v interpolation functions, which exceeds the limit of %v", n, l)
+		}
+		if l := e.fieldLimits.MaxLength; l > 0 && len(expr) > l {
+			return nil, fmt.Errorf("field with interpolation functions is %v bytes long, which exceeds the limit of %v", len(expr), l)
+		}
+		if e.fieldTimers != nil {
+			f = f.WithEvaluationTimer(e.fieldTimers())
+		}
+	}
 	return f, nil
 }
 
@@ -178,6 +205,25 @@ func (e *Environment) WithMaxMapRecursion(n int) *Environment {
 	return &env
 }
 
+// WithFieldEvaluationTimers returns a copy of the environment where dynamic
+// field expressions created with NewField record a sample of their evaluation
+// durations to a timer obtained from the provided closure, which is only called
+// for fields that contain interpolation functions.
+func (e *Environment) WithFieldEvaluationTimers(fn func() field.EvaluationTimer) *Environment {
+	env := *e
+	env.fieldTimers = fn
+	return &env
+}
+
+// WithFieldLimits returns a copy of the environment where dynamic field
+// expressions created with NewField are rejected when they exceed limits on
+// their complexity.
+func (e *Environment) WithFieldLimits(limits FieldLimits) *Environment {
+	env := *e
+	env.fieldLimits = limits
+	return &env
+}
+
 // WalkFunctions executes a provided function argument for every function that
 // has been registered to the environment.
 func (e *Environment) WalkFunctions(fn func(name string, spec query.FunctionSpec)) {
@@ -2,7 +2,10 @@ package field
 
 import (
 	"bytes"
+	"sync/atomic"
+	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/message"
 )
 
@@ -15,20 +18,38 @@ type Message interface {
 
 //------------------------------------------------------------------------------
 
-// NewExpression creates a field expression from a slice of resolvers.
+// NewExpression creates a field expression from a slice of resolvers. Adjacent
+// static resolvers are merged and queries that simply reference a metadata key
+// are replaced with a resolver that reads the key directly.
 func NewExpression(resolvers ...Resolver) *Expression {
-	e := &Expression{
-		resolvers: resolvers,
-	}
+	e := &Expression{}
 	var staticBuf bytes.Buffer
+	flushStatic := func() {
+		if staticBuf.Len() > 0 {
+			e.resolvers = append(e.resolvers, StaticResolver(staticBuf.String()))
+			e.staticLen += staticBuf.Len()
+			staticBuf.Reset()
+		}
+	}
 	for _, r := range resolvers {
-		if s, is := r.(StaticResolver); is {
-			staticBuf.Write(s.ResolveBytes(0, message.QuickBatch(nil), false))
-		} else {
-			e.dynamicExpressions++
+		switch t := r.(type) {
+		case StaticResolver:
+			staticBuf.WriteString(string(t))
+			continue
+		case *QueryResolver:
+			if key, ok := query.MetaFieldKey(t.fn); ok {
+				r = metaResolver(key)
+			}
 		}
+		flushStatic()
+		e.resolvers = append(e.resolvers, r)
+		e.dynamicExpressions++
+	}
+	if e.dynamicExpressions > 0 {
+		flushStatic()
+		return e
 	}
-	if e.dynamicExpressions > 0 || staticBuf.Len() == 0 {
+	if staticBuf.Len() == 0 {
 		return e
 	}
 	return &Expression{
@@ -38,6 +59,16 @@ func NewExpression(resolvers ...Resolver) *Expression {
 
 //------------------------------------------------------------------------------
 
+// EvaluationTimer is a stat timer that can be provided to an expression in
+// order to record how long evaluations of the expression take.
+type EvaluationTimer interface {
+	Timing(delta int64)
+}
+
+// Only one in this many evaluations of an expression are timed, which keeps the
+// overhead of timing negligible for expressions that are cheap to evaluate.
+const evaluationTimerSampleRate = 64
+
 // Expression represents a Benthos dynamic field expression, used to configure
 // string fields where the contents should change based on the contents of
 // messages and other factors.
@@ -47,16 +78,51 @@ func NewExpression(resolvers ...Resolver) *Expression {
 type Expression struct {
 	static             string
 	resolvers          []Resolver
+	staticLen          int
 	dynamicExpressions int
+
+	timer EvaluationTimer
+	evals *uint64
+}
+
+// WithEvaluationTimer returns a copy of the expression that records a sample of
+// its evaluation durations to a timer in nanoseconds. Expressions without any
+// dynamic functions are returned unchanged.
+func (e *Expression) WithEvaluationTimer(timer EvaluationTimer) *Expression {
+	if e.dynamicExpressions == 0 {
+		return e
+	}
+	newE := *e
+	newE.timer = timer
+	newE.evals = new(uint64)
+	return &newE
 }
 
 func (e *Expression) resolve(index int, msg Message, escaped bool) []byte {
+	if e.timer != nil && atomic.AddUint64(e.evals, 1)%evaluationTimerSampleRate == 1 {
+		started := time.Now()
+		defer func() {
+			e.timer.Timing(time.Since(started).Nanoseconds())
+		}()
+	}
 	if len(e.resolvers) == 1 {
 		return e.resolvers[0].ResolveBytes(index, msg, escaped)
 	}
 	var buf bytes.Buffer
+	buf.Grow(e.staticLen + 16*e.dynamicExpressions)
 	for _, r := range e.resolvers {
-		buf.Write(r.ResolveBytes(index, msg, escaped))
+		switch t := r.(type) {
+		case StaticResolver:
+			buf.WriteString(string(t))
+		case metaResolver:
+			if v := t.resolve(index, msg); escaped {
+				buf.Write(escapeBytes([]byte(v)))
+			} else {
+				buf.WriteString(v)
+			}
+		default:
+			buf.Write(r.ResolveBytes(index, msg, escaped))
+		}
 	}
 	return buf.Bytes()
 }
@@ -82,5 +148,8 @@ func (e *Expression) String(index int, msg Message) string {
 	if len(e.resolvers) == 0 {
 		return e.static
 	}
+	if len(e.resolvers) == 1 && e.timer == nil {
+		return e.resolvers[0].ResolveString(index, msg, false)
+	}
 	return string(e.Bytes(index, msg))
 }
@@ -348,3 +348,79 @@ func TestExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestExpressionStaticSegmentsMerged(t *testing.T) {
+	e := NewExpression(
+		StaticResolver("foo "),
+		StaticResolver("$"),
+		StaticResolver(" bar "),
+		NewQueryResolver(func() query.Function {
+			fn, err := query.InitFunctionHelper("content")
+			require.NoError(t, err)
+			return fn
+		}()),
+		StaticResolver(" baz"),
+		StaticResolver("!"),
+	)
+	require.Len(t, e.resolvers, 3)
+	assert.Equal(t, StaticResolver("foo $ bar "), e.resolvers[0])
+	assert.Equal(t, StaticResolver(" baz!"), e.resolvers[2])
+	assert.Equal(t, 1, e.NumDynamicExpressions())
+	assert.Equal(t, "foo $ bar hello baz!", e.String(0, message.QuickBatch([][]byte{[]byte("hello")})))
+}
+
+func TestExpressionMetaFastPath(t *testing.T) {
+	metaFn := func(key string) Resolver {
+		fn, err := query.InitFunctionHelper("meta", key)
+		require.NoError(t, err)
+		return NewQueryResolver(fn)
+	}
+
+	e := NewExpression(StaticResolver("topic: "), metaFn("topic"), StaticResolver(", nope: "), metaFn("nope"))
+	require.Len(t, e.resolvers, 4)
+	assert.Equal(t, metaResolver("topic"), e.resolvers[1])
+
+	part := message.NewPart(nil)
+	part.MetaSetMut("topic", `foo"bar`)
+	msg := message.Batch{part}
+
+	assert.Equal(t, `topic: foo"bar, nope: null`, e.String(0, msg))
+	assert.Equal(t, `topic: foo"bar, nope: null`, string(e.Bytes(0, msg)))
+	assert.Equal(t, `topic: foo\"bar, nope: null`, string(e.resolve(0, msg, true)))
+	assert.Equal(t, `topic: null, nope: null`, e.String(0, nil))
+
+	// A single reference resolves in the same way as executing the query.
+	single := NewExpression(metaFn("topic"))
+	assert.Equal(t, `foo"bar`, single.String(0, msg))
+	assert.Equal(t, "null", single.String(0, message.QuickBatch(nil)))
+}
+
+type testTimer struct {
+	timings []int64
+}
+
+func (t *testTimer) Timing(delta int64) {
+	t.timings = append(t.timings, delta)
+}
+
+func TestExpressionEvaluationTimer(t *testing.T) {
+	static := NewExpression(StaticResolver("foo"))
+	assert.Same(t, static, static.WithEvaluationTimer(&testTimer{}))
+
+	fn, err := query.InitFunctionHelper("content")
+	require.NoError(t, err)
+
+	e := NewExpression(StaticResolver("foo "), NewQueryResolver(fn))
+	timer := &testTimer{}
+	timed := e.WithEvaluationTimer(timer)
+
+	msg := message.QuickBatch([][]byte{[]byte("bar")})
+	for i := 0; i < evaluationTimerSampleRate*2; i++ {
+		assert.Equal(t, "foo bar", timed.String(0, msg))
+	}
+	assert.Len(t, timer.timings, 2)
+
+	// The original expression is unaffected.
+	_ = e.String(0, msg)
+	assert.Len(t, timer.timings, 2)
+}
@@ -82,6 +82,39 @@ func (q QueryResolver) ResolveBytes(index int, msg Message, escaped bool) []byte
 	return bs
 }
 
+//------------------------------------------------------------------------------
+
+// metaResolver returns the value of a metadata key, and is equivalent to a
+// QueryResolver of the query `meta("key")` without the overhead of executing a
+// query.
+type metaResolver string
+
+func (m metaResolver) resolve(index int, msg Message) string {
+	if msg == nil || msg.Len() == 0 {
+		return "null"
+	}
+	if v := msg.Get(index).MetaGet(string(m)); v != "" {
+		return v
+	}
+	return "null"
+}
+
+// ResolveString returns a string.
+func (m metaResolver) ResolveString(index int, msg Message, escaped bool) string {
+	return m.resolve(index, msg)
+}
+
+// ResolveBytes returns a byte slice.
+func (m metaResolver) ResolveBytes(index int, msg Message, escaped bool) []byte {
+	bs := []byte(m.resolve(index, msg))
+	if escaped {
+		bs = escapeBytes(bs)
+	}
+	return bs
+}
+
+//------------------------------------------------------------------------------
+
 func escapeBytes(in []byte) []byte {
 	quoted := strconv.Quote(string(in))
 	if len(quoted) < 3 {
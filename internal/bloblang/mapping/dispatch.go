@@ -0,0 +1,110 @@
+package mapping
+
+import (
+	"reflect"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+)
+
+// Dispatcher selects the first of a list of boolean check mappings that passes
+// for a message, where each check is an equality comparison between the same
+// query and a literal string. Rather than executing each check in turn the
+// query is executed once and its result is looked up within a map.
+type Dispatcher struct {
+	operand query.Function
+	cases   map[string]int
+	always  int
+}
+
+// NewDispatcher attempts to create a dispatcher from a list of boolean check
+// mappings, where a nil mapping always passes. Returns false if the checks are
+// not all equality comparisons of the same query against literal strings, or
+// if there are fewer than two such comparisons.
+func NewDispatcher(checks []*Executor) (*Dispatcher, bool) {
+	d := &Dispatcher{
+		cases:  map[string]int{},
+		always: -1,
+	}
+	for i, check := range checks {
+		if check == nil {
+			if d.always == -1 {
+				d.always = i
+			}
+			continue
+		}
+		operand, value, ok := check.rootEqualsLiteral()
+		if !ok {
+			return nil, false
+		}
+		str, isStr := value.(string)
+		if !isStr {
+			return nil, false
+		}
+		if d.operand == nil {
+			d.operand = operand
+		} else if !reflect.DeepEqual(d.operand, operand) {
+			// Functions that are implemented with closures are never deeply
+			// equal and are therefore never dispatched.
+			return nil, false
+		}
+		if _, exists := d.cases[str]; !exists {
+			d.cases[str] = i
+		}
+	}
+	if len(d.cases) < 2 {
+		return nil, false
+	}
+	return d, true
+}
+
+// rootEqualsLiteral returns the operands of the mapping when it consists of a
+// single assignment to the root of an equality comparison between a query and
+// a literal value.
+func (e *Executor) rootEqualsLiteral() (query.Function, any, bool) {
+	if len(e.maps) > 0 || len(e.statements) != 1 {
+		return nil, nil, false
+	}
+	stmt := e.statements[0]
+	if a, ok := stmt.assignment.(*JSONAssignment); !ok || len(a.path) > 0 {
+		return nil, nil, false
+	}
+	return query.EqualsLiteral(stmt.query)
+}
+
+// FirstMatch returns the index of the first check that passes for a message of
+// a batch, or -1 when no checks pass. When the query does not result in a
+// string, including when it fails, false is returned and each check should
+// instead be executed individually in order to obtain the same result, or
+// error, as they otherwise would.
+func (d *Dispatcher) FirstMatch(index int, msg Message) (int, bool) {
+	v, err := d.operand.Exec(query.FunctionContext{
+		Vars:     map[string]any{},
+		Index:    index,
+		MsgBatch: msg,
+		NewMeta:  msg.Get(index),
+	}.WithValueFunc(func() *any {
+		if jObj, err := msg.Get(index).AsStructured(); err == nil {
+			return &jObj
+		}
+		return nil
+	}))
+	if err != nil {
+		return -1, false
+	}
+
+	var str string
+	switch t := v.(type) {
+	case string:
+		str = t
+	case []byte:
+		str = string(t)
+	default:
+		return -1, false
+	}
+
+	match, exists := d.cases[str]
+	if !exists || (d.always >= 0 && d.always < match) {
+		match = d.always
+	}
+	return match, true
+}
@@ -0,0 +1,115 @@
+package mapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestDispatcher(t *testing.T) {
+	eqCheck := func(lhs query.Function, v any) *Executor {
+		fn, err := query.NewArithmeticExpression(
+			[]query.Function{lhs, query.NewLiteralFunction("", v)},
+			[]query.ArithmeticOperator{query.ArithmeticEq},
+		)
+		require.NoError(t, err)
+		return NewExecutor("", nil, nil, NewStatement(nil, NewJSONAssignment(), fn))
+	}
+	metaFn := func(key string) query.Function {
+		fn, err := query.InitFunctionHelper("meta", key)
+		require.NoError(t, err)
+		return fn
+	}
+
+	d, ok := NewDispatcher([]*Executor{
+		eqCheck(metaFn("topic"), "foo"),
+		eqCheck(metaFn("topic"), "bar"),
+		eqCheck(metaFn("topic"), "foo"),
+		nil,
+		eqCheck(metaFn("topic"), "baz"),
+	})
+	require.True(t, ok)
+
+	for _, test := range []struct {
+		topic string
+		match int
+	}{
+		{topic: "foo", match: 0},
+		{topic: "bar", match: 1},
+		{topic: "baz", match: 3},
+		{topic: "nope", match: 3},
+	} {
+		part := message.NewPart(nil)
+		part.MetaSetMut("topic", test.topic)
+		match, ok := d.FirstMatch(0, message.Batch{part})
+		require.True(t, ok, test.topic)
+		assert.Equal(t, test.match, match, test.topic)
+	}
+
+	d, ok = NewDispatcher([]*Executor{
+		eqCheck(query.NewFieldFunction("type"), "foo"),
+		eqCheck(query.NewFieldFunction("type"), "bar"),
+	})
+	require.True(t, ok)
+
+	match, ok := d.FirstMatch(0, message.QuickBatch([][]byte{[]byte(`{"type":"bar"}`)}))
+	require.True(t, ok)
+	assert.Equal(t, 1, match)
+
+	match, ok = d.FirstMatch(0, message.QuickBatch([][]byte{[]byte(`{"type":"nope"}`)}))
+	require.True(t, ok)
+	assert.Equal(t, -1, match)
+
+	// Non-string values and failed queries must be checked individually.
+	_, ok = d.FirstMatch(0, message.QuickBatch([][]byte{[]byte(`{"type":10}`)}))
+	assert.False(t, ok)
+	_, ok = d.FirstMatch(0, message.QuickBatch([][]byte{[]byte(`not json`)}))
+	assert.False(t, ok)
+}
+
+func TestDispatcherNotApplicable(t *testing.T) {
+	eqCheck := func(lhs query.Function, op query.ArithmeticOperator, v any) *Executor {
+		fn, err := query.NewArithmeticExpression(
+			[]query.Function{lhs, query.NewLiteralFunction("", v)},
+			[]query.ArithmeticOperator{op},
+		)
+		require.NoError(t, err)
+		return NewExecutor("", nil, nil, NewStatement(nil, NewJSONAssignment(), fn))
+	}
+	fooField, barField := query.NewFieldFunction("foo"), query.NewFieldFunction("bar")
+	upperFoo, err := query.InitMethodHelper("uppercase", query.NewFieldFunction("foo"))
+	require.NoError(t, err)
+
+	for name, checks := range map[string][]*Executor{
+		"single case": {
+			eqCheck(fooField, query.ArithmeticEq, "a"),
+		},
+		"different operands": {
+			eqCheck(fooField, query.ArithmeticEq, "a"),
+			eqCheck(barField, query.ArithmeticEq, "b"),
+		},
+		"not equality": {
+			eqCheck(fooField, query.ArithmeticEq, "a"),
+			eqCheck(fooField, query.ArithmeticNeq, "b"),
+		},
+		"non string literal": {
+			eqCheck(fooField, query.ArithmeticEq, "a"),
+			eqCheck(fooField, query.ArithmeticEq, int64(10)),
+		},
+		"closure operands": {
+			eqCheck(upperFoo, query.ArithmeticEq, "A"),
+			eqCheck(upperFoo, query.ArithmeticEq, "B"),
+		},
+		"assigns a field": {
+			eqCheck(fooField, query.ArithmeticEq, "a"),
+			NewExecutor("", nil, nil, NewStatement(nil, NewJSONAssignment("foo"), fooField)),
+		},
+	} {
+		_, ok := NewDispatcher(checks)
+		assert.False(t, ok, name)
+	}
+}
@@ -456,6 +456,40 @@ bar""")`,
 				{},
 			},
 		},
+		"json from part": {
+			input:  `json_from_part(1, "foo")`,
+			output: `bar`,
+			messages: []easyMsg{
+				{content: `{"foo":"baz"}`},
+				{content: `{"foo":"bar"}`},
+			},
+		},
+		"json from part negative": {
+			input:  `json_from_part(-2)`,
+			output: `{"foo":"baz"}`,
+			index:  1,
+			messages: []easyMsg{
+				{content: `{"foo":"baz"}`},
+				{content: `{"foo":"bar"}`},
+			},
+		},
+		"json from part dynamic": {
+			input:  `json_from_part(batch_index() - 1, "foo")`,
+			output: `baz`,
+			index:  1,
+			messages: []easyMsg{
+				{content: `{"foo":"baz"}`},
+				{content: `{"foo":"bar"}`},
+			},
+		},
+		"json from part out of bounds": {
+			input:  `json_from_part(2, "foo").catch("failed")`,
+			output: `failed`,
+			messages: []easyMsg{
+				{content: `{"foo":"baz"}`},
+				{content: `{"foo":"bar"}`},
+			},
+		},
 		"field no context": {
 			input:  `this`,
 			output: `null`,
@@ -485,6 +485,13 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 			if fnsNew[len(fnsNew)-1], err = arithmeticFunc(leftFn, rightFn, opFunc); err != nil {
 				return nil, err
 			}
+			if _, isLit := fnsNew[len(fnsNew)-1].(*Literal); !isLit && op == ArithmeticEq {
+				fnsNew[len(fnsNew)-1] = equalsFunction{
+					Function: fnsNew[len(fnsNew)-1],
+					lhs:      leftFn,
+					rhs:      rightFn,
+				}
+			}
 		} else {
 			fnsNew = append(fnsNew, rightFn)
 			opsNew = append(opsNew, op)
@@ -518,3 +525,28 @@ func NewArithmeticExpression(fns []Function, ops []ArithmeticOperator) (Function
 }
 
 //------------------------------------------------------------------------------
+
+// equalsFunction is an equality comparison between two functions, and is
+// distinct from other arithmetic so that callers are able to optimise
+// comparisons against literal values.
+type equalsFunction struct {
+	Function
+	lhs, rhs Function
+}
+
+// EqualsLiteral returns the operands of a function when it is exactly an
+// equality comparison between a function and a literal value, e.g.
+// `this.foo == "bar"`.
+func EqualsLiteral(fn Function) (operand Function, value any, ok bool) {
+	eq, isEq := fn.(equalsFunction)
+	if !isEq {
+		return nil, nil, false
+	}
+	if lit, isLit := eq.rhs.(*Literal); isLit {
+		return eq.lhs, lit.Value, true
+	}
+	if lit, isLit := eq.lhs.(*Literal); isLit {
+		return eq.rhs, lit.Value, true
+	}
+	return nil, nil, false
+}
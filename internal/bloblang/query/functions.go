@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	gonanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/segmentio/ksuid"
 
+	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
 
@@ -350,6 +352,19 @@ var _ = registerSimpleFunction(
 	},
 )
 
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "expired",
+		"Returns a boolean value indicating whether a message has passed the expiry set with a [`ttl` processor](/docs/components/processors/ttl). Messages without an expiry never expire.",
+		NewExampleSpec("",
+			`root = if expired() { deleted() }`,
+		),
+	).AtVersion("4.9.0"),
+	func(ctx FunctionContext) (any, error) {
+		return message.IsExpired(ctx.MsgBatch.Get(ctx.Index), time.Now()), nil
+	},
+)
+
 //------------------------------------------------------------------------------
 
 var _ = registerFunction(
@@ -454,6 +469,67 @@ func jsonFunction(args *ParsedParams) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "json_from_part",
+		"Returns the value of a field within a JSON message of the batch located by its index and a [dot path][field_paths] argument, allowing a mapping to reference the sibling messages of the message being mapped. Negative indexes count backwards from the end of the batch, and an error is returned if the index is out of bounds. The index can be a dynamic query, such as one using the [`batch_index` function](#batch_index).",
+		NewExampleSpec("",
+			`root.prev_id = if batch_index() > 0 { json_from_part(batch_index() - 1, "id") }`,
+		),
+		NewExampleSpec(
+			"The path argument is optional and if omitted the entire JSON payload of the message is returned.",
+			`root.last = json_from_part(-1)`,
+		),
+	).AtVersion("4.9.0").
+		Param(ParamInt64("index", "The index of the message within the batch.")).
+		Param(ParamString("path", "An optional [dot path][field_paths] identifying a field to obtain.").Default("")),
+	jsonFromPartFunction,
+)
+
+func jsonFromPartFunction(args *ParsedParams) (Function, error) {
+	index, err := args.FieldInt64("index")
+	if err != nil {
+		return nil, err
+	}
+	path, err := args.FieldString("path")
+	if err != nil {
+		return nil, err
+	}
+	var argPath []string
+	if len(path) > 0 {
+		argPath = gabs.DotPathToSlice(path)
+	}
+	return ClosureFunction("json path `"+SliceToDotPath(argPath...)+"` from part "+strconv.FormatInt(index, 10), func(ctx FunctionContext) (any, error) {
+		i, size := int(index), ctx.MsgBatch.Len()
+		if i < 0 {
+			i = size + i
+		}
+		if i < 0 || i >= size {
+			return nil, fmt.Errorf("index %v is out of bounds for a batch of size %v", index, size)
+		}
+		jPart, err := ctx.MsgBatch.Get(i).AsStructured()
+		if err != nil {
+			return nil, &ErrRecoverable{
+				Recovered: nil,
+				Err:       err,
+			}
+		}
+		gPart := gabs.Wrap(jPart)
+		if len(argPath) > 0 {
+			gPart = gPart.Search(argPath...)
+		}
+		return ISanitize(gPart.Data()), nil
+	}, func(ctx TargetsContext) (TargetsContext, []TargetPath) {
+		paths := []TargetPath{
+			NewTargetPath(TargetValue, argPath...),
+		}
+		ctx = ctx.WithValues(paths)
+		return ctx, paths
+	}), nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerFunction(
 	NewFunctionSpec(
 		FunctionCategoryMessage, "meta",
@@ -473,12 +549,86 @@ var _ = registerFunction(
 			return nil, err
 		}
 		if len(key) > 0 {
-			return ClosureFunction("meta field "+key, func(ctx FunctionContext) (any, error) {
-				v := ctx.MsgBatch.Get(ctx.Index).MetaGet(key)
-				if v == "" {
+			return metaFieldFunction(key), nil
+		}
+		return ClosureFunction("meta object", func(ctx FunctionContext) (any, error) {
+			kvs := map[string]any{}
+			_ = ctx.MsgBatch.Get(ctx.Index).MetaIter(func(k, v string) error {
+				if len(v) > 0 {
+					kvs[k] = v
+				}
+				return nil
+			})
+			return kvs, nil
+		}, func(ctx TargetsContext) (TargetsContext, []TargetPath) {
+			paths := []TargetPath{
+				NewTargetPath(TargetMetadata),
+			}
+			ctx = ctx.WithValues(paths)
+			return ctx, paths
+		}), nil
+	},
+)
+
+// metaFieldFunction returns the string value of a metadata key of the input
+// message, and is distinct from other functions so that callers are able to
+// optimise references to metadata.
+type metaFieldFunction string
+
+func (f metaFieldFunction) Annotation() string {
+	return "meta field " + string(f)
+}
+
+func (f metaFieldFunction) Exec(ctx FunctionContext) (any, error) {
+	v := ctx.MsgBatch.Get(ctx.Index).MetaGet(string(f))
+	if v == "" {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (f metaFieldFunction) QueryTargets(ctx TargetsContext) (TargetsContext, []TargetPath) {
+	paths := []TargetPath{
+		NewTargetPath(TargetMetadata, string(f)),
+	}
+	ctx = ctx.WithValues(paths)
+	return ctx, paths
+}
+
+// MetaFieldKey returns the metadata key referenced by a function when it is
+// exactly a meta function with a static key argument, e.g. `meta("foo")`.
+func MetaFieldKey(fn Function) (string, bool) {
+	f, ok := fn.(metaFieldFunction)
+	return string(f), ok
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "metadata",
+		"Returns the value of a metadata key from the input message with its type preserved, or `null` if the key does not exist. Metadata values that are numbers, booleans, timestamps or structured values keep their type, whereas the [`meta` function](#meta) always returns strings. Since values are extracted from the read-only input message they do NOT reflect changes made from within the map. This function supports extracting metadata from other messages of a batch with the `from` method.",
+		NewExampleSpec("",
+			`root.partition = metadata("kafka_partition") + 1`,
+			`root.partition = metadata("nope") | 0`,
+		),
+		NewExampleSpec(
+			"The key parameter is optional and if omitted the entire metadata contents are returned as an object.",
+			`root.all_metadata = metadata()`,
+		),
+	).Param(ParamString("key", "An optional key of a metadata value to obtain.").Default("")),
+	func(args *ParsedParams) (Function, error) {
+		key, err := args.FieldString("key")
+		if err != nil {
+			return nil, err
+		}
+		if len(key) > 0 {
+			return ClosureFunction("metadata field "+key, func(ctx FunctionContext) (any, error) {
+				v, exists := ctx.MsgBatch.Get(ctx.Index).MetaGetMut(key)
+				if !exists {
 					return nil, nil
 				}
-				return v, nil
+				return IClone(v), nil
 			}, func(ctx TargetsContext) (TargetsContext, []TargetPath) {
 				paths := []TargetPath{
 					NewTargetPath(TargetMetadata, key),
@@ -487,12 +637,10 @@ var _ = registerFunction(
 				return ctx, paths
 			}), nil
 		}
-		return ClosureFunction("meta object", func(ctx FunctionContext) (any, error) {
+		return ClosureFunction("metadata object", func(ctx FunctionContext) (any, error) {
 			kvs := map[string]any{}
-			_ = ctx.MsgBatch.Get(ctx.Index).MetaIter(func(k, v string) error {
-				if len(v) > 0 {
-					kvs[k] = v
-				}
+			_ = ctx.MsgBatch.Get(ctx.Index).MetaIterMut(func(k string, v any) error {
+				kvs[k] = IClone(v)
 				return nil
 			})
 			return kvs, nil
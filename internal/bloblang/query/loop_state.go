@@ -0,0 +1,66 @@
+package query
+
+import (
+	"context"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type loopStateKey struct{}
+
+// LoopState describes the progress of a processor that loops over messages,
+// which is carried across each iteration of the loop and exposed to mappings
+// with the loop_state function.
+type LoopState struct {
+	// Iteration is the number of iterations completed so far.
+	Iteration int64
+
+	// LastError is the error that the first message had following the most
+	// recent iteration, or empty if it had none.
+	LastError string
+
+	// Values are arbitrary values carried across iterations.
+	Values any
+}
+
+// WithLoopState returns a message part with a loop state attached, or the part
+// itself when the state is already attached.
+func WithLoopState(p *message.Part, state *LoopState) *message.Part {
+	ctx := message.GetContext(p)
+	if s, _ := ctx.Value(loopStateKey{}).(*LoopState); s == state {
+		return p
+	}
+	return message.WithContext(context.WithValue(ctx, loopStateKey{}, state), p)
+}
+
+// GetLoopState returns the loop state attached to a message part, or nil if
+// the part is not within a loop.
+func GetLoopState(p *message.Part) *LoopState {
+	s, _ := message.GetContext(p).Value(loopStateKey{}).(*LoopState)
+	return s
+}
+
+var _ = registerSimpleFunction(
+	NewFunctionSpec(
+		FunctionCategoryMessage, "loop_state",
+		"Returns an object describing the progress of a processor that loops over messages, such as the [`while` processor](/docs/components/processors/while), or `null` if the message is not being processed within a loop. The field `iteration` is the number of iterations completed so far, `last_error` is the error of the first message following the most recent iteration or `null` if it had none, and `values` contains any values set by the loop.",
+		NewExampleSpec("",
+			`root = if loop_state().iteration < 5 && errored() { true } else { false }`,
+		),
+	).AtVersion("4.9.0"),
+	func(fCtx FunctionContext) (any, error) {
+		state := GetLoopState(fCtx.MsgBatch.Get(fCtx.Index))
+		if state == nil {
+			return nil, nil
+		}
+		var lastErr any
+		if state.LastError != "" {
+			lastErr = state.LastError
+		}
+		return map[string]any{
+			"iteration":  state.Iteration,
+			"last_error": lastErr,
+			"values":     state.Values,
+		}, nil
+	},
+)
@@ -74,6 +74,9 @@ func (s *ProcessorSet) Init(conf processor.Config, mgr NewManagement) (processor
 		return nil, component.ErrInvalidType("processor", conf.Type)
 	}
 	c, err := spec.constructor(conf, mgr)
+	if err == nil {
+		c, err = processor.WrapExecution(conf.Execution, c)
+	}
 	err = wrapComponentErr(mgr, "processor", err)
 	return c, err
 }
@@ -0,0 +1,232 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+)
+
+// ErrOpen is returned by Allow when a request should not be attempted because
+// the circuit is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State represents the current state of a circuit breaker.
+type State int
+
+// The possible states of a circuit breaker, the values of which are exposed via
+// the circuit_breaker_state gauge.
+const (
+	StateClosed State = iota
+	StateHalfOpen
+	StateOpen
+)
+
+// String returns a human readable representation of the state.
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+	return "unknown"
+}
+
+// Breaker tracks the outcome of requests made to a downstream service and
+// determines whether subsequent requests should be attempted. A breaker created
+// from a config that isn't enabled permits all requests.
+type Breaker struct {
+	enabled        bool
+	errorThreshold float64
+	minRequests    int
+	window         time.Duration
+	openPeriod     time.Duration
+	halfOpenTrials int
+
+	mut            sync.Mutex
+	state          State
+	windowStart    time.Time
+	successes      int
+	failures       int
+	openedAt       time.Time
+	trialsInFlight int
+	trialSuccesses int
+
+	mState    metrics.StatGauge
+	mOpened   metrics.StatCounter
+	mRejected metrics.StatCounter
+
+	now func() time.Time
+}
+
+// Metrics contains the metrics that a circuit breaker reports to.
+type Metrics struct {
+	State    metrics.StatGauge
+	Opened   metrics.StatCounter
+	Rejected metrics.StatCounter
+}
+
+// New creates a circuit breaker from a config, registering its metrics with
+// the provided metrics, which should be scoped to the component that owns the
+// breaker so that each breaker is reported under a distinct series. Disabled
+// breakers do not register metrics.
+func New(conf Config, stats metrics.Type) (*Breaker, error) {
+	if !conf.Enabled {
+		stats = metrics.Noop()
+	}
+	return NewWithMetrics(conf, Metrics{
+		State:    stats.GetGauge("circuit_breaker_state"),
+		Opened:   stats.GetCounter("circuit_breaker_opened"),
+		Rejected: stats.GetCounter("circuit_breaker_rejected"),
+	})
+}
+
+// NewWithMetrics creates a circuit breaker from a config that reports to the
+// provided metrics, which allows components to register the metrics of many
+// breakers under distinct labels.
+func NewWithMetrics(conf Config, m Metrics) (*Breaker, error) {
+	b := &Breaker{
+		enabled:        conf.Enabled,
+		errorThreshold: conf.ErrorThreshold,
+		minRequests:    conf.MinRequests,
+		halfOpenTrials: conf.HalfOpenTrials,
+		mState:         m.State,
+		mOpened:        m.Opened,
+		mRejected:      m.Rejected,
+		now:            time.Now,
+	}
+	if !b.enabled {
+		return b, nil
+	}
+
+	if b.errorThreshold <= 0 || b.errorThreshold > 1 {
+		return nil, fmt.Errorf("error_threshold must be greater than 0 and no more than 1, got %v", b.errorThreshold)
+	}
+	if b.minRequests < 1 {
+		b.minRequests = 1
+	}
+	if b.halfOpenTrials < 1 {
+		b.halfOpenTrials = 1
+	}
+
+	var err error
+	if b.window, err = time.ParseDuration(conf.Window); err != nil {
+		return nil, fmt.Errorf("failed to parse window duration: %w", err)
+	}
+	if b.openPeriod, err = time.ParseDuration(conf.OpenPeriod); err != nil {
+		return nil, fmt.Errorf("failed to parse open_period duration: %w", err)
+	}
+
+	b.windowStart = b.now()
+	b.mState.Set(int64(StateClosed))
+	return b, nil
+}
+
+// State returns the current state of the circuit breaker.
+func (b *Breaker) State() State {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	// An open circuit is half-open once the open period has elapsed, even
+	// though the transition only occurs on the next request.
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openPeriod {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) setState(s State) {
+	b.state = s
+	b.mState.Set(int64(s))
+}
+
+func (b *Breaker) resetWindow() {
+	b.windowStart = b.now()
+	b.successes, b.failures = 0, 0
+}
+
+// Allow returns ErrOpen if a request should not be attempted, otherwise nil is
+// returned and the caller must call Done with the outcome of the request.
+func (b *Breaker) Allow() error {
+	if !b.enabled {
+		return nil
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.openPeriod {
+			b.mRejected.Incr(1)
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+		b.trialsInFlight, b.trialSuccesses = 0, 0
+		fallthrough
+	case StateHalfOpen:
+		if b.trialsInFlight+b.trialSuccesses >= b.halfOpenTrials {
+			b.mRejected.Incr(1)
+			return ErrOpen
+		}
+		b.trialsInFlight++
+	}
+	return nil
+}
+
+// Done reports the outcome of a request that was permitted by Allow. Context
+// cancellations are not counted as failures as they typically indicate that
+// the component is shutting down.
+func (b *Breaker) Done(err error) {
+	if !b.enabled {
+		return
+	}
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, ErrOpen)) {
+		return
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	switch b.state {
+	case StateHalfOpen:
+		if b.trialsInFlight > 0 {
+			b.trialsInFlight--
+		}
+		if err != nil {
+			b.open()
+			return
+		}
+		if b.trialSuccesses++; b.trialSuccesses >= b.halfOpenTrials {
+			b.setState(StateClosed)
+			b.resetWindow()
+		}
+	case StateClosed:
+		if b.now().Sub(b.windowStart) >= b.window {
+			b.resetWindow()
+		}
+		if err != nil {
+			b.failures++
+		} else {
+			b.successes++
+		}
+		total := b.successes + b.failures
+		if total >= b.minRequests && float64(b.failures)/float64(total) >= b.errorThreshold {
+			b.open()
+		}
+	}
+}
+
+func (b *Breaker) open() {
+	b.setState(StateOpen)
+	b.openedAt = b.now()
+	b.trialsInFlight, b.trialSuccesses = 0, 0
+	b.resetWindow()
+	b.mOpened.Incr(1)
+}
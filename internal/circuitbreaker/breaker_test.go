@@ -0,0 +1,157 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.t
+}
+
+func (f *fakeClock) add(d time.Duration) {
+	f.t = f.t.Add(d)
+}
+
+func newTestBreaker(t *testing.T, conf Config) (*Breaker, *fakeClock, *metrics.Local) {
+	t.Helper()
+
+	stats := metrics.NewLocal()
+	b, err := New(conf, stats)
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b.now = clock.now
+	b.windowStart = clock.now()
+	return b, clock, stats
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b, _, _ := newTestBreaker(t, NewConfig())
+
+	errBad := errors.New("bad")
+	for i := 0; i < 100; i++ {
+		require.NoError(t, b.Allow())
+		b.Done(errBad)
+	}
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerBadConfig(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.ErrorThreshold = 1.5
+	_, err := New(conf, metrics.Noop())
+	require.Error(t, err)
+
+	conf = NewConfig()
+	conf.Enabled = true
+	conf.Window = "nope"
+	_, err = New(conf, metrics.Noop())
+	require.Error(t, err)
+}
+
+func TestBreakerOpensAndCloses(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.MinRequests = 4
+	conf.ErrorThreshold = 0.5
+	conf.OpenPeriod = "10s"
+	conf.HalfOpenTrials = 2
+
+	b, clock, stats := newTestBreaker(t, conf)
+	errBad := errors.New("bad")
+
+	for _, err := range []error{nil, errBad, nil} {
+		require.NoError(t, b.Allow())
+		b.Done(err)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	require.NoError(t, b.Allow())
+	b.Done(errBad)
+	assert.Equal(t, StateOpen, b.State())
+
+	assert.Equal(t, ErrOpen, b.Allow())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	clock.add(10 * time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	// Only two trials permitted at once.
+	require.NoError(t, b.Allow())
+	require.NoError(t, b.Allow())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	b.Done(nil)
+	assert.Equal(t, StateHalfOpen, b.State())
+	b.Done(nil)
+	assert.Equal(t, StateClosed, b.State())
+
+	counters := stats.GetCounters()
+	assert.Equal(t, int64(1), counters["circuit_breaker_opened"])
+	assert.Equal(t, int64(3), counters["circuit_breaker_rejected"])
+}
+
+func TestBreakerHalfOpenFailure(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.MinRequests = 1
+	conf.OpenPeriod = "10s"
+
+	b, clock, _ := newTestBreaker(t, conf)
+	errBad := errors.New("bad")
+
+	require.NoError(t, b.Allow())
+	b.Done(errBad)
+	assert.Equal(t, StateOpen, b.State())
+
+	clock.add(10 * time.Second)
+	require.NoError(t, b.Allow())
+	b.Done(errBad)
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Allow())
+}
+
+func TestBreakerWindowReset(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.MinRequests = 4
+	conf.Window = "1m"
+
+	b, clock, _ := newTestBreaker(t, conf)
+	errBad := errors.New("bad")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, b.Allow())
+		b.Done(errBad)
+	}
+
+	clock.add(time.Minute)
+	require.NoError(t, b.Allow())
+	b.Done(errBad)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerIgnoresCancellation(t *testing.T) {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.MinRequests = 1
+
+	b, _, _ := newTestBreaker(t, conf)
+
+	require.NoError(t, b.Allow())
+	b.Done(context.Canceled)
+	assert.Equal(t, StateClosed, b.State())
+}
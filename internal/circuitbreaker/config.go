@@ -0,0 +1,23 @@
+package circuitbreaker
+
+// Config contains configuration parameters for a circuit breaker.
+type Config struct {
+	Enabled        bool    `json:"enabled" yaml:"enabled"`
+	ErrorThreshold float64 `json:"error_threshold" yaml:"error_threshold"`
+	MinRequests    int     `json:"min_requests" yaml:"min_requests"`
+	Window         string  `json:"window" yaml:"window"`
+	OpenPeriod     string  `json:"open_period" yaml:"open_period"`
+	HalfOpenTrials int     `json:"half_open_trials" yaml:"half_open_trials"`
+}
+
+// NewConfig creates a circuit breaker config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:        false,
+		ErrorThreshold: 0.5,
+		MinRequests:    20,
+		Window:         "1m",
+		OpenPeriod:     "30s",
+		HalfOpenTrials: 5,
+	}
+}
@@ -0,0 +1,26 @@
+package circuitbreaker
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// PolicyFieldSpecs returns the field specs that describe the behaviour of a
+// circuit breaker, excluding the enabled toggle.
+func PolicyFieldSpecs() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldFloat("error_threshold", "The ratio of failed requests to total requests within a window at which the circuit is opened, between `0` and `1`.").HasDefault(0.5),
+		docs.FieldInt("min_requests", "The minimum number of requests that must be observed within a window before the error threshold is evaluated.").HasDefault(20),
+		docs.FieldString("window", "The period over which request outcomes are counted, counts are reset at the end of each window.", "30s", "5m").HasDefault("1m"),
+		docs.FieldString("open_period", "The period of time to wait after the circuit has opened before probing the downstream service with half-open trials.", "10s", "1m").HasDefault("30s"),
+		docs.FieldInt("half_open_trials", "The number of trial requests permitted whilst the circuit is half-open. The circuit closes once this many trials succeed, and opens again upon any trial failing.").HasDefault(5),
+	}
+}
+
+// FieldSpec returns a spec for a common circuit_breaker field.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject("circuit_breaker", `
+Allows you to configure a circuit breaker that stops attempting requests when the error rate of the downstream service breaches a threshold. Whilst the circuit is open requests fail immediately, and after a period a limited number of trial requests are attempted in order to determine whether the circuit should be closed again.
+
+The state of the circuit breaker is exposed with the gauge metric `+"`circuit_breaker_state`"+`, where `+"`0`"+` is closed, `+"`1`"+` is half-open and `+"`2`"+` is open. The counters `+"`circuit_breaker_opened`"+` and `+"`circuit_breaker_rejected`"+` track the number of times the circuit has opened and the number of requests rejected whilst open respectively. These metrics are labelled with the path of this field within the config, and therefore each circuit breaker is reported separately.`,
+	).WithChildren(append(docs.FieldSpecs{
+		docs.FieldBool("enabled", "Whether the circuit breaker is enabled.").HasDefault(false),
+	}, PolicyFieldSpecs()...)...).Advanced().ChildDefaultAndTypesFromStruct(NewConfig())
+}
@@ -0,0 +1,5 @@
+// Package circuitbreaker provides a generic circuit breaker that can wrap calls
+// made by components to downstream services, opening the circuit once an error
+// rate threshold is breached and probing the service with half-open trials
+// before closing it again.
+package circuitbreaker
@@ -0,0 +1,349 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ErrNoBundle is returned when a file does not end with a bundle.
+var ErrNoBundle = errors.New("file does not contain a bundle")
+
+const (
+	manifestPath = "manifest.json"
+	filesPrefix  = "files/"
+
+	trailerMagic = "benthos-bundle-1"
+	trailerLen   = 8 + sha256.Size + len(trailerMagic)
+)
+
+// Manifest describes the files of a bundle and how they are used.
+type Manifest struct {
+	BenthosVersion string   `json:"benthos_version"`
+	Config         string   `json:"config"`
+	Resources      []string `json:"resources,omitempty"`
+	Templates      []string `json:"templates,omitempty"`
+
+	// Files maps the path of each file of the bundle to the hex encoded SHA256
+	// checksum of its contents.
+	Files map[string]string `json:"files"`
+}
+
+// Bundle is a config and the files that it depends on, which are written as a
+// zip archive followed by a trailer that contains the size and checksum of the
+// archive. Since the trailer is at the end of the file a bundle can be
+// appended to an executable.
+type Bundle struct {
+	Manifest Manifest
+	files    map[string][]byte
+	sum      []byte
+}
+
+func cleanPath(path string) (string, error) {
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+	if !fs.ValidPath(cleaned) || cleaned == "." {
+		return "", fmt.Errorf("path %v must be relative to, and within, the current directory", path)
+	}
+	return cleaned, nil
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Build creates a bundle from a config and the resources, templates and other
+// files that it depends on. Paths must be relative to the current directory,
+// as that is the directory a bundle is run from once extracted.
+func Build(version, config string, resources, templates, files []string) (*Bundle, error) {
+	b := &Bundle{
+		Manifest: Manifest{
+			BenthosVersion: version,
+			Files:          map[string]string{},
+		},
+		files: map[string][]byte{},
+	}
+
+	add := func(path string) (string, error) {
+		cleaned, err := cleanPath(path)
+		if err != nil {
+			return "", err
+		}
+		if _, exists := b.files[cleaned]; exists {
+			return cleaned, nil
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		b.files[cleaned] = contents
+		b.Manifest.Files[cleaned] = checksum(contents)
+		return cleaned, nil
+	}
+
+	var err error
+	if config == "" {
+		return nil, errors.New("a config file must be specified")
+	}
+	if b.Manifest.Config, err = add(config); err != nil {
+		return nil, err
+	}
+	for _, path := range resources {
+		cleaned, err := add(path)
+		if err != nil {
+			return nil, err
+		}
+		b.Manifest.Resources = append(b.Manifest.Resources, cleaned)
+	}
+	for _, path := range templates {
+		cleaned, err := add(path)
+		if err != nil {
+			return nil, err
+		}
+		b.Manifest.Templates = append(b.Manifest.Templates, cleaned)
+	}
+	for _, path := range files {
+		if _, err := add(path); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Write the bundle as an archive followed by its trailer.
+func (b *Bundle) Write(w io.Writer) error {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+
+	// Entries are written in a consistent order and without modification
+	// times so that bundles of the same files are identical.
+	writeEntry := func(name string, contents []byte) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Unix(0, 0).UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(contents)
+		return err
+	}
+
+	manifestBytes, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeEntry(manifestPath, manifestBytes); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(b.files))
+	for path := range b.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := writeEntry(filesPrefix+path, b.files[path]); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	trailer := make([]byte, trailerLen)
+	binary.BigEndian.PutUint64(trailer, uint64(archive.Len()))
+	sum := sha256.Sum256(archive.Bytes())
+	copy(trailer[8:], sum[:])
+	copy(trailer[8+sha256.Size:], trailerMagic)
+
+	if _, err := w.Write(archive.Bytes()); err != nil {
+		return err
+	}
+	_, err = w.Write(trailer)
+	return err
+}
+
+// locate returns the offset and size of the archive of a bundle at the end of
+// a file, along with its expected checksum.
+func locate(r io.ReaderAt, size int64) (offset, archiveSize int64, sum []byte, err error) {
+	if size < int64(trailerLen) {
+		return 0, 0, nil, ErrNoBundle
+	}
+	trailer := make([]byte, trailerLen)
+	if _, err = r.ReadAt(trailer, size-int64(trailerLen)); err != nil {
+		return 0, 0, nil, err
+	}
+	if string(trailer[8+sha256.Size:]) != trailerMagic {
+		return 0, 0, nil, ErrNoBundle
+	}
+
+	archiveSize = int64(binary.BigEndian.Uint64(trailer[:8]))
+	if archiveSize <= 0 || archiveSize > size-int64(trailerLen) {
+		return 0, 0, nil, errors.New("bundle trailer has an invalid archive size")
+	}
+	return size - int64(trailerLen) - archiveSize, archiveSize, trailer[8 : 8+sha256.Size], nil
+}
+
+// Read a bundle from the end of a file, verifying the checksum of the archive
+// and every file within it.
+func Read(r io.ReaderAt, size int64) (*Bundle, error) {
+	offset, archiveSize, sum, err := locate(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := make([]byte, archiveSize)
+	if _, err := r.ReadAt(archive, offset); err != nil {
+		return nil, err
+	}
+	if actual := sha256.Sum256(archive); !bytes.Equal(actual[:], sum) {
+		return nil, errors.New("bundle checksum does not match, the file may be corrupted or modified")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), archiveSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle archive: %w", err)
+	}
+
+	entries := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle file %v: %w", f.Name, err)
+		}
+		entries[f.Name] = contents
+	}
+
+	b := &Bundle{files: map[string][]byte{}, sum: sum}
+	manifestBytes, exists := entries[manifestPath]
+	if !exists {
+		return nil, errors.New("bundle does not contain a manifest")
+	}
+	if err := json.Unmarshal(manifestBytes, &b.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if len(entries)-1 != len(b.Manifest.Files) {
+		return nil, errors.New("bundle contains files that are not listed in its manifest")
+	}
+
+	for path, expected := range b.Manifest.Files {
+		if _, err := cleanPath(path); err != nil {
+			return nil, fmt.Errorf("bundle manifest contains an invalid path: %w", err)
+		}
+		contents, exists := entries[filesPrefix+path]
+		if !exists {
+			return nil, fmt.Errorf("bundle is missing file %v", path)
+		}
+		if checksum(contents) != expected {
+			return nil, fmt.Errorf("checksum of bundle file %v does not match", path)
+		}
+		b.files[path] = contents
+	}
+
+	for _, path := range append([]string{b.Manifest.Config}, append(b.Manifest.Resources, b.Manifest.Templates...)...) {
+		if _, exists := b.files[path]; !exists {
+			return nil, fmt.Errorf("bundle is missing file %v", path)
+		}
+	}
+	return b, nil
+}
+
+// Open reads a bundle from the end of a file, which is either a bundle
+// artifact or an executable with a bundle appended to it.
+func Open(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, info.Size())
+}
+
+// FromExecutable reads the bundle appended to the running executable, and
+// returns ErrNoBundle if there isn't one.
+func FromExecutable() (*Bundle, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return Open(executable)
+}
+
+// DefaultDir returns a directory within the temporary directory of the system
+// that is unique to the contents of the bundle.
+func (b *Bundle) DefaultDir() string {
+	return filepath.Join(os.TempDir(), "benthos-bundle-"+hex.EncodeToString(b.sum)[:16])
+}
+
+// Extract writes the files of the bundle to a directory.
+func (b *Bundle) Extract(dir string) error {
+	for path, contents := range b.files {
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, contents, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteExecutable writes a copy of an executable with the bundle appended to
+// it. If the executable already contains a bundle then it is replaced.
+func (b *Bundle) WriteExecutable(executable, target string) error {
+	in, err := os.Open(executable)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := info.Size()
+	if offset, _, _, err := locate(in, size); err == nil {
+		size = offset
+	} else if !errors.Is(err, ErrNoBundle) {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, io.NewSectionReader(in, 0, size)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := b.Write(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
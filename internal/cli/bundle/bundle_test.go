@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+	return dir
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for path, contents := range files {
+		target := filepath.Join(dir, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o755))
+		require.NoError(t, os.WriteFile(target, []byte(contents), 0o644))
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	dir := chdirTemp(t)
+	writeTestFiles(t, dir, map[string]string{
+		"config.yaml":            "input:\n  stdin: {}\n",
+		"resources/caches.yaml":  "cache_resources: []\n",
+		"templates/foo.yaml":     "name: foo\n",
+		"mappings/a.blobl":       "root = this\n",
+		"lookups/countries.json": `{"uk":"United Kingdom"}`,
+	})
+
+	b, err := Build("1.2.3", "./config.yaml", []string{"resources/caches.yaml"}, []string{"./templates/foo.yaml"}, []string{
+		"mappings/a.blobl", "lookups/countries.json", "config.yaml",
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+
+	// Bundles of the same files are identical.
+	var bufTwo bytes.Buffer
+	require.NoError(t, b.Write(&bufTwo))
+	assert.Equal(t, buf.Bytes(), bufTwo.Bytes())
+
+	// A bundle is read from the end of a file.
+	contents := append([]byte("executable contents"), buf.Bytes()...)
+	read, err := Read(bytes.NewReader(contents), int64(len(contents)))
+	require.NoError(t, err)
+
+	assert.Equal(t, "1.2.3", read.Manifest.BenthosVersion)
+	assert.Equal(t, "config.yaml", read.Manifest.Config)
+	assert.Equal(t, []string{"resources/caches.yaml"}, read.Manifest.Resources)
+	assert.Equal(t, []string{"templates/foo.yaml"}, read.Manifest.Templates)
+	assert.Len(t, read.Manifest.Files, 5)
+
+	extractDir := t.TempDir()
+	require.NoError(t, read.Extract(extractDir))
+
+	countries, err := os.ReadFile(filepath.Join(extractDir, "lookups", "countries.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"uk":"United Kingdom"}`, string(countries))
+}
+
+func TestBundleBuildErrors(t *testing.T) {
+	dir := chdirTemp(t)
+	writeTestFiles(t, dir, map[string]string{
+		"config.yaml": "input:\n  stdin: {}\n",
+	})
+
+	_, err := Build("", "", nil, nil, nil)
+	require.Error(t, err)
+
+	_, err = Build("", "./nope.yaml", nil, nil, nil)
+	require.Error(t, err)
+
+	_, err = Build("", filepath.Join(dir, "config.yaml"), nil, nil, nil)
+	require.Error(t, err)
+
+	_, err = Build("", "config.yaml", nil, nil, []string{"../outside.blobl"})
+	require.Error(t, err)
+}
+
+func TestBundleReadErrors(t *testing.T) {
+	dir := chdirTemp(t)
+	writeTestFiles(t, dir, map[string]string{
+		"config.yaml": "input:\n  stdin: {}\n",
+	})
+
+	b, err := Build("", "config.yaml", nil, nil, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(&buf))
+
+	_, err = Read(bytes.NewReader([]byte("not a bundle")), 12)
+	require.ErrorIs(t, err, ErrNoBundle)
+
+	corrupted := append([]byte(nil), buf.Bytes()...)
+	corrupted[10] ^= 0xff
+	_, err = Read(bytes.NewReader(corrupted), int64(len(corrupted)))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoBundle)
+
+	truncated := buf.Bytes()[20:]
+	_, err = Read(bytes.NewReader(truncated), int64(len(truncated)))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoBundle)
+}
+
+func TestBundleWriteExecutable(t *testing.T) {
+	dir := chdirTemp(t)
+	writeTestFiles(t, dir, map[string]string{
+		"benthos":     "executable contents",
+		"config.yaml": "input:\n  stdin: {}\n",
+		"other.yaml":  "input:\n  generate: {}\n",
+	})
+
+	b, err := Build("", "config.yaml", nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, b.WriteExecutable("benthos", "pipeline"))
+
+	info, err := os.Stat("pipeline")
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&0o100)
+
+	read, err := Open("pipeline")
+	require.NoError(t, err)
+	assert.Equal(t, "config.yaml", read.Manifest.Config)
+
+	// The bundle of an executable that already contains one is replaced.
+	bTwo, err := Build("", "other.yaml", nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, bTwo.WriteExecutable("pipeline", "pipeline_two"))
+
+	read, err = Open("pipeline_two")
+	require.NoError(t, err)
+	assert.Equal(t, "other.yaml", read.Manifest.Config)
+	assert.Len(t, read.Manifest.Files, 1)
+
+	var expected bytes.Buffer
+	expected.WriteString("executable contents")
+	require.NoError(t, bTwo.Write(&expected))
+
+	contents, err := os.ReadFile("pipeline_two")
+	require.NoError(t, err)
+	assert.Equal(t, expected.Bytes(), contents)
+}
@@ -0,0 +1,113 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath"
+)
+
+// CliCommand is a cli.Command definition for bundling a config and the files it
+// depends on into a single binary or artifact.
+func CliCommand(version string) *cli.Command {
+	return &cli.Command{
+		Name:  "bundle",
+		Usage: "Bundle a config and the files it depends on into a single executable",
+		Description: `
+Creates a copy of a Benthos executable with a config, along with its resources,
+templates and any other files it depends on, embedded within it. When the
+executable is run it verifies the checksums of the bundled files, extracts them
+and runs the bundled config, which makes it possible to ship a pipeline to a
+device as a single file:
+
+  benthos -c ./config.yaml -t "./templates/*.yaml" bundle --file "./mappings/*.blobl" -o ./pipeline
+  ./pipeline
+
+The executable that is copied defaults to the one running this command, and a
+different one can be specified with --binary in order to target another
+platform. Alternatively, the bundle can be written as a standalone artifact
+with --archive, which is run with the --bundle flag:
+
+  benthos -c ./config.yaml bundle --archive -o ./pipeline.bundle
+  benthos --bundle ./pipeline.bundle
+
+Paths must be relative to, and within, the current directory. Bundles are run
+from the directory they are extracted to, and therefore relative paths within
+the bundled config resolve to the bundled files.
+
+For more information check out the docs at:
+https://benthos.dev/docs/guides/bundles`[1:],
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "The path to write the bundled executable or artifact to",
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "Extra files to bundle, such as mappings and lookup files, supports glob patterns (requires quotes)",
+			},
+			&cli.StringFlag{
+				Name:  "binary",
+				Usage: "The Benthos executable to bundle the config with, defaults to the current executable",
+			},
+			&cli.BoolFlag{
+				Name:  "archive",
+				Value: false,
+				Usage: "Write the bundle as a standalone artifact rather than an executable",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if err := create(c, version); err != nil {
+				fmt.Fprintf(os.Stderr, "Bundle failed: %v\n", err)
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+}
+
+func create(c *cli.Context, version string) error {
+	resources, err := filepath.Globs(c.StringSlice("resources"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve resource glob pattern: %w", err)
+	}
+	templates, err := filepath.Globs(c.StringSlice("templates"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve template glob pattern: %w", err)
+	}
+	files, err := filepath.Globs(c.StringSlice("file"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve file glob pattern: %w", err)
+	}
+
+	b, err := Build(version, c.String("config"), resources, templates, files)
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if c.Bool("archive") {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		if err := b.Write(f); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+
+	executable := c.String("binary")
+	if executable == "" {
+		if executable, err = os.Executable(); err != nil {
+			return err
+		}
+	}
+	return b.WriteExecutable(executable, output)
+}
@@ -2,21 +2,26 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
 	"github.com/benthosdev/benthos/v4/internal/cli/blobl"
+	"github.com/benthosdev/benthos/v4/internal/cli/bundle"
 	"github.com/benthosdev/benthos/v4/internal/cli/studio"
 	clitemplate "github.com/benthosdev/benthos/v4/internal/cli/template"
 	"github.com/benthosdev/benthos/v4/internal/cli/test"
 	"github.com/benthosdev/benthos/v4/internal/config"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/filepath"
+	"github.com/benthosdev/benthos/v4/internal/goplugin"
+	"github.com/benthosdev/benthos/v4/internal/kubernetes"
 	"github.com/benthosdev/benthos/v4/internal/template"
 )
 
@@ -100,6 +105,62 @@ func cmdVersion() {
 
 //------------------------------------------------------------------------------
 
+// applyBundle extracts a bundle that is either specified with --bundle or
+// appended to the running executable, and then sets the config, resources and
+// templates flags to the files of the bundle. Bundles are run from the
+// directory they are extracted to so that relative paths resolve to their
+// files.
+func applyBundle(c *cli.Context) error {
+	if c.Args().First() == "bundle" {
+		return nil
+	}
+
+	var b *bundle.Bundle
+	var err error
+	if path := c.String("bundle"); path != "" {
+		b, err = bundle.Open(path)
+	} else if b, err = bundle.FromExecutable(); errors.Is(err, bundle.ErrNoBundle) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"config", "resources", "templates"} {
+		if c.IsSet(name) {
+			return fmt.Errorf("the %v flag cannot be used with a bundle, fields of the bundled config can be overridden with --set", name)
+		}
+	}
+
+	dir := c.String("bundle-dir")
+	if dir == "" {
+		dir = b.DefaultDir()
+	}
+	if err := b.Extract(dir); err != nil {
+		return fmt.Errorf("failed to extract bundle: %w", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		return err
+	}
+
+	if err := c.Set("config", b.Manifest.Config); err != nil {
+		return err
+	}
+	for _, path := range b.Manifest.Resources {
+		if err := c.Set("resources", path); err != nil {
+			return err
+		}
+	}
+	for _, path := range b.Manifest.Templates {
+		if err := c.Set("templates", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // RunWithOpts runs the Benthos service after first applying opt funcs, which
 // are used for specify service customisations.
 func RunWithOpts(opts ...func()) {
@@ -152,6 +213,10 @@ func Run() {
 			Aliases: []string{"t"},
 			Usage:   "EXPERIMENTAL: import Benthos templates, supports glob patterns (requires quotes)",
 		},
+		&cli.StringSliceFlag{
+			Name:  "plugins-dir",
+			Usage: "EXPERIMENTAL: load Go plugins (.so files) from a directory, plugins must export a BenthosVersion string compatible with this binary",
+		},
 		&cli.BoolFlag{
 			Name:  "chilled",
 			Value: false,
@@ -163,6 +228,16 @@ func Run() {
 			Value:   false,
 			Usage:   "EXPERIMENTAL: watch config files for changes and automatically apply them",
 		},
+		&cli.StringFlag{
+			Name:  "bundle",
+			Value: "",
+			Usage: "run the config of a bundle artifact created with the bundle command",
+		},
+		&cli.StringFlag{
+			Name:  "bundle-dir",
+			Value: "",
+			Usage: "the directory to extract a bundle to, defaults to a directory within the system temporary directory",
+		},
 	}
 	if len(customFlags) > 0 {
 		flags = append(flags, customFlags...)
@@ -194,6 +269,24 @@ Either run Benthos as a stream processor or choose a command:
 				}
 			}
 
+			pluginLints, err := goplugin.InitPlugins(Version, c.StringSlice("plugins-dir")...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Plugin load error: %v\n", err)
+				os.Exit(1)
+			}
+			if !c.Bool("chilled") && len(pluginLints) > 0 {
+				for _, lint := range pluginLints {
+					fmt.Fprintln(os.Stderr, lint)
+				}
+				fmt.Println("Shutting down due to plugin linter errors, to prevent shutdown run Benthos with --chilled")
+				os.Exit(1)
+			}
+
+			if err := applyBundle(c); err != nil {
+				fmt.Fprintf(os.Stderr, "Bundle error: %v\n", err)
+				os.Exit(1)
+			}
+
 			templatesPaths, err := filepath.Globs(c.StringSlice("templates"))
 			if err != nil {
 				fmt.Printf("Failed to resolve template glob pattern: %v\n", err)
@@ -234,6 +327,7 @@ Either run Benthos as a stream processor or choose a command:
 				false,
 				false,
 				nil,
+				kubernetes.StreamsConfig{},
 			); code != 0 {
 				os.Exit(code)
 			}
@@ -294,6 +388,13 @@ In streams mode the stream fields of a root target config (input, buffer,
 pipeline, output) will be ignored. Other fields will be shared across all
 loaded streams (resources, metrics, etc).
 
+When running within Kubernetes stream configs can also be watched from either
+a ConfigMap or StreamConfig custom resources, and changes are applied one
+stream at a time:
+
+  benthos streams --kubernetes-configmap my-streams
+  benthos streams --kubernetes-crd
+
 For more information check out the docs at:
 https://benthos.dev/docs/guides/streams_mode/about`[1:],
 				Flags: []cli.Flag{
@@ -307,6 +408,24 @@ https://benthos.dev/docs/guides/streams_mode/about`[1:],
 						Value: true,
 						Usage: "Whether HTTP endpoints registered by stream configs should be prefixed with the stream ID",
 					},
+					&cli.StringFlag{
+						Name:  "kubernetes-configmap",
+						Usage: "Watch a Kubernetes ConfigMap of this name for stream configs, where each key is a stream ID",
+					},
+					&cli.BoolFlag{
+						Name:  "kubernetes-crd",
+						Value: false,
+						Usage: "Watch Kubernetes StreamConfig custom resources for stream configs, and report the status of each stream within its resource",
+					},
+					&cli.StringFlag{
+						Name:  "kubernetes-namespace",
+						Usage: "The Kubernetes namespace to watch for stream configs, defaults to the namespace of the pod",
+					},
+					&cli.DurationFlag{
+						Name:  "kubernetes-drain-timeout",
+						Value: time.Second * 30,
+						Usage: "The maximum period of time to wait for a stream to drain when it is updated or removed via Kubernetes",
+					},
 				},
 				Action: func(c *cli.Context) error {
 					os.Exit(cmdService(
@@ -320,12 +439,20 @@ https://benthos.dev/docs/guides/streams_mode/about`[1:],
 						c.Bool("prefix-stream-endpoints"),
 						true,
 						c.Args().Slice(),
+						kubernetes.StreamsConfig{
+							ConfigMap:       c.String("kubernetes-configmap"),
+							CustomResources: c.Bool("kubernetes-crd"),
+							Namespace:       c.String("kubernetes-namespace"),
+							DrainTimeout:    c.Duration("kubernetes-drain-timeout"),
+						},
 					))
 					return nil
 				},
 			},
 			listCliCommand(),
+			stateCliCommand(),
 			createCliCommand(),
+			bundle.CliCommand(Version),
 			test.CliCommand(testSuffix),
 			clitemplate.CliCommand(),
 			blobl.CliCommand(),
@@ -23,9 +23,11 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/config"
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/kubernetes"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/profiling"
 	"github.com/benthosdev/benthos/v4/internal/stream"
 	strmmgr "github.com/benthosdev/benthos/v4/internal/stream/manager"
 )
@@ -68,6 +70,7 @@ func readConfig(path string, streamsMode bool, resourcesPaths, streamsPaths, ove
 func initStreamsMode(
 	strict, watching, enableAPI bool,
 	confReader *config.Reader,
+	k8sConf kubernetes.StreamsConfig,
 	manager *manager.Type,
 	logger log.Modular,
 	stats *metrics.Namespaced,
@@ -125,9 +128,54 @@ func initStreamsMode(
 			os.Exit(1)
 		}
 	}
+
+	if k8sConf.Enabled() {
+		watcher, err := initKubernetesStreams(k8sConf, streamMgr, logger)
+		if err != nil {
+			logger.Errorf("Failed to create Kubernetes stream config watcher: %v", err)
+			os.Exit(1)
+		}
+		return &kubernetesStreamsStopper{watcher: watcher, streams: streamMgr}
+	}
 	return streamMgr
 }
 
+func initKubernetesStreams(conf kubernetes.StreamsConfig, streamMgr *strmmgr.Type, logger log.Modular) (*kubernetes.StreamsWatcher, error) {
+	client, err := kubernetes.NewInClusterClient()
+	if err != nil {
+		return nil, err
+	}
+	if conf.Namespace == "" {
+		conf.Namespace = kubernetes.InClusterNamespace()
+	}
+	watcher, err := kubernetes.NewStreamsWatcher(conf, client, streamMgr, logger)
+	if err != nil {
+		return nil, err
+	}
+	watcher.Start()
+
+	if conf.CustomResources {
+		logger.Infof("Watching StreamConfig resources of namespace %v for stream configs", conf.Namespace)
+	} else {
+		logger.Infof("Watching ConfigMap %v of namespace %v for stream configs", conf.ConfigMap, conf.Namespace)
+	}
+	return watcher, nil
+}
+
+// kubernetesStreamsStopper stops watching for stream configs before stopping
+// the streams, such that no streams are created during shutdown.
+type kubernetesStreamsStopper struct {
+	watcher *kubernetes.StreamsWatcher
+	streams *strmmgr.Type
+}
+
+func (k *kubernetesStreamsStopper) Stop(ctx context.Context) error {
+	if err := k.watcher.Close(ctx); err != nil {
+		return err
+	}
+	return k.streams.Stop(ctx)
+}
+
 type swappableStopper struct {
 	stopped bool
 	current stoppable
@@ -189,6 +237,7 @@ func initNormalMode(
 					close(stoppedChan)
 				}
 			}),
+			stream.OptSetShutdownPhases(conf.ShutdownPhases),
 		)
 	}
 
@@ -236,6 +285,7 @@ func cmdService(
 	strict, watching, enableStreamsAPI, namespaceStreamEndpoints bool,
 	streamsMode bool,
 	streamsPaths []string,
+	k8sConf kubernetes.StreamsConfig,
 ) int {
 	mainPath, inferredMainPath, confReader := readConfig(confPath, streamsMode, resourcesPaths, streamsPaths, confOverrides)
 	conf := config.New()
@@ -337,6 +387,23 @@ func cmdService(
 		}
 	}()
 
+	// Create our continuous profiler.
+	if conf.Profiler.Enabled {
+		profiler, err := profiling.NewPusher(conf.Profiler, logger)
+		if err != nil {
+			logger.Errorf("Failed to initialise profiler: %v\n", err)
+			return 1
+		}
+		profiler.Start()
+		defer func() {
+			ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+			defer done()
+			if pCloseErr := profiler.Close(ctx); pCloseErr != nil {
+				logger.Errorf("Failed to cleanly close profiler: %v\n", pCloseErr)
+			}
+		}()
+	}
+
 	// Create HTTP API with a sanitised service config.
 	var sanitNode yaml.Node
 	err = sanitNode.Encode(conf)
@@ -368,13 +435,14 @@ func cmdService(
 		logger.Errorf("Failed to create resource: %v\n", err)
 		return 1
 	}
+	manager.RegisterStateEndpoints()
 
 	var stoppableStream stoppable
 	var dataStreamClosedChan chan struct{}
 
 	// Create data streams.
 	if streamsMode {
-		stoppableStream = initStreamsMode(strict, watching, enableStreamsAPI, confReader, manager, logger, stats)
+		stoppableStream = initStreamsMode(strict, watching, enableStreamsAPI, confReader, k8sConf, manager, logger, stats)
 	} else {
 		stoppableStream, dataStreamClosedChan = initNormalMode(conf, strict, watching, confReader, manager, logger, stats)
 	}
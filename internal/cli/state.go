@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func stateCliCommand() *cli.Command {
+	addressFlag := &cli.StringFlag{
+		Name:    "address",
+		Aliases: []string{"a"},
+		Value:   "http://localhost:4195",
+		Usage:   "The address of the HTTP server of the Benthos instance.",
+	}
+
+	return &cli.Command{
+		Name:  "state",
+		Usage: "Export or import the runtime state of a running Benthos instance",
+		Description: `
+Obtains a snapshot of the runtime state that a running instance holds in
+memory, such as the items of memory caches that back deduplication and stored
+cursors, and imports it into another instance. This allows the state to be
+migrated during a planned replacement of an instance:
+
+  benthos state export --address http://old-node:4195 > ./state.json
+  benthos state import --address http://new-node:4195 ./state.json
+
+The state is obtained from the /state endpoint of the HTTP server of the
+instance, and therefore the server must be enabled.
+
+The contents of window buffers are not part of the snapshot. Messages of
+windows that have not been flushed are rejected when an instance shuts down,
+and are therefore consumed again by the instance that replaces it.`[1:],
+		Subcommands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "Export a snapshot of the runtime state of an instance",
+				Description: `
+Writes the snapshot as JSON to stdout, or to a file when one is specified.
+
+  benthos state export > ./state.json
+  benthos state export --address http://localhost:4195 ./state.json`[1:],
+				Flags: []cli.Flag{addressFlag},
+				Action: func(c *cli.Context) error {
+					if err := stateExport(c.String("address"), c.Args().First()); err != nil {
+						fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+						os.Exit(1)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "import",
+				Usage: "Import a snapshot of runtime state into an instance",
+				Description: `
+Reads the snapshot from a file, or from stdin when no file or - is specified.
+Items of caches are written into the caches of the same labels, which must
+exist within the instance.
+
+  benthos state import ./state.json
+  cat ./state.json | benthos state import --address http://localhost:4195`[1:],
+				Flags: []cli.Flag{addressFlag},
+				Action: func(c *cli.Context) error {
+					if err := stateImport(c.String("address"), c.Args().First()); err != nil {
+						fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+						os.Exit(1)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func stateURL(address string) (string, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse address: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/state"
+	return u.String(), nil
+}
+
+func checkStateResponse(res *http.Response) ([]byte, error) {
+	resBytes, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("request failed (%v): %v", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	}
+	return resBytes, nil
+}
+
+func stateExport(address, path string) error {
+	u, err := stateURL(address)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Get(u)
+	if err != nil {
+		return err
+	}
+	state, err := checkStateResponse(res)
+	if err != nil {
+		return err
+	}
+
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(state)
+		return err
+	}
+	return os.WriteFile(path, state, 0o644)
+}
+
+func stateImport(address, path string) error {
+	u, err := stateURL(address)
+	if err != nil {
+		return err
+	}
+
+	var state []byte
+	if path == "" || path == "-" {
+		state, err = io.ReadAll(os.Stdin)
+	} else {
+		state, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	res, err := http.Post(u, "application/json", bytes.NewReader(state))
+	if err != nil {
+		return err
+	}
+	_, err = checkStateResponse(res)
+	return err
+}
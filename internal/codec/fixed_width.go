@@ -0,0 +1,250 @@
+package codec
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type fixedWidthField struct {
+	name   string
+	offset int
+	length int
+	typ    string
+}
+
+type fixedWidthConfig struct {
+	fields       []fixedWidthField
+	recordLength int
+	ebcdic       bool
+	lines        bool
+}
+
+// parseFixedWidthArgs parses the arguments of a fixed-width codec, which are a
+// comma separated list of fields of the form `name=offset:length[:type]`
+// followed by optional parameters separated with `;`.
+func parseFixedWidthArgs(args string) (conf fixedWidthConfig, err error) {
+	params := strings.Split(args, ";")
+	if params[0] == "" {
+		return conf, errors.New("fixed-width codec requires one or more fields")
+	}
+
+	for _, fieldStr := range strings.Split(params[0], ",") {
+		name, layout, _ := strings.Cut(fieldStr, "=")
+		layoutParts := strings.Split(layout, ":")
+		if name == "" || len(layoutParts) < 2 || len(layoutParts) > 3 {
+			return conf, fmt.Errorf("fixed-width codec field expected the form name=offset:length[:type]: %v", fieldStr)
+		}
+
+		f := fixedWidthField{name: name, typ: "string"}
+		if f.offset, err = strconv.Atoi(layoutParts[0]); err != nil || f.offset < 0 {
+			return conf, fmt.Errorf("fixed-width codec field %v has an invalid offset: %v", name, layoutParts[0])
+		}
+		if f.length, err = strconv.Atoi(layoutParts[1]); err != nil || f.length <= 0 {
+			return conf, fmt.Errorf("fixed-width codec field %v has an invalid length: %v", name, layoutParts[1])
+		}
+		if len(layoutParts) == 3 {
+			f.typ = layoutParts[2]
+		}
+		switch f.typ {
+		case "string", "int", "float", "bytes":
+		default:
+			return conf, fmt.Errorf("fixed-width codec field %v has an unrecognised type: %v", name, f.typ)
+		}
+
+		if end := f.offset + f.length; end > conf.recordLength {
+			conf.recordLength = end
+		}
+		conf.fields = append(conf.fields, f)
+	}
+
+	minLength := conf.recordLength
+	for _, param := range params[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		switch key {
+		case "ebcdic":
+			conf.ebcdic = true
+		case "lines":
+			conf.lines = true
+		case "record_length":
+			if conf.recordLength, err = strconv.Atoi(value); err != nil {
+				return conf, fmt.Errorf("fixed-width codec has an invalid record_length: %w", err)
+			}
+			if conf.recordLength < minLength {
+				return conf, fmt.Errorf("fixed-width codec record_length %v is shorter than its fields", conf.recordLength)
+			}
+		default:
+			return conf, fmt.Errorf("fixed-width codec parameter not recognised: %v", key)
+		}
+	}
+	return conf, nil
+}
+
+//------------------------------------------------------------------------------
+
+type fixedWidthReader struct {
+	conf      fixedWidthConfig
+	r         io.ReadCloser
+	buf       *bufio.Reader
+	scanner   *bufio.Scanner
+	record    []byte
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newFixedWidthReader(rConf ReaderConfig, conf fixedWidthConfig, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	f := &fixedWidthReader{
+		conf:      conf,
+		r:         r,
+		sourceAck: ackOnce(ackFn),
+	}
+	if conf.lines {
+		f.scanner = bufio.NewScanner(r)
+		if rConf.MaxScanTokenSize != bufio.MaxScanTokenSize {
+			f.scanner.Buffer([]byte{}, rConf.MaxScanTokenSize)
+		}
+	} else {
+		f.buf = bufio.NewReader(r)
+		f.record = make([]byte, conf.recordLength)
+	}
+	return f, nil
+}
+
+func (f *fixedWidthReader) ack(ctx context.Context, err error) error {
+	f.mut.Lock()
+	f.pending--
+	doAck := f.pending == 0 && f.finished
+	f.mut.Unlock()
+
+	if err != nil {
+		return f.sourceAck(ctx, err)
+	}
+	if doAck {
+		return f.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (f *fixedWidthReader) readRecord() ([]byte, error) {
+	if f.scanner != nil {
+		if f.scanner.Scan() {
+			return f.scanner.Bytes(), nil
+		}
+		if err := f.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	if _, err := io.ReadFull(f.buf, f.record); err != nil {
+		return nil, err
+	}
+	return f.record, nil
+}
+
+// decodeRecord extracts the fields of a record into a structured object. When a
+// field cannot be parsed as its type the raw string is kept and an error is
+// returned.
+func (f *fixedWidthReader) decodeRecord(record []byte) (map[string]any, error) {
+	var fieldErr error
+	obj := make(map[string]any, len(f.conf.fields))
+	for _, field := range f.conf.fields {
+		var raw []byte
+		if field.offset < len(record) {
+			end := field.offset + field.length
+			if end > len(record) {
+				end = len(record)
+			}
+			raw = record[field.offset:end]
+		}
+
+		if field.typ == "bytes" {
+			obj[field.name] = append([]byte(nil), raw...)
+			continue
+		}
+		if f.conf.ebcdic {
+			var err error
+			if raw, err = charmap.CodePage037.NewDecoder().Bytes(raw); err != nil {
+				return nil, err
+			}
+		}
+
+		str := strings.TrimRight(string(raw), " ")
+		switch field.typ {
+		case "string":
+			obj[field.name] = str
+		case "int", "float":
+			if str = strings.TrimSpace(str); str == "" {
+				obj[field.name] = nil
+				continue
+			}
+			var v any
+			var err error
+			if field.typ == "int" {
+				v, err = strconv.ParseInt(str, 10, 64)
+			} else {
+				v, err = strconv.ParseFloat(str, 64)
+			}
+			if err != nil {
+				fieldErr = fmt.Errorf("failed to parse field %v as %v: %w", field.name, field.typ, err)
+				v = str
+			}
+			obj[field.name] = v
+		}
+	}
+	return obj, fieldErr
+}
+
+func (f *fixedWidthReader) Next(ctx context.Context) ([]*message.Part, ReaderAckFn, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	record, err := f.readRecord()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			f.finished = true
+		} else {
+			_ = f.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	obj, err := f.decodeRecord(record)
+	if obj == nil {
+		_ = f.sourceAck(ctx, err)
+		return nil, nil, err
+	}
+
+	f.pending++
+
+	part := message.NewPart(nil)
+	part.SetStructuredMut(obj)
+	if err != nil {
+		part.ErrorSet(err)
+	}
+	return []*message.Part{part}, f.ack, nil
+}
+
+func (f *fixedWidthReader) Close(ctx context.Context) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if !f.finished {
+		_ = f.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if f.pending == 0 {
+		_ = f.sourceAck(ctx, nil)
+	}
+	return f.r.Close()
+}
@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func readAllStructured(t *testing.T, codec string, data []byte) (results []any, errs []error) {
+	t.Helper()
+
+	ctor, err := GetReader(codec, NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor("", io.NopCloser(bytes.NewReader(data)), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	for {
+		parts, ackFn, err := r.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, p := range parts {
+			v, err := p.AsStructured()
+			require.NoError(t, err)
+			results = append(results, v)
+			errs = append(errs, p.ErrorGet())
+		}
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+	return
+}
+
+func TestFixedWidthReader(t *testing.T) {
+	data := []byte("000001foo       12.50" + "000002bar baz      -3" + "  nopenope        wat")
+
+	results, errs := readAllStructured(t, "fixed-width:id=0:6:int,name=6:10,amount=16:5:float", data)
+	assert.Equal(t, []any{
+		map[string]any{"id": int64(1), "name": "foo", "amount": 12.5},
+		map[string]any{"id": int64(2), "name": "bar baz", "amount": float64(-3)},
+		map[string]any{"id": "nope", "name": "nope", "amount": "wat"},
+	}, results)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.Error(t, errs[2])
+}
+
+func TestFixedWidthReaderLines(t *testing.T) {
+	data := []byte("0001foo\n0002\n0003barbazbuz\n")
+
+	results, _ := readAllStructured(t, "fixed-width:id=0:4:int,name=4:6;lines", data)
+	assert.Equal(t, []any{
+		map[string]any{"id": int64(1), "name": "foo"},
+		map[string]any{"id": int64(2), "name": ""},
+		map[string]any{"id": int64(3), "name": "barbaz"},
+	}, results)
+}
+
+func TestFixedWidthReaderEBCDIC(t *testing.T) {
+	record, err := charmap.CodePage037.NewEncoder().Bytes([]byte("42HELLO   "))
+	require.NoError(t, err)
+	record = append(record, 0x01, 0x02)
+
+	data := append(append([]byte{}, record...), record...)
+
+	results, _ := readAllStructured(t, "fixed-width:num=0:2:int,greeting=2:8,raw=10:2:bytes;ebcdic;record_length=12", data)
+	assert.Equal(t, []any{
+		map[string]any{"num": int64(42), "greeting": "HELLO", "raw": []byte{0x01, 0x02}},
+		map[string]any{"num": int64(42), "greeting": "HELLO", "raw": []byte{0x01, 0x02}},
+	}, results)
+}
+
+func TestFixedWidthReaderSuite(t *testing.T) {
+	testReaderSuite(t, "fixed-width:v=0:3:bytes", "", []byte("foobarbaz"), `{"v":"Zm9v"}`, `{"v":"YmFy"}`, `{"v":"YmF6"}`)
+}
+
+func TestFixedWidthBadConfig(t *testing.T) {
+	for _, c := range []string{
+		"fixed-width:",
+		"fixed-width:foo",
+		"fixed-width:foo=1",
+		"fixed-width:foo=a:1",
+		"fixed-width:foo=0:0",
+		"fixed-width:foo=0:1:nope",
+		"fixed-width:foo=0:10;record_length=5",
+		"fixed-width:foo=0:10;nope",
+	} {
+		_, err := GetReader(c, NewReaderConfig())
+		assert.Error(t, err, c)
+	}
+}
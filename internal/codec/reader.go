@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -15,8 +16,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	goavro "github.com/linkedin/goavro/v2"
+	"github.com/pierrec/lz4/v4"
 
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -27,16 +32,24 @@ import (
 var ReaderDocs = docs.FieldString(
 	"codec", "The way in which the bytes of a data source should be converted into discrete messages, codecs are useful for specifying how large files or continuous streams of data might be processed in small chunks rather than loading it all in memory. It's possible to consume lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter. Codecs can be chained with `/`, for example a gzip compressed CSV file can be consumed with the codec `gzip/csv`.", "lines", "delim:\t", "delim:foobar", "gzip/csv",
 ).HasAnnotatedOptions(
-	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the `gzip/tar` codec. Defaults to all-bytes.",
+	"auto", "EXPERIMENTAL: Attempts to derive a codec for each file based on information such as the extension. For example, a .tar.gz file would be consumed with the `gzip/tar` codec, and a .log.zst file with the `zstd/lines` codec. Defaults to all-bytes.",
 	"all-bytes", "Consume the entire file as a single binary message.",
-	"avro-ocf:marshaler=x", "EXPERIMENTAL: Consume a stream of Avro OCF datum. The `marshaler` parameter is optional and has the options: `goavro` (default), `json`. Use `goavro` if OCF contains logical types.",
+	"avro-ocf:marshaler=x", "EXPERIMENTAL: Consume a stream of Avro OCF datum. The `marshaler` parameter is optional and has the options: `goavro` (default), `json`. Use `goavro` if OCF contains logical types. The writer schema of the file is added to each message as the metadata field `avro_schema`.",
 	"chunker:x", "Consume the file in chunks of a given number of bytes.",
 	"csv", "Consume structured rows as comma separated values, the first row must be a header row.",
 	"csv:x", "Consume structured rows as values separated by a custom delimiter, the first row must be a header row. The custom delimiter must be a single character, e.g. the codec `\"csv:\\t\"` would consume a tab delimited file.",
 	"delim:x", "Consume the file in segments divided by a custom delimiter.",
+	"fixed-width:x", "Consume structured records of fixed width fields, where `x` is a comma separated list of fields of the form `name=offset:length:type`. The type is optional and can be one of `string` (default), `int`, `float` or `bytes`. Records are read consecutively with a length equal to the end of the last field, and parameters can be added after the fields separated by `;`, where `record_length=n` sets the length of records explicitly, `lines` consumes newline delimited records instead, and `ebcdic` decodes text fields from the EBCDIC code page 037. For example, the codec `fixed-width:id=0:6:int,name=6:20,amount=26:10:float;ebcdic;record_length=40` would consume 40 byte EBCDIC records. Messages with fields that fail to parse as their type are flagged with an error.",
 	"gzip", "Decompress a gzip file, this codec should precede another codec, e.g. `gzip/all-bytes`, `gzip/tar`, `gzip/csv`, etc.",
+	"lz4", "Decompress an lz4 frame stream, this codec should precede another codec, e.g. `lz4/lines`.",
 	"lines", "Consume the file in segments divided by linebreaks.",
+	"protobuf-delim", "Consume a stream of messages that are each prefixed with their length as a varint, as written by the `writeDelimitedTo` method of protobuf libraries. Messages can be decoded with the [`protobuf` processor](/docs/components/processors/protobuf).",
+	"sse", "Consume a stream of [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html), where the data lines of each event are joined into a message. The event type and id of each event are added as the metadata fields `sse_event` and `sse_id` when present.",
+	"snappy", "Decompress a stream in the snappy framing format, this codec should precede another codec, e.g. `snappy/lines`.",
+	"zstd", "Decompress a zstd file, this codec should precede another codec, e.g. `zstd/lines`, `zstd/tar`, `zstd/csv`, etc.",
 	"multipart", "Consumes the output of another codec and batches messages together. A batch ends when an empty message is consumed. For example, the codec `lines/multipart` could be used to consume multipart messages where an empty line indicates the end of each batch.",
+	"multiline:x", "Consume lines of the file in groups, where each group begins with a line matching the regular expression `x` and includes all following lines until the next match, e.g. `multiline:^\\d{4}-\\d\\d-\\d\\d` would consume log lines that begin with a date along with any stack traces that follow them. The size of a group is limited by the max buffer of the input, and for continuous streams a duration can be specified with `multiline:flush_timeout=x:y`, after which a group is flushed if no further lines are received.",
+	"parquet", "EXPERIMENTAL: Consume the rows of a Parquet file as structured messages. Files are read with random access and therefore sources that do not support seeking, such as the objects of an `aws_s3` input, are first buffered to a temporary file on disk. The columns extracted can be limited with the parameter `columns`, and row groups can be skipped with one or more `filter` parameters, which are compared against column statistics, e.g. `parquet:columns=id,name;filter=age>=18;filter=country=uk`. Filters are applied to whole row groups only and therefore some rows that do not match may still be consumed.",
 	"regex:(?m)^\\d\\d:\\d\\d:\\d\\d", "Consume the file in segments divided by regular expression.",
 	"tar", "Parse the file as a tar archive, and consume each file of the archive as a message.",
 ).LinterFunc(nil) // Disable default option linter as it doesn't include foo:bar formats.
@@ -184,7 +197,8 @@ func chainedReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 }
 
 func ioReader(codec string, conf ReaderConfig) (ioReaderConstructor, bool) {
-	if codec == "gzip" {
+	switch codec {
+	case "gzip":
 		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
 			g, err := gzip.NewReader(r)
 			if err != nil {
@@ -193,10 +207,44 @@ func ioReader(codec string, conf ReaderConfig) (ioReaderConstructor, bool) {
 			}
 			return g, nil
 		}, true
+	case "zstd":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			// Buffering the source protects the decoder from readers that
+			// return io.EOF alongside the final bytes of data.
+			z, err := zstd.NewReader(bufio.NewReader(r))
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			return &decompressedReader{Reader: z, closeFn: z.Close, source: r}, nil
+		}, true
+	case "lz4":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			return &decompressedReader{Reader: lz4.NewReader(r), source: r}, nil
+		}, true
+	case "snappy":
+		return func(_ string, r io.ReadCloser) (io.ReadCloser, error) {
+			return &decompressedReader{Reader: snappy.NewReader(r), source: r}, nil
+		}, true
 	}
 	return nil, false
 }
 
+// decompressedReader reads from a decompressing reader, and closes both the
+// decompressor and the source it consumes from.
+type decompressedReader struct {
+	io.Reader
+	closeFn func()
+	source  io.ReadCloser
+}
+
+func (d *decompressedReader) Close() error {
+	if d.closeFn != nil {
+		d.closeFn()
+	}
+	return d.source.Close()
+}
+
 func readerReader(codec string, conf ReaderConfig) (readerReaderConstructor, bool) {
 	if codec == "multipart" {
 		return func(_ string, r Reader) (Reader, error) {
@@ -226,6 +274,14 @@ func partReader(codec string, conf ReaderConfig) (ReaderConstructor, bool, error
 		}, true, nil
 	case "tar":
 		return newTarReader, true, nil
+	case "protobuf-delim":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newVarintDelimReader(conf, r, fn)
+		}, true, nil
+	case "sse":
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newSSEReader(conf, r, fn)
+		}, true, nil
 	}
 
 	if strings.HasPrefix(codec, "avro-ocf:") {
@@ -276,6 +332,40 @@ func partReader(codec string, conf ReaderConfig) (ReaderConstructor, bool, error
 			return newChunkerReader(conf, r, chunkSize, fn)
 		}, true, nil
 	}
+	if strings.HasPrefix(codec, "fixed-width:") {
+		fConf, err := parseFixedWidthArgs(strings.TrimPrefix(codec, "fixed-width:"))
+		if err != nil {
+			return nil, false, err
+		}
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newFixedWidthReader(conf, fConf, r, fn)
+		}, true, nil
+	}
+	if strings.HasPrefix(codec, "multiline:") {
+		expr := strings.TrimPrefix(codec, "multiline:")
+
+		var flushTimeout time.Duration
+		if strings.HasPrefix(expr, "flush_timeout=") {
+			var timeoutStr string
+			timeoutStr, expr, _ = strings.Cut(strings.TrimPrefix(expr, "flush_timeout="), ":")
+
+			var err error
+			if flushTimeout, err = time.ParseDuration(timeoutStr); err != nil {
+				return nil, false, fmt.Errorf("invalid flush_timeout for multiline codec: %w", err)
+			}
+		}
+		if expr == "" {
+			return nil, false, errors.New("multiline codec requires a non-empty expression")
+		}
+
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, false, err
+		}
+		return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
+			return newMultilineReader(conf, r, compiled, flushTimeout, fn), nil
+		}, true, nil
+	}
 	if strings.HasPrefix(codec, "regex:") {
 		by := strings.TrimPrefix(codec, "regex:")
 		if by == "" {
@@ -285,9 +375,36 @@ func partReader(codec string, conf ReaderConfig) (ReaderConstructor, bool, error
 			return newRexExpSplitReader(conf, r, by, fn)
 		}, true, nil
 	}
+
+	name, args := codec, ""
+	if i := strings.Index(codec, ":"); i >= 0 {
+		name, args = codec[:i], codec[i+1:]
+	}
+	if creator, exists := registeredPartReaders[name]; exists {
+		ctor, err := creator(conf, args)
+		if err != nil {
+			return nil, false, err
+		}
+		return ctor, true, nil
+	}
 	return nil, false, nil
 }
 
+// PartReaderCreator creates a reader codec from a reader config and the
+// arguments that follow the name of the codec, e.g. the codec `foo:bar=baz`
+// is given the arguments `bar=baz`.
+type PartReaderCreator func(conf ReaderConfig, args string) (ReaderConstructor, error)
+
+var registeredPartReaders = map[string]PartReaderCreator{}
+
+// RegisterPartReader adds a reader codec that is implemented outside of this
+// package, which allows codecs with heavy dependencies to live alongside the
+// rest of their implementation. This function is not thread safe and should be
+// called during init.
+func RegisterPartReader(name string, creator PartReaderCreator) {
+	registeredPartReaders[name] = creator
+}
+
 func convertDeprecatedCodec(codec string) string {
 	switch codec {
 	case "csv-gzip":
@@ -307,6 +424,15 @@ func GetReader(codec string, conf ReaderConfig) (ReaderConstructor, error) {
 	return chainedReader(codec, conf)
 }
 
+// autoDecompressors maps file extensions to the decompression codecs that the
+// auto codec applies to them.
+var autoDecompressors = map[string]string{
+	".zst":  "zstd",
+	".zstd": "zstd",
+	".lz4":  "lz4",
+	".sz":   "snappy",
+}
+
 func autoCodec(conf ReaderConfig) ReaderConstructor {
 	return func(path string, r io.ReadCloser, fn ReaderAckFn) (Reader, error) {
 		codec := "all-bytes"
@@ -315,6 +441,8 @@ func autoCodec(conf ReaderConfig) ReaderConstructor {
 			codec = "avro-ocf"
 		case ".csv":
 			codec = "csv"
+		case ".parquet":
+			codec = "parquet"
 		case ".csv.gz", ".csv.gzip":
 			codec = "gzip/csv"
 		case ".tar":
@@ -327,6 +455,17 @@ func autoCodec(conf ReaderConfig) ReaderConstructor {
 		} else if strings.HasSuffix(path, ".tar.gz") {
 			codec = "gzip/tar"
 		}
+		if decompressor, exists := autoDecompressors[filepath.Ext(path)]; exists {
+			codec = decompressor + "/all-bytes"
+			switch filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))) {
+			case ".csv":
+				codec = decompressor + "/csv"
+			case ".tar":
+				codec = decompressor + "/tar"
+			case ".log", ".txt", ".jsonl", ".ndjson":
+				codec = decompressor + "/lines"
+			}
+		}
 
 		ctor, err := GetReader(codec, conf)
 		if err != nil {
@@ -403,6 +542,7 @@ func newAvroOCFReader(conf ReaderConfig, marshaler string, r io.ReadCloser, ackF
 				return nil, err
 			}
 			part := message.NewPart(mp)
+			part.MetaSet("avro_schema", a.avroCodec.Schema())
 			return part, nil
 		}
 		jb, err := a.avroCodec.TextualFromNative(nil, datum)
@@ -415,6 +555,7 @@ func newAvroOCFReader(conf ReaderConfig, marshaler string, r io.ReadCloser, ackF
 			return nil, err
 		}
 		part := message.NewPart(mp)
+		part.MetaSet("avro_schema", a.avroCodec.Schema())
 		return part, nil
 	}
 
@@ -1070,3 +1211,285 @@ func (a *regexReader) Close(ctx context.Context) error {
 	}
 	return a.r.Close()
 }
+
+//------------------------------------------------------------------------------
+
+type multilineScan struct {
+	line []byte
+	err  error
+}
+
+// multilineReader consumes lines and groups them into messages, where each
+// message begins with a line that matches a regular expression and subsequent
+// lines that do not match are appended to it.
+type multilineReader struct {
+	r            io.ReadCloser
+	start        *regexp.Regexp
+	maxSize      int
+	flushTimeout time.Duration
+	sourceAck    ReaderAckFn
+
+	scanner  *bufio.Scanner
+	scans    chan multilineScan
+	shutSig  chan struct{}
+	buffered []byte
+	hasBuf   bool
+	scanErr  error
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newMultilineReader(conf ReaderConfig, r io.ReadCloser, start *regexp.Regexp, flushTimeout time.Duration, ackFn ReaderAckFn) Reader {
+	m := &multilineReader{
+		r:            r,
+		start:        start,
+		maxSize:      conf.MaxScanTokenSize,
+		flushTimeout: flushTimeout,
+		sourceAck:    ackOnce(ackFn),
+		shutSig:      make(chan struct{}),
+	}
+
+	m.scanner = bufio.NewScanner(r)
+	if conf.MaxScanTokenSize != bufio.MaxScanTokenSize {
+		m.scanner.Buffer([]byte{}, conf.MaxScanTokenSize)
+	}
+	if flushTimeout <= 0 {
+		return m
+	}
+
+	// Lines are scanned in the background so that a buffered message can be
+	// flushed when the source stalls for longer than the flush timeout.
+	m.scans = make(chan multilineScan)
+	go func() {
+		defer close(m.scans)
+		for {
+			scan, more := m.scan()
+			if !more {
+				return
+			}
+			select {
+			case m.scans <- scan:
+			case <-m.shutSig:
+				return
+			}
+			if scan.err != nil {
+				return
+			}
+		}
+	}()
+	return m
+}
+
+// scan reads the next line from the source, returning false once the source is
+// exhausted.
+func (m *multilineReader) scan() (multilineScan, bool) {
+	if m.scanner.Scan() {
+		lineCopy := make([]byte, len(m.scanner.Bytes()))
+		copy(lineCopy, m.scanner.Bytes())
+		return multilineScan{line: lineCopy}, true
+	}
+	if err := m.scanner.Err(); err != nil {
+		return multilineScan{err: err}, true
+	}
+	return multilineScan{}, false
+}
+
+func (m *multilineReader) ack(ctx context.Context, err error) error {
+	m.mut.Lock()
+	m.pending--
+	doAck := m.pending == 0 && m.finished
+	m.mut.Unlock()
+
+	if err != nil {
+		return m.sourceAck(ctx, err)
+	}
+	if doAck {
+		return m.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (m *multilineReader) flush() ([]*message.Part, ReaderAckFn, error) {
+	part := message.NewPart(m.buffered)
+	m.buffered, m.hasBuf = nil, false
+	m.pending++
+	return []*message.Part{part}, m.ack, nil
+}
+
+func (m *multilineReader) Next(ctx context.Context) ([]*message.Part, ReaderAckFn, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	for {
+		if m.scanErr != nil {
+			if m.hasBuf {
+				return m.flush()
+			}
+			err := m.scanErr
+			if err == io.EOF {
+				m.finished = true
+			} else {
+				_ = m.sourceAck(ctx, err)
+			}
+			return nil, nil, err
+		}
+
+		scan, open, flushed, err := m.nextScan(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if flushed {
+			return m.flush()
+		}
+		if !open {
+			m.scanErr = io.EOF
+			continue
+		}
+		if scan.err != nil {
+			m.scanErr = scan.err
+			continue
+		}
+		if !m.hasBuf {
+			m.buffered, m.hasBuf = scan.line, true
+			continue
+		}
+
+		// A buffered message is flushed when the next line begins a new
+		// message, or would take the message beyond the max buffer size.
+		if m.start.Match(scan.line) || len(m.buffered)+len(scan.line)+1 > m.maxSize {
+			parts, ackFn, err := m.flush()
+			m.buffered, m.hasBuf = scan.line, true
+			return parts, ackFn, err
+		}
+		m.buffered = append(m.buffered, '\n')
+		m.buffered = append(m.buffered, scan.line...)
+	}
+}
+
+// nextScan waits for the next scanned line, or for the flush timeout to elapse
+// when a message is buffered.
+func (m *multilineReader) nextScan(ctx context.Context) (scan multilineScan, open, flushed bool, err error) {
+	if m.scans == nil {
+		scan, open = m.scan()
+		return
+	}
+
+	var flushChan <-chan time.Time
+	if m.hasBuf && m.flushTimeout > 0 {
+		timer := time.NewTimer(m.flushTimeout)
+		defer timer.Stop()
+		flushChan = timer.C
+	}
+
+	select {
+	case scan, open = <-m.scans:
+	case <-flushChan:
+		flushed = true
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+func (m *multilineReader) Close(ctx context.Context) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	select {
+	case <-m.shutSig:
+	default:
+		close(m.shutSig)
+	}
+	if !m.finished {
+		_ = m.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if m.pending == 0 {
+		_ = m.sourceAck(ctx, nil)
+	}
+	return m.r.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type varintDelimReader struct {
+	r         *bufio.Reader
+	source    io.ReadCloser
+	maxSize   uint64
+	sourceAck ReaderAckFn
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newVarintDelimReader(conf ReaderConfig, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	return &varintDelimReader{
+		r:         bufio.NewReader(r),
+		source:    r,
+		maxSize:   uint64(conf.MaxScanTokenSize),
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (a *varintDelimReader) ack(ctx context.Context, err error) error {
+	a.mut.Lock()
+	a.pending--
+	doAck := a.pending == 0 && a.finished
+	a.mut.Unlock()
+
+	if err != nil {
+		return a.sourceAck(ctx, err)
+	}
+	if doAck {
+		return a.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (a *varintDelimReader) Next(ctx context.Context) ([]*message.Part, ReaderAckFn, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.finished {
+		return nil, nil, io.EOF
+	}
+
+	size, err := binary.ReadUvarint(a.r)
+	if err == nil && size > a.maxSize {
+		err = fmt.Errorf("message size %v exceeds the maximum of %v", size, a.maxSize)
+	}
+
+	var msgBytes []byte
+	if err == nil {
+		msgBytes = make([]byte, size)
+		if _, err = io.ReadFull(a.r, msgBytes); err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			a.finished = true
+		} else {
+			_ = a.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	a.pending++
+	return []*message.Part{message.NewPart(msgBytes)}, a.ack, nil
+}
+
+func (a *varintDelimReader) Close(ctx context.Context) error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if !a.finished {
+		_ = a.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if a.pending == 0 {
+		_ = a.sourceAck(ctx, nil)
+	}
+	return a.source.Close()
+}
@@ -5,12 +5,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	goavro "github.com/linkedin/goavro/v2"
+	"github.com/pierrec/lz4/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -770,3 +777,202 @@ func TestRegexpSplitReader(t *testing.T) {
 	data = []byte("")
 	testReaderSuite(t, "regex:split", "", data)
 }
+
+func TestCompressedLinesReaders(t *testing.T) {
+	input := "foo\nbar\nbaz"
+
+	var zstdBuf bytes.Buffer
+	zstdW, err := zstd.NewWriter(&zstdBuf)
+	require.NoError(t, err)
+	_, _ = zstdW.Write([]byte(input))
+	require.NoError(t, zstdW.Close())
+
+	var lz4Buf bytes.Buffer
+	lz4W := lz4.NewWriter(&lz4Buf)
+	_, _ = lz4W.Write([]byte(input))
+	require.NoError(t, lz4W.Close())
+
+	var snappyBuf bytes.Buffer
+	snappyW := snappy.NewBufferedWriter(&snappyBuf)
+	_, _ = snappyW.Write([]byte(input))
+	require.NoError(t, snappyW.Close())
+
+	testReaderSuite(t, "zstd/lines", "", zstdBuf.Bytes(), "foo", "bar", "baz")
+	testReaderSuite(t, "lz4/lines", "", lz4Buf.Bytes(), "foo", "bar", "baz")
+	testReaderSuite(t, "snappy/lines", "", snappyBuf.Bytes(), "foo", "bar", "baz")
+	testReaderSuite(t, "auto", "foo.log.zst", zstdBuf.Bytes(), "foo", "bar", "baz")
+	testReaderSuite(t, "auto", "foo.zst", zstdBuf.Bytes(), input)
+}
+
+func TestProtobufDelimReader(t *testing.T) {
+	var data []byte
+	for _, v := range []string{"foo", "", strings.Repeat("x", 300)} {
+		data = binary.AppendUvarint(data, uint64(len(v)))
+		data = append(data, v...)
+	}
+	testReaderSuite(t, "protobuf-delim", "", data, "foo", "", strings.Repeat("x", 300))
+
+	testReaderSuite(t, "protobuf-delim", "", nil)
+}
+
+func TestProtobufDelimReaderTruncated(t *testing.T) {
+	data := binary.AppendUvarint(nil, 10)
+	data = append(data, "foo"...)
+
+	ctor, err := GetReader("protobuf-delim", NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor("", io.NopCloser(bytes.NewReader(data)), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, _, err = r.Next(context.Background())
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.NoError(t, r.Close(context.Background()))
+}
+
+func TestAvroOCFReaderSchemaMetadata(t *testing.T) {
+	schema := `{"type":"record","name":"foo","fields":[{"name":"name","type":"string"}]}`
+
+	var buf bytes.Buffer
+	ocfW, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: schema})
+	require.NoError(t, err)
+	require.NoError(t, ocfW.Append([]any{
+		map[string]any{"name": "foo"},
+		map[string]any{"name": "bar"},
+	}))
+
+	ctor, err := GetReader("avro-ocf:marshaler=json", NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor("", io.NopCloser(bytes.NewReader(buf.Bytes())), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, exp := range []string{`{"name":"foo"}`, `{"name":"bar"}`} {
+		parts, ackFn, err := r.Next(context.Background())
+		require.NoError(t, err)
+		require.Len(t, parts, 1)
+		assert.Equal(t, exp, string(parts[0].AsBytes()))
+		assert.Equal(t, schema, parts[0].MetaGet("avro_schema"))
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+
+	_, _, err = r.Next(context.Background())
+	assert.Equal(t, io.EOF, err)
+	require.NoError(t, r.Close(context.Background()))
+}
+
+func TestMultilineReader(t *testing.T) {
+	data := []byte(`2022-10-01 foo
+2022-10-02 bar
+  at baz.go:10
+  at buz.go:20
+2022-10-03 qux`)
+
+	testReaderSuite(
+		t, `multiline:^\d{4}-\d\d-\d\d`, "", data,
+		"2022-10-01 foo",
+		"2022-10-02 bar\n  at baz.go:10\n  at buz.go:20",
+		"2022-10-03 qux",
+	)
+
+	testReaderSuite(
+		t, `multiline:^\d{4}-\d\d-\d\d`, "", []byte("leading\n2022-10-01 foo\n"),
+		"leading",
+		"2022-10-01 foo",
+	)
+}
+
+func TestMultilineReaderMaxBuffer(t *testing.T) {
+	conf := NewReaderConfig()
+	conf.MaxScanTokenSize = 10
+
+	ctor, err := GetReader(`multiline:^start`, conf)
+	require.NoError(t, err)
+
+	r, err := ctor("", io.NopCloser(bytes.NewReader([]byte("start\nfoo\nbar\nstart"))), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, exp := range []string{"start\nfoo", "bar", "start"} {
+		parts, ackFn, err := r.Next(context.Background())
+		require.NoError(t, err)
+		require.Len(t, parts, 1)
+		assert.Equal(t, exp, string(parts[0].AsBytes()))
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+
+	_, _, err = r.Next(context.Background())
+	assert.Equal(t, io.EOF, err)
+	require.NoError(t, r.Close(context.Background()))
+}
+
+func TestMultilineReaderFlushTimeout(t *testing.T) {
+	ctor, err := GetReader(`multiline:flush_timeout=10ms:^start`, NewReaderConfig())
+	require.NoError(t, err)
+
+	pR, pW := io.Pipe()
+	r, err := ctor("", pR, func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	go func() {
+		_, _ = pW.Write([]byte("start foo\n  bar\n"))
+	}()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+
+	parts, ackFn, err := r.Next(ctx)
+	require.NoError(t, err)
+	require.Len(t, parts, 1)
+	assert.Equal(t, "start foo\n  bar", string(parts[0].AsBytes()))
+	require.NoError(t, ackFn(ctx, nil))
+
+	require.NoError(t, r.Close(ctx))
+}
+
+func TestMultilineReaderBadConfig(t *testing.T) {
+	for _, c := range []string{
+		"multiline:",
+		"multiline:flush_timeout=nope:^foo",
+		"multiline:flush_timeout=1s:",
+		"multiline:(",
+	} {
+		_, err := GetReader(c, NewReaderConfig())
+		assert.Error(t, err, c)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	data := []byte(": a comment\n\nevent: update\nid: 1\ndata: foo\ndata: bar\n\ndata:baz\r\n\r\nevent: ignored\n\nid: 2\ndata: buz")
+	testReaderSuite(t, "sse", "", data, "foo\nbar", "baz", "buz")
+
+	ctor, err := GetReader("sse", NewReaderConfig())
+	require.NoError(t, err)
+
+	r, err := ctor("", io.NopCloser(bytes.NewReader(data)), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	var meta [][2]string
+	for {
+		parts, ackFn, err := r.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Len(t, parts, 1)
+		meta = append(meta, [2]string{parts[0].MetaGet("sse_event"), parts[0].MetaGet("sse_id")})
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	require.NoError(t, r.Close(context.Background()))
+
+	assert.Equal(t, [][2]string{{"update", "1"}, {"", "1"}, {"", "2"}}, meta)
+}
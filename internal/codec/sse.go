@@ -0,0 +1,146 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// sseReader consumes a stream of server-sent events, where the data lines of
+// each event are joined into a single message and the event type and id are
+// added as metadata.
+type sseReader struct {
+	r         io.ReadCloser
+	scanner   *bufio.Scanner
+	sourceAck ReaderAckFn
+
+	// The last event id persists across events until it is set again.
+	lastEventID string
+
+	mut      sync.Mutex
+	finished bool
+	pending  int32
+}
+
+func newSSEReader(conf ReaderConfig, r io.ReadCloser, ackFn ReaderAckFn) (Reader, error) {
+	scanner := bufio.NewScanner(r)
+	if conf.MaxScanTokenSize != bufio.MaxScanTokenSize {
+		scanner.Buffer([]byte{}, conf.MaxScanTokenSize)
+	}
+	return &sseReader{
+		r:         r,
+		scanner:   scanner,
+		sourceAck: ackOnce(ackFn),
+	}, nil
+}
+
+func (s *sseReader) ack(ctx context.Context, err error) error {
+	s.mut.Lock()
+	s.pending--
+	doAck := s.pending == 0 && s.finished
+	s.mut.Unlock()
+
+	if err != nil {
+		return s.sourceAck(ctx, err)
+	}
+	if doAck {
+		return s.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+// nextEvent scans lines until an event is dispatched by an empty line or the
+// end of the stream, events without any data are skipped as per the spec.
+func (s *sseReader) nextEvent() (*message.Part, error) {
+	var data bytes.Buffer
+	var hasData bool
+	var eventType string
+
+	dispatch := func() *message.Part {
+		if !hasData {
+			eventType = ""
+			return nil
+		}
+		part := message.NewPart(append([]byte(nil), data.Bytes()...))
+		if eventType != "" {
+			part.MetaSet("sse_event", eventType)
+		}
+		if s.lastEventID != "" {
+			part.MetaSet("sse_id", s.lastEventID)
+		}
+		data.Reset()
+		hasData, eventType = false, ""
+		return part
+	}
+
+	for s.scanner.Scan() {
+		line := bytes.TrimSuffix(s.scanner.Bytes(), []byte("\r"))
+		if len(line) == 0 {
+			if part := dispatch(); part != nil {
+				return part, nil
+			}
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.Write(value)
+			hasData = true
+		case "event":
+			eventType = string(value)
+		case "id":
+			s.lastEventID = string(value)
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	if part := dispatch(); part != nil {
+		return part, nil
+	}
+	return nil, io.EOF
+}
+
+func (s *sseReader) Next(ctx context.Context) ([]*message.Part, ReaderAckFn, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	part, err := s.nextEvent()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			s.finished = true
+		} else {
+			_ = s.sourceAck(ctx, err)
+		}
+		return nil, nil, err
+	}
+
+	s.pending++
+	return []*message.Part{part}, s.ack, nil
+}
+
+func (s *sseReader) Close(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if !s.finished {
+		_ = s.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if s.pending == 0 {
+		_ = s.sourceAck(ctx, nil)
+	}
+	return s.r.Close()
+}
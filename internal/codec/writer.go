@@ -2,24 +2,35 @@ package codec
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/message"
 )
 
 // WriterDocs is a static field documentation for output codecs.
 var WriterDocs = docs.FieldString(
-	"codec", "The way in which the bytes of messages should be written out into the output data stream. It's possible to write lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter.", "lines", "delim:\t", "delim:foobar",
+	"codec", "The way in which the bytes of messages should be written out into the output data stream. It's possible to write lines using a custom delimiter with the `delim:x` codec, where x is the character sequence custom delimiter. The output stream can be compressed by preceding a codec with a compression codec, for example `zstd/lines` writes zstd compressed lines.", "lines", "delim:\t", "delim:foobar", "zstd/lines",
 ).HasAnnotatedOptions(
 	"all-bytes", "Only applicable to file based outputs. Writes each message to a file in full, if the file already exists the old content is deleted.",
 	"append", "Append each message to the output stream without any delimiter or special encoding.",
 	"lines", "Append each message to the output stream followed by a line break.",
 	"delim:x", "Append each message to the output stream followed by a custom delimiter.",
+	"protobuf-delim", "Append each message to the output stream prefixed with its length as a varint, as read by the `parseDelimitedFrom` method of protobuf libraries.",
+	"gzip", "Compress the output stream with gzip, this codec should precede another codec, e.g. `gzip/lines`.",
+	"lz4", "Compress the output stream as lz4 frames, this codec should precede another codec, e.g. `lz4/lines`.",
+	"snappy", "Compress the output stream in the snappy framing format, this codec should precede another codec, e.g. `snappy/lines`.",
+	"zstd", "Compress the output stream with zstd, this codec should precede another codec, e.g. `zstd/lines`.",
 ).LinterFunc(nil) // Disable default option linter as it doesn't include foo:bar formats.
 
 //------------------------------------------------------------------------------
@@ -43,6 +54,28 @@ type WriterConstructor func(io.WriteCloser) (Writer, error)
 
 // GetWriter returns a constructor that creates write codecs.
 func GetWriter(codec string) (WriterConstructor, WriterConfig, error) {
+	var compressors []compressWriterConstructor
+	for {
+		i := strings.Index(codec, "/")
+		if i < 0 {
+			break
+		}
+		ctor, exists := compressWriters[codec[:i]]
+		if !exists {
+			break
+		}
+		compressors = append(compressors, ctor)
+		codec = codec[i+1:]
+	}
+
+	ctor, conf, err := getPartWriter(codec)
+	if err != nil || len(compressors) == 0 {
+		return ctor, conf, err
+	}
+	return chainCompressWriters(compressors, ctor), conf, nil
+}
+
+func getPartWriter(codec string) (WriterConstructor, WriterConfig, error) {
 	switch codec {
 	case "all-bytes":
 		return func(w io.WriteCloser) (Writer, error) {
@@ -54,6 +87,8 @@ func GetWriter(codec string) (WriterConstructor, WriterConfig, error) {
 		}, customDelimConfig, nil
 	case "lines":
 		return newLinesWriter, linesWriterConfig, nil
+	case "protobuf-delim":
+		return newVarintDelimWriter, customDelimConfig, nil
 	}
 	if strings.HasPrefix(codec, "delim:") {
 		by := strings.TrimPrefix(codec, "delim:")
@@ -148,3 +183,111 @@ func (d *customDelimWriter) Write(ctx context.Context, p *message.Part) error {
 func (d *customDelimWriter) Close(ctx context.Context) error {
 	return d.w.Close()
 }
+
+//------------------------------------------------------------------------------
+
+type varintDelimWriter struct {
+	w io.WriteCloser
+}
+
+func newVarintDelimWriter(w io.WriteCloser) (Writer, error) {
+	return &varintDelimWriter{w: w}, nil
+}
+
+func (d *varintDelimWriter) Write(ctx context.Context, p *message.Part) error {
+	partBytes := p.AsBytes()
+	sizeBytes := binary.AppendUvarint(nil, uint64(len(partBytes)))
+	if _, err := d.w.Write(sizeBytes); err != nil {
+		return err
+	}
+	_, err := d.w.Write(partBytes)
+	return err
+}
+
+func (d *varintDelimWriter) Close(ctx context.Context) error {
+	return d.w.Close()
+}
+
+//------------------------------------------------------------------------------
+
+type flushWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+}
+
+type compressWriterConstructor func(io.Writer) (flushWriteCloser, error)
+
+var compressWriters = map[string]compressWriterConstructor{
+	"gzip": func(w io.Writer) (flushWriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+	"lz4": func(w io.Writer) (flushWriteCloser, error) {
+		return lz4.NewWriter(w), nil
+	},
+	"snappy": func(w io.Writer) (flushWriteCloser, error) {
+		return snappy.NewBufferedWriter(w), nil
+	},
+	"zstd": func(w io.Writer) (flushWriteCloser, error) {
+		return zstd.NewWriter(w)
+	},
+}
+
+// compressedWriteCloser writes to a compressor, and closes both the compressor
+// and the destination it writes to.
+type compressedWriteCloser struct {
+	flushWriteCloser
+	dest io.WriteCloser
+}
+
+func (c *compressedWriteCloser) Close() error {
+	if err := c.flushWriteCloser.Close(); err != nil {
+		_ = c.dest.Close()
+		return err
+	}
+	return c.dest.Close()
+}
+
+// flushingWriter flushes the compressors of a writer after each message is
+// written, so that messages are not held within the buffers of a compressor
+// when the underlying stream is kept open.
+type flushingWriter struct {
+	Writer
+	compressors []flushWriteCloser
+}
+
+func (f *flushingWriter) Write(ctx context.Context, p *message.Part) error {
+	if err := f.Writer.Write(ctx, p); err != nil {
+		return err
+	}
+	for _, c := range f.compressors {
+		if err := c.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chainCompressWriters(compressors []compressWriterConstructor, ctor WriterConstructor) WriterConstructor {
+	return func(w io.WriteCloser) (Writer, error) {
+		// Compressors are applied in the order specified, where the first
+		// compresses the output of the second, and so on, therefore layers are
+		// created from the destination outwards, and flushed in reverse.
+		flushers := make([]flushWriteCloser, len(compressors))
+		for i, cCtor := range compressors {
+			c, err := cCtor(w)
+			if err != nil {
+				_ = w.Close()
+				return nil, err
+			}
+			flushers[len(compressors)-1-i] = c
+			w = &compressedWriteCloser{flushWriteCloser: c, dest: w}
+		}
+
+		pw, err := ctor(w)
+		if err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		return &flushingWriter{Writer: pw, compressors: flushers}, nil
+	}
+}
@@ -0,0 +1,87 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWriterRoundTrips(t *testing.T) {
+	for _, codec := range []string{
+		"lines",
+		"protobuf-delim",
+		"gzip/lines",
+		"lz4/lines",
+		"snappy/lines",
+		"zstd/lines",
+		"zstd/gzip/protobuf-delim",
+	} {
+		codec := codec
+		t.Run(codec, func(t *testing.T) {
+			wCtor, _, err := GetWriter(codec)
+			require.NoError(t, err)
+
+			var buf closeRecorder
+			w, err := wCtor(&buf)
+			require.NoError(t, err)
+
+			require.NoError(t, w.Write(context.Background(), message.NewPart([]byte("foo"))))
+			flushedLen := buf.Len()
+			assert.Greater(t, flushedLen, 0, "messages should be flushed after each write")
+
+			require.NoError(t, w.Write(context.Background(), message.NewPart([]byte("bar"))))
+			require.NoError(t, w.Close(context.Background()))
+			assert.True(t, buf.closed)
+
+			rCtor, err := GetReader(codec, NewReaderConfig())
+			require.NoError(t, err)
+
+			r, err := rCtor("", io.NopCloser(bytes.NewReader(buf.Bytes())), func(ctx context.Context, err error) error {
+				return nil
+			})
+			require.NoError(t, err)
+
+			var actual []string
+			for {
+				parts, ackFn, err := r.Next(context.Background())
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				for _, p := range parts {
+					actual = append(actual, string(p.AsBytes()))
+				}
+				require.NoError(t, ackFn(context.Background(), nil))
+			}
+			require.NoError(t, r.Close(context.Background()))
+			assert.Equal(t, []string{"foo", "bar"}, actual)
+		})
+	}
+}
+
+func TestWriterDelimWithSlash(t *testing.T) {
+	wCtor, _, err := GetWriter("delim:/")
+	require.NoError(t, err)
+
+	var buf closeRecorder
+	w, err := wCtor(&buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(context.Background(), message.NewPart([]byte("foo"))))
+	assert.Equal(t, "foo/", buf.String())
+}
@@ -130,6 +130,14 @@ func (m *Stream) inputLoop() {
 		batchLen := tr.Payload.Len()
 
 		writeBatch, _ := tracing.WithSiblingSpans(m.tracer, m.typeStr, tr.Payload)
+
+		// Sibling spans replace the context of each message, and so expiries
+		// need to be carried over.
+		for i, p := range tr.Payload {
+			if t, ok := message.GetExpiry(p); ok {
+				writeBatch[i] = message.WithExpiry(writeBatch[i], t)
+			}
+		}
 		err := m.buffer.Write(closeAtLeisureCtx, writeBatch, ackFunc)
 		if err == nil {
 			mReceivedCount.Incr(int64(batchLen))
@@ -155,6 +163,7 @@ func (m *Stream) outputLoop() {
 		mSent      = m.stats.GetCounter("buffer_sent")
 		mSentBatch = m.stats.GetCounter("buffer_batch_sent")
 		mLatency   = m.stats.GetTimer("buffer_latency_ns")
+		mExpired   = m.stats.GetCounter("buffer_expired")
 	)
 
 	closeNowCtx, done := m.shutSig.CloseNowCtx(context.Background())
@@ -175,6 +184,17 @@ func (m *Stream) outputLoop() {
 			continue
 		}
 
+		// Messages that expired whilst buffered are dropped.
+		if live, liveIndexes := message.WithoutExpired(msg, time.Now()); liveIndexes != nil {
+			mExpired.Incr(int64(msg.Len() - live.Len()))
+			if msg = live; msg.Len() == 0 {
+				if ackErr := ackFunc(closeNowCtx, nil); ackErr != nil && ackErr != component.ErrTypeClosed {
+					m.log.Errorf("Failed to ack buffer message: %v\n", ackErr)
+				}
+				continue
+			}
+		}
+
 		// It's possible that the buffer wiped our previous root span.
 		tracing.InitSpans(m.tracer, m.typeStr, msg)
 
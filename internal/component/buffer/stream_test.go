@@ -267,3 +267,47 @@ func TestStreamReadErrors(t *testing.T) {
 	close(resChan)
 	close(tChan)
 }
+
+func TestStreamBufferExpired(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	tChan := make(chan message.Transaction)
+	resChan := make(chan error)
+
+	b := NewStream("meow", newMemoryBuffer(10), component.NoopObservability())
+	require.NoError(t, b.Consume(tChan))
+
+	past := time.Now().Add(-time.Second)
+
+	expiredMsg := message.QuickBatch([][]byte{[]byte("foo")})
+	expiredMsg[0] = message.WithExpiry(expiredMsg[0], past)
+
+	mixedMsg := message.QuickBatch([][]byte{[]byte("bar"), []byte("baz")})
+	mixedMsg[0] = message.WithExpiry(mixedMsg[0], past)
+
+	for _, msg := range []message.Batch{expiredMsg, mixedMsg} {
+		select {
+		case tChan <- message.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	select {
+	case outTr := <-b.TransactionChan():
+		assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(outTr.Payload))
+		require.NoError(t, outTr.Ack(tCtx, nil))
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	b.TriggerCloseNow()
+	require.NoError(t, b.WaitForClose(tCtx))
+}
@@ -63,6 +63,16 @@ func MetricsForCache(c V1, stats metrics.Type) V1 {
 	}
 }
 
+// ExportItems exports the items of the underlying cache when it implements
+// Snapshotter.
+func (a *metricsCache) ExportItems(ctx context.Context) (map[string]TTLItem, error) {
+	s, ok := a.c.(Snapshotter)
+	if !ok {
+		return nil, ErrSnapshotNotSupported
+	}
+	return s.ExportItems(ctx)
+}
+
 func (a *metricsCache) Get(ctx context.Context, key string) ([]byte, error) {
 	started := time.Now()
 	b, err := a.c.Get(ctx, key)
@@ -79,6 +89,19 @@ func (a *metricsCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *metricsCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	started := time.Now()
+	values, err := a.c.GetMulti(ctx, keys...)
+	a.mGetLatency.Timing(int64(time.Since(started)))
+	if err != nil {
+		a.mGetError.Incr(int64(len(keys)))
+	} else {
+		a.mGetSuccess.Incr(int64(len(values)))
+		a.mGetNotFound.Incr(int64(len(keys) - len(values)))
+	}
+	return values, err
+}
+
 func (a *metricsCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	started := time.Now()
 	err := a.c.Set(ctx, key, value, ttl)
@@ -119,6 +142,23 @@ func (a *metricsCache) Add(ctx context.Context, key string, value []byte, ttl *t
 	return err
 }
 
+func (a *metricsCache) AddMulti(ctx context.Context, items map[string]TTLItem) map[string]error {
+	started := time.Now()
+	errs := a.c.AddMulti(ctx, items)
+	a.mAddLatency.Timing(int64(time.Since(started)))
+
+	var dupes int64
+	for _, err := range errs {
+		if errors.Is(err, component.ErrKeyAlreadyExists) {
+			dupes++
+		}
+	}
+	a.mAddDupe.Incr(dupes)
+	a.mAddError.Incr(int64(len(errs)) - dupes)
+	a.mAddSuccess.Incr(int64(len(items) - len(errs)))
+	return errs
+}
+
 func (a *metricsCache) Delete(ctx context.Context, key string) error {
 	started := time.Now()
 	err := a.c.Delete(ctx, key)
@@ -43,6 +43,19 @@ func (c *closableCache) Set(ctx context.Context, key string, value []byte, ttl *
 	return nil
 }
 
+func (c *closableCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.m[k]; ok {
+			values[k] = i.b
+		}
+	}
+	return values, nil
+}
+
 func (c *closableCache) SetMulti(ctx context.Context, keyValues map[string]TTLItem) error {
 	if c.err != nil {
 		return c.err
@@ -68,6 +81,16 @@ func (c *closableCache) Add(ctx context.Context, key string, value []byte, ttl *
 	return nil
 }
 
+func (c *closableCache) AddMulti(ctx context.Context, keyValues map[string]TTLItem) map[string]error {
+	errs := map[string]error{}
+	for k, v := range keyValues {
+		if err := c.Add(ctx, k, v.Value, v.TTL); err != nil {
+			errs[k] = err
+		}
+	}
+	return errs
+}
+
 func (c *closableCache) Delete(ctx context.Context, key string) error {
 	if c.err != nil {
 		return c.err
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -21,10 +22,21 @@ type V1 interface {
 	// fails.
 	Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error
 
+	// GetMulti attempts to locate and return the cached values of multiple
+	// keys in as few requests as possible. Keys that do not exist are omitted
+	// from the result, and an error is returned if the command fails.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+
 	// SetMulti attempts to set the value of multiple keys, returns an error if
 	// any of the keys fail.
 	SetMulti(ctx context.Context, items map[string]TTLItem) error
 
+	// AddMulti attempts to set the value of multiple keys only if each key does
+	// not already exist, in as few requests as possible. An error is returned
+	// for each key that was not added, which is component.ErrKeyAlreadyExists
+	// for keys that already exist.
+	AddMulti(ctx context.Context, items map[string]TTLItem) map[string]error
+
 	// Add attempts to set the value of a key only if the key does not already
 	// exist, returns an error if the key already exists or if the command
 	// fails.
@@ -38,3 +50,16 @@ type V1 interface {
 	// is cancelled.
 	Close(ctx context.Context) error
 }
+
+// ErrSnapshotNotSupported is returned when attempting to export the items of a
+// cache that does not implement Snapshotter.
+var ErrSnapshotNotSupported = errors.New("cache does not support exporting its items")
+
+// Snapshotter is an optional interface implemented by caches that hold their
+// items within the memory of the process, allowing the items to be exported in
+// order to migrate them to another instance.
+type Snapshotter interface {
+	// ExportItems returns a copy of all items that have not expired, along
+	// with the remaining TTL of each item that expires.
+	ExportItems(ctx context.Context) (map[string]TTLItem, error)
+}
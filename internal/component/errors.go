@@ -49,6 +49,7 @@ var (
 	ErrProcessorNotFound = errors.New("processor not found")
 	ErrRateLimitNotFound = errors.New("rate limit not found")
 	ErrOutputNotFound    = errors.New("output not found")
+	ErrMappingNotFound   = errors.New("mapping not found")
 	ErrKeyAlreadyExists  = errors.New("key already exists")
 	ErrKeyNotFound       = errors.New("key does not exist")
 	ErrPipeNotFound      = errors.New("pipe was not found")
@@ -15,10 +15,12 @@ import (
 )
 
 type asyncPreserverResend struct {
-	boff     backoff.BackOff
-	attempts int
-	msg      message.Batch
-	ackFn    AsyncAckFn
+	boff        backoff.BackOff
+	attempts    int
+	firstFailed time.Time
+	msg         message.Batch
+	spilled     *asyncPreserverSpillRef
+	ackFn       AsyncAckFn
 }
 
 func newResendMsg(msg message.Batch, ackFn AsyncAckFn) asyncPreserverResend {
@@ -38,23 +40,99 @@ func newResendMsg(msg message.Batch, ackFn AsyncAckFn) asyncPreserverResend {
 //
 // Wrapping an input with this type is useful when your source of messages
 // doesn't have a concept of a NoAck (like Kafka), and instead of "rejecting"
-// messages we always intend to simply retry them until success.
+// messages we always intend to simply retry them until success. Retries can
+// optionally be limited with AsyncPreserverMaxRetries and
+// AsyncPreserverMaxElapsedTime, in which case messages that exceed the limits
+// are given up on with AsyncPreserverRejectFn.
+//
+// The number of failed batches held in memory can be limited with
+// AsyncPreserverSpill, beyond which their contents are written to disk until
+// they are resent.
 type AsyncPreserver struct {
 	resendMessages  []asyncPreserverResend
 	resendInterrupt func()
 	msgsMut         sync.Mutex
 	pendingMessages int64
 
+	maxRetries     int
+	maxElapsedTime time.Duration
+	rejectFn       AsyncPreserverRejectFunc
+
+	maxInMemory int
+	inMemory    int
+	spill       *asyncPreserverSpill
+
 	inputClosed int32
 	r           Async
 }
 
+// AsyncPreserverRejectFunc is called with the messages of a batch that have
+// exceeded the retry limits of an AsyncPreserver, along with the error of
+// their last attempt, and is able to route them elsewhere, such as to a dead
+// letter queue. When it returns nil the batch is acknowledged with the wrapped
+// input, otherwise the messages continue to be retried.
+type AsyncPreserverRejectFunc func(ctx context.Context, msg message.Batch, err error) error
+
+// AsyncPreserverOpt is a functional option for an AsyncPreserver.
+type AsyncPreserverOpt func(p *AsyncPreserver)
+
+// AsyncPreserverMaxRetries sets the maximum number of times that a message is
+// resent before it is rejected. Zero, the default, retries messages forever.
+func AsyncPreserverMaxRetries(n int) AsyncPreserverOpt {
+	return func(p *AsyncPreserver) {
+		p.maxRetries = n
+	}
+}
+
+// AsyncPreserverMaxElapsedTime sets the maximum period of time after the first
+// failure of a message that it's resent before it is rejected. Zero, the
+// default, retries messages forever.
+func AsyncPreserverMaxElapsedTime(d time.Duration) AsyncPreserverOpt {
+	return func(p *AsyncPreserver) {
+		p.maxElapsedTime = d
+	}
+}
+
+// AsyncPreserverRejectFn sets a function that is called with messages that
+// exceed the retry limits. Without one the error of the last attempt of such
+// messages is passed to the acknowledgement function of the wrapped input.
+func AsyncPreserverRejectFn(fn AsyncPreserverRejectFunc) AsyncPreserverOpt {
+	return func(p *AsyncPreserver) {
+		p.rejectFn = fn
+	}
+}
+
+// AsyncPreserverSpill limits the number of failed batches that are held in
+// memory whilst waiting to be resent. Beyond the limit the contents and
+// metadata of failed batches are spilled to temporary files within dir, or
+// the default directory for temporary files when dir is empty, and read back
+// once they're due to be resent. Metadata values are stored as JSON, and
+// therefore values that aren't strings might change type when read back. The
+// contexts of spilled messages, which carry their tracing spans, remain in
+// memory.
+//
+// Zero, the default, holds all failed batches in memory.
+func AsyncPreserverSpill(maxInMemory int, dir string) AsyncPreserverOpt {
+	return func(p *AsyncPreserver) {
+		if maxInMemory <= 0 {
+			p.maxInMemory, p.spill = 0, nil
+			return
+		}
+		p.maxInMemory = maxInMemory
+		p.spill = newAsyncPreserverSpill(dir)
+	}
+}
+
 // NewAsyncPreserver returns a new AsyncPreserver wrapper around a input.Async.
-func NewAsyncPreserver(r Async) *AsyncPreserver {
-	return &AsyncPreserver{
+func NewAsyncPreserver(r Async, opts ...AsyncPreserverOpt) *AsyncPreserver {
+	p := &AsyncPreserver{
 		r:               r,
 		resendInterrupt: func() {},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 //------------------------------------------------------------------------------
@@ -108,12 +186,7 @@ func (p *AsyncPreserver) wrapBatchAckFn(m asyncPreserverResend) (message.Batch,
 				}
 			}
 			m.msg = resendMsg
-
-			p.msgsMut.Lock()
-			p.resendMessages = append(p.resendMessages, m)
-			p.resendInterrupt()
-			p.msgsMut.Unlock()
-			return nil
+			return p.nack(ctx, m, res)
 		}
 		atomic.AddInt64(&p.pendingMessages, -1)
 		return m.ackFn(ctx, res)
@@ -123,17 +196,52 @@ func (p *AsyncPreserver) wrapBatchAckFn(m asyncPreserverResend) (message.Batch,
 func (p *AsyncPreserver) wrapSingleAckFn(m asyncPreserverResend) (message.Batch, AsyncAckFn) {
 	return m.msg, func(ctx context.Context, res error) error {
 		if res != nil {
-			p.msgsMut.Lock()
-			p.resendMessages = append(p.resendMessages, m)
-			p.resendInterrupt()
-			p.msgsMut.Unlock()
-			return nil
+			return p.nack(ctx, m, res)
 		}
 		atomic.AddInt64(&p.pendingMessages, -1)
 		return m.ackFn(ctx, res)
 	}
 }
 
+// nack either queues a failed message to be resent or, if it has exceeded the
+// retry limits, rejects it.
+func (p *AsyncPreserver) nack(ctx context.Context, m asyncPreserverResend, res error) error {
+	if m.firstFailed.IsZero() {
+		m.firstFailed = time.Now()
+	}
+
+	exceeded := (p.maxRetries > 0 && m.attempts >= p.maxRetries) ||
+		(p.maxElapsedTime > 0 && time.Since(m.firstFailed) >= p.maxElapsedTime)
+	if exceeded {
+		if p.rejectFn == nil {
+			atomic.AddInt64(&p.pendingMessages, -1)
+			return m.ackFn(ctx, res)
+		}
+		if err := p.rejectFn(ctx, m.msg, res); err == nil {
+			atomic.AddInt64(&p.pendingMessages, -1)
+			return m.ackFn(ctx, nil)
+		}
+		// If the messages couldn't be rejected then we keep retrying them,
+		// as otherwise they'd be lost.
+	}
+
+	p.msgsMut.Lock()
+	if p.spill != nil && p.inMemory >= p.maxInMemory {
+		// If the batch fails to spill then we hold it in memory instead, as
+		// that's preferable to giving up on it.
+		if ref, err := p.spill.write(m.msg); err == nil {
+			m.msg, m.spilled = nil, &ref
+		}
+	}
+	if m.spilled == nil {
+		p.inMemory++
+	}
+	p.resendMessages = append(p.resendMessages, m)
+	p.resendInterrupt()
+	p.msgsMut.Unlock()
+	return nil
+}
+
 // ReadBatch attempts to read a new message from the source.
 func (p *AsyncPreserver) ReadBatch(ctx context.Context) (message.Batch, AsyncAckFn, error) {
 	var cancel func()
@@ -150,8 +258,24 @@ func (p *AsyncPreserver) ReadBatch(ctx context.Context) (message.Batch, AsyncAck
 		} else {
 			p.resendMessages = nil
 		}
+
+		var spillErr error
+		if resend.spilled != nil {
+			resend.msg, spillErr = p.spill.read(*resend.spilled)
+			resend.spilled = nil
+		} else {
+			p.inMemory--
+		}
 		p.msgsMut.Unlock()
 
+		if spillErr != nil {
+			// The contents of the batch are lost, and so we pass the error on
+			// to the wrapped input in the hope that it can redeliver them.
+			atomic.AddInt64(&p.pendingMessages, -1)
+			_ = resend.ackFn(ctx, spillErr)
+			return nil, nil, spillErr
+		}
+
 		resend.attempts++
 		if resend.attempts > 2 {
 			// This sleep prevents a busy loop on permanently failed messages.
@@ -208,5 +332,14 @@ func (p *AsyncPreserver) ReadBatch(ctx context.Context) (message.Batch, AsyncAck
 // Close triggers the shut down of this component and blocks until completion or
 // context cancellation.
 func (p *AsyncPreserver) Close(ctx context.Context) error {
-	return p.r.Close(ctx)
+	err := p.r.Close(ctx)
+
+	p.msgsMut.Lock()
+	if p.spill != nil {
+		if sErr := p.spill.close(); sErr != nil && err == nil {
+			err = sErr
+		}
+	}
+	p.msgsMut.Unlock()
+	return err
 }
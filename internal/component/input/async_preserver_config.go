@@ -0,0 +1,102 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// RedeliveryConfig contains configuration fields that limit how failed
+// messages are resent by an AsyncPreserver, and how many of them are held in
+// memory whilst waiting.
+type RedeliveryConfig struct {
+	MaxRetries     int    `json:"max_retries" yaml:"max_retries"`
+	MaxElapsedTime string `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+	RejectOutput   string `json:"reject_output" yaml:"reject_output"`
+	MaxInMemory    int    `json:"max_in_memory" yaml:"max_in_memory"`
+	SpillDirectory string `json:"spill_directory" yaml:"spill_directory"`
+}
+
+// NewRedeliveryConfig creates a new RedeliveryConfig with default values,
+// where failed messages are resent forever.
+func NewRedeliveryConfig() RedeliveryConfig {
+	return RedeliveryConfig{
+		MaxRetries:     0,
+		MaxElapsedTime: "",
+		RejectOutput:   "",
+		MaxInMemory:    0,
+		SpillDirectory: "",
+	}
+}
+
+// RedeliveryFieldSpec returns a field spec for a RedeliveryConfig.
+func RedeliveryFieldSpec() docs.FieldSpec {
+	return docs.FieldObject(
+		"redelivery", "Controls how messages that fail to be delivered are resent. By default failed messages are resent until they succeed, which means a message that can never be delivered blocks the consumption of new messages indefinitely.",
+	).WithChildren(
+		docs.FieldInt("max_retries", "The maximum number of times that a failed message is resent before it is rejected. Set to `0` in order to resend messages forever."),
+		docs.FieldString("max_elapsed_time", "The maximum period of time after the first failure of a message during which it's resent before it is rejected. Set to empty in order to resend messages forever.", "1m", "1h"),
+		docs.FieldString("reject_output", "The name of an [`output` resource](/docs/components/outputs/about#labels) that rejected messages are written to, such as a dead letter queue, before they're acknowledged. When empty rejected messages are acknowledged with the error of their last attempt, which means they're given up on unless the source is able to redeliver them. If a rejected message cannot be written to the output it continues to be resent."),
+		docs.FieldInt("max_in_memory", "The maximum number of failed batches to hold in memory whilst they wait to be resent, beyond which the contents and metadata of batches are written to temporary files until they're resent. Metadata values are stored as JSON and therefore values that aren't strings might change type. Set to `0` in order to hold all failed batches in memory."),
+		docs.FieldString("spill_directory", "The directory in which to create temporary files for batches beyond the `max_in_memory` limit. When empty the default directory for temporary files is used."),
+	).AtVersion("4.9.0").Advanced()
+}
+
+// redeliveryOutputs is the subset of a manager required for rejecting messages
+// to an output resource.
+type redeliveryOutputs interface {
+	ProbeOutput(name string) bool
+	AccessOutput(ctx context.Context, name string, fn func(output.Sync)) error
+}
+
+// RedeliveryOpts returns the AsyncPreserver options described by a config.
+func RedeliveryOpts(conf RedeliveryConfig, mgr redeliveryOutputs) ([]AsyncPreserverOpt, error) {
+	if conf.MaxRetries < 0 {
+		return nil, fmt.Errorf("redelivery max_retries must be greater than or equal to zero, got %v", conf.MaxRetries)
+	}
+	opts := []AsyncPreserverOpt{AsyncPreserverMaxRetries(conf.MaxRetries)}
+
+	if conf.MaxInMemory < 0 {
+		return nil, fmt.Errorf("redelivery max_in_memory must be greater than or equal to zero, got %v", conf.MaxInMemory)
+	}
+	if conf.MaxInMemory > 0 {
+		opts = append(opts, AsyncPreserverSpill(conf.MaxInMemory, conf.SpillDirectory))
+	}
+
+	if conf.MaxElapsedTime != "" {
+		d, err := time.ParseDuration(conf.MaxElapsedTime)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redelivery max_elapsed_time: %w", err)
+		}
+		opts = append(opts, AsyncPreserverMaxElapsedTime(d))
+	}
+
+	if name := conf.RejectOutput; name != "" {
+		if !mgr.ProbeOutput(name) {
+			return nil, fmt.Errorf("output resource '%v' was not found", name)
+		}
+		opts = append(opts, AsyncPreserverRejectFn(func(ctx context.Context, msg message.Batch, _ error) error {
+			resChan := make(chan error, 1)
+			var err error
+			if aErr := mgr.AccessOutput(ctx, name, func(o output.Sync) {
+				err = o.WriteTransaction(ctx, message.NewTransaction(msg.ShallowCopy(), resChan))
+			}); aErr != nil {
+				return aErr
+			}
+			if err != nil {
+				return err
+			}
+			select {
+			case err = <-resChan:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+			return err
+		}))
+	}
+	return opts, nil
+}
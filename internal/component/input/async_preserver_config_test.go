@@ -0,0 +1,208 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// batchesReader provides a list of batches and reports the results of their
+// acknowledgements.
+type batchesReader struct {
+	msgs []message.Batch
+	acks chan error
+}
+
+func (r *batchesReader) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (r *batchesReader) ReadBatch(ctx context.Context) (message.Batch, input.AsyncAckFn, error) {
+	if len(r.msgs) == 0 {
+		<-ctx.Done()
+		return nil, nil, component.ErrTimeout
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, func(ctx context.Context, err error) error {
+		r.acks <- err
+		return nil
+	}, nil
+}
+
+func (r *batchesReader) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestRedeliveryOptsErrors(t *testing.T) {
+	mgr := mock.NewManager()
+
+	conf := input.NewRedeliveryConfig()
+	conf.MaxRetries = -1
+	_, err := input.RedeliveryOpts(conf, mgr)
+	require.EqualError(t, err, "redelivery max_retries must be greater than or equal to zero, got -1")
+
+	conf = input.NewRedeliveryConfig()
+	conf.MaxElapsedTime = "nope"
+	_, err = input.RedeliveryOpts(conf, mgr)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse redelivery max_elapsed_time")
+
+	conf = input.NewRedeliveryConfig()
+	conf.MaxInMemory = -1
+	_, err = input.RedeliveryOpts(conf, mgr)
+	require.EqualError(t, err, "redelivery max_in_memory must be greater than or equal to zero, got -1")
+
+	conf = input.NewRedeliveryConfig()
+	conf.RejectOutput = "nope"
+	_, err = input.RedeliveryOpts(conf, mgr)
+	require.EqualError(t, err, "output resource 'nope' was not found")
+}
+
+func TestRedeliveryRejectOutput(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var rejectedMut sync.Mutex
+	var rejected [][]byte
+	writeErrs := []error{errors.New("dlq is down"), nil}
+
+	mgr := mock.NewManager()
+	mgr.Outputs["dlq"] = func(ctx context.Context, t message.Transaction) error {
+		rejectedMut.Lock()
+		err := writeErrs[0]
+		writeErrs = writeErrs[1:]
+		if err == nil {
+			rejected = append(rejected, message.GetAllBytes(t.Payload)...)
+		}
+		rejectedMut.Unlock()
+		return t.Ack(ctx, err)
+	}
+
+	conf := input.NewRedeliveryConfig()
+	conf.MaxRetries = 1
+	conf.RejectOutput = "dlq"
+
+	opts, err := input.RedeliveryOpts(conf, mgr)
+	require.NoError(t, err)
+
+	rdr := &batchesReader{
+		msgs: []message.Batch{message.QuickBatch([][]byte{[]byte("foo")})},
+		acks: make(chan error, 1),
+	}
+	pres := input.NewAsyncPreserver(rdr, opts...)
+	require.NoError(t, pres.Connect(ctx))
+
+	// The first failure is retried, the second exceeds the limit but the
+	// dead letter output fails and so the message is retried again, and the
+	// third failure is successfully written to the dead letter output.
+	for i := 0; i < 3; i++ {
+		msg, ackFn, err := pres.ReadBatch(ctx)
+		require.NoError(t, err, i)
+		assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(msg))
+		require.NoError(t, ackFn(ctx, errors.New("output is down")))
+	}
+
+	select {
+	case err := <-rdr.acks:
+		require.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("source was not acknowledged")
+	}
+
+	rejectedMut.Lock()
+	assert.Equal(t, [][]byte{[]byte("foo")}, rejected)
+	rejectedMut.Unlock()
+}
+
+func TestRedeliveryNoRejectOutput(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := input.NewRedeliveryConfig()
+	conf.MaxRetries = 1
+
+	opts, err := input.RedeliveryOpts(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	rdr := &batchesReader{
+		msgs: []message.Batch{message.QuickBatch([][]byte{[]byte("foo")})},
+		acks: make(chan error, 1),
+	}
+	pres := input.NewAsyncPreserver(rdr, opts...)
+	require.NoError(t, pres.Connect(ctx))
+
+	for i := 0; i < 2; i++ {
+		_, ackFn, err := pres.ReadBatch(ctx)
+		require.NoError(t, err, i)
+		require.NoError(t, ackFn(ctx, errors.New("output is down")))
+	}
+
+	select {
+	case err := <-rdr.acks:
+		require.EqualError(t, err, "output is down")
+	case <-ctx.Done():
+		t.Fatal("source was not acknowledged")
+	}
+}
+
+func TestRedeliverySpill(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := input.NewRedeliveryConfig()
+	conf.MaxInMemory = 1
+	conf.SpillDirectory = t.TempDir()
+
+	opts, err := input.RedeliveryOpts(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	rdr := &batchesReader{
+		msgs: []message.Batch{
+			message.QuickBatch([][]byte{[]byte("foo")}),
+			message.QuickBatch([][]byte{[]byte("bar")}),
+		},
+		acks: make(chan error, 2),
+	}
+	pres := input.NewAsyncPreserver(rdr, opts...)
+	require.NoError(t, pres.Connect(ctx))
+
+	var ackFns []input.AsyncAckFn
+	for i := 0; i < 2; i++ {
+		_, ackFn, err := pres.ReadBatch(ctx)
+		require.NoError(t, err, i)
+		ackFns = append(ackFns, ackFn)
+	}
+	for _, ackFn := range ackFns {
+		require.NoError(t, ackFn(ctx, errors.New("output is down")))
+	}
+
+	// The second batch exceeds the in memory limit and is spilled.
+	spilled, err := filepath.Glob(filepath.Join(conf.SpillDirectory, "*"))
+	require.NoError(t, err)
+	require.Len(t, spilled, 1)
+
+	for _, exp := range []string{"foo", "bar"} {
+		msg, ackFn, err := pres.ReadBatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte(exp)}, message.GetAllBytes(msg))
+		require.NoError(t, ackFn(ctx, nil))
+		require.NoError(t, <-rdr.acks)
+	}
+
+	require.NoError(t, pres.Close(ctx))
+	spilled, err = filepath.Glob(filepath.Join(conf.SpillDirectory, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, spilled)
+}
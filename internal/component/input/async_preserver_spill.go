@@ -0,0 +1,170 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// asyncPreserverSpillSegmentSize is the size beyond which a new spill file is
+// started, allowing the space of older files to be reclaimed once all of their
+// batches have been read back even when new batches continue to be spilled.
+const asyncPreserverSpillSegmentSize = 16 * 1024 * 1024
+
+// asyncPreserverSpillRef is the location of a spilled batch within a spill
+// file, along with the contexts of its messages, which cannot be written to
+// disk.
+type asyncPreserverSpillRef struct {
+	segment *asyncPreserverSpillSegment
+	offset  int64
+	size    int
+	ctxs    []context.Context
+}
+
+// asyncPreserverSpillSegment is an append only temporary file holding spilled
+// batches.
+type asyncPreserverSpillSegment struct {
+	file    *os.File
+	size    int64
+	pending int
+}
+
+// asyncPreserverSpill holds the contents of batches that are waiting to be
+// resent when the in-memory limit of an AsyncPreserver is reached. Batches are
+// appended to a temporary file until it exceeds a segment size, at which point
+// a new file is started. Files are removed once all of their batches have been
+// read back, or truncated if still in use, and any that remain are removed
+// when the preserver is closed.
+//
+// A spill is not safe for concurrent use, it's protected by the message mutex
+// of the preserver.
+type asyncPreserverSpill struct {
+	dir         string
+	segmentSize int64
+	active      *asyncPreserverSpillSegment
+	segments    map[*asyncPreserverSpillSegment]struct{}
+}
+
+func newAsyncPreserverSpill(dir string) *asyncPreserverSpill {
+	return &asyncPreserverSpill{
+		dir:         dir,
+		segmentSize: asyncPreserverSpillSegmentSize,
+		segments:    map[*asyncPreserverSpillSegment]struct{}{},
+	}
+}
+
+// write appends the contents and metadata of a batch to the spill file.
+func (s *asyncPreserverSpill) write(msg message.Batch) (asyncPreserverSpillRef, error) {
+	if s.active == nil || s.active.size >= s.segmentSize {
+		f, err := os.CreateTemp(s.dir, "benthos-resend-*")
+		if err != nil {
+			return asyncPreserverSpillRef{}, fmt.Errorf("failed to create spill file: %w", err)
+		}
+		s.active = &asyncPreserverSpillSegment{file: f}
+		s.segments[s.active] = struct{}{}
+	}
+
+	// Each message is stored as two parts, its contents followed by its
+	// metadata as a JSON object.
+	parts := make([][]byte, 0, msg.Len()*2)
+	ctxs := make([]context.Context, 0, msg.Len())
+	if err := msg.Iter(func(i int, p *message.Part) error {
+		ctxs = append(ctxs, message.GetContext(p))
+		meta := map[string]any{}
+		_ = p.MetaIterMut(func(k string, v any) error {
+			meta[k] = v
+			return nil
+		})
+		metaBytes, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to serialise metadata of message %v: %w", i, err)
+		}
+		parts = append(parts, p.AsBytes(), metaBytes)
+		return nil
+	}); err != nil {
+		return asyncPreserverSpillRef{}, err
+	}
+
+	seg := s.active
+	b := message.SerializeBytes(parts)
+	if _, err := seg.file.WriteAt(b, seg.size); err != nil {
+		return asyncPreserverSpillRef{}, fmt.Errorf("failed to write to spill file: %w", err)
+	}
+
+	ref := asyncPreserverSpillRef{segment: seg, offset: seg.size, size: len(b), ctxs: ctxs}
+	seg.size += int64(len(b))
+	seg.pending++
+	return ref, nil
+}
+
+// read loads a spilled batch back from the spill file.
+func (s *asyncPreserverSpill) read(ref asyncPreserverSpillRef) (message.Batch, error) {
+	defer s.release(ref.segment)
+
+	b := make([]byte, ref.size)
+	if _, err := ref.segment.file.ReadAt(b, ref.offset); err != nil {
+		return nil, fmt.Errorf("failed to read from spill file: %w", err)
+	}
+
+	parts, err := message.DeserializeBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from spill file: %w", err)
+	}
+	if len(parts)%2 != 0 || len(parts)/2 != len(ref.ctxs) {
+		return nil, fmt.Errorf("failed to read from spill file: %w", message.ErrBadMessageBytes)
+	}
+
+	msg := make(message.Batch, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		p := message.NewPart(parts[i]).WithContext(ref.ctxs[i/2])
+
+		var meta map[string]any
+		if err := json.Unmarshal(parts[i+1], &meta); err != nil {
+			return nil, fmt.Errorf("failed to read metadata from spill file: %w", err)
+		}
+		for k, v := range meta {
+			p.MetaSetMut(k, v)
+		}
+		msg = append(msg, p)
+	}
+	return msg, nil
+}
+
+// release marks a spilled batch of a segment as no longer pending, reclaiming
+// the space of the segment once there are none left.
+func (s *asyncPreserverSpill) release(seg *asyncPreserverSpillSegment) {
+	if seg.pending--; seg.pending > 0 {
+		return
+	}
+	seg.pending = 0
+	if seg == s.active {
+		if seg.file.Truncate(0) == nil {
+			seg.size = 0
+		}
+		return
+	}
+	delete(s.segments, seg)
+	_ = removeSpillFile(seg.file)
+}
+
+// close removes all spill files.
+func (s *asyncPreserverSpill) close() error {
+	var err error
+	for seg := range s.segments {
+		if rErr := removeSpillFile(seg.file); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+	s.active = nil
+	s.segments = map[*asyncPreserverSpillSegment]struct{}{}
+	return err
+}
+
+func removeSpillFile(f *os.File) error {
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
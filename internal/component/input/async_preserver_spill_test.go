@@ -0,0 +1,83 @@
+package input
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type spillTestKey struct{}
+
+func spillFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestAsyncPreserverSpillRotation(t *testing.T) {
+	dir := t.TempDir()
+	s := newAsyncPreserverSpill(dir)
+	s.segmentSize = 1
+
+	// Each batch exceeds the segment size and therefore gets a file of its
+	// own.
+	var refs []asyncPreserverSpillRef
+	for _, v := range []string{"foo", "bar", "baz"} {
+		ref, err := s.write(message.QuickBatch([][]byte{[]byte(v)}))
+		require.NoError(t, err)
+		refs = append(refs, ref)
+	}
+	require.Len(t, spillFiles(t, dir), 3)
+
+	// Files are removed as soon as their batches are read back, regardless of
+	// whether other files are still pending.
+	msg, err := s.read(refs[0])
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("foo")}, message.GetAllBytes(msg))
+	require.Len(t, spillFiles(t, dir), 2)
+
+	msg, err = s.read(refs[2])
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(msg))
+
+	// The active file is truncated rather than removed.
+	files := spillFiles(t, dir)
+	require.Len(t, files, 2)
+	assert.Equal(t, s.active.file.Name(), refs[2].segment.file.Name())
+
+	info, err := os.Stat(refs[2].segment.file.Name())
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	require.NoError(t, s.close())
+	assert.Empty(t, spillFiles(t, dir))
+}
+
+func TestAsyncPreserverSpillContext(t *testing.T) {
+	s := newAsyncPreserverSpill(t.TempDir())
+	t.Cleanup(func() {
+		_ = s.close()
+	})
+
+	ctx := context.WithValue(context.Background(), spillTestKey{}, "foo")
+	part := message.NewPart([]byte("hello world")).WithContext(ctx)
+	part.MetaSetMut("bar", "baz")
+
+	ref, err := s.write(message.Batch{part})
+	require.NoError(t, err)
+
+	msg, err := s.read(ref)
+	require.NoError(t, err)
+	require.Len(t, msg, 1)
+	assert.Equal(t, "hello world", string(msg[0].AsBytes()))
+	assert.Equal(t, "baz", msg[0].MetaGet("bar"))
+	assert.Equal(t, "foo", message.GetContext(msg[0]).Value(spillTestKey{}))
+}
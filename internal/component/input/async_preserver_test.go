@@ -3,6 +3,8 @@ package input_test
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"testing"
@@ -971,3 +973,268 @@ func TestAsyncPreserverBufferBatchedAcks(t *testing.T) {
 		sendAck()
 	}
 }
+
+func TestAsyncPreserverMaxRetriesReject(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	var rejected [][]byte
+	var rejectedErr error
+	readerImpl := newMockAsyncReaderBlocked()
+	pres := input.NewAsyncPreserver(readerImpl,
+		input.AsyncPreserverMaxRetries(2),
+		input.AsyncPreserverRejectFn(func(ctx context.Context, msg message.Batch, err error) error {
+			rejected = message.GetAllBytes(msg)
+			rejectedErr = err
+			return nil
+		}))
+
+	go func() {
+		select {
+		case readerImpl.connChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		readerImpl.msgsToSnd = []message.Batch{
+			message.QuickBatch([][]byte{
+				[]byte("foo"),
+				[]byte("bar"),
+				[]byte("baz"),
+			}),
+		}
+		select {
+		case readerImpl.readChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		select {
+		case readerImpl.ackChan <- errors.New("ack propagated"):
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	require.NoError(t, pres.Connect(ctx))
+
+	msg, ackFn, err := pres.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	bErr := batch.NewError(msg, errors.New("first"))
+	bErr.Failed(1, errors.New("second"))
+	require.NoError(t, ackFn(ctx, bErr))
+
+	for i := 0; i < 2; i++ {
+		msg, ackFn, err = pres.ReadBatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("bar")}, message.GetAllBytes(msg))
+		assert.Nil(t, rejected)
+
+		if i == 0 {
+			require.NoError(t, ackFn(ctx, errors.New("nope")))
+		} else {
+			require.EqualError(t, ackFn(ctx, errors.New("nope")), "ack propagated")
+		}
+	}
+
+	assert.Equal(t, [][]byte{[]byte("bar")}, rejected)
+	assert.EqualError(t, rejectedErr, "nope")
+
+	readerImpl.ackMut.Lock()
+	assert.Equal(t, []error{nil}, readerImpl.ackRcvd)
+	readerImpl.ackMut.Unlock()
+}
+
+func TestAsyncPreserverMaxRetriesNoReject(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	readerImpl := newMockAsyncReaderBlocked()
+	readerImpl.msgsToSnd = []message.Batch{
+		message.QuickBatch([][]byte{[]byte("hello world")}),
+	}
+	pres := input.NewAsyncPreserver(readerImpl, input.AsyncPreserverMaxRetries(1))
+
+	go func() {
+		select {
+		case readerImpl.connChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		select {
+		case readerImpl.readChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		select {
+		case readerImpl.ackChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	require.NoError(t, pres.Connect(ctx))
+
+	_, ackFn, err := pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, errors.New("first")))
+
+	msg, ackFn, err := pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello world")}, message.GetAllBytes(msg))
+	require.NoError(t, ackFn(ctx, errors.New("second")))
+
+	readerImpl.ackMut.Lock()
+	assert.Equal(t, []error{errors.New("second")}, readerImpl.ackRcvd)
+	readerImpl.ackMut.Unlock()
+}
+
+func TestAsyncPreserverMaxElapsedTimeRejectFails(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	var rejectAttempts int
+	readerImpl := newMockAsyncReaderBlocked()
+	readerImpl.msgsToSnd = []message.Batch{
+		message.QuickBatch([][]byte{[]byte("hello world")}),
+	}
+	pres := input.NewAsyncPreserver(readerImpl,
+		input.AsyncPreserverMaxElapsedTime(time.Millisecond*50),
+		input.AsyncPreserverRejectFn(func(ctx context.Context, msg message.Batch, err error) error {
+			rejectAttempts++
+			if rejectAttempts == 1 {
+				return errors.New("dead letter queue unavailable")
+			}
+			return nil
+		}))
+
+	go func() {
+		select {
+		case readerImpl.connChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		select {
+		case readerImpl.readChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+		select {
+		case readerImpl.ackChan <- nil:
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	require.NoError(t, pres.Connect(ctx))
+
+	_, ackFn, err := pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, errors.New("first")))
+
+	_, ackFn, err = pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, errors.New("second")))
+	assert.Equal(t, 0, rejectAttempts)
+
+	<-time.After(time.Millisecond * 60)
+
+	// The message is retried when it fails to be rejected.
+	_, ackFn, err = pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, errors.New("third")))
+	assert.Equal(t, 1, rejectAttempts)
+
+	msg, ackFn, err := pres.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello world")}, message.GetAllBytes(msg))
+	require.NoError(t, ackFn(ctx, errors.New("fourth")))
+	assert.Equal(t, 2, rejectAttempts)
+
+	readerImpl.ackMut.Lock()
+	assert.Equal(t, []error{nil}, readerImpl.ackRcvd)
+	readerImpl.ackMut.Unlock()
+}
+
+func TestAsyncPreserverSpill(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+
+	spillDir := t.TempDir()
+	readerImpl := newMockAsyncReaderBlocked()
+	pres := input.NewAsyncPreserver(readerImpl, input.AsyncPreserverSpill(1, spillDir))
+
+	withMeta := message.QuickBatch([][]byte{[]byte("msg 2a"), []byte("msg 2b")})
+	withMeta.Get(1).MetaSetMut("foo", "bar")
+	readerImpl.msgsToSnd = []message.Batch{
+		message.QuickBatch([][]byte{[]byte("msg 1")}),
+		withMeta,
+		message.QuickBatch([][]byte{[]byte("msg 3")}),
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			select {
+			case readerImpl.readChan <- nil:
+			case <-time.After(time.Second):
+				t.Error("Timed out")
+			}
+		}
+	}()
+
+	var ackFns []input.AsyncAckFn
+	for i := 0; i < 3; i++ {
+		_, aFn, err := pres.ReadBatch(ctx)
+		require.NoError(t, err)
+		ackFns = append(ackFns, aFn)
+	}
+
+	// Fail all batches, where only the first is held in memory.
+	for _, aFn := range ackFns {
+		require.NoError(t, aFn(ctx, errors.New("failed")))
+	}
+
+	spillFiles, err := filepath.Glob(filepath.Join(spillDir, "*"))
+	require.NoError(t, err)
+	require.Len(t, spillFiles, 1)
+
+	info, err := os.Stat(spillFiles[0])
+	require.NoError(t, err)
+	assert.Greater(t, info.Size(), int64(0))
+
+	expected := [][][]byte{
+		{[]byte("msg 1")},
+		{[]byte("msg 2a"), []byte("msg 2b")},
+		{[]byte("msg 3")},
+	}
+
+	for i, exp := range expected {
+		msg, _, err := pres.ReadBatch(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, exp, message.GetAllBytes(msg), i)
+		if i == 1 {
+			assert.Equal(t, "bar", msg.Get(1).MetaGet("foo"))
+		}
+	}
+
+	// Once all spilled batches are read back the file is truncated.
+	info, err = os.Stat(spillFiles[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	close(readerImpl.unblockCloseAsyncChan)
+	go func() {
+		readerImpl.waitForCloseChan <- nil
+	}()
+	require.NoError(t, pres.Close(ctx))
+
+	_, err = os.Stat(spillFiles[0])
+	assert.True(t, os.IsNotExist(err))
+}
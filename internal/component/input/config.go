@@ -50,6 +50,8 @@ type Config struct {
 	STDIN             STDINConfig             `json:"stdin" yaml:"stdin"`
 	Subprocess        SubprocessConfig        `json:"subprocess" yaml:"subprocess"`
 	Websocket         WebsocketConfig         `json:"websocket" yaml:"websocket"`
+	Singleton         bool                    `json:"singleton,omitempty" yaml:"singleton,omitempty"`
+	DetectFormats     []string                `json:"detect_formats,omitempty" yaml:"detect_formats,omitempty"`
 	Processors        []processor.Config      `json:"processors" yaml:"processors"`
 }
 
@@ -97,6 +99,8 @@ func NewConfig() Config {
 		STDIN:             NewSTDINConfig(),
 		Subprocess:        NewSubprocessConfig(),
 		Websocket:         NewWebsocketConfig(),
+		Singleton:         false,
+		DetectFormats:     []string{},
 		Processors:        []processor.Config{},
 	}
 }
@@ -27,17 +27,34 @@ func NewDynamoDBCheckpointConfig() DynamoDBCheckpointConfig {
 	}
 }
 
+// AWSKinesisEnhancedFanOutConfig contains configuration parameters for
+// consuming Kinesis shards with enhanced fan-out subscriptions.
+type AWSKinesisEnhancedFanOutConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	ConsumerName string `json:"consumer_name" yaml:"consumer_name"`
+}
+
+// NewAWSKinesisEnhancedFanOutConfig returns an AWSKinesisEnhancedFanOutConfig
+// struct with default values.
+func NewAWSKinesisEnhancedFanOutConfig() AWSKinesisEnhancedFanOutConfig {
+	return AWSKinesisEnhancedFanOutConfig{
+		Enabled:      false,
+		ConsumerName: "",
+	}
+}
+
 // AWSKinesisConfig is configuration values for the input type.
 type AWSKinesisConfig struct {
 	session.Config  `json:",inline" yaml:",inline"`
-	Streams         []string                 `json:"streams" yaml:"streams"`
-	DynamoDB        DynamoDBCheckpointConfig `json:"dynamodb" yaml:"dynamodb"`
-	CheckpointLimit int                      `json:"checkpoint_limit" yaml:"checkpoint_limit"`
-	CommitPeriod    string                   `json:"commit_period" yaml:"commit_period"`
-	LeasePeriod     string                   `json:"lease_period" yaml:"lease_period"`
-	RebalancePeriod string                   `json:"rebalance_period" yaml:"rebalance_period"`
-	StartFromOldest bool                     `json:"start_from_oldest" yaml:"start_from_oldest"`
-	Batching        batchconfig.Config       `json:"batching" yaml:"batching"`
+	Streams         []string                       `json:"streams" yaml:"streams"`
+	DynamoDB        DynamoDBCheckpointConfig       `json:"dynamodb" yaml:"dynamodb"`
+	CheckpointLimit int                            `json:"checkpoint_limit" yaml:"checkpoint_limit"`
+	CommitPeriod    string                         `json:"commit_period" yaml:"commit_period"`
+	LeasePeriod     string                         `json:"lease_period" yaml:"lease_period"`
+	RebalancePeriod string                         `json:"rebalance_period" yaml:"rebalance_period"`
+	StartFromOldest bool                           `json:"start_from_oldest" yaml:"start_from_oldest"`
+	EnhancedFanOut  AWSKinesisEnhancedFanOutConfig `json:"enhanced_fan_out" yaml:"enhanced_fan_out"`
+	Batching        batchconfig.Config             `json:"batching" yaml:"batching"`
 }
 
 // NewAWSKinesisConfig creates a new Config with default values.
@@ -51,6 +68,7 @@ func NewAWSKinesisConfig() AWSKinesisConfig {
 		LeasePeriod:     "30s",
 		RebalancePeriod: "30s",
 		StartFromOldest: true,
+		EnhancedFanOut:  NewAWSKinesisEnhancedFanOutConfig(),
 		Batching:        batchconfig.NewConfig(),
 	}
 }
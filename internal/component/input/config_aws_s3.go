@@ -2,6 +2,7 @@ package input
 
 import (
 	sess "github.com/benthosdev/benthos/v4/internal/impl/aws/session"
+	"github.com/benthosdev/benthos/v4/internal/partition"
 )
 
 // AWSS3SQSConfig contains configuration for hooking up the S3 input with an SQS queue.
@@ -31,12 +32,13 @@ func NewAWSS3SQSConfig() AWSS3SQSConfig {
 // AWSS3Config contains configuration values for the aws_s3 input type.
 type AWSS3Config struct {
 	sess.Config        `json:",inline" yaml:",inline"`
-	Bucket             string         `json:"bucket" yaml:"bucket"`
-	Codec              string         `json:"codec" yaml:"codec"`
-	Prefix             string         `json:"prefix" yaml:"prefix"`
-	ForcePathStyleURLs bool           `json:"force_path_style_urls" yaml:"force_path_style_urls"`
-	DeleteObjects      bool           `json:"delete_objects" yaml:"delete_objects"`
-	SQS                AWSS3SQSConfig `json:"sqs" yaml:"sqs"`
+	Bucket             string           `json:"bucket" yaml:"bucket"`
+	Codec              string           `json:"codec" yaml:"codec"`
+	Prefix             string           `json:"prefix" yaml:"prefix"`
+	ForcePathStyleURLs bool             `json:"force_path_style_urls" yaml:"force_path_style_urls"`
+	DeleteObjects      bool             `json:"delete_objects" yaml:"delete_objects"`
+	SQS                AWSS3SQSConfig   `json:"sqs" yaml:"sqs"`
+	Partitioning       partition.Config `json:"partitioning" yaml:"partitioning"`
 }
 
 // NewAWSS3Config creates a new AWSS3Config with default values.
@@ -49,5 +51,6 @@ func NewAWSS3Config() AWSS3Config {
 		ForcePathStyleURLs: false,
 		DeleteObjects:      false,
 		SQS:                NewAWSS3SQSConfig(),
+		Partitioning:       partition.NewConfig(),
 	}
 }
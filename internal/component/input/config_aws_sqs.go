@@ -12,6 +12,8 @@ type AWSSQSConfig struct {
 	DeleteMessage       bool   `json:"delete_message" yaml:"delete_message"`
 	ResetVisibility     bool   `json:"reset_visibility" yaml:"reset_visibility"`
 	MaxNumberOfMessages int    `json:"max_number_of_messages" yaml:"max_number_of_messages"`
+	MessageTimeout      string `json:"message_timeout" yaml:"message_timeout"`
+	FIFOOrdering        bool   `json:"fifo_ordering" yaml:"fifo_ordering"`
 }
 
 // NewAWSSQSConfig creates a new Config with default values.
@@ -23,5 +25,7 @@ func NewAWSSQSConfig() AWSSQSConfig {
 		DeleteMessage:       true,
 		ResetVisibility:     true,
 		MaxNumberOfMessages: 10,
+		MessageTimeout:      "",
+		FIFOOrdering:        false,
 	}
 }
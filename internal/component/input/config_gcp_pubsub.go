@@ -7,6 +7,7 @@ type GCPPubSubConfig struct {
 	MaxOutstandingMessages int    `json:"max_outstanding_messages" yaml:"max_outstanding_messages"`
 	MaxOutstandingBytes    int    `json:"max_outstanding_bytes" yaml:"max_outstanding_bytes"`
 	Sync                   bool   `json:"sync" yaml:"sync"`
+	ExactlyOnceDelivery    bool   `json:"exactly_once_delivery" yaml:"exactly_once_delivery"`
 }
 
 // NewGCPPubSubConfig creates a new Config with default values.
@@ -17,5 +18,6 @@ func NewGCPPubSubConfig() GCPPubSubConfig {
 		MaxOutstandingMessages: 1000, // pubsub.DefaultReceiveSettings.MaxOutstandingMessages
 		MaxOutstandingBytes:    1e9,  // pubsub.DefaultReceiveSettings.MaxOutstandingBytes (1G)
 		Sync:                   false,
+		ExactlyOnceDelivery:    false,
 	}
 }
@@ -1,6 +1,9 @@
 package input
 
-import "github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+import (
+	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+	"github.com/benthosdev/benthos/v4/internal/pagination"
+)
 
 // StreamConfig contains fields for specifying consumption behaviour when the
 // body of a request is a constant stream of bytes.
@@ -14,9 +17,10 @@ type StreamConfig struct {
 // HTTPClientConfig contains configuration for the HTTPClient output type.
 type HTTPClientConfig struct {
 	oldconfig.OldConfig `json:",inline" yaml:",inline"`
-	Payload             string       `json:"payload" yaml:"payload"`
-	DropEmptyBodies     bool         `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
-	Stream              StreamConfig `json:"stream" yaml:"stream"`
+	Payload             string            `json:"payload" yaml:"payload"`
+	DropEmptyBodies     bool              `json:"drop_empty_bodies" yaml:"drop_empty_bodies"`
+	Stream              StreamConfig      `json:"stream" yaml:"stream"`
+	Pagination          pagination.Config `json:"pagination" yaml:"pagination"`
 }
 
 // NewHTTPClientConfig creates a new HTTPClientConfig with default values.
@@ -34,5 +38,6 @@ func NewHTTPClientConfig() HTTPClientConfig {
 			Codec:     "lines",
 			MaxBuffer: 1000000,
 		},
+		Pagination: pagination.NewConfig(),
 	}
 }
@@ -11,6 +11,7 @@ type HTTPServerResponseConfig struct {
 	Status          string                       `json:"status" yaml:"status"`
 	Headers         map[string]string            `json:"headers" yaml:"headers"`
 	ExtractMetadata metadata.IncludeFilterConfig `json:"metadata_headers" yaml:"metadata_headers"`
+	Streaming       string                       `json:"streaming" yaml:"streaming"`
 }
 
 // NewHTTPServerResponseConfig creates a new HTTPServerConfig with default values.
@@ -21,6 +22,7 @@ func NewHTTPServerResponseConfig() HTTPServerResponseConfig {
 			"Content-Type": "application/octet-stream",
 		},
 		ExtractMetadata: metadata.NewIncludeFilterConfig(),
+		Streaming:       "none",
 	}
 }
 
@@ -37,6 +39,7 @@ type HTTPServerConfig struct {
 	CertFile           string                   `json:"cert_file" yaml:"cert_file"`
 	KeyFile            string                   `json:"key_file" yaml:"key_file"`
 	CORS               httpserver.CORSConfig    `json:"cors" yaml:"cors"`
+	Auth               httpserver.AuthConfig    `json:"auth" yaml:"auth"`
 	Response           HTTPServerResponseConfig `json:"sync_response" yaml:"sync_response"`
 }
 
@@ -56,6 +59,7 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		CertFile:  "",
 		KeyFile:   "",
 		CORS:      httpserver.NewServerCORSConfig(),
+		Auth:      httpserver.NewAuthConfig(),
 		Response:  NewHTTPServerResponseConfig(),
 	}
 }
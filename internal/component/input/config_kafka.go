@@ -41,6 +41,7 @@ type KafkaConfig struct {
 	TLS                 btls.Config              `json:"tls" yaml:"tls"`
 	SASL                sasl.Config              `json:"sasl" yaml:"sasl"`
 	Batching            batchconfig.Config       `json:"batching" yaml:"batching"`
+	Redelivery          RedeliveryConfig         `json:"redelivery" yaml:"redelivery"`
 }
 
 // NewKafkaConfig creates a new KafkaConfig with default values.
@@ -61,5 +62,6 @@ func NewKafkaConfig() KafkaConfig {
 		TLS:                 btls.NewConfig(),
 		SASL:                sasl.NewConfig(),
 		Batching:            batchconfig.NewConfig(),
+		Redelivery:          NewRedeliveryConfig(),
 	}
 }
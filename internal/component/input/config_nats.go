@@ -7,12 +7,13 @@ import (
 
 // NATSConfig contains configuration fields for the NATS input type.
 type NATSConfig struct {
-	URLs          []string    `json:"urls" yaml:"urls"`
-	Subject       string      `json:"subject" yaml:"subject"`
-	QueueID       string      `json:"queue" yaml:"queue"`
-	PrefetchCount int         `json:"prefetch_count" yaml:"prefetch_count"`
-	TLS           btls.Config `json:"tls" yaml:"tls"`
-	Auth          auth.Config `json:"auth" yaml:"auth"`
+	URLs          []string         `json:"urls" yaml:"urls"`
+	Subject       string           `json:"subject" yaml:"subject"`
+	QueueID       string           `json:"queue" yaml:"queue"`
+	PrefetchCount int              `json:"prefetch_count" yaml:"prefetch_count"`
+	TLS           btls.Config      `json:"tls" yaml:"tls"`
+	Auth          auth.Config      `json:"auth" yaml:"auth"`
+	Redelivery    RedeliveryConfig `json:"redelivery" yaml:"redelivery"`
 }
 
 // NewNATSConfig creates a new NATSConfig with default values.
@@ -24,5 +25,6 @@ func NewNATSConfig() NATSConfig {
 		PrefetchCount: 32,
 		TLS:           btls.NewConfig(),
 		Auth:          auth.New(),
+		Redelivery:    NewRedeliveryConfig(),
 	}
 }
@@ -1,12 +1,14 @@
 package processors
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/pipeline"
 )
 
@@ -14,16 +16,23 @@ import (
 // returns a new slice of them where the processors of the provided input
 // configuration will also be initialized.
 func AppendFromConfig(conf input.Config, mgr bundle.NewManagement, pipelines ...processor.PipelineConstructorFunc) []processor.PipelineConstructorFunc {
-	if len(conf.Processors) > 0 {
+	if len(conf.Processors) > 0 || len(conf.DetectFormats) > 0 {
 		pipelines = append([]processor.PipelineConstructorFunc{func() (processor.Pipeline, error) {
-			processors := make([]processor.V1, len(conf.Processors))
+			processors := make([]processor.V1, 0, len(conf.Processors)+1)
+			if len(conf.DetectFormats) > 0 {
+				formats, err := message.GetStructuredFormats(conf.DetectFormats...)
+				if err != nil {
+					return nil, fmt.Errorf("failed to enable format detection: %w", err)
+				}
+				processors = append(processors, &formatDetector{formats: formats})
+			}
 			for j, procConf := range conf.Processors {
 				newMgr := mgr.IntoPath("processors", strconv.Itoa(j))
-				var err error
-				processors[j], err = newMgr.NewProcessor(procConf)
+				proc, err := newMgr.NewProcessor(procConf)
 				if err != nil {
 					return nil, fmt.Errorf("failed to create processor '%v': %v", procConf.Type, err)
 				}
+				processors = append(processors, proc)
 			}
 			return pipeline.NewProcessor(processors...), nil
 		}}, pipelines...)
@@ -31,6 +40,23 @@ func AppendFromConfig(conf input.Config, mgr bundle.NewManagement, pipelines ...
 	return pipelines
 }
 
+// formatDetector enables the detection of structured formats on messages
+// before they reach the processors of an input.
+type formatDetector struct {
+	formats []message.StructuredFormat
+}
+
+func (f *formatDetector) ProcessBatch(ctx context.Context, b message.Batch) ([]message.Batch, error) {
+	for _, p := range b {
+		p.SetStructuredFormats(f.formats)
+	}
+	return []message.Batch{b}, nil
+}
+
+func (f *formatDetector) Close(ctx context.Context) error {
+	return nil
+}
+
 // WrapConstructor provides a way to define an input constructor without
 // manually initializing processors of the config.
 func WrapConstructor(fn func(input.Config, bundle.NewManagement) (input.Streamed, error)) bundle.InputConstructor {
@@ -0,0 +1,71 @@
+package processors_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/component/input/processors"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestAppendFromConfigDetectFormats(t *testing.T) {
+	message.RegisterStructuredFormat(message.StructuredFormat{
+		Name: "append_test_format",
+		Detect: func(b []byte) bool {
+			return b[0] == '~'
+		},
+		Parse: func(b []byte) (any, error) {
+			return string(b[1:]), nil
+		},
+	})
+
+	conf := input.NewConfig()
+	conf.DetectFormats = []string{"nope"}
+
+	pcf := processors.AppendFromConfig(conf, mock.NewManager())
+	require.Len(t, pcf, 1)
+	_, err := pcf[0]()
+	require.EqualError(t, err, "failed to enable format detection: format 'nope' was not recognised")
+
+	conf.DetectFormats = []string{"append_test_format"}
+
+	pcf = processors.AppendFromConfig(conf, mock.NewManager())
+	require.Len(t, pcf, 1)
+	pipe, err := pcf[0]()
+	require.NoError(t, err)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, pipe.Consume(tChan))
+	t.Cleanup(func() {
+		close(tChan)
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, pipe.WaitForClose(ctx))
+	})
+
+	resChan := make(chan error, 1)
+	select {
+	case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("~foo")}), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	var tran message.Transaction
+	select {
+	case tran = <-pipe.TransactionChan():
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+
+	v, err := tran.Payload.Get(0).AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+	require.NoError(t, tran.Ack(context.Background(), nil))
+	require.NoError(t, <-resChan)
+}
@@ -0,0 +1,206 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/leader"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+// ErrLeadershipLost is returned to transactions that were read by a singleton
+// input but not yet delivered when its instance lost leadership.
+var ErrLeadershipLost = errors.New("leadership was lost")
+
+// The period of time to wait after a failure to campaign for leadership or to
+// create an input before trying again.
+var singletonRetryPeriod = time.Second
+
+// Singleton is an input that only consumes whilst its instance is the leader of
+// an election, which ensures that amongst multiple instances of Benthos
+// running the same config only one of them is consuming at any given time. The
+// underlying input is created when leadership is acquired and closed when it
+// is lost, in which case another instance takes over.
+type Singleton struct {
+	ctor     func() (Streamed, error)
+	election leader.Election
+	log      log.Modular
+
+	currentMut sync.Mutex
+	current    Streamed
+
+	transactions chan message.Transaction
+	shutSig      *shutdown.Signaller
+}
+
+// NewSingleton creates a new singleton input, where the provided constructor is
+// called each time leadership is acquired. The election is closed along with
+// the input.
+func NewSingleton(ctor func() (Streamed, error), election leader.Election, logger log.Modular) *Singleton {
+	s := &Singleton{
+		ctor:         ctor,
+		election:     election,
+		log:          logger,
+		transactions: make(chan message.Transaction),
+		shutSig:      shutdown.NewSignaller(),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *Singleton) loop() {
+	defer func() {
+		closeCtx, done := s.shutSig.CloseNowCtx(context.Background())
+		if err := s.election.Close(closeCtx); err != nil {
+			s.log.Errorf("Failed to resign leadership: %v\n", err)
+		}
+		done()
+
+		close(s.transactions)
+		s.shutSig.ShutdownComplete()
+	}()
+
+	ctx, done := s.shutSig.CloseAtLeisureCtx(context.Background())
+	defer done()
+
+	for {
+		lost, err := s.election.Campaign(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.log.Errorf("Failed to campaign for leadership: %v\n", err)
+			select {
+			case <-time.After(singletonRetryPeriod):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		s.log.Infoln("Acquired leadership, starting input")
+		if finished := s.lead(ctx, lost); finished {
+			return
+		}
+	}
+}
+
+// lead runs the input until either leadership is lost, the input ends or the
+// singleton is closed, and returns true in the latter two cases.
+func (s *Singleton) lead(ctx context.Context, lost <-chan struct{}) bool {
+	in, err := s.ctor()
+	if err != nil {
+		s.log.Errorf("Failed to create input: %v\n", err)
+		if err := s.election.Resign(ctx); err != nil {
+			s.log.Errorf("Failed to resign leadership: %v\n", err)
+		}
+		select {
+		case <-time.After(singletonRetryPeriod):
+		case <-ctx.Done():
+			return true
+		}
+		return false
+	}
+
+	s.currentMut.Lock()
+	s.current = in
+	s.currentMut.Unlock()
+
+	defer func() {
+		s.currentMut.Lock()
+		s.current = nil
+		s.currentMut.Unlock()
+	}()
+
+	for {
+		var tran message.Transaction
+		var open bool
+		select {
+		case tran, open = <-in.TransactionChan():
+			if !open {
+				return true
+			}
+		case <-lost:
+			s.log.Warnln("Lost leadership, stopping input")
+			s.stop(in, false)
+			return false
+		case <-ctx.Done():
+			s.stop(in, true)
+			return true
+		}
+
+		select {
+		case s.transactions <- tran:
+		case <-lost:
+			s.log.Warnln("Lost leadership, stopping input")
+			_ = tran.Ack(context.Background(), ErrLeadershipLost)
+			s.stop(in, false)
+			return false
+		case <-ctx.Done():
+			_ = tran.Ack(context.Background(), ErrLeadershipLost)
+			s.stop(in, true)
+			return true
+		}
+	}
+}
+
+// stop closes an input, either gracefully when the singleton is closing or
+// immediately when leadership is lost, as another instance might already be
+// consuming.
+func (s *Singleton) stop(in Streamed, graceful bool) {
+	if graceful {
+		in.TriggerStopConsuming()
+	} else {
+		in.TriggerCloseNow()
+	}
+
+	closeNowCtx, done := s.shutSig.CloseNowCtx(context.Background())
+	defer done()
+
+	if err := in.WaitForClose(closeNowCtx); err != nil {
+		in.TriggerCloseNow()
+		_ = in.WaitForClose(context.Background())
+	}
+}
+
+// TransactionChan returns a transactions channel for consuming messages from
+// this input type.
+func (s *Singleton) TransactionChan() <-chan message.Transaction {
+	return s.transactions
+}
+
+// Connected returns a boolean indicating whether this instance is the leader
+// and its input is connected to its target.
+func (s *Singleton) Connected() bool {
+	s.currentMut.Lock()
+	defer s.currentMut.Unlock()
+	return s.current != nil && s.current.Connected()
+}
+
+// TriggerStopConsuming instructs the input to start shutting down resources
+// once all pending messages are delivered and acknowledged. This call does
+// not block.
+func (s *Singleton) TriggerStopConsuming() {
+	s.shutSig.CloseAtLeisure()
+}
+
+// TriggerCloseNow triggers the shut down of this component but should not
+// block the calling goroutine.
+func (s *Singleton) TriggerCloseNow() {
+	s.shutSig.CloseNow()
+}
+
+// WaitForClose is a blocking call to wait until the component has finished
+// shutting down and cleaning up resources.
+func (s *Singleton) WaitForClose(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.shutSig.HasClosedChan():
+	}
+	return nil
+}
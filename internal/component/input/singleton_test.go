@@ -0,0 +1,148 @@
+package input_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type mockElection struct {
+	grants chan chan struct{}
+
+	mut     sync.Mutex
+	resigns int
+	closed  bool
+}
+
+func newMockElection() *mockElection {
+	return &mockElection{grants: make(chan chan struct{})}
+}
+
+func (e *mockElection) Campaign(ctx context.Context) (<-chan struct{}, error) {
+	select {
+	case lost := <-e.grants:
+		return lost, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (e *mockElection) Resign(ctx context.Context) error {
+	e.mut.Lock()
+	e.resigns++
+	e.mut.Unlock()
+	return nil
+}
+
+func (e *mockElection) Close(ctx context.Context) error {
+	e.mut.Lock()
+	e.closed = true
+	e.mut.Unlock()
+	return nil
+}
+
+func (e *mockElection) isClosed() bool {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	return e.closed
+}
+
+func TestSingletonFailover(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	election := newMockElection()
+	inputs := make(chan *mock.Input, 2)
+
+	s := input.NewSingleton(func() (input.Streamed, error) {
+		in := &mock.Input{TChan: make(chan message.Transaction)}
+		inputs <- in
+		return in, nil
+	}, election, log.Noop())
+
+	// Nothing is consumed until leadership is acquired.
+	assert.False(t, s.Connected())
+	select {
+	case <-inputs:
+		t.Fatal("input created before leadership was acquired")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	sendAndReceive := func(in *mock.Input, content string) {
+		t.Helper()
+
+		resChan := make(chan error, 1)
+		select {
+		case in.TChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte(content)}), resChan):
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+
+		select {
+		case tran := <-s.TransactionChan():
+			assert.Equal(t, content, string(tran.Payload.Get(0).AsBytes()))
+			require.NoError(t, tran.Ack(ctx, nil))
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+		require.NoError(t, <-resChan)
+	}
+
+	lost := make(chan struct{})
+	election.grants <- lost
+
+	first := <-inputs
+	sendAndReceive(first, "foo")
+	assert.True(t, s.Connected())
+
+	// Losing leadership closes the input, which is created again once
+	// leadership is regained.
+	close(lost)
+	select {
+	case _, open := <-first.TChan:
+		assert.False(t, open)
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	election.grants <- make(chan struct{})
+
+	second := <-inputs
+	sendAndReceive(second, "bar")
+
+	s.TriggerStopConsuming()
+	require.NoError(t, s.WaitForClose(ctx))
+
+	_, open := <-s.TransactionChan()
+	assert.False(t, open)
+	assert.True(t, election.isClosed())
+}
+
+func TestSingletonInputEnds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	election := newMockElection()
+	in := &mock.Input{TChan: make(chan message.Transaction)}
+
+	s := input.NewSingleton(func() (input.Streamed, error) {
+		return in, nil
+	}, election, log.Noop())
+
+	election.grants <- make(chan struct{})
+	in.TriggerStopConsuming()
+
+	require.NoError(t, s.WaitForClose(ctx))
+	_, open := <-s.TransactionChan()
+	assert.False(t, open)
+	assert.True(t, election.isClosed())
+}
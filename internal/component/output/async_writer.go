@@ -10,6 +10,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -48,6 +49,7 @@ type AsyncWriter struct {
 	writer      AsyncSink
 
 	injectTracingMap *mapping.Executor
+	scaler           *autoscale.Scaler
 
 	log    log.Modular
 	stats  metrics.Type
@@ -79,6 +81,18 @@ func (w *AsyncWriter) SetInjectTracingMap(exec *mapping.Executor) {
 	w.injectTracingMap = exec
 }
 
+// SetAutoscale enables the adjustment of the number of messages in flight
+// within the bounds of an autoscale config, starting from the max in flight of
+// the writer.
+func (w *AsyncWriter) SetAutoscale(conf autoscale.Config) error {
+	scaler, err := autoscale.New(conf, w.maxInflight, w.stats)
+	if err != nil {
+		return err
+	}
+	w.scaler = scaler
+	return nil
+}
+
 //------------------------------------------------------------------------------
 
 func (w *AsyncWriter) latencyMeasuringWrite(ctx context.Context, msg message.Batch) (latencyNs int64, err error) {
@@ -125,6 +139,7 @@ func (w *AsyncWriter) loop() {
 		mConn       = w.stats.GetCounter("output_connection_up")
 		mFailedConn = w.stats.GetCounter("output_connection_failed")
 		mLostConn   = w.stats.GetCounter("output_connection_lost")
+		mExpired    = w.stats.GetCounter("output_expired")
 
 		traceName = "output_" + w.typeStr
 	)
@@ -169,8 +184,17 @@ func (w *AsyncWriter) loop() {
 	mConn.Incr(1)
 	atomic.StoreInt32(&w.isConnected, 1)
 
+	writers := w.maxInflight
+	if w.scaler != nil {
+		writers = w.scaler.Max()
+
+		stopScaling := make(chan struct{})
+		defer close(stopScaling)
+		go w.scaler.Loop(stopScaling)
+	}
+
 	wg := sync.WaitGroup{}
-	wg.Add(w.maxInflight)
+	wg.Add(writers)
 
 	connectMut := sync.Mutex{}
 	connectLoop := func(msg message.Batch) (latency int64, err error) {
@@ -206,33 +230,57 @@ func (w *AsyncWriter) loop() {
 		}
 	}
 
-	writerLoop := func() {
+	writerLoop := func(index int) {
 		defer wg.Done()
 
 		for {
+			if w.scaler != nil && !w.scaler.WaitActive(index, w.shutSig.CloseAtLeisureChan()) {
+				return
+			}
+
 			var ts message.Transaction
 			var open bool
 			select {
 			case ts, open = <-w.transactions:
 				if !open {
+					if w.scaler != nil {
+						w.scaler.Release()
+					}
 					return
 				}
 			case <-w.shutSig.CloseAtLeisureChan():
 				return
 			}
 
-			w.log.Tracef("Attempting to write %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
-			_, spans := tracing.WithChildSpans(w.tracer, traceName, ts.Payload)
-			w.injectSpans(ts.Payload, spans)
+			// Messages that have expired are dropped rather than delivered.
+			payload, liveIndexes := message.WithoutExpired(ts.Payload, time.Now())
+			if liveIndexes != nil {
+				w.log.Debugf("Dropping %v expired messages.\n", ts.Payload.Len()-payload.Len())
+				mExpired.Incr(int64(ts.Payload.Len() - payload.Len()))
+				if payload.Len() == 0 {
+					_ = ts.Ack(closeLeisureCtx, nil)
+					continue
+				}
+			}
 
-			latency, err := w.latencyMeasuringWrite(closeLeisureCtx, ts.Payload)
+			w.log.Tracef("Attempting to write %v messages to '%v'.\n", payload.Len(), w.typeStr)
+			_, spans := tracing.WithChildSpans(w.tracer, traceName, payload)
+			w.injectSpans(payload, spans)
+
+			latency, err := w.latencyMeasuringWrite(closeLeisureCtx, payload)
+			if w.scaler != nil {
+				w.scaler.Record(time.Duration(latency))
+			}
 
 			// If our writer says it is not connected.
 			if errors.Is(err, component.ErrNotConnected) {
-				latency, err = connectLoop(ts.Payload)
+				latency, err = connectLoop(payload)
 			} else if err != nil {
 				mError.Incr(1)
 			}
+			if err != nil && liveIndexes != nil {
+				err = reindexBatchError(ts.Payload, liveIndexes, err)
+			}
 
 			// Close immediately if our writer is closed.
 			if errors.Is(err, component.ErrTypeClosed) {
@@ -249,9 +297,9 @@ func (w *AsyncWriter) loop() {
 				}
 			} else {
 				mBatchSent.Incr(1)
-				mSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
+				mSent.Incr(int64(batch.MessageCollapsedCount(payload)))
 				mLatency.Timing(latency)
-				w.log.Tracef("Successfully wrote %v messages to '%v'.\n", ts.Payload.Len(), w.typeStr)
+				w.log.Tracef("Successfully wrote %v messages to '%v'.\n", payload.Len(), w.typeStr)
 			}
 
 			for _, s := range spans {
@@ -262,12 +310,29 @@ func (w *AsyncWriter) loop() {
 		}
 	}
 
-	for i := 0; i < w.maxInflight; i++ {
-		go writerLoop()
+	for i := 0; i < writers; i++ {
+		go writerLoop(i)
 	}
 	wg.Wait()
 }
 
+// reindexBatchError converts the indexed errors of a write of the messages of a
+// batch that had not expired into indexed errors of the original batch.
+func reindexBatchError(msg message.Batch, liveIndexes []int, err error) error {
+	var bErr batch.WalkableError
+	if !errors.As(err, &bErr) || bErr.IndexedErrors() == 0 {
+		return err
+	}
+	newErr := batch.NewError(msg, err)
+	bErr.WalkParts(func(i int, _ *message.Part, pErr error) bool {
+		if pErr != nil {
+			newErr.Failed(liveIndexes[i], pErr)
+		}
+		return true
+	})
+	return newErr
+}
+
 // Consume assigns a messages channel for the output to read.
 func (w *AsyncWriter) Consume(ts <-chan message.Transaction) error {
 	if w.transactions != nil {
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/message"
 )
@@ -635,3 +637,155 @@ func TestAsyncWriterSadPath(t *testing.T) {
 		t.Errorf("Wrong message sent: %v != %v", act, exp)
 	}
 }
+
+func TestAsyncWriterExpired(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newAsyncMockWriter()
+
+	w, err := NewAsyncWriter("foo", 1, writerImpl, component.NoopObservability())
+	require.NoError(t, err)
+
+	msgChan := make(chan message.Transaction)
+	resChan := make(chan error)
+	require.NoError(t, w.Consume(msgChan))
+
+	past := time.Now().Add(-time.Second)
+
+	msg := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	msg[1] = message.WithExpiry(msg[1], past)
+	msg[2] = message.WithExpiry(msg[2], time.Now().Add(time.Hour))
+
+	go func() {
+		select {
+		case msgChan <- message.NewTransaction(msg, resChan):
+		case <-time.After(time.Second):
+			t.Error("Timed out")
+		}
+	}()
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	msgRcvd, exists := writerImpl.msgsRcvd.Load(uint64(1))
+	for !exists {
+		time.Sleep(time.Millisecond)
+		msgRcvd, exists = writerImpl.msgsRcvd.Load(uint64(1))
+	}
+	require.Equal(t, [][]byte{[]byte("foo"), []byte("baz")}, message.GetAllBytes(msgRcvd.(message.Batch)))
+
+	expErr := errors.New("baz failed")
+	select {
+	case writerImpl.writeChan <- batch.NewError(msgRcvd.(message.Batch), errors.New("nope")).Failed(1, expErr):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	select {
+	case res := <-resChan:
+		var bErr *batch.Error
+		require.ErrorAs(t, res, &bErr)
+		var failed []int
+		bErr.WalkParts(func(i int, _ *message.Part, err error) bool {
+			if err != nil {
+				require.Equal(t, expErr, err)
+				failed = append(failed, i)
+			}
+			return true
+		})
+		require.Equal(t, []int{2}, failed)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	// A batch where every message has expired is acknowledged without being
+	// written.
+	msg = message.QuickBatch([][]byte{[]byte("foo")})
+	msg[0] = message.WithExpiry(msg[0], past)
+	select {
+	case msgChan <- message.NewTransaction(msg, resChan):
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+	select {
+	case res := <-resChan:
+		require.NoError(t, res)
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	w.TriggerCloseNow()
+	require.NoError(t, w.WaitForClose(ctx))
+
+	_, exists = writerImpl.msgsRcvd.Load(uint64(2))
+	require.False(t, exists)
+}
+
+func TestAsyncWriterAutoscaleLimit(t *testing.T) {
+	t.Parallel()
+
+	writerImpl := newAsyncMockWriter()
+
+	w, err := NewAsyncWriter("foo", 1, writerImpl, component.NoopObservability())
+	require.NoError(t, err)
+
+	conf := autoscale.NewConfig()
+	conf.Enabled = true
+	conf.Max = 3
+	conf.Interval = "1h"
+	require.NoError(t, w.(*AsyncWriter).SetAutoscale(conf))
+
+	msgChan := make(chan message.Transaction)
+	resChan := make(chan error)
+	require.NoError(t, w.Consume(msgChan))
+
+	select {
+	case writerImpl.connChan <- nil:
+	case <-time.After(time.Second):
+		t.Fatal("Timed out")
+	}
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			select {
+			case msgChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("foo")}), resChan):
+			case <-time.After(time.Second):
+				t.Error("Timed out")
+			}
+		}()
+	}
+
+	// Only a single write is in flight until autoscaling adds writers.
+	require.Eventually(t, func() bool {
+		return atomic.LoadUint64(&writerImpl.msgsTotal) == 1
+	}, time.Second, time.Millisecond*10)
+	<-time.After(time.Millisecond * 50)
+	require.Equal(t, uint64(1), atomic.LoadUint64(&writerImpl.msgsTotal))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case writerImpl.writeChan <- nil:
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+		select {
+		case res := <-resChan:
+			require.NoError(t, res)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out")
+		}
+	}
+
+	// Inactive writers must also shut down once the input is closed.
+	close(msgChan)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+	require.NoError(t, w.WaitForClose(ctx))
+}
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"github.com/benthosdev/benthos/v4/internal/batch/policy/batchconfig"
 	"github.com/benthosdev/benthos/v4/internal/metadata"
 	btls "github.com/benthosdev/benthos/v4/internal/tls"
 )
@@ -31,6 +32,7 @@ type AMQPConfig struct {
 	Immediate       bool                         `json:"immediate" yaml:"immediate"`
 	TLS             btls.Config                  `json:"tls" yaml:"tls"`
 	Timeout         string                       `json:"timeout" yaml:"timeout"`
+	Batching        batchconfig.Config           `json:"batching" yaml:"batching"`
 }
 
 // NewAMQPConfig creates a new AMQPConfig with default values.
@@ -55,5 +57,6 @@ func NewAMQPConfig() AMQPConfig {
 		Immediate:       false,
 		TLS:             btls.NewConfig(),
 		Timeout:         "",
+		Batching:        batchconfig.NewConfig(),
 	}
 }
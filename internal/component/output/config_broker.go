@@ -1,6 +1,9 @@
 package output
 
-import "github.com/benthosdev/benthos/v4/internal/batch/policy/batchconfig"
+import (
+	"github.com/benthosdev/benthos/v4/internal/batch/policy/batchconfig"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
+)
 
 // BrokerConfig contains configuration fields for the Broker output type.
 type BrokerConfig struct {
@@ -8,6 +11,7 @@ type BrokerConfig struct {
 	Pattern  string             `json:"pattern" yaml:"pattern"`
 	Outputs  []Config           `json:"outputs" yaml:"outputs"`
 	Batching batchconfig.Config `json:"batching" yaml:"batching"`
+	InFlight inflight.Config    `json:"in_flight" yaml:"in_flight"`
 }
 
 // NewBrokerConfig creates a new BrokerConfig with default values.
@@ -17,5 +21,6 @@ func NewBrokerConfig() BrokerConfig {
 		Pattern:  "fan_out",
 		Outputs:  []Config{},
 		Batching: batchconfig.NewConfig(),
+		InFlight: inflight.NewConfig(),
 	}
 }
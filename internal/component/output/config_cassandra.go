@@ -23,6 +23,11 @@ type CassandraConfig struct {
 	Query                    string                `json:"query" yaml:"query"`
 	ArgsMapping              string                `json:"args_mapping" yaml:"args_mapping"`
 	Consistency              string                `json:"consistency" yaml:"consistency"`
+	SerialConsistency        string                `json:"serial_consistency" yaml:"serial_consistency"`
+	Idempotent               bool                  `json:"idempotent" yaml:"idempotent"`
+	TokenAware               bool                  `json:"token_aware" yaml:"token_aware"`
+	GroupByPartitionKey      bool                  `json:"group_by_partition_key" yaml:"group_by_partition_key"`
+	MaxPreparedStatements    int                   `json:"max_prepared_statements" yaml:"max_prepared_statements"`
 	Timeout                  string                `json:"timeout" yaml:"timeout"`
 	// TODO: V4 Remove this and replace with explicit values.
 	retries.Config `json:",inline" yaml:",inline"`
@@ -50,6 +55,11 @@ func NewCassandraConfig() CassandraConfig {
 		Query:                    "",
 		ArgsMapping:              "",
 		Consistency:              "QUORUM",
+		SerialConsistency:        "",
+		Idempotent:               false,
+		TokenAware:               true,
+		GroupByPartitionKey:      false,
+		MaxPreparedStatements:    1000,
 		Timeout:                  "600ms",
 		Config:                   rConf,
 		MaxInFlight:              64,
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/batch/policy/batchconfig"
 	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
 )
@@ -21,6 +22,7 @@ type HTTPClientConfig struct {
 	oldconfig.OldConfig `json:",inline" yaml:",inline"`
 	BatchAsMultipart    bool                            `json:"batch_as_multipart" yaml:"batch_as_multipart"`
 	MaxInFlight         int                             `json:"max_in_flight" yaml:"max_in_flight"`
+	Autoscale           autoscale.Config                `json:"autoscale" yaml:"autoscale"`
 	PropagateResponse   bool                            `json:"propagate_response" yaml:"propagate_response"`
 	Batching            batchconfig.Config              `json:"batching" yaml:"batching"`
 	Multipart           []HTTPClientMultipartExpression `json:"multipart" yaml:"multipart"`
@@ -32,6 +34,7 @@ func NewHTTPClientConfig() HTTPClientConfig {
 		OldConfig:         oldconfig.NewOldConfig(),
 		BatchAsMultipart:  false,
 		MaxInFlight:       64,
+		Autoscale:         autoscale.NewConfig(),
 		PropagateResponse: false,
 		Batching:          batchconfig.NewConfig(),
 	}
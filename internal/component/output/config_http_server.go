@@ -8,21 +8,26 @@ type HTTPServerConfig struct {
 	Address      string                `json:"address" yaml:"address"`
 	Path         string                `json:"path" yaml:"path"`
 	StreamPath   string                `json:"stream_path" yaml:"stream_path"`
+	StreamFormat string                `json:"stream_format" yaml:"stream_format"`
 	WSPath       string                `json:"ws_path" yaml:"ws_path"`
+	WSFilter     HTTPServerWSFilter    `json:"ws_filter" yaml:"ws_filter"`
 	AllowedVerbs []string              `json:"allowed_verbs" yaml:"allowed_verbs"`
 	Timeout      string                `json:"timeout" yaml:"timeout"`
 	CertFile     string                `json:"cert_file" yaml:"cert_file"`
 	KeyFile      string                `json:"key_file" yaml:"key_file"`
 	CORS         httpserver.CORSConfig `json:"cors" yaml:"cors"`
+	Auth         httpserver.AuthConfig `json:"auth" yaml:"auth"`
 }
 
 // NewHTTPServerConfig creates a new HTTPServerConfig with default values.
 func NewHTTPServerConfig() HTTPServerConfig {
 	return HTTPServerConfig{
-		Address:    "",
-		Path:       "/get",
-		StreamPath: "/get/stream",
-		WSPath:     "/get/ws",
+		Address:      "",
+		Path:         "/get",
+		StreamPath:   "/get/stream",
+		StreamFormat: "lines",
+		WSPath:       "/get/ws",
+		WSFilter:     NewHTTPServerWSFilter(),
 		AllowedVerbs: []string{
 			"GET",
 		},
@@ -30,5 +35,23 @@ func NewHTTPServerConfig() HTTPServerConfig {
 		CertFile: "",
 		KeyFile:  "",
 		CORS:     httpserver.NewServerCORSConfig(),
+		Auth:     httpserver.NewAuthConfig(),
+	}
+}
+
+// HTTPServerWSFilter contains configuration fields for the subscription
+// filters of websocket connections.
+type HTTPServerWSFilter struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	QueryParam    string `json:"query_param" yaml:"query_param"`
+	RequireFilter bool   `json:"require_filter" yaml:"require_filter"`
+}
+
+// NewHTTPServerWSFilter creates a new HTTPServerWSFilter with default values.
+func NewHTTPServerWSFilter() HTTPServerWSFilter {
+	return HTTPServerWSFilter{
+		Enabled:       false,
+		QueryParam:    "filter",
+		RequireFilter: false,
 	}
 }
@@ -12,6 +12,7 @@ import (
 type Config struct {
 	Label        string             `json:"label" yaml:"label"`
 	Type         string             `json:"type" yaml:"type"`
+	Execution    ExecutionConfig    `json:"execution" yaml:"execution"`
 	Avro         AvroConfig         `json:"avro" yaml:"avro"`
 	AWK          AWKConfig          `json:"awk" yaml:"awk"`
 	Bloblang     string             `json:"bloblang" yaml:"bloblang"`
@@ -60,6 +61,7 @@ func NewConfig() Config {
 	return Config{
 		Label:        "",
 		Type:         "bounds_check",
+		Execution:    NewExecutionConfig(),
 		Avro:         NewAvroConfig(),
 		AWK:          NewAWKConfig(),
 		Bloblang:     "",
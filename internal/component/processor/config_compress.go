@@ -2,14 +2,18 @@ package processor
 
 // CompressConfig contains configuration fields for the Compress processor.
 type CompressConfig struct {
-	Algorithm string `json:"algorithm" yaml:"algorithm"`
-	Level     int    `json:"level" yaml:"level"`
+	Algorithm      string `json:"algorithm" yaml:"algorithm"`
+	Level          int    `json:"level" yaml:"level"`
+	MessageLevel   string `json:"message_level" yaml:"message_level"`
+	DictionaryFile string `json:"dictionary_file" yaml:"dictionary_file"`
 }
 
 // NewCompressConfig returns a CompressConfig with default values.
 func NewCompressConfig() CompressConfig {
 	return CompressConfig{
-		Algorithm: "",
-		Level:     -1,
+		Algorithm:      "",
+		Level:          -1,
+		MessageLevel:   "",
+		DictionaryFile: "",
 	}
 }
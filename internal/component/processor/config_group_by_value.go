@@ -5,12 +5,20 @@ package processor
 // smaller size according to a function interpolated string evaluated per
 // message part.
 type GroupByValueConfig struct {
-	Value string `json:"value" yaml:"value"`
+	Value          string   `json:"value" yaml:"value"`
+	Values         []string `json:"values" yaml:"values"`
+	KeyMetadata    string   `json:"key_metadata" yaml:"key_metadata"`
+	MaxGroups      int      `json:"max_groups" yaml:"max_groups"`
+	OverflowPolicy string   `json:"overflow_policy" yaml:"overflow_policy"`
 }
 
 // NewGroupByValueConfig returns a GroupByValueConfig with default values.
 func NewGroupByValueConfig() GroupByValueConfig {
 	return GroupByValueConfig{
-		Value: "",
+		Value:          "",
+		Values:         []string{},
+		KeyMetadata:    "",
+		MaxGroups:      0,
+		OverflowPolicy: "flush",
 	}
 }
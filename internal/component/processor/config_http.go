@@ -1,11 +1,19 @@
 package processor
 
-import "github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+import (
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+	"github.com/benthosdev/benthos/v4/internal/pagination"
+)
 
 // HTTPConfig contains configuration fields for the HTTP processor.
 type HTTPConfig struct {
-	BatchAsMultipart    bool `json:"batch_as_multipart" yaml:"batch_as_multipart"`
-	Parallel            bool `json:"parallel" yaml:"parallel"`
+	BatchAsMultipart    bool                  `json:"batch_as_multipart" yaml:"batch_as_multipart"`
+	Parallel            bool                  `json:"parallel" yaml:"parallel"`
+	ResponseCodec       string                `json:"response_codec" yaml:"response_codec"`
+	Singleflight        bool                  `json:"singleflight" yaml:"singleflight"`
+	CircuitBreaker      circuitbreaker.Config `json:"circuit_breaker" yaml:"circuit_breaker"`
+	Pagination          pagination.Config     `json:"pagination" yaml:"pagination"`
 	oldconfig.OldConfig `json:",inline" yaml:",inline"`
 }
 
@@ -14,6 +22,10 @@ func NewHTTPConfig() HTTPConfig {
 	return HTTPConfig{
 		BatchAsMultipart: false,
 		Parallel:         false,
+		ResponseCodec:    "",
+		Singleflight:     false,
+		CircuitBreaker:   circuitbreaker.NewConfig(),
+		Pagination:       pagination.NewConfig(),
 		OldConfig:        oldconfig.NewOldConfig(),
 	}
 }
@@ -6,6 +6,7 @@ type WhileConfig struct {
 	AtLeastOnce bool     `json:"at_least_once" yaml:"at_least_once"`
 	MaxLoops    int      `json:"max_loops" yaml:"max_loops"`
 	Check       string   `json:"check" yaml:"check"`
+	StateMap    string   `json:"state_map" yaml:"state_map"`
 	Processors  []Config `json:"processors" yaml:"processors"`
 }
 
@@ -15,6 +16,7 @@ func NewWhileConfig() WhileConfig {
 		AtLeastOnce: false,
 		MaxLoops:    0,
 		Check:       "",
+		StateMap:    "",
 		Processors:  []Config{},
 	}
 }
@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// Execution modes supported by an execution policy.
+const (
+	ExecutionModeBatch = "batch"
+	ExecutionModePart  = "part"
+)
+
+// ExecutionConfig describes the execution policy of a processor, which
+// determines whether it is applied to batches as a whole or to each message
+// part individually.
+type ExecutionConfig struct {
+	Mode     string `json:"mode" yaml:"mode"`
+	Parallel int    `json:"parallel" yaml:"parallel"`
+}
+
+// NewExecutionConfig returns an ExecutionConfig with default values, where
+// processors are applied to batches as a whole.
+func NewExecutionConfig() ExecutionConfig {
+	return ExecutionConfig{
+		Mode:     ExecutionModeBatch,
+		Parallel: 0,
+	}
+}
+
+// WrapExecution returns a processor that applies the execution policy of a
+// config to a child processor. When the policy is the default the child is
+// returned unchanged.
+func WrapExecution(conf ExecutionConfig, child V1) (V1, error) {
+	switch conf.Mode {
+	case "", ExecutionModeBatch:
+		if conf.Parallel > 1 {
+			return nil, fmt.Errorf("execution parallel field requires mode %v", ExecutionModePart)
+		}
+		return child, nil
+	case ExecutionModePart:
+	default:
+		return nil, fmt.Errorf("execution mode not recognised: %v", conf.Mode)
+	}
+	if conf.Parallel < 0 {
+		return nil, fmt.Errorf("execution parallel field must be greater than or equal to zero, got %v", conf.Parallel)
+	}
+	parallel := conf.Parallel
+	if parallel == 0 {
+		parallel = 1
+	}
+	return &perPartProcessor{
+		child: child,
+		sem:   make(chan struct{}, parallel),
+	}, nil
+}
+
+type perPartProcessor struct {
+	child V1
+	sem   chan struct{}
+}
+
+func (p *perPartProcessor) ProcessBatch(ctx context.Context, b message.Batch) ([]message.Batch, error) {
+	return ExecutePerPart(ctx, p.sem, b, p.child.ProcessBatch)
+}
+
+// ExecutePerPart executes a function on each message part of a batch as a
+// batch of one, where the number of concurrent executions is bounded by the
+// capacity of a semaphore channel that may be shared between callers. The
+// results are re-assembled into a single batch in their original order.
+func ExecutePerPart(ctx context.Context, sem chan struct{}, b message.Batch, fn func(context.Context, message.Batch) ([]message.Batch, error)) ([]message.Batch, error) {
+	results := make([][]message.Batch, len(b))
+	errs := make([]error, len(b))
+
+	var wg sync.WaitGroup
+	for i, part := range b {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int, part *message.Part) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i], errs[i] = fn(ctx, message.Batch{part})
+		}(i, part)
+	}
+	wg.Wait()
+
+	resBatch := message.QuickBatch(nil)
+	for i, batches := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, rb := range batches {
+			resBatch = append(resBatch, rb...)
+		}
+	}
+	if len(resBatch) == 0 {
+		return nil, nil
+	}
+	return []message.Batch{resBatch}, nil
+}
+
+func (p *perPartProcessor) Close(ctx context.Context) error {
+	return p.child.Close(ctx)
+}
@@ -0,0 +1,118 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type batchSizeRecorder struct {
+	running    int32
+	maxRunning int32
+	sizesChan  chan int
+}
+
+func (p *batchSizeRecorder) ProcessBatch(ctx context.Context, msg message.Batch) ([]message.Batch, error) {
+	n := atomic.AddInt32(&p.running, 1)
+	defer atomic.AddInt32(&p.running, -1)
+	for {
+		m := atomic.LoadInt32(&p.maxRunning)
+		if n <= m || atomic.CompareAndSwapInt32(&p.maxRunning, m, n) {
+			break
+		}
+	}
+	<-time.After(time.Millisecond * 10)
+	p.sizesChan <- msg.Len()
+	if string(msg.Get(0).AsBytes()) == "drop" {
+		return nil, nil
+	}
+	if string(msg.Get(0).AsBytes()) == "fail" {
+		return nil, errors.New("nope")
+	}
+	return []message.Batch{msg}, nil
+}
+
+func (p *batchSizeRecorder) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestExecutionDefault(t *testing.T) {
+	child := &passthrough{}
+
+	proc, err := WrapExecution(NewExecutionConfig(), child)
+	require.NoError(t, err)
+	assert.Equal(t, child, proc)
+
+	conf := NewExecutionConfig()
+	conf.Parallel = 2
+	_, err = WrapExecution(conf, child)
+	require.Error(t, err)
+
+	conf.Mode = "nope"
+	_, err = WrapExecution(conf, child)
+	require.Error(t, err)
+}
+
+func TestExecutionPerPart(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	for _, parallel := range []int{0, 3} {
+		child := &batchSizeRecorder{sizesChan: make(chan int, 10)}
+
+		conf := NewExecutionConfig()
+		conf.Mode = ExecutionModePart
+		conf.Parallel = parallel
+
+		proc, err := WrapExecution(conf, child)
+		require.NoError(t, err)
+
+		msgs, err := proc.ProcessBatch(tCtx, message.QuickBatch([][]byte{
+			[]byte("a"), []byte("b"), []byte("drop"), []byte("c"), []byte("d"), []byte("e"),
+		}))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+
+		var results []string
+		for _, p := range msgs[0] {
+			results = append(results, string(p.AsBytes()))
+		}
+		assert.Equal(t, []string{"a", "b", "c", "d", "e"}, results)
+
+		close(child.sizesChan)
+		for s := range child.sizesChan {
+			assert.Equal(t, 1, s)
+		}
+
+		expMax := int32(parallel)
+		if expMax == 0 {
+			expMax = 1
+		}
+		assert.LessOrEqual(t, atomic.LoadInt32(&child.maxRunning), expMax)
+		require.NoError(t, proc.Close(tCtx))
+	}
+}
+
+func TestExecutionPerPartError(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf := NewExecutionConfig()
+	conf.Mode = ExecutionModePart
+	conf.Parallel = 2
+
+	proc, err := WrapExecution(conf, &batchSizeRecorder{sizesChan: make(chan int, 10)})
+	require.NoError(t, err)
+
+	_, err = proc.ProcessBatch(tCtx, message.QuickBatch([][]byte{
+		[]byte("a"), []byte("fail"), []byte("b"),
+	}))
+	require.Error(t, err)
+}
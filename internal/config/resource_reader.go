@@ -31,6 +31,7 @@ type resourceFileInfo struct {
 	outputs    map[string]*output.Config
 	caches     map[string]*cache.Config
 	rateLimits map[string]*ratelimit.Config
+	mappings   map[string]*manager.MappingConfig
 }
 
 func resInfoFromConfig(conf *manager.ResourceConfig) resourceFileInfo {
@@ -40,6 +41,7 @@ func resInfoFromConfig(conf *manager.ResourceConfig) resourceFileInfo {
 		outputs:    map[string]*output.Config{},
 		caches:     map[string]*cache.Config{},
 		rateLimits: map[string]*ratelimit.Config{},
+		mappings:   map[string]*manager.MappingConfig{},
 	}
 
 	// This is an unlikely race condition, see readMain for more info.
@@ -61,6 +63,9 @@ func resInfoFromConfig(conf *manager.ResourceConfig) resourceFileInfo {
 	for _, c := range conf.ResourceRateLimits {
 		resInfo.rateLimits[c.Label] = &c
 	}
+	for _, c := range conf.ResourceMappings {
+		resInfo.mappings[c.Label] = &c
+	}
 
 	return resInfo
 }
@@ -176,6 +181,13 @@ func (i *resourceFileInfo) applyChanges(mgr bundle.NewManagement) bool {
 	// WARNING: The order here is actually kind of important, we want to start
 	// with components that could be dependencies of other components. This is
 	// a "best attempt", so not all edge cases need to be accounted for.
+	for k, v := range i.mappings {
+		if err := mgr.StoreMapping(ctx, k, v.Mapping); err != nil {
+			mgr.Logger().Errorf("Failed to update resource %v: %v", k, err)
+			return false
+		}
+		mgr.Logger().Infof("Updated resource %v config from file.", k)
+	}
 	for k, v := range i.rateLimits {
 		if err := mgr.StoreRateLimit(ctx, k, *v); err != nil {
 			mgr.Logger().Errorf("Failed to update resource %v: %v", k, err)
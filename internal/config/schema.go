@@ -8,6 +8,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/profiling"
 	"github.com/benthosdev/benthos/v4/internal/stream"
 )
 
@@ -16,12 +17,14 @@ type Type struct {
 	HTTP                   api.Config `json:"http" yaml:"http"`
 	stream.Config          `json:",inline" yaml:",inline"`
 	manager.ResourceConfig `json:",inline" yaml:",inline"`
-	Logger                 log.Config     `json:"logger" yaml:"logger"`
-	Metrics                metrics.Config `json:"metrics" yaml:"metrics"`
-	Tracer                 tracer.Config  `json:"tracer" yaml:"tracer"`
-	SystemCloseDelay       string         `json:"shutdown_delay" yaml:"shutdown_delay"`
-	SystemCloseTimeout     string         `json:"shutdown_timeout" yaml:"shutdown_timeout"`
-	Tests                  []any          `json:"tests,omitempty" yaml:"tests,omitempty"`
+	Logger                 log.Config            `json:"logger" yaml:"logger"`
+	Metrics                metrics.Config        `json:"metrics" yaml:"metrics"`
+	Tracer                 tracer.Config         `json:"tracer" yaml:"tracer"`
+	Profiler               profiling.Config      `json:"profiler" yaml:"profiler"`
+	SystemCloseDelay       string                `json:"shutdown_delay" yaml:"shutdown_delay"`
+	SystemCloseTimeout     string                `json:"shutdown_timeout" yaml:"shutdown_timeout"`
+	ShutdownPhases         stream.ShutdownConfig `json:"shutdown_phases" yaml:"shutdown_phases"`
+	Tests                  []any                 `json:"tests,omitempty" yaml:"tests,omitempty"`
 }
 
 // New returns a new configuration with default values.
@@ -33,8 +36,10 @@ func New() Type {
 		Logger:             log.NewConfig(),
 		Metrics:            metrics.NewConfig(),
 		Tracer:             tracer.NewConfig(),
+		Profiler:           profiling.NewConfig(),
 		SystemCloseDelay:   "",
 		SystemCloseTimeout: "20s",
+		ShutdownPhases:     stream.NewShutdownConfig(),
 		Tests:              nil,
 	}
 }
@@ -45,8 +50,10 @@ var observabilityFields = docs.FieldSpecs{
 	docs.FieldObject("logger", "Describes how operational logs should be emitted.").WithChildren(log.Spec()...),
 	docs.FieldMetrics("metrics", "A mechanism for exporting metrics.").Optional(),
 	docs.FieldTracer("tracer", "A mechanism for exporting traces.").Optional(),
+	profiling.FieldSpec(),
 	docs.FieldString("shutdown_delay", "A period of time to wait for metrics and traces to be pulled or pushed from the process.").HasDefault("0s"),
 	docs.FieldString("shutdown_timeout", "The maximum period of time to wait for a clean shutdown. If this time is exceeded Benthos will forcefully close.").HasDefault("20s"),
+	stream.ShutdownFieldSpec(),
 }
 
 // Spec returns a docs.FieldSpec for an entire Benthos configuration.
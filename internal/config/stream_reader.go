@@ -43,17 +43,28 @@ func InferStreamID(dir, path string) (string, error) {
 
 // ReadStreamFile attempts to read a stream config and returns the result.
 func ReadStreamFile(path string) (conf stream.Config, lints []string, err error) {
-	conf = stream.NewConfig()
-
 	var confBytes []byte
 	var dLints []docs.Lint
 	if confBytes, dLints, err = ReadFileEnvSwap(path); err != nil {
+		conf = stream.NewConfig()
 		return
 	}
 	for _, l := range dLints {
 		lints = append(lints, l.Error())
 	}
 
+	var pLints []string
+	conf, pLints, err = ParseStreamConfig(path, confBytes)
+	lints = append(lints, pLints...)
+	return
+}
+
+// ParseStreamConfig attempts to parse a stream config where environment
+// variable interpolations have already been replaced, and returns the result.
+// The name of the source of the config is used as a prefix of lints.
+func ParseStreamConfig(name string, confBytes []byte) (conf stream.Config, lints []string, err error) {
+	conf = stream.NewConfig()
+
 	var rawNode yaml.Node
 	if err = yaml.Unmarshal(confBytes, &rawNode); err != nil {
 		return
@@ -64,7 +75,7 @@ func ReadStreamFile(path string) (conf stream.Config, lints []string, err error)
 
 	if !bytes.HasPrefix(confBytes, []byte("# BENTHOS LINT DISABLE")) {
 		for _, lint := range confSpec.LintYAML(docs.NewLintContext(), &rawNode) {
-			lints = append(lints, fmt.Sprintf("%v%v", path, lint.Error()))
+			lints = append(lints, fmt.Sprintf("%v%v", name, lint.Error()))
 		}
 	}
 
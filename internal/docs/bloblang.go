@@ -9,6 +9,17 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 )
 
+// MappingResourceName returns the name of a mapping resource referenced by a
+// Bloblang mapping field value of the form `resource:<label>`, and a boolean
+// indicating whether the value is a resource reference.
+func MappingResourceName(str string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(str), "resource:")
+	if !ok || name == "" || strings.ContainsAny(name, " \t\n") {
+		return "", false
+	}
+	return name, true
+}
+
 // LintBloblangMapping is function for linting a config field expected to be a
 // bloblang mapping.
 func LintBloblangMapping(ctx LintContext, line, col int, v any) []Lint {
@@ -19,6 +30,11 @@ func LintBloblangMapping(ctx LintContext, line, col int, v any) []Lint {
 	if str == "" {
 		return nil
 	}
+	if _, isResource := MappingResourceName(str); isResource {
+		// References to mapping resources are validated when the referencing
+		// component is constructed.
+		return nil
+	}
 	_, err := ctx.BloblangEnv.NewMapping(str)
 	if err == nil {
 		return nil
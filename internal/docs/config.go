@@ -60,6 +60,52 @@ var labelField = FieldString(
 	return nil
 }).HasDefault("")
 
+var executionField = FieldObject("execution", "An optional execution policy that determines whether the processor is applied to a batch as a whole or to each message of the batch individually.").WithChildren(
+	FieldString("mode", "Whether the processor is applied to batches as a whole or to each message individually.").HasAnnotatedOptions(
+		"batch", "Apply the processor to each batch as a whole, which is the behaviour of the processor when this field is omitted.",
+		"part", "Apply the processor to each message of a batch as though it were a batch of one message.",
+	).HasDefault("batch"),
+	FieldInt("parallel", "When the mode is `part`, the maximum number of messages of a batch to process in parallel. Values of `0` and `1` process messages sequentially.").HasDefault(0),
+).AtVersion("4.9.0").OmitWhen(func(field, _ any) (string, bool) {
+	obj, ok := field.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	for k, v := range obj {
+		switch k {
+		case "mode":
+			if v != "batch" {
+				return "", false
+			}
+		case "parallel":
+			if n, _ := v.(int); n != 0 {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	return "field execution is the default and can be removed", true
+})
+
+var singletonField = FieldBool(
+	"singleton", "Whether the input should only consume on one instance amongst multiple instances of Benthos running the same config, which is elected with the `leader_election` config.",
+).HasDefault(false).Advanced().AtVersion("4.9.0").OmitWhen(func(field, _ any) (string, bool) {
+	if b, ok := field.(bool); ok && !b {
+		return "field singleton is false and can be removed", true
+	}
+	return "", false
+})
+
+var detectFormatsField = FieldString(
+	"detect_formats", "A list of formats other than JSON that the contents of messages consumed by the input are detected and parsed as the first time their structured form is accessed, such as by a Bloblang mapping. Formats are detected in the order listed, and contents that could be a JSON document are always parsed as JSON.", []string{"msgpack"},
+).Array().HasDefault([]any{}).Advanced().AtVersion("4.9.0").OmitWhen(func(field, _ any) (string, bool) {
+	if arr, ok := field.([]any); ok && len(arr) == 0 {
+		return "field detect_formats is empty and can be removed", true
+	}
+	return "", false
+})
+
 // ReservedFieldsByType returns a map of fields for a specific type.
 func ReservedFieldsByType(t Type) map[string]FieldSpec {
 	m := map[string]FieldSpec{
@@ -74,6 +120,13 @@ func ReservedFieldsByType(t Type) map[string]FieldSpec {
 			return "", false
 		})
 	}
+	if t == TypeInput {
+		m["singleton"] = singletonField
+		m["detect_formats"] = detectFormatsField
+	}
+	if t == TypeProcessor {
+		m["execution"] = executionField
+	}
 	if t == TypeMetrics {
 		m["mapping"] = MetricsMappingFieldSpec("mapping")
 	}
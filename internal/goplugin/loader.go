@@ -0,0 +1,139 @@
+package goplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionSymbol is the name of an exported string variable that every Go
+// plugin loaded by Benthos must declare, its value should be the version of
+// Benthos that the plugin was compiled against, e.g. `var BenthosVersion =
+// "v4.9.0"`.
+const VersionSymbol = "BenthosVersion"
+
+// Extension is the file extension used to identify Go plugins within a plugin
+// directory.
+const Extension = ".so"
+
+// InitPlugins walks each of the provided directories and opens any Go plugins
+// found within them. Opening a plugin executes its init functions, which is
+// where plugins are expected to register their components with the global
+// environment.
+//
+// Each plugin must export a VersionSymbol string variable which is checked for
+// compatibility against the provided host version. Problems that do not
+// prevent a plugin from being loaded are returned as lints.
+func InitPlugins(hostVersion string, dirs ...string) ([]string, error) {
+	var lints []string
+	for _, dir := range dirs {
+		paths, err := pluginPaths(dir)
+		if err != nil {
+			return nil, fmt.Errorf("plugin directory %v: %w", dir, err)
+		}
+		for _, p := range paths {
+			pLints, err := loadPlugin(hostVersion, p)
+			if err != nil {
+				return nil, fmt.Errorf("plugin %v: %w", p, err)
+			}
+			for _, l := range pLints {
+				lints = append(lints, fmt.Sprintf("plugin file %v: %v", p, l))
+			}
+		}
+	}
+	return lints, nil
+}
+
+// pluginPaths returns the sorted paths of all plugin files found at the root of
+// a directory, sub directories are ignored.
+func pluginPaths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), Extension) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func loadPlugin(hostVersion, path string) ([]string, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := p.Lookup(VersionSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("missing exported %v variable: %w", VersionSymbol, err)
+	}
+
+	var pluginVersion string
+	switch t := sym.(type) {
+	case *string:
+		pluginVersion = *t
+	case func() string:
+		pluginVersion = t()
+	default:
+		return nil, fmt.Errorf("expected exported %v to be a string, got %T", VersionSymbol, sym)
+	}
+
+	var lints []string
+	if err := CheckVersion(hostVersion, pluginVersion); err != nil {
+		if !errors.Is(err, ErrUnknownHostVersion) {
+			return nil, err
+		}
+		lints = append(lints, err.Error())
+	}
+	return lints, nil
+}
+
+//------------------------------------------------------------------------------
+
+// ErrUnknownHostVersion is returned by CheckVersion when the version of the
+// running Benthos binary is not a valid semantic version (e.g. a development
+// build), and therefore the compatibility of a plugin cannot be verified.
+var ErrUnknownHostVersion = errors.New("unable to verify plugin compatibility as the running version of Benthos is unknown")
+
+func canonicalVersion(v string) string {
+	v = strings.TrimSpace(v)
+	if v != "" && !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	return v
+}
+
+// CheckVersion returns an error if a plugin compiled against the Benthos
+// version pluginVersion is incompatible with the running version hostVersion.
+// A plugin is compatible when it shares the same major version as the host and
+// was not compiled against a newer version than the host.
+func CheckVersion(hostVersion, pluginVersion string) error {
+	pluginVersion = canonicalVersion(pluginVersion)
+	if !semver.IsValid(pluginVersion) {
+		return fmt.Errorf("plugin declares an invalid Benthos version: %q", pluginVersion)
+	}
+
+	hostVersion = canonicalVersion(hostVersion)
+	if !semver.IsValid(hostVersion) {
+		return ErrUnknownHostVersion
+	}
+
+	if hMajor, pMajor := semver.Major(hostVersion), semver.Major(pluginVersion); hMajor != pMajor {
+		return fmt.Errorf("plugin was compiled against Benthos %v which is incompatible with the running major version %v", pluginVersion, hMajor)
+	}
+	if semver.Compare(pluginVersion, hostVersion) > 0 {
+		return fmt.Errorf("plugin was compiled against Benthos %v which is newer than the running version %v", pluginVersion, hostVersion)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package goplugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		host        string
+		plugin      string
+		errContains string
+	}{
+		{name: "exact match", host: "v4.9.0", plugin: "v4.9.0"},
+		{name: "missing prefixes", host: "4.9.0", plugin: "4.8.1"},
+		{name: "older minor", host: "v4.9.0", plugin: "v4.2.0"},
+		{name: "newer minor", host: "v4.9.0", plugin: "v4.10.0", errContains: "newer than the running version"},
+		{name: "older major", host: "v4.9.0", plugin: "v3.65.0", errContains: "incompatible with the running major version"},
+		{name: "invalid plugin", host: "v4.9.0", plugin: "nope", errContains: "invalid Benthos version"},
+		{name: "empty plugin", host: "v4.9.0", plugin: "", errContains: "invalid Benthos version"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckVersion(test.host, test.plugin)
+			if test.errContains == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
+
+func TestCheckVersionUnknownHost(t *testing.T) {
+	for _, host := range []string{"", "(devel)", "main"} {
+		err := CheckVersion(host, "v4.9.0")
+		assert.True(t, errors.Is(err, ErrUnknownHostVersion), host)
+	}
+}
+
+func TestPluginPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"b.so", "a.so", "readme.md", "sub/c.so"} {
+		fullPath := filepath.Join(dir, p)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0o755))
+		require.NoError(t, os.WriteFile(fullPath, nil, 0o644))
+	}
+
+	paths, err := pluginPaths(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.so"),
+		filepath.Join(dir, "b.so"),
+	}, paths)
+}
+
+func TestInitPluginsErrors(t *testing.T) {
+	_, err := InitPlugins("v4.9.0", filepath.Join(t.TempDir(), "does_not_exist"))
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bad.so"), []byte("not a plugin"), 0o644))
+
+	_, err = InitPlugins("v4.9.0", dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad.so")
+}
+
+func TestInitPluginsEmptyDir(t *testing.T) {
+	lints, err := InitPlugins("v4.9.0", t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, lints)
+}
@@ -0,0 +1,4 @@
+// Package goplugin implements a mechanism for discovering and loading Go
+// plugins (.so files) that register Benthos components at startup, allowing a
+// single blessed binary to be extended with team-supplied connectors.
+package goplugin
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// hostBreakers lazily creates a circuit breaker for each host that requests
+// are sent to.
+type hostBreakers struct {
+	conf circuitbreaker.Config
+
+	mState    metrics.StatGaugeVec
+	mOpened   metrics.StatCounterVec
+	mRejected metrics.StatCounterVec
+
+	breakers map[string]*circuitbreaker.Breaker
+	mut      sync.Mutex
+}
+
+func newHostBreakers(conf circuitbreaker.Config, stats metrics.Type) (*hostBreakers, error) {
+	// Create a breaker up front in order to validate the config.
+	if _, err := circuitbreaker.New(conf, metrics.Noop()); err != nil {
+		return nil, err
+	}
+	return &hostBreakers{
+		conf:      conf,
+		mState:    stats.GetGaugeVec("http_host_circuit_breaker_state", "host"),
+		mOpened:   stats.GetCounterVec("http_host_circuit_breaker_opened", "host"),
+		mRejected: stats.GetCounterVec("http_host_circuit_breaker_rejected", "host"),
+		breakers:  map[string]*circuitbreaker.Breaker{},
+	}, nil
+}
+
+func (h *hostBreakers) get(host string) (*circuitbreaker.Breaker, error) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if b, exists := h.breakers[host]; exists {
+		return b, nil
+	}
+	b, err := circuitbreaker.NewWithMetrics(h.conf, circuitbreaker.Metrics{
+		State:    h.mState.With(host),
+		Opened:   h.mOpened.With(host),
+		Rejected: h.mRejected.With(host),
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.breakers[host] = b
+	return b, nil
+}
+
+//------------------------------------------------------------------------------
+
+// errHostUnhealthy is reported to host circuit breakers when a request returns
+// a status code that indicates the host is failing.
+var errHostUnhealthy = component.ErrFailedSend
+
+// doOnce performs a single request attempt, guarded by the circuit breaker of
+// the target host when configured. Requests that return a status code which
+// should be retried count as failures of the host, whereas status codes within
+// drop_on are considered a problem with the request itself.
+func (h *Client) doOnce(req *http.Request) (*http.Response, error) {
+	if h.hostBreakers == nil {
+		return h.client.Do(req)
+	}
+
+	breaker, err := h.hostBreakers.get(req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if err := breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	res, err := h.client.Do(req)
+	outcome := err
+	if err == nil {
+		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved && retryStrat != noRetry {
+			outcome = errHostUnhealthy
+		}
+	}
+	breaker.Done(outcome)
+	return res, err
+}
+
+type attemptResult struct {
+	index int
+	res   *http.Response
+	err   error
+}
+
+// final returns whether an attempt has returned a response that should be
+// used rather than waiting for other attempts.
+func (h *Client) final(r attemptResult) bool {
+	if r.err != nil {
+		return false
+	}
+	resolved, retryStrat := h.checkStatus(r.res.StatusCode)
+	return resolved || retryStrat == noRetry
+}
+
+// cancelOnClose cancels the context of a request attempt once the body of its
+// response is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func closeAttempt(r attemptResult) {
+	if r.res != nil && r.res.Body != nil {
+		_, _ = io.Copy(io.Discard, r.res.Body)
+		r.res.Body.Close()
+	}
+}
+
+// do performs a request, and when hedging is enabled sends a second attempt if
+// the first hasn't returned within the configured threshold. The first attempt
+// to return a final response is used and the other is cancelled.
+func (h *Client) do(ctx context.Context, req *http.Request, sendMsg message.Batch) (*http.Response, error) {
+	if !h.hedge {
+		return h.doOnce(req.WithContext(ctx))
+	}
+
+	results := make(chan attemptResult, 2)
+	var cancels []context.CancelFunc
+
+	attempt := func(req *http.Request, waitForAccess bool) {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			if waitForAccess && !h.waitForAccess(attemptCtx) {
+				results <- attemptResult{index: index, err: component.ErrTypeClosed}
+				return
+			}
+			res, err := h.doOnce(req.WithContext(attemptCtx))
+			results <- attemptResult{index: index, res: res, err: err}
+		}()
+	}
+	attempt(req, false)
+	pending := 1
+
+	timer := time.NewTimer(h.hedgeAfter)
+	defer timer.Stop()
+
+	var last attemptResult
+	var hasLast bool
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			hedgeReq, err := h.reqCreator.Create(sendMsg)
+			if err != nil {
+				h.log.Debugf("Failed to create hedged request: %v\n", err)
+				continue
+			}
+			h.mHedged.Incr(1)
+			attempt(hedgeReq, true)
+			pending++
+		case r := <-results:
+			pending--
+			if hasLast {
+				// Only the latest failed attempt is kept for the retry logic.
+				closeAttempt(last)
+				cancels[last.index]()
+			}
+			last, hasLast = r, true
+			if !h.final(r) {
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != r.index {
+					cancel()
+				}
+			}
+			go func(n int) {
+				for ; n > 0; n-- {
+					closeAttempt(<-results)
+				}
+			}(pending)
+			pending = 0
+		}
+	}
+
+	if last.err != nil {
+		cancels[last.index]()
+		return nil, last.err
+	}
+	last.res.Body = &cancelOnClose{ReadCloser: last.res.Body, cancel: cancels[last.index]}
+	return last.res, nil
+}
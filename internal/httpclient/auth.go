@@ -4,6 +4,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/docs/interop"
 	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -29,6 +30,7 @@ func OldAuthFieldSpecsExpanded() docs.FieldSpecs {
 		interop.Unwrap(oAuth2FieldSpec()),
 		interop.Unwrap(jwtFieldSpec()),
 		interop.Unwrap(basicAuthField()),
+		kerberos.FieldSpec("HTTP"),
 	}
 }
 
@@ -3,18 +3,22 @@ package httpclient
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/client"
+
 	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/codec"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
@@ -34,6 +38,7 @@ type Client struct {
 	client       *http.Client
 	clientCtx    context.Context
 	clientCancel func()
+	krbClient    *client.Client
 
 	// Request execution and retry logic
 	rateLimit     string
@@ -42,6 +47,9 @@ type Client struct {
 	backoffOn     map[int]struct{}
 	dropOn        map[int]struct{}
 	successOn     map[int]struct{}
+	hedge         bool
+	hedgeAfter    time.Duration
+	hostBreakers  *hostBreakers
 
 	// Response extraction
 	metaExtractFilter *metadata.IncludeFilter
@@ -51,6 +59,7 @@ type Client struct {
 	mgr bundle.NewManagement
 
 	mLatency metrics.StatTimer
+	mHedged  metrics.StatCounter
 	mCodes   map[int]metrics.StatCounter
 	codesMut sync.RWMutex
 }
@@ -75,6 +84,13 @@ func NewClientFromOldConfig(conf oldconfig.OldConfig, mgr bundle.NewManagement,
 		log: mgr.Logger(),
 	}
 
+	if conf.Kerberos.Enabled {
+		if h.krbClient, err = conf.Kerberos.Client(); err != nil {
+			return nil, err
+		}
+		reqCreator.reqSigner = kerberosSigner(h.krbClient, conf.Kerberos.ServiceName, reqCreator.reqSigner)
+	}
+
 	h.clientCtx, h.clientCancel = context.WithCancel(context.Background())
 	h.client = conf.OAuth2.Client(h.clientCtx)
 
@@ -85,40 +101,12 @@ func NewClientFromOldConfig(conf oldconfig.OldConfig, mgr bundle.NewManagement,
 		}
 	}
 
-	if conf.TLS.Enabled {
-		tlsConf, err := conf.TLS.Get()
-		if err != nil {
-			return nil, err
-		}
-		if tlsConf != nil {
-			if c, ok := http.DefaultTransport.(*http.Transport); ok {
-				cloned := c.Clone()
-				cloned.TLSClientConfig = tlsConf
-				h.client.Transport = cloned
-			} else {
-				h.client.Transport = &http.Transport{
-					TLSClientConfig: tlsConf,
-				}
-			}
-		}
+	tr, err := newTransport(conf)
+	if err != nil {
+		return nil, err
 	}
-
-	if conf.ProxyURL != "" {
-		proxyURL, err := url.Parse(conf.ProxyURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse proxy_url string: %v", err)
-		}
-		if h.client.Transport != nil {
-			if tr, ok := h.client.Transport.(*http.Transport); ok {
-				tr.Proxy = http.ProxyURL(proxyURL)
-			} else {
-				return nil, fmt.Errorf("unable to apply proxy_url to transport, unexpected type %T", h.client.Transport)
-			}
-		} else {
-			h.client.Transport = &http.Transport{
-				Proxy: http.ProxyURL(proxyURL),
-			}
-		}
+	if tr != nil {
+		setTransport(h.client, tr)
 	}
 
 	for _, c := range conf.BackoffOn {
@@ -136,6 +124,7 @@ func NewClientFromOldConfig(conf oldconfig.OldConfig, mgr bundle.NewManagement,
 	}
 
 	h.mLatency = h.mgr.Metrics().GetTimer("http_request_latency_ns")
+	h.mHedged = h.mgr.Metrics().GetCounter("http_request_hedged")
 	h.mCodes = map[int]metrics.StatCounter{}
 
 	var retry, maxBackoff time.Duration
@@ -158,6 +147,21 @@ func NewClientFromOldConfig(conf oldconfig.OldConfig, mgr bundle.NewManagement,
 		}
 	}
 
+	if h.hedge = conf.Hedge.Enabled; h.hedge {
+		if h.hedgeAfter, err = time.ParseDuration(conf.Hedge.After); err != nil {
+			return nil, fmt.Errorf("failed to parse hedge after duration string: %v", err)
+		}
+		if h.hedgeAfter <= 0 {
+			return nil, errors.New("hedge after duration must be greater than zero")
+		}
+	}
+
+	if conf.HostBreaker.Enabled {
+		if h.hostBreakers, err = newHostBreakers(conf.HostBreaker, h.mgr.Metrics()); err != nil {
+			return nil, fmt.Errorf("failed to create host circuit breaker: %w", err)
+		}
+	}
+
 	h.numRetries = conf.NumRetries
 	h.retryThrottle = throttle.New(
 		throttle.OptMaxUnthrottledRetries(0),
@@ -221,20 +225,30 @@ func (h *Client) waitForAccess(ctx context.Context) bool {
 	}
 }
 
+func (h *Client) annotatePart(res *http.Response, p *message.Part) {
+	p.MetaSet("http_status_code", strconv.Itoa(res.StatusCode))
+	if h.metaExtractFilter.IsSet() {
+		for k, values := range res.Header {
+			normalisedHeader := strings.ToLower(k)
+			if len(values) > 0 && h.metaExtractFilter.Match(normalisedHeader) {
+				p.MetaSet(normalisedHeader, values[0])
+			}
+		}
+	}
+}
+
+// RequestKey returns a string that uniquely identifies the request that would
+// be sent for a message batch, allowing identical requests to be coalesced.
+func (h *Client) RequestKey(sendMsg message.Batch) (string, error) {
+	return h.reqCreator.Key(sendMsg)
+}
+
 // ResponseToBatch attempts to parse an HTTP response into a 2D slice of bytes.
 func (h *Client) ResponseToBatch(res *http.Response) (resMsg message.Batch, err error) {
 	resMsg = message.QuickBatch(nil)
 
 	annotatePart := func(p *message.Part) {
-		p.MetaSet("http_status_code", strconv.Itoa(res.StatusCode))
-		if h.metaExtractFilter.IsSet() {
-			for k, values := range res.Header {
-				normalisedHeader := strings.ToLower(k)
-				if len(values) > 0 && h.metaExtractFilter.Match(normalisedHeader) {
-					p.MetaSet(normalisedHeader, values[0])
-				}
-			}
-		}
+		h.annotatePart(res, p)
 	}
 
 	if res.Body == nil {
@@ -299,6 +313,41 @@ func (h *Client) ResponseToBatch(res *http.Response) (resMsg message.Batch, err
 	return
 }
 
+// ResponseToCodecBatch consumes the body of an HTTP response with a codec,
+// where each message produced by the codec is read as the body arrives and
+// becomes a message of the resulting batch.
+func (h *Client) ResponseToCodecBatch(ctx context.Context, res *http.Response, ctor codec.ReaderConstructor) (resMsg message.Batch, err error) {
+	resMsg = message.QuickBatch(nil)
+	if res.Body == nil {
+		return
+	}
+
+	var rdr codec.Reader
+	if rdr, err = ctor("", res.Body, func(ctx context.Context, err error) error {
+		return nil
+	}); err != nil {
+		res.Body.Close()
+		return
+	}
+	defer rdr.Close(ctx)
+
+	for {
+		var parts []*message.Part
+		var ackFn codec.ReaderAckFn
+		if parts, ackFn, err = rdr.Next(ctx); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return
+		}
+		for _, p := range parts {
+			h.annotatePart(res, p)
+			resMsg = append(resMsg, p)
+		}
+		_ = ackFn(ctx, nil)
+	}
+}
+
 type retryStrategy int
 
 const (
@@ -368,7 +417,7 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg message.Batch) (res
 	numRetries := h.numRetries
 
 	startedAt := time.Now()
-	if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+	if res, err = h.do(ctx, req, sendMsg); err == nil {
 		h.incrCode(res.StatusCode)
 		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
 			rateLimited = retryStrat == retryBackoff
@@ -380,6 +429,8 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg message.Batch) (res
 				res.Body.Close()
 			}
 		}
+	} else if errors.Is(err, circuitbreaker.ErrOpen) {
+		numRetries = 0
 	}
 	h.mLatency.Timing(time.Since(startedAt).Nanoseconds())
 
@@ -404,7 +455,7 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg message.Batch) (res
 		rateLimited = false
 
 		startedAt = time.Now()
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		if res, err = h.do(ctx, req, sendMsg); err == nil {
 			h.incrCode(res.StatusCode)
 			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
 				rateLimited = retryStrat == retryBackoff
@@ -416,6 +467,8 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg message.Batch) (res
 					res.Body.Close()
 				}
 			}
+		} else if errors.Is(err, circuitbreaker.ErrOpen) {
+			j = 0
 		}
 		h.mLatency.Timing(time.Since(startedAt).Nanoseconds())
 		i++
@@ -455,5 +508,8 @@ func (h *Client) Send(ctx context.Context, sendMsg message.Batch) (message.Batch
 // Close the client.
 func (h *Client) Close(ctx context.Context) error {
 	h.clientCancel()
+	if h.krbClient != nil {
+		h.krbClient.Destroy()
+	}
 	return nil
 }
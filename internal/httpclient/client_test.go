@@ -19,6 +19,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
 	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -466,3 +467,110 @@ func TestHTTPClientReceiveMultipart(t *testing.T) {
 		assert.Equal(t, "201", resMsg.Get(1).MetaGet("http_status_code"))
 	}
 }
+
+func TestHTTPClientHedge(t *testing.T) {
+	var reqCount uint32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint32(&reqCount, 1) == 1 {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			_, _ = w.Write([]byte("slow"))
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	conf := oldconfig.NewOldConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Hedge.Enabled = true
+	conf.Hedge.After = "10ms"
+
+	h, err := NewClientFromOldConfig(conf, mock.NewManager())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	resMsg, err := h.Send(context.Background(), message.QuickBatch([][]byte{[]byte("test")}))
+	require.NoError(t, err)
+	require.Equal(t, 1, resMsg.Len())
+	assert.Equal(t, "fast", string(resMsg.Get(0).AsBytes()))
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientHedgeNotNeeded(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		_, _ = w.Write([]byte("hello world"))
+	}))
+	defer ts.Close()
+
+	conf := oldconfig.NewOldConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Hedge.Enabled = true
+	conf.Hedge.After = "1s"
+
+	h, err := NewClientFromOldConfig(conf, mock.NewManager())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	for i := 0; i < 5; i++ {
+		resMsg, err := h.Send(context.Background(), message.QuickBatch([][]byte{[]byte("test")}))
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(resMsg.Get(0).AsBytes()))
+	}
+	assert.Equal(t, uint32(5), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientHostCircuitBreaker(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "test error", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	conf := oldconfig.NewOldConfig()
+	conf.URL = ts.URL + "/testpost"
+	conf.Retry = "1ms"
+	conf.NumRetries = 1
+	conf.HostBreaker.Enabled = true
+	conf.HostBreaker.MinRequests = 2
+	conf.HostBreaker.OpenPeriod = "1h"
+
+	h, err := NewClientFromOldConfig(conf, mock.NewManager())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	_, err = h.Send(context.Background(), message.QuickBatch([][]byte{[]byte("test")}))
+	require.Error(t, err)
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+
+	// The circuit is now open and requests are rejected without being retried.
+	_, err = h.Send(context.Background(), message.QuickBatch([][]byte{[]byte("test")}))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, circuitbreaker.ErrOpen)
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+}
+
+func TestHTTPClientBadHedgeAndBreakerConfig(t *testing.T) {
+	conf := oldconfig.NewOldConfig()
+	conf.Hedge.Enabled = true
+	conf.Hedge.After = "nope"
+	_, err := NewClientFromOldConfig(conf, mock.NewManager())
+	require.Error(t, err)
+
+	conf.Hedge.After = "0s"
+	_, err = NewClientFromOldConfig(conf, mock.NewManager())
+	require.Error(t, err)
+
+	conf = oldconfig.NewOldConfig()
+	conf.HostBreaker.Enabled = true
+	conf.HostBreaker.Window = "nope"
+	_, err = NewClientFromOldConfig(conf, mock.NewManager())
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
+)
+
+// kerberosSigner returns a RequestSigner that authenticates requests with the
+// Negotiate scheme of RFC 4559, where each request is sent with a token for
+// the service principal of its host, such as HTTP/example.com.
+func kerberosSigner(cl *client.Client, serviceName string, next RequestSigner) RequestSigner {
+	return func(req *http.Request) error {
+		if err := next(req); err != nil {
+			return err
+		}
+		token, err := kerberos.NegotiateToken(cl, serviceName+"/"+req.URL.Hostname())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Negotiate "+base64.StdEncoding.EncodeToString(token))
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package httpclient
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func TestKerberosSignerLoginError(t *testing.T) {
+	krbConf, err := krb5config.NewFromString(`
+[libdefaults]
+  default_realm = EXAMPLE.COM
+  dns_lookup_kdc = false
+
+[realms]
+  EXAMPLE.COM = {
+    kdc = 127.0.0.1:1
+  }
+`)
+	require.NoError(t, err)
+
+	cl := client.NewWithPassword("benthos", "EXAMPLE.COM", "nope", krbConf)
+	defer cl.Destroy()
+
+	var nextCalled bool
+	signer := kerberosSigner(cl, "HTTP", func(req *http.Request) error {
+		nextCalled = true
+		return nil
+	})
+
+	req, err := http.NewRequest("GET", "http://localhost:4195/foo", http.NoBody)
+	require.NoError(t, err)
+
+	require.ErrorContains(t, signer(req), "failed to login")
+	assert.True(t, nextCalled)
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestKerberosClientConfigError(t *testing.T) {
+	conf := oldconfig.NewOldConfig()
+	conf.URL = "http://localhost:4195/foo"
+	conf.Kerberos.Enabled = true
+	conf.Kerberos.ConfigFile = filepath.Join(t.TempDir(), "nope.conf")
+
+	_, err := NewClientFromOldConfig(conf, mock.NewManager())
+	require.ErrorContains(t, err, "failed to load kerberos config file")
+}
@@ -1,6 +1,8 @@
 package oldconfig
 
 import (
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
 	"github.com/benthosdev/benthos/v4/internal/metadata"
 	"github.com/benthosdev/benthos/v4/internal/tls"
 )
@@ -22,8 +24,50 @@ type OldConfig struct {
 	SuccessfulOn    []int                        `json:"successful_on" yaml:"successful_on"`
 	TLS             tls.Config                   `json:"tls" yaml:"tls"`
 	ProxyURL        string                       `json:"proxy_url" yaml:"proxy_url"`
+	Transport       TransportConfig              `json:"transport" yaml:"transport"`
+	Hedge           HedgeConfig                  `json:"hedge" yaml:"hedge"`
+	HostBreaker     circuitbreaker.Config        `json:"host_circuit_breaker" yaml:"host_circuit_breaker"`
 	AuthConfig      `json:",inline" yaml:",inline"`
-	OAuth2          OAuth2Config `json:"oauth2" yaml:"oauth2"`
+	OAuth2          OAuth2Config           `json:"oauth2" yaml:"oauth2"`
+	Kerberos        kerberos.ToggledConfig `json:"kerberos" yaml:"kerberos"`
+}
+
+// TransportConfig contains configuration parameters for the connections of an
+// HTTP client.
+type TransportConfig struct {
+	MaxIdleConns        int    `json:"max_idle_conns" yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost int    `json:"max_idle_conns_per_host" yaml:"max_idle_conns_per_host"`
+	IdleConnTimeout     string `json:"idle_conn_timeout" yaml:"idle_conn_timeout"`
+	DialTimeout         string `json:"dial_timeout" yaml:"dial_timeout"`
+	TLSHandshakeTimeout string `json:"tls_handshake_timeout" yaml:"tls_handshake_timeout"`
+	HTTP2               bool   `json:"http2" yaml:"http2"`
+}
+
+// NewTransportConfig creates a new TransportConfig with default values, which
+// match the defaults of the standard library.
+func NewTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 2,
+		IdleConnTimeout:     "90s",
+		DialTimeout:         "30s",
+		TLSHandshakeTimeout: "10s",
+		HTTP2:               true,
+	}
+}
+
+// HedgeConfig contains configuration parameters for hedged requests.
+type HedgeConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	After   string `json:"after" yaml:"after"`
+}
+
+// NewHedgeConfig creates a new HedgeConfig with default values.
+func NewHedgeConfig() HedgeConfig {
+	return HedgeConfig{
+		Enabled: false,
+		After:   "1s",
+	}
 }
 
 // NewOldConfig creates a new Config with default values.
@@ -42,7 +86,11 @@ func NewOldConfig() OldConfig {
 		DropOn:          []int{},
 		SuccessfulOn:    []int{},
 		TLS:             tls.NewConfig(),
+		Transport:       NewTransportConfig(),
+		Hedge:           NewHedgeConfig(),
+		HostBreaker:     circuitbreaker.NewConfig(),
 		AuthConfig:      NewAuthConfig(),
 		OAuth2:          NewOAuth2Config(),
+		Kerberos:        kerberos.NewToggledConfig("HTTP"),
 	}
 }
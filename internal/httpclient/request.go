@@ -227,3 +227,47 @@ func (r *RequestCreator) Create(refBatch message.Batch) (req *http.Request, err
 	err = r.reqSigner(req)
 	return
 }
+
+// Key returns a string that uniquely identifies the request that would be
+// created from a reference message batch, excluding any signatures added by
+// authentication methods, which allows identical requests to be coalesced.
+func (r *RequestCreator) Key(refBatch message.Batch) (string, error) {
+	body, overrideContentType, err := r.body(refBatch)
+	if err != nil {
+		return "", err
+	}
+
+	headers := http.Header{}
+	for k, v := range r.headers {
+		headers.Add(k, v.String(0, refBatch))
+	}
+	if len(refBatch) > 0 {
+		_ = r.metaInsertFilter.Iter(refBatch[0], func(k, v string) error {
+			headers.Add(k, v)
+			return nil
+		})
+	}
+	if overrideContentType != "" {
+		headers.Set("Content-Type", overrideContentType)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(r.verb)
+	buf.WriteByte(0)
+	buf.WriteString(r.url.String(0, refBatch))
+	buf.WriteByte(0)
+	if r.host != nil {
+		buf.WriteString(r.host.String(0, refBatch))
+	}
+	buf.WriteByte(0)
+	if err := headers.Write(&buf); err != nil {
+		return "", err
+	}
+	buf.WriteByte(0)
+	if body != nil {
+		if _, err := buf.ReadFrom(body); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
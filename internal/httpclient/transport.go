@@ -0,0 +1,98 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/benthosdev/benthos/v4/internal/httpclient/oldconfig"
+)
+
+// newTransport creates an HTTP transport from a config, or returns nil when
+// the config doesn't customise the transport and the default can be used.
+func newTransport(conf oldconfig.OldConfig) (*http.Transport, error) {
+	var tlsConf *tls.Config
+	if conf.TLS.Enabled {
+		var err error
+		if tlsConf, err = conf.TLS.Get(); err != nil {
+			return nil, err
+		}
+	}
+	if tlsConf == nil && conf.ProxyURL == "" && conf.Transport == oldconfig.NewTransportConfig() {
+		return nil, nil
+	}
+
+	var tr *http.Transport
+	if c, ok := http.DefaultTransport.(*http.Transport); ok {
+		tr = c.Clone()
+	} else {
+		tr = &http.Transport{}
+	}
+	if tlsConf != nil {
+		tr.TLSClientConfig = tlsConf
+	}
+
+	if conf.ProxyURL != "" {
+		proxyURL, err := url.Parse(conf.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy_url string: %v", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("proxy_url scheme not supported: %v", proxyURL.Scheme)
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tConf := conf.Transport
+	tr.MaxIdleConns = tConf.MaxIdleConns
+	tr.MaxIdleConnsPerHost = tConf.MaxIdleConnsPerHost
+
+	var err error
+	if tr.IdleConnTimeout, err = parseTransportDuration("idle_conn_timeout", tConf.IdleConnTimeout); err != nil {
+		return nil, err
+	}
+	if tr.TLSHandshakeTimeout, err = parseTransportDuration("tls_handshake_timeout", tConf.TLSHandshakeTimeout); err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{KeepAlive: 30 * time.Second}
+	if dialer.Timeout, err = parseTransportDuration("dial_timeout", tConf.DialTimeout); err != nil {
+		return nil, err
+	}
+	tr.DialContext = dialer.DialContext
+
+	if !tConf.HTTP2 {
+		// A non-nil empty map disables HTTP/2 support.
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return tr, nil
+}
+
+func parseTransportDuration(field, str string) (time.Duration, error) {
+	if str == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse transport %v string: %v", field, err)
+	}
+	return d, nil
+}
+
+// setTransport applies a transport to a client, retaining any OAuth2 layer of
+// the client.
+func setTransport(client *http.Client, tr *http.Transport) {
+	if oTr, ok := client.Transport.(*oauth2.Transport); ok {
+		oTr.Base = tr
+		return
+	}
+	client.Transport = tr
+}
@@ -0,0 +1,429 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+// AuthConfig contains struct based fields for authenticating requests with API
+// keys, JSON web tokens or OIDC token introspection.
+type AuthConfig struct {
+	APIKeys AuthAPIKeysConfig `json:"api_keys" yaml:"api_keys"`
+	JWT     AuthJWTConfig     `json:"jwt" yaml:"jwt"`
+	OIDC    AuthOIDCConfig    `json:"oidc" yaml:"oidc"`
+}
+
+// AuthAPIKeysConfig contains fields for authenticating requests with static
+// API keys.
+type AuthAPIKeysConfig struct {
+	Enabled bool              `json:"enabled" yaml:"enabled"`
+	Header  string            `json:"header" yaml:"header"`
+	Keys    map[string]string `json:"keys" yaml:"keys"`
+}
+
+// AuthJWTConfig contains fields for authenticating requests with bearer JSON
+// web tokens verified against a JSON web key set.
+type AuthJWTConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	JWKSURL  string `json:"jwks_url" yaml:"jwks_url"`
+	Issuer   string `json:"issuer" yaml:"issuer"`
+	Audience string `json:"audience" yaml:"audience"`
+}
+
+// AuthOIDCConfig contains fields for authenticating requests with bearer tokens
+// verified by an OAuth 2.0 token introspection endpoint.
+type AuthOIDCConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled"`
+	IntrospectionURL string `json:"introspection_url" yaml:"introspection_url"`
+	ClientID         string `json:"client_id" yaml:"client_id"`
+	ClientSecret     string `json:"client_secret" yaml:"client_secret"`
+}
+
+// NewAuthConfig returns an AuthConfig with default values.
+func NewAuthConfig() AuthConfig {
+	return AuthConfig{
+		APIKeys: AuthAPIKeysConfig{
+			Enabled: false,
+			Header:  "X-API-Key",
+			Keys:    map[string]string{},
+		},
+		JWT: AuthJWTConfig{
+			Enabled:  false,
+			JWKSURL:  "",
+			Issuer:   "",
+			Audience: "",
+		},
+		OIDC: AuthOIDCConfig{
+			Enabled:          false,
+			IntrospectionURL: "",
+			ClientID:         "",
+			ClientSecret:     "",
+		},
+	}
+}
+
+// AuthFieldSpec returns the spec for the authentication fields of an HTTP
+// server.
+func AuthFieldSpec() docs.FieldSpec {
+	return docs.FieldObject("auth", "Allows you to require that requests to the HTTP server are authenticated with an API key or bearer token. When more than one method is enabled a request is accepted when any of them succeeds.").WithChildren(
+		docs.FieldObject("api_keys", "Authenticate requests with static API keys.").WithChildren(
+			docs.FieldBool("enabled", "Whether to accept API keys.").HasDefault(false),
+			docs.FieldString("header", "The header from which API keys are read.").HasDefault("X-API-Key"),
+			docs.FieldString("keys", "A map of identities to the API key that authenticates them, the identity of a matched key is used as the subject of the request.").Map().HasDefault(map[string]any{}),
+		),
+		docs.FieldObject("jwt", "Authenticate requests with bearer JSON web tokens, which are verified with the keys of a JSON web key set.").WithChildren(
+			docs.FieldBool("enabled", "Whether to accept JSON web tokens.").HasDefault(false),
+			docs.FieldString("jwks_url", "The URL of a JSON web key set used to verify token signatures.", "https://example.com/.well-known/jwks.json").HasDefault(""),
+			docs.FieldString("issuer", "An optional issuer that tokens must have been issued by.").HasDefault(""),
+			docs.FieldString("audience", "An optional audience that tokens must have been issued for.").HasDefault(""),
+		),
+		docs.FieldObject("oidc", "Authenticate requests with bearer tokens, which are verified by an [OAuth 2.0 token introspection](https://datatracker.ietf.org/doc/html/rfc7662) endpoint.").WithChildren(
+			docs.FieldBool("enabled", "Whether to accept tokens verified by introspection.").HasDefault(false),
+			docs.FieldString("introspection_url", "The URL of the token introspection endpoint.").HasDefault(""),
+			docs.FieldString("client_id", "The client ID used to authenticate with the introspection endpoint.").HasDefault(""),
+			docs.FieldString("client_secret", "The client secret used to authenticate with the introspection endpoint.").HasDefault(""),
+		),
+	).AtVersion("4.9.0").Advanced()
+}
+
+// Enabled returns true if any authentication method is enabled.
+func (a AuthConfig) Enabled() bool {
+	return a.APIKeys.Enabled || a.JWT.Enabled || a.OIDC.Enabled
+}
+
+// Validate confirms that the authentication methods are properly configured.
+func (a AuthConfig) Validate() error {
+	if a.APIKeys.Enabled {
+		if a.APIKeys.Header == "" {
+			return errors.New("an api key header is required")
+		}
+		if len(a.APIKeys.Keys) == 0 {
+			return errors.New("at least one api key is required")
+		}
+		for id, k := range a.APIKeys.Keys {
+			if k == "" {
+				return fmt.Errorf("api key of identity %v must not be empty", id)
+			}
+		}
+	}
+	if a.JWT.Enabled && a.JWT.JWKSURL == "" {
+		return errors.New("a jwks_url is required for jwt authentication")
+	}
+	if a.OIDC.Enabled && a.OIDC.IntrospectionURL == "" {
+		return errors.New("an introspection_url is required for oidc authentication")
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// Identity describes the authenticated origin of a request.
+type Identity struct {
+	// Method is the authentication method that accepted the request, which is
+	// one of api_key, jwt or oidc.
+	Method string
+
+	// Subject is the name of a matched API key, or the subject of a token.
+	Subject string
+
+	// Claims contains the claims of a token.
+	Claims map[string]any
+}
+
+type identityKeyType int
+
+const identityKey identityKeyType = iota
+
+// IdentityFromContext returns the identity of an authenticated request from its
+// context, if one exists.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey).(Identity)
+	return id, ok
+}
+
+//------------------------------------------------------------------------------
+
+// jwksRefreshPeriod is the minimum period between fetches of a JSON web key
+// set triggered by tokens signed with unknown keys.
+const jwksRefreshPeriod = time.Minute
+
+// Authenticator is a middleware that enforces the authentication methods of an
+// AuthConfig, and must be created with NewAuthenticator.
+type Authenticator struct {
+	conf   AuthConfig
+	client *http.Client
+
+	jwksMut       sync.Mutex
+	jwksKeys      map[string]any
+	jwksFetchedAt time.Time
+}
+
+// NewAuthenticator validates an AuthConfig and returns an Authenticator for it.
+func NewAuthenticator(conf AuthConfig) (*Authenticator, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return &Authenticator{
+		conf:   conf,
+		client: &http.Client{Timeout: time.Second * 10},
+	}, nil
+}
+
+// WrapHandler wraps the provided HTTP handler with middleware that rejects
+// unauthenticated requests, and adds the identity of authenticated requests to
+// their context.
+func (a *Authenticator) WrapHandler(next http.HandlerFunc) http.HandlerFunc {
+	if a == nil || !a.conf.Enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := a.authenticate(r)
+		if err != nil {
+			if a.conf.JWT.Enabled || a.conf.OIDC.Enabled {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey, id)))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (Identity, error) {
+	if a.conf.APIKeys.Enabled {
+		if key := r.Header.Get(a.conf.APIKeys.Header); key != "" {
+			for id, k := range a.conf.APIKeys.Keys {
+				if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+					return Identity{Method: "api_key", Subject: id}, nil
+				}
+			}
+		}
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		return Identity{}, errors.New("no credentials provided")
+	}
+
+	if a.conf.JWT.Enabled {
+		if claims, err := a.verifyJWT(token); err == nil {
+			return newTokenIdentity("jwt", claims), nil
+		}
+	}
+	if a.conf.OIDC.Enabled {
+		if claims, err := a.introspect(r.Context(), token); err == nil {
+			return newTokenIdentity("oidc", claims), nil
+		}
+	}
+	return Identity{}, errors.New("invalid credentials")
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+func newTokenIdentity(method string, claims map[string]any) Identity {
+	id := Identity{Method: method, Claims: claims}
+	id.Subject, _ = claims["sub"].(string)
+	return id
+}
+
+//------------------------------------------------------------------------------
+
+func (a *Authenticator) verifyJWT(tokenStr string) (map[string]any, error) {
+	parser := jwt.Parser{
+		ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.jwksKey(kid)
+	}); err != nil {
+		return nil, err
+	}
+
+	if a.conf.JWT.Issuer != "" && !claims.VerifyIssuer(a.conf.JWT.Issuer, true) {
+		return nil, errors.New("token issuer mismatch")
+	}
+	if a.conf.JWT.Audience != "" && !claims.VerifyAudience(a.conf.JWT.Audience, true) {
+		return nil, errors.New("token audience mismatch")
+	}
+	return claims, nil
+}
+
+// jwksKey returns the key of a JSON web key set by its ID, fetching the set
+// when the key is not yet known.
+func (a *Authenticator) jwksKey(kid string) (any, error) {
+	a.jwksMut.Lock()
+	defer a.jwksMut.Unlock()
+
+	if k, exists := a.jwksKeys[kid]; exists {
+		return k, nil
+	}
+	if a.jwksKeys != nil && time.Since(a.jwksFetchedAt) < jwksRefreshPeriod {
+		return nil, fmt.Errorf("key %v not found", kid)
+	}
+
+	keys, err := a.fetchJWKS()
+	if err != nil {
+		return nil, err
+	}
+	a.jwksKeys, a.jwksFetchedAt = keys, time.Now()
+
+	if k, exists := keys[kid]; exists {
+		return k, nil
+	}
+	return nil, fmt.Errorf("key %v not found", kid)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (a *Authenticator) fetchJWKS() (map[string]any, error) {
+	res, err := a.client.Get(a.conf.JWT.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: status code %v", res.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks key %v: %w", k.Kid, err)
+		}
+		if pub != nil {
+			keys[k.Kid] = pub
+		}
+	}
+	return keys, nil
+}
+
+// publicKey returns the public key of a JSON web key, or nil if the key type is
+// not supported.
+func (k jsonWebKey) publicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %v not supported", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (a *Authenticator) introspect(ctx context.Context, token string) (map[string]any, error) {
+	form := url.Values{}
+	form.Set("token", token)
+	form.Set("token_type_hint", "access_token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.conf.OIDC.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.conf.OIDC.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(a.conf.OIDC.ClientID), url.QueryEscape(a.conf.OIDC.ClientSecret))
+	}
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to introspect token: status code %v", res.StatusCode)
+	}
+
+	claims := map[string]any{}
+	if err := json.NewDecoder(res.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse introspection response: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errors.New("token is not active")
+	}
+	return claims, nil
+}
@@ -0,0 +1,166 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func authTestHandler(t *testing.T, conf AuthConfig) http.HandlerFunc {
+	t.Helper()
+
+	a, err := NewAuthenticator(conf)
+	require.NoError(t, err)
+
+	return a.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := IdentityFromContext(r.Context())
+		require.True(t, ok)
+		_, _ = w.Write([]byte(id.Method + ":" + id.Subject))
+	})
+}
+
+func authTestRequest(h http.HandlerFunc, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestAuthAPIKeys(t *testing.T) {
+	conf := NewAuthConfig()
+	conf.APIKeys.Enabled = true
+	conf.APIKeys.Keys = map[string]string{
+		"foo": "foosecret",
+		"bar": "barsecret",
+	}
+	h := authTestHandler(t, conf)
+
+	rec := authTestRequest(h, map[string]string{"X-API-Key": "barsecret"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "api_key:bar", rec.Body.String())
+
+	rec = authTestRequest(h, map[string]string{"X-API-Key": "nope"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = authTestRequest(h, nil)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]any{
+				{
+					"kid": "foo",
+					"kty": "RSA",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	}))
+	t.Cleanup(jwks.Close)
+
+	conf := NewAuthConfig()
+	conf.JWT.Enabled = true
+	conf.JWT.JWKSURL = jwks.URL
+	conf.JWT.Issuer = "benthos"
+	h := authTestHandler(t, conf)
+
+	sign := func(kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		str, err := token.SignedString(key)
+		require.NoError(t, err)
+		return str
+	}
+
+	rec := authTestRequest(h, map[string]string{
+		"Authorization": "Bearer " + sign("foo", jwt.MapClaims{
+			"sub": "alice",
+			"iss": "benthos",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}),
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "jwt:alice", rec.Body.String())
+
+	for name, token := range map[string]string{
+		"expired": sign("foo", jwt.MapClaims{
+			"sub": "alice",
+			"iss": "benthos",
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}),
+		"wrong issuer": sign("foo", jwt.MapClaims{"sub": "alice", "iss": "nope"}),
+		"unknown key":  sign("bar", jwt.MapClaims{"sub": "alice", "iss": "benthos"}),
+		"not a jwt":    "nope",
+	} {
+		rec := authTestRequest(h, map[string]string{"Authorization": "Bearer " + token})
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"), name)
+	}
+}
+
+func TestAuthOIDCIntrospection(t *testing.T) {
+	introspect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		require.Equal(t, "client", user)
+		require.Equal(t, "secret", pass)
+		require.NoError(t, r.ParseForm())
+
+		res := map[string]any{"active": false}
+		if r.PostForm.Get("token") == "good" {
+			res = map[string]any{"active": true, "sub": "bob", "scope": "read"}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(introspect.Close)
+
+	conf := NewAuthConfig()
+	conf.OIDC.Enabled = true
+	conf.OIDC.IntrospectionURL = introspect.URL
+	conf.OIDC.ClientID = "client"
+	conf.OIDC.ClientSecret = "secret"
+	h := authTestHandler(t, conf)
+
+	rec := authTestRequest(h, map[string]string{"Authorization": "Bearer good"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "oidc:bob", rec.Body.String())
+
+	rec = authTestRequest(h, map[string]string{"Authorization": "Bearer bad"})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAuthValidate(t *testing.T) {
+	conf := NewAuthConfig()
+	conf.APIKeys.Enabled = true
+	_, err := NewAuthenticator(conf)
+	require.Error(t, err)
+
+	conf = NewAuthConfig()
+	conf.JWT.Enabled = true
+	_, err = NewAuthenticator(conf)
+	require.Error(t, err)
+
+	conf = NewAuthConfig()
+	conf.OIDC.Enabled = true
+	_, err = NewAuthenticator(conf)
+	require.Error(t, err)
+}
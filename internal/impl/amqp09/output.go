@@ -1,6 +1,7 @@
 package amqp09
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -13,10 +14,13 @@ import (
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/batch/policy"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/component/output/batcher"
 	"github.com/benthosdev/benthos/v4/internal/component/output/processors"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -35,13 +39,13 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
-		return output.OnlySinglePayloads(w), nil
+		return batcher.NewFromConfig(c.AMQP09.Batching, w, nm)
 	}), docs.ComponentSpec{
 		Name: "amqp_0_9",
 		Summary: `
 Sends messages to an AMQP (0.91) exchange. AMQP is a messaging protocol used by
 various message brokers, including RabbitMQ.`,
-		Description: output.Description(true, false, `
+		Description: output.Description(true, true, `
 The metadata from each message are delivered as headers.
 
 It's possible for this output type to create the target exchange by setting
@@ -52,7 +56,13 @@ TLS is automatic when connecting to an `+"`amqps`"+` URL, but custom
 settings can be enabled in the `+"`tls`"+` section.
 
 The fields 'key' and 'type' can be dynamically set using function interpolations described
-[here](/docs/configuration/interpolation#bloblang-queries).`),
+[here](/docs/configuration/interpolation#bloblang-queries).
+
+### Publisher Confirms
+
+All messages of a batch are published before awaiting the confirms of the broker, and messages that are nacked by the broker are marked as failed individually rather than failing the entire batch. When the `+"`mandatory`"+` flag is set messages that cannot be routed to a queue are returned by the broker and are treated as nacks, with an error containing the reply code and reason given by the broker. When combined with a `+"[`fallback`](/docs/components/outputs/fallback)"+` output this error is made available to the following outputs within the metadata field `+"`fallback_error`"+`, and can be used to route unroutable messages to an alternative tier.
+
+Batches are published one at a time when either the `+"`mandatory`"+` or `+"`immediate`"+` flags are set, as returned messages would otherwise be indistinguishable between batches.`),
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("urls",
 				"A list of URLs to connect to. The first URL to successfully establish a connection will be used until the connection is closed. If an item of the list contains commas it will be expanded into multiple URLs.",
@@ -75,6 +85,7 @@ The fields 'key' and 'type' can be dynamically set using function interpolations
 			docs.FieldObject("metadata", "Specify criteria for which metadata values are attached to messages as headers.").WithChildren(metadata.ExcludeFilterFields()...),
 			docs.FieldString("priority", "Set the priority of each message with a dynamic interpolated expression.", "0", `${! meta("amqp_priority") }`, `${! json("doc.priority") }`).IsInterpolated().Advanced().HasDefault(""),
 			docs.FieldInt("max_in_flight", "The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").HasDefault(64),
+			policy.FieldSpec().AtVersion("4.9.0"),
 			docs.FieldBool("persistent", "Whether message delivery should be persistent (transient by default).").Advanced().HasDefault(false),
 			docs.FieldBool("mandatory", "Whether to set the mandatory flag on published messages. When set if a published message is routed to zero queues it is returned.").Advanced().HasDefault(false),
 			docs.FieldBool("immediate", "Whether to set the immediate flag on published messages. When set if there are no ready consumers of a queue then the message is dropped instead of waiting.").Advanced().HasDefault(false),
@@ -106,7 +117,9 @@ type amqp09Writer struct {
 
 	conn       *amqp.Connection
 	amqpChan   *amqp.Channel
+	publisher  amqp09Publisher
 	returnChan <-chan amqp.Return
+	returnLock sync.Mutex
 	timeout    time.Duration
 
 	deliveryMode uint8
@@ -207,6 +220,7 @@ func (a *amqp09Writer) Connect(ctx context.Context) error {
 
 	a.conn = conn
 	a.amqpChan = amqpChan
+	a.publisher = channelPublisher{ch: amqpChan}
 	if a.conf.Mandatory || a.conf.Immediate {
 		a.returnChan = amqpChan.NotifyReturn(make(chan amqp.Return, 1))
 	}
@@ -222,6 +236,7 @@ func (a *amqp09Writer) disconnect() error {
 
 	if a.amqpChan != nil {
 		a.amqpChan = nil
+		a.publisher = nil
 	}
 	if a.conn != nil {
 		if err := a.conn.Close(); err != nil {
@@ -232,14 +247,44 @@ func (a *amqp09Writer) disconnect() error {
 	return nil
 }
 
+// amqp09Confirmation is a pending confirm of a published message, which
+// reports whether the broker acknowledged the message once it arrives.
+type amqp09Confirmation interface {
+	Wait() bool
+}
+
+// amqp09Publisher publishes messages with deferred confirms, and is
+// implemented by channels in confirm mode.
+type amqp09Publisher interface {
+	publish(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (amqp09Confirmation, error)
+}
+
+type channelPublisher struct {
+	ch *amqp.Channel
+}
+
+func (c channelPublisher) publish(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (amqp09Confirmation, error) {
+	return c.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+}
+
+// errReturned is the error reported for messages that were returned by the
+// broker as unroutable, and carries the reason given by the broker.
+type errReturned struct {
+	code uint16
+	text string
+}
+
+func (e *errReturned) Error() string {
+	return fmt.Sprintf("message returned by broker with reply code %v: %v", e.code, e.text)
+}
+
 func (a *amqp09Writer) WriteBatch(wctx context.Context, msg message.Batch) error {
 	a.connLock.RLock()
-	conn := a.conn
-	amqpChan := a.amqpChan
+	publisher := a.publisher
 	returnChan := a.returnChan
 	a.connLock.RUnlock()
 
-	if conn == nil {
+	if publisher == nil {
 		return component.ErrNotConnected
 	}
 
@@ -254,8 +299,19 @@ func (a *amqp09Writer) WriteBatch(wctx context.Context, msg message.Batch) error
 		ctx = wctx
 	}
 
-	return output.IterateBatchedSend(msg, func(i int, p *message.Part) error {
-		bindingKey := strings.ReplaceAll(a.key.String(i, msg), "/", ".")
+	if returnChan != nil {
+		// Returned messages can only be attributed to a batch when no other
+		// batches are being published at the same time.
+		a.returnLock.Lock()
+		defer a.returnLock.Unlock()
+	}
+
+	publishings := make([]amqp.Publishing, msg.Len())
+	bindingKeys := make([]string, msg.Len())
+	confirms := make([]amqp09Confirmation, msg.Len())
+
+	if err := msg.Iter(func(i int, p *message.Part) error {
+		bindingKeys[i] = strings.ReplaceAll(a.key.String(i, msg), "/", ".")
 		msgType := strings.ReplaceAll(a.msgType.String(i, msg), "/", ".")
 		contentType := a.contentType.String(i, msg)
 		contentEncoding := a.contentEncoding.String(i, msg)
@@ -278,44 +334,128 @@ func (a *amqp09Writer) WriteBatch(wctx context.Context, msg message.Batch) error
 			return nil
 		})
 
-		conf, err := amqpChan.PublishWithDeferredConfirmWithContext(
+		publishings[i] = amqp.Publishing{
+			Headers:         headers,
+			ContentType:     contentType,
+			ContentEncoding: contentEncoding,
+			Body:            p.AsBytes(),
+			DeliveryMode:    a.deliveryMode, // 1=non-persistent, 2=persistent
+			Priority:        priority,       // 0-9
+			Type:            msgType,
+			// a bunch of application/implementation-specific fields
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Publish the entire batch before awaiting any confirms so that the
+	// broker can confirm them together.
+	for i, pub := range publishings {
+		var err error
+		if confirms[i], err = publisher.publish(
 			ctx,
 			a.conf.Exchange,  // publish to an exchange
-			bindingKey,       // routing to 0 or more queues
+			bindingKeys[i],   // routing to 0 or more queues
 			a.conf.Mandatory, // mandatory
 			a.conf.Immediate, // immediate
-			amqp.Publishing{
-				Headers:         headers,
-				ContentType:     contentType,
-				ContentEncoding: contentEncoding,
-				Body:            p.AsBytes(),
-				DeliveryMode:    a.deliveryMode, // 1=non-persistent, 2=persistent
-				Priority:        priority,       // 0-9
-				Type:            msgType,
-				// a bunch of application/implementation-specific fields
-			},
-		)
-		if err != nil {
+			pub,
+		); err != nil {
 			_ = a.disconnect()
 			a.log.Errorf("Failed to send message: %v\n", err)
 			return component.ErrNotConnected
 		}
-		if !conf.Wait() {
-			a.log.Errorln("Failed to acknowledge message.")
-			return component.ErrNoAck
+	}
+
+	acks := make([]bool, len(confirms))
+	confirmed := make(chan struct{})
+	go func() {
+		for i, c := range confirms {
+			acks[i] = c.Wait()
+		}
+		close(confirmed)
+	}()
+
+	// The broker sends a basic.return for a message before its confirm, and
+	// therefore once all confirms have arrived every return for the batch has
+	// been delivered to the return channel.
+	var returns []amqp.Return
+	var returnsClosed bool
+	if returnChan != nil {
+	awaitConfirms:
+		for {
+			select {
+			case r, open := <-returnChan:
+				if !open {
+					returnsClosed = true
+					returnChan = nil
+					continue
+				}
+				returns = append(returns, r)
+			case <-confirmed:
+				break awaitConfirms
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
-		if returnChan != nil {
+	drainReturns:
+		for returnChan != nil {
 			select {
-			case _, open := <-returnChan:
+			case r, open := <-returnChan:
 				if !open {
-					return fmt.Errorf("acknowledgement not supported, ensure server supports immediate and mandatory flags")
+					returnsClosed = true
+					break drainReturns
 				}
-				return component.ErrNoAck
+				returns = append(returns, r)
 			default:
+				break drainReturns
 			}
 		}
-		return nil
-	})
+	} else {
+		select {
+		case <-confirmed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if returnsClosed {
+		return errors.New("acknowledgement not supported, ensure server supports immediate and mandatory flags")
+	}
+
+	var bErr *batch.Error
+	setFailed := func(i int, err error) {
+		if bErr == nil {
+			bErr = batch.NewError(msg, err)
+		}
+		bErr.Failed(i, err)
+	}
+
+	// Returns do not reference the delivery they belong to, and so they're
+	// matched against the batch by routing key and contents. Any messages that
+	// cannot be told apart are identical and so it makes no difference which
+	// of them is marked as failed.
+	returned := make([]bool, len(publishings))
+	for _, r := range returns {
+		for i, pub := range publishings {
+			if returned[i] || r.RoutingKey != bindingKeys[i] || !bytes.Equal(r.Body, pub.Body) {
+				continue
+			}
+			returned[i] = true
+			a.log.Errorf("Message returned by broker with reply code %v: %v\n", r.ReplyCode, r.ReplyText)
+			setFailed(i, &errReturned{code: r.ReplyCode, text: r.ReplyText})
+			break
+		}
+	}
+	for i, ack := range acks {
+		if !ack && !returned[i] {
+			a.log.Errorln("Failed to acknowledge message.")
+			setFailed(i, component.ErrNoAck)
+		}
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
 }
 
 func (a *amqp09Writer) Close(context.Context) error {
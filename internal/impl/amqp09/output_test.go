@@ -0,0 +1,205 @@
+package amqp09
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type fakeConfirmation struct {
+	ack  bool
+	done chan struct{}
+}
+
+func (f *fakeConfirmation) Wait() bool {
+	<-f.done
+	return f.ack
+}
+
+// fakePublisher mimics a channel in confirm mode, where the outcome of each
+// publish is determined by a closure that returns whether the message is
+// acknowledged and whether it is returned by the broker.
+type fakePublisher struct {
+	returns   chan amqp.Return
+	outcome   func(key string, msg amqp.Publishing) (ack, returned, confirmed bool)
+	published []amqp.Publishing
+}
+
+func (f *fakePublisher) publish(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (amqp09Confirmation, error) {
+	f.published = append(f.published, msg)
+
+	ack, returned, confirmed := f.outcome(key, msg)
+	if returned {
+		f.returns <- amqp.Return{
+			ReplyCode:  312,
+			ReplyText:  "NO_ROUTE",
+			RoutingKey: key,
+			Body:       msg.Body,
+		}
+	}
+
+	c := &fakeConfirmation{ack: ack, done: make(chan struct{})}
+	if confirmed {
+		close(c.done)
+	}
+	return c, nil
+}
+
+func testAMQP09Writer(t *testing.T, mandatory bool, outcome func(key string, msg amqp.Publishing) (ack, returned, confirmed bool)) (*amqp09Writer, *fakePublisher) {
+	t.Helper()
+
+	conf := output.NewAMQPConfig()
+	conf.URLs = []string{"amqp://localhost:5672/"}
+	conf.BindingKey = `${! meta("key") }`
+	conf.Mandatory = mandatory
+
+	w, err := newAMQP09Writer(mock.NewManager(), conf, log.Noop())
+	require.NoError(t, err)
+
+	pub := &fakePublisher{
+		returns: make(chan amqp.Return, 10),
+		outcome: outcome,
+	}
+	w.publisher = pub
+	if mandatory {
+		w.returnChan = pub.returns
+	}
+	return w, pub
+}
+
+func testAMQP09Batch(parts ...[2]string) message.Batch {
+	b := message.QuickBatch(nil)
+	for _, p := range parts {
+		part := message.NewPart([]byte(p[1]))
+		part.MetaSetMut("key", p[0])
+		b = append(b, part)
+	}
+	return b
+}
+
+// failedIndexes returns the errors of each failed message of a batch error.
+func failedIndexes(t *testing.T, err error) map[int]error {
+	t.Helper()
+
+	var bErr *batch.Error
+	require.ErrorAs(t, err, &bErr)
+
+	failed := map[int]error{}
+	bErr.WalkParts(func(i int, _ *message.Part, err error) bool {
+		if err != nil {
+			failed[i] = err
+		}
+		return true
+	})
+	return failed
+}
+
+func TestAMQP09WriterConfirms(t *testing.T) {
+	w, pub := testAMQP09Writer(t, false, func(key string, msg amqp.Publishing) (bool, bool, bool) {
+		return string(msg.Body) != "nacked", false, true
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), testAMQP09Batch(
+		[2]string{"foo", "first"},
+		[2]string{"foo", "second"},
+	)))
+	assert.Len(t, pub.published, 2)
+
+	err := w.WriteBatch(context.Background(), testAMQP09Batch(
+		[2]string{"foo", "first"},
+		[2]string{"foo", "nacked"},
+		[2]string{"foo", "third"},
+	))
+	failed := failedIndexes(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, component.ErrNoAck, failed[1])
+}
+
+func TestAMQP09WriterMandatoryReturns(t *testing.T) {
+	// The broker acknowledges returned messages, and so only the returns
+	// identify which messages were not routed.
+	w, _ := testAMQP09Writer(t, true, func(key string, msg amqp.Publishing) (bool, bool, bool) {
+		return true, key == "unroutable", true
+	})
+
+	err := w.WriteBatch(context.Background(), testAMQP09Batch(
+		[2]string{"foo", "first"},
+		[2]string{"unroutable", "second"},
+		[2]string{"foo", "third"},
+	))
+	failed := failedIndexes(t, err)
+	require.Len(t, failed, 1)
+
+	var rErr *errReturned
+	require.ErrorAs(t, failed[1], &rErr)
+	assert.Equal(t, uint16(312), rErr.code)
+	assert.Equal(t, "NO_ROUTE", rErr.text)
+	assert.Equal(t, "message returned by broker with reply code 312: NO_ROUTE", rErr.Error())
+}
+
+func TestAMQP09WriterAmbiguousReturns(t *testing.T) {
+	w, _ := testAMQP09Writer(t, true, func(key string, msg amqp.Publishing) (bool, bool, bool) {
+		return true, key == "unroutable", true
+	})
+
+	// Messages with the same contents are told apart by their routing key.
+	err := w.WriteBatch(context.Background(), testAMQP09Batch(
+		[2]string{"foo", "same"},
+		[2]string{"unroutable", "same"},
+		[2]string{"foo", "same"},
+	))
+	failed := failedIndexes(t, err)
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed, 1)
+
+	// Identical messages each match a single return.
+	err = w.WriteBatch(context.Background(), testAMQP09Batch(
+		[2]string{"unroutable", "same"},
+		[2]string{"foo", "other"},
+		[2]string{"unroutable", "same"},
+	))
+	failed = failedIndexes(t, err)
+	require.Len(t, failed, 2)
+	assert.Contains(t, failed, 0)
+	assert.Contains(t, failed, 2)
+}
+
+func TestAMQP09WriterContextCancelled(t *testing.T) {
+	for _, mandatory := range []bool{false, true} {
+		w, _ := testAMQP09Writer(t, mandatory, func(key string, msg amqp.Publishing) (bool, bool, bool) {
+			return true, false, false
+		})
+
+		ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- w.WriteBatch(ctx, testAMQP09Batch([2]string{"foo", "first"}))
+		}()
+
+		select {
+		case err := <-errChan:
+			assert.True(t, errors.Is(err, context.DeadlineExceeded), "mandatory: %v, err: %v", mandatory, err)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("write was not abandoned with mandatory: %v", mandatory)
+		}
+		done()
+	}
+}
+
+func TestAMQP09WriterNotConnected(t *testing.T) {
+	w, _ := testAMQP09Writer(t, false, nil)
+	w.publisher = nil
+	require.Equal(t, component.ErrNotConnected, w.WriteBatch(context.Background(), testAMQP09Batch([2]string{"foo", "first"})))
+}
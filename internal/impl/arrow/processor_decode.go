@@ -0,0 +1,95 @@
+package arrow
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func arrowDecodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Summary("Decodes [Apache Arrow](https://arrow.apache.org/) records in the IPC streaming format into a batch of structured messages.").
+		Field(service.NewBoolField("binary_as_string").
+			Description("Whether to extract `BINARY` values as strings rather than byte slices. Enabling this field makes serialising the data as JSON more intuitive as `[]byte` values are serialised as base64 encoded strings by default.").
+			Default(false)).
+		Description(`
+Each row of every record within the stream becomes a message, with each column becoming a field of the resulting object. Integer columns are extracted as 64-bit integers, floating point columns as 64-bit floats, ` + "`BINARY`" + ` columns as byte slices and timestamps in UTC.
+
+The metadata of the original message is copied to each message of the resulting batch.
+`).
+		Version("4.9.0")
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"arrow_decode", arrowDecodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newArrowDecodeProcessorFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newArrowDecodeProcessorFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*arrowDecodeProcessor, error) {
+	binaryAsString, err := conf.FieldBool("binary_as_string")
+	if err != nil {
+		return nil, err
+	}
+	return newArrowDecodeProcessor(logger, binaryAsString), nil
+}
+
+type arrowDecodeProcessor struct {
+	logger         *service.Logger
+	binaryAsString bool
+	mem            memory.Allocator
+}
+
+func newArrowDecodeProcessor(logger *service.Logger, binaryAsString bool) *arrowDecodeProcessor {
+	return &arrowDecodeProcessor{
+		logger:         logger,
+		binaryAsString: binaryAsString,
+		mem:            memory.NewGoAllocator(),
+	}
+}
+
+func (s *arrowDecodeProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	rdr, err := ipc.NewReader(bytes.NewReader(mBytes), ipc.WithAllocator(s.mem))
+	if err != nil {
+		return nil, err
+	}
+	defer rdr.Release()
+
+	var resBatch service.MessageBatch
+	for rdr.Next() {
+		rows, err := recordToRows(rdr.Record(), s.binaryAsString)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			newMsg := msg.Copy()
+			newMsg.SetStructuredMut(row)
+			resBatch = append(resBatch, newMsg)
+		}
+	}
+	if err := rdr.Err(); err != nil {
+		return nil, err
+	}
+	return resBatch, nil
+}
+
+func (s *arrowDecodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func arrowEncodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Summary("Encodes a batch of structured messages into a single columnar [Apache Arrow](https://arrow.apache.org/) record in the IPC streaming format.").
+		Field(arrowSchemaConfig()).
+		Description(`
+Converting a batch into a columnar representation once allows analytical sinks that natively accept Arrow, such as ClickHouse with the `+"`ArrowStream`"+` input format, to ingest wide events without each row being serialised and parsed individually.
+
+The resulting message retains the metadata of the first message of the batch, and can be expanded back into a batch of rows with the `+"[`arrow_decode`](/docs/components/processors/arrow_decode)"+` processor. Other processors are not aware of the columnar format and treat the encoded record as a single opaque message, and therefore this processor is best placed at the end of the batch processors of an output.
+`).
+		Version("4.9.0").
+		Example("Inserting into ClickHouse",
+			"In this example we use the batching mechanism of an `http_client` output to collect a batch of messages in memory, which is then converted into a single Arrow record and inserted into a ClickHouse table.",
+			`
+output:
+  http_client:
+    url: 'http://localhost:8123/?query=INSERT%20INTO%20events%20FORMAT%20ArrowStream'
+    verb: POST
+    batching:
+      count: 10000
+      period: 10s
+      processors:
+        - arrow_encode:
+            schema:
+              - name: id
+                type: INT64
+              - name: timestamp
+                type: TIMESTAMP
+              - name: body
+                type: UTF8
+                optional: true
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"arrow_encode", arrowEncodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newArrowEncodeProcessorFromConfig(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func newArrowEncodeProcessorFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*arrowEncodeProcessor, error) {
+	var schema *arrow.Schema
+	if schemaConfs, _ := conf.FieldObjectList("schema"); len(schemaConfs) > 0 {
+		var err error
+		if schema, err = arrowSchemaFromConfig(schemaConfs); err != nil {
+			return nil, err
+		}
+	}
+	return newArrowEncodeProcessor(logger, schema), nil
+}
+
+type arrowEncodeProcessor struct {
+	logger *service.Logger
+	schema *arrow.Schema
+	mem    memory.Allocator
+}
+
+func newArrowEncodeProcessor(logger *service.Logger, schema *arrow.Schema) *arrowEncodeProcessor {
+	return &arrowEncodeProcessor{
+		logger: logger,
+		schema: schema,
+		mem:    memory.NewGoAllocator(),
+	}
+}
+
+func (s *arrowEncodeProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	rows := make([]map[string]any, len(batch))
+	for i, m := range batch {
+		ms, err := m.AsStructured()
+		if err != nil {
+			return nil, err
+		}
+
+		obj, isObj := ms.(map[string]any)
+		if !isObj {
+			return nil, fmt.Errorf("unable to encode message type %T as arrow row", ms)
+		}
+		rows[i] = obj
+	}
+
+	schema := s.schema
+	if schema == nil {
+		schema = inferArrowSchema(rows)
+	}
+
+	builder := array.NewRecordBuilder(s.mem, schema)
+	defer builder.Release()
+
+	rec, err := rowsToRecord(builder, rows)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+
+	buf := bytes.NewBuffer(nil)
+	wtr := ipc.NewWriter(buf, ipc.WithSchema(schema), ipc.WithAllocator(s.mem))
+	if err := wtr.Write(rec); err != nil {
+		return nil, err
+	}
+	if err := wtr.Close(); err != nil {
+		return nil, err
+	}
+
+	outMsg := batch[0]
+	outMsg.SetBytes(buf.Bytes())
+	return []service.MessageBatch{{outMsg}}, nil
+}
+
+func (s *arrowEncodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
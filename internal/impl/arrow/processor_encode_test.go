@@ -0,0 +1,156 @@
+package arrow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestArrowEncodeDecodeRoundTrip(t *testing.T) {
+	encodeConf, err := arrowEncodeProcessorConfig().ParseYAML(`
+schema:
+  - { name: id, type: INT64 }
+  - { name: weight, type: DOUBLE }
+  - { name: active, type: BOOLEAN }
+  - { name: name, type: UTF8, optional: true }
+  - { name: content, type: BINARY }
+  - { name: ts, type: TIMESTAMP }
+`, nil)
+	require.NoError(t, err)
+
+	encodeProc, err := newArrowEncodeProcessorFromConfig(encodeConf, nil)
+	require.NoError(t, err)
+
+	decodeProc := newArrowDecodeProcessor(nil, false)
+
+	tctx := context.Background()
+
+	inBatch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"weight":1.5,"active":true,"name":"foo","content":"hello","ts":"2022-10-01T10:00:00Z"}`)),
+		service.NewMessage([]byte(`{"id":2,"weight":2,"active":false,"content":"world","ts":1664618400}`)),
+	}
+	inBatch[0].MetaSet("foo", "bar")
+
+	encoded, err := encodeProc.ProcessBatch(tctx, inBatch)
+	require.NoError(t, err)
+	require.Len(t, encoded, 1)
+	require.Len(t, encoded[0], 1)
+
+	v, _ := encoded[0][0].MetaGet("foo")
+	assert.Equal(t, "bar", v)
+
+	decoded, err := decodeProc.Process(tctx, encoded[0][0])
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+
+	var rows []any
+	for _, m := range decoded {
+		s, err := m.AsStructured()
+		require.NoError(t, err)
+		rows = append(rows, s)
+
+		v, _ := m.MetaGet("foo")
+		assert.Equal(t, "bar", v)
+	}
+
+	assert.Equal(t, []any{
+		map[string]any{
+			"id":      int64(1),
+			"weight":  1.5,
+			"active":  true,
+			"name":    "foo",
+			"content": []byte("hello"),
+			"ts":      time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC),
+		},
+		map[string]any{
+			"id":      int64(2),
+			"weight":  2.0,
+			"active":  false,
+			"name":    nil,
+			"content": []byte("world"),
+			"ts":      time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC),
+		},
+	}, rows)
+}
+
+func TestArrowEncodeInferredSchema(t *testing.T) {
+	encodeConf, err := arrowEncodeProcessorConfig().ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	encodeProc, err := newArrowEncodeProcessorFromConfig(encodeConf, nil)
+	require.NoError(t, err)
+
+	decodeConf, err := arrowDecodeProcessorConfig().ParseYAML(`
+binary_as_string: true
+`, nil)
+	require.NoError(t, err)
+
+	decodeProc, err := newArrowDecodeProcessorFromConfig(decodeConf, nil)
+	require.NoError(t, err)
+
+	tctx := context.Background()
+
+	encoded, err := encodeProc.ProcessBatch(tctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"a":1,"b":"foo","c":{"nested":true},"d":1}`)),
+		service.NewMessage([]byte(`{"a":2.5,"b":10,"c":null}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, encoded, 1)
+
+	decoded, err := decodeProc.Process(tctx, encoded[0][0])
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+
+	var rows []any
+	for _, m := range decoded {
+		s, err := m.AsStructured()
+		require.NoError(t, err)
+		rows = append(rows, s)
+	}
+
+	assert.Equal(t, []any{
+		map[string]any{
+			"a": 1.0,
+			"b": "foo",
+			"c": `{"nested":true}`,
+			"d": int64(1),
+		},
+		map[string]any{
+			"a": 2.5,
+			"b": "10",
+			"c": nil,
+			"d": nil,
+		},
+	}, rows)
+}
+
+func TestArrowEncodeErrors(t *testing.T) {
+	encodeConf, err := arrowEncodeProcessorConfig().ParseYAML(`
+schema:
+  - { name: id, type: INT64 }
+`, nil)
+	require.NoError(t, err)
+
+	encodeProc, err := newArrowEncodeProcessorFromConfig(encodeConf, nil)
+	require.NoError(t, err)
+
+	tctx := context.Background()
+
+	_, err = encodeProc.ProcessBatch(tctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"nope":1}`)),
+	})
+	require.EqualError(t, err, "field id: missing and non-optional")
+
+	_, err = encodeProc.ProcessBatch(tctx, service.MessageBatch{
+		service.NewMessage([]byte(`[1,2,3]`)),
+	})
+	require.EqualError(t, err, "unable to encode message type []interface {} as arrow row")
+
+	_, err = newArrowDecodeProcessor(nil, false).Process(tctx, service.NewMessage([]byte(`not arrow`)))
+	require.Error(t, err)
+}
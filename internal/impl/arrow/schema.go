@@ -0,0 +1,282 @@
+package arrow
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func arrowSchemaConfig() *service.ConfigField {
+	return service.NewObjectListField("schema",
+		service.NewStringField("name").Description("The name of the column."),
+		service.NewStringEnumField("type", "BOOLEAN", "INT64", "DOUBLE", "UTF8", "BINARY", "TIMESTAMP").
+			Description("The type of the column. Values of a `TIMESTAMP` column are stored with microsecond precision in UTC."),
+		service.NewBoolField("optional").Description("Whether the column is optional, in which case missing and null values are permitted.").Default(false),
+	).
+		Description("An optional list of columns that each record must consist of. When omitted the schema is inferred from the fields of each batch, where columns of conflicting types are encoded as JSON strings and integer columns that contain floats are widened to DOUBLE.").
+		Optional()
+}
+
+func arrowTypeFromString(name, typeStr string) (arrow.DataType, error) {
+	switch typeStr {
+	case "BOOLEAN":
+		return arrow.FixedWidthTypes.Boolean, nil
+	case "INT64":
+		return arrow.PrimitiveTypes.Int64, nil
+	case "DOUBLE":
+		return arrow.PrimitiveTypes.Float64, nil
+	case "UTF8":
+		return arrow.BinaryTypes.String, nil
+	case "BINARY":
+		return arrow.BinaryTypes.Binary, nil
+	case "TIMESTAMP":
+		return arrow.FixedWidthTypes.Timestamp_us, nil
+	}
+	return nil, fmt.Errorf("field %v type of '%v' not recognised", name, typeStr)
+}
+
+func arrowSchemaFromConfig(columnConfs []*service.ParsedConfig) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, 0, len(columnConfs))
+	for _, colConf := range columnConfs {
+		name, err := colConf.FieldString("name")
+		if err != nil {
+			return nil, err
+		}
+		typeStr, err := colConf.FieldString("type")
+		if err != nil {
+			return nil, err
+		}
+		dType, err := arrowTypeFromString(name, typeStr)
+		if err != nil {
+			return nil, err
+		}
+		optional, err := colConf.FieldBool("optional")
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: dType, Nullable: optional})
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+//------------------------------------------------------------------------------
+
+// inferValueType returns the arrow type most suitable for a structured value,
+// where values that have no columnar equivalent are stored as JSON strings.
+func inferValueType(v any) arrow.DataType {
+	switch t := v.(type) {
+	case bool:
+		return arrow.FixedWidthTypes.Boolean
+	case int, int32, int64, uint32, uint64:
+		return arrow.PrimitiveTypes.Int64
+	case float32, float64:
+		return arrow.PrimitiveTypes.Float64
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return arrow.PrimitiveTypes.Int64
+		}
+		return arrow.PrimitiveTypes.Float64
+	case []byte:
+		return arrow.BinaryTypes.Binary
+	case time.Time:
+		return arrow.FixedWidthTypes.Timestamp_us
+	}
+	return arrow.BinaryTypes.String
+}
+
+// mergeTypes returns a type that both a and b can be converted to.
+func mergeTypes(a, b arrow.DataType) arrow.DataType {
+	if arrow.TypeEqual(a, b) {
+		return a
+	}
+	isNumber := func(t arrow.DataType) bool {
+		return t.ID() == arrow.INT64 || t.ID() == arrow.FLOAT64
+	}
+	if isNumber(a) && isNumber(b) {
+		return arrow.PrimitiveTypes.Float64
+	}
+	return arrow.BinaryTypes.String
+}
+
+// inferArrowSchema creates a schema from the fields of a batch of objects,
+// columns are sorted by name and are nullable when a row is missing them.
+func inferArrowSchema(rows []map[string]any) *arrow.Schema {
+	types := map[string]arrow.DataType{}
+	counts := map[string]int{}
+	for _, row := range rows {
+		for k, v := range row {
+			if v == nil {
+				continue
+			}
+			counts[k]++
+			vType := inferValueType(v)
+			if existing, exists := types[k]; exists {
+				types[k] = mergeTypes(existing, vType)
+			} else {
+				types[k] = vType
+			}
+		}
+		for k := range row {
+			if _, exists := types[k]; !exists {
+				// Columns consisting only of null values.
+				types[k] = arrow.BinaryTypes.String
+			}
+		}
+	}
+
+	names := make([]string, 0, len(types))
+	for k := range types {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	fields := make([]arrow.Field, len(names))
+	for i, k := range names {
+		fields[i] = arrow.Field{Name: k, Type: types[k], Nullable: counts[k] < len(rows)}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+//------------------------------------------------------------------------------
+
+func appendValue(f arrow.Field, b array.Builder, v any) error {
+	if v == nil {
+		if !f.Nullable {
+			return fmt.Errorf("field %v: missing and non-optional", f.Name)
+		}
+		b.AppendNull()
+		return nil
+	}
+
+	var err error
+	switch t := b.(type) {
+	case *array.BooleanBuilder:
+		var bv bool
+		if bv, err = query.IGetBool(v); err == nil {
+			t.Append(bv)
+		}
+	case *array.Int64Builder:
+		var iv int64
+		if iv, err = query.IGetInt(v); err == nil {
+			t.Append(iv)
+		}
+	case *array.Float64Builder:
+		var fv float64
+		if fv, err = query.IGetNumber(v); err == nil {
+			t.Append(fv)
+		}
+	case *array.StringBuilder:
+		if sv, serr := query.IGetString(v); serr == nil {
+			t.Append(sv)
+		} else {
+			var jBytes []byte
+			if jBytes, err = json.Marshal(v); err == nil {
+				t.Append(string(jBytes))
+			}
+		}
+	case *array.BinaryBuilder:
+		var bv []byte
+		if bv, err = query.IGetBytes(v); err == nil {
+			t.Append(bv)
+		}
+	case *array.TimestampBuilder:
+		var tv time.Time
+		if tv, err = query.IGetTimestamp(v); err == nil {
+			t.Append(arrow.Timestamp(tv.UnixMicro()))
+		}
+	default:
+		err = fmt.Errorf("columns of type %v are not currently supported", f.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("field %v: %w", f.Name, err)
+	}
+	return nil
+}
+
+// rowsToRecord builds a single columnar record from a batch of objects. The
+// caller is responsible for releasing the record.
+func rowsToRecord(b *array.RecordBuilder, rows []map[string]any) (array.Record, error) {
+	fields := b.Schema().Fields()
+	b.Reserve(len(rows))
+	for _, row := range rows {
+		for i, f := range fields {
+			if err := appendValue(f, b.Field(i), row[f.Name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+func columnValue(col array.Interface, i int, binaryAsString bool) any {
+	if col.IsNull(i) {
+		return nil
+	}
+	switch t := col.(type) {
+	case *array.Boolean:
+		return t.Value(i)
+	case *array.Int8:
+		return int64(t.Value(i))
+	case *array.Int16:
+		return int64(t.Value(i))
+	case *array.Int32:
+		return int64(t.Value(i))
+	case *array.Int64:
+		return t.Value(i)
+	case *array.Uint8:
+		return uint64(t.Value(i))
+	case *array.Uint16:
+		return uint64(t.Value(i))
+	case *array.Uint32:
+		return uint64(t.Value(i))
+	case *array.Uint64:
+		return t.Value(i)
+	case *array.Float32:
+		return float64(t.Value(i))
+	case *array.Float64:
+		return t.Value(i)
+	case *array.String:
+		return t.Value(i)
+	case *array.Binary:
+		if binaryAsString {
+			return string(t.Value(i))
+		}
+		// Values reference the record buffer, which is released after decoding.
+		return append([]byte(nil), t.Value(i)...)
+	case *array.Timestamp:
+		unit := t.DataType().(*arrow.TimestampType).Unit
+		return time.Unix(0, int64(t.Value(i))*int64(unit.Multiplier())).UTC()
+	}
+	return nil
+}
+
+// recordToRows expands a columnar record into a slice of objects.
+func recordToRows(rec array.Record, binaryAsString bool) ([]map[string]any, error) {
+	fields := rec.Schema().Fields()
+	for i, f := range fields {
+		switch rec.Column(i).(type) {
+		case *array.Boolean, *array.Int8, *array.Int16, *array.Int32, *array.Int64,
+			*array.Uint8, *array.Uint16, *array.Uint32, *array.Uint64,
+			*array.Float32, *array.Float64, *array.String, *array.Binary, *array.Timestamp:
+		default:
+			return nil, fmt.Errorf("field %v: columns of type %v are not currently supported", f.Name, f.Type)
+		}
+	}
+
+	rows := make([]map[string]any, rec.NumRows())
+	for r := range rows {
+		row := make(map[string]any, len(fields))
+		for i, f := range fields {
+			row[f.Name] = columnValue(rec.Column(i), r, binaryAsString)
+		}
+		rows[r] = row
+	}
+	return rows, nil
+}
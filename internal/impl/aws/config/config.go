@@ -36,7 +36,22 @@ func SessionFields() []*service.ConfigField {
 				Default("").Advanced(),
 			service.NewStringField("role_external_id").
 				Description("An external ID to provide when assuming a role.").
-				Default("").Advanced()).
+				Default("").Advanced(),
+			service.NewObjectListField("role_chain",
+				service.NewStringField("role").
+					Description("A role ARN to assume, which must not be empty.").
+					Default(""),
+				service.NewStringField("role_external_id").
+					Description("An external ID to provide when assuming the role.").
+					Default("")).
+				Description("A list of roles to assume in order after `role`, where each role is assumed with the credentials of the previous role. This allows cross-account access where a role can only be assumed from an intermediate account.").
+				Default([]any{}).Advanced().Version("4.9.0"),
+			service.NewStringField("web_identity_token_file").
+				Description("A path to a web identity token file used to assume the first role, such as the token projected into pods by [EKS IAM roles for service accounts](https://docs.aws.amazon.com/eks/latest/userguide/iam-roles-for-service-accounts.html). When the environment variables `AWS_WEB_IDENTITY_TOKEN_FILE` and `AWS_ROLE_ARN` are set and no other credentials are configured these are used automatically.").
+				Default("").Advanced().Version("4.9.0"),
+			service.NewStringField("sts_endpoint").
+				Description("A custom endpoint for the STS API used when assuming roles. Requests to STS are never sent to the `endpoint` of the component.").
+				Default("").Advanced().Version("4.9.0")).
 			Advanced().
 			Description("Optional manual configuration of AWS credentials to use. More information can be found [in this document](/docs/guides/cloud/aws)."),
 	}
@@ -50,6 +50,12 @@ Benthos will not store a consumed sequence unless it is acknowledged at the outp
 
 By default messages of a shard can be processed in parallel, up to a limit determined by the field ` + "`checkpoint_limit`" + `. However, if strict ordered processing is required then this value must be set to 1 in order to process shard messages in lock-step. When doing so it is recommended that you perform batching at this component for performance as it will not be possible to batch lock-stepped messages at the output level.
 
+### Enhanced Fan-Out
+
+By default shards are consumed by polling for records, where the read throughput of a shard is shared between all consumers of the stream. When ` + "`enhanced_fan_out.enabled`" + ` is set to ` + "`true`" + ` shards are instead consumed with [enhanced fan-out](https://docs.aws.amazon.com/streams/latest/dev/enhanced-consumers.html) subscriptions, where records are pushed to the input with a dedicated read throughput. The stream consumer named by ` + "`enhanced_fan_out.consumer_name`" + ` is registered for each stream if it does not already exist, and is not deregistered when the input shuts down.
+
+Enhanced fan-out is compatible with both balanced and explicit shards, and the same checkpoint table is used.
+
 ### Table Schema
 
 It's possible to configure Benthos to create the DynamoDB table required for coordination if it does not already exist. However, if you wish to create this yourself (recommended) then create a table with a string HASH key ` + "`StreamID`" + ` and a string RANGE key ` + "`ShardID`" + `. 
@@ -76,6 +82,10 @@ Use the ` + "`batching`" + ` fields to configure an optional [batching policy](/
 			docs.FieldString("rebalance_period", "The period of time between each attempt to rebalance shards across clients.").Advanced(),
 			docs.FieldString("lease_period", "The period of time after which a client that has failed to update a shard checkpoint is assumed to be inactive.").Advanced(),
 			docs.FieldBool("start_from_oldest", "Whether to consume from the oldest message when a sequence does not yet exist for the stream."),
+			docs.FieldObject("enhanced_fan_out", "Optionally consume shards with [enhanced fan-out](#enhanced-fan-out) subscriptions.").WithChildren(
+				docs.FieldBool("enabled", "Whether to consume shards with enhanced fan-out subscriptions instead of polling."),
+				docs.FieldString("consumer_name", "The name of the stream consumer to subscribe with, which is registered if it does not yet exist. All inputs balancing the shards of a stream should use the same consumer name."),
+			).Advanced().AtVersion("4.9.0"),
 		).WithChildren(session.FieldSpecs()...).
 			WithChildren(policy.FieldSpec()).
 			ChildDefaultAndTypesFromStruct(input.NewAWSKinesisConfig()),
@@ -110,6 +120,7 @@ type kinesisReader struct {
 
 	svc          kinesisiface.KinesisAPI
 	checkpointer *awsKinesisCheckpointer
+	consumerARNs map[string]string
 
 	streamShards    map[string][]string
 	balancedStreams []string
@@ -193,6 +204,9 @@ func newKinesisReader(conf input.AWSKinesisConfig, mgr bundle.NewManagement) (*k
 	if k.rebalancePeriod, err = time.ParseDuration(k.conf.RebalancePeriod); err != nil {
 		return nil, fmt.Errorf("failed to parse rebalance period string: %v", err)
 	}
+	if k.conf.EnhancedFanOut.Enabled && k.conf.EnhancedFanOut.ConsumerName == "" {
+		return nil, errors.New("a consumer_name must be specified when enhanced fan-out is enabled")
+	}
 	return &k, nil
 }
 
@@ -312,7 +326,14 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 	// Stores consumed records that have yet to be added to the batcher.
 	var pending []*kinesis.Record
 	var iter string
-	if iter, initErr = k.getIter(streamID, shardID, startingSequence); initErr != nil {
+
+	// When consuming with enhanced fan-out records are pushed to us from a
+	// subscription instead of being pulled with a shard iterator.
+	consumerARN := k.consumerARNs[streamID]
+	var efoRecordsChan chan awsKinesisEFORecords
+	if consumerARN != "" {
+		efoRecordsChan = make(chan awsKinesisEFORecords)
+	} else if iter, initErr = k.getIter(streamID, shardID, startingSequence); initErr != nil {
 		return initErr
 	}
 
@@ -333,12 +354,22 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 	//    is nil when our current batched message is a zero value (we don't have
 	//    one prepared).
 	// 4. Next commit, is "done" when the next commit is due.
+	//
+	// When consuming with enhanced fan-out the record pulling channel is nil
+	// whilst waiting for the subscription to push records.
 	var nextTimedBatchChan <-chan time.Time
 	var nextPullChan <-chan time.Time = unblockedChan
 	var nextFlushChan chan<- asyncMessage
+	var nextRecordsChan <-chan awsKinesisEFORecords
 	commitCtx, commitCtxClose := context.WithTimeout(k.ctx, k.commitPeriod)
 
 	go func() {
+		subCtx, subDone := context.WithCancel(k.ctx)
+		defer subDone()
+		if efoRecordsChan != nil {
+			go k.runShardSubscription(subCtx, consumerARN, shardID, startingSequence, efoRecordsChan)
+		}
+
 		defer func() {
 			commitCtxClose()
 			recordBatcher.Close(context.Background(), state == awsKinesisConsumerFinished)
@@ -382,34 +413,41 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 		for {
 			var err error
 			if state == awsKinesisConsumerConsuming && len(pending) == 0 && nextPullChan == unblockedChan {
-				if pending, iter, err = k.getRecords(streamID, shardID, iter); err != nil {
-					if !awsErrIsTimeout(err) {
-						nextPullChan = time.After(boff.NextBackOff())
-
-						if aerr, ok := err.(awserr.Error); ok && aerr.Code() == kinesis.ErrCodeExpiredIteratorException {
-							k.log.Warnln("Shard iterator expired, attempting to refresh")
-							newIter, err := k.getIter(streamID, shardID, recordBatcher.GetSequence())
-							if err != nil {
-								k.log.Errorf("Failed to refresh shard iterator: %v", err)
+				if efoRecordsChan != nil {
+					// Pulling is disabled until the subscription pushes
+					// records, unblockPullChan leaves a nil channel alone.
+					nextRecordsChan = efoRecordsChan
+					nextPullChan = nil
+				} else {
+					if pending, iter, err = k.getRecords(streamID, shardID, iter); err != nil {
+						if !awsErrIsTimeout(err) {
+							nextPullChan = time.After(boff.NextBackOff())
+
+							if aerr, ok := err.(awserr.Error); ok && aerr.Code() == kinesis.ErrCodeExpiredIteratorException {
+								k.log.Warnln("Shard iterator expired, attempting to refresh")
+								newIter, err := k.getIter(streamID, shardID, recordBatcher.GetSequence())
+								if err != nil {
+									k.log.Errorf("Failed to refresh shard iterator: %v", err)
+								} else {
+									iter = newIter
+								}
 							} else {
-								iter = newIter
+								k.log.Errorf("Failed to pull Kinesis records: %v\n", err)
 							}
-						} else {
-							k.log.Errorf("Failed to pull Kinesis records: %v\n", err)
 						}
+					} else if len(pending) == 0 {
+						nextPullChan = time.After(boff.NextBackOff())
+					} else {
+						boff.Reset()
+						nextPullChan = blockedChan
+					}
+					// The getRecords method ensures that it returns the input
+					// iterator whenever it errors out. Therefore, regardless of the
+					// outcome of the call if iter is now empty we have definitely
+					// reached the end of the shard.
+					if iter == "" {
+						state = awsKinesisConsumerFinished
 					}
-				} else if len(pending) == 0 {
-					nextPullChan = time.After(boff.NextBackOff())
-				} else {
-					boff.Reset()
-					nextPullChan = blockedChan
-				}
-				// The getRecords method ensures that it returns the input
-				// iterator whenever it errors out. Therefore, regardless of the
-				// outcome of the call if iter is now empty we have definitely
-				// reached the end of the shard.
-				if iter == "" {
-					state = awsKinesisConsumerFinished
 				}
 			} else {
 				unblockPullChan()
@@ -482,6 +520,12 @@ func (k *kinesisReader) runConsumer(wg *sync.WaitGroup, streamID, shardID, start
 				pendingMsg = asyncMessage{}
 			case <-nextPullChan:
 				nextPullChan = unblockedChan
+			case res := <-nextRecordsChan:
+				nextRecordsChan = nil
+				nextPullChan = unblockedChan
+				if pending = res.records; res.finished {
+					state = awsKinesisConsumerFinished
+				}
 			case <-k.ctx.Done():
 				state = awsKinesisConsumerClosing
 				return
@@ -693,7 +737,22 @@ func (k *kinesisReader) Connect(ctx context.Context) error {
 	}
 
 	k.svc = svc
+
+	consumerARNs := map[string]string{}
+	if k.conf.EnhancedFanOut.Enabled {
+		streams := append([]string{}, k.balancedStreams...)
+		for streamID := range k.streamShards {
+			streams = append(streams, streamID)
+		}
+		for _, streamID := range streams {
+			if consumerARNs[streamID], err = k.registerStreamConsumer(ctx, streamID); err != nil {
+				return err
+			}
+		}
+	}
+
 	k.checkpointer = checkpointer
+	k.consumerARNs = consumerARNs
 	k.msgChan = make(chan asyncMessage)
 
 	if len(k.streamShards) > 0 {
@@ -0,0 +1,149 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+)
+
+// awsKinesisEFORecords is a set of records pushed to a shard consumer from an
+// enhanced fan-out subscription.
+type awsKinesisEFORecords struct {
+	records  []*kinesis.Record
+	finished bool
+}
+
+// registerStreamConsumer obtains the ARN of the enhanced fan-out consumer of a
+// stream, registering it if it does not yet exist, and waits until the
+// consumer is active.
+func (k *kinesisReader) registerStreamConsumer(ctx context.Context, streamID string) (string, error) {
+	summary, err := k.svc.DescribeStreamSummaryWithContext(ctx, &kinesis.DescribeStreamSummaryInput{
+		StreamName: &streamID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe stream '%v': %w", streamID, err)
+	}
+	streamARN := summary.StreamDescriptionSummary.StreamARN
+	consumerName := k.conf.EnhancedFanOut.ConsumerName
+
+	var consumerARN, status string
+	desc, err := k.svc.DescribeStreamConsumerWithContext(ctx, &kinesis.DescribeStreamConsumerInput{
+		StreamARN:    streamARN,
+		ConsumerName: &consumerName,
+	})
+	if err == nil {
+		consumerARN = aws.StringValue(desc.ConsumerDescription.ConsumerARN)
+		status = aws.StringValue(desc.ConsumerDescription.ConsumerStatus)
+	} else if aerr, ok := err.(awserr.Error); ok && aerr.Code() == kinesis.ErrCodeResourceNotFoundException {
+		k.log.Infof("Registering enhanced fan-out consumer '%v' for stream '%v'\n", consumerName, streamID)
+		res, err := k.svc.RegisterStreamConsumerWithContext(ctx, &kinesis.RegisterStreamConsumerInput{
+			StreamARN:    streamARN,
+			ConsumerName: &consumerName,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to register consumer '%v' for stream '%v': %w", consumerName, streamID, err)
+		}
+		consumerARN = aws.StringValue(res.Consumer.ConsumerARN)
+		status = aws.StringValue(res.Consumer.ConsumerStatus)
+	} else {
+		return "", fmt.Errorf("failed to describe consumer '%v' of stream '%v': %w", consumerName, streamID, err)
+	}
+
+	for status != kinesis.ConsumerStatusActive {
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		desc, err := k.svc.DescribeStreamConsumerWithContext(ctx, &kinesis.DescribeStreamConsumerInput{
+			ConsumerARN: &consumerARN,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to describe consumer '%v' of stream '%v': %w", consumerName, streamID, err)
+		}
+		status = aws.StringValue(desc.ConsumerDescription.ConsumerStatus)
+	}
+	return consumerARN, nil
+}
+
+// runShardSubscription subscribes to a shard and pushes the records received
+// to recordsChan until the shard is finished or the context is cancelled.
+// Subscriptions expire after five minutes, at which point the shard is
+// subscribed to again from the last sequence received.
+func (k *kinesisReader) runShardSubscription(ctx context.Context, consumerARN, shardID, sequence string, recordsChan chan<- awsKinesisEFORecords) {
+	boff := k.backoffCtor()
+	for {
+		startingPosition := &kinesis.StartingPosition{
+			Type: aws.String(kinesis.ShardIteratorTypeTrimHorizon),
+		}
+		if !k.conf.StartFromOldest {
+			startingPosition.Type = aws.String(kinesis.ShardIteratorTypeLatest)
+		}
+		if len(sequence) > 0 {
+			startingPosition.Type = aws.String(kinesis.ShardIteratorTypeAfterSequenceNumber)
+			startingPosition.SequenceNumber = aws.String(sequence)
+		}
+
+		res, err := k.svc.SubscribeToShardWithContext(ctx, &kinesis.SubscribeToShardInput{
+			ConsumerARN:      &consumerARN,
+			ShardId:          &shardID,
+			StartingPosition: startingPosition,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			k.log.Errorf("Failed to subscribe to shard '%v': %v\n", shardID, err)
+			select {
+			case <-time.After(boff.NextBackOff()):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+		boff.Reset()
+
+		finished := k.readShardSubscription(ctx, res.EventStream, &sequence, recordsChan)
+		if finished || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// readShardSubscription pushes the records of a subscription event stream to
+// recordsChan until the stream ends, and returns true if the shard has
+// finished.
+func (k *kinesisReader) readShardSubscription(ctx context.Context, stream *kinesis.SubscribeToShardEventStream, sequence *string, recordsChan chan<- awsKinesisEFORecords) bool {
+	defer stream.Close()
+
+	for event := range stream.Events() {
+		e, ok := event.(*kinesis.SubscribeToShardEvent)
+		if !ok {
+			continue
+		}
+
+		// A missing continuation sequence indicates that the shard has been
+		// closed and all of its records have been delivered.
+		finished := e.ContinuationSequenceNumber == nil
+		if len(e.Records) > 0 || finished {
+			select {
+			case recordsChan <- awsKinesisEFORecords{records: e.Records, finished: finished}:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if finished {
+			return true
+		}
+		*sequence = *e.ContinuationSequenceNumber
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		k.log.Errorf("Shard subscription failed: %v\n", err)
+	}
+	return false
+}
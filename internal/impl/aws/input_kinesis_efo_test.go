@@ -0,0 +1,170 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+type mockKinesisEFO struct {
+	kinesisiface.KinesisAPI
+
+	registered  []string
+	subscribeFn func(input *kinesis.SubscribeToShardInput) []kinesis.SubscribeToShardEventStreamEvent
+}
+
+func (m *mockKinesisEFO) DescribeStreamSummaryWithContext(ctx aws.Context, in *kinesis.DescribeStreamSummaryInput, opts ...request.Option) (*kinesis.DescribeStreamSummaryOutput, error) {
+	return &kinesis.DescribeStreamSummaryOutput{
+		StreamDescriptionSummary: &kinesis.StreamDescriptionSummary{
+			StreamARN: aws.String("arn:" + *in.StreamName),
+		},
+	}, nil
+}
+
+func (m *mockKinesisEFO) DescribeStreamConsumerWithContext(ctx aws.Context, in *kinesis.DescribeStreamConsumerInput, opts ...request.Option) (*kinesis.DescribeStreamConsumerOutput, error) {
+	for _, arn := range m.registered {
+		if (in.ConsumerARN != nil && *in.ConsumerARN == arn) ||
+			(in.StreamARN != nil && *in.StreamARN+"/"+*in.ConsumerName == arn) {
+			return &kinesis.DescribeStreamConsumerOutput{
+				ConsumerDescription: &kinesis.ConsumerDescription{
+					ConsumerARN:    aws.String(arn),
+					ConsumerStatus: aws.String(kinesis.ConsumerStatusActive),
+				},
+			}, nil
+		}
+	}
+	return nil, awserr.New(kinesis.ErrCodeResourceNotFoundException, "not found", nil)
+}
+
+func (m *mockKinesisEFO) RegisterStreamConsumerWithContext(ctx aws.Context, in *kinesis.RegisterStreamConsumerInput, opts ...request.Option) (*kinesis.RegisterStreamConsumerOutput, error) {
+	arn := *in.StreamARN + "/" + *in.ConsumerName
+	m.registered = append(m.registered, arn)
+	return &kinesis.RegisterStreamConsumerOutput{
+		Consumer: &kinesis.Consumer{
+			ConsumerARN:    aws.String(arn),
+			ConsumerStatus: aws.String(kinesis.ConsumerStatusCreating),
+		},
+	}, nil
+}
+
+type mockShardEventReader struct {
+	events chan kinesis.SubscribeToShardEventStreamEvent
+}
+
+func (r *mockShardEventReader) Events() <-chan kinesis.SubscribeToShardEventStreamEvent {
+	return r.events
+}
+
+func (r *mockShardEventReader) Close() error {
+	return nil
+}
+
+func (r *mockShardEventReader) Err() error {
+	return nil
+}
+
+func (m *mockKinesisEFO) SubscribeToShardWithContext(ctx aws.Context, in *kinesis.SubscribeToShardInput, opts ...request.Option) (*kinesis.SubscribeToShardOutput, error) {
+	events := m.subscribeFn(in)
+	r := &mockShardEventReader{events: make(chan kinesis.SubscribeToShardEventStreamEvent, len(events))}
+	for _, e := range events {
+		r.events <- e
+	}
+	close(r.events)
+	return &kinesis.SubscribeToShardOutput{
+		EventStream: kinesis.NewSubscribeToShardEventStream(func(es *kinesis.SubscribeToShardEventStream) {
+			es.Reader = r
+			es.StreamCloser = io.NopCloser(strings.NewReader(""))
+		}),
+	}, nil
+}
+
+func TestKinesisRegisterStreamConsumer(t *testing.T) {
+	conf := input.NewAWSKinesisConfig()
+	conf.EnhancedFanOut.Enabled = true
+	conf.EnhancedFanOut.ConsumerName = "foo"
+
+	svc := &mockKinesisEFO{}
+	k := &kinesisReader{conf: conf, svc: svc, log: log.Noop()}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	arn, err := k.registerStreamConsumer(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:bar/foo", arn)
+	assert.Equal(t, []string{"arn:bar/foo"}, svc.registered)
+
+	arn, err = k.registerStreamConsumer(ctx, "bar")
+	require.NoError(t, err)
+	assert.Equal(t, "arn:bar/foo", arn)
+	assert.Equal(t, []string{"arn:bar/foo"}, svc.registered)
+}
+
+func TestKinesisShardSubscriptionResubscribes(t *testing.T) {
+	var positions []kinesis.StartingPosition
+	svc := &mockKinesisEFO{
+		subscribeFn: func(in *kinesis.SubscribeToShardInput) []kinesis.SubscribeToShardEventStreamEvent {
+			positions = append(positions, *in.StartingPosition)
+			if len(positions) == 1 {
+				return []kinesis.SubscribeToShardEventStreamEvent{
+					&kinesis.SubscribeToShardEvent{
+						ContinuationSequenceNumber: aws.String("1"),
+						Records:                    []*kinesis.Record{{Data: []byte("first")}},
+					},
+					&kinesis.SubscribeToShardEvent{
+						ContinuationSequenceNumber: aws.String("2"),
+					},
+				}
+			}
+			return []kinesis.SubscribeToShardEventStreamEvent{
+				&kinesis.SubscribeToShardEvent{
+					Records: []*kinesis.Record{{Data: []byte("second")}},
+				},
+			}
+		},
+	}
+
+	k := &kinesisReader{
+		conf: input.NewAWSKinesisConfig(),
+		svc:  svc,
+		log:  log.Noop(),
+		backoffCtor: func() backoff.BackOff {
+			return backoff.NewConstantBackOff(time.Millisecond)
+		},
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	recordsChan := make(chan awsKinesisEFORecords)
+	go k.runShardSubscription(ctx, "arn:bar/foo", "0", "", recordsChan)
+
+	res := <-recordsChan
+	require.Len(t, res.records, 1)
+	assert.Equal(t, "first", string(res.records[0].Data))
+	assert.False(t, res.finished)
+
+	res = <-recordsChan
+	require.Len(t, res.records, 1)
+	assert.Equal(t, "second", string(res.records[0].Data))
+	assert.True(t, res.finished)
+
+	require.Len(t, positions, 2)
+	assert.Equal(t, kinesis.ShardIteratorTypeTrimHorizon, *positions[0].Type)
+	assert.Equal(t, kinesis.ShardIteratorTypeAfterSequenceNumber, *positions[1].Type)
+	assert.Equal(t, "2", *positions[1].SequenceNumber)
+}
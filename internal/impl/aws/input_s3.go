@@ -27,6 +27,7 @@ import (
 	sess "github.com/benthosdev/benthos/v4/internal/impl/aws/session"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/partition"
 )
 
 func init() {
@@ -59,6 +60,10 @@ If your notification events are being routed to SQS via an SNS topic then the ev
 
 When using SQS please make sure you have sensible values for ` + "`sqs.max_messages`" + ` and also the visibility timeout of the queue itself. When Benthos consumes an S3 object the SQS message that triggered it is not deleted until the S3 object has been sent onwards. This ensures at-least-once crash resiliency, but also means that if the S3 object takes longer to process than the visibility timeout of your queue then the same objects might be processed multiple times.
 
+## Sharing a Bucket Between Instances
+
+When walking a bucket the objects can be shared amongst multiple instances of Benthos running the same config with the field ` + "[`partitioning`](#partitioning)" + `, where each object is only consumed by the instance that holds the partition its key belongs to. Partitions are checked as objects are listed, and therefore instances should be started at roughly the same time in order to share a walk.
+
 ## Downloading Large Files
 
 When downloading large files it's often necessary to process it in streamed parts in order to avoid loading the entire file in memory at a given time. In order to do this a ` + "[`codec`](#codec)" + ` can be specified that determines how to break the input into smaller individual messages.
@@ -74,6 +79,7 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 - s3_key
 - s3_bucket
+- s3_message_index
 - s3_last_modified_unix
 - s3_last_modified (RFC3339)
 - s3_content_type
@@ -102,6 +108,7 @@ You can access these metadata fields using [function interpolation](/docs/config
 				).Advanced(),
 				docs.FieldInt("max_messages", "The maximum number of SQS messages to consume from each request.").Advanced(),
 			),
+			partition.FieldSpec(),
 		).ChildDefaultAndTypesFromStruct(input.NewAWSS3Config()),
 		Categories: []string{
 			"Services",
@@ -170,6 +177,7 @@ type staticTargetReader struct {
 	pending    []*s3ObjectTarget
 	s3         *s3.S3
 	conf       input.AWSS3Config
+	partitions *partition.Coordinator
 	startAfter *string
 }
 
@@ -178,6 +186,7 @@ func newStaticTargetReader(
 	conf input.AWSS3Config,
 	log log.Modular,
 	s3Client *s3.S3,
+	partitions *partition.Coordinator,
 ) (*staticTargetReader, error) {
 	listInput := &s3.ListObjectsV2Input{
 		Bucket:  aws.String(conf.Bucket),
@@ -191,8 +200,9 @@ func newStaticTargetReader(
 		return nil, fmt.Errorf("failed to list objects: %v", err)
 	}
 	staticKeys := staticTargetReader{
-		s3:   s3Client,
-		conf: conf,
+		s3:         s3Client,
+		conf:       conf,
+		partitions: partitions,
 	}
 	for _, obj := range output.Contents {
 		ackFn := deleteS3ObjectAckFn(s3Client, conf.Bucket, *obj.Key, conf.DeleteObjects, nil)
@@ -205,6 +215,20 @@ func newStaticTargetReader(
 }
 
 func (s *staticTargetReader) Pop(ctx context.Context) (*s3ObjectTarget, error) {
+	for {
+		obj, err := s.pop(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Objects that belong to partitions held by other instances are
+		// skipped.
+		if s.partitions == nil || s.partitions.OwnsKey(obj.key) {
+			return obj, nil
+		}
+	}
+}
+
+func (s *staticTargetReader) pop(ctx context.Context) (*s3ObjectTarget, error) {
 	if len(s.pending) == 0 && s.startAfter != nil {
 		s.pending = nil
 		listInput := &s3.ListObjectsV2Input{
@@ -509,6 +533,8 @@ type awsS3Reader struct {
 	s3      *s3.S3
 	sqs     *sqs.SQS
 
+	partitions *partition.Coordinator
+
 	gracePeriod time.Duration
 
 	objectMut sync.Mutex
@@ -521,6 +547,7 @@ type s3PendingObject struct {
 	target    *s3ObjectTarget
 	obj       *s3.GetObjectOutput
 	extracted int
+	parts     int
 	scanner   codec.Reader
 }
 
@@ -545,6 +572,14 @@ func newAmazonS3Reader(conf input.AWSS3Config, nm bundle.NewManagement) (*awsS3R
 			return nil, fmt.Errorf("failed to parse grace period: %w", err)
 		}
 	}
+	if conf.Partitioning.Enabled() {
+		if conf.SQS.URL != "" {
+			return nil, errors.New("cannot specify both partitioning and sqs.url")
+		}
+		if s.partitions, err = partition.New(conf.Partitioning, nm.Label(), nm, nm.Logger()); err != nil {
+			return nil, fmt.Errorf("failed to create partition coordinator: %w", err)
+		}
+	}
 	return s, nil
 }
 
@@ -552,7 +587,12 @@ func (a *awsS3Reader) getTargetReader(ctx context.Context) (s3ObjectTargetReader
 	if a.sqs != nil {
 		return newSQSTargetReader(a.conf, a.log, a.s3, a.sqs), nil
 	}
-	return newStaticTargetReader(ctx, a.conf, a.log, a.s3)
+	if a.partitions != nil {
+		if err := a.partitions.WaitForSync(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return newStaticTargetReader(ctx, a.conf, a.log, a.s3, a.partitions)
 }
 
 // Connect attempts to establish a connection to the target S3 bucket
@@ -596,9 +636,10 @@ func (a *awsS3Reader) Connect(ctx context.Context) error {
 
 func s3MsgFromParts(p *s3PendingObject, parts []*message.Part) message.Batch {
 	msg := message.Batch(parts)
-	_ = msg.Iter(func(_ int, part *message.Part) error {
+	_ = msg.Iter(func(i int, part *message.Part) error {
 		part.MetaSet("s3_key", p.target.key)
 		part.MetaSet("s3_bucket", p.target.bucket)
+		part.MetaSet("s3_message_index", strconv.Itoa(p.parts+i))
 		if p.obj.LastModified != nil {
 			part.MetaSet("s3_last_modified", p.obj.LastModified.Format(time.RFC3339))
 			part.MetaSet("s3_last_modified_unix", strconv.FormatInt(p.obj.LastModified.Unix(), 10))
@@ -616,6 +657,7 @@ func s3MsgFromParts(p *s3PendingObject, parts []*message.Part) message.Batch {
 		}
 		return nil
 	})
+	p.parts += len(parts)
 	return msg
 }
 
@@ -728,5 +770,10 @@ func (a *awsS3Reader) Close(ctx context.Context) (err error) {
 		err = a.object.scanner.Close(ctx)
 		a.object = nil
 	}
+	if a.partitions != nil {
+		if pErr := a.partitions.Close(ctx); err == nil {
+			err = pErr
+		}
+	}
 	return
 }
@@ -0,0 +1,51 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/codec"
+)
+
+func TestS3MsgFromPartsIndex(t *testing.T) {
+	ctx := context.Background()
+
+	ctor, err := codec.GetReader("lines", codec.NewReaderConfig())
+	require.NoError(t, err)
+
+	scanner, err := ctor("foo.txt", io.NopCloser(strings.NewReader("first\nsecond\nthird\n")), func(context.Context, error) error {
+		return nil
+	})
+	require.NoError(t, err)
+
+	object := &s3PendingObject{
+		target:  newS3ObjectTarget("foo.txt", "bar", time.Time{}, nil),
+		obj:     &s3.GetObjectOutput{},
+		scanner: scanner,
+	}
+
+	var contents, indexes []string
+	for {
+		parts, _, err := scanner.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		for _, p := range s3MsgFromParts(object, parts) {
+			contents = append(contents, string(p.AsBytes()))
+			indexes = append(indexes, p.MetaGet("s3_message_index"))
+			assert.Equal(t, "foo.txt", p.MetaGet("s3_key"))
+		}
+	}
+
+	assert.Equal(t, []string{"first", "second", "third"}, contents)
+	assert.Equal(t, []string{"0", "1", "2"}, indexes)
+}
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/cenkalti/backoff/v4"
 
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -55,13 +57,23 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 
 You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+### FIFO Queues
+
+When consuming from a FIFO queue set ` + "`fifo_ordering`" + ` to ` + "`true`" + ` in order to process the messages of each message group in order. A message is only dispatched once the previous message of its group has been acknowledged, whilst messages of different groups are processed in parallel. When a message is rejected the messages of its group that have already been received are also returned to the queue, so that they are redelivered after it.
+
+### Visibility Timeouts
+
+By default the visibility timeout of the queue applies to consumed messages, and a message that takes longer than this timeout to be acknowledged is delivered again. When ` + "`message_timeout`" + ` is set consumed messages are given a visibility timeout of this period, which is extended whenever half of the period has elapsed until the message is acknowledged.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("url", "The SQS URL to consume from."),
 			docs.FieldBool("delete_message", "Whether to delete the consumed message once it is acked. Disabling allows you to handle the deletion using a different mechanism.").Advanced(),
 			docs.FieldBool("reset_visibility", "Whether to set the visibility timeout of the consumed message to zero once it is nacked. Disabling honors the preset visibility timeout specified for the queue.").AtVersion("3.58.0").Advanced(),
 			docs.FieldInt("max_number_of_messages", "The maximum number of messages to return on one poll. Valid values: 1 to 10.").Advanced(),
 			docs.FieldInt("wait_time_seconds", "Whether to set the wait time. Enabling this activates long-polling. Valid values: 0 to 20.").Advanced(),
+			docs.FieldString("message_timeout", "An optional visibility timeout to set for consumed messages, which is automatically extended until messages are acknowledged. When left empty the visibility timeout of the queue is used and is not extended.", "30s").Advanced().AtVersion("4.9.0"),
+			docs.FieldBool("fifo_ordering", "Whether to process the messages of each message group of a FIFO queue in order, one message at a time, whilst processing different groups in parallel.").Advanced().AtVersion("4.9.0"),
 		).WithChildren(sess.FieldSpecs()...).ChildDefaultAndTypesFromStruct(input.NewAWSSQSConfig()),
 		Categories: []string{
 			"Services",
@@ -79,25 +91,49 @@ type awsSQSReader struct {
 	conf input.AWSSQSConfig
 
 	session *session.Session
-	sqs     *sqs.SQS
+	sqs     sqsiface.SQSAPI
+
+	messageTimeout time.Duration
 
 	messagesChan     chan *sqs.Message
 	ackMessagesChan  chan sqsMessageHandle
 	nackMessagesChan chan sqsMessageHandle
 	closeSignal      *shutdown.Signaller
 
+	// Tracks messages that have been received and not yet acknowledged, along
+	// with the message groups that currently have a message being processed.
+	inFlightMut    sync.Mutex
+	inFlight       map[string]sqsMessageHandle
+	busyGroups     map[string]struct{}
+	failedGroups   map[string]struct{}
+	groupFreedChan chan struct{}
+
 	log log.Modular
 }
 
 func newAWSSQSReader(conf input.AWSSQSConfig, log log.Modular) (*awsSQSReader, error) {
-	return &awsSQSReader{
+	a := &awsSQSReader{
 		conf:             conf,
 		log:              log,
 		messagesChan:     make(chan *sqs.Message),
 		ackMessagesChan:  make(chan sqsMessageHandle),
 		nackMessagesChan: make(chan sqsMessageHandle),
 		closeSignal:      shutdown.NewSignaller(),
-	}, nil
+		inFlight:         map[string]sqsMessageHandle{},
+		busyGroups:       map[string]struct{}{},
+		failedGroups:     map[string]struct{}{},
+		groupFreedChan:   make(chan struct{}, 1),
+	}
+	if conf.MessageTimeout != "" {
+		var err error
+		if a.messageTimeout, err = time.ParseDuration(conf.MessageTimeout); err != nil {
+			return nil, fmt.Errorf("failed to parse message timeout string: %v", err)
+		}
+		if a.messageTimeout < time.Second*2 {
+			return nil, fmt.Errorf("message timeout must be at least two seconds, got %v", a.messageTimeout)
+		}
+	}
+	return a, nil
 }
 
 // Connect attempts to establish a connection to the target SQS
@@ -119,6 +155,10 @@ func (a *awsSQSReader) Connect(ctx context.Context) error {
 	wg.Add(2)
 	go a.readLoop(&wg)
 	go a.ackLoop(&wg)
+	if a.messageTimeout > 0 {
+		wg.Add(1)
+		go a.refreshLoop(&wg)
+	}
 	go func() {
 		wg.Wait()
 		a.closeSignal.ShutdownComplete()
@@ -190,6 +230,148 @@ ackLoop:
 	flushNacks()
 }
 
+// refreshLoop periodically extends the visibility timeout of all in flight
+// messages until they're acknowledged.
+func (a *awsSQSReader) refreshLoop(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	refreshTicker := time.NewTicker(a.messageTimeout / 2)
+	defer refreshTicker.Stop()
+
+	for {
+		select {
+		case <-refreshTicker.C:
+			a.inFlightMut.Lock()
+			handles := make([]sqsMessageHandle, 0, len(a.inFlight))
+			for _, h := range a.inFlight {
+				handles = append(handles, h)
+			}
+			a.inFlightMut.Unlock()
+
+			ctx, done := a.closeSignal.CloseNowCtx(context.Background())
+			if err := a.changeVisibility(ctx, a.messageTimeout, handles...); err != nil {
+				a.log.Errorf("Failed to extend the visibility timeout of messages: %v", err)
+			}
+			done()
+		case <-a.closeSignal.CloseAtLeisureChan():
+			return
+		}
+	}
+}
+
+func sqsMessageGroup(m *sqs.Message) string {
+	if g := m.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]; g != nil {
+		return *g
+	}
+	return ""
+}
+
+// trackMessages registers received messages as in flight.
+func (a *awsSQSReader) trackMessages(msgs ...*sqs.Message) {
+	a.inFlightMut.Lock()
+	defer a.inFlightMut.Unlock()
+	for _, m := range msgs {
+		if m.MessageId == nil || m.ReceiptHandle == nil {
+			continue
+		}
+		a.inFlight[*m.MessageId] = sqsMessageHandle{
+			id:            *m.MessageId,
+			receiptHandle: *m.ReceiptHandle,
+		}
+	}
+}
+
+// nextDispatchable returns the index of the first pending message that can be
+// dispatched, which when ordering FIFO groups is the first message of a group
+// that isn't already being processed, and marks its group as busy. Returns -1
+// if no message can be dispatched.
+func (a *awsSQSReader) nextDispatchable(pending []*sqs.Message) int {
+	if !a.conf.FIFOOrdering {
+		return 0
+	}
+
+	a.inFlightMut.Lock()
+	defer a.inFlightMut.Unlock()
+	for i, m := range pending {
+		group := sqsMessageGroup(m)
+		if group == "" {
+			return i
+		}
+		// Messages of failed groups are dropped before dispatching more
+		// messages and must not be dispatched in the meantime.
+		if _, failed := a.failedGroups[group]; failed {
+			continue
+		}
+		if _, busy := a.busyGroups[group]; !busy {
+			a.busyGroups[group] = struct{}{}
+			return i
+		}
+	}
+	return -1
+}
+
+// releaseMessage removes a message from the in flight messages and frees its
+// group for the next message to be dispatched.
+func (a *awsSQSReader) releaseMessage(m *sqs.Message, failed bool) {
+	a.inFlightMut.Lock()
+	defer a.inFlightMut.Unlock()
+
+	if m.MessageId != nil {
+		delete(a.inFlight, *m.MessageId)
+	}
+	if !a.conf.FIFOOrdering {
+		return
+	}
+	if group := sqsMessageGroup(m); group != "" {
+		delete(a.busyGroups, group)
+		if failed {
+			a.failedGroups[group] = struct{}{}
+		}
+		select {
+		case a.groupFreedChan <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// dropFailedGroups removes pending messages that belong to a group where a
+// message has been rejected, as they need to be redelivered after it, and
+// returns the remaining pending messages.
+func (a *awsSQSReader) dropFailedGroups(pending []*sqs.Message) []*sqs.Message {
+	a.inFlightMut.Lock()
+	if len(a.failedGroups) == 0 {
+		a.inFlightMut.Unlock()
+		return pending
+	}
+
+	var dropped []sqsMessageHandle
+	remaining := pending[:0]
+	for _, m := range pending {
+		if _, failed := a.failedGroups[sqsMessageGroup(m)]; !failed {
+			remaining = append(remaining, m)
+			continue
+		}
+		if m.MessageId != nil && m.ReceiptHandle != nil {
+			delete(a.inFlight, *m.MessageId)
+			dropped = append(dropped, sqsMessageHandle{
+				id:            *m.MessageId,
+				receiptHandle: *m.ReceiptHandle,
+			})
+		}
+	}
+	a.failedGroups = map[string]struct{}{}
+	a.inFlightMut.Unlock()
+
+	if len(dropped) > 0 {
+		ctx, done := a.closeSignal.CloseNowCtx(context.Background())
+		defer done()
+		if err := a.resetMessages(ctx, dropped...); err != nil {
+			a.log.Errorf("Failed to reset the visibility timeout of messages: %v", err)
+		}
+	}
+	return remaining
+}
+
 func (a *awsSQSReader) readLoop(wg *sync.WaitGroup) {
 	defer wg.Done()
 
@@ -221,13 +403,17 @@ func (a *awsSQSReader) readLoop(wg *sync.WaitGroup) {
 	getMsgs := func() {
 		ctx, done := a.closeSignal.CloseAtLeisureCtx(context.Background())
 		defer done()
-		res, err := a.sqs.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		input := &sqs.ReceiveMessageInput{
 			QueueUrl:              aws.String(a.conf.URL),
 			MaxNumberOfMessages:   aws.Int64(int64(a.conf.MaxNumberOfMessages)),
 			WaitTimeSeconds:       aws.Int64(int64(a.conf.WaitTimeSeconds)),
 			AttributeNames:        []*string{aws.String("All")},
 			MessageAttributeNames: []*string{aws.String("All")},
-		})
+		}
+		if a.messageTimeout > 0 {
+			input.VisibilityTimeout = aws.Int64(int64(a.messageTimeout / time.Second))
+		}
+		res, err := a.sqs.ReceiveMessageWithContext(ctx, input)
 		if err != nil {
 			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != request.CanceledErrorCode {
 				a.log.Errorf("Failed to pull new SQS messages: %v", aerr)
@@ -235,12 +421,14 @@ func (a *awsSQSReader) readLoop(wg *sync.WaitGroup) {
 			return
 		}
 		if len(res.Messages) > 0 {
+			a.trackMessages(res.Messages...)
 			pendingMsgs = append(pendingMsgs, res.Messages...)
 			backoff.Reset()
 		}
 	}
 
 	for {
+		pendingMsgs = a.dropFailedGroups(pendingMsgs)
 		if len(pendingMsgs) == 0 {
 			getMsgs()
 			if len(pendingMsgs) == 0 {
@@ -252,9 +440,22 @@ func (a *awsSQSReader) readLoop(wg *sync.WaitGroup) {
 				continue
 			}
 		}
+
+		i := a.nextDispatchable(pendingMsgs)
+		if i < 0 {
+			// Every pending message belongs to a group that already has a
+			// message being processed.
+			select {
+			case <-a.groupFreedChan:
+			case <-a.closeSignal.CloseAtLeisureChan():
+				return
+			}
+			continue
+		}
+
 		select {
-		case a.messagesChan <- pendingMsgs[0]:
-			pendingMsgs = pendingMsgs[1:]
+		case a.messagesChan <- pendingMsgs[i]:
+			pendingMsgs = append(pendingMsgs[:i], pendingMsgs[i+1:]...)
 		case <-a.closeSignal.CloseAtLeisureChan():
 			return
 		}
@@ -298,7 +499,10 @@ func (a *awsSQSReader) resetMessages(ctx context.Context, msgs ...sqsMessageHand
 	if !a.conf.ResetVisibility {
 		return nil
 	}
+	return a.changeVisibility(ctx, 0, msgs...)
+}
 
+func (a *awsSQSReader) changeVisibility(ctx context.Context, timeout time.Duration, msgs ...sqsMessageHandle) error {
 	for len(msgs) > 0 {
 		input := sqs.ChangeMessageVisibilityBatchInput{
 			QueueUrl: aws.String(a.conf.URL),
@@ -309,7 +513,7 @@ func (a *awsSQSReader) resetMessages(ctx context.Context, msgs ...sqsMessageHand
 			input.Entries = append(input.Entries, &sqs.ChangeMessageVisibilityBatchRequestEntry{
 				Id:                aws.String(msg.id),
 				ReceiptHandle:     aws.String(msg.receiptHandle),
-				VisibilityTimeout: aws.Int64(0),
+				VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
 			})
 			if len(input.Entries) == a.conf.MaxNumberOfMessages {
 				break
@@ -322,7 +526,7 @@ func (a *awsSQSReader) resetMessages(ctx context.Context, msgs ...sqsMessageHand
 			return err
 		}
 		for _, fail := range response.Failed {
-			a.log.Errorf("Failed to delete consumed SQS message '%v', response code: %v\n", *fail.Id, *fail.Code)
+			a.log.Errorf("Failed to change the visibility timeout of consumed SQS message '%v', response code: %v\n", *fail.Id, *fail.Code)
 		}
 	}
 	return nil
@@ -367,6 +571,7 @@ func (a *awsSQSReader) ReadBatch(ctx context.Context) (message.Batch, input.Asyn
 		msg = append(msg, part)
 	}
 	if msg.Len() == 0 {
+		a.releaseMessage(next, false)
 		return nil, nil, component.ErrTimeout
 	}
 
@@ -377,6 +582,7 @@ func (a *awsSQSReader) ReadBatch(ctx context.Context) (message.Batch, input.Asyn
 		mHandle.receiptHandle = *next.ReceiptHandle
 	}
 	return msg, func(rctx context.Context, res error) error {
+		a.releaseMessage(next, res != nil)
 		if mHandle.receiptHandle == "" {
 			return nil
 		}
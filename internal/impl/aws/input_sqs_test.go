@@ -0,0 +1,211 @@
+package aws
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+type mockSQSInput struct {
+	sqsiface.SQSAPI
+
+	mut         sync.Mutex
+	queue       []*sqs.Message
+	visibility  map[string]int64
+	receiveVisT *int64
+}
+
+func (m *mockSQSInput) ReceiveMessageWithContext(ctx aws.Context, in *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	m.mut.Lock()
+	msgs := m.queue
+	m.queue = nil
+	m.receiveVisT = in.VisibilityTimeout
+	m.mut.Unlock()
+
+	if len(msgs) == 0 {
+		select {
+		case <-time.After(time.Millisecond * 10):
+		case <-ctx.Done():
+		}
+	}
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (m *mockSQSInput) DeleteMessageBatchWithContext(ctx aws.Context, in *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (m *mockSQSInput) ChangeMessageVisibilityBatchWithContext(ctx aws.Context, in *sqs.ChangeMessageVisibilityBatchInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	m.mut.Lock()
+	for _, e := range in.Entries {
+		m.visibility[*e.Id] = *e.VisibilityTimeout
+	}
+	m.mut.Unlock()
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func (m *mockSQSInput) getVisibility(id string) (int64, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	v, exists := m.visibility[id]
+	return v, exists
+}
+
+func fifoSQSMessage(id, group string) *sqs.Message {
+	return &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String("handle-" + id),
+		Body:          aws.String(id),
+		Attributes: map[string]*string{
+			sqs.MessageSystemAttributeNameMessageGroupId: aws.String(group),
+		},
+	}
+}
+
+func startTestSQSReader(t *testing.T, conf input.AWSSQSConfig, mock *mockSQSInput) *awsSQSReader {
+	t.Helper()
+
+	r, err := newAWSSQSReader(conf, log.Noop())
+	require.NoError(t, err)
+
+	r.session = session.Must(session.NewSession())
+	r.sqs = mock
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go r.readLoop(&wg)
+	go r.ackLoop(&wg)
+	if r.messageTimeout > 0 {
+		wg.Add(1)
+		go r.refreshLoop(&wg)
+	}
+	go func() {
+		wg.Wait()
+		r.closeSignal.ShutdownComplete()
+	}()
+
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		require.NoError(t, r.Close(ctx))
+	})
+	return r
+}
+
+func TestSQSInputFIFOOrdering(t *testing.T) {
+	conf := input.NewAWSSQSConfig()
+	conf.FIFOOrdering = true
+
+	mock := &mockSQSInput{
+		visibility: map[string]int64{},
+		queue: []*sqs.Message{
+			fifoSQSMessage("a", "foo"),
+			fifoSQSMessage("b", "foo"),
+			fifoSQSMessage("c", "bar"),
+		},
+	}
+	r := startTestSQSReader(t, conf, mock)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msgA, ackA, err := r.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(msgA.Get(0).AsBytes()))
+
+	msgC, ackC, err := r.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(msgC.Get(0).AsBytes()))
+
+	// The next message of the group foo must wait until a is acknowledged.
+	shortCtx, shortDone := context.WithTimeout(ctx, time.Millisecond*100)
+	_, _, err = r.ReadBatch(shortCtx)
+	shortDone()
+	require.Error(t, err)
+
+	require.NoError(t, ackA(ctx, nil))
+
+	msgB, ackB, err := r.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(msgB.Get(0).AsBytes()))
+
+	require.NoError(t, ackB(ctx, nil))
+	require.NoError(t, ackC(ctx, nil))
+}
+
+func TestSQSInputFIFONackDropsGroup(t *testing.T) {
+	conf := input.NewAWSSQSConfig()
+	conf.FIFOOrdering = true
+
+	mock := &mockSQSInput{
+		visibility: map[string]int64{},
+		queue: []*sqs.Message{
+			fifoSQSMessage("a", "foo"),
+			fifoSQSMessage("b", "foo"),
+		},
+	}
+	r := startTestSQSReader(t, conf, mock)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msgA, ackA, err := r.ReadBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(msgA.Get(0).AsBytes()))
+
+	require.NoError(t, ackA(ctx, assert.AnError))
+
+	// Message b must be returned to the queue rather than dispatched ahead of
+	// the redelivery of a.
+	shortCtx, shortDone := context.WithTimeout(ctx, time.Millisecond*100)
+	_, _, err = r.ReadBatch(shortCtx)
+	shortDone()
+	require.Error(t, err)
+
+	v, exists := mock.getVisibility("b")
+	require.True(t, exists)
+	assert.Equal(t, int64(0), v)
+}
+
+func TestSQSInputMessageTimeoutRefresh(t *testing.T) {
+	conf := input.NewAWSSQSConfig()
+	conf.MessageTimeout = "2s"
+
+	mock := &mockSQSInput{
+		visibility: map[string]int64{},
+		queue: []*sqs.Message{
+			fifoSQSMessage("a", "foo"),
+		},
+	}
+	r := startTestSQSReader(t, conf, mock)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, ackA, err := r.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	mock.mut.Lock()
+	require.NotNil(t, mock.receiveVisT)
+	assert.Equal(t, int64(2), *mock.receiveVisT)
+	mock.mut.Unlock()
+
+	assert.Eventually(t, func() bool {
+		v, exists := mock.getVisibility("a")
+		return exists && v == 2
+	}, time.Second*3, time.Millisecond*50)
+
+	require.NoError(t, ackA(ctx, nil))
+}
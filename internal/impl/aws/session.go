@@ -1,6 +1,9 @@
 package aws
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
@@ -13,54 +16,47 @@ import (
 
 // GetSession attempts to create an AWS session based on the parsedConfig.
 func GetSession(parsedConf *service.ParsedConfig, opts ...func(*aws.Config)) (*session.Session, error) {
-	awsConf := aws.NewConfig()
-
-	if region, _ := parsedConf.FieldString("region"); region != "" {
-		awsConf = awsConf.WithRegion(region)
-	}
-	if endpoint, _ := parsedConf.FieldString("endpoint"); endpoint != "" {
-		awsConf = awsConf.WithEndpoint(endpoint)
-	}
-	if profile, _ := parsedConf.FieldString("credentials", "profile"); profile != "" {
-		awsConf = awsConf.WithCredentials(credentials.NewSharedCredentials(
-			"", profile,
-		))
-	} else if id, _ := parsedConf.FieldString("credentials", "id"); id != "" {
-		secret, _ := parsedConf.FieldString("credentials", "secret")
-		token, _ := parsedConf.FieldString("credentials", "token")
-		awsConf = awsConf.WithCredentials(credentials.NewStaticCredentials(
-			id, secret, token,
-		))
-	}
-
-	for _, opt := range opts {
-		opt(awsConf)
-	}
-
-	sess, err := session.NewSession(awsConf)
+	c, err := sessionConfigFromParsed(parsedConf)
 	if err != nil {
 		return nil, err
 	}
+	return GetSessionFromConf(c, opts...)
+}
 
-	if role, _ := parsedConf.FieldString("credentials", "role"); role != "" {
-		var opts []func(*stscreds.AssumeRoleProvider)
-		if externalID, _ := parsedConf.FieldString("credentials", "role_external_id"); externalID != "" {
-			opts = []func(*stscreds.AssumeRoleProvider){
-				func(p *stscreds.AssumeRoleProvider) {
-					p.ExternalID = &externalID
-				},
+func sessionConfigFromParsed(parsedConf *service.ParsedConfig) (bsession.Config, error) {
+	c := bsession.NewConfig()
+	c.Region, _ = parsedConf.FieldString("region")
+	c.Endpoint, _ = parsedConf.FieldString("endpoint")
+
+	credsConf := parsedConf.Namespace("credentials")
+	c.Credentials.Profile, _ = credsConf.FieldString("profile")
+	c.Credentials.ID, _ = credsConf.FieldString("id")
+	c.Credentials.Secret, _ = credsConf.FieldString("secret")
+	c.Credentials.Token, _ = credsConf.FieldString("token")
+	c.Credentials.UseEC2Creds, _ = credsConf.FieldBool("from_ec2_role")
+	c.Credentials.Role, _ = credsConf.FieldString("role")
+	c.Credentials.ExternalID, _ = credsConf.FieldString("role_external_id")
+	c.Credentials.WebIdentityTokenFile, _ = credsConf.FieldString("web_identity_token_file")
+	c.Credentials.STSEndpoint, _ = credsConf.FieldString("sts_endpoint")
+
+	if credsConf.Contains("role_chain") {
+		chainConfs, err := credsConf.FieldObjectList("role_chain")
+		if err != nil {
+			return c, err
+		}
+		for i, hopConf := range chainConfs {
+			var hop bsession.RoleConfig
+			if hop.Role, err = hopConf.FieldString("role"); err != nil {
+				return c, err
 			}
+			if hop.Role == "" {
+				return c, fmt.Errorf("role_chain[%v].role must not be empty", i)
+			}
+			hop.ExternalID, _ = hopConf.FieldString("role_external_id")
+			c.Credentials.RoleChain = append(c.Credentials.RoleChain, hop)
 		}
-		sess.Config = sess.Config.WithCredentials(
-			stscreds.NewCredentials(sess, role, opts...),
-		)
-	}
-
-	if useEC2, _ := parsedConf.FieldBool("from_ec2_role"); useEC2 {
-		sess.Config = sess.Config.WithCredentials(ec2rolecreds.NewCredentials(sess))
 	}
-
-	return sess, nil
+	return c, nil
 }
 
 // GetSessionFromConf attempts to create an AWS session based on Config.
@@ -95,23 +91,61 @@ func GetSessionFromConf(c bsession.Config, opts ...func(*aws.Config)) (*session.
 		return nil, err
 	}
 
-	if len(c.Credentials.Role) > 0 {
+	if c.Credentials.UseEC2Creds {
+		sess.Config = sess.Config.WithCredentials(ec2rolecreds.NewCredentials(sess))
+	}
+
+	if err := assumeRoleChain(sess, c.Credentials); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// assumeRoleChain replaces the credentials of a session with those of the last
+// role of the configured chain, where each role is assumed using the
+// credentials of the role before it.
+func assumeRoleChain(sess *session.Session, c bsession.CredentialsConfig) error {
+	var roles []bsession.RoleConfig
+	if len(c.Role) > 0 {
+		roles = append(roles, bsession.RoleConfig{
+			Role:       c.Role,
+			ExternalID: c.ExternalID,
+		})
+	}
+	roles = append(roles, c.RoleChain...)
+
+	if len(c.WebIdentityTokenFile) > 0 && len(roles) == 0 {
+		return errors.New("a role must be specified in order to use a web identity token file")
+	}
+
+	for i, r := range roles {
+		if len(r.Role) == 0 {
+			return errors.New("roles of a role chain must not be empty")
+		}
+
+		// The endpoint of the component is only relevant to its own service,
+		// and so STS requests are sent to a separate endpoint.
+		stsSess := sess.Copy(aws.NewConfig().WithEndpoint(c.STSEndpoint))
+
+		if i == 0 && len(c.WebIdentityTokenFile) > 0 {
+			sess.Config = sess.Config.WithCredentials(
+				stscreds.NewWebIdentityCredentials(stsSess, r.Role, "", c.WebIdentityTokenFile),
+			)
+			continue
+		}
+
 		var opts []func(*stscreds.AssumeRoleProvider)
-		if len(c.Credentials.ExternalID) > 0 {
+		if len(r.ExternalID) > 0 {
+			externalID := r.ExternalID
 			opts = []func(*stscreds.AssumeRoleProvider){
 				func(p *stscreds.AssumeRoleProvider) {
-					p.ExternalID = &c.Credentials.ExternalID
+					p.ExternalID = &externalID
 				},
 			}
 		}
 		sess.Config = sess.Config.WithCredentials(
-			stscreds.NewCredentials(sess, c.Credentials.Role, opts...),
+			stscreds.NewCredentials(stsSess, r.Role, opts...),
 		)
 	}
-
-	if c.Credentials.UseEC2Creds {
-		sess.Config = sess.Config.WithCredentials(ec2rolecreds.NewCredentials(sess))
-	}
-
-	return sess, nil
+	return nil
 }
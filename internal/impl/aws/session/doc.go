@@ -17,6 +17,12 @@ func FieldSpecs() docs.FieldSpecs {
 				docs.FieldBool("from_ec2_role", "Use the credentials of a host EC2 machine configured to assume [an IAM role associated with the instance](https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_use_switch-role-ec2.html).").HasDefault(false).AtVersion("4.2.0"),
 				docs.FieldString("role", "A role ARN to assume.").HasDefault(""),
 				docs.FieldString("role_external_id", "An external ID to provide when assuming a role.").HasDefault(""),
+				docs.FieldObject("role_chain", "A list of roles to assume in order after `role`, where each role is assumed with the credentials of the previous role. This allows cross-account access where a role can only be assumed from an intermediate account.").Array().WithChildren(
+					docs.FieldString("role", "A role ARN to assume, which must not be empty.").HasDefault(""),
+					docs.FieldString("role_external_id", "An external ID to provide when assuming the role.").HasDefault(""),
+				).HasDefault([]any{}).AtVersion("4.9.0"),
+				docs.FieldString("web_identity_token_file", "A path to a web identity token file used to assume the first role, such as the token projected into pods by [EKS IAM roles for service accounts](https://docs.aws.amazon.com/eks/latest/userguide/iam-roles-for-service-accounts.html). When the environment variables `AWS_WEB_IDENTITY_TOKEN_FILE` and `AWS_ROLE_ARN` are set and no other credentials are configured these are used automatically.").HasDefault("").AtVersion("4.9.0"),
+				docs.FieldString("sts_endpoint", "A custom endpoint for the STS API used when assuming roles. Requests to STS are never sent to the `endpoint` of the component.").HasDefault("").AtVersion("4.9.0"),
 			),
 	}
 }
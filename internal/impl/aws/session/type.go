@@ -1,14 +1,23 @@
 package session
 
+// RoleConfig contains configuration params for a role to assume.
+type RoleConfig struct {
+	Role       string `json:"role" yaml:"role"`
+	ExternalID string `json:"role_external_id" yaml:"role_external_id"`
+}
+
 // CredentialsConfig contains configuration params for AWS credentials.
 type CredentialsConfig struct {
-	Profile     string `json:"profile" yaml:"profile"`
-	ID          string `json:"id" yaml:"id"`
-	Secret      string `json:"secret" yaml:"secret"`
-	Token       string `json:"token" yaml:"token"`
-	UseEC2Creds bool   `json:"from_ec2_role" yaml:"from_ec2_role"`
-	Role        string `json:"role" yaml:"role"`
-	ExternalID  string `json:"role_external_id" yaml:"role_external_id"`
+	Profile              string       `json:"profile" yaml:"profile"`
+	ID                   string       `json:"id" yaml:"id"`
+	Secret               string       `json:"secret" yaml:"secret"`
+	Token                string       `json:"token" yaml:"token"`
+	UseEC2Creds          bool         `json:"from_ec2_role" yaml:"from_ec2_role"`
+	Role                 string       `json:"role" yaml:"role"`
+	ExternalID           string       `json:"role_external_id" yaml:"role_external_id"`
+	RoleChain            []RoleConfig `json:"role_chain" yaml:"role_chain"`
+	WebIdentityTokenFile string       `json:"web_identity_token_file" yaml:"web_identity_token_file"`
+	STSEndpoint          string       `json:"sts_endpoint" yaml:"sts_endpoint"`
 }
 
 // Config contains configuration fields for an AWS session. This config is
@@ -29,6 +38,7 @@ func NewConfig() Config {
 			Token:      "",
 			Role:       "",
 			ExternalID: "",
+			RoleChain:  []RoleConfig{},
 		},
 		Endpoint: "",
 		Region:   "",
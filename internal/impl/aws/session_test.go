@@ -0,0 +1,84 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	bsession "github.com/benthosdev/benthos/v4/internal/impl/aws/session"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSessionConfigFromParsed(t *testing.T) {
+	spec := service.NewConfigSpec()
+	for _, f := range config.SessionFields() {
+		spec = spec.Field(f)
+	}
+
+	parsed, err := spec.ParseYAML(`
+region: eu-west-1
+endpoint: http://localhost:4566
+credentials:
+  from_ec2_role: true
+  role: arn:aws:iam::111111111111:role/first
+  role_external_id: foo
+  role_chain:
+    - role: arn:aws:iam::222222222222:role/second
+    - role: arn:aws:iam::333333333333:role/third
+      role_external_id: bar
+  sts_endpoint: https://sts.eu-west-1.amazonaws.com
+`, nil)
+	require.NoError(t, err)
+
+	c, err := sessionConfigFromParsed(parsed)
+	require.NoError(t, err)
+
+	assert.Equal(t, "eu-west-1", c.Region)
+	assert.Equal(t, "http://localhost:4566", c.Endpoint)
+	assert.True(t, c.Credentials.UseEC2Creds)
+	assert.Equal(t, "arn:aws:iam::111111111111:role/first", c.Credentials.Role)
+	assert.Equal(t, "foo", c.Credentials.ExternalID)
+	assert.Equal(t, []bsession.RoleConfig{
+		{Role: "arn:aws:iam::222222222222:role/second"},
+		{Role: "arn:aws:iam::333333333333:role/third", ExternalID: "bar"},
+	}, c.Credentials.RoleChain)
+	assert.Equal(t, "https://sts.eu-west-1.amazonaws.com", c.Credentials.STSEndpoint)
+
+	sess, err := GetSessionFromConf(c)
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4566", *sess.Config.Endpoint)
+}
+
+func TestSessionConfigEmptyChainedRole(t *testing.T) {
+	spec := service.NewConfigSpec()
+	for _, f := range config.SessionFields() {
+		spec = spec.Field(f)
+	}
+
+	parsed, err := spec.ParseYAML(`
+credentials:
+  role: arn:aws:iam::111111111111:role/first
+  role_chain:
+    - role: arn:aws:iam::222222222222:role/second
+    - role_external_id: bar
+`, nil)
+	require.NoError(t, err)
+
+	_, err = sessionConfigFromParsed(parsed)
+	require.EqualError(t, err, "role_chain[1].role must not be empty")
+}
+
+func TestSessionWebIdentityRequiresRole(t *testing.T) {
+	c := bsession.NewConfig()
+	c.Region = "eu-west-1"
+	c.Credentials.WebIdentityTokenFile = "/var/run/secrets/token"
+
+	_, err := GetSessionFromConf(c)
+	require.Error(t, err)
+
+	c.Credentials.Role = "arn:aws:iam::111111111111:role/first"
+	_, err = GetSessionFromConf(c)
+	require.NoError(t, err)
+}
@@ -54,6 +54,7 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 - blob_storage_key
 - blob_storage_container
+- blob_storage_message_index
 - blob_storage_last_modified
 - blob_storage_last_modified_unix
 - blob_storage_content_type
@@ -141,6 +142,7 @@ type azurePendingObject struct {
 	target    *azureObjectTarget
 	obj       *storage.Blob
 	extracted int
+	parts     int
 	scanner   codec.Reader
 }
 
@@ -333,8 +335,9 @@ func (a *azureBlobStorage) getObjectTarget(ctx context.Context) (*azurePendingOb
 
 func blobStorageMsgFromParts(p *azurePendingObject, parts []*message.Part) message.Batch {
 	msg := message.Batch(parts)
-	_ = msg.Iter(func(_ int, part *message.Part) error {
+	_ = msg.Iter(func(i int, part *message.Part) error {
 		part.MetaSet("blob_storage_key", p.target.key)
+		part.MetaSet("blob_storage_message_index", strconv.Itoa(p.parts+i))
 		if p.obj.Container != nil {
 			part.MetaSet("blob_storage_container", p.obj.Container.Name)
 		}
@@ -348,6 +351,7 @@ func blobStorageMsgFromParts(p *azurePendingObject, parts []*message.Part) messa
 		}
 		return nil
 	})
+	p.parts += len(parts)
 	return msg
 }
 
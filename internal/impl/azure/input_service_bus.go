@@ -0,0 +1,320 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-amqp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sbiFieldSessions        = "sessions"
+	sbiFieldSessionID       = "session_id"
+	sbiFieldDeadLetterQueue = "dead_letter_queue"
+	sbiFieldDeadLetterNack  = "dead_letter_on_nack"
+	sbiFieldPrefetch        = "prefetch_count"
+
+	// The filter that a receiver attaches with in order to lock a session,
+	// which is either a session ID or null for the next available session.
+	sbSessionFilterName = "com.microsoft:session-filter"
+	sbSessionFilterCode = 0x00000137000000C
+
+	// The condition of a rejection that moves a message to the dead-letter
+	// queue of its entity.
+	sbDeadLetterCondition amqp.ErrorCondition = "com.microsoft:dead-letter"
+)
+
+func sbInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "Azure").
+		Summary("Consumes messages from an Azure Service Bus queue or topic subscription.").
+		Description(`
+Messages are received over AMQP 1.0 with a peek-lock, where a message is completed once it has been acknowledged, and is otherwise abandoned in order to be delivered again. A message that has been delivered more times than the maximum delivery count of its entity is moved to the dead-letter queue of the entity by Service Bus. Alternatively, when ` + "`dead_letter_on_nack`" + ` is enabled messages that are rejected are moved to the dead-letter queue immediately, with the error that caused the rejection as the reason.
+
+The lock of a message is held for the lock duration of the entity, and when a message is not acknowledged within that duration it is delivered again. The lock duration should therefore be longer than the time taken to process and deliver messages.
+
+In order to consume from a subscription of a topic set ` + "`entity_path`" + ` to ` + "`<topic>/Subscriptions/<subscription>`" + `.
+
+### Sessions
+
+Entities with sessions enabled can only be consumed by locking a session, in which case set ` + "`sessions`" + ` to ` + "`true`" + ` in order to lock the next available session, or ` + "`session_id`" + ` in order to lock a specific session. Messages of a session are received in the order in which they were sent.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- azure_service_bus_message_id
+- azure_service_bus_session_id
+- azure_service_bus_sequence_number
+- azure_service_bus_enqueued_time
+- azure_service_bus_delivery_count
+- azure_service_bus_content_type
+- azure_service_bus_subject
+- All string typed application properties
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range sbConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewBoolField(sbiFieldSessions).
+			Description("Whether to lock the next available session of the entity and consume its messages.").
+			Default(false)).
+		Field(service.NewStringField(sbiFieldSessionID).
+			Description("An optional ID of a specific session to lock and consume the messages of.").
+			Optional()).
+		Field(service.NewBoolField(sbiFieldDeadLetterQueue).
+			Description("Whether to consume from the dead-letter queue of the entity rather than the entity itself.").
+			Advanced().
+			Default(false)).
+		Field(service.NewBoolField(sbiFieldDeadLetterNack).
+			Description("Whether to move messages that are rejected to the dead-letter queue immediately, rather than abandoning them to be delivered again.").
+			Default(false)).
+		Field(service.NewIntField(sbiFieldPrefetch).
+			Description("The maximum number of messages to receive before they are acknowledged.").
+			Advanced().
+			Default(10)).
+		Example(
+			"Orders Subscription",
+			"In this example orders are consumed from a subscription of a topic, where orders that fail to be processed are moved to the dead-letter queue.",
+			`
+input:
+  azure_service_bus:
+    connection_string: "${SERVICE_BUS_CONNECTION_STRING}"
+    entity_path: orders/Subscriptions/fulfilment
+    dead_letter_on_nack: true
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"azure_service_bus", sbInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newSBInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sbInput struct {
+	conn           sbConnDetails
+	log            *service.Logger
+	address        string
+	sessions       bool
+	sessionID      *string
+	deadLetterNack bool
+	prefetch       uint32
+
+	m        sync.RWMutex
+	client   *amqp.Client
+	session  *amqp.Session
+	receiver *amqp.Receiver
+}
+
+func newSBInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*sbInput, error) {
+	s := &sbInput{log: log}
+
+	var err error
+	if s.conn, err = sbConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	s.address = s.conn.entityPath
+
+	deadLetterQueue, err := conf.FieldBool(sbiFieldDeadLetterQueue)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetterQueue {
+		s.address += "/$DeadLetterQueue"
+	}
+
+	if s.sessions, err = conf.FieldBool(sbiFieldSessions); err != nil {
+		return nil, err
+	}
+	if conf.Contains(sbiFieldSessionID) {
+		id, err := conf.FieldString(sbiFieldSessionID)
+		if err != nil {
+			return nil, err
+		}
+		s.sessionID = &id
+		s.sessions = true
+	}
+	if s.deadLetterNack, err = conf.FieldBool(sbiFieldDeadLetterNack); err != nil {
+		return nil, err
+	}
+
+	prefetch, err := conf.FieldInt(sbiFieldPrefetch)
+	if err != nil {
+		return nil, err
+	}
+	if prefetch < 1 {
+		return nil, fmt.Errorf("%v must be at least 1", sbiFieldPrefetch)
+	}
+	s.prefetch = uint32(prefetch)
+	return s, nil
+}
+
+func (s *sbInput) linkOptions() []amqp.LinkOption {
+	opts := []amqp.LinkOption{
+		amqp.LinkSourceAddress(s.address),
+		amqp.LinkCredit(s.prefetch),
+		amqp.LinkReceiverSettle(amqp.ModeSecond),
+	}
+	if s.sessions {
+		var filterValue any
+		if s.sessionID != nil {
+			filterValue = *s.sessionID
+		}
+		opts = append(opts, amqp.LinkSourceFilter(sbSessionFilterName, sbSessionFilterCode, filterValue))
+	}
+	return opts
+}
+
+func (s *sbInput) Connect(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.receiver != nil {
+		return nil
+	}
+
+	client, session, err := sbConnect(s.conn)
+	if err != nil {
+		return err
+	}
+	receiver, err := session.NewReceiver(s.linkOptions()...)
+	if err != nil {
+		_ = session.Close(ctx)
+		_ = client.Close()
+		return err
+	}
+
+	s.client, s.session, s.receiver = client, session, receiver
+	s.log.Infof("Receiving Azure Service Bus messages from entity: %v", s.address)
+	return nil
+}
+
+// sbMessage converts a received message into a benthos message with metadata
+// describing it.
+func sbMessage(aMsg *amqp.Message) *service.Message {
+	msg := service.NewMessage(aMsg.GetData())
+
+	if p := aMsg.Properties; p != nil {
+		if p.MessageID != nil {
+			msg.MetaSetMut("azure_service_bus_message_id", fmt.Sprintf("%v", p.MessageID))
+		}
+		if p.GroupID != nil {
+			msg.MetaSetMut("azure_service_bus_session_id", *p.GroupID)
+		}
+		if p.ContentType != nil {
+			msg.MetaSetMut("azure_service_bus_content_type", string(*p.ContentType))
+		}
+		if p.Subject != nil {
+			msg.MetaSetMut("azure_service_bus_subject", *p.Subject)
+		}
+	}
+	if seq, ok := aMsg.Annotations[sbAnnotationSequenceNo].(int64); ok {
+		msg.MetaSetMut("azure_service_bus_sequence_number", strconv.FormatInt(seq, 10))
+	}
+	if t, ok := aMsg.Annotations[sbAnnotationEnqueued].(time.Time); ok {
+		msg.MetaSetMut("azure_service_bus_enqueued_time", t.UTC().Format(time.RFC3339Nano))
+	}
+	if aMsg.Header != nil {
+		msg.MetaSetMut("azure_service_bus_delivery_count", strconv.FormatUint(uint64(aMsg.Header.DeliveryCount), 10))
+	}
+	for k, v := range aMsg.ApplicationProperties {
+		if str, ok := v.(string); ok {
+			msg.MetaSetMut(k, str)
+		}
+	}
+	return msg
+}
+
+// sbDeadLetterError returns the error a message is rejected with in order to
+// move it to the dead-letter queue.
+func sbDeadLetterError(err error) *amqp.Error {
+	desc := err.Error()
+	// Service Bus limits the length of dead-letter properties.
+	if len(desc) > 4096 {
+		desc = desc[:4096]
+	}
+	return &amqp.Error{
+		Condition:   sbDeadLetterCondition,
+		Description: desc,
+		Info: map[string]any{
+			"DeadLetterReason":           "Rejected",
+			"DeadLetterErrorDescription": desc,
+		},
+	}
+}
+
+func (s *sbInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	s.m.RLock()
+	receiver := s.receiver
+	s.m.RUnlock()
+
+	if receiver == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	aMsg, err := receiver.Receive(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		var dErr *amqp.DetachError
+		if errors.As(err, &dErr) && dErr.RemoteError != nil {
+			s.log.Errorf("Lost connection due to: %v", dErr.RemoteError)
+		} else {
+			s.log.Errorf("Lost connection due to: %v", err)
+		}
+		s.disconnect(ctx)
+		return nil, nil, service.ErrNotConnected
+	}
+
+	return sbMessage(aMsg), func(ctx context.Context, res error) error {
+		if res == nil {
+			return receiver.AcceptMessage(ctx, aMsg)
+		}
+		if s.deadLetterNack {
+			return receiver.RejectMessage(ctx, aMsg, sbDeadLetterError(res))
+		}
+		return receiver.ModifyMessage(ctx, aMsg, true, false, nil)
+	}, nil
+}
+
+func (s *sbInput) disconnect(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.receiver != nil {
+		_ = s.receiver.Close(ctx)
+		s.receiver = nil
+	}
+	if s.session != nil {
+		_ = s.session.Close(ctx)
+		s.session = nil
+	}
+	if s.client != nil {
+		_ = s.client.Close()
+		s.client = nil
+	}
+}
+
+func (s *sbInput) Close(ctx context.Context) error {
+	s.disconnect(ctx)
+	return nil
+}
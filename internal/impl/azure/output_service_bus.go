@@ -0,0 +1,318 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-amqp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sboFieldSessionID     = "session_id"
+	sboFieldMessageID     = "message_id"
+	sboFieldPartitionKey  = "partition_key"
+	sboFieldScheduledTime = "scheduled_enqueue_time"
+	sboFieldContentType   = "content_type"
+	sboFieldSubject       = "subject"
+	sboFieldMetadata      = "metadata"
+	sboFieldMaxInFlight   = "max_in_flight"
+)
+
+func sbOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "Azure").
+		Summary("Sends messages to an Azure Service Bus queue or topic.").
+		Description(`
+Messages are sent over AMQP 1.0 and authenticated with the shared access key of a connection string.
+
+### Sessions
+
+The entities of Service Bus that have sessions enabled require each message to have a session ID, which can be set with ` + "`session_id`" + `, and messages that share a session ID are delivered in the order they were sent to a single consumer at a time. In order to preserve this order it is recommended to use ` + "`max_in_flight: 1`" + `.
+
+### Scheduled Messages
+
+When ` + "`scheduled_enqueue_time`" + ` resolves to a timestamp, either as unix seconds or an [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, the message is only made available to consumers from that time onwards. When it resolves to an empty string the message is made available immediately.
+
+### Duplicate Detection
+
+Entities with duplicate detection enabled discard messages that share a message ID with another message sent within the detection window. Setting ` + "`message_id`" + ` to an ID derived from the contents of messages therefore prevents messages that are delivered more than once, for example when a batch is reattempted, from being received twice.`)
+
+	for _, f := range sbConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewInterpolatedStringField(sboFieldSessionID).
+			Description("An optional session ID of each message.").
+			Example(`${! this.customer_id }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(sboFieldMessageID).
+			Description("An optional ID of each message, which is used for duplicate detection.").
+			Example(`${! this.event_id }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(sboFieldPartitionKey).
+			Description("An optional key that determines the partition messages are sent to when the entity is partitioned. When a session ID is also set the two must match.").
+			Advanced().
+			Optional()).
+		Field(service.NewInterpolatedStringField(sboFieldScheduledTime).
+			Description("An optional time at which each message is made available to consumers.").
+			Example(`${! meta("scheduled_for") }`).
+			Example(`${! (timestamp_unix() + 3600) }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(sboFieldContentType).
+			Description("An optional content type of each message.").
+			Example("application/json").
+			Advanced().
+			Optional()).
+		Field(service.NewInterpolatedStringField(sboFieldSubject).
+			Description("An optional subject of each message, also known as its label, which subscription rules can filter by.").
+			Advanced().
+			Optional()).
+		Field(service.NewMetadataFilterField(sboFieldMetadata).
+			Description("Specify criteria for which metadata values are sent as application properties of messages.").
+			Optional()).
+		Field(service.NewIntField(sboFieldMaxInFlight).
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Delayed Reminders",
+			"In this example reminders are scheduled for the time within their contents, within sessions of the users they are for.",
+			`
+output:
+  azure_service_bus:
+    connection_string: "${SERVICE_BUS_CONNECTION_STRING}"
+    entity_path: reminders
+    session_id: ${! this.user_id }
+    message_id: ${! this.reminder_id }
+    scheduled_enqueue_time: ${! this.remind_at }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"azure_service_bus", sbOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(sboFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newSBOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sbOutput struct {
+	conn sbConnDetails
+	log  *service.Logger
+
+	sessionID     *service.InterpolatedString
+	messageID     *service.InterpolatedString
+	partitionKey  *service.InterpolatedString
+	scheduledTime *service.InterpolatedString
+	contentType   *service.InterpolatedString
+	subject       *service.InterpolatedString
+	metaFilter    *service.MetadataFilter
+
+	m       sync.RWMutex
+	client  *amqp.Client
+	session *amqp.Session
+	sender  *amqp.Sender
+}
+
+func newSBOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*sbOutput, error) {
+	s := &sbOutput{log: log}
+
+	var err error
+	if s.conn, err = sbConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct {
+		name string
+		ptr  **service.InterpolatedString
+	}{
+		{sboFieldSessionID, &s.sessionID},
+		{sboFieldMessageID, &s.messageID},
+		{sboFieldPartitionKey, &s.partitionKey},
+		{sboFieldScheduledTime, &s.scheduledTime},
+		{sboFieldContentType, &s.contentType},
+		{sboFieldSubject, &s.subject},
+	} {
+		if !conf.Contains(f.name) {
+			continue
+		}
+		if *f.ptr, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains(sboFieldMetadata) {
+		if s.metaFilter, err = conf.FieldMetadataFilter(sboFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *sbOutput) Connect(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.sender != nil {
+		return nil
+	}
+
+	client, session, err := sbConnect(s.conn)
+	if err != nil {
+		return err
+	}
+	sender, err := session.NewSender(amqp.LinkTargetAddress(s.conn.entityPath))
+	if err != nil {
+		_ = session.Close(ctx)
+		_ = client.Close()
+		return err
+	}
+
+	s.client, s.session, s.sender = client, session, sender
+	s.log.Infof("Sending Azure Service Bus messages to entity: %v", s.conn.entityPath)
+	return nil
+}
+
+// parseSBTime parses a time from either unix seconds or an RFC 3339
+// timestamp.
+func parseSBTime(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(f*float64(time.Second))).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return t, fmt.Errorf("failed to parse time '%v': expected either unix seconds or an RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
+func (s *sbOutput) amqpMessage(msg *service.Message) (*amqp.Message, error) {
+	body, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	aMsg := amqp.NewMessage(body)
+
+	props := &amqp.MessageProperties{}
+	hasProps := false
+	if s.sessionID != nil {
+		if v := s.sessionID.String(msg); v != "" {
+			props.GroupID = &v
+			hasProps = true
+		}
+	}
+	if s.messageID != nil {
+		if v := s.messageID.String(msg); v != "" {
+			props.MessageID = v
+			hasProps = true
+		}
+	}
+	if s.contentType != nil {
+		if v := s.contentType.String(msg); v != "" {
+			ct := amqp.AMQPSymbol(v)
+			props.ContentType = &ct
+			hasProps = true
+		}
+	}
+	if s.subject != nil {
+		if v := s.subject.String(msg); v != "" {
+			props.Subject = &v
+			hasProps = true
+		}
+	}
+	if hasProps {
+		aMsg.Properties = props
+	}
+
+	if s.partitionKey != nil {
+		if v := s.partitionKey.String(msg); v != "" {
+			if aMsg.Annotations == nil {
+				aMsg.Annotations = amqp.Annotations{}
+			}
+			aMsg.Annotations[sbAnnotationPartition] = v
+		}
+	}
+	if s.scheduledTime != nil {
+		if v := s.scheduledTime.String(msg); v != "" {
+			t, err := parseSBTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("scheduled enqueue time: %w", err)
+			}
+			if aMsg.Annotations == nil {
+				aMsg.Annotations = amqp.Annotations{}
+			}
+			aMsg.Annotations[sbAnnotationScheduled] = t
+		}
+	}
+
+	_ = s.metaFilter.Walk(msg, func(key, value string) error {
+		if aMsg.ApplicationProperties == nil {
+			aMsg.ApplicationProperties = map[string]any{}
+		}
+		aMsg.ApplicationProperties[key] = value
+		return nil
+	})
+	return aMsg, nil
+}
+
+func (s *sbOutput) Write(ctx context.Context, msg *service.Message) error {
+	s.m.RLock()
+	sender := s.sender
+	s.m.RUnlock()
+
+	if sender == nil {
+		return service.ErrNotConnected
+	}
+
+	aMsg, err := s.amqpMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := sender.Send(ctx, aMsg); err != nil {
+		if dErr, isDetachError := err.(*amqp.DetachError); isDetachError {
+			s.log.Errorf("Lost connection due to: %v", dErr)
+			s.disconnect(ctx)
+			return service.ErrNotConnected
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *sbOutput) disconnect(ctx context.Context) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.sender != nil {
+		_ = s.sender.Close(ctx)
+		s.sender = nil
+	}
+	if s.session != nil {
+		_ = s.session.Close(ctx)
+		s.session = nil
+	}
+	if s.client != nil {
+		_ = s.client.Close()
+		s.client = nil
+	}
+}
+
+func (s *sbOutput) Close(ctx context.Context) error {
+	s.disconnect(ctx)
+	return nil
+}
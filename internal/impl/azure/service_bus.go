@@ -0,0 +1,111 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/go-amqp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sbFieldConnectionString = "connection_string"
+	sbFieldEntityPath       = "entity_path"
+
+	// Message annotations with special meaning to Service Bus.
+	sbAnnotationScheduled  = "x-opt-scheduled-enqueue-time"
+	sbAnnotationPartition  = "x-opt-partition-key"
+	sbAnnotationSequenceNo = "x-opt-sequence-number"
+	sbAnnotationEnqueued   = "x-opt-enqueued-time"
+)
+
+func sbConnectionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(sbFieldConnectionString).
+			Description("A connection string of the namespace, which can be found under Shared access policies in the Azure portal.").
+			Example("Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=RootManageSharedAccessKey;SharedAccessKey=xxx"),
+		service.NewStringField(sbFieldEntityPath).
+			Description("The path of the entity, which can be omitted when the connection string contains an `EntityPath`.").
+			Default(""),
+	}
+}
+
+// sbConnDetails are the parts of a Service Bus connection string required in
+// order to connect to an entity.
+type sbConnDetails struct {
+	host       string
+	keyName    string
+	key        string
+	entityPath string
+}
+
+// parseSBConnectionString parses a connection string of the form
+// Endpoint=sb://<host>/;SharedAccessKeyName=<name>;SharedAccessKey=<key>.
+func parseSBConnectionString(connStr string) (d sbConnDetails, err error) {
+	for _, part := range strings.Split(connStr, ";") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		i := strings.Index(part, "=")
+		if i < 0 {
+			return d, fmt.Errorf("connection string segment missing a value: %v", part)
+		}
+		key, value := part[:i], part[i+1:]
+		switch strings.ToLower(key) {
+		case "endpoint":
+			value = strings.TrimPrefix(value, "sb://")
+			d.host = strings.TrimSuffix(value, "/")
+		case "sharedaccesskeyname":
+			d.keyName = value
+		case "sharedaccesskey":
+			d.key = value
+		case "entitypath":
+			d.entityPath = value
+		}
+	}
+	if d.host == "" {
+		return d, errors.New("connection string is missing an Endpoint")
+	}
+	if d.keyName == "" || d.key == "" {
+		return d, errors.New("connection string is missing a SharedAccessKeyName or SharedAccessKey")
+	}
+	return d, nil
+}
+
+func sbConnDetailsFromParsed(conf *service.ParsedConfig) (d sbConnDetails, err error) {
+	var connStr string
+	if connStr, err = conf.FieldString(sbFieldConnectionString); err != nil {
+		return
+	}
+	if d, err = parseSBConnectionString(connStr); err != nil {
+		return
+	}
+	var entityPath string
+	if entityPath, err = conf.FieldString(sbFieldEntityPath); err != nil {
+		return
+	}
+	if entityPath != "" {
+		d.entityPath = entityPath
+	}
+	if d.entityPath == "" {
+		err = fmt.Errorf("an %v must be specified when the connection string does not contain an EntityPath", sbFieldEntityPath)
+	}
+	return
+}
+
+// sbConnect opens a client and session to the namespace of a connection
+// string, authenticated with the shared access key.
+func sbConnect(d sbConnDetails) (*amqp.Client, *amqp.Session, error) {
+	client, err := amqp.Dial("amqps://"+d.host, amqp.ConnSASLPlain(d.keyName, d.key))
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+	return client, session, nil
+}
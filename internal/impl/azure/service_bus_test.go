@@ -0,0 +1,158 @@
+package azure
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestServiceBusConnectionString(t *testing.T) {
+	d, err := parseSBConnectionString("Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=Root;SharedAccessKey=abc=;EntityPath=orders")
+	require.NoError(t, err)
+	assert.Equal(t, sbConnDetails{
+		host:       "foo.servicebus.windows.net",
+		keyName:    "Root",
+		key:        "abc=",
+		entityPath: "orders",
+	}, d)
+
+	_, err = parseSBConnectionString("SharedAccessKeyName=Root;SharedAccessKey=abc")
+	require.Error(t, err)
+
+	_, err = parseSBConnectionString("Endpoint=sb://foo.servicebus.windows.net/")
+	require.Error(t, err)
+}
+
+func TestServiceBusOutputMessage(t *testing.T) {
+	conf, err := sbOutputSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=Root;SharedAccessKey=abc
+entity_path: reminders
+session_id: ${! this.user }
+message_id: ${! this.id }
+partition_key: ${! this.user }
+scheduled_enqueue_time: ${! this.at }
+subject: reminder
+metadata:
+  include_prefixes: [ app_ ]
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newSBOutputFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte(`{"user":"alice","id":"r1","at":"2026-01-02T03:04:05Z"}`))
+	msg.MetaSetMut("app_region", "eu")
+	msg.MetaSetMut("other", "nope")
+
+	aMsg, err := out.amqpMessage(msg)
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"user":"alice","id":"r1","at":"2026-01-02T03:04:05Z"}`, string(aMsg.GetData()))
+	require.NotNil(t, aMsg.Properties)
+	assert.Equal(t, "alice", *aMsg.Properties.GroupID)
+	assert.Equal(t, "r1", aMsg.Properties.MessageID)
+	assert.Equal(t, "reminder", *aMsg.Properties.Subject)
+	assert.Nil(t, aMsg.Properties.ContentType)
+	assert.Equal(t, "alice", aMsg.Annotations[sbAnnotationPartition])
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), aMsg.Annotations[sbAnnotationScheduled])
+	assert.Equal(t, map[string]any{"app_region": "eu"}, aMsg.ApplicationProperties)
+
+	// An empty scheduled time sends the message immediately.
+	aMsg, err = out.amqpMessage(service.NewMessage([]byte(`{"user":"bob","id":"r2","at":""}`)))
+	require.NoError(t, err)
+	assert.NotContains(t, aMsg.Annotations, sbAnnotationScheduled)
+
+	_, err = out.amqpMessage(service.NewMessage([]byte(`{"user":"bob","id":"r2","at":"tomorrow"}`)))
+	require.Error(t, err)
+}
+
+func TestServiceBusTime(t *testing.T) {
+	ts, err := parseSBTime("1767323045")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), ts)
+
+	ts, err = parseSBTime("2026-01-02T03:04:05.5+01:00")
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(time.Date(2026, 1, 2, 2, 4, 5, 500000000, time.UTC)))
+}
+
+func TestServiceBusInputMessage(t *testing.T) {
+	sessionID, subject := "alice", "reminder"
+	enqueued := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg := sbMessage(&amqp.Message{
+		Data: [][]byte{[]byte("hello world")},
+		Header: &amqp.MessageHeader{
+			DeliveryCount: 2,
+		},
+		Properties: &amqp.MessageProperties{
+			MessageID: "r1",
+			GroupID:   &sessionID,
+			Subject:   &subject,
+		},
+		Annotations: amqp.Annotations{
+			sbAnnotationSequenceNo: int64(42),
+			sbAnnotationEnqueued:   enqueued,
+		},
+		ApplicationProperties: map[string]any{
+			"region": "eu",
+			"count":  int64(5),
+		},
+	})
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+
+	meta := map[string]string{}
+	_ = msg.MetaWalk(func(k, v string) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]string{
+		"azure_service_bus_message_id":      "r1",
+		"azure_service_bus_session_id":      "alice",
+		"azure_service_bus_subject":         "reminder",
+		"azure_service_bus_sequence_number": "42",
+		"azure_service_bus_enqueued_time":   "2026-01-02T03:04:05Z",
+		"azure_service_bus_delivery_count":  "2",
+		"region":                            "eu",
+	}, meta)
+}
+
+func TestServiceBusInputLinkOptions(t *testing.T) {
+	conf, err := sbInputSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=Root;SharedAccessKey=abc;EntityPath=orders
+session_id: alice
+dead_letter_queue: true
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newSBInputFromParsed(conf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "orders/$DeadLetterQueue", in.address)
+	assert.True(t, in.sessions)
+	assert.Len(t, in.linkOptions(), 4)
+
+	conf, err = sbInputSpec().ParseYAML(`
+connection_string: Endpoint=sb://foo.servicebus.windows.net/;SharedAccessKeyName=Root;SharedAccessKey=abc
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newSBInputFromParsed(conf, nil)
+	require.Error(t, err)
+}
+
+func TestServiceBusDeadLetterError(t *testing.T) {
+	err := sbDeadLetterError(errors.New(strings.Repeat("a", 5000)))
+	assert.Equal(t, sbDeadLetterCondition, err.Condition)
+	assert.Len(t, err.Description, 4096)
+	assert.Equal(t, err.Description, err.Info["DeadLetterErrorDescription"])
+}
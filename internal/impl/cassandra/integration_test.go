@@ -99,6 +99,48 @@ output:
 		)
 	})
 
+	t.Run("with partition batches", func(t *testing.T) {
+		template := `
+output:
+  cassandra:
+    addresses:
+      - localhost:$PORT
+    query: 'INSERT INTO testspace.table$ID JSON ?'
+    args_mapping: 'root = [ this ]'
+    idempotent: true
+    group_by_partition_key: true
+`
+		queryGetFn := func(ctx context.Context, testID, messageID string) (string, []string, error) {
+			var resID int
+			var resContent string
+			if err := session.Query(
+				fmt.Sprintf("select id, content from testspace.table%v where id = ?;", testID), messageID,
+			).Scan(&resID, &resContent); err != nil {
+				return "", nil, err
+			}
+			return fmt.Sprintf(`{"content":"%v","id":%v}`, resContent, resID), nil, err
+		}
+		suite := integration.StreamTests(
+			integration.StreamTestOutputOnlySendSequential(10, queryGetFn),
+			integration.StreamTestOutputOnlySendBatch(10, queryGetFn),
+		)
+		suite.Run(
+			t, template,
+			integration.StreamTestOptPort(resource.GetPort("9042/tcp")),
+			integration.StreamTestOptSleepAfterInput(time.Second*10),
+			integration.StreamTestOptSleepAfterOutput(time.Second*10),
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, testID string, vars *integration.StreamTestConfigVars) {
+				vars.ID = strings.ReplaceAll(testID, "-", "")
+				require.NoError(t, session.Query(
+					fmt.Sprintf(
+						"CREATE TABLE testspace.table%v (id int primary key, content text, created_at timestamp);",
+						vars.ID,
+					),
+				).Exec())
+			}),
+		)
+	})
+
 	t.Run("with values", func(t *testing.T) {
 		template := `
 output:
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/gocql/gocql"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/batch/policy"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
@@ -48,7 +50,19 @@ Runs a query against a Cassandra database for each message in order to insert da
 		Description: output.Description(true, true, `
 Query arguments can be set using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the `+"`args`"+` field or by creating a bloblang array for the fields using the `+"`args_mapping`"+` field.
 
-When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.`),
+When populating timestamp columns the value must either be a string in ISO 8601 format (2006-01-02T15:04:05Z07:00), or an integer representing unix time in seconds.
+
+### Prepared Statements
+
+Queries are prepared by the driver the first time they are executed against a host, and the prepared statements are cached for subsequent executions. The size of this cache can be set with the field `+"`max_prepared_statements`"+`, which only needs to be increased when the `+"`query`"+` yields a large number of distinct statements.
+
+### Partition Batching
+
+Batches of messages are written as a single unlogged batch, which for rows spanning many partitions places a burden on the coordinating node. When `+"`group_by_partition_key`"+` is enabled the rows of a batch are instead grouped by their partition key and each group is written as a separate unlogged batch, which with `+"`token_aware`"+` routing is sent directly to a replica of the partition. When a group fails only the messages of that group are reattempted.
+
+### Retries
+
+Requests that fail due to an unavailable replica or a timeout are retried up to `+"`max_retries`"+` times. Since it is impossible to determine whether a write that timed out was applied, writes that time out are only retried when `+"`idempotent`"+` is set, which should only be done when the query can be safely applied more than once, for example a plain `+"`INSERT`"+` or `+"`UPDATE`"+` without counters, list appends or lightweight transactions.`),
 		Examples: []docs.AnnotatedExample{
 			{
 				Title:   "Basic Inserts",
@@ -115,6 +129,14 @@ output:
 			).HasOptions(
 				"ANY", "ONE", "TWO", "THREE", "QUORUM", "ALL", "LOCAL_QUORUM", "EACH_QUORUM", "LOCAL_ONE",
 			).Advanced(),
+			docs.FieldString(
+				"serial_consistency",
+				"The consistency level to use for the serial phase of lightweight transactions, either `SERIAL` or `LOCAL_SERIAL`. When empty the default of the cluster is used.",
+			).Advanced().AtVersion("4.9.0"),
+			docs.FieldBool("idempotent", "Whether the query is idempotent, in which case writes that time out or fail due to a lost connection are safe to retry.").Advanced().AtVersion("4.9.0"),
+			docs.FieldBool("token_aware", "Whether to route requests directly to a replica of the partition being written to. This requires host information and therefore has no effect when `disable_initial_host_lookup` is enabled.").Advanced().AtVersion("4.9.0"),
+			docs.FieldBool("group_by_partition_key", "Whether to split batches of messages into unlogged batches of rows that share a partition key.").Advanced().AtVersion("4.9.0"),
+			docs.FieldInt("max_prepared_statements", "The maximum number of prepared statements to cache.").Advanced().AtVersion("4.9.0"),
 			docs.FieldInt("max_retries", "The maximum number of retries before giving up on a request.").Advanced(),
 			docs.FieldObject("backoff", "Control time intervals between retry attempts.").WithChildren(
 				docs.FieldString("initial_interval", "The initial period to wait between retry attempts."),
@@ -210,11 +232,23 @@ func (c *cassandraWriter) Connect(ctx context.Context) error {
 	if conn.Consistency, err = gocql.ParseConsistencyWrapper(c.conf.Consistency); err != nil {
 		return fmt.Errorf("parsing consistency: %w", err)
 	}
+	if c.conf.SerialConsistency != "" {
+		if err = conn.SerialConsistency.UnmarshalText([]byte(c.conf.SerialConsistency)); err != nil {
+			return fmt.Errorf("parsing serial consistency: %w", err)
+		}
+	}
+	if c.conf.TokenAware {
+		conn.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
+	}
+	if c.conf.MaxPreparedStatements > 0 {
+		conn.MaxPreparedStmts = c.conf.MaxPreparedStatements
+	}
 
 	conn.RetryPolicy = &decorator{
 		NumRetries: int(c.conf.Config.MaxRetries),
 		Min:        c.backoffMin,
 		Max:        c.backoffMax,
+		Idempotent: c.conf.Idempotent,
 	}
 	if tout := c.conf.Timeout; len(tout) > 0 {
 		var err error
@@ -244,6 +278,9 @@ func (c *cassandraWriter) WriteBatch(ctx context.Context, msg message.Batch) err
 	if msg.Len() == 1 {
 		return c.writeRow(session, msg)
 	}
+	if c.conf.GroupByPartitionKey {
+		return c.writePartitionBatches(session, msg)
+	}
 	return c.writeBatch(session, msg)
 }
 
@@ -253,7 +290,7 @@ func (c *cassandraWriter) writeRow(session *gocql.Session, msg message.Batch) er
 		return fmt.Errorf("parsing args: %w", err)
 	}
 
-	if err := session.Query(c.conf.Query, values...).Exec(); err != nil {
+	if err := session.Query(c.conf.Query, values...).Idempotent(c.conf.Idempotent).Exec(); err != nil {
 		return err
 	}
 	return nil
@@ -267,7 +304,11 @@ func (c *cassandraWriter) writeBatch(session *gocql.Session, msg message.Batch)
 		if err != nil {
 			return fmt.Errorf("parsing args for part: %d: %w", i, err)
 		}
-		batch.Query(c.conf.Query, values...)
+		batch.Entries = append(batch.Entries, gocql.BatchEntry{
+			Stmt:       c.conf.Query,
+			Args:       values,
+			Idempotent: c.conf.Idempotent,
+		})
 		return nil
 	}); err != nil {
 		return err
@@ -280,6 +321,64 @@ func (c *cassandraWriter) writeBatch(session *gocql.Session, msg message.Batch)
 	return nil
 }
 
+// writePartitionBatches groups the rows of a batch by their partition key and
+// writes each group as a separate unlogged batch, in order for the token aware
+// host policy to route each to a replica of the partition.
+func (c *cassandraWriter) writePartitionBatches(session *gocql.Session, msg message.Batch) error {
+	type group struct {
+		indexes []int
+		batch   *gocql.Batch
+	}
+
+	var groups []*group
+	byKey := map[string]*group{}
+
+	if err := msg.Iter(func(i int, p *message.Part) error {
+		values, err := c.mapArgs(msg, i)
+		if err != nil {
+			return fmt.Errorf("parsing args for part: %d: %w", i, err)
+		}
+		key, err := session.Query(c.conf.Query, values...).GetRoutingKey()
+		if err != nil {
+			return fmt.Errorf("obtaining partition key for part: %d: %w", i, err)
+		}
+		g, exists := byKey[string(key)]
+		if !exists {
+			g = &group{batch: session.NewBatch(gocql.UnloggedBatch)}
+			byKey[string(key)] = g
+			groups = append(groups, g)
+		}
+		g.indexes = append(g.indexes, i)
+		g.batch.Entries = append(g.batch.Entries, gocql.BatchEntry{
+			Stmt:       c.conf.Query,
+			Args:       values,
+			Idempotent: c.conf.Idempotent,
+		})
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var batchErr *batch.Error
+	for _, g := range groups {
+		if err := session.ExecuteBatch(g.batch); err != nil {
+			if len(groups) == 1 {
+				return err
+			}
+			if batchErr == nil {
+				batchErr = batch.NewError(msg, err)
+			}
+			for _, i := range g.indexes {
+				batchErr.Failed(i, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
 func (c *cassandraWriter) mapArgs(msg message.Batch, index int) ([]any, error) {
 	if c.argsMapping != nil {
 		// We've got an "args_mapping" field, extract values from there.
@@ -329,6 +428,7 @@ func (c *cassandraWriter) Close(context.Context) error {
 type decorator struct {
 	NumRetries int
 	Min, Max   time.Duration
+	Idempotent bool
 }
 
 func (d *decorator) Attempt(q gocql.RetryableQuery) bool {
@@ -361,13 +461,20 @@ func (d *decorator) GetRetryType(err error) gocql.RetryType {
 		return gocql.Retry
 	// write timeout - uncertain whetever write was successful or not
 	case *gocql.RequestErrWriteTimeout:
+		if d.Idempotent {
+			return gocql.Retry
+		}
 		if t.Received > 0 {
 			return gocql.Ignore
 		}
 		return gocql.Retry
-	default:
-		return gocql.Rethrow
 	}
+	// no response from the host - only safe to retry elsewhere when the write
+	// can be applied more than once
+	if d.Idempotent && (errors.Is(err, gocql.ErrTimeoutNoResponse) || errors.Is(err, gocql.ErrConnectionClosed)) {
+		return gocql.RetryNextHost
+	}
+	return gocql.Rethrow
 }
 
 func formatCassandraInt64(x int64) []byte {
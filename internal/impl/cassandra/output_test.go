@@ -0,0 +1,35 @@
+package cassandra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		idempotent bool
+		exp        gocql.RetryType
+	}{
+		{name: "unavailable with replicas", err: &gocql.RequestErrUnavailable{Alive: 1}, exp: gocql.RetryNextHost},
+		{name: "unavailable without replicas", err: &gocql.RequestErrUnavailable{}, exp: gocql.Retry},
+		{name: "write timeout received", err: &gocql.RequestErrWriteTimeout{Received: 1}, exp: gocql.Ignore},
+		{name: "write timeout received idempotent", err: &gocql.RequestErrWriteTimeout{Received: 1}, idempotent: true, exp: gocql.Retry},
+		{name: "no response", err: gocql.ErrTimeoutNoResponse, exp: gocql.Rethrow},
+		{name: "no response idempotent", err: gocql.ErrTimeoutNoResponse, idempotent: true, exp: gocql.RetryNextHost},
+		{name: "connection closed idempotent", err: gocql.ErrConnectionClosed, idempotent: true, exp: gocql.RetryNextHost},
+		{name: "other idempotent", err: errors.New("nope"), idempotent: true, exp: gocql.Rethrow},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			d := &decorator{Idempotent: test.idempotent}
+			assert.Equal(t, test.exp, d.GetRetryType(test.err))
+		})
+	}
+}
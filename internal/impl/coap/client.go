@@ -0,0 +1,424 @@
+package coap
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	coapFieldURL           = "url"
+	coapFieldPSK           = "psk"
+	coapFieldPSKIdentity   = "identity"
+	coapFieldPSKKey        = "key"
+	coapFieldAckTimeout    = "ack_timeout"
+	coapFieldMaxRetransmit = "max_retransmit"
+
+	// The period of time for which message IDs of the server are remembered in
+	// order to detect duplicates, which is the EXCHANGE_LIFETIME of RFC 7252
+	// section 4.8.2.
+	coapExchangeLifetime = 247 * time.Second
+)
+
+func coapConnectionFields(urlDescription string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(coapFieldURL).
+			Description(urlDescription + " The scheme `coap` is used for plain UDP and the scheme `coaps` for DTLS, where the port defaults to 5683 and 5684 respectively.").
+			Example("coap://localhost:5683/sensors/temperature").
+			Example("coaps://10.0.0.12/actuators/valve?id=3"),
+		service.NewObjectField(coapFieldPSK,
+			service.NewStringField(coapFieldPSKIdentity).
+				Description("The identity of the pre-shared key."),
+			service.NewStringField(coapFieldPSKKey).
+				Description("The pre-shared key."),
+		).
+			Description("The pre-shared key to authenticate with when the `coaps` scheme is used, where the cipher suite `TLS_PSK_WITH_AES_128_CCM_8` of DTLS 1.2 is negotiated.").
+			Optional(),
+		service.NewDurationField(coapFieldAckTimeout).
+			Description("The initial period of time to wait for a confirmable message to be acknowledged before it is sent again, which is doubled after each attempt.").
+			Advanced().
+			Default("2s"),
+		service.NewIntField(coapFieldMaxRetransmit).
+			Description("The maximum number of times that a confirmable message is sent again before it is considered lost.").
+			Advanced().
+			Default(4),
+	}
+}
+
+type coapConnDetails struct {
+	url           *url.URL
+	address       string
+	pskIdentity   string
+	pskKey        []byte
+	ackTimeout    time.Duration
+	maxRetransmit int
+}
+
+func coapConnDetailsFromParsed(conf *service.ParsedConfig) (d coapConnDetails, err error) {
+	var urlStr string
+	if urlStr, err = conf.FieldString(coapFieldURL); err != nil {
+		return
+	}
+	if d.url, err = url.Parse(urlStr); err != nil {
+		err = fmt.Errorf("failed to parse url: %w", err)
+		return
+	}
+
+	var defaultPort string
+	switch d.url.Scheme {
+	case "coap":
+		defaultPort = "5683"
+	case "coaps":
+		defaultPort = "5684"
+	default:
+		err = fmt.Errorf("unsupported url scheme %q, expected coap or coaps", d.url.Scheme)
+		return
+	}
+	if d.url.Hostname() == "" {
+		err = errors.New("the url must have a host")
+		return
+	}
+	port := d.url.Port()
+	if port == "" {
+		port = defaultPort
+	}
+	d.address = net.JoinHostPort(d.url.Hostname(), port)
+
+	if conf.Contains(coapFieldPSK) {
+		if d.pskIdentity, err = conf.FieldString(coapFieldPSK, coapFieldPSKIdentity); err != nil {
+			return
+		}
+		var key string
+		if key, err = conf.FieldString(coapFieldPSK, coapFieldPSKKey); err != nil {
+			return
+		}
+		d.pskKey = []byte(key)
+	}
+	if d.url.Scheme == "coaps" && len(d.pskKey) == 0 {
+		err = errors.New("a pre-shared key must be specified for the coaps scheme")
+		return
+	}
+
+	if d.ackTimeout, err = conf.FieldDuration(coapFieldAckTimeout); err != nil {
+		return
+	}
+	if d.maxRetransmit, err = conf.FieldInt(coapFieldMaxRetransmit); err != nil {
+		return
+	}
+	return
+}
+
+// requestOptions returns the options of a request for an escaped path and a
+// query, where the host is added when it is not an IP address as described in
+// RFC 7252 section 6.4. The port is never added as requests are always sent to
+// the port of the url.
+func (d coapConnDetails) requestOptions(path, query string) ([]coapOption, error) {
+	var options []coapOption
+	if host := d.url.Hostname(); net.ParseIP(host) == nil {
+		options = append(options, coapOption{number: coapOptionURIHost, value: []byte(host)})
+	}
+	pathOptions, err := coapPathOptions(path, query)
+	if err != nil {
+		return nil, err
+	}
+	return append(options, pathOptions...), nil
+}
+
+//------------------------------------------------------------------------------
+
+// coapClient is an endpoint that exchanges messages with a single server,
+// where confirmable messages are sent again until they are acknowledged and
+// responses are matched to their requests by their tokens.
+type coapClient struct {
+	conn          io.ReadWriteCloser
+	log           *service.Logger
+	ackTimeout    time.Duration
+	maxRetransmit int
+
+	mut       sync.Mutex
+	nextMID   uint16
+	acks      map[uint16]chan *coapMessage
+	handlers  map[string]func(*coapMessage)
+	seen      map[uint16]time.Time
+	lastPurge time.Time
+
+	closed  chan struct{}
+	failMut sync.Mutex
+	err     error
+}
+
+func dialCoAP(ctx context.Context, d coapConnDetails, log *service.Logger) (*coapClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", d.address)
+	if err != nil {
+		return nil, err
+	}
+
+	var rwc io.ReadWriteCloser = conn
+	if d.url.Scheme == "coaps" {
+		if d.ackTimeout > 0 {
+			var done func()
+			ctx, done = context.WithTimeout(ctx, d.ackTimeout*time.Duration(1<<d.maxRetransmit))
+			defer done()
+		}
+		if rwc, err = dialDTLS(ctx, conn, d.pskIdentity, d.pskKey); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("dtls handshake failed: %w", err)
+		}
+	}
+	return newCoAPClient(rwc, d, log), nil
+}
+
+func newCoAPClient(conn io.ReadWriteCloser, d coapConnDetails, log *service.Logger) *coapClient {
+	mid, _ := rand.Int(rand.Reader, big.NewInt(1<<16))
+	c := &coapClient{
+		conn:          conn,
+		log:           log,
+		ackTimeout:    d.ackTimeout,
+		maxRetransmit: d.maxRetransmit,
+		nextMID:       uint16(mid.Int64()),
+		acks:          map[uint16]chan *coapMessage{},
+		handlers:      map[string]func(*coapMessage){},
+		seen:          map[uint16]time.Time{},
+		closed:        make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *coapClient) fail(err error) {
+	c.failMut.Lock()
+	defer c.failMut.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.closed)
+		_ = c.conn.Close()
+	}
+}
+
+func (c *coapClient) failure() error {
+	c.failMut.Lock()
+	defer c.failMut.Unlock()
+	return c.err
+}
+
+func (c *coapClient) close() {
+	c.fail(errors.New("connection closed"))
+}
+
+func (c *coapClient) send(m *coapMessage) error {
+	b, err := m.marshal()
+	if err != nil {
+		return err
+	}
+	if _, err := c.conn.Write(b); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+func newCoAPToken() ([]byte, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// handle registers a handler of the responses with a token.
+func (c *coapClient) handle(token []byte, fn func(*coapMessage)) {
+	c.mut.Lock()
+	c.handlers[string(token)] = fn
+	c.mut.Unlock()
+}
+
+func (c *coapClient) unhandle(token []byte) {
+	c.mut.Lock()
+	delete(c.handlers, string(token))
+	c.mut.Unlock()
+}
+
+// exchange sends a request with a new message ID, where confirmable requests
+// are sent again until they are acknowledged. Responses are passed to the
+// handler of the token of the request, including those that are piggybacked on
+// the acknowledgement.
+func (c *coapClient) exchange(ctx context.Context, m *coapMessage) error {
+	c.mut.Lock()
+	m.messageID = c.nextMID
+	c.nextMID++
+	var ackCh chan *coapMessage
+	if m.typ == coapConfirmable {
+		ackCh = make(chan *coapMessage, 1)
+		c.acks[m.messageID] = ackCh
+	}
+	c.mut.Unlock()
+
+	if ackCh == nil {
+		return c.send(m)
+	}
+	defer func() {
+		c.mut.Lock()
+		delete(c.acks, m.messageID)
+		c.mut.Unlock()
+	}()
+
+	// The initial timeout is randomised between the timeout and one and a half
+	// times the timeout, RFC 7252 section 4.2.
+	timeout := c.ackTimeout
+	if jitter, err := rand.Int(rand.Reader, big.NewInt(int64(c.ackTimeout/2)+1)); err == nil {
+		timeout += time.Duration(jitter.Int64())
+	}
+	for attempt := 0; ; attempt++ {
+		if err := c.send(m); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(timeout)
+		select {
+		case ack := <-ackCh:
+			timer.Stop()
+			if ack.typ == coapReset {
+				return errors.New("request was rejected with a reset message")
+			}
+			return nil
+		case <-timer.C:
+		case <-c.closed:
+			timer.Stop()
+			return c.failure()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		if attempt >= c.maxRetransmit {
+			return fmt.Errorf("request was not acknowledged after %v attempts", attempt+1)
+		}
+		timeout *= 2
+	}
+}
+
+// do sends a request and waits for its response.
+func (c *coapClient) do(ctx context.Context, m *coapMessage) (*coapMessage, error) {
+	token, err := newCoAPToken()
+	if err != nil {
+		return nil, err
+	}
+	m.token = token
+
+	resCh := make(chan *coapMessage, 1)
+	c.handle(token, func(res *coapMessage) {
+		select {
+		case resCh <- res:
+		default:
+		}
+	})
+	defer c.unhandle(token)
+
+	if err := c.exchange(ctx, m); err != nil {
+		return nil, err
+	}
+
+	// A response that is not piggybacked on the acknowledgement is waited for
+	// at most for the MAX_TRANSMIT_WAIT of RFC 7252 section 4.8.2.
+	timer := time.NewTimer(c.ackTimeout * time.Duration(1<<(c.maxRetransmit+1)-1) * 3 / 2)
+	defer timer.Stop()
+	select {
+	case res := <-resCh:
+		return res, nil
+	case <-timer.C:
+		return nil, errors.New("timed out waiting for a response")
+	case <-c.closed:
+		return nil, c.failure()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// duplicate returns whether a message of the server has been received before,
+// where the message IDs of the exchange lifetime are remembered.
+func (c *coapClient) duplicate(mid uint16) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPurge) > time.Second {
+		for k, t := range c.seen {
+			if now.Sub(t) > coapExchangeLifetime {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPurge = now
+	}
+	if _, exists := c.seen[mid]; exists {
+		return true
+	}
+	c.seen[mid] = now
+	return false
+}
+
+func (c *coapClient) readLoop() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		m, err := unmarshalCoAPMessage(buf[:n])
+		if err != nil {
+			c.log.Debugf("Discarding malformed message: %v", err)
+			continue
+		}
+
+		c.mut.Lock()
+		fn := c.handlers[string(m.token)]
+		c.mut.Unlock()
+
+		switch m.typ {
+		case coapAcknowledgment, coapReset:
+			c.mut.Lock()
+			ackCh := c.acks[m.messageID]
+			c.mut.Unlock()
+			if ackCh != nil {
+				select {
+				case ackCh <- m:
+				default:
+				}
+			}
+			if m.typ == coapReset || !m.isResponse() {
+				continue
+			}
+		case coapConfirmable, coapNonConfirmable:
+			// Requests of the server, and responses that nothing is waiting
+			// for, are rejected, which also cancels the observation of a
+			// resource that is no longer wanted.
+			if !m.isResponse() || fn == nil {
+				_ = c.send(&coapMessage{typ: coapReset, messageID: m.messageID})
+				continue
+			}
+
+			// An acknowledgement is sent for each copy of a confirmable message
+			// as the previous acknowledgement may have been lost.
+			duplicate := c.duplicate(m.messageID)
+			if m.typ == coapConfirmable {
+				_ = c.send(&coapMessage{typ: coapAcknowledgment, messageID: m.messageID})
+			}
+			if duplicate {
+				continue
+			}
+		}
+		if fn != nil {
+			fn(m)
+		}
+	}
+}
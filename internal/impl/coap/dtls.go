@@ -0,0 +1,732 @@
+package coap
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// The following is a client of DTLS 1.2 (RFC 6347) that authenticates with a
+// pre-shared key (RFC 4279) and uses the TLS_PSK_WITH_AES_128_CCM_8 cipher
+// suite (RFC 6655), which CoAP implementations are required to support in the
+// PreSharedKey mode (RFC 7252 section 9.1.3.1). Handshake messages are small
+// with pre-shared keys and are therefore never fragmented.
+
+const (
+	dtlsVersion             uint16 = 0xfefd
+	dtlsCipherPSKAES128CCM8 uint16 = 0xc0a8
+
+	dtlsContentChangeCipherSpec uint8 = 20
+	dtlsContentAlert            uint8 = 21
+	dtlsContentHandshake        uint8 = 22
+	dtlsContentApplicationData  uint8 = 23
+
+	dtlsHandshakeClientHello        uint8 = 1
+	dtlsHandshakeServerHello        uint8 = 2
+	dtlsHandshakeHelloVerifyRequest uint8 = 3
+	dtlsHandshakeServerKeyExchange  uint8 = 12
+	dtlsHandshakeServerHelloDone    uint8 = 14
+	dtlsHandshakeClientKeyExchange  uint8 = 16
+	dtlsHandshakeFinished           uint8 = 20
+
+	dtlsRecordHeaderLen    = 13
+	dtlsHandshakeHeaderLen = 12
+	dtlsExplicitNonceLen   = 8
+	dtlsTagLen             = 8
+
+	// The period of time to wait for a flight of handshake messages before the
+	// previous flight is sent again, which is doubled with each attempt.
+	dtlsInitialRetransmit = time.Second
+	dtlsMaxDatagramSize   = 65535
+)
+
+var errDTLSClosed = errors.New("dtls connection closed")
+
+// dtlsTransport is the connection of a single peer that datagrams are sent
+// over, which is a connected UDP socket for clients.
+type dtlsTransport interface {
+	io.ReadWriteCloser
+	SetReadDeadline(t time.Time) error
+}
+
+//------------------------------------------------------------------------------
+
+func dtlsAppendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+// dtlsXOR sets dst to the exclusive or of the common length of a and b, and
+// returns that length.
+func dtlsXOR(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
+
+func dtlsPRF(secret []byte, label string, seed []byte, n int) []byte {
+	seed = append([]byte(label), seed...)
+
+	h := hmac.New(sha256.New, secret)
+	_, _ = h.Write(seed)
+	a := h.Sum(nil)
+
+	var out []byte
+	for len(out) < n {
+		h.Reset()
+		_, _ = h.Write(a)
+		_, _ = h.Write(seed)
+		out = h.Sum(out)
+
+		h.Reset()
+		_, _ = h.Write(a)
+		a = h.Sum(nil)
+	}
+	return out[:n]
+}
+
+// dtlsPSKMasterSecret returns the master secret of a pre-shared key, where the
+// premaster secret consists of a zero filled block and the key, each of which
+// is prefixed with its length.
+func dtlsPSKMasterSecret(psk, clientRandom, serverRandom []byte) []byte {
+	premaster := make([]byte, 0, 4+2*len(psk))
+	premaster = dtlsAppendUint16(premaster, uint16(len(psk)))
+	premaster = append(premaster, make([]byte, len(psk))...)
+	premaster = dtlsAppendUint16(premaster, uint16(len(psk)))
+	premaster = append(premaster, psk...)
+	return dtlsPRF(premaster, "master secret", append(append([]byte(nil), clientRandom...), serverRandom...), 48)
+}
+
+// dtlsKeys derives the keys and implicit nonces of both directions from a
+// master secret.
+func dtlsKeys(master, clientRandom, serverRandom []byte) (clientKey, serverKey, clientIV, serverIV []byte) {
+	block := dtlsPRF(master, "key expansion", append(append([]byte(nil), serverRandom...), clientRandom...), 40)
+	return block[0:16], block[16:32], block[32:36], block[36:40]
+}
+
+//------------------------------------------------------------------------------
+
+// dtlsCCM implements the AES-CCM mode of RFC 3610 with a tag of eight bytes.
+type dtlsCCM struct {
+	block cipher.Block
+}
+
+func newDTLSCCM(key []byte) (*dtlsCCM, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &dtlsCCM{block: block}, nil
+}
+
+func (c *dtlsCCM) counterBlock(nonce []byte, i byte) []byte {
+	a := make([]byte, aes.BlockSize)
+	a[0] = byte(14 - len(nonce))
+	copy(a[1:], nonce)
+	a[aes.BlockSize-1] = i
+	return a
+}
+
+func (c *dtlsCCM) tag(nonce, plaintext, aad []byte) []byte {
+	l := 15 - len(nonce)
+
+	b := make([]byte, aes.BlockSize)
+	b[0] = byte((dtlsTagLen-2)/2)<<3 | byte(l-1)
+	if len(aad) > 0 {
+		b[0] |= 0x40
+	}
+	copy(b[1:], nonce)
+	n := len(plaintext)
+	for i := aes.BlockSize - 1; i > len(nonce); i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+
+	x := make([]byte, aes.BlockSize)
+	c.block.Encrypt(x, b)
+	mac := func(data []byte) {
+		for len(data) > 0 {
+			n := dtlsXOR(x, x, data)
+			data = data[n:]
+			c.block.Encrypt(x, x)
+		}
+	}
+	if len(aad) > 0 {
+		encoded := dtlsAppendUint16(nil, uint16(len(aad)))
+		encoded = append(encoded, aad...)
+		for len(encoded)%aes.BlockSize != 0 {
+			encoded = append(encoded, 0)
+		}
+		mac(encoded)
+	}
+	padded := append([]byte(nil), plaintext...)
+	for len(padded)%aes.BlockSize != 0 {
+		padded = append(padded, 0)
+	}
+	mac(padded)
+
+	s0 := make([]byte, aes.BlockSize)
+	c.block.Encrypt(s0, c.counterBlock(nonce, 0))
+	dtlsXOR(x, x, s0)
+	return x[:dtlsTagLen]
+}
+
+func (c *dtlsCCM) xorKeyStream(nonce, dst, src []byte) {
+	cipher.NewCTR(c.block, c.counterBlock(nonce, 1)).XORKeyStream(dst, src)
+}
+
+func (c *dtlsCCM) seal(nonce, plaintext, aad []byte) []byte {
+	out := make([]byte, len(plaintext), len(plaintext)+dtlsTagLen)
+	c.xorKeyStream(nonce, out, plaintext)
+	return append(out, c.tag(nonce, plaintext, aad)...)
+}
+
+func (c *dtlsCCM) open(nonce, ciphertext, aad []byte) ([]byte, error) {
+	if len(ciphertext) < dtlsTagLen {
+		return nil, errors.New("ciphertext is shorter than its tag")
+	}
+	tag := ciphertext[len(ciphertext)-dtlsTagLen:]
+	out := make([]byte, len(ciphertext)-dtlsTagLen)
+	c.xorKeyStream(nonce, out, ciphertext[:len(out)])
+	if subtle.ConstantTimeCompare(tag, c.tag(nonce, out, aad)) != 1 {
+		return nil, errors.New("record failed authentication")
+	}
+	return out, nil
+}
+
+//------------------------------------------------------------------------------
+
+type dtlsRecord struct {
+	typ     uint8
+	epoch   uint16
+	seq     uint64
+	payload []byte
+}
+
+type dtlsHandshake struct {
+	typ  uint8
+	seq  uint16
+	body []byte
+}
+
+func (h dtlsHandshake) marshal() []byte {
+	b := make([]byte, dtlsHandshakeHeaderLen, dtlsHandshakeHeaderLen+len(h.body))
+	b[0] = h.typ
+	b[1], b[2], b[3] = byte(len(h.body)>>16), byte(len(h.body)>>8), byte(len(h.body))
+	binary.BigEndian.PutUint16(b[4:], h.seq)
+	// The fragment offset is zero and the fragment length equals the length.
+	copy(b[9:12], b[1:4])
+	return append(b, h.body...)
+}
+
+func unmarshalDTLSHandshakes(b []byte) ([]dtlsHandshake, error) {
+	var msgs []dtlsHandshake
+	for len(b) > 0 {
+		if len(b) < dtlsHandshakeHeaderLen {
+			return nil, errors.New("handshake message is truncated")
+		}
+		length := int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+		offset := int(b[6])<<16 | int(b[7])<<8 | int(b[8])
+		fragLength := int(b[9])<<16 | int(b[10])<<8 | int(b[11])
+		if offset != 0 || fragLength != length {
+			return nil, errors.New("fragmented handshake messages are not supported")
+		}
+		if len(b) < dtlsHandshakeHeaderLen+length {
+			return nil, errors.New("handshake message is truncated")
+		}
+		msgs = append(msgs, dtlsHandshake{
+			typ:  b[0],
+			seq:  binary.BigEndian.Uint16(b[4:]),
+			body: b[dtlsHandshakeHeaderLen : dtlsHandshakeHeaderLen+length],
+		})
+		b = b[dtlsHandshakeHeaderLen+length:]
+	}
+	return msgs, nil
+}
+
+// dtlsReplayWindow discards records that have been received before, RFC 6347
+// section 4.1.2.6.
+type dtlsReplayWindow struct {
+	latest uint64
+	bitmap uint64
+}
+
+func (w *dtlsReplayWindow) seen(seq uint64) bool {
+	if seq > w.latest {
+		return false
+	}
+	diff := w.latest - seq
+	return diff >= 64 || w.bitmap&(1<<diff) != 0
+}
+
+func (w *dtlsReplayWindow) mark(seq uint64) {
+	if seq > w.latest {
+		shift := seq - w.latest
+		if shift >= 64 {
+			w.bitmap = 0
+		} else {
+			w.bitmap <<= shift
+		}
+		w.latest = seq
+	}
+	w.bitmap |= 1 << (w.latest - seq)
+}
+
+//------------------------------------------------------------------------------
+
+// dtlsConn is an established DTLS session over which datagrams of application
+// data are exchanged, where the state of the session that is modified during
+// the handshake is only accessed by the goroutine of the handshake.
+type dtlsConn struct {
+	transport dtlsTransport
+
+	readEpoch  uint16
+	readCipher *dtlsCCM
+	readIV     []byte
+	replay     dtlsReplayWindow
+	records    []dtlsRecord
+	readBuf    []byte
+
+	writeMut    sync.Mutex
+	writeEpoch  uint16
+	writeSeqs   [2]uint64
+	writeCipher *dtlsCCM
+	writeIV     []byte
+}
+
+func newDTLSConn(transport dtlsTransport) *dtlsConn {
+	return &dtlsConn{
+		transport: transport,
+		readBuf:   make([]byte, dtlsMaxDatagramSize),
+	}
+}
+
+func (c *dtlsConn) setReadKeys(key, iv []byte) (err error) {
+	c.readCipher, err = newDTLSCCM(key)
+	c.readIV = iv
+	return
+}
+
+func (c *dtlsConn) setWriteKeys(key, iv []byte) (err error) {
+	c.writeCipher, err = newDTLSCCM(key)
+	c.writeIV = iv
+	return
+}
+
+func dtlsAdditionalData(typ uint8, epoch uint16, seq uint64, length int) []byte {
+	aad := make([]byte, 13)
+	binary.BigEndian.PutUint64(aad, uint64(epoch)<<48|seq)
+	aad[8] = typ
+	binary.BigEndian.PutUint16(aad[9:], dtlsVersion)
+	binary.BigEndian.PutUint16(aad[11:], uint16(length))
+	return aad
+}
+
+// seal returns the encoding of a record with the next sequence number of its
+// epoch, where records of the first epoch are encrypted.
+func (c *dtlsConn) seal(typ uint8, epoch uint16, payload []byte) []byte {
+	c.writeMut.Lock()
+	seq := c.writeSeqs[epoch]
+	c.writeSeqs[epoch]++
+	c.writeMut.Unlock()
+
+	b := make([]byte, dtlsRecordHeaderLen, dtlsRecordHeaderLen+dtlsExplicitNonceLen+len(payload)+dtlsTagLen)
+	b[0] = typ
+	binary.BigEndian.PutUint16(b[1:], dtlsVersion)
+	binary.BigEndian.PutUint64(b[3:], uint64(epoch)<<48|seq)
+
+	if epoch == 0 {
+		b = append(b, payload...)
+	} else {
+		explicit := b[3:11]
+		nonce := append(append([]byte(nil), c.writeIV...), explicit...)
+		b = append(b, explicit...)
+		b = append(b, c.writeCipher.seal(nonce, payload, dtlsAdditionalData(typ, epoch, seq, len(payload)))...)
+	}
+	binary.BigEndian.PutUint16(b[11:], uint16(len(b)-dtlsRecordHeaderLen))
+	return b
+}
+
+// readRecord returns the next record that is received, where records that are
+// not of the current epoch, that fail authentication or that have been received
+// before are discarded.
+func (c *dtlsConn) readRecord() (dtlsRecord, error) {
+	for {
+		if len(c.records) == 0 {
+			n, err := c.transport.Read(c.readBuf)
+			if err != nil {
+				return dtlsRecord{}, err
+			}
+			c.records = c.parseDatagram(c.readBuf[:n])
+			continue
+		}
+
+		r := c.records[0]
+		c.records = c.records[1:]
+		if r.epoch != c.readEpoch {
+			continue
+		}
+		if r.epoch > 0 {
+			if c.replay.seen(r.seq) || len(r.payload) < dtlsExplicitNonceLen {
+				continue
+			}
+			nonce := append(append([]byte(nil), c.readIV...), r.payload[:dtlsExplicitNonceLen]...)
+			ciphertext := r.payload[dtlsExplicitNonceLen:]
+			plaintext, err := c.readCipher.open(nonce, ciphertext, dtlsAdditionalData(r.typ, r.epoch, r.seq, len(ciphertext)-dtlsTagLen))
+			if err != nil {
+				continue
+			}
+			c.replay.mark(r.seq)
+			r.payload = plaintext
+		}
+		return r, nil
+	}
+}
+
+// parseDatagram returns the records of a datagram, where malformed records and
+// their remainder are discarded.
+func (c *dtlsConn) parseDatagram(b []byte) (records []dtlsRecord) {
+	for len(b) >= dtlsRecordHeaderLen {
+		length := int(binary.BigEndian.Uint16(b[11:]))
+		if len(b) < dtlsRecordHeaderLen+length {
+			break
+		}
+		epochSeq := binary.BigEndian.Uint64(b[3:])
+		records = append(records, dtlsRecord{
+			typ:     b[0],
+			epoch:   uint16(epochSeq >> 48),
+			seq:     epochSeq & (1<<48 - 1),
+			payload: append([]byte(nil), b[dtlsRecordHeaderLen:dtlsRecordHeaderLen+length]...),
+		})
+		b = b[dtlsRecordHeaderLen+length:]
+	}
+	return
+}
+
+// Read reads the next datagram of application data.
+func (c *dtlsConn) Read(b []byte) (int, error) {
+	for {
+		r, err := c.readRecord()
+		if err != nil {
+			return 0, err
+		}
+		switch r.typ {
+		case dtlsContentApplicationData:
+			return copy(b, r.payload), nil
+		case dtlsContentAlert:
+			if err := dtlsAlertError(r.payload); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// Write sends a datagram of application data.
+func (c *dtlsConn) Write(b []byte) (int, error) {
+	if _, err := c.transport.Write(c.seal(dtlsContentApplicationData, c.writeEpoch, b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close notifies the peer that the session is closed and closes the transport.
+func (c *dtlsConn) Close() error {
+	_, _ = c.transport.Write(c.seal(dtlsContentAlert, c.writeEpoch, []byte{1, 0}))
+	return c.transport.Close()
+}
+
+// dtlsAlertError returns the error of an alert that ends a session, which is
+// either a fatal alert or a notification of the peer closing the session.
+func dtlsAlertError(payload []byte) error {
+	if len(payload) < 2 {
+		return errors.New("received malformed alert")
+	}
+	if payload[1] == 0 {
+		return errDTLSClosed
+	}
+	if payload[0] == 2 {
+		return fmt.Errorf("received fatal alert %v", payload[1])
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type dtlsFlightRecord struct {
+	typ     uint8
+	epoch   uint16
+	payload []byte
+}
+
+// dtlsHandshaker holds the state of a handshake, which consists of flights of
+// handshake messages that are sent again when the flight of the peer that
+// follows them is not received in time.
+type dtlsHandshaker struct {
+	c          *dtlsConn
+	flight     []dtlsFlightRecord
+	nextSeq    uint16
+	transcript bytes.Buffer
+
+	// The sequence number of the next handshake message of the peer, which is
+	// only known once a message of the peer has been received.
+	recvSeq uint16
+	synced  bool
+	pending []dtlsHandshake
+}
+
+func (h *dtlsHandshaker) sendFlight(records ...dtlsFlightRecord) error {
+	h.flight = records
+	return h.resendFlight()
+}
+
+// resendFlight sends the current flight, where the records are sealed with new
+// sequence numbers as records that have been received already would otherwise
+// be discarded by the peer.
+func (h *dtlsHandshaker) resendFlight() error {
+	if len(h.flight) == 0 {
+		return nil
+	}
+	var datagram []byte
+	for _, r := range h.flight {
+		datagram = append(datagram, h.c.seal(r.typ, r.epoch, r.payload)...)
+	}
+	_, err := h.c.transport.Write(datagram)
+	return err
+}
+
+// handshakeRecord returns a record that carries a handshake message with the
+// next sequence number, which is added to the transcript.
+func (h *dtlsHandshaker) handshakeRecord(typ uint8, body []byte, epoch uint16) dtlsFlightRecord {
+	msg := dtlsHandshake{typ: typ, seq: h.nextSeq, body: body}.marshal()
+	h.nextSeq++
+	_, _ = h.transcript.Write(msg)
+	return dtlsFlightRecord{typ: dtlsContentHandshake, epoch: epoch, payload: msg}
+}
+
+func (h *dtlsHandshaker) transcriptHash() []byte {
+	sum := sha256.Sum256(h.transcript.Bytes())
+	return sum[:]
+}
+
+// next returns the next handshake message or change cipher spec of the peer,
+// where the current flight is sent again whenever nothing new is received in
+// time or the peer sends its previous flight again.
+func (h *dtlsHandshaker) next(ctx context.Context) (*dtlsHandshake, error) {
+	timeout := dtlsInitialRetransmit
+	for {
+		for len(h.pending) > 0 {
+			msg := h.pending[0]
+			h.pending = h.pending[1:]
+			if !h.synced {
+				h.recvSeq, h.synced = msg.seq, true
+			}
+			if msg.seq > h.recvSeq {
+				continue
+			}
+			if msg.seq < h.recvSeq {
+				h.pending = nil
+				if err := h.resendFlight(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			h.recvSeq++
+			return &msg, nil
+		}
+
+		deadline := time.Now().Add(timeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		_ = h.c.transport.SetReadDeadline(deadline)
+		r, err := h.c.readRecord()
+		if err != nil {
+			var nerr net.Error
+			if !errors.As(err, &nerr) || !nerr.Timeout() {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("handshake timed out: %w", ctx.Err())
+			}
+			if err := h.resendFlight(); err != nil {
+				return nil, err
+			}
+			timeout *= 2
+			continue
+		}
+
+		switch r.typ {
+		case dtlsContentHandshake:
+			if h.pending, err = unmarshalDTLSHandshakes(r.payload); err != nil {
+				return nil, err
+			}
+		case dtlsContentChangeCipherSpec:
+			return nil, nil
+		case dtlsContentAlert:
+			if err := dtlsAlertError(r.payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// expect returns the next handshake message of the peer, which must be of one
+// of the given types, and adds it to the transcript.
+func (h *dtlsHandshaker) expect(ctx context.Context, types ...uint8) (*dtlsHandshake, error) {
+	msg, err := h.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if msg == nil {
+		return nil, errors.New("unexpected change cipher spec")
+	}
+	for _, t := range types {
+		if msg.typ == t {
+			if t != dtlsHandshakeHelloVerifyRequest {
+				_, _ = h.transcript.Write(msg.marshal())
+			}
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected handshake message of type %v", msg.typ)
+}
+
+// expectChangeCipherSpec waits for the peer to change to the keys of the next
+// epoch.
+func (h *dtlsHandshaker) expectChangeCipherSpec(ctx context.Context, key, iv []byte) error {
+	msg, err := h.next(ctx)
+	if err != nil {
+		return err
+	}
+	if msg != nil {
+		return fmt.Errorf("unexpected handshake message of type %v, expected change cipher spec", msg.typ)
+	}
+	h.c.readEpoch = 1
+	return h.c.setReadKeys(key, iv)
+}
+
+func dtlsRandom() ([]byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func dtlsClientHello(random, cookie []byte) []byte {
+	b := dtlsAppendUint16(nil, dtlsVersion)
+	b = append(b, random...)
+	b = append(b, 0) // An empty session id
+	b = append(b, byte(len(cookie)))
+	b = append(b, cookie...)
+	b = dtlsAppendUint16(b, 2)
+	b = dtlsAppendUint16(b, dtlsCipherPSKAES128CCM8)
+	b = append(b, 1, 0) // The null compression method
+	return b
+}
+
+// dialDTLS performs the handshake of a client over a transport, with an
+// identity and a pre-shared key.
+func dialDTLS(ctx context.Context, transport dtlsTransport, identity string, psk []byte) (*dtlsConn, error) {
+	c := newDTLSConn(transport)
+	h := &dtlsHandshaker{c: c}
+	defer func() {
+		_ = transport.SetReadDeadline(time.Time{})
+	}()
+
+	clientRandom, err := dtlsRandom()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.sendFlight(h.handshakeRecord(dtlsHandshakeClientHello, dtlsClientHello(clientRandom, nil), 0)); err != nil {
+		return nil, err
+	}
+
+	msg, err := h.expect(ctx, dtlsHandshakeHelloVerifyRequest, dtlsHandshakeServerHello)
+	if err != nil {
+		return nil, err
+	}
+	if msg.typ == dtlsHandshakeHelloVerifyRequest {
+		// server_version(2) cookie_len(1) cookie
+		if len(msg.body) < 3 || int(msg.body[2]) > len(msg.body)-3 {
+			return nil, errors.New("malformed hello verify request")
+		}
+		cookie := msg.body[3 : 3+int(msg.body[2])]
+
+		// The first client hello and the hello verify request are not part of
+		// the transcript, RFC 6347 section 4.2.6, and the server restarts the
+		// sequence of its handshake messages.
+		h.transcript.Reset()
+		h.synced = false
+		if err := h.sendFlight(h.handshakeRecord(dtlsHandshakeClientHello, dtlsClientHello(clientRandom, cookie), 0)); err != nil {
+			return nil, err
+		}
+		if msg, err = h.expect(ctx, dtlsHandshakeServerHello); err != nil {
+			return nil, err
+		}
+	}
+
+	// server_version(2) random(32) session_id_len(1) session_id suite(2) compression(1)
+	if len(msg.body) < 35 || len(msg.body) < 35+int(msg.body[34])+3 {
+		return nil, errors.New("malformed server hello")
+	}
+	serverRandom := msg.body[2:34]
+	if suite := binary.BigEndian.Uint16(msg.body[35+int(msg.body[34]):]); suite != dtlsCipherPSKAES128CCM8 {
+		return nil, fmt.Errorf("server selected unsupported cipher suite %#04x", suite)
+	}
+
+	// The server may send an identity hint, which is ignored, before the end of
+	// its flight.
+	for {
+		if msg, err = h.expect(ctx, dtlsHandshakeServerKeyExchange, dtlsHandshakeServerHelloDone); err != nil {
+			return nil, err
+		}
+		if msg.typ == dtlsHandshakeServerHelloDone {
+			break
+		}
+	}
+
+	master := dtlsPSKMasterSecret(psk, clientRandom, serverRandom)
+	clientKey, serverKey, clientIV, serverIV := dtlsKeys(master, clientRandom, serverRandom)
+	if err := c.setWriteKeys(clientKey, clientIV); err != nil {
+		return nil, err
+	}
+
+	exchange := dtlsAppendUint16(nil, uint16(len(identity)))
+	exchange = append(exchange, identity...)
+	cke := h.handshakeRecord(dtlsHandshakeClientKeyExchange, exchange, 0)
+	ccs := dtlsFlightRecord{typ: dtlsContentChangeCipherSpec, payload: []byte{1}}
+	finished := h.handshakeRecord(dtlsHandshakeFinished, dtlsPRF(master, "client finished", h.transcriptHash(), 12), 1)
+	if err := h.sendFlight(cke, ccs, finished); err != nil {
+		return nil, err
+	}
+	c.writeEpoch = 1
+
+	if err := h.expectChangeCipherSpec(ctx, serverKey, serverIV); err != nil {
+		return nil, err
+	}
+	expected := dtlsPRF(master, "server finished", h.transcriptHash(), 12)
+	if msg, err = h.expect(ctx, dtlsHandshakeFinished); err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(expected, msg.body) {
+		return nil, errors.New("server finished message failed verification")
+	}
+	return c, nil
+}
@@ -0,0 +1,354 @@
+package coap
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPacketListener demultiplexes the datagrams of a UDP socket by the
+// address of their sender, where each sender is a peer with a transport.
+type testPacketListener struct {
+	conn   net.PacketConn
+	accept chan *testPeer
+
+	mut   sync.Mutex
+	peers map[string]*testPeer
+}
+
+func listenTestPackets(t *testing.T) *testPacketListener {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	l := &testPacketListener{
+		conn:   conn,
+		accept: make(chan *testPeer, 16),
+		peers:  map[string]*testPeer{},
+	}
+	go l.loop()
+	return l
+}
+
+func (l *testPacketListener) loop() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, addr, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		l.mut.Lock()
+		p, exists := l.peers[addr.String()]
+		if !exists {
+			p = &testPeer{l: l, addr: addr, in: make(chan []byte, 64), closed: make(chan struct{})}
+			l.peers[addr.String()] = p
+		}
+		l.mut.Unlock()
+		if !exists {
+			l.accept <- p
+		}
+
+		select {
+		case p.in <- append([]byte(nil), buf[:n]...):
+		default:
+		}
+	}
+}
+
+type testPeer struct {
+	l      *testPacketListener
+	addr   net.Addr
+	in     chan []byte
+	closed chan struct{}
+
+	mut       sync.Mutex
+	deadline  time.Time
+	closeOnce sync.Once
+}
+
+func (p *testPeer) Read(b []byte) (int, error) {
+	p.mut.Lock()
+	deadline := p.deadline
+	p.mut.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case d := <-p.in:
+		return copy(b, d), nil
+	case <-timeout:
+		return 0, os.ErrDeadlineExceeded
+	case <-p.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (p *testPeer) Write(b []byte) (int, error) {
+	return p.l.conn.WriteTo(b, p.addr)
+}
+
+func (p *testPeer) SetReadDeadline(t time.Time) error {
+	p.mut.Lock()
+	p.deadline = t
+	p.mut.Unlock()
+	return nil
+}
+
+func (p *testPeer) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		p.l.mut.Lock()
+		delete(p.l.peers, p.addr.String())
+		p.l.mut.Unlock()
+	})
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var errTestUnknownIdentity = errors.New("unknown psk identity")
+
+// acceptTestDTLS performs the handshake of a server, which requires a cookie
+// from the client before it continues with the handshake.
+func acceptTestDTLS(ctx context.Context, transport dtlsTransport, psks map[string]string) (*dtlsConn, error) {
+	c := newDTLSConn(transport)
+	h := &dtlsHandshaker{c: c}
+
+	msg, err := h.expect(ctx, dtlsHandshakeClientHello)
+	if err != nil {
+		return nil, err
+	}
+	cookie := []byte("cookie")
+	if _, sent := testClientHello(msg.body); len(sent) == 0 {
+		// The hello verify request isn't part of the transcript, and neither
+		// is the first client hello.
+		h.transcript.Reset()
+		verify := dtlsAppendUint16(nil, dtlsVersion)
+		verify = append(verify, byte(len(cookie)))
+		verify = append(verify, cookie...)
+		msg := dtlsHandshake{typ: dtlsHandshakeHelloVerifyRequest, seq: h.nextSeq, body: verify}.marshal()
+		h.nextSeq++
+		if err := h.sendFlight(dtlsFlightRecord{typ: dtlsContentHandshake, payload: msg}); err != nil {
+			return nil, err
+		}
+		if msg, err := h.expect(ctx, dtlsHandshakeClientHello); err != nil {
+			return nil, err
+		} else if _, sent := testClientHello(msg.body); string(sent) != string(cookie) {
+			return nil, errors.New("client hello has the wrong cookie")
+		}
+	}
+	clientRandom, _ := testClientHello(h.lastClientHello())
+
+	serverRandom, err := dtlsRandom()
+	if err != nil {
+		return nil, err
+	}
+	hello := dtlsAppendUint16(nil, dtlsVersion)
+	hello = append(hello, serverRandom...)
+	hello = append(hello, 0)
+	hello = dtlsAppendUint16(hello, dtlsCipherPSKAES128CCM8)
+	hello = append(hello, 0)
+	hint := dtlsAppendUint16(nil, 4)
+	hint = append(hint, "hint"...)
+	if err := h.sendFlight(
+		h.handshakeRecord(dtlsHandshakeServerHello, hello, 0),
+		h.handshakeRecord(dtlsHandshakeServerKeyExchange, hint, 0),
+		h.handshakeRecord(dtlsHandshakeServerHelloDone, nil, 0),
+	); err != nil {
+		return nil, err
+	}
+
+	if msg, err = h.expect(ctx, dtlsHandshakeClientKeyExchange); err != nil {
+		return nil, err
+	}
+	identity := string(msg.body[2:])
+	psk, exists := psks[identity]
+	if !exists {
+		_, _ = transport.Write(c.seal(dtlsContentAlert, 0, []byte{2, 115}))
+		return nil, errTestUnknownIdentity
+	}
+
+	master := dtlsPSKMasterSecret([]byte(psk), clientRandom, serverRandom)
+	clientKey, serverKey, clientIV, serverIV := dtlsKeys(master, clientRandom, serverRandom)
+	if err := h.expectChangeCipherSpec(ctx, clientKey, clientIV); err != nil {
+		return nil, err
+	}
+	expected := dtlsPRF(master, "client finished", h.transcriptHash(), 12)
+	if msg, err = h.expect(ctx, dtlsHandshakeFinished); err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(expected, msg.body) {
+		_, _ = transport.Write(c.seal(dtlsContentAlert, 0, []byte{2, 51}))
+		return nil, errors.New("client finished message failed verification")
+	}
+
+	if err := c.setWriteKeys(serverKey, serverIV); err != nil {
+		return nil, err
+	}
+	finished := h.handshakeRecord(dtlsHandshakeFinished, dtlsPRF(master, "server finished", h.transcriptHash(), 12), 1)
+	if err := h.sendFlight(dtlsFlightRecord{typ: dtlsContentChangeCipherSpec, payload: []byte{1}}, finished); err != nil {
+		return nil, err
+	}
+	c.writeEpoch = 1
+	return c, nil
+}
+
+// lastClientHello returns the body of the client hello at the start of the
+// transcript.
+func (h *dtlsHandshaker) lastClientHello() []byte {
+	msgs, _ := unmarshalDTLSHandshakes(h.transcript.Bytes())
+	return msgs[0].body
+}
+
+// testClientHello returns the random and the cookie of a client hello.
+func testClientHello(body []byte) (random, cookie []byte) {
+	random = body[2:34]
+	rest := body[35+int(body[34]):]
+	return random, rest[1 : 1+int(rest[0])]
+}
+
+//------------------------------------------------------------------------------
+
+func TestDTLSCCMVector(t *testing.T) {
+	// Packet vector #1 of RFC 3610.
+	key, _ := hex.DecodeString("c0c1c2c3c4c5c6c7c8c9cacbcccdcecf")
+	nonce, _ := hex.DecodeString("00000003020100a0a1a2a3a4a5")
+	aad, _ := hex.DecodeString("0001020304050607")
+	plaintext, _ := hex.DecodeString("08090a0b0c0d0e0f101112131415161718191a1b1c1d1e")
+
+	c, err := newDTLSCCM(key)
+	require.NoError(t, err)
+
+	sealed := c.seal(nonce, plaintext, aad)
+	assert.Equal(t, "588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0", hex.EncodeToString(sealed))
+
+	opened, err := c.open(nonce, sealed, aad)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	sealed[3] ^= 1
+	_, err = c.open(nonce, sealed, aad)
+	require.Error(t, err)
+}
+
+func TestDTLSReplayWindow(t *testing.T) {
+	var w dtlsReplayWindow
+	for _, seq := range []uint64{0, 1, 5, 3, 100} {
+		require.False(t, w.seen(seq), seq)
+		w.mark(seq)
+	}
+	for _, seq := range []uint64{0, 1, 5, 3, 100, 36} {
+		assert.True(t, w.seen(seq), seq)
+	}
+	for _, seq := range []uint64{37, 99, 101} {
+		assert.False(t, w.seen(seq), seq)
+	}
+}
+
+func TestDTLSHandshake(t *testing.T) {
+	l := listenTestPackets(t)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	type accepted struct {
+		conn *dtlsConn
+		err  error
+	}
+	acceptCh := make(chan accepted, 1)
+	go func() {
+		p := <-l.accept
+		conn, err := acceptTestDTLS(ctx, p, map[string]string{"device": "secret"})
+		acceptCh <- accepted{conn, err}
+	}()
+
+	raw, err := net.Dial("udp", l.conn.LocalAddr().String())
+	require.NoError(t, err)
+	client, err := dialDTLS(ctx, raw, "device", []byte("secret"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	a := <-acceptCh
+	require.NoError(t, a.err)
+	server := a.conn
+
+	_, err = client.Write([]byte("hello server"))
+	require.NoError(t, err)
+	buf := make([]byte, 1024)
+	n, err := server.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello server", string(buf[:n]))
+
+	_, err = server.Write([]byte("hello client"))
+	require.NoError(t, err)
+	n, err = client.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello client", string(buf[:n]))
+
+	// Records are encrypted with the keys of the session, and their sequence
+	// numbers are those of the first epoch.
+	record := server.seal(dtlsContentApplicationData, 1, []byte("plaintext"))
+	assert.Equal(t, uint16(1), binary.BigEndian.Uint16(record[3:]))
+	assert.NotContains(t, string(record), "plaintext")
+
+	require.NoError(t, server.Close())
+	_, err = client.Read(buf)
+	require.ErrorIs(t, err, errDTLSClosed)
+}
+
+func TestDTLSHandshakeUnknownIdentity(t *testing.T) {
+	l := listenTestPackets(t)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	go func() {
+		p := <-l.accept
+		_, _ = acceptTestDTLS(ctx, p, map[string]string{"device": "secret"})
+	}()
+
+	raw, err := net.Dial("udp", l.conn.LocalAddr().String())
+	require.NoError(t, err)
+	_, err = dialDTLS(ctx, raw, "intruder", []byte("secret"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fatal alert 115")
+}
+
+func TestDTLSHandshakeWrongKey(t *testing.T) {
+	l := listenTestPackets(t)
+
+	// Records that fail authentication are discarded, and the handshake
+	// therefore times out.
+	ctx, done := context.WithTimeout(context.Background(), time.Second*3)
+	defer done()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		p := <-l.accept
+		_, err := acceptTestDTLS(ctx, p, map[string]string{"device": "secret"})
+		serverErr <- err
+	}()
+
+	raw, err := net.Dial("udp", l.conn.LocalAddr().String())
+	require.NoError(t, err)
+	_, err = dialDTLS(ctx, raw, "device", []byte("wrong"))
+	require.Error(t, err)
+	require.Error(t, <-serverErr)
+}
@@ -0,0 +1,339 @@
+package coap
+
+import (
+	"context"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// The freshness of a notification that has no Max-Age option, RFC 7252
+	// section 5.10.5.
+	coapDefaultMaxAge = 60 * time.Second
+
+	// The minimum period of time between registrations of an observation,
+	// which prevents resources that are never fresh from being polled.
+	coapMinReregister = 5 * time.Second
+)
+
+func coapInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Observes a resource of a CoAP server, where each notification of a change of the resource is consumed as a message.").
+		Description(`
+Registers as an observer of a resource with a ` + "`GET`" + ` request as described in [RFC 7641](https://www.rfc-editor.org/rfc/rfc7641), after which the server sends a notification whenever the state of the resource changes. Notifications that arrive out of order are discarded, and the registration is renewed whenever the last notification is no longer fresh according to its ` + "`Max-Age`" + ` option.
+
+When the server responds without an ` + "`Observe`" + ` option, because it does not support observing the resource or has ended the observation, the response is consumed and the resource is registered again after reconnecting.
+
+Notifications are acknowledged to the server once they are received and are therefore not delivered again when a message is rejected. The payload of each notification must fit within a single datagram, as block-wise transfers are not supported.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- coap_code
+- coap_content_format
+- coap_observe
+- coap_etag
+` + "```" + `
+
+Where ` + "`coap_code`" + ` is the code of the response in dotted notation, for example ` + "`2.05`" + `, the content format is the number of the format, and the entity tag is hex encoded. Options that are not present in the notification are not added.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range coapConnectionFields("The URL of the resource to observe.") {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Example(
+			"Sensor Readings",
+			"In this example the temperature readings of a sensor of a constrained device are observed over DTLS.",
+			`
+input:
+  coap:
+    url: coaps://10.0.0.12/sensors/temperature
+    psk:
+      identity: benthos
+      key: "${DEVICE_PSK}"
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"coap", coapInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newCoAPInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// coapObservation is the registration of the input as an observer of a
+// resource, where notifications are delivered in the order of their sequence
+// numbers.
+type coapObservation struct {
+	token         []byte
+	notifications chan *coapMessage
+	minAge        time.Duration
+
+	// Signals that the time at which the observation expires has changed.
+	renewed chan struct{}
+
+	mut      sync.Mutex
+	received bool
+	seq      uint32
+	at       time.Time
+	expires  time.Time
+}
+
+// fresh returns whether a notification with a sequence number is newer than
+// the last notification, RFC 7641 section 3.4.
+func (o *coapObservation) fresh(seq uint32, now time.Time) bool {
+	if !o.received {
+		return true
+	}
+	v1, v2 := o.seq, seq
+	return (v1 < v2 && v2-v1 < 1<<23) ||
+		(v1 > v2 && v1-v2 > 1<<23) ||
+		now.After(o.at.Add(128*time.Second))
+}
+
+func (o *coapObservation) extend(d time.Duration) {
+	if d < o.minAge {
+		d = o.minAge
+	}
+	o.mut.Lock()
+	o.expires = time.Now().Add(d)
+	o.mut.Unlock()
+}
+
+// accept returns whether a notification is delivered, and updates the time at
+// which the observation expires.
+func (o *coapObservation) accept(m *coapMessage) bool {
+	seq, observed := m.getUint(coapOptionObserve)
+	if !observed || !m.isSuccess() {
+		return true
+	}
+
+	now := time.Now()
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	if !o.fresh(seq, now) {
+		return false
+	}
+	o.received, o.seq, o.at = true, seq, now
+
+	maxAge := coapDefaultMaxAge
+	if v, exists := m.getUint(coapOptionMaxAge); exists {
+		maxAge = time.Duration(v) * time.Second
+	}
+	if maxAge < o.minAge {
+		maxAge = o.minAge
+	}
+	o.expires = now.Add(maxAge)
+	select {
+	case o.renewed <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+type coapInput struct {
+	conn    coapConnDetails
+	log     *service.Logger
+	options []coapOption
+
+	// The minimum period of time between registrations of the observation,
+	// which is only modified by tests.
+	minReregister time.Duration
+
+	m   sync.RWMutex
+	cl  *coapClient
+	obs *coapObservation
+}
+
+func newCoAPInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*coapInput, error) {
+	c := &coapInput{log: log, minReregister: coapMinReregister}
+
+	var err error
+	if c.conn, err = coapConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if c.options, err = c.conn.requestOptions(c.conn.url.EscapedPath(), c.conn.url.RawQuery); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *coapInput) observeRequest(obs *coapObservation, register bool) *coapMessage {
+	m := &coapMessage{
+		typ:     coapConfirmable,
+		code:    coapCodeGET,
+		token:   obs.token,
+		options: append([]coapOption(nil), c.options...),
+	}
+	if register {
+		m.addUint(coapOptionObserve, 0)
+	} else {
+		m.typ = coapNonConfirmable
+		m.addUint(coapOptionObserve, 1)
+	}
+	return m
+}
+
+func (c *coapInput) Connect(ctx context.Context) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.cl != nil {
+		return nil
+	}
+
+	token, err := newCoAPToken()
+	if err != nil {
+		return err
+	}
+	obs := &coapObservation{
+		token:         token,
+		notifications: make(chan *coapMessage),
+		minAge:        c.minReregister,
+		renewed:       make(chan struct{}, 1),
+	}
+	obs.extend(coapDefaultMaxAge)
+
+	cl, err := dialCoAP(ctx, c.conn, c.log)
+	if err != nil {
+		return err
+	}
+	cl.handle(token, func(m *coapMessage) {
+		if !obs.accept(m) {
+			return
+		}
+		select {
+		case obs.notifications <- m:
+		case <-cl.closed:
+		}
+	})
+	if err := cl.exchange(ctx, c.observeRequest(obs, true)); err != nil {
+		cl.close()
+		return err
+	}
+	go c.keepObserving(cl, obs)
+
+	c.cl, c.obs = cl, obs
+	c.log.Infof("Observing CoAP resource: %v", c.conn.url.Redacted())
+	return nil
+}
+
+// keepObserving registers the observation again whenever the last
+// notification is no longer fresh, which renews the registration with servers
+// that have forgotten it.
+func (c *coapInput) keepObserving(cl *coapClient, obs *coapObservation) {
+	for {
+		obs.mut.Lock()
+		wait := time.Until(obs.expires)
+		obs.mut.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-obs.renewed:
+				timer.Stop()
+			case <-cl.closed:
+				timer.Stop()
+				return
+			}
+			continue
+		}
+
+		c.log.Debugf("Registering observation of resource again as the last notification is no longer fresh")
+		if err := cl.exchange(context.Background(), c.observeRequest(obs, true)); err != nil {
+			c.log.Errorf("Failed to register observation of resource: %v", err)
+			cl.fail(err)
+			return
+		}
+		obs.extend(coapDefaultMaxAge)
+	}
+}
+
+func (c *coapInput) disconnect(cl *coapClient) {
+	c.m.Lock()
+	if c.cl == cl {
+		c.cl, c.obs = nil, nil
+	}
+	c.m.Unlock()
+	cl.close()
+}
+
+func coapMessageToService(m *coapMessage) *service.Message {
+	msg := service.NewMessage(m.payload)
+	msg.MetaSetMut("coap_code", coapCodeString(m.code))
+	if v, exists := m.getUint(coapOptionContentFormat); exists {
+		msg.MetaSetMut("coap_content_format", strconv.FormatUint(uint64(v), 10))
+	}
+	if v, exists := m.getUint(coapOptionObserve); exists {
+		msg.MetaSetMut("coap_observe", strconv.FormatUint(uint64(v), 10))
+	}
+	if v, exists := m.get(coapOptionETag); exists {
+		msg.MetaSetMut("coap_etag", hex.EncodeToString(v))
+	}
+	return msg
+}
+
+func (c *coapInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.m.RLock()
+	cl, obs := c.cl, c.obs
+	c.m.RUnlock()
+
+	if cl == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var m *coapMessage
+	select {
+	case m = <-obs.notifications:
+	case <-cl.closed:
+		c.log.Errorf("Lost connection due to: %v", cl.failure())
+		c.disconnect(cl)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if !m.isSuccess() {
+		c.log.Errorf("Observation of resource failed with code %v: %s", coapCodeString(m.code), m.payload)
+		c.disconnect(cl)
+		return nil, nil, service.ErrNotConnected
+	}
+	if _, observed := m.get(coapOptionObserve); !observed {
+		c.log.Debugf("Observation of resource ended by the server, and the resource will be registered again")
+		c.disconnect(cl)
+	}
+	return coapMessageToService(m), func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (c *coapInput) Close(ctx context.Context) error {
+	c.m.RLock()
+	cl, obs := c.cl, c.obs
+	c.m.RUnlock()
+
+	if cl != nil {
+		// The observation is cancelled explicitly as otherwise the server keeps
+		// sending notifications until it receives a reset message.
+		_ = cl.exchange(ctx, c.observeRequest(obs, false))
+		c.disconnect(cl)
+	}
+	return nil
+}
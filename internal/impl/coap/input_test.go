@@ -0,0 +1,230 @@
+package coap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCoAPInput(t *testing.T, conf string) *coapInput {
+	t.Helper()
+
+	pConf, err := coapInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	in, err := newCoAPInputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = in.Close(context.Background()) })
+	return in
+}
+
+func readCoAPMessage(t *testing.T, in *coapInput) (string, map[string]any) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	body, err := msg.AsBytes()
+	require.NoError(t, err)
+	meta := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	return string(body), meta
+}
+
+func TestCoAPInputObserve(t *testing.T) {
+	s := startTestServer(t, nil)
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature?unit=c
+ack_timeout: 100ms
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	reqs := s.received()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, coapConfirmable, reqs[0].typ)
+	assert.Equal(t, coapCodeGET, reqs[0].code)
+	assert.Equal(t, []coapOption{
+		{number: coapOptionObserve, value: nil},
+		{number: coapOptionURIPath, value: []byte("sensors")},
+		{number: coapOptionURIPath, value: []byte("temperature")},
+		{number: coapOptionURIQuery, value: []byte("unit=c")},
+	}, reqs[0].options)
+
+	body, meta := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+	assert.Equal(t, map[string]any{"coap_code": "2.05", "coap_observe": "1"}, meta)
+
+	s.notify("/sensors/temperature", "21.5", nil,
+		coapOption{number: coapOptionContentFormat, value: []byte{50}},
+		coapOption{number: coapOptionETag, value: []byte{0xbe, 0xef}},
+	)
+	body, meta = readCoAPMessage(t, in)
+	assert.Equal(t, "21.5", body)
+	assert.Equal(t, map[string]any{
+		"coap_code":           "2.05",
+		"coap_observe":        "2",
+		"coap_content_format": "50",
+		"coap_etag":           "beef",
+	}, meta)
+
+	// A notification that is older than the last one is discarded.
+	stale := uint32(1)
+	s.notify("/sensors/temperature", "stale", &stale)
+	s.set(func(s *testServer) { s.confirmable = true })
+	s.notify("/sensors/temperature", "22.0", nil)
+	body, _ = readCoAPMessage(t, in)
+	assert.Equal(t, "22.0", body)
+
+	// Closing the input cancels the observation.
+	require.NoError(t, in.Close(context.Background()))
+	assert.Eventually(t, func() bool {
+		return s.observerCount("/sensors/temperature") == 0
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestCoAPInputDTLS(t *testing.T) {
+	s := startTestServer(t, map[string]string{"benthos": "secret"})
+	in := testCoAPInput(t, `
+url: coaps://`+s.addr+`/sensors/temperature
+psk:
+  identity: benthos
+  key: secret
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	body, _ := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+
+	s.notify("/sensors/temperature", "21.5", nil)
+	body, _ = readCoAPMessage(t, in)
+	assert.Equal(t, "21.5", body)
+}
+
+func TestCoAPInputDTLSWrongIdentity(t *testing.T) {
+	s := startTestServer(t, map[string]string{"benthos": "secret"})
+	in := testCoAPInput(t, `
+url: coaps://`+s.addr+`/sensors/temperature
+psk:
+  identity: intruder
+  key: secret
+`)
+	err := in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dtls handshake failed")
+}
+
+func TestCoAPInputNotObservable(t *testing.T) {
+	s := startTestServer(t, nil)
+	s.set(func(s *testServer) { s.observeSupported = false })
+
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	body, meta := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+	assert.Equal(t, map[string]any{"coap_code": "2.05"}, meta)
+
+	// The response ends the observation and the resource is registered again
+	// after reconnecting.
+	_, _, err := in.Read(context.Background())
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, in.Connect(context.Background()))
+	body, _ = readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+	assert.Len(t, s.received(), 2)
+}
+
+func TestCoAPInputErrorResponse(t *testing.T) {
+	s := startTestServer(t, nil)
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/forbidden
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	_, _, err := in.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+}
+
+func TestCoAPInputRetransmission(t *testing.T) {
+	s := startTestServer(t, nil)
+	s.set(func(s *testServer) { s.dropRequests = 2 })
+
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature
+ack_timeout: 20ms
+`)
+	require.NoError(t, in.Connect(context.Background()))
+	body, _ := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+
+	s.set(func(s *testServer) { s.dropRequests = 100 })
+	in = testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature
+ack_timeout: 20ms
+max_retransmit: 1
+`)
+	err := in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not acknowledged after 2 attempts")
+}
+
+func TestCoAPInputReregister(t *testing.T) {
+	s := startTestServer(t, nil)
+	maxAge := uint32(0)
+	s.set(func(s *testServer) { s.maxAge = &maxAge })
+
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature
+`)
+	in.minReregister = time.Millisecond * 50
+	require.NoError(t, in.Connect(context.Background()))
+	body, _ := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+
+	// The registration is renewed with the same token once the last
+	// notification is no longer fresh, and the response is consumed.
+	body, meta := readCoAPMessage(t, in)
+	assert.Equal(t, "initial", body)
+	assert.Equal(t, "2", meta["coap_observe"])
+
+	reqs := s.received()
+	require.GreaterOrEqual(t, len(reqs), 2)
+	assert.Equal(t, reqs[0].token, reqs[1].token)
+	assert.Equal(t, 1, s.observerCount("/sensors/temperature"))
+}
+
+func TestCoAPInputUnknownToken(t *testing.T) {
+	s := startTestServer(t, nil)
+	in := testCoAPInput(t, `
+url: coap://`+s.addr+`/sensors/temperature
+`)
+	require.NoError(t, in.Connect(context.Background()))
+	_, _ = readCoAPMessage(t, in)
+
+	// Notifications with a token that the input didn't register with are
+	// rejected with a reset message.
+	s.set(func(s *testServer) {
+		o := s.observers["/sensors/temperature"][0]
+		s.observers["/other"] = []testObserver{{conn: o.conn, token: []byte("bogus")}}
+	})
+	s.notify("/other", "unwanted", nil)
+	assert.Eventually(t, func() bool {
+		return s.resetCount() == 1
+	}, time.Second*5, time.Millisecond*10)
+}
@@ -0,0 +1,262 @@
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// The types of messages, RFC 7252 section 3.
+const (
+	coapConfirmable    uint8 = 0
+	coapNonConfirmable uint8 = 1
+	coapAcknowledgment uint8 = 2
+	coapReset          uint8 = 3
+)
+
+// The codes of requests and responses, which consist of a class in the upper
+// three bits and a detail in the lower five bits.
+const (
+	coapCodeEmpty uint8 = 0x00
+	coapCodeGET   uint8 = 0x01
+	coapCodePOST  uint8 = 0x02
+	coapCodePUT   uint8 = 0x03
+)
+
+// The numbers of the options that are used, RFC 7252 section 5.10 and RFC 7641.
+const (
+	coapOptionURIHost       uint16 = 3
+	coapOptionETag          uint16 = 4
+	coapOptionObserve       uint16 = 6
+	coapOptionURIPath       uint16 = 11
+	coapOptionContentFormat uint16 = 12
+	coapOptionMaxAge        uint16 = 14
+	coapOptionURIQuery      uint16 = 15
+)
+
+type coapOption struct {
+	number uint16
+	value  []byte
+}
+
+// coapMessage is a message of the CoAP protocol, where the options are kept in
+// the order in which they are encoded.
+type coapMessage struct {
+	typ       uint8
+	code      uint8
+	messageID uint16
+	token     []byte
+	options   []coapOption
+	payload   []byte
+}
+
+func (m *coapMessage) add(number uint16, value []byte) {
+	m.options = append(m.options, coapOption{number: number, value: value})
+}
+
+func (m *coapMessage) addUint(number uint16, v uint32) {
+	m.add(number, coapEncodeUint(v))
+}
+
+func (m *coapMessage) get(number uint16) ([]byte, bool) {
+	for _, o := range m.options {
+		if o.number == number {
+			return o.value, true
+		}
+	}
+	return nil, false
+}
+
+func (m *coapMessage) getUint(number uint16) (uint32, bool) {
+	v, exists := m.get(number)
+	if !exists {
+		return 0, false
+	}
+	return coapDecodeUint(v), true
+}
+
+func (m *coapMessage) isRequest() bool {
+	return m.code >= 0x01 && m.code < 0x20
+}
+
+func (m *coapMessage) isResponse() bool {
+	return m.code >= 0x40
+}
+
+func (m *coapMessage) isSuccess() bool {
+	return m.code>>5 == 2
+}
+
+// coapCodeString returns a code in the dotted notation of RFC 7252, for
+// example 2.05 for the code of a Content response.
+func coapCodeString(code uint8) string {
+	return fmt.Sprintf("%d.%02d", code>>5, code&0x1f)
+}
+
+// coapEncodeUint returns the shortest encoding of an unsigned integer option,
+// where zero is encoded as an empty value.
+func coapEncodeUint(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	for len(b) > 0 && b[0] == 0 {
+		b = b[1:]
+	}
+	return b
+}
+
+func coapDecodeUint(b []byte) (v uint32) {
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return
+}
+
+func coapOptionNibble(v int) (nibble byte, ext []byte) {
+	switch {
+	case v < 13:
+		return byte(v), nil
+	case v < 269:
+		return 13, []byte{byte(v - 13)}
+	default:
+		return 14, []byte{byte((v - 269) >> 8), byte(v - 269)}
+	}
+}
+
+func (m *coapMessage) marshal() ([]byte, error) {
+	if len(m.token) > 8 {
+		return nil, errors.New("token exceeds eight bytes")
+	}
+
+	b := make([]byte, 4, 64)
+	b[0] = 1<<6 | m.typ<<4 | byte(len(m.token))
+	b[1] = m.code
+	binary.BigEndian.PutUint16(b[2:], m.messageID)
+	b = append(b, m.token...)
+
+	// Options are encoded by the delta of their numbers and must therefore be
+	// sorted, where the order of repeated options is preserved.
+	options := append([]coapOption(nil), m.options...)
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].number < options[j].number
+	})
+
+	var last uint16
+	for _, o := range options {
+		if len(o.value) > 65535+269 {
+			return nil, fmt.Errorf("value of option %v is too long", o.number)
+		}
+		delta, deltaExt := coapOptionNibble(int(o.number - last))
+		length, lengthExt := coapOptionNibble(len(o.value))
+		b = append(b, delta<<4|length)
+		b = append(b, deltaExt...)
+		b = append(b, lengthExt...)
+		b = append(b, o.value...)
+		last = o.number
+	}
+	if len(m.payload) > 0 {
+		b = append(b, 0xff)
+		b = append(b, m.payload...)
+	}
+	return b, nil
+}
+
+func coapReadNibble(nibble byte, b []byte) (int, []byte, error) {
+	switch nibble {
+	case 13:
+		if len(b) < 1 {
+			return 0, nil, errors.New("option is truncated")
+		}
+		return int(b[0]) + 13, b[1:], nil
+	case 14:
+		if len(b) < 2 {
+			return 0, nil, errors.New("option is truncated")
+		}
+		return int(binary.BigEndian.Uint16(b)) + 269, b[2:], nil
+	case 15:
+		return 0, nil, errors.New("option uses a reserved length")
+	}
+	return int(nibble), b, nil
+}
+
+func unmarshalCoAPMessage(b []byte) (*coapMessage, error) {
+	if len(b) < 4 {
+		return nil, errors.New("message is shorter than its header")
+	}
+	if b[0]>>6 != 1 {
+		return nil, fmt.Errorf("unsupported version %v", b[0]>>6)
+	}
+
+	m := &coapMessage{
+		typ:       (b[0] >> 4) & 0x3,
+		code:      b[1],
+		messageID: binary.BigEndian.Uint16(b[2:]),
+	}
+	tokenLen := int(b[0] & 0xf)
+	if tokenLen > 8 {
+		return nil, errors.New("token exceeds eight bytes")
+	}
+	b = b[4:]
+	if len(b) < tokenLen {
+		return nil, errors.New("token is truncated")
+	}
+	m.token = append([]byte(nil), b[:tokenLen]...)
+	b = b[tokenLen:]
+
+	var number int
+	for len(b) > 0 {
+		if b[0] == 0xff {
+			if len(b) == 1 {
+				return nil, errors.New("payload marker is followed by an empty payload")
+			}
+			m.payload = append([]byte(nil), b[1:]...)
+			break
+		}
+
+		delta, length := b[0]>>4, b[0]&0xf
+		var d, l int
+		var err error
+		if d, b, err = coapReadNibble(delta, b[1:]); err != nil {
+			return nil, err
+		}
+		if l, b, err = coapReadNibble(length, b); err != nil {
+			return nil, err
+		}
+		if number += d; number > 65535 {
+			return nil, errors.New("option number exceeds the maximum")
+		}
+		if len(b) < l {
+			return nil, errors.New("option is truncated")
+		}
+		m.add(uint16(number), append([]byte(nil), b[:l]...))
+		b = b[l:]
+	}
+	return m, nil
+}
+
+// coapPathOptions returns the Uri-Path and Uri-Query options of the escaped
+// path and query of a URL, where each segment of the path and each argument of
+// the query is an option.
+func coapPathOptions(path, query string) (options []coapOption, err error) {
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		if segment, err = url.PathUnescape(segment); err != nil {
+			return nil, err
+		}
+		options = append(options, coapOption{number: coapOptionURIPath, value: []byte(segment)})
+	}
+	for _, q := range strings.Split(query, "&") {
+		if q == "" {
+			continue
+		}
+		if q, err = url.QueryUnescape(q); err != nil {
+			return nil, err
+		}
+		options = append(options, coapOption{number: coapOptionURIQuery, value: []byte(q)})
+	}
+	return
+}
@@ -0,0 +1,96 @@
+package coap
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoAPMessageRoundTrip(t *testing.T) {
+	m := &coapMessage{
+		typ:       coapConfirmable,
+		code:      coapCodeGET,
+		messageID: 0x7d34,
+		token:     []byte{0xde, 0xad},
+	}
+	m.add(coapOptionURIPath, []byte("temperature"))
+	m.addUint(coapOptionObserve, 0)
+	m.add(coapOptionURIHost, []byte("sensor"))
+
+	b, err := m.marshal()
+	require.NoError(t, err)
+
+	// The options are sorted by their number, and a zero integer is encoded
+	// as an empty value.
+	assert.Equal(t, "42017d34dead"+"3673656e736f72"+"30"+"5b74656d7065726174757265", hex.EncodeToString(b))
+
+	res, err := unmarshalCoAPMessage(b)
+	require.NoError(t, err)
+	assert.Equal(t, coapConfirmable, res.typ)
+	assert.Equal(t, coapCodeGET, res.code)
+	assert.Equal(t, uint16(0x7d34), res.messageID)
+	assert.Equal(t, []byte{0xde, 0xad}, res.token)
+	observe, exists := res.getUint(coapOptionObserve)
+	assert.True(t, exists)
+	assert.Equal(t, uint32(0), observe)
+	host, _ := res.get(coapOptionURIHost)
+	assert.Equal(t, "sensor", string(host))
+	assert.Empty(t, res.payload)
+}
+
+func TestCoAPMessageExtendedOptions(t *testing.T) {
+	m := &coapMessage{typ: coapNonConfirmable, code: 0x45, payload: []byte("hello")}
+	m.add(coapOptionURIPath, []byte(strings.Repeat("a", 20)))
+	m.add(coapOptionURIPath, []byte(strings.Repeat("b", 300)))
+	m.addUint(coapOptionContentFormat, 50)
+	m.add(2049, []byte("x"))
+
+	b, err := m.marshal()
+	require.NoError(t, err)
+
+	res, err := unmarshalCoAPMessage(b)
+	require.NoError(t, err)
+	assert.Equal(t, []coapOption{
+		{number: coapOptionURIPath, value: []byte(strings.Repeat("a", 20))},
+		{number: coapOptionURIPath, value: []byte(strings.Repeat("b", 300))},
+		{number: coapOptionContentFormat, value: []byte{50}},
+		{number: 2049, value: []byte("x")},
+	}, res.options)
+	assert.Equal(t, "hello", string(res.payload))
+	assert.Equal(t, "2.05", coapCodeString(res.code))
+}
+
+func TestCoAPMessageErrors(t *testing.T) {
+	for _, input := range []string{
+		"4001",
+		"8001aaaa",
+		"49010000",
+		"420100aa",
+		"40010000ff",
+		"40010000d1",
+		"40010000f0",
+		"4001000012aa",
+	} {
+		b, err := hex.DecodeString(input)
+		require.NoError(t, err)
+		_, err = unmarshalCoAPMessage(b)
+		require.Error(t, err, input)
+	}
+}
+
+func TestCoAPPathOptions(t *testing.T) {
+	options, err := coapPathOptions("/sensors/room%2F1/", "unit=c&a%20b")
+	require.NoError(t, err)
+	assert.Equal(t, []coapOption{
+		{number: coapOptionURIPath, value: []byte("sensors")},
+		{number: coapOptionURIPath, value: []byte("room/1")},
+		{number: coapOptionURIQuery, value: []byte("unit=c")},
+		{number: coapOptionURIQuery, value: []byte("a b")},
+	}, options)
+
+	_, err = coapPathOptions("/bad%zz", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,235 @@
+package coap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	coapoFieldPath          = "path"
+	coapoFieldMethod        = "method"
+	coapoFieldContentFormat = "content_format"
+	coapoFieldConfirmable   = "confirmable"
+	coapoFieldMaxInFlight   = "max_in_flight"
+)
+
+func coapOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sends messages to a resource of a CoAP server with `PUT` or `POST` requests.").
+		Description(`
+Each message is sent as the payload of a request, which is a confirmable message by default that is sent again until the server acknowledges it, and the message is only acknowledged once the server has responded with a success code. With ` + "`confirmable`" + ` set to ` + "`false`" + ` requests are sent as non-confirmable messages without waiting for a response, which is suitable for data that is expendable.
+
+The payload of each message must fit within a single datagram, as block-wise transfers are not supported.`)
+
+	for _, f := range coapConnectionFields("The URL of the resource to send messages to.") {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewInterpolatedStringField(coapoFieldPath).
+			Description("An optional path that overrides the path of the url for each message, which may include a query and is percent-encoded like the path and query of a url.").
+			Example(`/actuators/${! meta("actuator") }`).
+			Example(`/readings?device=${! this.device_id }`).
+			Optional()).
+		Field(service.NewStringEnumField(coapoFieldMethod, "POST", "PUT").
+			Description("The method of the requests.").
+			Default("POST")).
+		Field(service.NewIntField(coapoFieldContentFormat).
+			Description("The number of the content format of the payload, for example `0` for `text/plain`, `50` for `application/json` and `60` for `application/cbor`, which is sent as the `Content-Format` option when set.").
+			Example(50).
+			Optional()).
+		Field(service.NewBoolField(coapoFieldConfirmable).
+			Description("Whether requests are sent as confirmable messages and their responses are waited for.").
+			Default(true)).
+		Field(service.NewIntField(coapoFieldMaxInFlight).
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Actuator Commands",
+			"In this example commands are sent to the actuators of a constrained device, where the path of each command is taken from its metadata.",
+			`
+output:
+  coap:
+    url: coap://10.0.0.12
+    path: /actuators/${! meta("actuator") }
+    method: PUT
+    content_format: 50
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"coap", coapOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(coapoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newCoAPOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type coapOutput struct {
+	conn    coapConnDetails
+	log     *service.Logger
+	options []coapOption
+
+	path          *service.InterpolatedString
+	code          uint8
+	contentFormat *uint32
+	confirmable   bool
+
+	m  sync.RWMutex
+	cl *coapClient
+}
+
+func newCoAPOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*coapOutput, error) {
+	c := &coapOutput{log: log}
+
+	var err error
+	if c.conn, err = coapConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if c.options, err = c.conn.requestOptions(c.conn.url.EscapedPath(), c.conn.url.RawQuery); err != nil {
+		return nil, err
+	}
+	if conf.Contains(coapoFieldPath) {
+		if c.path, err = conf.FieldInterpolatedString(coapoFieldPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var method string
+	if method, err = conf.FieldString(coapoFieldMethod); err != nil {
+		return nil, err
+	}
+	c.code = coapCodePOST
+	if method == "PUT" {
+		c.code = coapCodePUT
+	}
+
+	if conf.Contains(coapoFieldContentFormat) {
+		var format int
+		if format, err = conf.FieldInt(coapoFieldContentFormat); err != nil {
+			return nil, err
+		}
+		if format < 0 || format > 65535 {
+			return nil, fmt.Errorf("content format %v is outside of the range 0 to 65535", format)
+		}
+		f := uint32(format)
+		c.contentFormat = &f
+	}
+	if c.confirmable, err = conf.FieldBool(coapoFieldConfirmable); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *coapOutput) Connect(ctx context.Context) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.cl != nil {
+		return nil
+	}
+
+	cl, err := dialCoAP(ctx, c.conn, c.log)
+	if err != nil {
+		return err
+	}
+	c.cl = cl
+	c.log.Infof("Sending CoAP requests to: %v", c.conn.url.Redacted())
+	return nil
+}
+
+func (c *coapOutput) request(msg *service.Message) (*coapMessage, error) {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &coapMessage{
+		typ:     coapConfirmable,
+		code:    c.code,
+		options: c.options,
+		payload: payload,
+	}
+	if !c.confirmable {
+		m.typ = coapNonConfirmable
+	}
+	if c.path != nil {
+		pathStr := c.path.String(msg)
+		path, query, _ := strings.Cut(pathStr, "?")
+		if m.options, err = c.conn.requestOptions(path, query); err != nil {
+			return nil, fmt.Errorf("invalid path %q: %w", pathStr, err)
+		}
+	}
+	m.options = append([]coapOption(nil), m.options...)
+	if c.contentFormat != nil {
+		m.addUint(coapOptionContentFormat, *c.contentFormat)
+	}
+	return m, nil
+}
+
+func (c *coapOutput) Write(ctx context.Context, msg *service.Message) error {
+	c.m.RLock()
+	cl := c.cl
+	c.m.RUnlock()
+
+	if cl == nil {
+		return service.ErrNotConnected
+	}
+
+	req, err := c.request(msg)
+	if err != nil {
+		return err
+	}
+
+	if !c.confirmable {
+		if req.token, err = newCoAPToken(); err != nil {
+			return err
+		}
+		err = cl.exchange(ctx, req)
+	} else {
+		var res *coapMessage
+		if res, err = cl.do(ctx, req); err == nil && !res.isSuccess() {
+			return fmt.Errorf("server responded with code %v: %s", coapCodeString(res.code), res.payload)
+		}
+	}
+	if err != nil && cl.failure() != nil {
+		c.log.Errorf("Lost connection due to: %v", cl.failure())
+		c.disconnect(cl)
+		return service.ErrNotConnected
+	}
+	return err
+}
+
+func (c *coapOutput) disconnect(cl *coapClient) {
+	c.m.Lock()
+	if c.cl == cl {
+		c.cl = nil
+	}
+	c.m.Unlock()
+	cl.close()
+}
+
+func (c *coapOutput) Close(ctx context.Context) error {
+	c.m.RLock()
+	cl := c.cl
+	c.m.RUnlock()
+
+	if cl != nil {
+		c.disconnect(cl)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package coap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCoAPOutput(t *testing.T, conf string) *coapOutput {
+	t.Helper()
+
+	pConf, err := coapOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newCoAPOutputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() { _ = out.Close(context.Background()) })
+	return out
+}
+
+func TestCoAPOutput(t *testing.T) {
+	s := startTestServer(t, nil)
+	out := testCoAPOutput(t, `
+url: coap://`+s.addr+`/readings
+method: PUT
+path: /actuators/${! meta("actuator") }?mode=${! this.mode }
+content_format: 50
+`)
+
+	msg := service.NewMessage([]byte(`{"mode":"open"}`))
+	msg.MetaSetMut("actuator", "valve 1")
+	require.NoError(t, out.Write(context.Background(), msg))
+
+	reqs := s.received()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, coapConfirmable, reqs[0].typ)
+	assert.Equal(t, coapCodePUT, reqs[0].code)
+	assert.Len(t, reqs[0].token, 8)
+	assert.Equal(t, []coapOption{
+		{number: coapOptionURIPath, value: []byte("actuators")},
+		{number: coapOptionURIPath, value: []byte("valve 1")},
+		{number: coapOptionContentFormat, value: []byte{50}},
+		{number: coapOptionURIQuery, value: []byte("mode=open")},
+	}, reqs[0].options)
+	assert.Equal(t, `{"mode":"open"}`, string(reqs[0].payload))
+}
+
+func TestCoAPOutputResponses(t *testing.T) {
+	s := startTestServer(t, nil)
+	out := testCoAPOutput(t, `
+url: coap://`+s.addr+`/readings
+ack_timeout: 20ms
+`)
+
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte("a"))))
+
+	// Requests are sent again until they are acknowledged, and responses that
+	// are sent separately from the acknowledgement are waited for.
+	s.set(func(s *testServer) {
+		s.dropRequests = 2
+		s.separate = true
+	})
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte("b"))))
+
+	reqs := s.received()
+	require.Len(t, reqs, 2)
+	assert.Equal(t, coapCodePOST, reqs[1].code)
+	assert.Equal(t, "b", string(reqs[1].payload))
+
+	out.options = []coapOption{{number: coapOptionURIPath, value: []byte("forbidden")}}
+	err := out.Write(context.Background(), service.NewMessage([]byte("c")))
+	require.Error(t, err)
+	assert.Equal(t, "server responded with code 4.03: not allowed", err.Error())
+}
+
+func TestCoAPOutputNonConfirmable(t *testing.T) {
+	s := startTestServer(t, map[string]string{"benthos": "secret"})
+	out := testCoAPOutput(t, `
+url: coaps://`+s.addr+`/readings
+psk:
+  identity: benthos
+  key: secret
+confirmable: false
+`)
+
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte("a"))))
+	assert.Eventually(t, func() bool {
+		reqs := s.received()
+		return len(reqs) == 1 && reqs[0].typ == coapNonConfirmable && string(reqs[0].payload) == "a"
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, out.Close(context.Background()))
+	require.ErrorIs(t, out.Write(context.Background(), service.NewMessage([]byte("b"))), service.ErrNotConnected)
+}
@@ -0,0 +1,244 @@
+package coap
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testObserver struct {
+	conn  io.ReadWriter
+	token []byte
+}
+
+// testServer is a fake of a CoAP server with observable resources, which
+// optionally requires DTLS with pre-shared keys.
+type testServer struct {
+	t    *testing.T
+	l    *testPacketListener
+	addr string
+	psks map[string]string
+
+	mut       sync.Mutex
+	requests  []*coapMessage
+	observers map[string][]testObserver
+	seq       uint32
+	nextMID   uint16
+	resets    int
+
+	// Behaviours that are set by tests.
+	dropRequests     int
+	separate         bool
+	confirmable      bool
+	observeSupported bool
+	maxAge           *uint32
+}
+
+func startTestServer(t *testing.T, psks map[string]string) *testServer {
+	t.Helper()
+
+	l := listenTestPackets(t)
+	s := &testServer{
+		t:                t,
+		l:                l,
+		addr:             l.conn.LocalAddr().String(),
+		psks:             psks,
+		observers:        map[string][]testObserver{},
+		seq:              1,
+		observeSupported: true,
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	go func() {
+		for {
+			select {
+			case p := <-l.accept:
+				go s.serve(p)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *testServer) serve(p *testPeer) {
+	var conn io.ReadWriter = p
+	if s.psks != nil {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+		dc, err := acceptTestDTLS(ctx, p, s.psks)
+		done()
+		if err != nil {
+			_ = p.Close()
+			return
+		}
+		conn = dc
+	}
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		m, err := unmarshalCoAPMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		s.handle(conn, m)
+	}
+}
+
+func testPath(m *coapMessage) string {
+	var segments []string
+	for _, o := range m.options {
+		if o.number == coapOptionURIPath {
+			segments = append(segments, string(o.value))
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (s *testServer) send(conn io.ReadWriter, m *coapMessage) {
+	b, _ := m.marshal()
+	_, _ = conn.Write(b)
+}
+
+func (s *testServer) handle(conn io.ReadWriter, m *coapMessage) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if m.typ == coapReset {
+		s.resets++
+		for path, observers := range s.observers {
+			var remaining []testObserver
+			for _, o := range observers {
+				if o.conn != conn {
+					remaining = append(remaining, o)
+				}
+			}
+			s.observers[path] = remaining
+		}
+		return
+	}
+	if !m.isRequest() {
+		return
+	}
+	if m.typ == coapConfirmable && s.dropRequests > 0 {
+		s.dropRequests--
+		return
+	}
+	s.requests = append(s.requests, m)
+
+	res := &coapMessage{typ: coapAcknowledgment, messageID: m.messageID, token: m.token}
+	if m.typ == coapNonConfirmable {
+		res.typ = coapNonConfirmable
+		res.messageID = s.nextMID
+		s.nextMID++
+	}
+
+	path := testPath(m)
+	switch {
+	case path == "/forbidden":
+		res.code = 0x83 // 4.03 Forbidden
+		res.payload = []byte("not allowed")
+	case m.code == coapCodeGET:
+		res.code = 0x45 // 2.05 Content
+		res.payload = []byte("initial")
+		observe, observed := m.getUint(coapOptionObserve)
+		if observed && observe == 0 && s.observeSupported {
+			// A registration with the token of an existing observer renews it.
+			registered := false
+			for _, o := range s.observers[path] {
+				registered = registered || (o.conn == conn && string(o.token) == string(m.token))
+			}
+			if !registered {
+				s.observers[path] = append(s.observers[path], testObserver{conn: conn, token: m.token})
+			}
+			res.addUint(coapOptionObserve, s.seq)
+			s.seq++
+		} else if observed && observe == 1 {
+			for i, o := range s.observers[path] {
+				if string(o.token) == string(m.token) {
+					s.observers[path] = append(s.observers[path][:i:i], s.observers[path][i+1:]...)
+					break
+				}
+			}
+		}
+		if s.maxAge != nil {
+			res.addUint(coapOptionMaxAge, *s.maxAge)
+		}
+	case m.code == coapCodePOST:
+		res.code = 0x41 // 2.01 Created
+	default:
+		res.code = 0x44 // 2.04 Changed
+	}
+	if m.typ == coapNonConfirmable && m.code != coapCodeGET {
+		return
+	}
+
+	if s.separate && m.typ == coapConfirmable {
+		s.send(conn, &coapMessage{typ: coapAcknowledgment, messageID: m.messageID})
+		res.typ = coapConfirmable
+		res.messageID = s.nextMID
+		s.nextMID++
+	}
+	s.send(conn, res)
+}
+
+// notify sends a notification to the observers of a resource, where the
+// sequence number is optionally given by the test.
+func (s *testServer) notify(path, payload string, seq *uint32, options ...coapOption) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	n := s.seq
+	if seq != nil {
+		n = *seq
+	} else {
+		s.seq++
+	}
+	for _, o := range s.observers[path] {
+		m := &coapMessage{
+			typ:       coapNonConfirmable,
+			code:      0x45,
+			messageID: s.nextMID,
+			token:     o.token,
+			payload:   []byte(payload),
+		}
+		if s.confirmable {
+			m.typ = coapConfirmable
+		}
+		s.nextMID++
+		m.addUint(coapOptionObserve, n)
+		m.options = append(m.options, options...)
+		s.send(o.conn, m)
+	}
+}
+
+func (s *testServer) observerCount(path string) int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return len(s.observers[path])
+}
+
+func (s *testServer) received() []*coapMessage {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]*coapMessage(nil), s.requests...)
+}
+
+func (s *testServer) resetCount() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.resets
+}
+
+func (s *testServer) set(fn func(s *testServer)) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	fn(s)
+}
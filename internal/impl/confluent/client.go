@@ -0,0 +1,369 @@
+package confluent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/httpclient"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func sharedClientField() *service.ConfigField {
+	return service.NewStringField("shared_client").
+		Description("An optional name of a schema registry client to share with all other schema registry components of the process that specify the same name, allowing them to reuse connections to the registry and its cache of schemas. Components sharing a client must use the same `url`, `cache_ttl`, authentication and TLS settings, otherwise they fail to start.").
+		Example("primary_registry").
+		Optional().
+		Advanced().
+		Version("4.9.0")
+}
+
+func cacheTTLField() *service.ConfigField {
+	return service.NewDurationField("cache_ttl").
+		Description("The period for which schemas obtained from the registry are cached by the client, where each schema is obtained at most once per period by all components sharing the client. The latest schema of each subject is also cached, and therefore encoders with a `refresh_period` shorter than this period only observe new schema versions once the cached schema expires. Set to `0s` in order to disable the cache.").
+		Default("0s").
+		Example("5m").
+		Advanced().
+		Version("4.9.0")
+}
+
+// clientSettingsFields are the fields that determine the behaviour of a client,
+// and therefore must match between components that share one.
+var clientSettingsFields = []string{"url", "cache_ttl", "basic_auth", "oauth", "jwt", "tls"}
+
+// clientSettingsFingerprint returns a serialised form of the settings of a
+// client that can be compared between components.
+func clientSettingsFingerprint(conf *service.ParsedConfig) (string, error) {
+	settings := map[string]any{}
+	for _, f := range clientSettingsFields {
+		if !conf.Contains(f) {
+			continue
+		}
+		v, err := conf.FieldAny(f)
+		if err != nil {
+			return "", err
+		}
+		settings[f] = v
+	}
+	b, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type schemaRegistryClient struct {
+	client                *http.Client
+	schemaRegistryBaseURL *url.URL
+	requestSigner         httpclient.RequestSigner
+	logger                *service.Logger
+
+	cacheTTL    time.Duration
+	cacheMut    sync.Mutex
+	schemaCache map[string]cachedResponse
+	nowFn       func() time.Time
+
+	sharedName  string
+	fingerprint string
+	refs        int
+}
+
+type cachedResponse struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+var (
+	sharedClientsMut sync.Mutex
+	sharedClients    = map[string]*schemaRegistryClient{}
+)
+
+func newSchemaRegistryClient(
+	urlStr string,
+	reqSigner httpclient.RequestSigner,
+	tlsConf *tls.Config,
+	logger *service.Logger,
+) (*schemaRegistryClient, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	c := &schemaRegistryClient{
+		schemaRegistryBaseURL: u,
+		requestSigner:         reqSigner,
+		logger:                logger,
+		schemaCache:           map[string]cachedResponse{},
+		nowFn:                 time.Now,
+	}
+
+	c.client = http.DefaultClient
+	if tlsConf != nil {
+		c.client = &http.Client{}
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			c.client.Transport = cloned
+		} else {
+			c.client.Transport = &http.Transport{
+				TLSClientConfig: tlsConf,
+			}
+		}
+	}
+	return c, nil
+}
+
+// newSharedSchemaRegistryClient returns a client that is shared with all other
+// components that reference the same name, creating it if it does not yet
+// exist. Shared clients must be released once they are no longer used.
+func newSharedSchemaRegistryClient(name, fingerprint string, newFn func() (*schemaRegistryClient, error)) (*schemaRegistryClient, error) {
+	sharedClientsMut.Lock()
+	defer sharedClientsMut.Unlock()
+
+	if c, exists := sharedClients[name]; exists {
+		if c.fingerprint != fingerprint {
+			return nil, fmt.Errorf("shared client %v already exists with different settings", name)
+		}
+		c.refs++
+		return c, nil
+	}
+
+	c, err := newFn()
+	if err != nil {
+		return nil, err
+	}
+	c.sharedName = name
+	c.fingerprint = fingerprint
+	c.refs = 1
+	sharedClients[name] = c
+	return c, nil
+}
+
+// release removes a reference to a shared client, and has no effect on clients
+// that are not shared.
+func (c *schemaRegistryClient) release() {
+	if c.sharedName == "" {
+		return
+	}
+
+	sharedClientsMut.Lock()
+	defer sharedClientsMut.Unlock()
+
+	if c.refs--; c.refs <= 0 && sharedClients[c.sharedName] == c {
+		delete(sharedClients, c.sharedName)
+	}
+}
+
+func schemaRegistryClientFromParsed(conf *service.ParsedConfig, logger *service.Logger) (*schemaRegistryClient, error) {
+	urlStr, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS("tls")
+	if err != nil {
+		return nil, err
+	}
+	authSigner, err := httpclient.AuthSignerFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	cacheTTL, err := conf.FieldDuration("cache_ttl")
+	if err != nil {
+		return nil, err
+	}
+
+	newFn := func() (*schemaRegistryClient, error) {
+		c, err := newSchemaRegistryClient(urlStr, authSigner, tlsConf, logger)
+		if err != nil {
+			return nil, err
+		}
+		c.cacheTTL = cacheTTL
+		return c, nil
+	}
+	if !conf.Contains("shared_client") {
+		return newFn()
+	}
+
+	name, err := conf.FieldString("shared_client")
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := clientSettingsFingerprint(conf)
+	if err != nil {
+		return nil, err
+	}
+	return newSharedSchemaRegistryClient(name, fingerprint, newFn)
+}
+
+// getCached performs a GET request against the registry, reusing the response
+// of a previous request to the same path until the cache TTL has passed.
+func (c *schemaRegistryClient) getCached(reqPath, desc string) ([]byte, error) {
+	if c.cacheTTL <= 0 {
+		return c.do("GET", reqPath, nil, desc)
+	}
+
+	c.cacheMut.Lock()
+	cached, exists := c.schemaCache[reqPath]
+	c.cacheMut.Unlock()
+
+	now := c.nowFn()
+	if exists && now.Before(cached.expiresAt) {
+		return cached.body, nil
+	}
+
+	resBytes, err := c.do("GET", reqPath, nil, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	c.cacheMut.Lock()
+	for k, v := range c.schemaCache {
+		if !now.Before(v.expiresAt) {
+			delete(c.schemaCache, k)
+		}
+	}
+	c.schemaCache[reqPath] = cachedResponse{body: resBytes, expiresAt: now.Add(c.cacheTTL)}
+	c.cacheMut.Unlock()
+	return resBytes, nil
+}
+
+// do performs a request against the registry with a limited number of
+// attempts, where desc describes the target of the request for errors and logs.
+func (c *schemaRegistryClient) do(method, reqPath string, body []byte, desc string) ([]byte, error) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	reqURL := *c.schemaRegistryBaseURL
+	reqURL.Path = path.Join(reqURL.Path, reqPath)
+
+	var resBytes []byte
+	var err error
+	for i := 0; i < 3; i++ {
+		var bodyReader io.Reader = http.NoBody
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, method, reqURL.String(), bodyReader); err != nil {
+			return nil, err
+		}
+		req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+		if body != nil {
+			req.Header.Add("Content-Type", "application/vnd.schemaregistry.v1+json")
+		}
+		if err = c.requestSigner(req); err != nil {
+			return nil, err
+		}
+
+		var res *http.Response
+		if res, err = c.client.Do(req); err != nil {
+			c.logger.Errorf("request failed for %v: %v", desc, err)
+			continue
+		}
+
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			err = fmt.Errorf("%v not found by registry", desc)
+			c.logger.Errorf(err.Error())
+			break
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			err = fmt.Errorf("request failed for %v", desc)
+			c.logger.Errorf(err.Error())
+			// TODO: Best attempt at parsing out the body
+			continue
+		}
+
+		if res.Body == nil {
+			c.logger.Errorf("request for %v returned an empty body", desc)
+			err = errors.New("schema request returned an empty body")
+			continue
+		}
+
+		resBytes, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			c.logger.Errorf("failed to read response for %v: %v", desc, err)
+			continue
+		}
+
+		break
+	}
+	return resBytes, err
+}
+
+type schemaResponse struct {
+	Schema string `json:"schema"`
+	ID     int    `json:"id"`
+}
+
+// GetSchemaByID returns the schema that has the given ID.
+func (c *schemaRegistryClient) GetSchemaByID(id int) (string, error) {
+	desc := fmt.Sprintf("schema '%v'", id)
+	resBytes, err := c.getCached(fmt.Sprintf("/schemas/ids/%v", id), desc)
+	if err != nil {
+		return "", err
+	}
+
+	var resPayload schemaResponse
+	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
+		c.logger.Errorf("failed to parse response for %v: %v", desc, err)
+		return "", err
+	}
+	return resPayload.Schema, nil
+}
+
+// GetLatestSchema returns the latest version of the schema of a subject along
+// with its ID.
+func (c *schemaRegistryClient) GetLatestSchema(subject string) (string, int, error) {
+	desc := fmt.Sprintf("schema subject '%v'", subject)
+	resBytes, err := c.getCached(fmt.Sprintf("/subjects/%s/versions/latest", subject), desc)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var resPayload schemaResponse
+	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
+		c.logger.Errorf("failed to parse response for %v: %v", desc, err)
+		return "", 0, err
+	}
+
+	c.logger.Tracef("Loaded new schema for subject %v: %s", subject, resBytes)
+	return resPayload.Schema, resPayload.ID, nil
+}
+
+// RegisterSchema registers a schema under a subject and returns its ID. If the
+// schema is already registered under the subject the existing ID is returned.
+func (c *schemaRegistryClient) RegisterSchema(subject, schema string) (int, error) {
+	reqBytes, err := json.Marshal(struct {
+		Schema string `json:"schema"`
+	}{Schema: schema})
+	if err != nil {
+		return 0, err
+	}
+
+	desc := fmt.Sprintf("schema subject '%v'", subject)
+	resBytes, err := c.do("POST", fmt.Sprintf("/subjects/%s/versions", subject), reqBytes, desc)
+	if err != nil {
+		return 0, err
+	}
+
+	var resPayload schemaResponse
+	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
+		c.logger.Errorf("failed to parse response for %v: %v", desc, err)
+		return 0, err
+	}
+	return resPayload.ID, nil
+}
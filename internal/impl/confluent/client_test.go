@@ -0,0 +1,117 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistrySharedClientSettings(t *testing.T) {
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		return nil, errors.New("nope")
+	})
+
+	newDecoder := func(extra string) (*schemaRegistryDecoder, error) {
+		t.Helper()
+		conf, err := schemaRegistryDecoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+shared_client: foo
+%v
+`, urlStr, extra), nil)
+		require.NoError(t, err)
+		return newSchemaRegistryDecoderFromConfig(conf, nil)
+	}
+
+	first, err := newDecoder(`
+basic_auth:
+  enabled: true
+  username: foo
+  password: bar
+`)
+	require.NoError(t, err)
+
+	for _, extra := range []string{
+		``,
+		`
+basic_auth:
+  enabled: true
+  username: foo
+  password: baz
+`,
+		`
+basic_auth:
+  enabled: true
+  username: foo
+  password: bar
+cache_ttl: 1m
+`,
+	} {
+		_, err := newDecoder(extra)
+		assert.Error(t, err, extra)
+	}
+
+	second, err := newDecoder(`
+basic_auth:
+  username: foo
+  password: bar
+  enabled: true
+`)
+	require.NoError(t, err)
+	assert.Same(t, first.client, second.client)
+
+	require.NoError(t, first.Close(context.Background()))
+	require.NoError(t, second.Close(context.Background()))
+
+	sharedClientsMut.Lock()
+	assert.Empty(t, sharedClients)
+	sharedClientsMut.Unlock()
+}
+
+func TestSchemaRegistryClientCache(t *testing.T) {
+	payload, err := json.Marshal(struct {
+		Schema string `json:"schema"`
+	}{
+		Schema: testSchema,
+	})
+	require.NoError(t, err)
+
+	var requests int32
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		if path == "/schemas/ids/3" {
+			atomic.AddInt32(&requests, 1)
+			return payload, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	conf, err := schemaRegistryDecoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+cache_ttl: 1m
+`, urlStr), nil)
+	require.NoError(t, err)
+
+	client, err := schemaRegistryClientFromParsed(conf, nil)
+	require.NoError(t, err)
+
+	now := time.Now()
+	client.nowFn = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		schema, err := client.GetSchemaByID(3)
+		require.NoError(t, err)
+		assert.Equal(t, testSchema, schema)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	now = now.Add(time.Minute)
+	_, err = client.GetSchemaByID(3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
@@ -4,13 +4,8 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"path"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -49,7 +44,9 @@ However, it is possible to instead create documents in [standard/raw JSON format
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether Avro messages should be decoded into normal JSON (\"json that meets the expectations of regular internet json\") rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). If `true` the schema returned from the subject should be decoded as [standard json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) instead of as [avro json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodec). There is a [comment in goavro](https://github.com/linkedin/goavro/blob/5ec5a5ee7ec82e16e6e2b438d610e1cab2588393/union.go#L224-L249), the [underlining library used for avro serialization](https://github.com/linkedin/goavro), that explains in more detail the difference between the standard json and avro json.").
 			Advanced().Default(false)).
-		Field(service.NewStringField("url").Description("The base URL of the schema registry service."))
+		Field(service.NewStringField("url").Description("The base URL of the schema registry service.")).
+		Field(sharedClientField()).
+		Field(cacheTTLField())
 
 	for _, f := range httpclient.AuthFields() {
 		spec = spec.Field(f.Version("4.7.0"))
@@ -72,12 +69,9 @@ func init() {
 //------------------------------------------------------------------------------
 
 type schemaRegistryDecoder struct {
-	client      *http.Client
+	client      *schemaRegistryClient
 	avroRawJSON bool
 
-	schemaRegistryBaseURL *url.URL
-	requestSigner         httpclient.RequestSigner
-
 	schemas    map[int]*cachedSchemaDecoder
 	cacheMut   sync.RWMutex
 	requestMut sync.Mutex
@@ -87,23 +81,15 @@ type schemaRegistryDecoder struct {
 }
 
 func newSchemaRegistryDecoderFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*schemaRegistryDecoder, error) {
-	urlStr, err := conf.FieldString("url")
-	if err != nil {
-		return nil, err
-	}
-	tlsConf, err := conf.FieldTLS("tls")
-	if err != nil {
-		return nil, err
-	}
-	authSigner, err := httpclient.AuthSignerFromParsed(conf)
+	avroRawJSON, err := conf.FieldBool("avro_raw_json")
 	if err != nil {
 		return nil, err
 	}
-	avroRawJSON, err := conf.FieldBool("avro_raw_json")
+	client, err := schemaRegistryClientFromParsed(conf, logger)
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryDecoder(urlStr, authSigner, tlsConf, avroRawJSON, logger)
+	return newSchemaRegistryDecoderWithClient(client, avroRawJSON, logger), nil
 }
 
 func newSchemaRegistryDecoder(
@@ -113,32 +99,20 @@ func newSchemaRegistryDecoder(
 	avroRawJSON bool,
 	logger *service.Logger,
 ) (*schemaRegistryDecoder, error) {
-	u, err := url.Parse(urlStr)
+	client, err := newSchemaRegistryClient(urlStr, reqSigner, tlsConf, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse url: %w", err)
+		return nil, err
 	}
+	return newSchemaRegistryDecoderWithClient(client, avroRawJSON, logger), nil
+}
 
+func newSchemaRegistryDecoderWithClient(client *schemaRegistryClient, avroRawJSON bool, logger *service.Logger) *schemaRegistryDecoder {
 	s := &schemaRegistryDecoder{
-		avroRawJSON:           avroRawJSON,
-		schemaRegistryBaseURL: u,
-		requestSigner:         reqSigner,
-		schemas:               map[int]*cachedSchemaDecoder{},
-		shutSig:               shutdown.NewSignaller(),
-		logger:                logger,
-	}
-
-	s.client = http.DefaultClient
-	if tlsConf != nil {
-		s.client = &http.Client{}
-		if c, ok := http.DefaultTransport.(*http.Transport); ok {
-			cloned := c.Clone()
-			cloned.TLSClientConfig = tlsConf
-			s.client.Transport = cloned
-		} else {
-			s.client.Transport = &http.Transport{
-				TLSClientConfig: tlsConf,
-			}
-		}
+		client:      client,
+		avroRawJSON: avroRawJSON,
+		schemas:     map[int]*cachedSchemaDecoder{},
+		shutSig:     shutdown.NewSignaller(),
+		logger:      logger,
 	}
 
 	go func() {
@@ -151,7 +125,7 @@ func newSchemaRegistryDecoder(
 			}
 		}
 	}()
-	return s, nil
+	return s
 }
 
 func (s *schemaRegistryDecoder) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
@@ -180,6 +154,7 @@ func (s *schemaRegistryDecoder) Process(ctx context.Context, msg *service.Messag
 
 func (s *schemaRegistryDecoder) Close(ctx context.Context) error {
 	s.shutSig.CloseNow()
+	s.client.release()
 	s.cacheMut.Lock()
 	defer s.cacheMut.Unlock()
 	if ctx.Err() != nil {
@@ -265,77 +240,19 @@ func (s *schemaRegistryDecoder) getDecoder(id int) (schemaDecoder, error) {
 		return c.decoder, nil
 	}
 
-	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
-	defer done()
-
-	reqURL := *s.schemaRegistryBaseURL
-	reqURL.Path = path.Join(reqURL.Path, fmt.Sprintf("/schemas/ids/%v", id))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), http.NoBody)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-	if err := s.requestSigner(req); err != nil {
-		return nil, err
-	}
-
-	var resBytes []byte
-	for i := 0; i < 3; i++ {
-		var res *http.Response
-		if res, err = s.client.Do(req); err != nil {
-			s.logger.Errorf("request failed for schema '%v': %v", id, err)
-			continue
-		}
-
-		if res.StatusCode == http.StatusNotFound {
-			err = fmt.Errorf("schema '%v' not found by registry", id)
-			s.logger.Errorf(err.Error())
-			break
-		}
-
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("request failed for schema '%v'", id)
-			s.logger.Errorf(err.Error())
-			// TODO: Best attempt at parsing out the body
-			continue
-		}
-
-		if res.Body == nil {
-			s.logger.Errorf("request for schema '%v' returned an empty body", id)
-			err = errors.New("schema request returned an empty body")
-			continue
-		}
-
-		resBytes, err = io.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			s.logger.Errorf("failed to read response for schema '%v': %v", id, err)
-			continue
-		}
-
-		break
-	}
+	schema, err := s.client.GetSchemaByID(id)
 	if err != nil {
 		return nil, err
 	}
 
-	resPayload := struct {
-		Schema string `json:"schema"`
-	}{}
-	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
-		s.logger.Errorf("failed to parse response for schema '%v': %v", id, err)
-		return nil, err
-	}
-
 	var codec *goavro.Codec
 	if s.avroRawJSON {
-		if codec, err = goavro.NewCodecForStandardJSONFull(resPayload.Schema); err != nil {
+		if codec, err = goavro.NewCodecForStandardJSONFull(schema); err != nil {
 			s.logger.Errorf("failed to parse response for schema subject '%v': %v", id, err)
 			return nil, err
 		}
 	} else {
-		if codec, err = goavro.NewCodec(resPayload.Schema); err != nil {
+		if codec, err = goavro.NewCodec(schema); err != nil {
 			s.logger.Errorf("failed to parse response for schema subject '%v': %v", id, err)
 			return nil, err
 		}
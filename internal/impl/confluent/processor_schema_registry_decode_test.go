@@ -59,7 +59,7 @@ basic_auth:
 
 			e, err := newSchemaRegistryDecoderFromConfig(conf, nil)
 			if e != nil {
-				assert.Equal(t, test.expectedBaseURL, e.schemaRegistryBaseURL.String())
+				assert.Equal(t, test.expectedBaseURL, e.client.schemaRegistryBaseURL.String())
 			}
 
 			if err == nil {
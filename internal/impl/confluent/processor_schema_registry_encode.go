@@ -7,10 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"path"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -50,6 +47,23 @@ For example, the union schema ` + "`[\"null\",\"string\",\"Foo\"]`, where `Foo`"
 
 However, it is possible to instead consume documents in [standard/raw JSON format](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) by setting the field ` + "[`avro_raw_json`](#avro_raw_json) to `true`" + `.
 
+### Kafka
+
+In order to encode messages written by a Kafka output this processor can be added to the ` + "`processors`" + ` of the output, where the ` + "`topic_name`" + ` subject name strategy derives subjects from the topic of each message in the same way as Confluent producers:
+
+` + "```yaml" + `
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topic: ${! meta("topic") }
+    processors:
+      - schema_registry_encode:
+          url: http://localhost:8081
+          subject: ${! meta("topic") }
+          subject_name_strategy: topic_name
+          shared_client: registry
+` + "```" + `
+
 ### Known Issues
 
 Important! There is an outstanding issue in the [avro serializing library](https://github.com/linkedin/goavro) that benthos uses which means it [doesn't encode logical types correctly](https://github.com/linkedin/goavro/issues/252). It's still possible to encode logical types that are in-line with the spec if ` + "`avro_raw_json` is set to true" + `, though now of course non-logical types will not be in-line with the spec.
@@ -78,7 +92,24 @@ Important! There is an outstanding issue in the [avro serializing library](https
 			Example("1h")).
 		Field(service.NewBoolField("avro_raw_json").
 			Description("Whether messages encoded in Avro format should be parsed as normal JSON (\"json that meets the expectations of regular internet json\") rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). If `true` the schema returned from the subject should be parsed as [standard json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodecForStandardJSONFull) instead of as [avro json](https://pkg.go.dev/github.com/linkedin/goavro/v2#NewCodec). There is a [comment in goavro](https://github.com/linkedin/goavro/blob/5ec5a5ee7ec82e16e6e2b438d610e1cab2588393/union.go#L224-L249), the [underlining library used for avro serialization](https://github.com/linkedin/goavro), that explains in more detail the difference between standard json and avro json.").
-			Advanced().Default(false).Version("3.59.0"))
+			Advanced().Default(false).Version("3.59.0")).
+		Field(service.NewStringEnumField("subject_name_strategy", "none", "topic_name", "record_name", "topic_record_name").
+			Description("The strategy used to derive the schema subject from the field `subject`. With `none` the subject is used as is, with `topic_name` the subject is treated as a topic and suffixed with `-value`, with `record_name` the fully qualified record name of `schema` is used, and with `topic_record_name` the subject is treated as a topic and suffixed with the fully qualified record name of `schema`.").
+			Advanced().
+			Default("none").
+			Version("4.9.0")).
+		Field(service.NewStringField("schema").
+			Description("An optional Avro schema to encode messages with, which is required by the `record_name` and `topic_record_name` subject name strategies and when `auto_register` is enabled.").
+			Optional().
+			Advanced().
+			Version("4.9.0")).
+		Field(service.NewBoolField("auto_register").
+			Description("Whether to register the `schema` under each subject rather than obtaining the latest schema of the subject from the registry. Registering a schema that already exists under a subject returns its existing ID.").
+			Advanced().
+			Default(false).
+			Version("4.9.0")).
+		Field(sharedClientField()).
+		Field(cacheTTLField())
 
 	for _, f := range httpclient.AuthFields() {
 		spec = spec.Field(f.Version("4.7.0"))
@@ -101,13 +132,15 @@ func init() {
 //------------------------------------------------------------------------------
 
 type schemaRegistryEncoder struct {
-	client             *http.Client
+	client             *schemaRegistryClient
 	subject            *service.InterpolatedString
 	avroRawJSON        bool
 	schemaRefreshAfter time.Duration
 
-	schemaRegistryBaseURL *url.URL
-	requestSigner         httpclient.RequestSigner
+	subjectStrategy string
+	schema          string
+	recordName      string
+	autoRegister    bool
 
 	schemas    map[string]*cachedSchemaEncoder
 	cacheMut   sync.RWMutex
@@ -119,10 +152,6 @@ type schemaRegistryEncoder struct {
 }
 
 func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*schemaRegistryEncoder, error) {
-	urlStr, err := conf.FieldString("url")
-	if err != nil {
-		return nil, err
-	}
 	subject, err := conf.FieldInterpolatedString("subject")
 	if err != nil {
 		return nil, err
@@ -143,15 +172,47 @@ func newSchemaRegistryEncoderFromConfig(conf *service.ParsedConfig, logger *serv
 	if refreshTicker < time.Second {
 		refreshTicker = time.Second
 	}
-	authSigner, err := httpclient.AuthSignerFromParsed(conf)
+
+	subjectStrategy, err := conf.FieldString("subject_name_strategy")
 	if err != nil {
 		return nil, err
 	}
-	tlsConf, err := conf.FieldTLS("tls")
+	var schema string
+	if conf.Contains("schema") {
+		if schema, err = conf.FieldString("schema"); err != nil {
+			return nil, err
+		}
+	}
+	autoRegister, err := conf.FieldBool("auto_register")
 	if err != nil {
 		return nil, err
 	}
-	return newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, subject, avroRawJSON, refreshPeriod, refreshTicker, logger)
+
+	if autoRegister && schema == "" {
+		return nil, errors.New("a schema must be specified when auto_register is enabled")
+	}
+
+	var recordName string
+	if subjectStrategy == "record_name" || subjectStrategy == "topic_record_name" {
+		if schema == "" {
+			return nil, fmt.Errorf("a schema must be specified for the %v subject name strategy", subjectStrategy)
+		}
+		if recordName, err = avroRecordName(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := schemaRegistryClientFromParsed(conf, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSchemaRegistryEncoderWithClient(client, subject, avroRawJSON, refreshPeriod, refreshTicker, logger)
+	s.subjectStrategy = subjectStrategy
+	s.schema = schema
+	s.recordName = recordName
+	s.autoRegister = autoRegister
+	return s, nil
 }
 
 func newSchemaRegistryEncoder(
@@ -163,35 +224,30 @@ func newSchemaRegistryEncoder(
 	schemaRefreshAfter, schemaRefreshTicker time.Duration,
 	logger *service.Logger,
 ) (*schemaRegistryEncoder, error) {
-	u, err := url.Parse(urlStr)
+	client, err := newSchemaRegistryClient(urlStr, reqSigner, tlsConf, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse url: %w", err)
+		return nil, err
 	}
+	return newSchemaRegistryEncoderWithClient(client, subject, avroRawJSON, schemaRefreshAfter, schemaRefreshTicker, logger), nil
+}
 
+func newSchemaRegistryEncoderWithClient(
+	client *schemaRegistryClient,
+	subject *service.InterpolatedString,
+	avroRawJSON bool,
+	schemaRefreshAfter, schemaRefreshTicker time.Duration,
+	logger *service.Logger,
+) *schemaRegistryEncoder {
 	s := &schemaRegistryEncoder{
-		schemaRegistryBaseURL: u,
-		requestSigner:         reqSigner,
-		subject:               subject,
-		avroRawJSON:           avroRawJSON,
-		schemaRefreshAfter:    schemaRefreshAfter,
-		schemas:               map[string]*cachedSchemaEncoder{},
-		shutSig:               shutdown.NewSignaller(),
-		logger:                logger,
-		nowFn:                 time.Now,
-	}
-
-	s.client = http.DefaultClient
-	if tlsConf != nil {
-		s.client = &http.Client{}
-		if c, ok := http.DefaultTransport.(*http.Transport); ok {
-			cloned := c.Clone()
-			cloned.TLSClientConfig = tlsConf
-			s.client.Transport = cloned
-		} else {
-			s.client.Transport = &http.Transport{
-				TLSClientConfig: tlsConf,
-			}
-		}
+		client:             client,
+		subject:            subject,
+		avroRawJSON:        avroRawJSON,
+		schemaRefreshAfter: schemaRefreshAfter,
+		subjectStrategy:    "none",
+		schemas:            map[string]*cachedSchemaEncoder{},
+		shutSig:            shutdown.NewSignaller(),
+		logger:             logger,
+		nowFn:              time.Now,
 	}
 
 	go func() {
@@ -204,13 +260,44 @@ func newSchemaRegistryEncoder(
 			}
 		}
 	}()
-	return s, nil
+	return s
+}
+
+// avroRecordName returns the fully qualified name of an Avro record schema.
+func avroRecordName(schema string) (string, error) {
+	var record struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	}
+	if err := json.Unmarshal([]byte(schema), &record); err != nil {
+		return "", fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if record.Name == "" {
+		return "", errors.New("schema does not specify a record name")
+	}
+	if record.Namespace == "" || strings.Contains(record.Name, ".") {
+		return record.Name, nil
+	}
+	return record.Namespace + "." + record.Name, nil
+}
+
+// subjectFor applies the subject name strategy to an interpolated subject.
+func (s *schemaRegistryEncoder) subjectFor(subject string) string {
+	switch s.subjectStrategy {
+	case "topic_name":
+		return subject + "-value"
+	case "record_name":
+		return s.recordName
+	case "topic_record_name":
+		return subject + "-" + s.recordName
+	}
+	return subject
 }
 
 func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
 	batch = batch.Copy()
 	for i, msg := range batch {
-		encoder, id, err := s.getEncoder(batch.InterpolatedString(i, s.subject))
+		encoder, id, err := s.getEncoder(s.subjectFor(batch.InterpolatedString(i, s.subject)))
 		if err != nil {
 			msg.SetError(err)
 			continue
@@ -238,6 +325,7 @@ func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.
 
 func (s *schemaRegistryEncoder) Close(ctx context.Context) error {
 	s.shutSig.CloseNow()
+	s.client.release()
 	s.cacheMut.Lock()
 	defer s.cacheMut.Unlock()
 	if ctx.Err() != nil {
@@ -316,80 +404,27 @@ func (s *schemaRegistryEncoder) refreshEncoders() {
 }
 
 func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder, int, error) {
-	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
-	defer done()
-
-	reqURL := *s.schemaRegistryBaseURL
-	reqURL.Path = path.Join(reqURL.Path, fmt.Sprintf("/subjects/%s/versions/latest", subject))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), http.NoBody)
-	if err != nil {
-		return nil, 0, err
-	}
-	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
-	if err := s.requestSigner(req); err != nil {
-		return nil, 0, err
-	}
-
-	var resBytes []byte
-	for i := 0; i < 3; i++ {
-		var res *http.Response
-		if res, err = s.client.Do(req); err != nil {
-			s.logger.Errorf("request failed for schema subject '%v': %v", subject, err)
-			continue
-		}
-
-		if res.StatusCode == http.StatusNotFound {
-			err = fmt.Errorf("schema subject '%v' not found by registry", subject)
-			s.logger.Errorf(err.Error())
-			break
-		}
-
-		if res.StatusCode != http.StatusOK {
-			err = fmt.Errorf("request failed for schema subject '%v'", subject)
-			s.logger.Errorf(err.Error())
-			// TODO: Best attempt at parsing out the body
-			continue
-		}
-
-		if res.Body == nil {
-			s.logger.Errorf("request for schema subject latest '%v' returned an empty body", subject)
-			err = errors.New("schema request returned an empty body")
-			continue
-		}
-
-		resBytes, err = io.ReadAll(res.Body)
-		res.Body.Close()
-		if err != nil {
-			s.logger.Errorf("failed to read response for schema subject '%v': %v", subject, err)
-			continue
-		}
-
-		break
+	var schema string
+	var id int
+	var err error
+	if s.autoRegister {
+		schema = s.schema
+		id, err = s.client.RegisterSchema(subject, schema)
+	} else {
+		schema, id, err = s.client.GetLatestSchema(subject)
 	}
 	if err != nil {
 		return nil, 0, err
 	}
 
-	resPayload := struct {
-		Schema string `json:"schema"`
-		ID     int    `json:"id"`
-	}{}
-	if err = json.Unmarshal(resBytes, &resPayload); err != nil {
-		s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
-		return nil, 0, err
-	}
-
-	s.logger.Tracef("Loaded new codec for subject %v: %s", subject, resBytes)
-
 	var codec *goavro.Codec
 	if s.avroRawJSON {
-		if codec, err = goavro.NewCodecForStandardJSONFull(resPayload.Schema); err != nil {
+		if codec, err = goavro.NewCodecForStandardJSONFull(schema); err != nil {
 			s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 			return nil, 0, err
 		}
 	} else {
-		if codec, err = goavro.NewCodec(resPayload.Schema); err != nil {
+		if codec, err = goavro.NewCodec(schema); err != nil {
 			s.logger.Errorf("failed to parse response for schema subject '%v': %v", subject, err)
 			return nil, 0, err
 		}
@@ -413,7 +448,7 @@ func (s *schemaRegistryEncoder) getLatestEncoder(subject string) (schemaEncoder,
 
 		m.SetBytes(binary)
 		return nil
-	}, resPayload.ID, nil
+	}, id, nil
 }
 
 func (s *schemaRegistryEncoder) getEncoder(subject string) (schemaEncoder, int, error) {
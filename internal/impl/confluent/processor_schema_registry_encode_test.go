@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -89,7 +90,7 @@ subject: foo
 
 			e, err := newSchemaRegistryEncoderFromConfig(conf, nil)
 			if e != nil {
-				assert.Equal(t, test.expectedBaseURL, e.schemaRegistryBaseURL.String())
+				assert.Equal(t, test.expectedBaseURL, e.client.schemaRegistryBaseURL.String())
 			}
 
 			if err == nil {
@@ -565,3 +566,60 @@ func TestSchemaRegistryEncodeRefresh(t *testing.T) {
 	assert.Equal(t, int32(1), atomic.LoadInt32(&fooReqs))
 	assert.Equal(t, int32(1), atomic.LoadInt32(&barReqs))
 }
+
+func TestSchemaRegistryEncodeAutoRegister(t *testing.T) {
+	var registered int32
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		if path == "/subjects/foo-foo.namespace.com.identity/versions" {
+			atomic.AddInt32(&registered, 1)
+			return []byte(`{"id":4}`), nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	conf, err := schemaRegistryEncoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+subject: ${! meta("topic") }
+subject_name_strategy: topic_record_name
+auto_register: true
+shared_client: foo
+schema: '%v'
+`, urlStr, strings.ReplaceAll(testSchema, "\n", " ")), nil)
+	require.NoError(t, err)
+
+	encoder, err := newSchemaRegistryEncoderFromConfig(conf, nil)
+	require.NoError(t, err)
+
+	decoderConf, err := schemaRegistryDecoderConfig().ParseYAML(fmt.Sprintf(`
+url: %v
+shared_client: foo
+`, urlStr), nil)
+	require.NoError(t, err)
+
+	decoder, err := newSchemaRegistryDecoderFromConfig(decoderConf, nil)
+	require.NoError(t, err)
+	assert.Same(t, encoder.client, decoder.client)
+
+	for i := 0; i < 2; i++ {
+		msg := service.NewMessage([]byte(`{"Name":"foo","MaybeHobby":null}`))
+		msg.MetaSet("topic", "foo")
+
+		outBatches, err := encoder.ProcessBatch(context.Background(), service.MessageBatch{msg})
+		require.NoError(t, err)
+		require.Len(t, outBatches, 1)
+		require.Len(t, outBatches[0], 1)
+		require.NoError(t, outBatches[0][0].GetError())
+
+		b, err := outBatches[0][0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, "\x00\x00\x00\x00\x04\x06foo\x00\x00", string(b))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&registered))
+
+	require.NoError(t, encoder.Close(context.Background()))
+	require.NoError(t, decoder.Close(context.Background()))
+
+	sharedClientsMut.Lock()
+	assert.Empty(t, sharedClients)
+	sharedClientsMut.Unlock()
+}
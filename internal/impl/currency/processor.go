@@ -0,0 +1,342 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+	"github.com/shopspring/decimal"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ccFieldAmount     = "amount"
+	ccFieldFrom       = "from"
+	ccFieldTo         = "to"
+	ccFieldDate       = "date"
+	ccFieldTargetPath = "target_path"
+	ccFieldDecimals   = "decimals"
+	ccFieldRounding   = "rounding"
+	ccFieldAsString   = "as_string"
+
+	// The number of decimal places of intermediate results, which are rounded
+	// to the configured number of decimal places afterwards.
+	intermediatePrecision = 16
+)
+
+func currencyConvertProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Mapping").
+		Summary("Converts an amount of a message from one currency to another with exchange rates from a file, an HTTP API or a cache resource.").
+		Description(`
+The amount is resolved from each message with a Bloblang query and can be either a number or a string of a decimal number, where strings are converted without any loss of precision. The converted amount is rounded to a number of decimal places and written to a path of the message, which must be structured.
+
+When a date is set the rates of that date are used, which allows historical amounts to be converted with the rates of the time they were recorded, otherwise the latest rates are used.
+
+### Rates
+
+Rates are objects of a base currency and the amount of each currency that is worth one unit of the base currency, in the format that's used by most exchange rate APIs:
+
+`+"```json"+`
+{
+  "base": "EUR",
+  "rates": { "USD": 1.0712, "GBP": 0.8537 }
+}
+`+"```"+`
+
+Files of the `+"`static`"+` source add the rates of dates relative to the same base with the field `+"`historical`"+`, where the rates of a date that's not listed are those of the closest date before it:
+
+`+"```yaml"+`
+base: EUR
+rates: { USD: 1.0712, GBP: 0.8537 }
+historical:
+  "2022-06-01": { USD: 1.0711, GBP: 0.8544 }
+`+"```"+`
+
+### Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```"+`
+- currency_rate
+- currency_rate_date
+`+"```"+`
+
+Where the rate is the exchange rate from the source currency to the target currency, and the rate date is the date of the rates when it's known.
+
+### Error Handling
+
+Messages fail when the amount isn't a number, when there are no rates for either currency or when the rates cannot be obtained. Failed messages continue through the pipeline unchanged, but can be dropped or placed in a dead letter queue according to your config, you can read about these patterns [here](/docs/configuration/error_handling).`).
+		Field(service.NewBloblangField(ccFieldAmount).
+			Description("A [Bloblang query](/docs/guides/bloblang/about) that returns the amount to convert.").
+			Example("this.price").
+			Example(`this.total.string()`)).
+		Field(service.NewInterpolatedStringField(ccFieldFrom).
+			Description("The [ISO 4217](https://en.wikipedia.org/wiki/ISO_4217) code of the currency to convert from.").
+			Example("USD").
+			Example(`${! this.currency }`)).
+		Field(service.NewInterpolatedStringField(ccFieldTo).
+			Description("The ISO 4217 code of the currency to convert to.").
+			Example("EUR")).
+		Field(service.NewInterpolatedStringField(ccFieldDate).
+			Description("An optional date of the rates to use, either in the format `YYYY-MM-DD` or an RFC 3339 timestamp. When it's empty the latest rates are used.").
+			Example(`${! this.created_at }`).
+			Optional()).
+		Field(service.NewStringField(ccFieldTargetPath).
+			Description("The [dot path](/docs/configuration/field_paths) of the message to write the converted amount to.").
+			Example("price_eur")).
+		Field(service.NewIntField(ccFieldDecimals).
+			Description("The number of decimal places to round the converted amount to.").
+			Default(2)).
+		Field(service.NewStringAnnotatedEnumField(ccFieldRounding, map[string]string{
+			"half_up":   "Rounds to the nearest value, away from zero when halfway between two values.",
+			"half_even": "Rounds to the nearest value, to the even value when halfway between two values, which is also known as banker's rounding.",
+			"truncate":  "Rounds towards zero.",
+		}).
+			Description("How to round the converted amount.").
+			Advanced().
+			Default("half_up")).
+		Field(service.NewBoolField(ccFieldAsString).
+			Description("Whether to write the converted amount as a string of exactly `decimals` decimal places, which preserves its precision, instead of a number.").
+			Default(false)).
+		Field(ratesField()).
+		Example(
+			"Historical Rates from an API",
+			"Convert the totals of orders to euros with the rates of the day they were placed.",
+			`
+pipeline:
+  processors:
+    - currency_convert:
+        amount: this.total
+        from: ${! this.currency }
+        to: EUR
+        date: ${! this.placed_at }
+        target_path: total_eur
+        rates:
+          http:
+            url: https://api.frankfurter.app/latest
+            historical_url: https://api.frankfurter.app/{date}
+`,
+		).
+		Example(
+			"Rates from a File",
+			"Convert amounts given as strings with rates from a file, writing the results as strings in order to preserve their precision.",
+			`
+pipeline:
+  processors:
+    - currency_convert:
+        amount: this.amount
+        from: USD
+        to: ${! this.account_currency }
+        target_path: account_amount
+        decimals: 4
+        as_string: true
+        rates:
+          static:
+            file: ./rates.yaml
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"currency_convert", currencyConvertProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newCurrencyConvertFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type currencyConvertProcessor struct {
+	amount     *bloblang.Executor
+	from       *service.InterpolatedString
+	to         *service.InterpolatedString
+	date       *service.InterpolatedString
+	targetPath string
+	decimals   int32
+	round      func(d decimal.Decimal, places int32) decimal.Decimal
+	asString   bool
+	provider   rateProvider
+}
+
+func newCurrencyConvertFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*currencyConvertProcessor, error) {
+	p := &currencyConvertProcessor{}
+
+	var err error
+	if p.amount, err = conf.FieldBloblang(ccFieldAmount); err != nil {
+		return nil, err
+	}
+	if p.from, err = conf.FieldInterpolatedString(ccFieldFrom); err != nil {
+		return nil, err
+	}
+	if p.to, err = conf.FieldInterpolatedString(ccFieldTo); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldDate) {
+		if p.date, err = conf.FieldInterpolatedString(ccFieldDate); err != nil {
+			return nil, err
+		}
+	}
+	if p.targetPath, err = conf.FieldString(ccFieldTargetPath); err != nil {
+		return nil, err
+	}
+
+	decimals, err := conf.FieldInt(ccFieldDecimals)
+	if err != nil {
+		return nil, err
+	}
+	if decimals < 0 || decimals > intermediatePrecision {
+		return nil, fmt.Errorf("decimals must be between 0 and %v", intermediatePrecision)
+	}
+	p.decimals = int32(decimals)
+
+	rounding, err := conf.FieldString(ccFieldRounding)
+	if err != nil {
+		return nil, err
+	}
+	switch rounding {
+	case "half_up":
+		p.round = decimal.Decimal.Round
+	case "half_even":
+		p.round = decimal.Decimal.RoundBank
+	case "truncate":
+		p.round = decimal.Decimal.Truncate
+	default:
+		return nil, fmt.Errorf("rounding '%v' is not supported", rounding)
+	}
+
+	if p.asString, err = conf.FieldBool(ccFieldAsString); err != nil {
+		return nil, err
+	}
+	if p.provider, err = rateProviderFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *currencyConvertProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	amount, err := p.resolveAmount(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	from := strings.ToUpper(strings.TrimSpace(p.from.String(msg)))
+	to := strings.ToUpper(strings.TrimSpace(p.to.String(msg)))
+
+	var date string
+	if p.date != nil {
+		if date, err = normaliseDate(p.date.String(msg)); err != nil {
+			return nil, err
+		}
+	}
+
+	table, err := p.provider.rates(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain rates: %w", err)
+	}
+	fromRate, err := table.rate(from)
+	if err != nil {
+		return nil, err
+	}
+	toRate, err := table.rate(to)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := toRate.DivRound(fromRate, intermediatePrecision)
+	converted := p.round(amount.Mul(toRate).DivRound(fromRate, intermediatePrecision), p.decimals)
+
+	var value any
+	if p.asString {
+		value = converted.StringFixed(p.decimals)
+	} else {
+		value, _ = converted.Float64()
+	}
+
+	root, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as structured: %w", err)
+	}
+	gObj := gabs.Wrap(root)
+	if _, err := gObj.SetP(value, p.targetPath); err != nil {
+		return nil, fmt.Errorf("failed to set converted amount: %w", err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+
+	msg.MetaSetMut("currency_rate", rate.String())
+	if table.Date != "" {
+		msg.MetaSetMut("currency_rate_date", table.Date)
+	} else if date != "" {
+		msg.MetaSetMut("currency_rate_date", date)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *currencyConvertProcessor) resolveAmount(msg *service.Message) (decimal.Decimal, error) {
+	res, err := msg.BloblangQuery(p.amount)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to resolve amount: %w", err)
+	}
+	if res == nil {
+		return decimal.Decimal{}, errors.New("amount mapping resulted in a deleted message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		// Query results that aren't structured are raw strings.
+		b, bErr := res.AsBytes()
+		if bErr != nil {
+			return decimal.Decimal{}, fmt.Errorf("failed to resolve amount: %w", bErr)
+		}
+		v = string(b)
+	}
+
+	switch t := v.(type) {
+	case string:
+		d, err := decimal.NewFromString(strings.TrimSpace(t))
+		if err != nil {
+			return decimal.Decimal{}, fmt.Errorf("amount '%v' is not a number", t)
+		}
+		return d, nil
+	case json.Number:
+		return decimal.NewFromString(t.String())
+	case float64:
+		return decimal.NewFromFloat(t), nil
+	case int64:
+		return decimal.NewFromInt(t), nil
+	case uint64:
+		return decimal.NewFromString(fmt.Sprint(t))
+	case int:
+		return decimal.NewFromInt(int64(t)), nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("amount of type %T is not a number", v)
+}
+
+// normaliseDate converts either a date or a timestamp to a date, where an
+// empty string is the latest date.
+func normaliseDate(s string) (string, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return "", nil
+	}
+	if _, err := time.Parse(dateLayout, s); err == nil {
+		return s, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return "", fmt.Errorf("date '%v' is neither a date of the format YYYY-MM-DD nor an RFC 3339 timestamp", s)
+	}
+	return t.UTC().Format(dateLayout), nil
+}
+
+func (p *currencyConvertProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,260 @@
+package currency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCurrencyConvert(t *testing.T, mgr *service.Resources, conf string) *currencyConvertProcessor {
+	t.Helper()
+
+	pConf, err := currencyConvertProcessorSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	p, err := newCurrencyConvertFromParsed(pConf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.Close(context.Background()) })
+	return p
+}
+
+func writeRatesFile(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "rates.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testRatesFile), 0o644))
+	return path
+}
+
+func TestCurrencyConvertStatic(t *testing.T) {
+	path := writeRatesFile(t)
+
+	tests := []struct {
+		name     string
+		conf     string
+		input    string
+		output   string
+		rate     string
+		rateDate string
+		errConts string
+	}{
+		{
+			name: "number",
+			conf: `
+amount: this.price
+from: ${! this.currency }
+to: gbp
+target_path: converted.gbp
+`,
+			input:  `{"price":100,"currency":"USD"}`,
+			output: `{"price":100,"currency":"USD","converted":{"gbp":79.7}}`,
+			rate:   "0.7969566840926064",
+		},
+		{
+			name: "string of historical date",
+			conf: `
+amount: this.price
+from: EUR
+to: USD
+date: ${! this.at }
+target_path: usd
+decimals: 4
+as_string: true
+`,
+			input:    `{"price":"19.99","at":"2022-06-02T23:30:00-02:00"}`,
+			output:   `{"price":"19.99","at":"2022-06-02T23:30:00-02:00","usd":"21.4293"}`,
+			rate:     "1.072",
+			rateDate: "2022-06-03",
+		},
+		{
+			name: "half even rounding",
+			conf: `
+amount: this.price
+from: EUR
+to: EUR
+target_path: rounded
+decimals: 0
+rounding: half_even
+`,
+			input:  `{"price":2.5}`,
+			output: `{"price":2.5,"rounded":2}`,
+			rate:   "1",
+		},
+		{
+			name: "truncate",
+			conf: `
+amount: this.price
+from: EUR
+to: EUR
+target_path: rounded
+decimals: 1
+rounding: truncate
+as_string: true
+`,
+			input:  `{"price":-2.56}`,
+			output: `{"price":-2.56,"rounded":"-2.5"}`,
+			rate:   "1",
+		},
+		{
+			name: "unknown currency",
+			conf: `
+amount: this.price
+from: EUR
+to: XYZ
+target_path: converted
+`,
+			input:    `{"price":1}`,
+			errConts: "no rate for currency XYZ",
+		},
+		{
+			name: "not a number",
+			conf: `
+amount: this.price
+from: EUR
+to: USD
+target_path: converted
+`,
+			input:    `{"price":"one"}`,
+			errConts: "amount 'one' is not a number",
+		},
+		{
+			name: "invalid date",
+			conf: `
+amount: this.price
+from: EUR
+to: USD
+date: yesterday
+target_path: converted
+`,
+			input:    `{"price":1}`,
+			errConts: "date 'yesterday' is neither",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			p := testCurrencyConvert(t, service.MockResources(), test.conf+`
+rates:
+  static:
+    file: `+path)
+
+			batch, err := p.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			if test.errConts != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errConts)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+
+			body, err := batch[0].AsBytes()
+			require.NoError(t, err)
+			assert.JSONEq(t, test.output, string(body))
+
+			rate, _ := batch[0].MetaGet("currency_rate")
+			assert.Equal(t, test.rate, rate)
+			rateDate, _ := batch[0].MetaGet("currency_rate_date")
+			assert.Equal(t, test.rateDate, rateDate)
+		})
+	}
+}
+
+func TestCurrencyConvertCache(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("rates"))
+	require.NoError(t, mgr.AccessCache(context.Background(), "rates", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "fx_latest", []byte(`{"base":"USD","rates":{"JPY":"135.5"}}`), nil))
+		require.NoError(t, c.Set(context.Background(), "fx_2022-06-01", []byte(`{"base":"USD","rates":{"JPY":"128.25"}}`), nil))
+	}))
+
+	p := testCurrencyConvert(t, mgr, `
+amount: this.amount
+from: USD
+to: JPY
+date: ${! meta("date").or("") }
+target_path: amount
+decimals: 0
+rates:
+  cache:
+    resource: rates
+    key_prefix: fx_
+`)
+
+	tests := []struct {
+		date     string
+		output   string
+		errConts string
+	}{
+		{date: "", output: `{"amount":1355}`},
+		{date: "2022-06-01", output: `{"amount":1283}`},
+		{date: "2022-06-02", errConts: "no rates found at key 'fx_2022-06-02'"},
+	}
+
+	for _, test := range tests {
+		msg := service.NewMessage([]byte(`{"amount":10}`))
+		msg.MetaSetMut("date", test.date)
+
+		batch, err := p.Process(context.Background(), msg)
+		if test.errConts != "" {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errConts)
+			continue
+		}
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		body, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, test.output, string(body), test.date)
+	}
+}
+
+func TestCurrencyConvertConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`
+amount: this.price
+from: EUR
+to: USD
+target_path: converted
+rates: {}
+`,
+		`
+amount: this.price
+from: EUR
+to: USD
+target_path: converted
+rates:
+  static: { file: ./nope.yaml }
+  cache: { resource: foo }
+`,
+		`
+amount: this.price
+from: EUR
+to: USD
+target_path: converted
+rates:
+  cache: { resource: foo }
+`,
+		`
+amount: this.price
+from: EUR
+to: USD
+target_path: converted
+decimals: -1
+rates:
+  http: { url: http://localhost }
+`,
+	} {
+		pConf, err := currencyConvertProcessorSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newCurrencyConvertFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,442 @@
+package currency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ccFieldRates = "rates"
+
+	ccFieldStatic     = "static"
+	ccFieldStaticFile = "file"
+
+	ccFieldHTTP                = "http"
+	ccFieldHTTPURL             = "url"
+	ccFieldHTTPHistoricalURL   = "historical_url"
+	ccFieldHTTPHeaders         = "headers"
+	ccFieldHTTPMapping         = "mapping"
+	ccFieldHTTPTimeout         = "timeout"
+	ccFieldHTTPRefreshInterval = "refresh_interval"
+
+	ccFieldCache          = "cache"
+	ccFieldCacheResource  = "resource"
+	ccFieldCacheKeyPrefix = "key_prefix"
+
+	// The layout of the dates of historical rates.
+	dateLayout = "2006-01-02"
+
+	// The maximum number of dates of historical rates cached by the HTTP
+	// provider.
+	maxHistoricalDates = 1024
+)
+
+func ratesField() *service.ConfigField {
+	return service.NewObjectField(ccFieldRates,
+		service.NewObjectField(ccFieldStatic,
+			service.NewStringField(ccFieldStaticFile).
+				Description("The path of a JSON or YAML file of rates."),
+		).Description("Reads rates from a file once at start up.").Optional(),
+		service.NewObjectField(ccFieldHTTP,
+			service.NewStringField(ccFieldHTTPURL).
+				Description("The URL to fetch the latest rates from.").
+				Example("https://api.frankfurter.app/latest"),
+			service.NewStringField(ccFieldHTTPHistoricalURL).
+				Description("An optional URL to fetch the rates of a date from, where `{date}` is replaced with the date in the format `YYYY-MM-DD`.").
+				Example("https://api.frankfurter.app/{date}").
+				Optional(),
+			service.NewStringMapField(ccFieldHTTPHeaders).
+				Description("A map of headers to add to requests, such as those of API keys.").
+				Example(map[string]any{"Authorization": "Token ${RATES_API_KEY}"}).
+				Default(map[string]any{}),
+			service.NewBloblangField(ccFieldHTTPMapping).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that converts the responses of a provider into the format of rates.").
+				Example(`root.base = this.base_code
+root.rates = this.conversion_rates`).
+				Optional(),
+			service.NewDurationField(ccFieldHTTPTimeout).
+				Description("The maximum period of time to wait for a response.").
+				Advanced().
+				Default("10s"),
+			service.NewDurationField(ccFieldHTTPRefreshInterval).
+				Description("The period of time after which the latest rates are fetched again. Historical rates are cached until the processor is closed.").
+				Default("1h"),
+		).Description("Fetches rates from an HTTP API and caches them.").Optional(),
+		service.NewObjectField(ccFieldCache,
+			service.NewStringField(ccFieldCacheResource).
+				Description("The name of the cache resource to read rates from."),
+			service.NewStringField(ccFieldCacheKeyPrefix).
+				Description("A prefix of the keys of rates, which are `latest` for the latest rates and the date in the format `YYYY-MM-DD` for historical rates.").
+				Default(""),
+		).Description("Reads rates from a [cache resource](/docs/components/caches/about) on each lookup, which allows them to be shared and updated by other streams.").Optional(),
+	).Description("The source of exchange rates, where exactly one source must be set.")
+}
+
+//------------------------------------------------------------------------------
+
+// rateTable holds the exchange rates of a date relative to a base currency,
+// where each rate is the amount of the currency that's worth one unit of the
+// base currency.
+type rateTable struct {
+	Base  string                     `json:"base"`
+	Date  string                     `json:"date,omitempty"`
+	Rates map[string]decimal.Decimal `json:"rates"`
+}
+
+func (t *rateTable) validate() error {
+	if t.Base == "" {
+		return errors.New("rates have no base currency")
+	}
+	if len(t.Rates) == 0 {
+		return errors.New("rates are empty")
+	}
+	rates := make(map[string]decimal.Decimal, len(t.Rates)+1)
+	for k, v := range t.Rates {
+		if !v.IsPositive() {
+			return fmt.Errorf("rate of currency %v is not positive", k)
+		}
+		rates[strings.ToUpper(k)] = v
+	}
+	t.Base = strings.ToUpper(t.Base)
+	rates[t.Base] = decimal.NewFromInt(1)
+	t.Rates = rates
+	return nil
+}
+
+func (t *rateTable) rate(currency string) (decimal.Decimal, error) {
+	r, exists := t.Rates[currency]
+	if !exists {
+		return decimal.Decimal{}, fmt.Errorf("no rate for currency %v", currency)
+	}
+	return r, nil
+}
+
+func parseRateTable(b []byte) (*rateTable, error) {
+	var t rateTable
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse rates: %w", err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// rateProvider provides the latest rates, or those of a date when it's not
+// empty.
+type rateProvider interface {
+	rates(ctx context.Context, date string) (*rateTable, error)
+}
+
+func rateProviderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (rateProvider, error) {
+	conf = conf.Namespace(ccFieldRates)
+
+	var providers []rateProvider
+	if conf.Contains(ccFieldStatic) {
+		p, err := staticProviderFromParsed(conf.Namespace(ccFieldStatic))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if conf.Contains(ccFieldHTTP) {
+		p, err := httpProviderFromParsed(conf.Namespace(ccFieldHTTP), mgr.Logger())
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if conf.Contains(ccFieldCache) {
+		p, err := cacheProviderFromParsed(conf.Namespace(ccFieldCache), mgr)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if len(providers) != 1 {
+		return nil, fmt.Errorf("exactly one of %v, %v or %v must be set", ccFieldStatic, ccFieldHTTP, ccFieldCache)
+	}
+	return providers[0], nil
+}
+
+//------------------------------------------------------------------------------
+
+// staticFile is the format of the files of the static provider, which are the
+// latest rates along with the rates of dates relative to the same base.
+type staticFile struct {
+	rateTable
+	Historical map[string]map[string]decimal.Decimal `json:"historical"`
+}
+
+// staticProvider serves rates from a file, where the rates of a date that's
+// not in the file are those of the closest date before it, as rates are
+// typically not published for weekends and holidays.
+type staticProvider struct {
+	latest     *rateTable
+	historical map[string]*rateTable
+	dates      []string
+}
+
+func staticProviderFromParsed(conf *service.ParsedConfig) (*staticProvider, error) {
+	path, err := conf.FieldString(ccFieldStaticFile)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newStaticProvider(b)
+}
+
+func newStaticProvider(b []byte) (*staticProvider, error) {
+	// Files are parsed as YAML, which is a superset of JSON, and converted to
+	// JSON in order to decode the rates as decimals.
+	var v any
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+	jBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+	var f staticFile
+	if err := json.Unmarshal(jBytes, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse rates file: %w", err)
+	}
+
+	p := &staticProvider{historical: map[string]*rateTable{}}
+	for date, rates := range f.Historical {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("historical rates have invalid date '%v'", date)
+		}
+		t := &rateTable{Base: f.Base, Date: date, Rates: rates}
+		if err := t.validate(); err != nil {
+			return nil, fmt.Errorf("historical rates of %v: %w", date, err)
+		}
+		p.historical[date] = t
+		p.dates = append(p.dates, date)
+	}
+	sort.Strings(p.dates)
+
+	if len(f.Rates) > 0 {
+		p.latest = &f.rateTable
+		if err := p.latest.validate(); err != nil {
+			return nil, err
+		}
+	} else if len(p.dates) > 0 {
+		p.latest = p.historical[p.dates[len(p.dates)-1]]
+	} else {
+		return nil, errors.New("rates file has no rates")
+	}
+	return p, nil
+}
+
+func (p *staticProvider) rates(ctx context.Context, date string) (*rateTable, error) {
+	if date == "" {
+		return p.latest, nil
+	}
+	i := sort.SearchStrings(p.dates, date)
+	if i < len(p.dates) && p.dates[i] == date {
+		return p.historical[date], nil
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("no rates for date %v", date)
+	}
+	return p.historical[p.dates[i-1]], nil
+}
+
+//------------------------------------------------------------------------------
+
+// httpProvider fetches rates from an HTTP API, where the latest rates are
+// refreshed periodically and historical rates, which don't change, are cached
+// indefinitely.
+type httpProvider struct {
+	url           string
+	historicalURL string
+	headers       map[string]string
+	mapping       *bloblang.Executor
+	refresh       time.Duration
+	client        *http.Client
+	log           *service.Logger
+
+	mut        sync.Mutex
+	latest     *rateTable
+	fetched    time.Time
+	historical map[string]*rateTable
+}
+
+func httpProviderFromParsed(conf *service.ParsedConfig, log *service.Logger) (*httpProvider, error) {
+	p := &httpProvider{log: log, historical: map[string]*rateTable{}}
+
+	var err error
+	if p.url, err = conf.FieldString(ccFieldHTTPURL); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldHTTPHistoricalURL) {
+		if p.historicalURL, err = conf.FieldString(ccFieldHTTPHistoricalURL); err != nil {
+			return nil, err
+		}
+	}
+	if p.headers, err = conf.FieldStringMap(ccFieldHTTPHeaders); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldHTTPMapping) {
+		if p.mapping, err = conf.FieldBloblang(ccFieldHTTPMapping); err != nil {
+			return nil, err
+		}
+	}
+	timeout, err := conf.FieldDuration(ccFieldHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p.client = &http.Client{Timeout: timeout}
+	if p.refresh, err = conf.FieldDuration(ccFieldHTTPRefreshInterval); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *httpProvider) rates(ctx context.Context, date string) (*rateTable, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if date != "" {
+		if p.historicalURL == "" {
+			return nil, errors.New("historical rates require a historical_url")
+		}
+		if t, exists := p.historical[date]; exists {
+			return t, nil
+		}
+		t, err := p.fetch(ctx, strings.ReplaceAll(p.historicalURL, "{date}", date))
+		if err != nil {
+			return nil, err
+		}
+		if len(p.historical) >= maxHistoricalDates {
+			for k := range p.historical {
+				delete(p.historical, k)
+				break
+			}
+		}
+		p.historical[date] = t
+		return t, nil
+	}
+
+	if p.latest != nil && time.Since(p.fetched) < p.refresh {
+		return p.latest, nil
+	}
+	t, err := p.fetch(ctx, p.url)
+	if err != nil {
+		if p.latest == nil {
+			return nil, err
+		}
+		// Stale rates are better than none, and the refresh is attempted
+		// again on the next lookup.
+		p.log.Warnf("Failed to refresh rates, using rates fetched at %v: %v", p.fetched.Format(time.RFC3339), err)
+		return p.latest, nil
+	}
+	p.latest, p.fetched = t, time.Now()
+	return t, nil
+}
+
+func (p *httpProvider) fetch(ctx context.Context, url string) (*rateTable, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("request to %v returned status %v: %s", url, res.StatusCode, bytes.TrimSpace(body))
+	}
+
+	if p.mapping != nil {
+		var v any
+		if err := json.Unmarshal(body, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		if v, err = p.mapping.Query(v); err != nil {
+			return nil, fmt.Errorf("failed to map response: %w", err)
+		}
+		if body, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return parseRateTable(body)
+}
+
+//------------------------------------------------------------------------------
+
+// cacheProvider reads rates from a cache resource.
+type cacheProvider struct {
+	resource  string
+	keyPrefix string
+	mgr       *service.Resources
+}
+
+func cacheProviderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cacheProvider, error) {
+	p := &cacheProvider{mgr: mgr}
+
+	var err error
+	if p.resource, err = conf.FieldString(ccFieldCacheResource); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.resource) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.resource)
+	}
+	if p.keyPrefix, err = conf.FieldString(ccFieldCacheKeyPrefix); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *cacheProvider) rates(ctx context.Context, date string) (*rateTable, error) {
+	key := p.keyPrefix + "latest"
+	if date != "" {
+		key = p.keyPrefix + date
+	}
+
+	var b []byte
+	var cErr error
+	if err := p.mgr.AccessCache(ctx, p.resource, func(c service.Cache) {
+		b, cErr = c.Get(ctx, key)
+	}); err != nil {
+		return nil, err
+	}
+	if cErr != nil {
+		if errors.Is(cErr, service.ErrKeyNotFound) {
+			return nil, fmt.Errorf("no rates found at key '%v'", key)
+		}
+		return nil, cErr
+	}
+	return parseRateTable(b)
+}
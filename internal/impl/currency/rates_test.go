@@ -0,0 +1,169 @@
+package currency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testRatesFile = `
+base: eur
+rates: { USD: 1.0712, GBP: "0.8537" }
+historical:
+  "2022-06-01": { USD: 1.0711, GBP: 0.8544 }
+  "2022-06-03": { USD: 1.0720, GBP: 0.8550 }
+`
+
+func TestStaticProvider(t *testing.T) {
+	p, err := newStaticProvider([]byte(testRatesFile))
+	require.NoError(t, err)
+
+	tests := []struct {
+		date     string
+		expDate  string
+		expUSD   string
+		errConts string
+	}{
+		{date: "", expUSD: "1.0712"},
+		{date: "2022-06-01", expDate: "2022-06-01", expUSD: "1.0711"},
+		{date: "2022-06-02", expDate: "2022-06-01", expUSD: "1.0711"},
+		{date: "2022-06-10", expDate: "2022-06-03", expUSD: "1.072"},
+		{date: "2022-05-31", errConts: "no rates for date 2022-05-31"},
+	}
+
+	for _, test := range tests {
+		table, err := p.rates(context.Background(), test.date)
+		if test.errConts != "" {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errConts)
+			continue
+		}
+		require.NoError(t, err, test.date)
+		assert.Equal(t, "EUR", table.Base)
+		assert.Equal(t, test.expDate, table.Date)
+
+		usd, err := table.rate("USD")
+		require.NoError(t, err)
+		assert.Equal(t, test.expUSD, usd.String())
+
+		eur, err := table.rate("EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "1", eur.String())
+	}
+}
+
+func TestStaticProviderErrors(t *testing.T) {
+	for _, f := range []string{
+		`not: [valid`,
+		`{}`,
+		`{ rates: { USD: 1 } }`,
+		`{ base: EUR, rates: { USD: 0 } }`,
+		`{ base: EUR, rates: { USD: nope } }`,
+		`{ base: EUR, historical: { yesterday: { USD: 1 } } }`,
+	} {
+		_, err := newStaticProvider([]byte(f))
+		assert.Error(t, err, f)
+	}
+
+	// The latest rates default to those of the last date.
+	p, err := newStaticProvider([]byte(`{ base: EUR, historical: { "2022-06-01": { USD: 1 }, "2022-06-02": { USD: 2 } } }`))
+	require.NoError(t, err)
+	table, err := p.rates(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "2022-06-02", table.Date)
+}
+
+func testHTTPProvider(t *testing.T, conf string) *httpProvider {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(ratesField())
+	pConf, err := spec.ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	p, err := httpProviderFromParsed(pConf.Namespace(ccFieldRates, ccFieldHTTP), service.MockResources().Logger())
+	require.NoError(t, err)
+	return p
+}
+
+func TestHTTPProvider(t *testing.T) {
+	var requests, failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		assert.Equal(t, "Token foo", r.Header.Get("Authorization"))
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "nope", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/latest":
+			_, _ = w.Write([]byte(`{"base_code":"USD","conversion_rates":{"EUR":0.93}}`))
+		case "/history/2022-06-01":
+			_, _ = w.Write([]byte(`{"base_code":"USD","date":"2022-06-01","conversion_rates":{"EUR":0.94}}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	p := testHTTPProvider(t, `
+rates:
+  http:
+    url: `+server.URL+`/latest
+    historical_url: `+server.URL+`/history/{date}
+    headers:
+      Authorization: Token foo
+    mapping: |
+      root.base = this.base_code
+      root.date = this.date
+      root.rates = this.conversion_rates
+    refresh_interval: 1h
+`)
+	ctx := context.Background()
+
+	table, err := p.rates(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", table.Base)
+	assert.Equal(t, "0.93", table.Rates["EUR"].String())
+
+	table, err = p.rates(ctx, "2022-06-01")
+	require.NoError(t, err)
+	assert.Equal(t, "2022-06-01", table.Date)
+	assert.Equal(t, "0.94", table.Rates["EUR"].String())
+
+	// Rates are cached.
+	_, err = p.rates(ctx, "")
+	require.NoError(t, err)
+	_, err = p.rates(ctx, "2022-06-01")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	_, err = p.rates(ctx, "2022-06-02")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "returned status 404: not found")
+
+	// Stale latest rates are used when they fail to refresh.
+	atomic.StoreInt32(&failing, 1)
+	p.fetched = time.Now().Add(-time.Hour * 2)
+	table, err = p.rates(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, "0.93", table.Rates["EUR"].String())
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestHTTPProviderNoHistoricalURL(t *testing.T) {
+	p := testHTTPProvider(t, `
+rates:
+  http:
+    url: http://localhost:1/latest
+`)
+	_, err := p.rates(context.Background(), "2022-06-01")
+	require.EqualError(t, err, "historical rates require a historical_url")
+}
@@ -0,0 +1,216 @@
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ddFieldSite        = "site"
+	ddFieldAPIKey      = "api_key"
+	ddFieldURL         = "url"
+	ddFieldTags        = "tags"
+	ddFieldTagMeta     = "tag_metadata"
+	ddFieldCompress    = "compress"
+	ddFieldBatching    = "batching"
+	ddFieldMaxInFlight = "max_in_flight"
+)
+
+func clientFields(urlDescription string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(ddFieldSite).
+			Description("The [Datadog site](https://docs.datadoghq.com/getting_started/site/) to send data to.").
+			Example("datadoghq.eu").
+			Example("us3.datadoghq.com").
+			Default("datadoghq.com"),
+		service.NewStringField(ddFieldAPIKey).
+			Description("The Datadog API key to authenticate with."),
+		service.NewStringField(ddFieldURL).
+			Description(urlDescription).
+			Advanced().
+			Optional(),
+		service.NewInterpolatedStringMapField(ddFieldTags).
+			Description("A map of tags to add to each message, where the values support interpolation functions.").
+			Example(map[string]any{"env": "prod", "topic": `${! meta("kafka_topic") }`}).
+			Optional(),
+		service.NewMetadataFilterField(ddFieldTagMeta).
+			Description("Specify which metadata keys of each message are added as tags.").
+			Optional(),
+		service.NewBoolField(ddFieldCompress).
+			Description("Whether to compress request bodies with gzip.").
+			Advanced().
+			Default(true),
+	}
+}
+
+// client sends JSON payloads to a Datadog intake API, splitting the entries of
+// a batch into as many requests as are needed to satisfy the limits of the API.
+type client struct {
+	url      string
+	apiKey   string
+	compress bool
+
+	tags    map[string]*service.InterpolatedString
+	tagMeta *service.MetadataFilter
+
+	maxEntries int
+	maxBytes   int
+
+	http *http.Client
+}
+
+func clientFromParsed(conf *service.ParsedConfig, defaultURL string, maxEntries, maxBytes int) (*client, error) {
+	c := &client{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		http:       &http.Client{},
+	}
+
+	var err error
+	if conf.Contains(ddFieldURL) {
+		if c.url, err = conf.FieldString(ddFieldURL); err != nil {
+			return nil, err
+		}
+	} else {
+		site, err := conf.FieldString(ddFieldSite)
+		if err != nil {
+			return nil, err
+		}
+		c.url = fmt.Sprintf(defaultURL, site)
+	}
+	if c.apiKey, err = conf.FieldString(ddFieldAPIKey); err != nil {
+		return nil, err
+	}
+	if c.compress, err = conf.FieldBool(ddFieldCompress); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ddFieldTags) {
+		if c.tags, err = conf.FieldInterpolatedStringMap(ddFieldTags); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(ddFieldTagMeta) {
+		if c.tagMeta, err = conf.FieldMetadataFilter(ddFieldTagMeta); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// tagsFor returns the tags of a message in the form key:value sorted by key.
+func (c *client) tagsFor(msg *service.Message) []string {
+	tags := make([]string, 0, len(c.tags))
+	for k, v := range c.tags {
+		tags = append(tags, k+":"+v.String(msg))
+	}
+	_ = c.tagMeta.Walk(msg, func(k, v string) error {
+		tags = append(tags, k+":"+v)
+		return nil
+	})
+	sort.Strings(tags)
+	return tags
+}
+
+// send writes encoded entries, one per message of a batch, as JSON payloads
+// created by wrap from the raw array of entries. Entries that are nil belong to
+// messages that could not be encoded, and are reported as failed with the
+// errors of batchErr, which may be nil when all messages were encoded. When
+// only some of the messages are sent the returned error indicates which
+// messages were not.
+func (c *client) send(ctx context.Context, batch service.MessageBatch, entries [][]byte, batchErr *service.BatchError, wrap func(array []byte) []byte) error {
+	live := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if e != nil {
+			live = append(live, i)
+		}
+	}
+
+	for start := 0; start < len(live); {
+		end, size := start, 2
+		for end < len(live) && end-start < c.maxEntries {
+			entrySize := len(entries[live[end]]) + 1
+			if end > start && size+entrySize > c.maxBytes {
+				break
+			}
+			size += entrySize
+			end++
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i := start; i < end; i++ {
+			if i > start {
+				buf.WriteByte(',')
+			}
+			buf.Write(entries[live[i]])
+		}
+		buf.WriteByte(']')
+
+		if err := c.post(ctx, wrap(buf.Bytes())); err != nil {
+			if batchErr == nil {
+				if start == 0 && end == len(live) {
+					return err
+				}
+				batchErr = service.NewBatchError(batch, err)
+			}
+			for i := start; i < end; i++ {
+				batchErr.Failed(live[i], err)
+			}
+		}
+		start = end
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (c *client) post(ctx context.Context, body []byte) error {
+	if c.compress {
+		var zBuf bytes.Buffer
+		zw := gzip.NewWriter(&zBuf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = zBuf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("DD-API-KEY", c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if c.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBytes, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (c *client) close() {
+	c.http.CloseIdleConnections()
+}
@@ -0,0 +1,184 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ddlFieldService  = "service"
+	ddlFieldSource   = "source"
+	ddlFieldHostname = "hostname"
+
+	// Limits of the logs intake API, see:
+	// https://docs.datadoghq.com/api/latest/logs/#send-logs
+	ddlMaxEntries = 1000
+	ddlMaxBytes   = 5 * 1024 * 1024
+)
+
+func logsOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sends messages as logs to the [Datadog logs intake API](https://docs.datadoghq.com/api/latest/logs/#send-logs).").
+		Description(`
+Messages that contain a JSON object are sent as structured logs where each field of the object becomes an attribute of the log, with the field ` + "`message`" + ` being the log message itself. All other messages are sent as the message of a log.
+
+The intake API accepts payloads of up to 1000 logs and 5MB, batches that exceed these limits are automatically split into multiple requests, and therefore any batching policy can be used. When only some of the requests of a batch fail then only the messages of the failed requests are sent again.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field ` + "`max_in_flight`" + `.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`)
+
+	for _, f := range clientFields("An optional URL to send logs to instead of the intake API of the `site`.") {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField(ddlFieldService).
+			Description("An optional name of the service that produced each log.").
+			Example("my-app").
+			Optional()).
+		Field(service.NewInterpolatedStringField(ddlFieldSource).
+			Description("An optional source of each log, which determines the integration pipeline used to process it.").
+			Example("nginx").
+			Optional()).
+		Field(service.NewInterpolatedStringField(ddlFieldHostname).
+			Description("An optional hostname of each log.").
+			Example(`${! hostname() }`).
+			Optional()).
+		Field(service.NewBatchPolicyField(ddFieldBatching)).
+		Field(service.NewIntField(ddFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Kafka Logs",
+			"In this example logs consumed from Kafka are sent to Datadog with the topic and partition of each message as tags.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ logs ]
+    consumer_group: benthos_group
+
+output:
+  datadog_logs:
+    api_key: "${DD_API_KEY}"
+    service: my-app
+    tags:
+      env: prod
+    tag_metadata:
+      include_patterns: [ '^kafka_(topic|partition)$' ]
+    batching:
+      count: 1000
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"datadog_logs", logsOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(ddFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(ddFieldBatching); err != nil {
+				return
+			}
+			out, err = newLogsOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type logsOutput struct {
+	client *client
+
+	service  *service.InterpolatedString
+	source   *service.InterpolatedString
+	hostname *service.InterpolatedString
+}
+
+func newLogsOutputFromParsed(conf *service.ParsedConfig) (*logsOutput, error) {
+	l := &logsOutput{}
+
+	var err error
+	if l.client, err = clientFromParsed(conf, "https://http-intake.logs.%v/api/v2/logs", ddlMaxEntries, ddlMaxBytes); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name  string
+		field **service.InterpolatedString
+	}{
+		{ddlFieldService, &l.service},
+		{ddlFieldSource, &l.source},
+		{ddlFieldHostname, &l.hostname},
+	} {
+		if !conf.Contains(f.name) {
+			continue
+		}
+		if *f.field, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (l *logsOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (l *logsOutput) entry(msg *service.Message) ([]byte, error) {
+	var entry map[string]any
+	if structured, err := msg.AsStructuredMut(); err == nil {
+		entry, _ = structured.(map[string]any)
+	}
+	if entry == nil {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		entry = map[string]any{"message": string(mBytes)}
+	}
+
+	if l.service != nil {
+		entry["service"] = l.service.String(msg)
+	}
+	if l.source != nil {
+		entry["ddsource"] = l.source.String(msg)
+	}
+	if l.hostname != nil {
+		entry["hostname"] = l.hostname.String(msg)
+	}
+	if tags := l.client.tagsFor(msg); len(tags) > 0 {
+		entry["ddtags"] = strings.Join(tags, ",")
+	}
+	return json.Marshal(entry)
+}
+
+func (l *logsOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	entries := make([][]byte, len(batch))
+	for i, msg := range batch {
+		var err error
+		if entries[i], err = l.entry(msg); err != nil {
+			return err
+		}
+	}
+	return l.client.send(ctx, batch, entries, nil, func(array []byte) []byte {
+		return array
+	})
+}
+
+func (l *logsOutput) Close(ctx context.Context) error {
+	l.client.close()
+	return nil
+}
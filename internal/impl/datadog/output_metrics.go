@@ -0,0 +1,241 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ddmFieldMetric    = "metric"
+	ddmFieldValue     = "value"
+	ddmFieldType      = "type"
+	ddmFieldInterval  = "interval"
+	ddmFieldTimestamp = "timestamp"
+	ddmFieldHost      = "host"
+
+	// Limits of the metrics intake API, see:
+	// https://docs.datadoghq.com/api/latest/metrics/#submit-metrics
+	ddmMaxBytes = 5 * 1024 * 1024
+)
+
+var ddmTypes = map[string]int{
+	"count": 1,
+	"rate":  2,
+	"gauge": 3,
+}
+
+func metricsOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sends a metric point for each message to the [Datadog metrics intake API](https://docs.datadoghq.com/api/latest/metrics/#submit-metrics).").
+		Description(`
+The name, value and timestamp of each point are resolved from each message with interpolation functions, and messages where the value is not a number are rejected.
+
+The intake API accepts payloads of up to 5MB, batches that exceed this limit are automatically split into multiple requests, and therefore any batching policy can be used. When only some of the requests of a batch fail then only the messages of the failed requests are sent again.`)
+
+	for _, f := range clientFields("An optional URL to send metrics to instead of the intake API of the `site`.") {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField(ddmFieldMetric).
+			Description("The name of the metric of each message.").
+			Example("benthos.orders.total").
+			Example(`${! this.name }`)).
+		Field(service.NewInterpolatedStringField(ddmFieldValue).
+			Description("The value of the metric of each message, which must resolve to a number.").
+			Example(`${! this.value }`)).
+		Field(service.NewStringAnnotatedEnumField(ddmFieldType, map[string]string{
+			"gauge": "The value is the current value of the metric.",
+			"count": "The value is the number of occurrences within the interval.",
+			"rate":  "The value is the number of occurrences per second within the interval.",
+		}).
+			Description("The type of the metric.").
+			Default("gauge")).
+		Field(service.NewIntField(ddmFieldInterval).
+			Description("An optional interval in seconds that each `count` or `rate` value describes.").
+			Example(10).
+			Optional()).
+		Field(service.NewInterpolatedStringField(ddmFieldTimestamp).
+			Description("An optional unix timestamp in seconds of each point, when omitted the time at which the point is written is used.").
+			Example(`${! this.ts }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(ddmFieldHost).
+			Description("An optional host that each point is associated with.").
+			Example(`${! hostname() }`).
+			Optional()).
+		Field(service.NewBatchPolicyField(ddFieldBatching)).
+		Field(service.NewIntField(ddFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Order Totals",
+			"In this example the total of each order is sent as a gauge tagged with the region of the order.",
+			`
+output:
+  datadog_metrics:
+    api_key: "${DD_API_KEY}"
+    metric: orders.total
+    value: ${! this.total }
+    tags:
+      region: ${! this.region }
+    batching:
+      count: 500
+      period: 10s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"datadog_metrics", metricsOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(ddFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(ddFieldBatching); err != nil {
+				return
+			}
+			out, err = newMetricsOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type metricsOutput struct {
+	client *client
+
+	metric    *service.InterpolatedString
+	value     *service.InterpolatedString
+	mType     int
+	interval  int
+	timestamp *service.InterpolatedString
+	host      *service.InterpolatedString
+}
+
+func newMetricsOutputFromParsed(conf *service.ParsedConfig) (*metricsOutput, error) {
+	m := &metricsOutput{}
+
+	var err error
+	if m.client, err = clientFromParsed(conf, "https://api.%v/api/v2/series", math.MaxInt, ddmMaxBytes); err != nil {
+		return nil, err
+	}
+	if m.metric, err = conf.FieldInterpolatedString(ddmFieldMetric); err != nil {
+		return nil, err
+	}
+	if m.value, err = conf.FieldInterpolatedString(ddmFieldValue); err != nil {
+		return nil, err
+	}
+
+	typeStr, err := conf.FieldString(ddmFieldType)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if m.mType, exists = ddmTypes[typeStr]; !exists {
+		return nil, fmt.Errorf("metric type not recognised: %v", typeStr)
+	}
+
+	if conf.Contains(ddmFieldInterval) {
+		if m.interval, err = conf.FieldInt(ddmFieldInterval); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(ddmFieldTimestamp) {
+		if m.timestamp, err = conf.FieldInterpolatedString(ddmFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(ddmFieldHost) {
+		if m.host, err = conf.FieldInterpolatedString(ddmFieldHost); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *metricsOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+type ddmPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type ddmResource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ddmSeries struct {
+	Metric    string        `json:"metric"`
+	Type      int           `json:"type"`
+	Interval  int           `json:"interval,omitempty"`
+	Points    []ddmPoint    `json:"points"`
+	Tags      []string      `json:"tags,omitempty"`
+	Resources []ddmResource `json:"resources,omitempty"`
+}
+
+func (m *metricsOutput) entry(msg *service.Message, now time.Time) ([]byte, error) {
+	value, err := strconv.ParseFloat(m.value.String(msg), 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metric value: %w", err)
+	}
+
+	ts := now.Unix()
+	if m.timestamp != nil {
+		tsFloat, err := strconv.ParseFloat(m.timestamp.String(msg), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse metric timestamp: %w", err)
+		}
+		ts = int64(tsFloat)
+	}
+
+	series := ddmSeries{
+		Metric:   m.metric.String(msg),
+		Type:     m.mType,
+		Interval: m.interval,
+		Points:   []ddmPoint{{Timestamp: ts, Value: value}},
+		Tags:     m.client.tagsFor(msg),
+	}
+	if m.host != nil {
+		series.Resources = []ddmResource{{Name: m.host.String(msg), Type: "host"}}
+	}
+	return json.Marshal(series)
+}
+
+func (m *metricsOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	now := time.Now()
+
+	var batchErr *service.BatchError
+	entries := make([][]byte, len(batch))
+	for i, msg := range batch {
+		var err error
+		if entries[i], err = m.entry(msg, now); err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+		}
+	}
+
+	return m.client.send(ctx, batch, entries, batchErr, func(array []byte) []byte {
+		return append(append([]byte(`{"series":`), array...), '}')
+	})
+}
+
+func (m *metricsOutput) Close(ctx context.Context) error {
+	m.client.close()
+	return nil
+}
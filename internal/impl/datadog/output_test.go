@@ -0,0 +1,202 @@
+package datadog
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testIntake struct {
+	mut      sync.Mutex
+	payloads []json.RawMessage
+	apiKeys  []string
+	failNext int
+}
+
+func (d *testIntake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if d.failNext > 0 {
+		d.failNext--
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.payloads = append(d.payloads, b)
+	d.apiKeys = append(d.apiKeys, r.Header.Get("DD-API-KEY"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestLogsOutput(t *testing.T) {
+	intake := &testIntake{}
+	ts := httptest.NewServer(intake)
+	defer ts.Close()
+
+	conf, err := logsOutputSpec().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+service: my-app
+tags:
+  env: prod
+tag_metadata:
+  include_prefixes: [ kafka_ ]
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLogsOutputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	msgA := service.NewMessage([]byte(`{"message":"hello","level":"info"}`))
+	msgA.MetaSet("kafka_topic", "logs")
+	msgA.MetaSet("other", "ignored")
+	msgB := service.NewMessage([]byte(`plain text`))
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+	require.NoError(t, out.Close(context.Background()))
+
+	intake.mut.Lock()
+	defer intake.mut.Unlock()
+
+	require.Len(t, intake.payloads, 1)
+	assert.Equal(t, []string{"foo"}, intake.apiKeys)
+
+	var logs []map[string]any
+	require.NoError(t, json.Unmarshal(intake.payloads[0], &logs))
+	assert.Equal(t, []map[string]any{
+		{"message": "hello", "level": "info", "service": "my-app", "ddtags": "env:prod,kafka_topic:logs"},
+		{"message": "plain text", "service": "my-app", "ddtags": "env:prod"},
+	}, logs)
+}
+
+func TestLogsOutputSplitsPayloads(t *testing.T) {
+	intake := &testIntake{}
+	ts := httptest.NewServer(intake)
+	defer ts.Close()
+
+	conf, err := logsOutputSpec().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+compress: false
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newLogsOutputFromParsed(conf)
+	require.NoError(t, err)
+	out.client.maxEntries = 2
+	out.client.maxBytes = 60
+
+	var batch service.MessageBatch
+	for _, s := range []string{"a", "b", "c", "a much longer message that fills a payload", "e"} {
+		batch = append(batch, service.NewMessage([]byte(s)))
+	}
+	require.NoError(t, out.WriteBatch(context.Background(), batch))
+
+	intake.mut.Lock()
+	var counts []int
+	for _, p := range intake.payloads {
+		var logs []any
+		require.NoError(t, json.Unmarshal(p, &logs))
+		counts = append(counts, len(logs))
+	}
+	intake.payloads = nil
+	intake.failNext = 1
+	intake.mut.Unlock()
+
+	assert.Equal(t, []int{2, 1, 1, 1}, counts)
+
+	// Only the messages of the failed request are reported as failed.
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{0, 1}, failed)
+}
+
+func TestMetricsOutput(t *testing.T) {
+	intake := &testIntake{}
+	ts := httptest.NewServer(intake)
+	defer ts.Close()
+
+	conf, err := metricsOutputSpec().ParseYAML(`
+api_key: foo
+url: `+ts.URL+`
+metric: orders.total
+value: ${! this.total }
+timestamp: ${! this.ts }
+type: count
+interval: 10
+host: ${! this.host }
+tags:
+  region: ${! this.region }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newMetricsOutputFromParsed(conf)
+	require.NoError(t, err)
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"total":5.5,"ts":1700000000,"host":"foo","region":"eu"}`)),
+		service.NewMessage([]byte(`{"total":"nope","ts":1700000000,"host":"foo","region":"eu"}`)),
+	})
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+
+	intake.mut.Lock()
+	defer intake.mut.Unlock()
+
+	require.Len(t, intake.payloads, 1)
+	assert.JSONEq(t, `{"series":[{
+	"metric":"orders.total",
+	"type":1,
+	"interval":10,
+	"points":[{"timestamp":1700000000,"value":5.5}],
+	"tags":["region:eu"],
+	"resources":[{"name":"foo","type":"host"}]
+}]}`, string(intake.payloads[0]))
+}
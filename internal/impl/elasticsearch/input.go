@@ -0,0 +1,469 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olivere/elastic/v7"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	esiFieldURLs             = "urls"
+	esiFieldIndex            = "index"
+	esiFieldQuery            = "query"
+	esiFieldTimestampField   = "timestamp_field"
+	esiFieldFrom             = "from"
+	esiFieldTo               = "to"
+	esiFieldMode             = "mode"
+	esiFieldPageSize         = "page_size"
+	esiFieldKeepAlive        = "keep_alive"
+	esiFieldSniff            = "sniff"
+	esiFieldHealthcheck      = "healthcheck"
+	esiFieldTimeout          = "timeout"
+	esiFieldTLS              = "tls"
+	esiFieldBasicAuth        = "basic_auth"
+	esiFieldBasicAuthEnabled = "enabled"
+	esiFieldBasicAuthUser    = "username"
+	esiFieldBasicAuthPass    = "password"
+)
+
+func esInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Exports the documents of an Elasticsearch query, optionally within a window of time, creating a message for each document.").
+		Description(`
+The query is executed in pages of up to `+"`page_size`"+` documents, and the source of each document is emitted as a message. Once every document has been read this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute), which makes it suitable for migrating or reprocessing the documents of an index.
+
+When `+"`from`"+` or `+"`to`"+` are set the query is limited to documents where the `+"`timestamp_field`"+` falls within the window, and documents are read in the order of that field.
+
+### Modes
+
+With the `+"`pit`"+` mode, which requires Elasticsearch 7.12 or later, a [point in time](https://www.elastic.co/guide/en/elasticsearch/reference/current/point-in-time-api.html) is opened for the index and pages are obtained with `+"`search_after`"+`. With the `+"`scroll`"+` mode pages are obtained with the [scroll API](https://www.elastic.co/guide/en/elasticsearch/reference/current/paginate-search-results.html#scroll-search-results) instead, which is supported by older versions. In both modes the query sees the documents of the index as they were when the first page was read, and so documents written afterwards are not exported.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- elasticsearch_index
+- elasticsearch_id
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringListField(esiFieldURLs).
+			Description("A list of URLs to connect to. If an item of the list contains commas it will be expanded into multiple URLs.").
+			Example([]string{"http://localhost:9200"})).
+		Field(service.NewStringField(esiFieldIndex).
+			Description("The index, or a comma separated list of indexes, to read documents from.").
+			Example("logs-*")).
+		Field(service.NewStringField(esiFieldQuery).
+			Description("A JSON [query](https://www.elastic.co/guide/en/elasticsearch/reference/current/query-dsl.html) that selects documents.").
+			Example(`{"term":{"level":"error"}}`).
+			Default(`{"match_all":{}}`)).
+		Field(service.NewStringField(esiFieldTimestampField).
+			Description("The field of each document that `from` and `to` are compared with.").
+			Default("@timestamp")).
+		Field(service.NewStringField(esiFieldFrom).
+			Description("An optional [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp of the start of the window, inclusive.").
+			Example("2026-01-02T15:00:00Z").
+			Optional()).
+		Field(service.NewStringField(esiFieldTo).
+			Description("An optional [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp of the end of the window, exclusive.").
+			Example("2026-01-02T16:00:00Z").
+			Optional()).
+		Field(service.NewStringAnnotatedEnumField(esiFieldMode, map[string]string{
+			"pit":    "Read pages from a point in time with `search_after`.",
+			"scroll": "Read pages with the scroll API.",
+		}).
+			Description("The method of reading pages of documents, see [modes](#modes).").
+			Default("pit")).
+		Field(service.NewIntField(esiFieldPageSize).
+			Description("The maximum number of documents to read within each page.").
+			Advanced().
+			Default(1000)).
+		Field(service.NewStringField(esiFieldKeepAlive).
+			Description("The period for which Elasticsearch keeps the point in time or scroll context alive between pages.").
+			Advanced().
+			Default("5m")).
+		Field(service.NewBoolField(esiFieldSniff).
+			Description("Prompts Benthos to sniff for brokers to connect to when establishing a connection.").
+			Advanced().
+			Default(true)).
+		Field(service.NewBoolField(esiFieldHealthcheck).
+			Description("Whether to enable healthchecks.").
+			Advanced().
+			Default(true)).
+		Field(service.NewDurationField(esiFieldTimeout).
+			Description("The maximum time to wait for each page before abandoning the request (and trying again).").
+			Advanced().
+			Default("30s")).
+		Field(service.NewTLSToggledField(esiFieldTLS)).
+		Field(service.NewObjectField(esiFieldBasicAuth,
+			service.NewBoolField(esiFieldBasicAuthEnabled).
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField(esiFieldBasicAuthUser).
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField(esiFieldBasicAuthPass).
+				Description("A password to authenticate with.").
+				Default(""),
+		).
+			Description("Allows you to specify basic authentication.").
+			Advanced()).
+		Example(
+			"Migrating an Index",
+			"In this example the error logs of a one hour window are copied from one cluster to another.",
+			`
+input:
+  elasticsearch:
+    urls: [ http://old-cluster:9200 ]
+    index: logs
+    query: '{"term":{"level":"error"}}'
+    from: 2026-01-02T15:00:00Z
+    to: 2026-01-02T16:00:00Z
+
+output:
+  elasticsearch:
+    urls: [ http://new-cluster:9200 ]
+    index: ${! meta("elasticsearch_index") }
+    id: ${! meta("elasticsearch_id") }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"elasticsearch", esInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newESInputFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type esInput struct {
+	opts      []elastic.ClientOptionFunc
+	indexes   []string
+	query     elastic.Query
+	sorters   []elastic.Sorter
+	scroll    bool
+	pageSize  int
+	keepAlive string
+	timeout   time.Duration
+
+	mut    sync.Mutex
+	client *elastic.Client
+	done   bool
+	page   []*elastic.SearchHit
+
+	pitID       string
+	searchAfter []any
+	scroller    *elastic.ScrollService
+}
+
+func newESInputFromParsed(conf *service.ParsedConfig) (*esInput, error) {
+	e := &esInput{}
+
+	urlStrs, err := conf.FieldStringList(esiFieldURLs)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, u := range urlStrs {
+		for _, splitURL := range strings.Split(u, ",") {
+			if len(splitURL) > 0 {
+				urls = append(urls, splitURL)
+			}
+		}
+	}
+
+	indexStr, err := conf.FieldString(esiFieldIndex)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range strings.Split(indexStr, ",") {
+		if index = strings.TrimSpace(index); index != "" {
+			e.indexes = append(e.indexes, index)
+		}
+	}
+	if len(e.indexes) == 0 {
+		return nil, errors.New("an index must be specified")
+	}
+
+	queryStr, err := conf.FieldString(esiFieldQuery)
+	if err != nil {
+		return nil, err
+	}
+	tsField, err := conf.FieldString(esiFieldTimestampField)
+	if err != nil {
+		return nil, err
+	}
+	e.query = elastic.NewRawStringQuery(queryStr)
+	if conf.Contains(esiFieldFrom) || conf.Contains(esiFieldTo) {
+		rangeQuery := elastic.NewRangeQuery(tsField).Format("strict_date_optional_time")
+		var from, to time.Time
+		if conf.Contains(esiFieldFrom) {
+			if from, err = parseWindowTime(conf, esiFieldFrom); err != nil {
+				return nil, err
+			}
+			rangeQuery = rangeQuery.Gte(from.Format(time.RFC3339Nano))
+		}
+		if conf.Contains(esiFieldTo) {
+			if to, err = parseWindowTime(conf, esiFieldTo); err != nil {
+				return nil, err
+			}
+			rangeQuery = rangeQuery.Lt(to.Format(time.RFC3339Nano))
+		}
+		if !from.IsZero() && !to.IsZero() && !to.After(from) {
+			return nil, errors.New("to must be after from")
+		}
+		e.query = elastic.NewBoolQuery().Must(e.query).Filter(rangeQuery)
+		e.sorters = append(e.sorters, elastic.NewFieldSort(tsField).Asc())
+	}
+
+	mode, err := conf.FieldString(esiFieldMode)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "pit":
+		// Search after requires a total ordering of documents.
+		e.sorters = append(e.sorters, elastic.NewFieldSort("_shard_doc").Asc())
+	case "scroll":
+		e.scroll = true
+		if len(e.sorters) == 0 {
+			e.sorters = append(e.sorters, elastic.NewFieldSort("_doc"))
+		}
+	default:
+		return nil, fmt.Errorf("mode not recognised: %v", mode)
+	}
+
+	if e.pageSize, err = conf.FieldInt(esiFieldPageSize); err != nil {
+		return nil, err
+	}
+	if e.pageSize <= 0 {
+		return nil, errors.New("page_size must be greater than zero")
+	}
+	if e.keepAlive, err = conf.FieldString(esiFieldKeepAlive); err != nil {
+		return nil, err
+	}
+	if e.timeout, err = conf.FieldDuration(esiFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	sniff, err := conf.FieldBool(esiFieldSniff)
+	if err != nil {
+		return nil, err
+	}
+	healthcheck, err := conf.FieldBool(esiFieldHealthcheck)
+	if err != nil {
+		return nil, err
+	}
+	e.opts = []elastic.ClientOptionFunc{
+		elastic.SetURL(urls...),
+		elastic.SetSniff(sniff),
+		elastic.SetHealthcheck(healthcheck),
+	}
+
+	authConf := conf.Namespace(esiFieldBasicAuth)
+	authEnabled, err := authConf.FieldBool(esiFieldBasicAuthEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if authEnabled {
+		username, err := authConf.FieldString(esiFieldBasicAuthUser)
+		if err != nil {
+			return nil, err
+		}
+		password, err := authConf.FieldString(esiFieldBasicAuthPass)
+		if err != nil {
+			return nil, err
+		}
+		e.opts = append(e.opts, elastic.SetBasicAuth(username, password))
+	}
+
+	httpClient := &http.Client{Timeout: e.timeout}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(esiFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConf}
+	}
+	e.opts = append(e.opts, elastic.SetHttpClient(httpClient))
+	return e, nil
+}
+
+func parseWindowTime(conf *service.ParsedConfig, field string) (time.Time, error) {
+	s, err := conf.FieldString(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse %v: %w", field, err)
+	}
+	return t, nil
+}
+
+func (e *esInput) Connect(ctx context.Context) error {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	if e.client != nil {
+		return nil
+	}
+	client, err := elastic.NewClient(e.opts...)
+	if err != nil {
+		return err
+	}
+	e.client = client
+	return nil
+}
+
+// fetch obtains the next page of documents, returning false when there are
+// none remaining.
+func (e *esInput) fetch(ctx context.Context) (bool, error) {
+	for len(e.page) == 0 {
+		if e.done {
+			return false, nil
+		}
+
+		ctx, done := context.WithTimeout(ctx, e.timeout)
+		var hits []*elastic.SearchHit
+		var err error
+		if e.scroll {
+			hits, err = e.nextScroll(ctx)
+		} else {
+			hits, err = e.nextPIT(ctx)
+		}
+		done()
+		if err != nil {
+			return false, err
+		}
+		if len(hits) < e.pageSize {
+			e.done = true
+		}
+		e.page = hits
+	}
+	return true, nil
+}
+
+func (e *esInput) nextScroll(ctx context.Context) ([]*elastic.SearchHit, error) {
+	if e.scroller == nil {
+		e.scroller = e.client.Scroll(e.indexes...).
+			Query(e.query).
+			SortBy(e.sorters...).
+			Size(e.pageSize).
+			KeepAlive(e.keepAlive)
+	}
+	res, err := e.scroller.Do(ctx)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Hits == nil {
+		return nil, nil
+	}
+	return res.Hits.Hits, nil
+}
+
+func (e *esInput) nextPIT(ctx context.Context) ([]*elastic.SearchHit, error) {
+	if e.pitID == "" {
+		res, err := e.client.OpenPointInTime(e.indexes...).KeepAlive(e.keepAlive).Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open point in time: %w", err)
+		}
+		e.pitID = res.Id
+	}
+
+	search := e.client.Search().
+		PointInTime(elastic.NewPointInTimeWithKeepAlive(e.pitID, e.keepAlive)).
+		Query(e.query).
+		SortBy(e.sorters...).
+		Size(e.pageSize)
+	if len(e.searchAfter) > 0 {
+		search = search.SearchAfter(e.searchAfter...)
+	}
+
+	res, err := search.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if res.PitId != "" {
+		e.pitID = res.PitId
+	}
+	if res.Hits == nil || len(res.Hits.Hits) == 0 {
+		return nil, nil
+	}
+	hits := res.Hits.Hits
+	e.searchAfter = hits[len(hits)-1].Sort
+	return hits, nil
+}
+
+func (e *esInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	if e.client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	ok, err := e.fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, service.ErrEndOfInput
+	}
+
+	hit := e.page[0]
+	e.page = e.page[1:]
+
+	msg := service.NewMessage(hit.Source)
+	msg.MetaSet("elasticsearch_index", hit.Index)
+	msg.MetaSet("elasticsearch_id", hit.Id)
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (e *esInput) Close(ctx context.Context) error {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	if e.client == nil {
+		return nil
+	}
+
+	var err error
+	if e.scroller != nil {
+		err = e.scroller.Clear(ctx)
+		e.scroller = nil
+	}
+	if e.pitID != "" {
+		_, err = e.client.ClosePointInTime(e.pitID).Do(ctx)
+		e.pitID = ""
+	}
+	e.client.Stop()
+	e.client = nil
+	return err
+}
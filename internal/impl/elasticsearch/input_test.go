@@ -0,0 +1,194 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testSearchServer serves pages of documents with either the point in time or
+// scroll APIs.
+type testSearchServer struct {
+	t    *testing.T
+	docs []string
+
+	mut      sync.Mutex
+	bodies   []map[string]any
+	closed   []string
+	scrolled int
+}
+
+func (s *testSearchServer) hits(from, n int) []any {
+	hits := []any{}
+	for i := from; i < from+n && i < len(s.docs); i++ {
+		hits = append(hits, map[string]any{
+			"_index":  "logs",
+			"_id":     fmt.Sprintf("id%v", i),
+			"_source": json.RawMessage(s.docs[i]),
+			"sort":    []any{i},
+		})
+	}
+	return hits
+}
+
+func (s *testSearchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	reply := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/logs/_pit":
+		assert.Equal(s.t, "5m", r.URL.Query().Get("keep_alive"))
+		reply(map[string]any{"id": "pit1"})
+
+	case r.Method == http.MethodDelete && r.URL.Path == "/_pit":
+		s.closed = append(s.closed, body["id"].(string))
+		reply(map[string]any{"succeeded": true, "num_freed": 1})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_search":
+		s.bodies = append(s.bodies, body)
+		from := 0
+		if after, ok := body["search_after"].([]any); ok {
+			from = int(after[0].(float64)) + 1
+		}
+		size := int(body["size"].(float64))
+		reply(map[string]any{"pit_id": "pit1", "hits": map[string]any{"hits": s.hits(from, size)}})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/logs/_search":
+		s.bodies = append(s.bodies, body)
+		s.scrolled = 2
+		reply(map[string]any{"_scroll_id": "scroll1", "hits": map[string]any{"hits": s.hits(0, 2)}})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/_search/scroll":
+		assert.Equal(s.t, "scroll1", body["scroll_id"])
+		hits := s.hits(s.scrolled, 2)
+		s.scrolled += 2
+		reply(map[string]any{"_scroll_id": "scroll1", "hits": map[string]any{"hits": hits}})
+
+	case r.Method == http.MethodDelete && r.URL.Path == "/_search/scroll":
+		s.closed = append(s.closed, "scroll1")
+		reply(map[string]any{"succeeded": true})
+
+	default:
+		s.t.Errorf("unexpected request: %v %v", r.Method, r.URL.Path)
+		http.Error(w, "nope", http.StatusNotFound)
+	}
+}
+
+func readAllES(t *testing.T, in *esInput) (docs, ids []string) {
+	t.Helper()
+
+	require.NoError(t, in.Connect(context.Background()))
+	for {
+		msg, ackFn, err := in.Read(context.Background())
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, ackFn(context.Background(), nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(b))
+
+		id, _ := msg.MetaGet("elasticsearch_id")
+		ids = append(ids, id)
+	}
+	require.NoError(t, in.Close(context.Background()))
+	return
+}
+
+func TestElasticsearchInputPIT(t *testing.T) {
+	srv := &testSearchServer{t: t, docs: []string{`{"n":0}`, `{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := esInputSpec().ParseYAML(`
+urls: [ `+ts.URL+` ]
+index: logs
+query: '{"term":{"level":"error"}}'
+from: 2026-01-02T15:00:00Z
+to: 2026-01-02T16:00:00Z
+page_size: 2
+sniff: false
+healthcheck: false
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newESInputFromParsed(conf)
+	require.NoError(t, err)
+
+	docs, ids := readAllES(t, in)
+	assert.Equal(t, srv.docs, docs)
+	assert.Equal(t, []string{"id0", "id1", "id2", "id3", "id4"}, ids)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, []string{"pit1"}, srv.closed)
+	require.Len(t, srv.bodies, 3)
+
+	first, err := json.Marshal(srv.bodies[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+	"pit":{"id":"pit1","keep_alive":"5m"},
+	"size":2,
+	"query":{"bool":{
+		"must":{"term":{"level":"error"}},
+		"filter":{"range":{"@timestamp":{
+			"format":"strict_date_optional_time",
+			"from":"2026-01-02T15:00:00Z","include_lower":true,
+			"to":"2026-01-02T16:00:00Z","include_upper":false
+		}}}
+	}},
+	"sort":[{"@timestamp":{"order":"asc"}},{"_shard_doc":{"order":"asc"}}]
+}`, string(first))
+	assert.Equal(t, []any{float64(1)}, srv.bodies[1]["search_after"])
+}
+
+func TestElasticsearchInputScroll(t *testing.T) {
+	srv := &testSearchServer{t: t, docs: []string{`{"n":0}`, `{"n":1}`, `{"n":2}`}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := esInputSpec().ParseYAML(`
+urls: [ `+ts.URL+` ]
+index: logs
+mode: scroll
+page_size: 2
+sniff: false
+healthcheck: false
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newESInputFromParsed(conf)
+	require.NoError(t, err)
+
+	docs, ids := readAllES(t, in)
+	assert.Equal(t, srv.docs, docs)
+	assert.Equal(t, []string{"id0", "id1", "id2"}, ids)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, []string{"scroll1"}, srv.closed)
+	require.Len(t, srv.bodies, 1)
+	assert.Equal(t, map[string]any{"match_all": map[string]any{}}, srv.bodies[0]["query"])
+}
@@ -0,0 +1,295 @@
+package ftp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ftpConnConfig contains everything required in order to establish and log
+// into an FTP control connection.
+type ftpConnConfig struct {
+	address     string
+	username    string
+	password    string
+	tlsConf     *tls.Config
+	implicitTLS bool
+	timeout     time.Duration
+}
+
+// ftpConn is a logged in control connection to an FTP server, which supports
+// the small subset of commands required in order to upload files.
+type ftpConn struct {
+	conf ftpConnConfig
+	host string
+	conn net.Conn
+	text *textproto.Conn
+}
+
+func dialFTP(ctx context.Context, conf ftpConnConfig) (*ftpConn, error) {
+	host, _, err := net.SplitHostPort(conf.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: conf.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", conf.address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ftpConn{conf: conf, host: host, conn: conn}
+	if conf.tlsConf != nil && conf.implicitTLS {
+		if err := c.startTLS(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	c.text = textproto.NewConn(c.conn)
+
+	if err := c.login(ctx); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *ftpConn) startTLS(ctx context.Context) error {
+	tlsConn := tls.Client(c.conn, c.dataTLSConfig())
+	hsCtx, done := context.WithTimeout(ctx, c.conf.timeout)
+	defer done()
+	if err := tlsConn.HandshakeContext(hsCtx); err != nil {
+		return fmt.Errorf("tls handshake: %w", err)
+	}
+	c.conn = tlsConn
+	return nil
+}
+
+// dataTLSConfig returns the TLS config for both the control connection and
+// data connections. Data connections must share the server name of the control
+// connection as many servers require data connections to resume its session.
+func (c *ftpConn) dataTLSConfig() *tls.Config {
+	conf := c.conf.tlsConf
+	if conf.ServerName == "" {
+		conf = conf.Clone()
+		conf.ServerName = c.host
+	}
+	return conf
+}
+
+func (c *ftpConn) login(ctx context.Context) error {
+	if err := c.readResponse(2); err != nil {
+		return fmt.Errorf("server greeting: %w", err)
+	}
+
+	if c.conf.tlsConf != nil && !c.conf.implicitTLS {
+		if _, err := c.cmd(2, "AUTH TLS"); err != nil {
+			return fmt.Errorf("failed to negotiate tls: %w", err)
+		}
+		if err := c.startTLS(ctx); err != nil {
+			return err
+		}
+		c.text = textproto.NewConn(c.conn)
+	}
+
+	code, err := c.cmd(0, "USER %s", c.conf.username)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	switch {
+	case code == 331:
+		if _, err := c.cmd(2, "PASS %s", c.conf.password); err != nil {
+			return fmt.Errorf("login: %w", err)
+		}
+	case code/100 != 2:
+		return fmt.Errorf("login: unexpected response code %v", code)
+	}
+
+	if c.conf.tlsConf != nil {
+		if _, err := c.cmd(2, "PBSZ 0"); err != nil {
+			return err
+		}
+		if _, err := c.cmd(2, "PROT P"); err != nil {
+			return fmt.Errorf("failed to enable data connection protection: %w", err)
+		}
+	}
+	_, err = c.cmd(2, "TYPE I")
+	return err
+}
+
+func (c *ftpConn) readResponse(expectCode int) error {
+	_ = c.conn.SetDeadline(time.Now().Add(c.conf.timeout))
+	_, _, err := c.text.ReadResponse(expectCode)
+	return err
+}
+
+// cmd sends a command and reads its response, returning an error when the
+// response code does not begin with expectCode. When expectCode is 0 any
+// response is accepted.
+func (c *ftpConn) cmd(expectCode int, format string, args ...any) (int, error) {
+	_ = c.conn.SetDeadline(time.Now().Add(c.conf.timeout))
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return 0, err
+	}
+	code, _, err := c.text.ReadResponse(expectCode)
+	return code, err
+}
+
+func (c *ftpConn) cmdMsg(expectCode int, format string, args ...any) (string, error) {
+	_ = c.conn.SetDeadline(time.Now().Add(c.conf.timeout))
+	if _, err := c.text.Cmd(format, args...); err != nil {
+		return "", err
+	}
+	_, msg, err := c.text.ReadResponse(expectCode)
+	return msg, err
+}
+
+// parseEPSV parses the port from an extended passive mode response of the
+// form "Entering Extended Passive Mode (|||port|)".
+func parseEPSV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid extended passive mode response: %v", msg)
+	}
+	parts := strings.Split(msg[start+1:end], "|")
+	if len(parts) != 5 {
+		return 0, fmt.Errorf("invalid extended passive mode response: %v", msg)
+	}
+	return strconv.Atoi(parts[3])
+}
+
+// parsePASV parses the port from a passive mode response of the form
+// "Entering Passive Mode (h1,h2,h3,h4,p1,p2)". The host is ignored in favour
+// of the host of the control connection, as servers behind NAT commonly
+// advertise a private address.
+func parsePASV(msg string) (int, error) {
+	start, end := strings.Index(msg, "("), strings.LastIndex(msg, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("invalid passive mode response: %v", msg)
+	}
+	parts := strings.Split(msg[start+1:end], ",")
+	if len(parts) != 6 {
+		return 0, fmt.Errorf("invalid passive mode response: %v", msg)
+	}
+	p1, err := strconv.Atoi(parts[4])
+	if err != nil {
+		return 0, err
+	}
+	p2, err := strconv.Atoi(parts[5])
+	if err != nil {
+		return 0, err
+	}
+	return p1<<8 | p2, nil
+}
+
+// openData opens a passive mode data connection, preferring extended passive
+// mode and falling back to passive mode for servers that do not support it.
+func (c *ftpConn) openData(ctx context.Context) (net.Conn, error) {
+	var port int
+	msg, err := c.cmdMsg(2, "EPSV")
+	if err == nil {
+		port, err = parseEPSV(msg)
+	} else {
+		var tErr *textproto.Error
+		if !errors.As(err, &tErr) {
+			return nil, err
+		}
+		if msg, err = c.cmdMsg(2, "PASV"); err == nil {
+			port, err = parsePASV(msg)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to enter passive mode: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: c.conf.timeout}
+	return dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.host, strconv.Itoa(port)))
+}
+
+// store uploads the contents of r to a file at a path.
+func (c *ftpConn) store(ctx context.Context, filePath string, r io.Reader) error {
+	data, err := c.openData(ctx)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+
+	if _, err := c.cmd(1, "STOR %s", filePath); err != nil {
+		return err
+	}
+
+	if c.conf.tlsConf != nil {
+		tlsData := tls.Client(data, c.dataTLSConfig())
+		hsCtx, done := context.WithTimeout(ctx, c.conf.timeout)
+		err := tlsData.HandshakeContext(hsCtx)
+		done()
+		if err != nil {
+			return fmt.Errorf("data connection tls handshake: %w", err)
+		}
+		data = tlsData
+	}
+
+	_ = data.SetDeadline(time.Now().Add(c.conf.timeout))
+	if _, err := io.Copy(data, r); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return c.readResponse(2)
+}
+
+// rename moves a file from one path to another, replacing any existing file.
+func (c *ftpConn) rename(from, to string) error {
+	if _, err := c.cmd(3, "RNFR %s", from); err != nil {
+		return err
+	}
+	_, err := c.cmd(2, "RNTO %s", to)
+	return err
+}
+
+func (c *ftpConn) delete(filePath string) error {
+	_, err := c.cmd(2, "DELE %s", filePath)
+	return err
+}
+
+// mkdirAll creates a directory and any of its parents that do not exist.
+// Servers reject attempts to create directories that already exist, and
+// therefore errors are ignored and left to surface from subsequent commands.
+func (c *ftpConn) mkdirAll(dir string) error {
+	dir = path.Clean(dir)
+	if dir == "." || dir == "/" {
+		return nil
+	}
+
+	var current string
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+	for _, seg := range strings.Split(strings.Trim(dir, "/"), "/") {
+		current = path.Join(current, seg)
+		if _, err := c.cmd(0, "MKD %s", current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// noop checks that the connection is still alive.
+func (c *ftpConn) noop() error {
+	_, err := c.cmd(2, "NOOP")
+	return err
+}
+
+func (c *ftpConn) close() error {
+	_, _ = c.cmd(0, "QUIT")
+	return c.conn.Close()
+}
@@ -0,0 +1,321 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"path"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	foFieldAddress     = "address"
+	foFieldUsername    = "username"
+	foFieldPassword    = "password"
+	foFieldPath        = "path"
+	foFieldTLS         = "tls"
+	foFieldImplicitTLS = "implicit_tls"
+	foFieldAtomic      = "atomic_upload"
+	foFieldCreateDirs  = "create_dirs"
+	foFieldTimeout     = "timeout"
+	foFieldMaxInFlight = "max_in_flight"
+)
+
+func ftpOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Writes files to a server over FTP, or FTPS when TLS is enabled.").
+		Description(`
+Each message is written as a file to the path resolved from `+"`path`"+`, replacing any existing file at that path. In order to have a different path for each file you should use function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).
+
+When `+"`atomic_upload`"+` is enabled each file is first uploaded to a temporary file within the same directory, which is hidden by a leading dot and has the suffix `+"`.tmp`"+`, and is then renamed to its path once the upload completes. This prevents consumers of the directory from reading files that have only been partially uploaded, which is only guaranteed when they ignore hidden or `+"`.tmp`"+` files.
+
+### TLS
+
+When `+"`tls`"+` is enabled the connection is upgraded to TLS with the `+"`AUTH TLS`"+` command (explicit FTPS), and the transfers of files are also protected with TLS. Servers that instead expect TLS from the start of connections (implicit FTPS), which are usually listening on port 990, require `+"`implicit_tls`"+` to be enabled.
+
+### Connections
+
+Files are transferred in passive mode, where the host of data connections is always the host of `+"`address`"+` rather than the host advertised by the server, as servers behind NAT commonly advertise a private address.
+
+Connections are pooled and reused across files, and the number of connections open at a given time is limited by `+"`max_in_flight`"+`. Servers commonly limit the number of connections of each user, in which case `+"`max_in_flight`"+` should not exceed that limit.`).
+		Field(service.NewStringField(foFieldAddress).
+			Description("The address of the server to connect to, where the port defaults to 21, or 990 when `implicit_tls` is enabled.").
+			Example("ftp.example.com:21")).
+		Field(service.NewStringField(foFieldUsername).
+			Description("The username to log into the server with.").
+			Default("anonymous")).
+		Field(service.NewStringField(foFieldPassword).
+			Description("The password to log into the server with.").
+			Default("")).
+		Field(service.NewInterpolatedStringField(foFieldPath).
+			Description("The path of each file to write to the server.").
+			Example(`/drops/${! timestamp_unix_nano() }.json`).
+			Example(`/orders/${! meta("kafka_key") }.csv`)).
+		Field(service.NewTLSToggledField(foFieldTLS)).
+		Field(service.NewBoolField(foFieldImplicitTLS).
+			Description("Whether connections begin with TLS (implicit FTPS) rather than being upgraded to TLS. Only takes effect when TLS is enabled.").
+			Advanced().
+			Default(false)).
+		Field(service.NewBoolField(foFieldAtomic).
+			Description("Whether to upload files to a temporary file before renaming it to the path of the file.").
+			Default(true)).
+		Field(service.NewBoolField(foFieldCreateDirs).
+			Description("Whether to create the parent directories of files when they do not exist.").
+			Advanced().
+			Default(true)).
+		Field(service.NewDurationField(foFieldTimeout).
+			Description("The maximum period to wait for a response from the server, or for a connection to be established.").
+			Advanced().
+			Default("30s")).
+		Field(service.NewIntField(foFieldMaxInFlight).
+			Description("The maximum number of files to upload in parallel, which is also the maximum number of connections to the server.").
+			Default(4)).
+		Example(
+			"Partner Drops",
+			"In this example documents are uploaded as files to an FTPS server, where a partner only collects files once they have been fully uploaded.",
+			`
+output:
+  ftp:
+    address: ftp.partner.example.com:21
+    username: benthos
+    password: "${FTP_PASSWORD}"
+    path: /inbound/${! this.order_id }.json
+    tls:
+      enabled: true
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"ftp", ftpOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(foFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newFTPOutputFromParsed(conf, maxInFlight, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ftpOutput struct {
+	connConf   ftpConnConfig
+	path       *service.InterpolatedString
+	atomic     bool
+	createDirs bool
+	log        *service.Logger
+
+	poolMut sync.Mutex
+	idle    []*ftpConn
+	maxIdle int
+	open    bool
+}
+
+func newFTPOutputFromParsed(conf *service.ParsedConfig, maxInFlight int, log *service.Logger) (*ftpOutput, error) {
+	f := &ftpOutput{log: log, maxIdle: maxInFlight}
+
+	var err error
+	if f.connConf.address, err = conf.FieldString(foFieldAddress); err != nil {
+		return nil, err
+	}
+	if f.connConf.username, err = conf.FieldString(foFieldUsername); err != nil {
+		return nil, err
+	}
+	if f.connConf.password, err = conf.FieldString(foFieldPassword); err != nil {
+		return nil, err
+	}
+	if f.connConf.timeout, err = conf.FieldDuration(foFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(foFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if tlsConf.ClientSessionCache == nil {
+			tlsConf.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+		}
+		f.connConf.tlsConf = tlsConf
+		if f.connConf.implicitTLS, err = conf.FieldBool(foFieldImplicitTLS); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(f.connConf.address); err != nil {
+		port := "21"
+		if f.connConf.implicitTLS {
+			port = "990"
+		}
+		f.connConf.address = net.JoinHostPort(f.connConf.address, port)
+	}
+
+	if f.path, err = conf.FieldInterpolatedString(foFieldPath); err != nil {
+		return nil, err
+	}
+	if f.atomic, err = conf.FieldBool(foFieldAtomic); err != nil {
+		return nil, err
+	}
+	if f.createDirs, err = conf.FieldBool(foFieldCreateDirs); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *ftpOutput) Connect(ctx context.Context) error {
+	f.poolMut.Lock()
+	defer f.poolMut.Unlock()
+
+	if f.open {
+		return nil
+	}
+
+	c, err := dialFTP(ctx, f.connConf)
+	if err != nil {
+		return err
+	}
+	f.idle = append(f.idle, c)
+	f.open = true
+	f.log.Infof("Writing files to FTP server: %v", f.connConf.address)
+	return nil
+}
+
+// acquire takes an idle connection from the pool, or establishes a new
+// connection when there are none. Idle connections are checked before they
+// are reused as servers close connections that have been idle for too long.
+func (f *ftpOutput) acquire(ctx context.Context) (*ftpConn, error) {
+	for {
+		f.poolMut.Lock()
+		if !f.open {
+			f.poolMut.Unlock()
+			return nil, service.ErrNotConnected
+		}
+		var c *ftpConn
+		if n := len(f.idle); n > 0 {
+			c = f.idle[n-1]
+			f.idle = f.idle[:n-1]
+		}
+		f.poolMut.Unlock()
+
+		if c == nil {
+			return dialFTP(ctx, f.connConf)
+		}
+		if err := c.noop(); err != nil {
+			f.log.Debugf("Dropping idle FTP connection: %v", err)
+			_ = c.conn.Close()
+			continue
+		}
+		return c, nil
+	}
+}
+
+// release returns a connection to the pool, unless the error it last
+// encountered was not a response from the server, in which case its state is
+// unknown and it is closed instead.
+func (f *ftpOutput) release(c *ftpConn, err error) {
+	var tErr *textproto.Error
+	if err == nil || errors.As(err, &tErr) {
+		f.poolMut.Lock()
+		if f.open && len(f.idle) < f.maxIdle {
+			f.idle = append(f.idle, c)
+			c = nil
+		}
+		f.poolMut.Unlock()
+	}
+	if c != nil {
+		_ = c.close()
+	}
+}
+
+func ftpTempPath(filePath string) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	dir, base := path.Split(filePath)
+	return dir + "." + base + "." + hex.EncodeToString(b) + ".tmp", nil
+}
+
+func (f *ftpOutput) upload(ctx context.Context, c *ftpConn, filePath string, data []byte) error {
+	if f.createDirs {
+		if err := c.mkdirAll(path.Dir(filePath)); err != nil {
+			return err
+		}
+	}
+	if !f.atomic {
+		return c.store(ctx, filePath, bytes.NewReader(data))
+	}
+
+	tmpPath, err := ftpTempPath(filePath)
+	if err != nil {
+		return err
+	}
+	if err := c.store(ctx, tmpPath, bytes.NewReader(data)); err != nil {
+		var tErr *textproto.Error
+		if errors.As(err, &tErr) {
+			_ = c.delete(tmpPath)
+		}
+		return err
+	}
+	if err := c.rename(tmpPath, filePath); err != nil {
+		var tErr *textproto.Error
+		if !errors.As(err, &tErr) {
+			return err
+		}
+		// Some servers refuse to rename a file over an existing file, in which
+		// case the existing file is removed first.
+		_ = c.delete(filePath)
+		if err = c.rename(tmpPath, filePath); err != nil {
+			_ = c.delete(tmpPath)
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *ftpOutput) Write(ctx context.Context, msg *service.Message) error {
+	filePath := f.path.String(msg)
+	if filePath == "" || path.Base(filePath) == "/" {
+		return fmt.Errorf("path resolved to an invalid file path: '%v'", filePath)
+	}
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	c, err := f.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = f.upload(ctx, c, filePath, data)
+	f.release(c, err)
+	return err
+}
+
+func (f *ftpOutput) Close(ctx context.Context) error {
+	f.poolMut.Lock()
+	idle := f.idle
+	f.idle = nil
+	f.open = false
+	f.poolMut.Unlock()
+
+	for _, c := range idle {
+		_ = c.close()
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package ftp
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testFTPServer is a minimal FTP server that stores uploaded files in memory.
+type testFTPServer struct {
+	t       *testing.T
+	lis     net.Listener
+	tlsConf *tls.Config
+
+	mut      sync.Mutex
+	files    map[string]string
+	dirs     map[string]bool
+	commands []string
+	logins   int
+}
+
+func startTestFTPServer(t *testing.T, withTLS bool) *testFTPServer {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	s := &testFTPServer{
+		t:     t,
+		lis:   lis,
+		files: map[string]string{},
+		dirs:  map[string]bool{},
+	}
+	if withTLS {
+		s.tlsConf = testTLSConfig(t)
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
+
+func (s *testFTPServer) addr() string {
+	return s.lis.Addr().String()
+}
+
+func (s *testFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+
+	var dataLis net.Listener
+	var renameFrom string
+	protected := false
+
+	reply("220 hello")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		s.mut.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mut.Unlock()
+
+		switch cmd {
+		case "AUTH":
+			if s.tlsConf == nil {
+				reply("502 no tls")
+				continue
+			}
+			reply("234 ok")
+			tlsConn := tls.Server(conn, s.tlsConf)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			if arg != "bar" {
+				reply("530 nope")
+				continue
+			}
+			s.mut.Lock()
+			s.logins++
+			s.mut.Unlock()
+			reply("230 logged in")
+		case "PBSZ":
+			reply("200 ok")
+		case "PROT":
+			protected = arg == "P"
+			reply("200 ok")
+		case "TYPE", "NOOP":
+			reply("200 ok")
+		case "EPSV":
+			// Reject extended passive mode in order to test the fallback.
+			reply("500 what")
+		case "PASV":
+			if dataLis, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 nope")
+				continue
+			}
+			port := dataLis.Addr().(*net.TCPAddr).Port
+			reply("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&0xff)
+		case "STOR":
+			if dataLis == nil {
+				reply("425 no data connection")
+				continue
+			}
+			data, err := dataLis.Accept()
+			dataLis.Close()
+			dataLis = nil
+			if err != nil {
+				reply("425 no data connection")
+				continue
+			}
+			if strings.Contains(arg, "forbidden") {
+				data.Close()
+				reply("553 not allowed")
+				continue
+			}
+			reply("150 go ahead")
+			if protected {
+				data = tls.Server(data, s.tlsConf)
+			}
+			b, err := io.ReadAll(data)
+			data.Close()
+			if err != nil {
+				reply("426 broken")
+				continue
+			}
+			s.mut.Lock()
+			s.files[arg] = string(b)
+			s.mut.Unlock()
+			reply("226 done")
+		case "MKD":
+			s.mut.Lock()
+			exists := s.dirs[arg]
+			s.dirs[arg] = true
+			s.mut.Unlock()
+			if exists {
+				reply("550 exists")
+				continue
+			}
+			reply("257 created")
+		case "RNFR":
+			renameFrom = arg
+			reply("350 ready")
+		case "RNTO":
+			s.mut.Lock()
+			v, ok := s.files[renameFrom]
+			if ok {
+				delete(s.files, renameFrom)
+				s.files[arg] = v
+			}
+			s.mut.Unlock()
+			if !ok {
+				reply("550 not found")
+				continue
+			}
+			reply("250 renamed")
+		case "DELE":
+			s.mut.Lock()
+			delete(s.files, arg)
+			s.mut.Unlock()
+			reply("250 deleted")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func testFTPOutput(t *testing.T, addr, extra string) *ftpOutput {
+	t.Helper()
+
+	conf, err := ftpOutputSpec().ParseYAML(fmt.Sprintf(`
+address: %v
+username: foo
+password: bar
+path: /out/${! meta("id") }.txt
+timeout: 5s
+%v
+`, addr, extra), nil)
+	require.NoError(t, err)
+
+	out, err := newFTPOutputFromParsed(conf, 2, nil)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = out.Close(context.Background())
+	})
+	return out
+}
+
+func testFTPMessage(id, content string) *service.Message {
+	msg := service.NewMessage([]byte(content))
+	msg.MetaSetMut("id", id)
+	return msg
+}
+
+func TestFTPOutputAtomic(t *testing.T) {
+	srv := startTestFTPServer(t, false)
+	out := testFTPOutput(t, srv.addr(), "")
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, out.Write(context.Background(), testFTPMessage(fmt.Sprintf("foo%d", i), "hello world")))
+	}
+	require.Error(t, out.Write(context.Background(), testFTPMessage("forbidden", "nope")))
+	require.NoError(t, out.Write(context.Background(), testFTPMessage("foo0", "replaced")))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, map[string]string{
+		"/out/foo0.txt": "replaced",
+		"/out/foo1.txt": "hello world",
+		"/out/foo2.txt": "hello world",
+		"/out/foo3.txt": "hello world",
+		"/out/foo4.txt": "hello world",
+	}, srv.files)
+	assert.Equal(t, 1, srv.logins)
+	assert.Contains(t, srv.commands, "RNFR")
+	assert.NotContains(t, srv.commands, "AUTH")
+}
+
+func TestFTPOutputDirect(t *testing.T) {
+	srv := startTestFTPServer(t, false)
+	out := testFTPOutput(t, srv.addr(), `
+atomic_upload: false
+create_dirs: false
+`)
+
+	require.NoError(t, out.Write(context.Background(), testFTPMessage("foo", "hello world")))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, map[string]string{"/out/foo.txt": "hello world"}, srv.files)
+	assert.NotContains(t, srv.commands, "RNFR")
+	assert.NotContains(t, srv.commands, "MKD")
+}
+
+func TestFTPOutputTLS(t *testing.T) {
+	srv := startTestFTPServer(t, true)
+	out := testFTPOutput(t, srv.addr(), `
+tls:
+  enabled: true
+  skip_cert_verify: true
+`)
+
+	require.NoError(t, out.Write(context.Background(), testFTPMessage("foo", "hello world")))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, map[string]string{"/out/foo.txt": "hello world"}, srv.files)
+	assert.Contains(t, srv.commands, "AUTH")
+	assert.Contains(t, srv.commands, "PROT")
+}
+
+func TestFTPOutputPool(t *testing.T) {
+	srv := startTestFTPServer(t, false)
+	out := testFTPOutput(t, srv.addr(), "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, out.Write(context.Background(), testFTPMessage(fmt.Sprintf("foo%d", i), "hello world")))
+		}(i)
+	}
+	wg.Wait()
+
+	out.poolMut.Lock()
+	assert.LessOrEqual(t, len(out.idle), 2)
+	out.poolMut.Unlock()
+
+	srv.mut.Lock()
+	assert.Len(t, srv.files, 10)
+	srv.mut.Unlock()
+
+	require.NoError(t, out.Close(context.Background()))
+	require.ErrorIs(t, out.Write(context.Background(), testFTPMessage("foo", "bar")), service.ErrNotConnected)
+}
+
+func TestFTPPassiveResponses(t *testing.T) {
+	port, err := parseEPSV("229 Entering Extended Passive Mode (|||6446|)")
+	require.NoError(t, err)
+	assert.Equal(t, 6446, port)
+
+	port, err = parsePASV("Entering Passive Mode (192,168,1,2,25,46)")
+	require.NoError(t, err)
+	assert.Equal(t, 25<<8|46, port)
+
+	_, err = parsePASV("Entering Passive Mode")
+	require.Error(t, err)
+}
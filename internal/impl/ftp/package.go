@@ -0,0 +1,3 @@
+// Package ftp contains implementations of components that transfer files over
+// FTP and FTPS.
+package ftp
@@ -0,0 +1,165 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	firestorepb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	firestoreEndpoint    = "firestore.googleapis.com:443"
+	firestoreEmulatorEnv = "FIRESTORE_EMULATOR_HOST"
+	firestoreScope       = "https://www.googleapis.com/auth/datastore"
+)
+
+func firestoreDial(ctx context.Context) (*grpc.ClientConn, error) {
+	return gcpDialGRPC(ctx, firestoreEndpoint, firestoreEmulatorEnv, firestoreScope)
+}
+
+func firestoreDatabasePath(project, database string) string {
+	return "projects/" + project + "/databases/" + database
+}
+
+// firestoreContext adds the headers required by the API for routing requests
+// to a database.
+func firestoreContext(ctx context.Context, dbPath string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		"google-cloud-resource-prefix", dbPath,
+		"x-goog-request-params", "database="+url.QueryEscape(dbPath),
+	)
+}
+
+var firestoreSimpleFieldName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+// firestoreFieldPath quotes a top level field name for use within a field
+// path when it contains characters other than letters, digits and
+// underscores.
+func firestoreFieldPath(name string) string {
+	if firestoreSimpleFieldName.MatchString(name) {
+		return name
+	}
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+func toFirestoreValue(v any) (*firestorepb.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_NullValue{NullValue: structpb.NullValue_NULL_VALUE}}, nil
+	case bool:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_BooleanValue{BooleanValue: t}}, nil
+	case string:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_StringValue{StringValue: t}}, nil
+	case []byte:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_BytesValue{BytesValue: t}}, nil
+	case time.Time:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_TimestampValue{TimestampValue: timestamppb.New(t)}}, nil
+	case int:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: int64(t)}}, nil
+	case int32:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: int64(t)}}, nil
+	case int64:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: t}}, nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("integer value %v exceeds the range of a firestore integer", t)
+		}
+		return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: int64(t)}}, nil
+	case float32:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: float64(t)}}, nil
+	case float64:
+		return &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: t}}, nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: i}}, nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: f}}, nil
+	case []any:
+		values := make([]*firestorepb.Value, len(t))
+		for i, e := range t {
+			var err error
+			if values[i], err = toFirestoreValue(e); err != nil {
+				return nil, fmt.Errorf("index %v: %w", i, err)
+			}
+		}
+		return &firestorepb.Value{ValueType: &firestorepb.Value_ArrayValue{ArrayValue: &firestorepb.ArrayValue{Values: values}}}, nil
+	case map[string]any:
+		fields, err := toFirestoreFields(t)
+		if err != nil {
+			return nil, err
+		}
+		return &firestorepb.Value{ValueType: &firestorepb.Value_MapValue{MapValue: &firestorepb.MapValue{Fields: fields}}}, nil
+	}
+	return nil, fmt.Errorf("value type %T not supported", v)
+}
+
+func toFirestoreFields(obj map[string]any) (map[string]*firestorepb.Value, error) {
+	fields := make(map[string]*firestorepb.Value, len(obj))
+	for k, v := range obj {
+		var err error
+		if fields[k], err = toFirestoreValue(v); err != nil {
+			return nil, fmt.Errorf("field %v: %w", k, err)
+		}
+	}
+	return fields, nil
+}
+
+func fromFirestoreValue(v *firestorepb.Value) any {
+	switch t := v.GetValueType().(type) {
+	case *firestorepb.Value_BooleanValue:
+		return t.BooleanValue
+	case *firestorepb.Value_IntegerValue:
+		return t.IntegerValue
+	case *firestorepb.Value_DoubleValue:
+		return t.DoubleValue
+	case *firestorepb.Value_TimestampValue:
+		return t.TimestampValue.AsTime()
+	case *firestorepb.Value_StringValue:
+		return t.StringValue
+	case *firestorepb.Value_BytesValue:
+		return t.BytesValue
+	case *firestorepb.Value_ReferenceValue:
+		return t.ReferenceValue
+	case *firestorepb.Value_GeoPointValue:
+		return geoPointToMap(t.GeoPointValue)
+	case *firestorepb.Value_ArrayValue:
+		values := make([]any, len(t.ArrayValue.GetValues()))
+		for i, e := range t.ArrayValue.GetValues() {
+			values[i] = fromFirestoreValue(e)
+		}
+		return values
+	case *firestorepb.Value_MapValue:
+		return fromFirestoreFields(t.MapValue.GetFields())
+	}
+	return nil
+}
+
+func geoPointToMap(p *latlng.LatLng) map[string]any {
+	return map[string]any{
+		"latitude":  p.GetLatitude(),
+		"longitude": p.GetLongitude(),
+	}
+}
+
+func fromFirestoreFields(fields map[string]*firestorepb.Value) map[string]any {
+	obj := make(map[string]any, len(fields))
+	for k, v := range fields {
+		obj[k] = fromFirestoreValue(v)
+	}
+	return obj
+}
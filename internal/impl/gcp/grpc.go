@@ -0,0 +1,21 @@
+package gcp
+
+import (
+	"context"
+	"os"
+
+	"google.golang.org/api/option"
+	gtransport "google.golang.org/api/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// gcpDialGRPC opens a connection to the gRPC API of a GCP service with the
+// default credentials, or to an emulator without authentication when the
+// address of one is set with the provided environment variable.
+func gcpDialGRPC(ctx context.Context, endpoint, emulatorEnv string, scopes ...string) (*grpc.ClientConn, error) {
+	if host := os.Getenv(emulatorEnv); host != "" {
+		return grpc.DialContext(ctx, host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	return gtransport.Dial(ctx, option.WithEndpoint(endpoint), option.WithScopes(scopes...))
+}
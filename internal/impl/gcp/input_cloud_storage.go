@@ -51,6 +51,7 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 - gcs_key
 - gcs_bucket
+- gcs_message_index
 - gcs_last_modified
 - gcs_last_modified_unix
 - gcs_content_type
@@ -122,6 +123,7 @@ type gcpCloudStoragePendingObject struct {
 	target    *gcpCloudStorageObjectTarget
 	obj       *storage.ObjectAttrs
 	extracted int
+	parts     int
 	scanner   codec.Reader
 }
 
@@ -280,9 +282,10 @@ func (g *gcpCloudStorageInput) getObjectTarget(ctx context.Context) (*gcpCloudSt
 
 func gcpCloudStorageMsgFromParts(p *gcpCloudStoragePendingObject, parts []*message.Part) message.Batch {
 	msg := message.Batch(parts)
-	_ = msg.Iter(func(_ int, part *message.Part) error {
+	_ = msg.Iter(func(i int, part *message.Part) error {
 		part.MetaSet("gcs_key", p.target.key)
 		part.MetaSet("gcs_bucket", p.obj.Bucket)
+		part.MetaSet("gcs_message_index", strconv.Itoa(p.parts+i))
 		part.MetaSet("gcs_last_modified", p.obj.Updated.Format(time.RFC3339))
 		part.MetaSet("gcs_last_modified_unix", strconv.FormatInt(p.obj.Updated.Unix(), 10))
 		part.MetaSet("gcs_content_type", p.obj.ContentType)
@@ -293,6 +296,7 @@ func gcpCloudStorageMsgFromParts(p *gcpCloudStoragePendingObject, parts []*messa
 		}
 		return nil
 	})
+	p.parts += len(parts)
 
 	return msg
 }
@@ -0,0 +1,449 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	firestorepb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fsiFieldProject        = "project"
+	fsiFieldDatabase       = "database"
+	fsiFieldCollection     = "collection"
+	fsiFieldAllDescendants = "all_descendants"
+	fsiFieldWhere          = "where"
+	fsiFieldWhereField     = "field"
+	fsiFieldWhereOp        = "op"
+	fsiFieldWhereValue     = "value"
+	fsiFieldOrderBy        = "order_by"
+	fsiFieldOrderField     = "field"
+	fsiFieldOrderDesc      = "descending"
+	fsiFieldLimit          = "limit"
+	fsiFieldPageSize       = "page_size"
+)
+
+var firestoreFilterOps = map[string]firestorepb.StructuredQuery_FieldFilter_Operator{
+	"==":                 firestorepb.StructuredQuery_FieldFilter_EQUAL,
+	"!=":                 firestorepb.StructuredQuery_FieldFilter_NOT_EQUAL,
+	"<":                  firestorepb.StructuredQuery_FieldFilter_LESS_THAN,
+	"<=":                 firestorepb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL,
+	">":                  firestorepb.StructuredQuery_FieldFilter_GREATER_THAN,
+	">=":                 firestorepb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL,
+	"array-contains":     firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS,
+	"array-contains-any": firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS_ANY,
+	"in":                 firestorepb.StructuredQuery_FieldFilter_IN,
+	"not-in":             firestorepb.StructuredQuery_FieldFilter_NOT_IN,
+}
+
+func firestoreInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "GCP").
+		Summary("Executes a query against a collection of a Google Cloud Firestore database and creates a message for each document received.").
+		Description(`
+The documents matching the query are read in pages of `+"`page_size`"+` documents, and once they are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+The fields of each document are converted into a structured message, where timestamps are converted into timestamp values, references into the path of the referenced document and geo points into objects with the fields `+"`latitude`"+` and `+"`longitude`"+`.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to GCP services. You can find out more [in this document](/docs/guides/cloud/gcp). When the environment variable `+"`FIRESTORE_EMULATOR_HOST`"+` is set Benthos connects to the emulator at that address instead.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- firestore_document_id
+- firestore_document_path
+- firestore_create_time
+- firestore_update_time
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(fsiFieldProject).
+			Description("The project ID of the database.")).
+		Field(service.NewStringField(fsiFieldDatabase).
+			Description("The ID of the database.").
+			Advanced().
+			Default("(default)")).
+		Field(service.NewStringField(fsiFieldCollection).
+			Description("The path of the collection to query, which may be a subcollection of a document.").
+			Example("users").
+			Example("users/alice/orders")).
+		Field(service.NewBoolField(fsiFieldAllDescendants).
+			Description("Whether to query all collections with the ID of `collection` that are descendants of its parent, also known as a collection group query.").
+			Advanced().
+			Default(false)).
+		Field(service.NewObjectListField(fsiFieldWhere,
+			service.NewStringField(fsiFieldWhereField).
+				Description("The path of the field to filter by."),
+			service.NewStringEnumField(fsiFieldWhereOp, "==", "!=", "<", "<=", ">", ">=", "array-contains", "array-contains-any", "in", "not-in").
+				Description("The operator to compare the field with."),
+			service.NewAnyField(fsiFieldWhereValue).
+				Description("The value to compare the field with."),
+		).
+			Description("A list of filters that documents must all match.").
+			Example([]any{
+				map[string]any{"field": "status", "op": "==", "value": "active"},
+				map[string]any{"field": "age", "op": ">=", "value": 18},
+			}).
+			Default([]any{})).
+		Field(service.NewObjectListField(fsiFieldOrderBy,
+			service.NewStringField(fsiFieldOrderField).
+				Description("The path of the field to order by."),
+			service.NewBoolField(fsiFieldOrderDesc).
+				Description("Whether to order by descending values.").
+				Default(false),
+		).
+			Description("A list of fields to order documents by. Documents are always ordered by their path after any fields listed here.").
+			Example([]any{
+				map[string]any{"field": "created_at"},
+			}).
+			Default([]any{})).
+		Field(service.NewIntField(fsiFieldLimit).
+			Description("The maximum number of documents to read, or zero for no limit.").
+			Default(0)).
+		Field(service.NewIntField(fsiFieldPageSize).
+			Description("The maximum number of documents to read with each request.").
+			Advanced().
+			Default(1000)).
+		Example(
+			"Export Active Users",
+			"In this example the active users of a collection are read in order of the time they were created.",
+			`
+input:
+  gcp_firestore:
+    project: my-project
+    collection: users
+    where:
+      - field: status
+        op: ==
+        value: active
+    order_by:
+      - field: created_at
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"gcp_firestore", firestoreInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newFirestoreInputFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type firestoreInput struct {
+	dbPath   string
+	parent   string
+	query    *firestorepb.StructuredQuery
+	limit    int
+	pageSize int
+
+	mut     sync.Mutex
+	conn    *grpc.ClientConn
+	client  firestorepb.FirestoreClient
+	docs    []*firestorepb.Document
+	cursor  *firestorepb.Cursor
+	read    int
+	drained bool
+}
+
+func newFirestoreInputFromParsed(conf *service.ParsedConfig) (*firestoreInput, error) {
+	f := &firestoreInput{}
+
+	project, err := conf.FieldString(fsiFieldProject)
+	if err != nil {
+		return nil, err
+	}
+	database, err := conf.FieldString(fsiFieldDatabase)
+	if err != nil {
+		return nil, err
+	}
+	f.dbPath = firestoreDatabasePath(project, database)
+
+	collection, err := conf.FieldString(fsiFieldCollection)
+	if err != nil {
+		return nil, err
+	}
+	collection = strings.Trim(collection, "/")
+	if collection == "" {
+		return nil, errors.New("collection must not be empty")
+	}
+
+	// The parent of a collection is either the root of the database or the
+	// document that it belongs to.
+	f.parent = f.dbPath + "/documents"
+	collectionID := collection
+	if i := strings.LastIndex(collection, "/"); i >= 0 {
+		f.parent += "/" + collection[:i]
+		collectionID = collection[i+1:]
+	}
+
+	allDescendants, err := conf.FieldBool(fsiFieldAllDescendants)
+	if err != nil {
+		return nil, err
+	}
+	f.query = &firestorepb.StructuredQuery{
+		From: []*firestorepb.StructuredQuery_CollectionSelector{
+			{CollectionId: collectionID, AllDescendants: allDescendants},
+		},
+	}
+
+	whereConfs, err := conf.FieldObjectList(fsiFieldWhere)
+	if err != nil {
+		return nil, err
+	}
+	var filters []*firestorepb.StructuredQuery_Filter
+	for i, wConf := range whereConfs {
+		filter, err := firestoreFilterFromParsed(wConf)
+		if err != nil {
+			return nil, fmt.Errorf("where %v: %w", i, err)
+		}
+		filters = append(filters, filter)
+	}
+	switch len(filters) {
+	case 0:
+	case 1:
+		f.query.Where = filters[0]
+	default:
+		f.query.Where = &firestorepb.StructuredQuery_Filter{
+			FilterType: &firestorepb.StructuredQuery_Filter_CompositeFilter{
+				CompositeFilter: &firestorepb.StructuredQuery_CompositeFilter{
+					Op:      firestorepb.StructuredQuery_CompositeFilter_AND,
+					Filters: filters,
+				},
+			},
+		}
+	}
+
+	orderConfs, err := conf.FieldObjectList(fsiFieldOrderBy)
+	if err != nil {
+		return nil, err
+	}
+	direction := firestorepb.StructuredQuery_ASCENDING
+	for _, oConf := range orderConfs {
+		field, err := oConf.FieldString(fsiFieldOrderField)
+		if err != nil {
+			return nil, err
+		}
+		desc, err := oConf.FieldBool(fsiFieldOrderDesc)
+		if err != nil {
+			return nil, err
+		}
+		direction = firestorepb.StructuredQuery_ASCENDING
+		if desc {
+			direction = firestorepb.StructuredQuery_DESCENDING
+		}
+		f.query.OrderBy = append(f.query.OrderBy, &firestorepb.StructuredQuery_Order{
+			Field:     &firestorepb.StructuredQuery_FieldReference{FieldPath: field},
+			Direction: direction,
+		})
+	}
+
+	// Ordering by the path of documents last gives each document a unique
+	// position from which the next page can start.
+	f.query.OrderBy = append(f.query.OrderBy, &firestorepb.StructuredQuery_Order{
+		Field:     &firestorepb.StructuredQuery_FieldReference{FieldPath: "__name__"},
+		Direction: direction,
+	})
+
+	if f.limit, err = conf.FieldInt(fsiFieldLimit); err != nil {
+		return nil, err
+	}
+	if f.pageSize, err = conf.FieldInt(fsiFieldPageSize); err != nil {
+		return nil, err
+	}
+	if f.pageSize <= 0 {
+		return nil, errors.New("page_size must be greater than zero")
+	}
+	return f, nil
+}
+
+func firestoreFilterFromParsed(conf *service.ParsedConfig) (*firestorepb.StructuredQuery_Filter, error) {
+	field, err := conf.FieldString(fsiFieldWhereField)
+	if err != nil {
+		return nil, err
+	}
+	opStr, err := conf.FieldString(fsiFieldWhereOp)
+	if err != nil {
+		return nil, err
+	}
+	op, exists := firestoreFilterOps[opStr]
+	if !exists {
+		return nil, fmt.Errorf("operator not recognised: %v", opStr)
+	}
+	v, err := conf.FieldAny(fsiFieldWhereValue)
+	if err != nil {
+		return nil, err
+	}
+	value, err := toFirestoreValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return &firestorepb.StructuredQuery_Filter{
+		FilterType: &firestorepb.StructuredQuery_Filter_FieldFilter{
+			FieldFilter: &firestorepb.StructuredQuery_FieldFilter{
+				Field: &firestorepb.StructuredQuery_FieldReference{FieldPath: field},
+				Op:    op,
+				Value: value,
+			},
+		},
+	}, nil
+}
+
+func (f *firestoreInput) Connect(ctx context.Context) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.conn != nil {
+		return nil
+	}
+
+	conn, err := firestoreDial(ctx)
+	if err != nil {
+		return err
+	}
+	f.conn = conn
+	f.client = firestorepb.NewFirestoreClient(conn)
+	return nil
+}
+
+// documentValue obtains the value of a field of a document by its path, which
+// is null when the field does not exist.
+func documentValue(doc *firestorepb.Document, path string) *firestorepb.Value {
+	if path == "__name__" {
+		return &firestorepb.Value{ValueType: &firestorepb.Value_ReferenceValue{ReferenceValue: doc.GetName()}}
+	}
+	fields := doc.GetFields()
+	segments := strings.Split(path, ".")
+	for i, seg := range segments {
+		v, exists := fields[strings.Trim(seg, "`")]
+		if !exists {
+			break
+		}
+		if i == len(segments)-1 {
+			return v
+		}
+		fields = v.GetMapValue().GetFields()
+	}
+	return &firestorepb.Value{ValueType: &firestorepb.Value_NullValue{}}
+}
+
+func (f *firestoreInput) nextPage(ctx context.Context) error {
+	size := f.pageSize
+	if f.limit > 0 && f.limit-f.read < size {
+		size = f.limit - f.read
+	}
+
+	query := proto.Clone(f.query).(*firestorepb.StructuredQuery)
+	query.StartAt = f.cursor
+	query.Limit = wrapperspb.Int32(int32(size))
+
+	stream, err := f.client.RunQuery(firestoreContext(ctx, f.dbPath), &firestorepb.RunQueryRequest{
+		Parent:    f.parent,
+		QueryType: &firestorepb.RunQueryRequest_StructuredQuery{StructuredQuery: query},
+	})
+	if err != nil {
+		return err
+	}
+
+	var docs []*firestorepb.Document
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if doc := res.GetDocument(); doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+
+	f.docs = docs
+	f.read += len(docs)
+	if len(docs) < size || (f.limit > 0 && f.read >= f.limit) {
+		f.drained = true
+		return nil
+	}
+
+	last := docs[len(docs)-1]
+	cursor := &firestorepb.Cursor{}
+	for _, o := range f.query.OrderBy {
+		cursor.Values = append(cursor.Values, documentValue(last, o.GetField().GetFieldPath()))
+	}
+	f.cursor = cursor
+	return nil
+}
+
+func (f *firestoreInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for len(f.docs) == 0 {
+		if f.drained {
+			return nil, nil, service.ErrEndOfInput
+		}
+		if err := f.nextPage(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	doc := f.docs[0]
+	f.docs = f.docs[1:]
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(fromFirestoreFields(doc.GetFields()))
+
+	path := strings.TrimPrefix(doc.GetName(), f.dbPath+"/documents/")
+	msg.MetaSetMut("firestore_document_path", path)
+	msg.MetaSetMut("firestore_document_id", path[strings.LastIndex(path, "/")+1:])
+	if doc.CreateTime != nil {
+		msg.MetaSetMut("firestore_create_time", doc.CreateTime.AsTime().Format(time.RFC3339Nano))
+	}
+	if doc.UpdateTime != nil {
+		msg.MetaSetMut("firestore_update_time", doc.UpdateTime.AsTime().Format(time.RFC3339Nano))
+	}
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (f *firestoreInput) Close(ctx context.Context) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	f.client = nil
+	return err
+}
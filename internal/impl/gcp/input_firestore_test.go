@@ -0,0 +1,209 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	firestorepb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testFirestoreServer struct {
+	firestorepb.UnimplementedFirestoreServer
+
+	mut     sync.Mutex
+	docs    []*firestorepb.Document
+	queries []*firestorepb.RunQueryRequest
+	writes  []*firestorepb.Write
+}
+
+// RunQuery serves documents in order of their names, starting after the
+// document named by the final value of any cursor.
+func (s *testFirestoreServer) RunQuery(req *firestorepb.RunQueryRequest, stream firestorepb.Firestore_RunQueryServer) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.queries = append(s.queries, req)
+
+	q := req.GetStructuredQuery()
+	var after string
+	if values := q.GetStartAt().GetValues(); len(values) > 0 {
+		after = values[len(values)-1].GetReferenceValue()
+	}
+
+	sent := 0
+	for _, doc := range s.docs {
+		if doc.Name <= after {
+			continue
+		}
+		if sent >= int(q.GetLimit().GetValue()) {
+			break
+		}
+		if err := stream.Send(&firestorepb.RunQueryResponse{Document: doc}); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+// BatchWrite rejects any documents with IDs beginning with "fail".
+func (s *testFirestoreServer) BatchWrite(ctx context.Context, req *firestorepb.BatchWriteRequest) (*firestorepb.BatchWriteResponse, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	res := &firestorepb.BatchWriteResponse{}
+	for _, w := range req.GetWrites() {
+		name := w.GetUpdate().GetName()
+		if strings.HasPrefix(name[strings.LastIndex(name, "/")+1:], "fail") {
+			res.Status = append(res.Status, &status.Status{Code: int32(codes.FailedPrecondition), Message: "nope"})
+			continue
+		}
+		s.writes = append(s.writes, w)
+		res.Status = append(res.Status, &status.Status{Code: int32(codes.OK)})
+	}
+	return res, nil
+}
+
+func startTestFirestore(t *testing.T, srv *testFirestoreServer) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer()
+	firestorepb.RegisterFirestoreServer(s, srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	t.Setenv(firestoreEmulatorEnv, lis.Addr().String())
+}
+
+func TestFirestoreInputPages(t *testing.T) {
+	dbPath := "projects/foo/databases/(default)"
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	srv := &testFirestoreServer{}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		srv.docs = append(srv.docs, &firestorepb.Document{
+			Name: dbPath + "/documents/users/alice/orders/" + id,
+			Fields: map[string]*firestorepb.Value{
+				"id":    {ValueType: &firestorepb.Value_StringValue{StringValue: id}},
+				"count": {ValueType: &firestorepb.Value_IntegerValue{IntegerValue: 2}},
+			},
+			CreateTime: timestamppb.New(created),
+		})
+	}
+	sort.Slice(srv.docs, func(i, j int) bool { return srv.docs[i].Name < srv.docs[j].Name })
+	startTestFirestore(t, srv)
+
+	conf, err := firestoreInputSpec().ParseYAML(`
+project: foo
+collection: users/alice/orders
+where:
+  - field: count
+    op: '>='
+    value: 2
+  - field: status
+    op: in
+    value: [ new, paid ]
+order_by:
+  - field: count
+    descending: true
+limit: 4
+page_size: 2
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newFirestoreInputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+
+	var docs, ids []string
+	for {
+		msg, ackFn, err := in.Read(context.Background())
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, ackFn(context.Background(), nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(b))
+
+		id, _ := msg.MetaGet("firestore_document_id")
+		path, _ := msg.MetaGet("firestore_document_path")
+		assert.Equal(t, "users/alice/orders/"+id, path)
+		ids = append(ids, id)
+
+		createTime, _ := msg.MetaGet("firestore_create_time")
+		assert.Equal(t, "2026-01-02T03:04:05Z", createTime)
+	}
+	require.NoError(t, in.Close(context.Background()))
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, ids)
+	assert.Equal(t, `{"count":2,"id":"a"}`, docs[0])
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.queries, 2)
+	assert.Equal(t, dbPath+"/documents/users/alice", srv.queries[0].Parent)
+
+	q := srv.queries[0].GetStructuredQuery()
+	assert.Equal(t, "orders", q.From[0].CollectionId)
+	require.Len(t, q.GetWhere().GetCompositeFilter().GetFilters(), 2)
+	require.Len(t, q.OrderBy, 2)
+	assert.Equal(t, "count", q.OrderBy[0].Field.FieldPath)
+	assert.Equal(t, firestorepb.StructuredQuery_DESCENDING, q.OrderBy[0].Direction)
+	assert.Equal(t, "__name__", q.OrderBy[1].Field.FieldPath)
+	assert.Equal(t, firestorepb.StructuredQuery_DESCENDING, q.OrderBy[1].Direction)
+	assert.Nil(t, q.StartAt)
+
+	next := srv.queries[1].GetStructuredQuery()
+	require.Len(t, next.GetStartAt().GetValues(), 2)
+	assert.Equal(t, int64(2), next.StartAt.Values[0].GetIntegerValue())
+	assert.Equal(t, dbPath+"/documents/users/alice/orders/b", next.StartAt.Values[1].GetReferenceValue())
+	assert.False(t, next.StartAt.Before)
+}
+
+func TestFirestoreValues(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	in := map[string]any{
+		"str":   "foo",
+		"int":   int64(5),
+		"float": 1.5,
+		"bool":  true,
+		"null":  nil,
+		"time":  ts,
+		"arr":   []any{"a", int64(1)},
+		"obj":   map[string]any{"nested": "bar"},
+	}
+
+	fields, err := toFirestoreFields(in)
+	require.NoError(t, err)
+	assert.Equal(t, in, fromFirestoreFields(fields))
+
+	_, err = toFirestoreValue(struct{}{})
+	require.Error(t, err)
+
+	assert.Equal(t, "simple_name", firestoreFieldPath("simple_name"))
+	assert.Equal(t, "`with space`", firestoreFieldPath("with space"))
+	assert.Equal(t, "`back\\`tick`", firestoreFieldPath("back`tick"))
+}
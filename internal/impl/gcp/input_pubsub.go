@@ -2,6 +2,7 @@ package gcp
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"sync"
 
@@ -37,7 +38,11 @@ This input adds the following metadata fields to each message:
 ` + "```" + `
 
 You can access these metadata fields using
-[function interpolation](/docs/configuration/interpolation#metadata).`,
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+### Exactly-Once Delivery
+
+When consuming from a subscription with [exactly-once delivery](https://cloud.google.com/pubsub/docs/exactly-once-delivery) enabled set ` + "`exactly_once_delivery`" + ` to ` + "`true`" + `, which causes acknowledgements to wait for confirmation from the server that they were successful. When the subscription does not have exactly-once delivery enabled a warning is logged and acknowledgements are not confirmed. A failed acknowledgement is reported as an error and the message will be redelivered.`,
 		Categories: []string{
 			"Services",
 			"GCP",
@@ -48,6 +53,7 @@ You can access these metadata fields using
 			docs.FieldBool("sync", "Enable synchronous pull mode."),
 			docs.FieldInt("max_outstanding_messages", "The maximum number of outstanding pending messages to be consumed at a given time."),
 			docs.FieldInt("max_outstanding_bytes", "The maximum number of outstanding pending messages to be consumed measured in bytes."),
+			docs.FieldBool("exactly_once_delivery", "Whether to confirm the acknowledgement of each message with the server, which should be enabled when the subscription has exactly-once delivery enabled.").Advanced().AtVersion("4.9.0"),
 		).ChildDefaultAndTypesFromStruct(input.NewGCPPubSubConfig()),
 	})
 	if err != nil {
@@ -72,6 +78,10 @@ type gcpPubSubReader struct {
 	closeFunc    context.CancelFunc
 	subMut       sync.Mutex
 
+	// Whether acknowledgements are confirmed with the server, which is only
+	// the case when exactly-once delivery is enabled for the subscription.
+	confirmAcks bool
+
 	client *pubsub.Client
 
 	log log.Modular
@@ -89,7 +99,7 @@ func newGCPPubSubReader(conf input.GCPPubSubConfig, log log.Modular, stats metri
 	}, nil
 }
 
-func (c *gcpPubSubReader) Connect(ignored context.Context) error {
+func (c *gcpPubSubReader) Connect(ctx context.Context) error {
 	c.subMut.Lock()
 	defer c.subMut.Unlock()
 	if c.subscription != nil {
@@ -97,6 +107,20 @@ func (c *gcpPubSubReader) Connect(ignored context.Context) error {
 	}
 
 	sub := c.client.Subscription(c.conf.SubscriptionID)
+
+	// The client library only acknowledges messages on calls that await a
+	// result when the subscription has exactly-once delivery enabled.
+	c.confirmAcks = false
+	if c.conf.ExactlyOnceDelivery {
+		subConf, err := sub.Config(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to obtain subscription config: %w", err)
+		}
+		if c.confirmAcks = subConf.EnableExactlyOnceDelivery; !c.confirmAcks {
+			c.log.Warnf("Subscription '%v' does not have exactly-once delivery enabled, acknowledgements will not be confirmed\n", c.conf.SubscriptionID)
+		}
+	}
+
 	sub.ReceiveSettings.MaxOutstandingMessages = c.conf.MaxOutstandingMessages
 	sub.ReceiveSettings.MaxOutstandingBytes = c.conf.MaxOutstandingBytes
 	sub.ReceiveSettings.Synchronous = c.conf.Sync
@@ -136,6 +160,7 @@ func (c *gcpPubSubReader) Connect(ignored context.Context) error {
 func (c *gcpPubSubReader) ReadBatch(ctx context.Context) (message.Batch, input.AsyncAckFn, error) {
 	c.subMut.Lock()
 	msgsChan := c.msgsChan
+	confirmAcks := c.confirmAcks
 	c.subMut.Unlock()
 	if msgsChan == nil {
 		return nil, nil, component.ErrNotConnected
@@ -160,6 +185,9 @@ func (c *gcpPubSubReader) ReadBatch(ctx context.Context) (message.Batch, input.A
 
 	msg := message.Batch{part}
 	return msg, func(ctx context.Context, res error) error {
+		if confirmAcks {
+			return ackPubSubWithResult(ctx, gmsg, res == nil)
+		}
 		if res != nil {
 			gmsg.Nack()
 		} else {
@@ -169,6 +197,50 @@ func (c *gcpPubSubReader) ReadBatch(ctx context.Context) (message.Batch, input.A
 	}, nil
 }
 
+// ackPubSubWithResult acknowledges or rejects a message and waits for the
+// server to confirm the outcome.
+func ackPubSubWithResult(ctx context.Context, gmsg *pubsub.Message, ack bool) error {
+	var result *pubsub.AckResult
+	if ack {
+		result = gmsg.AckWithResult()
+	} else {
+		result = gmsg.NackWithResult()
+	}
+	if result == nil {
+		return nil
+	}
+	return awaitPubSubAckResult(ctx, result)
+}
+
+type pubSubAckResult interface {
+	Get(ctx context.Context) (pubsub.AcknowledgeStatus, error)
+}
+
+func awaitPubSubAckResult(ctx context.Context, result pubSubAckResult) error {
+	status, err := result.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to confirm acknowledgement: %w", err)
+	}
+	if status != pubsub.AcknowledgeStatusSuccess {
+		return fmt.Errorf("acknowledgement was not successful: %v", pubSubAckStatusString(status))
+	}
+	return nil
+}
+
+func pubSubAckStatusString(s pubsub.AcknowledgeStatus) string {
+	switch s {
+	case pubsub.AcknowledgeStatusSuccess:
+		return "success"
+	case pubsub.AcknowledgeStatusPermissionDenied:
+		return "permission denied"
+	case pubsub.AcknowledgeStatusFailedPrecondition:
+		return "failed precondition"
+	case pubsub.AcknowledgeStatusInvalidAckID:
+		return "invalid ack id"
+	}
+	return "unknown error"
+}
+
 func (c *gcpPubSubReader) Close(ctx context.Context) error {
 	c.subMut.Lock()
 	defer c.subMut.Unlock()
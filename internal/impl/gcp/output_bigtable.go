@@ -0,0 +1,388 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	bigtablepb "google.golang.org/genproto/googleapis/bigtable/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	btoFieldProject     = "project"
+	btoFieldInstance    = "instance"
+	btoFieldTable       = "table"
+	btoFieldAppProfile  = "app_profile_id"
+	btoFieldRowKey      = "row_key"
+	btoFieldFamilies    = "column_families"
+	btoFieldTimestamp   = "timestamp"
+	btoFieldBatching    = "batching"
+	btoFieldMaxInFlight = "max_in_flight"
+
+	bigtableEndpoint    = "bigtable.googleapis.com:443"
+	bigtableEmulatorEnv = "BIGTABLE_EMULATOR_HOST"
+	bigtableScope       = "https://www.googleapis.com/auth/bigtable.data"
+)
+
+func bigtableOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "GCP").
+		Summary("Writes messages as rows of a Google Cloud Bigtable table.").
+		Description(`
+Each message is written as a row mutation that sets the cells of the row with the key `+"`row_key`"+`. The cells are the result of `+"`column_families`"+`, a mapping that must result in an object where each key is the name of a column family and each value is an object of column qualifiers to cell values. For example, the mapping:
+
+`+"```coffee"+`
+root.stats.clicks = this.clicks
+root.stats.views = this.views
+root.info.name = this.name
+`+"```"+`
+
+Sets the cells `+"`stats:clicks`"+`, `+"`stats:views`"+` and `+"`info:name`"+`. The column families must already exist within the table. Cell values that are strings are written as they are, null values are skipped, and all other values are written as JSON, meaning numbers are written as their string representation.
+
+Batches of messages are written with a single `+"[MutateRows](https://cloud.google.com/bigtable/docs/reference/data/rpc/google.bigtable.v2#google.bigtable.v2.Bigtable.MutateRows)"+` request, where each row is mutated atomically but the batch as a whole is not, and therefore only the messages of a batch that failed to be written are reattempted.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to GCP services. You can find out more [in this document](/docs/guides/cloud/gcp). When the environment variable `+"`BIGTABLE_EMULATOR_HOST`"+` is set Benthos connects to the emulator at that address instead.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(btoFieldProject).
+			Description("The project ID of the Bigtable instance.")).
+		Field(service.NewStringField(btoFieldInstance).
+			Description("The ID of the Bigtable instance.")).
+		Field(service.NewStringField(btoFieldTable).
+			Description("The table to write rows to.")).
+		Field(service.NewStringField(btoFieldAppProfile).
+			Description("An optional app profile to route requests with, when empty the default app profile of the instance is used.").
+			Advanced().
+			Default("")).
+		Field(service.NewInterpolatedStringField(btoFieldRowKey).
+			Description("The key of the row written for each message.").
+			Example(`${! this.user_id }#${! this.ts }`)).
+		Field(service.NewBloblangField(btoFieldFamilies).
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of column families, each of which is an object of column qualifiers to cell values.").
+			Example(`root.stats = this.without("user_id")`)).
+		Field(service.NewInterpolatedStringField(btoFieldTimestamp).
+			Description("An optional timestamp of the cells written, either as a unix timestamp in seconds or an [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, which is truncated to millisecond precision. When omitted the time at which the cells are written is assigned by the server.").
+			Example(`${! this.ts }`).
+			Optional()).
+		Field(service.NewBatchPolicyField(btoFieldBatching)).
+		Field(service.NewIntField(btoFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"User Activity",
+			"In this example counts of user activity are written to the `stats` column family of rows keyed by the user and day of the activity.",
+			`
+output:
+  gcp_bigtable:
+    project: my-project
+    instance: my-instance
+    table: activity
+    row_key: ${! this.user_id }#${! this.day }
+    column_families: |
+      root.stats.clicks = this.clicks
+      root.stats.views = this.views
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"gcp_bigtable", bigtableOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(btoFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(btoFieldBatching); err != nil {
+				return
+			}
+			out, err = newBigtableOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type bigtableOutput struct {
+	tableName    string
+	appProfileID string
+	rowKey       *service.InterpolatedString
+	families     *bloblang.Executor
+	timestamp    *service.InterpolatedString
+
+	connMut sync.RWMutex
+	conn    *grpc.ClientConn
+	client  bigtablepb.BigtableClient
+}
+
+func newBigtableOutputFromParsed(conf *service.ParsedConfig) (*bigtableOutput, error) {
+	b := &bigtableOutput{}
+
+	project, err := conf.FieldString(btoFieldProject)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := conf.FieldString(btoFieldInstance)
+	if err != nil {
+		return nil, err
+	}
+	table, err := conf.FieldString(btoFieldTable)
+	if err != nil {
+		return nil, err
+	}
+	b.tableName = "projects/" + project + "/instances/" + instance + "/tables/" + table
+
+	if b.appProfileID, err = conf.FieldString(btoFieldAppProfile); err != nil {
+		return nil, err
+	}
+	if b.rowKey, err = conf.FieldInterpolatedString(btoFieldRowKey); err != nil {
+		return nil, err
+	}
+	if b.families, err = conf.FieldBloblang(btoFieldFamilies); err != nil {
+		return nil, err
+	}
+	if conf.Contains(btoFieldTimestamp) {
+		if b.timestamp, err = conf.FieldInterpolatedString(btoFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func (b *bigtableOutput) Connect(ctx context.Context) error {
+	b.connMut.Lock()
+	defer b.connMut.Unlock()
+
+	if b.conn != nil {
+		return nil
+	}
+
+	conn, err := gcpDialGRPC(ctx, bigtableEndpoint, bigtableEmulatorEnv, bigtableScope)
+	if err != nil {
+		return err
+	}
+	b.conn = conn
+	b.client = bigtablepb.NewBigtableClient(conn)
+	return nil
+}
+
+func bigtableCellValue(v any) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		return []byte(t), nil
+	case []byte:
+		return t, nil
+	}
+	return json.Marshal(v)
+}
+
+func (b *bigtableOutput) cellTimestamp(batch service.MessageBatch, index int) (int64, error) {
+	if b.timestamp == nil {
+		// A timestamp of -1 is assigned by the server.
+		return -1, nil
+	}
+	tsStr := batch.InterpolatedString(index, b.timestamp)
+	var ts time.Time
+	if f, err := strconv.ParseFloat(tsStr, 64); err == nil {
+		ts = time.Unix(0, int64(f*float64(time.Second)))
+	} else if ts, err = time.Parse(time.RFC3339Nano, tsStr); err != nil {
+		return 0, fmt.Errorf("failed to parse timestamp '%v': expected either unix seconds or an RFC 3339 timestamp", tsStr)
+	}
+	return ts.UnixMilli() * 1000, nil
+}
+
+func (b *bigtableOutput) entry(batch service.MessageBatch, index int) (*bigtablepb.MutateRowsRequest_Entry, error) {
+	key := batch.InterpolatedString(index, b.rowKey)
+	if key == "" {
+		return nil, errors.New("row key resolved to an empty string")
+	}
+	tsMicros, err := b.cellTimestamp(batch, index)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := batch.BloblangQuery(index, b.families)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("column families mapping resulted in a deleted message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+	families, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("column families mapping must result in an object, got %T", v)
+	}
+
+	entry := &bigtablepb.MutateRowsRequest_Entry{RowKey: []byte(key)}
+	for _, family := range sortedMapKeys(families) {
+		columns, ok := families[family].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("column family %v must be an object, got %T", family, families[family])
+		}
+		for _, qualifier := range sortedMapKeys(columns) {
+			cv := columns[qualifier]
+			if cv == nil {
+				continue
+			}
+			value, err := bigtableCellValue(cv)
+			if err != nil {
+				return nil, fmt.Errorf("column %v:%v: %w", family, qualifier, err)
+			}
+			entry.Mutations = append(entry.Mutations, &bigtablepb.Mutation{
+				Mutation: &bigtablepb.Mutation_SetCell_{
+					SetCell: &bigtablepb.Mutation_SetCell{
+						FamilyName:      family,
+						ColumnQualifier: []byte(qualifier),
+						TimestampMicros: tsMicros,
+						Value:           value,
+					},
+				},
+			})
+		}
+	}
+	if len(entry.Mutations) == 0 {
+		return nil, errors.New("column families mapping resulted in no cells")
+	}
+	return entry, nil
+}
+
+func sortedMapKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (b *bigtableOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	b.connMut.RLock()
+	client := b.client
+	b.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	failed := func(index int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(index, err)
+	}
+
+	req := &bigtablepb.MutateRowsRequest{
+		TableName:    b.tableName,
+		AppProfileId: b.appProfileID,
+	}
+	var indexes []int
+	for i := range batch {
+		entry, err := b.entry(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		req.Entries = append(req.Entries, entry)
+		indexes = append(indexes, i)
+	}
+
+	if len(req.Entries) > 0 {
+		params := "table_name=" + url.QueryEscape(b.tableName)
+		if b.appProfileID != "" {
+			params += "&app_profile_id=" + url.QueryEscape(b.appProfileID)
+		}
+		if err := b.mutateRows(metadata.AppendToOutgoingContext(ctx, "x-goog-request-params", params), client, req, indexes, failed); err != nil {
+			if batchErr == nil {
+				return err
+			}
+			for _, i := range indexes {
+				failed(i, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// mutateRows sends a request and reports the entries that failed, returning
+// an error when the request as a whole fails.
+func (b *bigtableOutput) mutateRows(ctx context.Context, client bigtablepb.BigtableClient, req *bigtablepb.MutateRowsRequest, indexes []int, failed func(int, error)) error {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+
+	stream, err := client.MutateRows(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	reported := make([]bool, len(req.Entries))
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range res.GetEntries() {
+			i := int(e.GetIndex())
+			if i < 0 || i >= len(reported) {
+				continue
+			}
+			reported[i] = true
+			if s := e.GetStatus(); codes.Code(s.GetCode()) != codes.OK {
+				failed(indexes[i], fmt.Errorf("%v: %v", codes.Code(s.GetCode()), s.GetMessage()))
+			}
+		}
+	}
+	for i, r := range reported {
+		if !r {
+			failed(indexes[i], errors.New("no status was received for the row"))
+		}
+	}
+	return nil
+}
+
+func (b *bigtableOutput) Close(ctx context.Context) error {
+	b.connMut.Lock()
+	defer b.connMut.Unlock()
+
+	if b.conn == nil {
+		return nil
+	}
+	err := b.conn.Close()
+	b.conn = nil
+	b.client = nil
+	return err
+}
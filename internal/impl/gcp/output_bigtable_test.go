@@ -0,0 +1,122 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigtablepb "google.golang.org/genproto/googleapis/bigtable/v2"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testBigtableServer struct {
+	bigtablepb.UnimplementedBigtableServer
+
+	mut      sync.Mutex
+	requests []*bigtablepb.MutateRowsRequest
+}
+
+// MutateRows rejects any rows with keys beginning with "fail".
+func (s *testBigtableServer) MutateRows(req *bigtablepb.MutateRowsRequest, stream bigtablepb.Bigtable_MutateRowsServer) error {
+	s.mut.Lock()
+	s.requests = append(s.requests, req)
+	s.mut.Unlock()
+
+	res := &bigtablepb.MutateRowsResponse{}
+	for i, e := range req.GetEntries() {
+		code := codes.OK
+		if string(e.GetRowKey()[:4]) == "fail" {
+			code = codes.InvalidArgument
+		}
+		res.Entries = append(res.Entries, &bigtablepb.MutateRowsResponse_Entry{
+			Index:  int64(i),
+			Status: &status.Status{Code: int32(code)},
+		})
+	}
+	return stream.Send(res)
+}
+
+func TestBigtableOutputMutations(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &testBigtableServer{}
+	s := grpc.NewServer()
+	bigtablepb.RegisterBigtableServer(s, srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+	t.Setenv(bigtableEmulatorEnv, lis.Addr().String())
+
+	conf, err := bigtableOutputSpec().ParseYAML(`
+project: foo
+instance: bar
+table: baz
+row_key: ${! this.key }
+column_families: |
+  root.stats.clicks = this.clicks
+  root.stats.skipped = null
+  root.info = this.info
+timestamp: ${! this.ts }
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newBigtableOutputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"key":"keya","clicks":5,"info":{"name":"alice","tags":["x"]},"ts":1700000000.1234}`)),
+		service.NewMessage([]byte(`{"key":"keyb","clicks":1,"info":"nope","ts":1}`)),
+		service.NewMessage([]byte(`{"key":"failc","clicks":2,"info":{},"ts":"2023-11-14T22:13:20Z"}`)),
+	})
+	require.Error(t, err)
+	require.NoError(t, out.Close(context.Background()))
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.requests, 1)
+	req := srv.requests[0]
+	assert.Equal(t, "projects/foo/instances/bar/tables/baz", req.TableName)
+	require.Len(t, req.Entries, 2)
+
+	type cell struct {
+		family, qualifier, value string
+		ts                       int64
+	}
+	var cells []cell
+	for _, m := range req.Entries[0].Mutations {
+		c := m.GetSetCell()
+		cells = append(cells, cell{c.FamilyName, string(c.ColumnQualifier), string(c.Value), c.TimestampMicros})
+	}
+	assert.Equal(t, "keya", string(req.Entries[0].RowKey))
+	assert.Equal(t, []cell{
+		{"info", "name", "alice", 1700000000123000},
+		{"info", "tags", `["x"]`, 1700000000123000},
+		{"stats", "clicks", "5", 1700000000123000},
+	}, cells)
+
+	assert.Equal(t, "failc", string(req.Entries[1].RowKey))
+	assert.Equal(t, int64(1700000000000000), req.Entries[1].Mutations[0].GetSetCell().TimestampMicros)
+}
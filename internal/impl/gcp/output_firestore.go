@@ -0,0 +1,327 @@
+package gcp
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	firestorepb "google.golang.org/genproto/googleapis/firestore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fsoFieldProject     = "project"
+	fsoFieldDatabase    = "database"
+	fsoFieldCollection  = "collection"
+	fsoFieldDocumentID  = "document_id"
+	fsoFieldMapping     = "fields_mapping"
+	fsoFieldMerge       = "merge"
+	fsoFieldBatching    = "batching"
+	fsoFieldMaxInFlight = "max_in_flight"
+
+	// The maximum number of writes accepted by a BatchWrite request.
+	firestoreMaxBatchWrites = 500
+)
+
+func firestoreOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "GCP").
+		Summary("Upserts messages as documents of a Google Cloud Firestore database.").
+		Description(`
+Each message is written as a document with the path `+"`<collection>/<document_id>`"+`, replacing any existing document with that path unless `+"`merge`"+` is enabled, in which case only the top level fields of the message are written and the other fields of an existing document are preserved. When `+"`document_id`"+` is empty a random ID is generated for each document.
+
+Batches of messages are written with the [BatchWrite](https://cloud.google.com/firestore/docs/reference/rpc/google.firestore.v1#google.firestore.v1.Firestore.BatchWrite) method, which is not atomic, and therefore only the messages of a batch that failed to be written are reattempted.
+
+The fields of each document are the result of `+"`fields_mapping`"+`, which defaults to the contents of the message, and must be an object. Timestamp values are written as Firestore timestamps.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to GCP services. You can find out more [in this document](/docs/guides/cloud/gcp). When the environment variable `+"`FIRESTORE_EMULATOR_HOST`"+` is set Benthos connects to the emulator at that address instead.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(fsoFieldProject).
+			Description("The project ID of the database.")).
+		Field(service.NewStringField(fsoFieldDatabase).
+			Description("The ID of the database.").
+			Advanced().
+			Default("(default)")).
+		Field(service.NewInterpolatedStringField(fsoFieldCollection).
+			Description("The path of the collection to write documents to, which may be a subcollection of a document.").
+			Example("users").
+			Example(`users/${! this.user_id }/orders`)).
+		Field(service.NewInterpolatedStringField(fsoFieldDocumentID).
+			Description("The ID of each document, when empty a random ID is generated.").
+			Example(`${! this.id }`).
+			Default("")).
+		Field(service.NewBloblangField(fsoFieldMapping).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of the fields of each document. When omitted the contents of each message are parsed as JSON.").
+			Example(`root = this.without("id")`).
+			Example(`root.updated_at = now().ts_parse("2006-01-02T15:04:05Z07:00")`).
+			Optional()).
+		Field(service.NewBoolField(fsoFieldMerge).
+			Description("Whether to only write the top level fields of each document and preserve any other fields of an existing document.").
+			Default(false)).
+		Field(service.NewBatchPolicyField(fsoFieldBatching)).
+		Field(service.NewIntField(fsoFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"User Profiles",
+			"In this example user profile updates are merged into the documents of a collection keyed by the ID of each user.",
+			`
+output:
+  gcp_firestore:
+    project: my-project
+    collection: users
+    document_id: ${! this.id }
+    fields_mapping: 'root = this.without("id")'
+    merge: true
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"gcp_firestore", firestoreOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(fsoFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(fsoFieldBatching); err != nil {
+				return
+			}
+			out, err = newFirestoreOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type firestoreOutput struct {
+	dbPath     string
+	collection *service.InterpolatedString
+	documentID *service.InterpolatedString
+	mapping    *bloblang.Executor
+	merge      bool
+
+	connMut sync.RWMutex
+	conn    *grpc.ClientConn
+	client  firestorepb.FirestoreClient
+}
+
+func newFirestoreOutputFromParsed(conf *service.ParsedConfig) (*firestoreOutput, error) {
+	f := &firestoreOutput{}
+
+	project, err := conf.FieldString(fsoFieldProject)
+	if err != nil {
+		return nil, err
+	}
+	database, err := conf.FieldString(fsoFieldDatabase)
+	if err != nil {
+		return nil, err
+	}
+	f.dbPath = firestoreDatabasePath(project, database)
+
+	if f.collection, err = conf.FieldInterpolatedString(fsoFieldCollection); err != nil {
+		return nil, err
+	}
+	if f.documentID, err = conf.FieldInterpolatedString(fsoFieldDocumentID); err != nil {
+		return nil, err
+	}
+	if conf.Contains(fsoFieldMapping) {
+		if f.mapping, err = conf.FieldBloblang(fsoFieldMapping); err != nil {
+			return nil, err
+		}
+	}
+	if f.merge, err = conf.FieldBool(fsoFieldMerge); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *firestoreOutput) Connect(ctx context.Context) error {
+	f.connMut.Lock()
+	defer f.connMut.Unlock()
+
+	if f.conn != nil {
+		return nil
+	}
+
+	conn, err := firestoreDial(ctx)
+	if err != nil {
+		return err
+	}
+	f.conn = conn
+	f.client = firestorepb.NewFirestoreClient(conn)
+	return nil
+}
+
+const firestoreIDChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// firestoreAutoID generates a random document ID in the same form as those
+// generated by the Firestore client libraries.
+func firestoreAutoID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = firestoreIDChars[int(b[i])%len(firestoreIDChars)]
+	}
+	return string(b), nil
+}
+
+func (f *firestoreOutput) write(batch service.MessageBatch, index int) (*firestorepb.Write, error) {
+	collection := strings.Trim(batch.InterpolatedString(index, f.collection), "/")
+	if collection == "" {
+		return nil, errors.New("collection resolved to an empty path")
+	}
+	id := batch.InterpolatedString(index, f.documentID)
+	if id == "" {
+		var err error
+		if id, err = firestoreAutoID(); err != nil {
+			return nil, err
+		}
+	} else if strings.Contains(id, "/") {
+		return nil, fmt.Errorf("document id must not contain slashes: %v", id)
+	}
+
+	var v any
+	var err error
+	if f.mapping == nil {
+		v, err = batch[index].AsStructured()
+	} else {
+		var res *service.Message
+		if res, err = batch.BloblangQuery(index, f.mapping); err == nil {
+			if res == nil {
+				return nil, errors.New("fields mapping resulted in a deleted message")
+			}
+			v, err = res.AsStructured()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("document fields must be an object, got %T", v)
+	}
+	fields, err := toFirestoreFields(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &firestorepb.Write{
+		Operation: &firestorepb.Write_Update{
+			Update: &firestorepb.Document{
+				Name:   f.dbPath + "/documents/" + collection + "/" + id,
+				Fields: fields,
+			},
+		},
+	}
+	if f.merge {
+		paths := make([]string, 0, len(obj))
+		for k := range obj {
+			paths = append(paths, firestoreFieldPath(k))
+		}
+		sort.Strings(paths)
+		w.UpdateMask = &firestorepb.DocumentMask{FieldPaths: paths}
+	}
+	return w, nil
+}
+
+func (f *firestoreOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	f.connMut.RLock()
+	client := f.client
+	f.connMut.RUnlock()
+
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	failed := func(index int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(index, err)
+	}
+
+	var writes []*firestorepb.Write
+	var indexes []int
+	for i := range batch {
+		w, err := f.write(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		writes = append(writes, w)
+		indexes = append(indexes, i)
+	}
+
+	ctx = firestoreContext(ctx, f.dbPath)
+	for start := 0; start < len(writes); start += firestoreMaxBatchWrites {
+		end := start + firestoreMaxBatchWrites
+		if end > len(writes) {
+			end = len(writes)
+		}
+
+		res, err := client.BatchWrite(ctx, &firestorepb.BatchWriteRequest{
+			Database: f.dbPath,
+			Writes:   writes[start:end],
+		})
+		if err != nil {
+			if batchErr == nil && start == 0 && end == len(writes) {
+				return err
+			}
+			for _, i := range indexes[start:end] {
+				failed(i, err)
+			}
+			continue
+		}
+
+		for j, s := range res.GetStatus() {
+			if j >= end-start {
+				break
+			}
+			if codes.Code(s.GetCode()) != codes.OK {
+				failed(indexes[start+j], fmt.Errorf("%v: %v", codes.Code(s.GetCode()), s.GetMessage()))
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (f *firestoreOutput) Close(ctx context.Context) error {
+	f.connMut.Lock()
+	defer f.connMut.Unlock()
+
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	f.client = nil
+	return err
+}
@@ -0,0 +1,96 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestFirestoreOutputWrites(t *testing.T) {
+	srv := &testFirestoreServer{}
+	startTestFirestore(t, srv)
+
+	conf, err := firestoreOutputSpec().ParseYAML(`
+project: foo
+collection: users/${! this.user }/orders
+document_id: ${! this.id }
+fields_mapping: 'root = this.without("user", "id")'
+merge: true
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newFirestoreOutputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	err = out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"user":"alice","id":"a","total":10,"item name":"hat"}`)),
+		service.NewMessage([]byte(`{"user":"alice","id":"fail1","total":5}`)),
+		service.NewMessage([]byte(`{"user":"bob","id":"b/c","total":5}`)),
+		service.NewMessage([]byte(`{"user":"bob","id":"b","total":2.5}`)),
+	})
+	require.Error(t, err)
+	require.NoError(t, out.Close(context.Background()))
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2}, failed)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.writes, 2)
+
+	first := srv.writes[0]
+	assert.Equal(t, "projects/foo/databases/(default)/documents/users/alice/orders/a", first.GetUpdate().GetName())
+	assert.Equal(t, []string{"`item name`", "total"}, first.GetUpdateMask().GetFieldPaths())
+	assert.Equal(t, int64(10), first.GetUpdate().GetFields()["total"].GetIntegerValue())
+	assert.Equal(t, "hat", first.GetUpdate().GetFields()["item name"].GetStringValue())
+
+	second := srv.writes[1]
+	assert.Equal(t, "projects/foo/databases/(default)/documents/users/bob/orders/b", second.GetUpdate().GetName())
+	assert.Equal(t, 2.5, second.GetUpdate().GetFields()["total"].GetDoubleValue())
+}
+
+func TestFirestoreOutputAutoID(t *testing.T) {
+	srv := &testFirestoreServer{}
+	startTestFirestore(t, srv)
+
+	conf, err := firestoreOutputSpec().ParseYAML(`
+project: foo
+collection: events
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newFirestoreOutputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"n":1}`)),
+		service.NewMessage([]byte(`{"n":2}`)),
+	}))
+	require.NoError(t, out.Close(context.Background()))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.writes, 2)
+	for _, w := range srv.writes {
+		assert.Regexp(t, `^projects/foo/databases/\(default\)/documents/events/[a-zA-Z0-9]{20}$`, w.GetUpdate().GetName())
+		assert.Nil(t, w.GetUpdateMask())
+	}
+	assert.NotEqual(t, srv.writes[0].GetUpdate().GetName(), srv.writes[1].GetUpdate().GetName())
+}
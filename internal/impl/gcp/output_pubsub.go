@@ -30,6 +30,10 @@ func init() {
 		Description: output.Description(true, false, `
 For information on how to set up credentials check out [this guide](https://cloud.google.com/docs/authentication/production).
 
+### Ordering Keys
+
+When an `+"`ordering_key`"+` is set messages that share a key are delivered in the order they were published to subscriptions with message ordering enabled. If a message fails to be published then the client pauses publishing of its ordering key in order to preserve ordering, and publishing of the key is resumed once the failed message is reattempted. It is therefore recommended to use `+"`max_in_flight: 1`"+` when strict ordering is required.
+
 ### Troubleshooting
 
 If you're consistently seeing `+"`Failed to send message to gcp_pubsub: context deadline exceeded`"+` error logs without any further information it is possible that you are encountering https://github.com/benthosdev/benthos/issues/1042, which occurs when metadata values contain characters that are not valid utf-8. This can frequently occur when consuming from Kafka as the key metadata field may be populated with an arbitrary binary value, but this issue is not exclusive to Kafka.
@@ -177,6 +181,7 @@ func (c *gcpPubSubWriter) WriteBatch(ctx context.Context, msg message.Batch) err
 	}
 
 	results := make([]*pubsub.PublishResult, msg.Len())
+	orderingKeys := make([]string, msg.Len())
 	_ = msg.Iter(func(i int, part *message.Part) error {
 		topic := topics[i]
 		attr := map[string]string{}
@@ -188,7 +193,8 @@ func (c *gcpPubSubWriter) WriteBatch(ctx context.Context, msg message.Batch) err
 			Data: part.AsBytes(),
 		}
 		if c.orderingEnabled {
-			gmsg.OrderingKey = c.orderingKey.String(i, msg)
+			orderingKeys[i] = c.orderingKey.String(i, msg)
+			gmsg.OrderingKey = orderingKeys[i]
 		}
 		if len(attr) > 0 {
 			gmsg.Attributes = attr
@@ -204,6 +210,13 @@ func (c *gcpPubSubWriter) WriteBatch(ctx context.Context, msg message.Batch) err
 				batchErr = batch.NewError(msg, err)
 			}
 			batchErr.Failed(i, err)
+
+			// Publishing of an ordering key is paused after an error, the
+			// failed message is reattempted and so we resume publishing of the
+			// key in order to allow the reattempt to succeed.
+			if key := orderingKeys[i]; key != "" {
+				topics[i].ResumePublish(key)
+			}
 		}
 	}
 	if batchErr != nil {
@@ -0,0 +1,152 @@
+package gcp
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+const pubSubTestProject = "benthos-test-project"
+
+func runPubSubTestServer(t *testing.T, opts ...pstest.ServerReactorOption) *pubsub.Client {
+	t.Helper()
+
+	srv := pstest.NewServer(opts...)
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	client, err := pubsub.NewClient(context.Background(), pubSubTestProject)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
+
+type fakePubSubAckResult struct {
+	status pubsub.AcknowledgeStatus
+	err    error
+}
+
+func (f fakePubSubAckResult) Get(ctx context.Context) (pubsub.AcknowledgeStatus, error) {
+	return f.status, f.err
+}
+
+func TestPubSubAwaitAckResult(t *testing.T) {
+	ctx := context.Background()
+
+	require.NoError(t, awaitPubSubAckResult(ctx, fakePubSubAckResult{status: pubsub.AcknowledgeStatusSuccess}))
+
+	err := awaitPubSubAckResult(ctx, fakePubSubAckResult{err: errors.New("nope")})
+	require.EqualError(t, err, "failed to confirm acknowledgement: nope")
+
+	err = awaitPubSubAckResult(ctx, fakePubSubAckResult{status: pubsub.AcknowledgeStatusInvalidAckID})
+	require.EqualError(t, err, "acknowledgement was not successful: invalid ack id")
+
+	err = awaitPubSubAckResult(ctx, fakePubSubAckResult{status: pubsub.AcknowledgeStatusPermissionDenied})
+	require.EqualError(t, err, "acknowledgement was not successful: permission denied")
+}
+
+func TestPubSubInputExactlyOnceDetection(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	client := runPubSubTestServer(t)
+
+	topic, err := client.CreateTopic(ctx, "foo")
+	require.NoError(t, err)
+
+	for _, exactlyOnce := range []bool{false, true} {
+		subID := "without-exactly-once"
+		if exactlyOnce {
+			subID = "with-exactly-once"
+		}
+		_, err = client.CreateSubscription(ctx, subID, pubsub.SubscriptionConfig{
+			Topic:                     topic,
+			EnableExactlyOnceDelivery: exactlyOnce,
+		})
+		require.NoError(t, err)
+
+		conf := input.NewGCPPubSubConfig()
+		conf.ProjectID = pubSubTestProject
+		conf.SubscriptionID = subID
+		conf.ExactlyOnceDelivery = true
+
+		reader, err := newGCPPubSubReader(conf, log.Noop(), nil)
+		require.NoError(t, err)
+		require.NoError(t, reader.Connect(ctx))
+		assert.Equal(t, exactlyOnce, reader.confirmAcks, subID)
+
+		_, err = topic.Publish(ctx, &pubsub.Message{Data: []byte("hello " + subID)}).Get(ctx)
+		require.NoError(t, err)
+
+		batch, ackFn, err := reader.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+		assert.Equal(t, "hello "+subID, string(batch[0].AsBytes()))
+		require.NoError(t, ackFn(ctx, nil))
+
+		require.NoError(t, reader.Close(ctx))
+	}
+	topic.Stop()
+}
+
+// failOnceReactor fails the first request it receives and passes subsequent
+// requests through to the server.
+type failOnceReactor struct {
+	calls int32
+}
+
+func (f *failOnceReactor) React(_ any) (bool, any, error) {
+	if atomic.AddInt32(&f.calls, 1) == 1 {
+		return true, nil, status.Error(codes.InvalidArgument, "nope")
+	}
+	return false, nil, nil
+}
+
+func TestPubSubOutputResumePublish(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	client := runPubSubTestServer(t, pstest.ServerReactorOption{
+		FuncName: "Publish",
+		Reactor:  &failOnceReactor{},
+	})
+
+	topic, err := client.CreateTopic(ctx, "foo")
+	require.NoError(t, err)
+	topic.Stop()
+
+	conf := output.NewGCPPubSubConfig()
+	conf.ProjectID = pubSubTestProject
+	conf.TopicID = "foo"
+	conf.OrderingKey = "bar"
+
+	writer, err := newGCPPubSubWriter(conf, mock.NewManager(), log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, writer.Connect(ctx))
+
+	// Publishing of the ordering key is paused by the failure, and must be
+	// resumed in order for the reattempt to succeed.
+	require.Error(t, writer.WriteBatch(ctx, message.QuickBatch([][]byte{[]byte("first")})))
+	require.NoError(t, writer.WriteBatch(ctx, message.QuickBatch([][]byte{[]byte("first")})))
+
+	require.NoError(t, writer.Close(ctx))
+}
@@ -0,0 +1,92 @@
+package gdrive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gdFieldOAuth2       = "oauth2"
+	gdFieldClientID     = "client_id"
+	gdFieldClientSecret = "client_secret"
+	gdFieldRefreshToken = "refresh_token"
+	gdFieldTokenURL     = "token_url"
+)
+
+func gdriveAuthField() *service.ConfigField {
+	return service.NewObjectField(gdFieldOAuth2,
+		service.NewStringField(gdFieldClientID).
+			Description("The client ID of an OAuth 2.0 client of a Google Cloud project.").
+			Default(""),
+		service.NewStringField(gdFieldClientSecret).
+			Description("The client secret of the OAuth 2.0 client.").
+			Default(""),
+		service.NewStringField(gdFieldRefreshToken).
+			Description("A refresh token of a user that has authorised the client to access their Drive, which is used in order to obtain access tokens. When empty Application Default Credentials are used instead.").
+			Default(""),
+		service.NewStringField(gdFieldTokenURL).
+			Description("The URL to obtain access tokens from.").
+			Advanced().
+			Default(google.Endpoint.TokenURL),
+	).Description("Credentials of a user to access Google Drive as with the OAuth 2.0 refresh token flow.")
+}
+
+// gdriveClientOptions returns the options for creating a Drive service from
+// the credentials of a config.
+func gdriveClientOptions(conf *service.ParsedConfig, scope string) ([]option.ClientOption, error) {
+	conf = conf.Namespace(gdFieldOAuth2)
+
+	refreshToken, err := conf.FieldString(gdFieldRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if refreshToken == "" {
+		return []option.ClientOption{option.WithScopes(scope)}, nil
+	}
+
+	oConf := &oauth2.Config{Scopes: []string{scope}}
+	if oConf.ClientID, err = conf.FieldString(gdFieldClientID); err != nil {
+		return nil, err
+	}
+	if oConf.ClientSecret, err = conf.FieldString(gdFieldClientSecret); err != nil {
+		return nil, err
+	}
+	if oConf.Endpoint.TokenURL, err = conf.FieldString(gdFieldTokenURL); err != nil {
+		return nil, err
+	}
+	if oConf.ClientID == "" {
+		return nil, fmt.Errorf("a %v must be specified with a %v", gdFieldClientID, gdFieldRefreshToken)
+	}
+
+	// Access tokens are exchanged for the refresh token by the Drive client
+	// whenever they expire, which can happen long after this function returns.
+	ts := oConf.TokenSource(context.Background(), &oauth2.Token{RefreshToken: refreshToken})
+	return []option.ClientOption{option.WithTokenSource(ts)}, nil
+}
+
+// gdriveQuote quotes a string value for use within a Drive query.
+func gdriveQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// gdriveIsPermanent returns whether an error of the Drive API is the result
+// of the request itself, and therefore will not succeed when retried. Rate
+// limits are enforced with a 403 status code and so it is never permanent.
+func gdriveIsPermanent(err error) bool {
+	var gErr *googleapi.Error
+	if !errors.As(err, &gErr) {
+		return false
+	}
+	return gErr.Code >= 400 && gErr.Code < 500 && gErr.Code != 408 && gErr.Code != 429 && gErr.Code != 403
+}
@@ -0,0 +1,349 @@
+package gdrive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gdiFieldFolderID     = "folder_id"
+	gdiFieldExportMime   = "export_mime_type"
+	gdiFieldWatch        = "watch"
+	gdiFieldWatchEnabled = "enabled"
+	gdiFieldWatchPoll    = "poll_interval"
+	gdiFieldPageSize     = "page_size"
+
+	gdriveFolderMime    = "application/vnd.google-apps.folder"
+	gdriveShortcutMime  = "application/vnd.google-apps.shortcut"
+	gdriveWorkspaceMime = "application/vnd.google-apps."
+
+	gdriveFileFields = "id,name,mimeType,modifiedTime,md5Checksum,parents,trashed"
+)
+
+func gdriveInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Downloads files from Google Drive.").
+		Description(`
+Lists the files of a folder, or all files accessible to the user when `+"`folder_id`"+` is empty, and downloads each file as a message. Google Workspace files such as Docs and Sheets, which cannot be downloaded directly, are exported with the MIME type `+"`export_mime_type`"+`.
+
+When `+"`watch.enabled`"+` is true the input continues once all files have been listed by polling the [changes](https://developers.google.com/drive/api/guides/manage-changes) of the Drive, and downloads any file that is created or modified within the folder. Otherwise the input shuts down once all files have been downloaded. The position within the changes of the Drive is not persisted, and therefore all files are listed again when the input is restarted.
+
+### Credentials
+
+By default Benthos uses Application Default Credentials, which for Drive are usually the credentials of a service account that the folder has been shared with. Alternatively the fields of `+"`oauth2`"+` can be set in order to access the Drive of a user with a refresh token, which is obtained when the user authorises an OAuth 2.0 client with the scope `+"`https://www.googleapis.com/auth/drive.readonly`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- google_drive_id
+- google_drive_name
+- google_drive_mime_type
+- google_drive_modified_time
+- google_drive_md5_checksum
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(gdriveAuthField()).
+		Field(service.NewStringField(gdiFieldFolderID).
+			Description("The ID of a folder to download the files within. Files within subfolders are not downloaded. When empty all files accessible to the user are downloaded.").
+			Example("1dyUEebJaFnWa3Z4n0BFMVAXQ7mfUH11g").
+			Default("")).
+		Field(service.NewStringField(gdiFieldExportMime).
+			Description("The MIME type to export Google Workspace files as.").
+			Example("text/csv").
+			Advanced().
+			Default("application/pdf")).
+		Field(service.NewObjectField(gdiFieldWatch,
+			service.NewBoolField(gdiFieldWatchEnabled).
+				Description("Whether to poll for changes to files once all files have been listed.").
+				Default(false),
+			service.NewDurationField(gdiFieldWatchPoll).
+				Description("The period of time between each poll for changes.").
+				Default("1m"),
+		).Description("Options for polling the changes of the Drive for files that are created or modified.")).
+		Field(service.NewIntField(gdiFieldPageSize).
+			Description("The maximum number of files to list with each request.").
+			Advanced().
+			Default(100)).
+		Example(
+			"Invoice Folder",
+			"In this example invoices are downloaded from a shared folder as they are uploaded.",
+			`
+input:
+  google_drive:
+    folder_id: 1dyUEebJaFnWa3Z4n0BFMVAXQ7mfUH11g
+    watch:
+      enabled: true
+      poll_interval: 30s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"google_drive", gdriveInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newGDriveInputFromParsed(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type gdriveInput struct {
+	opts         []option.ClientOption
+	folderID     string
+	exportMime   string
+	watch        bool
+	pollInterval time.Duration
+	pageSize     int64
+	log          *service.Logger
+
+	mut          sync.Mutex
+	svc          *drive.Service
+	pending      []*drive.File
+	listToken    string
+	listed       bool
+	changesToken string
+	lastPoll     time.Time
+}
+
+func newGDriveInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*gdriveInput, error) {
+	g := &gdriveInput{log: log}
+
+	var err error
+	if g.opts, err = gdriveClientOptions(conf, drive.DriveReadonlyScope); err != nil {
+		return nil, err
+	}
+	if g.folderID, err = conf.FieldString(gdiFieldFolderID); err != nil {
+		return nil, err
+	}
+	if g.exportMime, err = conf.FieldString(gdiFieldExportMime); err != nil {
+		return nil, err
+	}
+	if g.watch, err = conf.FieldBool(gdiFieldWatch, gdiFieldWatchEnabled); err != nil {
+		return nil, err
+	}
+	if g.pollInterval, err = conf.FieldDuration(gdiFieldWatch, gdiFieldWatchPoll); err != nil {
+		return nil, err
+	}
+	pageSize, err := conf.FieldInt(gdiFieldPageSize)
+	if err != nil {
+		return nil, err
+	}
+	g.pageSize = int64(pageSize)
+	return g, nil
+}
+
+func (g *gdriveInput) Connect(ctx context.Context) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.svc != nil {
+		return nil
+	}
+
+	svc, err := drive.NewService(ctx, g.opts...)
+	if err != nil {
+		return err
+	}
+	if g.watch && g.changesToken == "" {
+		// The token is obtained before listing files so that changes made
+		// whilst files are listed are not missed.
+		res, err := svc.Changes.GetStartPageToken().SupportsAllDrives(true).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to obtain changes token: %w", err)
+		}
+		g.changesToken = res.StartPageToken
+	}
+	g.svc = svc
+	return nil
+}
+
+func (g *gdriveInput) query() string {
+	q := fmt.Sprintf("trashed = false and mimeType != %v", gdriveQuote(gdriveFolderMime))
+	if g.folderID != "" {
+		q += fmt.Sprintf(" and %v in parents", gdriveQuote(g.folderID))
+	}
+	return q
+}
+
+// wanted returns whether a file from the changes of the Drive is one that
+// would be listed.
+func (g *gdriveInput) wanted(f *drive.File) bool {
+	if f == nil || f.Trashed || f.MimeType == gdriveFolderMime || f.MimeType == gdriveShortcutMime {
+		return false
+	}
+	if g.folderID == "" {
+		return true
+	}
+	for _, p := range f.Parents {
+		if p == g.folderID {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gdriveInput) listPage(ctx context.Context) error {
+	call := g.svc.Files.List().
+		Q(g.query()).
+		PageSize(g.pageSize).
+		OrderBy("modifiedTime").
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Fields("nextPageToken", "files("+gdriveFileFields+")").
+		Context(ctx)
+	if g.listToken != "" {
+		call = call.PageToken(g.listToken)
+	}
+	res, err := call.Do()
+	if err != nil {
+		return err
+	}
+	for _, f := range res.Files {
+		if f.MimeType != gdriveShortcutMime {
+			g.pending = append(g.pending, f)
+		}
+	}
+	if g.listToken = res.NextPageToken; g.listToken == "" {
+		g.listed = true
+	}
+	return nil
+}
+
+func (g *gdriveInput) pollChanges(ctx context.Context) error {
+	res, err := g.svc.Changes.List(g.changesToken).
+		PageSize(g.pageSize).
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Fields("nextPageToken", "newStartPageToken", "changes(removed,file("+gdriveFileFields+"))").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return err
+	}
+	for _, c := range res.Changes {
+		if !c.Removed && g.wanted(c.File) {
+			g.pending = append(g.pending, c.File)
+		}
+	}
+	if res.NextPageToken != "" {
+		g.changesToken = res.NextPageToken
+	} else {
+		g.changesToken = res.NewStartPageToken
+		g.lastPoll = time.Now()
+	}
+	return nil
+}
+
+func (g *gdriveInput) download(ctx context.Context, f *drive.File) ([]byte, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(f.MimeType, gdriveWorkspaceMime) {
+		res, err := g.svc.Files.Export(f.Id, g.exportMime).Context(ctx).Download()
+		if err != nil {
+			return nil, err
+		}
+		body = res.Body
+	} else {
+		res, err := g.svc.Files.Get(f.Id).SupportsAllDrives(true).Context(ctx).Download()
+		if err != nil {
+			return nil, err
+		}
+		body = res.Body
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// fetch adds the next files to download to the pending files, either from
+// the listing of files or by polling for changes, and blocks until it is time
+// to poll when all changes have been consumed.
+func (g *gdriveInput) fetch(ctx context.Context) error {
+	if !g.listed {
+		if err := g.listPage(ctx); err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		return nil
+	}
+	if !g.watch {
+		return service.ErrEndOfInput
+	}
+	if wait := time.Until(g.lastPoll.Add(g.pollInterval)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := g.pollChanges(ctx); err != nil {
+		return fmt.Errorf("failed to poll changes: %w", err)
+	}
+	return nil
+}
+
+func (g *gdriveInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.svc == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var f *drive.File
+	var data []byte
+	for f == nil {
+		if len(g.pending) == 0 {
+			if err := g.fetch(ctx); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		var err error
+		if data, err = g.download(ctx, g.pending[0]); err != nil {
+			if !gdriveIsPermanent(err) {
+				return nil, nil, fmt.Errorf("failed to download file %v: %w", g.pending[0].Id, err)
+			}
+			g.log.Errorf("Skipping file %v as it could not be downloaded: %v", g.pending[0].Id, err)
+		} else {
+			f = g.pending[0]
+		}
+		g.pending = g.pending[1:]
+	}
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut("google_drive_id", f.Id)
+	msg.MetaSetMut("google_drive_name", f.Name)
+	msg.MetaSetMut("google_drive_mime_type", f.MimeType)
+	msg.MetaSetMut("google_drive_modified_time", f.ModifiedTime)
+	if f.Md5Checksum != "" {
+		msg.MetaSetMut("google_drive_md5_checksum", f.Md5Checksum)
+	}
+	return msg, func(context.Context, error) error { return nil }, nil
+}
+
+func (g *gdriveInput) Close(ctx context.Context) error {
+	g.mut.Lock()
+	g.svc = nil
+	g.mut.Unlock()
+	return nil
+}
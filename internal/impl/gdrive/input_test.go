@@ -0,0 +1,265 @@
+package gdrive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testDriveFile struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	MimeType string   `json:"mimeType"`
+	Parents  []string `json:"parents"`
+	content  string
+	gone     bool
+}
+
+// testDrive is a fake of the small subset of the Drive API used by the
+// components, which requires requests to be authorised with the access token
+// obtained from its token endpoint.
+type testDrive struct {
+	t *testing.T
+
+	mut      sync.Mutex
+	files    []*testDriveFile
+	changes  []*testDriveFile
+	queries  []string
+	tokens   int
+	uploaded int
+}
+
+func (d *testDrive) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (d *testDrive) file(id string) *testDriveFile {
+	for _, f := range d.files {
+		if f.ID == id && !f.gone {
+			return f
+		}
+	}
+	return nil
+}
+
+func (d *testDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	if r.URL.Path == "/token" {
+		require.NoError(d.t, r.ParseForm())
+		assert.Equal(d.t, "refresh_token", r.Form.Get("grant_type"))
+		assert.Equal(d.t, "foorefresh", r.Form.Get("refresh_token"))
+		d.tokens++
+		d.writeJSON(w, map[string]any{"access_token": "fooaccess", "token_type": "Bearer", "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer fooaccess" {
+		http.Error(w, `{"error":{"code":401,"message":"nope"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/drive/v3")
+	switch {
+	case r.Method == "GET" && path == "/files":
+		q := r.URL.Query()
+		d.queries = append(d.queries, q.Get("q"))
+
+		// Pages of a single file, where the page token is the next index.
+		index := len(q.Get("pageToken"))
+		res := map[string]any{"files": []any{}}
+		if strings.Contains(q.Get("q"), "name = ") {
+			for _, f := range d.files {
+				if strings.Contains(q.Get("q"), "'"+f.Name+"'") {
+					res["files"] = []any{f}
+				}
+			}
+		} else if index < len(d.files) {
+			res["files"] = []any{d.files[index]}
+			if index+1 < len(d.files) {
+				res["nextPageToken"] = strings.Repeat("x", index+1)
+			}
+		}
+		d.writeJSON(w, res)
+	case r.Method == "GET" && strings.HasPrefix(path, "/files/") && strings.HasSuffix(path, "/export"):
+		f := d.file(strings.TrimSuffix(strings.TrimPrefix(path, "/files/"), "/export"))
+		if f == nil {
+			http.Error(w, `{"error":{"code":404,"message":"nope"}}`, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(f.content + " as " + r.URL.Query().Get("mimeType")))
+	case r.Method == "GET" && strings.HasPrefix(path, "/files/"):
+		f := d.file(strings.TrimPrefix(path, "/files/"))
+		if f == nil {
+			http.Error(w, `{"error":{"code":404,"message":"nope"}}`, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(f.content))
+	case r.Method == "GET" && path == "/changes/startPageToken":
+		d.writeJSON(w, map[string]any{"startPageToken": "1"})
+	case r.Method == "GET" && path == "/changes":
+		var changes []any
+		if r.URL.Query().Get("pageToken") == "1" {
+			for _, f := range d.changes {
+				changes = append(changes, map[string]any{"file": f})
+				d.files = append(d.files, f)
+			}
+		}
+		d.writeJSON(w, map[string]any{"changes": changes, "newStartPageToken": "2"})
+	case r.Method == "POST" && r.URL.Path == "/upload/drive/v3/files",
+		r.Method == "PATCH" && strings.HasPrefix(r.URL.Path, "/upload/drive/v3/files/"):
+		f := &testDriveFile{}
+		d.readUpload(r, f)
+		if r.Method == "POST" {
+			f.ID = "new" + f.Name
+			d.files = append(d.files, f)
+		} else if existing := d.file(strings.TrimPrefix(r.URL.Path, "/upload/drive/v3/files/")); existing != nil {
+			existing.content = f.content
+			existing.MimeType = f.MimeType
+		}
+		d.uploaded++
+		d.writeJSON(w, map[string]any{"id": f.ID})
+	default:
+		http.Error(w, `{"error":{"code":404,"message":"nope"}}`, http.StatusNotFound)
+	}
+}
+
+func (d *testDrive) readUpload(r *http.Request, f *testDriveFile) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	require.NoError(d.t, err)
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	meta, err := mr.NextPart()
+	require.NoError(d.t, err)
+	require.NoError(d.t, json.NewDecoder(meta).Decode(f))
+
+	media, err := mr.NextPart()
+	require.NoError(d.t, err)
+	b, err := io.ReadAll(media)
+	require.NoError(d.t, err)
+	f.content = string(b)
+}
+
+func startTestDrive(t *testing.T) (*testDrive, string) {
+	t.Helper()
+
+	d := &testDrive{t: t}
+	srv := httptest.NewServer(d)
+	t.Cleanup(srv.Close)
+	return d, srv.URL
+}
+
+func testDriveAuthConf(url string) string {
+	return `
+oauth2:
+  client_id: fooclient
+  client_secret: foosecret
+  refresh_token: foorefresh
+  token_url: ` + url + `/token
+`
+}
+
+func TestGDriveInput(t *testing.T) {
+	d, url := startTestDrive(t)
+	d.files = []*testDriveFile{
+		{ID: "a", Name: "a.txt", MimeType: "text/plain", Parents: []string{"foofolder"}, content: "hello"},
+		{ID: "b", Name: "b", MimeType: "application/vnd.google-apps.document", Parents: []string{"foofolder"}, content: "doc"},
+	}
+	d.changes = []*testDriveFile{
+		{ID: "c", Name: "c.txt", MimeType: "text/plain", Parents: []string{"foofolder"}, content: "world"},
+		{ID: "d", Name: "d.txt", MimeType: "text/plain", Parents: []string{"otherfolder"}, content: "nope"},
+		{ID: "e", Name: "e", MimeType: gdriveFolderMime, Parents: []string{"foofolder"}},
+	}
+
+	conf, err := gdriveInputSpec().ParseYAML(testDriveAuthConf(url)+`
+folder_id: foofolder
+export_mime_type: text/plain
+watch:
+  enabled: true
+  poll_interval: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newGDriveInputFromParsed(conf, nil)
+	require.NoError(t, err)
+	in.opts = append(in.opts, option.WithEndpoint(url+"/drive/v3/"))
+	require.NoError(t, in.Connect(context.Background()))
+
+	var contents, names []string
+	for i := 0; i < 3; i++ {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		msg, ackFn, err := in.Read(ctx)
+		done()
+		require.NoError(t, err)
+		require.NoError(t, ackFn(context.Background(), nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(b))
+
+		name, _ := msg.MetaGet("google_drive_name")
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"hello", "doc as text/plain", "world"}, contents)
+	assert.Equal(t, []string{"a.txt", "b", "c.txt"}, names)
+
+	// No further changes are found.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	_, _, err = in.Read(ctx)
+	done()
+	require.Error(t, err)
+
+	require.NoError(t, in.Close(context.Background()))
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	assert.Equal(t, 1, d.tokens)
+	assert.Equal(t, "trashed = false and mimeType != 'application/vnd.google-apps.folder' and 'foofolder' in parents", d.queries[0])
+}
+
+func TestGDriveInputEndOfInput(t *testing.T) {
+	d, url := startTestDrive(t)
+	d.files = []*testDriveFile{
+		{ID: "a", Name: "a.txt", MimeType: "text/plain", content: "hello"},
+		{ID: "gone", Name: "gone.txt", MimeType: "text/plain", gone: true},
+	}
+
+	conf, err := gdriveInputSpec().ParseYAML(testDriveAuthConf(url), nil)
+	require.NoError(t, err)
+
+	in, err := newGDriveInputFromParsed(conf, nil)
+	require.NoError(t, err)
+	in.opts = append(in.opts, option.WithEndpoint(url+"/drive/v3/"))
+	require.NoError(t, in.Connect(context.Background()))
+
+	msg, _, err := in.Read(context.Background())
+	require.NoError(t, err)
+	id, _ := msg.MetaGet("google_drive_id")
+	assert.Equal(t, "a", id)
+
+	// Files that can no longer be downloaded are skipped.
+	_, _, err = in.Read(context.Background())
+	require.True(t, errors.Is(err, service.ErrEndOfInput), err)
+}
+
+func TestGDriveQuote(t *testing.T) {
+	assert.Equal(t, `'foo'`, gdriveQuote("foo"))
+	assert.Equal(t, `'it\'s a \\ slash'`, gdriveQuote(`it's a \ slash`))
+}
@@ -0,0 +1,220 @@
+package gdrive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gdoFieldFolderID    = "folder_id"
+	gdoFieldName        = "name"
+	gdoFieldMimeType    = "mime_type"
+	gdoFieldOverwrite   = "overwrite"
+	gdoFieldMaxInFlight = "max_in_flight"
+)
+
+func gdriveOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Uploads messages as files to Google Drive.").
+		Description(`
+Each message is uploaded as a file with the name `+"`name`"+` within the folder `+"`folder_id`"+`. Drive allows multiple files of a folder to share a name, and therefore each message results in a new file unless `+"`overwrite`"+` is enabled, in which case the contents of an existing file with the same name are replaced instead.
+
+When `+"`mime_type`"+` is empty the MIME type of each file is detected from its contents.
+
+### Credentials
+
+By default Benthos uses Application Default Credentials, which for Drive are usually the credentials of a service account that the folder has been shared with. Alternatively the fields of `+"`oauth2`"+` can be set in order to access the Drive of a user with a refresh token, which is obtained when the user authorises an OAuth 2.0 client with the scope `+"`https://www.googleapis.com/auth/drive`"+`.`).
+		Field(gdriveAuthField()).
+		Field(service.NewInterpolatedStringField(gdoFieldFolderID).
+			Description("The ID of the folder to upload files to. When empty files are uploaded to the root of the Drive of the user.").
+			Example("1dyUEebJaFnWa3Z4n0BFMVAXQ7mfUH11g").
+			Default("")).
+		Field(service.NewInterpolatedStringField(gdoFieldName).
+			Description("The name of each file.").
+			Example(`${! timestamp_unix_nano() }.json`).
+			Example(`${! meta("kafka_key") }.csv`)).
+		Field(service.NewInterpolatedStringField(gdoFieldMimeType).
+			Description("The MIME type of each file.").
+			Example("application/json").
+			Default("")).
+		Field(service.NewBoolField(gdoFieldOverwrite).
+			Description("Whether to replace the contents of an existing file of the folder with the same name, rather than uploading a new file.").
+			Default(false)).
+		Field(service.NewIntField(gdoFieldMaxInFlight).
+			Description("The maximum number of files to upload in parallel.").
+			Default(8)).
+		Example(
+			"Daily Reports",
+			"In this example a report is uploaded to a shared folder for each day, replacing any earlier report of the same day.",
+			`
+output:
+  google_drive:
+    folder_id: 1dyUEebJaFnWa3Z4n0BFMVAXQ7mfUH11g
+    name: report-${! now().ts_format("2006-01-02") }.csv
+    mime_type: text/csv
+    overwrite: true
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"google_drive", gdriveOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(gdoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newGDriveOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type gdriveOutput struct {
+	opts      []option.ClientOption
+	folderID  *service.InterpolatedString
+	name      *service.InterpolatedString
+	mimeType  *service.InterpolatedString
+	overwrite bool
+
+	mut sync.RWMutex
+	svc *drive.Service
+}
+
+func newGDriveOutputFromParsed(conf *service.ParsedConfig) (*gdriveOutput, error) {
+	g := &gdriveOutput{}
+
+	var err error
+	if g.opts, err = gdriveClientOptions(conf, drive.DriveScope); err != nil {
+		return nil, err
+	}
+	if g.folderID, err = conf.FieldInterpolatedString(gdoFieldFolderID); err != nil {
+		return nil, err
+	}
+	if g.name, err = conf.FieldInterpolatedString(gdoFieldName); err != nil {
+		return nil, err
+	}
+	if g.mimeType, err = conf.FieldInterpolatedString(gdoFieldMimeType); err != nil {
+		return nil, err
+	}
+	if g.overwrite, err = conf.FieldBool(gdoFieldOverwrite); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gdriveOutput) Connect(ctx context.Context) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.svc != nil {
+		return nil
+	}
+
+	svc, err := drive.NewService(ctx, g.opts...)
+	if err != nil {
+		return err
+	}
+	g.svc = svc
+	return nil
+}
+
+// existing returns the ID of a file of a folder with a name, or an empty
+// string when there is no such file.
+func (g *gdriveOutput) existing(ctx context.Context, svc *drive.Service, folderID, name string) (string, error) {
+	if folderID == "" {
+		folderID = "root"
+	}
+	res, err := svc.Files.List().
+		Q(fmt.Sprintf("name = %v and %v in parents and trashed = false", gdriveQuote(name), gdriveQuote(folderID))).
+		PageSize(1).
+		OrderBy("modifiedTime desc").
+		SupportsAllDrives(true).
+		IncludeItemsFromAllDrives(true).
+		Fields("files(id)").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if len(res.Files) == 0 {
+		return "", nil
+	}
+	return res.Files[0].Id, nil
+}
+
+func (g *gdriveOutput) Write(ctx context.Context, msg *service.Message) error {
+	g.mut.RLock()
+	svc := g.svc
+	g.mut.RUnlock()
+
+	if svc == nil {
+		return service.ErrNotConnected
+	}
+
+	name := g.name.String(msg)
+	if name == "" {
+		return errors.New("name resolved to an empty string")
+	}
+	folderID := g.folderID.String(msg)
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	mimeType := g.mimeType.String(msg)
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	media := bytes.NewReader(data)
+
+	if g.overwrite {
+		id, err := g.existing(ctx, svc, folderID, name)
+		if err != nil {
+			return fmt.Errorf("failed to find existing file: %w", err)
+		}
+		if id != "" {
+			_, err = svc.Files.Update(id, &drive.File{MimeType: mimeType}).
+				Media(media, googleapi.ContentType(mimeType)).
+				SupportsAllDrives(true).
+				Fields("id").
+				Context(ctx).
+				Do()
+			return err
+		}
+	}
+
+	file := &drive.File{Name: name, MimeType: mimeType}
+	if folderID != "" {
+		file.Parents = []string{folderID}
+	}
+	_, err = svc.Files.Create(file).
+		Media(media, googleapi.ContentType(mimeType)).
+		SupportsAllDrives(true).
+		Fields("id").
+		Context(ctx).
+		Do()
+	return err
+}
+
+func (g *gdriveOutput) Close(ctx context.Context) error {
+	g.mut.Lock()
+	g.svc = nil
+	g.mut.Unlock()
+	return nil
+}
@@ -0,0 +1,53 @@
+package gdrive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestGDriveOutput(t *testing.T) {
+	d, url := startTestDrive(t)
+
+	conf, err := gdriveOutputSpec().ParseYAML(testDriveAuthConf(url)+`
+folder_id: foofolder
+name: ${! meta("name") }
+overwrite: true
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newGDriveOutputFromParsed(conf)
+	require.NoError(t, err)
+	out.opts = append(out.opts, option.WithEndpoint(url+"/drive/v3/"))
+	require.NoError(t, out.Connect(context.Background()))
+
+	for _, v := range []struct{ name, content string }{
+		{"a.json", `{"id":"a"}`},
+		{"b.txt", "hello world"},
+		{"a.json", `{"id":"a2"}`},
+	} {
+		msg := service.NewMessage([]byte(v.content))
+		msg.MetaSetMut("name", v.name)
+		require.NoError(t, out.Write(context.Background(), msg))
+	}
+	require.NoError(t, out.Close(context.Background()))
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	require.Len(t, d.files, 2)
+	assert.Equal(t, 3, d.uploaded)
+
+	assert.Equal(t, "a.json", d.files[0].Name)
+	assert.Equal(t, []string{"foofolder"}, d.files[0].Parents)
+	assert.Equal(t, `{"id":"a2"}`, d.files[0].content)
+	assert.Equal(t, "text/plain; charset=utf-8", d.files[0].MimeType)
+
+	assert.Equal(t, "b.txt", d.files[1].Name)
+	assert.Equal(t, "hello world", d.files[1].content)
+}
@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// loadProtoFiles creates a registry of descriptors from a list of files, where
+// files with a .proto extension are parsed as protobuf schemas, and any other
+// files are parsed as serialised FileDescriptorSet messages, such as those
+// created with `protoc --include_imports --descriptor_set_out`.
+func loadProtoFiles(paths, importPaths []string) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]struct{}{}
+	add := func(fdp *descriptorpb.FileDescriptorProto) {
+		if _, exists := seen[fdp.GetName()]; exists {
+			return
+		}
+		seen[fdp.GetName()] = struct{}{}
+		set.File = append(set.File, fdp)
+	}
+
+	var protoPaths []string
+	for _, path := range paths {
+		if filepath.Ext(path) == ".proto" {
+			protoPaths = append(protoPaths, path)
+			continue
+		}
+
+		setBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fileSet descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(setBytes, &fileSet); err != nil {
+			return nil, fmt.Errorf("failed to parse descriptor set %v: %w", path, err)
+		}
+		for _, fdp := range fileSet.File {
+			add(fdp)
+		}
+	}
+
+	if len(protoPaths) > 0 {
+		parser := protoparse.Parser{ImportPaths: importPaths}
+		fds, err := parser.ParseFiles(protoPaths...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse .proto file: %w", err)
+		}
+
+		// Dependencies are added before the files that import them.
+		var addWithDeps func(fd *desc.FileDescriptor)
+		addWithDeps = func(fd *desc.FileDescriptor) {
+			for _, dep := range fd.GetDependencies() {
+				addWithDeps(dep)
+			}
+			add(fd.AsFileDescriptorProto())
+		}
+		for _, fd := range fds {
+			addWithDeps(fd)
+		}
+	}
+
+	if len(set.File) == 0 {
+		return nil, errors.New("no descriptors were found within the files")
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link descriptors: %w", err)
+	}
+	return files, nil
+}
+
+// findMethod obtains the descriptor of a method from its full name, which can
+// be in the form `package.Service/Method` or `package.Service.Method`.
+func findMethod(files *protoregistry.Files, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndexAny(name, "/.")
+	if i <= 0 {
+		return nil, fmt.Errorf("method %v must be in the form package.Service/Method", name)
+	}
+	serviceName, methodName := name[:i], name[i+1:]
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find service %v: %w", serviceName, err)
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("service %v does not have a method %v", serviceName, methodName)
+	}
+	return method, nil
+}
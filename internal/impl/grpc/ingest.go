@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	_ "embed"
+	"io"
+	"strings"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+//go:embed ingest.proto
+var ingestProto string
+
+const ingestProtoName = "benthos/ingest/v1/ingest.proto"
+
+// The descriptors of the Ingest service, which are parsed from the embedded
+// .proto file in order that messages can be handled without generated code.
+var (
+	ingestService         protoreflect.ServiceDescriptor
+	ingestPublishRequest  protoreflect.MessageDescriptor
+	ingestPublishResponse protoreflect.MessageDescriptor
+)
+
+func init() {
+	parser := protoparse.Parser{
+		Accessor: func(filename string) (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(ingestProto)), nil
+		},
+	}
+	fds, err := parser.ParseFiles(ingestProtoName)
+	if err != nil {
+		panic(err)
+	}
+
+	file, err := protodesc.NewFile(fds[0].AsFileDescriptorProto(), protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+
+	// Registering the file allows clients to discover the service with server
+	// reflection.
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		panic(err)
+	}
+
+	ingestService = file.Services().ByName("Ingest")
+	ingestPublishRequest = file.Messages().ByName("PublishRequest")
+	ingestPublishResponse = file.Messages().ByName("PublishResponse")
+}
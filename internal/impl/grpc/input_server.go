@@ -0,0 +1,384 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gsiFieldAddress      = "address"
+	gsiFieldMaxInFlight  = "max_in_flight"
+	gsiFieldCertFile     = "cert_file"
+	gsiFieldKeyFile      = "key_file"
+	gsiFieldClientCAFile = "client_ca_file"
+)
+
+func grpcServerInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Receives messages from clients of a gRPC service, where each message is acknowledged to its client once it has been delivered.").
+		Description(`
+Clients publish messages with the bidirectional streaming RPC `+"`Publish`"+` of the service `+"`benthos.ingest.v1.Ingest`"+`, which is defined by the following .proto file:
+
+`+"```protobuf"+`
+`+ingestProto+"```"+`
+
+Each request is consumed as a message with the payload of the request, and the metadata of the request is added to the message as metadata. Once the message has been delivered a response with the ID of the request is sent to the client, and when delivery fails the response contains the error. It's therefore the responsibility of the client to retry the requests of failed messages.
+
+No more than `+"`max_in_flight`"+` messages of a stream are consumed before they are acknowledged, after which no further requests are received from the stream until messages are acknowledged, which applies back pressure to the client through the flow control of gRPC. When a client closes its side of a stream the stream is ended once the responses of all remaining messages have been sent.
+
+The service supports [server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md), which allows tools such as `+"[grpcurl](https://github.com/fullstorydev/grpcurl)"+` to publish messages without the .proto file.`).
+		Field(service.NewStringField(gsiFieldAddress).
+			Description("The address to listen on.").
+			Default("0.0.0.0:50051")).
+		Field(service.NewIntField(gsiFieldMaxInFlight).
+			Description("The maximum number of messages of each stream that can be consumed without having been acknowledged.").
+			Default(64)).
+		Field(service.NewStringField(gsiFieldCertFile).
+			Description("Enable TLS by specifying a certificate and key file. The certificate is reloaded when either file is modified.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField(gsiFieldKeyFile).
+			Description("Enable TLS by specifying a certificate and key file.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField(gsiFieldClientCAFile).
+			Description("Require clients to present a certificate that is signed by a certificate authority of this file, which enables mutual TLS. Requires TLS to be enabled.").
+			Advanced().
+			Default("")).
+		Example(
+			"Publishing With grpcurl",
+			"In this example messages are received on the default port, and can be published with grpcurl using server reflection.",
+			`
+input:
+  grpc_server:
+    address: 0.0.0.0:50051
+
+# grpcurl -plaintext -d '{"id":1,"payload":"aGVsbG8=","metadata":{"source":"cli"}}' \
+#   localhost:50051 benthos.ingest.v1.Ingest/Publish
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"grpc_server", grpcServerInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newGRPCServerInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type gsRequest struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type grpcServerInput struct {
+	address      string
+	maxInFlight  int
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	log          *service.Logger
+
+	reqChan chan gsRequest
+
+	mut      sync.Mutex
+	server   *grpc.Server
+	listener net.Listener
+	closed   chan struct{}
+}
+
+func newGRPCServerInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*grpcServerInput, error) {
+	g := &grpcServerInput{
+		log:     mgr.Logger(),
+		reqChan: make(chan gsRequest),
+		closed:  make(chan struct{}),
+	}
+
+	var err error
+	if g.address, err = conf.FieldString(gsiFieldAddress); err != nil {
+		return nil, err
+	}
+	if g.maxInFlight, err = conf.FieldInt(gsiFieldMaxInFlight); err != nil {
+		return nil, err
+	}
+	if g.maxInFlight < 1 {
+		return nil, errors.New("max_in_flight must be at least 1")
+	}
+	if g.certFile, err = conf.FieldString(gsiFieldCertFile); err != nil {
+		return nil, err
+	}
+	if g.keyFile, err = conf.FieldString(gsiFieldKeyFile); err != nil {
+		return nil, err
+	}
+	if (g.certFile == "") != (g.keyFile == "") {
+		return nil, errors.New("both a cert_file and key_file must be specified in order to enable TLS")
+	}
+	if g.clientCAFile, err = conf.FieldString(gsiFieldClientCAFile); err != nil {
+		return nil, err
+	}
+	if g.clientCAFile != "" && g.certFile == "" {
+		return nil, errors.New("a client_ca_file requires TLS to be enabled with a cert_file and key_file")
+	}
+	return g, nil
+}
+
+func (g *grpcServerInput) tlsConfig() (*tls.Config, error) {
+	reloader, err := btls.NewCertReloader(g.certFile, g.keyFile, "")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf := reloader.ServerConfig()
+	tlsConf.MinVersion = tls.VersionTLS12
+
+	if g.clientCAFile != "" {
+		caBytes, err := os.ReadFile(g.clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, errors.New("failed to parse any certificates from client_ca_file")
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConf, nil
+}
+
+func (g *grpcServerInput) Connect(ctx context.Context) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	if g.server != nil {
+		return nil
+	}
+
+	var opts []grpc.ServerOption
+	if g.certFile != "" {
+		tlsConf, err := g.tlsConfig()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConf)))
+	}
+
+	listener, err := net.Listen("tcp", g.address)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: string(ingestService.FullName()),
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Publish",
+				Handler:       g.handlePublish,
+				ServerStreams: true,
+				ClientStreams: true,
+			},
+		},
+		Metadata: ingestProtoName,
+	}, struct{}{})
+	reflection.Register(server)
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			g.log.Errorf("Server error: %v", err)
+		}
+	}()
+
+	g.server, g.listener = server, listener
+	return nil
+}
+
+// handlePublish consumes the requests of a stream, and sends the response of
+// each request once its message has been acknowledged.
+func (g *grpcServerInput) handlePublish(_ any, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+
+	var pending sync.WaitGroup
+	inFlight := make(chan struct{}, g.maxInFlight)
+
+	// Responses can not be sent once the handler has returned, which happens
+	// when a stream is cancelled while messages are in flight.
+	var sendMut sync.Mutex
+	var ended bool
+	defer func() {
+		sendMut.Lock()
+		ended = true
+		sendMut.Unlock()
+	}()
+
+	// Requests are received in the background in order that the stream can
+	// be ended during shutdown while waiting for a request. A request is only
+	// received once a message of the stream is permitted to be in flight.
+	type received struct {
+		req *dynamicpb.Message
+		err error
+	}
+	recvChan := make(chan received)
+	go func() {
+		for {
+			select {
+			case inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			req := dynamicpb.NewMessage(ingestPublishRequest)
+			err := stream.RecvMsg(req)
+			select {
+			case recvChan <- received{req: req, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// waitForAcks blocks until all messages of the stream that are in flight
+	// have been acknowledged.
+	waitForAcks := func() error {
+		acked := make(chan struct{})
+		go func() {
+			pending.Wait()
+			close(acked)
+		}()
+		select {
+		case <-acked:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	fields := ingestPublishRequest.Fields()
+	for {
+		var r received
+		select {
+		case r = <-recvChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.closed:
+			return waitForAcks()
+		}
+		if r.err != nil {
+			if errors.Is(r.err, io.EOF) {
+				// The client has closed its side of the stream, and so the
+				// stream ends once all responses have been sent.
+				return waitForAcks()
+			}
+			return r.err
+		}
+
+		id := r.req.Get(fields.ByName("id")).Uint()
+		msg := service.NewMessage(r.req.Get(fields.ByName("payload")).Bytes())
+		r.req.Get(fields.ByName("metadata")).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			msg.MetaSetMut(k.String(), v.String())
+			return true
+		})
+
+		pending.Add(1)
+		var ackOnce sync.Once
+		ackFn := func(_ context.Context, err error) error {
+			ackOnce.Do(func() {
+				defer func() {
+					<-inFlight
+					pending.Done()
+				}()
+
+				res := dynamicpb.NewMessage(ingestPublishResponse)
+				res.Set(ingestPublishResponse.Fields().ByName("id"), protoreflect.ValueOfUint64(id))
+				if err != nil {
+					res.Set(ingestPublishResponse.Fields().ByName("error"), protoreflect.ValueOfString(err.Error()))
+				}
+
+				sendMut.Lock()
+				sErr := errors.New("stream has ended")
+				if !ended {
+					sErr = stream.SendMsg(res)
+				}
+				sendMut.Unlock()
+				if sErr != nil {
+					g.log.Debugf("Failed to send response of request %v: %v", id, sErr)
+				}
+			})
+			return nil
+		}
+
+		select {
+		case g.reqChan <- gsRequest{msg: msg, ackFn: ackFn}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.closed:
+			_ = ackFn(ctx, service.ErrNotConnected)
+			return waitForAcks()
+		}
+	}
+}
+
+func (g *grpcServerInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case req := <-g.reqChan:
+		return req.msg, req.ackFn, nil
+	case <-g.closed:
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (g *grpcServerInput) Close(ctx context.Context) error {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	select {
+	case <-g.closed:
+	default:
+		close(g.closed)
+	}
+
+	if g.server == nil {
+		return nil
+	}
+
+	// Streams end gracefully once the responses of their remaining messages
+	// have been sent, and are terminated when that takes too long.
+	stopped := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		g.server.Stop()
+	}
+	g.server = nil
+	return nil
+}
@@ -0,0 +1,221 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func startTestServerInput(t *testing.T, conf string) *grpcServerInput {
+	t.Helper()
+
+	pConf, err := grpcServerInputSpec().ParseYAML(`
+address: 127.0.0.1:0
+`+conf, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCServerInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		_ = g.Close(ctx)
+	})
+	return g
+}
+
+func openTestPublishStream(t *testing.T, g *grpcServerInput) grpc.ClientStream {
+	t.Helper()
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	ctx, done := context.WithCancel(context.Background())
+	t.Cleanup(done)
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{
+		ServerStreams: true,
+		ClientStreams: true,
+	}, "/benthos.ingest.v1.Ingest/Publish")
+	require.NoError(t, err)
+	return stream
+}
+
+func sendTestRequest(t *testing.T, stream grpc.ClientStream, id uint64, payload string, metadata map[string]string) {
+	t.Helper()
+
+	fields := ingestPublishRequest.Fields()
+	req := dynamicpb.NewMessage(ingestPublishRequest)
+	req.Set(fields.ByName("id"), protoreflect.ValueOfUint64(id))
+	req.Set(fields.ByName("payload"), protoreflect.ValueOfBytes([]byte(payload)))
+	meta := req.Mutable(fields.ByName("metadata")).Map()
+	for k, v := range metadata {
+		meta.Set(protoreflect.ValueOfString(k).MapKey(), protoreflect.ValueOfString(v))
+	}
+	require.NoError(t, stream.SendMsg(req))
+}
+
+func recvTestResponse(t *testing.T, stream grpc.ClientStream) (id uint64, errStr string) {
+	t.Helper()
+
+	res := dynamicpb.NewMessage(ingestPublishResponse)
+	require.NoError(t, stream.RecvMsg(res))
+	fields := ingestPublishResponse.Fields()
+	return res.Get(fields.ByName("id")).Uint(), res.Get(fields.ByName("error")).String()
+}
+
+func readTestMessage(t *testing.T, g *grpcServerInput, timeout time.Duration) (*service.Message, service.AckFunc, error) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), timeout)
+	defer done()
+	return g.Read(ctx)
+}
+
+func TestGRPCServerInputConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`max_in_flight: 0`,
+		`cert_file: ./foo.pem`,
+		`client_ca_file: ./ca.pem`,
+	} {
+		pConf, err := grpcServerInputSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newGRPCServerInputFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
+
+func TestGRPCServerInputAcks(t *testing.T) {
+	g := startTestServerInput(t, "")
+	stream := openTestPublishStream(t, g)
+
+	sendTestRequest(t, stream, 1, "hello", map[string]string{"foo": "bar"})
+	sendTestRequest(t, stream, 2, "world", nil)
+
+	msgOne, ackOne, err := readTestMessage(t, g, time.Second*5)
+	require.NoError(t, err)
+	b, err := msgOne.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+	v, _ := msgOne.MetaGetMut("foo")
+	assert.Equal(t, "bar", v)
+
+	msgTwo, ackTwo, err := readTestMessage(t, g, time.Second*5)
+	require.NoError(t, err)
+	b, err = msgTwo.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "world", string(b))
+
+	// Responses are sent in the order of acknowledgements.
+	require.NoError(t, ackTwo(context.Background(), errors.New("nope")))
+	id, errStr := recvTestResponse(t, stream)
+	assert.Equal(t, uint64(2), id)
+	assert.Equal(t, "nope", errStr)
+
+	require.NoError(t, ackOne(context.Background(), nil))
+	id, errStr = recvTestResponse(t, stream)
+	assert.Equal(t, uint64(1), id)
+	assert.Equal(t, "", errStr)
+}
+
+func TestGRPCServerInputBackPressure(t *testing.T) {
+	g := startTestServerInput(t, `max_in_flight: 1`)
+	stream := openTestPublishStream(t, g)
+
+	sendTestRequest(t, stream, 1, "first", nil)
+	sendTestRequest(t, stream, 2, "second", nil)
+
+	_, ackOne, err := readTestMessage(t, g, time.Second*5)
+	require.NoError(t, err)
+
+	// The second request is not received until the first is acknowledged.
+	_, _, err = readTestMessage(t, g, time.Millisecond*100)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, ackOne(context.Background(), nil))
+	id, _ := recvTestResponse(t, stream)
+	assert.Equal(t, uint64(1), id)
+
+	msgTwo, ackTwo, err := readTestMessage(t, g, time.Second*5)
+	require.NoError(t, err)
+	b, err := msgTwo.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(b))
+
+	// Once the client closes its side of the stream the stream ends after the
+	// remaining response is sent.
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, ackTwo(context.Background(), nil))
+
+	id, _ = recvTestResponse(t, stream)
+	assert.Equal(t, uint64(2), id)
+	assert.ErrorIs(t, stream.RecvMsg(dynamicpb.NewMessage(ingestPublishResponse)), io.EOF)
+}
+
+func TestGRPCServerInputClose(t *testing.T) {
+	g := startTestServerInput(t, "")
+	stream := openTestPublishStream(t, g)
+
+	sendTestRequest(t, stream, 1, "hello", nil)
+	_, ackFn, err := readTestMessage(t, g, time.Second*5)
+	require.NoError(t, err)
+
+	closed := make(chan error)
+	go func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		defer done()
+		closed <- g.Close(ctx)
+	}()
+
+	// Messages that are in flight are still acknowledged during shutdown.
+	require.NoError(t, ackFn(context.Background(), nil))
+	id, errStr := recvTestResponse(t, stream)
+	assert.Equal(t, uint64(1), id)
+	assert.Equal(t, "", errStr)
+
+	require.NoError(t, <-closed)
+
+	_, _, err = readTestMessage(t, g, time.Second)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+}
+
+func TestGRPCServerInputReflection(t *testing.T) {
+	g := startTestServerInput(t, "")
+
+	conn, err := grpc.Dial(g.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "benthos.ingest.v1.Ingest",
+		},
+	}))
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.Len(t, res.GetFileDescriptorResponse().GetFileDescriptorProto(), 1)
+}
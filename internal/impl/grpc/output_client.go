@@ -0,0 +1,426 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gcoFieldAddress        = "address"
+	gcoFieldProtoFiles     = "proto_files"
+	gcoFieldImportPaths    = "import_paths"
+	gcoFieldMethod         = "method"
+	gcoFieldRequestMapping = "request_mapping"
+	gcoFieldMetadata       = "metadata"
+	gcoFieldTimeout        = "timeout"
+	gcoFieldTLS            = "tls"
+	gcoFieldBackoff        = "backoff"
+	gcoFieldBatching       = "batching"
+	gcoFieldMaxInFlight    = "max_in_flight"
+)
+
+func grpcClientOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Calls a unary or client streaming RPC of a gRPC service for each message, where the RPC is described by protobuf schemas.").
+		Description(`
+The service and its messages are described by the files of `+"`proto_files`"+`, which are either .proto files or descriptor sets created with `+"`protoc --include_imports --descriptor_set_out`"+`. The request of each message is the result of the `+"`request_mapping`"+`, which is converted into the request message type of the method using the [JSON mapping of protobuf](https://developers.google.com/protocol-buffers/docs/proto3#json). When the mapping is omitted the contents of each message are parsed as the JSON of the request.
+
+### Unary and Client Streaming RPCs
+
+When the method is a unary RPC each message of a batch is sent as the request of its own call, and only the messages of failed calls are rejected.
+
+When the method is a client streaming RPC the messages of a batch are sent as the requests of a single stream, and the batch is acknowledged once the response of the stream has been received. Therefore the size of batches determines the number of requests of each stream, which can be configured with `+"`batching`"+`.
+
+Server streaming and bidirectional streaming RPCs are not supported.
+
+### Retries
+
+Calls that fail with the status codes `+"`UNAVAILABLE`"+`, `+"`RESOURCE_EXHAUSTED`"+` or `+"`ABORTED`"+` are attempted again according to `+"`backoff`"+`, any other failed calls result in their messages being rejected immediately.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(gcoFieldAddress).
+			Description("The address of the gRPC server.").
+			Example("localhost:50051")).
+		Field(service.NewStringListField(gcoFieldProtoFiles).
+			Description("A list of .proto files or descriptor set files that describe the service.").
+			Example([]string{"./protos/greeter.proto"}).
+			Example([]string{"./greeter.protoset"})).
+		Field(service.NewStringListField(gcoFieldImportPaths).
+			Description("A list of directories to resolve the imports of .proto files from, where .proto files are then specified relative to these directories.").
+			Advanced().
+			Default([]string{})).
+		Field(service.NewStringField(gcoFieldMethod).
+			Description("The full name of the method to call.").
+			Example("helloworld.Greeter/SayHello")).
+		Field(service.NewBloblangField(gcoFieldRequestMapping).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the request of each message as an object of the JSON mapping of the request message type.").
+			Example(`root.name = this.user.name
+root.trace_id = meta("trace_id")`).
+			Optional()).
+		Field(service.NewInterpolatedStringMapField(gcoFieldMetadata).
+			Description("A map of metadata to send with each call, where streams are sent the metadata of the first message of a batch.").
+			Example(map[string]any{"authorization": `Bearer ${! env("TOKEN") }`}).
+			Default(map[string]any{})).
+		Field(service.NewDurationField(gcoFieldTimeout).
+			Description("The maximum period of time to wait for each call to complete.").
+			Default("5s")).
+		Field(service.NewTLSToggledField(gcoFieldTLS)).
+		Field(service.NewBackOffField(gcoFieldBackoff, false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 500,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Advanced()).
+		Field(service.NewBatchPolicyField(gcoFieldBatching)).
+		Field(service.NewIntField(gcoFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Unary Calls",
+			"In this example a unary RPC is called for each message, with a request created from the fields of the message.",
+			`
+output:
+  grpc_client:
+    address: localhost:50051
+    proto_files: [ ./protos/greeter.proto ]
+    method: helloworld.Greeter/SayHello
+    request_mapping: |
+      root.name = this.user.name
+    metadata:
+      authorization: Bearer ${! env("TOKEN") }
+`,
+		).
+		Example(
+			"Client Streaming With Mutual TLS",
+			"In this example batches of up to 100 messages are sent as the requests of client streams over a mutual TLS connection.",
+			`
+output:
+  grpc_client:
+    address: ingest.example.com:443
+    proto_files: [ ./ingest.protoset ]
+    method: example.Ingest/Upload
+    tls:
+      enabled: true
+      root_cas_file: ./ca.pem
+      client_certs:
+        - cert_file: ./client.pem
+          key_file: ./client-key.pem
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"grpc_client", grpcClientOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(gcoFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(gcoFieldBatching); err != nil {
+				return
+			}
+			out, err = newGRPCClientOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type grpcClientOutput struct {
+	log *service.Logger
+
+	address  string
+	method   protoreflect.MethodDescriptor
+	fullName string
+	mapping  *bloblang.Executor
+	metadata map[string]*service.InterpolatedString
+	timeout  time.Duration
+
+	tlsConf    credentials.TransportCredentials
+	tlsEnabled bool
+
+	backoffCtor func() backoff.BackOff
+
+	connMut sync.RWMutex
+	conn    *grpc.ClientConn
+}
+
+func newGRPCClientOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*grpcClientOutput, error) {
+	g := &grpcClientOutput{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if g.address, err = conf.FieldString(gcoFieldAddress); err != nil {
+		return nil, err
+	}
+
+	protoFiles, err := conf.FieldStringList(gcoFieldProtoFiles)
+	if err != nil {
+		return nil, err
+	}
+	importPaths, err := conf.FieldStringList(gcoFieldImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	files, err := loadProtoFiles(protoFiles, importPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	methodName, err := conf.FieldString(gcoFieldMethod)
+	if err != nil {
+		return nil, err
+	}
+	if g.method, err = findMethod(files, methodName); err != nil {
+		return nil, err
+	}
+	if g.method.IsStreamingServer() {
+		return nil, fmt.Errorf("method %v is a server streaming or bidirectional streaming RPC, which is not supported", methodName)
+	}
+	g.fullName = fmt.Sprintf("/%v/%v", g.method.Parent().FullName(), g.method.Name())
+
+	if conf.Contains(gcoFieldRequestMapping) {
+		if g.mapping, err = conf.FieldBloblang(gcoFieldRequestMapping); err != nil {
+			return nil, err
+		}
+	}
+	if g.metadata, err = conf.FieldInterpolatedStringMap(gcoFieldMetadata); err != nil {
+		return nil, err
+	}
+	if g.timeout, err = conf.FieldDuration(gcoFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gcoFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		g.tlsConf, g.tlsEnabled = credentials.NewTLS(tlsConf), true
+	}
+
+	boff, err := conf.FieldBackOff(gcoFieldBackoff)
+	if err != nil {
+		return nil, err
+	}
+	g.backoffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+	return g, nil
+}
+
+func (g *grpcClientOutput) Connect(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn != nil {
+		return nil
+	}
+
+	creds := insecure.NewCredentials()
+	if g.tlsEnabled {
+		creds = g.tlsConf
+	}
+	conn, err := grpc.DialContext(ctx, g.address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	g.conn = conn
+	return nil
+}
+
+// request converts a message of a batch into the request message of the
+// method.
+func (g *grpcClientOutput) request(batch service.MessageBatch, i int) (*dynamicpb.Message, error) {
+	msg := batch[i]
+	if g.mapping != nil {
+		var err error
+		if msg, err = batch.BloblangQuery(i, g.mapping); err != nil {
+			return nil, fmt.Errorf("request mapping failed: %w", err)
+		}
+		if msg == nil {
+			return nil, errors.New("request mapping resulted in a deleted message")
+		}
+	}
+
+	reqBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	req := dynamicpb.NewMessage(g.method.Input())
+	if err := protojson.Unmarshal(reqBytes, req); err != nil {
+		return nil, fmt.Errorf("failed to convert request into %v: %w", g.method.Input().FullName(), err)
+	}
+	return req, nil
+}
+
+func (g *grpcClientOutput) callContext(ctx context.Context, msg *service.Message) (context.Context, context.CancelFunc) {
+	ctx, done := context.WithTimeout(ctx, g.timeout)
+	if len(g.metadata) > 0 {
+		md := make(metadata.MD, len(g.metadata))
+		for k, v := range g.metadata {
+			md.Set(k, v.String(msg))
+		}
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+	return ctx, done
+}
+
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// withRetries calls a closure until it succeeds, fails with an error that is
+// not retryable, or the backoff is exhausted.
+func (g *grpcClientOutput) withRetries(ctx context.Context, fn func() error) error {
+	boff := g.backoffCtor()
+	for {
+		err := fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		g.log.Debugf("Retrying call to %v after %v due to: %v", g.fullName, wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (g *grpcClientOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	g.connMut.RLock()
+	conn := g.conn
+	g.connMut.RUnlock()
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	reqs := make([]*dynamicpb.Message, len(batch))
+	var pending []int
+	for i := range batch {
+		req, err := g.request(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		reqs[i] = req
+		pending = append(pending, i)
+	}
+
+	if g.method.IsStreamingClient() {
+		if len(pending) > 0 {
+			if err := g.withRetries(ctx, func() error {
+				return g.callStream(ctx, conn, batch[pending[0]], reqs, pending)
+			}); err != nil {
+				if batchErr == nil {
+					return err
+				}
+				for _, i := range pending {
+					batchErr.Failed(i, err)
+				}
+			}
+		}
+	} else {
+		for _, i := range pending {
+			if err := g.withRetries(ctx, func() error {
+				return g.callUnary(ctx, conn, batch[i], reqs[i])
+			}); err != nil {
+				failed(i, err)
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (g *grpcClientOutput) callUnary(ctx context.Context, conn *grpc.ClientConn, msg *service.Message, req *dynamicpb.Message) error {
+	ctx, done := g.callContext(ctx, msg)
+	defer done()
+
+	res := dynamicpb.NewMessage(g.method.Output())
+	return conn.Invoke(ctx, g.fullName, req, res)
+}
+
+func (g *grpcClientOutput) callStream(ctx context.Context, conn *grpc.ClientConn, msg *service.Message, reqs []*dynamicpb.Message, indexes []int) error {
+	ctx, done := g.callContext(ctx, msg)
+	defer done()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, g.fullName)
+	if err != nil {
+		return err
+	}
+	for _, i := range indexes {
+		if err := stream.SendMsg(reqs[i]); err != nil {
+			// The status of the stream is obtained from the response.
+			break
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	return stream.RecvMsg(dynamicpb.NewMessage(g.method.Output()))
+}
+
+func (g *grpcClientOutput) Close(ctx context.Context) error {
+	g.connMut.Lock()
+	defer g.connMut.Unlock()
+
+	if g.conn == nil {
+		return nil
+	}
+	err := g.conn.Close()
+	g.conn = nil
+	return err
+}
@@ -0,0 +1,357 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testGreeterProto = `
+syntax = "proto3";
+
+package test.greeter;
+
+import "google/protobuf/timestamp.proto";
+
+service Greeter {
+  rpc Hello(HelloRequest) returns (HelloResponse);
+  rpc HelloStream(stream HelloRequest) returns (HelloResponse);
+  rpc HelloWatch(HelloRequest) returns (stream HelloResponse);
+}
+
+message HelloRequest {
+  string name = 1;
+  google.protobuf.Timestamp sent_at = 2;
+}
+
+message HelloResponse {
+  int64 count = 1;
+}
+`
+
+type testGreeterServer struct {
+	method protoreflect.MethodDescriptor
+
+	mut       sync.Mutex
+	requests  []string
+	metadata  []string
+	failCodes []codes.Code
+}
+
+func (s *testGreeterServer) record(ctx context.Context, reqs ...*dynamicpb.Message) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.failCodes) > 0 {
+		code := s.failCodes[0]
+		s.failCodes = s.failCodes[1:]
+		return status.Error(code, "nope")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.metadata = append(s.metadata, md.Get("foo")...)
+	for _, req := range reqs {
+		reqBytes, err := protojson.Marshal(req)
+		if err != nil {
+			return err
+		}
+
+		// The output of protojson is deliberately unstable.
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, reqBytes); err != nil {
+			return err
+		}
+		s.requests = append(s.requests, compacted.String())
+	}
+	return nil
+}
+
+func (s *testGreeterServer) response(count int) *dynamicpb.Message {
+	res := dynamicpb.NewMessage(s.method.Output())
+	res.Set(s.method.Output().Fields().ByName("count"), protoreflect.ValueOfInt64(int64(count)))
+	return res
+}
+
+func (s *testGreeterServer) requestsSent() ([]string, []string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string(nil), s.requests...), append([]string(nil), s.metadata...)
+}
+
+func writeTestGreeterProto(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "greeter.proto")
+	require.NoError(t, os.WriteFile(path, []byte(testGreeterProto), 0o644))
+	return path
+}
+
+func startTestGreeterServer(t *testing.T, protoPath string) (*testGreeterServer, string) {
+	t.Helper()
+
+	files, err := loadProtoFiles([]string{protoPath}, nil)
+	require.NoError(t, err)
+	method, err := findMethod(files, "test.greeter.Greeter/Hello")
+	require.NoError(t, err)
+
+	s := &testGreeterServer{method: method}
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.greeter.Greeter",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Hello",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := dynamicpb.NewMessage(method.Input())
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if err := s.record(ctx, req); err != nil {
+					return nil, err
+				}
+				return s.response(1), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "HelloStream",
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				var reqs []*dynamicpb.Message
+				for {
+					req := dynamicpb.NewMessage(method.Input())
+					if err := stream.RecvMsg(req); err != nil {
+						if errors.Is(err, io.EOF) {
+							break
+						}
+						return err
+					}
+					reqs = append(reqs, req)
+				}
+				if err := s.record(stream.Context(), reqs...); err != nil {
+					return err
+				}
+				return stream.SendMsg(s.response(len(reqs)))
+			},
+		}},
+	}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return s, listener.Addr().String()
+}
+
+func startTestClientOutput(t *testing.T, conf string) *grpcClientOutput {
+	t.Helper()
+
+	pConf, err := grpcClientOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCClientOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, g.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = g.Close(context.Background())
+	})
+	return g
+}
+
+func TestGRPCClientOutputConfigErrors(t *testing.T) {
+	protoPath := writeTestGreeterProto(t)
+
+	for _, conf := range []string{
+		`method: test.greeter.Greeter/Hello
+proto_files: [ ./does_not_exist.proto ]`,
+		`method: test.greeter.Greeter/Nope
+proto_files: [ ` + protoPath + ` ]`,
+		`method: test.greeter.Nope/Hello
+proto_files: [ ` + protoPath + ` ]`,
+		`method: Hello
+proto_files: [ ` + protoPath + ` ]`,
+		`method: test.greeter.Greeter/HelloWatch
+proto_files: [ ` + protoPath + ` ]`,
+	} {
+		pConf, err := grpcClientOutputSpec().ParseYAML(`
+address: localhost:50051
+`+conf, nil)
+		require.NoError(t, err)
+
+		_, err = newGRPCClientOutputFromParsed(pConf, service.MockResources())
+		require.Error(t, err, conf)
+	}
+}
+
+func TestGRPCClientOutputUnary(t *testing.T) {
+	protoPath := writeTestGreeterProto(t)
+	s, addr := startTestGreeterServer(t, protoPath)
+
+	g := startTestClientOutput(t, `
+address: `+addr+`
+proto_files: [ `+protoPath+` ]
+method: test.greeter.Greeter/Hello
+request_mapping: |
+  root.name = this.user
+  root.sent_at = "2022-09-01T10:00:00Z"
+metadata:
+  foo: ${! meta("foo") }
+`)
+
+	msgOne := service.NewMessage([]byte(`{"user":"alice"}`))
+	msgOne.MetaSetMut("foo", "first")
+	msgTwo := service.NewMessage([]byte(`not json`))
+	msgThree := service.NewMessage([]byte(`{"user":"bob"}`))
+	msgThree.MetaSetMut("foo", "third")
+
+	batch := service.MessageBatch{msgOne, msgTwo, msgThree}
+	err := g.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+
+	reqs, md := s.requestsSent()
+	assert.Equal(t, []string{
+		`{"name":"alice","sentAt":"2022-09-01T10:00:00Z"}`,
+		`{"name":"bob","sentAt":"2022-09-01T10:00:00Z"}`,
+	}, reqs)
+	assert.Equal(t, []string{"first", "third"}, md)
+}
+
+func TestGRPCClientOutputRetries(t *testing.T) {
+	protoPath := writeTestGreeterProto(t)
+	s, addr := startTestGreeterServer(t, protoPath)
+
+	g := startTestClientOutput(t, `
+address: `+addr+`
+proto_files: [ `+protoPath+` ]
+method: test.greeter.Greeter/Hello
+backoff:
+  initial_interval: 1ms
+  max_interval: 10ms
+`)
+
+	s.failCodes = []codes.Code{codes.Unavailable, codes.ResourceExhausted}
+	require.NoError(t, g.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"alice"}`)),
+	}))
+
+	s.failCodes = []codes.Code{codes.InvalidArgument, codes.Unavailable}
+	err := g.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"bob"}`)),
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(errors.Unwrap(err)))
+
+	reqs, _ := s.requestsSent()
+	assert.Equal(t, []string{`{"name":"alice"}`}, reqs)
+}
+
+func TestGRPCClientOutputClientStream(t *testing.T) {
+	protoPath := writeTestGreeterProto(t)
+
+	// Descriptor sets are supported as well as .proto files.
+	files, err := loadProtoFiles([]string{protoPath}, nil)
+	require.NoError(t, err)
+	set := &descriptorpb.FileDescriptorSet{}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+		return true
+	})
+	setBytes, err := proto.Marshal(set)
+	require.NoError(t, err)
+	setPath := filepath.Join(t.TempDir(), "greeter.protoset")
+	require.NoError(t, os.WriteFile(setPath, setBytes, 0o644))
+
+	s, addr := startTestGreeterServer(t, protoPath)
+
+	g := startTestClientOutput(t, `
+address: `+addr+`
+proto_files: [ `+setPath+` ]
+method: test.greeter.Greeter.HelloStream
+timeout: 1s
+`)
+
+	require.NoError(t, g.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"alice"}`)),
+		service.NewMessage([]byte(`{"name":"bob"}`)),
+	}))
+
+	s.failCodes = []codes.Code{codes.InvalidArgument}
+	err = g.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"carol"}`)),
+		service.NewMessage([]byte(`{"nope":"dave"}`)),
+	})
+	require.Error(t, err)
+
+	var batchErr *service.BatchError
+	require.True(t, errors.As(err, &batchErr))
+
+	failed := 0
+	batchErr.WalkMessages(func(_ int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed++
+		}
+		return true
+	})
+	assert.Equal(t, 2, failed)
+
+	reqs, _ := s.requestsSent()
+	assert.Equal(t, []string{`{"name":"alice"}`, `{"name":"bob"}`}, reqs)
+}
+
+func TestGRPCClientOutputNotConnected(t *testing.T) {
+	protoPath := writeTestGreeterProto(t)
+
+	pConf, err := grpcClientOutputSpec().ParseYAML(`
+address: localhost:50051
+proto_files: [ `+protoPath+` ]
+method: test.greeter.Greeter/Hello
+`, nil)
+	require.NoError(t, err)
+
+	g, err := newGRPCClientOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	err = g.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`{}`))})
+	require.ErrorIs(t, err, service.ErrNotConnected)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+	require.NoError(t, g.Close(ctx))
+}
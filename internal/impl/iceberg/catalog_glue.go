@@ -0,0 +1,223 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/glue"
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	icgFieldCatalogID = "catalog_id"
+
+	glueMetadataLocation         = "metadata_location"
+	gluePreviousMetadataLocation = "previous_metadata_location"
+)
+
+func glueCatalogFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(icgFieldCatalogID).
+			Description("The ID of the Glue Data Catalog, which defaults to the catalog of the AWS account.").
+			Default(""),
+	}
+}
+
+// glueCatalog commits snapshots to tables of the AWS Glue Data Catalog, where
+// the catalog only stores the location of the current metadata file and new
+// metadata files are written by the output itself.
+type glueCatalog struct {
+	catalogID string
+	database  string
+	table     string
+	glue      *glue.Glue
+	files     *fileIO
+}
+
+func glueCatalogFromParsed(conf *service.ParsedConfig, sess *session.Session, files *fileIO, namespace []string, table string) (*glueCatalog, error) {
+	if len(namespace) != 1 {
+		return nil, fmt.Errorf("the glue catalog requires a namespace of exactly one level, got %v", len(namespace))
+	}
+	catalogID, err := conf.FieldString(icgFieldCatalogID)
+	if err != nil {
+		return nil, err
+	}
+	return &glueCatalog{
+		catalogID: catalogID,
+		database:  namespace[0],
+		table:     table,
+		glue:      glue.New(sess),
+		files:     files,
+	}, nil
+}
+
+func (g *glueCatalog) connect(ctx context.Context) error {
+	return nil
+}
+
+func (g *glueCatalog) getTable(ctx context.Context) (*glue.TableData, error) {
+	input := &glue.GetTableInput{
+		DatabaseName: aws.String(g.database),
+		Name:         aws.String(g.table),
+	}
+	if g.catalogID != "" {
+		input.CatalogId = aws.String(g.catalogID)
+	}
+	out, err := g.glue.GetTableWithContext(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table: %w", err)
+	}
+	return out.Table, nil
+}
+
+func (g *glueCatalog) loadTable(ctx context.Context) (*loadedTable, error) {
+	table, err := g.getTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	location := aws.StringValue(table.Parameters[glueMetadataLocation])
+	if location == "" {
+		return nil, fmt.Errorf("table %v.%v is not an iceberg table as it does not have the parameter %v", g.database, g.table, glueMetadataLocation)
+	}
+
+	raw, err := g.files.read(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := parseTableMetadata(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedTable{meta: meta, metadataLocation: location, raw: raw}, nil
+}
+
+// nextMetadataLocation returns the location of the metadata file that follows
+// a previous one, which are named with an incrementing version.
+func nextMetadataLocation(metadataDir, previous string) (string, error) {
+	var version int
+	if v, _, ok := strings.Cut(path.Base(previous), "-"); ok {
+		version, _ = strconv.Atoi(v)
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v/%05d-%v.metadata.json", metadataDir, version+1, id), nil
+}
+
+func appendToList(m map[string]any, key string, v any) {
+	l, _ := m[key].([]any)
+	m[key] = append(l, v)
+}
+
+// updateMetadata adds a snapshot to the raw metadata of a table and sets it as
+// the current snapshot of the main branch. The metadata is modified as a
+// generic structure so that the fields the output does not model are kept.
+func updateMetadata(t *loadedTable, snap *snapshot) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(t.raw))
+	dec.UseNumber()
+
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse table metadata: %w", err)
+	}
+
+	previousUpdate := m["last-updated-ms"]
+
+	appendToList(m, "snapshots", snap)
+	m["current-snapshot-id"] = snap.SnapshotID
+	m["last-updated-ms"] = snap.TimestampMs
+	if t.meta.FormatVersion > 1 {
+		m["last-sequence-number"] = snap.SequenceNumber
+	}
+
+	refs, _ := m["refs"].(map[string]any)
+	if refs == nil {
+		refs = map[string]any{}
+	}
+	refs["main"] = map[string]any{"snapshot-id": snap.SnapshotID, "type": "branch"}
+	m["refs"] = refs
+
+	appendToList(m, "snapshot-log", map[string]any{
+		"timestamp-ms": snap.TimestampMs,
+		"snapshot-id":  snap.SnapshotID,
+	})
+	if t.metadataLocation != "" && previousUpdate != nil {
+		appendToList(m, "metadata-log", map[string]any{
+			"timestamp-ms":  previousUpdate,
+			"metadata-file": t.metadataLocation,
+		})
+	}
+	return json.Marshal(m)
+}
+
+// commit a snapshot by writing a new metadata file and pointing the table at
+// it. Glue does not support conditional updates of tables and therefore the
+// location of the metadata is checked immediately before the update, which
+// narrows, but does not eliminate, the window for concurrent commits.
+func (g *glueCatalog) commit(ctx context.Context, t *loadedTable, snap *snapshot) error {
+	metadata, err := updateMetadata(t, snap)
+	if err != nil {
+		return err
+	}
+	location, err := nextMetadataLocation(t.meta.metadataLocation(), t.metadataLocation)
+	if err != nil {
+		return err
+	}
+	if err := g.files.write(ctx, location, metadata); err != nil {
+		return err
+	}
+
+	table, err := g.getTable(ctx)
+	if err != nil {
+		return err
+	}
+	if current := aws.StringValue(table.Parameters[glueMetadataLocation]); current != t.metadataLocation {
+		return fmt.Errorf("%w: metadata location changed to %v", errCommitConflict, current)
+	}
+
+	params := map[string]*string{}
+	for k, v := range table.Parameters {
+		params[k] = v
+	}
+	params[glueMetadataLocation] = aws.String(location)
+	params[gluePreviousMetadataLocation] = aws.String(t.metadataLocation)
+
+	input := &glue.UpdateTableInput{
+		DatabaseName: aws.String(g.database),
+		TableInput: &glue.TableInput{
+			Name:              table.Name,
+			Description:       table.Description,
+			Owner:             table.Owner,
+			LastAccessTime:    table.LastAccessTime,
+			LastAnalyzedTime:  table.LastAnalyzedTime,
+			Retention:         table.Retention,
+			StorageDescriptor: table.StorageDescriptor,
+			PartitionKeys:     table.PartitionKeys,
+			ViewOriginalText:  table.ViewOriginalText,
+			ViewExpandedText:  table.ViewExpandedText,
+			TableType:         table.TableType,
+			Parameters:        params,
+			TargetTable:       table.TargetTable,
+		},
+	}
+	if g.catalogID != "" {
+		input.CatalogId = aws.String(g.catalogID)
+	}
+	if _, err := g.glue.UpdateTableWithContext(ctx, input); err != nil {
+		return fmt.Errorf("failed to update table: %w", err)
+	}
+	return nil
+}
+
+func (g *glueCatalog) close() {}
@@ -0,0 +1,76 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextMetadataLocation(t *testing.T) {
+	location, err := nextMetadataLocation("s3://bucket/table/metadata", "s3://bucket/table/metadata/00004-9c12d441-03fe-4693-9a96-a0705ddf69c1.metadata.json")
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^s3://bucket/table/metadata/00005-[0-9a-f-]{36}\.metadata\.json$`), location)
+
+	location, err = nextMetadataLocation("s3://bucket/table/metadata", "s3://bucket/table/metadata/v3.metadata.json")
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^s3://bucket/table/metadata/00001-`), location)
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	raw := []byte(`{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "s3://bucket/table",
+  "last-sequence-number": 3,
+  "last-updated-ms": 1602638573590,
+  "current-schema-id": 0,
+  "schemas": [{"type": "struct", "schema-id": 0, "fields": []}],
+  "default-spec-id": 0,
+  "partition-specs": [{"spec-id": 0, "fields": []}],
+  "current-snapshot-id": 3051729675574597004,
+  "snapshots": [{"snapshot-id": 3051729675574597004, "sequence-number": 3, "timestamp-ms": 1515100955770, "manifest-list": "s3://bucket/table/metadata/snap-1.avro", "summary": {"operation": "append"}}],
+  "refs": {"main": {"snapshot-id": 3051729675574597004, "type": "branch"}, "audit": {"snapshot-id": 3051729675574597004, "type": "tag"}},
+  "snapshot-log": [{"timestamp-ms": 1515100955770, "snapshot-id": 3051729675574597004}],
+  "some-future-field": {"foo": "bar"}
+}`)
+	meta, err := parseTableMetadata(raw)
+	require.NoError(t, err)
+
+	parent := int64(3051729675574597004)
+	updated, err := updateMetadata(&loadedTable{
+		meta:             meta,
+		metadataLocation: "s3://bucket/table/metadata/00003-abc.metadata.json",
+		raw:              raw,
+	}, &snapshot{
+		SnapshotID:       3051729675574597005,
+		ParentSnapshotID: &parent,
+		SequenceNumber:   4,
+		TimestampMs:      1602638573999,
+		ManifestList:     "s3://bucket/table/metadata/snap-2.avro",
+		Summary:          map[string]string{"operation": "append"},
+	})
+	require.NoError(t, err)
+
+	var m map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(updated, &m))
+
+	assert.JSONEq(t, `3051729675574597005`, string(m["current-snapshot-id"]))
+	assert.JSONEq(t, `4`, string(m["last-sequence-number"]))
+	assert.JSONEq(t, `1602638573999`, string(m["last-updated-ms"]))
+	assert.JSONEq(t, `{"main": {"snapshot-id": 3051729675574597005, "type": "branch"}, "audit": {"snapshot-id": 3051729675574597004, "type": "tag"}}`, string(m["refs"]))
+	assert.JSONEq(t, `[
+  {"timestamp-ms": 1515100955770, "snapshot-id": 3051729675574597004},
+  {"timestamp-ms": 1602638573999, "snapshot-id": 3051729675574597005}
+]`, string(m["snapshot-log"]))
+	assert.JSONEq(t, `[{"timestamp-ms": 1602638573590, "metadata-file": "s3://bucket/table/metadata/00003-abc.metadata.json"}]`, string(m["metadata-log"]))
+	assert.JSONEq(t, `{"foo": "bar"}`, string(m["some-future-field"]))
+
+	reparsed, err := parseTableMetadata(updated)
+	require.NoError(t, err)
+	require.Len(t, reparsed.Snapshots, 2)
+	assert.Equal(t, int64(3051729675574597005), reparsed.currentSnapshot().SnapshotID)
+	assert.Equal(t, parent, *reparsed.currentSnapshot().ParentSnapshotID)
+}
@@ -0,0 +1,295 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	icrFieldURL          = "url"
+	icrFieldWarehouse    = "warehouse"
+	icrFieldToken        = "token"
+	icrFieldOAuth2       = "oauth2"
+	icrFieldEnabled      = "enabled"
+	icrFieldClientID     = "client_id"
+	icrFieldClientSecret = "client_secret"
+	icrFieldTokenURL     = "token_url"
+	icrFieldScopes       = "scopes"
+	icrFieldTLS          = "tls"
+)
+
+func restCatalogFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(icrFieldURL).
+			Description("The base URL of the REST catalog, without the `/v1` path.").
+			Example("http://localhost:8181").
+			Example("https://catalog.example.com/api/catalog"),
+		service.NewStringField(icrFieldWarehouse).
+			Description("A warehouse to request from the catalog, which some catalogs require in order to determine the location of tables.").
+			Default(""),
+		service.NewStringField(icrFieldToken).
+			Description("A static bearer token to send with requests to the catalog.").
+			Default(""),
+		service.NewObjectField(icrFieldOAuth2,
+			service.NewBoolField(icrFieldEnabled).
+				Description("Whether to obtain access tokens with the OAuth2 client credentials flow.").
+				Default(false),
+			service.NewStringField(icrFieldClientID).
+				Description("The client ID to obtain access tokens with.").
+				Default(""),
+			service.NewStringField(icrFieldClientSecret).
+				Description("The client secret to obtain access tokens with.").
+				Default(""),
+			service.NewStringField(icrFieldTokenURL).
+				Description("The URL to obtain access tokens from, which defaults to the token endpoint of the catalog.").
+				Default(""),
+			service.NewStringListField(icrFieldScopes).
+				Description("The scopes to request access tokens for.").
+				Default([]any{"catalog"}),
+		).Description("Credentials for the OAuth2 client credentials flow, which takes precedence over `token`.").
+			Advanced(),
+		service.NewTLSToggledField(icrFieldTLS),
+	}
+}
+
+// restCatalog commits snapshots to tables of an Iceberg REST catalog.
+type restCatalog struct {
+	baseURL   string
+	warehouse string
+	namespace []string
+	table     string
+	http      *http.Client
+
+	// The URL of the table, which is resolved from the prefix that the
+	// catalog returns from its config endpoint.
+	tableURL string
+}
+
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (b *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return b.base.RoundTrip(req)
+}
+
+func restCatalogFromParsed(conf *service.ParsedConfig, namespace []string, table string) (*restCatalog, error) {
+	r := &restCatalog{namespace: namespace, table: table}
+
+	var err error
+	if r.baseURL, err = conf.FieldString(icrFieldURL); err != nil {
+		return nil, err
+	}
+	r.baseURL = strings.TrimSuffix(r.baseURL, "/")
+	if r.warehouse, err = conf.FieldString(icrFieldWarehouse); err != nil {
+		return nil, err
+	}
+	token, err := conf.FieldString(icrFieldToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper = http.DefaultTransport
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(icrFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.TLSClientConfig = tlsConf
+		transport = t
+	}
+
+	aConf := conf.Namespace(icrFieldOAuth2)
+	oauthEnabled, err := aConf.FieldBool(icrFieldEnabled)
+	if err != nil {
+		return nil, err
+	}
+	if !oauthEnabled {
+		if token != "" {
+			transport = &bearerTransport{token: token, base: transport}
+		}
+		r.http = &http.Client{Transport: transport}
+		return r, nil
+	}
+
+	cConf := &clientcredentials.Config{}
+	if cConf.ClientID, err = aConf.FieldString(icrFieldClientID); err != nil {
+		return nil, err
+	}
+	if cConf.ClientSecret, err = aConf.FieldString(icrFieldClientSecret); err != nil {
+		return nil, err
+	}
+	if cConf.TokenURL, err = aConf.FieldString(icrFieldTokenURL); err != nil {
+		return nil, err
+	}
+	if cConf.TokenURL == "" {
+		cConf.TokenURL = r.baseURL + "/v1/oauth/tokens"
+	}
+	if cConf.Scopes, err = aConf.FieldStringList(icrFieldScopes); err != nil {
+		return nil, err
+	}
+
+	// The context only carries the HTTP client for token requests, which are
+	// made throughout the lifetime of the catalog.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+	r.http = cConf.Client(ctx)
+	return r, nil
+}
+
+// restError is an error response of a REST catalog.
+type restError struct {
+	Status  int    `json:"code"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *restError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("%v: %v", e.Status, e.Message)
+	}
+	return fmt.Sprintf("%v: %v (%v)", e.Status, e.Message, e.Type)
+}
+
+func readRESTError(res *http.Response) error {
+	rErr := &restError{Status: res.StatusCode}
+	var body struct {
+		Error *restError `json:"error"`
+	}
+	if b, _ := io.ReadAll(res.Body); json.Unmarshal(b, &body) == nil && body.Error != nil {
+		rErr.Type, rErr.Message = body.Error.Type, body.Error.Message
+	} else {
+		rErr.Message = strings.TrimSpace(string(b))
+	}
+	if res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %v", errCommitConflict, rErr)
+	}
+	return rErr
+}
+
+func (r *restCatalog) do(ctx context.Context, method, reqURL string, body, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := r.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return readRESTError(res)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (r *restCatalog) connect(ctx context.Context) error {
+	configURL := r.baseURL + "/v1/config"
+	if r.warehouse != "" {
+		configURL += "?warehouse=" + url.QueryEscape(r.warehouse)
+	}
+
+	var config struct {
+		Defaults  map[string]string `json:"defaults"`
+		Overrides map[string]string `json:"overrides"`
+	}
+	if err := r.do(ctx, http.MethodGet, configURL, nil, &config); err != nil {
+		return fmt.Errorf("failed to obtain catalog config: %w", err)
+	}
+
+	prefix := config.Defaults["prefix"]
+	if p, exists := config.Overrides["prefix"]; exists {
+		prefix = p
+	}
+
+	levels := make([]string, len(r.namespace))
+	for i, l := range r.namespace {
+		levels[i] = url.PathEscape(l)
+	}
+
+	tableURL := r.baseURL + "/v1/"
+	if prefix != "" {
+		tableURL += strings.Trim(prefix, "/") + "/"
+	}
+	r.tableURL = tableURL + "namespaces/" + strings.Join(levels, "%1F") + "/tables/" + url.PathEscape(r.table)
+	return nil
+}
+
+func (r *restCatalog) loadTable(ctx context.Context) (*loadedTable, error) {
+	var res struct {
+		MetadataLocation string          `json:"metadata-location"`
+		Metadata         json.RawMessage `json:"metadata"`
+	}
+	if err := r.do(ctx, http.MethodGet, r.tableURL, nil, &res); err != nil {
+		return nil, fmt.Errorf("failed to load table: %w", err)
+	}
+
+	meta, err := parseTableMetadata(res.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &loadedTable{meta: meta, metadataLocation: res.MetadataLocation, raw: res.Metadata}, nil
+}
+
+// commit a snapshot with the requirement that the main branch of the table
+// has not changed since it was loaded, the catalog responds with a conflict
+// otherwise.
+func (r *restCatalog) commit(ctx context.Context, t *loadedTable, snap *snapshot) error {
+	body := map[string]any{
+		"identifier": map[string]any{
+			"namespace": r.namespace,
+			"name":      r.table,
+		},
+		"requirements": []any{
+			map[string]any{"type": "assert-table-uuid", "uuid": t.meta.TableUUID},
+			map[string]any{"type": "assert-ref-snapshot-id", "ref": "main", "snapshot-id": t.meta.CurrentSnapshotID},
+		},
+		"updates": []any{
+			map[string]any{"action": "add-snapshot", "snapshot": snap},
+			map[string]any{"action": "set-snapshot-ref", "ref-name": "main", "type": "branch", "snapshot-id": snap.SnapshotID},
+		},
+	}
+	if err := r.do(ctx, http.MethodPost, r.tableURL, body, nil); err != nil {
+		if errors.Is(err, errCommitConflict) {
+			return err
+		}
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *restCatalog) close() {
+	r.http.CloseIdleConnections()
+}
@@ -0,0 +1,72 @@
+package iceberg
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3 with a seed of zero, which
+// is the hash function that Iceberg uses for bucket partitions.
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[nblocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
+
+// bucketHash returns the hash of a converted value, where integer types are
+// hashed as longs.
+func bucketHash(v any) int32 {
+	var data []byte
+	switch t := v.(type) {
+	case int32:
+		data = make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, uint64(int64(t)))
+	case int64:
+		data = make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, uint64(t))
+	case string:
+		data = []byte(t)
+	case []byte:
+		data = t
+	}
+	return int32(murmur3(data))
+}
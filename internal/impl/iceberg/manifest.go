@@ -0,0 +1,537 @@
+package iceberg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// dataFile is a parquet file that has been written to the data location of a
+// table and is added to a snapshot.
+type dataFile struct {
+	path        string
+	recordCount int64
+	sizeBytes   int64
+	partition   []any
+}
+
+type fieldSummary struct {
+	containsNull bool
+	containsNaN  *bool
+	lowerBound   []byte
+	upperBound   []byte
+}
+
+// manifestFile is an entry of a manifest list.
+type manifestFile struct {
+	path              string
+	length            int64
+	specID            int32
+	content           int32
+	sequenceNumber    int64
+	minSequenceNumber int64
+	addedSnapshotID   int64
+	addedFiles        int32
+	existingFiles     int32
+	deletedFiles      int32
+	addedRows         int64
+	existingRows      int64
+	deletedRows       int64
+	partitions        []fieldSummary
+	keyMetadata       []byte
+}
+
+//------------------------------------------------------------------------------
+
+func optional(t any) []any {
+	return []any{"null", t}
+}
+
+func avroField(name string, id int, t any) map[string]any {
+	f := map[string]any{"name": name, "type": t, "field-id": id}
+	if u, ok := t.([]any); ok && len(u) > 0 && u[0] == "null" {
+		f["default"] = nil
+	}
+	return f
+}
+
+// avroLogicalTypes describes the Avro types of partition values, along with
+// the name of each type within a union.
+var avroLogicalTypes = map[string]struct {
+	schema    any
+	unionName string
+}{
+	"boolean":     {"boolean", "boolean"},
+	"int":         {"int", "int"},
+	"long":        {"long", "long"},
+	"float":       {"float", "float"},
+	"double":      {"double", "double"},
+	"date":        {map[string]any{"type": "int", "logicalType": "date"}, "int.date"},
+	"time":        {map[string]any{"type": "long", "logicalType": "time-micros"}, "long.time-micros"},
+	"timestamp":   {map[string]any{"type": "long", "logicalType": "timestamp-micros", "adjust-to-utc": false}, "long.timestamp-micros"},
+	"timestamptz": {map[string]any{"type": "long", "logicalType": "timestamp-micros", "adjust-to-utc": true}, "long.timestamp-micros"},
+	"string":      {"string", "string"},
+	"binary":      {"bytes", "bytes"},
+}
+
+// avroPartitionValue converts a partition value into the native value that
+// goavro expects for its type.
+func avroPartitionValue(resultType string, v any) any {
+	if v == nil {
+		return nil
+	}
+	switch resultType {
+	case "date":
+		v = time.Unix(int64(v.(int32))*24*60*60, 0).UTC()
+	case "time":
+		v = time.Duration(v.(int64)) * time.Microsecond
+	case "timestamp", "timestamptz":
+		v = time.UnixMicro(v.(int64)).UTC()
+	}
+	return goavro.Union(avroLogicalTypes[resultType].unionName, v)
+}
+
+func manifestEntrySchema(formatVersion int, transforms []*transform, spec *partitionSpec) (string, error) {
+	partitionFields := []any{}
+	for i, pf := range spec.Fields {
+		partitionFields = append(partitionFields, avroField(pf.Name, pf.FieldID, optional(avroLogicalTypes[transforms[i].resultType].schema)))
+	}
+
+	dataFileFields := []any{}
+	if formatVersion > 1 {
+		dataFileFields = append(dataFileFields, avroField("content", 134, "int"))
+	}
+	dataFileFields = append(dataFileFields,
+		avroField("file_path", 100, "string"),
+		avroField("file_format", 101, "string"),
+		avroField("partition", 102, map[string]any{"type": "record", "name": "r102", "fields": partitionFields}),
+		avroField("record_count", 103, "long"),
+		avroField("file_size_in_bytes", 104, "long"),
+	)
+	if formatVersion == 1 {
+		dataFileFields = append(dataFileFields, avroField("block_size_in_bytes", 105, "long"))
+	}
+
+	fields := []any{avroField("status", 0, "int")}
+	if formatVersion == 1 {
+		fields = append(fields, avroField("snapshot_id", 1, "long"))
+	} else {
+		fields = append(fields,
+			avroField("snapshot_id", 1, optional("long")),
+			avroField("sequence_number", 3, optional("long")),
+			avroField("file_sequence_number", 4, optional("long")),
+		)
+	}
+	fields = append(fields, avroField("data_file", 2, map[string]any{"type": "record", "name": "r2", "fields": dataFileFields}))
+
+	schema, err := json.Marshal(map[string]any{"type": "record", "name": "manifest_entry", "fields": fields})
+	return string(schema), err
+}
+
+// writeManifest encodes a manifest of added data files, where sequence numbers
+// are inherited from the manifest list.
+func writeManifest(meta *tableMetadata, schema *tableSchema, spec *partitionSpec, transforms []*transform, snapshotID int64, files []dataFile) ([]byte, error) {
+	avroSchema, err := manifestEntrySchema(meta.FormatVersion, transforms, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaJSON, err := json.Marshal(map[string]any{"type": "struct", "schema-id": schema.SchemaID, "fields": schema.Fields})
+	if err != nil {
+		return nil, err
+	}
+	specJSON, err := json.Marshal(spec.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Schema:          avroSchema,
+		CompressionName: goavro.CompressionDeflateLabel,
+		MetaData: map[string][]byte{
+			"schema":            schemaJSON,
+			"schema-id":         []byte(strconv.Itoa(schema.SchemaID)),
+			"partition-spec":    specJSON,
+			"partition-spec-id": []byte(strconv.Itoa(spec.SpecID)),
+			"format-version":    []byte(strconv.Itoa(meta.FormatVersion)),
+			"content":           []byte("data"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest writer: %w", err)
+	}
+
+	entries := make([]any, 0, len(files))
+	for _, f := range files {
+		partition := map[string]any{}
+		for i, pf := range spec.Fields {
+			partition[pf.Name] = avroPartitionValue(transforms[i].resultType, f.partition[i])
+		}
+
+		df := map[string]any{
+			"file_path":          f.path,
+			"file_format":        "PARQUET",
+			"partition":          partition,
+			"record_count":       f.recordCount,
+			"file_size_in_bytes": f.sizeBytes,
+		}
+		entry := map[string]any{
+			"status":    int32(1),
+			"data_file": df,
+		}
+		if meta.FormatVersion == 1 {
+			df["block_size_in_bytes"] = int64(64 * 1024 * 1024)
+			entry["snapshot_id"] = snapshotID
+		} else {
+			df["content"] = int32(0)
+			entry["snapshot_id"] = goavro.Union("long", snapshotID)
+			entry["sequence_number"] = nil
+			entry["file_sequence_number"] = nil
+		}
+		entries = append(entries, entry)
+	}
+	if err := w.Append(entries); err != nil {
+		return nil, fmt.Errorf("failed to write manifest entries: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// singleValue returns the binary single-value serialisation of a converted
+// value, which is used for the bounds of partition summaries.
+func singleValue(v any) []byte {
+	switch t := v.(type) {
+	case bool:
+		if t {
+			return []byte{1}
+		}
+		return []byte{0}
+	case int32:
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, uint32(t))
+		return b
+	case int64:
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, uint64(t))
+		return b
+	case float32:
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, math.Float32bits(t))
+		return b
+	case float64:
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, math.Float64bits(t))
+		return b
+	case string:
+		return []byte(t)
+	case []byte:
+		return t
+	}
+	return nil
+}
+
+func isNaN(v any) bool {
+	switch t := v.(type) {
+	case float32:
+		return math.IsNaN(float64(t))
+	case float64:
+		return math.IsNaN(t)
+	}
+	return false
+}
+
+func lessValue(a, b any) bool {
+	switch t := a.(type) {
+	case bool:
+		return !t && b.(bool)
+	case int32:
+		return t < b.(int32)
+	case int64:
+		return t < b.(int64)
+	case float32:
+		return t < b.(float32)
+	case float64:
+		return t < b.(float64)
+	case string:
+		return t < b.(string)
+	case []byte:
+		return bytes.Compare(t, b.([]byte)) < 0
+	}
+	return false
+}
+
+// summarisePartitions computes the partition summaries of a manifest.
+func summarisePartitions(transforms []*transform, files []dataFile) []fieldSummary {
+	summaries := make([]fieldSummary, len(transforms))
+	for i, t := range transforms {
+		var lower, upper any
+		var containsNaN bool
+		for _, f := range files {
+			v := f.partition[i]
+			if v == nil {
+				summaries[i].containsNull = true
+				continue
+			}
+			if isNaN(v) {
+				containsNaN = true
+				continue
+			}
+			if lower == nil || lessValue(v, lower) {
+				lower = v
+			}
+			if upper == nil || lessValue(upper, v) {
+				upper = v
+			}
+		}
+		if t.resultType == "float" || t.resultType == "double" {
+			summaries[i].containsNaN = &containsNaN
+		}
+		if lower != nil {
+			summaries[i].lowerBound = singleValue(lower)
+			summaries[i].upperBound = singleValue(upper)
+		}
+	}
+	return summaries
+}
+
+//------------------------------------------------------------------------------
+
+func manifestListSchema(formatVersion int) string {
+	summary := map[string]any{
+		"type": "record",
+		"name": "r508",
+		"fields": []any{
+			avroField("contains_null", 509, "boolean"),
+			avroField("contains_nan", 518, optional("boolean")),
+			avroField("lower_bound", 510, optional("bytes")),
+			avroField("upper_bound", 511, optional("bytes")),
+		},
+	}
+	partitions := avroField("partitions", 507, optional(map[string]any{"type": "array", "items": summary, "element-id": 508}))
+
+	var fields []any
+	if formatVersion == 1 {
+		fields = []any{
+			avroField("manifest_path", 500, "string"),
+			avroField("manifest_length", 501, "long"),
+			avroField("partition_spec_id", 502, "int"),
+			avroField("added_snapshot_id", 503, optional("long")),
+			avroField("added_data_files_count", 504, optional("int")),
+			avroField("existing_data_files_count", 505, optional("int")),
+			avroField("deleted_data_files_count", 506, optional("int")),
+			partitions,
+			avroField("added_rows_count", 512, optional("long")),
+			avroField("existing_rows_count", 513, optional("long")),
+			avroField("deleted_rows_count", 514, optional("long")),
+		}
+	} else {
+		fields = []any{
+			avroField("manifest_path", 500, "string"),
+			avroField("manifest_length", 501, "long"),
+			avroField("partition_spec_id", 502, "int"),
+			avroField("content", 517, "int"),
+			avroField("sequence_number", 515, "long"),
+			avroField("min_sequence_number", 516, "long"),
+			avroField("added_snapshot_id", 503, "long"),
+			avroField("added_files_count", 504, "int"),
+			avroField("existing_files_count", 505, "int"),
+			avroField("deleted_files_count", 506, "int"),
+			avroField("added_rows_count", 512, "long"),
+			avroField("existing_rows_count", 513, "long"),
+			avroField("deleted_rows_count", 514, "long"),
+			partitions,
+			avroField("key_metadata", 519, optional("bytes")),
+		}
+	}
+
+	schema, _ := json.Marshal(map[string]any{"type": "record", "name": "manifest_file", "fields": fields})
+	return string(schema)
+}
+
+func optionalUnion(name string, v any, set bool) any {
+	if !set {
+		return nil
+	}
+	return goavro.Union(name, v)
+}
+
+// writeManifestList encodes a manifest list for a new snapshot.
+func writeManifestList(formatVersion int, snap *snapshot, manifests []manifestFile) ([]byte, error) {
+	parentID := "null"
+	if snap.ParentSnapshotID != nil {
+		parentID = strconv.FormatInt(*snap.ParentSnapshotID, 10)
+	}
+	metadata := map[string][]byte{
+		"snapshot-id":        []byte(strconv.FormatInt(snap.SnapshotID, 10)),
+		"parent-snapshot-id": []byte(parentID),
+		"format-version":     []byte(strconv.Itoa(formatVersion)),
+	}
+	if formatVersion > 1 {
+		metadata["sequence-number"] = []byte(strconv.FormatInt(snap.SequenceNumber, 10))
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Schema:          manifestListSchema(formatVersion),
+		CompressionName: goavro.CompressionDeflateLabel,
+		MetaData:        metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create manifest list writer: %w", err)
+	}
+
+	records := make([]any, 0, len(manifests))
+	for _, m := range manifests {
+		var partitions any
+		if m.partitions != nil {
+			summaries := make([]any, 0, len(m.partitions))
+			for _, s := range m.partitions {
+				summary := map[string]any{
+					"contains_null": s.containsNull,
+					"contains_nan":  nil,
+					"lower_bound":   optionalUnion("bytes", s.lowerBound, s.lowerBound != nil),
+					"upper_bound":   optionalUnion("bytes", s.upperBound, s.upperBound != nil),
+				}
+				if s.containsNaN != nil {
+					summary["contains_nan"] = goavro.Union("boolean", *s.containsNaN)
+				}
+				summaries = append(summaries, summary)
+			}
+			partitions = goavro.Union("array", summaries)
+		}
+
+		record := map[string]any{
+			"manifest_path":     m.path,
+			"manifest_length":   m.length,
+			"partition_spec_id": m.specID,
+			"partitions":        partitions,
+		}
+		if formatVersion == 1 {
+			record["added_snapshot_id"] = goavro.Union("long", m.addedSnapshotID)
+			record["added_data_files_count"] = goavro.Union("int", m.addedFiles)
+			record["existing_data_files_count"] = goavro.Union("int", m.existingFiles)
+			record["deleted_data_files_count"] = goavro.Union("int", m.deletedFiles)
+			record["added_rows_count"] = goavro.Union("long", m.addedRows)
+			record["existing_rows_count"] = goavro.Union("long", m.existingRows)
+			record["deleted_rows_count"] = goavro.Union("long", m.deletedRows)
+		} else {
+			record["content"] = m.content
+			record["sequence_number"] = m.sequenceNumber
+			record["min_sequence_number"] = m.minSequenceNumber
+			record["added_snapshot_id"] = m.addedSnapshotID
+			record["added_files_count"] = m.addedFiles
+			record["existing_files_count"] = m.existingFiles
+			record["deleted_files_count"] = m.deletedFiles
+			record["added_rows_count"] = m.addedRows
+			record["existing_rows_count"] = m.existingRows
+			record["deleted_rows_count"] = m.deletedRows
+			record["key_metadata"] = optionalUnion("bytes", m.keyMetadata, m.keyMetadata != nil)
+		}
+		records = append(records, record)
+	}
+	if len(records) > 0 {
+		if err := w.Append(records); err != nil {
+			return nil, fmt.Errorf("failed to write manifest list: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+//------------------------------------------------------------------------------
+
+// unwrapUnion returns the value of a decoded Avro union.
+func unwrapUnion(v any) any {
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		for _, inner := range m {
+			return inner
+		}
+	}
+	return v
+}
+
+func recordInt64(record map[string]any, names ...string) int64 {
+	for _, name := range names {
+		switch t := unwrapUnion(record[name]).(type) {
+		case int64:
+			return t
+		case int32:
+			return int64(t)
+		}
+	}
+	return 0
+}
+
+func recordBytes(record map[string]any, name string) []byte {
+	b, _ := unwrapUnion(record[name]).([]byte)
+	return b
+}
+
+// readManifestList decodes the manifest list of an existing snapshot, which
+// might have been written by any Iceberg implementation and format version.
+func readManifestList(b []byte) ([]manifestFile, error) {
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest list: %w", err)
+	}
+
+	var manifests []manifestFile
+	for r.Scan() {
+		datum, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest list: %w", err)
+		}
+		record, ok := datum.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected manifest list record type %T", datum)
+		}
+
+		path, _ := record["manifest_path"].(string)
+		m := manifestFile{
+			path:              path,
+			length:            recordInt64(record, "manifest_length"),
+			specID:            int32(recordInt64(record, "partition_spec_id")),
+			content:           int32(recordInt64(record, "content")),
+			sequenceNumber:    recordInt64(record, "sequence_number"),
+			minSequenceNumber: recordInt64(record, "min_sequence_number"),
+			addedSnapshotID:   recordInt64(record, "added_snapshot_id"),
+			addedFiles:        int32(recordInt64(record, "added_files_count", "added_data_files_count")),
+			existingFiles:     int32(recordInt64(record, "existing_files_count", "existing_data_files_count")),
+			deletedFiles:      int32(recordInt64(record, "deleted_files_count", "deleted_data_files_count")),
+			addedRows:         recordInt64(record, "added_rows_count"),
+			existingRows:      recordInt64(record, "existing_rows_count"),
+			deletedRows:       recordInt64(record, "deleted_rows_count"),
+			keyMetadata:       recordBytes(record, "key_metadata"),
+		}
+
+		if summaries, ok := unwrapUnion(record["partitions"]).([]any); ok {
+			m.partitions = []fieldSummary{}
+			for _, s := range summaries {
+				sm, _ := s.(map[string]any)
+				summary := fieldSummary{
+					lowerBound: recordBytes(sm, "lower_bound"),
+					upperBound: recordBytes(sm, "upper_bound"),
+				}
+				summary.containsNull, _ = sm["contains_null"].(bool)
+				if nan, ok := unwrapUnion(sm["contains_nan"]).(bool); ok {
+					summary.containsNaN = &nan
+				}
+				m.partitions = append(m.partitions, summary)
+			}
+		}
+		manifests = append(manifests, m)
+	}
+	if err := r.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest list: %w", err)
+	}
+	return manifests, nil
+}
@@ -0,0 +1,90 @@
+package iceberg
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestV1(t *testing.T) {
+	meta := &tableMetadata{FormatVersion: 1}
+	schema := &tableSchema{}
+	spec := &partitionSpec{Fields: []*partitionField{
+		{SourceID: 1, FieldID: 1000, Name: "event_day", Transform: "day"},
+		{SourceID: 2, FieldID: 1001, Name: "score", Transform: "identity"},
+	}}
+	dayT, err := parseTransform("day", "timestamptz")
+	require.NoError(t, err)
+	scoreT, err := parseTransform("identity", "double")
+	require.NoError(t, err)
+	transforms := []*transform{dayT, scoreT}
+
+	files := []dataFile{
+		{path: "file:///a.parquet", recordCount: 2, sizeBytes: 10, partition: []any{int32(17486), 1.5}},
+		{path: "file:///b.parquet", recordCount: 3, sizeBytes: 20, partition: []any{int32(17487), math.NaN()}},
+		{path: "file:///c.parquet", recordCount: 1, sizeBytes: 5, partition: []any{nil, -2.0}},
+	}
+
+	b, err := writeManifest(meta, schema, spec, transforms, 42, files)
+	require.NoError(t, err)
+
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(r.MetaData()["format-version"]))
+	assert.Equal(t, `[{"source-id":1,"field-id":1000,"name":"event_day","transform":"day"},{"source-id":2,"field-id":1001,"name":"score","transform":"identity"}]`, string(r.MetaData()["partition-spec"]))
+
+	var entries []map[string]any
+	for r.Scan() {
+		datum, err := r.Read()
+		require.NoError(t, err)
+		entries = append(entries, datum.(map[string]any))
+	}
+	require.NoError(t, r.Err())
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, int64(42), entries[0]["snapshot_id"])
+	df := entries[0]["data_file"].(map[string]any)
+	assert.Equal(t, "file:///a.parquet", df["file_path"])
+	assert.Equal(t, int64(64*1024*1024), df["block_size_in_bytes"])
+	assert.Equal(t, map[string]any{
+		"event_day": map[string]any{"int.date": time.Date(2017, 11, 16, 0, 0, 0, 0, time.UTC)},
+		"score":     map[string]any{"double": 1.5},
+	}, df["partition"])
+	assert.Nil(t, entries[2]["data_file"].(map[string]any)["partition"].(map[string]any)["event_day"])
+
+	summaries := summarisePartitions(transforms, files)
+	require.Len(t, summaries, 2)
+	assert.True(t, summaries[0].containsNull)
+	assert.Nil(t, summaries[0].containsNaN)
+	assert.Equal(t, singleValue(int32(17486)), summaries[0].lowerBound)
+	assert.Equal(t, singleValue(int32(17487)), summaries[0].upperBound)
+	assert.False(t, summaries[1].containsNull)
+	assert.True(t, *summaries[1].containsNaN)
+	assert.Equal(t, singleValue(-2.0), summaries[1].lowerBound)
+	assert.Equal(t, singleValue(1.5), summaries[1].upperBound)
+
+	parent := int64(41)
+	manifests := []manifestFile{
+		{path: "file:///m0.avro", length: int64(len(b)), addedSnapshotID: 42, addedFiles: 3, addedRows: 6, partitions: summaries},
+		{path: "file:///m1.avro", length: 100, addedSnapshotID: 41, existingFiles: 1, existingRows: 2},
+	}
+	list, err := writeManifestList(1, &snapshot{SnapshotID: 42, ParentSnapshotID: &parent}, manifests)
+	require.NoError(t, err)
+
+	read, err := readManifestList(list)
+	require.NoError(t, err)
+	assert.Equal(t, manifests, read)
+}
+
+func TestSingleValue(t *testing.T) {
+	assert.Equal(t, []byte{1}, singleValue(true))
+	assert.Equal(t, []byte{0xd2, 0x04, 0, 0}, singleValue(int32(1234)))
+	assert.Equal(t, []byte{0xd2, 0x04, 0, 0, 0, 0, 0, 0}, singleValue(int64(1234)))
+	assert.Equal(t, []byte{0, 0, 0x80, 0x3f}, singleValue(float32(1)))
+	assert.Equal(t, []byte("iceberg"), singleValue("iceberg"))
+}
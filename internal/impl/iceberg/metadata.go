@@ -0,0 +1,466 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tableMetadata contains the fields of Iceberg table metadata that are needed
+// in order to append data files to a table.
+type tableMetadata struct {
+	FormatVersion      int               `json:"format-version"`
+	TableUUID          string            `json:"table-uuid"`
+	Location           string            `json:"location"`
+	LastSequenceNumber int64             `json:"last-sequence-number"`
+	CurrentSchemaID    int               `json:"current-schema-id"`
+	Schemas            []*tableSchema    `json:"schemas"`
+	Schema             *tableSchema      `json:"schema"`
+	DefaultSpecID      int               `json:"default-spec-id"`
+	PartitionSpecs     []*partitionSpec  `json:"partition-specs"`
+	PartitionSpec      []*partitionField `json:"partition-spec"`
+	CurrentSnapshotID  *int64            `json:"current-snapshot-id"`
+	Snapshots          []*snapshot       `json:"snapshots"`
+	Properties         map[string]string `json:"properties"`
+}
+
+type tableSchema struct {
+	SchemaID int            `json:"schema-id"`
+	Fields   []*schemaField `json:"fields"`
+}
+
+type schemaField struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Required bool            `json:"required"`
+	Type     json.RawMessage `json:"type"`
+
+	primitive string
+}
+
+type partitionSpec struct {
+	SpecID int               `json:"spec-id"`
+	Fields []*partitionField `json:"fields"`
+}
+
+type partitionField struct {
+	SourceID  int    `json:"source-id"`
+	FieldID   int    `json:"field-id"`
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+}
+
+type snapshot struct {
+	SnapshotID       int64             `json:"snapshot-id"`
+	ParentSnapshotID *int64            `json:"parent-snapshot-id,omitempty"`
+	SequenceNumber   int64             `json:"sequence-number,omitempty"`
+	TimestampMs      int64             `json:"timestamp-ms"`
+	ManifestList     string            `json:"manifest-list"`
+	Summary          map[string]string `json:"summary"`
+	SchemaID         *int              `json:"schema-id,omitempty"`
+}
+
+func parseTableMetadata(b []byte) (*tableMetadata, error) {
+	var m tableMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse table metadata: %w", err)
+	}
+	if m.FormatVersion != 1 && m.FormatVersion != 2 {
+		return nil, fmt.Errorf("table format version %v is not supported", m.FormatVersion)
+	}
+
+	// Tables of format version 1 might only contain the legacy fields.
+	if len(m.Schemas) == 0 && m.Schema != nil {
+		m.Schemas = []*tableSchema{m.Schema}
+		m.CurrentSchemaID = m.Schema.SchemaID
+	}
+	if len(m.PartitionSpecs) == 0 {
+		m.PartitionSpecs = []*partitionSpec{{SpecID: 0, Fields: m.PartitionSpec}}
+		m.DefaultSpecID = 0
+	}
+	if m.CurrentSnapshotID != nil && *m.CurrentSnapshotID == -1 {
+		m.CurrentSnapshotID = nil
+	}
+	return &m, nil
+}
+
+func (m *tableMetadata) currentSchema() (*tableSchema, error) {
+	for _, s := range m.Schemas {
+		if s.SchemaID == m.CurrentSchemaID {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("table metadata does not contain the current schema %v", m.CurrentSchemaID)
+}
+
+func (m *tableMetadata) defaultSpec() (*partitionSpec, error) {
+	for _, s := range m.PartitionSpecs {
+		if s.SpecID == m.DefaultSpecID {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("table metadata does not contain the default partition spec %v", m.DefaultSpecID)
+}
+
+func (m *tableMetadata) currentSnapshot() *snapshot {
+	if m.CurrentSnapshotID == nil {
+		return nil
+	}
+	for _, s := range m.Snapshots {
+		if s.SnapshotID == *m.CurrentSnapshotID {
+			return s
+		}
+	}
+	return nil
+}
+
+func (m *tableMetadata) dataLocation() string {
+	if p := m.Properties["write.data.path"]; p != "" {
+		return strings.TrimSuffix(p, "/")
+	}
+	return strings.TrimSuffix(m.Location, "/") + "/data"
+}
+
+func (m *tableMetadata) metadataLocation() string {
+	if p := m.Properties["write.metadata.path"]; p != "" {
+		return strings.TrimSuffix(p, "/")
+	}
+	return strings.TrimSuffix(m.Location, "/") + "/metadata"
+}
+
+//------------------------------------------------------------------------------
+
+var supportedPrimitives = map[string]struct{}{
+	"boolean": {}, "int": {}, "long": {}, "float": {}, "double": {},
+	"date": {}, "time": {}, "timestamp": {}, "timestamptz": {},
+	"string": {}, "binary": {},
+}
+
+// resolveTypes checks that the fields of a schema are of supported types.
+func (s *tableSchema) resolveTypes() error {
+	for _, f := range s.Fields {
+		var t string
+		if err := json.Unmarshal(f.Type, &t); err != nil {
+			return fmt.Errorf("column %v has a nested type, which is not supported", f.Name)
+		}
+		if _, exists := supportedPrimitives[t]; !exists {
+			return fmt.Errorf("column %v has the type %v, which is not supported", f.Name, t)
+		}
+		f.primitive = t
+	}
+	return nil
+}
+
+func (s *tableSchema) fieldByID(id int) *schemaField {
+	for _, f := range s.Fields {
+		if f.ID == id {
+			return f
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	microsPerSecond = int64(time.Second / time.Microsecond)
+	microsPerDay    = 24 * 60 * 60 * microsPerSecond
+)
+
+func toInt64(v any) (int64, error) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case uint64:
+		if t > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v overflows a long", t)
+		}
+		return int64(t), nil
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("value %v is not an integer", t)
+		}
+		return int64(t), nil
+	case json.Number:
+		return t.Int64()
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func toFloat64(v any) (float64, error) {
+	switch t := v.(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	case json.Number:
+		return t.Float64()
+	}
+	i, err := toInt64(v)
+	return float64(i), err
+}
+
+func toTime(v any, layouts ...string) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		for _, layout := range layouts {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("failed to parse timestamp %q", t)
+	}
+	return time.Time{}, fmt.Errorf("expected a timestamp, got %T", v)
+}
+
+// convertValue converts a value of a structured message into the
+// representation of a primitive type that is written to data files, where
+// dates are days since the epoch, times are microseconds since midnight, and
+// timestamps are microseconds since the epoch.
+func convertValue(primitive string, v any) (any, error) {
+	switch primitive {
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected a boolean, got %T", v)
+	case "int":
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		if i > math.MaxInt32 || i < math.MinInt32 {
+			return nil, fmt.Errorf("value %v overflows an int", i)
+		}
+		return int32(i), nil
+	case "long":
+		return toInt64(v)
+	case "float":
+		f, err := toFloat64(v)
+		return float32(f), err
+	case "double":
+		return toFloat64(v)
+	case "date":
+		if i, err := toInt64(v); err == nil {
+			return int32(i), nil
+		}
+		t, err := toTime(v, "2006-01-02", time.RFC3339Nano)
+		if err != nil {
+			return nil, err
+		}
+		return int32(t.UTC().Truncate(24*time.Hour).Unix() / (24 * 60 * 60)), nil
+	case "time":
+		if i, err := toInt64(v); err == nil {
+			return i, nil
+		}
+		t, err := toTime(v, "15:04:05.999999999")
+		if err != nil {
+			return nil, err
+		}
+		return int64(t.Hour())*3600*microsPerSecond +
+			int64(t.Minute())*60*microsPerSecond +
+			int64(t.Second())*microsPerSecond +
+			int64(t.Nanosecond()/1000), nil
+	case "timestamp", "timestamptz":
+		if i, err := toInt64(v); err == nil {
+			return i, nil
+		}
+		t, err := toTime(v, time.RFC3339Nano, "2006-01-02T15:04:05.999999999")
+		if err != nil {
+			return nil, err
+		}
+		return t.UnixMicro(), nil
+	case "string":
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case []byte:
+			return string(t), nil
+		}
+		return nil, fmt.Errorf("expected a string, got %T", v)
+	case "binary":
+		switch t := v.(type) {
+		case string:
+			return []byte(t), nil
+		case []byte:
+			return t, nil
+		}
+		return nil, fmt.Errorf("expected binary data, got %T", v)
+	}
+	return nil, fmt.Errorf("type %v is not supported", primitive)
+}
+
+// convertRow converts a structured message into a row of a schema, keyed by
+// field ID.
+func convertRow(schema *tableSchema, v any) (map[int]any, error) {
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", v)
+	}
+
+	row := make(map[int]any, len(schema.Fields))
+	for _, f := range schema.Fields {
+		fv, exists := obj[f.Name]
+		if !exists || fv == nil {
+			if f.Required {
+				return nil, fmt.Errorf("required column %v is missing", f.Name)
+			}
+			continue
+		}
+		cv, err := convertValue(f.primitive, fv)
+		if err != nil {
+			return nil, fmt.Errorf("column %v: %w", f.Name, err)
+		}
+		row[f.ID] = cv
+	}
+	return row, nil
+}
+
+//------------------------------------------------------------------------------
+
+// transform is a partition transform that has been resolved against the type
+// of its source column.
+type transform struct {
+	name       string
+	param      int
+	source     string
+	resultType string
+}
+
+func parseTransform(str, source string) (*transform, error) {
+	t := &transform{name: str, source: source}
+	if name, rest, ok := strings.Cut(str, "["); ok {
+		param, err := strconv.Atoi(strings.TrimSuffix(rest, "]"))
+		if err != nil || param <= 0 {
+			return nil, fmt.Errorf("invalid transform %v", str)
+		}
+		t.name, t.param = name, param
+	}
+
+	isTemporal := source == "date" || source == "timestamp" || source == "timestamptz"
+	switch t.name {
+	case "identity", "void":
+		t.resultType = source
+	case "year", "month", "day", "hour":
+		if !isTemporal || (t.name == "hour" && source == "date") {
+			return nil, fmt.Errorf("transform %v cannot be applied to type %v", str, source)
+		}
+		t.resultType = "int"
+		if t.name == "day" {
+			t.resultType = "date"
+		}
+	case "bucket":
+		switch source {
+		case "int", "long", "date", "time", "timestamp", "timestamptz", "string", "binary":
+		default:
+			return nil, fmt.Errorf("transform %v cannot be applied to type %v", str, source)
+		}
+		t.resultType = "int"
+	case "truncate":
+		switch source {
+		case "int", "long", "string", "binary":
+		default:
+			return nil, fmt.Errorf("transform %v cannot be applied to type %v", str, source)
+		}
+		t.resultType = source
+	default:
+		return nil, fmt.Errorf("transform %v is not supported", str)
+	}
+	return t, nil
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// apply the transform to a converted value, nil values are always nil.
+func (t *transform) apply(v any) (any, error) {
+	if v == nil || t.name == "void" {
+		return nil, nil
+	}
+
+	switch t.name {
+	case "identity":
+		return v, nil
+	case "year", "month", "day", "hour":
+		var micros int64
+		if days, ok := v.(int32); ok {
+			micros = int64(days) * microsPerDay
+		} else {
+			micros = v.(int64)
+		}
+		ts := time.UnixMicro(micros).UTC()
+		switch t.name {
+		case "year":
+			return int32(ts.Year() - 1970), nil
+		case "month":
+			return int32((ts.Year()-1970)*12 + int(ts.Month()) - 1), nil
+		case "day":
+			return int32(floorDiv(micros, microsPerDay)), nil
+		default:
+			return int32(floorDiv(micros, 3600*microsPerSecond)), nil
+		}
+	case "bucket":
+		return int32((int64(bucketHash(v)) & math.MaxInt32) % int64(t.param)), nil
+	case "truncate":
+		w := int64(t.param)
+		switch tv := v.(type) {
+		case int32:
+			return int32(int64(tv) - (((int64(tv) % w) + w) % w)), nil
+		case int64:
+			return tv - (((tv % w) + w) % w), nil
+		case string:
+			runes := []rune(tv)
+			if len(runes) > t.param {
+				runes = runes[:t.param]
+			}
+			return string(runes), nil
+		case []byte:
+			if len(tv) > t.param {
+				tv = tv[:t.param]
+			}
+			return tv, nil
+		}
+	}
+	return nil, errors.New("unsupported value for transform")
+}
+
+// humanString returns the representation of a partition value that is used
+// within the paths of data files.
+func (t *transform) humanString(v any) string {
+	if v == nil {
+		return "null"
+	}
+	switch t.name {
+	case "year":
+		return strconv.Itoa(1970 + int(v.(int32)))
+	case "month":
+		m := int(v.(int32))
+		return fmt.Sprintf("%04d-%02d", 1970+int(floorDiv(int64(m), 12)), int(((int64(m)%12)+12)%12)+1)
+	case "hour":
+		return time.Unix(int64(v.(int32))*3600, 0).UTC().Format("2006-01-02-15")
+	}
+	switch t.resultType {
+	case "date":
+		return time.Unix(int64(v.(int32))*24*3600, 0).UTC().Format("2006-01-02")
+	case "timestamp", "timestamptz":
+		return time.UnixMicro(v.(int64)).UTC().Format("2006-01-02T15:04:05.999999")
+	case "binary":
+		return fmt.Sprintf("%x", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,161 @@
+package iceberg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketHash(t *testing.T) {
+	// Test vectors from the appendix of the Iceberg table spec.
+	for _, test := range []struct {
+		value    any
+		expected int32
+	}{
+		{value: int32(34), expected: 2017239379},
+		{value: int64(34), expected: 2017239379},
+		{value: int32(17486), expected: -653330422},
+		{value: int64(81068000000), expected: -662762989},
+		{value: int64(1510871468000000), expected: -2047944441},
+		{value: "iceberg", expected: 1210000089},
+		{value: []byte{0, 1, 2, 3}, expected: -188683207},
+	} {
+		assert.Equal(t, test.expected, bucketHash(test.value), "%v", test.value)
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	for _, test := range []struct {
+		primitive   string
+		value       any
+		expected    any
+		errContains string
+	}{
+		{primitive: "int", value: json.Number("5"), expected: int32(5)},
+		{primitive: "int", value: int64(1 << 40), errContains: "overflows"},
+		{primitive: "long", value: 5.0, expected: int64(5)},
+		{primitive: "long", value: 5.5, errContains: "not an integer"},
+		{primitive: "float", value: 1.5, expected: float32(1.5)},
+		{primitive: "boolean", value: "true", errContains: "expected a boolean"},
+		{primitive: "date", value: "2017-11-16", expected: int32(17486)},
+		{primitive: "date", value: int64(17486), expected: int32(17486)},
+		{primitive: "time", value: "22:31:08", expected: int64(81068000000)},
+		{primitive: "timestamp", value: "2017-11-16T22:31:08", expected: int64(1510871468000000)},
+		{primitive: "timestamptz", value: "2017-11-16T14:31:08-08:00", expected: int64(1510871468000000)},
+		{primitive: "timestamptz", value: "nope", errContains: "failed to parse"},
+		{primitive: "string", value: []byte("foo"), expected: "foo"},
+		{primitive: "binary", value: "foo", expected: []byte("foo")},
+	} {
+		v, err := convertValue(test.primitive, test.value)
+		if test.errContains != "" {
+			require.Error(t, err, "%v %v", test.primitive, test.value)
+			assert.Contains(t, err.Error(), test.errContains)
+			continue
+		}
+		require.NoError(t, err, "%v %v", test.primitive, test.value)
+		assert.Equal(t, test.expected, v, "%v %v", test.primitive, test.value)
+	}
+}
+
+func TestConvertRow(t *testing.T) {
+	schema := &tableSchema{Fields: []*schemaField{
+		{ID: 1, Name: "id", Required: true, Type: json.RawMessage(`"long"`)},
+		{ID: 2, Name: "name", Type: json.RawMessage(`"string"`)},
+	}}
+	require.NoError(t, schema.resolveTypes())
+
+	row, err := convertRow(schema, map[string]any{"id": json.Number("3"), "unknown": true})
+	require.NoError(t, err)
+	assert.Equal(t, map[int]any{1: int64(3)}, row)
+
+	_, err = convertRow(schema, map[string]any{"name": "foo"})
+	require.EqualError(t, err, "required column id is missing")
+
+	_, err = convertRow(schema, []any{})
+	require.Error(t, err)
+
+	nested := &tableSchema{Fields: []*schemaField{
+		{ID: 1, Name: "tags", Type: json.RawMessage(`{"type":"list","element-id":2,"element":"string","element-required":false}`)},
+	}}
+	require.EqualError(t, nested.resolveTypes(), "column tags has a nested type, which is not supported")
+}
+
+func TestTransforms(t *testing.T) {
+	for _, test := range []struct {
+		transform  string
+		source     string
+		value      any
+		expected   any
+		human      string
+		resultType string
+	}{
+		{transform: "identity", source: "string", value: "foo", expected: "foo", human: "foo", resultType: "string"},
+		{transform: "void", source: "long", value: int64(5), expected: nil, human: "null", resultType: "long"},
+		{transform: "bucket[16]", source: "int", value: int32(34), expected: int32(2017239379 % 16), human: "3", resultType: "int"},
+		{transform: "truncate[10]", source: "int", value: int32(-1), expected: int32(-10), human: "-10", resultType: "int"},
+		{transform: "truncate[10]", source: "long", value: int64(15), expected: int64(10), human: "10", resultType: "long"},
+		{transform: "truncate[3]", source: "string", value: "iceberg", expected: "ice", human: "ice", resultType: "string"},
+		{transform: "year", source: "date", value: int32(17486), expected: int32(47), human: "2017", resultType: "int"},
+		{transform: "month", source: "timestamp", value: int64(1510871468000000), expected: int32(574), human: "2017-11", resultType: "int"},
+		{transform: "day", source: "timestamptz", value: int64(1510871468000000), expected: int32(17486), human: "2017-11-16", resultType: "date"},
+		{transform: "day", source: "timestamp", value: int64(-1), expected: int32(-1), human: "1969-12-31", resultType: "date"},
+		{transform: "hour", source: "timestamp", value: int64(1510871468000000), expected: int32(419686), human: "2017-11-16-22", resultType: "int"},
+		{transform: "identity", source: "date", value: int32(17486), expected: int32(17486), human: "2017-11-16", resultType: "date"},
+	} {
+		tr, err := parseTransform(test.transform, test.source)
+		require.NoError(t, err, test.transform)
+		assert.Equal(t, test.resultType, tr.resultType, test.transform)
+
+		v, err := tr.apply(test.value)
+		require.NoError(t, err, test.transform)
+		assert.Equal(t, test.expected, v, test.transform)
+		assert.Equal(t, test.human, tr.humanString(v), test.transform)
+	}
+
+	for _, test := range []struct {
+		transform string
+		source    string
+	}{
+		{transform: "hour", source: "date"},
+		{transform: "year", source: "long"},
+		{transform: "bucket[0]", source: "long"},
+		{transform: "bucket[4]", source: "boolean"},
+		{transform: "truncate[2]", source: "double"},
+		{transform: "zorder", source: "long"},
+	} {
+		_, err := parseTransform(test.transform, test.source)
+		assert.Error(t, err, test.transform)
+	}
+}
+
+func TestParseTableMetadataV1(t *testing.T) {
+	meta, err := parseTableMetadata([]byte(`{
+  "format-version": 1,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "s3://bucket/db/table/",
+  "last-updated-ms": 1602638573874,
+  "last-column-id": 2,
+  "schema": {"type": "struct", "fields": [
+    {"id": 1, "name": "id", "required": true, "type": "long"},
+    {"id": 2, "name": "ts", "required": false, "type": "timestamptz"}
+  ]},
+  "partition-spec": [{"name": "ts_day", "transform": "day", "source-id": 2, "field-id": 1000}],
+  "properties": {"write.data.path": "s3://other/data/"},
+  "current-snapshot-id": -1,
+  "snapshots": []
+}`))
+	require.NoError(t, err)
+
+	layout, err := resolveLayout(meta)
+	require.NoError(t, err)
+	assert.Len(t, layout.schema.Fields, 2)
+	assert.Equal(t, "day", layout.transforms[0].name)
+	assert.Nil(t, meta.currentSnapshot())
+	assert.Equal(t, "s3://other/data", meta.dataLocation())
+	assert.Equal(t, "s3://bucket/db/table/metadata", meta.metadataLocation())
+
+	_, err = parseTableMetadata([]byte(`{"format-version":3}`))
+	require.EqualError(t, err, "table format version 3 is not supported")
+}
@@ -0,0 +1,609 @@
+package iceberg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	awsaws "github.com/aws/aws-sdk-go/aws"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gofrs/uuid"
+	"github.com/xitongsys/parquet-go/parquet"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws"
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	icoFieldCatalog            = "catalog"
+	icoFieldREST               = "rest"
+	icoFieldGlue               = "glue"
+	icoFieldAWS                = "aws"
+	icoFieldForcePathStyleURLs = "force_path_style_urls"
+	icoFieldNamespace          = "namespace"
+	icoFieldTable              = "table"
+	icoFieldMapping            = "mapping"
+	icoFieldCompression        = "compression"
+	icoFieldBackoff            = "backoff"
+	icoFieldBatching           = "batching"
+	icoFieldMaxInFlight        = "max_in_flight"
+)
+
+func icebergOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Writes batches of messages as parquet data files and appends them to an [Apache Iceberg](https://iceberg.apache.org/) table via a REST catalog or AWS Glue.").
+		Description(`
+Each batch of messages is written as one or more parquet data files to the data location of the table, and the files are then committed to the table as a new append snapshot. The batch is only acknowledged once the snapshot has been committed. Since every batch results in a snapshot it is recommended to configure `+"`batching`"+` with a large size and period, which can be tuned to trade the latency of data against the number of files and snapshots of the table.
+
+Tables of format versions 1 and 2 are supported.
+
+### Rows
+
+Messages are converted into rows of the current schema of the table by the names of its columns, after first applying the optional `+"`mapping`"+`. Columns that are missing from a message are written as null, unless they are required in which case the message is rejected. Only columns of primitive types are supported, and values of `+"`date`, `time` and `timestamp`"+` columns can be either strings, such as RFC 3339 timestamps, or integers of days or microseconds.
+
+### Partitioning
+
+Data files are partitioned according to the default partition spec of the table, where the source columns of the spec are computed like any other column and can therefore be derived with Bloblang within `+"`mapping`"+`. The transforms `+"`identity`, `bucket[N]`, `truncate[W]`, `year`, `month`, `day`, `hour` and `void`"+` are supported, and a batch results in a data file for each of its partitions.
+
+### Catalogs
+
+With the REST catalog, snapshots are committed with the requirement that the main branch of the table has not changed since it was loaded, and commits that conflict with other writers are attempted again according to `+"`backoff`"+`.
+
+With AWS Glue, the output writes the new metadata file of the table itself and updates the metadata location of the table. Glue does not support conditional updates and therefore concurrent commits from other writers to the same table are only detected on a best effort basis, it is recommended that only one writer commits to a table of a Glue catalog at a time.
+
+Data and metadata files are written to the locations of the table, which can be either S3 (`+"`s3://`"+`) or local (`+"`file://`"+`) locations. S3 is accessed with the credentials of the `+"`aws`"+` fields.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`. Data files of batches are written in parallel, whereas commits to the table are made one at a time.`).
+		Field(service.NewStringAnnotatedEnumField(icoFieldCatalog, map[string]string{
+			"rest": "An [Iceberg REST catalog](https://github.com/apache/iceberg/blob/main/open-api/rest-catalog-open-api.yaml), configured with the field `rest`.",
+			"glue": "The AWS Glue Data Catalog, configured with the fields `glue` and `aws`.",
+		}).
+			Description("The type of catalog that the table belongs to.").
+			Default("rest")).
+		Field(service.NewObjectField(icoFieldREST, restCatalogFields()...).
+			Description("The REST catalog of the table, when `catalog` is `rest`.").
+			Optional()).
+		Field(service.NewObjectField(icoFieldGlue, glueCatalogFields()...).
+			Description("The Glue catalog of the table, when `catalog` is `glue`.").
+			Advanced()).
+		Field(service.NewObjectField(icoFieldAWS, append(config.SessionFields(),
+			service.NewBoolField(icoFieldForcePathStyleURLs).
+				Description("Forces the client API to use path style URLs for S3, which helps when connecting to custom endpoints.").
+				Advanced().
+				Default(false),
+		)...).
+			Description("The AWS session used to access S3 locations and the Glue catalog.").
+			Advanced()).
+		Field(service.NewStringField(icoFieldNamespace).
+			Description("The namespace of the table, where the levels of nested namespaces are separated by dots.").
+			Example("analytics").
+			Example("warehouse.events")).
+		Field(service.NewStringField(icoFieldTable).
+			Description("The name of the table.").
+			Example("page_views")).
+		Field(service.NewBloblangField(icoFieldMapping).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the row of each message as an object keyed by column names, which can be used in order to derive the source columns of partitions.").
+			Example(`root = this
+root.event_date = this.timestamp.ts_parse("2006-01-02T15:04:05Z07:00").ts_format("2006-01-02")`).
+			Optional()).
+		Field(service.NewStringEnumField(icoFieldCompression, "uncompressed", "snappy", "gzip", "zstd").
+			Description("The compression of data files.").
+			Advanced().
+			Default("snappy")).
+		Field(service.NewBackOffField(icoFieldBackoff, false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 500,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Description("The backoff of commits that conflict with other writers to the table.").
+			Advanced()).
+		Field(service.NewBatchPolicyField(icoFieldBatching)).
+		Field(service.NewIntField(icoFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(4)).
+		Example(
+			"REST Catalog",
+			"In this example events are appended to a table of a REST catalog every minute, or every 100,000 events, whichever comes first. The table is partitioned by the column `event_date`, which is derived from the timestamp of each event.",
+			`
+output:
+  iceberg:
+    catalog: rest
+    rest:
+      url: http://localhost:8181
+      warehouse: s3://example-warehouse
+    namespace: analytics
+    table: page_views
+    mapping: |
+      root = this
+      root.event_date = this.timestamp.ts_parse("2006-01-02T15:04:05Z07:00").ts_format("2006-01-02")
+    batching:
+      count: 100000
+      period: 1m
+`,
+		).
+		Example(
+			"AWS Glue",
+			"In this example events are appended to a table of the Glue Data Catalog of the AWS account.",
+			`
+output:
+  iceberg:
+    catalog: glue
+    aws:
+      region: eu-west-1
+    namespace: analytics
+    table: page_views
+    batching:
+      count: 100000
+      period: 5m
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"iceberg", icebergOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(icoFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(icoFieldBatching); err != nil {
+				return
+			}
+			out, err = newIcebergOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var errCommitConflict = errors.New("commit conflict")
+
+// loadedTable is the metadata of a table as loaded from its catalog.
+type loadedTable struct {
+	meta             *tableMetadata
+	metadataLocation string
+	raw              []byte
+}
+
+// catalog loads tables and commits snapshots to them.
+type catalog interface {
+	connect(ctx context.Context) error
+	loadTable(ctx context.Context) (*loadedTable, error)
+	// commit a snapshot to the main branch of a table, an error wrapping
+	// errCommitConflict is returned when the table has changed since it was
+	// loaded.
+	commit(ctx context.Context, t *loadedTable, snap *snapshot) error
+	close()
+}
+
+type icebergOutput struct {
+	log *service.Logger
+
+	catalog     catalog
+	files       *fileIO
+	mapping     *bloblang.Executor
+	compression parquet.CompressionCodec
+	backoffCtor func() backoff.BackOff
+
+	commitMut sync.Mutex
+}
+
+func newIcebergOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*icebergOutput, error) {
+	o := &icebergOutput{
+		log: mgr.Logger(),
+	}
+
+	namespaceStr, err := conf.FieldString(icoFieldNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if namespaceStr == "" {
+		return nil, errors.New("a namespace must be specified")
+	}
+	namespace := strings.Split(namespaceStr, ".")
+	table, err := conf.FieldString(icoFieldTable)
+	if err != nil {
+		return nil, err
+	}
+
+	forcePathStyleURLs, err := conf.FieldBool(icoFieldAWS, icoFieldForcePathStyleURLs)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := aws.GetSession(conf.Namespace(icoFieldAWS), func(c *awsaws.Config) {
+		c.S3ForcePathStyle = awsaws.Bool(forcePathStyleURLs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	o.files = newFileIO(sess)
+
+	catalogType, err := conf.FieldString(icoFieldCatalog)
+	if err != nil {
+		return nil, err
+	}
+	switch catalogType {
+	case "rest":
+		if !conf.Contains(icoFieldREST) {
+			return nil, fmt.Errorf("the field %v must be specified when the catalog is rest", icoFieldREST)
+		}
+		if o.catalog, err = restCatalogFromParsed(conf.Namespace(icoFieldREST), namespace, table); err != nil {
+			return nil, err
+		}
+	case "glue":
+		if o.catalog, err = glueCatalogFromParsed(conf.Namespace(icoFieldGlue), sess, o.files, namespace, table); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("catalog type %v not recognised", catalogType)
+	}
+
+	if conf.Contains(icoFieldMapping) {
+		if o.mapping, err = conf.FieldBloblang(icoFieldMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	compressStr, err := conf.FieldString(icoFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compressStr {
+	case "uncompressed":
+		o.compression = parquet.CompressionCodec_UNCOMPRESSED
+	case "snappy":
+		o.compression = parquet.CompressionCodec_SNAPPY
+	case "gzip":
+		o.compression = parquet.CompressionCodec_GZIP
+	case "zstd":
+		o.compression = parquet.CompressionCodec_ZSTD
+	default:
+		return nil, fmt.Errorf("compression type %v not recognised", compressStr)
+	}
+
+	boff, err := conf.FieldBackOff(icoFieldBackoff)
+	if err != nil {
+		return nil, err
+	}
+	o.backoffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+	return o, nil
+}
+
+// tableLayout is the schema and partition spec that data files are written
+// with.
+type tableLayout struct {
+	schema     *tableSchema
+	spec       *partitionSpec
+	transforms []*transform
+}
+
+func resolveLayout(meta *tableMetadata) (*tableLayout, error) {
+	schema, err := meta.currentSchema()
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.resolveTypes(); err != nil {
+		return nil, err
+	}
+	spec, err := meta.defaultSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &tableLayout{schema: schema, spec: spec}
+	for _, pf := range spec.Fields {
+		source := schema.fieldByID(pf.SourceID)
+		if source == nil {
+			return nil, fmt.Errorf("partition field %v has a source column %v that does not exist", pf.Name, pf.SourceID)
+		}
+		t, err := parseTransform(pf.Transform, source.primitive)
+		if err != nil {
+			return nil, fmt.Errorf("partition field %v: %w", pf.Name, err)
+		}
+		l.transforms = append(l.transforms, t)
+	}
+	return l, nil
+}
+
+func (o *icebergOutput) Connect(ctx context.Context) error {
+	if err := o.catalog.connect(ctx); err != nil {
+		return err
+	}
+	t, err := o.catalog.loadTable(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = resolveLayout(t.meta)
+	return err
+}
+
+// partition is the rows of a batch that belong to the same partition.
+type partition struct {
+	path   string
+	values []any
+	rows   []map[int]any
+}
+
+func (l *tableLayout) partitionOf(row map[int]any) (path string, values []any, err error) {
+	segments := make([]string, len(l.spec.Fields))
+	values = make([]any, len(l.spec.Fields))
+	for i, pf := range l.spec.Fields {
+		if values[i], err = l.transforms[i].apply(row[pf.SourceID]); err != nil {
+			return "", nil, fmt.Errorf("partition field %v: %w", pf.Name, err)
+		}
+		segments[i] = url.QueryEscape(pf.Name) + "=" + url.QueryEscape(l.transforms[i].humanString(values[i]))
+	}
+	return strings.Join(segments, "/"), values, nil
+}
+
+func newSnapshotID() (int64, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return 0, err
+	}
+	return int64((binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:])) & math.MaxInt64), nil
+}
+
+func (o *icebergOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	t, err := o.catalog.loadTable(ctx)
+	if err != nil {
+		return err
+	}
+	layout, err := resolveLayout(t.meta)
+	if err != nil {
+		return err
+	}
+
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	partitions := map[string]*partition{}
+	for i := range batch {
+		msg := batch[i]
+		if o.mapping != nil {
+			if msg, err = batch.BloblangQuery(i, o.mapping); err != nil {
+				failed(i, fmt.Errorf("mapping failed: %w", err))
+				continue
+			}
+			if msg == nil {
+				failed(i, errors.New("mapping resulted in a deleted message"))
+				continue
+			}
+		}
+
+		v, err := msg.AsStructured()
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		row, err := convertRow(layout.schema, v)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		path, values, err := layout.partitionOf(row)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+
+		p, exists := partitions[path]
+		if !exists {
+			p = &partition{path: path, values: values}
+			partitions[path] = p
+		}
+		p.rows = append(p.rows, row)
+	}
+	if len(partitions) == 0 {
+		if batchErr != nil {
+			return batchErr
+		}
+		return nil
+	}
+
+	files, err := o.writeDataFiles(ctx, t.meta, layout, partitions)
+	if err != nil {
+		return err
+	}
+	if err := o.commit(ctx, t, layout, files); err != nil {
+		return err
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (o *icebergOutput) writeDataFiles(ctx context.Context, meta *tableMetadata, layout *tableLayout, partitions map[string]*partition) ([]dataFile, error) {
+	paths := make([]string, 0, len(partitions))
+	for path := range partitions {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	files := make([]dataFile, 0, len(paths))
+	for _, path := range paths {
+		p := partitions[path]
+		data, err := writeParquet(layout.schema, o.compression, p.rows)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		location := meta.dataLocation() + "/"
+		if path != "" {
+			location += path + "/"
+		}
+		location += id.String() + ".parquet"
+
+		if err := o.files.write(ctx, location, data); err != nil {
+			return nil, err
+		}
+		files = append(files, dataFile{
+			path:        location,
+			recordCount: int64(len(p.rows)),
+			sizeBytes:   int64(len(data)),
+			partition:   p.values,
+		})
+	}
+	return files, nil
+}
+
+// commit data files to the table as a new snapshot, reloading the table and
+// trying again when the commit conflicts with another writer.
+func (o *icebergOutput) commit(ctx context.Context, t *loadedTable, layout *tableLayout, files []dataFile) error {
+	snapshotID, err := newSnapshotID()
+	if err != nil {
+		return err
+	}
+
+	manifest, err := writeManifest(t.meta, layout.schema, layout.spec, layout.transforms, snapshotID, files)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	added := manifestFile{
+		path:            fmt.Sprintf("%v/%v-m0.avro", t.meta.metadataLocation(), id),
+		length:          int64(len(manifest)),
+		specID:          int32(layout.spec.SpecID),
+		addedSnapshotID: snapshotID,
+		addedFiles:      int32(len(files)),
+		partitions:      summarisePartitions(layout.transforms, files),
+	}
+	for _, f := range files {
+		added.addedRows += f.recordCount
+	}
+	if err := o.files.write(ctx, added.path, manifest); err != nil {
+		return err
+	}
+
+	o.commitMut.Lock()
+	defer o.commitMut.Unlock()
+
+	boff := o.backoffCtor()
+	for attempt := 0; ; attempt++ {
+		err := o.commitSnapshot(ctx, t, snapshotID, attempt, added, files)
+		if err == nil || !errors.Is(err, errCommitConflict) {
+			return err
+		}
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		o.log.Debugf("Retrying commit after %v due to: %v", wait, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if t, err = o.catalog.loadTable(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func summaryInt(summary map[string]string, key string) int64 {
+	i, _ := strconv.ParseInt(summary[key], 10, 64)
+	return i
+}
+
+func (o *icebergOutput) commitSnapshot(ctx context.Context, t *loadedTable, snapshotID int64, attempt int, added manifestFile, files []dataFile) error {
+	snap := &snapshot{
+		SnapshotID:  snapshotID,
+		TimestampMs: time.Now().UnixMilli(),
+		SchemaID:    &t.meta.CurrentSchemaID,
+	}
+	if t.meta.FormatVersion > 1 {
+		snap.SequenceNumber = t.meta.LastSequenceNumber + 1
+		added.sequenceNumber = snap.SequenceNumber
+		added.minSequenceNumber = snap.SequenceNumber
+	}
+
+	var parentSummary map[string]string
+	manifests := []manifestFile{added}
+	if parent := t.meta.currentSnapshot(); parent != nil {
+		snap.ParentSnapshotID = &parent.SnapshotID
+		parentSummary = parent.Summary
+		if parent.ManifestList != "" {
+			b, err := o.files.read(ctx, parent.ManifestList)
+			if err != nil {
+				return err
+			}
+			existing, err := readManifestList(b)
+			if err != nil {
+				return err
+			}
+			manifests = append(manifests, existing...)
+		}
+	}
+
+	var addedSize int64
+	for _, f := range files {
+		addedSize += f.sizeBytes
+	}
+	snap.Summary = map[string]string{
+		"operation":               "append",
+		"added-data-files":        strconv.Itoa(len(files)),
+		"added-records":           strconv.FormatInt(added.addedRows, 10),
+		"added-files-size":        strconv.FormatInt(addedSize, 10),
+		"changed-partition-count": strconv.Itoa(len(files)),
+		"total-data-files":        strconv.FormatInt(summaryInt(parentSummary, "total-data-files")+int64(len(files)), 10),
+		"total-records":           strconv.FormatInt(summaryInt(parentSummary, "total-records")+added.addedRows, 10),
+		"total-files-size":        strconv.FormatInt(summaryInt(parentSummary, "total-files-size")+addedSize, 10),
+		"total-delete-files":      strconv.FormatInt(summaryInt(parentSummary, "total-delete-files"), 10),
+		"total-position-deletes":  strconv.FormatInt(summaryInt(parentSummary, "total-position-deletes"), 10),
+		"total-equality-deletes":  strconv.FormatInt(summaryInt(parentSummary, "total-equality-deletes"), 10),
+	}
+
+	list, err := writeManifestList(t.meta.FormatVersion, snap, manifests)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	snap.ManifestList = fmt.Sprintf("%v/snap-%v-%v-%v.avro", t.meta.metadataLocation(), snapshotID, attempt, id)
+	if err := o.files.write(ctx, snap.ManifestList, list); err != nil {
+		return err
+	}
+	return o.catalog.commit(ctx, t, snap)
+}
+
+func (o *icebergOutput) Close(ctx context.Context) error {
+	o.catalog.close()
+	return nil
+}
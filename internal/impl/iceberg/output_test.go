@@ -0,0 +1,346 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeRESTCatalog serves a single table of a REST catalog.
+type fakeRESTCatalog struct {
+	t *testing.T
+
+	mut       sync.Mutex
+	metadata  map[string]any
+	conflicts int
+	commits   int
+}
+
+func newFakeRESTCatalog(t *testing.T, location string) *fakeRESTCatalog {
+	t.Helper()
+
+	var metadata map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+  "format-version": 2,
+  "table-uuid": "9c12d441-03fe-4693-9a96-a0705ddf69c1",
+  "location": "`+location+`",
+  "last-sequence-number": 0,
+  "last-updated-ms": 1602638573590,
+  "last-column-id": 3,
+  "current-schema-id": 0,
+  "schemas": [{"type": "struct", "schema-id": 0, "fields": [
+    {"id": 1, "name": "id", "required": true, "type": "long"},
+    {"id": 2, "name": "name", "required": false, "type": "string"},
+    {"id": 3, "name": "ts", "required": false, "type": "timestamptz"}
+  ]}],
+  "default-spec-id": 0,
+  "partition-specs": [{"spec-id": 0, "fields": [
+    {"name": "ts_day", "transform": "day", "source-id": 3, "field-id": 1000},
+    {"name": "id_bucket", "transform": "bucket[4]", "source-id": 1, "field-id": 1001}
+  ]}],
+  "last-partition-id": 1001,
+  "properties": {},
+  "current-snapshot-id": -1,
+  "snapshots": []
+}`), &metadata))
+
+	return &fakeRESTCatalog{t: t, metadata: metadata}
+}
+
+func (f *fakeRESTCatalog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer foo" {
+		http.Error(w, "nope", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/config":
+		assert.Equal(f.t, "test", r.URL.Query().Get("warehouse"))
+		_, _ = w.Write([]byte(`{"defaults":{},"overrides":{"prefix":"ws"}}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/ws/namespaces/analytics\x1fweb/tables/events":
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"metadata-location": "file:///unused.metadata.json",
+			"metadata":          f.metadata,
+		})
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/ws/namespaces/analytics\x1fweb/tables/events":
+		if f.conflicts > 0 {
+			f.conflicts--
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":{"message":"branch main has changed","type":"CommitFailedException","code":409}}`))
+			return
+		}
+
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		var body struct {
+			Requirements []map[string]any `json:"requirements"`
+			Updates      []map[string]any `json:"updates"`
+		}
+		require.NoError(f.t, dec.Decode(&body))
+
+		require.Len(f.t, body.Requirements, 2)
+		assert.Equal(f.t, f.metadata["table-uuid"], body.Requirements[0]["uuid"])
+		current := fmt.Sprintf("%v", f.metadata["current-snapshot-id"])
+		if current == "-1" {
+			current = "<nil>"
+		}
+		assert.Equal(f.t, current, fmt.Sprintf("%v", body.Requirements[1]["snapshot-id"]))
+
+		require.Len(f.t, body.Updates, 2)
+		assert.Equal(f.t, "add-snapshot", body.Updates[0]["action"])
+		assert.Equal(f.t, "set-snapshot-ref", body.Updates[1]["action"])
+		assert.Equal(f.t, "main", body.Updates[1]["ref-name"])
+
+		snap := body.Updates[0]["snapshot"].(map[string]any)
+		f.metadata["snapshots"] = append(f.metadata["snapshots"].([]any), snap)
+		f.metadata["current-snapshot-id"] = snap["snapshot-id"]
+		f.metadata["last-sequence-number"] = snap["sequence-number"]
+		f.commits++
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (f *fakeRESTCatalog) currentSnapshot() map[string]any {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	snaps := f.metadata["snapshots"].([]any)
+	return snaps[len(snaps)-1].(map[string]any)
+}
+
+func testIcebergOutput(t *testing.T, catalogURL string) *icebergOutput {
+	t.Helper()
+
+	pConf, err := icebergOutputSpec().ParseYAML(fmt.Sprintf(`
+rest:
+  url: %v
+  warehouse: test
+  token: foo
+namespace: analytics.web
+table: events
+mapping: |
+  root = this
+  root.id = this.user_id
+`, catalogURL), nil)
+	require.NoError(t, err)
+
+	o, err := newIcebergOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return o
+}
+
+func readAvroRecords(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	b, err := os.ReadFile(strings.TrimPrefix(path, "file://"))
+	require.NoError(t, err)
+
+	r, err := goavro.NewOCFReader(bytes.NewReader(b))
+	require.NoError(t, err)
+
+	var records []map[string]any
+	for r.Scan() {
+		datum, err := r.Read()
+		require.NoError(t, err)
+		records = append(records, datum.(map[string]any))
+	}
+	require.NoError(t, r.Err())
+	return records
+}
+
+func readParquetRows(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	b, err := os.ReadFile(strings.TrimPrefix(path, "file://"))
+	require.NoError(t, err)
+
+	pr, err := reader.NewParquetReader(buffer.NewBufferFileFromBytes(b), nil, 1)
+	require.NoError(t, err)
+	defer pr.ReadStop()
+
+	// Each column of the data file must carry the ID of its field, where the
+	// reader renames columns to Go identifiers.
+	ids := map[string]int32{}
+	for i, s := range pr.Footer.Schema[1:] {
+		ids[pr.SchemaHandler.GetExName(i+1)] = s.GetFieldID()
+	}
+	assert.Equal(t, map[string]int32{"id": 1, "name": 2, "ts": 3}, ids)
+
+	res, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	require.NoError(t, err)
+
+	jBytes, err := json.Marshal(res)
+	require.NoError(t, err)
+
+	var rows []map[string]any
+	require.NoError(t, json.Unmarshal(jBytes, &rows))
+	return rows
+}
+
+func TestIcebergOutputREST(t *testing.T) {
+	location := "file://" + filepath.ToSlash(t.TempDir())
+	fake := newFakeRESTCatalog(t, location)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	o := testIcebergOutput(t, server.URL)
+	ctx := context.Background()
+	require.NoError(t, o.Connect(ctx))
+	t.Cleanup(func() {
+		_ = o.Close(ctx)
+	})
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"user_id":1,"name":"foo","ts":"2022-10-01T10:00:00Z"}`)),
+		service.NewMessage([]byte(`{"user_id":1,"name":"bar","ts":"2022-10-01T11:00:00Z"}`)),
+		service.NewMessage([]byte(`{"name":"missing an id"}`)),
+		service.NewMessage([]byte(`{"user_id":1,"ts":"2022-10-02T10:00:00Z"}`)),
+	}
+	err := o.WriteBatch(ctx, batch)
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if i == 2 {
+			assert.EqualError(t, err, "required column id is missing")
+		} else {
+			assert.NoError(t, err)
+		}
+		return true
+	})
+
+	snap := fake.currentSnapshot()
+	assert.Equal(t, json.Number("1"), snap["sequence-number"])
+	assert.Nil(t, snap["parent-snapshot-id"])
+	summary := snap["summary"].(map[string]any)
+	assert.Equal(t, "append", summary["operation"])
+	assert.Equal(t, "2", summary["added-data-files"])
+	assert.Equal(t, "3", summary["added-records"])
+	assert.Equal(t, "3", summary["total-records"])
+
+	manifestList := snap["manifest-list"].(string)
+	lists := readAvroRecords(t, manifestList)
+	require.Len(t, lists, 1)
+	assert.Equal(t, int32(2), lists[0]["added_files_count"])
+	assert.Equal(t, int64(3), lists[0]["added_rows_count"])
+	assert.Equal(t, int64(1), lists[0]["sequence_number"])
+
+	entries := readAvroRecords(t, lists[0]["manifest_path"].(string))
+	require.Len(t, entries, 2)
+
+	var paths []string
+	rows := map[string][]map[string]any{}
+	for _, e := range entries {
+		assert.Equal(t, int32(1), e["status"])
+		assert.Nil(t, e["sequence_number"])
+
+		df := e["data_file"].(map[string]any)
+		path := df["file_path"].(string)
+		paths = append(paths, strings.TrimPrefix(path, location+"/data/"))
+		rows[path] = readParquetRows(t, path)
+		assert.Equal(t, int64(len(rows[path])), df["record_count"])
+
+		partition := df["partition"].(map[string]any)
+		assert.Equal(t, map[string]any{"int": int32(0)}, partition["id_bucket"])
+	}
+	sort.Strings(paths)
+	require.Len(t, paths, 2)
+	assert.True(t, strings.HasPrefix(paths[0], "ts_day=2022-10-01/id_bucket=0/"), paths[0])
+	assert.True(t, strings.HasPrefix(paths[1], "ts_day=2022-10-02/id_bucket=0/"), paths[1])
+
+	var names []any
+	for _, r := range rows {
+		for _, row := range r {
+			names = append(names, row["Name"])
+		}
+	}
+	assert.ElementsMatch(t, []any{"foo", "bar", nil}, names)
+
+	// A second batch that conflicts once is appended to the first snapshot.
+	fake.mut.Lock()
+	fake.conflicts = 1
+	fake.mut.Unlock()
+
+	require.NoError(t, o.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"user_id":2,"name":"baz","ts":"2022-10-03T10:00:00Z"}`)),
+	}))
+
+	second := fake.currentSnapshot()
+	assert.Equal(t, snap["snapshot-id"], second["parent-snapshot-id"])
+	assert.Equal(t, json.Number("2"), second["sequence-number"])
+	assert.Equal(t, "4", second["summary"].(map[string]any)["total-records"])
+	assert.Equal(t, 2, fake.commits)
+
+	lists = readAvroRecords(t, second["manifest-list"].(string))
+	require.Len(t, lists, 2)
+	assert.Equal(t, int64(2), lists[0]["sequence_number"])
+	assert.Equal(t, int64(1), lists[1]["sequence_number"])
+	assert.Contains(t, second["manifest-list"], "-1-")
+}
+
+func TestIcebergOutputRESTErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/config" {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":{"message":"table does not exist","type":"NoSuchTableException","code":404}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	o := testIcebergOutput(t, server.URL)
+	err := o.Connect(context.Background())
+	require.EqualError(t, err, "failed to load table: 404: table does not exist (NoSuchTableException)")
+}
+
+func TestIcebergOutputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name:   "rest without config",
+			conf:   "namespace: foo\ntable: bar\n",
+			errStr: "the field rest must be specified when the catalog is rest",
+		},
+		{
+			name:   "glue with nested namespace",
+			conf:   "catalog: glue\nnamespace: foo.bar\ntable: baz\n",
+			errStr: "the glue catalog requires a namespace of exactly one level, got 2",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := icebergOutputSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newIcebergOutputFromParsed(pConf, service.MockResources())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
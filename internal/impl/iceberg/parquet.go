@@ -0,0 +1,74 @@
+package iceberg
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+var parquetTypeTags = map[string]string{
+	"boolean":     "type=BOOLEAN",
+	"int":         "type=INT32",
+	"long":        "type=INT64",
+	"float":       "type=FLOAT",
+	"double":      "type=DOUBLE",
+	"date":        "type=INT32, convertedtype=DATE",
+	"time":        "type=INT64, convertedtype=TIME_MICROS",
+	"timestamp":   "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=false, logicaltype.unit=MICROS",
+	"timestamptz": "type=INT64, logicaltype=TIMESTAMP, logicaltype.isadjustedtoutc=true, logicaltype.unit=MICROS",
+	"string":      "type=BYTE_ARRAY, convertedtype=UTF8",
+	"binary":      "type=BYTE_ARRAY",
+}
+
+// parquetSchema returns the column tags of a schema, which include the field
+// IDs that Iceberg uses in order to resolve the columns of data files.
+func parquetSchema(schema *tableSchema) ([]string, error) {
+	tags := make([]string, 0, len(schema.Fields))
+	for _, f := range schema.Fields {
+		if strings.ContainsAny(f.Name, ",=") {
+			return nil, fmt.Errorf("column name %q contains characters that are not supported", f.Name)
+		}
+		repetition := "OPTIONAL"
+		if f.Required {
+			repetition = "REQUIRED"
+		}
+		tags = append(tags, fmt.Sprintf("name=%v, %v, repetitiontype=%v, fieldid=%v", f.Name, parquetTypeTags[f.primitive], repetition, f.ID))
+	}
+	return tags, nil
+}
+
+// writeParquet encodes rows of a schema as a parquet file.
+func writeParquet(schema *tableSchema, compression parquet.CompressionCodec, rows []map[int]any) ([]byte, error) {
+	tags, err := parquetSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	pw, err := writer.NewCSVWriterFromWriter(tags, &buf, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parquet writer: %w", err)
+	}
+	pw.CompressionType = compression
+
+	for _, row := range rows {
+		rec := make([]any, len(schema.Fields))
+		for i, f := range schema.Fields {
+			v := row[f.ID]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			rec[i] = v
+		}
+		if err := pw.Write(rec); err != nil {
+			return nil, fmt.Errorf("failed to write row to parquet file: %w", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("failed to close parquet writer: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package iceberg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// fileIO reads and writes the data and metadata files of a table, which are
+// addressed by their absolute locations.
+type fileIO struct {
+	s3       *s3.S3
+	uploader *s3manager.Uploader
+}
+
+func newFileIO(sess *session.Session) *fileIO {
+	client := s3.New(sess)
+	return &fileIO{
+		s3:       client,
+		uploader: s3manager.NewUploaderWithClient(client),
+	}
+}
+
+func parseS3Location(location string) (bucket, key string, ok bool) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", "", false
+	}
+	switch u.Scheme {
+	case "s3", "s3a", "s3n":
+		return u.Host, strings.TrimPrefix(u.Path, "/"), true
+	}
+	return "", "", false
+}
+
+func localPath(location string) (string, error) {
+	if strings.HasPrefix(location, "file:") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", err
+		}
+		return u.Path, nil
+	}
+	if filepath.IsAbs(location) {
+		return location, nil
+	}
+	return "", fmt.Errorf("location %v has a scheme that is not supported", location)
+}
+
+func (f *fileIO) read(ctx context.Context, location string) ([]byte, error) {
+	if bucket, key, ok := parseS3Location(location); ok {
+		out, err := f.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %v: %w", location, err)
+		}
+		defer out.Body.Close()
+		return io.ReadAll(out.Body)
+	}
+
+	path, err := localPath(location)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (f *fileIO) write(ctx context.Context, location string, data []byte) error {
+	if bucket, key, ok := parseS3Location(location); ok {
+		if _, err := f.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(data),
+		}); err != nil {
+			return fmt.Errorf("failed to write %v: %w", location, err)
+		}
+		return nil
+	}
+
+	path, err := localPath(location)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,530 @@
+package influxdb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ioFieldURL         = "url"
+	ioFieldToken       = "token"
+	ioFieldOrg         = "org"
+	ioFieldBucket      = "bucket"
+	ioFieldMeasurement = "measurement"
+	ioFieldTags        = "tags"
+	ioFieldFields      = "fields"
+	ioFieldTimestamp   = "timestamp"
+	ioFieldPrecision   = "precision"
+	ioFieldGzip        = "gzip"
+	ioFieldTLS         = "tls"
+	ioFieldBackoff     = "backoff"
+	ioFieldBatching    = "batching"
+	ioFieldMaxInFlight = "max_in_flight"
+)
+
+var ioPrecisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+func influxOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Writes points to InfluxDB 2.x or 3.0 with the line protocol.").
+		Description(`
+Each message of a batch is written as a point within a single request to the `+"[`/api/v2/write`](https://docs.influxdata.com/influxdb/v2/api/#operation/PostWrite)"+` endpoint, which is supported by both InfluxDB 2.x and 3.0.
+
+### Points
+
+The measurement of each point is obtained by resolving `+"`measurement`"+`, and the tags and fields by executing the `+"`tags`"+` and `+"`fields`"+` mappings, which must result in objects. Tag values are converted to strings. Field values that are integers are written as integers, other numbers as floats, and booleans and strings as their respective types. In order to avoid conflicting field types when a field is sometimes a whole number use the `+"[`number` method](/docs/guides/bloblang/methods#number)"+`, which always results in a float. Fields that are null are omitted, and a point without any fields is rejected.
+
+### Retries
+
+When InfluxDB responds with a status code of 429 or 503, which indicate that the write limits of the server have been reached, or any other 5XX status code, the write is attempted again according to `+"`backoff`"+`, where the period to wait is at least as long as any `+"`Retry-After`"+` header of the response. Any other failed responses result in the batch being rejected immediately.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(ioFieldURL).
+			Description("The base URL of the InfluxDB API.").
+			Example("http://localhost:8086")).
+		Field(service.NewStringField(ioFieldToken).
+			Description("An API token to authenticate with.").
+			Default("")).
+		Field(service.NewStringField(ioFieldOrg).
+			Description("The organization to write to, which is ignored by InfluxDB 3.0.").
+			Default("")).
+		Field(service.NewStringField(ioFieldBucket).
+			Description("The bucket, or database of InfluxDB 3.0, to write to.")).
+		Field(service.NewInterpolatedStringField(ioFieldMeasurement).
+			Description("The measurement of each point.").
+			Example("cpu").
+			Example(`${! meta("kafka_topic") }`)).
+		Field(service.NewBloblangField(ioFieldTags).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of tags for each point.").
+			Example(`root.host = this.host`).
+			Optional()).
+		Field(service.NewBloblangField(ioFieldFields).
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of fields for each point.").
+			Example(`root.usage = this.usage.number()`).
+			Example(`root = this.without("host", "ts")`)).
+		Field(service.NewInterpolatedStringField(ioFieldTimestamp).
+			Description("An optional timestamp of each point, either as a unix timestamp in seconds or an [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, when omitted the time at which the point is written is used.").
+			Example(`${! this.ts }`).
+			Optional()).
+		Field(service.NewStringEnumField(ioFieldPrecision, "ns", "us", "ms", "s").
+			Description("The precision of the timestamps of points written.").
+			Advanced().
+			Default("ns")).
+		Field(service.NewBoolField(ioFieldGzip).
+			Description("Whether to compress request bodies with gzip.").
+			Advanced().
+			Default(false)).
+		Field(service.NewTLSToggledField(ioFieldTLS)).
+		Field(service.NewBackOffField(ioFieldBackoff, false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 30,
+			MaxElapsedTime:  time.Minute * 2,
+		}).
+			Advanced()).
+		Field(service.NewBatchPolicyField(ioFieldBatching)).
+		Field(service.NewIntField(ioFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Sensor Readings",
+			"In this example JSON sensor readings are written as points of the `readings` measurement tagged with the sensor that produced them.",
+			`
+output:
+  influxdb:
+    url: http://localhost:8086
+    token: "${INFLUXDB_TOKEN}"
+    org: my-org
+    bucket: sensors
+    measurement: readings
+    tags: 'root.sensor = this.sensor_id'
+    fields: |
+      root.temperature = this.temperature.number()
+      root.humidity = this.humidity.number()
+    timestamp: ${! this.ts }
+    precision: ms
+    batching:
+      count: 5000
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"influxdb", influxOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(ioFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(ioFieldBatching); err != nil {
+				return
+			}
+			out, err = newInfluxOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type influxOutput struct {
+	log *service.Logger
+
+	url   string
+	token string
+	gzip  bool
+
+	measurement *service.InterpolatedString
+	tags        *bloblang.Executor
+	fields      *bloblang.Executor
+	timestamp   *service.InterpolatedString
+	precision   time.Duration
+
+	backoffCtor func() backoff.BackOff
+	client      *http.Client
+}
+
+func newInfluxOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*influxOutput, error) {
+	i := &influxOutput{log: mgr.Logger()}
+
+	baseURL, err := conf.FieldString(ioFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	org, err := conf.FieldString(ioFieldOrg)
+	if err != nil {
+		return nil, err
+	}
+	bucket, err := conf.FieldString(ioFieldBucket)
+	if err != nil {
+		return nil, err
+	}
+	precisionStr, err := conf.FieldString(ioFieldPrecision)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if i.precision, exists = ioPrecisions[precisionStr]; !exists {
+		return nil, fmt.Errorf("precision not recognised: %v", precisionStr)
+	}
+
+	query := url.Values{}
+	if org != "" {
+		query.Set("org", org)
+	}
+	query.Set("bucket", bucket)
+	query.Set("precision", precisionStr)
+	i.url = strings.TrimSuffix(baseURL, "/") + "/api/v2/write?" + query.Encode()
+
+	if i.token, err = conf.FieldString(ioFieldToken); err != nil {
+		return nil, err
+	}
+	if i.gzip, err = conf.FieldBool(ioFieldGzip); err != nil {
+		return nil, err
+	}
+	if i.measurement, err = conf.FieldInterpolatedString(ioFieldMeasurement); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ioFieldTags) {
+		if i.tags, err = conf.FieldBloblang(ioFieldTags); err != nil {
+			return nil, err
+		}
+	}
+	if i.fields, err = conf.FieldBloblang(ioFieldFields); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ioFieldTimestamp) {
+		if i.timestamp, err = conf.FieldInterpolatedString(ioFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+
+	boff, err := conf.FieldBackOff(ioFieldBackoff)
+	if err != nil {
+		return nil, err
+	}
+	i.backoffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+
+	i.client = &http.Client{}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(ioFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			i.client.Transport = cloned
+		} else {
+			i.client.Transport = &http.Transport{TLSClientConfig: tlsConf}
+		}
+	}
+	return i, nil
+}
+
+func (i *influxOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// Characters that must be escaped within the line protocol.
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+func mappedObject(batch service.MessageBatch, index int, exec *bloblang.Executor, name string) (map[string]any, error) {
+	res, err := batch.BloblangQuery(index, exec)
+	if err != nil {
+		return nil, fmt.Errorf("%v mapping failed: %w", name, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("%v mapping resulted in a deleted message", name)
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("%v mapping failed: %w", name, err)
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%v mapping resulted in a non-object type: %T", name, v)
+	}
+	return obj, nil
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendFieldValue(b []byte, v any) ([]byte, error) {
+	switch t := v.(type) {
+	case string:
+		b = append(b, '"')
+		b = append(b, stringEscaper.Replace(t)...)
+		return append(b, '"'), nil
+	case bool:
+		return strconv.AppendBool(b, t), nil
+	case int:
+		return append(strconv.AppendInt(b, int64(t), 10), 'i'), nil
+	case int64:
+		return append(strconv.AppendInt(b, t, 10), 'i'), nil
+	case uint64:
+		return append(strconv.AppendUint(b, t, 10), 'u'), nil
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return append(strconv.AppendInt(b, n, 10), 'i'), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendFieldValue(b, f)
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil, fmt.Errorf("value %v cannot be written", t)
+		}
+		return strconv.AppendFloat(b, t, 'f', -1, 64), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %T", v)
+}
+
+// appendPoint appends the line protocol form of the point of a message.
+func (i *influxOutput) appendPoint(b []byte, batch service.MessageBatch, index int, now time.Time) ([]byte, error) {
+	measurement := i.measurement.String(batch[index])
+	if measurement == "" {
+		return nil, errors.New("measurement must not be empty")
+	}
+
+	var tags map[string]any
+	if i.tags != nil {
+		var err error
+		if tags, err = mappedObject(batch, index, i.tags, "tags"); err != nil {
+			return nil, err
+		}
+	}
+	fields, err := mappedObject(batch, index, i.fields, "fields")
+	if err != nil {
+		return nil, err
+	}
+
+	ts := now
+	if i.timestamp != nil {
+		tsStr := i.timestamp.String(batch[index])
+		if secs, err := strconv.ParseFloat(tsStr, 64); err == nil {
+			ts = time.Unix(0, int64(secs*float64(time.Second)))
+		} else if ts, err = time.Parse(time.RFC3339Nano, tsStr); err != nil {
+			return nil, fmt.Errorf("failed to parse timestamp: %w", err)
+		}
+	}
+
+	start := len(b)
+	b = append(b, measurementEscaper.Replace(measurement)...)
+	for _, k := range sortedKeys(tags) {
+		var v string
+		switch t := tags[k].(type) {
+		case string:
+			v = t
+		case nil:
+		default:
+			v = fmt.Sprintf("%v", t)
+		}
+		if v == "" {
+			continue
+		}
+		b = append(b, ',')
+		b = append(b, keyEscaper.Replace(k)...)
+		b = append(b, '=')
+		b = append(b, keyEscaper.Replace(v)...)
+	}
+
+	written := 0
+	for _, k := range sortedKeys(fields) {
+		if fields[k] == nil {
+			continue
+		}
+		if written == 0 {
+			b = append(b, ' ')
+		} else {
+			b = append(b, ',')
+		}
+		b = append(b, keyEscaper.Replace(k)...)
+		b = append(b, '=')
+		if b, err = appendFieldValue(b, fields[k]); err != nil {
+			return nil, fmt.Errorf("field %v: %w", k, err)
+		}
+		written++
+	}
+	if written == 0 {
+		return b[:start], errors.New("point has no fields")
+	}
+
+	// Timestamps are rounded to the nearest unit of precision, as timestamps
+	// parsed from floats are rarely exact.
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, (ts.UnixNano()+int64(i.precision/2))/int64(i.precision), 10)
+	return append(b, '\n'), nil
+}
+
+func (i *influxOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+
+	now := time.Now()
+	var body []byte
+	for index := range batch {
+		b, err := i.appendPoint(body, batch, index, now)
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(index, err)
+			continue
+		}
+		body = b
+	}
+
+	if len(body) > 0 {
+		if err := i.write(ctx, body); err != nil {
+			if batchErr == nil {
+				return err
+			}
+			for index := range batch {
+				batchErr.Failed(index, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// errRetryable is an error from a write that may succeed when attempted again
+// after at least a given period.
+type errRetryable struct {
+	err   error
+	after time.Duration
+}
+
+func (e *errRetryable) Error() string {
+	return e.err.Error()
+}
+
+func (i *influxOutput) write(ctx context.Context, body []byte) error {
+	if i.gzip {
+		var zBuf bytes.Buffer
+		zw := gzip.NewWriter(&zBuf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		body = zBuf.Bytes()
+	}
+
+	boff := i.backoffCtor()
+	for {
+		err := i.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var rErr *errRetryable
+		if !errors.As(err, &rErr) {
+			return err
+		}
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if wait < rErr.after {
+			wait = rErr.after
+		}
+		i.log.Warnf("Retrying write in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (i *influxOutput) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+	if i.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBytes, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		rErr := &errRetryable{err: err}
+		if secs, pErr := strconv.Atoi(res.Header.Get("Retry-After")); pErr == nil {
+			rErr.after = time.Duration(secs) * time.Second
+		}
+		return rErr
+	}
+	return err
+}
+
+func (i *influxOutput) Close(ctx context.Context) error {
+	i.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,169 @@
+package influxdb
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testWriteServer struct {
+	mut      sync.Mutex
+	bodies   []string
+	requests []*http.Request
+	failures []int
+}
+
+func (s *testWriteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if len(s.failures) > 0 {
+		code := s.failures[0]
+		s.failures = s.failures[1:]
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "nope", code)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.bodies = append(s.bodies, string(b))
+	s.requests = append(s.requests, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testInfluxOutput(t *testing.T, srv *testWriteServer, extra string) (*influxOutput, func()) {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	conf, err := influxOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+token: foo
+org: my-org
+bucket: my-bucket
+measurement: ${! this.name }
+tags: 'root = this.tags | {}'
+fields: 'root = this.fields'
+timestamp: ${! this.ts }
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 50ms
+`+extra, nil)
+	require.NoError(t, err)
+
+	i, err := newInfluxOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, i.Connect(context.Background()))
+	return i, ts.Close
+}
+
+func TestInfluxOutputLineProtocol(t *testing.T) {
+	srv := &testWriteServer{}
+	i, done := testInfluxOutput(t, srv, `
+precision: ms
+gzip: true
+`)
+	defer done()
+
+	require.NoError(t, i.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"cpu","tags":{"host":"a b","region":"eu,west"},"fields":{"usage":0.5,"cores":8,"up":true,"note":"say \"hi\""},"ts":1700000000.123}`)),
+		service.NewMessage([]byte(`{"name":"mem load","tags":{"empty":""},"fields":{"used":1e3,"skipped":null},"ts":"2023-11-14T22:13:20Z"}`)),
+	}))
+	require.NoError(t, i.Close(context.Background()))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.bodies, 1)
+	assert.Equal(t, `cpu,host=a\ b,region=eu\,west cores=8i,note="say \"hi\"",up=true,usage=0.5 1700000000123
+mem\ load used=1000 1700000000000
+`, srv.bodies[0])
+
+	req := srv.requests[0]
+	assert.Equal(t, "Token foo", req.Header.Get("Authorization"))
+	assert.Equal(t, "/api/v2/write", req.URL.Path)
+	assert.Equal(t, "my-org", req.URL.Query().Get("org"))
+	assert.Equal(t, "my-bucket", req.URL.Query().Get("bucket"))
+	assert.Equal(t, "ms", req.URL.Query().Get("precision"))
+}
+
+func TestInfluxOutputRejectsPoints(t *testing.T) {
+	srv := &testWriteServer{}
+	i, done := testInfluxOutput(t, srv, "")
+	defer done()
+
+	err := i.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"cpu","fields":{"usage":1.5},"ts":1}`)),
+		service.NewMessage([]byte(`{"name":"cpu","fields":{},"ts":1}`)),
+		service.NewMessage([]byte(`{"name":"cpu","fields":{"usage":[1]},"ts":1}`)),
+		service.NewMessage([]byte(`{"name":"cpu","fields":{"usage":2.5},"ts":"nope"}`)),
+		service.NewMessage([]byte(`{"name":"cpu","fields":{"usage":3.5},"ts":2}`)),
+	})
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1, 2, 3}, failed)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.bodies, 1)
+	assert.Equal(t, "cpu usage=1.5 1000000000\ncpu usage=3.5 2000000000\n", srv.bodies[0])
+}
+
+func TestInfluxOutputRetries(t *testing.T) {
+	srv := &testWriteServer{failures: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}}
+	i, done := testInfluxOutput(t, srv, "")
+	defer done()
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"name":"cpu","fields":{"usage":1.5},"ts":1}`)),
+	}
+	require.NoError(t, i.WriteBatch(context.Background(), batch))
+
+	srv.mut.Lock()
+	assert.Len(t, srv.bodies, 1)
+	srv.failures = []int{http.StatusBadRequest}
+	srv.mut.Unlock()
+
+	// Client errors are not retried.
+	err := i.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+	assert.Len(t, srv.bodies, 1)
+	assert.Empty(t, srv.failures)
+}
@@ -0,0 +1,698 @@
+package io
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	walbFieldPath        = "path"
+	walbFieldSegmentSize = "segment_size"
+	walbFieldLimit       = "limit"
+)
+
+func walBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Stores consumed messages in a write-ahead log on disk and acknowledges them at the input level once they are synced. Messages that have not been acknowledged downstream are replayed when Benthos restarts, including after a crash.").
+		Description(`
+The log is written to a directory as a sequence of segment files, where each batch is appended to the newest segment and the input is only acknowledged once the segment has been synced to disk. When a batch is acknowledged downstream an acknowledgement record is appended to the log, and once all of the batches of the oldest segment have been acknowledged the segment is deleted as a whole. This means the log only ever writes sequentially, which allows it to sustain much higher throughput than buffers that update records in place.
+
+This buffer has a configurable limit, where consumption will be stopped with back pressure upstream if the total size of the batches that have not yet been acknowledged downstream reaches this amount. Since segments are only deleted once all of their batches are acknowledged the space used on disk can exceed this limit, by up to one segment in the typical case.
+
+## Delivery Guarantees
+
+Batches are replayed when the buffer is opened until they are acknowledged downstream, and therefore it's possible for batches that were delivered just before a crash to be delivered again. A batch that can no longer be decoded, for example because its segment was modified on disk, is not delivered and is instead logged and copied into the directory ` + "`quarantine`" + ` within the path of the log. Messages are stored along with their metadata, where metadata values are stored as JSON and therefore values of types that are not JSON types will be stored as strings or numbers.
+
+The directory of the log must not be shared by multiple buffers, including buffers of other Benthos instances.`).
+		Field(service.NewStringField(walbFieldPath).
+			Description("The path of a directory within which to store segments of the log, which is created if it does not already exist.").
+			Example("/var/lib/benthos/wal")).
+		Field(service.NewIntField(walbFieldSegmentSize).
+			Description("The size (in bytes) that a segment is allowed to reach before a new segment is started.").
+			Advanced().
+			Default(67108864)).
+		Field(service.NewIntField(walbFieldLimit).
+			Description("The maximum size (in bytes) of unacknowledged batches to allow before applying backpressure upstream.").
+			Default(1073741824))
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"wal", walBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newWALBufferFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newWALBufferFromConfig(conf *service.ParsedConfig, res *service.Resources) (*walBuffer, error) {
+	path, err := conf.FieldString(walbFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	segmentSize, err := conf.FieldInt(walbFieldSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := conf.FieldInt(walbFieldLimit)
+	if err != nil {
+		return nil, err
+	}
+	return newWALBuffer(path, int64(segmentSize), limit, res.Logger())
+}
+
+//------------------------------------------------------------------------------
+
+// Each record of the log consists of a header containing the length of its
+// body and a CRC of its body, followed by a body that starts with the type of
+// the record and the ID of the batch it refers to.
+const (
+	walHeaderSize     = 8
+	walBodyHeaderSize = 9
+	walSegmentExt     = ".wal"
+	walQuarantineDir  = "quarantine"
+)
+
+const (
+	walRecordBatch byte = 1
+	walRecordAck   byte = 2
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errWALCorrupt = errors.New("record is corrupt")
+
+type walSegment struct {
+	seq  uint64
+	path string
+	file *os.File
+	size int64
+
+	// The number of batches within the segment that have not been
+	// acknowledged.
+	live int
+}
+
+type walEntry struct {
+	id     uint64
+	seg    *walSegment
+	offset int64
+	size   int
+}
+
+// walBuffer implements the log directly on top of segment files rather than
+// with an embedded database, none of which are dependencies of Benthos. The
+// log needs very little from one: batches are only ever appended, they're
+// acknowledged out of order by appending further records rather than updating
+// them, and space is reclaimed by deleting whole segments. This keeps writes
+// sequential and recovery a single scan of the segments.
+type walBuffer struct {
+	dir         string
+	segmentSize int64
+	limit       int
+	log         *service.Logger
+
+	// writeMut protects appending to the log and the files of segments, and
+	// when both locks are needed it's always locked before cond.
+	writeMut sync.Mutex
+	segments []*walSegment
+	nextID   uint64
+
+	cond          *sync.Cond
+	pending       []walEntry
+	unackedCount  int
+	unackedBytes  int
+	reservedBytes int
+	endOfInput    bool
+	closed        bool
+}
+
+func newWALBuffer(dir string, segmentSize int64, limit int, logger *service.Logger) (*walBuffer, error) {
+	if dir == "" {
+		return nil, errors.New("a path must be specified")
+	}
+	if segmentSize <= 0 {
+		return nil, errors.New("segment_size must be greater than zero")
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be greater than zero")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	w := &walBuffer{
+		dir:         dir,
+		segmentSize: segmentSize,
+		limit:       limit,
+		log:         logger,
+		nextID:      1,
+		cond:        sync.NewCond(&sync.Mutex{}),
+	}
+	if err := w.recover(); err != nil {
+		w.closeSegments()
+		return nil, err
+	}
+	if len(w.pending) > 0 {
+		w.log.Infof("Replaying %v unacknowledged batches from write-ahead log", len(w.pending))
+	}
+	return w, nil
+}
+
+//------------------------------------------------------------------------------
+
+// Segments are named after a sequence number, which orders them.
+func walSegmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%v", seq, walSegmentExt)
+}
+
+// recover opens the existing segments of the log in order and rebuilds the
+// batches that have not been acknowledged. A record that was only partially
+// written to the newest segment, which happens when a crash interrupts a
+// write, is truncated as it was never synced and therefore never acknowledged.
+func (w *walBuffer) recover() error {
+	dirEntries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	var seqs []uint64
+	for _, e := range dirEntries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	entries := map[uint64]walEntry{}
+	for i, seq := range seqs {
+		seg, err := w.openSegment(seq)
+		if err != nil {
+			return err
+		}
+		w.segments = append(w.segments, seg)
+
+		isLast := i == len(seqs)-1
+		if err := w.scanSegment(seg, entries, isLast); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range entries {
+		w.pending = append(w.pending, e)
+		w.unackedCount++
+		w.unackedBytes += e.size
+		e.seg.live++
+	}
+	sort.Slice(w.pending, func(i, j int) bool { return w.pending[i].id < w.pending[j].id })
+
+	if len(w.segments) == 0 {
+		seg, err := w.createSegment(1)
+		if err != nil {
+			return err
+		}
+		w.segments = append(w.segments, seg)
+	}
+	return w.prune()
+}
+
+func (w *walBuffer) openSegment(seq uint64) (*walSegment, error) {
+	path := filepath.Join(w.dir, walSegmentName(seq))
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment: %w", err)
+	}
+	return &walSegment{seq: seq, path: path, file: f}, nil
+}
+
+// createSegment creates a new empty segment and syncs the directory so that
+// the segment survives a crash.
+func (w *walBuffer) createSegment(seq uint64) (*walSegment, error) {
+	path := filepath.Join(w.dir, walSegmentName(seq))
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+	if err := syncDir(w.dir); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to sync log directory: %w", err)
+	}
+	return &walSegment{seq: seq, path: path, file: f}, nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cErr := d.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+func (w *walBuffer) scanSegment(seg *walSegment, entries map[uint64]walEntry, isLast bool) error {
+	info, err := seg.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read segment %v: %w", seg.path, err)
+	}
+	fileSize := info.Size()
+
+	for seg.size < fileSize {
+		rType, id, recordSize, err := readWALRecordHeader(seg.file, seg.size, fileSize)
+		if err != nil {
+			if !errors.Is(err, errWALCorrupt) || !isLast {
+				return fmt.Errorf("failed to read segment %v at offset %v: %w", seg.path, seg.size, err)
+			}
+			w.log.Warnf("Truncating partially written record of segment %v at offset %v", seg.path, seg.size)
+			if err := seg.file.Truncate(seg.size); err != nil {
+				return fmt.Errorf("failed to truncate segment %v: %w", seg.path, err)
+			}
+			break
+		}
+
+		switch rType {
+		case walRecordBatch:
+			entries[id] = walEntry{id: id, seg: seg, offset: seg.size, size: recordSize}
+		case walRecordAck:
+			delete(entries, id)
+		}
+		if id >= w.nextID {
+			w.nextID = id + 1
+		}
+		seg.size += int64(recordSize)
+	}
+	return nil
+}
+
+// readWALRecordHeader reads and verifies the record at an offset of a segment,
+// returning its type, ID and total size.
+func readWALRecordHeader(f *os.File, offset, fileSize int64) (rType byte, id uint64, size int, err error) {
+	if fileSize-offset < walHeaderSize+walBodyHeaderSize {
+		return 0, 0, 0, errWALCorrupt
+	}
+
+	var header [walHeaderSize]byte
+	if _, err = f.ReadAt(header[:], offset); err != nil {
+		return
+	}
+	bodyLen := int64(binary.BigEndian.Uint32(header[0:4]))
+	if bodyLen < walBodyHeaderSize || fileSize-offset-walHeaderSize < bodyLen {
+		return 0, 0, 0, errWALCorrupt
+	}
+
+	body := make([]byte, bodyLen)
+	if _, err = f.ReadAt(body, offset+walHeaderSize); err != nil {
+		return
+	}
+	if crc32.Checksum(body, walCRCTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, 0, 0, errWALCorrupt
+	}
+	return body[0], binary.BigEndian.Uint64(body[1:9]), walHeaderSize + int(bodyLen), nil
+}
+
+func encodeWALRecord(rType byte, id uint64, payload []byte) []byte {
+	b := make([]byte, walHeaderSize+walBodyHeaderSize+len(payload))
+	body := b[walHeaderSize:]
+	body[0] = rType
+	binary.BigEndian.PutUint64(body[1:9], id)
+	copy(body[walBodyHeaderSize:], payload)
+
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.Checksum(body, walCRCTable))
+	return b
+}
+
+// Each message of a batch is stored as two parts, its contents followed by its
+// metadata as a JSON object.
+func encodeWALBatch(batch service.MessageBatch) ([]byte, error) {
+	parts := make([][]byte, 0, len(batch)*2)
+	for i, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		meta := map[string]any{}
+		_ = msg.MetaWalkMut(func(k string, v any) error {
+			meta[k] = v
+			return nil
+		})
+		metaBytes, err := json.Marshal(meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialise metadata of message %v: %w", i, err)
+		}
+		parts = append(parts, mBytes, metaBytes)
+	}
+	return message.SerializeBytes(parts), nil
+}
+
+func decodeWALBatch(b []byte) (service.MessageBatch, error) {
+	parts, err := message.DeserializeBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts)%2 != 0 {
+		return nil, message.ErrBadMessageBytes
+	}
+
+	batch := make(service.MessageBatch, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		msg := service.NewMessage(parts[i])
+
+		var meta map[string]any
+		if err := json.Unmarshal(parts[i+1], &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata: %w", err)
+		}
+		for k, v := range meta {
+			msg.MetaSetMut(k, v)
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+//------------------------------------------------------------------------------
+
+// append writes a record to the newest segment, starting a new segment first
+// when the newest one has reached the segment size. Must be called with
+// writeMut held.
+func (w *walBuffer) append(record []byte) (*walSegment, int64, error) {
+	if w.segments == nil {
+		return nil, 0, component.ErrTypeClosed
+	}
+
+	seg := w.segments[len(w.segments)-1]
+	if seg.size >= w.segmentSize {
+		// The current segment is synced so that acknowledgement records
+		// written to it aren't lost once it's no longer being appended to.
+		if err := seg.file.Sync(); err != nil {
+			return nil, 0, fmt.Errorf("failed to sync segment: %w", err)
+		}
+		newSeg, err := w.createSegment(seg.seq + 1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		w.cond.L.Lock()
+		w.segments = append(w.segments, newSeg)
+		err = w.prune()
+		w.cond.L.Unlock()
+		if err != nil {
+			w.log.Errorf("Failed to delete acknowledged segment: %v", err)
+		}
+		seg = newSeg
+	}
+
+	offset := seg.size
+	if _, err := seg.file.WriteAt(record, offset); err != nil {
+		// Attempt to remove any partially written data so that the record
+		// after it isn't lost when recovering.
+		_ = seg.file.Truncate(offset)
+		return nil, 0, fmt.Errorf("failed to write to segment: %w", err)
+	}
+	seg.size += int64(len(record))
+	return seg, offset, nil
+}
+
+// prune deletes the oldest segments for as long as all of their batches have
+// been acknowledged, the newest segment is always kept. Segments are only ever
+// deleted in order as the acknowledgement records of a segment might refer to
+// batches of older segments. Must be called with both locks held.
+func (w *walBuffer) prune() error {
+	for len(w.segments) > 1 && w.segments[0].live == 0 {
+		seg := w.segments[0]
+		_ = seg.file.Close()
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		w.segments[0] = nil
+		w.segments = w.segments[1:]
+	}
+	return nil
+}
+
+func (w *walBuffer) closeSegments() {
+	for _, seg := range w.segments {
+		_ = seg.file.Close()
+	}
+	w.segments = nil
+}
+
+//------------------------------------------------------------------------------
+
+func (w *walBuffer) WriteBatch(ctx context.Context, batch service.MessageBatch, aFn service.AckFunc) error {
+	payload, err := encodeWALBatch(batch)
+	if err != nil {
+		return err
+	}
+
+	recordSize := walHeaderSize + walBodyHeaderSize + len(payload)
+	if recordSize > w.limit {
+		return component.ErrMessageTooLarge
+	}
+
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+	go func() {
+		<-ctx.Done()
+		w.cond.Broadcast()
+	}()
+
+	w.cond.L.Lock()
+	for (w.unackedBytes + w.reservedBytes + recordSize) > w.limit {
+		if w.closed {
+			w.cond.L.Unlock()
+			return component.ErrTypeClosed
+		}
+		if ctx.Err() != nil {
+			w.cond.L.Unlock()
+			return ctx.Err()
+		}
+		w.cond.Wait()
+	}
+	if w.closed {
+		w.cond.L.Unlock()
+		return component.ErrTypeClosed
+	}
+	w.reservedBytes += recordSize
+	w.cond.L.Unlock()
+
+	entry, err := w.writeAndSync(payload)
+
+	w.cond.L.Lock()
+	w.reservedBytes -= recordSize
+	if err == nil {
+		entry.seg.live++
+		w.pending = append(w.pending, entry)
+		w.unackedCount++
+		w.unackedBytes += entry.size
+	}
+	w.cond.Broadcast()
+	w.cond.L.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return aFn(ctx, nil)
+}
+
+// writeAndSync appends a batch to the log and syncs it to disk.
+func (w *walBuffer) writeAndSync(payload []byte) (walEntry, error) {
+	w.writeMut.Lock()
+	defer w.writeMut.Unlock()
+
+	id := w.nextID
+	record := encodeWALRecord(walRecordBatch, id, payload)
+
+	seg, offset, err := w.append(record)
+	if err != nil {
+		return walEntry{}, err
+	}
+	if err := seg.file.Sync(); err != nil {
+		return walEntry{}, fmt.Errorf("failed to sync segment: %w", err)
+	}
+	w.nextID++
+	return walEntry{id: id, seg: seg, offset: offset, size: len(record)}, nil
+}
+
+func (w *walBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	ctx, done := context.WithCancel(ctx)
+	defer done()
+	go func() {
+		<-ctx.Done()
+		w.cond.Broadcast()
+	}()
+
+	var entry walEntry
+	var batch service.MessageBatch
+	for {
+		w.cond.L.Lock()
+		for len(w.pending) == 0 {
+			if w.closed || (w.endOfInput && w.unackedCount == 0) {
+				w.cond.L.Unlock()
+				return nil, nil, service.ErrEndOfBuffer
+			}
+			if ctx.Err() != nil {
+				w.cond.L.Unlock()
+				return nil, nil, ctx.Err()
+			}
+			w.cond.Wait()
+		}
+		entry = w.pending[0]
+		w.pending[0] = walEntry{}
+		w.pending = w.pending[1:]
+		w.cond.L.Unlock()
+
+		record, err := w.readRecord(entry)
+		if err != nil {
+			w.requeue(entry)
+			return nil, nil, err
+		}
+		if batch, err = decodeWALRecord(record); err == nil {
+			break
+		}
+
+		// A corrupt batch would fail again on every attempt, and so rather
+		// than blocking the batches after it we set it aside.
+		w.quarantine(entry, record, err)
+	}
+
+	var ackOnce sync.Once
+	return batch, func(ctx context.Context, err error) (ackErr error) {
+		ackOnce.Do(func() {
+			if err != nil {
+				w.requeue(entry)
+				return
+			}
+			ackErr = w.ack(entry)
+		})
+		return
+	}, nil
+}
+
+func (w *walBuffer) readRecord(entry walEntry) ([]byte, error) {
+	b := make([]byte, entry.size)
+	if _, err := entry.seg.file.ReadAt(b, entry.offset); err != nil {
+		return nil, fmt.Errorf("failed to read from segment: %w", err)
+	}
+	return b, nil
+}
+
+// decodeWALRecord verifies a batch record and decodes its batch.
+func decodeWALRecord(record []byte) (service.MessageBatch, error) {
+	body := record[walHeaderSize:]
+	if int(binary.BigEndian.Uint32(record[0:4])) != len(body) ||
+		crc32.Checksum(body, walCRCTable) != binary.BigEndian.Uint32(record[4:8]) {
+		return nil, errWALCorrupt
+	}
+	return decodeWALBatch(body[walBodyHeaderSize:])
+}
+
+// quarantine copies the record of a batch that could not be decoded into the
+// quarantine directory of the log, where it can be inspected, and then
+// acknowledges it so that it's never replayed.
+func (w *walBuffer) quarantine(entry walEntry, record []byte, cause error) {
+	dir := filepath.Join(w.dir, walQuarantineDir)
+	path := filepath.Join(dir, walSegmentName(entry.id))
+
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		err = os.WriteFile(path, record, 0o644)
+	}
+	if err != nil {
+		w.log.Errorf("Dropping batch %v of segment %v as it could not be decoded (%v), and it could not be quarantined: %v", entry.id, entry.seg.path, cause, err)
+	} else {
+		w.log.Errorf("Quarantined batch %v of segment %v to %v as it could not be decoded: %v", entry.id, entry.seg.path, path, cause)
+	}
+
+	if err := w.ack(entry); err != nil {
+		w.log.Errorf("Failed to acknowledge quarantined batch %v: %v", entry.id, err)
+	}
+}
+
+// requeue adds a batch back to the front of the queue so that it's the next to
+// be read.
+func (w *walBuffer) requeue(entry walEntry) {
+	w.cond.L.Lock()
+	w.pending = append([]walEntry{entry}, w.pending...)
+	w.cond.Broadcast()
+	w.cond.L.Unlock()
+}
+
+// ack appends an acknowledgement record of a batch to the log. The record is
+// not synced as losing it only results in the batch being delivered again.
+func (w *walBuffer) ack(entry walEntry) error {
+	w.writeMut.Lock()
+	defer w.writeMut.Unlock()
+
+	if _, _, err := w.append(encodeWALRecord(walRecordAck, entry.id, nil)); err != nil {
+		if errors.Is(err, component.ErrTypeClosed) {
+			return err
+		}
+		return fmt.Errorf("failed to write acknowledgement: %w", err)
+	}
+
+	w.cond.L.Lock()
+	defer w.cond.L.Unlock()
+
+	entry.seg.live--
+	w.unackedCount--
+	w.unackedBytes -= entry.size
+	w.cond.Broadcast()
+
+	if err := w.prune(); err != nil {
+		w.log.Errorf("Failed to delete acknowledged segment: %v", err)
+	}
+	return nil
+}
+
+func (w *walBuffer) EndOfInput() {
+	w.cond.L.Lock()
+	w.endOfInput = true
+	w.cond.Broadcast()
+	w.cond.L.Unlock()
+}
+
+func (w *walBuffer) Close(ctx context.Context) error {
+	w.cond.L.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.cond.L.Unlock()
+
+	w.writeMut.Lock()
+	defer w.writeMut.Unlock()
+
+	var err error
+	if len(w.segments) > 0 {
+		err = w.segments[len(w.segments)-1].file.Sync()
+	}
+	w.closeSegments()
+	return err
+}
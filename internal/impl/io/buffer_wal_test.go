@@ -0,0 +1,332 @@
+package io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func walBufFromConf(t *testing.T, conf string) *walBuffer {
+	t.Helper()
+
+	parsedConf, err := walBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	buf, err := newWALBufferFromConfig(parsedConf, service.MockResources())
+	require.NoError(t, err)
+
+	return buf
+}
+
+func walWrite(t *testing.T, w *walBuffer, contents ...string) {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		msg := service.NewMessage([]byte(c))
+		msg.MetaSetMut("content", c)
+		batch = append(batch, msg)
+	}
+
+	var acked bool
+	require.NoError(t, w.WriteBatch(context.Background(), batch, func(ctx context.Context, err error) error {
+		require.NoError(t, err)
+		acked = true
+		return nil
+	}))
+	require.True(t, acked)
+}
+
+func walRead(t *testing.T, w *walBuffer) ([]string, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := w.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	var contents []string
+	for _, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+
+		meta, _ := msg.MetaGetMut("content")
+		assert.Equal(t, string(mBytes), meta)
+
+		contents = append(contents, string(mBytes))
+	}
+	return contents, ackFn
+}
+
+func walSegmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+walSegmentExt))
+	require.NoError(t, err)
+	return files
+}
+
+func TestWALBufferBasic(t *testing.T) {
+	ctx := context.Background()
+	buf := walBufFromConf(t, fmt.Sprintf(`path: %v`, t.TempDir()))
+	defer buf.Close(ctx)
+
+	for i := 0; i < 10; i++ {
+		walWrite(t, buf, fmt.Sprintf("foo%v", i), fmt.Sprintf("bar%v", i))
+	}
+
+	for i := 0; i < 10; i++ {
+		contents, ackFn := walRead(t, buf)
+		assert.Equal(t, []string{fmt.Sprintf("foo%v", i), fmt.Sprintf("bar%v", i)}, contents)
+		require.NoError(t, ackFn(ctx, nil))
+	}
+
+	buf.EndOfInput()
+	_, _, err := buf.ReadBatch(ctx)
+	assert.Equal(t, service.ErrEndOfBuffer, err)
+}
+
+func TestWALBufferNack(t *testing.T) {
+	ctx := context.Background()
+	buf := walBufFromConf(t, fmt.Sprintf(`path: %v`, t.TempDir()))
+	defer buf.Close(ctx)
+
+	walWrite(t, buf, "foo")
+	walWrite(t, buf, "bar")
+
+	contents, ackFn := walRead(t, buf)
+	assert.Equal(t, []string{"foo"}, contents)
+	require.NoError(t, ackFn(ctx, errors.New("nope")))
+
+	contents, ackFn = walRead(t, buf)
+	assert.Equal(t, []string{"foo"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+
+	contents, ackFn = walRead(t, buf)
+	assert.Equal(t, []string{"bar"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+}
+
+func TestWALBufferReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`
+path: %v
+segment_size: 100
+`, dir)
+
+	buf := walBufFromConf(t, conf)
+	for i := 0; i < 10; i++ {
+		walWrite(t, buf, fmt.Sprintf("foo%v", i))
+	}
+
+	// Acknowledge every other batch, leaving the rest either unread or
+	// nacked.
+	for i := 0; i < 6; i++ {
+		contents, ackFn := walRead(t, buf)
+		require.Equal(t, []string{fmt.Sprintf("foo%v", i)}, contents)
+		if i%2 == 0 {
+			require.NoError(t, ackFn(ctx, nil))
+		}
+	}
+	require.NoError(t, buf.Close(ctx))
+
+	buf = walBufFromConf(t, conf)
+	defer buf.Close(ctx)
+
+	for _, exp := range []string{"foo1", "foo3", "foo5", "foo6", "foo7", "foo8", "foo9"} {
+		contents, ackFn := walRead(t, buf)
+		require.Equal(t, []string{exp}, contents)
+		require.NoError(t, ackFn(ctx, nil))
+	}
+
+	walWrite(t, buf, "bar")
+	contents, ackFn := walRead(t, buf)
+	require.Equal(t, []string{"bar"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+}
+
+func TestWALBufferSegmentDeletion(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	buf := walBufFromConf(t, fmt.Sprintf(`
+path: %v
+segment_size: 1
+`, dir))
+	defer buf.Close(ctx)
+
+	for i := 0; i < 5; i++ {
+		walWrite(t, buf, fmt.Sprintf("foo%v", i))
+	}
+	assert.Len(t, walSegmentFiles(t, dir), 5)
+
+	// The second segment can't be deleted until the first is.
+	_, ackFirst := walRead(t, buf)
+	_, ackSecond := walRead(t, buf)
+	require.NoError(t, ackSecond(ctx, nil))
+	assert.Len(t, walSegmentFiles(t, dir), 6)
+
+	require.NoError(t, ackFirst(ctx, nil))
+	assert.Len(t, walSegmentFiles(t, dir), 5)
+
+	for i := 2; i < 5; i++ {
+		_, ackFn := walRead(t, buf)
+		require.NoError(t, ackFn(ctx, nil))
+	}
+	assert.Len(t, walSegmentFiles(t, dir), 1)
+}
+
+func TestWALBufferTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`path: %v`, dir)
+
+	buf := walBufFromConf(t, conf)
+	walWrite(t, buf, "foo")
+	walWrite(t, buf, "bar")
+	require.NoError(t, buf.Close(ctx))
+
+	files := walSegmentFiles(t, dir)
+	require.Len(t, files, 1)
+
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(files[0], info.Size()-2))
+
+	buf = walBufFromConf(t, conf)
+	defer buf.Close(ctx)
+
+	contents, ackFn := walRead(t, buf)
+	assert.Equal(t, []string{"foo"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+
+	walWrite(t, buf, "baz")
+	contents, ackFn = walRead(t, buf)
+	assert.Equal(t, []string{"baz"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+}
+
+func TestWALBufferCorruptSegment(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	conf := fmt.Sprintf(`
+path: %v
+segment_size: 1
+`, dir)
+
+	buf := walBufFromConf(t, conf)
+	walWrite(t, buf, "foo")
+	walWrite(t, buf, "bar")
+	require.NoError(t, buf.Close(ctx))
+
+	files := walSegmentFiles(t, dir)
+	require.Len(t, files, 2)
+	require.NoError(t, os.WriteFile(files[0], []byte("not a record"), 0o644))
+
+	parsedConf, err := walBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	_, err = newWALBufferFromConfig(parsedConf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "record is corrupt")
+}
+
+func TestWALBufferLimit(t *testing.T) {
+	ctx := context.Background()
+	buf := walBufFromConf(t, fmt.Sprintf(`
+path: %v
+limit: 100
+`, t.TempDir()))
+	defer buf.Close(ctx)
+
+	err := buf.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage(make([]byte, 101)),
+	}, func(ctx context.Context, err error) error { return nil })
+	assert.Equal(t, component.ErrMessageTooLarge, err)
+
+	walWrite(t, buf, "foo")
+
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- buf.WriteBatch(ctx, service.MessageBatch{
+			service.NewMessage(make([]byte, 50)),
+		}, func(ctx context.Context, err error) error { return nil })
+	}()
+
+	select {
+	case err := <-writeErr:
+		t.Fatalf("write was not blocked: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	_, ackFn := walRead(t, buf)
+	require.NoError(t, ackFn(ctx, nil))
+
+	select {
+	case err := <-writeErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("write was not unblocked")
+	}
+}
+
+func TestWALBufferEndOfInputWithPending(t *testing.T) {
+	ctx := context.Background()
+	buf := walBufFromConf(t, fmt.Sprintf(`path: %v`, t.TempDir()))
+	defer buf.Close(ctx)
+
+	walWrite(t, buf, "foo")
+	buf.EndOfInput()
+
+	_, ackFn := walRead(t, buf)
+	require.NoError(t, ackFn(ctx, errors.New("nope")))
+
+	contents, ackFn := walRead(t, buf)
+	assert.Equal(t, []string{"foo"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+
+	_, _, err := buf.ReadBatch(ctx)
+	assert.Equal(t, service.ErrEndOfBuffer, err)
+}
+
+func TestWALBufferQuarantineCorrupt(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	buf := walBufFromConf(t, fmt.Sprintf(`path: %v`, dir))
+	defer buf.Close(ctx)
+
+	walWrite(t, buf, "foo")
+	walWrite(t, buf, "bar")
+
+	// Corrupt the contents of the first batch after it has been recovered.
+	files := walSegmentFiles(t, dir)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_RDWR, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("x"), walHeaderSize+walBodyHeaderSize+2)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	contents, ackFn := walRead(t, buf)
+	assert.Equal(t, []string{"bar"}, contents)
+	require.NoError(t, ackFn(ctx, nil))
+
+	quarantined, err := os.ReadFile(filepath.Join(dir, walQuarantineDir, walSegmentName(1)))
+	require.NoError(t, err)
+	assert.Contains(t, string(quarantined), `{"content":"foo"}`)
+
+	buf.EndOfInput()
+	_, _, err = buf.ReadBatch(ctx)
+	assert.Equal(t, service.ErrEndOfBuffer, err)
+}
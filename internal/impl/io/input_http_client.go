@@ -15,7 +15,9 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/input/processors"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/httpclient"
+	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/pagination"
 )
 
 func httpClientInputSpec() docs.FieldSpec {
@@ -36,6 +38,7 @@ func httpClientInputSpec() docs.FieldSpec {
 		docs.FieldObject(
 			"stream", "Allows you to set streaming mode, where requests are kept open and messages are processed line-by-line.",
 		).WithChildren(streamSpecs...),
+		pagination.FieldSpec(),
 	)
 }
 
@@ -58,7 +61,9 @@ If you enable streaming then Benthos will consume the body of the response as a
 
 ### Pagination
 
-This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination. However, in cases where pagination depends on logic it is recommended that you use an ` + "[`http` processor](/docs/components/processors/http) instead, often combined with a [`generate` input](/docs/components/inputs/generate)" + ` in order to schedule the processor.`,
+This input supports interpolation functions in the ` + "`url` and `headers`" + ` fields where data from the previous successfully consumed message (if there was one) can be referenced. This can be used in order to support basic levels of pagination.
+
+Alternatively, when the field ` + "`pagination.enabled`" + ` is set to ` + "`true`" + ` the ` + "`pagination.cursor`" + ` mapping is executed against each response in order to extract the cursor of the next page, which is then added to the following request as the metadata field ` + "`" + pagination.CursorMetaKey + "`" + `. Once the cursor is empty, the optional ` + "`pagination.check`" + ` fails, or ` + "`pagination.max_pages`" + ` is reached, the next request starts again from the first page, where the cursor metadata field is absent. Pagination is not supported in streaming mode.`,
 		Config: httpClientInputSpec().ChildDefaultAndTypesFromStruct(input.NewHTTPClientConfig()),
 		Categories: []string{
 			"Network",
@@ -87,6 +92,26 @@ rate_limit_resources:
     local:
       count: 1
       interval: 30s
+`,
+			},
+			{
+				Title:   "Cursor Pagination",
+				Summary: "Here every page of an API is consumed by extracting a cursor from each response, once the last page has been consumed the API is polled again from the first page once a minute.",
+				Config: `
+input:
+  http_client:
+    url: 'https://api.example.com/items?cursor=${! meta("http_pagination_cursor").or("") }'
+    verb: GET
+    rate_limit: item_pages
+    pagination:
+      enabled: true
+      cursor: 'root = this.next_cursor'
+
+rate_limit_resources:
+  - label: item_pages
+    local:
+      count: 1
+      interval: 1m
 `,
 			},
 		},
@@ -104,10 +129,15 @@ type httpClientInput struct {
 	client       *httpclient.Client
 	prevResponse message.Batch
 
+	paginator *pagination.Paginator
+	pages     int
+
 	codecCtor codec.ReaderConstructor
 
 	codecMut sync.Mutex
 	codec    codec.Reader
+
+	log log.Modular
 }
 
 func newHTTPClientInput(conf input.HTTPClientConfig, mgr bundle.NewManagement) (*httpClientInput, error) {
@@ -125,6 +155,14 @@ func newHTTPClientInput(conf input.HTTPClientConfig, mgr bundle.NewManagement) (
 		}
 	}
 
+	paginator, err := pagination.New(conf.Pagination, mgr)
+	if err != nil {
+		return nil, err
+	}
+	if paginator != nil && conf.Stream.Enabled {
+		return nil, errors.New("pagination cannot be enabled in streaming mode")
+	}
+
 	payloadExpr, err := mgr.BloblEnvironment().NewField(conf.Payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse payload expression: %w", err)
@@ -139,8 +177,10 @@ func newHTTPClientInput(conf input.HTTPClientConfig, mgr bundle.NewManagement) (
 		conf:         conf,
 		prevResponse: message.QuickBatch(nil),
 		client:       client,
+		paginator:    paginator,
 
 		codecCtor: codecCtor,
+		log:       mgr.Logger(),
 	}, nil
 }
 
@@ -264,11 +304,30 @@ func (h *httpClientInput) readNotStreamed(ctx context.Context) (message.Batch, i
 	}
 
 	h.prevResponse = msg
+	if h.paginator != nil {
+		h.nextPage(msg)
+	}
 	return msg.ShallowCopy(), func(context.Context, error) error {
 		return nil
 	}, nil
 }
 
+// nextPage prepares the request of the page following a response, or of the
+// first page again once there are no more pages.
+func (h *httpClientInput) nextPage(msg message.Batch) {
+	h.pages++
+	cursor, more, err := h.paginator.Next(msg, h.pages)
+	if err != nil {
+		h.log.Errorf("Failed to obtain next page: %v\n", err)
+	}
+	if !more {
+		h.pages = 0
+		h.prevResponse = message.QuickBatch(nil)
+		return
+	}
+	h.prevResponse = pagination.WithCursor(msg, cursor)
+}
+
 func (h *httpClientInput) Close(ctx context.Context) (err error) {
 	_ = h.client.Close(ctx)
 
@@ -138,6 +138,55 @@ func TestHTTPClientPagination(t *testing.T) {
 	}
 }
 
+func TestHTTPClientCursorPagination(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	pages := map[string]string{
+		"":   `{"item":"a","next":"p2"}`,
+		"p2": `{"item":"b","next":"p3"}`,
+		"p3": `{"item":"c"}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, exists := pages[r.URL.Query().Get("cursor")]
+		if !exists {
+			http.Error(w, "page not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	}))
+	defer ts.Close()
+
+	conf := input.NewConfig()
+	conf.Type = "http_client"
+	conf.HTTPClient.URL = ts.URL + `/items?cursor=${! meta("http_pagination_cursor").or("") }`
+	conf.HTTPClient.Retry = "1ms"
+	conf.HTTPClient.Pagination.Enabled = true
+	conf.HTTPClient.Pagination.Cursor = `root = this.next`
+
+	h, err := mock.NewManager().NewInput(conf)
+	require.NoError(t, err)
+
+	for _, exp := range []string{"a", "b", "c", "a", "b"} {
+		var tr message.Transaction
+		var open bool
+		select {
+		case tr, open = <-h.TransactionChan():
+			require.True(t, open)
+			require.Equal(t, 1, tr.Payload.Len())
+			structured, err := tr.Payload.Get(0).AsStructured()
+			require.NoError(t, err)
+			assert.Equal(t, exp, structured.(map[string]any)["item"])
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+		require.NoError(t, tr.Ack(tCtx, nil))
+	}
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+}
+
 func TestHTTPClientGETError(t *testing.T) {
 	t.Parallel()
 
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -34,6 +35,7 @@ import (
 	imetadata "github.com/benthosdev/benthos/v4/internal/metadata"
 	"github.com/benthosdev/benthos/v4/internal/old/util/throttle"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 	"github.com/benthosdev/benthos/v4/internal/transaction"
 )
@@ -56,6 +58,8 @@ When the rate limit is breached HTTP requests will have a 429 response returned
 
 It's possible to return a response for each message received using [synchronous responses](/docs/guides/sync_responses). When doing so you can customise headers with the ` + "`sync_response` field `headers`" + `, which can also use [function interpolation](/docs/configuration/interpolation#bloblang-queries) in the value based on the response message contents.
 
+By default responses are written once the request message has been fully delivered. Setting the ` + "`sync_response` field `streaming`" + ` to ` + "`chunked` or `sse`" + ` instead writes each response message to the client as soon as it is produced, either as a chunk of the response body or as a [server-sent event](https://html.spec.whatwg.org/multipage/server-sent-events.html), which allows streaming APIs to be fronted by Benthos. When streaming the ` + "`timeout`" + ` applies to the wait between each response rather than the request as a whole, and should delivery fail after a response has already been written then an ` + "`error`" + ` event is sent when streaming with ` + "`sse`" + `, otherwise the response is ended.
+
 ### Endpoints
 
 The following fields specify endpoints that are registered for sending messages, and support path parameters of the form ` + "`/{foo}`" + `, which are added to ingested messages as metadata:
@@ -94,6 +98,12 @@ If HTTPS is enabled, the following fields are added as well:
 - http_server_tls_subject
 - http_server_tls_cipher_suite
 ` + "```" + `
+If requests are authenticated with the ` + "`auth`" + ` field, the following fields are added as well:
+` + "``` text" + `
+- http_server_auth_method
+- http_server_auth_subject
+- http_server_auth_claim_* (one for each claim of a token)
+` + "```" + `
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#metadata).`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("address", "An alternative address to host from. If left empty the service wide address is used."),
@@ -104,9 +114,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 			docs.FieldString("allowed_verbs", "An array of verbs that are allowed for the `path` endpoint.").AtVersion("3.33.0").Array(),
 			docs.FieldString("timeout", "Timeout for requests. If a consumed messages takes longer than this to be delivered the connection is closed, but the message may still be delivered."),
 			docs.FieldString("rate_limit", "An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by."),
-			docs.FieldString("cert_file", "Enable TLS by specifying a certificate and key file. Only valid with a custom `address`.").Advanced(),
+			docs.FieldString("cert_file", "Enable TLS by specifying a certificate and key file. Only valid with a custom `address`. The certificate is reloaded when either file is modified.").Advanced(),
 			docs.FieldString("key_file", "Enable TLS by specifying a certificate and key file. Only valid with a custom `address`.").Advanced(),
 			corsSpec,
+			httpserver.AuthFieldSpec(),
 			docs.FieldObject("sync_response", "Customise messages returned via [synchronous responses](/docs/guides/sync_responses).").WithChildren(
 				docs.FieldString(
 					"status",
@@ -119,6 +130,11 @@ You can access these metadata fields using [function interpolation](/docs/config
 						"Content-Type": "application/octet-stream",
 					}),
 				docs.FieldObject("metadata_headers", "Specify criteria for which metadata values are added to the response as headers.").WithChildren(imetadata.IncludeFilterDocs()...),
+				docs.FieldString("streaming", "Whether responses should be streamed back to the client as they are produced rather than once the request message has been fully delivered. The status and headers are set from the first response.").HasAnnotatedOptions(
+					"none", "Responses are buffered and written once the request message has been delivered.",
+					"chunked", "Each response message is written and flushed as it is produced using chunked transfer encoding.",
+					"sse", "Each response message is written and flushed as a [server-sent event](https://html.spec.whatwg.org/multipage/server-sent-events.html) as it is produced.",
+				).AtVersion("4.9.0"),
 			).Advanced(),
 		).ChildDefaultAndTypesFromStruct(input.NewHTTPServerConfig()),
 		Categories: []string{
@@ -168,6 +184,13 @@ func newHTTPServerInput(conf input.Config, mgr bundle.NewManagement) (input.Stre
 		if server.Handler, err = conf.HTTPServer.CORS.WrapHandler(mux); err != nil {
 			return nil, fmt.Errorf("bad CORS configuration: %w", err)
 		}
+		if len(conf.HTTPServer.KeyFile) > 0 || len(conf.HTTPServer.CertFile) > 0 {
+			reloader, err := btls.NewCertReloader(conf.HTTPServer.CertFile, conf.HTTPServer.KeyFile, "")
+			if err != nil {
+				return nil, err
+			}
+			server.TLSConfig = reloader.ServerConfig()
+		}
 	}
 
 	var timeout time.Duration
@@ -213,12 +236,23 @@ func newHTTPServerInput(conf input.Config, mgr bundle.NewManagement) (input.Stre
 		}
 	}
 
+	switch h.conf.Response.Streaming {
+	case "none", "chunked", "sse":
+	default:
+		return nil, fmt.Errorf("sync response streaming mode not recognised: %v", h.conf.Response.Streaming)
+	}
+
 	if h.metaFilter, err = h.conf.Response.ExtractMetadata.CreateFilter(); err != nil {
 		return nil, fmt.Errorf("failed to construct metadata filter: %w", err)
 	}
 
-	postHdlr := gzipHandler(h.postHandler)
-	wsHdlr := gzipHandler(h.wsHandler)
+	auth, err := httpserver.NewAuthenticator(h.conf.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("bad auth configuration: %w", err)
+	}
+
+	postHdlr := auth.WrapHandler(gzipHandler(h.postHandler))
+	wsHdlr := auth.WrapHandler(gzipHandler(h.wsHandler))
 	if mux != nil {
 		if len(h.conf.Path) > 0 {
 			mux.HandleFunc(h.conf.Path, postHdlr)
@@ -314,6 +348,7 @@ func (h *httpServerInput) extractMessageFromRequest(r *http.Request) (message.Ba
 			}
 			p.MetaSet("http_server_tls_cipher_suite", tls.CipherSuiteName(r.TLS.CipherSuite))
 		}
+		setIdentityMetadata(p, r)
 		for k, v := range r.Header {
 			if len(v) > 0 {
 				p.MetaSet(k, v[0])
@@ -344,6 +379,35 @@ func (h *httpServerInput) extractMessageFromRequest(r *http.Request) (message.Ba
 	return msg, nil
 }
 
+// setIdentityMetadata adds the identity of an authenticated request to a
+// message as metadata.
+func setIdentityMetadata(p *message.Part, r *http.Request) {
+	id, ok := httpserver.IdentityFromContext(r.Context())
+	if !ok {
+		return
+	}
+	p.MetaSet("http_server_auth_method", id.Method)
+	if id.Subject != "" {
+		p.MetaSet("http_server_auth_subject", id.Subject)
+	}
+	for k, v := range id.Claims {
+		var str string
+		switch t := v.(type) {
+		case string:
+			str = t
+		case float64:
+			str = strconv.FormatFloat(t, 'f', -1, 64)
+		default:
+			b, err := json.Marshal(t)
+			if err != nil {
+				continue
+			}
+			str = string(b)
+		}
+		p.MetaSet("http_server_auth_claim_"+k, str)
+	}
+}
+
 func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 	h.handlerWG.Add(1)
 	defer h.handlerWG.Done()
@@ -385,7 +449,14 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 
 	startedAt := time.Now()
 
-	store := transaction.NewResultStore()
+	var store transaction.ResultStore
+	var streamedStore transaction.StreamedResultStore
+	if h.conf.Response.Streaming != "none" {
+		streamedStore = transaction.NewStreamedResultStore()
+		store = streamedStore
+	} else {
+		store = transaction.NewResultStore()
+	}
 	transaction.AddResultStore(msg, store)
 
 	h.mPostRcvd.Incr(int64(msg.Len()))
@@ -405,6 +476,11 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if streamedStore != nil {
+		h.streamResponses(w, r, streamedStore, resChan, startedAt)
+		return
+	}
+
 	select {
 	case res, open := <-resChan:
 		if !open {
@@ -508,6 +584,136 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// streamResponses writes the responses of a message to the client as they are
+// added to the result store, flushing each one with chunked transfer encoding
+// or as a server-sent event, until the message has been delivered. The timeout
+// is reset each time a response is written.
+func (h *httpServerInput) streamResponses(w http.ResponseWriter, r *http.Request, store transaction.StreamedResultStore, resChan <-chan error, startedAt time.Time) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		h.log.Errorln("Failed to cast response writer to flusher")
+		return
+	}
+
+	sse := h.conf.Response.Streaming == "sse"
+
+	var started bool
+	var sent int
+	writeResponses := func() error {
+		results := store.Get()
+		for ; sent < len(results); sent++ {
+			responseMsg := results[sent]
+			if responseMsg.Len() == 0 {
+				continue
+			}
+			if !started {
+				for k, v := range h.responseHeaders {
+					w.Header().Set(k, v.String(0, responseMsg))
+				}
+				_ = responseMsg.Get(0).MetaIter(func(k, v string) error {
+					if h.metaFilter.Match(k) {
+						w.Header().Set(k, v)
+					}
+					return nil
+				})
+				statusCode := 200
+				if statusCodeStr := h.responseStatus.String(0, responseMsg); statusCodeStr != "200" {
+					var err error
+					if statusCode, err = strconv.Atoi(statusCodeStr); err != nil {
+						return fmt.Errorf("failed to parse sync response status code expression: %w", err)
+					}
+				}
+				if sse {
+					w.Header().Set("Content-Type", "text/event-stream")
+					w.Header().Set("Cache-Control", "no-cache")
+				}
+				w.WriteHeader(statusCode)
+				started = true
+			}
+			for _, part := range responseMsg {
+				payload := part.AsBytes()
+				if sse {
+					payload = sseEvent("", payload)
+				}
+				if _, err := w.Write(payload); err != nil {
+					return err
+				}
+			}
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-store.AddedChan():
+			if err := writeResponses(); err != nil {
+				h.log.Errorf("Failed to stream sync response: %v\n", err)
+				if !started {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+				return
+			}
+		case res, open := <-resChan:
+			if !open {
+				if !started {
+					http.Error(w, "Server closing", http.StatusServiceUnavailable)
+				}
+				return
+			}
+			if err := writeResponses(); err != nil {
+				h.log.Errorf("Failed to stream sync response: %v\n", err)
+				if !started {
+					w.WriteHeader(http.StatusBadGateway)
+				}
+				return
+			}
+			if res != nil {
+				if !started {
+					http.Error(w, res.Error(), http.StatusBadGateway)
+				} else if sse {
+					_, _ = w.Write(sseEvent("error", []byte(res.Error())))
+					flusher.Flush()
+				}
+				return
+			}
+			h.mLatency.Timing(time.Since(startedAt).Nanoseconds())
+			return
+		case <-time.After(h.timeout):
+			if !started {
+				http.Error(w, "Request timed out", http.StatusRequestTimeout)
+			}
+			return
+		case <-r.Context().Done():
+			return
+		case <-h.shutSig.CloseNowChan():
+			if !started {
+				http.Error(w, "Server closing", http.StatusServiceUnavailable)
+			}
+			return
+		}
+	}
+}
+
+// sseEvent encodes a payload as a server-sent event, where each line of the
+// payload is written as a separate data field.
+func sseEvent(event string, payload []byte) []byte {
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(event)
+		buf.WriteByte('\n')
+	}
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimSuffix(line, []byte("\r")))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
 func (h *httpServerInput) wsHandler(w http.ResponseWriter, r *http.Request) {
 	h.handlerWG.Add(1)
 	defer h.handlerWG.Done()
@@ -588,6 +794,7 @@ func (h *httpServerInput) wsHandler(w http.ResponseWriter, r *http.Request) {
 		for _, c := range r.Cookies() {
 			part.MetaSet(c.Name, c.Value)
 		}
+		setIdentityMetadata(part, r)
 		tracing.InitSpans(h.mgr.Tracer(), "input_http_server_websocket", msg)
 
 		store := transaction.NewResultStore()
@@ -657,9 +864,7 @@ func (h *httpServerInput) loop() {
 					"Receiving HTTPS messages at: https://%s\n",
 					h.conf.Address+h.conf.Path,
 				)
-				if err := h.server.ListenAndServeTLS(
-					h.conf.CertFile, h.conf.KeyFile,
-				); err != http.ErrServerClosed {
+				if err := h.server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
 					h.log.Errorf("Server error: %v\n", err)
 				}
 			} else {
@@ -721,6 +926,17 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush writes any pending compressed data to the underlying response writer
+// and flushes it, which allows streamed responses to reach the client.
+func (w gzipResponseWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		_ = gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func gzipHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
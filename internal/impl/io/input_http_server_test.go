@@ -1071,3 +1071,136 @@ func TestHTTPSyncResponseHeadersStatus(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestHTTPSyncResponseStreamingSSE(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewResourceConfig(), manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.Type = "http_server"
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Response.Streaming = "sse"
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	t.Cleanup(func() {
+		server.Close()
+	})
+
+	firstReceived := make(chan struct{})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewReader([]byte("hello")))
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, 200, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		buf := make([]byte, len("data: first\n\n"))
+		_, err = io.ReadFull(res.Body, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "data: first\n\n", string(buf))
+		close(firstReceived)
+
+		rest, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "data: second\ndata: line\n\n", string(rest))
+	}()
+
+	var ts message.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+		assert.Equal(t, "hello", string(ts.Payload.Get(0).AsBytes()))
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	ts.Payload.Get(0).SetBytes([]byte("first"))
+	require.NoError(t, transaction.SetAsResponse(ts.Payload))
+
+	// The first response must be received before the message is delivered.
+	select {
+	case <-firstReceived:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for streamed response")
+	}
+
+	ts.Payload.Get(0).SetBytes([]byte("second\nline"))
+	require.NoError(t, transaction.SetAsResponse(ts.Payload))
+	require.NoError(t, ts.Ack(tCtx, nil))
+
+	wg.Wait()
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+}
+
+func TestHTTPServerAuthMetadata(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.NewResourceConfig(), manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.Type = "http_server"
+	conf.HTTPServer.Path = "/testpost"
+	conf.HTTPServer.Auth.APIKeys.Enabled = true
+	conf.HTTPServer.Auth.APIKeys.Keys = map[string]string{"foo": "foosecret"}
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	t.Cleanup(server.Close)
+
+	res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewReader([]byte("hello")))
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		req, err := http.NewRequest("POST", server.URL+"/testpost", bytes.NewReader([]byte("hello")))
+		require.NoError(t, err)
+		req.Header.Set("X-API-Key", "foosecret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}()
+
+	select {
+	case ts := <-h.TransactionChan():
+		p := ts.Payload.Get(0)
+		assert.Equal(t, "api_key", p.MetaGet("http_server_auth_method"))
+		assert.Equal(t, "foo", p.MetaGet("http_server_auth_subject"))
+		require.NoError(t, ts.Ack(tCtx, nil))
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+	wg.Wait()
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/batch/policy"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
@@ -33,7 +34,8 @@ It's possible to propagate the response from each HTTP request back to the input
 		Config: httpclient.OldFieldSpec(true,
 			docs.FieldBool("batch_as_multipart", "Send message batches as a single request using [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html). If disabled messages in batches will be sent as individual requests.").Advanced(),
 			docs.FieldBool("propagate_response", "Whether responses from the server should be [propagated back](/docs/guides/sync_responses) to the input.").Advanced(),
-			docs.FieldInt("max_in_flight", "The maximum number of parallel message batches to have in flight at any given time."),
+			docs.FieldInt("max_in_flight", "The maximum number of parallel message batches to have in flight at any given time. When `autoscale` is enabled this is the initial number of message batches in flight."),
+			autoscale.FieldSpec("Adjust the number of message batches in flight within the bounds `min` and `max`, starting from `max_in_flight`, based on how saturated the requests in flight are and their latency."),
 			policy.FieldSpec(),
 			docs.FieldObject(
 				"multipart", "EXPERIMENTAL: Create explicit multipart HTTP requests by specifying an array of parts to add to the request, each part specified consists of content headers and a data field that can be populated dynamically. If this field is populated it will override the default request creation behaviour.",
@@ -61,6 +63,15 @@ func newHTTPClientOutput(conf output.Config, mgr bundle.NewManagement) (output.S
 	if err != nil {
 		return w, err
 	}
+	if conf.HTTPClient.Autoscale.Enabled {
+		aw, ok := w.(*output.AsyncWriter)
+		if !ok {
+			return nil, fmt.Errorf("unable to enable autoscale due to wrong type: %T", w)
+		}
+		if err := aw.SetAutoscale(conf.HTTPClient.Autoscale); err != nil {
+			return nil, err
+		}
+	}
 	if !conf.HTTPClient.BatchAsMultipart {
 		w = output.OnlySinglePayloads(w)
 	}
@@ -15,6 +15,8 @@ import (
 	"github.com/gorilla/websocket"
 
 	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
@@ -25,6 +27,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 )
 
 func init() {
@@ -39,7 +42,13 @@ func init() {
 		Description: `
 Sets up an HTTP server that will send messages over HTTP(S) GET requests. If the ` + "`address`" + ` config field is left blank the [service-wide HTTP server](/docs/components/http/about) will be used.
 
-Three endpoints will be registered at the paths specified by the fields ` + "`path`, `stream_path` and `ws_path`" + `. Which allow you to consume a single message batch, a continuous stream of line delimited messages, or a websocket of messages for each request respectively.
+Three endpoints will be registered at the paths specified by the fields ` + "`path`, `stream_path` and `ws_path`" + `. Which allow you to consume a single message batch, a continuous stream of line delimited messages, or a websocket of messages for each request respectively. The ` + "`stream_path`" + ` endpoint can instead write messages as [server-sent events](https://html.spec.whatwg.org/multipage/server-sent-events.html) by setting ` + "`stream_format`" + ` to ` + "`sse`" + `.
+
+### Websocket Filters
+
+When ` + "`ws_filter.enabled`" + ` is set each message is sent to every websocket connection with a matching subscription filter, rather than to a single connection, which allows many clients such as live dashboards to consume the messages they are interested in from one pipeline. Filters are [Bloblang queries](/docs/guides/bloblang/about) that result in a boolean, such as ` + "`this.device_id == \"foo\"`" + `, and are provided by clients either within the query parameter ` + "`ws_filter.query_param`" + ` when the connection is established, or as a websocket message sent at any time, which replaces the current filter. Connections without a filter receive all messages unless ` + "`ws_filter.require_filter`" + ` is set.
+
+Filters can only use functions and methods that do not access the environment of the host, and cannot import files. A filter that fails to parse results in the request being rejected, or the connection being closed with the status 1008 (policy violation). When filters are enabled the ` + "`path`" + ` and ` + "`stream_path`" + ` endpoints are not registered, and messages that do not match any connection are dropped.
 
 When messages are batched the ` + "`path`" + ` endpoint encodes the batch according to [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html). This behaviour can be overridden by [archiving your batches](/docs/configuration/batching#post-batch-processing).
 
@@ -48,12 +57,22 @@ Please note, messages are considered delivered as soon as the data is written to
 			docs.FieldString("address", "An optional address to listen from. If left empty the service wide HTTP server is used."),
 			docs.FieldString("path", "The path from which discrete messages can be consumed."),
 			docs.FieldString("stream_path", "The path from which a continuous stream of messages can be consumed."),
+			docs.FieldString("stream_format", "The format in which messages are written to the `stream_path` endpoint.").HasAnnotatedOptions(
+				"lines", "Messages are written as line delimited payloads, with each batch followed by an empty line.",
+				"sse", "Each message is written as a [server-sent event](https://html.spec.whatwg.org/multipage/server-sent-events.html).",
+			).Advanced().AtVersion("4.9.0"),
 			docs.FieldString("ws_path", "The path from which websocket connections can be established."),
+			docs.FieldObject("ws_filter", "Allows websocket clients to subscribe to the messages that match a Bloblang query of their choosing.").WithChildren(
+				docs.FieldBool("enabled", "Whether messages are sent to every websocket connection with a matching filter."),
+				docs.FieldString("query_param", "The query parameter from which the filter of a connection is read when it is established. Leave empty to only accept filters sent as websocket messages."),
+				docs.FieldBool("require_filter", "Whether connections without a filter receive no messages, rather than all messages."),
+			).AtVersion("4.9.0").Advanced(),
 			docs.FieldString("allowed_verbs", "An array of verbs that are allowed for the `path` and `stream_path` HTTP endpoint.").Array(),
 			docs.FieldString("timeout", "The maximum time to wait before a blocking, inactive connection is dropped (only applies to the `path` endpoint).").Advanced(),
-			docs.FieldString("cert_file", "An optional certificate file to use for TLS connections. Only applicable when an `address` is specified.").Advanced(),
+			docs.FieldString("cert_file", "An optional certificate file to use for TLS connections. Only applicable when an `address` is specified. The certificate is reloaded when either file is modified.").Advanced(),
 			docs.FieldString("key_file", "An optional certificate key file to use for TLS connections. Only applicable when an `address` is specified.").Advanced(),
 			corsSpec,
+			httpserver.AuthFieldSpec(),
 		).ChildDefaultAndTypesFromStruct(output.NewHTTPServerConfig()),
 		Categories: []string{
 			"Network",
@@ -78,6 +97,10 @@ type httpServerOutput struct {
 
 	allowedVerbs map[string]struct{}
 
+	wsFilterEnv *bloblang.Environment
+	wsSubsMut   sync.RWMutex
+	wsSubs      map[*wsSubscriber]struct{}
+
 	mGetSent      metrics.StatCounter
 	mGetBatchSent metrics.StatCounter
 
@@ -105,6 +128,13 @@ func newHTTPServerOutput(conf output.Config, mgr bundle.NewManagement, log log.M
 		if server.Handler, err = conf.HTTPServer.CORS.WrapHandler(mux); err != nil {
 			return nil, fmt.Errorf("bad CORS configuration: %w", err)
 		}
+		if len(conf.HTTPServer.KeyFile) > 0 || len(conf.HTTPServer.CertFile) > 0 {
+			reloader, err := btls.NewCertReloader(conf.HTTPServer.CertFile, conf.HTTPServer.KeyFile, "")
+			if err != nil {
+				return nil, err
+			}
+			server.TLSConfig = reloader.ServerConfig()
+		}
 	}
 
 	verbs := map[string]struct{}{}
@@ -115,6 +145,12 @@ func newHTTPServerOutput(conf output.Config, mgr bundle.NewManagement, log log.M
 		return nil, errors.New("must provide at least one allowed verb")
 	}
 
+	switch conf.HTTPServer.StreamFormat {
+	case "lines", "sse":
+	default:
+		return nil, fmt.Errorf("stream format not recognised: %v", conf.HTTPServer.StreamFormat)
+	}
+
 	mSent := stats.GetCounter("output_sent")
 	mBatchSent := stats.GetCounter("output_batch_sent")
 	mLatency := stats.GetTimer("output_latency_ns")
@@ -142,41 +178,64 @@ func newHTTPServerOutput(conf output.Config, mgr bundle.NewManagement, log log.M
 		mStreamError:     mError,
 	}
 
+	if conf.HTTPServer.WSFilter.Enabled {
+		// Filters are provided by clients and therefore cannot access the
+		// environment or files of the host.
+		h.wsFilterEnv = mgr.BloblEnvironment().OnlyPure().WithDisabledImports()
+		h.wsSubs = map[*wsSubscriber]struct{}{}
+	}
+
 	if tout := conf.HTTPServer.Timeout; len(tout) > 0 {
 		if h.timeout, err = time.ParseDuration(tout); err != nil {
 			return nil, fmt.Errorf("failed to parse timeout string: %v", err)
 		}
 	}
 
+	auth, err := httpserver.NewAuthenticator(conf.HTTPServer.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("bad auth configuration: %w", err)
+	}
+	getHdlr := auth.WrapHandler(h.getHandler)
+	streamHdlr := auth.WrapHandler(h.streamHandler)
+	wsHdlr := auth.WrapHandler(h.wsHandler)
+
+	// With filters enabled messages are broadcast to websocket connections,
+	// and therefore can't be consumed by the other endpoints.
+	getPath, streamPath := h.conf.HTTPServer.Path, h.conf.HTTPServer.StreamPath
+	if conf.HTTPServer.WSFilter.Enabled {
+		wsHdlr = auth.WrapHandler(h.wsFilterHandler)
+		getPath, streamPath = "", ""
+	}
+
 	if mux != nil {
-		if len(h.conf.HTTPServer.Path) > 0 {
-			h.mux.HandleFunc(h.conf.HTTPServer.Path, h.getHandler)
+		if len(getPath) > 0 {
+			h.mux.HandleFunc(getPath, getHdlr)
 		}
-		if len(h.conf.HTTPServer.StreamPath) > 0 {
-			h.mux.HandleFunc(h.conf.HTTPServer.StreamPath, h.streamHandler)
+		if len(streamPath) > 0 {
+			h.mux.HandleFunc(streamPath, streamHdlr)
 		}
 		if len(h.conf.HTTPServer.WSPath) > 0 {
-			h.mux.HandleFunc(h.conf.HTTPServer.WSPath, h.wsHandler)
+			h.mux.HandleFunc(h.conf.HTTPServer.WSPath, wsHdlr)
 		}
 	} else {
-		if len(h.conf.HTTPServer.Path) > 0 {
+		if len(getPath) > 0 {
 			mgr.RegisterEndpoint(
-				h.conf.HTTPServer.Path, "Read a single message from Benthos.",
-				h.getHandler,
+				getPath, "Read a single message from Benthos.",
+				getHdlr,
 			)
 		}
-		if len(h.conf.HTTPServer.StreamPath) > 0 {
+		if len(streamPath) > 0 {
 			mgr.RegisterEndpoint(
-				h.conf.HTTPServer.StreamPath,
+				streamPath,
 				"Read a continuous stream of messages from Benthos.",
-				h.streamHandler,
+				streamHdlr,
 			)
 		}
 		if len(h.conf.HTTPServer.WSPath) > 0 {
 			mgr.RegisterEndpoint(
 				h.conf.HTTPServer.WSPath,
 				"Read messages from Benthos via websockets.",
-				h.wsHandler,
+				wsHdlr,
 			)
 		}
 	}
@@ -258,6 +317,12 @@ func (h *httpServerOutput) streamHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	sse := h.conf.HTTPServer.StreamFormat == "sse"
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
 	ctx, done := h.shutSig.CloseAtLeisureCtx(r.Context())
 	defer done()
 
@@ -276,7 +341,11 @@ func (h *httpServerOutput) streamHandler(w http.ResponseWriter, r *http.Request)
 		}
 
 		var data []byte
-		if ts.Payload.Len() == 1 {
+		if sse {
+			for _, part := range ts.Payload {
+				data = append(data, sseEvent("", part.AsBytes())...)
+			}
+		} else if ts.Payload.Len() == 1 {
 			data = ts.Payload.Get(0).AsBytes()
 		} else {
 			data = append(bytes.Join(message.GetAllBytes(ts.Payload), []byte("\n")), byte('\n'))
@@ -289,7 +358,9 @@ func (h *httpServerOutput) streamHandler(w http.ResponseWriter, r *http.Request)
 			return
 		}
 
-		_, _ = w.Write([]byte("\n"))
+		if !sse {
+			_, _ = w.Write([]byte("\n"))
+		}
 		flusher.Flush()
 		h.mStreamSent.Incr(int64(batch.MessageCollapsedCount(ts.Payload)))
 		h.mStreamBatchSent.Incr(1)
@@ -348,12 +419,204 @@ func (h *httpServerOutput) wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+//------------------------------------------------------------------------------
+
+// wsSubscriber is a websocket connection that receives the messages matching
+// its subscription filter.
+type wsSubscriber struct {
+	ws       *websocket.Conn
+	writeMut sync.Mutex
+
+	filterMut sync.RWMutex
+	filter    *mapping.Executor
+}
+
+func (s *wsSubscriber) setFilter(filter *mapping.Executor) {
+	s.filterMut.Lock()
+	s.filter = filter
+	s.filterMut.Unlock()
+}
+
+func (s *wsSubscriber) getFilter() *mapping.Executor {
+	s.filterMut.RLock()
+	defer s.filterMut.RUnlock()
+	return s.filter
+}
+
+func (s *wsSubscriber) write(msgs [][]byte) error {
+	s.writeMut.Lock()
+	defer s.writeMut.Unlock()
+	for _, msg := range msgs {
+		if err := s.ws.WriteMessage(websocket.BinaryMessage, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *wsSubscriber) closeWithError(err error) {
+	// The reason of a close message is limited to 123 bytes.
+	reason := fmt.Sprintf("bad filter: %v", err)
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+
+	s.writeMut.Lock()
+	defer s.writeMut.Unlock()
+	_ = s.ws.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(time.Second),
+	)
+}
+
+func (h *httpServerOutput) wsFilterHandler(w http.ResponseWriter, r *http.Request) {
+	sub := &wsSubscriber{}
+	if param := h.conf.HTTPServer.WSFilter.QueryParam; len(param) > 0 {
+		if filterStr := r.URL.Query().Get(param); len(filterStr) > 0 {
+			filter, err := h.wsFilterEnv.NewMapping(filterStr)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Bad filter: %v", err), http.StatusBadRequest)
+				return
+			}
+			sub.filter = filter
+		}
+	}
+
+	upgrader := websocket.Upgrader{}
+
+	var err error
+	if sub.ws, err = upgrader.Upgrade(w, r, nil); err != nil {
+		h.log.Warnf("Websocket request failed: %v\n", err)
+		return
+	}
+	defer sub.ws.Close()
+
+	h.wsSubsMut.Lock()
+	h.wsSubs[sub] = struct{}{}
+	h.wsSubsMut.Unlock()
+	defer func() {
+		h.wsSubsMut.Lock()
+		delete(h.wsSubs, sub)
+		h.wsSubsMut.Unlock()
+	}()
+
+	// The connection is closed during shutdown in order to unblock reads.
+	handlerDone := make(chan struct{})
+	defer close(handlerDone)
+	go func() {
+		select {
+		case <-h.shutSig.CloseAtLeisureChan():
+			_ = sub.ws.Close()
+		case <-handlerDone:
+		}
+	}()
+
+	// Each message received from the client replaces its filter.
+	for {
+		_, data, err := sub.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		filter, err := h.wsFilterEnv.NewMapping(string(data))
+		if err != nil {
+			sub.closeWithError(err)
+			return
+		}
+		sub.setFilter(filter)
+	}
+}
+
+// wsBroadcast writes the messages of a batch to every websocket connection with
+// a filter that matches them.
+func (h *httpServerOutput) wsBroadcast(msg message.Batch) {
+	h.wsSubsMut.RLock()
+	subs := make([]*wsSubscriber, 0, len(h.wsSubs))
+	for sub := range h.wsSubs {
+		subs = append(subs, sub)
+	}
+	h.wsSubsMut.RUnlock()
+
+	tStart := time.Now()
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		filter := sub.getFilter()
+		if filter == nil && h.conf.HTTPServer.WSFilter.RequireFilter {
+			continue
+		}
+
+		var matched [][]byte
+		for i := range msg {
+			if filter != nil {
+				matches, err := filter.QueryPart(i, msg)
+				if err != nil {
+					h.log.Tracef("Websocket filter failed: %v\n", err)
+				}
+				if !matches {
+					continue
+				}
+			}
+			matched = append(matched, msg.Get(i).AsBytes())
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(sub *wsSubscriber) {
+			defer wg.Done()
+			if err := sub.write(matched); err != nil {
+				h.mWSError.Incr(1)
+				h.log.Debugf("Failed to write to websocket: %v\n", err)
+				return
+			}
+			h.mWSBatchSent.Incr(1)
+			h.mWSSent.Incr(int64(len(matched)))
+		}(sub)
+	}
+	wg.Wait()
+
+	h.mWSLatency.Timing(time.Since(tStart).Nanoseconds())
+}
+
+func (h *httpServerOutput) wsBroadcastLoop() {
+	ctx, done := h.shutSig.CloseAtLeisureCtx(context.Background())
+	defer done()
+
+	for {
+		var ts message.Transaction
+		var open bool
+
+		select {
+		case ts, open = <-h.transactions:
+			if !open {
+				go h.TriggerCloseNow()
+				return
+			}
+		case <-h.shutSig.CloseAtLeisureChan():
+			return
+		}
+
+		// Messages are considered delivered once written to every matching
+		// connection, and a failed write only affects its own connection.
+		h.wsBroadcast(ts.Payload)
+		_ = ts.Ack(ctx, nil)
+	}
+}
+
+//------------------------------------------------------------------------------
+
 func (h *httpServerOutput) Consume(ts <-chan message.Transaction) error {
 	if h.transactions != nil {
 		return component.ErrAlreadyStarted
 	}
 	h.transactions = ts
 
+	if h.conf.HTTPServer.WSFilter.Enabled {
+		go h.wsBroadcastLoop()
+	}
+
 	if h.server != nil {
 		go func() {
 			if len(h.conf.HTTPServer.KeyFile) > 0 || len(h.conf.HTTPServer.CertFile) > 0 {
@@ -361,9 +624,7 @@ func (h *httpServerOutput) Consume(ts <-chan message.Transaction) error {
 					"Serving messages through HTTPS GET request at: https://%s\n",
 					h.conf.HTTPServer.Address+h.conf.HTTPServer.Path,
 				)
-				if err := h.server.ListenAndServeTLS(
-					h.conf.HTTPServer.CertFile, h.conf.HTTPServer.KeyFile,
-				); err != http.ErrServerClosed {
+				if err := h.server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
 					h.log.Errorf("Server error: %v\n", err)
 				}
 			} else {
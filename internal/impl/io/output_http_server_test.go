@@ -3,10 +3,16 @@ package io_test
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/output"
@@ -144,3 +150,219 @@ func TestHTTPServerOutputTimeout(t *testing.T) {
 	h.TriggerCloseNow()
 	require.NoError(t, h.WaitForClose(ctx))
 }
+
+func freeTestAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return addr
+}
+
+func sendTestTransaction(t *testing.T, msgChan chan message.Transaction, payloads ...string) {
+	t.Helper()
+
+	var parts [][]byte
+	for _, p := range payloads {
+		parts = append(parts, []byte(p))
+	}
+
+	resChan := make(chan error)
+	select {
+	case msgChan <- message.NewTransaction(message.QuickBatch(parts), resChan):
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for message")
+	}
+	select {
+	case err := <-resChan:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for response")
+	}
+}
+
+func dialTestWS(t *testing.T, u string) (*websocket.Conn, <-chan string) {
+	t.Helper()
+
+	var ws *websocket.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if ws, _, err = websocket.DefaultDialer.Dial(u, nil); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		ws.Close()
+	})
+
+	msgs := make(chan string, 100)
+	go func() {
+		defer close(msgs)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			msgs <- string(data)
+		}
+	}()
+	return ws, msgs
+}
+
+// readTestWS returns the next message of a connection that isn't a probe.
+func readTestWS(t *testing.T, msgs <-chan string) string {
+	t.Helper()
+
+	for {
+		select {
+		case msg, open := <-msgs:
+			require.True(t, open, "connection closed")
+			if !strings.Contains(msg, "probe") {
+				return msg
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for websocket message")
+		}
+	}
+}
+
+// probeTestWS sends probe messages until every connection has received one,
+// which ensures that the connections and their filters are registered.
+func probeTestWS(t *testing.T, msgChan chan message.Transaction, probe string, conns ...<-chan string) []<-chan string {
+	t.Helper()
+
+	var forwarded []<-chan string
+	for _, c := range conns {
+		fwd := make(chan string, 100)
+		forwarded = append(forwarded, fwd)
+
+		received := make(chan struct{})
+		go func(c <-chan string) {
+			defer close(fwd)
+			var once sync.Once
+			for msg := range c {
+				if strings.Contains(msg, "probe") {
+					once.Do(func() { close(received) })
+				}
+				fwd <- msg
+			}
+		}(c)
+
+		for probed := false; !probed; {
+			sendTestTransaction(t, msgChan, probe)
+			select {
+			case <-received:
+				probed = true
+			case <-time.After(time.Millisecond * 50):
+			}
+		}
+	}
+	return forwarded
+}
+
+func TestHTTPServerOutputWSFilter(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	addr := freeTestAddress(t)
+
+	conf := output.NewConfig()
+	conf.Type = "http_server"
+	conf.HTTPServer.Address = addr
+	conf.HTTPServer.WSFilter.Enabled = true
+
+	h, err := mock.NewManager().NewOutput(conf)
+	require.NoError(t, err)
+
+	msgChan := make(chan message.Transaction)
+	require.NoError(t, h.Consume(msgChan))
+
+	_, msgsA := dialTestWS(t, "ws://"+addr+"/get/ws?filter="+url.QueryEscape(`this.type == "a"`))
+	wsB, msgsB := dialTestWS(t, "ws://"+addr+"/get/ws")
+	require.NoError(t, wsB.WriteMessage(websocket.TextMessage, []byte(`this.type == "b" || this.probe == "b"`)))
+	_, msgsAll := dialTestWS(t, "ws://"+addr+"/get/ws")
+
+	conns := probeTestWS(t, msgChan, `{"type":"a","probe":"a"}`, msgsA, msgsAll)
+	msgsA, msgsAll = conns[0], conns[1]
+	msgsB = probeTestWS(t, msgChan, `{"type":"c","probe":"b"}`, msgsB)[0]
+
+	sendTestTransaction(t, msgChan, `{"type":"a","n":1}`, `{"type":"b","n":2}`, `not json`)
+	sendTestTransaction(t, msgChan, `{"type":"a","n":3}`)
+
+	assert.Equal(t, `{"type":"a","n":1}`, readTestWS(t, msgsA))
+	assert.Equal(t, `{"type":"a","n":3}`, readTestWS(t, msgsA))
+
+	assert.Equal(t, `{"type":"b","n":2}`, readTestWS(t, msgsB))
+
+	assert.Equal(t, `{"type":"a","n":1}`, readTestWS(t, msgsAll))
+	assert.Equal(t, `{"type":"b","n":2}`, readTestWS(t, msgsAll))
+	assert.Equal(t, `not json`, readTestWS(t, msgsAll))
+	assert.Equal(t, `{"type":"a","n":3}`, readTestWS(t, msgsAll))
+
+	// The get endpoints are not registered when filters are enabled.
+	res, err := http.Get("http://" + addr + "/get")
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	h.TriggerCloseNow()
+	require.NoError(t, h.WaitForClose(ctx))
+}
+
+func TestHTTPServerOutputWSFilterErrors(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	addr := freeTestAddress(t)
+
+	conf := output.NewConfig()
+	conf.Type = "http_server"
+	conf.HTTPServer.Address = addr
+	conf.HTTPServer.WSFilter.Enabled = true
+	conf.HTTPServer.WSFilter.RequireFilter = true
+
+	h, err := mock.NewManager().NewOutput(conf)
+	require.NoError(t, err)
+
+	msgChan := make(chan message.Transaction)
+	require.NoError(t, h.Consume(msgChan))
+
+	var res *http.Response
+	for i := 0; i < 50; i++ {
+		if res, err = http.Get("http://" + addr + "/get/ws?filter=" + url.QueryEscape(`this.type ==`)); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// Filters that access the host are rejected.
+	ws, msgs := dialTestWS(t, "ws://"+addr+"/get/ws")
+	require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(`env("HOME") != ""`)))
+	for range msgs {
+	}
+
+	_, _, err = ws.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+
+	// Without a filter connections receive nothing, and messages without a
+	// matching connection are dropped.
+	_, msgsNone := dialTestWS(t, "ws://"+addr+"/get/ws")
+	sendTestTransaction(t, msgChan, `{"type":"a"}`)
+	select {
+	case msg := <-msgsNone:
+		t.Errorf("Unexpected message: %v", msg)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	h.TriggerCloseNow()
+	require.NoError(t, h.WaitForClose(ctx))
+}
@@ -2,20 +2,36 @@ package io
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 
+	"golang.org/x/sync/singleflight"
+	"gopkg.in/yaml.v3"
+
 	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/codec"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/httpclient"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/pagination"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
 
+func httpProcResponseCodecSpec() docs.FieldSpec {
+	codecDocs := codec.ReaderDocs.AtVersion("4.9.0").Advanced()
+	codecDocs.Name = "response_codec"
+	codecDocs.Description = "An optional codec that response bodies are consumed with as they arrive, where each message produced by the codec becomes a message of the result. This allows streaming responses such as newline delimited JSON (`lines`) or server-sent events (`sse`) to be split into multiple messages. When empty the whole response body is read into a single message."
+	codecDocs.Examples = []any{"lines", "sse", "gzip/lines"}
+	return codecDocs
+}
+
 func init() {
 	err := bundle.AllProcessors.Add(func(conf processor.Config, mgr bundle.NewManagement) (processor.V1, error) {
 		p, err := newHTTPProc(conf.HTTP, mgr)
@@ -55,6 +71,12 @@ it will be retried after increasing intervals.
 When a request returns a response code within the ` + "`drop_on`" + ` field it
 will not be reattempted and is immediately considered a failed request.
 
+## Streaming Responses
+
+By default the body of a response is read as a whole into a single message. When the field ` + "`response_codec`" + ` is set the body is instead consumed as it arrives using the chosen [codec](#response_codec), and each message produced becomes a message of the result. For example, the codec ` + "`lines`" + ` splits a newline delimited JSON response into a message per line, and the codec ` + "`sse`" + ` produces a message per server-sent event.
+
+Each resulting message inherits the metadata of the message that triggered the request, along with the metadata extracted from the response.
+
 ## Adding Metadata
 
 If the request returns an error response code this processor sets a metadata
@@ -68,10 +90,30 @@ headers should be copied into the resulting message from the response.
 When all retry attempts for a message are exhausted the processor cancels the
 attempt. These failed messages will continue through the pipeline unchanged, but
 can be dropped or placed in a dead letter queue according to your config, you
-can read about these patterns [here](/docs/configuration/error_handling).`,
+can read about these patterns [here](/docs/configuration/error_handling).
+
+## Circuit Breaking
+
+When the field ` + "`circuit_breaker.enabled`" + ` is set to ` + "`true`" + ` requests are no longer attempted once the ratio of failed requests breaches ` + "`circuit_breaker.error_threshold`" + `, and messages are instead immediately flagged with an error until the circuit closes again.
+
+## Request Coalescing
+
+When the field ` + "`singleflight`" + ` is set to ` + "`true`" + ` identical requests that are in flight at the same time are coalesced into a single request, and its response is shared with each message. Requests are identical when they share the same verb, URL, headers and body, and are sent by processors with the same config, which includes the processors of each pipeline thread. This is useful for reducing the load on enrichment services when many messages resolve to the same lookup.
+
+Responses are only shared whilst a request is in flight, in order to reuse responses for longer consider placing the processor within a ` + "[`cached` processor](/docs/components/processors/cached)" + `.
+
+## Pagination
+
+When the field ` + "`pagination.enabled`" + ` is set to ` + "`true`" + ` the ` + "`pagination.cursor`" + ` mapping is executed against each response in order to extract the cursor of the next page. While a cursor is found, and the optional ` + "`pagination.check`" + ` passes, another request is made with the cursor added to the request message as the metadata field ` + "`" + pagination.CursorMetaKey + "`" + `, which can be referenced within the ` + "`url`" + ` and ` + "`headers`" + ` fields with interpolation functions. The messages of all pages are combined into the result in order, and if any page fails the whole request is considered failed.
+
+When messages are sent individually each message is paginated separately, and so a single message can result in many.`,
 		Config: httpclient.OldFieldSpec(false,
 			docs.FieldBool("batch_as_multipart", "Send message batches as a single request using [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html).").Advanced().HasDefault(false),
-			docs.FieldBool("parallel", "When processing batched messages, whether to send messages of the batch in parallel, otherwise they are sent serially.").HasDefault(false)).ChildDefaultAndTypesFromStruct(processor.NewHTTPConfig()),
+			docs.FieldBool("parallel", "When processing batched messages, whether to send messages of the batch in parallel, otherwise they are sent serially.").HasDefault(false),
+			httpProcResponseCodecSpec(),
+			docs.FieldBool("singleflight", "Whether to coalesce identical requests that are in flight at the same time into a single request, see [request coalescing](#request-coalescing).").Advanced().AtVersion("4.9.0").HasDefault(false),
+			circuitbreaker.FieldSpec(),
+			pagination.FieldSpec()).ChildDefaultAndTypesFromStruct(processor.NewHTTPConfig()),
 		Examples: []docs.AnnotatedExample{
 			{
 				Title: "Branched Request",
@@ -87,6 +129,23 @@ pipeline:
               url: https://hub.docker.com/v2/repositories/jeffail/benthos
               verb: GET
         result_map: 'root.repo.status = this'
+`,
+			},
+			{
+				Title: "Paginated Request",
+				Summary: `
+This example requests every page of a REST API that returns a cursor of the next page in the field ` + "`next`" + `, and a field ` + "`has_more`" + ` indicating whether there are more pages, where each page becomes a message of the result:`,
+				Config: `
+pipeline:
+  processors:
+    - http:
+        url: 'https://api.example.com/items?cursor=${! meta("http_pagination_cursor").or("") }'
+        verb: GET
+        pagination:
+          enabled: true
+          cursor: 'root = this.next'
+          check: 'this.has_more'
+          max_pages: 100
 `,
 			},
 		},
@@ -96,8 +155,17 @@ pipeline:
 	}
 }
 
+// httpProcRequests coalesces identical in flight requests of http processors,
+// keys are prefixed with a hash of the processor config so that only the
+// requests of processors with identical configs are coalesced.
+var httpProcRequests singleflight.Group
+
 type httpProc struct {
 	client      *httpclient.Client
+	sfPrefix    string
+	breaker     *circuitbreaker.Breaker
+	paginator   *pagination.Paginator
+	codecCtor   codec.ReaderConstructor
 	asMultipart bool
 	parallel    bool
 	rawURL      string
@@ -112,19 +180,86 @@ func newHTTPProc(conf processor.HTTPConfig, mgr bundle.NewManagement) (processor
 		parallel:    conf.Parallel,
 	}
 
+	if conf.Singleflight {
+		confBytes, err := yaml.Marshal(conf)
+		if err != nil {
+			return nil, err
+		}
+		g.sfPrefix = fmt.Sprintf("%x", sha256.Sum256(confBytes))
+	}
+
 	var err error
+	if conf.ResponseCodec != "" {
+		if g.codecCtor, err = codec.GetReader(conf.ResponseCodec, codec.NewReaderConfig()); err != nil {
+			return nil, fmt.Errorf("failed to parse response_codec: %w", err)
+		}
+	}
+	if g.breaker, err = circuitbreaker.New(conf.CircuitBreaker, mgr.IntoPath("circuit_breaker").Metrics()); err != nil {
+		return nil, err
+	}
+	if g.paginator, err = pagination.New(conf.Pagination, mgr); err != nil {
+		return nil, err
+	}
 	if g.client, err = httpclient.NewClientFromOldConfig(conf.OldConfig, mgr); err != nil {
 		return nil, err
 	}
 	return g, nil
 }
 
+func (h *httpProc) send(ctx context.Context, msg message.Batch) (message.Batch, error) {
+	if h.paginator == nil {
+		return h.sendPage(ctx, msg)
+	}
+	return h.paginator.Paginate(ctx, msg, h.sendPage)
+}
+
+func (h *httpProc) sendPage(ctx context.Context, msg message.Batch) (message.Batch, error) {
+	if h.sfPrefix == "" {
+		return h.sendOnce(ctx, msg)
+	}
+
+	key, err := h.client.RequestKey(msg)
+	if err != nil {
+		return nil, err
+	}
+	v, err, shared := httpProcRequests.Do(h.sfPrefix+key, func() (any, error) {
+		return h.sendOnce(ctx, msg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := v.(message.Batch)
+	if shared {
+		res = res.DeepCopy()
+	}
+	return res, nil
+}
+
+func (h *httpProc) sendOnce(ctx context.Context, msg message.Batch) (message.Batch, error) {
+	if err := h.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	var res message.Batch
+	var err error
+	if h.codecCtor != nil {
+		var hRes *http.Response
+		if hRes, err = h.client.SendToResponse(ctx, msg); err == nil {
+			res, err = h.client.ResponseToCodecBatch(ctx, hRes, h.codecCtor)
+		}
+	} else {
+		res, err = h.client.Send(ctx, msg)
+	}
+	h.breaker.Done(err)
+	return res, err
+}
+
 func (h *httpProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg message.Batch) ([]message.Batch, error) {
 	var responseMsg message.Batch
 
 	if h.asMultipart || msg.Len() == 1 {
 		// Easy, just do a single request.
-		resultMsg, err := h.client.Send(context.Background(), msg)
+		resultMsg, err := h.send(context.Background(), msg)
 		if err != nil {
 			var codeStr string
 			var hErr component.ErrUnexpectedHTTPRes
@@ -163,7 +298,7 @@ func (h *httpProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 		_ = msg.Iter(func(i int, p *message.Part) error {
 			tmpMsg := message.QuickBatch(nil)
 			tmpMsg = append(tmpMsg, p)
-			result, err := h.client.Send(context.Background(), tmpMsg)
+			result, err := h.send(context.Background(), tmpMsg)
 			if err != nil {
 				h.log.Errorf("HTTP request to '%v' failed: %v", h.rawURL, err)
 
@@ -191,9 +326,9 @@ func (h *httpProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 		})
 	} else {
 		// Hard, need to do parallel requests limited by max parallelism.
-		results := make([]*message.Part, msg.Len())
+		results := make([]message.Batch, msg.Len())
 		_ = msg.Iter(func(i int, p *message.Part) error {
-			results[i] = p.ShallowCopy()
+			results[i] = message.Batch{p.ShallowCopy()}
 			return nil
 		})
 		reqChan, resChan := make(chan int), make(chan error)
@@ -202,22 +337,30 @@ func (h *httpProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 			go func() {
 				for index := range reqChan {
 					tmpMsg := message.Batch{msg.Get(index)}
-					result, err := h.client.Send(context.Background(), tmpMsg)
-					if err == nil && result.Len() != 1 {
+					result, err := h.send(context.Background(), tmpMsg)
+					if err == nil && h.codecCtor == nil && h.paginator == nil && result.Len() != 1 {
 						err = fmt.Errorf("unexpected response size: %v", result.Len())
 					}
 					if err == nil {
-						results[index].SetBytes(result.Get(0).AsBytes())
-						_ = result.Get(0).MetaIter(func(k, v string) error {
-							results[index].MetaSet(k, v)
+						resParts := make(message.Batch, 0, result.Len())
+						_ = result.Iter(func(_ int, rp *message.Part) error {
+							tmpPart := msg.Get(index).ShallowCopy()
+							tmpPart.SetBytes(rp.AsBytes())
+							_ = rp.MetaIter(func(k, v string) error {
+								tmpPart.MetaSet(k, v)
+								return nil
+							})
+							resParts = append(resParts, tmpPart)
 							return nil
 						})
+						results[index] = resParts
 					} else {
+						errPart := results[index][0]
 						var hErr component.ErrUnexpectedHTTPRes
 						if ok := errors.As(err, &hErr); ok {
-							results[index].MetaSet("http_status_code", strconv.Itoa(hErr.Code))
+							errPart.MetaSet("http_status_code", strconv.Itoa(hErr.Code))
 						}
-						results[index].ErrorSet(err)
+						errPart.ErrorSet(err)
 					}
 					resChan <- err
 				}
@@ -235,7 +378,9 @@ func (h *httpProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 		}
 
 		close(reqChan)
-		responseMsg = results
+		for _, r := range results {
+			responseMsg = append(responseMsg, r...)
+		}
 	}
 
 	if responseMsg.Len() < 1 {
@@ -2,12 +2,14 @@ package io_test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -59,6 +61,36 @@ func TestHTTPClientRetries(t *testing.T) {
 	}
 }
 
+func TestHTTPClientCircuitBreaker(t *testing.T) {
+	var reqCount uint32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint32(&reqCount, 1)
+		http.Error(w, "test error", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + "/testpost"
+	conf.HTTP.OldConfig.NumRetries = 0
+	conf.HTTP.CircuitBreaker.Enabled = true
+	conf.HTTP.CircuitBreaker.MinRequests = 2
+	conf.HTTP.CircuitBreaker.OpenPeriod = "1h"
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("test")}))
+		require.Nil(t, res)
+		require.Len(t, msgs, 1)
+		require.Equal(t, 1, msgs[0].Len())
+		assert.Error(t, msgs[0].Get(0).ErrorGet())
+	}
+
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&reqCount))
+}
+
 func TestHTTPClientBasic(t *testing.T) {
 	i := 0
 	expPayloads := []string{"foo", "bar", "baz"}
@@ -410,3 +442,218 @@ func TestHTTPClientParallelError(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPClientResponseCodec(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, part := range []string{"a", "b"} {
+			_, _ = fmt.Fprintf(w, "id: %d\ndata: %s-%s\n\n", i, reqBody, part)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer ts.Close()
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + "/testpost"
+	conf.HTTP.ResponseCodec = "sse"
+
+	for _, parallel := range []bool{false, true} {
+		conf.HTTP.Parallel = parallel
+
+		h, err := mock.NewManager().NewProcessor(conf)
+		require.NoError(t, err)
+
+		inMsg := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar")})
+		inMsg.Get(0).MetaSet("baz", "buz")
+
+		msgs, res := h.ProcessBatch(context.Background(), inMsg)
+		require.NoError(t, res)
+		require.Len(t, msgs, 1)
+
+		assert.Equal(t, [][]byte{
+			[]byte("foo-a"), []byte("foo-b"), []byte("bar-a"), []byte("bar-b"),
+		}, message.GetAllBytes(msgs[0]), "parallel: %v", parallel)
+
+		for i, expID := range []string{"0", "1", "0", "1"} {
+			p := msgs[0].Get(i)
+			assert.NoError(t, p.ErrorGet())
+			assert.Equal(t, expID, p.MetaGet("sse_id"))
+			assert.Equal(t, "200", p.MetaGet("http_status_code"))
+		}
+		assert.Equal(t, "buz", msgs[0].Get(0).MetaGet("baz"))
+		assert.Equal(t, "buz", msgs[0].Get(1).MetaGet("baz"))
+		assert.Equal(t, "", msgs[0].Get(2).MetaGet("baz"))
+
+		require.NoError(t, h.Close(context.Background()))
+	}
+}
+
+func TestHTTPClientResponseCodecLines(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"))
+	}))
+	defer ts.Close()
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + "/testpost"
+	conf.HTTP.ResponseCodec = "lines"
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"id":1}`), []byte(`{"id":2}`), []byte(`{"id":3}`),
+	}, message.GetAllBytes(msgs[0]))
+
+	conf.HTTP.ResponseCodec = "nope"
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+}
+
+func TestHTTPClientSingleflight(t *testing.T) {
+	var reqCount int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqCount, 1)
+		<-release
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(append([]byte("echo: "), body...))
+	}))
+	defer ts.Close()
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + "/testpost"
+	conf.HTTP.Singleflight = true
+
+	var wg sync.WaitGroup
+	results := make([][][]byte, 5)
+	for i := range results {
+		h, err := mock.NewManager().NewProcessor(conf)
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+			require.NoError(t, res)
+			require.Len(t, msgs, 1)
+			results[i] = message.GetAllBytes(msgs[0])
+		}(i)
+	}
+
+	// Wait for the first request to arrive, and give the others a chance to
+	// join it.
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&reqCount) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(time.Millisecond * 50)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reqCount))
+	for _, r := range results {
+		assert.Equal(t, [][]byte{[]byte("echo: foo")}, r)
+	}
+}
+
+func paginatedTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	pages := map[string]string{
+		"":   `{"item":"a","next":"p2"}`,
+		"p2": `{"item":"b","next":"p3"}`,
+		"p3": `{"item":"c","next":null}`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, exists := pages[r.URL.Query().Get("cursor")]
+		if !exists {
+			http.Error(w, "page not found", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestHTTPClientPaginated(t *testing.T) {
+	ts := paginatedTestServer(t)
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + `/items?cursor=${! meta("http_pagination_cursor").or("") }`
+	conf.HTTP.OldConfig.Verb = "GET"
+	conf.HTTP.Pagination.Enabled = true
+	conf.HTTP.Pagination.Cursor = `root = this.next`
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"item":"a","next":"p2"}`),
+		[]byte(`{"item":"b","next":"p3"}`),
+		[]byte(`{"item":"c","next":null}`),
+	}, message.GetAllBytes(msgs[0]))
+
+	conf.HTTP.Pagination.MaxPages = 2
+	conf.HTTP.Parallel = true
+
+	h, err = mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res = h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo"), []byte("bar")}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"item":"a","next":"p2"}`),
+		[]byte(`{"item":"b","next":"p3"}`),
+		[]byte(`{"item":"a","next":"p2"}`),
+		[]byte(`{"item":"b","next":"p3"}`),
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestHTTPClientPaginatedCheck(t *testing.T) {
+	ts := paginatedTestServer(t)
+
+	conf := processor.NewConfig()
+	conf.Type = "http"
+	conf.HTTP.OldConfig.URL = ts.URL + `/items?cursor=${! meta("http_pagination_cursor").or("") }`
+	conf.HTTP.OldConfig.Verb = "GET"
+	conf.HTTP.OldConfig.NumRetries = 0
+	conf.HTTP.Pagination.Enabled = true
+	conf.HTTP.Pagination.Cursor = `root = this.next + "nope"`
+	conf.HTTP.Pagination.Check = `this.item != "b"`
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	// The second page does not exist and therefore the request fails.
+	msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	require.Equal(t, 1, msgs[0].Len())
+	assert.Equal(t, "foo", string(msgs[0].Get(0).AsBytes()))
+	assert.Error(t, msgs[0].Get(0).ErrorGet())
+
+	conf.HTTP.Pagination.Cursor = `root = this.next`
+
+	h, err = mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res = h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"item":"a","next":"p2"}`),
+		[]byte(`{"item":"b","next":"p3"}`),
+	}, message.GetAllBytes(msgs[0]))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
@@ -71,6 +72,17 @@ This input adds the following metadata fields to each message:
 			Description("If an offset is not found for a topic partition, determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset.").
 			Default(true).
 			Advanced()).
+		Field(service.NewStringListField("group_balancers").
+			Description("A list of balancers to use for dividing topic partitions among the members of the consumer group, in order of preference. Options are `cooperative_sticky`, `sticky`, `range` and `round_robin`. The `cooperative_sticky` balancer only revokes the partitions that are moving to another member during a rebalance, but cannot be combined with the other (eager) balancers unless all members of the group are migrated carefully.").
+			Default([]any{"cooperative_sticky"}).
+			Example([]string{"range", "round_robin"}).
+			Advanced().
+			Version("4.9.0")).
+		Field(service.NewDurationField("revoke_drain_timeout").
+			Description("The maximum period of time to wait, when partitions are revoked during a rebalance, for in flight messages of those partitions to be acknowledged so that their offsets can be committed before the partitions are handed to another member. Messages of revoked partitions that have not yet been dispatched are dropped, as they will be consumed by the new owner. Setting this to zero commits the offsets that are already acknowledged without waiting. This period must not exceed the rebalance timeout of the group.").
+			Default("5s").
+			Advanced().
+			Version("4.9.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField())
 }
@@ -106,6 +118,8 @@ type franzKafkaReader struct {
 	startFromOldest bool
 	commitPeriod    time.Duration
 	regexPattern    bool
+	balancers       []kgo.GroupBalancer
+	revokeDrain     time.Duration
 
 	msgChan atomic.Value
 	log     *service.Logger
@@ -163,6 +177,29 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	balancerStrs, err := conf.FieldStringList("group_balancers")
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range balancerStrs {
+		switch b {
+		case "cooperative_sticky":
+			f.balancers = append(f.balancers, kgo.CooperativeStickyBalancer())
+		case "sticky":
+			f.balancers = append(f.balancers, kgo.StickyBalancer())
+		case "range":
+			f.balancers = append(f.balancers, kgo.RangeBalancer())
+		case "round_robin":
+			f.balancers = append(f.balancers, kgo.RoundRobinBalancer())
+		default:
+			return nil, fmt.Errorf("group balancer %v not recognised", b)
+		}
+	}
+
+	if f.revokeDrain, err = conf.FieldDuration("revoke_drain_timeout"); err != nil {
+		return nil, err
+	}
+
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
 		return nil, err
@@ -180,13 +217,69 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 //------------------------------------------------------------------------------
 
 type checkpointTracker struct {
-	mut    sync.Mutex
-	topics map[string]map[int32]*checkpoint.Type
+	mut     sync.Mutex
+	topics  map[string]map[int32]*checkpoint.Type
+	revoked map[string]map[int32]struct{}
 }
 
 func newCheckpointTracker() *checkpointTracker {
 	return &checkpointTracker{
-		topics: map[string]map[int32]*checkpoint.Type{},
+		topics:  map[string]map[int32]*checkpoint.Type{},
+		revoked: map[string]map[int32]struct{}{},
+	}
+}
+
+// setRevoked marks topic partitions as revoked or assigned, records of revoked
+// partitions should no longer be dispatched.
+func (c *checkpointTracker) setRevoked(m map[string][]int32, revoked bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	for topicName, parts := range m {
+		revokedParts := c.revoked[topicName]
+		for _, part := range parts {
+			if revoked {
+				if revokedParts == nil {
+					revokedParts = map[int32]struct{}{}
+					c.revoked[topicName] = revokedParts
+				}
+				revokedParts[part] = struct{}{}
+			} else {
+				delete(revokedParts, part)
+			}
+		}
+		if len(revokedParts) == 0 {
+			delete(c.revoked, topicName)
+		}
+	}
+}
+
+func (c *checkpointTracker) isRevoked(topic string, partition int32) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	_, revoked := c.revoked[topic][partition]
+	return revoked
+}
+
+// waitForPending blocks until all tracked messages of the given topic
+// partitions have been released or the context is cancelled.
+func (c *checkpointTracker) waitForPending(ctx context.Context, m map[string][]int32) {
+	for {
+		pending := 0
+		for topic, parts := range m {
+			for _, part := range parts {
+				pending += c.getPending(topic, part)
+			}
+		}
+		if pending == 0 {
+			return
+		}
+		select {
+		case <-time.After(time.Millisecond * 10):
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
@@ -293,10 +386,22 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.ConsumeTopics(f.topics...),
 		kgo.ConsumeResetOffset(initialOffset),
 		kgo.SASL(f.saslConfs...),
+		kgo.OnPartitionsAssigned(func(_ context.Context, _ *kgo.Client, m map[string][]int32) {
+			checkpoints.setRevoked(m, false)
+		}),
 		kgo.OnPartitionsRevoked(func(rctx context.Context, c *kgo.Client, m map[string][]int32) {
+			// Stop dispatching records of the revoked partitions and give
+			// those already in flight a chance to be acknowledged.
+			checkpoints.setRevoked(m, true)
+			if f.revokeDrain > 0 {
+				drainCtx, done := context.WithTimeout(rctx, f.revokeDrain)
+				checkpoints.waitForPending(drainCtx, m)
+				done()
+			}
+
 			// Note: this is a best attempt, there's a chance of duplicates if
 			// the checkpoint limit is borked with slow moving pending messages,
-			// but we can't block here, so work with that we have.
+			// but we can't block forever, so work with that we have.
 			finalOffsets := map[string]map[int32]kgo.EpochOffset{}
 			for topic, parts := range m {
 				offsets := map[int32]kgo.EpochOffset{}
@@ -321,6 +426,7 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		}),
 		kgo.OnPartitionsLost(func(_ context.Context, _ *kgo.Client, m map[string][]int32) {
 			// No point trying to commit our offsets, just clean up our topic map
+			checkpoints.setRevoked(m, true)
 			checkpoints.removeTopicPartitions(m)
 		}),
 		kgo.AutoCommitMarks(),
@@ -336,6 +442,10 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		clientOpts = append(clientOpts, kgo.ConsumeRegex())
 	}
 
+	if len(f.balancers) > 0 {
+		clientOpts = append(clientOpts, kgo.Balancers(f.balancers...))
+	}
+
 	cl, err := kgo.NewClient(clientOpts...)
 	if err != nil {
 		return err
@@ -387,6 +497,11 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			iter := fetches.RecordIter()
 			for !iter.Done() {
 				record := iter.Next()
+				if checkpoints.isRevoked(record.Topic, record.Partition) {
+					// The partition has been handed to another member, which
+					// will consume this record from the last committed offset.
+					continue
+				}
 				msg := recordToMessage(record)
 
 				// The record lives on for checkpointing, but we don't need the
@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestCheckpointTrackerRevoke(t *testing.T) {
+	c := newCheckpointTracker()
+
+	releaseA, _ := c.addRecord(&kgo.Record{Topic: "foo", Partition: 0, Offset: 1})
+	releaseB, _ := c.addRecord(&kgo.Record{Topic: "foo", Partition: 1, Offset: 1})
+
+	parts := map[string][]int32{"foo": {0}}
+	c.setRevoked(parts, true)
+	assert.True(t, c.isRevoked("foo", 0))
+	assert.False(t, c.isRevoked("foo", 1))
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		releaseA()
+	}()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	c.waitForPending(ctx, parts)
+	assert.Equal(t, 0, c.getPending("foo", 0))
+	assert.Equal(t, 1, c.getPending("foo", 1))
+	assert.Equal(t, int64(1), c.getHighest("foo", 0).Offset)
+
+	// Waiting is abandoned once the context is cancelled.
+	shortCtx, shortDone := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer shortDone()
+	c.waitForPending(shortCtx, map[string][]int32{"foo": {1}})
+	assert.Equal(t, 1, c.getPending("foo", 1))
+	releaseB()
+
+	c.setRevoked(parts, false)
+	assert.False(t, c.isRevoked("foo", 0))
+	assert.Empty(t, c.revoked)
+}
@@ -107,6 +107,7 @@ Unfortunately this error message will appear for a wide range of connection prob
 				b.IsAdvanced = true
 				return b
 			}(),
+			input.RedeliveryFieldSpec(),
 		).ChildDefaultAndTypesFromStruct(input.NewKafkaConfig()),
 		Categories: []string{
 			"Services",
@@ -128,7 +129,11 @@ func newKafkaInput(conf input.Config, mgr bundle.NewManagement, log log.Modular,
 			return nil, err
 		}
 	}
-	return input.NewAsyncReader("kafka", false, input.NewAsyncPreserver(rdr), mgr)
+	opts, err := input.RedeliveryOpts(conf.Kafka.Redelivery, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return input.NewAsyncReader("kafka", false, input.NewAsyncPreserver(rdr, opts...), mgr)
 }
 
 //------------------------------------------------------------------------------
@@ -47,3 +47,16 @@ func TestKafkaBadParams(t *testing.T) {
 		})
 	}
 }
+
+func TestKafkaRedeliveryRejectOutputMissing(t *testing.T) {
+	conf := input.NewConfig()
+	conf.Type = "kafka"
+	conf.Kafka.Addresses = []string{"example.com:1234"}
+	conf.Kafka.Topics = []string{"foo"}
+	conf.Kafka.ConsumerGroup = "bar"
+	conf.Kafka.Redelivery.RejectOutput = "nope"
+
+	_, err := mock.NewManager().NewInput(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output resource 'nope' was not found")
+}
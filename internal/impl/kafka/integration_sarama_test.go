@@ -3,6 +3,8 @@ package kafka_test
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"testing"
@@ -12,12 +14,17 @@ import (
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
 
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/impl/kafka"
 	"github.com/benthosdev/benthos/v4/internal/integration"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/io"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
 )
 
 func TestIntegrationSaramaRedpanda(t *testing.T) {
@@ -492,3 +499,91 @@ input:
 		})
 	})
 }
+
+func TestIntegrationSaramaRedelivery(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+
+	kafkaPort, err := integration.GetFreePort()
+	require.NoError(t, err)
+
+	kafkaPortStr := strconv.Itoa(kafkaPort)
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "docker.vectorized.io/vectorized/redpanda",
+		Tag:          "latest",
+		Hostname:     "redpanda",
+		ExposedPorts: []string{"9092"},
+		PortBindings: map[docker.Port][]docker.PortBinding{
+			"9092/tcp": {{HostIP: "", HostPort: kafkaPortStr}},
+		},
+		Cmd: []string{
+			"redpanda", "start", "--smp 1", "--overprovisioned", "",
+			"--kafka-addr 0.0.0.0:9092",
+			fmt.Sprintf("--advertise-kafka-addr localhost:%v", kafkaPort),
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+
+	require.NoError(t, pool.Retry(func() error {
+		return createKafkaTopic("localhost:"+kafkaPortStr, "redelivery", 1)
+	}))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers("localhost:" + kafkaPortStr))
+	require.NoError(t, err)
+	for _, v := range []string{"foo", "bar"} {
+		require.NoError(t, cl.ProduceSync(ctx, &kgo.Record{Topic: "topic-redelivery", Value: []byte(v)}).FirstErr())
+	}
+	cl.Close()
+
+	// Messages that can never be delivered are written to the dead letter
+	// output once they exceed the retry limit, and are then committed.
+	dlqPath := filepath.Join(t.TempDir(), "dlq.txt")
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetYAML(fmt.Sprintf(`
+input:
+  kafka:
+    addresses: [ localhost:%v ]
+    topics: [ topic-redelivery ]
+    consumer_group: group-redelivery
+    start_from_oldest: true
+    checkpoint_limit: 1
+    redelivery:
+      max_retries: 2
+      reject_output: dlq
+
+output:
+  reject: 'output is down'
+
+output_resources:
+  - label: dlq
+    file:
+      path: %v
+      codec: lines
+`, kafkaPortStr, dlqPath)))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+	go func() {
+		_ = strm.Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		b, _ := os.ReadFile(dlqPath)
+		return string(b) == "foo\nbar\n"
+	}, time.Second*30, time.Millisecond*100)
+	require.NoError(t, strm.Stop(ctx))
+}
@@ -3,8 +3,11 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -44,12 +47,31 @@ This output is new and experimental, and the existing ` + "`kafka`" + ` input is
 			"murmur2_hash": "Kafka's default hash algorithm that uses a 32-bit murmur2 hash of the key to compute which partition the record will be on.",
 			"round_robin":  "Round-robin's messages through all available partitions. This algorithm has lower throughput and causes higher CPU load on brokers, but can be useful if you want to ensure an even distribution of records to partitions.",
 			"least_backup": "Chooses the least backed up partition (the partition with the fewest amount of buffered records). Partitions are selected per batch.",
+			"manual":       "Manually select a partition for each message, requires the field `partition` to be specified.",
 		}).
 			Description("Override the default murmur2 hashing partitioner.").
 			Advanced().Optional()).
+		Field(service.NewInterpolatedStringField("partition").
+			Description("An optional explicit partition to set for each message. This field is only relevant when the `partitioner` is set to `manual`. The provided interpolation string must be a valid integer.").
+			Example(`${! meta("partition") }`).
+			Optional().
+			Version("4.9.0")).
+		Field(service.NewInterpolatedStringField("timestamp").
+			Description("An optional timestamp to set for each message, which must resolve to a unix timestamp in seconds. When left empty the current time is used.").
+			Example(`${! timestamp_unix() }`).
+			Example(`${! metadata("kafka_timestamp_unix") }`).
+			Optional().
+			Advanced().
+			Version("4.9.0")).
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
+		Field(service.NewStringListField("metadata_exclude_patterns").
+			Description("A list of regular expression (re2) patterns of metadata keys that should not be added to messages as headers, even when they are included by the field `metadata`.").
+			Example([]string{"^kafka_", "_secret$"}).
+			Optional().
+			Advanced().
+			Version("4.9.0")).
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of batches to be sending in parallel at any given time.").
 			Default(10)).
@@ -101,9 +123,12 @@ type franzKafkaWriter struct {
 	topicStr         string
 	topic            *service.InterpolatedString
 	key              *service.InterpolatedString
+	partition        *service.InterpolatedString
+	timestamp        *service.InterpolatedString
 	tlsConf          *tls.Config
 	saslConfs        []sasl.Mechanism
 	metaFilter       *service.MetadataFilter
+	metaExcludes     []*regexp.Regexp
 	partitioner      kgo.Partitioner
 	timeout          time.Duration
 	produceMaxBytes  int32
@@ -194,17 +219,50 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 			f.partitioner = kgo.RoundRobinPartitioner()
 		case "least_backup":
 			f.partitioner = kgo.LeastBackupPartitioner()
+		case "manual":
+			f.partitioner = kgo.ManualPartitioner()
 		default:
 			return nil, fmt.Errorf("unknown partitioner: %v", partStr)
 		}
 	}
 
+	if conf.Contains("partition") {
+		if rmp, err := conf.FieldString("partitioner"); err != nil || rmp != "manual" {
+			return nil, errors.New("a partition cannot be specified unless the partitioner is set to manual")
+		}
+		if f.partition, err = conf.FieldInterpolatedString("partition"); err != nil {
+			return nil, err
+		}
+	} else if partStr, _ := conf.FieldString("partitioner"); partStr == "manual" {
+		return nil, errors.New("a partition must be specified when the partitioner is set to manual")
+	}
+
+	if conf.Contains("timestamp") {
+		if f.timestamp, err = conf.FieldInterpolatedString("timestamp"); err != nil {
+			return nil, err
+		}
+	}
+
 	if conf.Contains("metadata") {
 		if f.metaFilter, err = conf.FieldMetadataFilter("metadata"); err != nil {
 			return nil, err
 		}
 	}
 
+	if conf.Contains("metadata_exclude_patterns") {
+		patterns, err := conf.FieldStringList("metadata_exclude_patterns")
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compile metadata exclude pattern %q: %w", p, err)
+			}
+			f.metaExcludes = append(f.metaExcludes, re)
+		}
+	}
+
 	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
 	if err != nil {
 		return nil, err
@@ -259,7 +317,19 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		return service.ErrNotConnected
 	}
 
-	records := make([]*kgo.Record, 0, len(b))
+	records, err := f.batchToRecords(b)
+	if err != nil {
+		return err
+	}
+
+	// TODO: This is very cool and allows us to easily return granular errors,
+	// so we should honor travis by doing it.
+	err = f.client.ProduceSync(ctx, records...).FirstErr()
+	return
+}
+
+func (f *franzKafkaWriter) batchToRecords(b service.MessageBatch) (records []*kgo.Record, err error) {
+	records = make([]*kgo.Record, 0, len(b))
 	for i, msg := range b {
 		record := &kgo.Record{Topic: b.InterpolatedString(i, f.topic)}
 		if record.Value, err = msg.AsBytes(); err != nil {
@@ -268,7 +338,28 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		if f.key != nil {
 			record.Key = b.InterpolatedBytes(i, f.key)
 		}
+		if f.partition != nil {
+			partStr := b.InterpolatedString(i, f.partition)
+			partInt, err := strconv.ParseInt(partStr, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse valid integer from partition expression: %w", err)
+			}
+			record.Partition = int32(partInt)
+		}
+		if f.timestamp != nil {
+			tsStr := b.InterpolatedString(i, f.timestamp)
+			tsInt, err := strconv.ParseInt(tsStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse valid integer from timestamp expression: %w", err)
+			}
+			record.Timestamp = time.Unix(tsInt, 0)
+		}
 		_ = f.metaFilter.Walk(msg, func(key, value string) error {
+			for _, re := range f.metaExcludes {
+				if re.MatchString(key) {
+					return nil
+				}
+			}
 			record.Headers = append(record.Headers, kgo.RecordHeader{
 				Key:   key,
 				Value: []byte(value),
@@ -277,10 +368,6 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		})
 		records = append(records, record)
 	}
-
-	// TODO: This is very cool and allows us to easily return granular errors,
-	// so we should honor travis by doing it.
-	err = f.client.ProduceSync(ctx, records...).FirstErr()
 	return
 }
 
@@ -0,0 +1,143 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testFranzKafkaWriter(t *testing.T, conf string) (*franzKafkaWriter, error) {
+	t.Helper()
+
+	pConf, err := franzKafkaOutputConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+	return newFranzKafkaWriterFromConfig(pConf, nil)
+}
+
+func TestFranzKafkaOutputPartitionerValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name: "manual with partition",
+			conf: `
+partitioner: manual
+partition: '${! meta("partition") }'
+`,
+		},
+		{
+			name: "manual without partition",
+			conf: `
+partitioner: manual
+`,
+			errStr: "a partition must be specified when the partitioner is set to manual",
+		},
+		{
+			name: "partition without partitioner",
+			conf: `
+partition: '${! meta("partition") }'
+`,
+			errStr: "a partition cannot be specified unless the partitioner is set to manual",
+		},
+		{
+			name: "partition with other partitioner",
+			conf: `
+partitioner: round_robin
+partition: '${! meta("partition") }'
+`,
+			errStr: "a partition cannot be specified unless the partitioner is set to manual",
+		},
+		{
+			name: "bad exclude pattern",
+			conf: `
+metadata_exclude_patterns: [ '(' ]
+`,
+			errStr: "failed to compile metadata exclude pattern \"(\"",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testFranzKafkaWriter(t, `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+`+test.conf)
+			if test.errStr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errStr)
+			}
+		})
+	}
+}
+
+func TestFranzKafkaOutputRecords(t *testing.T) {
+	w, err := testFranzKafkaWriter(t, `
+seed_brokers: [ localhost:9092 ]
+topic: 'foo_${! meta("topic") }'
+key: '${! meta("key") }'
+partitioner: manual
+partition: '${! meta("partition") }'
+timestamp: '${! meta("ts") }'
+metadata:
+  include_prefixes: [ "" ]
+metadata_exclude_patterns: [ '^partition$', '^t' ]
+`)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("topic", "bar")
+	msg.MetaSetMut("key", "baz")
+	msg.MetaSetMut("partition", "3")
+	msg.MetaSetMut("ts", "1600000000")
+	msg.MetaSetMut("buz", "bev")
+
+	records, err := w.batchToRecords(service.MessageBatch{msg})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	assert.Equal(t, "foo_bar", records[0].Topic)
+	assert.Equal(t, "baz", string(records[0].Key))
+	assert.Equal(t, "hello world", string(records[0].Value))
+	assert.Equal(t, int32(3), records[0].Partition)
+	assert.Equal(t, time.Unix(1600000000, 0), records[0].Timestamp)
+	assert.ElementsMatch(t, []kgo.RecordHeader{
+		{Key: "buz", Value: []byte("bev")},
+		{Key: "key", Value: []byte("baz")},
+	}, records[0].Headers)
+}
+
+func TestFranzKafkaOutputRecordsBadValues(t *testing.T) {
+	w, err := testFranzKafkaWriter(t, `
+seed_brokers: [ localhost:9092 ]
+topic: foo
+partitioner: manual
+partition: '${! meta("partition") }'
+timestamp: '${! meta("ts") }'
+`)
+	require.NoError(t, err)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("partition", "nope")
+	msg.MetaSetMut("ts", "1600000000")
+
+	_, err = w.batchToRecords(service.MessageBatch{msg})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse valid integer from partition expression")
+
+	msg.MetaSetMut("partition", "1")
+	msg.MetaSetMut("ts", "nope")
+
+	_, err = w.batchToRecords(service.MessageBatch{msg})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse valid integer from timestamp expression")
+}
@@ -11,9 +11,11 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
 	ksasl "github.com/benthosdev/benthos/v4/internal/impl/kafka/sasl"
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
 	"github.com/benthosdev/benthos/v4/public/service"
 
 	"github.com/twmb/franz-go/pkg/sasl"
+	skerberos "github.com/twmb/franz-go/pkg/sasl/kerberos"
 	"github.com/twmb/franz-go/pkg/sasl/oauth"
 	"github.com/twmb/franz-go/pkg/sasl/plain"
 	"github.com/twmb/franz-go/pkg/sasl/scram"
@@ -35,6 +37,7 @@ func saslField() *service.ConfigField {
 			"SCRAM-SHA-256": "SCRAM based authentication as specified in RFC5802.",
 			"SCRAM-SHA-512": "SCRAM based authentication as specified in RFC5802.",
 			"AWS_MSK_IAM":   "AWS IAM based authentication as specified by the 'aws-msk-iam-auth' java library.",
+			"GSSAPI":        "Kerberos based authentication, configured with the field `kerberos`.",
 		}).
 			Description("The SASL mechanism to use."),
 		service.NewStringField("username").
@@ -52,6 +55,7 @@ func saslField() *service.ConfigField {
 		service.NewObjectField("aws", config.SessionFields()...).
 			Description("Contains AWS specific fields for when the `mechanism` is set to `AWS_MSK_IAM`.").
 			Optional(),
+		service.NewInternalField(kerberos.NonToggledFieldSpec("kafka")),
 	).
 		Description("Specify one or more methods of SASL authentication. SASL is tried in order; if the broker supports the first mechanism, all connections will use that mechanism. If the first mechanism fails, the client will pick the first supported mechanism. If the broker does not support any client mechanisms, connections will fail.").
 		Advanced().Optional().
@@ -99,6 +103,9 @@ func saslMechanismsFromConfig(c *service.ParsedConfig) ([]sasl.Mechanism, error)
 			case "AWS_MSK_IAM":
 				mechanism, err = AWSSASLFromConfigFn(mConf)
 				mechanisms = append(mechanisms, mechanism)
+			case "GSSAPI":
+				mechanism, err = kerberosSaslFromConfig(mConf)
+				mechanisms = append(mechanisms, mechanism)
 			default:
 				err = fmt.Errorf("unknown mechanism: %v", mechStr)
 			}
@@ -184,6 +191,29 @@ func scram512SaslFromConfig(c *service.ParsedConfig) (sasl.Mechanism, error) {
 	}), nil
 }
 
+func kerberosSaslFromConfig(c *service.ParsedConfig) (sasl.Mechanism, error) {
+	if !c.Contains("kerberos") {
+		return nil, errors.New("the field kerberos must be specified when using the GSSAPI mechanism")
+	}
+	v, err := c.FieldAny("kerberos")
+	if err != nil {
+		return nil, err
+	}
+	conf, err := kerberos.ConfigFromAny(v, "kafka")
+	if err != nil {
+		return nil, err
+	}
+	cl, err := conf.Client()
+	if err != nil {
+		return nil, err
+	}
+	return skerberos.Auth{
+		Client:           cl,
+		Service:          conf.ServiceName,
+		PersistAfterAuth: true,
+	}.AsMechanism(), nil
+}
+
 //------------------------------------------------------------------------------
 
 // SASL specific error types.
@@ -225,6 +255,19 @@ func ApplySASLConfig(s ksasl.Config, mgr bundle.NewManagement, conf *sarama.Conf
 	case sarama.SASLTypePlaintext:
 		conf.Net.SASL.User = s.User
 		conf.Net.SASL.Password = s.Password
+	case sarama.SASLTypeGSSAPI:
+		if s.Kerberos.KeytabFile == "" {
+			return errors.New("the GSSAPI mechanism of this component requires a kerberos keytab_file, in order to use a credential cache try the kafka_franz components")
+		}
+		conf.Net.SASL.GSSAPI = sarama.GSSAPIConfig{
+			AuthType:           sarama.KRB5_KEYTAB_AUTH,
+			KeyTabPath:         s.Kerberos.KeytabFile,
+			KerberosConfigPath: s.Kerberos.ConfigFile,
+			ServiceName:        s.Kerberos.ServiceName,
+			Username:           s.Kerberos.Username,
+			Realm:              s.Kerberos.Realm,
+			DisablePAFXFAST:    s.Kerberos.DisablePAFXFAST,
+		}
 	case "", "none":
 		return nil
 	default:
@@ -2,22 +2,25 @@ package sasl
 
 import (
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
 )
 
 // Config contains configuration for SASL based authentication.
 type Config struct {
-	Mechanism   string `json:"mechanism" yaml:"mechanism"`
-	User        string `json:"user" yaml:"user"`
-	Password    string `json:"password" yaml:"password"`
-	AccessToken string `json:"access_token" yaml:"access_token"`
-	TokenCache  string `json:"token_cache" yaml:"token_cache"`
-	TokenKey    string `json:"token_key" yaml:"token_key"`
+	Mechanism   string          `json:"mechanism" yaml:"mechanism"`
+	User        string          `json:"user" yaml:"user"`
+	Password    string          `json:"password" yaml:"password"`
+	AccessToken string          `json:"access_token" yaml:"access_token"`
+	TokenCache  string          `json:"token_cache" yaml:"token_cache"`
+	TokenKey    string          `json:"token_key" yaml:"token_key"`
+	Kerberos    kerberos.Config `json:"kerberos" yaml:"kerberos"`
 }
 
 // NewConfig returns a new SASL config for Kafka with default values.
 func NewConfig() Config {
 	return Config{
 		Mechanism: "none",
+		Kerberos:  kerberos.NewConfig("kafka"),
 	}
 }
 
@@ -30,11 +33,13 @@ func FieldSpec() docs.FieldSpec {
 			"OAUTHBEARER", "OAuth Bearer based authentication.",
 			"SCRAM-SHA-256", "Authentication using the SCRAM-SHA-256 mechanism.",
 			"SCRAM-SHA-512", "Authentication using the SCRAM-SHA-512 mechanism.",
+			"GSSAPI", "Kerberos based authentication, configured with the field `kerberos`. This component only supports obtaining tickets with a `keytab_file`, in order to use a credential cache try the [`kafka_franz`](/docs/components/inputs/kafka_franz) components.",
 		),
 		docs.FieldString("user", "A PLAIN username. It is recommended that you use environment variables to populate this field.", "${USER}"),
 		docs.FieldString("password", "A PLAIN password. It is recommended that you use environment variables to populate this field.", "${PASSWORD}"),
 		docs.FieldString("access_token", "A static OAUTHBEARER access token"),
 		docs.FieldString("token_cache", "Instead of using a static `access_token` allows you to query a [`cache`](/docs/components/caches/about) resource to fetch OAUTHBEARER tokens from"),
 		docs.FieldString("token_key", "Required when using a `token_cache`, the key to query the cache with for tokens."),
+		kerberos.NonToggledFieldSpec("kafka"),
 	).Advanced()
 }
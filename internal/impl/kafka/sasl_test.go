@@ -141,3 +141,28 @@ func TestApplyUnknownMechanism(t *testing.T) {
 		t.Errorf("Err %v != %v", err, kafka.ErrUnsupportedSASLMechanism)
 	}
 }
+
+func TestApplyGSSAPI(t *testing.T) {
+	conf := &sarama.Config{}
+
+	saslConf := sasl.NewConfig()
+	saslConf.Mechanism = string(sarama.SASLTypeGSSAPI)
+	saslConf.Kerberos.Username = "benthos"
+	saslConf.Kerberos.Realm = "EXAMPLE.COM"
+	saslConf.Kerberos.KeytabFile = "/etc/security/benthos.keytab"
+
+	require.NoError(t, kafka.ApplySASLConfig(saslConf, mock.NewManager(), conf))
+	require.True(t, conf.Net.SASL.Enable)
+	require.Equal(t, sarama.SASLTypeGSSAPI, string(conf.Net.SASL.Mechanism))
+	require.Equal(t, sarama.GSSAPIConfig{
+		AuthType:           sarama.KRB5_KEYTAB_AUTH,
+		KeyTabPath:         "/etc/security/benthos.keytab",
+		KerberosConfigPath: "/etc/krb5.conf",
+		ServiceName:        "kafka",
+		Username:           "benthos",
+		Realm:              "EXAMPLE.COM",
+	}, conf.Net.SASL.GSSAPI)
+
+	saslConf.Kerberos.KeytabFile = ""
+	require.Error(t, kafka.ApplySASLConfig(saslConf, mock.NewManager(), &sarama.Config{}))
+}
@@ -0,0 +1,191 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER identifiers of the universal types used by LDAP messages.
+const (
+	berBoolean     byte = 0x01
+	berInteger     byte = 0x02
+	berOctetString byte = 0x04
+	berEnumerated  byte = 0x0a
+	berSequence    byte = 0x30
+	berSet         byte = 0x31
+)
+
+// BER identifier bits of tagged types.
+const (
+	berApplication byte = 0x40
+	berContext     byte = 0x80
+	berConstructed byte = 0x20
+)
+
+// maxPacketSize is the maximum length of a message read from a server.
+const maxPacketSize = 64 * 1024 * 1024
+
+var errMalformedBER = errors.New("malformed BER encoding")
+
+// berTLV encodes an element with a tag and its contents, where the length is
+// always definite as required by LDAP.
+func berTLV(tag byte, contents ...[]byte) []byte {
+	var n int
+	for _, c := range contents {
+		n += len(c)
+	}
+
+	b := make([]byte, 0, n+6)
+	b = append(b, tag)
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	case n <= 0xffffff:
+		b = append(b, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	default:
+		b = append(b, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	for _, c := range contents {
+		b = append(b, c...)
+	}
+	return b
+}
+
+func berString(tag byte, s string) []byte {
+	return berTLV(tag, []byte(s))
+}
+
+func berInt(tag byte, v int64) []byte {
+	// Two's complement big endian with the minimum number of bytes.
+	var b []byte
+	for {
+		b = append([]byte{byte(v)}, b...)
+		if (v < 0x80 && v >= -0x80) || len(b) == 8 {
+			break
+		}
+		v >>= 8
+	}
+	return berTLV(tag, b)
+}
+
+func berBool(v bool) []byte {
+	if v {
+		return berTLV(berBoolean, []byte{0xff})
+	}
+	return berTLV(berBoolean, []byte{0x00})
+}
+
+//------------------------------------------------------------------------------
+
+// berElement is a decoded element, where the contents of constructed elements
+// are decoded further with children.
+type berElement struct {
+	tag      byte
+	contents []byte
+}
+
+func (e berElement) constructed() bool {
+	return e.tag&berConstructed != 0
+}
+
+// children decodes the elements contained by a constructed element.
+func (e berElement) children() ([]berElement, error) {
+	var elements []berElement
+	rest := e.contents
+	for len(rest) > 0 {
+		var child berElement
+		var err error
+		if child, rest, err = berDecode(rest); err != nil {
+			return nil, err
+		}
+		elements = append(elements, child)
+	}
+	return elements, nil
+}
+
+func (e berElement) str() string {
+	return string(e.contents)
+}
+
+func (e berElement) int() (int64, error) {
+	if len(e.contents) == 0 || len(e.contents) > 8 {
+		return 0, errMalformedBER
+	}
+	v := int64(int8(e.contents[0]))
+	for _, b := range e.contents[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// berDecode decodes the first element of a byte slice, returning the element
+// and the remaining bytes.
+func berDecode(b []byte) (e berElement, rest []byte, err error) {
+	if len(b) < 2 {
+		return e, nil, errMalformedBER
+	}
+	if b[0]&0x1f == 0x1f {
+		return e, nil, fmt.Errorf("%w: multi-byte tags are not supported", errMalformedBER)
+	}
+	e.tag = b[0]
+
+	length, lenBytes := int(b[1]), 1
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < 2+n {
+			return e, nil, fmt.Errorf("%w: invalid length", errMalformedBER)
+		}
+		length = 0
+		for _, lb := range b[2 : 2+n] {
+			length = length<<8 | int(lb)
+		}
+		lenBytes += n
+	}
+	start := 1 + lenBytes
+	if length < 0 || len(b)-start < length {
+		return e, nil, fmt.Errorf("%w: truncated element", errMalformedBER)
+	}
+	e.contents = b[start : start+length]
+	return e, b[start+length:], nil
+}
+
+// berReadPacket reads a single element from a stream.
+func berReadPacket(r *bufio.Reader) (berElement, error) {
+	var e berElement
+	header := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return e, err
+	}
+	e.tag = header[0]
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return e, fmt.Errorf("%w: invalid length", errMalformedBER)
+		}
+		lenBytes := make([]byte, n)
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return e, err
+		}
+		length = 0
+		for _, lb := range lenBytes {
+			length = length<<8 | int(lb)
+		}
+	}
+	if length > maxPacketSize {
+		return e, fmt.Errorf("message of %v bytes exceeds the maximum size", length)
+	}
+
+	e.contents = make([]byte, length)
+	if _, err := io.ReadFull(r, e.contents); err != nil {
+		return e, err
+	}
+	return e, nil
+}
@@ -0,0 +1,426 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Application tags of the protocol operations used by the client, as
+// specified by RFC 4511.
+const (
+	opBindRequest       byte = berApplication | berConstructed | 0
+	opBindResponse      byte = berApplication | berConstructed | 1
+	opUnbindRequest     byte = berApplication | 2
+	opSearchRequest     byte = berApplication | berConstructed | 3
+	opSearchResultEntry byte = berApplication | berConstructed | 4
+	opSearchResultDone  byte = berApplication | berConstructed | 5
+	opSearchResultRef   byte = berApplication | berConstructed | 19
+	opExtendedRequest   byte = berApplication | berConstructed | 23
+	opExtendedResponse  byte = berApplication | berConstructed | 24
+)
+
+const startTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// Result codes of operations that are handled by the client.
+const (
+	resultSuccess            = 0
+	resultSizeLimitExceeded  = 4
+	resultInvalidCredentials = 49
+)
+
+// searchScope is the scope of a search relative to its base object.
+type searchScope int64
+
+const (
+	scopeBaseObject searchScope = iota
+	scopeSingleLevel
+	scopeWholeSubtree
+)
+
+// ldapError is the result of an operation that failed, where the connection
+// remains usable.
+type ldapError struct {
+	code    int64
+	message string
+}
+
+func (e *ldapError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("ldap result code %v", e.code)
+	}
+	return fmt.Sprintf("ldap result code %v: %v", e.code, e.message)
+}
+
+type entryAttribute struct {
+	name   string
+	values []string
+}
+
+type searchEntry struct {
+	dn         string
+	attributes []entryAttribute
+}
+
+type searchRequest struct {
+	baseDN     string
+	scope      searchScope
+	sizeLimit  int64
+	timeLimit  int64
+	filter     []byte
+	attributes []string
+}
+
+//------------------------------------------------------------------------------
+
+// conn is a connection to an LDAP server, which executes a single operation
+// at a time.
+type conn struct {
+	nc    net.Conn
+	r     *bufio.Reader
+	msgID int64
+}
+
+// dialURL opens a connection to the server of an `ldap://` or `ldaps://` URL,
+// where ldap connections are upgraded with StartTLS when startTLS is true.
+func dialURL(ctx context.Context, urlStr string, tlsConf *tls.Config, startTLS bool) (*conn, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	var useTLS bool
+	switch u.Scheme {
+	case "ldap":
+		if port == "" {
+			port = "389"
+		}
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		useTLS = true
+	default:
+		return nil, fmt.Errorf("url scheme %v is not supported, expected ldap or ldaps", u.Scheme)
+	}
+	if useTLS || startTLS {
+		if tlsConf == nil {
+			tlsConf = &tls.Config{}
+		} else {
+			tlsConf = tlsConf.Clone()
+		}
+		if tlsConf.ServerName == "" {
+			tlsConf.ServerName = host
+		}
+	}
+
+	addr := net.JoinHostPort(host, port)
+	var nc net.Conn
+	if useTLS {
+		nc, err = (&tls.Dialer{Config: tlsConf}).DialContext(ctx, "tcp", addr)
+	} else {
+		nc, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConn(nc)
+	if startTLS && !useTLS {
+		if err := c.startTLS(ctx, tlsConf); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("failed to start tls: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func newConn(nc net.Conn) *conn {
+	return &conn{nc: nc, r: bufio.NewReader(nc)}
+}
+
+func (c *conn) send(ctx context.Context, op []byte) (int64, error) {
+	deadline, _ := ctx.Deadline()
+	if err := c.nc.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+	c.msgID++
+	_, err := c.nc.Write(berTLV(berSequence, berInt(berInteger, c.msgID), op))
+	return c.msgID, err
+}
+
+// recv reads the next response to a message, returning its protocol operation.
+func (c *conn) recv(msgID int64) (berElement, error) {
+	for {
+		packet, err := berReadPacket(c.r)
+		if err != nil {
+			return berElement{}, err
+		}
+		if packet.tag != berSequence {
+			return berElement{}, fmt.Errorf("%w: expected message sequence", errMalformedBER)
+		}
+		children, err := packet.children()
+		if err != nil {
+			return berElement{}, err
+		}
+		if len(children) < 2 {
+			return berElement{}, fmt.Errorf("%w: message is missing a protocol operation", errMalformedBER)
+		}
+		id, err := children[0].int()
+		if err != nil {
+			return berElement{}, err
+		}
+		// Unsolicited notifications have a message ID of zero, the only one of
+		// which is sent when the server is about to close the connection.
+		if id == 0 && children[1].tag == opExtendedResponse {
+			if res, _ := parseResult(children[1]); res != nil {
+				return berElement{}, fmt.Errorf("server closed the connection: %v", res)
+			}
+			return berElement{}, errors.New("server closed the connection")
+		}
+		if id != msgID {
+			continue
+		}
+		return children[1], nil
+	}
+}
+
+// parseResult parses the LDAPResult of a response, returning an ldapError
+// unless the result code indicates success.
+func parseResult(op berElement) (*ldapError, error) {
+	children, err := op.children()
+	if err != nil {
+		return nil, err
+	}
+	if len(children) < 3 {
+		return nil, fmt.Errorf("%w: result is missing fields", errMalformedBER)
+	}
+	code, err := children[0].int()
+	if err != nil {
+		return nil, err
+	}
+	if code == resultSuccess {
+		return nil, nil
+	}
+	return &ldapError{code: code, message: children[2].str()}, nil
+}
+
+func (c *conn) simpleResult(ctx context.Context, op []byte, resTag byte) error {
+	id, err := c.send(ctx, op)
+	if err != nil {
+		return err
+	}
+	res, err := c.recv(id)
+	if err != nil {
+		return err
+	}
+	if res.tag != resTag {
+		return fmt.Errorf("unexpected response tag %#x", res.tag)
+	}
+	ldapErr, err := parseResult(res)
+	if err != nil {
+		return err
+	}
+	if ldapErr != nil {
+		return ldapErr
+	}
+	return nil
+}
+
+func (c *conn) startTLS(ctx context.Context, tlsConf *tls.Config) error {
+	op := berTLV(opExtendedRequest, berString(berContext|0, startTLSOID))
+	if err := c.simpleResult(ctx, op, opExtendedResponse); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(c.nc, tlsConf)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.nc = tlsConn
+	c.r = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// bind authenticates the connection with a simple bind, where an empty DN and
+// password results in an anonymous bind.
+func (c *conn) bind(ctx context.Context, dn, password string) error {
+	op := berTLV(opBindRequest,
+		berInt(berInteger, 3),
+		berString(berOctetString, dn),
+		berString(berContext|0, password),
+	)
+	return c.simpleResult(ctx, op, opBindResponse)
+}
+
+func (c *conn) search(ctx context.Context, req searchRequest) ([]searchEntry, error) {
+	attrs := make([][]byte, 0, len(req.attributes))
+	for _, a := range req.attributes {
+		attrs = append(attrs, berString(berOctetString, a))
+	}
+	op := berTLV(opSearchRequest,
+		berString(berOctetString, req.baseDN),
+		berInt(berEnumerated, int64(req.scope)),
+		berInt(berEnumerated, 0), // Never dereference aliases
+		berInt(berInteger, req.sizeLimit),
+		berInt(berInteger, req.timeLimit),
+		berBool(false),
+		req.filter,
+		berTLV(berSequence, attrs...),
+	)
+
+	id, err := c.send(ctx, op)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []searchEntry
+	for {
+		res, err := c.recv(id)
+		if err != nil {
+			return nil, err
+		}
+		switch res.tag {
+		case opSearchResultEntry:
+			entry, err := parseSearchEntry(res)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultRef:
+			// Referrals to other servers aren't followed.
+		case opSearchResultDone:
+			ldapErr, err := parseResult(res)
+			if err != nil {
+				return nil, err
+			}
+			if ldapErr != nil && ldapErr.code != resultSizeLimitExceeded {
+				return nil, ldapErr
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("unexpected response tag %#x", res.tag)
+		}
+	}
+}
+
+func parseSearchEntry(op berElement) (e searchEntry, err error) {
+	children, err := op.children()
+	if err != nil {
+		return e, err
+	}
+	if len(children) != 2 {
+		return e, fmt.Errorf("%w: search entry is missing fields", errMalformedBER)
+	}
+	e.dn = children[0].str()
+
+	attrs, err := children[1].children()
+	if err != nil {
+		return e, err
+	}
+	for _, a := range attrs {
+		fields, err := a.children()
+		if err != nil {
+			return e, err
+		}
+		if len(fields) != 2 {
+			return e, fmt.Errorf("%w: attribute is missing fields", errMalformedBER)
+		}
+		vals, err := fields[1].children()
+		if err != nil {
+			return e, err
+		}
+		attr := entryAttribute{name: fields[0].str(), values: make([]string, 0, len(vals))}
+		for _, v := range vals {
+			attr.values = append(attr.values, v.str())
+		}
+		e.attributes = append(e.attributes, attr)
+	}
+	return e, nil
+}
+
+func (c *conn) close() error {
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+	_, _ = c.send(ctx, berTLV(opUnbindRequest))
+	return c.nc.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// connPool limits the number of connections that are open at a given time,
+// and keeps those that aren't in use open for the operations that follow.
+type connPool struct {
+	dial func(ctx context.Context) (*conn, error)
+
+	slots chan struct{}
+	idle  chan *conn
+
+	closeOnce sync.Once
+}
+
+func newConnPool(size int, dial func(ctx context.Context) (*conn, error)) *connPool {
+	return &connPool{
+		dial:  dial,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *conn, size),
+	}
+}
+
+// get returns an idle connection or opens a new one, blocking until the number
+// of connections in use is under the limit of the pool.
+func (p *connPool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// put returns a connection to the pool, where the connection is closed
+// instead if the error of its last operation indicates that it's broken.
+func (p *connPool) put(c *conn, err error) {
+	var ldapErr *ldapError
+	if err == nil || errors.As(err, &ldapErr) {
+		select {
+		case p.idle <- c:
+		default:
+			_ = c.close()
+		}
+	} else {
+		_ = c.close()
+	}
+	<-p.slots
+}
+
+func (p *connPool) close() {
+	p.closeOnce.Do(func() {
+		for {
+			select {
+			case c := <-p.idle:
+				_ = c.close()
+			default:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Context specific tags of the choices of a search filter, as specified by
+// RFC 4511.
+const (
+	filterAnd            byte = berContext | berConstructed | 0
+	filterOr             byte = berContext | berConstructed | 1
+	filterNot            byte = berContext | berConstructed | 2
+	filterEqualityMatch  byte = berContext | berConstructed | 3
+	filterSubstrings     byte = berContext | berConstructed | 4
+	filterGreaterOrEqual byte = berContext | berConstructed | 5
+	filterLessOrEqual    byte = berContext | berConstructed | 6
+	filterPresent        byte = berContext | 7
+	filterApproxMatch    byte = berContext | berConstructed | 8
+
+	substringInitial byte = berContext | 0
+	substringAny     byte = berContext | 1
+	substringFinal   byte = berContext | 2
+)
+
+// escapeFilterValue escapes a value so that it can be placed within a search
+// filter as a literal, as specified by RFC 4515.
+func escapeFilterValue(v string) string {
+	var sb strings.Builder
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&sb, "\\%02x", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// replaceFilterPlaceholders replaces each question mark of a filter with the
+// escaped string form of an argument.
+func replaceFilterPlaceholders(filter string, args []any) (string, error) {
+	var sb strings.Builder
+	var n int
+	for i := 0; i < len(filter); i++ {
+		if filter[i] != '?' {
+			sb.WriteByte(filter[i])
+			continue
+		}
+		if n >= len(args) {
+			return "", fmt.Errorf("filter contains more placeholders than the %v arguments provided", len(args))
+		}
+		var s string
+		switch t := args[n].(type) {
+		case string:
+			s = t
+		case []byte:
+			s = string(t)
+		default:
+			s = fmt.Sprintf("%v", t)
+		}
+		sb.WriteString(escapeFilterValue(s))
+		n++
+	}
+	if n != len(args) {
+		return "", fmt.Errorf("filter contains %v placeholders but %v arguments were provided", n, len(args))
+	}
+	return sb.String(), nil
+}
+
+//------------------------------------------------------------------------------
+
+var errFilterEnd = errors.New("unexpected end of filter")
+
+// compileFilter parses the string representation of a search filter and
+// returns its BER encoding.
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, errors.New("filter must not be empty")
+	}
+	// The outer parentheses are optional.
+	if filter[0] != '(' {
+		filter = "(" + filter + ")"
+	}
+
+	b, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse filter: %w", err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("failed to parse filter: unexpected trailing characters: %v", rest)
+	}
+	return b, nil
+}
+
+// parseFilter parses a parenthesised filter from the beginning of a string.
+func parseFilter(s string) ([]byte, string, error) {
+	if s == "" {
+		return nil, "", errFilterEnd
+	}
+	if s[0] != '(' {
+		return nil, "", fmt.Errorf("expected ( but found %q", s[0])
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", errFilterEnd
+	}
+
+	var b []byte
+	var err error
+	switch s[0] {
+	case '&', '|':
+		tag := filterAnd
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		s = s[1:]
+		var children [][]byte
+		for s != "" && s[0] == '(' {
+			var child []byte
+			if child, s, err = parseFilter(s); err != nil {
+				return nil, "", err
+			}
+			children = append(children, child)
+		}
+		if len(children) == 0 {
+			return nil, "", errors.New("and/or filters must contain at least one filter")
+		}
+		b = berTLV(tag, children...)
+	case '!':
+		var child []byte
+		if child, s, err = parseFilter(s[1:]); err != nil {
+			return nil, "", err
+		}
+		b = berTLV(filterNot, child)
+	default:
+		end := strings.IndexByte(s, ')')
+		if end == -1 {
+			return nil, "", errFilterEnd
+		}
+		if b, err = parseFilterItem(s[:end]); err != nil {
+			return nil, "", err
+		}
+		s = s[end:]
+	}
+
+	if s == "" {
+		return nil, "", errFilterEnd
+	}
+	if s[0] != ')' {
+		return nil, "", fmt.Errorf("expected ) but found %q", s[0])
+	}
+	return b, s[1:], nil
+}
+
+// parseFilterItem parses a simple, present or substrings filter without its
+// parentheses, such as `cn=foo*`.
+func parseFilterItem(s string) ([]byte, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("invalid filter item: %v", s)
+	}
+	attr, value := s[:eq], s[eq+1:]
+
+	tag := filterEqualityMatch
+	switch attr[len(attr)-1] {
+	case '~':
+		tag = filterApproxMatch
+		attr = attr[:len(attr)-1]
+	case '>':
+		tag = filterGreaterOrEqual
+		attr = attr[:len(attr)-1]
+	case '<':
+		tag = filterLessOrEqual
+		attr = attr[:len(attr)-1]
+	case ':':
+		return nil, fmt.Errorf("extensible match filters are not supported: %v", s)
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("invalid filter item: %v", s)
+	}
+	attrBytes := berString(berOctetString, attr)
+
+	if tag == filterEqualityMatch && value == "*" {
+		return berString(filterPresent, attr), nil
+	}
+
+	if tag == filterEqualityMatch && strings.IndexByte(value, '*') != -1 {
+		parts := strings.Split(value, "*")
+		var subs [][]byte
+		for i, p := range parts {
+			if p == "" {
+				continue
+			}
+			v, err := unescapeFilterValue(p)
+			if err != nil {
+				return nil, err
+			}
+			subTag := substringAny
+			switch i {
+			case 0:
+				subTag = substringInitial
+			case len(parts) - 1:
+				subTag = substringFinal
+			}
+			subs = append(subs, berString(subTag, v))
+		}
+		return berTLV(filterSubstrings, attrBytes, berTLV(berSequence, subs...)), nil
+	}
+
+	v, err := unescapeFilterValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return berTLV(tag, attrBytes, berString(berOctetString, v)), nil
+}
+
+func unescapeFilterValue(s string) (string, error) {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if len(s) < i+3 {
+			return "", fmt.Errorf("invalid escape sequence in filter value: %v", s)
+		}
+		b, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape sequence in filter value: %v", s)
+		}
+		sb.Write(b)
+		i += 2
+	}
+	return sb.String(), nil
+}
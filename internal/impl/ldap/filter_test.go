@@ -0,0 +1,108 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileFilter(t *testing.T) {
+	eq := func(attr, value string) []byte {
+		return berTLV(filterEqualityMatch, berString(berOctetString, attr), berString(berOctetString, value))
+	}
+
+	for _, test := range []struct {
+		filter   string
+		expected []byte
+	}{
+		{filter: "(cn=foo)", expected: eq("cn", "foo")},
+		{filter: "cn=foo", expected: eq("cn", "foo")},
+		{filter: `(cn=f\2aoo\29)`, expected: eq("cn", "f*oo)")},
+		{filter: "(cn=*)", expected: berString(filterPresent, "cn")},
+		{
+			filter: "(age>=21)",
+			expected: berTLV(filterGreaterOrEqual,
+				berString(berOctetString, "age"), berString(berOctetString, "21")),
+		},
+		{
+			filter: "(age<=21)",
+			expected: berTLV(filterLessOrEqual,
+				berString(berOctetString, "age"), berString(berOctetString, "21")),
+		},
+		{
+			filter: "(cn~=foo)",
+			expected: berTLV(filterApproxMatch,
+				berString(berOctetString, "cn"), berString(berOctetString, "foo")),
+		},
+		{
+			filter: "(cn=a*b*c)",
+			expected: berTLV(filterSubstrings, berString(berOctetString, "cn"), berTLV(berSequence,
+				berString(substringInitial, "a"),
+				berString(substringAny, "b"),
+				berString(substringFinal, "c"),
+			)),
+		},
+		{
+			filter: "(cn=*b*)",
+			expected: berTLV(filterSubstrings, berString(berOctetString, "cn"), berTLV(berSequence,
+				berString(substringAny, "b"),
+			)),
+		},
+		{
+			filter:   "(&(cn=foo)(|(sn=bar)(!(sn=baz))))",
+			expected: berTLV(filterAnd, eq("cn", "foo"), berTLV(filterOr, eq("sn", "bar"), berTLV(filterNot, eq("sn", "baz")))),
+		},
+	} {
+		b, err := compileFilter(test.filter)
+		require.NoError(t, err, test.filter)
+		assert.Equal(t, test.expected, b, test.filter)
+	}
+
+	for _, filter := range []string{
+		"",
+		"(cn=foo",
+		"(cn=foo))",
+		"(=foo)",
+		"(cn)",
+		"(&)",
+		`(cn=\zz)`,
+		"(cn:caseExactMatch:=foo)",
+	} {
+		_, err := compileFilter(filter)
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestReplaceFilterPlaceholders(t *testing.T) {
+	filter, err := replaceFilterPlaceholders("(&(cn=?)(uid=?))", []any{"a*(b)\\", 10})
+	require.NoError(t, err)
+	assert.Equal(t, `(&(cn=a\2a\28b\29\5c)(uid=10))`, filter)
+
+	_, err = replaceFilterPlaceholders("(cn=?)", nil)
+	require.Error(t, err)
+
+	_, err = replaceFilterPlaceholders("(cn=?)", []any{"a", "b"})
+	require.Error(t, err)
+}
+
+func TestBERRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 1 << 40} {
+		e, rest, err := berDecode(berInt(berInteger, v))
+		require.NoError(t, err)
+		assert.Empty(t, rest)
+
+		act, err := e.int()
+		require.NoError(t, err)
+		assert.Equal(t, v, act)
+	}
+
+	long := make([]byte, 70000)
+	e, rest, err := berDecode(berTLV(berOctetString, long))
+	require.NoError(t, err)
+	assert.Empty(t, rest)
+	assert.Equal(t, long, e.contents)
+
+	_, _, err = berDecode([]byte{berOctetString, 0x05, 'a'})
+	require.Error(t, err)
+}
@@ -0,0 +1,393 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func ldapProcConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.9.0").
+		Summary("Performs searches against an LDAP directory, such as Active Directory, or verifies credentials by binding with them, and replaces each message with the result.").
+		Description(`
+Connections to the server are pooled, where each connection is bound with the credentials of the fields `+"`bind_dn` and `bind_password`"+` when it's opened, and up to `+"`max_connections`"+` operations are executed in parallel.
+
+### Search
+
+The `+"`search`"+` operation executes a search with the fields `+"`base_dn`, `scope`, `filter` and `attributes`"+`, and replaces the message with an array of objects, one for each entry found, containing the key `+"`dn`"+` and a key for each attribute of the entry, where the values of attributes are arrays of strings.
+
+Values from the message can be placed within the filter with the field `+"`args_mapping`"+`, which is a mapping that evaluates to an array of values. Each question mark (`+"`?`"+`) of the filter is substituted with a value of the array, where the characters that are special within filters are escaped, making it safe to use values from untrusted sources.
+
+### Bind
+
+The `+"`bind`"+` operation verifies the credentials of the fields `+"`user_dn` and `user_password`"+` by binding with them, and replaces the message with an object containing the key `+"`authenticated`"+`, which is `+"`true`"+` when the credentials are valid and otherwise `+"`false`"+`. The connection is bound with the credentials of `+"`bind_dn`"+` again afterwards.
+
+If an operation fails then the message will remain unchanged and the error can be caught using error handling methods outlined [here](/docs/configuration/error_handling).`).
+		Field(service.NewStringField("url").
+			Description("The URL of the LDAP server, where the scheme `ldaps` connects with TLS, and the scheme `ldap` connects without it unless `start_tls` is enabled.").
+			Example("ldap://localhost:389").
+			Example("ldaps://ad.example.com")).
+		Field(service.NewBoolField("start_tls").
+			Description("Whether to upgrade connections of the scheme `ldap` to TLS with the StartTLS operation.").
+			Default(false).
+			Advanced()).
+		Field(service.NewTLSToggledField("tls").
+			Description("Custom TLS settings for connections of the scheme `ldaps` or when `start_tls` is enabled.")).
+		Field(service.NewStringField("bind_dn").
+			Description("The distinguished name to bind connections with, such as that of a service account. When empty connections are bound anonymously.").
+			Example("cn=benthos,ou=services,dc=example,dc=com").
+			Default("")).
+		Field(service.NewStringField("bind_password").
+			Description("The password to bind connections with.").
+			Default("")).
+		Field(service.NewStringAnnotatedEnumField("operation", map[string]string{
+			"search": "Search for entries and replace the message with them.",
+			"bind":   "Verify the credentials of `user_dn` and `user_password`.",
+		}).
+			Description("The operation to perform for each message.").
+			Default("search")).
+		Field(service.NewInterpolatedStringField("base_dn").
+			Description("The distinguished name of the entry to search relative to.").
+			Example("ou=users,dc=example,dc=com").
+			Default("")).
+		Field(service.NewStringAnnotatedEnumField("scope", map[string]string{
+			"base": "Search only the entry of `base_dn`.",
+			"one":  "Search the immediate children of the entry of `base_dn`.",
+			"sub":  "Search the entry of `base_dn` and all of its descendants.",
+		}).
+			Description("The scope of searches relative to `base_dn`.").
+			Default("sub")).
+		Field(service.NewStringField("filter").
+			Description("A search filter as specified by RFC 4515, containing question mark (`?`) placeholders that are substituted with the escaped values of `args_mapping`. A literal question mark can be written with the escape sequence `\\3f`. Extensible match filters are not supported.").
+			Example("(&(objectClass=user)(sAMAccountName=?))").
+			Example("(|(mail=?)(userPrincipalName=?))").
+			Default("(objectClass=*)")).
+		Field(service.NewBloblangField("args_mapping").
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) which should evaluate to an array of values matching in size to the number of placeholders in the field `filter`.").
+			Example("root = [ this.user.name ]").
+			Example(`root = [ meta("email"), meta("email") ]`).
+			Optional()).
+		Field(service.NewStringListField("attributes").
+			Description("A list of attributes to return for each entry found. When empty all attributes of entries are returned.").
+			Example([]string{"cn", "mail", "memberOf"}).
+			Default([]string{})).
+		Field(service.NewIntField("size_limit").
+			Description("The maximum number of entries to return for each search, where zero means no limit other than that of the server. When the limit is exceeded the entries found up to the limit are returned.").
+			Default(0).
+			Advanced()).
+		Field(service.NewInterpolatedStringField("user_dn").
+			Description("The distinguished name to verify the credentials of with the `bind` operation.").
+			Example(`${! json("user_dn") }`).
+			Default("")).
+		Field(service.NewInterpolatedStringField("user_password").
+			Description("The password to verify with the `bind` operation.").
+			Example(`${! json("password") }`).
+			Default("")).
+		Field(service.NewIntField("max_connections").
+			Description("The maximum number of connections to the server that can be open at a given time.").
+			Default(4).
+			Advanced()).
+		Field(service.NewDurationField("timeout").
+			Description("The maximum period of time to wait for each operation to complete, including that of opening a connection.").
+			Default("5s").
+			Advanced()).
+		Example("User Enrichment",
+			`
+Here we enrich security events with the groups of the user that triggered them, by searching Active Directory for the user with the account name of the field `+"`user.name`"+`. A `+"[`branch` processor](/docs/components/processors/branch)"+` is used in order to insert the groups of the first entry found into the original message at the path `+"`user.groups`"+`:`,
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - ldap:
+              url: ldaps://ad.example.com
+              bind_dn: cn=benthos,ou=services,dc=example,dc=com
+              bind_password: ${LDAP_PASSWORD}
+              base_dn: ou=users,dc=example,dc=com
+              filter: (&(objectClass=user)(sAMAccountName=?))
+              args_mapping: 'root = [ this.user.name ]'
+              attributes: [ memberOf ]
+        result_map: 'root.user.groups = this.index(0).memberOf | []'
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"ldap", ldapProcConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newLDAPProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type ldapProc struct {
+	pool *connPool
+
+	bindDN       string
+	bindPassword string
+
+	bind         bool
+	baseDN       *service.InterpolatedString
+	scope        searchScope
+	filter       string
+	argsMapping  *bloblang.Executor
+	attributes   []string
+	sizeLimit    int64
+	userDN       *service.InterpolatedString
+	userPassword *service.InterpolatedString
+	timeout      time.Duration
+
+	log *service.Logger
+}
+
+func newLDAPProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*ldapProc, error) {
+	l := &ldapProc{log: mgr.Logger()}
+
+	urlStr, err := conf.FieldString("url")
+	if err != nil {
+		return nil, err
+	}
+	startTLS, err := conf.FieldBool("start_tls")
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled("tls")
+	if err != nil {
+		return nil, err
+	}
+	if !tlsEnabled {
+		tlsConf = nil
+	}
+	if l.bindDN, err = conf.FieldString("bind_dn"); err != nil {
+		return nil, err
+	}
+	if l.bindPassword, err = conf.FieldString("bind_password"); err != nil {
+		return nil, err
+	}
+
+	operation, err := conf.FieldString("operation")
+	if err != nil {
+		return nil, err
+	}
+	switch operation {
+	case "search":
+	case "bind":
+		l.bind = true
+	default:
+		return nil, fmt.Errorf("operation %v is not recognised", operation)
+	}
+
+	if l.baseDN, err = conf.FieldInterpolatedString("base_dn"); err != nil {
+		return nil, err
+	}
+	scopeStr, err := conf.FieldString("scope")
+	if err != nil {
+		return nil, err
+	}
+	switch scopeStr {
+	case "base":
+		l.scope = scopeBaseObject
+	case "one":
+		l.scope = scopeSingleLevel
+	case "sub":
+		l.scope = scopeWholeSubtree
+	default:
+		return nil, fmt.Errorf("scope %v is not recognised", scopeStr)
+	}
+
+	if l.filter, err = conf.FieldString("filter"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("args_mapping") {
+		if l.argsMapping, err = conf.FieldBloblang("args_mapping"); err != nil {
+			return nil, err
+		}
+	} else if _, err := compileFilter(l.filter); err != nil {
+		// Filters without placeholders are validated up front.
+		return nil, err
+	}
+	if l.attributes, err = conf.FieldStringList("attributes"); err != nil {
+		return nil, err
+	}
+	sizeLimit, err := conf.FieldInt("size_limit")
+	if err != nil {
+		return nil, err
+	}
+	l.sizeLimit = int64(sizeLimit)
+
+	if l.userDN, err = conf.FieldInterpolatedString("user_dn"); err != nil {
+		return nil, err
+	}
+	if l.userPassword, err = conf.FieldInterpolatedString("user_password"); err != nil {
+		return nil, err
+	}
+
+	maxConns, err := conf.FieldInt("max_connections")
+	if err != nil {
+		return nil, err
+	}
+	if maxConns < 1 {
+		return nil, errors.New("max_connections must be greater than zero")
+	}
+	if l.timeout, err = conf.FieldDuration("timeout"); err != nil {
+		return nil, err
+	}
+
+	l.pool = newConnPool(maxConns, func(ctx context.Context) (*conn, error) {
+		return l.dial(ctx, urlStr, tlsConf, startTLS)
+	})
+	return l, nil
+}
+
+func (l *ldapProc) dial(ctx context.Context, urlStr string, tlsConf *tls.Config, startTLS bool) (*conn, error) {
+	c, err := dialURL(ctx, urlStr, tlsConf, startTLS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	if l.bindDN != "" || l.bindPassword != "" {
+		if err := c.bind(ctx, l.bindDN, l.bindPassword); err != nil {
+			_ = c.close()
+			return nil, fmt.Errorf("failed to bind: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (l *ldapProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	batch = batch.Copy()
+	for i, msg := range batch {
+		var res any
+		var err error
+		if l.bind {
+			res, err = l.bindCheck(ctx, batch, i)
+		} else {
+			res, err = l.search(ctx, batch, i)
+		}
+		if err != nil {
+			l.log.Debugf("Failed to perform ldap operation: %v", err)
+			msg.SetError(err)
+			continue
+		}
+		msg.SetStructuredMut(res)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+// withConn executes a function with a pooled connection.
+func (l *ldapProc) withConn(ctx context.Context, fn func(ctx context.Context, c *conn) error) error {
+	ctx, done := context.WithTimeout(ctx, l.timeout)
+	defer done()
+
+	c, err := l.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(ctx, c)
+	l.pool.put(c, err)
+	return err
+}
+
+func (l *ldapProc) search(ctx context.Context, batch service.MessageBatch, i int) (any, error) {
+	filter := l.filter
+	if l.argsMapping != nil {
+		resMsg, err := batch.BloblangQuery(i, l.argsMapping)
+		if err != nil {
+			return nil, fmt.Errorf("arguments mapping failed: %w", err)
+		}
+		iargs, err := resMsg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("mapping returned non-structured result: %w", err)
+		}
+		args, ok := iargs.([]any)
+		if !ok {
+			return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+		}
+		if filter, err = replaceFilterPlaceholders(filter, args); err != nil {
+			return nil, err
+		}
+	}
+	filterBytes, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	req := searchRequest{
+		baseDN:     batch.InterpolatedString(i, l.baseDN),
+		scope:      l.scope,
+		sizeLimit:  l.sizeLimit,
+		timeLimit:  int64(l.timeout.Seconds()),
+		filter:     filterBytes,
+		attributes: l.attributes,
+	}
+
+	var entries []searchEntry
+	if err := l.withConn(ctx, func(ctx context.Context, c *conn) (err error) {
+		entries, err = c.search(ctx, req)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	results := make([]any, 0, len(entries))
+	for _, e := range entries {
+		obj := make(map[string]any, len(e.attributes)+1)
+		for _, a := range e.attributes {
+			values := make([]any, 0, len(a.values))
+			for _, v := range a.values {
+				values = append(values, v)
+			}
+			obj[a.name] = values
+		}
+		obj["dn"] = e.dn
+		results = append(results, obj)
+	}
+	return results, nil
+}
+
+func (l *ldapProc) bindCheck(ctx context.Context, batch service.MessageBatch, i int) (any, error) {
+	userDN := batch.InterpolatedString(i, l.userDN)
+	userPassword := batch.InterpolatedString(i, l.userPassword)
+	if userDN == "" || userPassword == "" {
+		// An empty password results in an unauthenticated bind, which servers
+		// often accept regardless of the DN.
+		return nil, errors.New("user_dn and user_password must not be empty")
+	}
+
+	var authenticated bool
+	if err := l.withConn(ctx, func(ctx context.Context, c *conn) error {
+		err := c.bind(ctx, userDN, userPassword)
+		var ldapErr *ldapError
+		if err != nil && !(errors.As(err, &ldapErr) && ldapErr.code == resultInvalidCredentials) {
+			return err
+		}
+		authenticated = err == nil
+
+		// Restore the identity of the connection for subsequent operations,
+		// where the connection is closed rather than pooled if that fails.
+		if err := c.bind(ctx, l.bindDN, l.bindPassword); err != nil {
+			return fmt.Errorf("failed to bind: %v", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return map[string]any{"authenticated": authenticated}, nil
+}
+
+func (l *ldapProc) Close(ctx context.Context) error {
+	l.pool.close()
+	return nil
+}
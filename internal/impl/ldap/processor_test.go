@@ -0,0 +1,237 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+var testEntries = []searchEntry{
+	{dn: "dc=example,dc=com", attributes: []entryAttribute{
+		{name: "objectClass", values: []string{"domain"}},
+	}},
+	{dn: "ou=users,dc=example,dc=com", attributes: []entryAttribute{
+		{name: "objectClass", values: []string{"organizationalUnit"}},
+	}},
+	{dn: "cn=alice,ou=users,dc=example,dc=com", attributes: []entryAttribute{
+		{name: "objectClass", values: []string{"user"}},
+		{name: "sAMAccountName", values: []string{"alice"}},
+		{name: "mail", values: []string{"alice@example.com"}},
+		{name: "memberOf", values: []string{"cn=admins,dc=example,dc=com", "cn=staff,dc=example,dc=com"}},
+	}},
+	{dn: "cn=bob,ou=users,dc=example,dc=com", attributes: []entryAttribute{
+		{name: "objectClass", values: []string{"user"}},
+		{name: "sAMAccountName", values: []string{"bob"}},
+		{name: "mail", values: []string{"bob@example.com"}},
+		{name: "memberOf", values: []string{"cn=staff,dc=example,dc=com"}},
+	}},
+	{dn: "cn=benthos,dc=example,dc=com", attributes: []entryAttribute{
+		{name: "objectClass", values: []string{"service"}},
+	}},
+}
+
+var testPasswords = map[string]string{
+	"cn=benthos,dc=example,dc=com":        "servicepass",
+	"cn=alice,ou=users,dc=example,dc=com": "alicepass",
+}
+
+func testProc(t *testing.T, confStr string, args ...any) *ldapProc {
+	t.Helper()
+
+	conf, err := ldapProcConfig().ParseYAML(fmt.Sprintf(confStr, args...), nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func processStructured(t *testing.T, proc *ldapProc, contents ...string) []any {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], len(contents))
+
+	var results []any
+	for _, msg := range res[0] {
+		if err := msg.GetError(); err != nil {
+			results = append(results, err)
+			continue
+		}
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		results = append(results, v)
+	}
+	return results
+}
+
+func TestLDAPSearch(t *testing.T) {
+	server, url := startTestServer(t, testEntries, testPasswords)
+
+	proc := testProc(t, `
+url: %v
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: servicepass
+base_dn: ou=${! json("ou") },dc=example,dc=com
+filter: (&(objectClass=user)(sAMAccountName=?))
+args_mapping: 'root = [ this.user ]'
+attributes: [ mail, memberOf ]
+`, url)
+
+	results := processStructured(t, proc,
+		`{"ou":"users","user":"alice"}`,
+		`{"ou":"users","user":"bob"}`,
+		`{"ou":"users","user":"*"}`,
+		`{"ou":"nope","user":"alice"}`,
+	)
+	assert.Equal(t, []any{
+		map[string]any{
+			"dn":       "cn=alice,ou=users,dc=example,dc=com",
+			"mail":     []any{"alice@example.com"},
+			"memberOf": []any{"cn=admins,dc=example,dc=com", "cn=staff,dc=example,dc=com"},
+		},
+	}, results[0])
+	assert.Equal(t, []any{
+		map[string]any{
+			"dn":       "cn=bob,ou=users,dc=example,dc=com",
+			"mail":     []any{"bob@example.com"},
+			"memberOf": []any{"cn=staff,dc=example,dc=com"},
+		},
+	}, results[1])
+
+	// Values of the arguments are escaped and therefore match literally.
+	assert.Equal(t, []any{}, results[2])
+
+	require.IsType(t, &ldapError{}, results[3])
+	assert.Equal(t, int64(32), results[3].(*ldapError).code)
+
+	server.mut.Lock()
+	assert.Equal(t, []string{"cn=benthos,dc=example,dc=com"}, server.binds)
+	assert.Equal(t, 1, server.conns)
+	require.Len(t, server.searches, 4)
+	assert.Equal(t, "ou=users,dc=example,dc=com", server.searches[0].baseDN)
+	assert.Equal(t, scopeWholeSubtree, server.searches[0].scope)
+	assert.Equal(t, []string{"mail", "memberOf"}, server.searches[0].attributes)
+	server.mut.Unlock()
+}
+
+func TestLDAPSearchScopeAndLimit(t *testing.T) {
+	_, url := startTestServer(t, testEntries, testPasswords)
+
+	proc := testProc(t, `
+url: %v
+base_dn: dc=example,dc=com
+scope: one
+attributes: [ objectClass ]
+`, url)
+	assert.Equal(t, []any{
+		[]any{
+			map[string]any{"dn": "ou=users,dc=example,dc=com", "objectClass": []any{"organizationalUnit"}},
+			map[string]any{"dn": "cn=benthos,dc=example,dc=com", "objectClass": []any{"service"}},
+		},
+	}, processStructured(t, proc, `{}`))
+
+	proc = testProc(t, `
+url: %v
+base_dn: dc=example,dc=com
+filter: (&(mail=*@example.com)(!(sAMAccountName=bob)))
+attributes: [ sAMAccountName ]
+`, url)
+	assert.Equal(t, []any{
+		[]any{
+			map[string]any{"dn": "cn=alice,ou=users,dc=example,dc=com", "sAMAccountName": []any{"alice"}},
+		},
+	}, processStructured(t, proc, `{}`))
+
+	proc = testProc(t, `
+url: %v
+base_dn: dc=example,dc=com
+filter: objectClass=user
+attributes: [ sAMAccountName ]
+size_limit: 1
+`, url)
+	assert.Equal(t, []any{
+		[]any{
+			map[string]any{"dn": "cn=alice,ou=users,dc=example,dc=com", "sAMAccountName": []any{"alice"}},
+		},
+	}, processStructured(t, proc, `{}`))
+}
+
+func TestLDAPBind(t *testing.T) {
+	server, url := startTestServer(t, testEntries, testPasswords)
+
+	proc := testProc(t, `
+url: %v
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: servicepass
+operation: bind
+user_dn: ${! json("dn") }
+user_password: ${! json("password") }
+max_connections: 1
+`, url)
+
+	results := processStructured(t, proc,
+		`{"dn":"cn=alice,ou=users,dc=example,dc=com","password":"alicepass"}`,
+		`{"dn":"cn=alice,ou=users,dc=example,dc=com","password":"nope"}`,
+		`{"dn":"cn=alice,ou=users,dc=example,dc=com","password":""}`,
+	)
+	assert.Equal(t, map[string]any{"authenticated": true}, results[0])
+	assert.Equal(t, map[string]any{"authenticated": false}, results[1])
+	assert.EqualError(t, results[2].(error), "user_dn and user_password must not be empty")
+
+	// The connection is bound as the service account after each verification.
+	server.mut.Lock()
+	assert.Equal(t, []string{
+		"cn=benthos,dc=example,dc=com",
+		"cn=alice,ou=users,dc=example,dc=com",
+		"cn=benthos,dc=example,dc=com",
+		"cn=alice,ou=users,dc=example,dc=com",
+		"cn=benthos,dc=example,dc=com",
+	}, server.binds)
+	assert.Equal(t, 1, server.conns)
+	server.mut.Unlock()
+}
+
+func TestLDAPBindFailure(t *testing.T) {
+	_, url := startTestServer(t, testEntries, testPasswords)
+
+	proc := testProc(t, `
+url: %v
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: nope
+base_dn: dc=example,dc=com
+`, url)
+
+	results := processStructured(t, proc, `{}`)
+	require.Error(t, results[0].(error))
+	assert.Contains(t, results[0].(error).Error(), "failed to bind")
+}
+
+func TestLDAPConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		`url: ldap://localhost
+filter: (cn=foo`,
+		`url: ldap://localhost
+max_connections: 0`,
+	} {
+		conf, err := ldapProcConfig().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newLDAPProcFromConfig(conf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
@@ -0,0 +1,248 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testServer is an LDAP server of an in-memory directory, which supports the
+// subset of the protocol used by the client.
+type testServer struct {
+	entries   []searchEntry
+	passwords map[string]string
+
+	mut      sync.Mutex
+	conns    int
+	binds    []string
+	searches []searchRequest
+}
+
+func startTestServer(t *testing.T, entries []searchEntry, passwords map[string]string) (*testServer, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := &testServer{entries: entries, passwords: passwords}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns++
+			s.mut.Unlock()
+			go s.serve(nc)
+		}
+	}()
+	return s, "ldap://" + ln.Addr().String()
+}
+
+func (s *testServer) serve(nc net.Conn) {
+	defer nc.Close()
+
+	r := bufio.NewReader(nc)
+	for {
+		packet, err := berReadPacket(r)
+		if err != nil {
+			return
+		}
+		children, err := packet.children()
+		if err != nil || len(children) < 2 {
+			return
+		}
+		id, _ := children[0].int()
+		reply := func(op []byte) {
+			_, _ = nc.Write(berTLV(berSequence, berInt(berInteger, id), op))
+		}
+
+		op := children[1]
+		switch op.tag {
+		case opBindRequest:
+			fields, _ := op.children()
+			dn, password := fields[1].str(), fields[2].str()
+			s.mut.Lock()
+			s.binds = append(s.binds, dn)
+			s.mut.Unlock()
+
+			code := int64(resultSuccess)
+			if dn != "" || password != "" {
+				if expected, exists := s.passwords[dn]; !exists || expected != password {
+					code = resultInvalidCredentials
+				}
+			}
+			reply(testResult(opBindResponse, code))
+		case opSearchRequest:
+			fields, _ := op.children()
+			req := searchRequest{baseDN: fields[0].str()}
+			scope, _ := fields[1].int()
+			req.scope = searchScope(scope)
+			req.sizeLimit, _ = fields[3].int()
+			req.filter = fields[6].contents
+			attrs, _ := fields[7].children()
+			for _, a := range attrs {
+				req.attributes = append(req.attributes, a.str())
+			}
+			s.mut.Lock()
+			s.searches = append(s.searches, req)
+			s.mut.Unlock()
+
+			if !s.hasEntry(req.baseDN) {
+				reply(testResult(opSearchResultDone, 32))
+				continue
+			}
+
+			var found int64
+			code := int64(resultSuccess)
+			for _, e := range s.entries {
+				if !inScope(e.dn, req.baseDN, req.scope) || !matchFilter(e, fields[6]) {
+					continue
+				}
+				if req.sizeLimit > 0 && found == req.sizeLimit {
+					code = resultSizeLimitExceeded
+					break
+				}
+				found++
+				reply(testEntry(e, req.attributes))
+			}
+			reply(testResult(opSearchResultDone, code))
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+	}
+}
+
+func (s *testServer) hasEntry(dn string) bool {
+	for _, e := range s.entries {
+		if strings.EqualFold(e.dn, dn) {
+			return true
+		}
+	}
+	return false
+}
+
+func testResult(tag byte, code int64) []byte {
+	return berTLV(tag,
+		berInt(berEnumerated, code),
+		berString(berOctetString, ""),
+		berString(berOctetString, ""),
+	)
+}
+
+func testEntry(e searchEntry, attributes []string) []byte {
+	var attrs [][]byte
+	for _, a := range e.attributes {
+		if len(attributes) > 0 && !containsFold(attributes, a.name) {
+			continue
+		}
+		var vals [][]byte
+		for _, v := range a.values {
+			vals = append(vals, berString(berOctetString, v))
+		}
+		attrs = append(attrs, berTLV(berSequence,
+			berString(berOctetString, a.name),
+			berTLV(berSet, vals...),
+		))
+	}
+	return berTLV(opSearchResultEntry,
+		berString(berOctetString, e.dn),
+		berTLV(berSequence, attrs...),
+	)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func inScope(dn, base string, scope searchScope) bool {
+	dn, base = strings.ToLower(dn), strings.ToLower(base)
+	switch scope {
+	case scopeBaseObject:
+		return dn == base
+	case scopeSingleLevel:
+		i := strings.IndexByte(dn, ',')
+		return i != -1 && dn[i+1:] == base
+	default:
+		return dn == base || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+func entryValues(e searchEntry, name string) []string {
+	for _, a := range e.attributes {
+		if strings.EqualFold(a.name, name) {
+			return a.values
+		}
+	}
+	return nil
+}
+
+// matchFilter evaluates a filter against an entry, where all matches are case
+// insensitive.
+func matchFilter(e searchEntry, filter berElement) bool {
+	switch filter.tag {
+	case filterAnd, filterOr:
+		children, _ := filter.children()
+		for _, c := range children {
+			if matched := matchFilter(e, c); matched != (filter.tag == filterAnd) {
+				return matched
+			}
+		}
+		return filter.tag == filterAnd
+	case filterNot:
+		children, _ := filter.children()
+		return !matchFilter(e, children[0])
+	case filterPresent:
+		return len(entryValues(e, filter.str())) > 0
+	case filterEqualityMatch:
+		fields, _ := filter.children()
+		for _, v := range entryValues(e, fields[0].str()) {
+			if strings.EqualFold(v, fields[1].str()) {
+				return true
+			}
+		}
+	case filterSubstrings:
+		fields, _ := filter.children()
+		subs, _ := fields[1].children()
+	values:
+		for _, v := range entryValues(e, fields[0].str()) {
+			v = strings.ToLower(v)
+			for _, sub := range subs {
+				s := strings.ToLower(sub.str())
+				switch sub.tag {
+				case substringInitial:
+					if !strings.HasPrefix(v, s) {
+						continue values
+					}
+					v = v[len(s):]
+				case substringFinal:
+					if !strings.HasSuffix(v, s) {
+						continue values
+					}
+					v = v[:len(v)-len(s)]
+				default:
+					i := strings.Index(v, s)
+					if i == -1 {
+						continue values
+					}
+					v = v[i+len(s):]
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package loki
+
+import (
+	"net/http"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lokiFieldTenantID         = "tenant_id"
+	lokiFieldBasicAuth        = "basic_auth"
+	lokiFieldBasicAuthEnabled = "enabled"
+	lokiFieldBasicAuthUser    = "username"
+	lokiFieldBasicAuthPass    = "password"
+	lokiFieldTLS              = "tls"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(lokiFieldTenantID).
+			Description("An optional tenant to make requests as, which is sent as the `X-Scope-OrgID` header.").
+			Optional(),
+		service.NewObjectField(lokiFieldBasicAuth,
+			service.NewBoolField(lokiFieldBasicAuthEnabled).
+				Description("Whether to use basic authentication in requests.").
+				Default(false),
+			service.NewStringField(lokiFieldBasicAuthUser).
+				Description("A username to authenticate as.").
+				Default(""),
+			service.NewStringField(lokiFieldBasicAuthPass).
+				Description("A password to authenticate with.").
+				Default(""),
+		).
+			Description("Allows you to specify basic authentication.").
+			Advanced(),
+		service.NewTLSToggledField(lokiFieldTLS),
+	}
+}
+
+// client makes requests to the HTTP API of Loki with the tenant and
+// credentials of a config.
+type client struct {
+	tenantID  string
+	basicAuth bool
+	username  string
+	password  string
+
+	http *http.Client
+}
+
+func clientFromParsed(conf *service.ParsedConfig) (*client, error) {
+	c := &client{http: &http.Client{}}
+
+	var err error
+	if conf.Contains(lokiFieldTenantID) {
+		if c.tenantID, err = conf.FieldString(lokiFieldTenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	authConf := conf.Namespace(lokiFieldBasicAuth)
+	if c.basicAuth, err = authConf.FieldBool(lokiFieldBasicAuthEnabled); err != nil {
+		return nil, err
+	}
+	if c.username, err = authConf.FieldString(lokiFieldBasicAuthUser); err != nil {
+		return nil, err
+	}
+	if c.password, err = authConf.FieldString(lokiFieldBasicAuthPass); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(lokiFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			c.http.Transport = cloned
+		} else {
+			c.http.Transport = &http.Transport{TLSClientConfig: tlsConf}
+		}
+	}
+	return c, nil
+}
+
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	if c.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", c.tenantID)
+	}
+	if c.basicAuth {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	return c.http.Do(req)
+}
+
+func (c *client) close() {
+	c.http.CloseIdleConnections()
+}
@@ -0,0 +1,336 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lokiInFieldURL   = "url"
+	lokiInFieldQuery = "query"
+	lokiInFieldFrom  = "from"
+	lokiInFieldTo    = "to"
+	lokiInFieldLimit = "limit"
+)
+
+func lokiInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Exports the log lines of a [LogQL](https://grafana.com/docs/loki/latest/logql/) query from [Grafana Loki](https://grafana.com/oss/loki/) within a window of time, creating a message for each line.").
+		Description(`
+The query is executed with the ` + "`query_range`" + ` API in pages of up to ` + "`limit`" + ` lines, starting at the beginning of the window and moving forward in time, where the lines of all streams are emitted in order of their timestamp. Once the lines of the window are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute), which makes it suitable for migrating or reprocessing the logs of a period of time.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- loki_timestamp_unix_nano
+- All labels of the stream of the line
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(lokiInFieldURL).
+			Description("The base URL of the Loki API.").
+			Example("http://localhost:3100")).
+		Field(service.NewStringField(lokiInFieldQuery).
+			Description("A LogQL query that selects log lines.").
+			Example(`{app="foo"}`).
+			Example(`{app="foo"} |= "error"`)).
+		Field(service.NewStringField(lokiInFieldFrom).
+			Description("An [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp of the start of the window, inclusive.").
+			Example("2026-01-02T15:00:00Z")).
+		Field(service.NewStringField(lokiInFieldTo).
+			Description("An optional [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp of the end of the window, exclusive, when omitted the time at which the input starts is used.").
+			Example("2026-01-02T16:00:00Z").
+			Optional()).
+		Field(service.NewIntField(lokiInFieldLimit).
+			Description("The maximum number of lines to request within each page of results, which must not exceed the `max_entries_limit_per_query` of the server.").
+			Advanced().
+			Default(1000))
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Example(
+			"Reprocessing Errors",
+			"In this example the error logs of an app from a one hour window are written to a file as JSON documents.",
+			`
+input:
+  loki:
+    url: http://localhost:3100
+    query: '{app="foo"} |= "error"'
+    from: 2026-01-02T15:00:00Z
+    to: 2026-01-02T16:00:00Z
+
+pipeline:
+  processors:
+    - mapping: |
+        root.line = content().string()
+        root.labels = metadata().without("loki_timestamp_unix_nano")
+        root.timestamp_unix_nano = meta("loki_timestamp_unix_nano").number()
+
+output:
+  file:
+    path: ./errors.jsonl
+    codec: lines
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"loki", lokiInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newLokiInputFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type lokiEntry struct {
+	ts     int64
+	line   string
+	labels map[string]string
+}
+
+type lokiInput struct {
+	url    string
+	query  string
+	limit  int
+	end    int64
+	client *client
+
+	mut   sync.Mutex
+	start int64
+	done  bool
+	page  []lokiEntry
+
+	// The keys of lines that have already been emitted with the timestamp of
+	// start, which are returned again by the next page as the start of a page is
+	// inclusive.
+	seen map[string]struct{}
+}
+
+func newLokiInputFromParsed(conf *service.ParsedConfig) (*lokiInput, error) {
+	l := &lokiInput{seen: map[string]struct{}{}}
+
+	var err error
+	if l.url, err = conf.FieldString(lokiInFieldURL); err != nil {
+		return nil, err
+	}
+	l.url = strings.TrimSuffix(l.url, "/") + "/loki/api/v1/query_range"
+	if l.query, err = conf.FieldString(lokiInFieldQuery); err != nil {
+		return nil, err
+	}
+	if l.limit, err = conf.FieldInt(lokiInFieldLimit); err != nil {
+		return nil, err
+	}
+	if l.limit <= 0 {
+		return nil, errors.New("limit must be greater than zero")
+	}
+
+	fromStr, err := conf.FieldString(lokiInFieldFrom)
+	if err != nil {
+		return nil, err
+	}
+	from, err := time.Parse(time.RFC3339Nano, fromStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse from: %w", err)
+	}
+	l.start = from.UnixNano()
+
+	to := time.Now()
+	if conf.Contains(lokiInFieldTo) {
+		toStr, err := conf.FieldString(lokiInFieldTo)
+		if err != nil {
+			return nil, err
+		}
+		if to, err = time.Parse(time.RFC3339Nano, toStr); err != nil {
+			return nil, fmt.Errorf("failed to parse to: %w", err)
+		}
+	}
+	if !to.After(from) {
+		return nil, errors.New("to must be after from")
+	}
+	l.end = to.UnixNano()
+
+	if l.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lokiInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+type lokiQueryResponse struct {
+	Status string `json:"status"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func entryKey(e lokiEntry) string {
+	return formatLabels(e.labels) + e.line
+}
+
+// fetch obtains the next page of lines, returning false when there are none
+// remaining.
+func (l *lokiInput) fetch(ctx context.Context) (bool, error) {
+	for len(l.page) == 0 {
+		if l.done {
+			return false, nil
+		}
+
+		entries, err := l.queryRange(ctx)
+		if err != nil {
+			return false, err
+		}
+		if len(entries) < l.limit {
+			l.done = true
+		}
+
+		nextStart := l.start
+		for _, e := range entries {
+			if e.ts > nextStart {
+				nextStart = e.ts
+			}
+		}
+		if nextStart == l.start && len(entries) >= l.limit {
+			// Every line of a full page has the same timestamp, and so there is
+			// no way to advance beyond them without skipping lines. The lines
+			// beyond the limit for this timestamp are lost rather than looping
+			// forever.
+			nextStart++
+		}
+
+		for _, e := range entries {
+			key := entryKey(e)
+			if e.ts == l.start {
+				if _, exists := l.seen[key]; exists {
+					continue
+				}
+			}
+			l.page = append(l.page, e)
+		}
+
+		if nextStart != l.start {
+			l.seen = map[string]struct{}{}
+		}
+		for _, e := range entries {
+			if e.ts == nextStart {
+				l.seen[entryKey(e)] = struct{}{}
+			}
+		}
+		l.start = nextStart
+	}
+	return true, nil
+}
+
+func (l *lokiInput) queryRange(ctx context.Context) ([]lokiEntry, error) {
+	query := url.Values{}
+	query.Set("query", l.query)
+	query.Set("start", strconv.FormatInt(l.start, 10))
+	query.Set("end", strconv.FormatInt(l.end, 10))
+	query.Set("limit", strconv.Itoa(l.limit))
+	query.Set("direction", "forward")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url+"?"+query.Encode(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := l.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBytes, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	}
+
+	var qRes lokiQueryResponse
+	if err := json.NewDecoder(res.Body).Decode(&qRes); err != nil {
+		return nil, fmt.Errorf("failed to decode query response: %w", err)
+	}
+	if qRes.Status != "success" {
+		return nil, fmt.Errorf("query resulted in status: %v", qRes.Status)
+	}
+	if qRes.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("query resulted in %v rather than log lines", qRes.Data.ResultType)
+	}
+
+	var entries []lokiEntry
+	for _, s := range qRes.Data.Result {
+		for _, v := range s.Values {
+			ts, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse line timestamp: %w", err)
+			}
+			entries = append(entries, lokiEntry{ts: ts, line: v[1], labels: s.Stream})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ts < entries[j].ts
+	})
+	return entries, nil
+}
+
+func (l *lokiInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	ok, err := l.fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, service.ErrEndOfInput
+	}
+
+	e := l.page[0]
+	l.page = l.page[1:]
+
+	msg := service.NewMessage([]byte(e.line))
+	for k, v := range e.labels {
+		msg.MetaSet(k, v)
+	}
+	msg.MetaSet("loki_timestamp_unix_nano", strconv.FormatInt(e.ts, 10))
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (l *lokiInput) Close(ctx context.Context) error {
+	l.client.close()
+	return nil
+}
@@ -0,0 +1,141 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testQueryRange serves the query_range API from a list of entries, which are
+// returned in streams grouped by their labels.
+func testQueryRange(t *testing.T, entries []lokiEntry) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		assert.Equal(t, `{app="foo"}`, r.URL.Query().Get("query"))
+		assert.Equal(t, "forward", r.URL.Query().Get("direction"))
+		assert.Equal(t, "bar", r.Header.Get("X-Scope-OrgID"))
+
+		start, _ := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		var matched []lokiEntry
+		for _, e := range entries {
+			if e.ts >= start && e.ts < end {
+				matched = append(matched, e)
+			}
+		}
+		sort.SliceStable(matched, func(i, j int) bool {
+			return matched[i].ts < matched[j].ts
+		})
+		if len(matched) > limit {
+			matched = matched[:limit]
+		}
+
+		var res lokiQueryResponse
+		res.Status = "success"
+		res.Data.ResultType = "streams"
+		streams := map[string]int{}
+		for _, e := range matched {
+			key := formatLabels(e.labels)
+			i, exists := streams[key]
+			if !exists {
+				i = len(res.Data.Result)
+				streams[key] = i
+				res.Data.Result = append(res.Data.Result, struct {
+					Stream map[string]string `json:"stream"`
+					Values [][2]string       `json:"values"`
+				}{Stream: e.labels})
+			}
+			res.Data.Result[i].Values = append(res.Data.Result[i].Values, [2]string{strconv.FormatInt(e.ts, 10), e.line})
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+}
+
+func TestLokiInputPages(t *testing.T) {
+	a := map[string]string{"app": "foo", "level": "info"}
+	b := map[string]string{"app": "foo", "level": "error"}
+
+	var entries []lokiEntry
+	for i := 0; i < 7; i++ {
+		labels := a
+		if i%2 == 1 {
+			labels = b
+		}
+		entries = append(entries, lokiEntry{ts: int64(i+1) * 1e9, line: "line " + strconv.Itoa(i), labels: labels})
+	}
+	// Lines that share a timestamp straddle the boundary of a page.
+	entries = append(entries, lokiEntry{ts: 3e9, line: "line 2b", labels: b})
+	// Lines outside of the window.
+	entries = append(entries, lokiEntry{ts: 1e8, line: "too early", labels: a})
+	entries = append(entries, lokiEntry{ts: 9e9, line: "too late", labels: a})
+
+	ts := testQueryRange(t, entries)
+	defer ts.Close()
+
+	conf, err := lokiInputSpec().ParseYAML(`
+url: `+ts.URL+`
+query: '{app="foo"}'
+from: 1970-01-01T00:00:01Z
+to: 1970-01-01T00:00:08Z
+limit: 3
+tenant_id: bar
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newLokiInputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+
+	var lines, levels, timestamps []string
+	for {
+		msg, ackFn, err := in.Read(context.Background())
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, ackFn(context.Background(), nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		lines = append(lines, string(b))
+
+		level, _ := msg.MetaGet("level")
+		levels = append(levels, level)
+
+		ts, _ := msg.MetaGet("loki_timestamp_unix_nano")
+		timestamps = append(timestamps, ts)
+	}
+	require.NoError(t, in.Close(context.Background()))
+
+	assert.Equal(t, []string{"line 0", "line 1", "line 2", "line 2b", "line 3", "line 4", "line 5", "line 6"}, lines)
+	assert.Equal(t, []string{"info", "error", "info", "error", "error", "info", "error", "info"}, levels)
+	assert.Equal(t, "1000000000", timestamps[0])
+	assert.Equal(t, "7000000000", timestamps[7])
+}
+
+func TestLokiInputBadWindow(t *testing.T) {
+	conf, err := lokiInputSpec().ParseYAML(`
+url: http://localhost:3100
+query: '{app="foo"}'
+from: 2026-01-02T16:00:00Z
+to: 2026-01-02T15:00:00Z
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newLokiInputFromParsed(conf)
+	require.Error(t, err)
+}
@@ -0,0 +1,462 @@
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lokiFieldURL            = "url"
+	lokiFieldLabels         = "labels"
+	lokiFieldLine           = "line"
+	lokiFieldTimestamp      = "timestamp"
+	lokiFieldEncoding       = "encoding"
+	lokiFieldLimits         = "limits"
+	lokiFieldMaxLabelNames  = "max_label_names"
+	lokiFieldMaxValueLength = "max_label_value_length"
+	lokiFieldMaxStreams     = "max_streams"
+	lokiFieldBackoff        = "backoff"
+	lokiFieldBatching       = "batching"
+	lokiFieldMaxInFlight    = "max_in_flight"
+)
+
+func lokiOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Pushes log lines to [Grafana Loki](https://grafana.com/oss/loki/).").
+		Description(`
+Each message of a batch is written as a log line to a stream identified by the labels of the message, which are obtained by executing the ` + "`labels`" + ` mapping. Messages that share the same labels are grouped into a single stream within each push, and the entries of each stream are ordered by their timestamp before being sent.
+
+### Labels
+
+The ` + "`labels`" + ` mapping must result in an object where each value is a string, number or boolean. Keys that are not valid label names have any invalid characters replaced with underscores, and labels with empty values are removed. Loki indexes each distinct set of labels as a separate stream, and so labels should be limited to values with a small number of possible variations, such as the name of a service or environment, rather than values such as identifiers or timestamps.
+
+In order to protect Loki from a sudden explosion in the number of streams the output enforces the limits set within ` + "`limits`" + `. Messages with more labels than ` + "`limits.max_label_names`" + ` are rejected, label values longer than ` + "`limits.max_label_value_length`" + ` are truncated, and once ` + "`limits.max_streams`" + ` distinct label sets have been written messages with a label set that has not been seen before are rejected. Rejected messages can be routed elsewhere with a ` + "[`fallback`](/docs/components/outputs/fallback)" + ` output.
+
+### Retries
+
+When Loki responds with a status code of 429, which indicates that an ingestion rate limit has been reached, or a 5XX status code the push is attempted again according to ` + "`backoff`" + `, where the period to wait is at least as long as any ` + "`Retry-After`" + ` header of the response. Any other failed responses result in the batch being rejected immediately.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field ` + "`max_in_flight`" + `.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(lokiFieldURL).
+			Description("The URL of the push API.").
+			Example("http://localhost:3100/loki/api/v1/push")).
+		Field(service.NewBloblangField(lokiFieldLabels).
+			Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of labels for each message.").
+			Example(`root.service = "benthos"`).
+			Example(`root = { "app": meta("app"), "level": this.level.or("info") }`)).
+		Field(service.NewInterpolatedStringField(lokiFieldLine).
+			Description("The log line of each message.").
+			Example(`${! this.message }`).
+			Example(`level=${! this.level } msg=${! this.message.quote() }`).
+			Default("${! content() }")).
+		Field(service.NewInterpolatedStringField(lokiFieldTimestamp).
+			Description("An optional timestamp of each log line, either as a unix timestamp in seconds or an [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, when omitted the time at which the line is written is used.").
+			Example(`${! this.ts }`).
+			Example(`${! meta("kafka_timestamp_unix") }`).
+			Optional())
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringAnnotatedEnumField(lokiFieldEncoding, map[string]string{
+			"protobuf": "Snappy compressed protobuf, which is the most efficient encoding.",
+			"json":     "Uncompressed JSON.",
+		}).
+			Description("The encoding of push requests.").
+			Advanced().
+			Default("protobuf")).
+		Field(service.NewObjectField(lokiFieldLimits,
+			service.NewIntField(lokiFieldMaxLabelNames).
+				Description("The maximum number of labels of a message, messages with more labels are rejected.").
+				Default(15),
+			service.NewIntField(lokiFieldMaxValueLength).
+				Description("The maximum length of a label value, longer values are truncated.").
+				Default(2048),
+			service.NewIntField(lokiFieldMaxStreams).
+				Description("The maximum number of distinct label sets to write, once reached messages with a label set that has not been written before are rejected. Set to `0` to disable this limit.").
+				Default(1000),
+		).
+			Description("Limits that guard against an excessive number of streams, see [labels](#labels).").
+			Advanced()).
+		Field(service.NewBackOffField(lokiFieldBackoff, false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Second,
+			MaxInterval:     time.Second * 30,
+			MaxElapsedTime:  time.Minute * 2,
+		}).
+			Advanced()).
+		Field(service.NewBatchPolicyField(lokiFieldBatching)).
+		Field(service.NewIntField(lokiFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Structured Logs",
+			"In this example JSON logs are pushed to streams labelled by their service and level, with the remaining fields formatted as the log line.",
+			`
+output:
+  loki:
+    url: http://localhost:3100/loki/api/v1/push
+    labels: |
+      root.service = this.service
+      root.level = this.level.or("info")
+    line: ${! this.without("service", "level").string() }
+    timestamp: ${! this.ts }
+    batching:
+      count: 1000
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"loki", lokiOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(lokiFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(lokiFieldBatching); err != nil {
+				return
+			}
+			out, err = newLokiOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type lokiOutput struct {
+	log *service.Logger
+
+	url      string
+	protobuf bool
+
+	labels    *bloblang.Executor
+	line      *service.InterpolatedString
+	timestamp *service.InterpolatedString
+
+	maxLabelNames  int
+	maxValueLength int
+	maxStreams     int
+
+	streamsMut sync.Mutex
+	streams    map[string]struct{}
+
+	backoffCtor func() backoff.BackOff
+	client      *client
+}
+
+func newLokiOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*lokiOutput, error) {
+	l := &lokiOutput{
+		log:     mgr.Logger(),
+		streams: map[string]struct{}{},
+	}
+
+	var err error
+	if l.url, err = conf.FieldString(lokiFieldURL); err != nil {
+		return nil, err
+	}
+	if l.labels, err = conf.FieldBloblang(lokiFieldLabels); err != nil {
+		return nil, err
+	}
+	if l.line, err = conf.FieldInterpolatedString(lokiFieldLine); err != nil {
+		return nil, err
+	}
+	if conf.Contains(lokiFieldTimestamp) {
+		if l.timestamp, err = conf.FieldInterpolatedString(lokiFieldTimestamp); err != nil {
+			return nil, err
+		}
+	}
+	encoding, err := conf.FieldString(lokiFieldEncoding)
+	if err != nil {
+		return nil, err
+	}
+	switch encoding {
+	case "protobuf":
+		l.protobuf = true
+	case "json":
+	default:
+		return nil, fmt.Errorf("encoding not recognised: %v", encoding)
+	}
+
+	limitsConf := conf.Namespace(lokiFieldLimits)
+	if l.maxLabelNames, err = limitsConf.FieldInt(lokiFieldMaxLabelNames); err != nil {
+		return nil, err
+	}
+	if l.maxValueLength, err = limitsConf.FieldInt(lokiFieldMaxValueLength); err != nil {
+		return nil, err
+	}
+	if l.maxStreams, err = limitsConf.FieldInt(lokiFieldMaxStreams); err != nil {
+		return nil, err
+	}
+
+	boff, err := conf.FieldBackOff(lokiFieldBackoff)
+	if err != nil {
+		return nil, err
+	}
+	l.backoffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+
+	if l.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lokiOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// sanitiseLabelName replaces characters that are not permitted within a label
+// name with underscores.
+func sanitiseLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func (l *lokiOutput) labelsFor(batch service.MessageBatch, i int) (map[string]string, error) {
+	res, err := batch.BloblangQuery(i, l.labels)
+	if err != nil {
+		return nil, fmt.Errorf("labels mapping failed: %w", err)
+	}
+	if res == nil {
+		return nil, errors.New("labels mapping resulted in a deleted message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("labels mapping failed: %w", err)
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("labels mapping resulted in a non-object type: %T", v)
+	}
+
+	labels := make(map[string]string, len(obj))
+	for k, v := range obj {
+		var str string
+		switch t := v.(type) {
+		case string:
+			str = t
+		case bool, int, int64, uint64, float64, json.Number:
+			str = fmt.Sprintf("%v", t)
+		case nil:
+		default:
+			return nil, fmt.Errorf("label %v resulted in a non-scalar type: %T", k, v)
+		}
+		if str == "" {
+			continue
+		}
+		if len(str) > l.maxValueLength {
+			str = str[:l.maxValueLength]
+		}
+		labels[sanitiseLabelName(k)] = str
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("labels mapping resulted in no labels")
+	}
+	if len(labels) > l.maxLabelNames {
+		return nil, fmt.Errorf("labels mapping resulted in %v labels, exceeding the limit of %v", len(labels), l.maxLabelNames)
+	}
+	return labels, nil
+}
+
+func (l *lokiOutput) timestampFor(msg *service.Message, now time.Time) (time.Time, error) {
+	if l.timestamp == nil {
+		return now, nil
+	}
+	tsStr := l.timestamp.String(msg)
+	if secs, err := strconv.ParseFloat(tsStr, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, tsStr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse timestamp: %w", err)
+	}
+	return t, nil
+}
+
+// admitStream returns whether a label set may be written to without exceeding
+// the maximum number of streams.
+func (l *lokiOutput) admitStream(key string) bool {
+	if l.maxStreams <= 0 {
+		return true
+	}
+	l.streamsMut.Lock()
+	defer l.streamsMut.Unlock()
+
+	if _, exists := l.streams[key]; exists {
+		return true
+	}
+	if len(l.streams) >= l.maxStreams {
+		return false
+	}
+	l.streams[key] = struct{}{}
+	return true
+}
+
+func (l *lokiOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	now := time.Now()
+	req := newPushRequest()
+	written := 0
+	for i, msg := range batch {
+		labels, err := l.labelsFor(batch, i)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		if key := formatLabels(labels); !l.admitStream(key) {
+			failed(i, fmt.Errorf("stream %v exceeds the limit of %v streams", key, l.maxStreams))
+			continue
+		}
+		ts, err := l.timestampFor(msg, now)
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		req.add(labels, entry{ts: ts, line: l.line.String(msg)})
+		written++
+	}
+
+	if written > 0 {
+		req.sortEntries()
+		if err := l.push(ctx, req); err != nil {
+			if batchErr == nil {
+				return err
+			}
+			for i := range batch {
+				batchErr.Failed(i, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// errRetryable is an error from a push that may succeed when attempted again
+// after at least a given period.
+type errRetryable struct {
+	err   error
+	after time.Duration
+}
+
+func (e *errRetryable) Error() string {
+	return e.err.Error()
+}
+
+func (l *lokiOutput) push(ctx context.Context, req *pushRequest) error {
+	var body []byte
+	contentType := "application/x-protobuf"
+	if l.protobuf {
+		body = req.encodeProtobuf()
+	} else {
+		var err error
+		if body, err = req.encodeJSON(); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	boff := l.backoffCtor()
+	for {
+		err := l.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+
+		var rErr *errRetryable
+		if !errors.As(err, &rErr) {
+			return err
+		}
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		if wait < rErr.after {
+			wait = rErr.after
+		}
+		l.log.Warnf("Retrying push in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *lokiOutput) post(ctx context.Context, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := l.client.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	resBytes, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		rErr := &errRetryable{err: err}
+		if secs, pErr := strconv.Atoi(res.Header.Get("Retry-After")); pErr == nil {
+			rErr.after = time.Duration(secs) * time.Second
+		}
+		return rErr
+	}
+	return err
+}
+
+func (l *lokiOutput) Close(ctx context.Context) error {
+	l.client.close()
+	return nil
+}
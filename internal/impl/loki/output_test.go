@@ -0,0 +1,259 @@
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testServer struct {
+	mut       sync.Mutex
+	requests  [][]byte
+	headers   []http.Header
+	rateLimit int
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.rateLimit > 0 {
+		s.rateLimit--
+		http.Error(w, "ingestion rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, b)
+	s.headers = append(s.headers, r.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func testOutput(t *testing.T, ts *httptest.Server, extra string) *lokiOutput {
+	t.Helper()
+
+	conf, err := lokiOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+labels: 'root = { "app": this.app, "level": this.level }'
+line: ${! this.msg }
+timestamp: ${! this.ts }
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`+extra, nil)
+	require.NoError(t, err)
+
+	l, err := newLokiOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, l.Connect(context.Background()))
+	return l
+}
+
+func testBatch(docs ...string) (b service.MessageBatch) {
+	for _, d := range docs {
+		b = append(b, service.NewMessage([]byte(d)))
+	}
+	return
+}
+
+func failedIndexes(t *testing.T, err error) (failed []int) {
+	t.Helper()
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	return
+}
+
+func TestLokiOutputJSON(t *testing.T) {
+	srv := &testServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	l := testOutput(t, ts, `
+encoding: json
+tenant_id: foo
+`)
+
+	require.NoError(t, l.WriteBatch(context.Background(), testBatch(
+		`{"app":"a","level":"info","msg":"third","ts":30}`,
+		`{"app":"b","level":"info","msg":"other","ts":5}`,
+		`{"app":"a","level":"info","msg":"first","ts":10}`,
+		`{"app":"a","level":"info","msg":"second","ts":"1970-01-01T00:00:20Z"}`,
+	)))
+	require.NoError(t, l.Close(context.Background()))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.requests, 1)
+	assert.Equal(t, "foo", srv.headers[0].Get("X-Scope-OrgID"))
+	assert.Equal(t, "application/json", srv.headers[0].Get("Content-Type"))
+	assert.JSONEq(t, `{"streams":[
+	{"stream":{"app":"a","level":"info"},"values":[["10000000000","first"],["20000000000","second"],["30000000000","third"]]},
+	{"stream":{"app":"b","level":"info"},"values":[["5000000000","other"]]}
+]}`, string(srv.requests[0]))
+}
+
+func TestLokiOutputProtobuf(t *testing.T) {
+	srv := &testServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	l := testOutput(t, ts, "")
+
+	require.NoError(t, l.WriteBatch(context.Background(), testBatch(
+		`{"app":"a","level":"info","msg":"hello","ts":10.5}`,
+	)))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.requests, 1)
+	assert.Equal(t, "application/x-protobuf", srv.headers[0].Get("Content-Type"))
+
+	raw, err := snappy.Decode(nil, srv.requests[0])
+	require.NoError(t, err)
+
+	// Unwrap the single stream and entry of the request.
+	field := func(b []byte, expNum protowire.Number) ([]byte, []byte) {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		require.Equal(t, expNum, num)
+		require.Equal(t, protowire.BytesType, typ)
+		v, m := protowire.ConsumeBytes(b[n:])
+		require.GreaterOrEqual(t, m, 0)
+		return v, b[n+m:]
+	}
+
+	streamBytes, rest := field(raw, 1)
+	assert.Empty(t, rest)
+
+	labels, rest := field(streamBytes, 1)
+	assert.Equal(t, `{app="a", level="info"}`, string(labels))
+
+	entryBytes, rest := field(rest, 2)
+	assert.Empty(t, rest)
+
+	tsBytes, rest := field(entryBytes, 1)
+	line, rest := field(rest, 2)
+	assert.Empty(t, rest)
+	assert.Equal(t, "hello", string(line))
+
+	num, _, n := protowire.ConsumeTag(tsBytes)
+	require.Equal(t, protowire.Number(1), num)
+	secs, m := protowire.ConsumeVarint(tsBytes[n:])
+	tsBytes = tsBytes[n+m:]
+	assert.Equal(t, uint64(10), secs)
+
+	num, _, n = protowire.ConsumeTag(tsBytes)
+	require.Equal(t, protowire.Number(2), num)
+	nanos, _ := protowire.ConsumeVarint(tsBytes[n:])
+	assert.Equal(t, uint64(time.Second/2), nanos)
+}
+
+func TestLokiOutputLabelLimits(t *testing.T) {
+	srv := &testServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf, err := lokiOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+encoding: json
+labels: 'root = this.labels'
+limits:
+  max_label_names: 2
+  max_label_value_length: 3
+  max_streams: 2
+`, nil)
+	require.NoError(t, err)
+
+	l, err := newLokiOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = l.WriteBatch(context.Background(), testBatch(
+		`{"labels":{"app":"foobar","1st-label":"x","empty":""}}`,
+		`{"labels":{"a":"1","b":"2","c":"3"}}`,
+		`{"labels":{"app":"bar"}}`,
+		`{"labels":{"app":"baz"}}`,
+		`{"labels":{"app":"foo","_st_label":"x"}}`,
+		`{"labels":{"app":{"nested":true}}}`,
+		`{"labels":{}}`,
+		`"not an object"`,
+	))
+	assert.Equal(t, []int{1, 3, 5, 6, 7}, failedIndexes(t, err))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.requests, 1)
+
+	var req jsonPushRequest
+	require.NoError(t, json.Unmarshal(srv.requests[0], &req))
+
+	var streams []map[string]string
+	for _, s := range req.Streams {
+		streams = append(streams, s.Stream)
+	}
+	assert.Equal(t, []map[string]string{
+		{"app": "foo", "_st_label": "x"},
+		{"app": "bar"},
+	}, streams)
+}
+
+func TestLokiOutputRetries(t *testing.T) {
+	srv := &testServer{rateLimit: 2}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	l := testOutput(t, ts, "encoding: json")
+
+	require.NoError(t, l.WriteBatch(context.Background(), testBatch(
+		`{"app":"a","level":"info","msg":"hello","ts":10}`,
+	)))
+
+	srv.mut.Lock()
+	assert.Len(t, srv.requests, 1)
+	srv.rateLimit = 1000
+	srv.mut.Unlock()
+
+	// Retries are abandoned once the backoff elapses.
+	conf, err := lokiOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+labels: 'root.app = "a"'
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 20ms
+`, nil)
+	require.NoError(t, err)
+
+	l, err = newLokiOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = l.WriteBatch(context.Background(), testBatch(`hello`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
@@ -0,0 +1,143 @@
+package loki
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+// stream is a set of entries that share the same labels.
+type stream struct {
+	labels  map[string]string
+	key     string
+	entries []entry
+}
+
+// pushRequest groups entries by their labels in the order in which each stream
+// was first seen.
+type pushRequest struct {
+	streams []*stream
+	byKey   map[string]*stream
+}
+
+func newPushRequest() *pushRequest {
+	return &pushRequest{byKey: map[string]*stream{}}
+}
+
+func (p *pushRequest) add(labels map[string]string, e entry) {
+	key := formatLabels(labels)
+	s, exists := p.byKey[key]
+	if !exists {
+		s = &stream{labels: labels, key: key}
+		p.byKey[key] = s
+		p.streams = append(p.streams, s)
+	}
+	s.entries = append(s.entries, e)
+}
+
+// sortEntries orders the entries of each stream by their timestamp, as entries
+// of a stream are rejected when they are older than the most recent entry
+// written.
+func (p *pushRequest) sortEntries() {
+	for _, s := range p.streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].ts.Before(s.entries[j].ts)
+		})
+	}
+}
+
+// formatLabels returns labels in the form {key="value", ...} sorted by key,
+// which is how streams are identified by the push API.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+// encodeJSON returns the JSON form of a push request.
+func (p *pushRequest) encodeJSON() ([]byte, error) {
+	req := jsonPushRequest{Streams: make([]jsonStream, 0, len(p.streams))}
+	for _, s := range p.streams {
+		js := jsonStream{
+			Stream: s.labels,
+			Values: make([][2]string, 0, len(s.entries)),
+		}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
+
+// encodeProtobuf returns the snappy compressed protobuf form of a push request,
+// which is described by the logproto.PushRequest message of Loki:
+//
+//	message PushRequest { repeated Stream streams = 1; }
+//	message Stream { string labels = 1; repeated Entry entries = 2; }
+//	message Entry { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func (p *pushRequest) encodeProtobuf() []byte {
+	var req []byte
+	for _, s := range p.streams {
+		var sBytes []byte
+		sBytes = protowire.AppendTag(sBytes, 1, protowire.BytesType)
+		sBytes = protowire.AppendString(sBytes, s.key)
+		for _, e := range s.entries {
+			var ts []byte
+			if secs := e.ts.Unix(); secs != 0 {
+				ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+				ts = protowire.AppendVarint(ts, uint64(secs))
+			}
+			if nanos := e.ts.Nanosecond(); nanos != 0 {
+				ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+				ts = protowire.AppendVarint(ts, uint64(nanos))
+			}
+
+			var eBytes []byte
+			eBytes = protowire.AppendTag(eBytes, 1, protowire.BytesType)
+			eBytes = protowire.AppendBytes(eBytes, ts)
+			eBytes = protowire.AppendTag(eBytes, 2, protowire.BytesType)
+			eBytes = protowire.AppendString(eBytes, e.line)
+
+			sBytes = protowire.AppendTag(sBytes, 2, protowire.BytesType)
+			sBytes = protowire.AppendBytes(sBytes, eBytes)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, sBytes)
+	}
+	return snappy.Encode(nil, req)
+}
@@ -152,6 +152,40 @@ func (m *memcachedCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 }
 
+func (m *memcachedCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := m.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		m.boffPool.Put(boff)
+	}()
+
+	prefixedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		prefixedKeys[i] = m.prefix + k
+	}
+
+	for {
+		items, err := m.mc.GetMulti(prefixedKeys)
+		if err == nil {
+			values := make(map[string][]byte, len(items))
+			for k, item := range items {
+				values[strings.TrimPrefix(k, m.prefix)] = item.Value
+			}
+			return values, nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
 func (m *memcachedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := m.boffPool.Get().(backoff.BackOff)
 	defer func() {
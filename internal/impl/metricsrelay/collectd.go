@@ -0,0 +1,381 @@
+package metricsrelay
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	cdFieldSecurityLevel = "security_level"
+	cdFieldUsers         = "users"
+)
+
+func collectdInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Receives metrics sent with the binary network protocol of collectd over UDP, where each value is consumed as a JSON message.").
+		Description(`
+Each value is consumed as a metric named after its plugin, type and their instances, in the form `+"`<plugin>[.<plugin_instance>].<type>[.<type_instance>]`"+`, which is suffixed with the index of the value for types that have multiple values, such as `+"`interface.eth0.if_octets.0`"+`. The host, plugin and type are added as the tags `+"`host`, `plugin`, `plugin_instance`, `type` and `type_instance`"+`, where the instances are only added when they're set, and the data source type of the value as the tag `+"`ds_type`"+`.
+
+Values of the data source type `+"`gauge`"+` are consumed with the type `+"`gauge`"+`, and values of the types `+"`counter`, `derive` and `absolute`"+` with the type `+"`counter`"+`. Gauges that are not a number, which collectd sends for unknown values, are dropped, as are notifications.
+
+`+metricFormatDocs+`
+
+### Security
+
+Signed and encrypted packets are verified and decrypted with the passwords of their users, which are set with the field `+"`users`"+`. The field `+"`security_level`"+` sets the level of security that values must have in order to be consumed, where the level `+"`sign`"+` accepts signed and encrypted values. With the level `+"`none`"+` signed packets of unknown users are consumed without being verified, and encrypted packets of unknown users are dropped.
+
+Packets that cannot be parsed, or that fail to be verified, are logged and dropped. The values of each datagram are consumed as a batch.
+
+`+metricMetadataDocs("collectd")).
+		Field(listenerAddressField("0.0.0.0:25826")).
+		Field(service.NewStringEnumField(cdFieldSecurityLevel, "none", "sign", "encrypt").
+			Description("The minimum level of security of the values to consume.").
+			Default("none")).
+		Field(service.NewStringMapField(cdFieldUsers).
+			Description("The passwords of users that sign or encrypt packets, keyed by their usernames.").
+			Example(map[string]any{"collectd": "${COLLECTD_PASSWORD}"}).
+			Default(map[string]any{})).
+		Example(
+			"Store in Elasticsearch",
+			"Receive the values of collectd agents that encrypt their packets and index them into Elasticsearch.",
+			`
+input:
+  collectd:
+    address: 0.0.0.0:25826
+    security_level: encrypt
+    users:
+      collectd: ${COLLECTD_PASSWORD}
+
+output:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: metrics
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"collectd", collectdInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			p, err := newCollectdParserFromParsed(conf)
+			if err != nil {
+				return nil, err
+			}
+			return newMetricListenerFromParsed("collectd", conf, p.parse, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// The parts of the collectd network protocol,
+// https://collectd.org/wiki/index.php/Binary_protocol
+const (
+	cdPartHost           = 0x0000
+	cdPartTime           = 0x0001
+	cdPartPlugin         = 0x0002
+	cdPartPluginInstance = 0x0003
+	cdPartType           = 0x0004
+	cdPartTypeInstance   = 0x0005
+	cdPartValues         = 0x0006
+	cdPartInterval       = 0x0007
+	cdPartTimeHR         = 0x0008
+	cdPartIntervalHR     = 0x0009
+	cdPartSignature      = 0x0200
+	cdPartEncryption     = 0x0210
+)
+
+// The data source types of values.
+const (
+	cdTypeCounter  = 0
+	cdTypeGauge    = 1
+	cdTypeDerive   = 2
+	cdTypeAbsolute = 3
+)
+
+type cdSecurityLevel int
+
+const (
+	cdSecurityNone cdSecurityLevel = iota
+	cdSecuritySign
+	cdSecurityEncrypt
+)
+
+type collectdParser struct {
+	level cdSecurityLevel
+	users map[string]string
+}
+
+func newCollectdParserFromParsed(conf *service.ParsedConfig) (*collectdParser, error) {
+	p := &collectdParser{}
+
+	level, err := conf.FieldString(cdFieldSecurityLevel)
+	if err != nil {
+		return nil, err
+	}
+	switch level {
+	case "none":
+		p.level = cdSecurityNone
+	case "sign":
+		p.level = cdSecuritySign
+	case "encrypt":
+		p.level = cdSecurityEncrypt
+	default:
+		return nil, fmt.Errorf("security level '%v' is not supported", level)
+	}
+
+	if p.users, err = conf.FieldStringMap(cdFieldUsers); err != nil {
+		return nil, err
+	}
+	if p.level != cdSecurityNone && len(p.users) == 0 {
+		return nil, fmt.Errorf("at least one user must be set for the security level '%v'", level)
+	}
+	return p, nil
+}
+
+// collectdState is the state of the parts of a packet, which apply to all of
+// the values that follow them.
+type collectdState struct {
+	host, plugin, pluginInstance, typ, typeInstance string
+
+	timestamp time.Time
+	interval  time.Duration
+
+	// Whether values have been dropped due to having insufficient security.
+	insecure bool
+}
+
+func (p *collectdParser) parse(b []byte, now time.Time) (metrics []metric, errs []error) {
+	st := collectdState{timestamp: now}
+	if err := p.parseParts(b, cdSecurityNone, &st, &metrics); err != nil {
+		errs = append(errs, err)
+	}
+	if st.insecure {
+		errs = append(errs, errors.New("dropped values with a level of security below the minimum"))
+	}
+	return
+}
+
+func (p *collectdParser) parseParts(b []byte, sec cdSecurityLevel, st *collectdState, metrics *[]metric) error {
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return errors.New("truncated part header")
+		}
+		typ, length := binary.BigEndian.Uint16(b), int(binary.BigEndian.Uint16(b[2:]))
+		if length < 4 || length > len(b) {
+			return fmt.Errorf("part 0x%04x has invalid length %v", typ, length)
+		}
+		body, rest := b[4:length], b[length:]
+		b = rest
+
+		var err error
+		switch typ {
+		case cdPartHost:
+			st.host, err = cdString(body)
+		case cdPartPlugin:
+			st.plugin, err = cdString(body)
+		case cdPartPluginInstance:
+			st.pluginInstance, err = cdString(body)
+		case cdPartType:
+			st.typ, err = cdString(body)
+		case cdPartTypeInstance:
+			st.typeInstance, err = cdString(body)
+		case cdPartTime, cdPartTimeHR:
+			var v uint64
+			if v, err = cdNumber(body); err == nil {
+				st.timestamp = time.Unix(0, int64(cdDuration(typ == cdPartTimeHR, v)))
+			}
+		case cdPartInterval, cdPartIntervalHR:
+			var v uint64
+			if v, err = cdNumber(body); err == nil {
+				st.interval = cdDuration(typ == cdPartIntervalHR, v)
+			}
+		case cdPartValues:
+			if sec < p.level {
+				st.insecure = true
+				continue
+			}
+			err = st.values(body, metrics)
+		case cdPartSignature:
+			var verified bool
+			if verified, err = p.verify(body, rest); err == nil && verified && sec < cdSecuritySign {
+				sec = cdSecuritySign
+			}
+		case cdPartEncryption:
+			var plain []byte
+			if plain, err = p.decrypt(body); err == nil {
+				err = p.parseParts(plain, cdSecurityEncrypt, st, metrics)
+			}
+		}
+		// Other parts, such as those of notifications, are ignored.
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verify checks the signature of the rest of a packet, returning whether it
+// was verified, which it's not when the user is unknown and unsigned values
+// are accepted.
+func (p *collectdParser) verify(body, rest []byte) (bool, error) {
+	if len(body) < sha256.Size {
+		return false, errors.New("truncated signature")
+	}
+	mac, user := body[:sha256.Size], body[sha256.Size:]
+
+	password, exists := p.users[string(user)]
+	if !exists {
+		if p.level == cdSecurityNone {
+			return false, nil
+		}
+		return false, fmt.Errorf("packet signed by unknown user '%s'", user)
+	}
+
+	h := hmac.New(sha256.New, []byte(password))
+	_, _ = h.Write(user)
+	_, _ = h.Write(rest)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return false, fmt.Errorf("packet signed by user '%s' has an invalid signature", user)
+	}
+	return true, nil
+}
+
+// decrypt decrypts the parts of an encryption part, which are encrypted with
+// AES-256 in OFB mode and prefixed with their SHA-1 checksum.
+func (p *collectdParser) decrypt(body []byte) ([]byte, error) {
+	if len(body) < 2 {
+		return nil, errors.New("truncated encryption part")
+	}
+	userLen := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+userLen+aes.BlockSize+sha1.Size {
+		return nil, errors.New("truncated encryption part")
+	}
+	user := body[2 : 2+userLen]
+	iv := body[2+userLen : 2+userLen+aes.BlockSize]
+	encrypted := body[2+userLen+aes.BlockSize:]
+
+	password, exists := p.users[string(user)]
+	if !exists {
+		return nil, fmt.Errorf("packet encrypted by unknown user '%s'", user)
+	}
+
+	key := sha256.Sum256([]byte(password))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(encrypted))
+	cipher.NewOFB(block, iv).XORKeyStream(plain, encrypted)
+
+	if sum := sha1.Sum(plain[sha1.Size:]); !bytes.Equal(sum[:], plain[:sha1.Size]) {
+		return nil, fmt.Errorf("packet encrypted by user '%s' failed to decrypt", user)
+	}
+	return plain[sha1.Size:], nil
+}
+
+func (st *collectdState) values(body []byte, metrics *[]metric) error {
+	if len(body) < 2 {
+		return errors.New("truncated values part")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) != 2+n*9 {
+		return fmt.Errorf("values part of %v values has invalid length %v", n, len(body)+4)
+	}
+	types, values := body[2:2+n], body[2+n:]
+
+	name := st.plugin
+	if st.pluginInstance != "" {
+		name += "." + st.pluginInstance
+	}
+	name += "." + st.typ
+	if st.typeInstance != "" {
+		name += "." + st.typeInstance
+	}
+
+	for i, dsType := range types {
+		raw := values[i*8 : i*8+8]
+
+		m := metric{
+			name:      name,
+			typ:       "counter",
+			timestamp: st.timestamp,
+			interval:  st.interval,
+		}
+		if n > 1 {
+			m.name += "." + strconv.Itoa(i)
+		}
+
+		var ds string
+		switch dsType {
+		case cdTypeCounter:
+			ds, m.value = "counter", binary.BigEndian.Uint64(raw)
+		case cdTypeGauge:
+			f := math.Float64frombits(binary.LittleEndian.Uint64(raw))
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				continue
+			}
+			ds, m.value, m.typ = "gauge", f, "gauge"
+		case cdTypeDerive:
+			ds, m.value = "derive", int64(binary.BigEndian.Uint64(raw))
+		case cdTypeAbsolute:
+			ds, m.value = "absolute", binary.BigEndian.Uint64(raw)
+		default:
+			return fmt.Errorf("data source type %v is not supported", dsType)
+		}
+
+		m.tags = map[string]string{
+			"host":    st.host,
+			"plugin":  st.plugin,
+			"type":    st.typ,
+			"ds_type": ds,
+		}
+		if st.pluginInstance != "" {
+			m.tags["plugin_instance"] = st.pluginInstance
+		}
+		if st.typeInstance != "" {
+			m.tags["type_instance"] = st.typeInstance
+		}
+		*metrics = append(*metrics, m)
+	}
+	return nil
+}
+
+func cdString(body []byte) (string, error) {
+	if len(body) == 0 || body[len(body)-1] != 0 {
+		return "", errors.New("string part is not null terminated")
+	}
+	return string(body[:len(body)-1]), nil
+}
+
+func cdNumber(body []byte) (uint64, error) {
+	if len(body) != 8 {
+		return 0, fmt.Errorf("numeric part has invalid length %v", len(body)+4)
+	}
+	return binary.BigEndian.Uint64(body), nil
+}
+
+// cdDuration converts a time or interval in seconds, or in units of 2^-30
+// seconds for their high resolution parts, to a duration.
+func cdDuration(highRes bool, v uint64) time.Duration {
+	if !highRes {
+		return time.Duration(v) * time.Second
+	}
+	return time.Duration((v>>30)*uint64(time.Second)) + time.Duration(((v&(1<<30-1))*uint64(time.Second))>>30)
+}
@@ -0,0 +1,239 @@
+package metricsrelay
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cdTestPart(typ uint16, body []byte) []byte {
+	b := make([]byte, 4, 4+len(body))
+	binary.BigEndian.PutUint16(b, typ)
+	binary.BigEndian.PutUint16(b[2:], uint16(4+len(body)))
+	return append(b, body...)
+}
+
+func cdTestString(typ uint16, s string) []byte {
+	return cdTestPart(typ, append([]byte(s), 0))
+}
+
+func cdTestNumber(typ uint16, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return cdTestPart(typ, b)
+}
+
+type cdTestValue struct {
+	dsType byte
+	raw    uint64
+}
+
+func cdTestValues(values ...cdTestValue) []byte {
+	b := make([]byte, 2, 2+len(values)*9)
+	binary.BigEndian.PutUint16(b, uint16(len(values)))
+	for _, v := range values {
+		b = append(b, v.dsType)
+	}
+	for _, v := range values {
+		raw := make([]byte, 8)
+		if v.dsType == cdTypeGauge {
+			binary.LittleEndian.PutUint64(raw, v.raw)
+		} else {
+			binary.BigEndian.PutUint64(raw, v.raw)
+		}
+		b = append(b, raw...)
+	}
+	return cdTestPart(cdPartValues, b)
+}
+
+func cdTestPacket(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func cdTestSign(user, password string, packet []byte) []byte {
+	h := hmac.New(sha256.New, []byte(password))
+	_, _ = h.Write([]byte(user))
+	_, _ = h.Write(packet)
+	sig := cdTestPart(cdPartSignature, append(h.Sum(nil), user...))
+	return append(sig, packet...)
+}
+
+func cdTestEncrypt(user, password string, packet []byte) []byte {
+	sum := sha1.Sum(packet)
+	plain := append(sum[:], packet...)
+
+	key := sha256.Sum256([]byte(password))
+	block, _ := aes.NewCipher(key[:])
+	iv := make([]byte, aes.BlockSize)
+	for i := range iv {
+		iv[i] = byte(i)
+	}
+	encrypted := make([]byte, len(plain))
+	cipher.NewOFB(block, iv).XORKeyStream(encrypted, plain)
+
+	body := make([]byte, 2, 2+len(user)+len(iv)+len(encrypted))
+	binary.BigEndian.PutUint16(body, uint16(len(user)))
+	body = append(body, user...)
+	body = append(body, iv...)
+	return cdTestPart(cdPartEncryption, append(body, encrypted...))
+}
+
+func testCollectdParser(t *testing.T, conf string) *collectdParser {
+	t.Helper()
+
+	pConf, err := collectdInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	p, err := newCollectdParserFromParsed(pConf)
+	require.NoError(t, err)
+	return p
+}
+
+var cdTestTime = time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+func cdTestValuesPacket() []byte {
+	return cdTestPacket(
+		cdTestString(cdPartHost, "web1"),
+		cdTestNumber(cdPartTimeHR, uint64(cdTestTime.Unix())<<30|1<<29),
+		cdTestNumber(cdPartIntervalHR, 10<<30),
+		cdTestString(cdPartPlugin, "interface"),
+		cdTestString(cdPartPluginInstance, "eth0"),
+		cdTestString(cdPartType, "if_octets"),
+		cdTestString(cdPartTypeInstance, ""),
+		cdTestValues(cdTestValue{cdTypeDerive, 100}, cdTestValue{cdTypeDerive, 200}),
+		cdTestString(cdPartPlugin, "load"),
+		cdTestString(cdPartPluginInstance, ""),
+		cdTestString(cdPartType, "load"),
+		cdTestNumber(cdPartTime, uint64(cdTestTime.Unix())),
+		cdTestNumber(cdPartInterval, 20),
+		cdTestValues(cdTestValue{cdTypeGauge, math.Float64bits(0.5)}),
+		cdTestValues(cdTestValue{cdTypeGauge, math.Float64bits(math.NaN())}),
+	)
+}
+
+func cdTestExpectedMetrics() []metric {
+	ifTags := map[string]string{"host": "web1", "plugin": "interface", "plugin_instance": "eth0", "type": "if_octets", "ds_type": "derive"}
+	hrTime := time.Unix(cdTestTime.Unix(), int64(time.Second/2))
+	return []metric{
+		{name: "interface.eth0.if_octets.0", value: int64(100), typ: "counter", tags: ifTags, timestamp: hrTime, interval: 10 * time.Second},
+		{name: "interface.eth0.if_octets.1", value: int64(200), typ: "counter", tags: ifTags, timestamp: hrTime, interval: 10 * time.Second},
+		{
+			name: "load.load", value: 0.5, typ: "gauge",
+			tags:      map[string]string{"host": "web1", "plugin": "load", "type": "load", "ds_type": "gauge"},
+			timestamp: time.Unix(cdTestTime.Unix(), 0), interval: 20 * time.Second,
+		},
+	}
+}
+
+func TestCollectdParse(t *testing.T) {
+	p := testCollectdParser(t, `{}`)
+
+	metrics, errs := p.parse(cdTestValuesPacket(), time.Now())
+	assert.Empty(t, errs)
+	assert.Equal(t, cdTestExpectedMetrics(), metrics)
+
+	metrics, errs = p.parse(cdTestPacket(
+		cdTestString(cdPartPlugin, "cpu"),
+		cdTestString(cdPartType, "cpu"),
+		cdTestValues(cdTestValue{cdTypeCounter, 5}, cdTestValue{cdTypeAbsolute, 6}),
+	), cdTestTime)
+	assert.Empty(t, errs)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, uint64(5), metrics[0].value)
+	assert.Equal(t, "counter", metrics[0].tags["ds_type"])
+	assert.Equal(t, uint64(6), metrics[1].value)
+	assert.Equal(t, "absolute", metrics[1].tags["ds_type"])
+	assert.Equal(t, cdTestTime, metrics[1].timestamp)
+}
+
+func TestCollectdParseErrors(t *testing.T) {
+	p := testCollectdParser(t, `{}`)
+
+	for _, packet := range [][]byte{
+		{0x00},
+		{0x00, 0x00, 0x00, 0x02},
+		cdTestPart(cdPartHost, []byte("unterminated")),
+		cdTestPart(cdPartTime, []byte{0x01}),
+		cdTestPart(cdPartValues, []byte{0x00, 0x02, 0x01}),
+		cdTestValues(cdTestValue{dsType: 9}),
+	} {
+		metrics, errs := p.parse(packet, cdTestTime)
+		assert.Empty(t, metrics)
+		assert.Len(t, errs, 1, "%x", packet)
+	}
+
+	// Values preceding an invalid part are kept.
+	metrics, errs := p.parse(cdTestPacket(
+		cdTestString(cdPartPlugin, "load"),
+		cdTestString(cdPartType, "load"),
+		cdTestValues(cdTestValue{cdTypeGauge, math.Float64bits(1)}),
+		[]byte{0x00},
+	), cdTestTime)
+	assert.Len(t, metrics, 1)
+	assert.Len(t, errs, 1)
+}
+
+func TestCollectdSecurity(t *testing.T) {
+	packet := cdTestValuesPacket()
+	signed := cdTestSign("alice", "secret", packet)
+	badSignature := cdTestSign("alice", "wrong", packet)
+	unknownSigner := cdTestSign("bob", "secret", packet)
+	encrypted := cdTestEncrypt("alice", "secret", packet)
+	badEncryption := cdTestEncrypt("alice", "wrong", packet)
+
+	tests := []struct {
+		name   string
+		conf   string
+		packet []byte
+		ok     bool
+	}{
+		{name: "none unsigned", conf: `users: { alice: secret }`, packet: packet, ok: true},
+		{name: "none signed", conf: `users: { alice: secret }`, packet: signed, ok: true},
+		{name: "none unknown signer", conf: `{}`, packet: signed, ok: true},
+		{name: "none bad signature", conf: `users: { alice: secret }`, packet: badSignature},
+		{name: "none encrypted", conf: `users: { alice: secret }`, packet: encrypted, ok: true},
+		{name: "none unknown encrypter", conf: `{}`, packet: encrypted},
+		{name: "sign unsigned", conf: `{ security_level: sign, users: { alice: secret } }`, packet: packet},
+		{name: "sign signed", conf: `{ security_level: sign, users: { alice: secret } }`, packet: signed, ok: true},
+		{name: "sign unknown signer", conf: `{ security_level: sign, users: { alice: secret } }`, packet: unknownSigner},
+		{name: "sign encrypted", conf: `{ security_level: sign, users: { alice: secret } }`, packet: encrypted, ok: true},
+		{name: "encrypt signed", conf: `{ security_level: encrypt, users: { alice: secret } }`, packet: signed},
+		{name: "encrypt encrypted", conf: `{ security_level: encrypt, users: { alice: secret } }`, packet: encrypted, ok: true},
+		{name: "encrypt bad encryption", conf: `{ security_level: encrypt, users: { alice: secret } }`, packet: badEncryption},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			metrics, errs := testCollectdParser(t, test.conf).parse(test.packet, time.Now())
+			if test.ok {
+				assert.Empty(t, errs)
+				assert.Equal(t, cdTestExpectedMetrics(), metrics)
+			} else {
+				assert.Len(t, errs, 1)
+				assert.Empty(t, metrics)
+			}
+		})
+	}
+}
+
+func TestCollectdConfigErrors(t *testing.T) {
+	pConf, err := collectdInputSpec().ParseYAML(`security_level: sign`, nil)
+	require.NoError(t, err)
+
+	_, err = newCollectdParserFromParsed(pConf)
+	require.Error(t, err)
+}
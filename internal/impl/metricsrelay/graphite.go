@@ -0,0 +1,108 @@
+package metricsrelay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func graphiteInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Receives metrics sent with the plaintext protocol of Graphite over TCP or UDP, where each metric is consumed as a JSON message.").
+		Description(`
+Each line is a metric of the form `+"`<path> <value> <timestamp>`"+`, where the timestamp is in unix seconds and is the time at which the metric was received when it's omitted or `+"`-1`"+`. [Tags](https://graphite.readthedocs.io/en/latest/tags.html) are parsed from paths of the form `+"`<name>;<key>=<value>;...`"+`, and all metrics are consumed with the type `+"`gauge`"+`.
+
+`+metricFormatDocs+`
+
+Lines that cannot be parsed are logged and dropped. The metrics of each datagram, or of the lines of a TCP connection that are received at once, are consumed as a batch.
+
+`+metricMetadataDocs("graphite")).
+		Field(listenerNetworkField("tcp")).
+		Field(listenerAddressField("0.0.0.0:2003")).
+		Example(
+			"Metrics to Kafka",
+			"Receive metrics from Graphite clients and publish them to a Kafka topic keyed by their name.",
+			`
+input:
+  graphite:
+    address: 0.0.0.0:2003
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: metrics
+    key: ${! json("name") }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"graphite", graphiteInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newMetricListenerFromParsed("graphite", conf, parseGraphite, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func parseGraphite(b []byte, now time.Time) (metrics []metric, errs []error) {
+	errs = splitLines(b, func(line string) error {
+		m, err := parseGraphiteLine(line, now)
+		if err != nil {
+			return err
+		}
+		metrics = append(metrics, m)
+		return nil
+	})
+	return
+}
+
+func parseGraphiteLine(line string, now time.Time) (metric, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return metric{}, errors.New("expected a path, value and timestamp")
+	}
+
+	segments := strings.Split(fields[0], ";")
+	m := metric{
+		name:      segments[0],
+		typ:       "gauge",
+		tags:      map[string]string{},
+		timestamp: now,
+	}
+	if m.name == "" {
+		return metric{}, errors.New("expected a name")
+	}
+	for _, tag := range segments[1:] {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" {
+			return metric{}, fmt.Errorf("invalid tag '%v'", tag)
+		}
+		m.tags[k] = v
+	}
+
+	f, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return metric{}, fmt.Errorf("invalid value '%v'", fields[1])
+	}
+	m.value = f
+
+	if len(fields) == 3 && fields[2] != "-1" {
+		secs, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return metric{}, fmt.Errorf("invalid timestamp '%v'", fields[2])
+		}
+		m.timestamp = time.Unix(0, int64(secs*float64(time.Second)))
+	}
+	return m, nil
+}
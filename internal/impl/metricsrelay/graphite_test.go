@@ -0,0 +1,52 @@
+package metricsrelay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGraphite(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []metric
+		errs     int
+	}{
+		{
+			name:  "plaintext",
+			input: "servers.a.cpu 55.5 1654077600",
+			expected: []metric{
+				{name: "servers.a.cpu", value: 55.5, typ: "gauge", tags: map[string]string{}, timestamp: time.Unix(1654077600, 0)},
+			},
+		},
+		{
+			name:  "tags and no timestamp",
+			input: "cpu;host=a;region=eu 55\ncpu;host=b -1 -1",
+			expected: []metric{
+				{name: "cpu", value: float64(55), typ: "gauge", tags: map[string]string{"host": "a", "region": "eu"}, timestamp: now},
+				{name: "cpu", value: float64(-1), typ: "gauge", tags: map[string]string{"host": "b"}, timestamp: now},
+			},
+		},
+		{
+			name:  "invalid lines are skipped",
+			input: "a\nb nope 1\nc 1 nope\nd;host 1 1\ne 1 1 1\nf 2 1654077600.5\r\n",
+			expected: []metric{
+				{name: "f", value: float64(2), typ: "gauge", tags: map[string]string{}, timestamp: time.Unix(1654077600, 500000000)},
+			},
+			errs: 5,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			metrics, errs := parseGraphite([]byte(test.input), now)
+			assert.Len(t, errs, test.errs)
+			assert.Equal(t, test.expected, metrics)
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package metricsrelay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	mlFieldNetwork = "network"
+	mlFieldAddress = "address"
+
+	// The maximum size of a UDP datagram, and of a line received over TCP.
+	maxDatagramSize = 65535
+)
+
+// metric is a metric received by a listener, which is consumed as a message
+// in a format that is common to all of the listeners.
+type metric struct {
+	name       string
+	value      any
+	typ        string
+	tags       map[string]string
+	timestamp  time.Time
+	sampleRate float64
+	delta      bool
+	interval   time.Duration
+}
+
+func (m metric) toMessage(metaPrefix, remote string) *service.Message {
+	tags := make(map[string]any, len(m.tags))
+	for k, v := range m.tags {
+		tags[k] = v
+	}
+	fields := map[string]any{
+		"name":      m.name,
+		"value":     m.value,
+		"type":      m.typ,
+		"tags":      tags,
+		"timestamp": m.timestamp.UTC().Format(time.RFC3339Nano),
+	}
+	if m.sampleRate > 0 && m.sampleRate < 1 {
+		fields["sample_rate"] = m.sampleRate
+	}
+	if m.delta {
+		fields["delta"] = true
+	}
+	if m.interval > 0 {
+		fields["interval"] = m.interval.String()
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(fields)
+	msg.MetaSetMut(metaPrefix+"_remote_addr", remote)
+	return msg
+}
+
+const metricFormatDocs = `### Message Format
+
+Each metric is consumed as a message of the following format, which is common to the ` + "`statsd`, `graphite` and `collectd`" + ` inputs:
+
+` + "```json" + `
+{
+  "name": "api.requests",
+  "value": 1,
+  "type": "counter",
+  "tags": { "region": "eu" },
+  "timestamp": "2022-06-01T10:00:00Z"
+}
+` + "```" + `
+
+Where the value is a number, the timestamp is the time at which the metric was received unless the protocol provides one, and the tags are an object of strings that is empty when the metric has none. The fields ` + "`sample_rate`, `delta` and `interval`" + ` are added to metrics that have them.`
+
+func metricMetadataDocs(name string) string {
+	return `### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- ` + name + `_remote_addr
+` + "```" + `
+
+Which is the address from which the metric was sent.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`
+}
+
+// parseFn parses the metrics of a datagram, or of lines received over TCP,
+// returning errors for the metrics that could not be parsed.
+type parseFn func(b []byte, now time.Time) ([]metric, []error)
+
+func listenerNetworkField(defaultNetwork string) *service.ConfigField {
+	return service.NewStringEnumField(mlFieldNetwork, "udp", "tcp").
+		Description("The network to listen on, where metrics received over TCP are delimited by line breaks.").
+		Default(defaultNetwork)
+}
+
+func listenerAddressField(defaultAddress string) *service.ConfigField {
+	return service.NewStringField(mlFieldAddress).
+		Description("The address to listen on.").
+		Default(defaultAddress)
+}
+
+// metricListener receives metrics over UDP or TCP, where the metrics of each
+// datagram, or of the lines of a TCP connection that are available at once,
+// are consumed as a batch.
+type metricListener struct {
+	name    string
+	network string
+	address string
+	parse   parseFn
+	log     *service.Logger
+
+	m        sync.Mutex
+	closer   io.Closer
+	addr     net.Addr
+	batches  chan service.MessageBatch
+	shutdown chan struct{}
+	done     chan struct{}
+}
+
+func newMetricListenerFromParsed(name string, conf *service.ParsedConfig, parse parseFn, log *service.Logger) (*metricListener, error) {
+	l := &metricListener{name: name, parse: parse, log: log, network: "udp"}
+
+	var err error
+	if conf.Contains(mlFieldNetwork) {
+		if l.network, err = conf.FieldString(mlFieldNetwork); err != nil {
+			return nil, err
+		}
+	}
+	switch l.network {
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("network '%v' is not supported", l.network)
+	}
+	if l.address, err = conf.FieldString(mlFieldAddress); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *metricListener) Connect(ctx context.Context) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.closer != nil {
+		return nil
+	}
+
+	var lc net.ListenConfig
+	batches, shutdown, done := make(chan service.MessageBatch), make(chan struct{}), make(chan struct{})
+	if l.network == "tcp" {
+		ln, err := lc.Listen(ctx, "tcp", l.address)
+		if err != nil {
+			return err
+		}
+		l.closer, l.addr = ln, ln.Addr()
+		go l.acceptLoop(ln, batches, shutdown, done)
+	} else {
+		conn, err := lc.ListenPacket(ctx, "udp", l.address)
+		if err != nil {
+			return err
+		}
+		l.closer, l.addr = conn, conn.LocalAddr()
+		go l.packetLoop(conn, batches, shutdown, done)
+	}
+	l.batches, l.shutdown, l.done = batches, shutdown, done
+
+	l.log.Infof("Receiving %v metrics over %v on address: %v", l.name, l.network, l.addr)
+	return nil
+}
+
+// send parses metrics and sends them as a batch, returning false when the
+// listener is shutting down.
+func (l *metricListener) send(remote string, b []byte, batches chan<- service.MessageBatch, shutdown <-chan struct{}) bool {
+	metrics, errs := l.parse(b, time.Now())
+	for _, err := range errs {
+		l.log.Warnf("Failed to parse %v metric from %v: %v", l.name, remote, err)
+	}
+	if len(metrics) == 0 {
+		return true
+	}
+
+	batch := make(service.MessageBatch, 0, len(metrics))
+	for _, m := range metrics {
+		batch = append(batch, m.toMessage(l.name, remote))
+	}
+	select {
+	case batches <- batch:
+		return true
+	case <-shutdown:
+		return false
+	}
+}
+
+func (l *metricListener) packetLoop(conn net.PacketConn, batches chan<- service.MessageBatch, shutdown <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		size, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.log.Errorf("Failed to read datagram: %v", err)
+			}
+			return
+		}
+		if !l.send(addr.String(), buf[:size], batches, shutdown) {
+			return
+		}
+	}
+}
+
+func (l *metricListener) acceptLoop(ln net.Listener, batches chan<- service.MessageBatch, shutdown <-chan struct{}, done chan<- struct{}) {
+	var wg sync.WaitGroup
+	defer func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				l.log.Errorf("Failed to accept connection: %v", err)
+			}
+			return
+		}
+
+		connDone := make(chan struct{})
+		go func() {
+			select {
+			case <-shutdown:
+			case <-connDone:
+			}
+			conn.Close()
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				close(connDone)
+				wg.Done()
+			}()
+			l.readConn(conn, batches, shutdown)
+		}()
+	}
+}
+
+// readConn reads the lines of a connection, where all complete lines that have
+// been received are parsed together.
+func (l *metricListener) readConn(conn net.Conn, batches chan<- service.MessageBatch, shutdown <-chan struct{}) {
+	remote := conn.RemoteAddr().String()
+	r := bufio.NewReaderSize(conn, maxDatagramSize)
+
+	var lines []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		lines = append(lines, line...)
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				l.log.Errorf("Closing connection from %v as a line exceeds %v bytes", remote, maxDatagramSize)
+				return
+			}
+			if len(lines) > 0 {
+				l.send(remote, lines, batches, shutdown)
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				l.log.Errorf("Connection from %v dropped due to: %v", remote, err)
+			}
+			return
+		}
+
+		if buffered, _ := r.Peek(r.Buffered()); bytes.IndexByte(buffered, '\n') != -1 && len(lines) < maxDatagramSize {
+			continue
+		}
+		if !l.send(remote, lines, batches, shutdown) {
+			return
+		}
+		lines = lines[:0]
+	}
+}
+
+func (l *metricListener) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	l.m.Lock()
+	batches, done := l.batches, l.done
+	l.m.Unlock()
+
+	if batches == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case batch := <-batches:
+		// Metrics cannot be redelivered by the protocols, and therefore
+		// there's nothing to do when a batch is rejected.
+		return batch, func(ctx context.Context, err error) error {
+			return nil
+		}, nil
+	case <-done:
+		l.disconnect(done)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// disconnect closes the listener if the loop reading it is the one that
+// signals being done with the given channel.
+func (l *metricListener) disconnect(done chan struct{}) {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.closer != nil && l.done == done {
+		close(l.shutdown)
+		_ = l.closer.Close()
+		l.closer, l.addr, l.batches, l.shutdown, l.done = nil, nil, nil, nil, nil
+	}
+}
+
+func (l *metricListener) Close(ctx context.Context) error {
+	l.m.Lock()
+	done := l.done
+	l.m.Unlock()
+
+	l.disconnect(done)
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// splitLines calls fn for each non-empty line, collecting the errors it
+// returns.
+func splitLines(b []byte, fn func(line string) error) (errs []error) {
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", line, err))
+		}
+	}
+	return
+}
@@ -0,0 +1,130 @@
+package metricsrelay
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testMetricListener(t *testing.T, name string, spec *service.ConfigSpec, parse parseFn, conf string) *metricListener {
+	t.Helper()
+
+	pConf, err := spec.ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	l, err := newMetricListenerFromParsed(name, pConf, parse, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close(context.Background()) })
+
+	require.NoError(t, l.Connect(context.Background()))
+	return l
+}
+
+func listenerAddr(l *metricListener) string {
+	l.m.Lock()
+	defer l.m.Unlock()
+	return l.addr.String()
+}
+
+func readNames(t *testing.T, ctx context.Context, l *metricListener) []string {
+	t.Helper()
+
+	batch, ackFn, err := l.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	var names []string
+	for _, msg := range batch {
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		names = append(names, v.(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+func TestStatsDInputUDP(t *testing.T) {
+	l := testMetricListener(t, "statsd", statsdInputSpec(), parseStatsD, `address: 127.0.0.1:0`)
+
+	conn, err := net.Dial("udp", listenerAddr(l))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	// Datagrams without valid metrics produce no batches.
+	_, err = conn.Write([]byte("not a metric"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("a:1|c\nb:2|g"))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, _, err := l.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	addr, exists := batch[0].MetaGet("statsd_remote_addr")
+	assert.True(t, exists)
+	assert.Equal(t, conn.LocalAddr().String(), addr)
+
+	require.NoError(t, l.Close(ctx))
+	_, _, err = l.ReadBatch(ctx)
+	assert.Equal(t, service.ErrNotConnected, err)
+}
+
+func TestGraphiteInputTCP(t *testing.T) {
+	l := testMetricListener(t, "graphite", graphiteInputSpec(), parseGraphite, `address: 127.0.0.1:0`)
+
+	conn, err := net.Dial("tcp", listenerAddr(l))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	_, err = conn.Write([]byte("a 1 1654077600\nb 2 1654077600\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, readNames(t, ctx, l))
+
+	// The rest of a partial line is awaited, and the last line of a connection
+	// doesn't need to be terminated.
+	_, err = conn.Write([]byte("c 3"))
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 50)
+	_, err = conn.Write([]byte(" 1654077600\nd 4"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c"}, readNames(t, ctx, l))
+
+	require.NoError(t, conn.Close())
+	assert.Equal(t, []string{"d"}, readNames(t, ctx, l))
+
+	require.NoError(t, l.Close(ctx))
+	_, _, err = l.ReadBatch(ctx)
+	assert.Equal(t, service.ErrNotConnected, err)
+}
+
+func TestListenerCloseWithOpenConnection(t *testing.T) {
+	l := testMetricListener(t, "statsd", statsdInputSpec(), parseStatsD, `
+network: tcp
+address: 127.0.0.1:0
+`)
+
+	conn, err := net.Dial("tcp", listenerAddr(l))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	_, err = conn.Write([]byte("a:1|c\n"))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	// Closing while a batch is pending and the connection is open doesn't
+	// block.
+	time.Sleep(time.Millisecond * 50)
+	require.NoError(t, l.Close(ctx))
+}
@@ -0,0 +1,167 @@
+package metricsrelay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func statsdInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Receives metrics sent with the statsd protocol over UDP or TCP, where each metric is consumed as a JSON message.").
+		Description(`
+Each line is a metric of the form `+"`<name>:<value>|<type>`"+`, optionally followed by a sample rate `+"`|@<rate>`"+`, tags in the format of DogStatsD `+"`|#<key>:<value>,...`"+` and a timestamp `+"`|T<unix seconds>`"+`. Tags are also parsed from names in the format of InfluxDB, such as `+"`requests,region=eu:1|c`"+`, and lines with multiple values, such as `+"`latency:12:15|ms`"+`, are consumed as a message for each value.
+
+The types `+"`c`, `g`, `ms`, `h`, `s` and `d`"+` are consumed as the types `+"`counter`, `gauge`, `timer`, `histogram`, `set` and `distribution`"+` respectively. Gauges with a value that is prefixed with a sign are changes to the current value, and are consumed with the field `+"`delta`"+` set to `+"`true`"+`.
+
+`+metricFormatDocs+`
+
+Lines that cannot be parsed are logged and dropped. The metrics of each datagram, or of the lines of a TCP connection that are received at once, are consumed as a batch.
+
+`+metricMetadataDocs("statsd")).
+		Field(listenerNetworkField("udp")).
+		Field(listenerAddressField("0.0.0.0:8125")).
+		Example(
+			"Translate to Graphite",
+			"Receive statsd counters and gauges and forward them to Carbon in the plaintext protocol of Graphite, with the counters of sampled metrics scaled by their rate.",
+			`
+input:
+  statsd:
+    address: 0.0.0.0:8125
+  processors:
+    - mapping: |
+        let value = if this.sample_rate != null { this.value / this.sample_rate } else { this.value }
+        root = if ["counter", "gauge"].contains(this.type) && this.delta != true {
+          "%s %v %v".format(this.name, $value, this.timestamp.ts_unix())
+        } else {
+          deleted()
+        }
+
+output:
+  socket:
+    network: tcp
+    address: localhost:2003
+    codec: lines
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"statsd", statsdInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newMetricListenerFromParsed("statsd", conf, parseStatsD, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var statsdTypes = map[string]string{
+	"c":  "counter",
+	"g":  "gauge",
+	"ms": "timer",
+	"h":  "histogram",
+	"s":  "set",
+	"d":  "distribution",
+}
+
+func parseStatsD(b []byte, now time.Time) (metrics []metric, errs []error) {
+	errs = splitLines(b, func(line string) error {
+		parsed, err := parseStatsDLine(line, now)
+		metrics = append(metrics, parsed...)
+		return err
+	})
+	return
+}
+
+func parseStatsDLine(line string, now time.Time) ([]metric, error) {
+	sections := strings.Split(line, "|")
+	if len(sections) < 2 {
+		return nil, errors.New("expected a type")
+	}
+
+	name, values, ok := strings.Cut(sections[0], ":")
+	if !ok {
+		return nil, errors.New("expected a value")
+	}
+
+	tags := map[string]string{}
+	if n, influxTags, ok := strings.Cut(name, ","); ok {
+		name = n
+		for _, tag := range strings.Split(influxTags, ",") {
+			k, v, _ := strings.Cut(tag, "=")
+			tags[k] = v
+		}
+	}
+	if name == "" {
+		return nil, errors.New("expected a name")
+	}
+
+	typ, exists := statsdTypes[sections[1]]
+	if !exists {
+		return nil, fmt.Errorf("type '%v' is not supported", sections[1])
+	}
+
+	timestamp, sampleRate := now, float64(1)
+	for _, s := range sections[2:] {
+		if s == "" {
+			continue
+		}
+		switch s[0] {
+		case '@':
+			rate, err := strconv.ParseFloat(s[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return nil, fmt.Errorf("invalid sample rate '%v'", s[1:])
+			}
+			sampleRate = rate
+		case '#':
+			for _, tag := range strings.Split(s[1:], ",") {
+				if tag != "" {
+					k, v, _ := strings.Cut(tag, ":")
+					tags[k] = v
+				}
+			}
+		case 'T':
+			secs, err := strconv.ParseInt(s[1:], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp '%v'", s[1:])
+			}
+			timestamp = time.Unix(secs, 0)
+		}
+		// Other extensions, such as the container IDs of DogStatsD, are
+		// ignored.
+	}
+
+	metrics := make([]metric, 0, 1)
+	for _, v := range strings.Split(values, ":") {
+		m := metric{
+			name:       name,
+			typ:        typ,
+			tags:       tags,
+			timestamp:  timestamp,
+			sampleRate: sampleRate,
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		switch {
+		case err == nil && !math.IsNaN(f) && !math.IsInf(f, 0):
+			m.value = f
+			m.delta = typ == "gauge" && (v[0] == '+' || v[0] == '-')
+		case typ == "set" && v != "":
+			// The members of sets are not necessarily numbers.
+			m.value = v
+		default:
+			return nil, fmt.Errorf("invalid value '%v'", v)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,103 @@
+package metricsrelay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatsD(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []metric
+		errs     int
+	}{
+		{
+			name:  "counter",
+			input: "api.requests:1|c",
+			expected: []metric{
+				{name: "api.requests", value: float64(1), typ: "counter", tags: map[string]string{}, timestamp: now, sampleRate: 1},
+			},
+		},
+		{
+			name:  "sampled timer with tags",
+			input: "api.latency:12.5|ms|@0.1|#region:eu,canary",
+			expected: []metric{
+				{name: "api.latency", value: 12.5, typ: "timer", tags: map[string]string{"region": "eu", "canary": ""}, timestamp: now, sampleRate: 0.1},
+			},
+		},
+		{
+			name:  "influx tags and timestamp",
+			input: "cpu,host=a,region=eu:55|g|T1654077600",
+			expected: []metric{
+				{name: "cpu", value: float64(55), typ: "gauge", tags: map[string]string{"host": "a", "region": "eu"}, timestamp: time.Unix(1654077600, 0), sampleRate: 1},
+			},
+		},
+		{
+			name:  "gauge delta",
+			input: "queue.depth:-3|g",
+			expected: []metric{
+				{name: "queue.depth", value: float64(-3), typ: "gauge", tags: map[string]string{}, timestamp: now, sampleRate: 1, delta: true},
+			},
+		},
+		{
+			name:  "multiple values",
+			input: "api.latency:12:15|h",
+			expected: []metric{
+				{name: "api.latency", value: float64(12), typ: "histogram", tags: map[string]string{}, timestamp: now, sampleRate: 1},
+				{name: "api.latency", value: float64(15), typ: "histogram", tags: map[string]string{}, timestamp: now, sampleRate: 1},
+			},
+		},
+		{
+			name:  "set of strings",
+			input: "users.unique:alice|s",
+			expected: []metric{
+				{name: "users.unique", value: "alice", typ: "set", tags: map[string]string{}, timestamp: now, sampleRate: 1},
+			},
+		},
+		{
+			name:  "invalid lines are skipped",
+			input: "a:1|x\nb:nope|c\nc:1\nd:NaN|g\n\ne:2|d|c:abc\r\nf:1|c|@2",
+			expected: []metric{
+				{name: "e", value: float64(2), typ: "distribution", tags: map[string]string{}, timestamp: now, sampleRate: 1},
+			},
+			errs: 5,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			metrics, errs := parseStatsD([]byte(test.input), now)
+			assert.Len(t, errs, test.errs)
+			assert.Equal(t, test.expected, metrics)
+		})
+	}
+}
+
+func TestStatsDMessage(t *testing.T) {
+	metrics, errs := parseStatsD([]byte("api.latency:12.5|ms|@0.5|#region:eu"), time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC))
+	require.Empty(t, errs)
+	require.Len(t, metrics, 1)
+
+	msg := metrics[0].toMessage("statsd", "127.0.0.1:1234")
+	body, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "name": "api.latency",
+  "value": 12.5,
+  "type": "timer",
+  "tags": {"region": "eu"},
+  "timestamp": "2022-06-01T10:00:00Z",
+  "sample_rate": 0.5
+}`, string(body))
+
+	addr, exists := msg.MetaGet("statsd_remote_addr")
+	assert.True(t, exists)
+	assert.Equal(t, "127.0.0.1:1234", addr)
+}
@@ -0,0 +1,126 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// The functions of the Modbus application protocol that read each table.
+const (
+	mbFuncReadCoils            byte = 0x01
+	mbFuncReadDiscreteInputs   byte = 0x02
+	mbFuncReadHoldingRegisters byte = 0x03
+	mbFuncReadInputRegisters   byte = 0x04
+
+	// The length of the MBAP header that precedes each PDU with Modbus TCP.
+	mbHeaderLen = 7
+)
+
+var mbExceptionNames = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0a: "gateway path unavailable",
+	0x0b: "gateway target device failed to respond",
+}
+
+// mbException is an exception response of a device, which doesn't affect the
+// connection.
+type mbException struct {
+	function byte
+	code     byte
+}
+
+func (e *mbException) Error() string {
+	name, exists := mbExceptionNames[e.code]
+	if !exists {
+		name = "unknown exception"
+	}
+	return fmt.Sprintf("device responded to function %#02x with exception %#02x (%v)", e.function, e.code, name)
+}
+
+// mbClient sends requests to a device over Modbus TCP one at a time, as many
+// devices only process a single transaction at a time.
+type mbClient struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	mut           sync.Mutex
+	transactionID uint16
+}
+
+func dialMB(ctx context.Context, address string, timeout time.Duration) (*mbClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &mbClient{conn: conn, timeout: timeout}, nil
+}
+
+func (c *mbClient) close() error {
+	return c.conn.Close()
+}
+
+// read reads a range of a table of a unit, and returns the data of the
+// response, which are the packed bits of coils and discrete inputs or the
+// big-endian values of registers.
+func (c *mbClient) read(ctx context.Context, unitID, function byte, address, quantity uint16) ([]byte, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.transactionID++
+	req := make([]byte, mbHeaderLen+5)
+	binary.BigEndian.PutUint16(req[0:], c.transactionID)
+	binary.BigEndian.PutUint16(req[4:], 6)
+	req[6] = unitID
+	req[7] = function
+	binary.BigEndian.PutUint16(req[8:], address)
+	binary.BigEndian.PutUint16(req[10:], quantity)
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	// Responses of earlier transactions that timed out are skipped.
+	for {
+		header := make([]byte, mbHeaderLen)
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[4:]))
+		if length < 2 || length > 254 {
+			return nil, fmt.Errorf("response has invalid length %v", length)
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(c.conn, pdu); err != nil {
+			return nil, err
+		}
+		if binary.BigEndian.Uint16(header[0:]) != c.transactionID {
+			continue
+		}
+
+		if pdu[0] == function|0x80 && len(pdu) >= 2 {
+			return nil, &mbException{function: function, code: pdu[1]}
+		}
+		if pdu[0] != function || len(pdu) < 2 || int(pdu[1]) != len(pdu)-2 {
+			return nil, errors.New("response is malformed")
+		}
+		return pdu[2:], nil
+	}
+}
@@ -0,0 +1,507 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	mbiFieldAddress   = "address"
+	mbiFieldUnitID    = "unit_id"
+	mbiFieldInterval  = "interval"
+	mbiFieldTimeout   = "timeout"
+	mbiFieldRegisters = "registers"
+
+	mbiFieldRegName      = "name"
+	mbiFieldRegTable     = "table"
+	mbiFieldRegAddress   = "address"
+	mbiFieldRegType      = "type"
+	mbiFieldRegLength    = "length"
+	mbiFieldRegByteOrder = "byte_order"
+	mbiFieldRegWordOrder = "word_order"
+	mbiFieldRegScale     = "scale"
+
+	// The maximum quantities that a single request may read, which are
+	// limited by the size of the PDU of a response.
+	mbMaxRegisters = 125
+	mbMaxBits      = 2000
+)
+
+func mbInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Polls the coils, discrete inputs and registers of a device over Modbus TCP.").
+		Description(`
+Each poll reads all of the configured registers and creates a single message, which is an object with a field for each register where the value is decoded according to the type of the register. Registers of the same table that are adjacent or overlap are read with a single request.
+
+Values of 32 and 64 bits span multiple registers, and devices differ in how they order the bytes of each register and the registers of a value. These are configured with the fields `+"`byte_order`"+` and `+"`word_order`"+`, for example a float that is stored in the order of registers that is known as CDAB requires a `+"`word_order`"+` of `+"`little`"+`.
+
+When a device responds with an exception the poll fails and is attempted again, and when the connection is lost it is reconnected.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- modbus_unit_id
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(mbiFieldAddress).
+			Description("The address of the device to connect to.").
+			Example("localhost:502")).
+		Field(service.NewIntField(mbiFieldUnitID).
+			Description("The identifier of the unit to read from, which selects the device behind a gateway.").
+			Default(1)).
+		Field(service.NewDurationField(mbiFieldInterval).
+			Description("The period of time between polls.").
+			Default("1s")).
+		Field(service.NewDurationField(mbiFieldTimeout).
+			Description("The maximum period of time to wait for a response of the device.").
+			Advanced().
+			Default("5s")).
+		Field(service.NewObjectListField(mbiFieldRegisters,
+			service.NewStringField(mbiFieldRegName).
+				Description("The name of the field of the message that the value is stored in."),
+			service.NewStringEnumField(mbiFieldRegTable, "coil", "discrete_input", "holding_register", "input_register").
+				Description("The table of the register.").
+				Default("holding_register"),
+			service.NewIntField(mbiFieldRegAddress).
+				Description("The address of the register, which starts at zero."),
+			service.NewStringEnumField(mbiFieldRegType, "bool", "int16", "uint16", "int32", "uint32", "int64", "uint64", "float32", "float64", "string").
+				Description("The type to decode the value as, which must be `bool` for coils and discrete inputs. Defaults to `bool` for coils and discrete inputs and to `uint16` for registers.").
+				Optional(),
+			service.NewIntField(mbiFieldRegLength).
+				Description("The number of registers of a `string` value, where each register holds two characters and trailing null characters are removed.").
+				Default(1),
+			service.NewStringEnumField(mbiFieldRegByteOrder, "big", "little").
+				Description("The order of the two bytes of each register.").
+				Default("big"),
+			service.NewStringEnumField(mbiFieldRegWordOrder, "big", "little").
+				Description("The order of the registers of a value that spans multiple registers, where `big` stores the most significant register first.").
+				Default("big"),
+			service.NewFloatField(mbiFieldRegScale).
+				Description("An optional factor that numeric values are multiplied by, which results in a floating point value.").
+				Optional(),
+		).
+			Description("The registers to read with each poll.").
+			Example([]any{
+				map[string]any{"name": "temperature", "address": 0, "type": "int16", "scale": 0.1},
+				map[string]any{"name": "flow_rate", "table": "input_register", "address": 10, "type": "float32", "word_order": "little"},
+				map[string]any{"name": "pump_running", "table": "coil", "address": 3},
+			})).
+		Example(
+			"Bridging a PLC",
+			"In this example the registers of a PLC are polled every five seconds and sent to Kafka.",
+			`
+input:
+  modbus:
+    address: 10.0.0.20:502
+    interval: 5s
+    registers:
+      - name: temperature
+        address: 0
+        type: int16
+        scale: 0.1
+      - name: total_volume
+        table: input_register
+        address: 100
+        type: uint32
+      - name: pump_running
+        table: coil
+        address: 3
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: plc_readings
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"modbus", mbInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newMBInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type mbRegister struct {
+	name      string
+	function  byte
+	address   uint16
+	quantity  uint16
+	typ       string
+	byteOrder binary.ByteOrder
+	wordOrder binary.ByteOrder
+	scale     *float64
+}
+
+var mbTableFunctions = map[string]byte{
+	"coil":             mbFuncReadCoils,
+	"discrete_input":   mbFuncReadDiscreteInputs,
+	"holding_register": mbFuncReadHoldingRegisters,
+	"input_register":   mbFuncReadInputRegisters,
+}
+
+func mbIsBitFunction(function byte) bool {
+	return function == mbFuncReadCoils || function == mbFuncReadDiscreteInputs
+}
+
+func mbOrder(name string) binary.ByteOrder {
+	if name == "little" {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+func mbRegisterFromParsed(conf *service.ParsedConfig) (r mbRegister, err error) {
+	if r.name, err = conf.FieldString(mbiFieldRegName); err != nil {
+		return
+	}
+	var table string
+	if table, err = conf.FieldString(mbiFieldRegTable); err != nil {
+		return
+	}
+	r.function = mbTableFunctions[table]
+
+	var address int
+	if address, err = conf.FieldInt(mbiFieldRegAddress); err != nil {
+		return
+	}
+	if address < 0 || address > math.MaxUint16 {
+		err = fmt.Errorf("register %v has address %v outside of the range 0 to 65535", r.name, address)
+		return
+	}
+	r.address = uint16(address)
+
+	r.typ = "uint16"
+	if mbIsBitFunction(r.function) {
+		r.typ = "bool"
+	}
+	if conf.Contains(mbiFieldRegType) {
+		if r.typ, err = conf.FieldString(mbiFieldRegType); err != nil {
+			return
+		}
+	}
+	if mbIsBitFunction(r.function) != (r.typ == "bool") {
+		err = fmt.Errorf("register %v of table %v cannot have type %v", r.name, table, r.typ)
+		return
+	}
+
+	switch r.typ {
+	case "bool", "int16", "uint16":
+		r.quantity = 1
+	case "int32", "uint32", "float32":
+		r.quantity = 2
+	case "int64", "uint64", "float64":
+		r.quantity = 4
+	case "string":
+		var length int
+		if length, err = conf.FieldInt(mbiFieldRegLength); err != nil {
+			return
+		}
+		if length < 1 || length > mbMaxRegisters {
+			err = fmt.Errorf("register %v has length %v outside of the range 1 to %v", r.name, length, mbMaxRegisters)
+			return
+		}
+		r.quantity = uint16(length)
+	}
+	if int(r.address)+int(r.quantity) > math.MaxUint16+1 {
+		err = fmt.Errorf("register %v exceeds the last address", r.name)
+		return
+	}
+
+	var byteOrder, wordOrder string
+	if byteOrder, err = conf.FieldString(mbiFieldRegByteOrder); err != nil {
+		return
+	}
+	if wordOrder, err = conf.FieldString(mbiFieldRegWordOrder); err != nil {
+		return
+	}
+	r.byteOrder, r.wordOrder = mbOrder(byteOrder), mbOrder(wordOrder)
+
+	if conf.Contains(mbiFieldRegScale) {
+		var scale float64
+		if scale, err = conf.FieldFloat(mbiFieldRegScale); err != nil {
+			return
+		}
+		if r.typ == "bool" || r.typ == "string" {
+			err = fmt.Errorf("register %v of type %v cannot be scaled", r.name, r.typ)
+			return
+		}
+		r.scale = &scale
+	}
+	return
+}
+
+// decode returns the value of a register from the big-endian values of its
+// registers, or from the bit of a coil or discrete input.
+func (r *mbRegister) decode(data []byte) any {
+	if r.typ == "bool" {
+		return data[0] != 0
+	}
+
+	// The registers are reordered such that the value is big-endian, which
+	// is the order of Modbus itself.
+	b := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i += 2 {
+		b = append(b, data[i:i+2]...)
+		if r.byteOrder == binary.LittleEndian {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	if r.wordOrder == binary.LittleEndian {
+		for i, j := 0, len(b)-2; i < j; i, j = i+2, j-2 {
+			b[i], b[i+1], b[j], b[j+1] = b[j], b[j+1], b[i], b[i+1]
+		}
+	}
+
+	var v any
+	switch r.typ {
+	case "int16":
+		v = int64(int16(binary.BigEndian.Uint16(b)))
+	case "uint16":
+		v = int64(binary.BigEndian.Uint16(b))
+	case "int32":
+		v = int64(int32(binary.BigEndian.Uint32(b)))
+	case "uint32":
+		v = int64(binary.BigEndian.Uint32(b))
+	case "int64":
+		v = int64(binary.BigEndian.Uint64(b))
+	case "uint64":
+		v = binary.BigEndian.Uint64(b)
+	case "float32":
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case "float64":
+		v = math.Float64frombits(binary.BigEndian.Uint64(b))
+	case "string":
+		return strings.TrimRight(string(b), "\x00")
+	}
+	if r.scale == nil {
+		return v
+	}
+	switch n := v.(type) {
+	case int64:
+		return float64(n) * *r.scale
+	case uint64:
+		return float64(n) * *r.scale
+	}
+	return v.(float64) * *r.scale
+}
+
+// mbRead is a single request that reads a range of a table, which covers one
+// or more registers.
+type mbRead struct {
+	function  byte
+	address   uint16
+	quantity  uint16
+	registers []*mbRegister
+}
+
+// mbPlanReads groups registers of the same table that are adjacent or overlap
+// into as few requests as possible.
+func mbPlanReads(registers []*mbRegister) []*mbRead {
+	sorted := append([]*mbRegister(nil), registers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].function != sorted[j].function {
+			return sorted[i].function < sorted[j].function
+		}
+		return sorted[i].address < sorted[j].address
+	})
+
+	var reads []*mbRead
+	var current *mbRead
+	for _, r := range sorted {
+		limit := mbMaxRegisters
+		if mbIsBitFunction(r.function) {
+			limit = mbMaxBits
+		}
+		end := int(r.address) + int(r.quantity)
+		if current != nil && current.function == r.function &&
+			int(r.address) <= int(current.address)+int(current.quantity) &&
+			end-int(current.address) <= limit {
+			if extended := end - int(current.address); extended > int(current.quantity) {
+				current.quantity = uint16(extended)
+			}
+			current.registers = append(current.registers, r)
+			continue
+		}
+		current = &mbRead{function: r.function, address: r.address, quantity: r.quantity, registers: []*mbRegister{r}}
+		reads = append(reads, current)
+	}
+	return reads
+}
+
+// values returns the data of each register of a read from its response.
+func (m *mbRead) values(data []byte, fields map[string]any) error {
+	if mbIsBitFunction(m.function) {
+		if len(data) != (int(m.quantity)+7)/8 {
+			return fmt.Errorf("response has %v bytes of bits, expected %v", len(data), (int(m.quantity)+7)/8)
+		}
+		for _, r := range m.registers {
+			bit := int(r.address - m.address)
+			fields[r.name] = r.decode([]byte{(data[bit/8] >> (bit % 8)) & 1})
+		}
+		return nil
+	}
+
+	if len(data) != int(m.quantity)*2 {
+		return fmt.Errorf("response has %v bytes of registers, expected %v", len(data), int(m.quantity)*2)
+	}
+	for _, r := range m.registers {
+		offset := int(r.address-m.address) * 2
+		fields[r.name] = r.decode(data[offset : offset+int(r.quantity)*2])
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type mbInput struct {
+	address  string
+	unitID   byte
+	interval time.Duration
+	timeout  time.Duration
+	reads    []*mbRead
+	log      *service.Logger
+
+	m        sync.Mutex
+	client   *mbClient
+	lastPoll time.Time
+}
+
+func newMBInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*mbInput, error) {
+	m := &mbInput{log: log}
+
+	var err error
+	if m.address, err = conf.FieldString(mbiFieldAddress); err != nil {
+		return nil, err
+	}
+	var unitID int
+	if unitID, err = conf.FieldInt(mbiFieldUnitID); err != nil {
+		return nil, err
+	}
+	if unitID < 0 || unitID > 255 {
+		return nil, fmt.Errorf("unit id %v is outside of the range 0 to 255", unitID)
+	}
+	m.unitID = byte(unitID)
+	if m.interval, err = conf.FieldDuration(mbiFieldInterval); err != nil {
+		return nil, err
+	}
+	if m.timeout, err = conf.FieldDuration(mbiFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	regConfs, err := conf.FieldObjectList(mbiFieldRegisters)
+	if err != nil {
+		return nil, err
+	}
+	if len(regConfs) == 0 {
+		return nil, errors.New("at least one register must be specified")
+	}
+	names := map[string]struct{}{}
+	var registers []*mbRegister
+	for _, rConf := range regConfs {
+		r, err := mbRegisterFromParsed(rConf)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := names[r.name]; exists {
+			return nil, fmt.Errorf("register name %v is used more than once", r.name)
+		}
+		names[r.name] = struct{}{}
+		registers = append(registers, &r)
+	}
+	m.reads = mbPlanReads(registers)
+	return m, nil
+}
+
+func (m *mbInput) Connect(ctx context.Context) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+
+	client, err := dialMB(ctx, m.address, m.timeout)
+	if err != nil {
+		return err
+	}
+	m.client = client
+	m.log.Infof("Polling Modbus device at address: %v", m.address)
+	return nil
+}
+
+func (m *mbInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if wait := time.Until(m.lastPoll.Add(m.interval)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	m.lastPoll = time.Now()
+
+	fields := map[string]any{}
+	for _, r := range m.reads {
+		data, err := m.client.read(ctx, m.unitID, r.function, r.address, r.quantity)
+		if err == nil {
+			err = r.values(data, fields)
+		}
+		if err != nil {
+			var exception *mbException
+			if !errors.As(err, &exception) && ctx.Err() == nil {
+				m.log.Errorf("Lost connection due to: %v", err)
+				_ = m.client.close()
+				m.client = nil
+				return nil, nil, service.ErrNotConnected
+			}
+			return nil, nil, fmt.Errorf("failed to read %v registers at address %v: %w", len(r.registers), r.address, err)
+		}
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(fields)
+	msg.MetaSetMut("modbus_unit_id", strconv.Itoa(int(m.unitID)))
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (m *mbInput) Close(ctx context.Context) error {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	if m.client != nil {
+		_ = m.client.close()
+		m.client = nil
+	}
+	return nil
+}
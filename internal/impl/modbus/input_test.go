@@ -0,0 +1,208 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testMBInput(t *testing.T, conf string) *mbInput {
+	t.Helper()
+
+	pConf, err := mbInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	in, err := newMBInputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = in.Close(context.Background()) })
+	return in
+}
+
+func readMBMessage(t *testing.T, in *mbInput) (any, map[string]any) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	structured, err := msg.AsStructured()
+	require.NoError(t, err)
+	meta := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	return structured, meta
+}
+
+func TestMBInputTypes(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) {
+		s.holding[0] = 0xff38 // -200
+		s.holding[1] = 0x1234
+
+		// 0x12345678 with the order of registers reversed.
+		s.holding[2] = 0x5678
+		s.holding[3] = 0x1234
+
+		bits := math.Float32bits(21.5)
+		s.holding[4] = uint16(bits >> 16)
+		s.holding[5] = uint16(bits)
+
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(-3.25))
+		for i := 0; i < 4; i++ {
+			s.holding[6+uint16(i)] = binary.BigEndian.Uint16(b[i*2:])
+		}
+
+		s.holding[20] = 'P'<<8 | 'u'
+		s.holding[21] = 'm'<<8 | 'p'
+
+		// Bytes of each register swapped.
+		s.holding[30] = 0x3412
+
+		s.input[0] = 0xffff
+		s.input[1] = 0xfffe
+
+		s.coils[3] = true
+		s.discrete[9] = true
+	})
+
+	in := testMBInput(t, `
+address: `+s.addr+`
+unit_id: 7
+interval: 1ms
+registers:
+  - { name: temp, address: 0, type: int16, scale: 0.1 }
+  - { name: raw, address: 1 }
+  - { name: counter, address: 2, type: uint32, word_order: little }
+  - { name: flow, address: 4, type: float32 }
+  - { name: pressure, address: 6, type: float64 }
+  - { name: label, address: 20, type: string, length: 3 }
+  - { name: swapped, address: 30, byte_order: little }
+  - { name: signed, table: input_register, address: 0, type: int32 }
+  - { name: pump, table: coil, address: 3 }
+  - { name: valve, table: coil, address: 4 }
+  - { name: alarm, table: discrete_input, address: 9 }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	v, meta := readMBMessage(t, in)
+	assert.Equal(t, map[string]any{
+		"temp":     float64(-200) * 0.1,
+		"raw":      int64(0x1234),
+		"counter":  int64(0x12345678),
+		"flow":     21.5,
+		"pressure": -3.25,
+		"label":    "Pump",
+		"swapped":  int64(0x1234),
+		"signed":   int64(-2),
+		"pump":     true,
+		"valve":    false,
+		"alarm":    true,
+	}, v)
+	assert.Equal(t, map[string]any{"modbus_unit_id": "7"}, meta)
+
+	// Adjacent registers of a table are read together.
+	assert.ElementsMatch(t, []testRequest{
+		{unitID: 7, function: mbFuncReadCoils, address: 3, quantity: 2},
+		{unitID: 7, function: mbFuncReadDiscreteInputs, address: 9, quantity: 1},
+		{unitID: 7, function: mbFuncReadHoldingRegisters, address: 0, quantity: 10},
+		{unitID: 7, function: mbFuncReadHoldingRegisters, address: 20, quantity: 3},
+		{unitID: 7, function: mbFuncReadHoldingRegisters, address: 30, quantity: 1},
+		{unitID: 7, function: mbFuncReadInputRegisters, address: 0, quantity: 2},
+	}, s.received())
+
+	s.set(func(s *testServer) { s.coils[4] = true })
+	v, _ = readMBMessage(t, in)
+	assert.Equal(t, true, v.(map[string]any)["valve"])
+}
+
+func TestMBPlanReads(t *testing.T) {
+	var registers []*mbRegister
+	for _, r := range []struct {
+		function byte
+		address  uint16
+		quantity uint16
+	}{
+		{mbFuncReadHoldingRegisters, 10, 2},
+		{mbFuncReadHoldingRegisters, 0, 4},
+		{mbFuncReadHoldingRegisters, 2, 2},
+		{mbFuncReadHoldingRegisters, 100, 4},
+		{mbFuncReadHoldingRegisters, 220, 4},
+		{mbFuncReadHoldingRegisters, 104, 100},
+		{mbFuncReadCoils, 0, 1000},
+		{mbFuncReadCoils, 1000, 1000},
+		{mbFuncReadCoils, 2000, 1},
+	} {
+		registers = append(registers, &mbRegister{function: r.function, address: r.address, quantity: r.quantity})
+	}
+
+	var reads [][3]int
+	for _, r := range mbPlanReads(registers) {
+		reads = append(reads, [3]int{int(r.function), int(r.address), int(r.quantity)})
+	}
+	assert.Equal(t, [][3]int{
+		{int(mbFuncReadCoils), 0, 2000},
+		{int(mbFuncReadCoils), 2000, 1},
+		{int(mbFuncReadHoldingRegisters), 0, 4},
+		{int(mbFuncReadHoldingRegisters), 10, 2},
+		{int(mbFuncReadHoldingRegisters), 100, 104},
+		{int(mbFuncReadHoldingRegisters), 220, 4},
+	}, reads)
+}
+
+func TestMBInputErrors(t *testing.T) {
+	s := startTestServer(t)
+	in := testMBInput(t, `
+address: `+s.addr+`
+interval: 1ms
+registers:
+  - { name: temp, address: 0 }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	// Exceptions fail the poll without closing the connection.
+	s.set(func(s *testServer) { s.exception = 0x02 })
+	_, _, err := in.Read(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exception 0x02 (illegal data address)")
+
+	s.set(func(s *testServer) { s.exception = 0 })
+	v, _ := readMBMessage(t, in)
+	assert.Equal(t, map[string]any{"temp": int64(0)}, v)
+
+	// Losing the connection requires a reconnect.
+	s.dropConnections()
+	_, _, err = in.Read(context.Background())
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, in.Connect(context.Background()))
+	_, _ = readMBMessage(t, in)
+}
+
+func TestMBInputConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		`registers: []`,
+		`registers: [ { name: a, table: coil, address: 0, type: int16 } ]`,
+		`registers: [ { name: a, address: 0, type: bool } ]`,
+		`registers: [ { name: a, address: 65535, type: uint32 } ]`,
+		`registers: [ { name: a, address: 0, type: string, scale: 2 } ]`,
+		`registers: [ { name: a, address: 0 }, { name: a, address: 1 } ]`,
+		"unit_id: 256\nregisters: [ { name: a, address: 0 } ]",
+	} {
+		pConf, err := mbInputSpec().ParseYAML("address: localhost:502\n"+conf, nil)
+		require.NoError(t, err, conf)
+		_, err = newMBInputFromParsed(pConf, service.MockResources().Logger())
+		require.Error(t, err, conf)
+	}
+}
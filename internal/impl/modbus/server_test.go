@@ -0,0 +1,143 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	unitID   byte
+	function byte
+	address  uint16
+	quantity uint16
+}
+
+// testServer is a Modbus TCP device with tables that tests can modify.
+type testServer struct {
+	addr string
+	ln   net.Listener
+
+	mut       sync.Mutex
+	coils     map[uint16]bool
+	discrete  map[uint16]bool
+	holding   map[uint16]uint16
+	input     map[uint16]uint16
+	requests  []testRequest
+	exception byte
+	conns     []net.Conn
+}
+
+func startTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &testServer{
+		addr:     ln.Addr().String(),
+		ln:       ln,
+		coils:    map[uint16]bool{},
+		discrete: map[uint16]bool{},
+		holding:  map[uint16]uint16{},
+		input:    map[uint16]uint16{},
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+		s.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns = append(s.conns, conn)
+			s.mut.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) set(fn func(s *testServer)) {
+	s.mut.Lock()
+	fn(s)
+	s.mut.Unlock()
+}
+
+func (s *testServer) received() []testRequest {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]testRequest(nil), s.requests...)
+}
+
+func (s *testServer) dropConnections() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		req := make([]byte, mbHeaderLen+5)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		r := testRequest{
+			unitID:   req[6],
+			function: req[7],
+			address:  binary.BigEndian.Uint16(req[8:]),
+			quantity: binary.BigEndian.Uint16(req[10:]),
+		}
+
+		s.mut.Lock()
+		s.requests = append(s.requests, r)
+		var pdu []byte
+		switch {
+		case s.exception != 0:
+			pdu = []byte{r.function | 0x80, s.exception}
+		case r.function == mbFuncReadCoils || r.function == mbFuncReadDiscreteInputs:
+			table := s.coils
+			if r.function == mbFuncReadDiscreteInputs {
+				table = s.discrete
+			}
+			bits := make([]byte, (int(r.quantity)+7)/8)
+			for i := 0; i < int(r.quantity); i++ {
+				if table[r.address+uint16(i)] {
+					bits[i/8] |= 1 << (i % 8)
+				}
+			}
+			pdu = append([]byte{r.function, byte(len(bits))}, bits...)
+		default:
+			table := s.holding
+			if r.function == mbFuncReadInputRegisters {
+				table = s.input
+			}
+			pdu = []byte{r.function, byte(r.quantity * 2)}
+			for i := 0; i < int(r.quantity); i++ {
+				pdu = append(pdu, 0, 0)
+				binary.BigEndian.PutUint16(pdu[len(pdu)-2:], table[r.address+uint16(i)])
+			}
+		}
+		s.mut.Unlock()
+
+		res := make([]byte, mbHeaderLen, mbHeaderLen+len(pdu))
+		copy(res, req[:4])
+		binary.BigEndian.PutUint16(res[4:], uint16(len(pdu)+1))
+		res[6] = r.unitID
+		if _, err := conn.Write(append(res, pdu...)); err != nil {
+			return
+		}
+	}
+}
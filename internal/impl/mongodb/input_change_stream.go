@@ -0,0 +1,428 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/internal/impl/mongodb/client"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	csiFieldDatabase        = "database"
+	csiFieldCollection      = "collection"
+	csiFieldPipeline        = "pipeline"
+	csiFieldFullDocument    = "full_document"
+	csiFieldCache           = "cache"
+	csiFieldCacheKey        = "cache_key"
+	csiFieldCheckpointLimit = "checkpoint_limit"
+	csiFieldJSONMarshalMode = "json_marshal_mode"
+)
+
+func mongoChangeStreamInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes the events of a MongoDB change stream, which tails the changes made to a collection or to all collections of a database.").
+		Description(`
+Each [change event](https://www.mongodb.com/docs/manual/reference/change-events/) of the stream is consumed as a message, and is marshalled to JSON in the format of ` + "`json_marshal_mode`" + `. Change streams are only available on replica sets and sharded clusters.
+
+### Resume Tokens
+
+The resume token of an event is stored within the ` + "`cache`" + ` once the event has been delivered, along with all of the events before it, and the input resumes after the stored token when restarted. When no token is stored the input starts from the latest change. Events are therefore delivered at least once, and can only be resumed from as long as they remain within the oplog of the cluster. Resuming requires MongoDB 4.2 or later.
+
+When the stream is invalidated, for example when the watched collection is dropped or renamed, the ` + "`invalidate`" + ` event is delivered and the stream is opened again after it.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- mongodb_operation_type
+- mongodb_database
+- mongodb_collection
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewStringField(csiFieldDatabase).
+			Description("The name of the database to watch.")).
+		Field(service.NewStringField(csiFieldCollection).
+			Description("The name of the collection to watch. When empty the changes of all collections of the database are consumed.").
+			Default("")).
+		Field(service.NewBloblangField(csiFieldPipeline).
+			Description("An optional Bloblang mapping that produces an array of [aggregation pipeline stages](https://www.mongodb.com/docs/manual/changeStreams/#modify-change-stream-output) to filter or modify the events of the stream with. The `_id` field of events must not be modified, as it is the resume token of the event.").
+			Example(`root = [ { "$match": { "operationType": { "$in": [ "insert", "update" ] } } } ]`).
+			Optional()).
+		Field(service.NewStringAnnotatedEnumField(csiFieldFullDocument, map[string]string{
+			string(options.Default):      "The events of updates only contain the fields that were changed.",
+			string(options.UpdateLookup): "The events of updates also contain the current version of the updated document in the field `fullDocument`.",
+		}).
+			Description("Whether the events of updates contain the full document.").
+			Default(string(options.Default))).
+		Field(service.NewStringField(csiFieldCache).
+			Description("A [cache resource](/docs/components/caches/about) to store the resume token within.")).
+		Field(service.NewStringField(csiFieldCacheKey).
+			Description("The key to store the resume token at within the cache. When empty the key is the name of the database, followed by the name of the collection when set, separated by a dot.").
+			Advanced().
+			Default("")).
+		Field(service.NewIntField(csiFieldCheckpointLimit).
+			Description("The maximum number of events that can be in flight before the input waits for them to be delivered.").
+			Advanced().
+			Default(1024)).
+		Field(service.NewStringAnnotatedEnumField(csiFieldJSONMarshalMode, map[string]string{
+			string(client.JSONMarshalModeCanonical): "A string format that emphasizes type preservation at the expense of readability and interoperability. " +
+				"That is, conversion from canonical to BSON will generally preserve type information except in certain specific cases. ",
+			string(client.JSONMarshalModeRelaxed): "A string format that emphasizes readability and interoperability at the expense of type preservation." +
+				"That is, conversion from relaxed format to BSON can lose type information.",
+		}).
+			Description("Controls the format of the output message.").
+			Default(string(client.JSONMarshalModeCanonical)).
+			Advanced()).
+		Example(
+			"Mirroring a Collection into Kafka",
+			"In this example the changes of a collection are written to a Kafka topic keyed by the ID of the changed document, where resume tokens are stored within a Redis cache.",
+			`
+input:
+  mongodb_change_stream:
+    url: mongodb://localhost:27017/?replicaSet=rs0
+    database: shop
+    collection: orders
+    full_document: updateLookup
+    cache: tokens
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders_changes
+    key: ${! json("documentKey._id") }
+
+cache_resources:
+  - label: tokens
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mongodb_change_stream", mongoChangeStreamInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newMongoChangeStreamInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type mongoChangeStreamInput struct {
+	newClient    func() (*mongo.Client, error)
+	database     string
+	collection   string
+	pipeline     any
+	fullDocument options.FullDocument
+	cache        string
+	cacheKey     string
+	marshalCanon bool
+	mgr          *service.Resources
+	log          *service.Logger
+
+	checkpoints *checkpoint.Capped
+	commitMut   sync.Mutex
+
+	mut         sync.Mutex
+	client      *mongo.Client
+	stream      *mongo.ChangeStream
+	tokenLoaded bool
+	token       bson.Raw
+}
+
+func newMongoChangeStreamInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*mongoChangeStreamInput, error) {
+	m := &mongoChangeStreamInput{
+		newClient: func() (*mongo.Client, error) {
+			return getClient(conf)
+		},
+		mgr:      mgr,
+		log:      mgr.Logger(),
+		pipeline: []any{},
+	}
+
+	var err error
+	if m.database, err = conf.FieldString(csiFieldDatabase); err != nil {
+		return nil, err
+	}
+	if m.collection, err = conf.FieldString(csiFieldCollection); err != nil {
+		return nil, err
+	}
+	if conf.Contains(csiFieldPipeline) {
+		mapping, err := conf.FieldBloblang(csiFieldPipeline)
+		if err != nil {
+			return nil, err
+		}
+		if m.pipeline, err = mapping.Query(struct{}{}); err != nil {
+			return nil, fmt.Errorf("failed to execute %v mapping: %w", csiFieldPipeline, err)
+		}
+		if _, isArray := m.pipeline.([]any); !isArray {
+			return nil, fmt.Errorf("%v mapping must produce an array of stages, got %T", csiFieldPipeline, m.pipeline)
+		}
+	}
+
+	fullDocument, err := conf.FieldString(csiFieldFullDocument)
+	if err != nil {
+		return nil, err
+	}
+	m.fullDocument = options.FullDocument(fullDocument)
+
+	if m.cache, err = conf.FieldString(csiFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(m.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", m.cache)
+	}
+	if m.cacheKey, err = conf.FieldString(csiFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if m.cacheKey == "" {
+		m.cacheKey = m.database
+		if m.collection != "" {
+			m.cacheKey += "." + m.collection
+		}
+	}
+
+	checkpointLimit, err := conf.FieldInt(csiFieldCheckpointLimit)
+	if err != nil {
+		return nil, err
+	}
+	if checkpointLimit < 1 {
+		return nil, fmt.Errorf("%v must be greater than zero", csiFieldCheckpointLimit)
+	}
+	m.checkpoints = checkpoint.NewCapped(int64(checkpointLimit))
+
+	marshalMode, err := conf.FieldString(csiFieldJSONMarshalMode)
+	if err != nil {
+		return nil, err
+	}
+	m.marshalCanon = marshalMode == string(client.JSONMarshalModeCanonical)
+	return m, nil
+}
+
+// loadToken obtains the resume token stored within the cache, which is only
+// done once as the token of the last event read is resumed from when the
+// stream is opened again.
+func (m *mongoChangeStreamInput) loadToken(ctx context.Context) error {
+	if m.tokenLoaded {
+		return nil
+	}
+
+	var stored []byte
+	var cErr error
+	if err := m.mgr.AccessCache(ctx, m.cache, func(c service.Cache) {
+		stored, cErr = c.Get(ctx, m.cacheKey)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil && !errors.Is(cErr, service.ErrKeyNotFound) {
+		return fmt.Errorf("failed to obtain stored resume token: %w", cErr)
+	}
+
+	if len(stored) > 0 {
+		var token bson.D
+		if err := bson.UnmarshalExtJSON(stored, true, &token); err != nil {
+			return fmt.Errorf("failed to parse stored resume token: %w", err)
+		}
+		raw, err := bson.Marshal(token)
+		if err != nil {
+			return err
+		}
+		m.token = raw
+	}
+	m.tokenLoaded = true
+	return nil
+}
+
+func (m *mongoChangeStreamInput) storeToken(ctx context.Context, token bson.Raw) error {
+	stored, err := bson.MarshalExtJSON(token, true, false)
+	if err != nil {
+		return err
+	}
+
+	var cErr error
+	if err := m.mgr.AccessCache(ctx, m.cache, func(c service.Cache) {
+		cErr = c.Set(ctx, m.cacheKey, stored, nil)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil {
+		return fmt.Errorf("failed to store resume token: %w", cErr)
+	}
+	return nil
+}
+
+func (m *mongoChangeStreamInput) Connect(ctx context.Context) (err error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.stream != nil {
+		return nil
+	}
+	if err = m.loadToken(ctx); err != nil {
+		return err
+	}
+
+	var mClient *mongo.Client
+	if mClient, err = m.newClient(); err != nil {
+		return err
+	}
+	if err = mClient.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = mClient.Disconnect(ctx)
+		}
+	}()
+
+	opts := options.ChangeStream().SetFullDocument(m.fullDocument)
+	if m.token != nil {
+		opts.SetStartAfter(m.token)
+	}
+
+	var stream *mongo.ChangeStream
+	db := mClient.Database(m.database)
+	if m.collection == "" {
+		stream, err = db.Watch(ctx, m.pipeline, opts)
+	} else {
+		stream, err = db.Collection(m.collection).Watch(ctx, m.pipeline, opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+
+	m.client, m.stream = mClient, stream
+	if m.token != nil {
+		m.log.Infof("Resuming change stream of %v from stored resume token", m.cacheKey)
+	} else {
+		m.log.Infof("Consuming change stream of %v from the latest change", m.cacheKey)
+	}
+	return nil
+}
+
+func (m *mongoChangeStreamInput) disconnect(ctx context.Context) {
+	if m.stream != nil {
+		_ = m.stream.Close(ctx)
+		m.stream = nil
+	}
+	if m.client != nil {
+		_ = m.client.Disconnect(ctx)
+		m.client = nil
+	}
+}
+
+// eventToken returns a copy of the resume token of an event, which is its ID.
+func eventToken(event bson.Raw) (bson.Raw, error) {
+	id, err := event.LookupErr("_id")
+	if err != nil {
+		return nil, errors.New("change event does not contain a resume token")
+	}
+	if id.Type != bsontype.EmbeddedDocument {
+		return nil, fmt.Errorf("expected resume token of type document, got %v", id.Type)
+	}
+	return append(bson.Raw(nil), id.Value...), nil
+}
+
+func changeEventMessage(event bson.Raw, marshalCanon bool) (*service.Message, error) {
+	data, err := bson.MarshalExtJSON(event, marshalCanon, false)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := service.NewMessage(data)
+	if v, ok := event.Lookup("operationType").StringValueOK(); ok {
+		msg.MetaSetMut("mongodb_operation_type", v)
+	}
+	if v, ok := event.Lookup("ns", "db").StringValueOK(); ok {
+		msg.MetaSetMut("mongodb_database", v)
+	}
+	if v, ok := event.Lookup("ns", "coll").StringValueOK(); ok {
+		msg.MetaSetMut("mongodb_collection", v)
+	}
+	return msg, nil
+}
+
+func (m *mongoChangeStreamInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.stream == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if !m.stream.Next(ctx) {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err := m.stream.Err(); err != nil {
+			m.log.Errorf("Failed to read change stream: %v", err)
+		} else {
+			m.log.Warnf("Change stream of %v was closed", m.cacheKey)
+		}
+		m.disconnect(ctx)
+		return nil, nil, service.ErrNotConnected
+	}
+
+	token, err := eventToken(m.stream.Current)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, err := changeEventMessage(m.stream.Current, m.marshalCanon)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release, err := m.checkpoints.Track(ctx, token, 1)
+	if err != nil {
+		// The event is read again once the stream is opened after the last
+		// event that was tracked.
+		m.disconnect(ctx)
+		return nil, nil, err
+	}
+	m.token = token
+
+	return msg, func(ctx context.Context, err error) error {
+		m.commitMut.Lock()
+		defer m.commitMut.Unlock()
+
+		highest := release()
+		if highest == nil {
+			return nil
+		}
+		return m.storeToken(ctx, highest.(bson.Raw))
+	}, nil
+}
+
+func (m *mongoChangeStreamInput) Close(ctx context.Context) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.disconnect(ctx)
+	return nil
+}
@@ -0,0 +1,172 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testChangeStreamInput(t *testing.T, conf string) (*mongoChangeStreamInput, error) {
+	t.Helper()
+
+	parsed, err := mongoChangeStreamInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	return newMongoChangeStreamInputFromParsed(parsed, service.MockResources(service.MockResourcesOptAddCache("tokens")))
+}
+
+func TestChangeStreamInputConfig(t *testing.T) {
+	m, err := testChangeStreamInput(t, `
+url: mongodb://localhost:27017
+database: shop
+collection: orders
+cache: tokens
+pipeline: 'root = [ { "$match": { "operationType": "insert" } } ]'
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "shop.orders", m.cacheKey)
+	assert.Equal(t, []any{
+		map[string]any{"$match": map[string]any{"operationType": "insert"}},
+	}, m.pipeline)
+
+	m, err = testChangeStreamInput(t, `
+url: mongodb://localhost:27017
+database: shop
+cache: tokens
+`)
+	require.NoError(t, err)
+	assert.Equal(t, "shop", m.cacheKey)
+	assert.Equal(t, []any{}, m.pipeline)
+}
+
+func TestChangeStreamInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		conf   string
+		errStr string
+	}{
+		{
+			name: "missing cache",
+			conf: `
+url: mongodb://localhost:27017
+database: shop
+cache: nope
+`,
+			errStr: "cache resource 'nope' was not found",
+		},
+		{
+			name: "pipeline not an array",
+			conf: `
+url: mongodb://localhost:27017
+database: shop
+cache: tokens
+pipeline: 'root = { "$match": {} }'
+`,
+			errStr: "pipeline mapping must produce an array of stages, got map[string]interface {}",
+		},
+		{
+			name: "bad checkpoint limit",
+			conf: `
+url: mongodb://localhost:27017
+database: shop
+cache: tokens
+checkpoint_limit: 0
+`,
+			errStr: "checkpoint_limit must be greater than zero",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := testChangeStreamInput(t, test.conf)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestChangeStreamInputTokens(t *testing.T) {
+	ctx := context.Background()
+
+	m, err := testChangeStreamInput(t, `
+url: mongodb://localhost:27017
+database: shop
+collection: orders
+cache: tokens
+`)
+	require.NoError(t, err)
+
+	require.NoError(t, m.loadToken(ctx))
+	assert.Nil(t, m.token)
+
+	event, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "8263A1B2C3000000012B0229296E04"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "shop"}, {Key: "coll", Value: "orders"}}},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: int32(5)}}},
+		{Key: "fullDocument", Value: bson.D{{Key: "_id", Value: int32(5)}, {Key: "total", Value: 10.5}}},
+	})
+	require.NoError(t, err)
+
+	token, err := eventToken(event)
+	require.NoError(t, err)
+	require.NoError(t, m.storeToken(ctx, token))
+
+	var stored []byte
+	require.NoError(t, m.mgr.AccessCache(ctx, "tokens", func(c service.Cache) {
+		stored, err = c.Get(ctx, "shop.orders")
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, `{"_data":"8263A1B2C3000000012B0229296E04"}`, string(stored))
+
+	// A restarted input resumes from the stored token.
+	m.tokenLoaded, m.token = false, nil
+	require.NoError(t, m.loadToken(ctx))
+	assert.Equal(t, token, m.token)
+
+	noToken, err := bson.Marshal(bson.D{{Key: "operationType", Value: "insert"}})
+	require.NoError(t, err)
+	_, err = eventToken(noToken)
+	require.EqualError(t, err, "change event does not contain a resume token")
+}
+
+func TestChangeEventMessage(t *testing.T) {
+	event, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "foo"}}},
+		{Key: "operationType", Value: "update"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "shop"}, {Key: "coll", Value: "orders"}}},
+		{Key: "updateDescription", Value: bson.D{{Key: "updatedFields", Value: bson.D{{Key: "total", Value: int32(11)}}}}},
+	})
+	require.NoError(t, err)
+
+	msg, err := changeEventMessage(event, false)
+	require.NoError(t, err)
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "_id": {"_data": "foo"},
+  "operationType": "update",
+  "ns": {"db": "shop", "coll": "orders"},
+  "updateDescription": {"updatedFields": {"total": 11}}
+}`, string(b))
+
+	for k, v := range map[string]string{
+		"mongodb_operation_type": "update",
+		"mongodb_database":       "shop",
+		"mongodb_collection":     "orders",
+	} {
+		actual, exists := msg.MetaGet(k)
+		assert.True(t, exists, k)
+		assert.Equal(t, v, actual, k)
+	}
+
+	msg, err = changeEventMessage(event, true)
+	require.NoError(t, err)
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `{"$numberInt":"11"}`)
+}
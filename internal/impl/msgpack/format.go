@@ -0,0 +1,50 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func init() {
+	message.RegisterStructuredFormat(message.StructuredFormat{
+		Name:   "msgpack",
+		Detect: detectMsgPack,
+		Parse:  parseMsgPack,
+	})
+}
+
+// detectMsgPack returns true when the first byte of a payload is a MessagePack
+// map or array marker, none of which are valid at the start of a JSON document.
+func detectMsgPack(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+	switch c := b[0]; {
+	case c >= 0x80 && c <= 0x9f: // fixmap and fixarray
+		return true
+	case c == 0xdc || c == 0xdd: // array 16 and 32
+		return true
+	case c == 0xde || c == 0xdf: // map 16 and 32
+		return true
+	}
+	return false
+}
+
+func parseMsgPack(b []byte) (any, error) {
+	r := bytes.NewReader(b)
+	dec := msgpack.NewDecoder(r)
+	dec.UseLooseInterfaceDecoding(true)
+
+	v, err := dec.DecodeInterface()
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() > 0 {
+		return nil, errors.New("message contains trailing data")
+	}
+	return v, nil
+}
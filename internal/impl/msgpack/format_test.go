@@ -0,0 +1,47 @@
+package msgpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestMsgPackFormatDetection(t *testing.T) {
+	formats, err := message.GetStructuredFormats("msgpack")
+	require.NoError(t, err)
+
+	newPart := func(b []byte) *message.Part {
+		p := message.NewPart(b)
+		p.SetStructuredFormats(formats)
+		return p
+	}
+
+	b, err := msgpack.Marshal(map[string]any{
+		"foo": "bar",
+		"baz": []any{1, 2.5, true},
+	})
+	require.NoError(t, err)
+
+	part := newPart(b)
+	v, err := part.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"foo": "bar",
+		"baz": []any{int64(1), 2.5, true},
+	}, v)
+
+	// JSON documents are still parsed as JSON.
+	v, err = newPart([]byte(`{"foo":"bar"}`)).AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, v)
+
+	_, err = newPart(append(b, 0x01)).AsStructured()
+	require.Error(t, err)
+
+	_, err = newPart([]byte("hello world")).AsStructured()
+	require.Error(t, err)
+}
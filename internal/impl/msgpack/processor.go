@@ -14,6 +14,24 @@ func processorConfig() *service.ConfigSpec {
 		// Stable(). TODO
 		Categories("Parsing").
 		Summary("Converts messages to or from the [MessagePack](https://msgpack.org/) format.").
+		Description(`
+### Format Detection
+
+When the field ` + "`detect_formats`" + ` of an input contains ` + "`msgpack`" + ` the messages it consumes that contain a MessagePack map or array are detected and parsed lazily the first time their structured contents are accessed, such as by a Bloblang mapping, without the need for this processor. The parsed result is reused by all subsequent processors until the message contents are replaced:
+
+` + "```yaml" + `
+input:
+  detect_formats: [ msgpack ]
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: benthos
+
+pipeline:
+  processors:
+    - mapping: 'root.name = this.name.uppercase()'
+` + "```" + `
+`).
 		Field(service.NewStringAnnotatedEnumField("operator", map[string]string{
 			"to_json":   "Convert MessagePack messages to JSON format",
 			"from_json": "Convert JSON messages to MessagePack format",
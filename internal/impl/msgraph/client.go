@@ -0,0 +1,297 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	mgFieldOAuth2       = "oauth2"
+	mgFieldTenantID     = "tenant_id"
+	mgFieldClientID     = "client_id"
+	mgFieldClientSecret = "client_secret"
+	mgFieldRefreshToken = "refresh_token"
+	mgFieldTokenURL     = "token_url"
+	mgFieldAPIURL       = "api_url"
+	mgFieldDriveID      = "drive_id"
+	mgFieldSiteID       = "site_id"
+
+	graphScope = "https://graph.microsoft.com/.default"
+)
+
+func graphFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewObjectField(mgFieldOAuth2,
+			service.NewStringField(mgFieldTenantID).
+				Description("The ID of the Azure AD tenant of the application.").
+				Default("common"),
+			service.NewStringField(mgFieldClientID).
+				Description("The client ID of the application."),
+			service.NewStringField(mgFieldClientSecret).
+				Description("A client secret of the application.").
+				Default(""),
+			service.NewStringField(mgFieldRefreshToken).
+				Description("A refresh token of a user that has authorised the application, which is used in order to obtain access tokens on behalf of the user. When empty access tokens are obtained for the application itself with the client credentials flow, which requires a specific tenant.").
+				Default(""),
+			service.NewStringField(mgFieldTokenURL).
+				Description("The URL to obtain access tokens from, which defaults to the token endpoint of the tenant.").
+				Advanced().
+				Default(""),
+		).Description("Credentials of an application registered with Azure AD with access to Microsoft Graph."),
+		service.NewStringField(mgFieldDriveID).
+			Description("The ID of a drive, which when empty defaults to the default document library of `site_id`, or otherwise the OneDrive of the user of the refresh token.").
+			Default(""),
+		service.NewStringField(mgFieldSiteID).
+			Description("The ID of a SharePoint site, the default document library of which is used when `drive_id` is empty.").
+			Example("contoso.sharepoint.com,2c0f8ed2-1bf2-4a5f-a7c8-1c0a9f54b1a7,712a596e-90a1-49e3-9b48-bfa80bee8740").
+			Default(""),
+		service.NewStringField(mgFieldAPIURL).
+			Description("The base URL of the Microsoft Graph API.").
+			Advanced().
+			Default("https://graph.microsoft.com/v1.0"),
+	}
+}
+
+// graphClient makes requests to the Microsoft Graph API against the items of
+// a drive.
+type graphClient struct {
+	// The URL of the drive, e.g. https://graph.microsoft.com/v1.0/me/drive
+	driveURL string
+	http     *http.Client
+	// A client without credentials for pre-authenticated URLs, which must not
+	// receive access tokens.
+	plain *http.Client
+}
+
+func graphClientFromParsed(conf *service.ParsedConfig) (*graphClient, error) {
+	apiURL, err := conf.FieldString(mgFieldAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	driveID, err := conf.FieldString(mgFieldDriveID)
+	if err != nil {
+		return nil, err
+	}
+	siteID, err := conf.FieldString(mgFieldSiteID)
+	if err != nil {
+		return nil, err
+	}
+
+	aConf := conf.Namespace(mgFieldOAuth2)
+	tenantID, err := aConf.FieldString(mgFieldTenantID)
+	if err != nil {
+		return nil, err
+	}
+	clientID, err := aConf.FieldString(mgFieldClientID)
+	if err != nil {
+		return nil, err
+	}
+	clientSecret, err := aConf.FieldString(mgFieldClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := aConf.FieldString(mgFieldRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	tokenURL, err := aConf.FieldString(mgFieldTokenURL)
+	if err != nil {
+		return nil, err
+	}
+	if tokenURL == "" {
+		if refreshToken == "" && tenantID == "common" {
+			return nil, fmt.Errorf("a specific %v must be specified when a %v is not", mgFieldTenantID, mgFieldRefreshToken)
+		}
+		tokenURL = fmt.Sprintf("https://login.microsoftonline.com/%v/oauth2/v2.0/token", url.PathEscape(tenantID))
+	}
+
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	g := &graphClient{plain: &http.Client{}}
+	switch {
+	case driveID != "":
+		g.driveURL = apiURL + "/drives/" + url.PathEscape(driveID)
+	case siteID != "":
+		g.driveURL = apiURL + "/sites/" + url.PathEscape(siteID) + "/drive"
+	case refreshToken != "":
+		g.driveURL = apiURL + "/me/drive"
+	default:
+		return nil, fmt.Errorf("either a %v or %v must be specified when a %v is not", mgFieldDriveID, mgFieldSiteID, mgFieldRefreshToken)
+	}
+
+	// The oauth2 clients fetch new access tokens with this context throughout
+	// the lifetime of the graph client rather than only during construction.
+	ctx := context.Background()
+	if refreshToken != "" {
+		oConf := &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+			Scopes:       []string{graphScope, "offline_access"},
+		}
+		g.http = oConf.Client(ctx, &oauth2.Token{RefreshToken: refreshToken})
+	} else {
+		cConf := &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       []string{graphScope},
+			AuthStyle:    oauth2.AuthStyleInParams,
+		}
+		g.http = cConf.Client(ctx)
+	}
+	return g, nil
+}
+
+// graphError is an error response of the Microsoft Graph API.
+type graphError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("%v: %v (%v)", e.Status, e.Message, e.Code)
+}
+
+// graphIsPermanent returns whether an error is the result of the request
+// itself, and therefore will not succeed when retried.
+func graphIsPermanent(err error) bool {
+	var gErr *graphError
+	if !errors.As(err, &gErr) {
+		return false
+	}
+	return gErr.Status >= 400 && gErr.Status < 500 && gErr.Status != http.StatusRequestTimeout && gErr.Status != http.StatusTooManyRequests
+}
+
+func readGraphError(res *http.Response) error {
+	gErr := &graphError{Status: res.StatusCode}
+	var body struct {
+		Error *graphError `json:"error"`
+	}
+	if b, _ := io.ReadAll(res.Body); json.Unmarshal(b, &body) == nil && body.Error != nil {
+		gErr.Code, gErr.Message = body.Error.Code, body.Error.Message
+	} else {
+		gErr.Message = strings.TrimSpace(string(b))
+	}
+	return gErr
+}
+
+// itemURL returns the URL of the item of the drive at a path, where the path
+// of the root is "/".
+func (g *graphClient) itemURL(itemPath string) string {
+	itemPath = strings.Trim(itemPath, "/")
+	if itemPath == "" {
+		return g.driveURL + "/root"
+	}
+	segments := strings.Split(itemPath, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return g.driveURL + "/root:/" + strings.Join(segments, "/") + ":"
+}
+
+// do sends a request, decoding a JSON response into out when it is not nil.
+func (g *graphClient) do(ctx context.Context, client *http.Client, method, reqURL string, header http.Header, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return readGraphError(res)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// getJSON sends an authorised GET request and decodes its JSON response.
+func (g *graphClient) getJSON(ctx context.Context, reqURL string, out any) error {
+	return g.do(ctx, g.http, http.MethodGet, reqURL, nil, nil, out)
+}
+
+// graphItem is the subset of the properties of a driveItem that are used.
+type graphItem struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	ETag                 string `json:"eTag"`
+	Size                 int64  `json:"size"`
+	LastModifiedDateTime string `json:"lastModifiedDateTime"`
+	File                 *struct {
+		MimeType string `json:"mimeType"`
+	} `json:"file,omitempty"`
+	Folder  *struct{} `json:"folder,omitempty"`
+	Deleted *struct{} `json:"deleted,omitempty"`
+	Parent  *struct {
+		ID   string `json:"id"`
+		Path string `json:"path"`
+	} `json:"parentReference,omitempty"`
+}
+
+// graphItemPage is a page of a collection of items, which is followed by
+// either another page or, for the changes of a drive, by a link to the next
+// changes.
+type graphItemPage struct {
+	Value     []*graphItem `json:"value"`
+	NextLink  string       `json:"@odata.nextLink"`
+	DeltaLink string       `json:"@odata.deltaLink"`
+}
+
+// download obtains the contents of a file item. The contents are served from
+// a pre-authenticated URL that the API redirects to, which is requested
+// without credentials.
+func (g *graphClient) download(ctx context.Context, id string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.driveURL+"/items/"+url.PathEscape(id)+"/content", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	noRedirect := *g.http
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	res, err := noRedirect.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 && res.StatusCode <= 399 {
+		loc, err := res.Location()
+		if err != nil {
+			return nil, err
+		}
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, loc.String(), nil); err != nil {
+			return nil, err
+		}
+		if res, err = g.plain.Do(req); err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, readGraphError(res)
+	}
+	return io.ReadAll(res.Body)
+}
@@ -0,0 +1,271 @@
+package msgraph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	odiFieldPath         = "path"
+	odiFieldWatch        = "watch"
+	odiFieldWatchEnabled = "enabled"
+	odiFieldWatchPoll    = "poll_interval"
+)
+
+func onedriveInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "Azure").
+		Summary("Downloads files from OneDrive or a SharePoint document library with the Microsoft Graph API.").
+		Description(`
+Lists the files of a folder and downloads each file as a message. Files within subfolders are not downloaded.
+
+When ` + "`watch.enabled`" + ` is true the input continues once all files have been listed by polling the [changes](https://learn.microsoft.com/en-us/graph/api/driveitem-delta) of the drive, and downloads any file that is created or modified within the folder. Otherwise the input shuts down once all files have been downloaded. The position within the changes of the drive is not persisted, and therefore all files are listed again when the input is restarted.
+
+### Credentials
+
+Access tokens are obtained with the credentials of an application registered with Azure AD. When ` + "`oauth2.refresh_token`" + ` is set the application accesses drives on behalf of the user that authorised it, which requires the delegated permission ` + "`Files.Read.All`" + ` and the scope ` + "`offline_access`" + `. Otherwise the application accesses drives as itself, which requires the application permission ` + "`Files.Read.All`" + ` or ` + "`Sites.Read.All`" + `, and a ` + "`drive_id`" + ` or ` + "`site_id`" + ` to be specified.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- onedrive_item_id
+- onedrive_name
+- onedrive_mime_type
+- onedrive_etag
+- onedrive_size
+- onedrive_last_modified
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range graphFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewStringField(odiFieldPath).
+			Description("The path of the folder within the drive to download the files of.").
+			Example("/Invoices").
+			Default("/")).
+		Field(service.NewObjectField(odiFieldWatch,
+			service.NewBoolField(odiFieldWatchEnabled).
+				Description("Whether to poll for changes to files once all files have been listed.").
+				Default(false),
+			service.NewDurationField(odiFieldWatchPoll).
+				Description("The period of time between each poll for changes.").
+				Default("1m"),
+		).Description("Options for polling the changes of the drive for files that are created or modified.")).
+		Example(
+			"SharePoint Invoices",
+			"In this example invoices are downloaded from a folder of the document library of a SharePoint site as they are uploaded.",
+			`
+input:
+  onedrive:
+    oauth2:
+      tenant_id: "${TENANT_ID}"
+      client_id: "${CLIENT_ID}"
+      client_secret: "${CLIENT_SECRET}"
+    site_id: contoso.sharepoint.com,2c0f8ed2-1bf2-4a5f-a7c8-1c0a9f54b1a7,712a596e-90a1-49e3-9b48-bfa80bee8740
+    path: /Invoices
+    watch:
+      enabled: true
+      poll_interval: 30s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"onedrive", onedriveInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newOneDriveInputFromParsed(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type onedriveInput struct {
+	client       *graphClient
+	path         string
+	watch        bool
+	pollInterval time.Duration
+	log          *service.Logger
+
+	mut       sync.Mutex
+	connected bool
+	folderID  string
+	pending   []*graphItem
+	listLink  string
+	listed    bool
+	deltaLink string
+	lastPoll  time.Time
+}
+
+func newOneDriveInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*onedriveInput, error) {
+	o := &onedriveInput{log: log}
+
+	var err error
+	if o.client, err = graphClientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.path, err = conf.FieldString(odiFieldPath); err != nil {
+		return nil, err
+	}
+	if o.watch, err = conf.FieldBool(odiFieldWatch, odiFieldWatchEnabled); err != nil {
+		return nil, err
+	}
+	if o.pollInterval, err = conf.FieldDuration(odiFieldWatch, odiFieldWatchPoll); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *onedriveInput) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.connected {
+		return nil
+	}
+
+	if o.folderID == "" {
+		var folder graphItem
+		if err := o.client.getJSON(ctx, o.client.itemURL(o.path), &folder); err != nil {
+			return fmt.Errorf("failed to obtain folder %v: %w", o.path, err)
+		}
+		if folder.Folder == nil {
+			return fmt.Errorf("item %v is not a folder", o.path)
+		}
+		o.folderID = folder.ID
+		o.listLink = o.client.itemURL(o.path) + "/children"
+	}
+
+	if o.watch && o.deltaLink == "" {
+		// The link to changes is obtained before listing files so that changes
+		// made whilst files are listed are not missed. Changes can only be
+		// tracked from the root of drives of organisations, and are therefore
+		// filtered by the folder.
+		var page graphItemPage
+		if err := o.client.getJSON(ctx, o.client.driveURL+"/root/delta?token=latest", &page); err != nil {
+			return fmt.Errorf("failed to obtain changes link: %w", err)
+		}
+		o.deltaLink = page.DeltaLink
+	}
+
+	o.connected = true
+	return nil
+}
+
+// wanted returns whether a changed item is a file within the folder.
+func (o *onedriveInput) wanted(item *graphItem) bool {
+	return item.File != nil && item.Deleted == nil && item.Parent != nil && item.Parent.ID == o.folderID
+}
+
+// fetch adds the next files to download to the pending files, either from
+// the listing of files or by polling for changes, and blocks until it is time
+// to poll when all changes have been consumed.
+func (o *onedriveInput) fetch(ctx context.Context) error {
+	if !o.listed {
+		var page graphItemPage
+		if err := o.client.getJSON(ctx, o.listLink, &page); err != nil {
+			return fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, item := range page.Value {
+			if item.File != nil {
+				o.pending = append(o.pending, item)
+			}
+		}
+		if o.listLink = page.NextLink; o.listLink == "" {
+			o.listed = true
+		}
+		return nil
+	}
+	if !o.watch {
+		return service.ErrEndOfInput
+	}
+
+	if wait := time.Until(o.lastPoll.Add(o.pollInterval)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var page graphItemPage
+	if err := o.client.getJSON(ctx, o.deltaLink, &page); err != nil {
+		return fmt.Errorf("failed to poll changes: %w", err)
+	}
+	for _, item := range page.Value {
+		if o.wanted(item) {
+			o.pending = append(o.pending, item)
+		}
+	}
+	if page.NextLink != "" {
+		o.deltaLink = page.NextLink
+	} else {
+		o.deltaLink = page.DeltaLink
+		o.lastPoll = time.Now()
+	}
+	return nil
+}
+
+func (o *onedriveInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if !o.connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var item *graphItem
+	var data []byte
+	for item == nil {
+		if len(o.pending) == 0 {
+			if err := o.fetch(ctx); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		var err error
+		if data, err = o.client.download(ctx, o.pending[0].ID); err != nil {
+			if !graphIsPermanent(err) {
+				return nil, nil, fmt.Errorf("failed to download file %v: %w", o.pending[0].ID, err)
+			}
+			o.log.Errorf("Skipping file %v as it could not be downloaded: %v", o.pending[0].ID, err)
+		} else {
+			item = o.pending[0]
+		}
+		o.pending = o.pending[1:]
+	}
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut("onedrive_item_id", item.ID)
+	msg.MetaSetMut("onedrive_name", item.Name)
+	msg.MetaSetMut("onedrive_mime_type", item.File.MimeType)
+	msg.MetaSetMut("onedrive_etag", item.ETag)
+	msg.MetaSetMut("onedrive_size", strconv.FormatInt(item.Size, 10))
+	msg.MetaSetMut("onedrive_last_modified", item.LastModifiedDateTime)
+	return msg, func(context.Context, error) error { return nil }, nil
+}
+
+func (o *onedriveInput) Close(ctx context.Context) error {
+	o.mut.Lock()
+	o.connected = false
+	o.mut.Unlock()
+	return nil
+}
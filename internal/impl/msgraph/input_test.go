@@ -0,0 +1,270 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testGraph is a fake of the small subset of the Microsoft Graph API used by
+// the components, which requires requests to be authorised with the access
+// token obtained from its token endpoint, and serves file contents from
+// pre-authenticated URLs.
+type testGraph struct {
+	t   *testing.T
+	url string
+
+	mut       sync.Mutex
+	items     map[string]*graphItem
+	contents  map[string]string
+	children  []string
+	changes   []string
+	grants    []string
+	conflicts []string
+	chunks    []string
+}
+
+func startTestGraph(t *testing.T) *testGraph {
+	t.Helper()
+
+	g := &testGraph{
+		t:        t,
+		items:    map[string]*graphItem{},
+		contents: map[string]string{},
+	}
+	srv := httptest.NewServer(g)
+	t.Cleanup(srv.Close)
+	g.url = srv.URL
+	return g
+}
+
+func (g *testGraph) addFile(id, name, parent, content string) {
+	item := &graphItem{ID: id, Name: name, ETag: "etag" + id, Size: int64(len(content))}
+	item.File = &struct {
+		MimeType string `json:"mimeType"`
+	}{MimeType: "text/plain"}
+	item.Parent = &struct {
+		ID   string `json:"id"`
+		Path string `json:"path"`
+	}{ID: parent}
+	g.items[id] = item
+	g.contents[id] = content
+}
+
+func (g *testGraph) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (g *testGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	switch {
+	case r.URL.Path == "/token":
+		require.NoError(g.t, r.ParseForm())
+		g.grants = append(g.grants, r.Form.Get("grant_type"))
+		g.writeJSON(w, map[string]any{"access_token": "fooaccess", "token_type": "Bearer", "expires_in": 3600})
+		return
+	case strings.HasPrefix(r.URL.Path, "/download/"):
+		assert.Empty(g.t, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(g.contents[strings.TrimPrefix(r.URL.Path, "/download/")]))
+		return
+	case r.URL.Path == "/upload/session":
+		assert.Empty(g.t, r.Header.Get("Authorization"))
+		b, _ := io.ReadAll(r.Body)
+		g.chunks = append(g.chunks, r.Header.Get("Content-Range"))
+		g.contents["session"] += string(b)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer fooaccess" {
+		w.WriteHeader(http.StatusUnauthorized)
+		g.writeJSON(w, map[string]any{"error": map[string]any{"code": "InvalidAuthenticationToken", "message": "nope"}})
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/v1.0/me/drive")
+	switch {
+	case r.Method == "GET" && path == "/root:/In%20box:":
+		g.writeJSON(w, map[string]any{"id": "folder", "name": "In box", "folder": map[string]any{}})
+	case r.Method == "GET" && path == "/root:/In%20box:/children":
+		// Pages of a single file, where the skip token is the next index.
+		var index int
+		_, _ = fmt.Sscanf(r.URL.Query().Get("skip"), "%d", &index)
+		res := map[string]any{"value": []any{}}
+		if index < len(g.children) {
+			res["value"] = []any{g.items[g.children[index]]}
+			if index+1 < len(g.children) {
+				res["@odata.nextLink"] = fmt.Sprintf("%v/v1.0/me/drive/root:/In%%20box:/children?skip=%d", g.url, index+1)
+			}
+		}
+		g.writeJSON(w, res)
+	case r.Method == "GET" && path == "/root/delta":
+		res := map[string]any{"value": []any{}}
+		if r.URL.Query().Get("token") == "1" {
+			var items []any
+			for _, id := range g.changes {
+				items = append(items, g.items[id])
+			}
+			res["value"] = items
+		}
+		res["@odata.deltaLink"] = g.url + "/v1.0/me/drive/root/delta?token=" + r.URL.Query().Get("token") + "1"
+		if r.URL.Query().Get("token") == "latest" {
+			res["@odata.deltaLink"] = g.url + "/v1.0/me/drive/root/delta?token=1"
+		}
+		g.writeJSON(w, res)
+	case r.Method == "GET" && strings.HasPrefix(path, "/items/") && strings.HasSuffix(path, "/content"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/items/"), "/content")
+		if _, ok := g.contents[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			g.writeJSON(w, map[string]any{"error": map[string]any{"code": "itemNotFound", "message": "nope"}})
+			return
+		}
+		http.Redirect(w, r, g.url+"/download/"+id, http.StatusFound)
+	case r.Method == "PUT" && strings.HasSuffix(path, ":/content"):
+		b, _ := io.ReadAll(r.Body)
+		g.conflicts = append(g.conflicts, r.URL.Query().Get("@microsoft.graph.conflictBehavior"))
+		g.contents[strings.TrimSuffix(path, "/content")] = string(b)
+		g.writeJSON(w, map[string]any{"id": "new"})
+	case r.Method == "POST" && strings.HasSuffix(path, ":/createUploadSession"):
+		var body struct {
+			Item map[string]string `json:"item"`
+		}
+		require.NoError(g.t, json.NewDecoder(r.Body).Decode(&body))
+		g.conflicts = append(g.conflicts, body.Item["@microsoft.graph.conflictBehavior"])
+		g.writeJSON(w, map[string]any{"uploadUrl": g.url + "/upload/session"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		g.writeJSON(w, map[string]any{"error": map[string]any{"code": "itemNotFound", "message": "nope"}})
+	}
+}
+
+func testGraphConf(url string) string {
+	return `
+oauth2:
+  client_id: fooclient
+  client_secret: foosecret
+  refresh_token: foorefresh
+  token_url: ` + url + `/token
+api_url: ` + url + `/v1.0
+`
+}
+
+func TestOneDriveInput(t *testing.T) {
+	g := startTestGraph(t)
+	g.addFile("a", "a.txt", "folder", "hello")
+	g.addFile("b", "b.txt", "folder", "world")
+	g.addFile("c", "c.txt", "folder", "changed")
+	g.addFile("d", "d.txt", "otherfolder", "nope")
+	g.children = []string{"a", "b"}
+	g.changes = []string{"c", "d"}
+
+	conf, err := onedriveInputSpec().ParseYAML(testGraphConf(g.url)+`
+path: /In box
+watch:
+  enabled: true
+  poll_interval: 10ms
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newOneDriveInputFromParsed(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+
+	var contents []string
+	for i := 0; i < 3; i++ {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+		msg, ackFn, err := in.Read(ctx)
+		done()
+		require.NoError(t, err)
+		require.NoError(t, ackFn(context.Background(), nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		contents = append(contents, string(b))
+
+		if i == 0 {
+			name, _ := msg.MetaGet("onedrive_name")
+			assert.Equal(t, "a.txt", name)
+			etag, _ := msg.MetaGet("onedrive_etag")
+			assert.Equal(t, "etaga", etag)
+			size, _ := msg.MetaGet("onedrive_size")
+			assert.Equal(t, "5", size)
+		}
+	}
+	assert.Equal(t, []string{"hello", "world", "changed"}, contents)
+
+	// No further changes are found.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*100)
+	_, _, err = in.Read(ctx)
+	done()
+	require.Error(t, err)
+	require.NoError(t, in.Close(context.Background()))
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	assert.Equal(t, []string{"refresh_token"}, g.grants)
+}
+
+func TestOneDriveInputEndOfInput(t *testing.T) {
+	g := startTestGraph(t)
+	g.addFile("a", "a.txt", "folder", "hello")
+	g.children = []string{"a", "gone"}
+	g.items["gone"] = &graphItem{ID: "gone", File: g.items["a"].File}
+
+	conf, err := onedriveInputSpec().ParseYAML(testGraphConf(g.url)+`
+path: /In box/
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newOneDriveInputFromParsed(conf, nil)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+
+	msg, _, err := in.Read(context.Background())
+	require.NoError(t, err)
+	id, _ := msg.MetaGet("onedrive_item_id")
+	assert.Equal(t, "a", id)
+
+	// Files that can no longer be downloaded are skipped.
+	_, _, err = in.Read(context.Background())
+	require.True(t, errors.Is(err, service.ErrEndOfInput), err)
+}
+
+func TestOneDriveClientCredentials(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{conf: "oauth2: { client_id: foo }", errStr: "tenant_id"},
+		{conf: "oauth2: { client_id: foo, tenant_id: bar }", errStr: "drive_id"},
+		{conf: "oauth2: { client_id: foo, tenant_id: bar }\nsite_id: baz"},
+	} {
+		conf, err := onedriveInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		c, err := graphClientFromParsed(conf)
+		if test.errStr != "" {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errStr)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "https://graph.microsoft.com/v1.0/sites/baz/drive", c.driveURL)
+	}
+}
@@ -0,0 +1,170 @@
+package msgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	odoFieldPath        = "path"
+	odoFieldConflict    = "conflict_behavior"
+	odoFieldMaxInFlight = "max_in_flight"
+
+	// Files larger than this are uploaded in chunks with an upload session.
+	graphSimpleUploadMax = 4 * 1024 * 1024
+
+	// The size of each chunk of an upload session, which must be a multiple
+	// of 320 KiB.
+	graphUploadChunkSize = 32 * 320 * 1024
+)
+
+func onedriveOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services", "Azure").
+		Summary("Uploads messages as files to OneDrive or a SharePoint document library with the Microsoft Graph API.").
+		Description(`
+Each message is uploaded as a file to the path resolved from ` + "`path`" + `, where any folders of the path that do not exist are created. Files larger than 4 MiB are uploaded in chunks within an [upload session](https://learn.microsoft.com/en-us/graph/api/driveitem-createuploadsession).
+
+### Credentials
+
+Access tokens are obtained with the credentials of an application registered with Azure AD. When ` + "`oauth2.refresh_token`" + ` is set the application accesses drives on behalf of the user that authorised it, which requires the delegated permission ` + "`Files.ReadWrite.All`" + ` and the scope ` + "`offline_access`" + `. Otherwise the application accesses drives as itself, which requires the application permission ` + "`Files.ReadWrite.All`" + ` or ` + "`Sites.ReadWrite.All`" + `, and a ` + "`drive_id`" + ` or ` + "`site_id`" + ` to be specified.`)
+
+	for _, f := range graphFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewInterpolatedStringField(odoFieldPath).
+			Description("The path of each file within the drive.").
+			Example(`/Reports/${! timestamp_unix_nano() }.json`).
+			Example(`/Exports/${! meta("kafka_key") }.csv`)).
+		Field(service.NewStringEnumField(odoFieldConflict, "replace", "rename", "fail").
+			Description("The behaviour when a file already exists at the path of a file, where `rename` uploads the file with a unique name instead, and `fail` rejects the message.").
+			Advanced().
+			Default("replace")).
+		Field(service.NewIntField(odoFieldMaxInFlight).
+			Description("The maximum number of files to upload in parallel.").
+			Default(8)).
+		Example(
+			"SharePoint Exports",
+			"In this example exports are uploaded to a folder of the document library of a SharePoint site.",
+			`
+output:
+  onedrive:
+    oauth2:
+      tenant_id: "${TENANT_ID}"
+      client_id: "${CLIENT_ID}"
+      client_secret: "${CLIENT_SECRET}"
+    site_id: contoso.sharepoint.com,2c0f8ed2-1bf2-4a5f-a7c8-1c0a9f54b1a7,712a596e-90a1-49e3-9b48-bfa80bee8740
+    path: /Exports/${! this.customer }/${! this.id }.json
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"onedrive", onedriveOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(odoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newOneDriveOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type onedriveOutput struct {
+	client   *graphClient
+	path     *service.InterpolatedString
+	conflict string
+}
+
+func newOneDriveOutputFromParsed(conf *service.ParsedConfig) (*onedriveOutput, error) {
+	o := &onedriveOutput{}
+
+	var err error
+	if o.client, err = graphClientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.path, err = conf.FieldInterpolatedString(odoFieldPath); err != nil {
+		return nil, err
+	}
+	if o.conflict, err = conf.FieldString(odoFieldConflict); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *onedriveOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (o *onedriveOutput) uploadSession(ctx context.Context, itemURL string, data []byte) error {
+	reqBody, err := json.Marshal(map[string]any{
+		"item": map[string]any{
+			"@microsoft.graph.conflictBehavior": o.conflict,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var session struct {
+		UploadURL string `json:"uploadUrl"`
+	}
+	if err := o.client.do(ctx, o.client.http, http.MethodPost, itemURL+"/createUploadSession", http.Header{
+		"Content-Type": []string{"application/json"},
+	}, bytes.NewReader(reqBody), &session); err != nil {
+		return fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	// The upload URL is pre-authenticated and must not receive access tokens.
+	for start := 0; start < len(data); start += graphUploadChunkSize {
+		end := start + graphUploadChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		if err := o.client.do(ctx, o.client.plain, http.MethodPut, session.UploadURL, http.Header{
+			"Content-Range": []string{fmt.Sprintf("bytes %v-%v/%v", start, end-1, len(data))},
+		}, bytes.NewReader(data[start:end]), nil); err != nil {
+			_ = o.client.do(ctx, o.client.plain, http.MethodDelete, session.UploadURL, nil, nil, nil)
+			return fmt.Errorf("failed to upload bytes %v-%v: %w", start, end-1, err)
+		}
+	}
+	return nil
+}
+
+func (o *onedriveOutput) Write(ctx context.Context, msg *service.Message) error {
+	filePath := strings.Trim(o.path.String(msg), "/")
+	if filePath == "" {
+		return fmt.Errorf("path resolved to an empty path")
+	}
+	data, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	itemURL := o.client.itemURL(filePath)
+	if len(data) > graphSimpleUploadMax {
+		return o.uploadSession(ctx, itemURL, data)
+	}
+	return o.client.do(ctx, o.client.http, http.MethodPut,
+		itemURL+"/content?"+url.Values{"@microsoft.graph.conflictBehavior": []string{o.conflict}}.Encode(),
+		http.Header{"Content-Type": []string{"application/octet-stream"}},
+		bytes.NewReader(data), nil)
+}
+
+func (o *onedriveOutput) Close(ctx context.Context) error {
+	return nil
+}
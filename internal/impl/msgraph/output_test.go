@@ -0,0 +1,48 @@
+package msgraph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestOneDriveOutput(t *testing.T) {
+	g := startTestGraph(t)
+
+	conf, err := onedriveOutputSpec().ParseYAML(testGraphConf(g.url)+`
+path: /Exports/${! meta("name") }
+conflict_behavior: rename
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newOneDriveOutputFromParsed(conf)
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+
+	large := strings.Repeat("x", graphUploadChunkSize+graphSimpleUploadMax)
+	for _, v := range []struct{ name, content string }{
+		{"a b.json", `{"id":"a"}`},
+		{"large.txt", large},
+	} {
+		msg := service.NewMessage([]byte(v.content))
+		msg.MetaSetMut("name", v.name)
+		require.NoError(t, out.Write(context.Background(), msg))
+	}
+	require.NoError(t, out.Close(context.Background()))
+
+	g.mut.Lock()
+	defer g.mut.Unlock()
+
+	assert.Equal(t, `{"id":"a"}`, g.contents["/root:/Exports/a%20b.json:"])
+	assert.Equal(t, large, g.contents["session"])
+	assert.Equal(t, []string{
+		"bytes 0-10485759/14680064",
+		"bytes 10485760-14680063/14680064",
+	}, g.chunks)
+	assert.Equal(t, []string{"rename", "rename"}, g.conflicts)
+}
@@ -49,6 +49,7 @@ You can access these metadata fields using [function interpolation](/docs/config
 			docs.FieldInt("prefetch_count", "The maximum number of messages to pull at a time.").Advanced(),
 			btls.FieldSpec(),
 			auth.FieldSpec(),
+			input.RedeliveryFieldSpec(),
 		).ChildDefaultAndTypesFromStruct(input.NewNATSConfig()),
 		Categories: []string{
 			"Services",
@@ -64,7 +65,11 @@ func newNATSInput(conf input.Config, mgr bundle.NewManagement) (input.Streamed,
 	if err != nil {
 		return nil, err
 	}
-	return input.NewAsyncReader("nats", true, input.NewAsyncPreserver(n), mgr)
+	opts, err := input.RedeliveryOpts(conf.NATS.Redelivery, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return input.NewAsyncReader("nats", true, input.NewAsyncPreserver(n, opts...), mgr)
 }
 
 type natsReader struct {
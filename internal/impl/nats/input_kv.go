@@ -0,0 +1,245 @@
+package nats
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/nats/auth"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func natsKVInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Services").
+		Version("4.9.0").
+		Summary("Watches for updates in a NATS JetStream key-value bucket.").
+		Description(`
+Each update of a watched key is emitted as a message containing the value of the key. By default the current value of each key is emitted when the input first connects, followed by all subsequent updates.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- nats_kv_key
+- nats_kv_bucket
+- nats_kv_revision
+- nats_kv_delta
+- nats_kv_operation
+- nats_kv_created
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+` + auth.Description()).
+		Field(connectionURLsField()).
+		Field(service.NewStringField("bucket").
+			Description("The name of the KV bucket to watch for updates.").
+			Example("my_kv_bucket")).
+		Field(service.NewStringField("key").
+			Description("The key to watch for updates, can include wildcards.").
+			Default(">").
+			Example("foo.bar.baz").Example("foo.*.baz").Example("foo.bar.*").Example("foo.>")).
+		Field(service.NewBoolField("ignore_deletes").
+			Description("Do not send delete markers as messages.").
+			Default(false)).
+		Field(service.NewBoolField("include_history").
+			Description("Include all the history per key, not just the last one.").
+			Default(false)).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewInternalField(auth.FieldSpec()))
+}
+
+func init() {
+	err := service.RegisterInput(
+		"nats_kv", natsKVInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			reader, err := newKVReader(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(reader), nil
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type kvReader struct {
+	connDetails    connectionDetails
+	bucket         string
+	key            string
+	ignoreDeletes  bool
+	includeHistory bool
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	natsConn *nats.Conn
+	watcher  nats.KeyWatcher
+
+	shutSig *shutdown.Signaller
+}
+
+func newKVReader(conf *service.ParsedConfig, log *service.Logger) (*kvReader, error) {
+	r := &kvReader{
+		log:     log,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if r.connDetails, err = connectionDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if r.bucket, err = conf.FieldString("bucket"); err != nil {
+		return nil, err
+	}
+	if r.key, err = conf.FieldString("key"); err != nil {
+		return nil, err
+	}
+	if r.ignoreDeletes, err = conf.FieldBool("ignore_deletes"); err != nil {
+		return nil, err
+	}
+	if r.includeHistory, err = conf.FieldBool("include_history"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *kvReader) Connect(ctx context.Context) error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.natsConn != nil {
+		return nil
+	}
+
+	var natsConn *nats.Conn
+	var watcher nats.KeyWatcher
+	var err error
+
+	defer func() {
+		if err != nil {
+			if watcher != nil {
+				_ = watcher.Stop()
+			}
+			if natsConn != nil {
+				natsConn.Close()
+			}
+		}
+	}()
+
+	if natsConn, err = r.connDetails.connect(); err != nil {
+		return err
+	}
+
+	var js nats.JetStreamContext
+	if js, err = natsConn.JetStream(); err != nil {
+		return err
+	}
+
+	var kv nats.KeyValue
+	if kv, err = js.KeyValue(r.bucket); err != nil {
+		return err
+	}
+
+	var watchOpts []nats.WatchOpt
+	if r.ignoreDeletes {
+		watchOpts = append(watchOpts, nats.IgnoreDeletes())
+	}
+	if r.includeHistory {
+		watchOpts = append(watchOpts, nats.IncludeHistory())
+	}
+	if watcher, err = kv.Watch(r.key, watchOpts...); err != nil {
+		return err
+	}
+
+	r.log.Infof("Watching NATS KV bucket: %s for key(s): %s", r.bucket, r.key)
+
+	r.natsConn = natsConn
+	r.watcher = watcher
+	return nil
+}
+
+func (r *kvReader) disconnect() {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.watcher != nil {
+		_ = r.watcher.Stop()
+		r.watcher = nil
+	}
+	if r.natsConn != nil {
+		r.natsConn.Close()
+		r.natsConn = nil
+	}
+}
+
+func (r *kvReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.connMut.Lock()
+	watcher := r.watcher
+	r.connMut.Unlock()
+	if watcher == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		var entry nats.KeyValueEntry
+		var open bool
+
+		select {
+		case entry, open = <-watcher.Updates():
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if !open {
+			r.disconnect()
+			return nil, nil, service.ErrNotConnected
+		}
+
+		// A nil entry marks the end of the initial values of the watched keys,
+		// which carries no data of its own.
+		if entry == nil {
+			continue
+		}
+
+		return newMessageFromKVEntry(entry), func(ctx context.Context, res error) error {
+			return nil
+		}, nil
+	}
+}
+
+func (r *kvReader) Close(ctx context.Context) error {
+	go func() {
+		r.disconnect()
+		r.shutSig.ShutdownComplete()
+	}()
+	select {
+	case <-r.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newMessageFromKVEntry(entry nats.KeyValueEntry) *service.Message {
+	msg := service.NewMessage(entry.Value())
+	msg.MetaSet("nats_kv_key", entry.Key())
+	msg.MetaSet("nats_kv_bucket", entry.Bucket())
+	msg.MetaSet("nats_kv_revision", strconv.FormatUint(entry.Revision(), 10))
+	msg.MetaSet("nats_kv_delta", strconv.FormatUint(entry.Delta(), 10))
+	msg.MetaSet("nats_kv_operation", entry.Operation().String())
+	msg.MetaSet("nats_kv_created", entry.Created().Format(time.RFC3339Nano))
+	return msg
+}
@@ -0,0 +1,232 @@
+package nats
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/nats/auth"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func natsObjectStoreInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Services").
+		Version("4.9.0").
+		Summary("Watches for objects written to a NATS JetStream object store bucket and reads their contents.").
+		Description(`
+Each object found within the bucket is emitted as a message containing its contents. The latest version of each object is emitted when the input first connects, followed by any objects put into the bucket after that. Deleted objects are skipped.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```text" + `
+- nats_object_store_name
+- nats_object_store_bucket
+- nats_object_store_size
+- nats_object_store_digest
+- nats_object_store_modified
+- All object headers
+` + "```" + `
+
+You can access these metadata fields using
+[function interpolation](/docs/configuration/interpolation#metadata).
+
+` + auth.Description()).
+		Field(connectionURLsField()).
+		Field(service.NewStringField("bucket").
+			Description("The name of the object store bucket to read from.").
+			Example("my_bucket")).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewInternalField(auth.FieldSpec()))
+}
+
+func init() {
+	err := service.RegisterInput(
+		"nats_object_store", natsObjectStoreInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			reader, err := newObjectStoreReader(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(reader), nil
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type objectStoreReader struct {
+	connDetails connectionDetails
+	bucket      string
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	natsConn *nats.Conn
+	objStore nats.ObjectStore
+	watcher  nats.ObjectWatcher
+
+	shutSig *shutdown.Signaller
+}
+
+func newObjectStoreReader(conf *service.ParsedConfig, log *service.Logger) (*objectStoreReader, error) {
+	r := &objectStoreReader{
+		log:     log,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if r.connDetails, err = connectionDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if r.bucket, err = conf.FieldString("bucket"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (r *objectStoreReader) Connect(ctx context.Context) error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.natsConn != nil {
+		return nil
+	}
+
+	var natsConn *nats.Conn
+	var watcher nats.ObjectWatcher
+	var err error
+
+	defer func() {
+		if err != nil {
+			if watcher != nil {
+				_ = watcher.Stop()
+			}
+			if natsConn != nil {
+				natsConn.Close()
+			}
+		}
+	}()
+
+	if natsConn, err = r.connDetails.connect(); err != nil {
+		return err
+	}
+
+	var js nats.JetStreamContext
+	if js, err = natsConn.JetStream(); err != nil {
+		return err
+	}
+
+	var objStore nats.ObjectStore
+	if objStore, err = js.ObjectStore(r.bucket); err != nil {
+		return err
+	}
+
+	if watcher, err = objStore.Watch(nats.IgnoreDeletes()); err != nil {
+		return err
+	}
+
+	r.log.Infof("Watching NATS object store bucket: %s", r.bucket)
+
+	r.natsConn = natsConn
+	r.objStore = objStore
+	r.watcher = watcher
+	return nil
+}
+
+func (r *objectStoreReader) disconnect() {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.watcher != nil {
+		_ = r.watcher.Stop()
+		r.watcher = nil
+	}
+	if r.natsConn != nil {
+		r.natsConn.Close()
+		r.natsConn = nil
+	}
+	r.objStore = nil
+}
+
+func (r *objectStoreReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.connMut.Lock()
+	objStore, watcher := r.objStore, r.watcher
+	r.connMut.Unlock()
+	if watcher == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		var info *nats.ObjectInfo
+		var open bool
+
+		select {
+		case info, open = <-watcher.Updates():
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if !open {
+			r.disconnect()
+			return nil, nil, service.ErrNotConnected
+		}
+
+		// A nil info marks the end of the objects that already existed when
+		// the watch began.
+		if info == nil || info.Deleted {
+			continue
+		}
+
+		data, err := objStore.GetBytes(info.Name)
+		if err != nil {
+			if err == nats.ErrObjectNotFound {
+				// The object was deleted before we could read it.
+				continue
+			}
+			return nil, nil, err
+		}
+
+		return newMessageFromObjectInfo(info, data), func(ctx context.Context, res error) error {
+			return nil
+		}, nil
+	}
+}
+
+func (r *objectStoreReader) Close(ctx context.Context) error {
+	go func() {
+		r.disconnect()
+		r.shutSig.ShutdownComplete()
+	}()
+	select {
+	case <-r.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func newMessageFromObjectInfo(info *nats.ObjectInfo, data []byte) *service.Message {
+	msg := service.NewMessage(data)
+	for k := range info.Headers {
+		if v := info.Headers.Get(k); v != "" {
+			msg.MetaSet(k, v)
+		}
+	}
+	msg.MetaSet("nats_object_store_name", info.Name)
+	msg.MetaSet("nats_object_store_bucket", info.Bucket)
+	msg.MetaSet("nats_object_store_size", strconv.FormatUint(info.Size, 10))
+	msg.MetaSet("nats_object_store_digest", info.Digest)
+	msg.MetaSet("nats_object_store_modified", info.ModTime.Format(time.RFC3339Nano))
+	return msg
+}
@@ -0,0 +1,30 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func TestNATSRedeliveryConfigErrors(t *testing.T) {
+	conf := input.NewConfig()
+	conf.Type = "nats"
+	conf.NATS.URLs = []string{"nats://localhost:4222"}
+	conf.NATS.Subject = "foo"
+	conf.NATS.Redelivery.RejectOutput = "nope"
+
+	_, err := mock.NewManager().NewInput(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "output resource 'nope' was not found")
+
+	conf.NATS.Redelivery.RejectOutput = ""
+	conf.NATS.Redelivery.MaxElapsedTime = "nope"
+
+	_, err = mock.NewManager().NewInput(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse redelivery max_elapsed_time")
+}
@@ -1,7 +1,11 @@
 package nats
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,6 +15,10 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/io"
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
 )
 
 func TestIntegrationNats(t *testing.T) {
@@ -75,3 +83,70 @@ input:
 		)
 	})
 }
+
+func TestIntegrationNatsRedelivery(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.Run("nats", "latest", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+
+	var natsConn *nats.Conn
+	require.NoError(t, pool.Retry(func() error {
+		natsConn, err = nats.Connect(fmt.Sprintf("tcp://localhost:%v", resource.GetPort("4222/tcp")))
+		return err
+	}))
+	t.Cleanup(natsConn.Close)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	// Messages that can never be delivered are written to the dead letter
+	// output once they exceed the retry limit.
+	dlqPath := filepath.Join(t.TempDir(), "dlq.txt")
+	builder := service.NewStreamBuilder()
+	require.NoError(t, builder.SetYAML(fmt.Sprintf(`
+input:
+  nats:
+    urls: [ tcp://localhost:%v ]
+    subject: subject-redelivery
+    redelivery:
+      max_retries: 2
+      reject_output: dlq
+
+output:
+  reject: 'output is down'
+
+output_resources:
+  - label: dlq
+    file:
+      path: %v
+      codec: lines
+`, resource.GetPort("4222/tcp"), dlqPath)))
+
+	strm, err := builder.Build()
+	require.NoError(t, err)
+	go func() {
+		_ = strm.Run(ctx)
+	}()
+
+	// Subjects aren't persisted and so we publish until the input is
+	// subscribed.
+	assert.Eventually(t, func() bool {
+		if b, _ := os.ReadFile(dlqPath); len(b) > 0 {
+			return strings.HasPrefix(string(b), "foo\n")
+		}
+		_ = natsConn.Publish("subject-redelivery", []byte("foo"))
+		return false
+	}, time.Second*30, time.Second)
+	require.NoError(t, strm.Stop(ctx))
+}
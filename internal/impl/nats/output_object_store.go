@@ -0,0 +1,171 @@
+package nats
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/nats/auth"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func natsObjectStoreOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Services").
+		Version("4.9.0").
+		Summary("Put messages as objects into a NATS JetStream object store bucket.").
+		Description(`
+Each message is written as an object named by the ` + "`object_name`" + ` field. Writing a message to the name of an existing object replaces its contents.
+
+` + auth.Description()).
+		Field(connectionURLsField()).
+		Field(service.NewStringField("bucket").
+			Description("The name of the object store bucket to write to.").
+			Example("my_bucket")).
+		Field(service.NewInterpolatedStringField("object_name").
+			Description("The name of the object to write each message to.").
+			Example(`${! meta("kafka_key") }`).
+			Example(`${! uuid_v4() }.json`)).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time. Increase this to improve throughput.").
+			Default(1024)).
+		Field(service.NewTLSToggledField("tls")).
+		Field(service.NewInternalField(auth.FieldSpec()))
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"nats_object_store", natsObjectStoreOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+			maxInFlight, err := conf.FieldInt("max_in_flight")
+			if err != nil {
+				return nil, 0, err
+			}
+			w, err := newObjectStoreOutput(conf, mgr.Logger())
+			return w, maxInFlight, err
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type objectStoreOutput struct {
+	connDetails connectionDetails
+	bucket      string
+	objectName  *service.InterpolatedString
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	natsConn *nats.Conn
+	objStore nats.ObjectStore
+
+	shutSig *shutdown.Signaller
+}
+
+func newObjectStoreOutput(conf *service.ParsedConfig, log *service.Logger) (*objectStoreOutput, error) {
+	o := &objectStoreOutput{
+		log:     log,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if o.connDetails, err = connectionDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if o.bucket, err = conf.FieldString("bucket"); err != nil {
+		return nil, err
+	}
+	if o.objectName, err = conf.FieldInterpolatedString("object_name"); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *objectStoreOutput) Connect(ctx context.Context) error {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.natsConn != nil {
+		return nil
+	}
+
+	var natsConn *nats.Conn
+	var err error
+
+	defer func() {
+		if err != nil && natsConn != nil {
+			natsConn.Close()
+		}
+	}()
+
+	if natsConn, err = o.connDetails.connect(); err != nil {
+		return err
+	}
+
+	var js nats.JetStreamContext
+	if js, err = natsConn.JetStream(); err != nil {
+		return err
+	}
+
+	var objStore nats.ObjectStore
+	if objStore, err = js.ObjectStore(o.bucket); err != nil {
+		return err
+	}
+
+	o.log.Infof("Writing NATS objects to bucket: %s", o.bucket)
+
+	o.natsConn = natsConn
+	o.objStore = objStore
+	return nil
+}
+
+func (o *objectStoreOutput) disconnect() {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.natsConn != nil {
+		o.natsConn.Close()
+		o.natsConn = nil
+	}
+	o.objStore = nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *objectStoreOutput) Write(ctx context.Context, msg *service.Message) error {
+	o.connMut.Lock()
+	objStore := o.objStore
+	o.connMut.Unlock()
+	if objStore == nil {
+		return service.ErrNotConnected
+	}
+
+	msgBytes, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	_, err = objStore.PutBytes(o.objectName.String(msg), msgBytes)
+	return err
+}
+
+func (o *objectStoreOutput) Close(ctx context.Context) error {
+	go func() {
+		o.disconnect()
+		o.shutSig.ShutdownComplete()
+	}()
+	select {
+	case <-o.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestOutputObjectStoreConfigParse(t *testing.T) {
+	spec := natsObjectStoreOutputConfig()
+	env := service.NewEnvironment()
+
+	outputConfig := `
+urls: [ url1, url2 ]
+bucket: testbucket
+object_name: ${! meta("name") }.json
+auth:
+  nkey_file: test auth n key file
+`
+
+	conf, err := spec.ParseYAML(outputConfig, env)
+	require.NoError(t, err)
+
+	e, err := newObjectStoreOutput(conf, nil)
+	require.NoError(t, err)
+
+	msg := service.NewMessage(nil)
+	msg.MetaSet("name", "foo")
+	assert.Equal(t, "url1,url2", e.connDetails.urls)
+	assert.Equal(t, "testbucket", e.bucket)
+	assert.Equal(t, "foo.json", e.objectName.String(msg))
+	assert.Equal(t, "test auth n key file", e.connDetails.authConf.NKeyFile)
+}
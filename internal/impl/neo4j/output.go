@@ -0,0 +1,426 @@
+package neo4j
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	noFieldURL          = "url"
+	noFieldDatabase     = "database"
+	noFieldUsername     = "username"
+	noFieldPassword     = "password"
+	noFieldQuery        = "query"
+	noFieldRowMapping   = "row_mapping"
+	noFieldRowsParam    = "rows_parameter"
+	noFieldParamMapping = "params_mapping"
+	noFieldTimeout      = "timeout"
+	noFieldTLS          = "tls"
+	noFieldBackoff      = "backoff"
+	noFieldBatching     = "batching"
+	noFieldMaxInFlight  = "max_in_flight"
+)
+
+func neo4jOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Executes a parameterised Cypher query against Neo4j for each batch of messages.").
+		Description(`
+Each batch of messages is written within a single transaction with the [HTTP API](https://neo4j.com/docs/http-api/current/) of Neo4j, where the query is executed once with a list parameter containing a row for each message. The query is therefore expected to iterate the rows with an `+"`UNWIND`"+` clause, which is far more efficient than executing a query for each message.
+
+The row of each message is the result of `+"`row_mapping`"+`, which defaults to the contents of the message, and is provided to the query as the parameter named by `+"`rows_parameter`"+`. Any other parameters of the query can be set with `+"`params_mapping`"+`, which is executed against the first message of each batch.
+
+### Retries
+
+When a transaction fails with a [transient error](https://neo4j.com/docs/status-codes/current/errors/transient-errors/), such as a deadlock between concurrent transactions that modify the same nodes, or when the server responds with a 5XX status code, the transaction is attempted again according to `+"`backoff`"+`. Any other errors, such as syntax errors or constraint violations, result in the batch being rejected immediately.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`. However, concurrent transactions that modify the same nodes or relationships are likely to deadlock, in which case it may be more efficient to reduce `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(noFieldURL).
+			Description("The base URL of the Neo4j HTTP API.").
+			Example("http://localhost:7474")).
+		Field(service.NewStringField(noFieldDatabase).
+			Description("The database to write to.").
+			Default("neo4j")).
+		Field(service.NewStringField(noFieldUsername).
+			Description("A username to authenticate with.").
+			Default("")).
+		Field(service.NewStringField(noFieldPassword).
+			Description("A password to authenticate with.").
+			Default("")).
+		Field(service.NewStringField(noFieldQuery).
+			Description("A Cypher query to execute for each batch, which should iterate the rows of the batch with an `UNWIND` clause.").
+			Example(`UNWIND $rows AS row MERGE (u:User {id: row.id}) SET u.name = row.name`)).
+		Field(service.NewBloblangField(noFieldRowMapping).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) executed for each message that results in its row. When omitted the contents of each message are parsed as JSON.").
+			Example(`root = this.user`).
+			Optional()).
+		Field(service.NewStringField(noFieldRowsParam).
+			Description("The name of the parameter that the rows of a batch are provided as.").
+			Advanced().
+			Default("rows")).
+		Field(service.NewBloblangField(noFieldParamMapping).
+			Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of additional parameters of the query, executed against the first message of each batch.").
+			Example(`root.source = meta("kafka_topic")`).
+			Advanced().
+			Optional()).
+		Field(service.NewDurationField(noFieldTimeout).
+			Description("The maximum period to wait for a transaction to be committed.").
+			Advanced().
+			Default("30s")).
+		Field(service.NewTLSToggledField(noFieldTLS)).
+		Field(service.NewBackOffField(noFieldBackoff, false, &backoff.ExponentialBackOff{
+			InitialInterval: time.Millisecond * 500,
+			MaxInterval:     time.Second * 10,
+			MaxElapsedTime:  time.Minute,
+		}).
+			Advanced()).
+		Field(service.NewBatchPolicyField(noFieldBatching)).
+		Field(service.NewIntField(noFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(8)).
+		Example(
+			"Follower Graph",
+			"In this example events of users following each other are materialised as a graph of users connected by `FOLLOWS` relationships.",
+			`
+output:
+  neo4j:
+    url: http://localhost:7474
+    username: neo4j
+    password: "${NEO4J_PASSWORD}"
+    query: |
+      UNWIND $rows AS row
+      MERGE (a:User {id: row.follower})
+      MERGE (b:User {id: row.followee})
+      MERGE (a)-[f:FOLLOWS]->(b)
+      SET f.since = row.ts
+    row_mapping: |
+      root.follower = this.from.id
+      root.followee = this.to.id
+      root.ts = this.timestamp
+    batching:
+      count: 1000
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"neo4j", neo4jOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(noFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(noFieldBatching); err != nil {
+				return
+			}
+			out, err = newNeo4jOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type neo4jOutput struct {
+	log *service.Logger
+
+	url      string
+	username string
+	password string
+	timeout  time.Duration
+
+	query         string
+	rowMapping    *bloblang.Executor
+	rowsParam     string
+	paramsMapping *bloblang.Executor
+
+	backoffCtor func() backoff.BackOff
+	client      *http.Client
+}
+
+func newNeo4jOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*neo4jOutput, error) {
+	n := &neo4jOutput{log: mgr.Logger()}
+
+	baseURL, err := conf.FieldString(noFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	database, err := conf.FieldString(noFieldDatabase)
+	if err != nil {
+		return nil, err
+	}
+	n.url = strings.TrimSuffix(baseURL, "/") + "/db/" + url.PathEscape(database) + "/tx/commit"
+
+	if n.username, err = conf.FieldString(noFieldUsername); err != nil {
+		return nil, err
+	}
+	if n.password, err = conf.FieldString(noFieldPassword); err != nil {
+		return nil, err
+	}
+	if n.timeout, err = conf.FieldDuration(noFieldTimeout); err != nil {
+		return nil, err
+	}
+	if n.query, err = conf.FieldString(noFieldQuery); err != nil {
+		return nil, err
+	}
+	if conf.Contains(noFieldRowMapping) {
+		if n.rowMapping, err = conf.FieldBloblang(noFieldRowMapping); err != nil {
+			return nil, err
+		}
+	}
+	if n.rowsParam, err = conf.FieldString(noFieldRowsParam); err != nil {
+		return nil, err
+	}
+	if n.rowsParam == "" {
+		return nil, errors.New("rows_parameter must not be empty")
+	}
+	if conf.Contains(noFieldParamMapping) {
+		if n.paramsMapping, err = conf.FieldBloblang(noFieldParamMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	boff, err := conf.FieldBackOff(noFieldBackoff)
+	if err != nil {
+		return nil, err
+	}
+	n.backoffCtor = func() backoff.BackOff {
+		b := *boff
+		b.Reset()
+		return &b
+	}
+
+	n.client = &http.Client{}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(noFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			n.client.Transport = cloned
+		} else {
+			n.client.Transport = &http.Transport{TLSClientConfig: tlsConf}
+		}
+	}
+	return n, nil
+}
+
+func (n *neo4jOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+type txStatement struct {
+	Statement  string         `json:"statement"`
+	Parameters map[string]any `json:"parameters"`
+}
+
+type txRequest struct {
+	Statements []txStatement `json:"statements"`
+}
+
+type txError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type txResponse struct {
+	Errors []txError `json:"errors"`
+}
+
+func (n *neo4jOutput) row(batch service.MessageBatch, index int) (any, error) {
+	if n.rowMapping == nil {
+		return batch[index].AsStructured()
+	}
+	res, err := batch.BloblangQuery(index, n.rowMapping)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, errors.New("mapping resulted in a deleted message")
+	}
+	return res.AsStructured()
+}
+
+func (n *neo4jOutput) params(batch service.MessageBatch) (map[string]any, error) {
+	params := map[string]any{}
+	if n.paramsMapping != nil {
+		res, err := batch.BloblangQuery(0, n.paramsMapping)
+		if err != nil {
+			return nil, fmt.Errorf("params mapping failed: %w", err)
+		}
+		if res != nil {
+			v, err := res.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("params mapping failed: %w", err)
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("params mapping must result in an object, got %T", v)
+			}
+			for k, v := range obj {
+				params[k] = v
+			}
+		}
+	}
+	return params, nil
+}
+
+func (n *neo4jOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	params, err := n.params(batch)
+	if err != nil {
+		return err
+	}
+
+	var batchErr *service.BatchError
+	rows := make([]any, 0, len(batch))
+	for index := range batch {
+		row, err := n.row(batch, index)
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(index, fmt.Errorf("row mapping failed: %w", err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) > 0 {
+		params[n.rowsParam] = rows
+		body, err := json.Marshal(txRequest{
+			Statements: []txStatement{{Statement: n.query, Parameters: params}},
+		})
+		if err != nil {
+			return err
+		}
+		if err := n.commit(ctx, body); err != nil {
+			if batchErr == nil {
+				return err
+			}
+			for index := range batch {
+				batchErr.Failed(index, err)
+			}
+		}
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+// errTransient is an error from a transaction that may succeed when attempted
+// again.
+type errTransient struct {
+	err error
+}
+
+func (e *errTransient) Error() string {
+	return e.err.Error()
+}
+
+func (n *neo4jOutput) commit(ctx context.Context, body []byte) error {
+	boff := n.backoffCtor()
+	for {
+		err := n.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+
+		var tErr *errTransient
+		if !errors.As(err, &tErr) {
+			return err
+		}
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return err
+		}
+		n.log.Warnf("Retrying transaction in %v: %v", wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (n *neo4jOutput) post(ctx context.Context, body []byte) error {
+	ctx, done := context.WithTimeout(ctx, n.timeout)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json;charset=UTF-8")
+	if n.username != "" || n.password != "" {
+		req.SetBasicAuth(n.username, n.password)
+	}
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBytes, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		err = fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBytes)))
+		if res.StatusCode >= 500 {
+			return &errTransient{err: err}
+		}
+		return err
+	}
+
+	// Errors within the transaction, which cause it to be rolled back, are
+	// reported within the body of a successful response.
+	var txRes txResponse
+	if err := json.NewDecoder(res.Body).Decode(&txRes); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(txRes.Errors) == 0 {
+		return nil
+	}
+
+	errStrs := make([]string, len(txRes.Errors))
+	transient := true
+	for i, e := range txRes.Errors {
+		errStrs[i] = e.Code + ": " + e.Message
+		if !strings.HasPrefix(e.Code, "Neo.TransientError.") {
+			transient = false
+		}
+	}
+	err = errors.New(strings.Join(errStrs, ", "))
+	if transient {
+		return &errTransient{err: err}
+	}
+	return err
+}
+
+func (n *neo4jOutput) Close(ctx context.Context) error {
+	n.client.CloseIdleConnections()
+	return nil
+}
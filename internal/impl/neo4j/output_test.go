@@ -0,0 +1,168 @@
+package neo4j
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testTxServer struct {
+	mut      sync.Mutex
+	requests []*http.Request
+	bodies   []txRequest
+	replies  []txResponse
+}
+
+func (s *testTxServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var body txRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, body)
+
+	reply := txResponse{Errors: []txError{}}
+	if len(s.replies) > 0 {
+		reply = s.replies[0]
+		s.replies = s.replies[1:]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+func testNeo4jOutput(t *testing.T, srv *testTxServer, extra string) (*neo4jOutput, func()) {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	conf, err := neo4jOutputSpec().ParseYAML(`
+url: `+ts.URL+`
+database: graph
+username: foo
+password: bar
+query: 'UNWIND $rows AS row MERGE (u:User {id: row.id})'
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+  max_elapsed_time: 50ms
+`+extra, nil)
+	require.NoError(t, err)
+
+	n, err := newNeo4jOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, n.Connect(context.Background()))
+	return n, ts.Close
+}
+
+func TestNeo4jOutputUnwind(t *testing.T) {
+	srv := &testTxServer{}
+	n, done := testNeo4jOutput(t, srv, `
+row_mapping: 'root.id = this.user.id'
+params_mapping: 'root.source = meta("topic")'
+`)
+	defer done()
+
+	first := service.NewMessage([]byte(`{"user":{"id":"a"}}`))
+	first.MetaSet("topic", "users")
+
+	require.NoError(t, n.WriteBatch(context.Background(), service.MessageBatch{
+		first,
+		service.NewMessage([]byte(`{"user":{"id":"b"}}`)),
+	}))
+	require.NoError(t, n.Close(context.Background()))
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.bodies, 1)
+	require.Len(t, srv.bodies[0].Statements, 1)
+	assert.Equal(t, "UNWIND $rows AS row MERGE (u:User {id: row.id})", srv.bodies[0].Statements[0].Statement)
+	assert.Equal(t, map[string]any{
+		"source": "users",
+		"rows": []any{
+			map[string]any{"id": "a"},
+			map[string]any{"id": "b"},
+		},
+	}, srv.bodies[0].Statements[0].Parameters)
+
+	req := srv.requests[0]
+	assert.Equal(t, "/db/graph/tx/commit", req.URL.Path)
+	user, pass, _ := req.BasicAuth()
+	assert.Equal(t, "foo", user)
+	assert.Equal(t, "bar", pass)
+}
+
+func TestNeo4jOutputRowErrors(t *testing.T) {
+	srv := &testTxServer{}
+	n, done := testNeo4jOutput(t, srv, "")
+	defer done()
+
+	err := n.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`not json`)),
+		service.NewMessage([]byte(`{"id":"c"}`)),
+	})
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	require.Len(t, srv.bodies, 1)
+	assert.Equal(t, []any{
+		map[string]any{"id": "a"},
+		map[string]any{"id": "c"},
+	}, srv.bodies[0].Statements[0].Parameters["rows"])
+}
+
+func TestNeo4jOutputRetries(t *testing.T) {
+	srv := &testTxServer{replies: []txResponse{
+		{Errors: []txError{{Code: "Neo.TransientError.Transaction.DeadlockDetected", Message: "deadlock"}}},
+	}}
+	n, done := testNeo4jOutput(t, srv, "")
+	defer done()
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+	}
+	require.NoError(t, n.WriteBatch(context.Background(), batch))
+
+	srv.mut.Lock()
+	assert.Len(t, srv.bodies, 2)
+	srv.replies = []txResponse{
+		{Errors: []txError{{Code: "Neo.ClientError.Statement.SyntaxError", Message: "bad query"}}},
+	}
+	srv.mut.Unlock()
+
+	// Client errors are not retried.
+	err := n.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Neo.ClientError.Statement.SyntaxError: bad query")
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+	assert.Len(t, srv.bodies, 3)
+	assert.Empty(t, srv.replies)
+}
@@ -0,0 +1,425 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	protocolNetFlowV5 = "netflow_v5"
+	protocolNetFlowV9 = "netflow_v9"
+	protocolIPFIX     = "ipfix"
+	protocolSFlowV5   = "sflow_v5"
+
+	recordTypeFlow          = "flow"
+	recordTypeOptions       = "options"
+	recordTypeFlowSample    = "flow_sample"
+	recordTypeCounterSample = "counter_sample"
+)
+
+// flowRecord is a record decoded from a datagram, along with the fields of the
+// header of the datagram.
+type flowRecord struct {
+	protocol   string
+	recordType string
+	sequence   uint32
+	exportTime time.Time
+	domain     uint32
+	templateID uint16
+	agent      string
+	fields     map[string]any
+}
+
+// decoder decodes the datagrams of NetFlow v5, NetFlow v9, IPFIX and sFlow v5,
+// where the templates of NetFlow v9 and IPFIX are cached per exporter.
+type decoder struct {
+	templates *templateCache
+	log       *service.Logger
+}
+
+func newDecoder(templateTTL time.Duration, log *service.Logger) *decoder {
+	return &decoder{
+		templates: newTemplateCache(templateTTL),
+		log:       log,
+	}
+}
+
+func (d *decoder) decode(exporter string, b []byte, now time.Time) ([]flowRecord, error) {
+	if len(b) < 4 {
+		return nil, errors.New("datagram is too short")
+	}
+	switch version := binary.BigEndian.Uint16(b); version {
+	case 5:
+		return decodeNetFlowV5(b)
+	case 9:
+		return d.decodeNetFlowV9(exporter, b, now)
+	case 10:
+		return d.decodeIPFIX(exporter, b, now)
+	case 0:
+		// The version of sFlow is a 32 bit integer, and therefore begins with
+		// zeros whereas the versions of NetFlow and IPFIX are 16 bit.
+		if v := binary.BigEndian.Uint32(b); v != 5 {
+			return nil, fmt.Errorf("unsupported sFlow version: %v", v)
+		}
+		return decodeSFlowV5(b)
+	default:
+		return nil, fmt.Errorf("unsupported NetFlow version: %v", version)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	netflowV5HeaderLen = 24
+	netflowV5RecordLen = 48
+)
+
+func decodeNetFlowV5(b []byte) ([]flowRecord, error) {
+	if len(b) < netflowV5HeaderLen {
+		return nil, errors.New("NetFlow v5 header is too short")
+	}
+	count := int(binary.BigEndian.Uint16(b[2:]))
+	uptime := binary.BigEndian.Uint32(b[4:])
+	exportTime := time.Unix(int64(binary.BigEndian.Uint32(b[8:])), int64(binary.BigEndian.Uint32(b[12:])))
+	sequence := binary.BigEndian.Uint32(b[16:])
+	engineType, engineID := b[20], b[21]
+	samplingInterval := binary.BigEndian.Uint16(b[22:]) & 0x3fff
+
+	if len(b) < netflowV5HeaderLen+count*netflowV5RecordLen {
+		return nil, fmt.Errorf("NetFlow v5 datagram of %v records is too short: %v bytes", count, len(b))
+	}
+
+	records := make([]flowRecord, 0, count)
+	for i := 0; i < count; i++ {
+		r := b[netflowV5HeaderLen+i*netflowV5RecordLen:]
+		first, last := binary.BigEndian.Uint32(r[24:]), binary.BigEndian.Uint32(r[28:])
+		records = append(records, flowRecord{
+			protocol:   protocolNetFlowV5,
+			recordType: recordTypeFlow,
+			sequence:   sequence,
+			exportTime: exportTime,
+			fields: map[string]any{
+				"sourceIPv4Address":           net.IP(r[0:4]).String(),
+				"destinationIPv4Address":      net.IP(r[4:8]).String(),
+				"ipNextHopIPv4Address":        net.IP(r[8:12]).String(),
+				"ingressInterface":            uint64(binary.BigEndian.Uint16(r[12:])),
+				"egressInterface":             uint64(binary.BigEndian.Uint16(r[14:])),
+				"packetDeltaCount":            uint64(binary.BigEndian.Uint32(r[16:])),
+				"octetDeltaCount":             uint64(binary.BigEndian.Uint32(r[20:])),
+				"flowStartSysUpTime":          uint64(first),
+				"flowEndSysUpTime":            uint64(last),
+				"flowStartMilliseconds":       formatTime(upTimeToTime(exportTime, uptime, first)),
+				"flowEndMilliseconds":         formatTime(upTimeToTime(exportTime, uptime, last)),
+				"sourceTransportPort":         uint64(binary.BigEndian.Uint16(r[32:])),
+				"destinationTransportPort":    uint64(binary.BigEndian.Uint16(r[34:])),
+				"tcpControlBits":              uint64(r[37]),
+				"protocolIdentifier":          uint64(r[38]),
+				"ipClassOfService":            uint64(r[39]),
+				"bgpSourceAsNumber":           uint64(binary.BigEndian.Uint16(r[40:])),
+				"bgpDestinationAsNumber":      uint64(binary.BigEndian.Uint16(r[42:])),
+				"sourceIPv4PrefixLength":      uint64(r[44]),
+				"destinationIPv4PrefixLength": uint64(r[45]),
+				"engineType":                  uint64(engineType),
+				"engineId":                    uint64(engineID),
+				"samplingInterval":            uint64(samplingInterval),
+			},
+		})
+	}
+	return records, nil
+}
+
+// upTimeToTime converts a time relative to the boot of an exporter, in
+// milliseconds, into an absolute time according to the current uptime of the
+// exporter at the time of export.
+func upTimeToTime(exportTime time.Time, uptime, at uint32) time.Time {
+	// The difference is computed as a signed integer in order to account for
+	// the uptime wrapping around.
+	return exportTime.Add(-time.Duration(int32(uptime-at)) * time.Millisecond)
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	netflowV9HeaderLen = 20
+	ipfixHeaderLen     = 16
+
+	netflowV9TemplateSetID        = 0
+	netflowV9OptionsTemplateSetID = 1
+	ipfixTemplateSetID            = 2
+	ipfixOptionsTemplateSetID     = 3
+
+	minDataSetID = 256
+)
+
+// flowSet is a set of templates or records, a FlowSet of NetFlow v9 or a Set
+// of IPFIX.
+type flowSet struct {
+	id   uint16
+	body []byte
+}
+
+func splitFlowSets(b []byte) ([]flowSet, error) {
+	var sets []flowSet
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, errors.New("set header is too short")
+		}
+		length := int(binary.BigEndian.Uint16(b[2:]))
+		if length < 4 || length > len(b) {
+			return nil, fmt.Errorf("invalid set length: %v", length)
+		}
+		sets = append(sets, flowSet{id: binary.BigEndian.Uint16(b), body: b[4:length]})
+		b = b[length:]
+	}
+	return sets, nil
+}
+
+func (d *decoder) decodeNetFlowV9(exporter string, b []byte, now time.Time) ([]flowRecord, error) {
+	if len(b) < netflowV9HeaderLen {
+		return nil, errors.New("NetFlow v9 header is too short")
+	}
+	uptime := binary.BigEndian.Uint32(b[4:])
+	exportTime := time.Unix(int64(binary.BigEndian.Uint32(b[8:])), 0)
+	header := flowRecord{
+		protocol:   protocolNetFlowV9,
+		sequence:   binary.BigEndian.Uint32(b[12:]),
+		exportTime: exportTime,
+		domain:     binary.BigEndian.Uint32(b[16:]),
+	}
+
+	sets, err := splitFlowSets(b[netflowV9HeaderLen:])
+	if err != nil {
+		return nil, err
+	}
+
+	keyFor := func(id uint16) templateKey {
+		return templateKey{exporter: exporter, version: 9, domain: header.domain, id: id}
+	}
+
+	var records []flowRecord
+	for _, set := range sets {
+		switch {
+		case set.id == netflowV9TemplateSetID:
+			if err := d.readTemplates(set.body, false, false, keyFor, now); err != nil {
+				return nil, err
+			}
+		case set.id == netflowV9OptionsTemplateSetID:
+			if err := d.readNetFlowV9OptionsTemplates(set.body, keyFor, now); err != nil {
+				return nil, err
+			}
+		case set.id >= minDataSetID:
+			recs, err := d.readDataSet(header, set, keyFor(set.id), now)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range recs {
+				addUpTimes(r.fields, exportTime, uptime)
+			}
+			records = append(records, recs...)
+		}
+	}
+	return records, nil
+}
+
+// addUpTimes adds absolute times for the fields of a NetFlow v9 record that are
+// relative to the uptime of the exporter.
+func addUpTimes(fields map[string]any, exportTime time.Time, uptime uint32) {
+	for rel, abs := range map[string]string{
+		"flowStartSysUpTime": "flowStartMilliseconds",
+		"flowEndSysUpTime":   "flowEndMilliseconds",
+	} {
+		v, ok := fields[rel].(uint64)
+		if !ok {
+			continue
+		}
+		if _, exists := fields[abs]; !exists {
+			fields[abs] = formatTime(upTimeToTime(exportTime, uptime, uint32(v)))
+		}
+	}
+}
+
+func (d *decoder) decodeIPFIX(exporter string, b []byte, now time.Time) ([]flowRecord, error) {
+	if len(b) < ipfixHeaderLen {
+		return nil, errors.New("IPFIX header is too short")
+	}
+	length := int(binary.BigEndian.Uint16(b[2:]))
+	if length < ipfixHeaderLen || length > len(b) {
+		return nil, fmt.Errorf("invalid IPFIX message length: %v", length)
+	}
+	header := flowRecord{
+		protocol:   protocolIPFIX,
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(b[4:])), 0),
+		sequence:   binary.BigEndian.Uint32(b[8:]),
+		domain:     binary.BigEndian.Uint32(b[12:]),
+	}
+
+	sets, err := splitFlowSets(b[ipfixHeaderLen:length])
+	if err != nil {
+		return nil, err
+	}
+
+	keyFor := func(id uint16) templateKey {
+		return templateKey{exporter: exporter, version: 10, domain: header.domain, id: id}
+	}
+
+	var records []flowRecord
+	for _, set := range sets {
+		switch {
+		case set.id == ipfixTemplateSetID:
+			if err := d.readTemplates(set.body, false, true, keyFor, now); err != nil {
+				return nil, err
+			}
+		case set.id == ipfixOptionsTemplateSetID:
+			if err := d.readTemplates(set.body, true, true, keyFor, now); err != nil {
+				return nil, err
+			}
+		case set.id >= minDataSetID:
+			recs, err := d.readDataSet(header, set, keyFor(set.id), now)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, recs...)
+		}
+	}
+	return records, nil
+}
+
+// readTemplates reads the template records of a template set of NetFlow v9 or
+// the (options) template records of a set of IPFIX.
+func (d *decoder) readTemplates(b []byte, options, ipfix bool, keyFor func(id uint16) templateKey, now time.Time) error {
+	for len(b) >= 4 {
+		id, count := binary.BigEndian.Uint16(b), int(binary.BigEndian.Uint16(b[2:]))
+		b = b[4:]
+
+		if id == 0 {
+			// Any remainder too short to be a template is padding.
+			return nil
+		}
+		if ipfix && count == 0 {
+			// A template withdrawal, where the ID of the set withdraws all
+			// templates of its type, RFC 7011 section 8.1.
+			key := keyFor(id)
+			if id == ipfixTemplateSetID || id == ipfixOptionsTemplateSetID {
+				d.templates.withdrawAll(key.exporter, key.version, key.domain, options)
+			} else {
+				d.templates.withdraw(key)
+			}
+			continue
+		}
+		if id < minDataSetID {
+			return fmt.Errorf("invalid template ID: %v", id)
+		}
+
+		if options {
+			if len(b) < 2 {
+				return errors.New("options template record is too short")
+			}
+			// The scope field count, which IPFIX only uses to distinguish the
+			// scope of options in ways that aren't reflected in the output.
+			b = b[2:]
+		}
+
+		t := &template{options: options, fields: make([]templateField, 0, count)}
+		for i := 0; i < count; i++ {
+			if len(b) < 4 {
+				return errors.New("template record is too short")
+			}
+			f := templateField{id: binary.BigEndian.Uint16(b), length: binary.BigEndian.Uint16(b[2:])}
+			b = b[4:]
+			if ipfix && f.id&0x8000 != 0 {
+				if len(b) < 4 {
+					return errors.New("template record is too short")
+				}
+				f.id &= 0x7fff
+				f.enterprise = binary.BigEndian.Uint32(b)
+				b = b[4:]
+			}
+			t.fields = append(t.fields, f)
+		}
+		d.templates.put(keyFor(id), t, now)
+	}
+	return nil
+}
+
+// readNetFlowV9OptionsTemplates reads the records of an options template set
+// of NetFlow v9, the format of which differs from those of IPFIX in that the
+// lengths of the scope and option fields are given in bytes.
+func (d *decoder) readNetFlowV9OptionsTemplates(b []byte, keyFor func(id uint16) templateKey, now time.Time) error {
+	for len(b) >= 6 {
+		id := binary.BigEndian.Uint16(b)
+		scopeLen, optionLen := int(binary.BigEndian.Uint16(b[2:])), int(binary.BigEndian.Uint16(b[4:]))
+		b = b[6:]
+		if id < minDataSetID {
+			return nil
+		}
+		if scopeLen%4 != 0 || optionLen%4 != 0 || len(b) < scopeLen+optionLen {
+			return errors.New("options template record is too short")
+		}
+
+		t := &template{options: true}
+		for i := 0; i < scopeLen+optionLen; i += 4 {
+			t.fields = append(t.fields, templateField{
+				id:      binary.BigEndian.Uint16(b[i:]),
+				length:  binary.BigEndian.Uint16(b[i+2:]),
+				v9Scope: i < scopeLen,
+			})
+		}
+		b = b[scopeLen+optionLen:]
+		d.templates.put(keyFor(id), t, now)
+	}
+	return nil
+}
+
+func (d *decoder) readDataSet(header flowRecord, set flowSet, key templateKey, now time.Time) ([]flowRecord, error) {
+	t := d.templates.get(key, now)
+	if t == nil {
+		d.log.Debugf("Dropping records of unknown template %v from exporter %v", key.id, key.exporter)
+		return nil, nil
+	}
+
+	minLen := t.minLength()
+	if minLen == 0 {
+		return nil, nil
+	}
+
+	var records []flowRecord
+	b := set.body
+	for len(b) >= minLen {
+		fields := make(map[string]any, len(t.fields))
+		for _, f := range t.fields {
+			length := int(f.length)
+			if f.length == variableLength {
+				if len(b) < 1 {
+					return nil, errors.New("record is too short")
+				}
+				length, b = int(b[0]), b[1:]
+				if length == 255 {
+					if len(b) < 2 {
+						return nil, errors.New("record is too short")
+					}
+					length, b = int(binary.BigEndian.Uint16(b)), b[2:]
+				}
+			}
+			if len(b) < length {
+				return nil, errors.New("record is too short")
+			}
+			fields[fieldName(f)] = fieldValue(f, b[:length])
+			b = b[length:]
+		}
+
+		r := header
+		r.recordType = recordTypeFlow
+		if t.options {
+			r.recordType = recordTypeOptions
+		}
+		r.templateID = key.id
+		r.fields = fields
+		records = append(records, r)
+	}
+	return records, nil
+}
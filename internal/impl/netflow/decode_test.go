@@ -0,0 +1,224 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testBuf []byte
+
+func (b testBuf) u8(v uint8) testBuf   { return append(b, v) }
+func (b testBuf) u16(v uint16) testBuf { return b.n(uint64(v), 2) }
+func (b testBuf) u32(v uint32) testBuf { return b.n(uint64(v), 4) }
+func (b testBuf) u64(v uint64) testBuf { return b.n(v, 8) }
+
+func (b testBuf) n(v uint64, size int) testBuf {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], v)
+	return append(b, tmp[8-size:]...)
+}
+func (b testBuf) ip(s string) testBuf {
+	ip := net.ParseIP(s)
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return append(b, ip...)
+}
+
+// set encodes a set of NetFlow v9 or IPFIX with its header.
+func testSet(id uint16, body testBuf) testBuf {
+	return testBuf{}.u16(id).u16(uint16(len(body) + 4)).bytes(body)
+}
+
+func (b testBuf) bytes(v []byte) testBuf { return append(b, v...) }
+func (b testBuf) hex() string            { return hex.EncodeToString(b) }
+
+func testNetFlowV9(uptime, secs, seq, sourceID uint32, sets ...testBuf) []byte {
+	b := testBuf{}.u16(9).u16(uint16(len(sets))).u32(uptime).u32(secs).u32(seq).u32(sourceID)
+	for _, s := range sets {
+		b = b.bytes(s)
+	}
+	return b
+}
+
+func testIPFIX(secs, seq, domain uint32, sets ...testBuf) []byte {
+	var body testBuf
+	for _, s := range sets {
+		body = body.bytes(s)
+	}
+	return testBuf{}.u16(10).u16(uint16(len(body) + 16)).u32(secs).u32(seq).u32(domain).bytes(body)
+}
+
+func testDecoder() *decoder {
+	return newDecoder(time.Minute, service.MockResources().Logger())
+}
+
+func TestDecodeNetFlowV5(t *testing.T) {
+	b := testBuf{}.u16(5).u16(1).u32(10000).u32(1665403200).u32(0).u32(42).u8(1).u8(2).u16(0x4000 | 100)
+	b = b.ip("10.0.0.1").ip("10.0.0.2").ip("10.0.0.254").
+		u16(3).u16(4).u32(10).u32(1500).u32(4000).u32(9000).
+		u16(51000).u16(443).u8(0).u8(0x1b).u8(6).u8(0).
+		u16(64512).u16(64513).u8(24).u8(16).u16(0)
+
+	records, err := testDecoder().decode("127.0.0.1:1234", b, time.Now())
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+
+	r := records[0]
+	assert.Equal(t, protocolNetFlowV5, r.protocol)
+	assert.Equal(t, recordTypeFlow, r.recordType)
+	assert.Equal(t, uint32(42), r.sequence)
+	assert.Equal(t, time.Unix(1665403200, 0), r.exportTime)
+	assert.Equal(t, map[string]any{
+		"sourceIPv4Address":           "10.0.0.1",
+		"destinationIPv4Address":      "10.0.0.2",
+		"ipNextHopIPv4Address":        "10.0.0.254",
+		"ingressInterface":            uint64(3),
+		"egressInterface":             uint64(4),
+		"packetDeltaCount":            uint64(10),
+		"octetDeltaCount":             uint64(1500),
+		"flowStartSysUpTime":          uint64(4000),
+		"flowEndSysUpTime":            uint64(9000),
+		"flowStartMilliseconds":       "2022-10-10T11:59:54Z",
+		"flowEndMilliseconds":         "2022-10-10T11:59:59Z",
+		"sourceTransportPort":         uint64(51000),
+		"destinationTransportPort":    uint64(443),
+		"tcpControlBits":              uint64(0x1b),
+		"protocolIdentifier":          uint64(6),
+		"ipClassOfService":            uint64(0),
+		"bgpSourceAsNumber":           uint64(64512),
+		"bgpDestinationAsNumber":      uint64(64513),
+		"sourceIPv4PrefixLength":      uint64(24),
+		"destinationIPv4PrefixLength": uint64(16),
+		"engineType":                  uint64(1),
+		"engineId":                    uint64(2),
+		"samplingInterval":            uint64(100),
+	}, r.fields)
+
+	_, err = testDecoder().decode("127.0.0.1:1234", b[:60], time.Now())
+	require.Error(t, err)
+}
+
+func TestDecodeNetFlowV9(t *testing.T) {
+	dec := testDecoder()
+	now := time.Now()
+
+	template := testSet(0, testBuf{}.
+		u16(256).u16(5).
+		u16(8).u16(4).   // sourceIPv4Address
+		u16(12).u16(4).  // destinationIPv4Address
+		u16(1).u16(4).   // octetDeltaCount, reduced size
+		u16(22).u16(4).  // flowStartSysUpTime
+		u16(400).u16(2)) // unknown
+	options := testSet(1, testBuf{}.
+		u16(257).u16(4).u16(8).
+		u16(1).u16(4).  // scopeSystem
+		u16(34).u16(4). // samplingInterval
+		u16(35).u16(1)) // samplingAlgorithm
+	data := testSet(256, testBuf{}.
+		ip("10.0.0.1").ip("10.0.0.2").u32(1500).u32(5000).u16(0xbeef).
+		ip("10.0.0.3").ip("10.0.0.4").u32(20).u32(9000).u16(0x0001).
+		u16(0)) // padding
+
+	records, err := dec.decode("127.0.0.1:1234", testNetFlowV9(10000, 1665403200, 7, 3, template, options, data), now)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, protocolNetFlowV9, records[0].protocol)
+	assert.Equal(t, uint32(3), records[0].domain)
+	assert.Equal(t, uint16(256), records[0].templateID)
+	assert.Equal(t, map[string]any{
+		"sourceIPv4Address":      "10.0.0.1",
+		"destinationIPv4Address": "10.0.0.2",
+		"octetDeltaCount":        uint64(1500),
+		"flowStartSysUpTime":     uint64(5000),
+		"flowStartMilliseconds":  "2022-10-10T11:59:55Z",
+		"ie400":                  "beef",
+	}, records[0].fields)
+	assert.Equal(t, "10.0.0.3", records[1].fields["sourceIPv4Address"])
+
+	// Templates are cached per exporter and source ID.
+	optionsData := testSet(257, testBuf{}.u32(1).u32(1000).u8(2).u8(0).u8(0).u8(0))
+	records, err = dec.decode("127.0.0.1:1234", testNetFlowV9(10000, 1665403200, 8, 3, data, optionsData), now)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, recordTypeOptions, records[2].recordType)
+	assert.Equal(t, map[string]any{
+		"scopeSystem":       uint64(1),
+		"samplingInterval":  uint64(1000),
+		"samplingAlgorithm": uint64(2),
+	}, records[2].fields)
+
+	records, err = dec.decode("127.0.0.1:1235", testNetFlowV9(10000, 1665403200, 8, 3, data), now)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	records, err = dec.decode("127.0.0.1:1234", testNetFlowV9(10000, 1665403200, 8, 4, data), now)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	// Templates expire unless they are received again.
+	records, err = dec.decode("127.0.0.1:1234", testNetFlowV9(10000, 1665403200, 8, 3, data), now.Add(time.Minute*2))
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	dec := testDecoder()
+	now := time.Now()
+
+	template := testSet(2, testBuf{}.
+		u16(300).u16(5).
+		u16(27).u16(16).              // sourceIPv6Address
+		u16(152).u16(8).              // flowStartMilliseconds
+		u16(82).u16(65535).           // interfaceName, variable length
+		u16(0x8000|12).u16(2).u32(9). // enterprise field
+		u16(156).u16(8))              // flowStartNanoseconds
+	data := testSet(300, testBuf{}.
+		ip("2001:db8::1").u64(1665403200123).u8(4).bytes([]byte("eth0")).u16(0xcafe).u32(1665403200+ntpEpochOffset).u32(1<<31).
+		ip("2001:db8::2").u64(1665403200456).u8(255).u16(3).bytes([]byte("lo0")).u16(0x0001).u32(1665403200+ntpEpochOffset).u32(0))
+
+	records, err := dec.decode("127.0.0.1:1234", testIPFIX(1665403200, 99, 5, template, data), now)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, protocolIPFIX, records[0].protocol)
+	assert.Equal(t, uint32(99), records[0].sequence)
+	assert.Equal(t, uint32(5), records[0].domain)
+	assert.Equal(t, map[string]any{
+		"sourceIPv6Address":     "2001:db8::1",
+		"flowStartMilliseconds": "2022-10-10T12:00:00.123Z",
+		"interfaceName":         "eth0",
+		"ie9_12":                "cafe",
+		"flowStartNanoseconds":  "2022-10-10T12:00:00.5Z",
+	}, records[0].fields)
+	assert.Equal(t, "lo0", records[1].fields["interfaceName"])
+
+	// A template withdrawal removes the template.
+	withdrawal := testSet(2, testBuf{}.u16(300).u16(0))
+	records, err = dec.decode("127.0.0.1:1234", testIPFIX(1665403200, 100, 5, withdrawal, data), now)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	_, err = dec.decode("127.0.0.1:1234", testIPFIX(1665403200, 100, 5, template)[:30], now)
+	require.Error(t, err)
+}
+
+func TestDecodeUnsupported(t *testing.T) {
+	for _, b := range [][]byte{
+		{0, 1},
+		testBuf{}.u16(7).u16(0),
+		testBuf{}.u32(4),
+	} {
+		_, err := testDecoder().decode("127.0.0.1:1234", b, time.Now())
+		assert.Error(t, err)
+	}
+}
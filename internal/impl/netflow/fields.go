@@ -0,0 +1,286 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ieType int
+
+const (
+	ieUnsigned ieType = iota
+	ieSigned
+	ieFloat
+	ieBool
+	ieIPv4
+	ieIPv6
+	ieMAC
+	ieString
+	ieOctets
+	ieDateSeconds
+	ieDateMilliseconds
+	// The microsecond and nanosecond timestamps of IPFIX are encoded in the NTP
+	// format, RFC 7011 section 6.1.9.
+	ieDateNTP
+)
+
+type informationElement struct {
+	name string
+	typ  ieType
+}
+
+// informationElements are the common information elements of the IANA IPFIX
+// registry, the identifiers of which are shared with the field types of
+// NetFlow v9 up to 127.
+var informationElements = map[uint16]informationElement{
+	1:   {"octetDeltaCount", ieUnsigned},
+	2:   {"packetDeltaCount", ieUnsigned},
+	3:   {"deltaFlowCount", ieUnsigned},
+	4:   {"protocolIdentifier", ieUnsigned},
+	5:   {"ipClassOfService", ieUnsigned},
+	6:   {"tcpControlBits", ieUnsigned},
+	7:   {"sourceTransportPort", ieUnsigned},
+	8:   {"sourceIPv4Address", ieIPv4},
+	9:   {"sourceIPv4PrefixLength", ieUnsigned},
+	10:  {"ingressInterface", ieUnsigned},
+	11:  {"destinationTransportPort", ieUnsigned},
+	12:  {"destinationIPv4Address", ieIPv4},
+	13:  {"destinationIPv4PrefixLength", ieUnsigned},
+	14:  {"egressInterface", ieUnsigned},
+	15:  {"ipNextHopIPv4Address", ieIPv4},
+	16:  {"bgpSourceAsNumber", ieUnsigned},
+	17:  {"bgpDestinationAsNumber", ieUnsigned},
+	18:  {"bgpNextHopIPv4Address", ieIPv4},
+	19:  {"postMCastPacketDeltaCount", ieUnsigned},
+	20:  {"postMCastOctetDeltaCount", ieUnsigned},
+	21:  {"flowEndSysUpTime", ieUnsigned},
+	22:  {"flowStartSysUpTime", ieUnsigned},
+	23:  {"postOctetDeltaCount", ieUnsigned},
+	24:  {"postPacketDeltaCount", ieUnsigned},
+	25:  {"minimumIpTotalLength", ieUnsigned},
+	26:  {"maximumIpTotalLength", ieUnsigned},
+	27:  {"sourceIPv6Address", ieIPv6},
+	28:  {"destinationIPv6Address", ieIPv6},
+	29:  {"sourceIPv6PrefixLength", ieUnsigned},
+	30:  {"destinationIPv6PrefixLength", ieUnsigned},
+	31:  {"flowLabelIPv6", ieUnsigned},
+	32:  {"icmpTypeCodeIPv4", ieUnsigned},
+	33:  {"igmpType", ieUnsigned},
+	34:  {"samplingInterval", ieUnsigned},
+	35:  {"samplingAlgorithm", ieUnsigned},
+	36:  {"flowActiveTimeout", ieUnsigned},
+	37:  {"flowIdleTimeout", ieUnsigned},
+	38:  {"engineType", ieUnsigned},
+	39:  {"engineId", ieUnsigned},
+	40:  {"exportedOctetTotalCount", ieUnsigned},
+	41:  {"exportedMessageTotalCount", ieUnsigned},
+	42:  {"exportedFlowRecordTotalCount", ieUnsigned},
+	44:  {"sourceIPv4Prefix", ieIPv4},
+	45:  {"destinationIPv4Prefix", ieIPv4},
+	46:  {"mplsTopLabelType", ieUnsigned},
+	47:  {"mplsTopLabelIPv4Address", ieIPv4},
+	48:  {"samplerId", ieUnsigned},
+	49:  {"samplerMode", ieUnsigned},
+	50:  {"samplerRandomInterval", ieUnsigned},
+	52:  {"minimumTTL", ieUnsigned},
+	53:  {"maximumTTL", ieUnsigned},
+	54:  {"fragmentIdentification", ieUnsigned},
+	55:  {"postIpClassOfService", ieUnsigned},
+	56:  {"sourceMacAddress", ieMAC},
+	57:  {"postDestinationMacAddress", ieMAC},
+	58:  {"vlanId", ieUnsigned},
+	59:  {"postVlanId", ieUnsigned},
+	60:  {"ipVersion", ieUnsigned},
+	61:  {"flowDirection", ieUnsigned},
+	62:  {"ipNextHopIPv6Address", ieIPv6},
+	63:  {"bgpNextHopIPv6Address", ieIPv6},
+	64:  {"ipv6ExtensionHeaders", ieUnsigned},
+	70:  {"mplsTopLabelStackSection", ieOctets},
+	80:  {"destinationMacAddress", ieMAC},
+	81:  {"postSourceMacAddress", ieMAC},
+	82:  {"interfaceName", ieString},
+	83:  {"interfaceDescription", ieString},
+	85:  {"octetTotalCount", ieUnsigned},
+	86:  {"packetTotalCount", ieUnsigned},
+	88:  {"fragmentOffset", ieUnsigned},
+	89:  {"forwardingStatus", ieUnsigned},
+	90:  {"mplsVpnRouteDistinguisher", ieOctets},
+	94:  {"applicationDescription", ieString},
+	95:  {"applicationId", ieOctets},
+	96:  {"applicationName", ieString},
+	98:  {"postIpDiffServCodePoint", ieUnsigned},
+	99:  {"multicastReplicationFactor", ieUnsigned},
+	128: {"bgpNextAdjacentAsNumber", ieUnsigned},
+	129: {"bgpPrevAdjacentAsNumber", ieUnsigned},
+	130: {"exporterIPv4Address", ieIPv4},
+	131: {"exporterIPv6Address", ieIPv6},
+	132: {"droppedOctetDeltaCount", ieUnsigned},
+	133: {"droppedPacketDeltaCount", ieUnsigned},
+	136: {"flowEndReason", ieUnsigned},
+	137: {"commonPropertiesId", ieUnsigned},
+	138: {"observationPointId", ieUnsigned},
+	139: {"icmpTypeCodeIPv6", ieUnsigned},
+	143: {"meteringProcessId", ieUnsigned},
+	144: {"exportingProcessId", ieUnsigned},
+	148: {"flowId", ieUnsigned},
+	149: {"observationDomainId", ieUnsigned},
+	150: {"flowStartSeconds", ieDateSeconds},
+	151: {"flowEndSeconds", ieDateSeconds},
+	152: {"flowStartMilliseconds", ieDateMilliseconds},
+	153: {"flowEndMilliseconds", ieDateMilliseconds},
+	154: {"flowStartMicroseconds", ieDateNTP},
+	155: {"flowEndMicroseconds", ieDateNTP},
+	156: {"flowStartNanoseconds", ieDateNTP},
+	157: {"flowEndNanoseconds", ieDateNTP},
+	160: {"systemInitTimeMilliseconds", ieDateMilliseconds},
+	161: {"flowDurationMilliseconds", ieUnsigned},
+	176: {"icmpTypeIPv4", ieUnsigned},
+	177: {"icmpCodeIPv4", ieUnsigned},
+	178: {"icmpTypeIPv6", ieUnsigned},
+	179: {"icmpCodeIPv6", ieUnsigned},
+	180: {"udpSourcePort", ieUnsigned},
+	181: {"udpDestinationPort", ieUnsigned},
+	182: {"tcpSourcePort", ieUnsigned},
+	183: {"tcpDestinationPort", ieUnsigned},
+	192: {"ipTTL", ieUnsigned},
+	195: {"ipDiffServCodePoint", ieUnsigned},
+	210: {"paddingOctets", ieOctets},
+	214: {"exportProtocolVersion", ieUnsigned},
+	215: {"exportTransportProtocol", ieUnsigned},
+	225: {"postNATSourceIPv4Address", ieIPv4},
+	226: {"postNATDestinationIPv4Address", ieIPv4},
+	227: {"postNAPTSourceTransportPort", ieUnsigned},
+	228: {"postNAPTDestinationTransportPort", ieUnsigned},
+	234: {"ingressVRFID", ieUnsigned},
+	235: {"egressVRFID", ieUnsigned},
+	239: {"biflowDirection", ieUnsigned},
+	243: {"dot1qVlanId", ieUnsigned},
+	244: {"dot1qPriority", ieUnsigned},
+	276: {"dataRecordsReliability", ieBool},
+	281: {"postNATSourceIPv6Address", ieIPv6},
+	282: {"postNATDestinationIPv6Address", ieIPv6},
+	311: {"samplingProbability", ieFloat},
+	323: {"observationTimeMilliseconds", ieDateMilliseconds},
+	324: {"observationTimeMicroseconds", ieDateNTP},
+	325: {"observationTimeNanoseconds", ieDateNTP},
+	434: {"mibObjectValueInteger", ieSigned},
+}
+
+// netflowV9ScopeFields are the scope field types of options templates of
+// NetFlow v9, RFC 3954 section 6.1.
+var netflowV9ScopeFields = map[uint16]string{
+	1: "scopeSystem",
+	2: "scopeInterface",
+	3: "scopeLineCard",
+	4: "scopeCache",
+	5: "scopeTemplate",
+}
+
+// fieldName returns the name of a field of a template, where fields that are
+// not known are named after their identifier and enterprise number.
+func fieldName(f templateField) string {
+	if f.v9Scope {
+		if name, exists := netflowV9ScopeFields[f.id]; exists {
+			return name
+		}
+		return "scope" + strconv.FormatUint(uint64(f.id), 10)
+	}
+	if f.enterprise == 0 {
+		if ie, exists := informationElements[f.id]; exists {
+			return ie.name
+		}
+		return "ie" + strconv.FormatUint(uint64(f.id), 10)
+	}
+	return "ie" + strconv.FormatUint(uint64(f.enterprise), 10) + "_" + strconv.FormatUint(uint64(f.id), 10)
+}
+
+// fieldValue decodes the value of a field according to the type of its
+// information element, where values that do not match the length of their type
+// are hex encoded.
+func fieldValue(f templateField, b []byte) any {
+	typ := ieOctets
+	if f.v9Scope {
+		typ = ieUnsigned
+	} else if f.enterprise == 0 {
+		if ie, exists := informationElements[f.id]; exists {
+			typ = ie.typ
+		}
+	}
+
+	switch typ {
+	case ieUnsigned:
+		// Unsigned values may use reduced size encoding, RFC 7011 section 6.2.
+		if len(b) > 0 && len(b) <= 8 {
+			return decodeUnsigned(b)
+		}
+	case ieSigned:
+		if len(b) > 0 && len(b) <= 8 {
+			v := decodeUnsigned(b)
+			shift := 64 - uint(len(b))*8
+			return int64(v<<shift) >> shift
+		}
+	case ieFloat:
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case ieBool:
+		if len(b) == 1 {
+			return b[0] == 1
+		}
+	case ieIPv4:
+		if len(b) == net.IPv4len {
+			return net.IP(b).String()
+		}
+	case ieIPv6:
+		if len(b) == net.IPv6len {
+			return net.IP(b).String()
+		}
+	case ieMAC:
+		if len(b) == 6 {
+			return net.HardwareAddr(b).String()
+		}
+	case ieString:
+		return strings.TrimRight(string(b), "\x00")
+	case ieDateSeconds:
+		if len(b) == 4 {
+			return formatTime(time.Unix(int64(binary.BigEndian.Uint32(b)), 0))
+		}
+	case ieDateMilliseconds:
+		if len(b) == 8 {
+			return formatTime(time.UnixMilli(int64(binary.BigEndian.Uint64(b))))
+		}
+	case ieDateNTP:
+		if len(b) == 8 {
+			return formatTime(ntpTime(binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:])))
+		}
+	}
+	return hex.EncodeToString(b)
+}
+
+func decodeUnsigned(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// The number of seconds between the NTP epoch of 1900 and the unix epoch.
+const ntpEpochOffset = 2208988800
+
+func ntpTime(secs, frac uint32) time.Time {
+	nanos := (uint64(frac) * 1e9) >> 32
+	return time.Unix(int64(secs)-ntpEpochOffset, int64(nanos))
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
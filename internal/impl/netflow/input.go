@@ -0,0 +1,258 @@
+package netflow
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	nfiFieldAddress         = "address"
+	nfiFieldTemplateTimeout = "template_timeout"
+
+	// The maximum size of a UDP datagram.
+	maxDatagramSize = 65535
+)
+
+func netflowInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Collects flow records exported over UDP with NetFlow v5, NetFlow v9, IPFIX or sFlow v5, where each record is consumed as a JSON message.").
+		Description(`
+The protocol of each datagram is detected from its version, and therefore exporters of different protocols can send datagrams to the same address. The records of each datagram are consumed as a batch.
+
+### NetFlow and IPFIX
+
+Flow records are decoded into objects keyed by the names of their [IPFIX information elements](https://www.iana.org/assignments/ipfix/ipfix.xhtml), such as `+"`sourceIPv4Address` and `octetDeltaCount`"+`, the field types of NetFlow v9 sharing the identifiers of the information elements. The fixed records of NetFlow v5 are decoded into the same names. Fields that are not known are named after their identifier, prefixed with `+"`ie`"+` and the enterprise number where one is given, such as `+"`ie282` or `ie9_12235`"+`, and their values are hex encoded.
+
+The times of NetFlow v5 and v9 records that are relative to the uptime of the exporter are also converted to absolute times with the fields `+"`flowStartMilliseconds` and `flowEndMilliseconds`"+`.
+
+The templates of NetFlow v9 and IPFIX are cached for each exporter and observation domain, and records of templates that have not been received, or that have expired, are dropped. The records of options templates are consumed with the record type `+"`options`"+`.
+
+### sFlow
+
+Each flow and counter sample is consumed as a message with its records in the array `+"`records`"+`, where the headers of sampled packets are decoded into the addresses, protocol and ports of the packet, and the interface, Ethernet and processor counters are decoded into their fields. Records of other formats are hex encoded.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- netflow_exporter
+- netflow_protocol
+- netflow_record_type
+- netflow_sequence
+- netflow_observation_domain
+- netflow_export_time
+- netflow_template_id
+- sflow_agent_address
+`+"```"+`
+
+Where the exporter is the address from which the datagram was sent, the protocol is one of `+"`netflow_v5`, `netflow_v9`, `ipfix` or `sflow_v5`"+`, and the record type is one of `+"`flow`, `options`, `flow_sample` or `counter_sample`"+`. The observation domain is the source ID of NetFlow v9 and the sub agent ID of sFlow. The export time and template ID are only added for protocols that have them, and the agent address only for sFlow.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(nfiFieldAddress).
+			Description("The address to listen for datagrams on.").
+			Example("0.0.0.0:2055").
+			Example("0.0.0.0:6343").
+			Default("0.0.0.0:2055")).
+		Field(service.NewDurationField(nfiFieldTemplateTimeout).
+			Description("The period of time after which templates of NetFlow v9 and IPFIX expire unless they are received again. Exporters send templates periodically, and therefore this should exceed the interval at which they do.").
+			Advanced().
+			Default("30m")).
+		Example(
+			"Flow Collector",
+			"Collect flows from routers and index them into Elasticsearch, with the records of options templates dropped.",
+			`
+input:
+  netflow:
+    address: 0.0.0.0:2055
+  processors:
+    - switch:
+        - check: 'meta("netflow_record_type") == "options"'
+          processors:
+            - mapping: root = deleted()
+
+output:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: flows
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"netflow", netflowInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newNetFlowInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type netflowInput struct {
+	address     string
+	templateTTL time.Duration
+	log         *service.Logger
+
+	m        sync.Mutex
+	conn     net.PacketConn
+	batches  chan service.MessageBatch
+	shutdown chan struct{}
+	done     chan struct{}
+}
+
+func newNetFlowInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*netflowInput, error) {
+	n := &netflowInput{log: log}
+
+	var err error
+	if n.address, err = conf.FieldString(nfiFieldAddress); err != nil {
+		return nil, err
+	}
+	if n.templateTTL, err = conf.FieldDuration(nfiFieldTemplateTimeout); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *netflowInput) Connect(ctx context.Context) error {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	if n.conn != nil {
+		return nil
+	}
+
+	var lc net.ListenConfig
+	conn, err := lc.ListenPacket(ctx, "udp", n.address)
+	if err != nil {
+		return err
+	}
+	n.conn = conn
+	n.batches = make(chan service.MessageBatch)
+	n.shutdown = make(chan struct{})
+	n.done = make(chan struct{})
+
+	go n.loop(conn, newDecoder(n.templateTTL, n.log), n.batches, n.shutdown, n.done)
+	n.log.Infof("Receiving flows on address: %v", conn.LocalAddr())
+	return nil
+}
+
+func (n *netflowInput) loop(conn net.PacketConn, dec *decoder, batches chan<- service.MessageBatch, shutdown <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		size, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				n.log.Errorf("Failed to read datagram: %v", err)
+			}
+			return
+		}
+
+		exporter := addr.String()
+		records, err := dec.decode(exporter, buf[:size], time.Now())
+		if err != nil {
+			n.log.Warnf("Failed to decode datagram from %v: %v", exporter, err)
+			continue
+		}
+		if len(records) == 0 {
+			continue
+		}
+
+		batch := make(service.MessageBatch, 0, len(records))
+		for _, r := range records {
+			batch = append(batch, recordToMessage(exporter, r))
+		}
+		select {
+		case batches <- batch:
+		case <-shutdown:
+			return
+		}
+	}
+}
+
+func recordToMessage(exporter string, r flowRecord) *service.Message {
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(r.fields)
+	msg.MetaSetMut("netflow_exporter", exporter)
+	msg.MetaSetMut("netflow_protocol", r.protocol)
+	msg.MetaSetMut("netflow_record_type", r.recordType)
+	msg.MetaSetMut("netflow_sequence", strconv.FormatUint(uint64(r.sequence), 10))
+	if r.protocol != protocolNetFlowV5 {
+		msg.MetaSetMut("netflow_observation_domain", strconv.FormatUint(uint64(r.domain), 10))
+	}
+	if !r.exportTime.IsZero() {
+		msg.MetaSetMut("netflow_export_time", formatTime(r.exportTime))
+	}
+	if r.templateID != 0 {
+		msg.MetaSetMut("netflow_template_id", strconv.FormatUint(uint64(r.templateID), 10))
+	}
+	if r.agent != "" {
+		msg.MetaSetMut("sflow_agent_address", r.agent)
+	}
+	return msg
+}
+
+func (n *netflowInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	n.m.Lock()
+	batches, done := n.batches, n.done
+	n.m.Unlock()
+
+	if batches == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case batch := <-batches:
+		// Datagrams cannot be redelivered, and therefore there's nothing to do
+		// when a batch is rejected.
+		return batch, func(ctx context.Context, err error) error {
+			return nil
+		}, nil
+	case <-done:
+		n.disconnect(done)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// disconnect closes the connection of the input if the loop reading it is the
+// one that signals being done with the given channel.
+func (n *netflowInput) disconnect(done chan struct{}) {
+	n.m.Lock()
+	defer n.m.Unlock()
+
+	if n.conn != nil && n.done == done {
+		close(n.shutdown)
+		_ = n.conn.Close()
+		n.conn, n.batches, n.shutdown, n.done = nil, nil, nil, nil
+	}
+}
+
+func (n *netflowInput) Close(ctx context.Context) error {
+	n.m.Lock()
+	done := n.done
+	n.m.Unlock()
+
+	n.disconnect(done)
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package netflow
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testNetFlowInput(t *testing.T, conf string) *netflowInput {
+	t.Helper()
+
+	pConf, err := netflowInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	in, err := newNetFlowInputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = in.Close(context.Background()) })
+	return in
+}
+
+func TestNetFlowInput(t *testing.T) {
+	in := testNetFlowInput(t, `address: 127.0.0.1:0`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	in.m.Lock()
+	addr := in.conn.LocalAddr().String()
+	in.m.Unlock()
+
+	conn, err := net.Dial("udp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	template := testSet(2, testBuf{}.u16(256).u16(2).u16(8).u16(4).u16(7).u16(2))
+	data := testSet(256, testBuf{}.ip("10.0.0.1").u16(443).ip("10.0.0.2").u16(80))
+
+	// A datagram of only templates produces no messages.
+	_, err = conn.Write(testIPFIX(1665403200, 1, 3, template))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("not a flow"))
+	require.NoError(t, err)
+	_, err = conn.Write(testIPFIX(1665403200, 2, 3, data))
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := in.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+	require.Len(t, batch, 2)
+
+	body, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sourceIPv4Address":"10.0.0.1","sourceTransportPort":443}`, string(body))
+
+	meta := map[string]any{}
+	_ = batch[1].MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	})
+	assert.Equal(t, map[string]any{
+		"netflow_exporter":           conn.LocalAddr().String(),
+		"netflow_protocol":           "ipfix",
+		"netflow_record_type":        "flow",
+		"netflow_sequence":           "2",
+		"netflow_observation_domain": "3",
+		"netflow_export_time":        "2022-10-10T12:00:00Z",
+		"netflow_template_id":        "256",
+	}, meta)
+
+	require.NoError(t, in.Close(ctx))
+	_, _, err = in.ReadBatch(ctx)
+	assert.Equal(t, service.ErrNotConnected, err)
+}
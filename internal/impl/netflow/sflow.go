@@ -0,0 +1,412 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// xdrReader reads the XDR encoded structures of sFlow, where the first error
+// encountered is retained and all subsequent reads return zero values.
+type xdrReader struct {
+	b   []byte
+	err error
+}
+
+var errXDRShort = errors.New("sFlow structure is too short")
+
+func (r *xdrReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errXDRShort
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *xdrReader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *xdrReader) u64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// opaque reads variable length data, which is padded to a multiple of four
+// bytes.
+func (r *xdrReader) opaque() []byte {
+	n := int(r.u32())
+	b := r.bytes(n)
+	r.bytes((4 - n%4) % 4)
+	return b
+}
+
+// address reads an address of sFlow, which is prefixed by its type.
+func (r *xdrReader) address() string {
+	switch typ := r.u32(); typ {
+	case 1:
+		return net.IP(r.bytes(net.IPv4len)).String()
+	case 2:
+		return net.IP(r.bytes(net.IPv6len)).String()
+	case 0:
+		return ""
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown sFlow address type: %v", typ)
+		}
+		return ""
+	}
+}
+
+// mac reads a MAC address, which is padded to eight bytes.
+func (r *xdrReader) mac() string {
+	b := r.bytes(8)
+	if b == nil {
+		return ""
+	}
+	return net.HardwareAddr(b[:6]).String()
+}
+
+// sflowDataFormat splits the data format of a sample or record into its
+// enterprise and format numbers.
+func sflowDataFormat(v uint32) (enterprise, format uint32) {
+	return v >> 12, v & 0xfff
+}
+
+func decodeSFlowV5(b []byte) ([]flowRecord, error) {
+	r := &xdrReader{b: b}
+	_ = r.u32() // The version, which has already been checked.
+	header := flowRecord{protocol: protocolSFlowV5}
+	header.agent = r.address()
+	header.domain = r.u32()
+	header.sequence = r.u32()
+	_ = r.u32() // The uptime of the agent.
+	count := r.u32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var records []flowRecord
+	for i := uint32(0); i < count; i++ {
+		enterprise, format := sflowDataFormat(r.u32())
+		sample := &xdrReader{b: r.opaque()}
+		if r.err != nil {
+			return nil, r.err
+		}
+		if enterprise != 0 {
+			continue
+		}
+
+		rec := header
+		switch format {
+		case 1, 3:
+			rec.recordType = recordTypeFlowSample
+			rec.fields = decodeSFlowFlowSample(sample, format == 3)
+		case 2, 4:
+			rec.recordType = recordTypeCounterSample
+			rec.fields = decodeSFlowCounterSample(sample, format == 4)
+		default:
+			continue
+		}
+		if sample.err != nil {
+			return nil, fmt.Errorf("failed to decode sample: %w", sample.err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// sflowInterface splits an interface of a compact flow sample into its format
+// and value.
+func sflowInterface(v uint32) (format, value uint64) {
+	return uint64(v >> 30), uint64(v & 0x3fffffff)
+}
+
+func decodeSFlowFlowSample(r *xdrReader, expanded bool) map[string]any {
+	fields := map[string]any{"sequence_number": uint64(r.u32())}
+	if expanded {
+		fields["source_id_type"] = uint64(r.u32())
+		fields["source_id_index"] = uint64(r.u32())
+	} else {
+		sourceID := r.u32()
+		fields["source_id_type"] = uint64(sourceID >> 24)
+		fields["source_id_index"] = uint64(sourceID & 0xffffff)
+	}
+	fields["sampling_rate"] = uint64(r.u32())
+	fields["sample_pool"] = uint64(r.u32())
+	fields["drops"] = uint64(r.u32())
+	if expanded {
+		fields["input_format"] = uint64(r.u32())
+		fields["input"] = uint64(r.u32())
+		fields["output_format"] = uint64(r.u32())
+		fields["output"] = uint64(r.u32())
+	} else {
+		fields["input_format"], fields["input"] = sflowInterface(r.u32())
+		fields["output_format"], fields["output"] = sflowInterface(r.u32())
+	}
+
+	count := r.u32()
+	records := []any{}
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		enterprise, format := sflowDataFormat(r.u32())
+		data := r.opaque()
+		if r.err != nil {
+			break
+		}
+		records = append(records, decodeSFlowFlowRecord(enterprise, format, data))
+	}
+	fields["records"] = records
+	return fields
+}
+
+func decodeSFlowFlowRecord(enterprise, format uint32, data []byte) map[string]any {
+	r := &xdrReader{b: data}
+	var rec map[string]any
+	if enterprise == 0 {
+		switch format {
+		case 1:
+			rec = map[string]any{
+				"type":            "sampled_header",
+				"header_protocol": uint64(r.u32()),
+				"frame_length":    uint64(r.u32()),
+				"stripped":        uint64(r.u32()),
+			}
+			header := r.opaque()
+			rec["header"] = hex.EncodeToString(header)
+			switch rec["header_protocol"] {
+			case uint64(1):
+				decodeEthernet(header, rec)
+			case uint64(11):
+				decodeIPv4(header, rec)
+			case uint64(12):
+				decodeIPv6(header, rec)
+			}
+		case 2:
+			rec = map[string]any{
+				"type":      "sampled_ethernet",
+				"length":    uint64(r.u32()),
+				"src_mac":   r.mac(),
+				"dst_mac":   r.mac(),
+				"ethertype": uint64(r.u32()),
+			}
+		case 3:
+			rec = map[string]any{
+				"type":        "sampled_ipv4",
+				"length":      uint64(r.u32()),
+				"ip_protocol": uint64(r.u32()),
+				"src_ip":      net.IP(r.bytes(4)).String(),
+				"dst_ip":      net.IP(r.bytes(4)).String(),
+				"src_port":    uint64(r.u32()),
+				"dst_port":    uint64(r.u32()),
+				"tcp_flags":   uint64(r.u32()),
+				"ip_tos":      uint64(r.u32()),
+			}
+		case 1001:
+			rec = map[string]any{
+				"type":         "extended_switch",
+				"src_vlan":     uint64(r.u32()),
+				"src_priority": uint64(r.u32()),
+				"dst_vlan":     uint64(r.u32()),
+				"dst_priority": uint64(r.u32()),
+			}
+		case 1002:
+			rec = map[string]any{
+				"type":         "extended_router",
+				"next_hop":     r.address(),
+				"src_mask_len": uint64(r.u32()),
+				"dst_mask_len": uint64(r.u32()),
+			}
+		}
+	}
+	if rec == nil || r.err != nil {
+		return sflowRawRecord(enterprise, format, data)
+	}
+	return rec
+}
+
+func sflowRawRecord(enterprise, format uint32, data []byte) map[string]any {
+	return map[string]any{
+		"type":       "unknown",
+		"enterprise": uint64(enterprise),
+		"format":     uint64(format),
+		"data":       hex.EncodeToString(data),
+	}
+}
+
+func decodeSFlowCounterSample(r *xdrReader, expanded bool) map[string]any {
+	fields := map[string]any{"sequence_number": uint64(r.u32())}
+	if expanded {
+		fields["source_id_type"] = uint64(r.u32())
+		fields["source_id_index"] = uint64(r.u32())
+	} else {
+		sourceID := r.u32()
+		fields["source_id_type"] = uint64(sourceID >> 24)
+		fields["source_id_index"] = uint64(sourceID & 0xffffff)
+	}
+
+	count := r.u32()
+	records := []any{}
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		enterprise, format := sflowDataFormat(r.u32())
+		data := r.opaque()
+		if r.err != nil {
+			break
+		}
+		records = append(records, decodeSFlowCounterRecord(enterprise, format, data))
+	}
+	fields["records"] = records
+	return fields
+}
+
+var (
+	sflowGenericInterfaceCounters = []struct {
+		name string
+		wide bool
+	}{
+		{"if_index", false}, {"if_type", false}, {"if_speed", true},
+		{"if_direction", false}, {"if_status", false}, {"if_in_octets", true},
+		{"if_in_ucast_pkts", false}, {"if_in_multicast_pkts", false},
+		{"if_in_broadcast_pkts", false}, {"if_in_discards", false},
+		{"if_in_errors", false}, {"if_in_unknown_protos", false},
+		{"if_out_octets", true}, {"if_out_ucast_pkts", false},
+		{"if_out_multicast_pkts", false}, {"if_out_broadcast_pkts", false},
+		{"if_out_discards", false}, {"if_out_errors", false},
+		{"if_promiscuous_mode", false},
+	}
+	sflowEthernetCounters = []string{
+		"dot3_stats_alignment_errors", "dot3_stats_fcs_errors",
+		"dot3_stats_single_collision_frames", "dot3_stats_multiple_collision_frames",
+		"dot3_stats_sqe_test_errors", "dot3_stats_deferred_transmissions",
+		"dot3_stats_late_collisions", "dot3_stats_excessive_collisions",
+		"dot3_stats_internal_mac_transmit_errors", "dot3_stats_carrier_sense_errors",
+		"dot3_stats_frame_too_longs", "dot3_stats_internal_mac_receive_errors",
+		"dot3_stats_symbol_errors",
+	}
+)
+
+func decodeSFlowCounterRecord(enterprise, format uint32, data []byte) map[string]any {
+	r := &xdrReader{b: data}
+	var rec map[string]any
+	if enterprise == 0 {
+		switch format {
+		case 1:
+			rec = map[string]any{"type": "generic_interface"}
+			for _, c := range sflowGenericInterfaceCounters {
+				if c.wide {
+					rec[c.name] = r.u64()
+				} else {
+					rec[c.name] = uint64(r.u32())
+				}
+			}
+		case 2:
+			rec = map[string]any{"type": "ethernet_interface"}
+			for _, name := range sflowEthernetCounters {
+				rec[name] = uint64(r.u32())
+			}
+		case 1001:
+			rec = map[string]any{
+				"type":         "processor",
+				"cpu_5s":       float64(r.u32()) / 100,
+				"cpu_1m":       float64(r.u32()) / 100,
+				"cpu_5m":       float64(r.u32()) / 100,
+				"total_memory": r.u64(),
+				"free_memory":  r.u64(),
+			}
+		}
+	}
+	if rec == nil || r.err != nil {
+		return sflowRawRecord(enterprise, format, data)
+	}
+	return rec
+}
+
+//------------------------------------------------------------------------------
+
+// decodeEthernet adds the fields of a sampled Ethernet frame to a record, along
+// with those of the IP packet it carries.
+func decodeEthernet(b []byte, rec map[string]any) {
+	if len(b) < 14 {
+		return
+	}
+	rec["dst_mac"] = net.HardwareAddr(b[0:6]).String()
+	rec["src_mac"] = net.HardwareAddr(b[6:12]).String()
+	etherType := binary.BigEndian.Uint16(b[12:])
+	b = b[14:]
+	if etherType == 0x8100 && len(b) >= 4 {
+		rec["vlan"] = uint64(binary.BigEndian.Uint16(b) & 0xfff)
+		etherType = binary.BigEndian.Uint16(b[2:])
+		b = b[4:]
+	}
+	rec["ethertype"] = uint64(etherType)
+
+	switch etherType {
+	case 0x0800:
+		decodeIPv4(b, rec)
+	case 0x86dd:
+		decodeIPv6(b, rec)
+	}
+}
+
+func decodeIPv4(b []byte, rec map[string]any) {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return
+	}
+	headerLen := int(b[0]&0x0f) * 4
+	rec["ip_version"] = uint64(4)
+	rec["ip_tos"] = uint64(b[1])
+	rec["ip_ttl"] = uint64(b[8])
+	rec["ip_protocol"] = uint64(b[9])
+	rec["src_ip"] = net.IP(b[12:16]).String()
+	rec["dst_ip"] = net.IP(b[16:20]).String()
+
+	// The transport header is only present in the first fragment.
+	if binary.BigEndian.Uint16(b[6:])&0x1fff == 0 && headerLen >= 20 && len(b) >= headerLen {
+		decodeTransport(b[9], b[headerLen:], rec)
+	}
+}
+
+func decodeIPv6(b []byte, rec map[string]any) {
+	if len(b) < 40 || b[0]>>4 != 6 {
+		return
+	}
+	rec["ip_version"] = uint64(6)
+	rec["ip_tos"] = uint64(binary.BigEndian.Uint16(b) >> 4 & 0xff)
+	rec["ip_ttl"] = uint64(b[7])
+	rec["ip_protocol"] = uint64(b[6])
+	rec["src_ip"] = net.IP(b[8:24]).String()
+	rec["dst_ip"] = net.IP(b[24:40]).String()
+	decodeTransport(b[6], b[40:], rec)
+}
+
+func decodeTransport(protocol byte, b []byte, rec map[string]any) {
+	switch protocol {
+	case 1, 58:
+		if len(b) >= 2 {
+			rec["icmp_type"] = uint64(b[0])
+			rec["icmp_code"] = uint64(b[1])
+		}
+	case 6:
+		if len(b) >= 14 {
+			rec["src_port"] = uint64(binary.BigEndian.Uint16(b))
+			rec["dst_port"] = uint64(binary.BigEndian.Uint16(b[2:]))
+			rec["tcp_flags"] = uint64(b[13])
+		}
+	case 17:
+		if len(b) >= 4 {
+			rec["src_port"] = uint64(binary.BigEndian.Uint16(b))
+			rec["dst_port"] = uint64(binary.BigEndian.Uint16(b[2:]))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package netflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSFlowRecord(format uint32, body testBuf) testBuf {
+	return testBuf{}.u32(format).u32(uint32(len(body))).bytes(body)
+}
+
+func testSFlowV5(seq uint32, samples ...testBuf) []byte {
+	b := testBuf{}.u32(5).u32(1).ip("192.0.2.1").u32(2).u32(seq).u32(123456).u32(uint32(len(samples)))
+	for _, s := range samples {
+		b = b.bytes(s)
+	}
+	return b
+}
+
+func TestDecodeSFlowV5(t *testing.T) {
+	// An Ethernet frame of an IPv4 TCP packet tagged with a VLAN.
+	frame := testBuf{}.
+		bytes([]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}).
+		bytes([]byte{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb}).
+		u16(0x8100).u16(10).u16(0x0800).
+		u8(0x45).u8(0x10).u16(52).u16(0).u16(0x4000).u8(64).u8(6).u16(0).
+		ip("10.0.0.1").ip("10.0.0.2").
+		u16(51000).u16(80).u32(0).u32(0).u8(0x50).u8(0x12).u16(0).u16(0).u16(0)
+	header := testBuf{}.u32(1).u32(1518).u32(4).u32(uint32(len(frame))).bytes(frame)
+	for len(header)%4 != 0 {
+		header = header.u8(0)
+	}
+
+	flowSample := testSFlowRecord(1, testBuf{}.
+		u32(17).u32(3<<24|7).u32(512).u32(1024).u32(0).u32(7).u32(1<<30|2).
+		u32(3).
+		bytes(testSFlowRecord(1, header)).
+		bytes(testSFlowRecord(1001, testBuf{}.u32(10).u32(0).u32(20).u32(1))).
+		bytes(testSFlowRecord(2<<12|5, testBuf{}.u32(0xdeadbeef))))
+
+	var counters testBuf
+	counters = counters.u32(7).u32(6).u64(1e9).u32(1).u32(3).u64(5000)
+	for i := 0; i < 6; i++ {
+		counters = counters.u32(uint32(i))
+	}
+	counters = counters.u64(6000)
+	for i := 0; i < 6; i++ {
+		counters = counters.u32(uint32(i + 10))
+	}
+	counterSample := testSFlowRecord(2, testBuf{}.u32(3).u32(7).u32(1).bytes(testSFlowRecord(1, counters)))
+
+	records, err := testDecoder().decode("127.0.0.1:1234", testSFlowV5(9, flowSample, counterSample), time.Now())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, protocolSFlowV5, records[0].protocol)
+	assert.Equal(t, recordTypeFlowSample, records[0].recordType)
+	assert.Equal(t, "192.0.2.1", records[0].agent)
+	assert.Equal(t, uint32(2), records[0].domain)
+	assert.Equal(t, uint32(9), records[0].sequence)
+	assert.True(t, records[0].exportTime.IsZero())
+	assert.Equal(t, map[string]any{
+		"sequence_number": uint64(17),
+		"source_id_type":  uint64(3),
+		"source_id_index": uint64(7),
+		"sampling_rate":   uint64(512),
+		"sample_pool":     uint64(1024),
+		"drops":           uint64(0),
+		"input_format":    uint64(0),
+		"input":           uint64(7),
+		"output_format":   uint64(1),
+		"output":          uint64(2),
+		"records": []any{
+			map[string]any{
+				"type":            "sampled_header",
+				"header_protocol": uint64(1),
+				"frame_length":    uint64(1518),
+				"stripped":        uint64(4),
+				"header":          frame.hex(),
+				"dst_mac":         "00:11:22:33:44:55",
+				"src_mac":         "66:77:88:99:aa:bb",
+				"vlan":            uint64(10),
+				"ethertype":       uint64(0x0800),
+				"ip_version":      uint64(4),
+				"ip_tos":          uint64(0x10),
+				"ip_ttl":          uint64(64),
+				"ip_protocol":     uint64(6),
+				"src_ip":          "10.0.0.1",
+				"dst_ip":          "10.0.0.2",
+				"src_port":        uint64(51000),
+				"dst_port":        uint64(80),
+				"tcp_flags":       uint64(0x12),
+			},
+			map[string]any{
+				"type":         "extended_switch",
+				"src_vlan":     uint64(10),
+				"src_priority": uint64(0),
+				"dst_vlan":     uint64(20),
+				"dst_priority": uint64(1),
+			},
+			map[string]any{
+				"type":       "unknown",
+				"enterprise": uint64(2),
+				"format":     uint64(5),
+				"data":       "deadbeef",
+			},
+		},
+	}, records[0].fields)
+
+	assert.Equal(t, recordTypeCounterSample, records[1].recordType)
+	counterRecords := records[1].fields["records"].([]any)
+	require.Len(t, counterRecords, 1)
+	assert.Equal(t, "generic_interface", counterRecords[0].(map[string]any)["type"])
+	assert.Equal(t, uint64(1e9), counterRecords[0].(map[string]any)["if_speed"])
+	assert.Equal(t, uint64(6000), counterRecords[0].(map[string]any)["if_out_octets"])
+	assert.Equal(t, uint64(15), counterRecords[0].(map[string]any)["if_promiscuous_mode"])
+
+	_, err = testDecoder().decode("127.0.0.1:1234", testSFlowV5(9, flowSample)[:60], time.Now())
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package netflow
+
+import (
+	"sync"
+	"time"
+)
+
+// The length of a field of a template that signals that its values are of a
+// variable length, RFC 7011 section 7.
+const variableLength = 65535
+
+type templateField struct {
+	id         uint16
+	length     uint16
+	enterprise uint32
+
+	// Whether the field is a scope field of an options template of NetFlow v9,
+	// the types of which are distinct from those of other fields.
+	v9Scope bool
+}
+
+type template struct {
+	fields  []templateField
+	options bool
+	expires time.Time
+}
+
+// minLength returns the minimum length of a record of the template, where each
+// field of a variable length is at least the byte encoding its length.
+func (t *template) minLength() int {
+	var n int
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			n++
+		} else {
+			n += int(f.length)
+		}
+	}
+	return n
+}
+
+// templateKey identifies a template, which is scoped to the exporter and the
+// observation domain of NetFlow v9 and IPFIX, RFC 7011 section 8.
+type templateKey struct {
+	exporter string
+	version  uint16
+	domain   uint32
+	id       uint16
+}
+
+// templateCache stores the templates of each exporter until they expire, where
+// templates are refreshed each time they're received.
+type templateCache struct {
+	ttl time.Duration
+
+	mut       sync.Mutex
+	templates map[templateKey]*template
+	lastSweep time.Time
+}
+
+func newTemplateCache(ttl time.Duration) *templateCache {
+	return &templateCache{
+		ttl:       ttl,
+		templates: map[templateKey]*template{},
+	}
+}
+
+func (c *templateCache) get(key templateKey, now time.Time) *template {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	t, exists := c.templates[key]
+	if !exists {
+		return nil
+	}
+	if c.ttl > 0 && now.After(t.expires) {
+		delete(c.templates, key)
+		return nil
+	}
+	return t
+}
+
+func (c *templateCache) put(key templateKey, t *template, now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	t.expires = now.Add(c.ttl)
+	c.templates[key] = t
+
+	// Templates of exporters that have gone away are removed periodically, as
+	// otherwise they're only removed when accessed after expiring.
+	if c.ttl > 0 && now.Sub(c.lastSweep) > c.ttl {
+		for k, v := range c.templates {
+			if now.After(v.expires) {
+				delete(c.templates, k)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+func (c *templateCache) withdraw(key templateKey) {
+	c.mut.Lock()
+	delete(c.templates, key)
+	c.mut.Unlock()
+}
+
+// withdrawAll removes all templates of an observation domain of an exporter,
+// RFC 7011 section 8.1.
+func (c *templateCache) withdrawAll(exporter string, version uint16, domain uint32, options bool) {
+	c.mut.Lock()
+	for k, v := range c.templates {
+		if k.exporter == exporter && k.version == version && k.domain == domain && v.options == options {
+			delete(c.templates, k)
+		}
+	}
+	c.mut.Unlock()
+}
@@ -0,0 +1,334 @@
+package opcua
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// uaClient is a session of a client with a server, which is activated either
+// anonymously or with a user name and password.
+type uaClient struct {
+	channel   *uaChannel
+	authToken uaNodeID
+}
+
+type uaIdentity struct {
+	username string
+	password string
+}
+
+func dialUA(ctx context.Context, endpoint string, identity uaIdentity, sessionTimeout, timeout time.Duration) (*uaClient, error) {
+	channel, err := dialUAChannel(ctx, endpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &uaClient{channel: channel}
+	policyID, err := c.createSession(ctx, identity, sessionTimeout)
+	if err == nil {
+		err = c.activateSession(ctx, identity, policyID)
+	}
+	if err != nil {
+		channel.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// createSession creates a session and returns the id of the policy of the
+// server that accepts the kind of identity.
+func (c *uaClient) createSession(ctx context.Context, identity uaIdentity, sessionTimeout time.Duration) (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	d, err := c.channel.call(ctx, "MSG", uaNodeID{}, uaIDCreateSessionRequest, uaIDCreateSessionResponse, func(e *uaEncoder) {
+		// ClientDescription
+		e.writeString("urn:benthos:client")
+		e.writeString("https://www.benthos.dev")
+		e.writeLocalizedText("Benthos")
+		e.writeUint32(uaApplicationTypeClient)
+		e.writeString("") // GatewayServerUri
+		e.writeString("") // DiscoveryProfileUri
+		e.writeInt32(-1)  // DiscoveryUrls
+
+		e.writeString("") // ServerUri
+		e.writeString(c.channel.endpoint)
+		e.writeString("benthos")
+		e.writeByteString(nonce)
+		e.writeByteString(nil) // ClientCertificate
+		e.writeFloat64(float64(sessionTimeout / time.Millisecond))
+		e.writeUint32(uaMaxMessageSize)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	_ = d.readNodeID() // SessionId
+	c.authToken = d.readNodeID()
+	_ = d.readFloat64()    // RevisedSessionTimeout
+	_ = d.readByteString() // ServerNonce
+	_ = d.readByteString() // ServerCertificate
+
+	tokenType := uint32(uaUserTokenAnonymous)
+	if identity.username != "" {
+		tokenType = uaUserTokenUserName
+	}
+	var policyID, policyErr string
+	for i, n := 0, d.readArrayLen(); i < n; i++ {
+		_ = d.readString() // EndpointUrl
+		d.skipApplicationDescription()
+		_ = d.readByteString() // ServerCertificate
+		securityMode := d.readUint32()
+		securityPolicy := d.readString()
+		for j, m := 0, d.readArrayLen(); j < m; j++ {
+			id := d.readString()
+			typ := d.readUint32()
+			_ = d.readString() // IssuedTokenType
+			_ = d.readString() // IssuerEndpointUrl
+			tokenPolicy := d.readString()
+			if policyID != "" || securityMode != uaMessageSecurityModeNone || securityPolicy != uaSecurityPolicyNone || typ != tokenType {
+				continue
+			}
+			if typ == uaUserTokenUserName && tokenPolicy != "" && tokenPolicy != uaSecurityPolicyNone {
+				policyErr = fmt.Sprintf("server requires the password to be encrypted with security policy %v, which is not supported", tokenPolicy)
+				continue
+			}
+			policyID = id
+		}
+		_ = d.readString() // TransportProfileUri
+		_ = d.readByte()   // SecurityLevel
+	}
+	if d.err != nil {
+		return "", fmt.Errorf("failed to read create session response: %w", d.err)
+	}
+	if policyID == "" {
+		if policyErr != "" {
+			return "", errors.New(policyErr)
+		}
+		if identity.username != "" {
+			return "", errors.New("server doesn't accept user names without security")
+		}
+		return "", errors.New("server doesn't accept anonymous users without security")
+	}
+	return policyID, nil
+}
+
+func (d *uaDecoder) skipApplicationDescription() {
+	_ = d.readString() // ApplicationUri
+	_ = d.readString() // ProductUri
+	_ = d.readLocalizedText()
+	_ = d.readUint32() // ApplicationType
+	_ = d.readString() // GatewayServerUri
+	_ = d.readString() // DiscoveryProfileUri
+	for i, n := 0, d.readArrayLen(); i < n; i++ {
+		_ = d.readString()
+	}
+}
+
+func (c *uaClient) activateSession(ctx context.Context, identity uaIdentity, policyID string) error {
+	var token uaEncoder
+	token.writeString(policyID)
+	tokenTypeID := uint32(uaIDAnonymousIdentityToken)
+	if identity.username != "" {
+		tokenTypeID = uaIDUserNameIdentityToken
+		token.writeString(identity.username)
+		token.writeByteString([]byte(identity.password))
+		token.writeString("") // EncryptionAlgorithm
+	}
+
+	_, err := c.channel.call(ctx, "MSG", c.authToken, uaIDActivateSessionRequest, uaIDActivateSessionResponse, func(e *uaEncoder) {
+		e.writeString("")      // ClientSignature.Algorithm
+		e.writeByteString(nil) // ClientSignature.Signature
+		e.writeInt32(-1)       // ClientSoftwareCertificates
+		e.writeInt32(-1)       // LocaleIds
+		e.writeExtensionObject(tokenTypeID, token.b)
+		e.writeString("")      // UserTokenSignature.Algorithm
+		e.writeByteString(nil) // UserTokenSignature.Signature
+	})
+	if err != nil {
+		return fmt.Errorf("failed to activate session: %w", err)
+	}
+	return nil
+}
+
+// read reads the values of nodes.
+func (c *uaClient) read(ctx context.Context, nodes []uaNodeID) ([]uaDataValue, error) {
+	d, err := c.channel.call(ctx, "MSG", c.authToken, uaIDReadRequest, uaIDReadResponse, func(e *uaEncoder) {
+		e.writeFloat64(0) // MaxAge
+		e.writeUint32(uaTimestampsBoth)
+		e.writeInt32(int32(len(nodes)))
+		for _, n := range nodes {
+			writeUAReadValueID(e, n)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]uaDataValue, d.readArrayLen())
+	for i := range values {
+		values[i] = d.readDataValue()
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", d.err)
+	}
+	if len(values) != len(nodes) {
+		return nil, fmt.Errorf("server responded with %v values for %v nodes", len(values), len(nodes))
+	}
+	return values, nil
+}
+
+func writeUAReadValueID(e *uaEncoder, n uaNodeID) {
+	e.writeNodeID(n)
+	e.writeUint32(uaAttributeValue)
+	e.writeString("") // IndexRange
+	e.writeUint16(0)  // DataEncoding.NamespaceIndex
+	e.writeString("") // DataEncoding.Name
+}
+
+// createSubscription creates a subscription and returns its id along with the
+// revised publishing interval and keep alive count.
+func (c *uaClient) createSubscription(ctx context.Context, interval time.Duration) (id uint32, revisedInterval time.Duration, keepAlive uint32, err error) {
+	d, err := c.channel.call(ctx, "MSG", c.authToken, uaIDCreateSubscriptionRequest, uaIDCreateSubscriptionResponse, func(e *uaEncoder) {
+		e.writeFloat64(float64(interval) / float64(time.Millisecond))
+		e.writeUint32(60) // RequestedLifetimeCount
+		e.writeUint32(10) // RequestedMaxKeepAliveCount
+		e.writeUint32(0)  // MaxNotificationsPerPublish
+		e.writeBool(true) // PublishingEnabled
+		e.writeByte(0)    // Priority
+	})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	id = d.readUint32()
+	revisedInterval = time.Duration(d.readFloat64() * float64(time.Millisecond))
+	_ = d.readUint32() // RevisedLifetimeCount
+	keepAlive = d.readUint32()
+	if d.err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to read create subscription response: %w", d.err)
+	}
+	return id, revisedInterval, keepAlive, nil
+}
+
+// createMonitoredItems monitors the values of nodes, where the client handle
+// of each item is the index of its node.
+func (c *uaClient) createMonitoredItems(ctx context.Context, subscriptionID uint32, nodes []uaNodeID, interval time.Duration, queueSize uint32) error {
+	d, err := c.channel.call(ctx, "MSG", c.authToken, uaIDCreateMonitoredItemsRequest, uaIDCreateMonitoredItemsResponse, func(e *uaEncoder) {
+		e.writeUint32(subscriptionID)
+		e.writeUint32(uaTimestampsBoth)
+		e.writeInt32(int32(len(nodes)))
+		for i, n := range nodes {
+			writeUAReadValueID(e, n)
+			e.writeUint32(uaMonitoringModeReporting)
+			e.writeUint32(uint32(i)) // ClientHandle
+			e.writeFloat64(float64(interval) / float64(time.Millisecond))
+			e.writeExtensionObject(0, nil) // Filter
+			e.writeUint32(queueSize)
+			e.writeBool(true) // DiscardOldest
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create monitored items: %w", err)
+	}
+
+	n := d.readArrayLen()
+	for i := 0; i < n; i++ {
+		status := uaStatus(d.readUint32())
+		_ = d.readUint32()  // MonitoredItemId
+		_ = d.readFloat64() // RevisedSamplingInterval
+		_ = d.readUint32()  // RevisedQueueSize
+		_, _ = d.readExtensionObject()
+		if d.err == nil && status.isBad() && i < len(nodes) {
+			return fmt.Errorf("failed to monitor node %v: %w", nodes[i], status)
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("failed to read create monitored items response: %w", d.err)
+	}
+	if n != len(nodes) {
+		return fmt.Errorf("server responded with %v results for %v monitored items", n, len(nodes))
+	}
+	return nil
+}
+
+// uaItemChange is a change of the value of a monitored item.
+type uaItemChange struct {
+	handle uint32
+	value  uaDataValue
+}
+
+// uaAcknowledgement acknowledges a notification message of a subscription.
+type uaAcknowledgement struct {
+	subscriptionID uint32
+	sequence       uint32
+}
+
+// publish waits for the next notification message of a subscription, and
+// returns its changes along with the acknowledgement of the message. Keep
+// alive messages have no changes and aren't acknowledged.
+func (c *uaClient) publish(ctx context.Context, acks []uaAcknowledgement) ([]uaItemChange, *uaAcknowledgement, error) {
+	d, err := c.channel.call(ctx, "MSG", c.authToken, uaIDPublishRequest, uaIDPublishResponse, func(e *uaEncoder) {
+		e.writeInt32(int32(len(acks)))
+		for _, a := range acks {
+			e.writeUint32(a.subscriptionID)
+			e.writeUint32(a.sequence)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	subscriptionID := d.readUint32()
+	for i, n := 0, d.readArrayLen(); i < n; i++ {
+		_ = d.readUint32() // AvailableSequenceNumbers
+	}
+	_ = d.readBool() // MoreNotifications
+	sequence := d.readUint32()
+	_ = d.readDateTime() // PublishTime
+
+	var changes []uaItemChange
+	var status uaStatus
+	n := d.readArrayLen()
+	for i := 0; i < n; i++ {
+		typeID, body := d.readExtensionObject()
+		nd := &uaDecoder{b: body}
+		switch typeID {
+		case uaNumericNodeID(uaIDDataChangeNotification):
+			for j, m := 0, nd.readArrayLen(); j < m; j++ {
+				handle := nd.readUint32()
+				changes = append(changes, uaItemChange{handle: handle, value: nd.readDataValue()})
+			}
+			nd.skipDiagnosticInfos()
+		case uaNumericNodeID(uaIDStatusChangeNotification):
+			status = uaStatus(nd.readUint32())
+		}
+		if nd.err != nil {
+			return nil, nil, fmt.Errorf("failed to read notification: %w", nd.err)
+		}
+	}
+	if d.err != nil {
+		return nil, nil, fmt.Errorf("failed to read publish response: %w", d.err)
+	}
+	if status.isBad() {
+		return nil, nil, fmt.Errorf("subscription changed to %w", status)
+	}
+	if n == 0 {
+		return nil, nil, nil
+	}
+	return changes, &uaAcknowledgement{subscriptionID: subscriptionID, sequence: sequence}, nil
+}
+
+// close closes the session along with its subscriptions, and then the secure
+// channel.
+func (c *uaClient) close(ctx context.Context) {
+	_, _ = c.channel.call(ctx, "MSG", c.authToken, uaIDCloseSessionRequest, uaIDCloseSessionResponse, func(e *uaEncoder) {
+		e.writeBool(true) // DeleteSubscriptions
+	})
+	c.channel.close()
+}
@@ -0,0 +1,477 @@
+package opcua
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// The limits that are advertised to the server, where a response may be
+	// sent in any number of chunks up to the maximum message size.
+	uaReceiveBufferSize = 1 << 16
+	uaMaxMessageSize    = 1 << 24
+
+	// The size of the header of each message, and of the headers of
+	// symmetrically secured chunks that precede their body.
+	uaHeaderLen          = 8
+	uaSymmetricHeaderLen = uaHeaderLen + 16
+
+	uaRequestIssue = 0
+	uaRequestRenew = 1
+)
+
+var errUAChannelClosed = errors.New("secure channel is closed")
+
+// uaResult is the body of a response to a request, or the reason that the
+// response was aborted.
+type uaResult struct {
+	body []byte
+	err  error
+}
+
+// uaChannel is a secure channel with the security policy None over a TCP
+// connection. Requests may be sent concurrently, and responses are matched to
+// them by their request ids.
+type uaChannel struct {
+	conn     net.Conn
+	endpoint string
+	timeout  time.Duration
+
+	// The limits of the server for the messages that are sent to it.
+	sendBufferSize uint32
+	maxMessageSize uint32
+	maxChunkCount  uint32
+
+	writeMut  sync.Mutex
+	channelID uint32
+	tokenID   uint32
+	sequence  uint32
+	requestID uint32
+	handle    uint32
+
+	pendingMut sync.Mutex
+	pending    map[uint32]chan uaResult
+
+	failOnce sync.Once
+	err      error
+	done     chan struct{}
+}
+
+// uaEndpointAddress returns the address of the TCP connection to an endpoint
+// such as `opc.tcp://localhost:4840/path`.
+func uaEndpointAddress(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "opc.tcp" {
+		return "", fmt.Errorf("endpoint %v must have the scheme opc.tcp", endpoint)
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "4840"), nil
+	}
+	return u.Host, nil
+}
+
+func dialUAChannel(ctx context.Context, endpoint string, timeout time.Duration) (*uaChannel, error) {
+	address, err := uaEndpointAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	c := &uaChannel{
+		conn:     conn,
+		endpoint: endpoint,
+		timeout:  timeout,
+		pending:  map[uint32]chan uaResult{},
+		done:     make(chan struct{}),
+	}
+	if err := c.hello(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+
+	lifetime, err := c.open(ctx, uaRequestIssue)
+	if err != nil {
+		c.fail(err)
+		return nil, fmt.Errorf("failed to open secure channel: %w", err)
+	}
+	go c.renewLoop(lifetime)
+	return c, nil
+}
+
+// hello negotiates the limits of the connection.
+func (c *uaChannel) hello(ctx context.Context) error {
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+	defer func() {
+		_ = c.conn.SetDeadline(time.Time{})
+	}()
+
+	var e uaEncoder
+	e.writeUint32(0) // ProtocolVersion
+	e.writeUint32(uaReceiveBufferSize)
+	e.writeUint32(uaReceiveBufferSize)
+	e.writeUint32(uaMaxMessageSize)
+	e.writeUint32(0) // MaxChunkCount
+	e.writeString(c.endpoint)
+	if _, err := c.conn.Write(uaChunk("HEL", 'F', e.b)); err != nil {
+		return err
+	}
+
+	typ, _, body, err := uaReadChunk(c.conn)
+	if err != nil {
+		return err
+	}
+	d := &uaDecoder{b: body}
+	switch typ {
+	case "ACK":
+		_ = d.readUint32() // ProtocolVersion
+		receiveBufferSize := d.readUint32()
+		_ = d.readUint32() // SendBufferSize
+		c.maxMessageSize = d.readUint32()
+		c.maxChunkCount = d.readUint32()
+		if d.err != nil {
+			return fmt.Errorf("failed to read acknowledge message: %w", d.err)
+		}
+		if receiveBufferSize < 8192 {
+			return fmt.Errorf("server has receive buffer size %v, which is below the minimum of 8192", receiveBufferSize)
+		}
+		c.sendBufferSize = receiveBufferSize
+		return nil
+	case "ERR":
+		return uaErrorMessage(d)
+	}
+	return fmt.Errorf("server responded to hello with unexpected message %v", typ)
+}
+
+func uaErrorMessage(d *uaDecoder) error {
+	status := uaStatus(d.readUint32())
+	if reason := d.readString(); reason != "" {
+		return fmt.Errorf("server sent error %v: %v", status, reason)
+	}
+	return fmt.Errorf("server sent error %v", status)
+}
+
+// uaChunk returns a message, or a chunk of one, with its header.
+func uaChunk(typ string, chunkType byte, body []byte) []byte {
+	b := make([]byte, uaHeaderLen, uaHeaderLen+len(body))
+	copy(b, typ)
+	b[3] = chunkType
+	binary.LittleEndian.PutUint32(b[4:], uint32(uaHeaderLen+len(body)))
+	return append(b, body...)
+}
+
+func uaReadChunk(r io.Reader) (typ string, chunkType byte, body []byte, err error) {
+	header := make([]byte, uaHeaderLen)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return
+	}
+	size := binary.LittleEndian.Uint32(header[4:])
+	if size < uaHeaderLen || size > uaReceiveBufferSize {
+		err = fmt.Errorf("message has size %v, which exceeds the receive buffer size", size)
+		return
+	}
+	body = make([]byte, size-uaHeaderLen)
+	if _, err = io.ReadFull(r, body); err != nil {
+		return
+	}
+	return string(header[:3]), header[3], body, nil
+}
+
+// readLoop reassembles the chunks of responses and routes them to their
+// requests until the connection fails.
+func (c *uaChannel) readLoop() {
+	partial := map[uint32][]byte{}
+	for {
+		typ, chunkType, body, err := uaReadChunk(c.conn)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		d := &uaDecoder{b: body}
+		switch typ {
+		case "ERR":
+			c.fail(uaErrorMessage(d))
+			return
+		case "OPN":
+			_ = d.readUint32() // SecureChannelId
+			_ = d.readString()
+			_ = d.readByteString()
+			_ = d.readByteString()
+		case "MSG":
+			if channelID := d.readUint32(); d.err == nil && channelID != c.currentChannelID() {
+				c.fail(fmt.Errorf("server sent message of unknown secure channel %v", channelID))
+				return
+			}
+			_ = d.readUint32() // TokenId
+		default:
+			c.fail(fmt.Errorf("server sent unexpected message %v", typ))
+			return
+		}
+		_ = d.readUint32() // SequenceNumber
+		requestID := d.readUint32()
+		if d.err != nil {
+			c.fail(fmt.Errorf("failed to read message: %w", d.err))
+			return
+		}
+
+		switch chunkType {
+		case 'C':
+			data := append(partial[requestID], d.b...)
+			if len(data) > uaMaxMessageSize {
+				c.fail(errors.New("server sent message that exceeds the maximum message size"))
+				return
+			}
+			partial[requestID] = data
+		case 'F':
+			data := append(partial[requestID], d.b...)
+			delete(partial, requestID)
+			c.deliver(requestID, uaResult{body: data})
+		case 'A':
+			delete(partial, requestID)
+			c.deliver(requestID, uaResult{err: fmt.Errorf("response was aborted: %w", uaErrorMessage(d))})
+		default:
+			c.fail(fmt.Errorf("server sent message with unknown chunk type %q", chunkType))
+			return
+		}
+	}
+}
+
+func (c *uaChannel) currentChannelID() uint32 {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+	return c.channelID
+}
+
+func (c *uaChannel) deliver(requestID uint32, res uaResult) {
+	c.pendingMut.Lock()
+	resChan, exists := c.pending[requestID]
+	delete(c.pending, requestID)
+	c.pendingMut.Unlock()
+
+	if exists {
+		resChan <- res
+	}
+}
+
+// fail closes the channel, where requests that are waiting for a response
+// and those sent afterwards fail with err.
+func (c *uaChannel) fail(err error) {
+	c.failOnce.Do(func() {
+		c.err = err
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
+
+// send sends a message in as many chunks as the server requires, and returns
+// a channel that receives the response when wait is true.
+func (c *uaChannel) send(typ string, body []byte, wait bool) (uint32, <-chan uaResult, error) {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	select {
+	case <-c.done:
+		return 0, nil, c.err
+	default:
+	}
+
+	if c.maxMessageSize > 0 && uint32(len(body)) > c.maxMessageSize {
+		return 0, nil, fmt.Errorf("request of %v bytes exceeds the maximum message size of the server", len(body))
+	}
+
+	c.requestID++
+	requestID := c.requestID
+
+	var chunks [][]byte
+	if typ == "OPN" {
+		// Asymmetrically secured messages are small enough to fit within a
+		// single chunk.
+		var e uaEncoder
+		e.writeUint32(c.channelID)
+		e.writeString(uaSecurityPolicyNone)
+		e.writeByteString(nil)
+		e.writeByteString(nil)
+		c.sequence++
+		e.writeUint32(c.sequence)
+		e.writeUint32(requestID)
+		e.b = append(e.b, body...)
+		chunks = append(chunks, uaChunk(typ, 'F', e.b))
+	} else {
+		maxBody := int(c.sendBufferSize) - uaSymmetricHeaderLen
+		for {
+			n := len(body)
+			chunkType := byte('F')
+			if n > maxBody {
+				n, chunkType = maxBody, 'C'
+			}
+			var e uaEncoder
+			e.writeUint32(c.channelID)
+			e.writeUint32(c.tokenID)
+			c.sequence++
+			e.writeUint32(c.sequence)
+			e.writeUint32(requestID)
+			e.b = append(e.b, body[:n]...)
+			chunks = append(chunks, uaChunk(typ, chunkType, e.b))
+			if body = body[n:]; chunkType == 'F' {
+				break
+			}
+		}
+	}
+	if c.maxChunkCount > 0 && uint32(len(chunks)) > c.maxChunkCount {
+		return 0, nil, fmt.Errorf("request of %v chunks exceeds the maximum chunk count of the server", len(chunks))
+	}
+
+	var resChan chan uaResult
+	if wait {
+		resChan = make(chan uaResult, 1)
+		c.pendingMut.Lock()
+		c.pending[requestID] = resChan
+		c.pendingMut.Unlock()
+	}
+
+	for _, chunk := range chunks {
+		if _, err := c.conn.Write(chunk); err != nil {
+			c.fail(err)
+			return 0, nil, err
+		}
+	}
+	return requestID, resChan, nil
+}
+
+// roundTrip sends a message and waits for the body of its response.
+func (c *uaChannel) roundTrip(ctx context.Context, typ string, body []byte) ([]byte, error) {
+	requestID, resChan, err := c.send(typ, body, true)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-resChan:
+		return res.body, res.err
+	case <-c.done:
+		return nil, c.err
+	case <-ctx.Done():
+		c.pendingMut.Lock()
+		delete(c.pending, requestID)
+		c.pendingMut.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// call invokes a service with a request that has the fields written by
+// fields, and returns a decoder of the fields of the response that follow its
+// header. Service faults and bad service results are returned as errors.
+func (c *uaChannel) call(ctx context.Context, typ string, authToken uaNodeID, requestID, responseID uint32, fields func(e *uaEncoder)) (*uaDecoder, error) {
+	timeout := c.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	c.writeMut.Lock()
+	c.handle++
+	handle := c.handle
+	c.writeMut.Unlock()
+
+	var e uaEncoder
+	e.writeNodeID(uaNumericNodeID(requestID))
+	e.writeRequestHeader(authToken, handle, timeout)
+	fields(&e)
+
+	body, err := c.roundTrip(ctx, typ, e.b)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &uaDecoder{b: body}
+	typeID := d.readNodeID()
+	result := d.readResponseHeader()
+	if d.err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", d.err)
+	}
+	if typeID == uaNumericNodeID(uaIDServiceFault) || result.isBad() {
+		return nil, result
+	}
+	if typeID != uaNumericNodeID(responseID) {
+		return nil, fmt.Errorf("server responded with unexpected type %v", typeID)
+	}
+	return d, nil
+}
+
+// open issues or renews the security token of the channel and returns its
+// lifetime.
+func (c *uaChannel) open(ctx context.Context, requestType uint32) (time.Duration, error) {
+	d, err := c.call(ctx, "OPN", uaNodeID{}, uaIDOpenSecureChannelRequest, uaIDOpenSecureChannelResponse, func(e *uaEncoder) {
+		e.writeUint32(0) // ClientProtocolVersion
+		e.writeUint32(requestType)
+		e.writeUint32(uaMessageSecurityModeNone)
+		e.writeByteString(nil) // ClientNonce
+		e.writeUint32(uint32(time.Hour / time.Millisecond))
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_ = d.readUint32() // ServerProtocolVersion
+	channelID := d.readUint32()
+	tokenID := d.readUint32()
+	_ = d.readDateTime()
+	lifetime := time.Duration(d.readUint32()) * time.Millisecond
+	_ = d.readByteString() // ServerNonce
+	if d.err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", d.err)
+	}
+
+	c.writeMut.Lock()
+	c.channelID, c.tokenID = channelID, tokenID
+	c.writeMut.Unlock()
+	return lifetime, nil
+}
+
+// renewLoop renews the security token once three quarters of its lifetime
+// have passed.
+func (c *uaChannel) renewLoop(lifetime time.Duration) {
+	for {
+		select {
+		case <-time.After(lifetime * 3 / 4):
+		case <-c.done:
+			return
+		}
+
+		ctx, done := context.WithTimeout(context.Background(), c.timeout)
+		var err error
+		lifetime, err = c.open(ctx, uaRequestRenew)
+		done()
+		if err != nil {
+			c.fail(fmt.Errorf("failed to renew secure channel: %w", err))
+			return
+		}
+	}
+}
+
+// close closes the secure channel and its connection.
+func (c *uaChannel) close() {
+	var e uaEncoder
+	e.writeNodeID(uaNumericNodeID(uaIDCloseSecureChannelRequest))
+	e.writeRequestHeader(uaNodeID{}, 0, c.timeout)
+	_, _, _ = c.send("CLO", e.b, false)
+	c.fail(errUAChannelClosed)
+}
@@ -0,0 +1,665 @@
+package opcua
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The identifiers of the binary encodings of the structures that are sent and
+// received, which precede the body of each message.
+const (
+	uaIDServiceFault                 = 397
+	uaIDAnonymousIdentityToken       = 321
+	uaIDUserNameIdentityToken        = 324
+	uaIDOpenSecureChannelRequest     = 446
+	uaIDOpenSecureChannelResponse    = 449
+	uaIDCloseSecureChannelRequest    = 452
+	uaIDCreateSessionRequest         = 461
+	uaIDCreateSessionResponse        = 464
+	uaIDActivateSessionRequest       = 467
+	uaIDActivateSessionResponse      = 470
+	uaIDCloseSessionRequest          = 473
+	uaIDCloseSessionResponse         = 476
+	uaIDReadRequest                  = 631
+	uaIDReadResponse                 = 634
+	uaIDCreateMonitoredItemsRequest  = 751
+	uaIDCreateMonitoredItemsResponse = 754
+	uaIDCreateSubscriptionRequest    = 787
+	uaIDCreateSubscriptionResponse   = 790
+	uaIDDataChangeNotification       = 811
+	uaIDStatusChangeNotification     = 820
+	uaIDPublishRequest               = 826
+	uaIDPublishResponse              = 829
+)
+
+const (
+	uaSecurityPolicyNone      = "http://opcfoundation.org/UA/SecurityPolicy#None"
+	uaAttributeValue          = 13
+	uaTimestampsBoth          = 2
+	uaMessageSecurityModeNone = 1
+	uaUserTokenAnonymous      = 0
+	uaUserTokenUserName       = 1
+	uaMonitoringModeReporting = 2
+	uaApplicationTypeClient   = 1
+
+	// The number of intervals of 100 nanoseconds between the epoch of
+	// DateTime values, which is the start of 1601, and the unix epoch.
+	uaDateTimeUnixEpoch int64 = 116444736000000000
+)
+
+// uaStatus is a status code of OPC UA, where the two most significant bits
+// are set for bad codes and the second most significant bit only for
+// uncertain codes.
+type uaStatus uint32
+
+var uaStatusNames = map[uaStatus]string{
+	0x00000000: "Good",
+	0x80010000: "BadUnexpectedError",
+	0x80020000: "BadInternalError",
+	0x80030000: "BadOutOfMemory",
+	0x80040000: "BadResourceUnavailable",
+	0x80050000: "BadCommunicationError",
+	0x80060000: "BadEncodingError",
+	0x80070000: "BadDecodingError",
+	0x80080000: "BadEncodingLimitsExceeded",
+	0x80090000: "BadUnknownResponse",
+	0x800A0000: "BadTimeout",
+	0x800B0000: "BadServiceUnsupported",
+	0x800C0000: "BadShutdown",
+	0x800D0000: "BadServerNotConnected",
+	0x800E0000: "BadServerHalted",
+	0x80100000: "BadTooManyOperations",
+	0x801F0000: "BadUserAccessDenied",
+	0x80200000: "BadIdentityTokenInvalid",
+	0x80210000: "BadIdentityTokenRejected",
+	0x80220000: "BadSecureChannelIdInvalid",
+	0x80250000: "BadSessionIdInvalid",
+	0x80260000: "BadSessionClosed",
+	0x80270000: "BadSessionNotActivated",
+	0x80280000: "BadSubscriptionIdInvalid",
+	0x80330000: "BadNodeIdInvalid",
+	0x80340000: "BadNodeIdUnknown",
+	0x80350000: "BadAttributeIdInvalid",
+	0x803A0000: "BadNotReadable",
+	0x80550000: "BadSecurityPolicyRejected",
+	0x80560000: "BadTooManySessions",
+	0x80780000: "BadTooManyPublishRequests",
+	0x80790000: "BadNoSubscription",
+}
+
+func (s uaStatus) isBad() bool {
+	return s&0x80000000 != 0
+}
+
+func (s uaStatus) String() string {
+	if name, exists := uaStatusNames[s&0xFFFF0000]; exists {
+		return name
+	}
+	return fmt.Sprintf("0x%08X", uint32(s))
+}
+
+func (s uaStatus) Error() string {
+	return "status " + s.String()
+}
+
+//------------------------------------------------------------------------------
+
+// uaNodeID is the identifier of a node, where the value of string, guid and
+// opaque identifiers is held in ident. The bytes of guids are held in the
+// order of their string form.
+type uaNodeID struct {
+	namespace uint16
+	kind      byte
+	numeric   uint32
+	ident     string
+}
+
+func uaNumericNodeID(id uint32) uaNodeID {
+	return uaNodeID{kind: 'i', numeric: id}
+}
+
+// parseUANodeID parses the string form of a node id, such as `ns=2;s=Pump`.
+func parseUANodeID(s string) (id uaNodeID, err error) {
+	rest := s
+	if strings.HasPrefix(rest, "ns=") {
+		sep := strings.IndexByte(rest, ';')
+		if sep < 0 {
+			return id, fmt.Errorf("node id %q is missing an identifier", s)
+		}
+		ns, err := strconv.ParseUint(rest[3:sep], 10, 16)
+		if err != nil {
+			return id, fmt.Errorf("node id %q has an invalid namespace: %w", s, err)
+		}
+		id.namespace, rest = uint16(ns), rest[sep+1:]
+	}
+	if len(rest) < 2 || rest[1] != '=' {
+		return id, fmt.Errorf("node id %q must have an identifier of the form i=, s=, g= or b=", s)
+	}
+
+	id.kind, rest = rest[0], rest[2:]
+	switch id.kind {
+	case 'i':
+		n, err := strconv.ParseUint(rest, 10, 32)
+		if err != nil {
+			return id, fmt.Errorf("node id %q has an invalid numeric identifier: %w", s, err)
+		}
+		id.numeric = uint32(n)
+	case 's':
+		id.ident = rest
+	case 'g':
+		b, err := hex.DecodeString(strings.ReplaceAll(rest, "-", ""))
+		if err != nil || len(b) != 16 {
+			return id, fmt.Errorf("node id %q has an invalid guid identifier", s)
+		}
+		id.ident = string(b)
+	case 'b':
+		b, err := base64.StdEncoding.DecodeString(rest)
+		if err != nil {
+			return id, fmt.Errorf("node id %q has an invalid opaque identifier: %w", s, err)
+		}
+		id.ident = string(b)
+	default:
+		return id, fmt.Errorf("node id %q must have an identifier of the form i=, s=, g= or b=", s)
+	}
+	return id, nil
+}
+
+func (n uaNodeID) String() string {
+	var prefix string
+	if n.namespace != 0 {
+		prefix = "ns=" + strconv.Itoa(int(n.namespace)) + ";"
+	}
+	switch n.kind {
+	case 's':
+		return prefix + "s=" + n.ident
+	case 'g':
+		return prefix + "g=" + uaGUIDString([]byte(n.ident))
+	case 'b':
+		return prefix + "b=" + base64.StdEncoding.EncodeToString([]byte(n.ident))
+	}
+	return prefix + "i=" + strconv.FormatUint(uint64(n.numeric), 10)
+}
+
+func uaGUIDString(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+//------------------------------------------------------------------------------
+
+// uaEncoder appends values with the binary encoding of OPC UA.
+type uaEncoder struct {
+	b []byte
+}
+
+func (e *uaEncoder) writeByte(v byte) {
+	e.b = append(e.b, v)
+}
+
+func (e *uaEncoder) writeBool(v bool) {
+	if v {
+		e.writeByte(1)
+	} else {
+		e.writeByte(0)
+	}
+}
+
+func (e *uaEncoder) writeUint16(v uint16) {
+	e.b = append(e.b, byte(v), byte(v>>8))
+}
+
+func (e *uaEncoder) writeUint32(v uint32) {
+	e.b = append(e.b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func (e *uaEncoder) writeInt32(v int32) {
+	e.writeUint32(uint32(v))
+}
+
+func (e *uaEncoder) writeUint64(v uint64) {
+	e.writeUint32(uint32(v))
+	e.writeUint32(uint32(v >> 32))
+}
+
+func (e *uaEncoder) writeFloat64(v float64) {
+	e.writeUint64(math.Float64bits(v))
+}
+
+// writeString writes an empty string as a null string, which some servers
+// expect of optional fields.
+func (e *uaEncoder) writeString(v string) {
+	if v == "" {
+		e.writeInt32(-1)
+		return
+	}
+	e.writeInt32(int32(len(v)))
+	e.b = append(e.b, v...)
+}
+
+func (e *uaEncoder) writeByteString(v []byte) {
+	e.writeString(string(v))
+}
+
+func (e *uaEncoder) writeDateTime(t time.Time) {
+	if t.IsZero() {
+		e.writeUint64(0)
+		return
+	}
+	e.writeUint64(uint64(t.UnixNano()/100 + uaDateTimeUnixEpoch))
+}
+
+func (e *uaEncoder) writeGUID(b []byte) {
+	e.writeUint32(binary.BigEndian.Uint32(b[0:]))
+	e.writeUint16(binary.BigEndian.Uint16(b[4:]))
+	e.writeUint16(binary.BigEndian.Uint16(b[6:]))
+	e.b = append(e.b, b[8:16]...)
+}
+
+func (e *uaEncoder) writeNodeID(n uaNodeID) {
+	switch n.kind {
+	case 's':
+		e.writeByte(0x03)
+		e.writeUint16(n.namespace)
+		e.writeString(n.ident)
+	case 'g':
+		e.writeByte(0x04)
+		e.writeUint16(n.namespace)
+		e.writeGUID([]byte(n.ident))
+	case 'b':
+		e.writeByte(0x05)
+		e.writeUint16(n.namespace)
+		e.writeString(n.ident)
+	default:
+		switch {
+		case n.namespace == 0 && n.numeric <= math.MaxUint8:
+			e.writeByte(0x00)
+			e.writeByte(byte(n.numeric))
+		case n.namespace <= math.MaxUint8 && n.numeric <= math.MaxUint16:
+			e.writeByte(0x01)
+			e.writeByte(byte(n.namespace))
+			e.writeUint16(uint16(n.numeric))
+		default:
+			e.writeByte(0x02)
+			e.writeUint16(n.namespace)
+			e.writeUint32(n.numeric)
+		}
+	}
+}
+
+func (e *uaEncoder) writeLocalizedText(text string) {
+	if text == "" {
+		e.writeByte(0)
+		return
+	}
+	e.writeByte(0x02)
+	e.writeString(text)
+}
+
+// writeExtensionObject writes a structure that is encoded in body, or a
+// null extension object when the type is zero.
+func (e *uaEncoder) writeExtensionObject(typeID uint32, body []byte) {
+	e.writeNodeID(uaNumericNodeID(typeID))
+	if typeID == 0 {
+		e.writeByte(0x00)
+		return
+	}
+	e.writeByte(0x01)
+	e.writeByteString(body)
+}
+
+func (e *uaEncoder) writeRequestHeader(authToken uaNodeID, handle uint32, timeout time.Duration) {
+	e.writeNodeID(authToken)
+	e.writeDateTime(time.Now())
+	e.writeUint32(handle)
+	e.writeUint32(0)  // ReturnDiagnostics
+	e.writeString("") // AuditEntryId
+	e.writeUint32(uint32(timeout / time.Millisecond))
+	e.writeExtensionObject(0, nil)
+}
+
+//------------------------------------------------------------------------------
+
+var errUATruncated = errors.New("message is truncated")
+
+// uaDecoder reads values with the binary encoding of OPC UA, where the first
+// error encountered is kept and the values read afterwards are zero.
+type uaDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *uaDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.b = nil
+}
+
+func (d *uaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.fail(errUATruncated)
+		return nil
+	}
+	v := d.b[:n:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *uaDecoder) readByte() byte {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *uaDecoder) readBool() bool {
+	return d.readByte() != 0
+}
+
+func (d *uaDecoder) readUint16() uint16 {
+	if b := d.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (d *uaDecoder) readUint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *uaDecoder) readInt32() int32 {
+	return int32(d.readUint32())
+}
+
+func (d *uaDecoder) readUint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *uaDecoder) readFloat64() float64 {
+	return math.Float64frombits(d.readUint64())
+}
+
+// readArrayLen reads the length of an array, where a null array has no
+// elements. As each element is at least a byte the length is limited by the
+// remaining bytes, which prevents huge allocations.
+func (d *uaDecoder) readArrayLen() int {
+	n := int(d.readInt32())
+	if n < 0 {
+		return 0
+	}
+	if n > len(d.b) {
+		d.fail(errUATruncated)
+		return 0
+	}
+	return n
+}
+
+func (d *uaDecoder) readString() string {
+	n := int(d.readInt32())
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(n))
+}
+
+func (d *uaDecoder) readByteString() []byte {
+	n := int(d.readInt32())
+	if n < 0 {
+		return nil
+	}
+	return d.next(n)
+}
+
+func (d *uaDecoder) readDateTime() time.Time {
+	ticks := int64(d.readUint64())
+	if ticks <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, (ticks-uaDateTimeUnixEpoch)*100).UTC()
+}
+
+func (d *uaDecoder) readGUID() string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint32(b[0:], d.readUint32())
+	binary.BigEndian.PutUint16(b[4:], d.readUint16())
+	binary.BigEndian.PutUint16(b[6:], d.readUint16())
+	copy(b[8:], d.next(8))
+	return string(b)
+}
+
+// readNodeID reads a node id or an expanded node id, where the namespace uri
+// and server index of the latter are discarded.
+func (d *uaDecoder) readNodeID() (n uaNodeID) {
+	mask := d.readByte()
+	n.kind = 'i'
+	switch mask & 0x0f {
+	case 0x00:
+		n.numeric = uint32(d.readByte())
+	case 0x01:
+		n.namespace = uint16(d.readByte())
+		n.numeric = uint32(d.readUint16())
+	case 0x02:
+		n.namespace = d.readUint16()
+		n.numeric = d.readUint32()
+	case 0x03:
+		n.kind, n.namespace, n.ident = 's', d.readUint16(), d.readString()
+	case 0x04:
+		n.kind, n.namespace, n.ident = 'g', d.readUint16(), d.readGUID()
+	case 0x05:
+		n.kind, n.namespace, n.ident = 'b', d.readUint16(), string(d.readByteString())
+	default:
+		d.fail(fmt.Errorf("node id has unknown encoding %#02x", mask))
+	}
+	if mask&0x80 != 0 {
+		_ = d.readString()
+	}
+	if mask&0x40 != 0 {
+		_ = d.readUint32()
+	}
+	return
+}
+
+func (d *uaDecoder) readLocalizedText() (text string) {
+	mask := d.readByte()
+	if mask&0x01 != 0 {
+		_ = d.readString()
+	}
+	if mask&0x02 != 0 {
+		text = d.readString()
+	}
+	return
+}
+
+func (d *uaDecoder) readExtensionObject() (typeID uaNodeID, body []byte) {
+	typeID = d.readNodeID()
+	switch d.readByte() {
+	case 0x00:
+	case 0x01, 0x02:
+		body = d.readByteString()
+	default:
+		d.fail(errors.New("extension object has unknown encoding"))
+	}
+	return
+}
+
+// skipDiagnosticInfo reads past diagnostics, which aren't requested.
+func (d *uaDecoder) skipDiagnosticInfo() {
+	mask := d.readByte()
+	for _, bit := range []byte{0x01, 0x02, 0x08, 0x04} {
+		if mask&bit != 0 {
+			_ = d.readInt32()
+		}
+	}
+	if mask&0x10 != 0 {
+		_ = d.readString()
+	}
+	if mask&0x20 != 0 {
+		_ = d.readUint32()
+	}
+	if mask&0x40 != 0 {
+		d.skipDiagnosticInfo()
+	}
+}
+
+func (d *uaDecoder) skipDiagnosticInfos() {
+	for i, n := 0, d.readArrayLen(); i < n; i++ {
+		d.skipDiagnosticInfo()
+	}
+}
+
+func (d *uaDecoder) readStatusCodes() []uaStatus {
+	codes := make([]uaStatus, d.readArrayLen())
+	for i := range codes {
+		codes[i] = uaStatus(d.readUint32())
+	}
+	return codes
+}
+
+// readResponseHeader reads the header of a response and returns the result
+// of the service.
+func (d *uaDecoder) readResponseHeader() uaStatus {
+	_ = d.readDateTime()
+	_ = d.readUint32() // RequestHandle
+	result := uaStatus(d.readUint32())
+	d.skipDiagnosticInfo()
+	for i, n := 0, d.readArrayLen(); i < n; i++ {
+		_ = d.readString()
+	}
+	_, _ = d.readExtensionObject()
+	return result
+}
+
+// readVariant reads a variant as a value that can be added to a structured
+// message. Arrays are read as slices, where the dimensions of multi
+// dimensional arrays are discarded.
+func (d *uaDecoder) readVariant() any {
+	mask := d.readByte()
+	typ := mask & 0x3f
+	if mask&0x80 == 0 {
+		return d.readVariantValue(typ)
+	}
+
+	values := make([]any, d.readArrayLen())
+	for i := range values {
+		values[i] = d.readVariantValue(typ)
+	}
+	if mask&0x40 != 0 {
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			_ = d.readInt32()
+		}
+	}
+	return values
+}
+
+func (d *uaDecoder) readVariantValue(typ byte) any {
+	switch typ {
+	case 0:
+		return nil
+	case 1:
+		return d.readBool()
+	case 2:
+		return int64(int8(d.readByte()))
+	case 3:
+		return int64(d.readByte())
+	case 4:
+		return int64(int16(d.readUint16()))
+	case 5:
+		return int64(d.readUint16())
+	case 6:
+		return int64(d.readInt32())
+	case 7:
+		return int64(d.readUint32())
+	case 8:
+		return int64(d.readUint64())
+	case 9:
+		return d.readUint64()
+	case 10:
+		return float64(math.Float32frombits(d.readUint32()))
+	case 11:
+		return d.readFloat64()
+	case 12, 16:
+		return d.readString()
+	case 13:
+		if t := d.readDateTime(); !t.IsZero() {
+			return t.Format(time.RFC3339Nano)
+		}
+		return nil
+	case 14:
+		return uaGUIDString([]byte(d.readGUID()))
+	case 15:
+		return d.readByteString()
+	case 17, 18:
+		return d.readNodeID().String()
+	case 19:
+		return int64(d.readUint32())
+	case 20:
+		ns := d.readUint16()
+		name := d.readString()
+		if ns == 0 {
+			return name
+		}
+		return strconv.Itoa(int(ns)) + ":" + name
+	case 21:
+		return d.readLocalizedText()
+	case 22:
+		typeID, body := d.readExtensionObject()
+		return map[string]any{"type_id": typeID.String(), "body": body}
+	case 23:
+		return d.readDataValue().value
+	case 24:
+		return d.readVariant()
+	case 25:
+		d.skipDiagnosticInfo()
+		return nil
+	}
+	d.fail(fmt.Errorf("variant has unknown type %v", typ))
+	return nil
+}
+
+// uaDataValue is the value of an attribute of a node along with its status
+// and timestamps.
+type uaDataValue struct {
+	value           any
+	status          uaStatus
+	sourceTimestamp time.Time
+	serverTimestamp time.Time
+}
+
+func (d *uaDecoder) readDataValue() (v uaDataValue) {
+	mask := d.readByte()
+	if mask&0x01 != 0 {
+		v.value = d.readVariant()
+	}
+	if mask&0x02 != 0 {
+		v.status = uaStatus(d.readUint32())
+	}
+	if mask&0x04 != 0 {
+		v.sourceTimestamp = d.readDateTime()
+	}
+	if mask&0x10 != 0 {
+		_ = d.readUint16()
+	}
+	if mask&0x08 != 0 {
+		v.serverTimestamp = d.readDateTime()
+	}
+	if mask&0x20 != 0 {
+		_ = d.readUint16()
+	}
+	return
+}
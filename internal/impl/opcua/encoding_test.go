@@ -0,0 +1,123 @@
+package opcua
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUANodeIDEncoding(t *testing.T) {
+	// The examples of the encodings of node ids of the specification.
+	for _, test := range []struct {
+		id      string
+		encoded string
+	}{
+		{id: "i=72", encoded: "0048"},
+		{id: "ns=5;i=1025", encoded: "01050104"},
+		{id: "ns=1;s=Hot水", encoded: "0301000600000048" + "6f74e6b0b4"},
+		{id: "g=72962b91-fa75-4ae6-8d28-b404dc7daf63", encoded: "040000912b967275fae64a8d28b404dc7daf63"},
+		{id: "ns=300;i=70000", encoded: "022c0170110100"},
+		{id: "ns=2;b=3q2+7w==", encoded: "05020004000000deadbeef"},
+	} {
+		n, err := parseUANodeID(test.id)
+		require.NoError(t, err, test.id)
+		assert.Equal(t, test.id, n.String())
+
+		var e uaEncoder
+		e.writeNodeID(n)
+		assert.Equal(t, test.encoded, hex.EncodeToString(e.b), test.id)
+
+		d := &uaDecoder{b: e.b}
+		assert.Equal(t, n, d.readNodeID(), test.id)
+		require.NoError(t, d.err)
+		assert.Empty(t, d.b)
+	}
+
+	for _, id := range []string{"", "ns=2", "ns=x;i=1", "i=x", "x=1", "g=1234", "b=!"} {
+		_, err := parseUANodeID(id)
+		require.Error(t, err, id)
+	}
+}
+
+func TestUAVariantDecoding(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	for _, test := range []struct {
+		value    any
+		expected any
+	}{
+		{value: nil, expected: nil},
+		{value: true, expected: true},
+		{value: int16(-2), expected: int64(-2)},
+		{value: uint32(4000000000), expected: int64(4000000000)},
+		{value: uint64(1 << 63), expected: uint64(1 << 63)},
+		{value: float32(1.5), expected: 1.5},
+		{value: "pump", expected: "pump"},
+		{value: ts, expected: "2023-01-02T15:04:05.123Z"},
+		{value: []byte("raw"), expected: []byte("raw")},
+		{value: uaNodeID{namespace: 2, kind: 's', ident: "Pump"}, expected: "ns=2;s=Pump"},
+		{value: []float64{1, 2.5}, expected: []any{1.0, 2.5}},
+	} {
+		var e uaEncoder
+		writeTestVariant(&e, test.value)
+		d := &uaDecoder{b: e.b}
+		assert.Equal(t, test.expected, d.readVariant())
+		require.NoError(t, d.err)
+		assert.Empty(t, d.b)
+	}
+
+	// A multi dimensional array of bytes with the dimensions 2 by 1.
+	b, err := hex.DecodeString("c302000000" + "0102" + "02000000" + "02000000" + "01000000")
+	require.NoError(t, err)
+	d := &uaDecoder{b: b}
+	assert.Equal(t, []any{int64(1), int64(2)}, d.readVariant())
+	require.NoError(t, d.err)
+	assert.Empty(t, d.b)
+}
+
+func TestUADataValueDecoding(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	var e uaEncoder
+	e.writeByte(0x01 | 0x02 | 0x04 | 0x08 | 0x10 | 0x20)
+	writeTestVariant(&e, 21.5)
+	e.writeUint32(0x40900000)
+	e.writeDateTime(ts)
+	e.writeUint16(10)
+	e.writeDateTime(ts.Add(time.Second))
+	e.writeUint16(20)
+
+	d := &uaDecoder{b: e.b}
+	v := d.readDataValue()
+	require.NoError(t, d.err)
+	assert.Equal(t, 21.5, v.value)
+	assert.Equal(t, "0x40900000", v.status.String())
+	assert.False(t, v.status.isBad())
+	assert.Equal(t, ts, v.sourceTimestamp)
+	assert.Equal(t, ts.Add(time.Second), v.serverTimestamp)
+	assert.Empty(t, d.b)
+}
+
+func TestUADecoderErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"0c05000000616263",
+		"8b10000000",
+		"3f",
+		"c50100000001000000",
+	} {
+		b, err := hex.DecodeString(input)
+		require.NoError(t, err)
+		d := &uaDecoder{b: b}
+		_ = d.readVariant()
+		require.Error(t, d.err, input)
+	}
+}
+
+func TestUAStatus(t *testing.T) {
+	assert.Equal(t, "status BadNodeIdUnknown", uaStatus(0x80340000).Error())
+	assert.True(t, uaStatus(0x80340000).isBad())
+	assert.Equal(t, "Good", uaStatus(0).String())
+}
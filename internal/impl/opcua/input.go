@@ -0,0 +1,405 @@
+package opcua
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	uaiFieldEndpoint       = "endpoint"
+	uaiFieldUsername       = "username"
+	uaiFieldPassword       = "password"
+	uaiFieldNodes          = "nodes"
+	uaiFieldNodeName       = "name"
+	uaiFieldNodeID         = "node_id"
+	uaiFieldMode           = "mode"
+	uaiFieldInterval       = "interval"
+	uaiFieldQueueSize      = "queue_size"
+	uaiFieldSessionTimeout = "session_timeout"
+	uaiFieldTimeout        = "timeout"
+)
+
+func uaInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Reads the values of nodes of an OPC UA server, either by polling them or by subscribing to changes of their values.").
+		Description(`
+Connects to an endpoint of a server over the binary protocol `+"`opc.tcp`"+` with the security policy `+"`None`"+`, and activates a session either anonymously or with a user name and password.
+
+### Modes
+
+In the mode `+"`poll`"+` the values of all nodes are read with each interval and a single message is created, which is an object with a field for each node. Nodes with a bad status have a `+"`null`"+` value.
+
+In the mode `+"`subscribe`"+` a subscription is created with a monitored item for each node, which the server samples with each interval. A message is created for each change of a value, which is an object of the following form:
+
+`+"```json"+`
+{
+  "name": "temperature",
+  "node_id": "ns=2;s=Temperature",
+  "value": 21.5,
+  "status": "Good",
+  "source_timestamp": "2023-01-02T15:04:05.123Z",
+  "server_timestamp": "2023-01-02T15:04:05.125Z"
+}
+`+"```"+`
+
+Notifications are acknowledged to the server once they are received and are therefore not delivered again when a message is rejected.
+
+### Values
+
+Values are converted from the built-in types of OPC UA, where integers and floating point numbers become numbers, date times become timestamp strings of RFC 3339, node ids and guids become their string forms and arrays become arrays. Structures are converted to an object with the fields `+"`type_id`"+` and `+"`body`"+`, where the body holds the encoded structure.`).
+		Field(service.NewStringField(uaiFieldEndpoint).
+			Description("The URL of the endpoint to connect to, where the port defaults to 4840.").
+			Example("opc.tcp://localhost:4840").
+			Example("opc.tcp://10.0.0.30:4840/plc")).
+		Field(service.NewStringField(uaiFieldUsername).
+			Description("An optional user name to activate the session with, which the server must accept without the password being encrypted. When empty the session is activated anonymously.").
+			Default("")).
+		Field(service.NewStringField(uaiFieldPassword).
+			Description("The password of the user.").
+			Default("")).
+		Field(service.NewObjectListField(uaiFieldNodes,
+			service.NewStringField(uaiFieldNodeName).
+				Description("The name of the node within messages."),
+			service.NewStringField(uaiFieldNodeID).
+				Description("The id of the node to read, in the string form of OPC UA.").
+				Example("ns=2;s=Temperature").
+				Example("i=2258"),
+		).
+			Description("The nodes to read the values of.").
+			Example([]any{
+				map[string]any{"name": "temperature", "node_id": "ns=2;s=Temperature"},
+				map[string]any{"name": "server_time", "node_id": "i=2258"},
+			})).
+		Field(service.NewStringEnumField(uaiFieldMode, "poll", "subscribe").
+			Description("Whether to read the values of the nodes with each interval or to subscribe to changes of their values.").
+			Default("poll")).
+		Field(service.NewDurationField(uaiFieldInterval).
+			Description("The period of time between polls, or between the samples and publishes of the subscription.").
+			Default("1s")).
+		Field(service.NewIntField(uaiFieldQueueSize).
+			Description("The number of changes of each node that the server queues between publishes of the subscription, where the oldest changes are discarded once the queue is full.").
+			Advanced().
+			Default(10)).
+		Field(service.NewDurationField(uaiFieldSessionTimeout).
+			Description("The period of time after which the server closes the session when it has not received a request.").
+			Advanced().
+			Default("1m")).
+		Field(service.NewDurationField(uaiFieldTimeout).
+			Description("The maximum period of time to wait for a response of the server.").
+			Advanced().
+			Default("10s")).
+		Example(
+			"Bridging machine data",
+			"In this example the changes of the values of the nodes of a machine are sent to Kafka.",
+			`
+input:
+  opcua:
+    endpoint: opc.tcp://10.0.0.30:4840
+    mode: subscribe
+    interval: 500ms
+    nodes:
+      - name: spindle_speed
+        node_id: ns=2;s=Machine.Spindle.Speed
+      - name: spindle_temperature
+        node_id: ns=2;s=Machine.Spindle.Temperature
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: machine_readings
+    key: ${! this.name }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"opcua", uaInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newUAInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type uaInput struct {
+	endpoint       string
+	identity       uaIdentity
+	names          []string
+	nodes          []uaNodeID
+	subscribe      bool
+	interval       time.Duration
+	queueSize      uint32
+	sessionTimeout time.Duration
+	timeout        time.Duration
+	log            *service.Logger
+
+	m      sync.Mutex
+	client *uaClient
+
+	// The state of reads, which are called sequentially.
+	lastPoll    time.Time
+	publishWait time.Duration
+	changes     []uaItemChange
+	acks        []uaAcknowledgement
+}
+
+func newUAInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*uaInput, error) {
+	u := &uaInput{log: log}
+
+	var err error
+	if u.endpoint, err = conf.FieldString(uaiFieldEndpoint); err != nil {
+		return nil, err
+	}
+	if _, err = uaEndpointAddress(u.endpoint); err != nil {
+		return nil, err
+	}
+	if u.identity.username, err = conf.FieldString(uaiFieldUsername); err != nil {
+		return nil, err
+	}
+	if u.identity.password, err = conf.FieldString(uaiFieldPassword); err != nil {
+		return nil, err
+	}
+
+	nodeConfs, err := conf.FieldObjectList(uaiFieldNodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeConfs) == 0 {
+		return nil, errors.New("at least one node must be specified")
+	}
+	names := map[string]struct{}{}
+	for _, nConf := range nodeConfs {
+		name, err := nConf.FieldString(uaiFieldNodeName)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := names[name]; exists {
+			return nil, fmt.Errorf("node name %v is used more than once", name)
+		}
+		names[name] = struct{}{}
+
+		idStr, err := nConf.FieldString(uaiFieldNodeID)
+		if err != nil {
+			return nil, err
+		}
+		id, err := parseUANodeID(idStr)
+		if err != nil {
+			return nil, err
+		}
+		u.names = append(u.names, name)
+		u.nodes = append(u.nodes, id)
+	}
+
+	mode, err := conf.FieldString(uaiFieldMode)
+	if err != nil {
+		return nil, err
+	}
+	u.subscribe = mode == "subscribe"
+	if u.interval, err = conf.FieldDuration(uaiFieldInterval); err != nil {
+		return nil, err
+	}
+	queueSize, err := conf.FieldInt(uaiFieldQueueSize)
+	if err != nil {
+		return nil, err
+	}
+	if queueSize < 1 {
+		return nil, errors.New("queue size must be at least 1")
+	}
+	u.queueSize = uint32(queueSize)
+	if u.sessionTimeout, err = conf.FieldDuration(uaiFieldSessionTimeout); err != nil {
+		return nil, err
+	}
+	if u.timeout, err = conf.FieldDuration(uaiFieldTimeout); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *uaInput) Connect(ctx context.Context) error {
+	u.m.Lock()
+	defer u.m.Unlock()
+
+	if u.client != nil {
+		return nil
+	}
+
+	client, err := dialUA(ctx, u.endpoint, u.identity, u.sessionTimeout, u.timeout)
+	if err != nil {
+		return err
+	}
+
+	if u.subscribe {
+		subscriptionID, interval, keepAlive, err := client.createSubscription(ctx, u.interval)
+		if err == nil {
+			err = client.createMonitoredItems(ctx, subscriptionID, u.nodes, u.interval, u.queueSize)
+		}
+		if err != nil {
+			client.close(ctx)
+			return err
+		}
+
+		// The server responds to a publish at least once per keep alive
+		// period, after which the connection is considered lost.
+		u.publishWait = interval*time.Duration(keepAlive) + u.timeout
+		u.changes, u.acks = nil, nil
+	}
+
+	u.client = client
+	u.log.Infof("Reading %v nodes of OPC UA endpoint: %v", len(u.nodes), u.endpoint)
+	return nil
+}
+
+func (u *uaInput) currentClient() *uaClient {
+	u.m.Lock()
+	defer u.m.Unlock()
+	return u.client
+}
+
+// disconnect closes a client of a connection that has failed.
+func (u *uaInput) disconnect(client *uaClient, err error) error {
+	u.m.Lock()
+	current := u.client == client
+	if current {
+		u.client = nil
+	}
+	u.m.Unlock()
+
+	// A client that is no longer current has been closed by the input.
+	if !current {
+		return service.ErrNotConnected
+	}
+
+	u.log.Errorf("Lost connection due to: %v", err)
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	client.close(ctx)
+	done()
+	return service.ErrNotConnected
+}
+
+func (u *uaInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	client := u.currentClient()
+	if client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+	if u.subscribe {
+		return u.readChange(ctx, client)
+	}
+
+	if wait := time.Until(u.lastPoll.Add(u.interval)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+	u.lastPoll = time.Now()
+
+	values, err := client.read(ctx, u.nodes)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		var status uaStatus
+		if errors.As(err, &status) && !uaSessionLost(status) {
+			return nil, nil, fmt.Errorf("failed to read nodes: %w", err)
+		}
+		return nil, nil, u.disconnect(client, err)
+	}
+
+	fields := make(map[string]any, len(values))
+	for i, v := range values {
+		if v.status.isBad() {
+			fields[u.names[i]] = nil
+			continue
+		}
+		fields[u.names[i]] = v.value
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(fields)
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+// uaSessionLost returns whether a status means that the session or secure
+// channel is no longer usable.
+func uaSessionLost(status uaStatus) bool {
+	switch status & 0xFFFF0000 {
+	case 0x80220000, 0x80250000, 0x80260000, 0x80270000, 0x800C0000, 0x800E0000:
+		return true
+	}
+	return false
+}
+
+func (u *uaInput) readChange(ctx context.Context, client *uaClient) (*service.Message, service.AckFunc, error) {
+	for len(u.changes) == 0 {
+		publishCtx, done := context.WithTimeout(ctx, u.publishWait)
+		changes, ack, err := client.publish(publishCtx, u.acks)
+		done()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			return nil, nil, u.disconnect(client, err)
+		}
+
+		u.acks = u.acks[:0]
+		if ack != nil {
+			u.acks = append(u.acks, *ack)
+		}
+		for _, c := range changes {
+			if int(c.handle) < len(u.nodes) {
+				u.changes = append(u.changes, c)
+			}
+		}
+	}
+
+	c := u.changes[0]
+	u.changes = u.changes[1:]
+
+	fields := map[string]any{
+		"name":             u.names[c.handle],
+		"node_id":          u.nodes[c.handle].String(),
+		"value":            c.value.value,
+		"status":           c.value.status.String(),
+		"source_timestamp": uaTimestamp(c.value.sourceTimestamp),
+		"server_timestamp": uaTimestamp(c.value.serverTimestamp),
+	}
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(fields)
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func uaTimestamp(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func (u *uaInput) Close(ctx context.Context) error {
+	u.m.Lock()
+	client := u.client
+	u.client = nil
+	u.m.Unlock()
+
+	if client != nil {
+		client.close(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package opcua
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testUAInput(t *testing.T, conf string) *uaInput {
+	t.Helper()
+
+	pConf, err := uaInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	in, err := newUAInputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = in.Close(context.Background()) })
+	return in
+}
+
+func readUAMessage(t *testing.T, in *uaInput) any {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	return v
+}
+
+func TestUAInputPoll(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) {
+		s.values["ns=2;s=Temperature"] = testValue{value: 21.5}
+		s.values["ns=2;s=Running"] = testValue{value: true}
+		s.values["ns=2;s=Broken"] = testValue{status: 0x80000000}
+	})
+
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+interval: 1ms
+nodes:
+  - { name: temperature, node_id: ns=2;s=Temperature }
+  - { name: running, node_id: ns=2;s=Running }
+  - { name: broken, node_id: ns=2;s=Broken }
+  - { name: missing, node_id: ns=2;s=Missing }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	assert.Equal(t, map[string]any{
+		"temperature": 21.5,
+		"running":     true,
+		"broken":      nil,
+		"missing":     nil,
+	}, readUAMessage(t, in))
+
+	s.set(func(s *testServer) { s.values["ns=2;s=Temperature"] = testValue{value: 22.0} })
+	assert.Equal(t, 22.0, readUAMessage(t, in).(map[string]any)["temperature"])
+
+	s.mut.Lock()
+	assert.Equal(t, []testIdentity{{policyID: "anonymous"}}, s.identities)
+	s.mut.Unlock()
+
+	require.NoError(t, in.Close(context.Background()))
+	assert.Eventually(t, func() bool {
+		r := s.received()
+		return len(r) > 0 && r[len(r)-1] == "CloseSecureChannel"
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, []string{
+		"OpenSecureChannel", "CreateSession", "ActivateSession", "Read", "Read", "CloseSession", "CloseSecureChannel",
+	}, s.received())
+}
+
+func TestUAInputUsername(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) {
+		s.values["i=2258"] = testValue{value: time.Date(2023, 1, 2, 15, 4, 5, 0, time.UTC)}
+		s.users["operator"] = "hunter2"
+	})
+
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+username: operator
+password: hunter2
+nodes:
+  - { name: server_time, node_id: i=2258 }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+	assert.Equal(t, map[string]any{"server_time": "2023-01-02T15:04:05Z"}, readUAMessage(t, in))
+
+	s.mut.Lock()
+	assert.Equal(t, []testIdentity{{policyID: "username", username: "operator", password: "hunter2"}}, s.identities)
+	s.mut.Unlock()
+
+	in = testUAInput(t, `
+endpoint: `+s.endpoint()+`
+username: operator
+password: wrong
+nodes:
+  - { name: server_time, node_id: i=2258 }
+`)
+	err := in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to activate session: status BadUserAccessDenied")
+
+	// Passwords that must be encrypted aren't supported.
+	s.set(func(s *testServer) {
+		s.policies = []testTokenPolicy{{id: "username", tokenType: uaUserTokenUserName, securityPolicy: "http://opcfoundation.org/UA/SecurityPolicy#Basic256Sha256"}}
+	})
+	err = in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server requires the password to be encrypted")
+}
+
+func TestUAInputChunks(t *testing.T) {
+	s := startTestServer(t)
+
+	var nodes []string
+	s.set(func(s *testServer) {
+		s.receiveBufferSize = 8192
+		s.chunkSize = 100
+		for i := 0; i < 50; i++ {
+			id := "ns=3;s=" + strings.Repeat("x", 200) + strconv.Itoa(i)
+			s.values[id] = testValue{value: strings.Repeat("v", 300)}
+			nodes = append(nodes, id)
+		}
+	})
+
+	var conf strings.Builder
+	conf.WriteString("endpoint: " + s.endpoint() + "\nnodes:\n")
+	for i, id := range nodes {
+		conf.WriteString("  - { name: n" + strconv.Itoa(i) + ", node_id: \"" + id + "\" }\n")
+	}
+
+	// Requests that exceed the receive buffer of the server are sent in
+	// multiple chunks, just as responses are received.
+	in := testUAInput(t, conf.String())
+	require.NoError(t, in.Connect(context.Background()))
+	v := readUAMessage(t, in).(map[string]any)
+	assert.Len(t, v, 50)
+	assert.Equal(t, strings.Repeat("v", 300), v["n0"])
+
+	s.mut.Lock()
+	assert.Equal(t, nodes, s.readNodes)
+	s.mut.Unlock()
+}
+
+func TestUAInputRenew(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) {
+		s.values["ns=2;i=5"] = testValue{value: int32(5)}
+		s.lifetime = 100
+	})
+
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+interval: 50ms
+nodes:
+  - { name: count, node_id: ns=2;i=5 }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	// Messages are sent with the renewed token of the secure channel.
+	assert.Eventually(t, func() bool {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		return len(s.openTypes) >= 3
+	}, time.Second*5, time.Millisecond*10)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, map[string]any{"count": int64(5)}, readUAMessage(t, in))
+	}
+
+	s.mut.Lock()
+	assert.Equal(t, []uint32{uaRequestIssue, uaRequestRenew, uaRequestRenew}, s.openTypes[:3])
+	s.mut.Unlock()
+}
+
+func TestUAInputSubscribe(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) {
+		s.values["ns=2;s=Temperature"] = testValue{value: 21.5}
+		s.values["ns=2;s=Pressure"] = testValue{value: 1.2}
+	})
+
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+mode: subscribe
+interval: 10ms
+nodes:
+  - { name: temperature, node_id: ns=2;s=Temperature }
+  - { name: pressure, node_id: ns=2;s=Pressure }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	source := time.Date(2023, 1, 2, 15, 4, 5, 123000000, time.UTC)
+	s.notify("ns=2;s=Temperature", testValue{value: 22.0, source: source, server: source.Add(time.Millisecond)})
+	assert.Equal(t, map[string]any{
+		"name":             "temperature",
+		"node_id":          "ns=2;s=Temperature",
+		"value":            22.0,
+		"status":           "Good",
+		"source_timestamp": "2023-01-02T15:04:05.123Z",
+		"server_timestamp": "2023-01-02T15:04:05.124Z",
+	}, readUAMessage(t, in))
+
+	// Keep alive messages are skipped until the next change, and received
+	// notifications are acknowledged with the next publish.
+	time.Sleep(time.Millisecond * 250)
+	s.notify("ns=2;s=Pressure", testValue{value: 1.3})
+	s.notify("ns=2;s=Temperature", testValue{status: 0x80000000})
+	v := readUAMessage(t, in).(map[string]any)
+	assert.Equal(t, "pressure", v["name"])
+	assert.Equal(t, 1.3, v["value"])
+	assert.Nil(t, v["source_timestamp"])
+	v = readUAMessage(t, in).(map[string]any)
+	assert.Equal(t, "temperature", v["name"])
+	assert.Equal(t, "0x80000000", v["status"])
+	assert.Nil(t, v["value"])
+
+	s.mut.Lock()
+	assert.Contains(t, s.acks, uaAcknowledgement{subscriptionID: 1, sequence: 1})
+	assert.Len(t, s.items, 2)
+	s.mut.Unlock()
+	assert.Contains(t, s.received(), "CreateSubscription")
+
+	// A subscription that times out requires a reconnect.
+	s.set(func(s *testServer) { s.statusChange = 0x800A0000 })
+	s.wake()
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	_, _, err := in.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+}
+
+func TestUAInputSubscribeUnknownNode(t *testing.T) {
+	s := startTestServer(t)
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+mode: subscribe
+nodes:
+  - { name: missing, node_id: ns=2;s=Missing }
+`)
+	err := in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to monitor node ns=2;s=Missing: status BadNodeIdUnknown")
+}
+
+func TestUAInputReconnect(t *testing.T) {
+	s := startTestServer(t)
+	s.set(func(s *testServer) { s.values["i=1"] = testValue{value: int64(1)} })
+
+	in := testUAInput(t, `
+endpoint: `+s.endpoint()+`
+interval: 1ms
+nodes:
+  - { name: a, node_id: i=1 }
+`)
+	require.NoError(t, in.Connect(context.Background()))
+	_ = readUAMessage(t, in)
+
+	s.dropConnections()
+	_, _, err := in.Read(context.Background())
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, in.Connect(context.Background()))
+	assert.Equal(t, map[string]any{"a": int64(1)}, readUAMessage(t, in))
+}
+
+func TestUAInputConfigErrors(t *testing.T) {
+	for _, conf := range []string{
+		"endpoint: http://localhost\nnodes: [ { name: a, node_id: i=1 } ]",
+		"endpoint: opc.tcp://localhost\nnodes: []",
+		"endpoint: opc.tcp://localhost\nnodes: [ { name: a, node_id: x } ]",
+		"endpoint: opc.tcp://localhost\nnodes: [ { name: a, node_id: i=1 }, { name: a, node_id: i=2 } ]",
+		"endpoint: opc.tcp://localhost\nqueue_size: 0\nnodes: [ { name: a, node_id: i=1 } ]",
+	} {
+		pConf, err := uaInputSpec().ParseYAML(conf, nil)
+		require.NoError(t, err, conf)
+		_, err = newUAInputFromParsed(pConf, service.MockResources().Logger())
+		require.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,691 @@
+package opcua
+
+import (
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestVariant(e *uaEncoder, v any) {
+	switch t := v.(type) {
+	case nil:
+		e.writeByte(0)
+	case bool:
+		e.writeByte(1)
+		e.writeBool(t)
+	case int16:
+		e.writeByte(4)
+		e.writeUint16(uint16(t))
+	case int32:
+		e.writeByte(6)
+		e.writeInt32(t)
+	case uint32:
+		e.writeByte(7)
+		e.writeUint32(t)
+	case int64:
+		e.writeByte(8)
+		e.writeUint64(uint64(t))
+	case uint64:
+		e.writeByte(9)
+		e.writeUint64(t)
+	case float32:
+		e.writeByte(10)
+		e.writeUint32(math.Float32bits(t))
+	case float64:
+		e.writeByte(11)
+		e.writeFloat64(t)
+	case string:
+		e.writeByte(12)
+		e.writeString(t)
+	case time.Time:
+		e.writeByte(13)
+		e.writeDateTime(t)
+	case []byte:
+		e.writeByte(15)
+		e.writeByteString(t)
+	case uaNodeID:
+		e.writeByte(17)
+		e.writeNodeID(t)
+	case []float64:
+		e.writeByte(0x80 | 11)
+		e.writeInt32(int32(len(t)))
+		for _, f := range t {
+			e.writeFloat64(f)
+		}
+	default:
+		panic("unsupported variant")
+	}
+}
+
+type testValue struct {
+	value  any
+	status uaStatus
+	source time.Time
+	server time.Time
+}
+
+func writeTestDataValue(e *uaEncoder, v testValue) {
+	var mask byte
+	if v.status == 0 {
+		mask |= 0x01
+	} else {
+		mask |= 0x02
+	}
+	if !v.source.IsZero() {
+		mask |= 0x04
+	}
+	if !v.server.IsZero() {
+		mask |= 0x08
+	}
+	e.writeByte(mask)
+	if mask&0x01 != 0 {
+		writeTestVariant(e, v.value)
+	}
+	if mask&0x02 != 0 {
+		e.writeUint32(uint32(v.status))
+	}
+	if mask&0x04 != 0 {
+		e.writeDateTime(v.source)
+	}
+	if mask&0x08 != 0 {
+		e.writeDateTime(v.server)
+	}
+}
+
+type testTokenPolicy struct {
+	id             string
+	tokenType      uint32
+	securityPolicy string
+}
+
+type testIdentity struct {
+	policyID string
+	username string
+	password string
+}
+
+type testItem struct {
+	handle uint32
+	node   string
+}
+
+type testChange struct {
+	handle uint32
+	value  testValue
+}
+
+// testServer is an OPC UA server with the security policy None, which holds
+// the values of nodes that tests can modify.
+type testServer struct {
+	addr string
+
+	mut               sync.Mutex
+	values            map[string]testValue
+	policies          []testTokenPolicy
+	users             map[string]string
+	receiveBufferSize uint32
+	chunkSize         int
+	lifetime          uint32
+	requests          []string
+	openTypes         []uint32
+	identities        []testIdentity
+	acks              []uaAcknowledgement
+	readNodes         []string
+	items             []testItem
+	keepAlive         time.Duration
+	changes           []testChange
+	statusChange      uaStatus
+	sequence          uint32
+	conns             []net.Conn
+
+	signal chan struct{}
+	done   chan struct{}
+}
+
+func startTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &testServer{
+		addr:   ln.Addr().String(),
+		values: map[string]testValue{},
+		policies: []testTokenPolicy{
+			{id: "anonymous", tokenType: uaUserTokenAnonymous},
+			{id: "username", tokenType: uaUserTokenUserName},
+		},
+		users:             map[string]string{},
+		receiveBufferSize: 1 << 16,
+		chunkSize:         8192,
+		lifetime:          uint32(time.Hour / time.Millisecond),
+		signal:            make(chan struct{}, 1),
+		done:              make(chan struct{}),
+	}
+	t.Cleanup(func() {
+		close(s.done)
+		_ = ln.Close()
+		s.dropConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns = append(s.conns, conn)
+			s.mut.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) endpoint() string {
+	return "opc.tcp://" + s.addr + "/test"
+}
+
+func (s *testServer) set(fn func(s *testServer)) {
+	s.mut.Lock()
+	fn(s)
+	s.mut.Unlock()
+}
+
+func (s *testServer) received() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *testServer) dropConnections() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+}
+
+// notify changes the value of a node and queues the change for each of the
+// items that monitor it.
+func (s *testServer) notify(node string, v testValue) {
+	s.mut.Lock()
+	s.values[node] = v
+	for _, item := range s.items {
+		if item.node == node {
+			s.changes = append(s.changes, testChange{handle: item.handle, value: v})
+		}
+	}
+	s.mut.Unlock()
+	s.wake()
+}
+
+func (s *testServer) wake() {
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+type testConn struct {
+	conn      net.Conn
+	writeMut  sync.Mutex
+	channelID uint32
+	tokenIDs  map[uint32]struct{}
+	tokenID   uint32
+	sequence  uint32
+	authToken uaNodeID
+	activated bool
+}
+
+func (s *testServer) serve(netConn net.Conn) {
+	defer netConn.Close()
+	c := &testConn{conn: netConn, channelID: 7, tokenIDs: map[uint32]struct{}{}}
+
+	typ, _, _, err := uaReadChunk(netConn)
+	if err != nil || typ != "HEL" {
+		return
+	}
+	s.mut.Lock()
+	var e uaEncoder
+	e.writeUint32(0)
+	e.writeUint32(s.receiveBufferSize)
+	e.writeUint32(1 << 16)
+	e.writeUint32(0)
+	e.writeUint32(0)
+	s.mut.Unlock()
+	if _, err := netConn.Write(uaChunk("ACK", 'F', e.b)); err != nil {
+		return
+	}
+
+	partial := map[uint32][]byte{}
+	for {
+		typ, chunkType, body, err := uaReadChunk(netConn)
+		if err != nil {
+			return
+		}
+		d := &uaDecoder{b: body}
+		switch typ {
+		case "OPN":
+			_ = d.readUint32()
+			if policy := d.readString(); policy != uaSecurityPolicyNone {
+				return
+			}
+			_ = d.readByteString()
+			_ = d.readByteString()
+		case "MSG", "CLO":
+			if d.readUint32() != c.channelID {
+				return
+			}
+			if _, exists := c.tokenIDs[d.readUint32()]; !exists {
+				return
+			}
+		default:
+			return
+		}
+		_ = d.readUint32()
+		requestID := d.readUint32()
+		if d.err != nil {
+			return
+		}
+
+		partial[requestID] = append(partial[requestID], d.b...)
+		if chunkType == 'C' {
+			continue
+		}
+		d = &uaDecoder{b: partial[requestID]}
+		delete(partial, requestID)
+
+		if typ == "CLO" {
+			s.mut.Lock()
+			s.requests = append(s.requests, "CloseSecureChannel")
+			s.mut.Unlock()
+			return
+		}
+		if !s.handle(c, typ, requestID, d) {
+			return
+		}
+	}
+}
+
+// respond writes a response, which is split into chunks of the chunk size.
+func (s *testServer) respond(c *testConn, typ string, requestID uint32, handle uint32, status uaStatus, responseID uint32, fields func(e *uaEncoder)) {
+	var e uaEncoder
+	if status.isBad() {
+		responseID = uaIDServiceFault
+	}
+	e.writeNodeID(uaNumericNodeID(responseID))
+	e.writeDateTime(time.Now())
+	e.writeUint32(handle)
+	e.writeUint32(uint32(status))
+	e.writeByte(0)
+	e.writeInt32(-1)
+	e.writeExtensionObject(0, nil)
+	if !status.isBad() && fields != nil {
+		fields(&e)
+	}
+
+	s.mut.Lock()
+	chunkSize := s.chunkSize
+	s.mut.Unlock()
+
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+	body := e.b
+	for {
+		n, chunkType := len(body), byte('F')
+		if typ == "MSG" && n > chunkSize {
+			n, chunkType = chunkSize, 'C'
+		}
+		var h uaEncoder
+		h.writeUint32(c.channelID)
+		if typ == "OPN" {
+			h.writeString(uaSecurityPolicyNone)
+			h.writeByteString(nil)
+			h.writeByteString(nil)
+		} else {
+			h.writeUint32(c.tokenID)
+		}
+		c.sequence++
+		h.writeUint32(c.sequence)
+		h.writeUint32(requestID)
+		if _, err := c.conn.Write(uaChunk(typ, chunkType, append(h.b, body[:n]...))); err != nil {
+			return
+		}
+		if body = body[n:]; chunkType == 'F' {
+			return
+		}
+	}
+}
+
+func (s *testServer) record(name string) {
+	s.mut.Lock()
+	s.requests = append(s.requests, name)
+	s.mut.Unlock()
+}
+
+func (s *testServer) handle(c *testConn, typ string, requestID uint32, d *uaDecoder) bool {
+	typeID := d.readNodeID()
+	authToken := d.readNodeID()
+	_ = d.readDateTime()
+	handle := d.readUint32()
+	_ = d.readUint32()
+	_ = d.readString()
+	_ = d.readUint32()
+	_, _ = d.readExtensionObject()
+	if d.err != nil {
+		return false
+	}
+
+	if typeID == uaNumericNodeID(uaIDOpenSecureChannelRequest) {
+		_ = d.readUint32()
+		requestType := d.readUint32()
+		_ = d.readUint32()
+		_ = d.readByteString()
+		_ = d.readUint32()
+
+		s.mut.Lock()
+		s.requests = append(s.requests, "OpenSecureChannel")
+		s.openTypes = append(s.openTypes, requestType)
+		lifetime := s.lifetime
+		s.mut.Unlock()
+
+		c.writeMut.Lock()
+		c.tokenID++
+		c.tokenIDs[c.tokenID] = struct{}{}
+		tokenID := c.tokenID
+		c.writeMut.Unlock()
+
+		s.respond(c, "OPN", requestID, handle, 0, uaIDOpenSecureChannelResponse, func(e *uaEncoder) {
+			e.writeUint32(0)
+			e.writeUint32(c.channelID)
+			e.writeUint32(tokenID)
+			e.writeDateTime(time.Now())
+			e.writeUint32(lifetime)
+			e.writeByteString(nil)
+		})
+		return true
+	}
+
+	if typ != "MSG" {
+		return false
+	}
+	if typeID != uaNumericNodeID(uaIDCreateSessionRequest) && authToken != c.authToken {
+		s.respond(c, typ, requestID, handle, 0x80250000, 0, nil)
+		return true
+	}
+	if typeID != uaNumericNodeID(uaIDCreateSessionRequest) && typeID != uaNumericNodeID(uaIDActivateSessionRequest) && !c.activated {
+		s.respond(c, typ, requestID, handle, 0x80270000, 0, nil)
+		return true
+	}
+
+	switch typeID.numeric {
+	case uaIDCreateSessionRequest:
+		s.record("CreateSession")
+		c.authToken = uaNodeID{namespace: 1, kind: 'b', ident: "token"}
+		s.mut.Lock()
+		policies := s.policies
+		s.mut.Unlock()
+		s.respond(c, typ, requestID, handle, 0, uaIDCreateSessionResponse, func(e *uaEncoder) {
+			e.writeNodeID(uaNodeID{namespace: 1, kind: 'i', numeric: 1000})
+			e.writeNodeID(c.authToken)
+			e.writeFloat64(60000)
+			e.writeByteString([]byte("nonce"))
+			e.writeByteString(nil)
+
+			// An endpoint that requires security is listed before the one
+			// without.
+			e.writeInt32(2)
+			for i, policy := range []string{"http://opcfoundation.org/UA/SecurityPolicy#Basic256Sha256", uaSecurityPolicyNone} {
+				e.writeString(s.endpoint())
+				e.writeString("urn:test:server")
+				e.writeString("")
+				e.writeLocalizedText("Test Server")
+				e.writeUint32(0)
+				e.writeString("")
+				e.writeString("")
+				e.writeInt32(-1)
+				e.writeByteString(nil)
+				e.writeUint32(uint32(3 - 2*i))
+				e.writeString(policy)
+				e.writeInt32(int32(len(policies)))
+				for _, p := range policies {
+					e.writeString(p.id)
+					e.writeUint32(p.tokenType)
+					e.writeString("")
+					e.writeString("")
+					e.writeString(p.securityPolicy)
+				}
+				e.writeString("http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary")
+				e.writeByte(0)
+			}
+			e.writeInt32(-1)
+			e.writeString("")
+			e.writeByteString(nil)
+			e.writeUint32(0)
+		})
+
+	case uaIDActivateSessionRequest:
+		s.record("ActivateSession")
+		_ = d.readString()
+		_ = d.readByteString()
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			_ = d.readByteString()
+			_ = d.readByteString()
+		}
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			_ = d.readString()
+		}
+		tokenType, body := d.readExtensionObject()
+		td := &uaDecoder{b: body}
+		var identity testIdentity
+		identity.policyID = td.readString()
+		if tokenType == uaNumericNodeID(uaIDUserNameIdentityToken) {
+			identity.username = td.readString()
+			identity.password = string(td.readByteString())
+		}
+		if td.err != nil || d.err != nil {
+			return false
+		}
+
+		s.mut.Lock()
+		s.identities = append(s.identities, identity)
+		password, exists := s.users[identity.username]
+		s.mut.Unlock()
+
+		var status uaStatus
+		if identity.username != "" && (!exists || password != identity.password) {
+			status = 0x801F0000
+		}
+		c.activated = status == 0
+		s.respond(c, typ, requestID, handle, status, uaIDActivateSessionResponse, func(e *uaEncoder) {
+			e.writeByteString([]byte("nonce"))
+			e.writeInt32(-1)
+			e.writeInt32(-1)
+		})
+
+	case uaIDReadRequest:
+		s.record("Read")
+		_ = d.readFloat64()
+		_ = d.readUint32()
+		var nodes []uaNodeID
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			nodes = append(nodes, d.readNodeID())
+			_ = d.readUint32()
+			_ = d.readString()
+			_ = d.readUint16()
+			_ = d.readString()
+		}
+		if d.err != nil {
+			return false
+		}
+
+		s.mut.Lock()
+		var values []testValue
+		for _, n := range nodes {
+			s.readNodes = append(s.readNodes, n.String())
+			v, exists := s.values[n.String()]
+			if !exists {
+				v = testValue{status: 0x80340000}
+			}
+			values = append(values, v)
+		}
+		s.mut.Unlock()
+
+		s.respond(c, typ, requestID, handle, 0, uaIDReadResponse, func(e *uaEncoder) {
+			e.writeInt32(int32(len(values)))
+			for _, v := range values {
+				writeTestDataValue(e, v)
+			}
+			e.writeInt32(-1)
+		})
+
+	case uaIDCreateSubscriptionRequest:
+		s.record("CreateSubscription")
+		interval := d.readFloat64()
+		lifetime := d.readUint32()
+		keepAlive := d.readUint32()
+		s.set(func(s *testServer) {
+			s.keepAlive = time.Duration(interval*float64(time.Millisecond)) * time.Duration(keepAlive)
+		})
+		s.respond(c, typ, requestID, handle, 0, uaIDCreateSubscriptionResponse, func(e *uaEncoder) {
+			e.writeUint32(1)
+			e.writeFloat64(interval)
+			e.writeUint32(lifetime)
+			e.writeUint32(keepAlive)
+		})
+
+	case uaIDCreateMonitoredItemsRequest:
+		s.record("CreateMonitoredItems")
+		_ = d.readUint32()
+		_ = d.readUint32()
+		var results []uaStatus
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			node := d.readNodeID().String()
+			_ = d.readUint32()
+			_ = d.readString()
+			_ = d.readUint16()
+			_ = d.readString()
+			_ = d.readUint32()
+			handle := d.readUint32()
+			_ = d.readFloat64()
+			_, _ = d.readExtensionObject()
+			_ = d.readUint32()
+			_ = d.readBool()
+
+			s.mut.Lock()
+			if _, exists := s.values[node]; exists {
+				s.items = append(s.items, testItem{handle: handle, node: node})
+				results = append(results, 0)
+			} else {
+				results = append(results, 0x80340000)
+			}
+			s.mut.Unlock()
+		}
+		if d.err != nil {
+			return false
+		}
+		s.respond(c, typ, requestID, handle, 0, uaIDCreateMonitoredItemsResponse, func(e *uaEncoder) {
+			e.writeInt32(int32(len(results)))
+			for i, r := range results {
+				e.writeUint32(uint32(r))
+				e.writeUint32(uint32(i + 1))
+				e.writeFloat64(100)
+				e.writeUint32(10)
+				e.writeExtensionObject(0, nil)
+			}
+			e.writeInt32(-1)
+		})
+
+	case uaIDPublishRequest:
+		s.record("Publish")
+		var acks []uaAcknowledgement
+		for i, n := 0, d.readArrayLen(); i < n; i++ {
+			acks = append(acks, uaAcknowledgement{subscriptionID: d.readUint32(), sequence: d.readUint32()})
+		}
+		s.set(func(s *testServer) { s.acks = append(s.acks, acks...) })
+		go s.publish(c, requestID, handle)
+
+	case uaIDCloseSessionRequest:
+		s.record("CloseSession")
+		s.respond(c, typ, requestID, handle, 0, uaIDCloseSessionResponse, nil)
+
+	default:
+		s.respond(c, typ, requestID, handle, 0x800B0000, 0, nil)
+	}
+	return true
+}
+
+// publish responds to a publish request with the queued changes, or with a
+// keep alive message once the keep alive period has passed.
+func (s *testServer) publish(c *testConn, requestID, handle uint32) {
+	s.mut.Lock()
+	timer := time.NewTimer(s.keepAlive)
+	s.mut.Unlock()
+	defer timer.Stop()
+
+	for {
+		s.mut.Lock()
+		changes, status := s.changes, s.statusChange
+		s.changes, s.statusChange = nil, 0
+		sequence := s.sequence + 1
+		if len(changes) > 0 || status != 0 {
+			s.sequence++
+		}
+		s.mut.Unlock()
+
+		if len(changes) > 0 || status != 0 {
+			s.respondPublish(c, requestID, handle, sequence, changes, status)
+			return
+		}
+		select {
+		case <-s.signal:
+		case <-timer.C:
+			s.respondPublish(c, requestID, handle, sequence, nil, 0)
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *testServer) respondPublish(c *testConn, requestID, handle, sequence uint32, changes []testChange, status uaStatus) {
+	s.respond(c, "MSG", requestID, handle, 0, uaIDPublishResponse, func(e *uaEncoder) {
+		e.writeUint32(1)
+		e.writeInt32(-1)
+		e.writeBool(false)
+		e.writeUint32(sequence)
+		e.writeDateTime(time.Now())
+
+		var notifications [][]byte
+		var ids []uint32
+		if len(changes) > 0 {
+			var n uaEncoder
+			n.writeInt32(int32(len(changes)))
+			for _, c := range changes {
+				n.writeUint32(c.handle)
+				writeTestDataValue(&n, c.value)
+			}
+			n.writeInt32(-1)
+			notifications, ids = append(notifications, n.b), append(ids, uaIDDataChangeNotification)
+		}
+		if status != 0 {
+			var n uaEncoder
+			n.writeUint32(uint32(status))
+			n.writeByte(0)
+			notifications, ids = append(notifications, n.b), append(ids, uaIDStatusChangeNotification)
+		}
+		e.writeInt32(int32(len(notifications)))
+		for i, n := range notifications {
+			e.writeExtensionObject(ids[i], n)
+		}
+		e.writeInt32(-1)
+		e.writeInt32(-1)
+	})
+}
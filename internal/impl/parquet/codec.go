@@ -0,0 +1,434 @@
+package parquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/segmentio/parquet-go/format"
+
+	"github.com/benthosdev/benthos/v4/internal/codec"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func init() {
+	codec.RegisterPartReader("parquet", func(conf codec.ReaderConfig, args string) (codec.ReaderConstructor, error) {
+		cConf, err := parseParquetCodecArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		return func(path string, r io.ReadCloser, fn codec.ReaderAckFn) (codec.Reader, error) {
+			return newParquetCodecReader(cConf, r, fn)
+		}, nil
+	})
+}
+
+//------------------------------------------------------------------------------
+
+type parquetCodecFilter struct {
+	column string
+	op     string
+	value  string
+}
+
+type parquetCodecConfig struct {
+	columns []string
+	filters []parquetCodecFilter
+}
+
+var parquetFilterOps = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// parseParquetCodecArgs parses the arguments of a parquet codec, which are of
+// the form `columns=a,b;filter=c>=10;filter=d=foo`.
+func parseParquetCodecArgs(args string) (conf parquetCodecConfig, err error) {
+	if args == "" {
+		return
+	}
+	for _, param := range strings.Split(args, ";") {
+		key, value, _ := strings.Cut(param, "=")
+		switch key {
+		case "columns":
+			for _, c := range strings.Split(value, ",") {
+				if c = strings.TrimSpace(c); c != "" {
+					conf.columns = append(conf.columns, c)
+				}
+			}
+			if len(conf.columns) == 0 {
+				return conf, errors.New("parquet codec parameter columns requires one or more column names")
+			}
+		case "filter":
+			f, err := parseParquetCodecFilter(value)
+			if err != nil {
+				return conf, err
+			}
+			conf.filters = append(conf.filters, f)
+		default:
+			return conf, fmt.Errorf("parquet codec parameter not recognised: %v", key)
+		}
+	}
+	return
+}
+
+func parseParquetCodecFilter(expr string) (parquetCodecFilter, error) {
+	index, op := -1, ""
+	for _, o := range parquetFilterOps {
+		if i := strings.Index(expr, o); i > 0 && (index == -1 || i < index || (i == index && len(o) > len(op))) {
+			index, op = i, o
+		}
+	}
+	if index == -1 {
+		return parquetCodecFilter{}, fmt.Errorf("parquet codec filter expected the form <column><operator><value> with an operator of %v: %v", strings.Join(parquetFilterOps, ", "), expr)
+	}
+	return parquetCodecFilter{
+		column: strings.TrimSpace(expr[:index]),
+		op:     op,
+		value:  strings.TrimSpace(expr[index+len(op):]),
+	}, nil
+}
+
+//------------------------------------------------------------------------------
+
+// rowGroupFilter checks the statistics of a row group column against a value,
+// and reports whether any rows of the group might match.
+type rowGroupFilter struct {
+	column int
+	typ    parquet.Type
+	kind   parquet.Kind
+	op     string
+	value  parquet.Value
+}
+
+func newRowGroupFilter(schema *parquet.Schema, f parquetCodecFilter) (*rowGroupFilter, error) {
+	leaf, exists := schema.Lookup(strings.Split(f.column, ".")...)
+	if !exists {
+		return nil, fmt.Errorf("parquet codec filter column not found in schema: %v", f.column)
+	}
+
+	typ := leaf.Node.Type()
+	var value parquet.Value
+	switch typ.Kind() {
+	case parquet.Boolean:
+		b, err := strconv.ParseBool(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("parquet codec filter value for boolean column %v: %w", f.column, err)
+		}
+		value = parquet.ValueOf(b)
+	case parquet.Int32:
+		i, err := strconv.ParseInt(f.value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parquet codec filter value for int32 column %v: %w", f.column, err)
+		}
+		value = parquet.ValueOf(int32(i))
+	case parquet.Int64:
+		i, err := strconv.ParseInt(f.value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parquet codec filter value for int64 column %v: %w", f.column, err)
+		}
+		value = parquet.ValueOf(i)
+	case parquet.Float:
+		v, err := strconv.ParseFloat(f.value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parquet codec filter value for float column %v: %w", f.column, err)
+		}
+		value = parquet.ValueOf(float32(v))
+	case parquet.Double:
+		v, err := strconv.ParseFloat(f.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parquet codec filter value for double column %v: %w", f.column, err)
+		}
+		value = parquet.ValueOf(v)
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		value = parquet.ValueOf([]byte(f.value))
+	default:
+		return nil, fmt.Errorf("parquet codec filter column %v has unsupported type %v", f.column, typ.Kind())
+	}
+
+	return &rowGroupFilter{
+		column: leaf.ColumnIndex,
+		typ:    typ,
+		kind:   typ.Kind(),
+		op:     f.op,
+		value:  value,
+	}, nil
+}
+
+// bounds returns the minimum and maximum values of the filtered column within
+// a row group, taken from the statistics of the column chunk when present and
+// otherwise from the page index.
+func (f *rowGroupFilter) bounds(rg parquet.RowGroup, meta *format.RowGroup) (minV, maxV parquet.Value, ok bool) {
+	if meta != nil && f.column < len(meta.Columns) {
+		stats := meta.Columns[f.column].MetaData.Statistics
+		minBytes, maxBytes := stats.MinValue, stats.MaxValue
+		if minBytes == nil || maxBytes == nil {
+			minBytes, maxBytes = stats.Min, stats.Max
+		}
+		if minBytes != nil && maxBytes != nil {
+			return f.kind.Value(minBytes), f.kind.Value(maxBytes), true
+		}
+	}
+
+	chunks := rg.ColumnChunks()
+	if f.column >= len(chunks) {
+		return
+	}
+	index := chunks[f.column].ColumnIndex()
+	if index == nil {
+		return
+	}
+	for i := 0; i < index.NumPages(); i++ {
+		if index.NullPage(i) {
+			continue
+		}
+		pageMin, pageMax := index.MinValue(i), index.MaxValue(i)
+		if !ok || f.typ.Compare(pageMin, minV) < 0 {
+			minV = pageMin
+		}
+		if !ok || f.typ.Compare(pageMax, maxV) > 0 {
+			maxV = pageMax
+		}
+		ok = true
+	}
+	return
+}
+
+// mightMatch returns false only when the bounds of a row group prove that none
+// of its rows match the filter.
+func (f *rowGroupFilter) mightMatch(rg parquet.RowGroup, meta *format.RowGroup) bool {
+	minV, maxV, ok := f.bounds(rg, meta)
+	if !ok {
+		return true
+	}
+
+	cmpMin, cmpMax := f.typ.Compare(f.value, minV), f.typ.Compare(f.value, maxV)
+	switch f.op {
+	case "=":
+		return cmpMin >= 0 && cmpMax <= 0
+	case "!=":
+		return !(cmpMin == 0 && cmpMax == 0)
+	case ">":
+		return cmpMax < 0
+	case ">=":
+		return cmpMax <= 0
+	case "<":
+		return cmpMin > 0
+	case "<=":
+		return cmpMin >= 0
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// openParquetSource returns a random access view of a source, buffering it to
+// a temporary file when it does not support seeking.
+func openParquetSource(r io.ReadCloser) (ra io.ReaderAt, size int64, spool *os.File, err error) {
+	if rs, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		if size, err = rs.Seek(0, io.SeekEnd); err == nil {
+			if _, err = rs.Seek(0, io.SeekStart); err == nil {
+				return rs, size, nil, nil
+			}
+		}
+	}
+
+	if spool, err = os.CreateTemp("", "benthos-parquet-*"); err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to create temporary file for parquet data: %w", err)
+	}
+	if size, err = io.Copy(spool, r); err != nil {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+		return nil, 0, nil, fmt.Errorf("failed to buffer parquet data: %w", err)
+	}
+	return spool, size, spool, nil
+}
+
+type parquetCodecReader struct {
+	r     io.ReadCloser
+	spool *os.File
+	eConf extractConfig
+
+	schema    *parquet.Schema
+	conv      parquet.Conversion
+	rowGroups []parquet.RowGroup
+	rows      parquet.Rows
+	rowBuf    []parquet.Row
+
+	mut       sync.Mutex
+	sourceAck codec.ReaderAckFn
+	pending   int
+	finished  bool
+}
+
+func newParquetCodecReader(conf parquetCodecConfig, r io.ReadCloser, ackFn codec.ReaderAckFn) (*parquetCodecReader, error) {
+	ra, size, spool, err := openParquetSource(r)
+	if err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+
+	p := &parquetCodecReader{
+		r:         r,
+		spool:     spool,
+		rowBuf:    make([]parquet.Row, 1),
+		sourceAck: ackOnce(ackFn),
+	}
+
+	if err = p.init(conf, ra, size); err != nil {
+		_ = p.closeSources()
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *parquetCodecReader) init(conf parquetCodecConfig, ra io.ReaderAt, size int64) error {
+	file, err := parquet.OpenFile(ra, size)
+	if err != nil {
+		return err
+	}
+	p.schema = file.Schema()
+
+	var filters []*rowGroupFilter
+	for _, f := range conf.filters {
+		rgf, err := newRowGroupFilter(file.Schema(), f)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, rgf)
+	}
+
+	if len(conf.columns) > 0 {
+		group := parquet.Group{}
+		for _, c := range conf.columns {
+			field, exists := fieldByName(file.Schema().Fields(), c)
+			if !exists {
+				return fmt.Errorf("parquet codec column not found in schema: %v", c)
+			}
+			group[c] = field
+		}
+		p.schema = parquet.NewSchema(file.Schema().Name(), group)
+		if p.conv, err = parquet.Convert(p.schema, file.Schema()); err != nil {
+			return err
+		}
+	}
+
+	metaGroups := file.Metadata().RowGroups
+rowGroups:
+	for i, rg := range file.RowGroups() {
+		var meta *format.RowGroup
+		if i < len(metaGroups) {
+			meta = &metaGroups[i]
+		}
+		for _, f := range filters {
+			if !f.mightMatch(rg, meta) {
+				continue rowGroups
+			}
+		}
+		if p.conv != nil {
+			rg = parquet.ConvertRowGroup(rg, p.conv)
+		}
+		p.rowGroups = append(p.rowGroups, rg)
+	}
+	return nil
+}
+
+func fieldByName(fields []parquet.Field, name string) (parquet.Field, bool) {
+	for _, f := range fields {
+		if f.Name() == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func ackOnce(fn codec.ReaderAckFn) codec.ReaderAckFn {
+	var once sync.Once
+	return func(ctx context.Context, err error) error {
+		var ackErr error
+		once.Do(func() {
+			ackErr = fn(ctx, err)
+		})
+		return ackErr
+	}
+}
+
+func (p *parquetCodecReader) ack(ctx context.Context, err error) error {
+	p.mut.Lock()
+	p.pending--
+	doAck := p.pending == 0 && p.finished
+	p.mut.Unlock()
+
+	if err != nil {
+		return p.sourceAck(ctx, err)
+	}
+	if doAck {
+		return p.sourceAck(ctx, nil)
+	}
+	return nil
+}
+
+func (p *parquetCodecReader) Next(ctx context.Context) ([]*message.Part, codec.ReaderAckFn, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for {
+		if p.rows == nil {
+			if len(p.rowGroups) == 0 {
+				p.finished = true
+				return nil, nil, io.EOF
+			}
+			p.rows = p.rowGroups[0].Rows()
+			p.rowGroups = p.rowGroups[1:]
+		}
+
+		n, err := p.rows.ReadRows(p.rowBuf)
+		if n > 0 {
+			mappedData := map[string]any{}
+			_, _ = p.eConf.extractPQValueGroup(p.schema.Fields(), p.rowBuf[0], mappedData, 0, 0)
+
+			part := message.NewPart(nil)
+			part.SetStructuredMut(mappedData)
+
+			p.pending++
+			return []*message.Part{part}, p.ack, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			_ = p.sourceAck(ctx, err)
+			return nil, nil, err
+		}
+		_ = p.rows.Close()
+		p.rows = nil
+	}
+}
+
+func (p *parquetCodecReader) closeSources() error {
+	if p.spool != nil {
+		_ = p.spool.Close()
+		_ = os.Remove(p.spool.Name())
+	}
+	return p.r.Close()
+}
+
+func (p *parquetCodecReader) Close(ctx context.Context) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.rows != nil {
+		_ = p.rows.Close()
+		p.rows = nil
+	}
+	if !p.finished {
+		_ = p.sourceAck(ctx, errors.New("service shutting down"))
+	}
+	if p.pending == 0 {
+		_ = p.sourceAck(ctx, nil)
+	}
+	return p.closeSources()
+}
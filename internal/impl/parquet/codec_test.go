@@ -0,0 +1,132 @@
+package parquet
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/codec"
+)
+
+type codecTestData struct {
+	ID    int64
+	Name  string
+	Score float64
+}
+
+func writeCodecTestFile(t *testing.T) []byte {
+	t.Helper()
+
+	buf := bytes.NewBuffer(nil)
+	pWtr := parquet.NewWriter(buf, parquet.SchemaOf(codecTestData{}))
+
+	// Three row groups with the IDs 1-3, 4-6 and 7-9.
+	for group := 0; group < 3; group++ {
+		for i := 1; i <= 3; i++ {
+			id := int64(group*3 + i)
+			require.NoError(t, pWtr.Write(codecTestData{
+				ID:    id,
+				Name:  "name " + string(rune('a'+id-1)),
+				Score: float64(id) / 2,
+			}))
+		}
+		require.NoError(t, pWtr.Flush())
+	}
+	require.NoError(t, pWtr.Close())
+	return buf.Bytes()
+}
+
+func readAllCodec(t *testing.T, codecStr string, r io.ReadCloser) []any {
+	t.Helper()
+
+	ctor, err := codec.GetReader(codecStr, codec.NewReaderConfig())
+	require.NoError(t, err)
+
+	var acked bool
+	rdr, err := ctor("foo.parquet", r, func(ctx context.Context, err error) error {
+		require.NoError(t, err)
+		acked = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	var results []any
+	for {
+		parts, ackFn, err := rdr.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, p := range parts {
+			v, err := p.AsStructured()
+			require.NoError(t, err)
+			results = append(results, v)
+		}
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	require.NoError(t, rdr.Close(context.Background()))
+	assert.True(t, acked)
+	return results
+}
+
+func TestParquetCodecSeekableSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.parquet")
+	require.NoError(t, os.WriteFile(path, writeCodecTestFile(t), 0o644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	results := readAllCodec(t, "parquet:columns=ID,Name;filter=ID>5", f)
+	assert.Equal(t, []any{
+		map[string]any{"ID": int64(4), "Name": "name d"},
+		map[string]any{"ID": int64(5), "Name": "name e"},
+		map[string]any{"ID": int64(6), "Name": "name f"},
+		map[string]any{"ID": int64(7), "Name": "name g"},
+		map[string]any{"ID": int64(8), "Name": "name h"},
+		map[string]any{"ID": int64(9), "Name": "name i"},
+	}, results)
+}
+
+func TestParquetCodecStreamedSource(t *testing.T) {
+	data := writeCodecTestFile(t)
+
+	results := readAllCodec(t, "parquet", io.NopCloser(bytes.NewReader(data)))
+	assert.Len(t, results, 9)
+	assert.Equal(t, map[string]any{"ID": int64(1), "Name": "name a", "Score": 0.5}, results[0])
+
+	results = readAllCodec(t, "parquet:filter=Name=name e", io.NopCloser(bytes.NewReader(data)))
+	assert.Len(t, results, 3)
+	assert.Equal(t, map[string]any{"ID": int64(4), "Name": "name d", "Score": 2.0}, results[0])
+
+	results = readAllCodec(t, "parquet:filter=Score<1;filter=ID>=1", io.NopCloser(bytes.NewReader(data)))
+	assert.Len(t, results, 3)
+
+	results = readAllCodec(t, "auto", io.NopCloser(bytes.NewReader(data)))
+	assert.Len(t, results, 9)
+}
+
+func TestParquetCodecBadArgs(t *testing.T) {
+	for _, c := range []string{
+		"parquet:nope=foo",
+		"parquet:columns=",
+		"parquet:filter=foo",
+	} {
+		_, err := codec.GetReader(c, codec.NewReaderConfig())
+		assert.Error(t, err, c)
+	}
+
+	ctor, err := codec.GetReader("parquet:filter=ID>foo", codec.NewReaderConfig())
+	require.NoError(t, err)
+
+	_, err = ctor("", io.NopCloser(bytes.NewReader(writeCodecTestFile(t))), func(ctx context.Context, err error) error {
+		return nil
+	})
+	require.Error(t, err)
+}
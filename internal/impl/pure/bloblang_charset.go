@@ -0,0 +1,313 @@
+package pure
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func init() {
+	// Note: The examples are run and tested from within
+	// ./internal/bloblang/query/parsed_test.go
+
+	detectCharsetSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryEncoding).
+		Description(`Attempts to detect the character encoding of a string or byte array, returning the name of the encoding in a form accepted by `+"[`convert_charset`](#convert_charset)"+`.
+
+Encodings with a byte order mark are detected as either `+"`utf-8`, `utf-16le` or `utf-16be`"+`, and any other value that is valid UTF-8 is detected as `+"`utf-8`"+`. Otherwise the value is detected as one of the legacy encodings `+"`shift_jis`, `gbk`, `windows-1251` or `windows-1252`"+`, which is a best guess based on the text that each of them would produce, and therefore short values might be detected incorrectly.`).
+		Version("4.9.0").
+		Example("",
+			`root.charset = this.data.decode("hex").detect_charset()`,
+			[2]string{
+				`{"data":"cff0e8e2e5f22c20ece8f021"}`,
+				`{"charset":"windows-1251"}`,
+			}).
+		Example("",
+			`root.charset = content().detect_charset()`,
+			[2]string{
+				`Grüße aus Köln`,
+				`{"charset":"utf-8"}`,
+			})
+
+	detectCharsetCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		return bloblang.BytesMethod(func(b []byte) (any, error) {
+			return detectCharset(b), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("detect_charset", detectCharsetSpec, detectCharsetCtor); err != nil {
+		panic(err)
+	}
+
+	//--------------------------------------------------------------------------
+
+	convertCharsetSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryEncoding).
+		Description(`Converts a string or byte array from one character encoding to another, returning a byte array. Encodings are named by any of the labels of the [WHATWG Encoding Standard](https://encoding.spec.whatwg.org/#names-and-labels), such as `+"`utf-8`, `windows-1251`, `windows-1252`, `iso-8859-2`, `shift_jis`, `euc-jp`, `gbk`, `big5` or `euc-kr`"+`.
+
+Bytes that are invalid in the source encoding are replaced with the Unicode replacement character, and the conversion fails when the text contains characters that cannot be represented by the target encoding.`).
+		Param(bloblang.NewStringParam("from").Description("The encoding of the value.")).
+		Param(bloblang.NewStringParam("to").Description("The encoding to convert the value to.").Default("utf-8")).
+		Version("4.9.0").
+		Example("Convert text from a legacy encoding to UTF-8, which is the default target encoding.",
+			`root.text = this.data.decode("hex").convert_charset("shift_jis").string()`,
+			[2]string{
+				`{"data":"82b182f182c982bf82cd"}`,
+				`{"text":"こんにちは"}`,
+			}).
+		Example("Convert text of any encoding to UTF-8 by detecting its encoding first.",
+			`let raw = this.data.decode("hex")
+root.text = $raw.convert_charset($raw.detect_charset()).string()`,
+			[2]string{
+				`{"data":"636166e9206372e86d65"}`,
+				`{"text":"café crème"}`,
+			}).
+		Example("",
+			`root = content().convert_charset("utf-8", "windows-1251").encode("hex")`,
+			[2]string{
+				`Привет`,
+				`cff0e8e2e5f2`,
+			})
+
+	convertCharsetCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		fromStr, err := args.GetString("from")
+		if err != nil {
+			return nil, err
+		}
+		toStr, err := args.GetString("to")
+		if err != nil {
+			return nil, err
+		}
+		from, err := charsetFromName(fromStr)
+		if err != nil {
+			return nil, err
+		}
+		to, err := charsetFromName(toStr)
+		if err != nil {
+			return nil, err
+		}
+		return bloblang.BytesMethod(func(b []byte) (any, error) {
+			decoded, err := from.NewDecoder().Bytes(b)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode %v: %w", fromStr, err)
+			}
+			encoded, err := to.NewEncoder().Bytes(decoded)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode %v: %w", toStr, err)
+			}
+			return encoded, nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("convert_charset", convertCharsetSpec, convertCharsetCtor); err != nil {
+		panic(err)
+	}
+}
+
+func charsetFromName(name string) (encoding.Encoding, error) {
+	e, err := htmlindex.Get(strings.TrimSpace(name))
+	if err != nil {
+		return nil, fmt.Errorf("charset '%v' is not supported", name)
+	}
+	return e, nil
+}
+
+//------------------------------------------------------------------------------
+
+// charsetCandidate is a legacy encoding that's considered when detecting the
+// encoding of a value that isn't valid UTF-8.
+type charsetCandidate struct {
+	name     string
+	encoding encoding.Encoding
+	score    func(raw []byte, decoded string) int
+}
+
+var charsetCandidates = []charsetCandidate{
+	{name: "windows-1252", encoding: charmap.Windows1252, score: scoreAlphabetic},
+	{name: "windows-1251", encoding: charmap.Windows1251, score: scoreAlphabetic},
+	{name: "shift_jis", encoding: japanese.ShiftJIS, score: scoreShiftJIS},
+	{name: "gbk", encoding: simplifiedchinese.GBK, score: scoreGBK},
+}
+
+var charsetBOMs = []struct {
+	name string
+	bom  []byte
+}{
+	{name: "utf-8", bom: []byte{0xEF, 0xBB, 0xBF}},
+	{name: "utf-16le", bom: []byte{0xFF, 0xFE}},
+	{name: "utf-16be", bom: []byte{0xFE, 0xFF}},
+}
+
+// detectCharset guesses the encoding of a value by decoding it with each
+// candidate encoding and scoring how plausible the resulting text is. Scores
+// are roughly one point per byte of plausible text, and candidates that fail
+// to decode the value are discarded. Ties go to the earliest candidate.
+func detectCharset(b []byte) string {
+	for _, c := range charsetBOMs {
+		if bytes.HasPrefix(b, c.bom) {
+			return c.name
+		}
+	}
+	if utf8.Valid(b) {
+		return "utf-8"
+	}
+
+	best, bestScore := charsetCandidates[0].name, math.MinInt
+	for _, c := range charsetCandidates {
+		decoded, err := c.encoding.NewDecoder().Bytes(b)
+		if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+			continue
+		}
+		if score := c.score(b, string(decoded)); score > bestScore {
+			best, bestScore = c.name, score
+		}
+	}
+	return best
+}
+
+// scoreAlphabetic scores the text of a single byte encoding by its words,
+// where words that mix scripts or letter cases in ways that natural languages
+// don't, which is typical of text decoded with the wrong encoding, count
+// against it.
+func scoreAlphabetic(_ []byte, decoded string) int {
+	score := 0
+
+	var word []rune
+	scoreWord := func() {
+		if len(word) > 0 {
+			score += scoreAlphabeticWord(word)
+			word = word[:0]
+		}
+	}
+
+	for _, r := range decoded {
+		if unicode.IsLetter(r) {
+			word = append(word, r)
+			continue
+		}
+		scoreWord()
+		if r < utf8.RuneSelf {
+			continue
+		}
+		if unicode.IsControl(r) {
+			score -= 2
+		} else if !strings.ContainsRune(" «»–—‘’‚“”„…•€°№©®™§", r) {
+			score--
+		}
+	}
+	scoreWord()
+	return score
+}
+
+func scoreAlphabeticWord(word []rune) int {
+	var ascii, latin, cyrillic, other, upper int
+	for _, r := range word {
+		switch {
+		case r < utf8.RuneSelf:
+			ascii++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		default:
+			other++
+		}
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	n := len(word) - ascii
+	if n == 0 {
+		return 0
+	}
+
+	// Words are either lower case, upper case or capitalised.
+	plausibleCase := upper == 0 || upper == len(word) ||
+		(upper == 1 && unicode.IsUpper(word[0]))
+
+	switch {
+	case other > 0,
+		cyrillic > 0 && ascii+latin > 0,
+		latin > ascii+1,
+		!plausibleCase:
+		return -n
+	}
+	return n
+}
+
+// scoreShiftJIS scores text by the rows of JIS X 0208 that its characters
+// belong to, where kana and the common kanji of level 1 are plausible, and
+// half width katakana are not.
+func scoreShiftJIS(b []byte, _ string) int {
+	score := 0
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c < 0x80 {
+			i++
+			continue
+		}
+		if c >= 0xA1 && c <= 0xDF {
+			score--
+			i++
+			continue
+		}
+		if i+1 >= len(b) {
+			break
+		}
+		t := b[i+1]
+		switch {
+		case c == 0x82 && t >= 0x9F, c == 0x83 && t <= 0x96:
+			score += 3
+		case c >= 0x88 && c <= 0x98:
+			score += 2
+		case c >= 0x81 && c <= 0x84, c >= 0x99 && c <= 0x9F, c >= 0xE0 && c <= 0xEA:
+			score++
+		}
+		i += 2
+	}
+	return score
+}
+
+// scoreGBK scores text by the regions of GBK that its characters belong to,
+// where the characters of GB 2312 are plausible, and especially the common
+// hanzi of level 1, whereas those of the GBK extensions are rare.
+func scoreGBK(b []byte, _ string) int {
+	score := 0
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c < 0x81 || c == 0xFF {
+			i++
+			continue
+		}
+		if i+1 >= len(b) {
+			break
+		}
+		t := b[i+1]
+		switch {
+		case t < 0xA1:
+		case c >= 0xB0 && c <= 0xD7:
+			score += 2
+		case c >= 0xD8 && c <= 0xF7, c >= 0xA1 && c <= 0xA9:
+			score++
+		}
+		i += 2
+	}
+	return score
+}
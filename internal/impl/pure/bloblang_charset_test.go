@@ -0,0 +1,120 @@
+package pure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func TestDetectCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		text    string
+	}{
+		{name: "russian", charset: "windows-1251", text: "Привет, мир! Это проверка кодировки."},
+		{name: "russian upper case", charset: "windows-1251", text: "ВНИМАНИЕ: СЕРВЕР НЕДОСТУПЕН"},
+		{name: "russian short", charset: "windows-1251", text: "Ульяновск"},
+		{name: "german", charset: "windows-1252", text: "Grüße aus Köln, das Wetter ist schön."},
+		{name: "french", charset: "windows-1252", text: "Ça a été un très bon été, n'est-ce pas ?"},
+		{name: "french short", charset: "windows-1252", text: "café"},
+		{name: "spanish", charset: "windows-1252", text: "¿Dónde está la estación?"},
+		{name: "japanese", charset: "shift_jis", text: "こんにちは、世界。これは文字コードのテストです。"},
+		{name: "japanese katakana", charset: "shift_jis", text: "コンピュータ"},
+		{name: "japanese kanji", charset: "shift_jis", text: "東京都新宿区"},
+		{name: "chinese", charset: "gbk", text: "你好，世界。这是一个字符编码的测试。"},
+		{name: "chinese short", charset: "gbk", text: "北京欢迎你"},
+		{name: "chinese mixed", charset: "gbk", text: "订单 12345 已发货"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			enc, err := charsetFromName(test.charset)
+			require.NoError(t, err)
+
+			b, err := enc.NewEncoder().Bytes([]byte(test.text))
+			require.NoError(t, err)
+
+			assert.Equal(t, test.charset, detectCharset(b))
+		})
+	}
+}
+
+func TestCharsetMethods(t *testing.T) {
+	tests := []struct {
+		name               string
+		mapping            string
+		input              any
+		output             any
+		parseErrorContains string
+		execErrorContains  string
+	}{
+		{
+			name:    "detect_charset utf-8",
+			mapping: `root = this.detect_charset()`,
+			input:   "hello world",
+			output:  "utf-8",
+		},
+		{
+			name:    "detect_charset utf-16 bom",
+			mapping: `root = this.detect_charset()`,
+			input:   []byte{0xFF, 0xFE, 'h', 0x00, 'i', 0x00},
+			output:  "utf-16le",
+		},
+		{
+			name:    "convert_charset shift_jis to utf-8",
+			mapping: `root = this.convert_charset("shift_jis").string()`,
+			input:   []byte{0x82, 0xb1, 0x82, 0xf1, 0x82, 0xc9, 0x82, 0xbf, 0x82, 0xcd},
+			output:  "こんにちは",
+		},
+		{
+			name:    "convert_charset utf-8 to gbk",
+			mapping: `root = this.convert_charset("utf-8", "gbk")`,
+			input:   "中文",
+			output:  []byte{0xd6, 0xd0, 0xce, 0xc4},
+		},
+		{
+			name:              "convert_charset unsupported by target",
+			mapping:           `root = this.convert_charset("latin1", "cp1251")`,
+			input:             []byte("caf\xe9"),
+			execErrorContains: "failed to encode cp1251",
+		},
+		{
+			name:    "convert_charset invalid bytes",
+			mapping: `root = this.convert_charset("utf-8", "utf-8").string()`,
+			input:   []byte("a\xffb"),
+			output:  "a�b",
+		},
+		{
+			name:               "convert_charset unknown charset",
+			mapping:            `root = this.convert_charset("klingon")`,
+			parseErrorContains: "charset 'klingon' is not supported",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			m, err := bloblang.Parse(test.mapping)
+			if test.parseErrorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.parseErrorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			v, err := m.Query(test.input)
+			if test.execErrorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.execErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, v)
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package pure
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func init() {
+	// Note: The examples are run and tested from within
+	// ./internal/bloblang/query/parsed_test.go
+
+	convertUnitSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryNumbers).
+		Description(`Converts a number from one unit to another of the same kind, where the kinds of units are data sizes, data rates, durations and temperatures.
+
+Data sizes are either bits (`+"`b`, `kb`, `Mb`, `Gb`, `Tb`, `Pb`"+`), bytes with decimal prefixes (`+"`B`, `kB`, `MB`, `GB`, `TB`, `PB`"+`) or bytes with binary prefixes (`+"`KiB`, `MiB`, `GiB`, `TiB`, `PiB`"+`), and data rates are data sizes per second, such as `+"`MB/s`"+`, where rates of bits are also written as `+"`bps`, `kbps`, `Mbps`, `Gbps` and `Tbps`"+`. Durations are `+"`ns`, `us`, `ms`, `s`, `m`, `h`, `d` and `w`"+`, and temperatures are `+"`C`, `F` and `K`"+`.`).
+		Param(bloblang.NewStringParam("from").Description("The unit of the number.")).
+		Param(bloblang.NewStringParam("to").Description("The unit to convert the number to.")).
+		Version("4.9.0").
+		Example("",
+			`root.size_mib = this.size.convert_unit("B", "MiB")
+root.bandwidth = this.bandwidth_mbps.convert_unit("Mbps", "MB/s")`,
+			[2]string{
+				`{"size":3145728,"bandwidth_mbps":100}`,
+				`{"bandwidth":12.5,"size_mib":3}`,
+			}).
+		Example("",
+			`root.temp_f = this.temp_c.convert_unit("C", "F")`,
+			[2]string{
+				`{"temp_c":100}`,
+				`{"temp_f":212}`,
+			})
+
+	convertUnitCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		fromStr, err := args.GetString("from")
+		if err != nil {
+			return nil, err
+		}
+		toStr, err := args.GetString("to")
+		if err != nil {
+			return nil, err
+		}
+		from, err := parseUnit(fromStr)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseUnit(toStr)
+		if err != nil {
+			return nil, err
+		}
+		if from.kind != to.kind {
+			return nil, fmt.Errorf("cannot convert %v unit '%v' to %v unit '%v'", from.kind, fromStr, to.kind, toStr)
+		}
+		return bloblang.Float64Method(func(f float64) (any, error) {
+			return to.fromBase(from.toBase(f)), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("convert_unit", convertUnitSpec, convertUnitCtor); err != nil {
+		panic(err)
+	}
+
+	//--------------------------------------------------------------------------
+
+	formatBytesSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryNumbers).
+		Description(`Formats a number of bytes as a human readable string, such as `+"`1.5 MB`"+`, with decimal prefixes or, when `+"`binary`"+` is set, binary prefixes such as `+"`1.5 MiB`"+`.`).
+		Param(bloblang.NewBoolParam("binary").Description("Whether to use binary prefixes, which are multiples of 1024.").Default(false)).
+		Version("4.9.0").
+		Example("",
+			`root.size = this.size.format_bytes()
+root.size_binary = this.size.format_bytes(binary: true)`,
+			[2]string{
+				`{"size":1572864}`,
+				`{"size":"1.6 MB","size_binary":"1.5 MiB"}`,
+			})
+
+	formatBytesCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		binary, err := args.GetBool("binary")
+		if err != nil {
+			return nil, err
+		}
+		return bloblang.Float64Method(func(f float64) (any, error) {
+			if f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("cannot format %v as bytes", f)
+			}
+			if binary {
+				return humanize.IBytes(uint64(f)), nil
+			}
+			return humanize.Bytes(uint64(f)), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("format_bytes", formatBytesSpec, formatBytesCtor); err != nil {
+		panic(err)
+	}
+
+	parseBytesSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryNumbers).
+		Description(`Parses a human readable string of a data size, such as `+"`1.5 MB` or `2GiB`"+`, into an integer of bytes, where the prefixes of units are case insensitive and units with decimal prefixes are multiples of 1000 and binary prefixes of 1024.`).
+		Version("4.9.0").
+		Example("",
+			`root.limit = this.limit.parse_bytes()`,
+			[2]string{
+				`{"limit":"1.5 MB"}`,
+				`{"limit":1500000}`,
+			},
+			[2]string{
+				`{"limit":"2GiB"}`,
+				`{"limit":2147483648}`,
+			})
+
+	parseBytesCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		return bloblang.StringMethod(func(s string) (any, error) {
+			b, err := humanize.ParseBytes(s)
+			if err != nil {
+				return nil, err
+			}
+			if b > math.MaxInt64 {
+				return nil, fmt.Errorf("data size '%v' is too large", s)
+			}
+			return int64(b), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("parse_bytes", parseBytesSpec, parseBytesCtor); err != nil {
+		panic(err)
+	}
+
+	//--------------------------------------------------------------------------
+
+	formatDurSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryTime).
+		Description(`Formats an integer of nanoseconds as a duration string, such as `+"`1h2m3.5s`"+`, which is the inverse of `+"[`parse_duration`](#parse_duration)"+`. The duration can be rounded to the nearest multiple of a duration string, such as `+"`1s`"+`.`).
+		Param(bloblang.NewStringParam("round").Description("An optional duration string to round the duration by.").Optional()).
+		Version("4.9.0").
+		Example("",
+			`root.took = this.took_ns.format_duration()
+root.took_rounded = this.took_ns.format_duration("1s")`,
+			[2]string{
+				`{"took_ns":3723500000000}`,
+				`{"took":"1h2m3.5s","took_rounded":"1h2m4s"}`,
+			})
+
+	formatDurCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		roundStr, err := args.GetOptionalString("round")
+		if err != nil {
+			return nil, err
+		}
+		var round time.Duration
+		if roundStr != nil {
+			if round, err = time.ParseDuration(*roundStr); err != nil {
+				return nil, fmt.Errorf("failed to parse round duration: %w", err)
+			}
+		}
+		return bloblang.Int64Method(func(i int64) (any, error) {
+			return time.Duration(i).Round(round).String(), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("format_duration", formatDurSpec, formatDurCtor); err != nil {
+		panic(err)
+	}
+
+	//--------------------------------------------------------------------------
+
+	formatNumberSpec := bloblang.NewPluginSpec().
+		Beta().
+		Static().
+		Category(query.MethodCategoryNumbers).
+		Description(`Formats a number as a string with the digit grouping and decimal separators of a locale, where numbers are formatted with up to three decimal places unless a number of decimal places is set.`).
+		Param(bloblang.NewStringParam("locale").Description("A [BCP 47](https://www.rfc-editor.org/info/bcp47) language tag of the locale to format the number for, such as `en-US` or `de`.").Default("en")).
+		Param(bloblang.NewInt64Param("decimals").Description("An optional number of decimal places to format the number with.").Optional()).
+		Version("4.9.0").
+		Example("",
+			`root.total = this.total.format_number()
+root.total_de = this.total.format_number("de", 2)`,
+			[2]string{
+				`{"total":1234567.891}`,
+				`{"total":"1,234,567.891","total_de":"1.234.567,89"}`,
+			}).
+		Example("Numbers are grouped according to the conventions of the locale.",
+			`root.population = this.population.format_number("en-IN")`,
+			[2]string{
+				`{"population":1380004385}`,
+				`{"population":"1,38,00,04,385"}`,
+			})
+
+	formatNumberCtor := func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		localeStr, err := args.GetString("locale")
+		if err != nil {
+			return nil, err
+		}
+		tag, err := language.Parse(localeStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse locale: %w", err)
+		}
+		decimals, err := args.GetOptionalInt64("decimals")
+		if err != nil {
+			return nil, err
+		}
+
+		var opts []number.Option
+		if decimals != nil {
+			if *decimals < 0 {
+				return nil, errors.New("decimals must not be negative")
+			}
+			opts = append(opts, number.MinFractionDigits(int(*decimals)), number.MaxFractionDigits(int(*decimals)))
+		}
+
+		printer := message.NewPrinter(tag)
+		return bloblang.Float64Method(func(f float64) (any, error) {
+			return printer.Sprint(number.Decimal(f, opts...)), nil
+		}), nil
+	}
+
+	if err := bloblang.RegisterMethodV2("format_number", formatNumberSpec, formatNumberCtor); err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// unit is a unit of a kind of measure, which converts values to and from the
+// base unit of the kind.
+type unit struct {
+	kind     string
+	toBase   func(float64) float64
+	fromBase func(float64) float64
+}
+
+func scaledUnit(kind string, factor float64) unit {
+	return unit{
+		kind:     kind,
+		toBase:   func(f float64) float64 { return f * factor },
+		fromBase: func(f float64) float64 { return f / factor },
+	}
+}
+
+// The units of data sizes are case sensitive, as bits and bytes differ only by
+// case, and are measured in bits.
+var dataSizeUnits = map[string]float64{
+	"b": 1, "kb": 1e3, "Kb": 1e3, "Mb": 1e6, "Gb": 1e9, "Tb": 1e12, "Pb": 1e15,
+	"B": 8, "kB": 8e3, "KB": 8e3, "MB": 8e6, "GB": 8e9, "TB": 8e12, "PB": 8e15,
+	"KiB": 8 << 10, "MiB": 8 << 20, "GiB": 8 << 30, "TiB": 8 << 40, "PiB": 8 << 50,
+}
+
+var bitRateUnits = map[string]float64{
+	"bps": 1, "kbps": 1e3, "Kbps": 1e3, "Mbps": 1e6, "Gbps": 1e9, "Tbps": 1e12,
+}
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond, "µs": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute, "min": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// Temperatures are converted via Celsius, which converts to and from
+// Fahrenheit without rounding errors for integers.
+var temperatureUnits = map[string]unit{
+	"C": {
+		kind:     "temperature",
+		toBase:   func(f float64) float64 { return f },
+		fromBase: func(f float64) float64 { return f },
+	},
+	"F": {
+		kind:     "temperature",
+		toBase:   func(f float64) float64 { return (f - 32) * 5 / 9 },
+		fromBase: func(f float64) float64 { return f*9/5 + 32 },
+	},
+	"K": {
+		kind:     "temperature",
+		toBase:   func(f float64) float64 { return f - 273.15 },
+		fromBase: func(f float64) float64 { return f + 273.15 },
+	},
+}
+
+func parseUnit(s string) (unit, error) {
+	if factor, exists := dataSizeUnits[s]; exists {
+		return scaledUnit("data size", factor), nil
+	}
+	if strings.HasSuffix(s, "/s") {
+		if factor, exists := dataSizeUnits[strings.TrimSuffix(s, "/s")]; exists {
+			return scaledUnit("data rate", factor), nil
+		}
+	}
+	if factor, exists := bitRateUnits[s]; exists {
+		return scaledUnit("data rate", factor), nil
+	}
+	if d, exists := durationUnits[s]; exists {
+		return scaledUnit("duration", float64(d)), nil
+	}
+	if u, exists := temperatureUnits[strings.TrimPrefix(s, "°")]; exists {
+		return u, nil
+	}
+	return unit{}, fmt.Errorf("unit '%v' is not supported", s)
+}
@@ -0,0 +1,147 @@
+package pure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func TestUnitMethods(t *testing.T) {
+	tests := []struct {
+		name               string
+		mapping            string
+		input              any
+		output             any
+		parseErrorContains string
+		execErrorContains  string
+	}{
+		{
+			name:    "convert_unit bytes to bits",
+			mapping: `root = this.convert_unit("kB", "kb")`,
+			input:   2.5,
+			output:  float64(20),
+		},
+		{
+			name:    "convert_unit binary to decimal",
+			mapping: `root = this.convert_unit("GiB", "MB")`,
+			input:   int64(1),
+			output:  1073.741824,
+		},
+		{
+			name:    "convert_unit data rate",
+			mapping: `root = this.convert_unit("MB/s", "Gbps")`,
+			input:   int64(250),
+			output:  float64(2),
+		},
+		{
+			name:    "convert_unit duration",
+			mapping: `root = this.convert_unit("ms", "min")`,
+			input:   int64(90000),
+			output:  1.5,
+		},
+		{
+			name:    "convert_unit fahrenheit to kelvin",
+			mapping: `root = this.convert_unit("°F", "K")`,
+			input:   int64(32),
+			output:  273.15,
+		},
+		{
+			name:              "convert_unit not a number",
+			mapping:           `root = this.convert_unit("s", "ms")`,
+			input:             "nope",
+			execErrorContains: "expected number value",
+		},
+		{
+			name:               "convert_unit different kinds",
+			mapping:            `root = this.convert_unit("MB", "MB/s")`,
+			parseErrorContains: "cannot convert data size unit 'MB' to data rate unit 'MB/s'",
+		},
+		{
+			name:               "convert_unit unknown unit",
+			mapping:            `root = this.convert_unit("furlong", "m")`,
+			parseErrorContains: "unit 'furlong' is not supported",
+		},
+		{
+			name:    "format_bytes small",
+			mapping: `root = this.format_bytes()`,
+			input:   int64(512),
+			output:  "512 B",
+		},
+		{
+			name:              "format_bytes negative",
+			mapping:           `root = this.format_bytes()`,
+			input:             int64(-1),
+			execErrorContains: "cannot format -1 as bytes",
+		},
+		{
+			name:    "parse_bytes lowercase",
+			mapping: `root = this.parse_bytes()`,
+			input:   "10mib",
+			output:  int64(10 << 20),
+		},
+		{
+			name:              "parse_bytes invalid",
+			mapping:           `root = this.parse_bytes()`,
+			input:             "ten bytes",
+			execErrorContains: "strconv.ParseFloat",
+		},
+		{
+			name:    "format_duration negative",
+			mapping: `root = this.format_duration("100us")`,
+			input:   int64(-1500400),
+			output:  "-1.5ms",
+		},
+		{
+			name:               "format_duration bad round",
+			mapping:            `root = this.format_duration("nope")`,
+			parseErrorContains: "failed to parse round duration",
+		},
+		{
+			name:    "format_number integer",
+			mapping: `root = this.format_number(decimals: 0)`,
+			input:   1234.5,
+			output:  "1,234",
+		},
+		{
+			name:    "format_number padded decimals",
+			mapping: `root = this.format_number("en-US", 2)`,
+			input:   int64(-1000),
+			output:  "-1,000.00",
+		},
+		{
+			name:               "format_number bad locale",
+			mapping:            `root = this.format_number("not a locale")`,
+			parseErrorContains: "failed to parse locale",
+		},
+		{
+			name:               "format_number negative decimals",
+			mapping:            `root = this.format_number(decimals: -1)`,
+			parseErrorContains: "decimals must not be negative",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			m, err := bloblang.Parse(test.mapping)
+			if test.parseErrorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.parseErrorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			v, err := m.Query(test.input)
+			if test.execErrorContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.execErrorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, v)
+		})
+	}
+}
@@ -240,6 +240,29 @@ func (m *memoryCache) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+func (m *memoryCache) ExportItems(context.Context) ([]service.CacheItem, error) {
+	var items []service.CacheItem
+	for _, shard := range m.shards {
+		shard.RLock()
+		for k, v := range shard.items {
+			if shard.isExpired(v) {
+				continue
+			}
+			item := service.CacheItem{
+				Key:   k,
+				Value: append([]byte(nil), v.value...),
+			}
+			if shard.compInterval > 0 && !v.expires.IsZero() {
+				ttl := time.Until(v.expires)
+				item.TTL = &ttl
+			}
+			items = append(items, item)
+		}
+		shard.RUnlock()
+	}
+	return items, nil
+}
+
 func (m *memoryCache) Close(context.Context) error {
 	return nil
 }
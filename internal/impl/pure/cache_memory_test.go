@@ -3,6 +3,7 @@ package pure
 import (
 	"context"
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
@@ -253,3 +254,37 @@ shards: 10
 		assert.Equal(b, value, res)
 	}
 }
+
+func TestMemoryCacheExportItems(t *testing.T) {
+	defConf, err := memCacheConfig().ParseYAML(`
+default_ttl: 5m
+shards: 4
+init_values:
+  foo: bar
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newMemCacheFromConfig(defConf)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	ttl := time.Millisecond
+	require.NoError(t, c.Set(ctx, "expired", []byte("nope"), &ttl))
+	require.NoError(t, c.Set(ctx, "baz", []byte("buz"), nil))
+	<-time.After(time.Millisecond * 5)
+
+	items, err := c.ExportItems(ctx)
+	require.NoError(t, err)
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Key < items[j].Key
+	})
+
+	require.Len(t, items, 2)
+	assert.Equal(t, "baz", items[0].Key)
+	assert.Equal(t, []byte("buz"), items[0].Value)
+	require.NotNil(t, items[0].TTL)
+	assert.True(t, *items[0].TTL > time.Minute*4 && *items[0].TTL <= time.Minute*5, *items[0].TTL)
+
+	assert.Equal(t, service.CacheItem{Key: "foo", Value: []byte("bar")}, items[1])
+}
@@ -0,0 +1,312 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func replayInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Reads messages from a child input whilst archiving a raw copy of each message to an output, allowing a time range of messages to be replayed from the archive at a later date.").
+		Description(`
+This input operates in one of two modes. When the `+"`replay`"+` field is omitted messages are read from the child `+"`input`"+` and a copy of each message is written to the `+"`archive`"+` output before any processing takes place. The archive is written to in parallel with the message being processed, and a message is only acknowledged at its source once both have succeeded. When the archive fails to be written the message is rejected, and will therefore be delivered again by sources that support redelivery.
+
+When the `+"`replay`"+` field is set the child `+"`input`"+` and `+"`archive`"+` are not used, and messages are instead read from the archive with `+"`replay.input`"+` and restored to their original form, including their metadata, before being sent through the pipeline. Only messages that were archived within the time range of `+"`replay.from`"+` and `+"`replay.to`"+` are restored, and all others are dropped. This makes it possible to backfill a pipeline, or recover from an outage, by running the same config with a `+"`replay`"+` section added.
+
+### Archive Format
+
+Each message is archived as a JSON document containing the time at which it was read, its metadata, and its raw contents encoded as base64:
+
+`+"```json"+`
+{"timestamp":"2026-01-02T15:04:05.123456Z","metadata":{"kafka_key":"foo"},"content":"aGVsbG8gd29ybGQ="}
+`+"```"+`
+
+The archive output would usually batch these documents together and combine them with an `+"[`archive` processor](/docs/components/processors/archive)"+` using the `+"`lines`"+` format, and the replay input would then read them back with a `+"`lines`"+` codec.`).
+		Field(service.NewInputField("input").
+			Description("The child input to read messages from when not replaying.").
+			Optional()).
+		Field(service.NewOutputField("archive").
+			Description("An output to write a raw copy of each message read from the child input to.").
+			Optional()).
+		Field(service.NewObjectField("replay",
+			service.NewInputField("input").
+				Description("An input that reads archived messages."),
+			service.NewStringField("from").
+				Description("An optional [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, where messages archived before this time are not replayed.").
+				Example("2026-01-02T15:04:05Z").
+				Optional(),
+			service.NewStringField("to").
+				Description("An optional [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp, where messages archived at or after this time are not replayed.").
+				Example("2026-01-02T16:04:05Z").
+				Optional(),
+		).
+			Description("When set, messages are replayed from an archive instead of being read from the child input.").
+			Optional()).
+		Example(
+			"Archiving to S3",
+			"In this example messages consumed from Kafka are archived to S3 in files of up to a thousand messages.",
+			`
+input:
+  replay:
+    input:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topics: [ foo ]
+        consumer_group: benthos_group
+    archive:
+      aws_s3:
+        bucket: TODO
+        path: 'archive/${! timestamp_unix_nano() }.jsonl'
+        batching:
+          count: 1000
+          period: 1m
+          processors:
+            - archive:
+                format: lines
+`,
+		).
+		Example(
+			"Replaying from S3",
+			"Here the same pipeline is backfilled with messages archived during a one hour window.",
+			`
+input:
+  replay:
+    replay:
+      from: 2026-01-02T15:00:00Z
+      to: 2026-01-02T16:00:00Z
+      input:
+        aws_s3:
+          bucket: TODO
+          prefix: archive/
+          codec: lines
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"replay", replayInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			return newReplayInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// replayArchiveEntry is the archived form of a message.
+type replayArchiveEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Content   []byte            `json:"content"`
+}
+
+type replayInput struct {
+	log *service.Logger
+
+	input   *service.OwnedInput
+	archive *service.OwnedOutput
+
+	replaying bool
+	from, to  time.Time
+
+	archiveCtx  context.Context
+	archiveDone func()
+}
+
+func newReplayInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*replayInput, error) {
+	r := &replayInput{log: mgr.Logger()}
+	r.archiveCtx, r.archiveDone = context.WithCancel(context.Background())
+
+	var err error
+	if conf.Contains("replay") {
+		rConf := conf.Namespace("replay")
+		r.replaying = true
+		if r.from, err = parseReplayTime(rConf, "from"); err != nil {
+			return nil, err
+		}
+		if r.to, err = parseReplayTime(rConf, "to"); err != nil {
+			return nil, err
+		}
+		if !r.from.IsZero() && !r.to.IsZero() && !r.to.After(r.from) {
+			return nil, errors.New("replay to must be after replay from")
+		}
+		if r.input, err = rConf.FieldInput("input"); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if !conf.Contains("input") {
+		return nil, errors.New("an input must be specified when not replaying")
+	}
+	if !conf.Contains("archive") {
+		return nil, errors.New("an archive output must be specified when not replaying")
+	}
+	if r.input, err = conf.FieldInput("input"); err != nil {
+		return nil, err
+	}
+	if r.archive, err = conf.FieldOutput("archive"); err != nil {
+		_ = r.input.Close(context.Background())
+		return nil, err
+	}
+	return r, nil
+}
+
+func parseReplayTime(conf *service.ParsedConfig, field string) (time.Time, error) {
+	if !conf.Contains(field) {
+		return time.Time{}, nil
+	}
+	s, err := conf.FieldString(field)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse replay %v: %w", field, err)
+	}
+	return t, nil
+}
+
+func (r *replayInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (r *replayInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	if r.replaying {
+		return r.readReplay(ctx)
+	}
+
+	batch, ackFn, err := r.input.ReadBatch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archived, err := encodeReplayEntries(batch, time.Now())
+	if err != nil {
+		_ = ackFn(ctx, err)
+		return nil, nil, err
+	}
+
+	archiveErr := make(chan error, 1)
+	go func() {
+		archiveErr <- r.archive.WriteBatch(r.archiveCtx, archived)
+	}()
+
+	return batch, func(ctx context.Context, err error) error {
+		if err == nil {
+			select {
+			case aErr := <-archiveErr:
+				if aErr != nil {
+					r.log.Errorf("Failed to archive messages: %v", aErr)
+					err = fmt.Errorf("failed to archive messages: %w", aErr)
+				}
+			case <-ctx.Done():
+				// The source is still acknowledged, with an error as it's
+				// unknown whether the messages were archived, and with a
+				// context that isn't cancelled as the acknowledgement would
+				// otherwise be abandoned.
+				err = fmt.Errorf("failed to await archiving of messages: %w", ctx.Err())
+				ctx = context.Background()
+			}
+		}
+		return ackFn(ctx, err)
+	}, nil
+}
+
+func encodeReplayEntries(batch service.MessageBatch, ts time.Time) (service.MessageBatch, error) {
+	archived := make(service.MessageBatch, 0, len(batch))
+	for _, msg := range batch {
+		content, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		entry := replayArchiveEntry{
+			Timestamp: ts,
+			Content:   content,
+		}
+		_ = msg.MetaWalk(func(k, v string) error {
+			if entry.Metadata == nil {
+				entry.Metadata = map[string]string{}
+			}
+			entry.Metadata[k] = v
+			return nil
+		})
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		archived = append(archived, service.NewMessage(b))
+	}
+	return archived, nil
+}
+
+func (r *replayInput) readReplay(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		batch, ackFn, err := r.input.ReadBatch(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		restored := make(service.MessageBatch, 0, len(batch))
+		for _, msg := range batch {
+			if m, ok := r.decodeEntry(msg); ok {
+				restored = append(restored, m)
+			}
+		}
+		if len(restored) > 0 {
+			return restored, ackFn, nil
+		}
+		// Nothing within the batch is to be replayed, and so it's acknowledged
+		// straight away.
+		_ = ackFn(ctx, nil)
+	}
+}
+
+func (r *replayInput) decodeEntry(msg *service.Message) (*service.Message, bool) {
+	b, err := msg.AsBytes()
+	if err != nil {
+		r.log.Errorf("Failed to read archived message: %v", err)
+		return nil, false
+	}
+
+	var entry replayArchiveEntry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		r.log.Errorf("Failed to decode archived message: %v", err)
+		return nil, false
+	}
+	if !r.from.IsZero() && entry.Timestamp.Before(r.from) {
+		return nil, false
+	}
+	if !r.to.IsZero() && !entry.Timestamp.Before(r.to) {
+		return nil, false
+	}
+
+	m := service.NewMessage(entry.Content)
+	for k, v := range entry.Metadata {
+		m.MetaSet(k, v)
+	}
+	return m, true
+}
+
+func (r *replayInput) Close(ctx context.Context) error {
+	err := r.input.Close(ctx)
+
+	// Any archive writes still pending belong to messages that have not been
+	// acknowledged, and so are abandoned.
+	r.archiveDone()
+	if r.archive != nil {
+		if aErr := r.archive.Close(ctx); err == nil {
+			err = aErr
+		}
+	}
+	return err
+}
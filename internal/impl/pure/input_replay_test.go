@@ -0,0 +1,190 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestReplayInputArchiveAndReplay(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	archivePath := filepath.Join(t.TempDir(), "archive.jsonl")
+	mgr := service.MockResources()
+
+	conf, err := replayInputSpec().ParseYAML(fmt.Sprintf(`
+input:
+  generate:
+    count: 3
+    interval: ""
+    mapping: |
+      root = "hello " + count("replay_test").string()
+      meta foo = "bar"
+archive:
+  file:
+    path: %v
+    codec: lines
+`, archivePath), nil)
+	require.NoError(t, err)
+
+	in, err := newReplayInputFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	var read []string
+	for {
+		batch, ackFn, err := in.ReadBatch(tCtx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+		for _, m := range batch {
+			b, err := m.AsBytes()
+			require.NoError(t, err)
+			read = append(read, string(b))
+		}
+		require.NoError(t, ackFn(tCtx, nil))
+	}
+	assert.Equal(t, []string{"hello 1", "hello 2", "hello 3"}, read)
+	require.NoError(t, in.Close(tCtx))
+
+	archived, err := os.ReadFile(archivePath)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(archived)), "\n"), 3)
+
+	replay := func(bounds string) (contents []string) {
+		t.Helper()
+
+		conf, err := replayInputSpec().ParseYAML(fmt.Sprintf(`
+replay:
+  input:
+    file:
+      paths: [ %v ]
+      codec: lines
+%v
+`, archivePath, bounds), nil)
+		require.NoError(t, err)
+
+		in, err := newReplayInputFromParsed(conf, mgr)
+		require.NoError(t, err)
+
+		for {
+			batch, ackFn, err := in.ReadBatch(tCtx)
+			if errors.Is(err, service.ErrEndOfInput) {
+				break
+			}
+			require.NoError(t, err)
+			for _, m := range batch {
+				b, err := m.AsBytes()
+				require.NoError(t, err)
+				foo, _ := m.MetaGet("foo")
+				contents = append(contents, string(b)+" "+foo)
+			}
+			require.NoError(t, ackFn(tCtx, nil))
+		}
+		require.NoError(t, in.Close(tCtx))
+		return
+	}
+
+	assert.Equal(t, []string{"hello 1 bar", "hello 2 bar", "hello 3 bar"}, replay(""))
+	assert.Empty(t, replay(`  to: 2000-01-01T00:00:00Z`))
+	assert.Empty(t, replay(`  from: 2100-01-01T00:00:00Z`))
+}
+
+func TestReplayInputArchiveFailure(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf, err := replayInputSpec().ParseYAML(`
+input:
+  generate:
+    count: 1
+    interval: ""
+    mapping: root = "hello world"
+archive:
+  reject: nope
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newReplayInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	// The generate input only yields a single message, and therefore reading
+	// it a second time shows that it was rejected due to the failed archive.
+	for i := 0; i < 2; i++ {
+		batch, ackFn, err := in.ReadBatch(tCtx)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(b))
+		require.NoError(t, ackFn(tCtx, nil))
+	}
+	require.NoError(t, in.Close(tCtx))
+}
+
+func TestReplayInputArchiveCancelled(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	// The archive does not complete until it's unblocked, and so the first
+	// acknowledgement is abandoned by cancelling its context.
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-unblock:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	conf, err := replayInputSpec().ParseYAML(fmt.Sprintf(`
+input:
+  generate:
+    count: 1
+    interval: ""
+    mapping: root = "hello world"
+archive:
+  http_client:
+    url: %v
+    retries: 0
+`, srv.URL), nil)
+	require.NoError(t, err)
+
+	in, err := newReplayInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	batch, ackFn, err := in.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	ackCtx, ackDone := context.WithTimeout(tCtx, time.Millisecond*50)
+	_ = ackFn(ackCtx, nil)
+	ackDone()
+	close(unblock)
+
+	// The generate input only yields a single message, and therefore reading
+	// it again shows that the source was acknowledged with an error.
+	batch, ackFn, err = in.ReadBatch(tCtx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(b))
+	require.NoError(t, ackFn(tCtx, nil))
+	require.NoError(t, in.Close(tCtx))
+}
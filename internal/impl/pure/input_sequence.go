@@ -170,7 +170,7 @@ Each message must be structured (JSON or otherwise processed into a structured f
 //------------------------------------------------------------------------------
 
 type joinedMessage struct {
-	metadata map[string]string
+	metadata map[string]any
 	fields   *gabs.Container
 }
 
@@ -179,7 +179,7 @@ func (j *joinedMessage) ToMsg() message.Batch {
 	jCopy, _ := message.CopyJSON(j.fields)
 	part.SetStructuredMut(jCopy)
 	for k, v := range j.metadata {
-		part.MetaSet(k, v)
+		part.MetaSetMut(k, v)
 	}
 	msg := message.Batch{part}
 	return msg
@@ -253,8 +253,8 @@ func (m *messageJoiner) Add(msg message.Batch, lastInSequence bool, fn func(msg
 			return nil
 		}
 
-		meta := map[string]string{}
-		_ = p.MetaIter(func(k, v string) error {
+		meta := map[string]any{}
+		_ = p.MetaIterMut(func(k string, v any) error {
 			meta[k] = v
 			return nil
 		})
@@ -276,7 +276,7 @@ func (m *messageJoiner) Add(msg message.Batch, lastInSequence bool, fn func(msg
 		_ = gIncoming.Delete(m.idPath)
 		_ = jObj.fields.MergeFn(gIncoming, m.collisionFn)
 
-		_ = p.MetaIter(func(k, v string) error {
+		_ = p.MetaIterMut(func(k string, v any) error {
 			jObj.metadata[k] = v
 			return nil
 		})
@@ -11,6 +11,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/output/batcher"
 	"github.com/benthosdev/benthos/v4/internal/component/output/processors"
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
 )
 
 // ErrBrokerNoOutputs is returned when creating a Broker type with zero
@@ -81,7 +82,11 @@ potentially disproportionate message allocations to those outputs. Each message
 is sent to a single output, which is determined by allowing outputs to claim
 messages as soon as they are able to process them. This results in certain
 faster outputs potentially processing more messages at the cost of slower
-outputs.`,
+outputs.
+
+## In Flight Accounting
+
+The field ` + "`in_flight`" + ` can be used in order to limit the number of messages and bytes in flight to each child output individually. When an output reaches its high watermark it applies back pressure until enough of its pending messages are acknowledged, which allows you to prevent a single slow output from accumulating an unbounded amount of data.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldInt("copies", "The number of copies of each configured output to spawn.").Advanced().HasDefault(1),
 			docs.FieldString("pattern", "The brokering pattern to use.").HasOptions(
@@ -89,6 +94,7 @@ outputs.`,
 			).HasDefault("fan_out"),
 			docs.FieldOutput("outputs", "A list of child outputs to broker.").Array().HasDefault([]any{}),
 			policy.FieldSpec(),
+			inflight.FieldSpec().AtVersion("4.9.0"),
 		),
 		Categories: []string{
 			"Utility",
@@ -113,6 +119,7 @@ func newBroker(conf output.Config, mgr bundle.NewManagement) (output.Streamed, e
 		if err != nil {
 			return nil, err
 		}
+		b = wrapInFlightOutput(conf.Broker.InFlight, mgr.IntoPath("broker", "outputs", "0").Metrics(), b)
 		if b, err = batcher.NewFromConfig(conf.Broker.Batching, b, mgr); err != nil {
 			return nil, err
 		}
@@ -139,7 +146,7 @@ func newBroker(conf output.Config, mgr bundle.NewManagement) (output.Streamed, e
 					return nil, err
 				}
 			}
-			outputs[j*len(outputConfs)+i] = tmpOut
+			outputs[j*len(outputConfs)+i] = wrapInFlightOutput(conf.Broker.InFlight, oMgr.Metrics(), tmpOut)
 		}
 	}
 
@@ -0,0 +1,52 @@
+package pure
+
+import (
+	"context"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type gatedOutput struct {
+	gate *inflight.Gate
+	out  output.Streamed
+}
+
+// wrapInFlightOutput returns an output that accounts for the messages and bytes in
+// flight to a wrapped output, applying back pressure once the high watermarks
+// of the config are reached. If the config is a noop then the wrapped output is
+// returned unchanged.
+func wrapInFlightOutput(conf inflight.Config, stats metrics.Type, out output.Streamed) output.Streamed {
+	if conf.IsNoop() {
+		return out
+	}
+	return &gatedOutput{
+		gate: inflight.NewGate(conf, stats),
+		out:  out,
+	}
+}
+
+func (g *gatedOutput) Consume(ts <-chan message.Transaction) error {
+	if err := g.gate.Consume(ts); err != nil {
+		return err
+	}
+	return g.out.Consume(g.gate.TransactionChan())
+}
+
+func (g *gatedOutput) Connected() bool {
+	return g.out.Connected()
+}
+
+func (g *gatedOutput) TriggerCloseNow() {
+	g.gate.TriggerCloseNow()
+	g.out.TriggerCloseNow()
+}
+
+func (g *gatedOutput) WaitForClose(ctx context.Context) error {
+	if err := g.gate.WaitForClose(ctx); err != nil {
+		return err
+	}
+	return g.out.WaitForClose(ctx)
+}
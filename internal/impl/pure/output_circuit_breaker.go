@@ -0,0 +1,123 @@
+package pure
+
+import (
+	"context"
+
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func circuitBreakerOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Writes messages to a child output and stops attempting to do so once an error rate threshold is breached, optionally routing messages to a fallback output whilst the circuit is open.").
+		Description(`
+The circuit breaker counts the outcome of each write to the child output within a window. Once at least ` + "`min_requests`" + ` writes have been observed and the ratio of them that failed reaches ` + "`error_threshold`" + ` the circuit opens.
+
+Whilst the circuit is open writes are not attempted against the child output. If a ` + "`fallback`" + ` output is configured then messages are written there instead, otherwise the write is rejected and the message will be reattempted according to the input it originated from. After ` + "`open_period`" + ` has elapsed the circuit becomes half-open and up to ` + "`half_open_trials`" + ` writes are attempted against the child output, if they all succeed the circuit closes again, otherwise it reopens.
+
+### Metrics
+
+The state of the circuit breaker is exposed with the gauge metric ` + "`circuit_breaker_state`" + `, where ` + "`0`" + ` is closed, ` + "`1`" + ` is half-open and ` + "`2`" + ` is open. The counters ` + "`circuit_breaker_opened`" + ` and ` + "`circuit_breaker_rejected`" + ` track the number of times the circuit has opened and the number of writes rejected whilst open respectively. These metrics are labelled with the path and label of the output, and therefore each circuit breaker within a config is reported separately.`).
+		Field(service.NewOutputField("output").
+			Description("The child output to write messages to.")).
+		Field(service.NewOutputField("fallback").
+			Description("An optional output to write messages to whilst the circuit is open.").
+			Optional())
+
+	for _, f := range circuitbreaker.PolicyFieldSpecs() {
+		spec = spec.Field(service.NewInternalField(f))
+	}
+
+	return spec.
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Failover to a Dead Letter Queue",
+			"In this example messages are written to an HTTP endpoint, and if more than half of the requests made within a minute fail then messages are instead written to a Kafka topic for a minute before the endpoint is tried again.",
+			`
+output:
+  circuit_breaker:
+    error_threshold: 0.5
+    window: 1m
+    open_period: 1m
+    output:
+      http_client:
+        url: http://example.com/post
+        verb: POST
+    fallback:
+      kafka:
+        addresses: [ localhost:9092 ]
+        topic: dead_letters
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"circuit_breaker", circuitBreakerOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newCircuitBreakerOutputFromParsed(conf)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type circuitBreakerOutput struct {
+	breaker  *service.CircuitBreaker
+	output   *service.OwnedOutput
+	fallback *service.OwnedOutput
+}
+
+func newCircuitBreakerOutputFromParsed(conf *service.ParsedConfig) (*circuitBreakerOutput, error) {
+	c := &circuitBreakerOutput{}
+
+	var err error
+	if c.breaker, err = conf.FieldCircuitBreaker(); err != nil {
+		return nil, err
+	}
+	if c.output, err = conf.FieldOutput("output"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("fallback") {
+		if c.fallback, err = conf.FieldOutput("fallback"); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *circuitBreakerOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c *circuitBreakerOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if err := c.breaker.Allow(); err != nil {
+		if c.fallback == nil {
+			return err
+		}
+		return c.fallback.WriteBatch(ctx, batch)
+	}
+
+	err := c.output.WriteBatch(ctx, batch)
+	c.breaker.Done(err)
+	return err
+}
+
+func (c *circuitBreakerOutput) Close(ctx context.Context) error {
+	err := c.output.Close(ctx)
+	if c.fallback != nil {
+		if ferr := c.fallback.Close(ctx); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
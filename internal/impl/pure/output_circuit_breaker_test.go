@@ -0,0 +1,106 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestCircuitBreakerOutputNoFallback(t *testing.T) {
+	conf, err := circuitBreakerOutputSpec().ParseYAML(`
+min_requests: 2
+error_threshold: 1
+open_period: 1h
+output:
+  reject: nope
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newCircuitBreakerOutputFromParsed(conf)
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	batch := service.MessageBatch{service.NewMessage([]byte("hello world"))}
+	for i := 0; i < 2; i++ {
+		err = out.WriteBatch(tCtx, batch)
+		require.Error(t, err)
+		assert.NotEqual(t, service.ErrCircuitBreakerOpen, err)
+	}
+
+	assert.Equal(t, service.ErrCircuitBreakerOpen, out.WriteBatch(tCtx, batch))
+	require.NoError(t, out.Close(tCtx))
+}
+
+func TestCircuitBreakerOutputFallback(t *testing.T) {
+	conf, err := circuitBreakerOutputSpec().ParseYAML(`
+min_requests: 1
+open_period: 1h
+output:
+  reject: nope
+fallback:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newCircuitBreakerOutputFromParsed(conf)
+	require.NoError(t, err)
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	batch := service.MessageBatch{service.NewMessage([]byte("hello world"))}
+	require.Error(t, out.WriteBatch(tCtx, batch))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, out.WriteBatch(tCtx, batch))
+	}
+	require.NoError(t, out.Close(tCtx))
+}
+
+func TestCircuitBreakerOutputMetrics(t *testing.T) {
+	stats := metrics.NewLocal()
+	mgr, err := manager.New(manager.NewResourceConfig(), manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	require.NoError(t, err)
+
+	conf := output.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+circuit_breaker:
+  min_requests: 1
+  error_threshold: 1
+  open_period: 1h
+  output:
+    drop: {}
+`), &conf))
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	for _, path := range []string{"a", "b"} {
+		out, err := mgr.IntoPath(path).NewOutput(conf)
+		require.NoError(t, err)
+		require.NoError(t, out.Consume(make(chan message.Transaction)))
+
+		out.TriggerCloseNow()
+		require.NoError(t, out.WaitForClose(tCtx))
+	}
+
+	counters := stats.GetCounters()
+	for _, path := range []string{"root.a", "root.b"} {
+		for _, name := range []string{"circuit_breaker_state", "circuit_breaker_opened", "circuit_breaker_rejected"} {
+			assert.Contains(t, counters, name+`{label="",path="`+path+`"}`)
+		}
+	}
+	assert.NotContains(t, counters, "circuit_breaker_state")
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strconv"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
@@ -42,7 +43,7 @@ output:
 
 ### Metadata
 
-When a given output fails the message routed to the following output will have a metadata value named ` + "`fallback_error`" + ` containing a string error message outlining the cause of the failure. The content of this string will depend on the particular output and can be used to enrich the message or provide information used to broker the data to an appropriate output using something like a ` + "`switch`" + ` output.
+When a given output fails the message routed to the following output will have a metadata value named ` + "`fallback_error`" + ` containing a string error message outlining the cause of the failure. When an output reports errors for individual messages of a batch only the messages that failed are routed to the following output, each with its own error. The content of this string will depend on the particular output and can be used to enrich the message or provide information used to broker the data to an appropriate output using something like a ` + "`switch`" + ` output.
 
 ### Batching
 
@@ -178,17 +179,43 @@ func (t *fallbackBroker) loop() {
 		}
 
 		i := 0
+
+		// The indexes of the original payload that are routed to the current
+		// output, which is every message until an output reports errors for
+		// individual messages of a batch.
+		indexes := make([]int, tran.Payload.Len())
+		for j := range indexes {
+			indexes[j] = j
+		}
+
 		var ackFn func(ctx context.Context, err error) error
 		ackFn = func(ctx context.Context, err error) error {
 			i++
-			if err == nil || len(t.outputTSChans) <= i {
-				return tran.Ack(ctx, err)
+			if err == nil {
+				return tran.Ack(ctx, nil)
+			}
+
+			failed, partErrs := fallbackFailedParts(len(indexes), err)
+			if len(t.outputTSChans) <= i {
+				if len(failed) == tran.Payload.Len() {
+					return tran.Ack(ctx, err)
+				}
+				bErr := batch.NewError(tran.Payload, err)
+				for j, index := range failed {
+					bErr.Failed(indexes[index], partErrs[j])
+				}
+				return tran.Ack(ctx, bErr)
+			}
+
+			newPayload := make(message.Batch, len(failed))
+			newIndexes := make([]int, len(failed))
+			for j, index := range failed {
+				newIndexes[j] = indexes[index]
+				newPayload[j] = tran.Payload.Get(newIndexes[j]).ShallowCopy()
+				newPayload[j].MetaSet("fallback_error", partErrs[j].Error())
 			}
-			newPayload := tran.Payload.ShallowCopy()
-			_ = newPayload.Iter(func(i int, p *message.Part) error {
-				p.MetaSet("fallback_error", err.Error())
-				return nil
-			})
+			indexes = newIndexes
+
 			select {
 			case t.outputTSChans[i] <- message.NewTransactionFunc(newPayload, ackFn):
 			case <-ctx.Done():
@@ -229,3 +256,31 @@ func closeAllOutputs(ctx context.Context, outputs []output.Streamed) error {
 	}
 	return nil
 }
+
+// fallbackFailedParts returns the indexes of the messages of a failed batch
+// along with their errors. When an output reports errors for individual
+// messages of a batch only those messages are returned, otherwise every message
+// of the batch is returned with the error of the batch as a whole.
+func fallbackFailedParts(size int, err error) (indexes []int, errs []error) {
+	var bErr batch.WalkableError
+	if errors.As(err, &bErr) && bErr.IndexedErrors() > 0 {
+		bErr.WalkParts(func(i int, _ *message.Part, pErr error) bool {
+			if i < size && pErr != nil {
+				indexes = append(indexes, i)
+				errs = append(errs, pErr)
+			}
+			return true
+		})
+		if len(indexes) > 0 {
+			return
+		}
+	}
+
+	indexes = make([]int, size)
+	errs = make([]error, size)
+	for i := range indexes {
+		indexes[i] = i
+		errs[i] = err
+	}
+	return
+}
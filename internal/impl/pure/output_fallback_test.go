@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
@@ -439,3 +440,87 @@ func TestFallbackAllFailParallel(t *testing.T) {
 	close(readChan)
 	require.NoError(t, oTM.WaitForClose(tCtx))
 }
+
+func TestFallbackBatchErrorMetadata(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	mockOutputs := []*mock.OutputChanneled{{}, {}, {}}
+
+	oTM, err := newFallbackBroker([]output.Streamed{mockOutputs[0], mockOutputs[1], mockOutputs[2]})
+	require.NoError(t, err)
+
+	readChan := make(chan message.Transaction)
+	resChan := make(chan error)
+	require.NoError(t, oTM.Consume(readChan))
+
+	inBatch := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	select {
+	case readChan <- message.NewTransaction(inBatch, resChan):
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+
+	ackErrs := make(chan error, 1)
+	ackAsync := func(ts message.Transaction, err error) {
+		go func() {
+			ackErrs <- ts.Ack(tCtx, err)
+		}()
+	}
+
+	var ts message.Transaction
+	select {
+	case ts = <-mockOutputs[0].TChan:
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+	ackAsync(ts, batch.NewError(ts.Payload, errors.New("batch err")).
+		Failed(1, errors.New("bar err")).
+		Failed(2, errors.New("baz err")))
+
+	// Only the failed messages are routed to the next output.
+	select {
+	case ts = <-mockOutputs[1].TChan:
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+	require.NoError(t, <-ackErrs)
+	require.Equal(t, 2, ts.Payload.Len())
+	assert.Equal(t, "bar", string(ts.Payload.Get(0).AsBytes()))
+	assert.Equal(t, "bar err", ts.Payload.Get(0).MetaGet("fallback_error"))
+	assert.Equal(t, "baz", string(ts.Payload.Get(1).AsBytes()))
+	assert.Equal(t, "baz err", ts.Payload.Get(1).MetaGet("fallback_error"))
+	ackAsync(ts, batch.NewError(ts.Payload, errors.New("batch err")).Failed(1, errors.New("baz err again")))
+
+	select {
+	case ts = <-mockOutputs[2].TChan:
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+	require.NoError(t, <-ackErrs)
+	require.Equal(t, 1, ts.Payload.Len())
+	assert.Equal(t, "baz", string(ts.Payload.Get(0).AsBytes()))
+	assert.Equal(t, "baz err again", ts.Payload.Get(0).MetaGet("fallback_error"))
+	ackAsync(ts, errors.New("final err"))
+
+	// The error of the final output is reported against the original index.
+	select {
+	case res := <-resChan:
+		var bErr *batch.Error
+		require.ErrorAs(t, res, &bErr)
+		failed := map[int]string{}
+		bErr.WalkParts(func(i int, _ *message.Part, err error) bool {
+			if err != nil {
+				failed[i] = err.Error()
+			}
+			return true
+		})
+		assert.Equal(t, map[int]string{2: "final err"}, failed)
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+	require.NoError(t, <-ackErrs)
+
+	close(readChan)
+	require.NoError(t, oTM.WaitForClose(tCtx))
+}
@@ -0,0 +1,287 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ibatch "github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/lineage"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func idempotencyOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Writes messages to a child output whilst suppressing messages that have already been delivered, using a key derived from the position of each message within its source.").
+		Description(`
+Each message is given an idempotency key which, when the `+"`key`"+` field is omitted, is derived from the metadata added by inputs that describe the position of the message within its source:
+
+| Source | Metadata |
+|---|---|
+| Kafka | `+"`kafka_topic`, `kafka_partition` and `kafka_offset`"+` |
+| AWS SQS | `+"`sqs_message_id`"+` |
+| AWS S3 | `+"`s3_bucket`, `s3_key` and `s3_message_index`"+` |
+| GCP Cloud Storage | `+"`gcs_bucket`, `gcs_key` and `gcs_message_index`"+` |
+| Azure Blob Storage | `+"`blob_storage_container`, `blob_storage_key` and `blob_storage_message_index`"+` |
+
+Before a batch is written the cache is checked for the key of each message, and any message with a key that already exists is acknowledged without being written. Once the child output has successfully written a message its key is added to the cache, even when other messages of the same batch failed, and therefore only the messages that failed to be written are written again when the batch is reattempted. Messages where a key could not be derived are always written.
+
+When the cache is persisted outside of Benthos, such as with a `+"`redis`"+` cache, duplicates are also suppressed across restarts, resulting in effectively-once delivery for sources that replay messages from their most recently committed position.
+
+Writes are not coordinated across concurrent batches, and therefore duplicates of the same message that are in flight at the same time can each be written. Set `+"`max_in_flight`"+` to `+"`1`"+` in order to avoid this at the cost of throughput.
+
+### Metrics
+
+The counter `+"`idempotency_duplicates`"+` tracks the number of messages that were suppressed.`).
+		Field(service.NewOutputField("output").
+			Description("The child output to write messages to.")).
+		Field(service.NewStringField("cache").
+			Description("A [cache resource](/docs/components/caches/about) to store the keys of delivered messages within.")).
+		Field(service.NewInterpolatedStringField("key").
+			Description("An optional key to be resolved for each message, when omitted the key is derived from the metadata of the message as described above.").
+			Example(`${! meta("kafka_topic") }-${! meta("kafka_partition") }-${! meta("kafka_offset") }`).
+			Example(`${! json("id") }`).
+			Optional()).
+		Field(service.NewDurationField("ttl").
+			Description("An optional expiry period to set for each key, which should exceed the period over which a source might replay messages. Some caches only have a general TTL and will therefore ignore this setting.").
+			Example("24h").
+			Optional()).
+		Field(service.NewIntField("max_in_flight").
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Effectively-Once Kafka Replication",
+			"In this example messages consumed from a Kafka topic are written to an HTTP endpoint, and the positions of delivered messages are stored within Redis so that messages replayed after a restart are not delivered again.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: benthos_group
+
+output:
+  idempotency:
+    cache: delivered
+    ttl: 24h
+    output:
+      http_client:
+        url: http://example.com/post
+        verb: POST
+
+cache_resources:
+  - label: delivered
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"idempotency", idempotencyOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+				return
+			}
+			out, err = newIdempotencyOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type idempotencyOutput struct {
+	mgr        *service.Resources
+	cacheName  string
+	key        *service.InterpolatedString
+	ttl        *time.Duration
+	output     *service.OwnedOutput
+	duplicates *service.MetricCounter
+}
+
+func newIdempotencyOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*idempotencyOutput, error) {
+	i := &idempotencyOutput{
+		mgr:        mgr,
+		duplicates: mgr.Metrics().NewCounter("idempotency_duplicates"),
+	}
+
+	var err error
+	if i.cacheName, err = conf.FieldString("cache"); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(i.cacheName) {
+		return nil, fmt.Errorf("cache named %v not found", i.cacheName)
+	}
+	if conf.Contains("key") {
+		if i.key, err = conf.FieldInterpolatedString("key"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("ttl") {
+		var ttl time.Duration
+		if ttl, err = conf.FieldDuration("ttl"); err != nil {
+			return nil, err
+		}
+		i.ttl = &ttl
+	}
+	if i.output, err = conf.FieldOutput("output"); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *idempotencyOutput) keyFor(msg *service.Message) (string, bool) {
+	if i.key != nil {
+		k := i.key.String(msg)
+		return k, k != ""
+	}
+	return lineage.SourceID(msg.MetaGet)
+}
+
+func (i *idempotencyOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// multiKeyCache is implemented by caches obtained from resources, and checks and
+// stores the keys of a batch in as few requests as the cache allows.
+type multiKeyCache interface {
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+	SetMulti(ctx context.Context, keyValues ...service.CacheItem) error
+}
+
+func getIdempotencyKeys(ctx context.Context, c service.Cache, keys []string) (map[string][]byte, error) {
+	if mc, ok := c.(multiKeyCache); ok {
+		return mc.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		v, err := c.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, service.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = v
+	}
+	return values, nil
+}
+
+func setIdempotencyKeys(ctx context.Context, c service.Cache, items []service.CacheItem) error {
+	if mc, ok := c.(multiKeyCache); ok {
+		return mc.SetMulti(ctx, items...)
+	}
+	for _, item := range items {
+		if err := c.Set(ctx, item.Key, item.Value, item.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *idempotencyOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	keys := make([]string, len(batch))
+	lookup := make([]string, 0, len(batch))
+	for j, msg := range batch {
+		if key, exists := i.keyFor(msg); exists {
+			keys[j] = key
+			lookup = append(lookup, key)
+		}
+	}
+
+	var delivered map[string][]byte
+	if len(lookup) > 0 {
+		var getErr error
+		if err := i.mgr.AccessCache(ctx, i.cacheName, func(c service.Cache) {
+			delivered, getErr = getIdempotencyKeys(ctx, c, lookup)
+		}); err != nil {
+			return err
+		}
+		if getErr != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", getErr)
+		}
+	}
+
+	// The indexes of pending messages within the batch, which are required in
+	// order to report the errors of individual messages.
+	var pending service.MessageBatch
+	var pendingIndexes []int
+	for j, msg := range batch {
+		if _, exists := delivered[keys[j]]; exists && keys[j] != "" {
+			i.duplicates.Incr(1)
+			continue
+		}
+		pending = append(pending, msg)
+		pendingIndexes = append(pendingIndexes, j)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	writeErr := i.output.WriteBatch(ctx, pending)
+
+	// When only some messages of the batch failed the keys of the others are
+	// still stored, as otherwise they're written again when the batch is
+	// reattempted.
+	var batchErr *service.BatchError
+	failed := make([]error, len(pending))
+	if writeErr != nil {
+		var childErr *ibatch.Error
+		if !errors.As(writeErr, &childErr) || childErr.IndexedErrors() == 0 {
+			return writeErr
+		}
+		batchErr = service.NewBatchError(batch, writeErr)
+		childErr.WalkParts(func(j int, _ *message.Part, err error) bool {
+			if j < len(failed) {
+				failed[j] = err
+			}
+			return true
+		})
+	}
+
+	items := make([]service.CacheItem, 0, len(pending))
+	for j, index := range pendingIndexes {
+		if err := failed[j]; err != nil {
+			batchErr.Failed(index, err)
+			continue
+		}
+		if keys[index] != "" {
+			items = append(items, service.CacheItem{
+				Key:   keys[index],
+				Value: []byte("1"),
+				TTL:   i.ttl,
+			})
+		}
+	}
+
+	if len(items) > 0 {
+		var setErr error
+		if err := i.mgr.AccessCache(ctx, i.cacheName, func(c service.Cache) {
+			setErr = setIdempotencyKeys(ctx, c, items)
+		}); err != nil {
+			setErr = err
+		}
+
+		// The messages have been delivered at this point and so failing to
+		// store their keys only risks future duplicates, which is logged rather
+		// than rejected.
+		if setErr != nil {
+			i.mgr.Logger().Errorf("Failed to store idempotency keys of delivered messages: %v", setErr)
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (i *idempotencyOutput) Close(ctx context.Context) error {
+	return i.output.Close(ctx)
+}
@@ -0,0 +1,185 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestIdempotencyOutputSuppression(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	newOutput := func(child string) *idempotencyOutput {
+		t.Helper()
+
+		conf, err := idempotencyOutputSpec().ParseYAML(`
+cache: foocache
+output:
+`+child, nil)
+		require.NoError(t, err)
+
+		out, err := newIdempotencyOutputFromParsed(conf, mgr)
+		require.NoError(t, err)
+		return out
+	}
+
+	hasKey := func(key string) (exists bool) {
+		t.Helper()
+		require.NoError(t, mgr.AccessCache(tCtx, "foocache", func(c service.Cache) {
+			_, err := c.Get(tCtx, key)
+			exists = err == nil
+		}))
+		return
+	}
+
+	newMsg := func(id string) *service.Message {
+		msg := service.NewMessage([]byte("hello world"))
+		msg.MetaSet("sqs_message_id", id)
+		return msg
+	}
+
+	// Failed writes do not store keys.
+	rejecting := newOutput(`  reject: nope`)
+	require.Error(t, rejecting.WriteBatch(tCtx, service.MessageBatch{newMsg("a")}))
+	assert.False(t, hasKey("sqs:a"))
+
+	// Successful writes store keys.
+	dropping := newOutput(`  drop: {}`)
+	require.NoError(t, dropping.WriteBatch(tCtx, service.MessageBatch{newMsg("a"), newMsg("b")}))
+	assert.True(t, hasKey("sqs:a"))
+	assert.True(t, hasKey("sqs:b"))
+
+	// Delivered messages are suppressed, and so are never written to the
+	// rejecting output.
+	require.NoError(t, rejecting.WriteBatch(tCtx, service.MessageBatch{newMsg("a"), newMsg("b")}))
+
+	// A message without a key is always written.
+	require.Error(t, rejecting.WriteBatch(tCtx, service.MessageBatch{newMsg("a"), service.NewMessage(nil)}))
+
+	require.NoError(t, rejecting.Close(tCtx))
+	require.NoError(t, dropping.Close(tCtx))
+}
+
+func TestIdempotencyOutputPartialFailure(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	conf, err := idempotencyOutputSpec().ParseYAML(`
+cache: foocache
+output:
+  switch:
+    cases:
+      - check: meta("fail") == "true"
+        output:
+          reject: nope
+      - output:
+          drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newIdempotencyOutputFromParsed(conf, mgr)
+	require.NoError(t, err)
+
+	hasKey := func(key string) (exists bool) {
+		t.Helper()
+		require.NoError(t, mgr.AccessCache(tCtx, "foocache", func(c service.Cache) {
+			_, err := c.Get(tCtx, key)
+			exists = err == nil
+		}))
+		return
+	}
+
+	newMsg := func(id string, fail bool) *service.Message {
+		msg := service.NewMessage([]byte("hello world"))
+		msg.MetaSet("sqs_message_id", id)
+		if fail {
+			msg.MetaSet("fail", "true")
+		}
+		return msg
+	}
+
+	failedIndexes := func(err error) (indexes []int) {
+		t.Helper()
+		var bErr *service.BatchError
+		require.ErrorAs(t, err, &bErr)
+		bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+			if err != nil {
+				indexes = append(indexes, i)
+			}
+			return true
+		})
+		return
+	}
+
+	// The keys of messages that were delivered are stored even though others
+	// within the batch failed.
+	err = out.WriteBatch(tCtx, service.MessageBatch{newMsg("a", true), newMsg("b", false)})
+	assert.Equal(t, []int{0}, failedIndexes(err))
+	assert.False(t, hasKey("sqs:a"))
+	assert.True(t, hasKey("sqs:b"))
+
+	// Errors are reported at the index of the message within the batch rather
+	// than within the messages that weren't suppressed.
+	err = out.WriteBatch(tCtx, service.MessageBatch{newMsg("b", true), newMsg("a", true), newMsg("c", false)})
+	assert.Equal(t, []int{1}, failedIndexes(err))
+	assert.False(t, hasKey("sqs:a"))
+	assert.True(t, hasKey("sqs:c"))
+
+	require.NoError(t, out.Close(tCtx))
+}
+
+func TestIdempotencyOutputSplitObject(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	newOutput := func(child string) *idempotencyOutput {
+		t.Helper()
+
+		conf, err := idempotencyOutputSpec().ParseYAML(`
+cache: foocache
+output:
+`+child, nil)
+		require.NoError(t, err)
+
+		out, err := newIdempotencyOutputFromParsed(conf, mgr)
+		require.NoError(t, err)
+		return out
+	}
+
+	// Lines of a single object consumed with the lines codec.
+	newLine := func(index string) *service.Message {
+		msg := service.NewMessage([]byte("line " + index))
+		msg.MetaSet("s3_bucket", "foo")
+		msg.MetaSet("s3_key", "bar.txt")
+		msg.MetaSet("s3_message_index", index)
+		return msg
+	}
+
+	dropping := newOutput(`  drop: {}`)
+	require.NoError(t, dropping.WriteBatch(tCtx, service.MessageBatch{newLine("0"), newLine("1")}))
+
+	// Only the lines that were delivered are suppressed.
+	rejecting := newOutput(`  reject: nope`)
+	require.NoError(t, rejecting.WriteBatch(tCtx, service.MessageBatch{newLine("0"), newLine("1")}))
+	require.Error(t, rejecting.WriteBatch(tCtx, service.MessageBatch{newLine("0"), newLine("1"), newLine("2")}))
+
+	// Without an index there is no key, and so the message is always written.
+	unindexed := newLine("0")
+	unindexed.MetaDelete("s3_message_index")
+	require.Error(t, rejecting.WriteBatch(tCtx, service.MessageBatch{unindexed}))
+
+	require.NoError(t, rejecting.Close(tCtx))
+	require.NoError(t, dropping.Close(tCtx))
+}
@@ -43,7 +43,9 @@ func init() {
 		Summary: `
 The switch output type allows you to route messages to different outputs based on their contents.`,
 		Description: `
-Messages must successfully route to one or more outputs, otherwise this is considered an error and the message is reprocessed. In order to explicitly drop messages that do not match your cases add one final case with a [drop output](/docs/components/outputs/drop).`,
+Messages must successfully route to one or more outputs, otherwise this is considered an error and the message is reprocessed. In order to explicitly drop messages that do not match your cases add one final case with a [drop output](/docs/components/outputs/drop).
+
+When every check compares the same query against a string literal, such as ` + "`meta(\"topic\") == \"foo\"`" + `, and no case sets ` + "`continue`" + `, the query is executed once per message and the matching case is looked up directly rather than testing each check in turn. This makes routing across a large number of cases cheap.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldBool(
 				"retry_until_success", `
@@ -194,6 +196,7 @@ type switchOutput struct {
 	outputTSChans []chan message.Transaction
 	outputs       []output.Streamed
 	checks        []*mapping.Executor
+	dispatch      *mapping.Dispatcher
 	continues     []bool
 	fallthroughs  []bool
 
@@ -238,6 +241,14 @@ func newSwitchOutput(conf output.SwitchConfig, mgr bundle.NewManagement) (output
 		o.continues[i] = cConf.Continue
 	}
 
+	hasContinue := false
+	for _, c := range o.continues {
+		hasContinue = hasContinue || c
+	}
+	if !hasContinue {
+		o.dispatch, _ = mapping.NewDispatcher(o.checks)
+	}
+
 	o.outputTSChans = make([]chan message.Transaction, len(o.outputs))
 	for i := range o.outputTSChans {
 		o.outputTSChans[i] = make(chan message.Transaction)
@@ -413,6 +424,17 @@ func (o *switchOutput) loop() {
 
 		outputTargets := make([][]*message.Part, len(o.checks))
 		if checksErr := trackedMsg.Iter(func(i int, p *message.Part) error {
+			if o.dispatch != nil {
+				if j, ok := o.dispatch.FirstMatch(i, trackedMsg); ok {
+					if j >= 0 {
+						outputTargets[j] = append(outputTargets[j], p.ShallowCopy())
+					} else if o.strictMode {
+						return ErrSwitchNoConditionMet
+					}
+					return nil
+				}
+			}
+
 			routedAtLeastOnce := false
 			for j, exe := range o.checks {
 				test := true
@@ -0,0 +1,224 @@
+package pure
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cefHeaderFields = []string{
+	"device_vendor", "device_product", "device_version", "signature_id", "name", "severity",
+}
+
+// Extension keys of the CEF dictionary that aren't strings, all others are
+// parsed as strings.
+var (
+	cefIntKeys = map[string]struct{}{
+		"cn1": {}, "cn2": {}, "cn3": {}, "cnt": {}, "dpid": {}, "dpt": {},
+		"dvcpid": {}, "fsize": {}, "in": {}, "oldFileSize": {}, "out": {},
+		"spid": {}, "spt": {}, "type": {}, "sourceTranslatedPort": {},
+		"destinationTranslatedPort": {}, "sourceServicePort": {},
+		"destinationServicePort": {}, "eventId": {}, "agentTranslatedZoneKey": {},
+		"customerKey": {}, "dvcTranslatedZoneKey": {},
+	}
+	cefFloatKeys = map[string]struct{}{
+		"cfp1": {}, "cfp2": {}, "cfp3": {}, "cfp4": {},
+		"dlat": {}, "dlong": {}, "slat": {}, "slong": {},
+	}
+	cefTimeKeys = map[string]struct{}{
+		"art": {}, "deviceCustomDate1": {}, "deviceCustomDate2": {}, "end": {},
+		"fileCreateTime": {}, "fileModificationTime": {}, "flexDate1": {},
+		"oldFileCreateTime": {}, "oldFileModificationTime": {}, "rt": {}, "start": {},
+	}
+)
+
+// Layouts of the timestamps of the CEF dictionary, other than milliseconds
+// since the epoch.
+var cefTimeLayouts = []string{
+	"Jan 02 2006 15:04:05.000 MST",
+	"Jan 02 2006 15:04:05 MST",
+	"Jan 02 2006 15:04:05.000",
+	"Jan 02 2006 15:04:05",
+	"Jan 02 15:04:05.000 MST",
+	"Jan 02 15:04:05 MST",
+	"Jan 02 15:04:05.000",
+	"Jan 02 15:04:05",
+}
+
+// parserCEF parses ArcSight Common Event Format messages, optionally preceded
+// by a syslog header.
+func parserCEF(bestEffort bool) parserFormat {
+	return func(body []byte) (map[string]any, error) {
+		start := bytes.Index(body, []byte("CEF:"))
+		if start == -1 {
+			return nil, errors.New("message does not contain a CEF header")
+		}
+		s := string(body[start+4:])
+
+		resMap := map[string]any{}
+		if start > 0 {
+			resMap["syslog_header"] = strings.TrimSpace(string(body[:start]))
+		}
+
+		header, rest, complete := splitEscapedHeader(s, '|', len(cefHeaderFields)+1)
+		version, err := strconv.Atoi(header[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CEF version: %v", header[0])
+		}
+		resMap["cef_version"] = version
+
+		if !complete && !bestEffort {
+			return nil, fmt.Errorf("expected %v CEF header fields, found %v", len(cefHeaderFields)+1, len(header))
+		}
+		for i, v := range header[1:] {
+			if i >= len(cefHeaderFields) {
+				break
+			}
+			resMap[cefHeaderFields[i]] = v
+		}
+		if sev, ok := resMap["severity"].(string); ok {
+			if i, err := strconv.Atoi(sev); err == nil {
+				resMap["severity"] = i
+			}
+		}
+
+		extensions := map[string]any{}
+		for _, kv := range parseCEFExtensions(rest) {
+			extensions[kv[0]] = cefTypedValue(kv[0], kv[1])
+		}
+		resMap["extensions"] = extensions
+		return resMap, nil
+	}
+}
+
+// splitEscapedHeader splits the first n fields delimited by sep, where the
+// delimiter and backslash can be escaped with a backslash. The remainder after
+// the last delimiter is returned along with whether all n fields were found.
+func splitEscapedHeader(s string, sep byte, n int) (fields []string, rest string, complete bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && (s[i+1] == sep || s[i+1] == '\\') {
+			sb.WriteByte(s[i+1])
+			i++
+			continue
+		}
+		if c == sep {
+			fields = append(fields, sb.String())
+			sb.Reset()
+			if len(fields) == n {
+				return fields, s[i+1:], true
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return append(fields, sb.String()), "", false
+}
+
+// parseCEFExtensions parses the space delimited key value pairs of a CEF
+// extension, where values may contain spaces, and a key begins after the last
+// space preceding each unescaped equals sign.
+func parseCEFExtensions(s string) (pairs [][2]string) {
+	s = strings.TrimSpace(s)
+
+	// Locate the keys, which are the words that precede unescaped equals signs.
+	type keySpan struct{ start, eq int }
+	var keys []keySpan
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			ks := strings.LastIndexByte(s[:i], ' ') + 1
+			if len(keys) > 0 && ks <= keys[len(keys)-1].eq {
+				// An unescaped equals sign within a value.
+				continue
+			}
+			if isCEFKey(s[ks:i]) {
+				keys = append(keys, keySpan{start: ks, eq: i})
+			}
+		}
+	}
+
+	for i, k := range keys {
+		valueEnd := len(s)
+		if i+1 < len(keys) {
+			valueEnd = keys[i+1].start
+		}
+		pairs = append(pairs, [2]string{s[k.start:k.eq], unescapeCEFValue(strings.TrimSpace(s[k.eq+1 : valueEnd]))})
+	}
+	return
+}
+
+func isCEFKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' && c != '.' && c != '-' && c != '[' && c != ']' {
+			return false
+		}
+	}
+	return true
+}
+
+func unescapeCEFValue(s string) string {
+	if strings.IndexByte(s, '\\') == -1 {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// cefTypedValue converts the value of an extension key of the CEF dictionary
+// to its type, where values that fail to convert remain strings.
+func cefTypedValue(key, value string) any {
+	if _, ok := cefIntKeys[key]; ok {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	} else if _, ok := cefFloatKeys[key]; ok {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	} else if _, ok := cefTimeKeys[key]; ok {
+		if t, ok := parseCEFTime(value); ok {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return value
+}
+
+func parseCEFTime(value string) (time.Time, bool) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), true
+	}
+	for _, layout := range cefTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if t.Year() == 0 {
+				t = t.AddDate(time.Now().Year(), 0, 0)
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
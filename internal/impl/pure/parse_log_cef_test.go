@@ -0,0 +1,95 @@
+package pure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCEF(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		bestEffort  bool
+		output      map[string]any
+		errContains string
+	}{
+		{
+			name:  "syslog header and typed extensions",
+			input: `<134>Sep 19 08:26:10 host CEF:0|Security|threatmanager|1.0|100|detected a \| in message|10|src=10.0.0.1 act=blocked a \\ spt=1232 cfp1=1.5 rt=1600000000000 msg=line one\nline two cs1Label=foo`,
+			output: map[string]any{
+				"syslog_header":  "<134>Sep 19 08:26:10 host",
+				"cef_version":    0,
+				"device_vendor":  "Security",
+				"device_product": "threatmanager",
+				"device_version": "1.0",
+				"signature_id":   "100",
+				"name":           "detected a | in message",
+				"severity":       10,
+				"extensions": map[string]any{
+					"src":      "10.0.0.1",
+					"act":      `blocked a \`,
+					"spt":      int64(1232),
+					"cfp1":     1.5,
+					"rt":       "2020-09-13T12:26:40Z",
+					"msg":      "line one\nline two",
+					"cs1Label": "foo",
+				},
+			},
+		},
+		{
+			name:  "non numeric severity and unconvertible values",
+			input: `CEF:1|Vendor|Product|2|sig|name|High|spt=http start=Jan 02 2021 15:04:05 UTC`,
+			output: map[string]any{
+				"cef_version":    1,
+				"device_vendor":  "Vendor",
+				"device_product": "Product",
+				"device_version": "2",
+				"signature_id":   "sig",
+				"name":           "name",
+				"severity":       "High",
+				"extensions": map[string]any{
+					"spt":   "http",
+					"start": "2021-01-02T15:04:05Z",
+				},
+			},
+		},
+		{
+			name:        "truncated header",
+			input:       `CEF:0|Vendor|Product|1.0`,
+			errContains: "expected 7 CEF header fields",
+		},
+		{
+			name:       "truncated header best effort",
+			input:      `CEF:0|Vendor|Product|1.0`,
+			bestEffort: true,
+			output: map[string]any{
+				"cef_version":    0,
+				"device_vendor":  "Vendor",
+				"device_product": "Product",
+				"device_version": "1.0",
+				"extensions":     map[string]any{},
+			},
+		},
+		{
+			name:        "not cef",
+			input:       `hello world`,
+			errContains: "does not contain a CEF header",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := parserCEF(test.bestEffort)([]byte(test.input))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package pure
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var leefHeaderFields = []string{
+	"vendor", "product", "product_version", "event_id",
+}
+
+// Predefined attributes of LEEF that aren't strings, all others are parsed as
+// strings.
+var leefIntKeys = map[string]struct{}{
+	"sev": {}, "srcPort": {}, "dstPort": {}, "srcPreNATPort": {},
+	"dstPreNATPort": {}, "srcPostNATPort": {}, "dstPostNATPort": {},
+	"srcBytes": {}, "dstBytes": {}, "totalBytes": {}, "srcPackets": {},
+	"dstPackets": {}, "totalPackets": {},
+}
+
+// parserLEEF parses IBM QRadar Log Event Extended Format messages of versions
+// 1.0 and 2.0, optionally preceded by a syslog header.
+func parserLEEF(bestEffort bool) parserFormat {
+	return func(body []byte) (map[string]any, error) {
+		start := bytes.Index(body, []byte("LEEF:"))
+		if start == -1 {
+			return nil, errors.New("message does not contain a LEEF header")
+		}
+		s := string(body[start+5:])
+
+		resMap := map[string]any{}
+		if start > 0 {
+			resMap["syslog_header"] = strings.TrimSpace(string(body[:start]))
+		}
+
+		versionEnd := strings.IndexByte(s, '|')
+		if versionEnd == -1 {
+			return nil, errors.New("LEEF header is missing a version")
+		}
+		version := s[:versionEnd]
+		fieldCount := len(leefHeaderFields)
+		switch version {
+		case "1.0":
+		case "2.0":
+			// Version 2.0 specifies the attribute delimiter after the event ID.
+			fieldCount++
+		default:
+			return nil, fmt.Errorf("LEEF version %v is not supported", version)
+		}
+		resMap["leef_version"] = version
+
+		header, rest, complete := splitEscapedHeader(s[versionEnd+1:], '|', fieldCount)
+		if !complete && !bestEffort {
+			return nil, fmt.Errorf("expected %v LEEF header fields, found %v", fieldCount+1, len(header)+1)
+		}
+		for i, v := range header {
+			if i >= len(leefHeaderFields) {
+				break
+			}
+			resMap[leefHeaderFields[i]] = v
+		}
+
+		delim := "\t"
+		if version == "2.0" && len(header) == fieldCount {
+			var err error
+			if delim, err = leefDelimiter(header[fieldCount-1]); err != nil {
+				if !bestEffort {
+					return nil, err
+				}
+				delim = "\t"
+			}
+		}
+
+		attributes := map[string]any{}
+		for _, kv := range strings.Split(rest, delim) {
+			if kv == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				if !bestEffort {
+					return nil, fmt.Errorf("LEEF attribute is missing a value: %v", kv)
+				}
+				continue
+			}
+			attributes[key] = leefTypedValue(key, value)
+		}
+		resMap["attributes"] = attributes
+		return resMap, nil
+	}
+}
+
+// leefDelimiter parses the attribute delimiter of a LEEF 2.0 header, which is
+// either a single character or its hex representation such as x09 or 0x09.
+func leefDelimiter(s string) (string, error) {
+	switch {
+	case s == "":
+		return "\t", nil
+	case len(s) == 1:
+		return s, nil
+	}
+	lower := strings.ToLower(s)
+	if hexStr := strings.TrimPrefix(strings.TrimPrefix(lower, "0x"), "x"); hexStr != lower {
+		if c, err := strconv.ParseUint(hexStr, 16, 32); err == nil {
+			return string(rune(c)), nil
+		}
+	}
+	return "", fmt.Errorf("invalid LEEF attribute delimiter: %v", s)
+}
+
+func leefTypedValue(key, value string) any {
+	if _, ok := leefIntKeys[key]; ok {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	}
+	return value
+}
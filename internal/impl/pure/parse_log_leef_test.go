@@ -0,0 +1,111 @@
+package pure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLEEF(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		bestEffort  bool
+		output      map[string]any
+		errContains string
+	}{
+		{
+			name:  "version 1 with syslog header",
+			input: "Jan 18 11:07:53 host LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=10.50.1.1\tdstPort=443\tusrName=joe=bloggs",
+			output: map[string]any{
+				"syslog_header":   "Jan 18 11:07:53 host",
+				"leef_version":    "1.0",
+				"vendor":          "Microsoft",
+				"product":         "MSExchange",
+				"product_version": "4.0 SP1",
+				"event_id":        "15345",
+				"attributes": map[string]any{
+					"src":     "10.50.1.1",
+					"dstPort": int64(443),
+					"usrName": "joe=bloggs",
+				},
+			},
+		},
+		{
+			name:  "version 2 with hex delimiter",
+			input: "LEEF:2.0|Lancope|StealthWatch|1.0|41|0x5e|src=10.0.1.8^sev=high^dst=10.0.0.5",
+			output: map[string]any{
+				"leef_version":    "2.0",
+				"vendor":          "Lancope",
+				"product":         "StealthWatch",
+				"product_version": "1.0",
+				"event_id":        "41",
+				"attributes": map[string]any{
+					"src": "10.0.1.8",
+					"sev": "high",
+					"dst": "10.0.0.5",
+				},
+			},
+		},
+		{
+			name:  "version 2 with character delimiter",
+			input: "LEEF:2.0|Vendor|Product|1.0|41|;|src=10.0.1.8;sev=3",
+			output: map[string]any{
+				"leef_version":    "2.0",
+				"vendor":          "Vendor",
+				"product":         "Product",
+				"product_version": "1.0",
+				"event_id":        "41",
+				"attributes": map[string]any{
+					"src": "10.0.1.8",
+					"sev": int64(3),
+				},
+			},
+		},
+		{
+			name:        "invalid delimiter",
+			input:       "LEEF:2.0|Vendor|Product|1.0|41|foo|src=10.0.1.8",
+			errContains: "invalid LEEF attribute delimiter",
+		},
+		{
+			name:        "unsupported version",
+			input:       "LEEF:3.0|Vendor|Product|1.0|41|src=10.0.1.8",
+			errContains: "LEEF version 3.0 is not supported",
+		},
+		{
+			name:        "attribute without value",
+			input:       "LEEF:1.0|Vendor|Product|1.0|41|src=10.0.1.8\tfoo",
+			errContains: "missing a value",
+		},
+		{
+			name:       "attribute without value best effort",
+			input:      "LEEF:1.0|Vendor|Product|1.0|41|src=10.0.1.8\tfoo",
+			bestEffort: true,
+			output: map[string]any{
+				"leef_version":    "1.0",
+				"vendor":          "Vendor",
+				"product":         "Product",
+				"product_version": "1.0",
+				"event_id":        "41",
+				"attributes": map[string]any{
+					"src": "10.0.1.8",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res, err := parserLEEF(test.bestEffort)([]byte(test.input))
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+}
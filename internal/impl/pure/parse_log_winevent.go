@@ -0,0 +1,268 @@
+package pure
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type winEventXML struct {
+	XMLName xml.Name `xml:"Event"`
+	System  struct {
+		Provider struct {
+			Name            string `xml:"Name,attr"`
+			GUID            string `xml:"Guid,attr"`
+			EventSourceName string `xml:"EventSourceName,attr"`
+		} `xml:"Provider"`
+		EventID struct {
+			Value      string `xml:",chardata"`
+			Qualifiers string `xml:"Qualifiers,attr"`
+		} `xml:"EventID"`
+		Version     string `xml:"Version"`
+		Level       string `xml:"Level"`
+		Task        string `xml:"Task"`
+		Opcode      string `xml:"Opcode"`
+		Keywords    string `xml:"Keywords"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID string `xml:"EventRecordID"`
+		Correlation   struct {
+			ActivityID        string `xml:"ActivityID,attr"`
+			RelatedActivityID string `xml:"RelatedActivityID,attr"`
+		} `xml:"Correlation"`
+		Execution struct {
+			ProcessID string `xml:"ProcessID,attr"`
+			ThreadID  string `xml:"ThreadID,attr"`
+		} `xml:"Execution"`
+		Channel  string `xml:"Channel"`
+		Computer string `xml:"Computer"`
+		Security struct {
+			UserID string `xml:"UserID,attr"`
+		} `xml:"Security"`
+	} `xml:"System"`
+	EventData *struct {
+		Data []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Data"`
+		Binary string `xml:"Binary"`
+	} `xml:"EventData"`
+	UserData *struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"UserData"`
+	RenderingInfo *struct {
+		Culture  string   `xml:"Culture,attr"`
+		Message  string   `xml:"Message"`
+		Level    string   `xml:"Level"`
+		Task     string   `xml:"Task"`
+		Opcode   string   `xml:"Opcode"`
+		Channel  string   `xml:"Channel"`
+		Provider string   `xml:"Provider"`
+		Keywords []string `xml:"Keywords>Keyword"`
+	} `xml:"RenderingInfo"`
+}
+
+// parserWindowsEventXML parses events of the Windows Event Log in their XML
+// representation.
+func parserWindowsEventXML(bestEffort bool) parserFormat {
+	return func(body []byte) (map[string]any, error) {
+		var event winEventXML
+		if err := xml.Unmarshal(body, &event); err != nil {
+			return nil, err
+		}
+
+		typed := func(m map[string]any, key, value string, parseInt func(string) (int64, error)) error {
+			if value == "" {
+				return nil
+			}
+			i, err := parseInt(value)
+			if err != nil {
+				if !bestEffort {
+					return fmt.Errorf("failed to parse %v: %w", key, err)
+				}
+				m[key] = value
+				return nil
+			}
+			m[key] = i
+			return nil
+		}
+		decimal := func(s string) (int64, error) {
+			return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		}
+
+		sys := event.System
+		system := map[string]any{}
+		setString(system, "channel", sys.Channel)
+		setString(system, "computer", sys.Computer)
+		setString(system, "keywords", sys.Keywords)
+		setString(system, "time_created", sys.TimeCreated.SystemTime)
+
+		provider := map[string]any{}
+		setString(provider, "name", sys.Provider.Name)
+		setString(provider, "guid", sys.Provider.GUID)
+		setString(provider, "event_source_name", sys.Provider.EventSourceName)
+		if len(provider) > 0 {
+			system["provider"] = provider
+		}
+
+		correlation := map[string]any{}
+		setString(correlation, "activity_id", sys.Correlation.ActivityID)
+		setString(correlation, "related_activity_id", sys.Correlation.RelatedActivityID)
+		if len(correlation) > 0 {
+			system["correlation"] = correlation
+		}
+
+		if sys.Security.UserID != "" {
+			system["security"] = map[string]any{"user_id": sys.Security.UserID}
+		}
+
+		execution := map[string]any{}
+		for _, f := range []struct {
+			key, value string
+			parse      func(string) (int64, error)
+			dst        map[string]any
+		}{
+			{key: "event_id", value: sys.EventID.Value, parse: decimal, dst: system},
+			{key: "event_id_qualifiers", value: sys.EventID.Qualifiers, parse: decimal, dst: system},
+			{key: "version", value: sys.Version, parse: decimal, dst: system},
+			{key: "level", value: sys.Level, parse: decimal, dst: system},
+			{key: "task", value: sys.Task, parse: decimal, dst: system},
+			{key: "opcode", value: sys.Opcode, parse: decimal, dst: system},
+			{key: "event_record_id", value: sys.EventRecordID, parse: decimal, dst: system},
+			{key: "process_id", value: sys.Execution.ProcessID, parse: decimal, dst: execution},
+			{key: "thread_id", value: sys.Execution.ThreadID, parse: decimal, dst: execution},
+		} {
+			if err := typed(f.dst, f.key, f.value, f.parse); err != nil {
+				return nil, err
+			}
+		}
+		if len(execution) > 0 {
+			system["execution"] = execution
+		}
+
+		resMap := map[string]any{"system": system}
+
+		if event.EventData != nil {
+			eventData := map[string]any{}
+			var unnamed int
+			for _, d := range event.EventData.Data {
+				name := d.Name
+				if name == "" {
+					unnamed++
+					name = "param" + strconv.Itoa(unnamed)
+				}
+				eventData[name] = d.Value
+			}
+			setString(eventData, "binary", event.EventData.Binary)
+			resMap["event_data"] = eventData
+		}
+
+		if event.UserData != nil {
+			userData, err := xmlElementsToMap(event.UserData.Inner)
+			if err != nil {
+				if !bestEffort {
+					return nil, fmt.Errorf("failed to parse user data: %w", err)
+				}
+			} else {
+				resMap["user_data"] = userData
+			}
+		}
+
+		if ri := event.RenderingInfo; ri != nil {
+			renderingInfo := map[string]any{}
+			setString(renderingInfo, "culture", ri.Culture)
+			setString(renderingInfo, "message", ri.Message)
+			setString(renderingInfo, "level", ri.Level)
+			setString(renderingInfo, "task", ri.Task)
+			setString(renderingInfo, "opcode", ri.Opcode)
+			setString(renderingInfo, "channel", ri.Channel)
+			setString(renderingInfo, "provider", ri.Provider)
+			if len(ri.Keywords) > 0 {
+				keywords := make([]any, 0, len(ri.Keywords))
+				for _, k := range ri.Keywords {
+					keywords = append(keywords, k)
+				}
+				renderingInfo["keywords"] = keywords
+			}
+			resMap["rendering_info"] = renderingInfo
+		}
+		return resMap, nil
+	}
+}
+
+func setString(m map[string]any, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		m[key] = value
+	}
+}
+
+// xmlElementsToMap converts a sequence of XML elements into a map of element
+// names to their contents, where elements containing only text are strings
+// and attributes are ignored. Repeated elements become arrays.
+func xmlElementsToMap(b []byte) (map[string]any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	res := map[string]any{}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := xmlElementValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			addXMLValue(res, start.Name.Local, v)
+		}
+	}
+}
+
+func xmlElementValue(dec *xml.Decoder) (any, error) {
+	var text strings.Builder
+	var children map[string]any
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := xmlElementValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = map[string]any{}
+			}
+			addXMLValue(children, t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+func addXMLValue(m map[string]any, key string, v any) {
+	existing, exists := m[key]
+	if !exists {
+		m[key] = v
+		return
+	}
+	if arr, ok := existing.([]any); ok {
+		m[key] = append(arr, v)
+		return
+	}
+	m[key] = []any{existing, v}
+}
@@ -0,0 +1,144 @@
+package pure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWindowsEventXML(t *testing.T) {
+	input := `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+  <System>
+    <Provider Name="Microsoft-Windows-Security-Auditing" Guid="{54849625-5478-4994-a5ba-3e3b0328c30d}"/>
+    <EventID>4624</EventID>
+    <Version>2</Version>
+    <Level>0</Level>
+    <Task>12544</Task>
+    <Opcode>0</Opcode>
+    <Keywords>0x8020000000000000</Keywords>
+    <TimeCreated SystemTime="2022-10-10T12:00:00.1234567Z"/>
+    <EventRecordID>123456</EventRecordID>
+    <Correlation ActivityID="{00000000-0000-0000-0000-000000000000}"/>
+    <Execution ProcessID="640" ThreadID="720"/>
+    <Channel>Security</Channel>
+    <Computer>DC01.example.com</Computer>
+    <Security/>
+  </System>
+  <EventData>
+    <Data Name="TargetUserName">bob</Data>
+    <Data Name="LogonType">3</Data>
+  </EventData>
+  <RenderingInfo Culture="en-US">
+    <Message>An account was successfully logged on.</Message>
+    <Level>Information</Level>
+    <Keywords><Keyword>Audit Success</Keyword></Keywords>
+  </RenderingInfo>
+</Event>`
+
+	res, err := parserWindowsEventXML(false)([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"system": map[string]any{
+			"provider": map[string]any{
+				"name": "Microsoft-Windows-Security-Auditing",
+				"guid": "{54849625-5478-4994-a5ba-3e3b0328c30d}",
+			},
+			"event_id":        int64(4624),
+			"version":         int64(2),
+			"level":           int64(0),
+			"task":            int64(12544),
+			"opcode":          int64(0),
+			"keywords":        "0x8020000000000000",
+			"time_created":    "2022-10-10T12:00:00.1234567Z",
+			"event_record_id": int64(123456),
+			"correlation": map[string]any{
+				"activity_id": "{00000000-0000-0000-0000-000000000000}",
+			},
+			"execution": map[string]any{
+				"process_id": int64(640),
+				"thread_id":  int64(720),
+			},
+			"channel":  "Security",
+			"computer": "DC01.example.com",
+		},
+		"event_data": map[string]any{
+			"TargetUserName": "bob",
+			"LogonType":      "3",
+		},
+		"rendering_info": map[string]any{
+			"culture":  "en-US",
+			"message":  "An account was successfully logged on.",
+			"level":    "Information",
+			"keywords": []any{"Audit Success"},
+		},
+	}, res)
+}
+
+func TestParseWindowsEventXMLClassic(t *testing.T) {
+	input := `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">
+  <System>
+    <Provider Name="Service Control Manager" EventSourceName="Service Control Manager"/>
+    <EventID Qualifiers="16384">7036</EventID>
+    <Security UserID="S-1-5-18"/>
+  </System>
+  <EventData>
+    <Data>Windows Update</Data>
+    <Data>running</Data>
+    <Binary>770075006100</Binary>
+  </EventData>
+  <UserData>
+    <LogFileCleared xmlns="http://manifests.microsoft.com/win/2004/08/windows/eventlog">
+      <SubjectUserName>admin</SubjectUserName>
+      <Group><Member>a</Member><Member>b</Member></Group>
+    </LogFileCleared>
+  </UserData>
+</Event>`
+
+	res, err := parserWindowsEventXML(false)([]byte(input))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"system": map[string]any{
+			"provider": map[string]any{
+				"name":              "Service Control Manager",
+				"event_source_name": "Service Control Manager",
+			},
+			"event_id":            int64(7036),
+			"event_id_qualifiers": int64(16384),
+			"security": map[string]any{
+				"user_id": "S-1-5-18",
+			},
+		},
+		"event_data": map[string]any{
+			"param1": "Windows Update",
+			"param2": "running",
+			"binary": "770075006100",
+		},
+		"user_data": map[string]any{
+			"LogFileCleared": map[string]any{
+				"SubjectUserName": "admin",
+				"Group": map[string]any{
+					"Member": []any{"a", "b"},
+				},
+			},
+		},
+	}, res)
+}
+
+func TestParseWindowsEventXMLErrors(t *testing.T) {
+	_, err := parserWindowsEventXML(false)([]byte(`<Event><System><EventID>nope</EventID></System></Event>`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse event_id")
+
+	res, err := parserWindowsEventXML(true)([]byte(`<Event><System><EventID>nope</EventID></System></Event>`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"system": map[string]any{"event_id": "nope"},
+	}, res)
+
+	_, err = parserWindowsEventXML(true)([]byte(`not xml`))
+	require.Error(t, err)
+
+	_, err = parserWindowsEventXML(true)([]byte(`<Other/>`))
+	require.Error(t, err)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"golang.org/x/sync/singleflight"
+
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -33,7 +35,11 @@ Performs operations against a [cache resource](/docs/components/caches/about) fo
 		Description: `
 For use cases where you wish to cache the result of processors consider using the ` + "[`cached` processor](/docs/components/processors/cached)" + ` instead.
 
-This processor will interpolate functions within the ` + "`key` and `value`" + ` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).`,
+This processor will interpolate functions within the ` + "`key` and `value`" + ` fields individually for each message. This allows you to specify dynamic keys and values based on the contents of the message payloads and metadata. You can find a list of functions [here](/docs/configuration/interpolation#bloblang-queries).
+
+## Batching
+
+When processing a batch of multiple messages the ` + "`get`, `set` and `add`" + ` operators are performed for all messages in a single multiple key operation where the cache supports it, such as pipelined commands for ` + "`redis`" + ` or multi-gets for ` + "`memcached`" + `, rather than a round trip per message. The results are the same as when each message is processed in order: the last value of a key wins for ` + "`set`" + `, and only the first message of a key can succeed for ` + "`add`" + `. The ` + "`delete`" + ` operator, and ` + "`get`" + ` with ` + "`singleflight`" + ` enabled, are always performed per message.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("resource", "The [`cache` resource](/docs/components/caches/about) to target with this processor."),
 			docs.FieldString("operator", "The [operation](#operators) to perform with the cache.").HasOptions("set", "add", "get", "delete"),
@@ -43,6 +49,7 @@ This processor will interpolate functions within the ` + "`key` and `value`" + `
 				"ttl", "The TTL of each individual item as a duration string. After this period an item will be eligible for removal during the next compaction. Not all caches support per-key TTLs, those that do will have a configuration field `default_ttl`, and those that do not will fall back to their generally configured TTL setting.",
 				"60s", "5m", "36h",
 			).IsInterpolated().AtVersion("3.33.0").Advanced(),
+			docs.FieldBool("singleflight", "Whether concurrent `get` operations of the same key against the same cache resource should be coalesced into a single lookup, where the result is shared with each message. This applies across all processors targeting the cache and reduces the load on the cache during bursts of lookups for a hot key. Other operators are not coalesced.").Advanced().AtVersion("4.9.0"),
 		).ChildDefaultAndTypesFromStruct(processor.NewCacheConfig()),
 		Examples: []docs.AnnotatedExample{
 			{
@@ -152,13 +159,21 @@ action is a no-op and will not fail with an error.`,
 
 //------------------------------------------------------------------------------
 
+// cacheGets coalesces concurrent get operations of cache processors, keys are
+// prefixed with the address of the cache so that caches sharing a label across
+// streams are kept distinct.
+var cacheGets singleflight.Group
+
 type cacheProc struct {
 	key   *field.Expression
 	value *field.Expression
 	ttl   *field.Expression
 
+	singleflight bool
+
 	mgr       bundle.NewManagement
 	cacheName string
+	opName    string
 	operator  cacheOperator
 }
 
@@ -197,8 +212,11 @@ func newCache(conf processor.CacheConfig, mgr bundle.NewManagement) (*cacheProc,
 		value: value,
 		ttl:   ttl,
 
+		singleflight: conf.Singleflight && conf.Operator == "get",
+
 		mgr:       mgr,
 		cacheName: cacheName,
+		opName:    conf.Operator,
 		operator:  op,
 	}, nil
 }
@@ -252,6 +270,11 @@ func cacheOperatorFromString(operator string) (cacheOperator, error) {
 //------------------------------------------------------------------------------
 
 func (c *cacheProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg message.Batch) ([]message.Batch, error) {
+	if msg.Len() > 1 && !c.singleflight && c.opName != "delete" {
+		c.processBatched(spans, msg)
+		return []message.Batch{msg}, nil
+	}
+
 	_ = msg.Iter(func(index int, part *message.Part) error {
 		key := c.key.String(index, msg)
 		value := c.value.Bytes(index, msg)
@@ -271,7 +294,19 @@ func (c *cacheProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 		var useResult bool
 		var err error
 		if cerr := c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
-			result, useResult, err = c.operator(context.Background(), cache, key, value, ttl)
+			if !c.singleflight {
+				result, useResult, err = c.operator(context.Background(), cache, key, value, ttl)
+				return
+			}
+			var v any
+			var shared bool
+			v, err, shared = cacheGets.Do(fmt.Sprintf("%p\x00%v", cache, key), func() (any, error) {
+				result, _, err := c.operator(context.Background(), cache, key, value, ttl)
+				return result, err
+			})
+			if result, useResult = v.([]byte), true; shared && result != nil {
+				result = append([]byte(nil), result...)
+			}
 		}); cerr != nil {
 			err = cerr
 		}
@@ -294,6 +329,149 @@ func (c *cacheProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 	return []message.Batch{msg}, nil
 }
 
+// processBatched performs the operator for all messages of a batch with a
+// single multiple key operation against the cache, rather than a round trip
+// per message.
+func (c *cacheProc) processBatched(spans []*tracing.Span, msg message.Batch) {
+	keys := make([]string, msg.Len())
+	ttls := make([]*time.Duration, msg.Len())
+	valid := make([]bool, msg.Len())
+	_ = msg.Iter(func(index int, part *message.Part) error {
+		keys[index] = c.key.String(index, msg)
+		if ttlStr := c.ttl.String(index, msg); ttlStr != "" {
+			td, err := time.ParseDuration(ttlStr)
+			if err != nil {
+				c.mgr.Logger().Debugf("TTL must be a duration: %v\n", err)
+				processor.MarkErr(part, spans[index], err)
+				return nil
+			}
+			ttls[index] = &td
+		}
+		valid[index] = true
+		return nil
+	})
+
+	markAll := func(err error) {
+		for i, ok := range valid {
+			if ok {
+				c.mgr.Logger().Debugf("Operator failed for key '%s': %v\n", keys[i], err)
+				processor.MarkErr(msg.Get(i), spans[i], err)
+			}
+		}
+	}
+
+	var cerr error
+	switch c.opName {
+	case "get":
+		var getKeys []string
+		seen := map[string]struct{}{}
+		for i, ok := range valid {
+			if _, exists := seen[keys[i]]; ok && !exists {
+				seen[keys[i]] = struct{}{}
+				getKeys = append(getKeys, keys[i])
+			}
+		}
+		if len(getKeys) == 0 {
+			return
+		}
+
+		var values map[string][]byte
+		var err error
+		if cerr = c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
+			values, err = cache.GetMulti(context.Background(), getKeys...)
+		}); cerr == nil && err != nil {
+			cerr = err
+		}
+		if cerr != nil {
+			break
+		}
+
+		used := map[string]struct{}{}
+		for i, ok := range valid {
+			if !ok {
+				continue
+			}
+			v, exists := values[keys[i]]
+			if !exists {
+				c.mgr.Logger().Debugf("Operator failed for key '%s': %v\n", keys[i], component.ErrKeyNotFound)
+				processor.MarkErr(msg.Get(i), spans[i], component.ErrKeyNotFound)
+				continue
+			}
+			if _, shared := used[keys[i]]; shared {
+				v = append([]byte(nil), v...)
+			}
+			used[keys[i]] = struct{}{}
+			msg.Get(i).SetBytes(v)
+		}
+	case "set":
+		// When a key is set by multiple messages the last value wins, as it
+		// would when setting each message sequentially.
+		items := map[string]cache.TTLItem{}
+		for i, ok := range valid {
+			if ok {
+				items[keys[i]] = cache.TTLItem{Value: c.value.Bytes(i, msg), TTL: ttls[i]}
+			}
+		}
+		if len(items) == 0 {
+			return
+		}
+
+		var err error
+		if cerr = c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
+			err = cache.SetMulti(context.Background(), items)
+		}); cerr == nil {
+			cerr = err
+		}
+	case "add":
+		// When a key is added by multiple messages the first wins, and the
+		// remaining messages fail as they would when added sequentially.
+		items := map[string]cache.TTLItem{}
+		firsts := map[string]int{}
+		for i, ok := range valid {
+			if !ok {
+				continue
+			}
+			if _, exists := firsts[keys[i]]; exists {
+				continue
+			}
+			firsts[keys[i]] = i
+			items[keys[i]] = cache.TTLItem{Value: c.value.Bytes(i, msg), TTL: ttls[i]}
+		}
+		if len(items) == 0 {
+			return
+		}
+
+		var errs map[string]error
+		if cerr = c.mgr.AccessCache(context.Background(), c.cacheName, func(cache cache.V1) {
+			errs = cache.AddMulti(context.Background(), items)
+		}); cerr != nil {
+			break
+		}
+
+		for i, ok := range valid {
+			if !ok {
+				continue
+			}
+			err := errs[keys[i]]
+			if firsts[keys[i]] != i {
+				err = component.ErrKeyAlreadyExists
+			}
+			if err == nil {
+				continue
+			}
+			if err != component.ErrKeyAlreadyExists {
+				c.mgr.Logger().Debugf("Operator failed for key '%s': %v\n", keys[i], err)
+			} else {
+				c.mgr.Logger().Debugf("Key already exists: %v\n", keys[i])
+			}
+			processor.MarkErr(msg.Get(i), spans[i], err)
+		}
+	}
+	if cerr != nil {
+		markAll(cerr)
+	}
+}
+
 func (c *cacheProc) Close(ctx context.Context) error {
 	return nil
 }
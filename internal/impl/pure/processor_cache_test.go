@@ -3,11 +3,16 @@ package pure_test
 import (
 	"context"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -202,3 +207,146 @@ func TestCacheDelete(t *testing.T) {
 	_, ok = mgr.Caches["foocache"]["3"]
 	require.False(t, ok)
 }
+
+type slowCountingCache struct {
+	*mock.Cache
+	gets    int32
+	release chan struct{}
+}
+
+func (c *slowCountingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	atomic.AddInt32(&c.gets, 1)
+	<-c.release
+	return c.Cache.Get(ctx, key)
+}
+
+type stableCacheManager struct {
+	*mock.Manager
+	cache *slowCountingCache
+}
+
+func (m *stableCacheManager) AccessCache(ctx context.Context, name string, fn func(cache.V1)) error {
+	fn(m.cache)
+	return nil
+}
+
+func TestCacheGetSingleflight(t *testing.T) {
+	mgr := &stableCacheManager{
+		Manager: mock.NewManager(),
+		cache: &slowCountingCache{
+			Cache: &mock.Cache{Values: map[string]mock.CacheItem{
+				"foo": {Value: "foo value"},
+			}},
+			release: make(chan struct{}),
+		},
+	}
+	mgr.Caches["foocache"] = mgr.cache.Values
+
+	conf := processor.NewConfig()
+	conf.Type = "cache"
+	conf.Cache.Operator = "get"
+	conf.Cache.Key = "${! content() }"
+	conf.Cache.Resource = "foocache"
+	conf.Cache.Singleflight = true
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		proc, err := bundle.AllProcessors.Init(conf, mgr)
+		require.NoError(t, err)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			output, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+			require.NoError(t, res)
+			require.Len(t, output, 1)
+			require.NoError(t, output[0].Get(0).ErrorGet())
+			results[i] = string(output[0].Get(0).AsBytes())
+		}(i)
+	}
+
+	// Wait for the first lookup to begin, and give the others a chance to join.
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&mgr.cache.gets) == 1
+	}, time.Second, time.Millisecond)
+	time.Sleep(time.Millisecond * 50)
+	close(mgr.cache.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&mgr.cache.gets))
+	for _, r := range results {
+		assert.Equal(t, "foo value", r)
+	}
+}
+
+type countingCacheManager struct {
+	*mock.Manager
+	accesses int
+}
+
+func (m *countingCacheManager) AccessCache(ctx context.Context, name string, fn func(cache.V1)) error {
+	m.accesses++
+	return m.Manager.AccessCache(ctx, name, fn)
+}
+
+func TestCacheBatchedRoundTrips(t *testing.T) {
+	mgr := &countingCacheManager{Manager: mock.NewManager()}
+	mgr.Caches["foocache"] = map[string]mock.CacheItem{
+		"1": {Value: "foo 1"},
+	}
+
+	newProc := func(operator string) processor.V1 {
+		conf := processor.NewConfig()
+		conf.Type = "cache"
+		conf.Cache.Operator = operator
+		conf.Cache.Key = "${! json(\"key\") }"
+		conf.Cache.Value = "${! json(\"value\") }"
+		conf.Cache.Resource = "foocache"
+		proc, err := bundle.AllProcessors.Init(conf, mgr)
+		require.NoError(t, err)
+		return proc
+	}
+
+	output, res := newProc("add").ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"key":"1","value":"bar 1"}`),
+		[]byte(`{"key":"2","value":"bar 2"}`),
+		[]byte(`{"key":"2","value":"bar 3"}`),
+		[]byte(`{"key":"3","value":"bar 4"}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+	assert.Equal(t, 1, mgr.accesses)
+	assert.Error(t, output[0].Get(0).ErrorGet())
+	assert.NoError(t, output[0].Get(1).ErrorGet())
+	assert.Error(t, output[0].Get(2).ErrorGet())
+	assert.NoError(t, output[0].Get(3).ErrorGet())
+
+	output, res = newProc("set").ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"key":"3","value":"baz 1"}`),
+		[]byte(`{"key":"3","value":"baz 2"}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+	assert.Equal(t, 2, mgr.accesses)
+
+	output, res = newProc("get").ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"key":"1"}`),
+		[]byte(`{"key":"2"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"3"}`),
+		[]byte(`{"key":"4"}`),
+	}))
+	require.NoError(t, res)
+	require.Len(t, output, 1)
+	assert.Equal(t, 3, mgr.accesses)
+	assert.Equal(t, [][]byte{
+		[]byte(`foo 1`),
+		[]byte(`bar 2`),
+		[]byte(`baz 2`),
+		[]byte(`baz 2`),
+		[]byte(`{"key":"4"}`),
+	}, message.GetAllBytes(output[0]))
+	assert.NoError(t, output[0].Get(3).ErrorGet())
+	assert.Error(t, output[0].Get(4).ErrorGet())
+}
@@ -7,10 +7,15 @@ import (
 	"compress/zlib"
 	"context"
 	"fmt"
+	"os"
+	"strconv"
+	"sync"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/docs"
@@ -32,12 +37,24 @@ func init() {
 		},
 		Summary: `
 Compresses messages according to the selected algorithm. Supported compression
-algorithms are: gzip, zlib, flate, snappy, lz4.`,
+algorithms are: gzip, zlib, flate, snappy, lz4, zstd.`,
 		Description: `
-The 'level' field might not apply to all algorithms.`,
+The 'level' field might not apply to all algorithms.
+
+### Dictionaries
+
+The ` + "`zstd`" + ` algorithm supports compressing messages with a dictionary, which can be trained on a sample of your data with the command ` + "`zstd --train`" + `. Dictionaries dramatically improve the compression ratio of small messages such as individual JSON documents, and data compressed with a dictionary must be decompressed with the same dictionary.`,
 		Config: docs.FieldComponent().WithChildren(
-			docs.FieldString("algorithm", "The compression algorithm to use.").HasOptions("gzip", "zlib", "flate", "snappy", "lz4"),
+			docs.FieldString("algorithm", "The compression algorithm to use.").HasOptions("gzip", "zlib", "flate", "snappy", "lz4", "zstd"),
 			docs.FieldInt("level", "The level of compression to use. May not be applicable to all algorithms."),
+			docs.FieldString(
+				"message_level", "An optional level of compression to use for each message, which overrides `level` when it resolves to a non-empty string. May not be applicable to all algorithms.",
+				`${! meta("compression_level").or("") }`,
+			).IsInterpolated().Advanced().AtVersion("4.9.0"),
+			docs.FieldString(
+				"dictionary_file", "An optional path to a dictionary file to compress messages with, which is only supported by the `zstd` algorithm.",
+				"./dicts/events.zdict",
+			).Advanced().AtVersion("4.9.0"),
 		).ChildDefaultAndTypesFromStruct(processor.NewCompressConfig()),
 	})
 	if err != nil {
@@ -121,6 +138,49 @@ func lz4Compress(level int, b []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// zstdCompressor compresses with zstd encoders that are created lazily for each
+// level of compression used.
+type zstdCompressor struct {
+	dict []byte
+
+	mut      sync.Mutex
+	encoders map[zstd.EncoderLevel]*zstd.Encoder
+}
+
+func (z *zstdCompressor) compress(level int, b []byte) ([]byte, error) {
+	encLevel := zstd.SpeedDefault
+	if level > 0 {
+		encLevel = zstd.EncoderLevelFromZstd(level)
+	}
+
+	z.mut.Lock()
+	enc, exists := z.encoders[encLevel]
+	if !exists {
+		opts := []zstd.EOption{zstd.WithEncoderLevel(encLevel)}
+		if len(z.dict) > 0 {
+			opts = append(opts, zstd.WithEncoderDict(z.dict))
+		}
+		var err error
+		if enc, err = zstd.NewWriter(nil, opts...); err != nil {
+			z.mut.Unlock()
+			return nil, err
+		}
+		z.encoders[encLevel] = enc
+	}
+	z.mut.Unlock()
+
+	return enc.EncodeAll(b, nil), nil
+}
+
+func (z *zstdCompressor) Close() {
+	z.mut.Lock()
+	defer z.mut.Unlock()
+	for _, enc := range z.encoders {
+		_ = enc.Close()
+	}
+	z.encoders = nil
+}
+
 func strToCompressor(str string) (compressFunc, error) {
 	switch str {
 	case "gzip":
@@ -138,25 +198,60 @@ func strToCompressor(str string) (compressFunc, error) {
 }
 
 type compressProc struct {
-	level int
-	comp  compressFunc
-	log   log.Modular
+	level        int
+	messageLevel *field.Expression
+	comp         compressFunc
+	zstd         *zstdCompressor
+	log          log.Modular
 }
 
 func newCompress(conf processor.CompressConfig, mgr bundle.NewManagement) (*compressProc, error) {
-	cor, err := strToCompressor(conf.Algorithm)
-	if err != nil {
-		return nil, err
-	}
-	return &compressProc{
+	c := &compressProc{
 		level: conf.Level,
-		comp:  cor,
 		log:   mgr.Logger(),
-	}, nil
+	}
+
+	if conf.Algorithm == "zstd" {
+		c.zstd = &zstdCompressor{encoders: map[zstd.EncoderLevel]*zstd.Encoder{}}
+		if conf.DictionaryFile != "" {
+			var err error
+			if c.zstd.dict, err = os.ReadFile(conf.DictionaryFile); err != nil {
+				return nil, fmt.Errorf("failed to read dictionary file: %w", err)
+			}
+		}
+		c.comp = c.zstd.compress
+	} else {
+		if conf.DictionaryFile != "" {
+			return nil, fmt.Errorf("a dictionary_file is not supported by the %v algorithm", conf.Algorithm)
+		}
+		var err error
+		if c.comp, err = strToCompressor(conf.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.MessageLevel != "" {
+		var err error
+		if c.messageLevel, err = mgr.BloblEnvironment().NewField(conf.MessageLevel); err != nil {
+			return nil, fmt.Errorf("failed to parse message_level expression: %v", err)
+		}
+	}
+	return c, nil
 }
 
 func (c *compressProc) Process(ctx context.Context, msg *message.Part) ([]*message.Part, error) {
-	newBytes, err := c.comp(c.level, msg.AsBytes())
+	level := c.level
+	if c.messageLevel != nil {
+		if levelStr := c.messageLevel.String(0, message.Batch{msg}); levelStr != "" {
+			var err error
+			if level, err = strconv.Atoi(levelStr); err != nil {
+				c.log.Errorf("Failed to parse message_level: %v\n", err)
+				return nil, fmt.Errorf("failed to parse message_level: %w", err)
+			}
+		}
+	}
+
+	newBytes, err := c.comp(level, msg.AsBytes())
 	if err != nil {
 		c.log.Errorf("Failed to compress message: %v\n", err)
 		return nil, err
@@ -166,5 +261,8 @@ func (c *compressProc) Process(ctx context.Context, msg *message.Part) ([]*messa
 }
 
 func (c *compressProc) Close(context.Context) error {
+	if c.zstd != nil {
+		c.zstd.Close()
+	}
 	return nil
 }
@@ -10,7 +10,10 @@ import (
 	"testing"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
@@ -253,3 +256,90 @@ func TestCompressLZ4(t *testing.T) {
 		t.Errorf("Unexpected output: %s != %s", act, exp)
 	}
 }
+
+func TestCompressZstdDictRoundTrip(t *testing.T) {
+	input := []byte(`{"id":5,"user":{"name":"alice","email":"user5@example.com"},"event":"login","tags":["a","b"]}`)
+
+	compConf := processor.NewConfig()
+	compConf.Type = "compress"
+	compConf.Compress.Algorithm = "zstd"
+	compConf.Compress.Level = 7
+	compConf.Compress.DictionaryFile = "./testdata/events.zdict"
+
+	decompConf := processor.NewConfig()
+	decompConf.Type = "decompress"
+	decompConf.Decompress.Algorithm = "zstd"
+	decompConf.Decompress.DictionaryFile = "./testdata/events.zdict"
+
+	plainConf := processor.NewConfig()
+	plainConf.Type = "decompress"
+	plainConf.Decompress.Algorithm = "zstd"
+
+	comp, err := mock.NewManager().NewProcessor(compConf)
+	require.NoError(t, err)
+
+	decomp, err := mock.NewManager().NewProcessor(decompConf)
+	require.NoError(t, err)
+
+	plainDecomp, err := mock.NewManager().NewProcessor(plainConf)
+	require.NoError(t, err)
+
+	msgs, res := comp.ProcessBatch(context.Background(), message.QuickBatch([][]byte{input}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	compressed := msgs[0].Get(0).AsBytes()
+
+	noDict, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(7)))
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(noDict.EncodeAll(input, nil)))
+
+	msgs, res = decomp.ProcessBatch(context.Background(), message.QuickBatch([][]byte{compressed}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, string(input), string(msgs[0].Get(0).AsBytes()))
+
+	msgs, _ = plainDecomp.ProcessBatch(context.Background(), message.QuickBatch([][]byte{compressed}))
+	require.Len(t, msgs, 1)
+	assert.Error(t, msgs[0].Get(0).ErrorGet())
+}
+
+func TestCompressMessageLevel(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "compress"
+	conf.Compress.Algorithm = "zstd"
+	conf.Compress.MessageLevel = `${! meta("level").or("") }`
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	input := bytes.Repeat([]byte("hello world this is some compressible text "), 100)
+
+	batch := message.QuickBatch([][]byte{input, input, input})
+	batch.Get(0).MetaSet("level", "1")
+	batch.Get(1).MetaSet("level", "nope")
+
+	msgs, res := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+
+	dec, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+
+	for _, i := range []int{0, 2} {
+		require.NoError(t, msgs[0].Get(i).ErrorGet())
+		decoded, err := dec.DecodeAll(msgs[0].Get(i).AsBytes(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, input, decoded)
+	}
+	assert.Error(t, msgs[0].Get(1).ErrorGet())
+}
+
+func TestCompressDictUnsupported(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "compress"
+	conf.Compress.Algorithm = "gzip"
+	conf.Compress.DictionaryFile = "./testdata/events.zdict"
+
+	_, err := mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+}
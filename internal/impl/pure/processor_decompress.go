@@ -9,8 +9,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4/v4"
 
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -34,9 +36,13 @@ func init() {
 		},
 		Summary: `
 Decompresses messages according to the selected algorithm. Supported
-decompression types are: gzip, zlib, bzip2, flate, snappy, lz4.`,
+decompression types are: gzip, zlib, bzip2, flate, snappy, lz4, zstd.`,
 		Config: docs.FieldComponent().WithChildren(
-			docs.FieldString("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy", "lz4"),
+			docs.FieldString("algorithm", "The decompression algorithm to use.").HasOptions("gzip", "zlib", "bzip2", "flate", "snappy", "lz4", "zstd"),
+			docs.FieldString(
+				"dictionary_file", "An optional path to a dictionary file that messages were compressed with, which is only supported by the `zstd` algorithm.",
+				"./dicts/events.zdict",
+			).Advanced().AtVersion("4.9.0"),
 		).ChildDefaultAndTypesFromStruct(processor.NewDecompressConfig()),
 	})
 	if err != nil {
@@ -134,18 +140,42 @@ func strToDecompressor(str string) (decompressFunc, error) {
 
 type decompressProc struct {
 	decomp decompressFunc
+	zstd   *zstd.Decoder
 	log    log.Modular
 }
 
 func newDecompress(conf processor.DecompressConfig, mgr bundle.NewManagement) (*decompressProc, error) {
-	dcor, err := strToDecompressor(conf.Algorithm)
-	if err != nil {
+	d := &decompressProc{
+		log: mgr.Logger(),
+	}
+
+	if conf.Algorithm == "zstd" {
+		var opts []zstd.DOption
+		if conf.DictionaryFile != "" {
+			dict, err := os.ReadFile(conf.DictionaryFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read dictionary file: %w", err)
+			}
+			opts = append(opts, zstd.WithDecoderDicts(dict))
+		}
+		var err error
+		if d.zstd, err = zstd.NewReader(nil, opts...); err != nil {
+			return nil, err
+		}
+		d.decomp = func(b []byte) ([]byte, error) {
+			return d.zstd.DecodeAll(b, nil)
+		}
+		return d, nil
+	}
+
+	if conf.DictionaryFile != "" {
+		return nil, fmt.Errorf("a dictionary_file is not supported by the %v algorithm", conf.Algorithm)
+	}
+	var err error
+	if d.decomp, err = strToDecompressor(conf.Algorithm); err != nil {
 		return nil, err
 	}
-	return &decompressProc{
-		decomp: dcor,
-		log:    mgr.Logger(),
-	}, nil
+	return d, nil
 }
 
 func (d *decompressProc) Process(ctx context.Context, msg *message.Part) ([]*message.Part, error) {
@@ -160,5 +190,8 @@ func (d *decompressProc) Process(ctx context.Context, msg *message.Part) ([]*mes
 }
 
 func (d *decompressProc) Close(context.Context) error {
+	if d.zstd != nil {
+		d.zstd.Close()
+	}
 	return nil
 }
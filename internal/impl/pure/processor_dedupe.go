@@ -38,6 +38,8 @@ When using this processor with an output target that might fail you should alway
 
 This processor enacts on individual messages only, in order to perform a deduplication on behalf of a batch (or window) of messages instead use the ` + "[`cache` processor](/docs/components/processors/cache#examples)" + `.
 
+When processing a batch of multiple messages the keys of all messages are added to the cache in a single multiple key operation where the cache supports it, such as pipelined commands for ` + "`redis`" + `, rather than a round trip per message. Where multiple messages of a batch share a key only the first is kept.
+
 ## Delivery Guarantees
 
 Performing deduplication on a stream using a distributed cache voids any at-least-once guarantees that it previously had. This is because the cache will preserve message signatures even if the message fails to leave the Benthos pipeline, which would cause message loss in the event of an outage at the output sink followed by a restart of the Benthos instance (or a server crash, etc).
@@ -106,17 +108,17 @@ func newDedupe(conf processor.DedupeConfig, mgr bundle.NewManagement) (*dedupePr
 //------------------------------------------------------------------------------
 
 func (d *dedupeProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, batch message.Batch) ([]message.Batch, error) {
-	newBatch := message.QuickBatch(nil)
+	keys := make([]string, batch.Len())
 	_ = batch.Iter(func(i int, p *message.Part) error {
-		key := d.key.String(i, batch)
+		keys[i] = d.key.String(i, batch)
+		return nil
+	})
 
-		var err error
-		if cerr := d.mgr.AccessCache(context.Background(), d.cacheName, func(cache cache.V1) {
-			err = cache.Add(context.Background(), key, []byte{'t'}, nil)
-		}); cerr != nil {
-			err = cerr
-		}
-		if err != nil {
+	errs := d.addKeys(keys)
+
+	newBatch := message.QuickBatch(nil)
+	_ = batch.Iter(func(i int, p *message.Part) error {
+		if err := errs[i]; err != nil {
 			if errors.Is(err, component.ErrKeyAlreadyExists) {
 				spans[i].LogKV(
 					"event", "dropped",
@@ -146,6 +148,46 @@ func (d *dedupeProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, ba
 	return []message.Batch{newBatch}, nil
 }
 
+// addKeys adds each key to the cache and returns the resulting error of each.
+// Batches of more than one message are added with a single multiple key
+// operation, where only the first message of a given key can succeed.
+func (d *dedupeProc) addKeys(keys []string) []error {
+	errs := make([]error, len(keys))
+	if len(keys) == 1 {
+		if cerr := d.mgr.AccessCache(context.Background(), d.cacheName, func(cache cache.V1) {
+			errs[0] = cache.Add(context.Background(), keys[0], []byte{'t'}, nil)
+		}); cerr != nil {
+			errs[0] = cerr
+		}
+		return errs
+	}
+
+	items := make(map[string]cache.TTLItem, len(keys))
+	firsts := make(map[string]int, len(keys))
+	for i, k := range keys {
+		if _, exists := firsts[k]; exists {
+			errs[i] = component.ErrKeyAlreadyExists
+			continue
+		}
+		firsts[k] = i
+		items[k] = cache.TTLItem{Value: []byte{'t'}}
+	}
+
+	var addErrs map[string]error
+	if cerr := d.mgr.AccessCache(context.Background(), d.cacheName, func(cache cache.V1) {
+		addErrs = cache.AddMulti(context.Background(), items)
+	}); cerr != nil {
+		for _, i := range firsts {
+			errs[i] = cerr
+		}
+		return errs
+	}
+	for k, err := range addErrs {
+		errs[firsts[k]] = err
+	}
+	return errs
+}
+
 func (d *dedupeProc) Close(context.Context) error {
 	return nil
 }
@@ -34,7 +34,42 @@ as the ` + "`value`" + ` field of the ` + "`metadata`" + ` processor to execute
 on individual message parts of a batch instead.
 
 Please note that most processors already process per message of a batch, and
-this processor is not needed in those cases.`,
+this processor is not needed in those cases.
+
+If the child processors fail to process a message then only that message is
+flagged with the error, which can be handled using the
+[standard error handling patterns](/docs/configuration/error_handling), and the
+remaining messages of the batch continue to be processed.
+
+Messages are processed sequentially by default. In order to process them with
+bounded concurrency instead, which is useful when the child processors are IO
+bound, set the ` + "`execution.mode`" + ` field of this processor to ` + "`part`" + `
+and ` + "`execution.parallel`" + ` to the maximum number of messages to process
+at once. The order of messages is preserved regardless of the order in which
+they finish.`,
+		Examples: []docs.AnnotatedExample{
+			{
+				Title: "Bounded Concurrency",
+				Summary: `
+Here we enrich each message of a batch with the result of an HTTP request,
+where up to four requests are in flight at any given time:`,
+				Config: `
+pipeline:
+  processors:
+    - for_each:
+        - branch:
+            request_map: 'root = this.id'
+            processors:
+              - http:
+                  url: http://example.com/enrich
+                  verb: POST
+            result_map: 'root.enriched = this'
+      execution:
+        mode: part
+        parallel: 4
+`,
+			},
+		},
 		Config: docs.FieldProcessor("", "").Array().HasDefault([]any{}),
 	})
 	if err != nil {
@@ -67,10 +102,17 @@ func (p *forEachProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, m
 	})
 
 	resMsg := message.QuickBatch(nil)
-	for _, tmpMsg := range individualMsgs {
+	for i, tmpMsg := range individualMsgs {
 		resultMsgs, err := processor.ExecuteAll(ctx, p.children, tmpMsg)
 		if err != nil {
-			return nil, err
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			// A failure is isolated to the message that caused it, which is
+			// flagged rather than aborting the remaining messages.
+			processor.MarkErr(tmpMsg[0], spans[i], err)
+			resMsg = append(resMsg, tmpMsg[0])
+			continue
 		}
 		for _, m := range resultMsgs {
 			_ = m.Iter(func(i int, p *message.Part) error {
@@ -2,10 +2,14 @@ package pure_test
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
@@ -179,3 +183,99 @@ func TestForEachFilterAll(t *testing.T) {
 		t.Errorf("Wrong count of result msgs: %v", len(msgs))
 	}
 }
+
+func TestForEachErrorIsolated(t *testing.T) {
+	mgr := mock.NewManager()
+	mgr.Processors["failsome"] = func(b message.Batch) ([]message.Batch, error) {
+		if string(b.Get(0).AsBytes()) == "bar" {
+			return nil, errors.New("nope")
+		}
+		b.Get(0).SetBytes([]byte("processed " + string(b.Get(0).AsBytes())))
+		return []message.Batch{b}, nil
+	}
+
+	resConf := processor.NewConfig()
+	resConf.Type = "resource"
+	resConf.Resource = "failsome"
+
+	conf := processor.NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(conf.ForEach, resConf)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("processed foo"),
+		[]byte("bar"),
+		[]byte("processed baz"),
+	}, message.GetAllBytes(msgs[0]))
+	assert.NoError(t, msgs[0].Get(0).ErrorGet())
+	assert.EqualError(t, msgs[0].Get(1).ErrorGet(), "nope")
+	assert.NoError(t, msgs[0].Get(2).ErrorGet())
+}
+
+func TestForEachBoundedConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	mgr := mock.NewManager()
+	mgr.Processors["slow"] = func(b message.Batch) ([]message.Batch, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if n <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, n) {
+				break
+			}
+		}
+
+		// Earlier messages finish last in order to check that the order of
+		// the batch is preserved.
+		content := string(b.Get(0).AsBytes())
+		time.Sleep(time.Millisecond * time.Duration(50-len(content)*5))
+		if content == "bb" {
+			return nil, errors.New("nope")
+		}
+		b.Get(0).SetBytes([]byte("processed " + content))
+		return []message.Batch{b}, nil
+	}
+
+	resConf := processor.NewConfig()
+	resConf.Type = "resource"
+	resConf.Resource = "slow"
+
+	conf := processor.NewConfig()
+	conf.Type = "for_each"
+	conf.ForEach = append(conf.ForEach, resConf)
+	conf.Execution.Mode = processor.ExecutionModePart
+	conf.Execution.Parallel = 2
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("a"),
+		[]byte("bb"),
+		[]byte("ccc"),
+		[]byte("dddd"),
+		[]byte("eeeee"),
+	}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("processed a"),
+		[]byte("bb"),
+		[]byte("processed ccc"),
+		[]byte("processed dddd"),
+		[]byte("processed eeeee"),
+	}, message.GetAllBytes(msgs[0]))
+	assert.EqualError(t, msgs[0].Get(1).ErrorGet(), "nope")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
@@ -3,6 +3,7 @@ package pure
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -29,6 +30,15 @@ func init() {
 		Description: `
 This allows you to group messages using arbitrary fields within their content or metadata, process them individually, and send them to unique locations as per their group.
 
+A composite group key can be formed by listing multiple interpolated strings in the field ` + "`values`" + `, in which case messages are grouped by the combination of all of their results. When ` + "`key_metadata`" + ` is set the group key of each message is written to the metadata key of that name, with the components of a composite key joined with ` + "`/`" + `.
+
+### Limiting Groups
+
+Batches with a large number of distinct keys can result in many small batches, which can be limited with the field ` + "`max_groups`" + `. When a message would form a group beyond this limit the ` + "`overflow_policy`" + ` determines what happens:
+
+- ` + "`flush`" + ` emits all groups formed so far and then continues grouping the remaining messages from scratch, which means messages of the same key can end up in multiple batches.
+- ` + "`spill`" + ` adds the message to a single overflow batch that is emitted after all other groups. Messages of the overflow batch do not have the ` + "`key_metadata`" + ` key set.
+
 The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`,
 		Footnotes: `
 ## Examples
@@ -50,12 +60,43 @@ output:
   aws_s3:
     bucket: TODO
     path: docs/${! meta("kafka_key") }/${! count("files") }-${! timestamp_unix_nano() }.tar.gz
+` + "```" + `
+
+Messages can also be grouped by more than one value, here we group by both a tenant and event type and store the resulting key for use further down the pipeline:
+
+` + "```yaml" + `
+pipeline:
+  processors:
+    - group_by_value:
+        values:
+          - ${! json("tenant") }
+          - ${! json("type") }
+        key_metadata: group_key
+        max_groups: 100
+        overflow_policy: spill
 ` + "```" + ``,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString(
 				"value", "The interpolated string to group based on.",
 				"${! meta(\"kafka_key\") }", "${! json(\"foo.bar\") }-${! meta(\"baz\") }",
 			).IsInterpolated().HasDefault(""),
+			docs.FieldString(
+				"values", "A list of interpolated strings that are evaluated in addition to `value`, where the results of each form a composite group key.",
+				[]string{"${! json(\"tenant\") }", "${! json(\"type\") }"},
+			).IsInterpolated().Array().HasDefault([]any{}).AtVersion("4.9.0"),
+			docs.FieldString(
+				"key_metadata", "An optional metadata key to store the group key of each message in. Components of a composite key are joined with `/`.",
+				"group_key",
+			).HasDefault("").AtVersion("4.9.0"),
+			docs.FieldInt(
+				"max_groups", "The maximum number of groups to form from a single batch, set to `0` for no limit.",
+			).HasDefault(0).AtVersion("4.9.0"),
+			docs.FieldString(
+				"overflow_policy", "The policy to apply when a message would exceed the `max_groups` limit.",
+			).HasAnnotatedOptions(
+				"flush", "Emit all groups formed so far and start grouping from scratch.",
+				"spill", "Add the message to an overflow batch emitted after all other groups.",
+			).HasDefault("flush").AtVersion("4.9.0"),
 		),
 	})
 	if err != nil {
@@ -64,49 +105,116 @@ output:
 }
 
 type groupByValueProc struct {
-	log   log.Modular
-	value *field.Expression
+	log            log.Modular
+	values         []*field.Expression
+	keyMetadata    string
+	maxGroups      int
+	spillOverflows bool
 }
 
 func newGroupByValue(conf processor.GroupByValueConfig, mgr bundle.NewManagement) (processor.V2Batched, error) {
-	value, err := mgr.BloblEnvironment().NewField(conf.Value)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse value expression: %v", err)
+	valueStrs := conf.Values
+	if conf.Value != "" || len(valueStrs) == 0 {
+		valueStrs = append([]string{conf.Value}, valueStrs...)
+	}
+
+	values := make([]*field.Expression, 0, len(valueStrs))
+	for i, v := range valueStrs {
+		value, err := mgr.BloblEnvironment().NewField(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse value expression %v: %v", i, err)
+		}
+		values = append(values, value)
 	}
+
+	if conf.MaxGroups < 0 {
+		return nil, fmt.Errorf("max_groups must not be negative: %v", conf.MaxGroups)
+	}
+
+	var spillOverflows bool
+	switch conf.OverflowPolicy {
+	case "flush", "":
+	case "spill":
+		spillOverflows = true
+	default:
+		return nil, fmt.Errorf("overflow_policy not recognised: %v", conf.OverflowPolicy)
+	}
+
 	return &groupByValueProc{
-		log:   mgr.Logger(),
-		value: value,
+		log:            mgr.Logger(),
+		values:         values,
+		keyMetadata:    conf.KeyMetadata,
+		maxGroups:      conf.MaxGroups,
+		spillOverflows: spillOverflows,
 	}, nil
 }
 
+// groupKey returns the components of the group key of a message.
+func (g *groupByValueProc) groupKey(i int, batch message.Batch) []string {
+	components := make([]string, len(g.values))
+	for j, v := range g.values {
+		components[j] = v.String(i, batch)
+	}
+	return components
+}
+
 func (g *groupByValueProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, batch message.Batch) ([]message.Batch, error) {
 	if batch.Len() == 0 {
 		return nil, nil
 	}
 
+	msgs := []message.Batch{}
 	groupKeys := []string{}
 	groupMap := map[string]message.Batch{}
+	var overflow message.Batch
+
+	flushGroups := func() {
+		for _, key := range groupKeys {
+			msgs = append(msgs, groupMap[key])
+		}
+		groupKeys = groupKeys[:0]
+		groupMap = map[string]message.Batch{}
+	}
 
 	_ = batch.Iter(func(i int, p *message.Part) error {
-		v := g.value.String(i, batch)
+		components := g.groupKey(i, batch)
+		v := strings.Join(components, "/")
 		spans[i].LogKV(
 			"event", "grouped",
 			"type", v,
 		)
 		spans[i].SetTag("group", v)
-		if group, exists := groupMap[v]; exists {
-			groupMap[v] = append(group, p)
+
+		// Components are joined with a null byte for the purpose of a map key
+		// in order to prevent ambiguous composite keys from colliding.
+		mapKey := strings.Join(components, "\x00")
+		group, exists := groupMap[mapKey]
+		if !exists && g.maxGroups > 0 && len(groupKeys) >= g.maxGroups {
+			if g.spillOverflows {
+				g.log.Tracef("Group limit reached, spilling message of group: %v\n", v)
+				overflow = append(overflow, p)
+				return nil
+			}
+			g.log.Tracef("Group limit reached, flushing %v groups\n", len(groupKeys))
+			flushGroups()
+		}
+
+		if g.keyMetadata != "" {
+			p.MetaSet(g.keyMetadata, v)
+		}
+		if exists {
+			groupMap[mapKey] = append(group, p)
 		} else {
 			g.log.Tracef("New group formed: %v\n", v)
-			groupKeys = append(groupKeys, v)
-			groupMap[v] = message.Batch{p}
+			groupKeys = append(groupKeys, mapKey)
+			groupMap[mapKey] = message.Batch{p}
 		}
 		return nil
 	})
 
-	msgs := []message.Batch{}
-	for _, key := range groupKeys {
-		msgs = append(msgs, groupMap[key])
+	flushGroups()
+	if len(overflow) > 0 {
+		msgs = append(msgs, overflow)
 	}
 	if len(msgs) == 0 {
 		return nil, nil
@@ -5,6 +5,9 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -70,3 +73,98 @@ func TestGroupByValueBasic(t *testing.T) {
 		t.Errorf("Wrong result: %s != %s", act, exp)
 	}
 }
+
+func groupByValueResults(t *testing.T, conf processor.Config, input message.Batch) (contents [][]string, keys [][]string) {
+	t.Helper()
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), input)
+	require.NoError(t, res)
+
+	for _, msg := range msgs {
+		var batchContents, batchKeys []string
+		_ = msg.Iter(func(i int, p *message.Part) error {
+			batchContents = append(batchContents, string(p.AsBytes()))
+			batchKeys = append(batchKeys, p.MetaGet("group_key"))
+			return nil
+		})
+		contents = append(contents, batchContents)
+		keys = append(keys, batchKeys)
+	}
+	return
+}
+
+func TestGroupByValueComposite(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "group_by_value"
+	conf.GroupByValue.Values = []string{`${! json("a") }`, `${! json("b") }`}
+	conf.GroupByValue.KeyMetadata = "group_key"
+
+	contents, keys := groupByValueResults(t, conf, message.QuickBatch([][]byte{
+		[]byte(`{"a":"x","b":"1"}`),
+		[]byte(`{"a":"x","b":"2"}`),
+		[]byte(`{"a":"y","b":"1"}`),
+		[]byte(`{"a":"x","b":"1"}`),
+		[]byte(`{"a":"x/1","b":""}`),
+	}))
+
+	assert.Equal(t, [][]string{
+		{`{"a":"x","b":"1"}`, `{"a":"x","b":"1"}`},
+		{`{"a":"x","b":"2"}`},
+		{`{"a":"y","b":"1"}`},
+		{`{"a":"x/1","b":""}`},
+	}, contents)
+	assert.Equal(t, [][]string{
+		{"x/1", "x/1"},
+		{"x/2"},
+		{"y/1"},
+		{"x/1/"},
+	}, keys)
+}
+
+func TestGroupByValueMaxGroups(t *testing.T) {
+	input := func() message.Batch {
+		return message.QuickBatch([][]byte{
+			[]byte(`a`), []byte(`b`), []byte(`a`), []byte(`c`), []byte(`a`), []byte(`c`), []byte(`d`),
+		})
+	}
+
+	conf := processor.NewConfig()
+	conf.Type = "group_by_value"
+	conf.GroupByValue.Value = `${! content() }`
+	conf.GroupByValue.KeyMetadata = "group_key"
+	conf.GroupByValue.MaxGroups = 2
+
+	contents, _ := groupByValueResults(t, conf, input())
+	assert.Equal(t, [][]string{
+		{"a", "a"}, {"b"}, {"c", "c"}, {"a"}, {"d"},
+	}, contents)
+
+	conf.GroupByValue.OverflowPolicy = "spill"
+
+	contents, keys := groupByValueResults(t, conf, input())
+	assert.Equal(t, [][]string{
+		{"a", "a", "a"}, {"b"}, {"c", "c", "d"},
+	}, contents)
+	assert.Equal(t, [][]string{
+		{"a", "a", "a"}, {"b"}, {"", "", ""},
+	}, keys)
+}
+
+func TestGroupByValueBadConfig(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "group_by_value"
+	conf.GroupByValue.Value = `${! content() }`
+	conf.GroupByValue.OverflowPolicy = "nope"
+
+	_, err := mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+
+	conf.GroupByValue.OverflowPolicy = "flush"
+	conf.GroupByValue.MaxGroups = -1
+
+	_, err = mock.NewManager().NewProcessor(conf)
+	require.Error(t, err)
+}
@@ -0,0 +1,459 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lsFieldSchema        = "schema"
+	lsFieldStrict        = "strict"
+	lsFieldFieldMappings = "field_mappings"
+
+	// The version of ECS that the curated mappings target.
+	lsECSVersion = "8.11.0"
+)
+
+func logSchemaProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Parsing").
+		Summary("Projects structured logs into the [Elastic Common Schema (ECS)](https://www.elastic.co/guide/en/ecs/current/index.html) or the [OpenTelemetry log data model](https://opentelemetry.io/docs/specs/otel/logs/data-model/) with a curated set of field mappings.").
+		Description(`
+The processor recognises the documents produced by each format of the `+"[`parse_log` processor](/docs/components/processors/parse_log)"+` as well as common field names of arbitrary JSON logs, such as `+"`timestamp`, `msg`, `level`, `src_ip` and `user`"+`, and moves each field it recognises to its place in the target schema, converting values to the types of the schema where needed. Fields can be mapped explicitly with `+"`field_mappings`"+`, which take precedence over the curated mappings.
+
+### Modes
+
+In lenient mode, which is the default, values that fail to convert and fields without a mapping are preserved, for ECS within the object `+"`labels`"+` as strings keyed by their path with dots replaced by underscores, and for OpenTelemetry within `+"`attributes`"+` keyed by their dot separated path.
+
+In strict mode the output conforms to the target schema, values that fail to convert cause the message to be flagged as having failed, which can be handled using [error handling patterns](/docs/configuration/error_handling), and fields without a mapping are dropped.
+
+Messages that fail to project are left unchanged.
+
+### ECS
+
+The resulting document contains nested ECS fields such as `+"`source.ip`, `log.level` and `host.name`, along with `ecs.version`"+`. When no timestamp is found `+"`@timestamp`"+` is set to the time of processing.
+
+Fields of the source format without an ECS equivalent are mapped to the namespaces used by Elastic integrations, such as `+"`winlog`"+` for Windows events.
+
+### OpenTelemetry
+
+The resulting document follows the fields of the log data model:
+
+- `+"`timestamp`"+` (string, RFC3339)
+- `+"`observed_timestamp`"+` (string, RFC3339), the time of processing
+- `+"`severity_text`"+` (string)
+- `+"`severity_number`"+` (int)
+- `+"`body`"+`
+- `+"`trace_id`"+` (string)
+- `+"`span_id`"+` (string)
+- `+"`attributes`"+` (object)
+- `+"`resource`"+` (object of `+"`attributes`"+`)
+
+Attributes are keyed by their [semantic convention](https://opentelemetry.io/docs/specs/semconv/) where one exists, such as `+"`source.address` and `user.name`"+`, and by their ECS field name otherwise. The host and service names are resource attributes.
+
+Severity numbers are derived from the level of the log, where the severities of syslog, CEF, LEEF and Windows events are translated to the ranges of the data model.`).
+		Field(service.NewStringEnumField(lsFieldSchema, "ecs", "otel").
+			Description("The schema to project logs into.")).
+		Field(service.NewBoolField(lsFieldStrict).
+			Description("Whether to fail messages with values that cannot be converted and drop fields without a mapping, rather than preserving them.").
+			Default(false)).
+		Field(service.NewStringMapField(lsFieldFieldMappings).
+			Description("A map of dot separated paths of input fields to the fields of the schema they are moved to, which take precedence over the curated mappings. For OpenTelemetry the targets are either top level fields of the log data model, or attributes prefixed with `attributes.` or `resource.`, where the remainder of the path is the attribute key.").
+			Example(map[string]any{"client.addr": "source.ip", "app.region": "cloud.region"}).
+			Example(map[string]any{"req_id": "attributes.http.request.id"}).
+			Advanced().
+			Default(map[string]any{})).
+		Example(
+			"CEF to ECS",
+			"Parse CEF events from syslog and project them into ECS before indexing into Elasticsearch.",
+			`
+pipeline:
+  processors:
+    - parse_log:
+        format: cef
+    - log_schema:
+        schema: ecs
+
+output:
+  elasticsearch:
+    urls: [ http://localhost:9200 ]
+    index: logs-cef-default
+`,
+		).
+		Example(
+			"Application Logs to OpenTelemetry",
+			"Project JSON application logs into the OpenTelemetry log data model, mapping a custom field to an attribute.",
+			`
+pipeline:
+  processors:
+    - log_schema:
+        schema: otel
+        field_mappings:
+          ctx.request: attributes.http.request.id
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"log_schema", logSchemaProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLogSchemaProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type logSchemaProc struct {
+	otel     bool
+	strict   bool
+	mappings []logFieldRule
+	nowFn    func() time.Time
+}
+
+func newLogSchemaProcFromParsed(conf *service.ParsedConfig) (*logSchemaProc, error) {
+	schema, err := conf.FieldString(lsFieldSchema)
+	if err != nil {
+		return nil, err
+	}
+	p := &logSchemaProc{nowFn: time.Now}
+	switch schema {
+	case "ecs":
+	case "otel":
+		p.otel = true
+	default:
+		return nil, fmt.Errorf("unrecognised schema: %v", schema)
+	}
+	if p.strict, err = conf.FieldBool(lsFieldStrict); err != nil {
+		return nil, err
+	}
+
+	mappings, err := conf.FieldStringMap(lsFieldFieldMappings)
+	if err != nil {
+		return nil, err
+	}
+	froms := make([]string, 0, len(mappings))
+	for k := range mappings {
+		froms = append(froms, k)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		to := mappings[from]
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("field mapping paths must not be empty: '%v': '%v'", from, to)
+		}
+		rule := logFieldRule{from: []string{from}, ecs: to, otel: to, conv: lsAny}
+		if p.otel {
+			if segs := otelTarget(to); len(segs) == 1 && !isOTelTopLevel(to) {
+				return nil, fmt.Errorf("field mapping target '%v' is not a field of the log data model, attributes must be prefixed with 'attributes.' or 'resource.'", to)
+			}
+		}
+		p.mappings = append(p.mappings, rule)
+	}
+	return p, nil
+}
+
+func (p *logSchemaProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields are removed from the input as they're mapped, and therefore it's
+	// copied in order to leave messages that fail unchanged.
+	in, ok := query.IClone(v).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %T", v)
+	}
+	out, err := p.project(in)
+	if err != nil {
+		return nil, err
+	}
+	msg.SetStructuredMut(out)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *logSchemaProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (p *logSchemaProc) target(rule logFieldRule) []string {
+	if p.otel {
+		return otelTarget(rule.otelPath())
+	}
+	return strings.Split(rule.ecs, ".")
+}
+
+func (p *logSchemaProc) project(in map[string]any) (map[string]any, error) {
+	out := map[string]any{}
+	kind := detectLogKind(in)
+
+	var convErrs []string
+	apply := func(rule logFieldRule) {
+		to := p.target(rule)
+		if _, exists := getLogPath(out, to); exists {
+			return
+		}
+		for _, from := range rule.from {
+			fromSegs := strings.Split(from, ".")
+			v, exists := getLogPath(in, fromSegs)
+			if !exists {
+				continue
+			}
+			conv, err := rule.conv(v)
+			if err != nil {
+				convErrs = append(convErrs, fmt.Sprintf("%v: %v", from, err))
+				continue
+			}
+			setLogPath(out, to, conv)
+			deleteLogPath(in, fromSegs)
+			return
+		}
+	}
+
+	for _, rule := range p.mappings {
+		apply(rule)
+	}
+	if err := p.applySeverity(kind, in, out); err != nil {
+		convErrs = append(convErrs, err.Error())
+	}
+	for _, rule := range logKindRules[kind] {
+		apply(rule)
+	}
+	for _, rule := range logCommonRules {
+		apply(rule)
+	}
+
+	if p.strict && len(convErrs) > 0 {
+		return nil, fmt.Errorf("failed to convert fields: %v", strings.Join(convErrs, ", "))
+	}
+
+	now := p.nowFn().Format(time.RFC3339Nano)
+	if p.otel {
+		out["observed_timestamp"] = now
+	} else {
+		if _, exists := out["@timestamp"]; !exists {
+			out["@timestamp"] = now
+		}
+		setLogPath(out, []string{"ecs", "version"}, lsECSVersion)
+	}
+
+	if !p.strict {
+		walkLogLeaves(in, nil, func(path []string, v any) {
+			if p.otel {
+				setLogPath(out, []string{"attributes", strings.Join(path, ".")}, v)
+			} else {
+				setLogPath(out, []string{"labels", strings.Join(path, "_")}, query.IToString(v))
+			}
+		})
+	}
+	return out, nil
+}
+
+// applySeverity maps the level of a log, which is expressed differently by each
+// source format, to the level of ECS or the severity of OpenTelemetry.
+func (p *logSchemaProc) applySeverity(kind logKind, in, out map[string]any) error {
+	var sev logSeverity
+	var from []string
+	var err error
+	for _, path := range logSeveritySources[kind] {
+		from = strings.Split(path, ".")
+		v, exists := getLogPath(in, from)
+		if !exists {
+			continue
+		}
+		if sev, err = logSeverityFns[kind](v); err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		break
+	}
+	if sev.text == "" {
+		return nil
+	}
+	deleteLogPath(in, from)
+
+	if p.otel {
+		out["severity_text"] = sev.text
+		if sev.number > 0 {
+			out["severity_number"] = sev.number
+		}
+		return nil
+	}
+	setLogPath(out, []string{"log", "level"}, strings.ToLower(sev.text))
+	switch kind {
+	case logKindSyslog:
+		setLogPath(out, []string{"log", "syslog", "severity", "code"}, sev.code)
+		setLogPath(out, []string{"log", "syslog", "severity", "name"}, sev.text)
+	case logKindCEF, logKindLEEF:
+		if sev.number > 0 {
+			setLogPath(out, []string{"event", "severity"}, sev.code)
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func otelTarget(path string) []string {
+	if key := strings.TrimPrefix(path, "attributes."); key != path {
+		return []string{"attributes", key}
+	}
+	if key := strings.TrimPrefix(path, "resource."); key != path {
+		return []string{"resource", "attributes", key}
+	}
+	return []string{path}
+}
+
+func isOTelTopLevel(path string) bool {
+	switch path {
+	case "timestamp", "observed_timestamp", "severity_text", "severity_number", "body", "trace_id", "span_id", "trace_flags":
+		return true
+	}
+	return false
+}
+
+func getLogPath(m map[string]any, path []string) (any, bool) {
+	var v any = m
+	for _, seg := range path {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[seg]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func setLogPath(m map[string]any, path []string, v any) {
+	for _, seg := range path[:len(path)-1] {
+		next, ok := m[seg].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[seg] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = v
+}
+
+// deleteLogPath removes the field at a path, along with any objects that are
+// left empty by its removal.
+func deleteLogPath(m map[string]any, path []string) {
+	if len(path) > 1 {
+		if child, ok := m[path[0]].(map[string]any); ok {
+			deleteLogPath(child, path[1:])
+			if len(child) == 0 {
+				delete(m, path[0])
+			}
+		}
+		return
+	}
+	delete(m, path[0])
+}
+
+func walkLogLeaves(m map[string]any, prefix []string, fn func(path []string, v any)) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := append(append([]string{}, prefix...), k)
+		if child, ok := m[k].(map[string]any); ok && len(child) > 0 {
+			walkLogLeaves(child, path, fn)
+			continue
+		}
+		fn(path, m[k])
+	}
+}
+
+//------------------------------------------------------------------------------
+
+func lsAny(v any) (any, error) {
+	return v, nil
+}
+
+func lsString(v any) (any, error) {
+	switch v.(type) {
+	case map[string]any, []any:
+		return nil, errors.New("expected a string value")
+	}
+	return query.IToString(v), nil
+}
+
+func lsLowerString(v any) (any, error) {
+	s, err := lsString(v)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s.(string)), nil
+}
+
+func lsInt(v any) (any, error) {
+	if s, ok := v.(string); ok {
+		v = strings.TrimSpace(s)
+	}
+	return query.IToInt(v)
+}
+
+var lsTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+func lsTimestamp(v any) (any, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	case string:
+		for _, layout := range lsTimeLayouts {
+			if ts, err := time.Parse(layout, t); err == nil {
+				return ts.Format(time.RFC3339Nano), nil
+			}
+		}
+		if _, err := strconv.ParseFloat(t, 64); err != nil {
+			if ts, ok := parseCEFTime(t); ok {
+				return ts.Format(time.RFC3339Nano), nil
+			}
+			return nil, fmt.Errorf("unrecognised timestamp format: %v", t)
+		}
+	}
+	n, err := query.IToNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return lsEpochTime(n).Format(time.RFC3339Nano), nil
+}
+
+// lsEpochTime interprets a number as a unix timestamp, where the unit is
+// inferred from its magnitude.
+func lsEpochTime(n float64) time.Time {
+	switch {
+	case n < 1e11:
+		return time.Unix(0, int64(n*1e9)).UTC()
+	case n < 1e14:
+		return time.UnixMilli(int64(n)).UTC()
+	case n < 1e17:
+		return time.UnixMicro(int64(n)).UTC()
+	}
+	return time.Unix(0, int64(n)).UTC()
+}
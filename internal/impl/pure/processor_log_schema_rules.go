@@ -0,0 +1,328 @@
+package pure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+)
+
+type logKind int
+
+const (
+	logKindGeneric logKind = iota
+	logKindSyslog
+	logKindCEF
+	logKindLEEF
+	logKindWindows
+)
+
+// detectLogKind determines the source format of a log from the fields produced
+// by the formats of the parse_log processor.
+func detectLogKind(in map[string]any) logKind {
+	if _, exists := in["cef_version"]; exists {
+		return logKindCEF
+	}
+	if _, exists := in["leef_version"]; exists {
+		return logKindLEEF
+	}
+	if sys, ok := in["system"].(map[string]any); ok {
+		if _, exists := sys["event_id"]; exists {
+			return logKindWindows
+		}
+	}
+	for _, k := range []string{"facility", "priority", "appname"} {
+		if _, exists := in[k]; exists {
+			return logKindSyslog
+		}
+	}
+	return logKindGeneric
+}
+
+// logFieldRule moves the first field found of a list of input paths to a field
+// of the schema.
+type logFieldRule struct {
+	from []string
+	ecs  string
+	// The target of OpenTelemetry, either a top level field of the log data
+	// model, or an attribute prefixed with attributes. or resource. When empty
+	// the ECS field name is used as an attribute key.
+	otel string
+	conv func(v any) (any, error)
+}
+
+func (r logFieldRule) otelPath() string {
+	if r.otel == "" {
+		return "attributes." + r.ecs
+	}
+	return r.otel
+}
+
+func lsRule(ecs, otel string, conv func(v any) (any, error), from ...string) logFieldRule {
+	return logFieldRule{from: from, ecs: ecs, otel: otel, conv: conv}
+}
+
+var logKindRules = map[logKind][]logFieldRule{
+	logKindSyslog: {
+		lsRule("log.syslog.facility.code", "", lsInt, "facility"),
+		lsRule("log.syslog.priority", "", lsInt, "priority"),
+		lsRule("log.syslog.version", "", lsString, "version"),
+		lsRule("log.syslog.msgid", "", lsString, "msgid"),
+		lsRule("log.syslog.structured_data", "", lsAny, "structureddata"),
+		lsRule("process.name", "attributes.process.executable.name", lsString, "appname"),
+		lsRule("process.pid", "attributes.process.pid", lsInt, "procid"),
+	},
+	logKindCEF: {
+		lsRule("cef.version", "", lsInt, "cef_version"),
+		lsRule("observer.vendor", "", lsString, "device_vendor"),
+		lsRule("observer.product", "", lsString, "device_product"),
+		lsRule("observer.version", "", lsString, "device_version"),
+		lsRule("event.code", "", lsString, "signature_id"),
+		lsRule("event.reason", "", lsString, "name"),
+		lsRule("log.syslog.header", "", lsString, "syslog_header"),
+		lsRule("message", "body", lsAny, "extensions.msg"),
+		lsRule("@timestamp", "timestamp", lsTimestamp, "extensions.rt"),
+		lsRule("event.start", "", lsTimestamp, "extensions.start"),
+		lsRule("event.end", "", lsTimestamp, "extensions.end"),
+		lsRule("event.id", "", lsString, "extensions.externalId"),
+		lsRule("event.action", "", lsString, "extensions.act"),
+		lsRule("event.outcome", "", lsLowerString, "extensions.outcome"),
+		lsRule("source.ip", "attributes.source.address", lsString, "extensions.src"),
+		lsRule("source.port", "attributes.source.port", lsInt, "extensions.spt"),
+		lsRule("source.domain", "", lsString, "extensions.shost"),
+		lsRule("source.mac", "", lsString, "extensions.smac"),
+		lsRule("source.user.name", "", lsString, "extensions.suser"),
+		lsRule("source.bytes", "", lsInt, "extensions.in"),
+		lsRule("destination.ip", "attributes.destination.address", lsString, "extensions.dst"),
+		lsRule("destination.port", "attributes.destination.port", lsInt, "extensions.dpt"),
+		lsRule("destination.domain", "", lsString, "extensions.dhost"),
+		lsRule("destination.mac", "", lsString, "extensions.dmac"),
+		lsRule("destination.user.name", "", lsString, "extensions.duser"),
+		lsRule("destination.bytes", "", lsInt, "extensions.out"),
+		lsRule("network.transport", "attributes.network.transport", lsLowerString, "extensions.proto"),
+		lsRule("network.protocol", "attributes.network.protocol.name", lsLowerString, "extensions.app"),
+		lsRule("observer.hostname", "", lsString, "extensions.dvchost"),
+		lsRule("observer.ip", "", lsString, "extensions.dvc"),
+		lsRule("url.original", "attributes.url.full", lsString, "extensions.request"),
+		lsRule("http.request.method", "attributes.http.request.method", lsString, "extensions.requestMethod"),
+		lsRule("user_agent.original", "attributes.user_agent.original", lsString, "extensions.requestClientApplication"),
+		lsRule("file.name", "", lsString, "extensions.fname"),
+		lsRule("file.path", "", lsString, "extensions.filePath"),
+		lsRule("file.size", "", lsInt, "extensions.fsize"),
+	},
+	logKindLEEF: {
+		lsRule("leef.version", "", lsString, "leef_version"),
+		lsRule("observer.vendor", "", lsString, "vendor"),
+		lsRule("observer.product", "", lsString, "product"),
+		lsRule("observer.version", "", lsString, "product_version"),
+		lsRule("event.code", "", lsString, "event_id"),
+		lsRule("log.syslog.header", "", lsString, "syslog_header"),
+		lsRule("@timestamp", "timestamp", lsTimestamp, "attributes.devTime"),
+		lsRule("event.category", "", lsString, "attributes.cat"),
+		lsRule("source.ip", "attributes.source.address", lsString, "attributes.src"),
+		lsRule("source.port", "attributes.source.port", lsInt, "attributes.srcPort"),
+		lsRule("source.mac", "", lsString, "attributes.srcMAC"),
+		lsRule("source.bytes", "", lsInt, "attributes.srcBytes"),
+		lsRule("source.packets", "", lsInt, "attributes.srcPackets"),
+		lsRule("destination.ip", "attributes.destination.address", lsString, "attributes.dst"),
+		lsRule("destination.port", "attributes.destination.port", lsInt, "attributes.dstPort"),
+		lsRule("destination.mac", "", lsString, "attributes.dstMAC"),
+		lsRule("destination.bytes", "", lsInt, "attributes.dstBytes"),
+		lsRule("destination.packets", "", lsInt, "attributes.dstPackets"),
+		lsRule("network.bytes", "", lsInt, "attributes.totalBytes"),
+		lsRule("network.packets", "", lsInt, "attributes.totalPackets"),
+		lsRule("network.transport", "attributes.network.transport", lsLowerString, "attributes.proto"),
+		lsRule("user.name", "attributes.user.name", lsString, "attributes.usrName"),
+		lsRule("url.original", "attributes.url.full", lsString, "attributes.url"),
+	},
+	logKindWindows: {
+		lsRule("event.code", "", lsString, "system.event_id"),
+		lsRule("event.provider", "", lsString, "system.provider.name"),
+		lsRule("winlog.provider_guid", "", lsString, "system.provider.guid"),
+		lsRule("winlog.event_source_name", "", lsString, "system.provider.event_source_name"),
+		lsRule("winlog.event_id_qualifiers", "", lsInt, "system.event_id_qualifiers"),
+		lsRule("winlog.version", "", lsInt, "system.version"),
+		lsRule("winlog.task", "", lsInt, "system.task"),
+		lsRule("winlog.opcode", "", lsInt, "system.opcode"),
+		lsRule("winlog.keywords", "", lsString, "system.keywords"),
+		lsRule("@timestamp", "timestamp", lsTimestamp, "system.time_created"),
+		lsRule("winlog.record_id", "", lsInt, "system.event_record_id"),
+		lsRule("winlog.activity_id", "", lsString, "system.correlation.activity_id"),
+		lsRule("winlog.related_activity_id", "", lsString, "system.correlation.related_activity_id"),
+		lsRule("process.pid", "attributes.process.pid", lsInt, "system.execution.process_id"),
+		lsRule("process.thread.id", "attributes.thread.id", lsInt, "system.execution.thread_id"),
+		lsRule("winlog.channel", "", lsString, "system.channel"),
+		lsRule("host.name", "resource.host.name", lsString, "system.computer"),
+		lsRule("winlog.user.identifier", "", lsString, "system.security.user_id"),
+		lsRule("winlog.event_data", "", lsAny, "event_data"),
+		lsRule("winlog.user_data", "", lsAny, "user_data"),
+		lsRule("message", "body", lsAny, "rendering_info.message"),
+	},
+}
+
+// logCommonRules map the common field names of logs of all kinds, and are
+// applied after the rules of a specific kind.
+var logCommonRules = []logFieldRule{
+	lsRule("@timestamp", "timestamp", lsTimestamp, "@timestamp", "timestamp", "time", "ts", "datetime", "date"),
+	lsRule("message", "body", lsAny, "message", "msg", "log", "body", "text"),
+	lsRule("host.name", "resource.host.name", lsString, "host.name", "hostname", "host", "server"),
+	lsRule("service.name", "resource.service.name", lsString, "service.name", "service", "app", "application"),
+	lsRule("service.version", "resource.service.version", lsString, "service.version", "version"),
+	lsRule("service.environment", "resource.deployment.environment", lsString, "env", "environment"),
+	lsRule("process.name", "attributes.process.executable.name", lsString, "process.name", "process", "program"),
+	lsRule("process.pid", "attributes.process.pid", lsInt, "process.pid", "pid"),
+	lsRule("process.thread.id", "attributes.thread.id", lsInt, "thread_id", "tid"),
+	lsRule("log.logger", "", lsString, "logger", "logger_name"),
+	lsRule("trace.id", "trace_id", lsString, "trace.id", "trace_id", "traceid", "traceId"),
+	lsRule("span.id", "span_id", lsString, "span.id", "span_id", "spanid", "spanId"),
+	lsRule("source.ip", "attributes.source.address", lsString, "source.ip", "src_ip", "source_ip", "src", "client_ip", "remote_addr"),
+	lsRule("source.port", "attributes.source.port", lsInt, "source.port", "src_port", "source_port", "client_port"),
+	lsRule("destination.ip", "attributes.destination.address", lsString, "destination.ip", "dst_ip", "dest_ip", "destination_ip", "dst"),
+	lsRule("destination.port", "attributes.destination.port", lsInt, "destination.port", "dst_port", "dest_port", "destination_port"),
+	lsRule("network.transport", "attributes.network.transport", lsLowerString, "network.transport", "protocol", "proto"),
+	lsRule("user.name", "attributes.user.name", lsString, "user.name", "user", "username", "user_name"),
+	lsRule("user.id", "attributes.user.id", lsString, "user.id", "user_id", "uid"),
+	lsRule("url.original", "attributes.url.full", lsString, "url.original", "url", "uri"),
+	lsRule("url.path", "attributes.url.path", lsString, "url.path", "path"),
+	lsRule("http.request.method", "attributes.http.request.method", lsString, "http.request.method", "method", "http_method"),
+	lsRule("http.response.status_code", "attributes.http.response.status_code", lsInt, "http.response.status_code", "status", "status_code", "http_status"),
+	lsRule("user_agent.original", "attributes.user_agent.original", lsString, "user_agent.original", "user_agent", "useragent"),
+	lsRule("event.id", "", lsString, "event.id", "event_id"),
+	lsRule("event.action", "", lsString, "event.action", "action"),
+	lsRule("error.message", "attributes.exception.message", lsString, "error.message", "error", "err"),
+	lsRule("error.type", "attributes.exception.type", lsString, "error.type", "error_type"),
+	lsRule("error.stack_trace", "attributes.exception.stacktrace", lsString, "error.stack_trace", "stack_trace", "stacktrace"),
+}
+
+//------------------------------------------------------------------------------
+
+// logSeverity is the level of a log as text, the severity number of
+// OpenTelemetry, and the numeric severity of the source format.
+type logSeverity struct {
+	text   string
+	number int
+	code   int64
+}
+
+var logSeveritySources = map[logKind][]string{
+	logKindGeneric: {"log.level", "level", "severity_text", "severity", "loglevel", "lvl"},
+	logKindSyslog:  {"severity"},
+	logKindCEF:     {"severity"},
+	logKindLEEF:    {"attributes.sev"},
+	logKindWindows: {"system.level"},
+}
+
+var logSeverityFns = map[logKind]func(v any) (logSeverity, error){
+	logKindGeneric: textSeverity,
+	logKindSyslog:  syslogSeverity,
+	logKindCEF:     securitySeverity,
+	logKindLEEF:    securitySeverity,
+	logKindWindows: windowsSeverity,
+}
+
+var textSeverityNumbers = map[string]int{
+	"trace": 1, "debug": 5, "dbg": 5, "verbose": 5,
+	"info": 9, "information": 9, "informational": 9, "notice": 10,
+	"warn": 13, "warning": 13,
+	"error": 17, "err": 17, "critical": 18, "crit": 18, "alert": 19,
+	"fatal": 21, "emerg": 21, "emergency": 21, "panic": 21,
+}
+
+func textSeverity(v any) (logSeverity, error) {
+	s, ok := v.(string)
+	if !ok {
+		return logSeverity{}, fmt.Errorf("expected a string value, got %T", v)
+	}
+	if s = strings.TrimSpace(s); s == "" {
+		return logSeverity{}, errors.New("empty level")
+	}
+	n, exists := textSeverityNumbers[strings.ToLower(s)]
+	if !exists {
+		return logSeverity{}, fmt.Errorf("unrecognised level: %v", s)
+	}
+	return logSeverity{text: s, number: n}, nil
+}
+
+var syslogSeverities = []logSeverity{
+	{text: "emergency", number: 21},
+	{text: "alert", number: 19},
+	{text: "critical", number: 18},
+	{text: "error", number: 17},
+	{text: "warning", number: 13},
+	{text: "notice", number: 10},
+	{text: "informational", number: 9},
+	{text: "debug", number: 5},
+}
+
+func syslogSeverity(v any) (logSeverity, error) {
+	i, err := query.IToInt(v)
+	if err != nil {
+		return logSeverity{}, err
+	}
+	if i < 0 || int(i) >= len(syslogSeverities) {
+		return logSeverity{}, fmt.Errorf("syslog severity out of range: %v", i)
+	}
+	sev := syslogSeverities[i]
+	sev.code = i
+	return sev, nil
+}
+
+// securitySeverity maps the severities of CEF and LEEF, which range from 0 to
+// 10, or the named severities of CEF.
+func securitySeverity(v any) (logSeverity, error) {
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(s) {
+		case "unknown":
+			return logSeverity{text: "unknown"}, nil
+		case "low":
+			return logSeverity{text: "low", number: 9, code: 0}, nil
+		case "medium":
+			return logSeverity{text: "medium", number: 13, code: 4}, nil
+		case "high":
+			return logSeverity{text: "high", number: 17, code: 7}, nil
+		case "very-high", "very high":
+			return logSeverity{text: "very-high", number: 21, code: 9}, nil
+		}
+	}
+	i, err := query.IToInt(v)
+	if err != nil {
+		return logSeverity{}, err
+	}
+	sev := logSeverity{code: i}
+	switch {
+	case i < 0 || i > 10:
+		return logSeverity{}, fmt.Errorf("severity out of range: %v", i)
+	case i <= 3:
+		sev.text, sev.number = "low", 9
+	case i <= 6:
+		sev.text, sev.number = "medium", 13
+	case i <= 8:
+		sev.text, sev.number = "high", 17
+	default:
+		sev.text, sev.number = "very-high", 21
+	}
+	return sev, nil
+}
+
+var windowsSeverities = []logSeverity{
+	{text: "information", number: 9},
+	{text: "critical", number: 18},
+	{text: "error", number: 17},
+	{text: "warning", number: 13},
+	{text: "information", number: 9},
+	{text: "verbose", number: 5},
+}
+
+func windowsSeverity(v any) (logSeverity, error) {
+	i, err := query.IToInt(v)
+	if err != nil {
+		return logSeverity{}, err
+	}
+	if i < 0 || int(i) >= len(windowsSeverities) {
+		return logSeverity{}, fmt.Errorf("event level out of range: %v", i)
+	}
+	sev := windowsSeverities[i]
+	sev.code = i
+	return sev, nil
+}
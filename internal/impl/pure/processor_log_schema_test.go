@@ -0,0 +1,199 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testLogSchemaProc(t *testing.T, confStr string) *logSchemaProc {
+	t.Helper()
+	conf, err := logSchemaProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+	proc, err := newLogSchemaProcFromParsed(conf)
+	require.NoError(t, err)
+	proc.nowFn = func() time.Time {
+		return time.Date(2022, 10, 10, 12, 0, 0, 0, time.UTC)
+	}
+	return proc
+}
+
+func logSchemaProject(t *testing.T, proc *logSchemaProc, input string) (string, error) {
+	t.Helper()
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+	if err != nil {
+		return "", err
+	}
+	require.Len(t, res, 1)
+	b, err := res[0].AsBytes()
+	require.NoError(t, err)
+	return string(b), nil
+}
+
+func TestLogSchemaBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`schema: nope`,
+		`{ schema: otel, field_mappings: { foo: bar } }`,
+		`{ schema: ecs, field_mappings: { foo: "" } }`,
+	} {
+		conf, err := logSchemaProcSpec().ParseYAML(confStr, nil)
+		if err != nil {
+			continue
+		}
+		_, err = newLogSchemaProcFromParsed(conf)
+		assert.Error(t, err, confStr)
+	}
+}
+
+func TestLogSchemaCEFToECS(t *testing.T) {
+	parsed, err := parserCEF(false)([]byte(`<134>Sep 19 08:26:10 host CEF:0|Security|threatmanager|1.0|100|worm stopped|10|src=10.0.0.1 spt=1232 dst=2.1.2.2 proto=TCP rt=1600000000000 msg=worm successfully stopped cs1=foo`))
+	require.NoError(t, err)
+	input, err := json.Marshal(parsed)
+	require.NoError(t, err)
+
+	res, err := logSchemaProject(t, testLogSchemaProc(t, `schema: ecs`), string(input))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "@timestamp": "2020-09-13T12:26:40Z",
+  "cef": {"version": 0},
+  "ecs": {"version": "8.11.0"},
+  "destination": {"ip": "2.1.2.2"},
+  "event": {"code": "100", "reason": "worm stopped", "severity": 10},
+  "labels": {"extensions_cs1": "foo"},
+  "log": {"level": "very-high", "syslog": {"header": "<134>Sep 19 08:26:10 host"}},
+  "message": "worm successfully stopped",
+  "network": {"transport": "tcp"},
+  "observer": {"product": "threatmanager", "vendor": "Security", "version": "1.0"},
+  "source": {"ip": "10.0.0.1", "port": 1232}
+}`, res)
+}
+
+func TestLogSchemaSyslogToOTel(t *testing.T) {
+	parsed, err := parserRFC5424(false)([]byte(`<42>4 2049-10-11T22:14:15.003Z toaster.smarthome myapp 1234 2 [home01 device_id="43"] failed to make a toast.`))
+	require.NoError(t, err)
+	input, err := json.Marshal(parsed)
+	require.NoError(t, err)
+
+	res, err := logSchemaProject(t, testLogSchemaProc(t, `schema: otel`), string(input))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "timestamp": "2049-10-11T22:14:15.003Z",
+  "observed_timestamp": "2022-10-10T12:00:00Z",
+  "severity_text": "critical",
+  "severity_number": 18,
+  "body": "failed to make a toast.",
+  "attributes": {
+    "log.syslog.facility.code": 5,
+    "log.syslog.priority": 42,
+    "log.syslog.version": "4",
+    "log.syslog.msgid": "2",
+    "log.syslog.structured_data": {"home01": {"device_id": "43"}},
+    "process.executable.name": "myapp",
+    "process.pid": 1234
+  },
+  "resource": {"attributes": {"host.name": "toaster.smarthome"}}
+}`, res)
+}
+
+func TestLogSchemaWindowsToECS(t *testing.T) {
+	parsed, err := parserWindowsEventXML(false)([]byte(`<Event><System><Provider Name="Microsoft-Windows-Security-Auditing"/><EventID>4625</EventID><Level>0</Level><TimeCreated SystemTime="2022-10-10T11:00:00.5Z"/><Execution ProcessID="640" ThreadID="720"/><Channel>Security</Channel><Computer>DC01</Computer></System><EventData><Data Name="TargetUserName">bob</Data></EventData></Event>`))
+	require.NoError(t, err)
+	input, err := json.Marshal(parsed)
+	require.NoError(t, err)
+
+	res, err := logSchemaProject(t, testLogSchemaProc(t, `schema: ecs`), string(input))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "@timestamp": "2022-10-10T11:00:00.5Z",
+  "ecs": {"version": "8.11.0"},
+  "event": {"code": "4625", "provider": "Microsoft-Windows-Security-Auditing"},
+  "host": {"name": "DC01"},
+  "log": {"level": "information"},
+  "process": {"pid": 640, "thread": {"id": 720}},
+  "winlog": {"channel": "Security", "event_data": {"TargetUserName": "bob"}}
+}`, res)
+}
+
+func TestLogSchemaGeneric(t *testing.T) {
+	input := `{"ts":1665403200,"level":"WARN","msg":"slow request","service":"api","src_ip":"10.0.0.1","status":"504","trace_id":"abc","ctx":{"region":"eu","attempt":2}}`
+
+	res, err := logSchemaProject(t, testLogSchemaProc(t, `schema: ecs`), input)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "@timestamp": "2022-10-10T12:00:00Z",
+  "ecs": {"version": "8.11.0"},
+  "http": {"response": {"status_code": 504}},
+  "labels": {"ctx_attempt": "2", "ctx_region": "eu"},
+  "log": {"level": "warn"},
+  "message": "slow request",
+  "service": {"name": "api"},
+  "source": {"ip": "10.0.0.1"},
+  "trace": {"id": "abc"}
+}`, res)
+
+	res, err = logSchemaProject(t, testLogSchemaProc(t, `schema: otel`), input)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "timestamp": "2022-10-10T12:00:00Z",
+  "observed_timestamp": "2022-10-10T12:00:00Z",
+  "severity_text": "WARN",
+  "severity_number": 13,
+  "body": "slow request",
+  "trace_id": "abc",
+  "attributes": {
+    "ctx.attempt": 2,
+    "ctx.region": "eu",
+    "http.response.status_code": 504,
+    "source.address": "10.0.0.1"
+  },
+  "resource": {"attributes": {"service.name": "api"}}
+}`, res)
+}
+
+func TestLogSchemaStrict(t *testing.T) {
+	proc := testLogSchemaProc(t, `
+schema: ecs
+strict: true
+field_mappings:
+  ctx.region: cloud.region
+`)
+
+	res, err := logSchemaProject(t, proc, `{"timestamp":"2022-10-10T11:00:00Z","message":"hello","ctx":{"region":"eu","other":"dropped"}}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "@timestamp": "2022-10-10T11:00:00Z",
+  "cloud": {"region": "eu"},
+  "ecs": {"version": "8.11.0"},
+  "message": "hello"
+}`, res)
+
+	input := []byte(`{"message":"hello","src_port":"nope"}`)
+	msg := service.NewMessage(input)
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "src_port")
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, string(input), string(b))
+
+	res, err = logSchemaProject(t, testLogSchemaProc(t, `schema: ecs`), string(input))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "@timestamp": "2022-10-10T12:00:00Z",
+  "ecs": {"version": "8.11.0"},
+  "labels": {"src_port": "nope"},
+  "message": "hello"
+}`, res)
+}
+
+func TestLogSchemaNotObject(t *testing.T) {
+	_, err := logSchemaProject(t, testLogSchemaProc(t, `schema: ecs`), `["foo"]`)
+	require.Error(t, err)
+}
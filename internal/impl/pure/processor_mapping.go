@@ -2,7 +2,9 @@ package pure
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -21,6 +23,8 @@ Bloblang is a powerful language that enables a wide range of mapping, transforma
 
 If your mapping is large and you'd prefer for it to live in a separate file then you can execute a mapping directly from a file with the expression `+"`from \"<path>\"`"+`, where the path must be absolute, or relative from the location that Benthos is executed from.
 
+A mapping that is shared by many processors can instead be declared once as a [mapping resource](#mapping-resources) and referenced with the value `+"`resource:<label>`"+`.
+
 ## Input Document Immutability
 
 Mapping operates by creating an entirely new object during assignments, this has the advantage of treating the original referenced document as immutable and therefore queryable at any stage of your mapping. For example, with the following mapping:
@@ -35,6 +39,24 @@ Notice that we mutate the value of `+"`invitees`"+` in the resulting document by
 
 Mapping documents is advantageous in situations where the result is a document with a dramatically different shape to the input document, since we are effectively rebuilding the document in its entirety and might as well keep a reference to the unchanged input document throughout. However, in situations where we are only performing minor alterations to the input document, the rest of which is unchanged, it might be more efficient to use the `+"[`mutation` processor](/docs/components/processors/mutation)"+` instead.
 
+## Mapping Resources
+
+Mappings can be declared as resources at the root of a config with the field `+"`mapping_resources`"+`, where each mapping is compiled a single time and can be referenced from any number of processors:
+
+`+"```yaml"+`
+pipeline:
+  processors:
+    - mapping: resource:enrich
+
+mapping_resources:
+  - label: enrich
+    mapping: |
+      root.id = this.id
+      root.tags = this.tags.or([]).append("enriched")
+`+"```"+`
+
+Mapping resources declared within [resource files](/docs/configuration/resources) are updated when the resource files are reloaded (with the `+"`--watcher`"+` flag), and processors referencing them use the updated mapping from the next batch onwards.
+
 ## Error Handling
 
 Bloblang mappings can fail, in which case the message remains unchanged, errors are logged, and the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
@@ -108,6 +130,14 @@ pipeline:
                         sort().join(", ")
 `),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			if resource, isResource := mappingResourceFromConfig(conf); isResource {
+				if !mgr.HasBloblangMapping(resource) {
+					return nil, fmt.Errorf("mapping resource '%v' was not found", resource)
+				}
+				p := newMapping(nil, mgr.Logger())
+				p.resource, p.mgr = resource, mgr
+				return p, nil
+			}
 			mapping, err := conf.FieldBloblang()
 			if err != nil {
 				return nil, err
@@ -122,9 +152,13 @@ pipeline:
 type mappingProc struct {
 	exec *bloblang.Executor
 	log  *service.Logger
+
+	// When set the mapping is obtained from a resource for each batch.
+	resource string
+	mgr      *service.Resources
 }
 
-func newMapping(exec *bloblang.Executor, log *service.Logger) service.BatchProcessor {
+func newMapping(exec *bloblang.Executor, log *service.Logger) *mappingProc {
 	return &mappingProc{
 		exec: exec,
 		log:  log,
@@ -132,9 +166,14 @@ func newMapping(exec *bloblang.Executor, log *service.Logger) service.BatchProce
 }
 
 func (m *mappingProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	exec, err := resourceOrStaticMapping(ctx, m.mgr, m.resource, m.exec)
+	if err != nil {
+		return nil, err
+	}
+
 	newBatch := make(service.MessageBatch, 0, len(batch))
 	for i, msg := range batch {
-		newPart, err := batch.BloblangQuery(i, m.exec)
+		newPart, err := batch.BloblangQuery(i, exec)
 		if err != nil {
 			m.log.Error(err.Error())
 			msg.SetError(err)
@@ -154,3 +193,28 @@ func (m *mappingProc) ProcessBatch(ctx context.Context, batch service.MessageBat
 func (m *mappingProc) Close(context.Context) error {
 	return nil
 }
+
+// mappingResourceFromConfig returns the name of a mapping resource when the
+// mapping of a processor config is a reference of the form `resource:<label>`.
+func mappingResourceFromConfig(conf *service.ParsedConfig) (string, bool) {
+	str, err := conf.FieldString()
+	if err != nil {
+		return "", false
+	}
+	return docs.MappingResourceName(str)
+}
+
+// resourceOrStaticMapping returns the current version of a mapping resource
+// when a resource name is provided, otherwise the static mapping is returned.
+func resourceOrStaticMapping(ctx context.Context, mgr *service.Resources, resource string, static *bloblang.Executor) (*bloblang.Executor, error) {
+	if resource == "" {
+		return static, nil
+	}
+	var exec *bloblang.Executor
+	if err := mgr.AccessBloblangMapping(ctx, resource, func(e *bloblang.Executor) {
+		exec = e
+	}); err != nil {
+		return nil, err
+	}
+	return exec, nil
+}
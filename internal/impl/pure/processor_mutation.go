@@ -2,6 +2,7 @@ package pure
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -21,6 +22,8 @@ Bloblang is a powerful language that enables a wide range of mapping, transforma
 
 If your mapping is large and you'd prefer for it to live in a separate file then you can execute a mapping directly from a file with the expression `+"`from \"<path>\"`"+`, where the path must be absolute, or relative from the location that Benthos is executed from.
 
+A mapping that is shared by many processors can instead be declared once as a [mapping resource](#mapping-resources) and referenced with the value `+"`resource:<label>`"+`.
+
 ## Input Document Mutability
 
 A mutation is a mapping that transforms input documents directly, this has the advantage of reducing the need to copy the data fed into the mapping. However, this also means that the referenced document is mutable and therefore changes throughout the mapping. For example, with the following Bloblang:
@@ -41,6 +44,24 @@ Then the new field `+"`rejected`"+` would be empty as we have already mutated `+
 
 Mutations are advantageous over a standard mapping in situations where the result is a document with mostly the same shape as the input document, since we can avoid unnecessarily copying data from the referenced input document. However, in situations where we are creating an entirely new document shape it can be more convenient to use the traditional `+"[`mapping` processor](/docs/components/processors/mapping)"+` instead.
 
+## Mapping Resources
+
+Mappings can be declared as resources at the root of a config with the field `+"`mapping_resources`"+`, where each mapping is compiled a single time and can be referenced from any number of processors:
+
+`+"```yaml"+`
+pipeline:
+  processors:
+    - mutation: resource:enrich
+
+mapping_resources:
+  - label: enrich
+    mapping: |
+      root.id = this.id
+      root.tags = this.tags.or([]).append("enriched")
+`+"```"+`
+
+Mapping resources declared within [resource files](/docs/configuration/resources) are updated when the resource files are reloaded (with the `+"`--watcher`"+` flag), and processors referencing them use the updated mapping from the next batch onwards.
+
 ## Error Handling
 
 Bloblang mappings can fail, in which case the error is logged and the message is flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
@@ -114,6 +135,14 @@ pipeline:
                         sort().join(", ")
 `),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			if resource, isResource := mappingResourceFromConfig(conf); isResource {
+				if !mgr.HasBloblangMapping(resource) {
+					return nil, fmt.Errorf("mapping resource '%v' was not found", resource)
+				}
+				p := newMutation(nil, mgr.Logger())
+				p.resource, p.mgr = resource, mgr
+				return p, nil
+			}
 			mapping, err := conf.FieldBloblang()
 			if err != nil {
 				return nil, err
@@ -128,9 +157,13 @@ pipeline:
 type mutationProc struct {
 	exec *bloblang.Executor
 	log  *service.Logger
+
+	// When set the mapping is obtained from a resource for each batch.
+	resource string
+	mgr      *service.Resources
 }
 
-func newMutation(exec *bloblang.Executor, log *service.Logger) service.BatchProcessor {
+func newMutation(exec *bloblang.Executor, log *service.Logger) *mutationProc {
 	return &mutationProc{
 		exec: exec,
 		log:  log,
@@ -138,9 +171,14 @@ func newMutation(exec *bloblang.Executor, log *service.Logger) service.BatchProc
 }
 
 func (m *mutationProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	exec, err := resourceOrStaticMapping(ctx, m.mgr, m.resource, m.exec)
+	if err != nil {
+		return nil, err
+	}
+
 	newBatch := make(service.MessageBatch, 0, len(batch))
 	for i, msg := range batch {
-		newPart, err := batch.BloblangMutate(i, m.exec)
+		newPart, err := batch.BloblangMutate(i, exec)
 		if err != nil {
 			m.log.Error(err.Error())
 			msg.SetError(err)
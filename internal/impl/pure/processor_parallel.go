@@ -29,6 +29,8 @@ A processor that applies a list of child processors to messages of a batch as th
 		Description: `
 The field ` + "`cap`" + `, if greater than zero, caps the maximum number of parallel processing threads.
 
+If the child processors fail to process a message then only that message is flagged with the error, which can be handled using the [standard error handling patterns](/docs/configuration/error_handling).
+
 The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldInt("cap", "The maximum number of messages to have processing at a given time."),
@@ -79,9 +81,14 @@ func (p *parallelProc) ProcessBatch(ctx context.Context, spans []*tracing.Span,
 
 	for i := 0; i < max; i++ {
 		go func() {
-			// TODO: V4 Handle processor errors when we migrate to service APIs
 			for index := range reqChan {
-				resMsgs, _ := processor.ExecuteAll(ctx, p.children, resultMsgs[index])
+				resMsgs, err := processor.ExecuteAll(ctx, p.children, resultMsgs[index])
+				if err != nil {
+					// A failure is isolated to the message that caused it,
+					// which is flagged rather than dropped.
+					processor.MarkErr(resultMsgs[index][0], spans[index], err)
+					continue
+				}
 				resultParts := []*message.Part{}
 				for _, m := range resMsgs {
 					_ = m.Iter(func(i int, p *message.Part) error {
@@ -2,6 +2,7 @@ package pure_test
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
@@ -114,6 +117,43 @@ func TestParallelError(t *testing.T) {
 	}
 }
 
+func TestParallelChildErrorIsolated(t *testing.T) {
+	mgr := mock.NewManager()
+	mgr.Processors["failsome"] = func(b message.Batch) ([]message.Batch, error) {
+		if string(b.Get(0).AsBytes()) == "bar" {
+			return nil, errors.New("nope")
+		}
+		return []message.Batch{b}, nil
+	}
+
+	resConf := processor.NewConfig()
+	resConf.Type = "resource"
+	resConf.Resource = "failsome"
+
+	conf := processor.NewConfig()
+	conf.Type = "parallel"
+	conf.Parallel.Processors = []processor.Config{resConf}
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	}, message.GetAllBytes(msgs[0]))
+	assert.NoError(t, msgs[0].Get(0).ErrorGet())
+	assert.EqualError(t, msgs[0].Get(1).ErrorGet(), "nope")
+	assert.NoError(t, msgs[0].Get(2).ErrorGet())
+}
+
 func TestParallelCapped(t *testing.T) {
 	var reqs int64
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -160,3 +200,29 @@ func TestParallelCapped(t *testing.T) {
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestProcessorExecutionPartMode(t *testing.T) {
+	conf := processor.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+mapping: 'root = "%v/%v".format(content().string(), batch_size())'
+execution:
+  mode: part
+  parallel: 2
+`), &conf))
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"),
+		[]byte("bar"),
+		[]byte("baz"),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("foo/1"),
+		[]byte("bar/1"),
+		[]byte("baz/1"),
+	}, message.GetAllBytes(msgs[0]))
+}
@@ -34,7 +34,7 @@ Parses common log [formats](#formats) into [structured data](#codecs). This is
 easier and often much faster than ` + "[`grok`](/docs/components/processors/grok)" + `.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldString("format", "A common log [format](#formats) to parse.").HasOptions(
-				"syslog_rfc5424", "syslog_rfc3164",
+				"syslog_rfc5424", "syslog_rfc3164", "cef", "leef", "windows_event_xml",
 			),
 			docs.FieldString("codec", "Specifies the structured format to parse a log into.").HasOptions(
 				"json",
@@ -86,6 +86,74 @@ spec. The resulting structured document may contain any of the following fields:
 - ` + "`procid`" + ` (string)
 - ` + "`appname`" + ` (string)
 - ` + "`msgid`" + ` (string)
+
+### ` + "`cef`" + `
+
+Attempts to parse a log following the [ArcSight Common Event Format](https://www.microfocus.com/documentation/arcsight/arcsight-smartconnectors/pdfdoc/common-event-format-v25/common-event-format-v25.pdf),
+which may be preceded by a syslog header. The resulting structured document may
+contain any of the following fields:
+
+- ` + "`syslog_header`" + ` (string)
+- ` + "`cef_version`" + ` (int)
+- ` + "`device_vendor`" + ` (string)
+- ` + "`device_product`" + ` (string)
+- ` + "`device_version`" + ` (string)
+- ` + "`signature_id`" + ` (string)
+- ` + "`name`" + ` (string)
+- ` + "`severity`" + ` (int, or string when not numeric)
+- ` + "`extensions`" + ` (object)
+
+Extensions of the CEF dictionary are converted to their types, where integer
+fields such as ` + "`spt`" + ` and ` + "`cnt`" + ` become ints, floating point fields such as ` + "`cfp1`" + `
+and ` + "`slat`" + ` become floats, and timestamp fields such as ` + "`rt`" + ` and ` + "`start`" + `
+become RFC3339 strings. All other extensions, and values that fail to convert,
+remain strings.
+
+### ` + "`leef`" + `
+
+Attempts to parse a log following the [IBM QRadar Log Event Extended Format](https://www.ibm.com/docs/en/dsm?topic=overview-leef-event-components)
+of versions 1.0 and 2.0, which may be preceded by a syslog header. The resulting
+structured document may contain any of the following fields:
+
+- ` + "`syslog_header`" + ` (string)
+- ` + "`leef_version`" + ` (string)
+- ` + "`vendor`" + ` (string)
+- ` + "`product`" + ` (string)
+- ` + "`product_version`" + ` (string)
+- ` + "`event_id`" + ` (string)
+- ` + "`attributes`" + ` (object)
+
+Attributes are delimited by tabs unless a LEEF 2.0 header specifies a different
+delimiter. Predefined attributes of integer types such as ` + "`sev`" + `, ` + "`srcPort`" + ` and
+` + "`srcBytes`" + ` are converted to ints, all others remain strings.
+
+### ` + "`windows_event_xml`" + `
+
+Attempts to parse a Windows Event Log event in its [XML representation](https://learn.microsoft.com/en-us/windows/win32/wes/eventschema-schema).
+The resulting structured document may contain any of the following fields:
+
+- ` + "`system`" + ` (object)
+  - ` + "`provider`" + ` (object of ` + "`name`" + `, ` + "`guid`" + ` and ` + "`event_source_name`" + `)
+  - ` + "`event_id`" + ` (int)
+  - ` + "`event_id_qualifiers`" + ` (int)
+  - ` + "`version`" + ` (int)
+  - ` + "`level`" + ` (int)
+  - ` + "`task`" + ` (int)
+  - ` + "`opcode`" + ` (int)
+  - ` + "`keywords`" + ` (string)
+  - ` + "`time_created`" + ` (string)
+  - ` + "`event_record_id`" + ` (int)
+  - ` + "`correlation`" + ` (object of ` + "`activity_id`" + ` and ` + "`related_activity_id`" + `)
+  - ` + "`execution`" + ` (object of ` + "`process_id`" + ` and ` + "`thread_id`" + ` ints)
+  - ` + "`channel`" + ` (string)
+  - ` + "`computer`" + ` (string)
+  - ` + "`security`" + ` (object of ` + "`user_id`" + `)
+- ` + "`event_data`" + ` (object)
+- ` + "`user_data`" + ` (object)
+- ` + "`rendering_info`" + ` (object)
+
+Named ` + "`Data`" + ` elements of the event data are keyed by their name, and unnamed
+elements are keyed ` + "`param1`" + `, ` + "`param2`" + ` and so on in their order.
 `,
 	})
 	if err != nil {
@@ -224,6 +292,12 @@ func getParseFormat(parser string, bestEffort, rfc3339 bool, defYear, defTZ stri
 		return parserRFC5424(bestEffort), nil
 	case "syslog_rfc3164":
 		return parserRFC3164(bestEffort, rfc3339, defYear, defTZ)
+	case "cef":
+		return parserCEF(bestEffort), nil
+	case "leef":
+		return parserLEEF(bestEffort), nil
+	case "windows_event_xml":
+		return parserWindowsEventXML(bestEffort), nil
 	}
 	return nil, fmt.Errorf("format not recognised: %s", parser)
 }
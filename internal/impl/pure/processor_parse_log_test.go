@@ -45,6 +45,30 @@ func TestParseLogCases(t *testing.T) {
 			input:   `<28>Dec  2 16:49:23 host app[23410]: Test`,
 			output:  fmt.Sprintf(`{"appname":"app","facility":3,"hostname":"host","message":"Test","priority":28,"procid":"23410","severity":4,"timestamp":"%v-12-02T16:49:23Z"}`, time.Now().Year()),
 		},
+		{
+			name:    "valid cef input, valid json output",
+			format:  "cef",
+			codec:   "json",
+			bestEff: true,
+			input:   `CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=foo\=bar baz`,
+			output:  `{"cef_version":0,"device_product":"threatmanager","device_vendor":"Security","device_version":"1.0","extensions":{"dst":"2.1.2.2","msg":"foo=bar baz","spt":1232,"src":"10.0.0.1"},"name":"worm successfully stopped","severity":10,"signature_id":"100"}`,
+		},
+		{
+			name:    "valid leef input, valid json output",
+			format:  "leef",
+			codec:   "json",
+			bestEff: true,
+			input:   "LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=10.50.1.1\tdst=2.10.20.20\tsev=5",
+			output:  `{"attributes":{"dst":"2.10.20.20","sev":5,"src":"10.50.1.1"},"event_id":"15345","leef_version":"1.0","product":"MSExchange","product_version":"4.0 SP1","vendor":"Microsoft"}`,
+		},
+		{
+			name:    "valid windows_event_xml input, valid json output",
+			format:  "windows_event_xml",
+			codec:   "json",
+			bestEff: true,
+			input:   `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event"><System><Provider Name="Microsoft-Windows-Security-Auditing"/><EventID>4624</EventID><Computer>DC01</Computer></System><EventData><Data Name="TargetUserName">bob</Data></EventData></Event>`,
+			output:  `{"event_data":{"TargetUserName":"bob"},"system":{"computer":"DC01","event_id":4624,"provider":{"name":"Microsoft-Windows-Security-Auditing"}}}`,
+		},
 	}
 
 	for _, test := range tests {
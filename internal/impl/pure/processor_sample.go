@@ -0,0 +1,241 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sampleModeRandom    = "random"
+	sampleModeHash      = "hash"
+	sampleModeReservoir = "reservoir"
+)
+
+func sampleProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Keeps a sample of messages and drops the rest.").
+		Description(`
+Messages that are not kept are dropped from the pipeline and acknowledged at their source. The counter `+"`sample_dropped`"+` tracks the number of messages that were dropped.
+
+### Random
+
+Each message is kept with a probability of `+"`rate`"+`, independently of all other messages.
+
+### Hash
+
+The interpolated `+"`key`"+` of each message is hashed, and a message is kept when its hash falls within the proportion `+"`rate`"+` of all possible hashes. This means that messages with the same key are always either all kept or all dropped, which is useful for sampling entire sessions or traces rather than individual events, and the same decisions are made by every instance of Benthos sampling the same data.
+
+### Reservoir
+
+Up to `+"`size`"+` messages of each batch are kept, where each message of the batch has an equal chance of being kept, and the kept messages retain their original order. Combining this mode with a [window buffer](/docs/components/buffers/system_window) or a [batching policy](/docs/configuration/batching) with a period allows you to sample a fixed number of messages per window of time.`).
+		Field(service.NewStringAnnotatedEnumField("mode", map[string]string{
+			sampleModeRandom:    "Keep each message with a probability of `rate`.",
+			sampleModeHash:      "Keep messages where the hash of `key` falls within `rate`.",
+			sampleModeReservoir: "Keep up to `size` messages of each batch chosen at random.",
+		}).
+			Description("The sampling mode to use.").
+			Default(sampleModeRandom)).
+		Field(service.NewFloatField("rate").
+			Description("The proportion of messages to keep, between 0 and 1, for the `random` and `hash` modes.").
+			Example(0.1).
+			Optional()).
+		Field(service.NewInterpolatedStringField("key").
+			Description("The key of each message to hash for the `hash` mode.").
+			Example(`${! meta("kafka_key") }`).
+			Example(`${! this.session_id }`).
+			Optional()).
+		Field(service.NewIntField("size").
+			Description("The maximum number of messages of each batch to keep for the `reservoir` mode.").
+			Example(100).
+			Optional()).
+		Example(
+			"Sample Sessions",
+			"In this example ten percent of user sessions are kept, where all events of a kept session are kept.",
+			`
+pipeline:
+  processors:
+    - sample:
+        mode: hash
+        rate: 0.1
+        key: ${! this.session_id }
+`,
+		).
+		Example(
+			"Sample Per Minute",
+			"Here a hundred messages are kept from each minute of data, messages are batched with a period of a minute and the batch is then sampled.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: benthos_group
+    batching:
+      period: 1m
+
+pipeline:
+  processors:
+    - sample:
+        mode: reservoir
+        size: 100
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"sample", sampleProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newSampleProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sampleProc struct {
+	mode      string
+	threshold uint64
+	rate      float64
+	key       *service.InterpolatedString
+	size      int
+
+	randMut sync.Mutex
+	rand    *rand.Rand
+
+	dropped *service.MetricCounter
+}
+
+func newSampleProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sampleProc, error) {
+	s := &sampleProc{
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		dropped: mgr.Metrics().NewCounter("sample_dropped"),
+	}
+
+	var err error
+	if s.mode, err = conf.FieldString("mode"); err != nil {
+		return nil, err
+	}
+
+	switch s.mode {
+	case sampleModeRandom, sampleModeHash:
+		if !conf.Contains("rate") {
+			return nil, fmt.Errorf("a rate must be specified for the %v mode", s.mode)
+		}
+		if s.rate, err = conf.FieldFloat("rate"); err != nil {
+			return nil, err
+		}
+		if s.rate < 0 || s.rate > 1 {
+			return nil, fmt.Errorf("rate must be between 0 and 1, got %v", s.rate)
+		}
+		if s.rate == 1 {
+			s.threshold = math.MaxUint64
+		} else {
+			s.threshold = uint64(s.rate * math.MaxUint64)
+		}
+		if s.mode == sampleModeHash {
+			if !conf.Contains("key") {
+				return nil, errors.New("a key must be specified for the hash mode")
+			}
+			if s.key, err = conf.FieldInterpolatedString("key"); err != nil {
+				return nil, err
+			}
+		}
+	case sampleModeReservoir:
+		if !conf.Contains("size") {
+			return nil, errors.New("a size must be specified for the reservoir mode")
+		}
+		if s.size, err = conf.FieldInt("size"); err != nil {
+			return nil, err
+		}
+		if s.size < 1 {
+			return nil, fmt.Errorf("size must be greater than zero, got %v", s.size)
+		}
+	default:
+		return nil, fmt.Errorf("sample mode not recognised: %v", s.mode)
+	}
+	return s, nil
+}
+
+func (s *sampleProc) keepHash(msg *service.Message) bool {
+	h := fnv.New64a()
+	_, _ = h.Write(s.key.Bytes(msg))
+	return s.threshold == math.MaxUint64 || h.Sum64() < s.threshold
+}
+
+// reservoir returns the sorted indexes of up to size messages of a batch of n
+// messages, chosen uniformly at random.
+func (s *sampleProc) reservoir(n int) []int {
+	if n <= s.size {
+		return nil
+	}
+	indexes := make([]int, s.size)
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	s.randMut.Lock()
+	for i := s.size; i < n; i++ {
+		if j := s.rand.Intn(i + 1); j < s.size {
+			indexes[j] = i
+		}
+	}
+	s.randMut.Unlock()
+
+	sort.Ints(indexes)
+	return indexes
+}
+
+func (s *sampleProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var kept service.MessageBatch
+	switch s.mode {
+	case sampleModeRandom:
+		kept = make(service.MessageBatch, 0, len(batch))
+		s.randMut.Lock()
+		for _, msg := range batch {
+			if s.rand.Float64() < s.rate {
+				kept = append(kept, msg)
+			}
+		}
+		s.randMut.Unlock()
+	case sampleModeHash:
+		kept = make(service.MessageBatch, 0, len(batch))
+		for _, msg := range batch {
+			if s.keepHash(msg) {
+				kept = append(kept, msg)
+			}
+		}
+	case sampleModeReservoir:
+		indexes := s.reservoir(len(batch))
+		if indexes == nil {
+			return []service.MessageBatch{batch}, nil
+		}
+		kept = make(service.MessageBatch, 0, len(indexes))
+		for _, i := range indexes {
+			kept = append(kept, batch[i])
+		}
+	}
+
+	if dropped := len(batch) - len(kept); dropped > 0 {
+		s.dropped.Incr(int64(dropped))
+	}
+	if len(kept) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{kept}, nil
+}
+
+func (s *sampleProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSampleProc(t *testing.T, confStr string) *sampleProc {
+	t.Helper()
+	conf, err := sampleProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+	proc, err := newSampleProcFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func sampleTestBatch(n int) service.MessageBatch {
+	batch := make(service.MessageBatch, n)
+	for i := range batch {
+		batch[i] = service.NewMessage([]byte(fmt.Sprintf("%v", i)))
+	}
+	return batch
+}
+
+func sampleResultContents(t *testing.T, res []service.MessageBatch) []string {
+	t.Helper()
+	var contents []string
+	for _, b := range res {
+		for _, m := range b {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			contents = append(contents, string(mBytes))
+		}
+	}
+	return contents
+}
+
+func TestSampleBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`mode: random`,
+		`rate: 1.5`,
+		`{ mode: hash, rate: 0.5 }`,
+		`mode: reservoir`,
+		`{ mode: reservoir, size: 0 }`,
+	} {
+		conf, err := sampleProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+		_, err = newSampleProcFromParsed(conf, service.MockResources())
+		assert.Error(t, err, confStr)
+	}
+}
+
+func TestSampleRandom(t *testing.T) {
+	res, err := testSampleProc(t, `rate: 0`).ProcessBatch(context.Background(), sampleTestBatch(100))
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	res, err = testSampleProc(t, `rate: 1`).ProcessBatch(context.Background(), sampleTestBatch(100))
+	require.NoError(t, err)
+	assert.Len(t, sampleResultContents(t, res), 100)
+
+	res, err = testSampleProc(t, `rate: 0.5`).ProcessBatch(context.Background(), sampleTestBatch(10000))
+	require.NoError(t, err)
+	kept := len(sampleResultContents(t, res))
+	assert.Greater(t, kept, 4000)
+	assert.Less(t, kept, 6000)
+}
+
+func TestSampleHash(t *testing.T) {
+	conf := `
+mode: hash
+rate: 0.3
+key: ${! content() }
+`
+	resA, err := testSampleProc(t, conf).ProcessBatch(context.Background(), sampleTestBatch(10000))
+	require.NoError(t, err)
+	resB, err := testSampleProc(t, conf).ProcessBatch(context.Background(), sampleTestBatch(10000))
+	require.NoError(t, err)
+
+	keptA, keptB := sampleResultContents(t, resA), sampleResultContents(t, resB)
+	assert.Equal(t, keptA, keptB)
+	assert.Greater(t, len(keptA), 2000)
+	assert.Less(t, len(keptA), 4000)
+
+	res, err := testSampleProc(t, conf).ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(keptA[0])),
+		service.NewMessage([]byte(keptA[0])),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{keptA[0], keptA[0]}, sampleResultContents(t, res))
+}
+
+func TestSampleReservoir(t *testing.T) {
+	proc := testSampleProc(t, `
+mode: reservoir
+size: 10
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), sampleTestBatch(5))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, sampleResultContents(t, res))
+
+	seen := map[string]struct{}{}
+	for i := 0; i < 20; i++ {
+		res, err = proc.ProcessBatch(context.Background(), sampleTestBatch(100))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+
+		kept := sampleResultContents(t, res)
+		require.Len(t, kept, 10)
+
+		indexes := make([]int, len(kept))
+		for j, k := range kept {
+			_, err := fmt.Sscan(k, &indexes[j])
+			require.NoError(t, err)
+			seen[k] = struct{}{}
+		}
+		assert.True(t, sort.IntsAreSorted(indexes), indexes)
+	}
+	// Sampling should not favour any particular messages.
+	assert.Greater(t, len(seen), 50)
+}
@@ -2,7 +2,9 @@ package pure
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/docs"
@@ -24,14 +26,22 @@ func init() {
 			"Utility",
 		},
 		Summary: `
-Breaks message batches (synonymous with multiple part messages) into smaller batches. The size of the resulting batches are determined either by a discrete size or, if the field ` + "`byte_size`" + ` is non-zero, then by total size in bytes (which ever limit is reached first).`,
+Breaks message batches (synonymous with multiple part messages) into smaller batches. The size of the resulting batches are determined either by a discrete size or, if the field ` + "`byte_size`" + ` is non-zero, then by total size in bytes (which ever limit is reached first). Batches can also be split at boundaries determined by a [Bloblang query](/docs/guides/bloblang/about) with the field ` + "`boundary_check`" + `, or into a number of roughly equal batches with the field ` + "`chunks`" + `.`,
 		Description: `
 This processor is for breaking batches down into smaller ones. In order to break a single message out into multiple messages use the ` + "[`unarchive` processor](/docs/components/processors/unarchive)" + `.
 
-If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.`,
+If there is a remainder of messages after splitting a batch the remainder is also sent as a single batch. For example, if your target size was 10, and the processor received a batch of 95 message parts, the result would be 9 batches of 10 messages followed by a batch of 5 messages.
+
+When splitting only by ` + "`byte_size` or `boundary_check`" + ` the field ` + "`size`" + ` should be set to ` + "`0`" + ` in order to disable splitting by a discrete size.`,
 		Config: docs.FieldComponent().WithChildren(
-			docs.FieldInt("size", "The target number of messages.").HasDefault(1),
+			docs.FieldInt("size", "The target number of messages, or `0` to disable splitting by count.").HasDefault(1),
 			docs.FieldInt("byte_size", "An optional target of total message bytes.").HasDefault(0),
+			docs.FieldBloblang(
+				"boundary_check", "An optional [Bloblang query](/docs/guides/bloblang/about) that is executed against each message after the first of a batch, and when it resolves to `true` a new batch is started at that message. The query is executed within the context of the original batch, and therefore the [`from` method](/docs/guides/bloblang/methods#from) can be used to compare a message against the one preceding it.",
+				`json("user_id") != json("user_id").from(batch_index() - 1)`,
+				`this.type == "header"`,
+			).HasDefault("").AtVersion("4.9.0"),
+			docs.FieldInt("chunks", "An optional number of batches of roughly equal size to split each batch into. When non-zero the field `size` is ignored.").HasDefault(0).AtVersion("4.9.0"),
 		),
 	})
 	if err != nil {
@@ -42,16 +52,29 @@ If there is a remainder of messages after splitting a batch the remainder is als
 type splitProc struct {
 	log log.Modular
 
-	size     int
-	byteSize int
+	size          int
+	byteSize      int
+	chunks        int
+	boundaryCheck *mapping.Executor
 }
 
 func newSplit(conf processor.SplitConfig, mgr bundle.NewManagement) (*splitProc, error) {
-	return &splitProc{
+	s := &splitProc{
 		log:      mgr.Logger(),
 		size:     conf.Size,
 		byteSize: conf.ByteSize,
-	}, nil
+		chunks:   conf.Chunks,
+	}
+	if conf.Chunks < 0 {
+		return nil, fmt.Errorf("chunks must be zero or greater, got %v", conf.Chunks)
+	}
+	if conf.BoundaryCheck != "" {
+		var err error
+		if s.boundaryCheck, err = mgr.BloblEnvironment().NewMapping(conf.BoundaryCheck); err != nil {
+			return nil, fmt.Errorf("failed to parse boundary_check: %w", err)
+		}
+	}
+	return s, nil
 }
 
 func (s *splitProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg message.Batch) ([]message.Batch, error) {
@@ -59,13 +82,23 @@ func (s *splitProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg mes
 		return nil, nil
 	}
 
+	size := s.size
+	if s.chunks > 0 {
+		size = (msg.Len() + s.chunks - 1) / s.chunks
+	}
+
 	msgs := []message.Batch{}
 
 	nextMsg := message.QuickBatch(nil)
 	byteSize := 0
 
 	_ = msg.Iter(func(i int, p *message.Part) error {
-		if (s.size > 0 && nextMsg.Len() >= s.size) ||
+		if nextMsg.Len() > 0 && s.isBoundary(i, msg) {
+			msgs = append(msgs, nextMsg)
+			nextMsg = message.QuickBatch(nil)
+			byteSize = 0
+		}
+		if (size > 0 && nextMsg.Len() >= size) ||
 			(s.byteSize > 0 && (byteSize+len(p.AsBytes())) > s.byteSize) {
 			if nextMsg.Len() > 0 {
 				msgs = append(msgs, nextMsg)
@@ -86,6 +119,18 @@ func (s *splitProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg mes
 	return msgs, nil
 }
 
+func (s *splitProc) isBoundary(i int, msg message.Batch) bool {
+	if s.boundaryCheck == nil {
+		return false
+	}
+	res, err := s.boundaryCheck.QueryPart(i, msg)
+	if err != nil {
+		s.log.Errorf("Failed to execute boundary_check: %v\n", err)
+		return false
+	}
+	return res
+}
+
 func (s *splitProc) Close(ctx context.Context) error {
 	return nil
 }
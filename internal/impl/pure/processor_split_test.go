@@ -4,6 +4,9 @@ import (
 	"context"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -170,3 +173,81 @@ func TestSplitByBytesTooLarge(t *testing.T) {
 		t.Errorf("Wrong contents: %v != %v", act, exp)
 	}
 }
+
+func splitBatchesToStrings(msgs []message.Batch) [][]string {
+	var res [][]string
+	for _, m := range msgs {
+		var batch []string
+		_ = m.Iter(func(i int, p *message.Part) error {
+			batch = append(batch, string(p.AsBytes()))
+			return nil
+		})
+		res = append(res, batch)
+	}
+	return res
+}
+
+func TestSplitByBoundaryCheck(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "split"
+	conf.Split.Size = 0
+	conf.Split.BoundaryCheck = `json("user") != json("user").from(batch_index() - 1)`
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"user":"a","n":1}`),
+		[]byte(`{"user":"a","n":2}`),
+		[]byte(`{"user":"b","n":3}`),
+		[]byte(`{"user":"c","n":4}`),
+		[]byte(`{"user":"c","n":5}`),
+	}))
+	require.NoError(t, res)
+	assert.Equal(t, [][]string{
+		{`{"user":"a","n":1}`, `{"user":"a","n":2}`},
+		{`{"user":"b","n":3}`},
+		{`{"user":"c","n":4}`, `{"user":"c","n":5}`},
+	}, splitBatchesToStrings(msgs))
+}
+
+func TestSplitByBoundaryCheckWithSize(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "split"
+	conf.Split.Size = 2
+	conf.Split.BoundaryCheck = `content() == "header"`
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("header"), []byte("a"), []byte("b"), []byte("header"), []byte("c"),
+	}))
+	require.NoError(t, res)
+	assert.Equal(t, [][]string{
+		{"header", "a"}, {"b"}, {"header", "c"},
+	}, splitBatchesToStrings(msgs))
+}
+
+func TestSplitByChunks(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "split"
+	conf.Split.Chunks = 3
+
+	proc, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e"), []byte("f"), []byte("g"),
+	}))
+	require.NoError(t, res)
+	assert.Equal(t, [][]string{
+		{"a", "b", "c"}, {"d", "e", "f"}, {"g"},
+	}, splitBatchesToStrings(msgs))
+
+	msgs, res = proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("a"), []byte("b"),
+	}))
+	require.NoError(t, res)
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, splitBatchesToStrings(msgs))
+}
@@ -30,7 +30,9 @@ func init() {
 		Summary: `
 Conditionally processes messages based on their contents.`,
 		Description: `
-For each switch case a [Bloblang query](/docs/guides/bloblang/about) is checked and, if the result is true (or the check is empty) the child processors are executed on the message.`,
+For each switch case a [Bloblang query](/docs/guides/bloblang/about) is checked and, if the result is true (or the check is empty) the child processors are executed on the message.
+
+When every check compares the same query against a string literal, such as ` + "`meta(\"topic\") == \"foo\"`" + `, the query is executed once per message and the matching case is looked up directly rather than testing each check in turn. This makes routing across a large number of cases cheap.`,
 		Footnotes: `
 ## Batching
 
@@ -94,8 +96,9 @@ type switchCase struct {
 }
 
 type switchProc struct {
-	cases []switchCase
-	log   log.Modular
+	cases    []switchCase
+	dispatch *mapping.Dispatcher
+	log      log.Modular
 }
 
 func newSwitchProc(conf processor.SwitchConfig, mgr bundle.NewManagement) (*switchProc, error) {
@@ -130,10 +133,17 @@ func newSwitchProc(conf processor.SwitchConfig, mgr bundle.NewManagement) (*swit
 			fallThrough: caseConf.Fallthrough,
 		})
 	}
-	return &switchProc{
+	s := &switchProc{
 		cases: cases,
 		log:   mgr.Logger(),
-	}, nil
+	}
+
+	checks := make([]*mapping.Executor, len(cases))
+	for i, c := range cases {
+		checks[i] = c.check
+	}
+	s.dispatch, _ = mapping.NewDispatcher(checks)
+	return s, nil
 }
 
 // SwitchReorderFromGroup takes a message sort group and rearranges a slice of
@@ -157,6 +167,10 @@ func SwitchReorderFromGroup(group *message.SortGroup, parts []*message.Part) {
 	})
 }
 
+// switchMatchUnknown indicates that the first case a message passes could not
+// be dispatched, and therefore each check must be tested.
+const switchMatchUnknown = -2
+
 func (s *switchProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg message.Batch) ([]message.Batch, error) {
 	var result []*message.Part
 	var remaining []*message.Part
@@ -169,9 +183,27 @@ func (s *switchProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg me
 		return nil
 	})
 
+	// When the cases can be dispatched we obtain the first case that each
+	// message passes up front, otherwise each check is tested in turn.
+	var matches []int
+	if s.dispatch != nil {
+		matches = make([]int, len(remaining))
+		for j := range remaining {
+			var ok bool
+			if matches[j], ok = s.dispatch.FirstMatch(j, sortMsg); !ok {
+				matches[j] = switchMatchUnknown
+			}
+		}
+	}
+
 	for i, switchCase := range s.cases {
 		passed, failed := carryOver, []*message.Part{}
 
+		var failedMatches []int
+		if matches != nil {
+			failedMatches = matches[:0]
+		}
+
 		// Form a message to test against, consisting of fallen through messages
 		// from prior cases plus remaining messages that haven't passed a case
 		// yet.
@@ -179,7 +211,9 @@ func (s *switchProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg me
 
 		for j, p := range remaining {
 			test := switchCase.check == nil
-			if !test {
+			if !test && matches != nil && matches[j] != switchMatchUnknown {
+				test = matches[j] == i
+			} else if !test {
 				var err error
 				if test, err = switchCase.check.QueryPart(j, testMsg); err != nil {
 					s.log.Errorf("Failed to test case %v: %v\n", i, err)
@@ -192,11 +226,15 @@ func (s *switchProc) ProcessBatch(ctx context.Context, _ []*tracing.Span, msg me
 				passed = append(passed, p)
 			} else {
 				failed = append(failed, p)
+				if matches != nil {
+					failedMatches = append(failedMatches, matches[j])
+				}
 			}
 		}
 
 		carryOver = nil
 		remaining = failed
+		matches = failedMatches
 
 		if len(passed) > 0 {
 			execMsg := message.Batch(passed)
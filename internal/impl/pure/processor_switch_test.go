@@ -194,6 +194,109 @@ func TestSwitchError(t *testing.T) {
 	}, resStrs)
 }
 
+func TestSwitchDispatchedCases(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "switch"
+
+	for i, c := range []struct {
+		check       string
+		fallThrough bool
+	}{
+		{check: `this.type == "a"`},
+		{check: `this.type == "b"`, fallThrough: true},
+		{check: `"c" == this.type`},
+		{check: `this.type == "a"`},
+	} {
+		procConf := processor.NewConfig()
+		procConf.Type = "bloblang"
+		procConf.Bloblang = fmt.Sprintf(`root = "Hit case %v: " + content().string()`, i)
+
+		conf.Switch = append(conf.Switch, processor.SwitchCaseConfig{
+			Check:       c.check,
+			Processors:  []processor.Config{procConf},
+			Fallthrough: c.fallThrough,
+		})
+	}
+
+	c, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	defer func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+		assert.NoError(t, c.Close(ctx))
+	}()
+
+	msgs, res := c.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"type":"c"}`),
+		[]byte(`{"type":"a"}`),
+		[]byte(`{"type":"b"}`),
+		[]byte(`{"type":"d"}`),
+		[]byte(`{"type":10}`),
+		[]byte(`not json`),
+	}))
+	require.Nil(t, res)
+	require.Len(t, msgs, 1)
+
+	assert.Equal(t, []string{
+		`Hit case 2: {"type":"c"}`,
+		`Hit case 0: {"type":"a"}`,
+		`Hit case 2: Hit case 1: {"type":"b"}`,
+		`{"type":"d"}`,
+		`{"type":10}`,
+		`not json`,
+	}, func() (strs []string) {
+		for _, b := range message.GetAllBytes(msgs[0]) {
+			strs = append(strs, string(b))
+		}
+		return
+	}())
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, msgs[0].Get(i).ErrorGet(), i)
+	}
+	assert.Error(t, msgs[0].Get(5).ErrorGet())
+}
+
+func BenchmarkSwitchDispatched100(b *testing.B) {
+	conf := processor.NewConfig()
+	conf.Type = "switch"
+
+	for i := 0; i < 100; i++ {
+		procConf := processor.NewConfig()
+		procConf.Type = "noop"
+
+		conf.Switch = append(conf.Switch, processor.SwitchCaseConfig{
+			Check:      fmt.Sprintf(`meta("topic") == "topic_%v"`, i),
+			Processors: []processor.Config{procConf},
+		})
+	}
+
+	c, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(b, err)
+	defer func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+		assert.NoError(b, c.Close(ctx))
+	}()
+
+	msg := message.QuickBatch(nil)
+	for i := 0; i < 10; i++ {
+		part := message.NewPart([]byte("hello world"))
+		part.MetaSetMut("topic", fmt.Sprintf("topic_%v", i*10+9))
+		msg = append(msg, part)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		msgs, res := c.ProcessBatch(context.Background(), msg)
+		require.Nil(b, res)
+		require.Len(b, msgs[0], 10)
+	}
+}
+
 func BenchmarkSwitch10(b *testing.B) {
 	conf := processor.NewConfig()
 	conf.Type = "switch"
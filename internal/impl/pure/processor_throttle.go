@@ -0,0 +1,165 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func throttleProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Smooths the throughput of messages to a steady rate of messages or bytes per second.").
+		Description(`
+Unlike the `+"[`rate_limit` processor](/docs/components/processors/rate_limit)"+`, which allows messages through in bursts up to the limit of each interval and then blocks until the next, this processor behaves as a leaky bucket and spaces messages evenly over time. A burst of messages is therefore released at the configured rate rather than all at once, which avoids overwhelming downstream services that enforce their own rate limits.
+
+When both `+"`messages_per_second` and `bytes_per_second`"+` are set each message is delayed until both rates are satisfied. A batch of messages is released as a whole, and delays the batch that follows it according to its total number of messages and bytes.
+
+Each instance of this processor throttles independently, which includes each processing thread when it is placed within a pipeline with more than one thread. In order to throttle across all threads define it as a [processor resource](/docs/components/processors/about#labels) and reference it with a `+"[`resource` processor](/docs/components/processors/resource)"+`.`).
+		Field(service.NewFloatField("messages_per_second").
+			Description("The maximum rate of messages per second.").
+			Example(10).
+			Example(0.5).
+			Optional()).
+		Field(service.NewFloatField("bytes_per_second").
+			Description("The maximum rate of message bytes per second.").
+			Example(1048576).
+			Optional()).
+		Example(
+			"Smoothing Writes to a SaaS API",
+			"In this example messages are consumed from Kafka and delivered to an API that allows five requests per second, here a throttle resource is shared across all pipeline threads.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ foo ]
+    consumer_group: benthos_group
+
+pipeline:
+  threads: 4
+  processors:
+    - resource: api_throttle
+
+output:
+  http_client:
+    url: https://api.example.com/events
+    verb: POST
+
+processor_resources:
+  - label: api_throttle
+    throttle:
+      messages_per_second: 5
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"throttle", throttleProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newThrottleProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type throttleProc struct {
+	perMessage time.Duration
+	perByte    float64
+
+	mut         sync.Mutex
+	nextMessage time.Time
+	nextByte    time.Time
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+func newThrottleProcFromParsed(conf *service.ParsedConfig) (*throttleProc, error) {
+	t := &throttleProc{
+		closeChan: make(chan struct{}),
+	}
+	if !conf.Contains("messages_per_second") && !conf.Contains("bytes_per_second") {
+		return nil, errors.New("at least one of messages_per_second or bytes_per_second must be set")
+	}
+	if conf.Contains("messages_per_second") {
+		rate, err := conf.FieldFloat("messages_per_second")
+		if err != nil {
+			return nil, err
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("messages_per_second must be greater than zero, got %v", rate)
+		}
+		t.perMessage = time.Duration(float64(time.Second) / rate)
+	}
+	if conf.Contains("bytes_per_second") {
+		rate, err := conf.FieldFloat("bytes_per_second")
+		if err != nil {
+			return nil, err
+		}
+		if rate <= 0 {
+			return nil, fmt.Errorf("bytes_per_second must be greater than zero, got %v", rate)
+		}
+		t.perByte = float64(time.Second) / rate
+	}
+	return t, nil
+}
+
+// reserve returns the time at which a batch of messages and bytes may be
+// released, and delays the batches that follow accordingly.
+func (t *throttleProc) reserve(now time.Time, messages, bytes int) time.Time {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	slot := now
+	if t.perMessage > 0 && t.nextMessage.After(slot) {
+		slot = t.nextMessage
+	}
+	if t.perByte > 0 && t.nextByte.After(slot) {
+		slot = t.nextByte
+	}
+	if t.perMessage > 0 {
+		t.nextMessage = slot.Add(t.perMessage * time.Duration(messages))
+	}
+	if t.perByte > 0 {
+		t.nextByte = slot.Add(time.Duration(t.perByte * float64(bytes)))
+	}
+	return slot
+}
+
+func (t *throttleProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var bytes int
+	if t.perByte > 0 {
+		for _, msg := range batch {
+			mBytes, err := msg.AsBytes()
+			if err != nil {
+				return nil, err
+			}
+			bytes += len(mBytes)
+		}
+	}
+
+	if wait := time.Until(t.reserve(time.Now(), len(batch), bytes)); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.closeChan:
+		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (t *throttleProc) Close(ctx context.Context) error {
+	t.closeOnce.Do(func() {
+		close(t.closeChan)
+	})
+	return nil
+}
@@ -0,0 +1,102 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testThrottleProc(t *testing.T, confStr string) *throttleProc {
+	t.Helper()
+	conf, err := throttleProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+	proc, err := newThrottleProcFromParsed(conf)
+	require.NoError(t, err)
+	return proc
+}
+
+func TestThrottleBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`{}`,
+		`messages_per_second: 0`,
+		`bytes_per_second: -1`,
+	} {
+		conf, err := throttleProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err, confStr)
+		_, err = newThrottleProcFromParsed(conf)
+		assert.Error(t, err, confStr)
+	}
+}
+
+func TestThrottleReserve(t *testing.T) {
+	proc := testThrottleProc(t, `
+messages_per_second: 10
+bytes_per_second: 100
+`)
+
+	now := time.Now()
+	assert.Equal(t, now, proc.reserve(now, 1, 5))
+
+	// The message rate is the limiting factor.
+	assert.Equal(t, now.Add(time.Millisecond*100), proc.reserve(now, 2, 5))
+
+	// The bytes of the previous batch are the limiting factor.
+	assert.Equal(t, now.Add(time.Millisecond*300), proc.reserve(now, 1, 50))
+	assert.Equal(t, now.Add(time.Millisecond*800), proc.reserve(now, 1, 0))
+
+	// Idle periods do not accumulate a burst.
+	later := now.Add(time.Hour)
+	assert.Equal(t, later, proc.reserve(later, 1, 0))
+	assert.Equal(t, later.Add(time.Millisecond*100), proc.reserve(later, 1, 0))
+}
+
+func TestThrottleSmoothing(t *testing.T) {
+	proc := testThrottleProc(t, `messages_per_second: 100`)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte("foo"))})
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*40)
+}
+
+func TestThrottleContextCancelled(t *testing.T) {
+	proc := testThrottleProc(t, `messages_per_second: 0.001`)
+
+	_, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer done()
+
+	res, err := proc.ProcessBatch(ctx, service.MessageBatch{service.NewMessage(nil)})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, res)
+}
+
+func TestThrottleClose(t *testing.T) {
+	proc := testThrottleProc(t, `messages_per_second: 0.001`)
+
+	_, err := proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = proc.ProcessBatch(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+		close(done)
+	}()
+
+	require.NoError(t, proc.Close(context.Background()))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
@@ -0,0 +1,156 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func ttlProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Utility").
+		Summary("Sets a time at which messages expire, after which they are no longer delivered.").
+		Description(`
+The expiry of a message is enforced throughout the pipeline, once a message has expired it is dropped by [buffers](/docs/components/buffers/about) before it can be processed, and by outputs before it can be delivered. Dropped messages are acknowledged at their source, and are counted by the metrics `+"`buffer_expired` and `output_expired`"+` respectively. This is useful for latency sensitive pipelines where stale data is worse than no data at all.
+
+The expiry can either be set relative to now with the field `+"`ttl`"+`, or as an absolute time with the field `+"`expires_at`"+`, both of which support [interpolation functions](/docs/configuration/interpolation#bloblang-queries) and can therefore be derived from the metadata or contents of each message. When the field resolves to an empty string, or both fields are omitted, the expiry of the message is removed.
+
+When a message fails to resolve an expiry it is flagged as having failed, which can be handled using [error handling patterns](/docs/configuration/error_handling), and its expiry is left unchanged.
+
+### Routing Expired Messages
+
+The Bloblang function `+"[`expired`](/docs/guides/bloblang/functions#expired)"+` can be used in order to route expired messages with a `+"[`switch` output](/docs/components/outputs/switch)"+` rather than dropping them, in which case the expiry must be removed within the processors of the output they are routed to, otherwise they will be dropped by that output as well.`).
+		Field(service.NewInterpolatedStringField("ttl").
+			Description("A duration after which messages expire, relative to when they pass through the processor.").
+			Example("30s").
+			Example(`${! meta("ttl") }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField("expires_at").
+			Description("A time at which messages expire, which must resolve to either an [RFC 3339](https://www.rfc-editor.org/rfc/rfc3339) timestamp or a unix timestamp in seconds.").
+			Example(`${! meta("kafka_timestamp_unix").number() + 60 }`).
+			Example(`${! this.expires_at }`).
+			Optional()).
+		Example(
+			"Drop Stale Messages",
+			"In this example messages consumed from Kafka are dropped if they are not delivered to a HTTP endpoint within ten seconds of being produced.",
+			`
+pipeline:
+  processors:
+    - ttl:
+        expires_at: '${! meta("kafka_timestamp_unix").number() + 10 }'
+
+output:
+  http_client:
+    url: http://localhost:4195/latest
+    verb: POST
+`,
+		).
+		Example(
+			"Route Stale Messages",
+			"Here expired messages are routed to a file instead of being dropped, and their expiry is removed so that the file output delivers them.",
+			`
+pipeline:
+  processors:
+    - ttl:
+        ttl: 5s
+
+output:
+  switch:
+    cases:
+      - check: expired()
+        output:
+          file:
+            path: ./stale.jsonl
+          processors:
+            - ttl: {}
+      - output:
+          http_client:
+            url: http://localhost:4195/latest
+            verb: POST
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"ttl", ttlProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newTTLProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ttlProc struct {
+	ttl       *service.InterpolatedString
+	expiresAt *service.InterpolatedString
+}
+
+func newTTLProcFromParsed(conf *service.ParsedConfig) (*ttlProc, error) {
+	p := &ttlProc{}
+
+	var err error
+	if conf.Contains("ttl") {
+		if p.ttl, err = conf.FieldInterpolatedString("ttl"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("expires_at") {
+		if p.expiresAt, err = conf.FieldInterpolatedString("expires_at"); err != nil {
+			return nil, err
+		}
+	}
+	if p.ttl != nil && p.expiresAt != nil {
+		return nil, errors.New("cannot set both ttl and expires_at")
+	}
+	return p, nil
+}
+
+func (p *ttlProc) expiry(msg *service.Message, now time.Time) (time.Time, error) {
+	if p.ttl != nil {
+		ttlStr := p.ttl.String(msg)
+		if ttlStr == "" {
+			return time.Time{}, nil
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		return now.Add(ttl), nil
+	}
+	if p.expiresAt != nil {
+		expStr := p.expiresAt.String(msg)
+		if expStr == "" {
+			return time.Time{}, nil
+		}
+		if secs, err := strconv.ParseFloat(expStr, 64); err == nil {
+			return time.Unix(0, int64(secs*float64(time.Second))), nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, expStr)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse expires_at: %w", err)
+		}
+		return t, nil
+	}
+	return time.Time{}, nil
+}
+
+func (p *ttlProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	t, err := p.expiry(msg, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return service.MessageBatch{msg.WithContext(message.ContextWithExpiry(msg.Context(), t))}, nil
+}
+
+func (p *ttlProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,95 @@
+package pure
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestTTLProcessor(t *testing.T) {
+	expiredQuery, err := bloblang.Parse(`root = expired()`)
+	require.NoError(t, err)
+
+	isExpired := func(t *testing.T, msg *service.Message) bool {
+		t.Helper()
+		res, err := msg.BloblangQuery(expiredQuery)
+		require.NoError(t, err)
+		v, err := res.AsStructured()
+		require.NoError(t, err)
+		return v.(bool)
+	}
+
+	future := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	for _, test := range []struct {
+		name    string
+		config  string
+		content string
+		expired bool
+	}{
+		{name: "future ttl", config: `ttl: 1h`},
+		{name: "past ttl", config: `ttl: -1s`, expired: true},
+		{name: "interpolated ttl", config: `ttl: ${! content() }`, content: "-1m", expired: true},
+		{name: "unix expires_at", config: `expires_at: ${! content() }`, content: future},
+		{name: "past unix expires_at", config: `expires_at: 1000`, expired: true},
+		{name: "rfc3339 expires_at", config: `expires_at: 2006-01-02T15:04:05Z`, expired: true},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := ttlProcSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			proc, err := newTTLProcFromParsed(conf)
+			require.NoError(t, err)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.content)))
+			require.NoError(t, err)
+			require.Len(t, batch, 1)
+			assert.Equal(t, test.expired, isExpired(t, batch[0]))
+		})
+	}
+
+	// An empty expiry removes any existing one.
+	conf, err := ttlProcSpec().ParseYAML(`ttl: -1s`, nil)
+	require.NoError(t, err)
+	proc, err := newTTLProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage(nil))
+	require.NoError(t, err)
+	require.True(t, isExpired(t, batch[0]))
+
+	conf, err = ttlProcSpec().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+	proc, err = newTTLProcFromParsed(conf)
+	require.NoError(t, err)
+
+	batch, err = proc.Process(context.Background(), batch[0])
+	require.NoError(t, err)
+	assert.False(t, isExpired(t, batch[0]))
+}
+
+func TestTTLProcessorErrors(t *testing.T) {
+	conf, err := ttlProcSpec().ParseYAML(`
+ttl: 1s
+expires_at: 1000
+`, nil)
+	require.NoError(t, err)
+	_, err = newTTLProcFromParsed(conf)
+	require.Error(t, err)
+
+	conf, err = ttlProcSpec().ParseYAML(`ttl: ${! content() }`, nil)
+	require.NoError(t, err)
+	proc, err := newTTLProcFromParsed(conf)
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("nope")))
+	require.Error(t, err)
+}
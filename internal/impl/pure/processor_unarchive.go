@@ -4,12 +4,15 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -23,10 +26,10 @@ func unarchiveProcConfig() *service.ConfigSpec {
 		Description(`
 When a message is unarchived the new messages replace the original message in the batch. Messages that are selected but fail to unarchive (invalid format) will remain unchanged in the message batch but will be flagged as having failed, allowing you to [error handle them](/docs/configuration/error_handling).
 
-For the unarchive formats that contain file information (tar, zip), a metadata field is added to each message called ` + "`archive_filename`" + ` with the extracted filename.
+For the unarchive formats that contain file information (tar, zip), metadata fields are added to each message called ` + "`archive_filename`" + ` with the extracted filename, ` + "`archive_mode`" + ` with the permission bits of the file in octal, and ` + "`archive_mtime`" + ` with the modification time of the file in RFC 3339 format when known.
 `).
 		Field(service.NewStringAnnotatedEnumField("format", map[string]string{
-			`tar`:            `Extract messages from a unix standard tape archive.`,
+			`tar`:            `Extract messages from a unix standard tape archive, which can optionally be gzip compressed.`,
 			`zip`:            `Extract messages from a zip file.`,
 			`binary`:         `Extract messages from a [binary blob format](https://github.com/benthosdev/benthos/blob/main/internal/message/message.go#L96).`,
 			`lines`:          `Extract the lines of a message each into their own message.`,
@@ -34,7 +37,12 @@ For the unarchive formats that contain file information (tar, zip), a metadata f
 			`json_array`:     `Attempt to parse a message as a JSON array, and extract each element into its own message.`,
 			`json_map`:       `Attempt to parse the message as a JSON map and for each element of the map expands its contents into a new message. A metadata field is added to each message called ` + "`archive_key`" + ` with the relevant key from the top-level map.`,
 			`csv`:            `Attempt to parse the message as a csv file (header required) and for each row in the file expands its contents into a json object in a new message.`,
-		}).Description("The unarchiving format to apply."))
+		}).Description("The unarchiving format to apply.")).
+		Field(service.NewIntField("max_depth").
+			Description("The maximum depth of nested archives to extract for the `tar` and `zip` formats. Files within an archive that are themselves tar (optionally gzip compressed) or zip archives are extracted recursively until this depth is reached, and the `archive_filename` of each message is the path of the file through each archive, e.g. `outer.zip/inner.tar/foo.txt`. When set to zero nested archives are not extracted.").
+			Default(0).
+			Advanced().
+			Version("4.9.0"))
 }
 
 func init() {
@@ -50,16 +58,33 @@ func init() {
 
 type unarchiveFunc func(part *service.Message) (service.MessageBatch, error)
 
-func tarUnarchive(part *service.Message) (service.MessageBatch, error) {
-	pBytes, err := part.AsBytes()
-	if err != nil {
-		return nil, err
-	}
+// archiveEntry is a file extracted from a tar or zip archive.
+type archiveEntry struct {
+	name  string
+	mode  fs.FileMode
+	mtime time.Time
+	data  []byte
+}
 
-	buf := bytes.NewBuffer(pBytes)
-	tr := tar.NewReader(buf)
+type archiveEntriesFunc func(data []byte) ([]archiveEntry, error)
 
-	var newParts []*service.Message
+var gzipMagic = []byte{0x1f, 0x8b}
+
+func tarEntries(data []byte) ([]archiveEntry, error) {
+	var r io.Reader = bytes.NewReader(data)
+
+	// Gzip compressed tarballs are decompressed as they're read.
+	if bytes.HasPrefix(data, gzipMagic) {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	tr := tar.NewReader(r)
+
+	var entries []archiveEntry
 
 	// Iterate through the files in the archive.
 	for {
@@ -77,28 +102,24 @@ func tarUnarchive(part *service.Message) (service.MessageBatch, error) {
 			return nil, err
 		}
 
-		newPart := part.Copy()
-		newPart.SetBytes(newPartBuf.Bytes())
-		newPart.MetaSet("archive_filename", h.Name)
-		newParts = append(newParts, newPart)
+		entries = append(entries, archiveEntry{
+			name:  h.Name,
+			mode:  h.FileInfo().Mode(),
+			mtime: h.ModTime,
+			data:  newPartBuf.Bytes(),
+		})
 	}
-
-	return newParts, nil
+	return entries, nil
 }
 
-func zipUnarchive(part *service.Message) (service.MessageBatch, error) {
-	pBytes, err := part.AsBytes()
-	if err != nil {
-		return nil, err
-	}
-
-	buf := bytes.NewReader(pBytes)
+func zipEntries(data []byte) ([]archiveEntry, error) {
+	buf := bytes.NewReader(data)
 	zr, err := zip.NewReader(buf, int64(buf.Len()))
 	if err != nil {
 		return nil, err
 	}
 
-	var newParts service.MessageBatch
+	var entries []archiveEntry
 
 	// Iterate through the files in the archive.
 	for _, f := range zr.File {
@@ -108,17 +129,94 @@ func zipUnarchive(part *service.Message) (service.MessageBatch, error) {
 		}
 
 		newPartBuf := bytes.Buffer{}
-		if _, err = newPartBuf.ReadFrom(fr); err != nil {
+		_, err = newPartBuf.ReadFrom(fr)
+		_ = fr.Close()
+		if err != nil {
 			return nil, err
 		}
 
-		newPart := part.Copy()
-		newPart.SetBytes(newPartBuf.Bytes())
-		newPart.MetaSet("archive_filename", f.Name)
-		newParts = append(newParts, newPart)
+		entries = append(entries, archiveEntry{
+			name:  f.Name,
+			mode:  f.Mode(),
+			mtime: f.Modified,
+			data:  newPartBuf.Bytes(),
+		})
 	}
+	return entries, nil
+}
 
-	return newParts, nil
+// nestedEntriesFunc returns a func for extracting the entries of a nested
+// archive, or nil if the data does not resemble a tar or zip archive.
+func nestedEntriesFunc(data []byte) archiveEntriesFunc {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return zipEntries
+	case bytes.HasPrefix(data, gzipMagic):
+		// Only gzip compressed tarballs are treated as archives, other gzip
+		// data is left as it is.
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil
+		}
+		defer gr.Close()
+		header := make([]byte, 262)
+		if _, err := io.ReadFull(gr, header); err != nil || string(header[257:262]) != "ustar" {
+			return nil
+		}
+		return tarEntries
+	case len(data) >= 262 && string(data[257:262]) == "ustar":
+		return tarEntries
+	}
+	return nil
+}
+
+// archiveUnarchive creates an unarchiver from a func that extracts the entries
+// of an archive. Entries that are themselves tar or zip archives are extracted
+// recursively up to maxDepth levels, in which case the archive_filename of
+// each message is the path of the entry through each archive.
+func archiveUnarchive(entriesFn archiveEntriesFunc, maxDepth int) unarchiveFunc {
+	return func(part *service.Message) (service.MessageBatch, error) {
+		pBytes, err := part.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		var newParts service.MessageBatch
+
+		var walk func(prefix string, fn archiveEntriesFunc, data []byte, depth int) error
+		walk = func(prefix string, fn archiveEntriesFunc, data []byte, depth int) error {
+			entries, err := fn(data)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				name := prefix + e.name
+				if depth < maxDepth && !e.mode.IsDir() {
+					if nestedFn := nestedEntriesFunc(e.data); nestedFn != nil {
+						if err := walk(name+"/", nestedFn, e.data, depth+1); err != nil {
+							return fmt.Errorf("%v: %w", name, err)
+						}
+						continue
+					}
+				}
+
+				newPart := part.Copy()
+				newPart.SetBytes(e.data)
+				newPart.MetaSet("archive_filename", name)
+				newPart.MetaSet("archive_mode", fmt.Sprintf("%#o", e.mode.Perm()))
+				if !e.mtime.IsZero() {
+					newPart.MetaSet("archive_mtime", e.mtime.UTC().Format(time.RFC3339))
+				}
+				newParts = append(newParts, newPart)
+			}
+			return nil
+		}
+
+		if err := walk("", entriesFn, pBytes, 0); err != nil {
+			return nil, err
+		}
+		return newParts, nil
+	}
 }
 
 func binaryUnarchive(part *service.Message) (service.MessageBatch, error) {
@@ -275,12 +373,12 @@ func csvUnarchive(part *service.Message) (service.MessageBatch, error) {
 	return newParts, nil
 }
 
-func strToUnarchiver(str string) (unarchiveFunc, error) {
+func strToUnarchiver(str string, maxDepth int) (unarchiveFunc, error) {
 	switch str {
 	case "tar":
-		return tarUnarchive, nil
+		return archiveUnarchive(tarEntries, maxDepth), nil
 	case "zip":
-		return zipUnarchive, nil
+		return archiveUnarchive(zipEntries, maxDepth), nil
 	case "binary":
 		return binaryUnarchive, nil
 	case "lines":
@@ -309,11 +407,15 @@ func newUnarchiveFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	if err != nil {
 		return nil, err
 	}
-	return newUnarchive(mgr, formatStr)
+	maxDepth, err := conf.FieldInt("max_depth")
+	if err != nil {
+		return nil, err
+	}
+	return newUnarchive(mgr, formatStr, maxDepth)
 }
 
-func newUnarchive(nm *service.Resources, format string) (*unarchiveProc, error) {
-	unarchiver, err := strToUnarchiver(format)
+func newUnarchive(nm *service.Resources, format string, maxDepth int) (*unarchiveProc, error) {
+	unarchiver, err := strToUnarchiver(format, maxDepth)
 	if err != nil {
 		return nil, err
 	}
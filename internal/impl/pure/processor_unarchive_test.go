@@ -4,9 +4,12 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -140,6 +143,109 @@ format: zip
 	}
 }
 
+func writeTestTar(t *testing.T, w io.Writer, files map[string][]byte, order ...string) {
+	t.Helper()
+
+	tw := tar.NewWriter(w)
+	for _, name := range order {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o640,
+			Size:    int64(len(files[name])),
+			ModTime: time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC),
+		}))
+		_, err := tw.Write(files[name])
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func TestUnarchiveTarGzipMetadata(t *testing.T) {
+	conf, err := unarchiveProcConfig().ParseYAML(`
+format: tar
+`, nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	writeTestTar(t, gw, map[string][]byte{
+		"foo.txt": []byte("foo"),
+		"bar.txt": []byte("bar"),
+	}, "foo.txt", "bar.txt")
+	require.NoError(t, gw.Close())
+
+	proc, err := newUnarchiveFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	for i, exp := range []string{"foo", "bar"} {
+		mBytes, err := msgs[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(mBytes))
+
+		v, _ := msgs[i].MetaGet("archive_filename")
+		assert.Equal(t, exp+".txt", v)
+		v, _ = msgs[i].MetaGet("archive_mode")
+		assert.Equal(t, "0640", v)
+		v, _ = msgs[i].MetaGet("archive_mtime")
+		assert.Equal(t, "2022-10-01T12:00:00Z", v)
+	}
+}
+
+func TestUnarchiveNested(t *testing.T) {
+	var innerTar bytes.Buffer
+	writeTestTar(t, &innerTar, map[string][]byte{
+		"baz.txt": []byte("baz"),
+	}, "baz.txt")
+
+	var innerZip bytes.Buffer
+	zw := zip.NewWriter(&innerZip)
+	fw, err := zw.Create("inner.tar")
+	require.NoError(t, err)
+	_, err = fw.Write(innerTar.Bytes())
+	require.NoError(t, err)
+	fw, err = zw.Create("bar.txt")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("bar"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	var outer bytes.Buffer
+	writeTestTar(t, &outer, map[string][]byte{
+		"foo.txt":    []byte("foo"),
+		"nested.zip": innerZip.Bytes(),
+	}, "foo.txt", "nested.zip")
+
+	for depth, exp := range map[int][]string{
+		0: {"foo.txt", "nested.zip"},
+		1: {"foo.txt", "nested.zip/inner.tar", "nested.zip/bar.txt"},
+		2: {"foo.txt", "nested.zip/inner.tar/baz.txt", "nested.zip/bar.txt"},
+		5: {"foo.txt", "nested.zip/inner.tar/baz.txt", "nested.zip/bar.txt"},
+	} {
+		conf, err := unarchiveProcConfig().ParseYAML(fmt.Sprintf(`
+format: tar
+max_depth: %v
+`, depth), nil)
+		require.NoError(t, err)
+
+		proc, err := newUnarchiveFromParsed(conf, service.MockResources())
+		require.NoError(t, err)
+
+		msgs, err := proc.Process(context.Background(), service.NewMessage(outer.Bytes()))
+		require.NoError(t, err)
+
+		var names []string
+		for _, m := range msgs {
+			v, _ := m.MetaGet("archive_filename")
+			names = append(names, v)
+		}
+		assert.Equal(t, exp, names, "depth %v", depth)
+	}
+}
+
 func TestUnarchiveLines(t *testing.T) {
 	conf, err := unarchiveProcConfig().ParseYAML(`
 format: lines
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
@@ -38,7 +39,13 @@ The field ` + "`max_loops`" + `, if greater than zero, caps the number of loops
 
 If following a loop execution the number of messages in a batch is reduced to zero the loop is exited regardless of the condition result. If following a loop execution there are more than 1 message batches the query is checked against the first batch only.
 
-The conditions of this processor are applied across entire message batches. You can find out more about batching [in this doc](/docs/configuration/batching).`,
+The conditions of this processor are applied across entire message batches. You can find out more about batching [in this doc](/docs/configuration/batching).
+
+### Loop State
+
+The progress of the loop can be accessed from within the check and the child processors with the ` + "[`loop_state` function](/docs/guides/bloblang/functions#loop_state)" + `, which returns an object containing the number of iterations completed so far as ` + "`iteration`" + `, the error of the first message following the most recent iteration as ` + "`last_error`" + `, and any values set by the ` + "`state_map`" + ` as ` + "`values`" + `.
+
+The field ` + "`state_map`" + ` is a mapping executed against the first message following each iteration, where the result replaces the ` + "`values`" + ` of the loop state, and the previous values are accessible with ` + "`loop_state().values`" + `. This makes it possible to carry information such as a pagination cursor across iterations without storing it within the messages.`,
 		Config: docs.FieldComponent().WithChildren(
 			docs.FieldBool("at_least_once", "Whether to always run the child processors at least one time."),
 			docs.FieldInt("max_loops", "An optional maximum number of loops to execute. Helps protect against accidentally creating infinite loops.").Advanced(),
@@ -48,6 +55,11 @@ The conditions of this processor are applied across entire message batches. You
 				`errored()`,
 				`this.urls.unprocessed.length() > 0`,
 			).HasDefault(""),
+			docs.FieldBloblang(
+				"state_map",
+				"An optional [Bloblang mapping](/docs/guides/bloblang/about/) executed against the first message following each loop, where the result is carried to the next loop as `loop_state().values`.",
+				`root.cursor = this.next_cursor`,
+			).HasDefault("").AtVersion("4.9.0"),
 			docs.FieldProcessor("processors", "A list of child processors to execute on each loop.").Array(),
 		).ChildDefaultAndTypesFromStruct(processor.NewWhileConfig()),
 	})
@@ -60,6 +72,7 @@ type whileProc struct {
 	maxLoops    int
 	atLeastOnce bool
 	check       *mapping.Executor
+	stateMap    *mapping.Executor
 	children    []processor.V1
 	log         log.Modular
 
@@ -78,6 +91,13 @@ func newWhile(conf processor.WhileConfig, mgr bundle.NewManagement) (*whileProc,
 		return nil, errors.New("a check query is required")
 	}
 
+	var stateMap *mapping.Executor
+	if len(conf.StateMap) > 0 {
+		if stateMap, err = mgr.BloblEnvironment().NewMapping(conf.StateMap); err != nil {
+			return nil, fmt.Errorf("failed to parse state map: %w", err)
+		}
+	}
+
 	var children []processor.V1
 	for i, pconf := range conf.Processors {
 		pMgr := mgr.IntoPath("while", "processors", strconv.Itoa(i))
@@ -92,6 +112,7 @@ func newWhile(conf processor.WhileConfig, mgr bundle.NewManagement) (*whileProc,
 		maxLoops:    conf.MaxLoops,
 		atLeastOnce: conf.AtLeastOnce,
 		check:       check,
+		stateMap:    stateMap,
 		children:    children,
 		log:         mgr.Logger(),
 		shutSig:     shutdown.NewSignaller(),
@@ -107,11 +128,60 @@ func (w *whileProc) checkMsg(msg message.Batch) bool {
 	return c
 }
 
+// attachLoopState returns copies of the batches where each message carries the
+// loop state, including messages created by child processors. The batches are
+// copied as they may be owned by the caller.
+func attachLoopState(msgs []message.Batch, state *query.LoopState) []message.Batch {
+	newMsgs := make([]message.Batch, len(msgs))
+	for i, msg := range msgs {
+		newMsg := make(message.Batch, len(msg))
+		for j, p := range msg {
+			newMsg[j] = query.WithLoopState(p, state)
+		}
+		newMsgs[i] = newMsg
+	}
+	return newMsgs
+}
+
+// updateState updates the loop state following an iteration.
+func (w *whileProc) updateState(msg message.Batch, state *query.LoopState) {
+	state.Iteration++
+	state.LastError = ""
+	if err := msg.Get(0).ErrorGet(); err != nil {
+		state.LastError = err.Error()
+	}
+	if w.stateMap == nil {
+		return
+	}
+
+	res, err := w.stateMap.MapPart(0, msg)
+	if err != nil {
+		w.log.Errorf("State map failed for loop: %v", err)
+		return
+	}
+	if res == nil {
+		state.Values = nil
+		return
+	}
+	if state.Values, err = res.AsStructuredMut(); err != nil {
+		w.log.Errorf("State map failed for loop: %v", err)
+	}
+}
+
 func (w *whileProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg message.Batch) (msgs []message.Batch, res error) {
-	msgs = []message.Batch{msg}
+	// The state of any loop that this processor is nested within is restored
+	// once finished.
+	outerState := query.GetLoopState(msg.Get(0))
+	state := &query.LoopState{}
+	msgs = attachLoopState([]message.Batch{msg}, state)
+	defer func() {
+		if len(msgs) > 0 {
+			msgs = attachLoopState(msgs, outerState)
+		}
+	}()
 
 	loops := 0
-	condResult := w.atLeastOnce || w.checkMsg(msg)
+	condResult := w.atLeastOnce || w.checkMsg(msgs[0])
 	for condResult {
 		if w.shutSig.ShouldCloseAtLeisure() || ctx.Err() != nil {
 			return nil, component.ErrTypeClosed
@@ -130,6 +200,8 @@ func (w *whileProc) ProcessBatch(ctx context.Context, spans []*tracing.Span, msg
 		if len(msgs) == 0 {
 			return
 		}
+		msgs = attachLoopState(msgs, state)
+		w.updateState(msgs[0], state)
 		condResult = w.checkMsg(msgs[0])
 		loops++
 	}
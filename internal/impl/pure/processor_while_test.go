@@ -187,3 +187,90 @@ func TestWhileWithStaticTrue(t *testing.T) {
 	_, err = c.ProcessBatch(ctx, message.QuickBatch([][]byte{[]byte("bar")}))
 	assert.NoError(t, err)
 }
+
+func TestWhileLoopState(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "while"
+	conf.While.AtLeastOnce = true
+	conf.While.Check = `loop_state().values.cursor != null`
+	conf.While.StateMap = `root.cursor = this.next
+root.pages = (loop_state().values.pages | []).append(this.page)`
+
+	procConf := processor.NewConfig()
+	procConf.Type = "bloblang"
+	procConf.Bloblang = `root.page = loop_state().iteration
+root.next = if loop_state().iteration < 2 { "c" + loop_state().iteration.string() }`
+
+	conf.While.Processors = append(conf.While.Processors, procConf)
+
+	c, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msg, res := c.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(`{}`)}))
+	require.Nil(t, res)
+	require.Len(t, msg, 1)
+	assert.Equal(t, [][]byte{[]byte(`{"page":2}`)}, message.GetAllBytes(msg[0]))
+
+	// The state is no longer accessible once the loop has finished.
+	checkConf := processor.NewConfig()
+	checkConf.Type = "bloblang"
+	checkConf.Bloblang = `root = loop_state()`
+
+	check, err := mock.NewManager().NewProcessor(checkConf)
+	require.NoError(t, err)
+
+	msg, res = check.ProcessBatch(context.Background(), msg[0])
+	require.Nil(t, res)
+	assert.Equal(t, [][]byte{[]byte(`null`)}, message.GetAllBytes(msg[0]))
+}
+
+func TestWhileLoopStateLastError(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "while"
+	conf.While.AtLeastOnce = true
+	conf.While.Check = `errored() && loop_state().iteration < 3`
+
+	failConf := processor.NewConfig()
+	failConf.Type = "bloblang"
+	failConf.Bloblang = `root = if loop_state().last_error == null { throw("first failure") } else { loop_state().last_error + " " + loop_state().iteration.string() }`
+
+	conf.While.Processors = append(conf.While.Processors, failConf)
+
+	c, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msg, res := c.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(`foo`)}))
+	require.Nil(t, res)
+	require.Len(t, msg, 1)
+	assert.Error(t, msg[0].Get(0).ErrorGet())
+	assert.Equal(t, "failed assignment (line 1): first failure 2", string(msg[0].Get(0).AsBytes()))
+}
+
+func TestWhileLoopStateCallerBatch(t *testing.T) {
+	conf := processor.NewConfig()
+	conf.Type = "while"
+	conf.While.Check = `loop_state().iteration < 2`
+
+	procConf := processor.NewConfig()
+	procConf.Type = "bloblang"
+	procConf.Bloblang = `root = content().string() + "x"`
+
+	conf.While.Processors = append(conf.While.Processors, procConf)
+
+	c, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	// The batch provided to the processor belongs to the caller, and so the
+	// loop state must not be attached to its messages in place.
+	input := message.QuickBatch([][]byte{[]byte(`foo`), []byte(`bar`)})
+	inputParts := []*message.Part{input[0], input[1]}
+
+	msg, res := c.ProcessBatch(context.Background(), input)
+	require.Nil(t, res)
+	require.Len(t, msg, 1)
+	assert.Equal(t, [][]byte{[]byte(`fooxx`), []byte(`barxx`)}, message.GetAllBytes(msg[0]))
+
+	require.Len(t, input, 2)
+	assert.Same(t, inputParts[0], input[0])
+	assert.Same(t, inputParts[1], input[1])
+}
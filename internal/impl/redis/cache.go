@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -35,6 +36,17 @@ func redisCacheConfig() *service.ConfigSpec {
 			Optional().
 			Advanced()).
 		Field(service.NewBackOffField("retries", false, retriesDefaults).
+			Advanced()).
+		Field(service.NewObjectField("client_cache",
+			service.NewBoolField("enabled").
+				Description("Whether to cache the values of keys in memory.").
+				Default(false),
+			service.NewIntField("max_keys").
+				Description("The maximum number of keys to cache in memory, where arbitrary keys are evicted to make room for new ones.").
+				Default(10000),
+		).
+			Description("Caches the values of keys in memory using [server assisted client side caching](https://redis.io/docs/manual/client-side-caching/), where the server notifies the cache of keys modified by any client so that they're removed. This requires Redis 6 or newer and is only supported by the `kind` `simple`. Values are only cached while the separate connection that receives notifications is established.").
+			Version("4.9.0").
 			Advanced())
 
 	return spec
@@ -44,15 +56,36 @@ func init() {
 	err := service.RegisterCache(
 		"redis", redisCacheConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
-			return newRedisCacheFromConfig(conf)
+			return newRedisCacheFromConfig(conf, mgr.Logger())
 		})
 	if err != nil {
 		panic(err)
 	}
 }
 
-func newRedisCacheFromConfig(conf *service.ParsedConfig) (*redisCache, error) {
-	client, err := getClient(conf)
+func newRedisCacheFromConfig(conf *service.ParsedConfig, log *service.Logger) (*redisCache, error) {
+	kind, opts, err := getClientOptions(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	ccConf := conf.Namespace("client_cache")
+	ccEnabled, err := ccConf.FieldBool("enabled")
+	if err != nil {
+		return nil, err
+	}
+	if ccEnabled && kind != "simple" {
+		return nil, fmt.Errorf("client_cache is not supported by the kind %v", kind)
+	}
+	maxKeys, err := ccConf.FieldInt("max_keys")
+	if err != nil {
+		return nil, err
+	}
+	if ccEnabled && maxKeys <= 0 {
+		return nil, errors.New("client_cache max_keys must be greater than zero")
+	}
+
+	client, err := newClient(kind, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +110,15 @@ func newRedisCacheFromConfig(conf *service.ParsedConfig) (*redisCache, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newRedisCache(ttl, prefix, client, backOff)
+
+	r, err := newRedisCache(ttl, prefix, client, backOff)
+	if err != nil {
+		return nil, err
+	}
+	if ccEnabled {
+		r.clientCache = newRedisClientCache(opts.Simple(), maxKeys, log)
+	}
+	return r, nil
 }
 
 //------------------------------------------------------------------------------
@@ -87,6 +128,10 @@ type redisCache struct {
 	defaultTTL time.Duration
 	prefix     string
 
+	// Optionally caches values in memory, in which case values are read
+	// through its tracked client when it's available.
+	clientCache *redisClientCache
+
 	boffPool sync.Pool
 }
 
@@ -118,9 +163,19 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}()
 
 	key = r.prefix + key
+	if r.clientCache != nil {
+		if value, ok := r.clientCache.lookup(key); ok {
+			return value, nil
+		}
+	}
+
 	for {
-		res, err := r.client.Get(key).Result()
+		client, entries := r.readClient(key)
+		res, err := client.Get(key).Result()
 		if err == nil {
+			if entries != nil {
+				r.clientCache.fill(key, entries[0], []byte(res))
+			}
 			return []byte(res), nil
 		}
 		if errors.Is(err, redis.Nil) {
@@ -139,6 +194,79 @@ func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
 	}
 }
 
+func (r *redisCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	cached := map[string][]byte{}
+	if r.clientCache != nil {
+		var misses []string
+		for _, k := range keys {
+			if value, ok := r.clientCache.lookup(r.prefix + k); ok {
+				cached[k] = value
+			} else {
+				misses = append(misses, k)
+			}
+		}
+		if keys = misses; len(keys) == 0 {
+			return cached, nil
+		}
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = r.prefix + k
+	}
+
+	for {
+		client, entries := r.readClient(prefixed...)
+		pipe := client.Pipeline()
+		cmds := make([]*redis.StringCmd, len(keys))
+		for i, k := range prefixed {
+			cmds[i] = pipe.Get(k)
+		}
+		_, err := pipe.Exec()
+
+		// The pipeline returns the first error of any command, which includes
+		// misses, and so we check each command for genuine errors instead.
+		values := make(map[string][]byte, len(keys)+len(cached))
+		for k, v := range cached {
+			values[k] = v
+		}
+		for i, cmd := range cmds {
+			res, cErr := cmd.Result()
+			if cErr == nil {
+				values[keys[i]] = []byte(res)
+				if entries != nil {
+					r.clientCache.fill(prefixed[i], entries[i], []byte(res))
+				}
+				continue
+			}
+			if !errors.Is(cErr, redis.Nil) {
+				err = cErr
+				break
+			}
+			err = nil
+		}
+		if err == nil {
+			return values, nil
+		}
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			return nil, err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
 func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	boff := r.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -147,6 +275,7 @@ func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl *tim
 	}()
 
 	key = r.prefix + key
+	defer r.invalidate(key)
 
 	var t time.Duration
 	if ttl != nil {
@@ -181,6 +310,7 @@ func (r *redisCache) Add(ctx context.Context, key string, value []byte, ttl *tim
 	}()
 
 	key = r.prefix + key
+	defer r.invalidate(key)
 
 	var t time.Duration
 	if ttl != nil {
@@ -210,6 +340,70 @@ func (r *redisCache) Add(ctx context.Context, key string, value []byte, ttl *tim
 	}
 }
 
+func (r *redisCache) AddMulti(ctx context.Context, items ...service.CacheItem) map[string]error {
+	boff := r.boffPool.Get().(backoff.BackOff)
+	defer func() {
+		boff.Reset()
+		r.boffPool.Put(boff)
+	}()
+
+	defer func(items []service.CacheItem) {
+		for _, item := range items {
+			r.invalidate(r.prefix + item.Key)
+		}
+	}(items)
+
+	errs := map[string]error{}
+	for {
+		pipe := r.client.Pipeline()
+		cmds := make([]*redis.BoolCmd, len(items))
+		for i, item := range items {
+			t := r.defaultTTL
+			if item.TTL != nil {
+				t = *item.TTL
+			}
+			cmds[i] = pipe.SetNX(r.prefix+item.Key, item.Value, t)
+		}
+		_, _ = pipe.Exec()
+
+		// Only items that failed are reattempted, as those that succeeded would
+		// otherwise be reported as already existing.
+		var failed []service.CacheItem
+		var err error
+		for i, cmd := range cmds {
+			set, cErr := cmd.Result()
+			if cErr != nil {
+				failed = append(failed, items[i])
+				err = cErr
+				continue
+			}
+			if !set {
+				errs[items[i].Key] = service.ErrKeyAlreadyExists
+			}
+		}
+		if len(failed) == 0 {
+			return errs
+		}
+		items = failed
+
+		wait := boff.NextBackOff()
+		if wait == backoff.Stop {
+			for _, item := range items {
+				errs[item.Key] = err
+			}
+			return errs
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			for _, item := range items {
+				errs[item.Key] = err
+			}
+			return errs
+		}
+	}
+}
+
 func (r *redisCache) Delete(ctx context.Context, key string) error {
 	boff := r.boffPool.Get().(backoff.BackOff)
 	defer func() {
@@ -218,6 +412,7 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 	}()
 
 	key = r.prefix + key
+	defer r.invalidate(key)
 
 	for {
 		_, err := r.client.Del(key).Result()
@@ -237,6 +432,28 @@ func (r *redisCache) Delete(ctx context.Context, key string) error {
 	}
 }
 
+// readClient returns the client to read keys with, which is the tracked client
+// of the client cache along with entries to fill when it's available.
+func (r *redisCache) readClient(keys ...string) (redis.UniversalClient, []*redisClientCacheEntry) {
+	if r.clientCache != nil {
+		if client, entries := r.clientCache.track(keys...); client != nil {
+			return client, entries
+		}
+	}
+	return r.client, nil
+}
+
+func (r *redisCache) invalidate(key string) {
+	if r.clientCache != nil {
+		r.clientCache.invalidate(key)
+	}
+}
+
 func (r *redisCache) Close(ctx context.Context) error {
+	if r.clientCache != nil {
+		if err := r.clientCache.Close(ctx); err != nil {
+			return err
+		}
+	}
 	return r.client.Close()
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/integration"
+	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 func TestIntegrationRedisCache(t *testing.T) {
@@ -39,7 +40,7 @@ func TestIntegrationRedisCache(t *testing.T) {
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources().Logger())
 		if cErr != nil {
 			return cErr
 		}
@@ -131,7 +132,7 @@ kind: cluster
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources().Logger())
 		if cErr != nil {
 			return cErr
 		}
@@ -243,7 +244,7 @@ master: mymaster
 			return cErr
 		}
 
-		r, cErr := newRedisCacheFromConfig(pConf)
+		r, cErr := newRedisCacheFromConfig(pConf, service.MockResources().Logger())
 		if cErr != nil {
 			return cErr
 		}
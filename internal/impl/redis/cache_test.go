@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestRedisCacheClientCache(t *testing.T) {
+	var mut sync.Mutex
+	values := map[string]string{"foo": "foo1", "bar": "bar1"}
+	gets := map[string]int{}
+
+	s := startRESPServer(t, func(cmd []string) string {
+		mut.Lock()
+		defer mut.Unlock()
+		switch strings.ToLower(cmd[0]) {
+		case "client":
+			if strings.EqualFold(cmd[1], "id") {
+				return ":42\r\n"
+			}
+			return "+OK\r\n"
+		case "get":
+			gets[cmd[1]]++
+			if v, exists := values[cmd[1]]; exists {
+				return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+			}
+			return "$-1\r\n"
+		case "set":
+			values[cmd[1]] = cmd[2]
+			return "+OK\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	getCount := func(key string) int {
+		mut.Lock()
+		defer mut.Unlock()
+		return gets[key]
+	}
+
+	pConf, err := redisCacheConfig().ParseYAML(`
+url: `+s.url()+`
+client_cache:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newRedisCacheFromConfig(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	ctx := context.Background()
+	assert.Eventually(t, func() bool {
+		c.clientCache.mut.Lock()
+		defer c.clientCache.mut.Unlock()
+		return c.clientCache.client != nil
+	}, time.Second*5, time.Millisecond*10)
+
+	// Repeated reads are served from memory.
+	for i := 0; i < 3; i++ {
+		v, err := c.Get(ctx, "foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo1", string(v))
+	}
+	assert.Equal(t, 1, getCount("foo"))
+	assert.Contains(t, s.received(), []string{"client", "tracking", "on", "redirect", "42"})
+
+	// Keys modified by other clients are invalidated by the server.
+	mut.Lock()
+	values["foo"] = "foo2"
+	mut.Unlock()
+	s.publish(redisInvalidateChannel, "*1\r\n$3\r\nfoo\r\n")
+	assert.Eventually(t, func() bool {
+		v, err := c.Get(ctx, "foo")
+		return err == nil && string(v) == "foo2"
+	}, time.Second*5, time.Millisecond*10)
+
+	// Keys set by the cache itself are invalidated immediately.
+	require.NoError(t, c.Set(ctx, "foo", []byte("foo3"), nil))
+	v, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo3", string(v))
+
+	res, err := c.GetMulti(ctx, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": []byte("foo3"), "bar": []byte("bar1")}, res)
+	foos, bars := getCount("foo"), getCount("bar")
+	_, err = c.GetMulti(ctx, "foo", "bar")
+	require.NoError(t, err)
+	assert.Equal(t, foos, getCount("foo"))
+	assert.Equal(t, bars, getCount("bar"))
+
+	// A flush invalidates all keys.
+	s.publish(redisInvalidateChannel, "*-1\r\n")
+	assert.Eventually(t, func() bool {
+		_, _ = c.Get(ctx, "bar")
+		return getCount("bar") > bars
+	}, time.Second*5, time.Millisecond*10)
+
+	// Keys aren't cached while the invalidation connection is lost.
+	bars = getCount("bar")
+	s.dropSubscribers()
+	assert.Eventually(t, func() bool {
+		_, _ = c.Get(ctx, "bar")
+		return getCount("bar") >= bars+2
+	}, time.Second*5, time.Millisecond*10)
+
+	// The connection is then reestablished with a new tracked client.
+	assert.Eventually(t, func() bool {
+		var subscribes int
+		for _, cmd := range s.received() {
+			if cmd[0] == "SUBSCRIBE" {
+				subscribes++
+			}
+		}
+		return subscribes == 2
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestRedisCacheClientCacheKind(t *testing.T) {
+	pConf, err := redisCacheConfig().ParseYAML(`
+url: redis://localhost:6379
+kind: cluster
+client_cache:
+  enabled: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisCacheFromConfig(pConf, service.MockResources().Logger())
+	require.Error(t, err)
+}
@@ -30,7 +30,7 @@ Some cloud hosted instances of Redis (such as Azure Cache) might need some hand
 			Example("redis://localhost:6379/1").
 			Example("redis://localhost:6379/1,redis://localhost:6380/1"),
 		service.NewStringEnumField("kind", "simple", "cluster", "failover").
-			Description("Specifies a simple, cluster-aware, or failover-aware redis client.").
+			Description("Specifies a simple, cluster-aware, or failover-aware redis client. Cluster-aware clients route commands to the node that serves the hash slot of their keys and follow `MOVED` and `ASK` redirects when slots are migrated.").
 			Default("simple").
 			Advanced(),
 		service.NewStringField("master").
@@ -43,24 +43,34 @@ Some cloud hosted instances of Redis (such as Azure Cache) might need some hand
 }
 
 func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error) {
-	urlStr, err := parsedConf.FieldString("url")
+	kind, opts, err := getClientOptions(parsedConf)
 	if err != nil {
 		return nil, err
 	}
+	return newClient(kind, opts)
+}
+
+// getClientOptions returns the kind and options of the client of a config,
+// which allows components to customise the options before creating clients.
+func getClientOptions(parsedConf *service.ParsedConfig) (string, *redis.UniversalOptions, error) {
+	urlStr, err := parsedConf.FieldString("url")
+	if err != nil {
+		return "", nil, err
+	}
 
 	kind, err := parsedConf.FieldString("kind")
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	master, err := parsedConf.FieldString("master")
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
 	tlsConf, tlsEnabled, err := parsedConf.FieldTLSToggled("tls")
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 	if !tlsEnabled {
 		tlsConf = nil
@@ -75,7 +85,7 @@ func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error)
 	for _, v := range strings.Split(urlStr, ",") {
 		url, err := url.Parse(v)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 
 		if url.Scheme == "tcp" {
@@ -84,7 +94,7 @@ func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error)
 
 		rurl, err := redis.ParseURL(url.String())
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 
 		addrs = append(addrs, rurl.Addr)
@@ -92,27 +102,25 @@ func getClient(parsedConf *service.ParsedConfig) (redis.UniversalClient, error)
 		pass = rurl.Password
 	}
 
-	var client redis.UniversalClient
-	opts := &redis.UniversalOptions{
-		Addrs:     addrs,
-		DB:        redisDB,
-		Password:  pass,
-		TLSConfig: tlsConf,
-	}
+	return kind, &redis.UniversalOptions{
+		Addrs:      addrs,
+		DB:         redisDB,
+		Password:   pass,
+		TLSConfig:  tlsConf,
+		MasterName: master,
+	}, nil
+}
 
+func newClient(kind string, opts *redis.UniversalOptions) (redis.UniversalClient, error) {
 	switch kind {
 	case "simple":
-		client = redis.NewClient(opts.Simple())
+		return redis.NewClient(opts.Simple()), nil
 	case "cluster":
-		client = redis.NewClusterClient(opts.Cluster())
+		return redis.NewClusterClient(opts.Cluster()), nil
 	case "failover":
-		opts.MasterName = master
-		client = redis.NewFailoverClient(opts.Failover())
-	default:
-		err = fmt.Errorf("invalid redis kind: %s", kind)
+		return redis.NewFailoverClient(opts.Failover()), nil
 	}
-
-	return client, err
+	return nil, fmt.Errorf("invalid redis kind: %s", kind)
 }
 
 func clientFromConfig(r old.Config) (redis.UniversalClient, error) {
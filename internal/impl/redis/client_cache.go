@@ -0,0 +1,319 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// The channel that servers publish invalidated keys to.
+const redisInvalidateChannel = "__redis__:invalidate"
+
+type redisClientCacheEntry struct {
+	value   []byte
+	pending bool
+}
+
+// redisClientCache keeps the values of keys read through a client in memory,
+// where server assisted client side caching is used to remove keys once they
+// are modified by any client.
+//
+// The go-redis client only supports RESP2, where tracked connections redirect
+// invalidation messages to a separate connection subscribed to the channel
+// __redis__:invalidate. The tracked client is replaced whenever that connection
+// is lost, as the invalidations of its connections would be lost too, and no
+// keys are cached until it's reestablished.
+type redisClientCache struct {
+	opts    *redis.Options
+	maxKeys int
+	log     *service.Logger
+
+	mut     sync.Mutex
+	client  *redis.Client
+	conn    net.Conn
+	entries map[string]*redisClientCacheEntry
+
+	closeChan  chan struct{}
+	closedChan chan struct{}
+	closeOnce  sync.Once
+}
+
+func newRedisClientCache(opts *redis.Options, maxKeys int, log *service.Logger) *redisClientCache {
+	c := &redisClientCache{
+		opts:       opts,
+		maxKeys:    maxKeys,
+		log:        log,
+		entries:    map[string]*redisClientCacheEntry{},
+		closeChan:  make(chan struct{}),
+		closedChan: make(chan struct{}),
+	}
+	go c.loop()
+	return c
+}
+
+func (c *redisClientCache) loop() {
+	defer close(c.closedChan)
+
+	for {
+		conn, r, id, err := c.subscribe()
+		if err == nil {
+			opts := *c.opts
+			opts.OnConnect = func(cn *redis.Conn) error {
+				return cn.Process(redis.NewStatusCmd("client", "tracking", "on", "redirect", id))
+			}
+			client := redis.NewClient(&opts)
+
+			c.mut.Lock()
+			c.client, c.conn = client, conn
+			c.mut.Unlock()
+			select {
+			case <-c.closeChan:
+				_ = conn.Close()
+			default:
+			}
+
+			err = c.listen(r)
+
+			c.mut.Lock()
+			c.client, c.conn = nil, nil
+			c.entries = map[string]*redisClientCacheEntry{}
+			c.mut.Unlock()
+
+			_ = client.Close()
+			_ = conn.Close()
+		}
+
+		select {
+		case <-c.closeChan:
+			return
+		default:
+		}
+		c.log.Errorf("Client side caching is disabled due to failed invalidation connection: %v", err)
+
+		select {
+		case <-time.After(time.Second):
+		case <-c.closeChan:
+			return
+		}
+	}
+}
+
+// subscribe opens a connection that receives the invalidation messages of
+// tracked connections, and returns its client id.
+func (c *redisClientCache) subscribe() (net.Conn, *bufio.Reader, int64, error) {
+	dialer := &net.Dialer{Timeout: time.Second * 5, KeepAlive: time.Minute}
+	conn, err := dialer.Dial("tcp", c.opts.Addr)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if c.opts.TLSConfig != nil {
+		conn = tls.Client(conn, c.opts.TLSConfig)
+	}
+
+	var cmds [][]string
+	if c.opts.Password != "" {
+		cmds = append(cmds, []string{"AUTH", c.opts.Password})
+	}
+	cmds = append(cmds, []string{"CLIENT", "ID"}, []string{"SUBSCRIBE", redisInvalidateChannel})
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second * 5))
+	for _, cmd := range cmds {
+		if _, err = conn.Write(appendRESPCommand(nil, cmd...)); err != nil {
+			_ = conn.Close()
+			return nil, nil, 0, err
+		}
+	}
+
+	r := bufio.NewReader(conn)
+	var id int64
+	for _, cmd := range cmds {
+		res, err := readRESPReply(r)
+		if err != nil {
+			_ = conn.Close()
+			return nil, nil, 0, err
+		}
+		if cmd[0] == "CLIENT" {
+			var ok bool
+			if id, ok = res.(int64); !ok {
+				_ = conn.Close()
+				return nil, nil, 0, fmt.Errorf("unexpected reply to CLIENT ID: %v", res)
+			}
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, id, nil
+}
+
+func (c *redisClientCache) listen(r *bufio.Reader) error {
+	for {
+		res, err := readRESPReply(r)
+		if err != nil {
+			return err
+		}
+		msg, ok := res.([]any)
+		if !ok || len(msg) != 3 || msg[0] != "message" {
+			continue
+		}
+
+		c.mut.Lock()
+		switch keys := msg[2].(type) {
+		case []any:
+			for _, k := range keys {
+				if s, ok := k.(string); ok {
+					delete(c.entries, s)
+				}
+			}
+		default:
+			// A nil message is sent when the database is flushed.
+			c.entries = map[string]*redisClientCacheEntry{}
+		}
+		c.mut.Unlock()
+	}
+}
+
+// lookup returns the cached value of a key.
+func (c *redisClientCache) lookup(key string) ([]byte, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if e := c.entries[key]; e != nil && !e.pending {
+		return append([]byte(nil), e.value...), true
+	}
+	return nil, false
+}
+
+// track returns the tracked client with entries for keys that are to be read
+// through it, or a nil client when keys are currently not cached.
+func (c *redisClientCache) track(keys ...string) (*redis.Client, []*redisClientCacheEntry) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.client == nil {
+		return nil, nil
+	}
+
+	entries := make([]*redisClientCacheEntry, len(keys))
+	for i, key := range keys {
+		if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxKeys {
+			for k := range c.entries {
+				delete(c.entries, k)
+				break
+			}
+		}
+		entries[i] = &redisClientCacheEntry{pending: true}
+		c.entries[key] = entries[i]
+	}
+	return c.client, entries
+}
+
+// fill sets the value of an entry returned by track, unless the key has been
+// invalidated since.
+func (c *redisClientCache) fill(key string, e *redisClientCacheEntry, value []byte) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.entries[key] == e {
+		e.value, e.pending = value, false
+	}
+}
+
+// invalidate removes keys, which is used for keys modified by the client
+// itself so that they aren't read before the invalidation is received.
+func (c *redisClientCache) invalidate(keys ...string) {
+	c.mut.Lock()
+	for _, k := range keys {
+		delete(c.entries, k)
+	}
+	c.mut.Unlock()
+}
+
+func (c *redisClientCache) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+		c.mut.Lock()
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		c.mut.Unlock()
+	})
+	select {
+	case <-c.closedChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func appendRESPCommand(b []byte, args ...string) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, a := range args {
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(a)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, a...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+// readRESPReply reads a RESP2 reply, where errors replied by the server are
+// returned as errors.
+func readRESPReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("invalid reply: %q", line)
+	}
+	kind, line := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, errors.New(line)
+	}
+
+	n, err := strconv.ParseInt(line, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reply: %q", line)
+	}
+	switch kind {
+	case ':':
+		return n, nil
+	case '$':
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]any, n)
+		for i := range arr {
+			if arr[i], err = readRESPReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return arr, nil
+	}
+	return nil, fmt.Errorf("invalid reply: %q", kind)
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// The number of hash slots that the keys of a cluster are distributed across.
+const redisClusterSlots = 16384
+
+// redisHashSlot returns the hash slot of a key within a cluster, where only the
+// hash tag of keys that contain one, such as {user1000}.following, is hashed.
+// Commands with multiple keys are rejected by clusters unless all keys belong
+// to the same slot.
+func redisHashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	// CRC16 with the XMODEM polynomial as specified by the cluster spec.
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % redisClusterSlots
+}
+
+// redisSlotGroups groups keys by their hash slot, preserving the order in
+// which each slot is first seen, so that commands with multiple keys can be
+// split into one command for each slot.
+func redisSlotGroups(keys []string) [][]string {
+	var groups [][]string
+	indexes := map[int]int{}
+	for _, k := range keys {
+		slot := redisHashSlot(k)
+		i, exists := indexes[slot]
+		if !exists {
+			i = len(groups)
+			indexes[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], k)
+	}
+	return groups
+}
+
+// redisKeys returns the keys that match a pattern. The KEYS command is only
+// executed by a single node of a cluster, and therefore keys are collected
+// from every master of cluster clients.
+func redisKeys(client redis.UniversalClient, pattern string) ([]string, error) {
+	cluster, ok := client.(*redis.ClusterClient)
+	if !ok {
+		return client.Keys(pattern).Result()
+	}
+
+	var mut sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(func(c *redis.Client) error {
+		res, err := c.Keys(pattern).Result()
+		if err != nil {
+			return err
+		}
+		mut.Lock()
+		keys = append(keys, res...)
+		mut.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,26 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisHashSlot(t *testing.T) {
+	assert.Equal(t, 12182, redisHashSlot("foo"))
+	assert.Equal(t, 0x31c3, redisHashSlot("123456789"))
+
+	// Only the first hash tag is hashed, unless it's empty.
+	assert.Equal(t, redisHashSlot("user1000"), redisHashSlot("{user1000}.following"))
+	assert.Equal(t, redisHashSlot("user1000"), redisHashSlot("foo{user1000}{bar}"))
+	assert.NotEqual(t, redisHashSlot("foo"), redisHashSlot("{}foo"))
+	assert.Equal(t, redisHashSlot("foo{"), redisHashSlot("foo{"))
+}
+
+func TestRedisSlotGroups(t *testing.T) {
+	assert.Equal(t, [][]string{
+		{"{a}.1", "{a}.2"},
+		{"{b}.1"},
+		{"{c}.1", "{c}.2"},
+	}, redisSlotGroups([]string{"{a}.1", "{b}.1", "{a}.2", "{c}.1", "{c}.2"}))
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/impl/redis/old"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/partition"
 )
 
 func init() {
@@ -29,7 +30,15 @@ Pulls messages from Redis (v5.0+) streams with the XREADGROUP command. The
 		Description: `
 Redis stream entries are key/value pairs, as such it is necessary to specify the
 key that contains the body of the message. All other keys/value pairs are saved
-as metadata fields.`,
+as metadata fields.
+
+When the ` + "`kind`" + ` is ` + "`cluster`" + ` the streams are read with an XREADGROUP command
+for each hash slot they belong to, as the streams of a single command must
+belong to the same slot. In order to consume multiple streams with a single
+command use [hash tags](https://redis.io/docs/reference/cluster-spec/#hash-tags)
+within their names, such as ` + "`{orders}.eu` and `{orders}.us`" + `.
+
+Consumer groups balance the entries of a stream amongst their consumers, but every consumer still reads from every stream. With the field ` + "[`partitioning`](#partitioning)" + ` the streams themselves are instead divided amongst multiple instances of Benthos running the same config, where each stream is only read by the instance that holds the partition its name belongs to.`,
 		Config: docs.FieldComponent().WithChildren(old.ConfigDocs()...).WithChildren(
 			docs.FieldString("body_key", "The field key to extract the raw message from. All other keys will be stored in the message as metadata."),
 			docs.FieldString("streams", "A list of streams to consume from.").Array(),
@@ -40,6 +49,7 @@ as metadata fields.`,
 			docs.FieldBool("start_from_oldest", "If an offset is not found for a stream, determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset.").Advanced(),
 			docs.FieldString("commit_period", "The period of time between each commit of the current offset. Offsets are always committed during shutdown.").Advanced(),
 			docs.FieldString("timeout", "The length of time to poll for new messages before reattempting.").Advanced(),
+			partition.FieldSpec(),
 		).ChildDefaultAndTypesFromStruct(input.NewRedisStreamsConfig()),
 		Categories: []string{
 			"Services",
@@ -53,7 +63,7 @@ as metadata fields.`,
 func newRedisStreamsInput(conf input.Config, mgr bundle.NewManagement) (input.Streamed, error) {
 	var c input.Async
 	var err error
-	if c, err = newRedisStreamsReader(conf.RedisStreams, mgr.Logger()); err != nil {
+	if c, err = newRedisStreamsReader(conf.RedisStreams, mgr); err != nil {
 		return nil, err
 	}
 	c = input.NewAsyncPreserver(c)
@@ -79,6 +89,9 @@ type redisStreamsReader struct {
 
 	backlogs map[string]string
 
+	partitions *partition.Coordinator
+	owned      map[string]struct{}
+
 	aMut    sync.Mutex
 	ackSend map[string][]string // Acks that can be sent
 
@@ -89,10 +102,10 @@ type redisStreamsReader struct {
 	closeOnce  sync.Once
 }
 
-func newRedisStreamsReader(conf input.RedisStreamsConfig, log log.Modular) (*redisStreamsReader, error) {
+func newRedisStreamsReader(conf input.RedisStreamsConfig, mgr bundle.NewManagement) (*redisStreamsReader, error) {
 	r := &redisStreamsReader{
 		conf:       conf,
-		log:        log,
+		log:        mgr.Logger(),
 		backlogs:   make(map[string]string, len(conf.Streams)),
 		ackSend:    make(map[string][]string, len(conf.Streams)),
 		closeChan:  make(chan struct{}),
@@ -121,6 +134,14 @@ func newRedisStreamsReader(conf input.RedisStreamsConfig, log log.Modular) (*red
 		}
 	}
 
+	if conf.Partitioning.Enabled() {
+		var err error
+		if r.partitions, err = partition.New(conf.Partitioning, mgr.Label(), mgr, mgr.Logger()); err != nil {
+			return nil, fmt.Errorf("failed to create partition coordinator: %w", err)
+		}
+		r.owned = map[string]struct{}{}
+	}
+
 	go r.loop()
 	return r, nil
 }
@@ -137,6 +158,9 @@ func (r *redisStreamsReader) loop() {
 		if client != nil {
 			client.Close()
 		}
+		if r.partitions != nil {
+			_ = r.partitions.Close(context.Background())
+		}
 		close(r.closedChan)
 	}()
 	commitTimer := time.NewTicker(r.commitPeriod)
@@ -206,6 +230,11 @@ func (r *redisStreamsReader) Connect(ctx context.Context) error {
 	if _, err := client.Ping().Result(); err != nil {
 		return err
 	}
+	if r.partitions != nil {
+		if err := r.partitions.WaitForSync(ctx); err != nil {
+			return err
+		}
+	}
 
 	for _, s := range r.conf.Streams {
 		offset := "$"
@@ -249,23 +278,25 @@ func (r *redisStreamsReader) read() (pendingRedisStreamMsg, error) {
 		return msg, nil
 	}
 
-	strs := make([]string, len(r.conf.Streams)*2)
-	for i, str := range r.conf.Streams {
-		strs[i] = str
-		if bl := r.backlogs[str]; bl != "" {
-			strs[len(r.conf.Streams)+i] = bl
-		} else {
-			strs[len(r.conf.Streams)+i] = ">"
+	streams := r.conf.Streams
+	if r.partitions != nil {
+		if streams = r.ownedStreams(); len(streams) == 0 {
+			select {
+			case <-time.After(r.timeout):
+			case <-r.partitions.Changed():
+			case <-r.closeChan:
+			}
+			return msg, component.ErrTimeout
 		}
 	}
 
-	res, err := client.XReadGroup(&redis.XReadGroupArgs{
-		Block:    r.timeout,
-		Consumer: r.conf.ClientID,
-		Group:    r.conf.ConsumerGroup,
-		Streams:  strs,
-		Count:    r.conf.Limit,
-	}).Result()
+	// The streams of a single XREADGROUP command must belong to the same
+	// hash slot of a cluster, and so they're read with a command per slot.
+	groups := [][]string{streams}
+	if _, ok := client.(*redis.ClusterClient); ok {
+		groups = redisSlotGroups(streams)
+	}
+	res, err := r.readStreamGroups(client, groups)
 
 	if err != nil && err != redis.Nil {
 		if strings.Contains(err.Error(), "i/o timeout") {
@@ -330,6 +361,96 @@ func (r *redisStreamsReader) read() (pendingRedisStreamMsg, error) {
 	return msg, nil
 }
 
+// ownedStreams returns the streams that belong to partitions held by this
+// instance. Streams that have been newly acquired are read from the start of
+// the backlog of this consumer, as it might have held them previously. Must be
+// called with pendingMsgsMut held.
+func (r *redisStreamsReader) ownedStreams() []string {
+	owned := make(map[string]struct{}, len(r.owned))
+	var streams []string
+	for _, s := range r.conf.Streams {
+		if !r.partitions.OwnsKey(s) {
+			continue
+		}
+		if _, exists := r.owned[s]; !exists {
+			r.backlogs[s] = "0"
+		}
+		owned[s] = struct{}{}
+		streams = append(streams, s)
+	}
+	r.owned = owned
+	return streams
+}
+
+func (r *redisStreamsReader) readStreams(client redis.UniversalClient, streams []string, block time.Duration) ([]redis.XStream, error) {
+	strs := make([]string, len(streams)*2)
+	for i, str := range streams {
+		strs[i] = str
+		if bl := r.backlogs[str]; bl != "" {
+			strs[len(streams)+i] = bl
+		} else {
+			strs[len(streams)+i] = ">"
+		}
+	}
+
+	return client.XReadGroup(&redis.XReadGroupArgs{
+		Block:    block,
+		Consumer: r.conf.ClientID,
+		Group:    r.conf.ConsumerGroup,
+		Streams:  strs,
+		Count:    r.conf.Limit,
+	}).Result()
+}
+
+// readStreamGroups reads multiple groups of streams by first reading each
+// without blocking, and only when none have messages are they all read
+// concurrently until each either has messages or times out.
+func (r *redisStreamsReader) readStreamGroups(client redis.UniversalClient, groups [][]string) ([]redis.XStream, error) {
+	if len(groups) == 1 {
+		return r.readStreams(client, groups[0], r.timeout)
+	}
+
+	var res []redis.XStream
+	for _, streams := range groups {
+		gRes, err := r.readStreams(client, streams, -1)
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for _, strRes := range gRes {
+			if len(strRes.Messages) > 0 {
+				res = append(res, gRes...)
+				break
+			}
+		}
+	}
+	if len(res) > 0 {
+		return res, nil
+	}
+
+	results := make([][]redis.XStream, len(groups))
+	errs := make([]error, len(groups))
+	var wg sync.WaitGroup
+	for i, streams := range groups {
+		wg.Add(1)
+		go func(i int, streams []string) {
+			defer wg.Done()
+			results[i], errs[i] = r.readStreams(client, streams, r.timeout)
+		}(i, streams)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		res = append(res, results[i]...)
+	}
+	if len(res) == 0 {
+		return nil, redis.Nil
+	}
+	return res, nil
+}
+
 func (r *redisStreamsReader) ReadBatch(ctx context.Context) (message.Batch, input.AsyncAckFn, error) {
 	msg, err := r.read()
 	if err != nil {
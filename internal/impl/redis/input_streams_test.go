@@ -0,0 +1,137 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func TestRedisStreamsClusterSlots(t *testing.T) {
+	var mut sync.Mutex
+	sent := false
+
+	var port string
+	s := startRESPServer(t, func(cmd []string) string {
+		switch strings.ToLower(cmd[0]) {
+		case "cluster":
+			// A single node that serves all slots.
+			return "*1\r\n*3\r\n:0\r\n:16383\r\n*2\r\n$9\r\n127.0.0.1\r\n:" + port + "\r\n"
+		case "xgroup":
+			return "+OK\r\n"
+		case "xreadgroup":
+			stream, id := cmd[len(cmd)-2], cmd[len(cmd)-1]
+			if id == "0" {
+				return "*1\r\n*2\r\n$" + strconv.Itoa(len(stream)) + "\r\n" + stream + "\r\n*0\r\n"
+			}
+			mut.Lock()
+			defer mut.Unlock()
+			if stream == "{b}.s" && !sent {
+				sent = true
+				return "*1\r\n*2\r\n$5\r\n{b}.s\r\n*1\r\n*2\r\n$3\r\n1-0\r\n*2\r\n$4\r\nbody\r\n$5\r\nhello\r\n"
+			}
+			return "*-1\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+	_, port, _ = net.SplitHostPort(s.listener.Addr().String())
+
+	conf := input.NewRedisStreamsConfig()
+	conf.URL = s.url()
+	conf.Kind = "cluster"
+	conf.Streams = []string{"{a}.s", "{b}.s"}
+	conf.ConsumerGroup = "group"
+	conf.Timeout = "10ms"
+
+	r, err := newRedisStreamsReader(conf, mock.NewManager())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close(context.Background()) })
+	require.NoError(t, r.Connect(context.Background()))
+
+	batch, _, err := r.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, batch.Len())
+	assert.Equal(t, "hello", string(batch.Get(0).AsBytes()))
+
+	// The streams of different slots are read with separate commands.
+	var reads int
+	for _, cmd := range s.received() {
+		if cmd[0] != "xreadgroup" {
+			continue
+		}
+		reads++
+		assert.Equal(t, "streams", cmd[len(cmd)-3], cmd)
+	}
+	assert.Greater(t, reads, 2)
+}
+
+func TestRedisStreamsPartitioning(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		switch strings.ToLower(cmd[0]) {
+		case "xgroup":
+			return "+OK\r\n"
+		case "xreadgroup":
+			return "*-1\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	})
+
+	conf := input.NewRedisStreamsConfig()
+	conf.URL = s.url()
+	conf.Streams = []string{"a", "b", "c", "d", "e", "f"}
+	conf.ConsumerGroup = "group"
+	conf.Timeout = "10ms"
+	conf.Partitioning.Cache = "foo"
+	conf.Partitioning.KeyPrefix = "streams"
+	conf.Partitioning.Count = 2
+
+	// Another instance is running and holds the second partition.
+	mgr := mock.NewManager()
+	mgr.Caches["foo"] = map[string]mock.CacheItem{
+		"streams_member_1":    {Value: "other"},
+		"streams_partition_1": {Value: "other"},
+	}
+
+	r, err := newRedisStreamsReader(conf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close(context.Background()) })
+	require.NoError(t, r.Connect(context.Background()))
+
+	var expected []string
+	for _, str := range conf.Streams {
+		if r.partitions.PartitionOf(str) == 0 {
+			expected = append(expected, str)
+		}
+	}
+	require.NotEmpty(t, expected)
+	require.Less(t, len(expected), len(conf.Streams))
+
+	_, _, err = r.ReadBatch(context.Background())
+	require.Error(t, err)
+
+	var reads int
+	for _, cmd := range s.received() {
+		if cmd[0] != "xreadgroup" {
+			continue
+		}
+		reads++
+
+		var streamsIndex int
+		for i, arg := range cmd {
+			if strings.EqualFold(arg, "streams") {
+				streamsIndex = i + 1
+			}
+		}
+		args := cmd[streamsIndex:]
+		assert.Equal(t, expected, args[:len(args)/2], cmd)
+	}
+	assert.Greater(t, reads, 0)
+}
@@ -40,7 +40,7 @@ Some cloud hosted instances of Redis (such as Azure Cache) might need some hand
 			"redis://localhost:6379/1",
 			"redis://localhost:6379/1,redis://localhost:6380/1",
 		).HasDefault(""),
-		docs.FieldString("kind", "Specifies a simple, cluster-aware, or failover-aware redis client.", "simple", "cluster", "failover").HasDefault("simple").Advanced(),
+		docs.FieldString("kind", "Specifies a simple, cluster-aware, or failover-aware redis client. Cluster-aware clients route commands to the node that serves the hash slot of their keys and follow `MOVED` and `ASK` redirects when slots are migrated.", "simple", "cluster", "failover").HasDefault("simple").Advanced(),
 		docs.FieldString("master", "Name of the redis master when `kind` is `failover`", "mymaster").HasDefault("").Advanced(),
 		tlsSpec,
 	}
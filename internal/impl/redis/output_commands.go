@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// redisCommandWriter maintains the client of an output that writes batches of
+// messages as module commands, which go-redis doesn't provide methods for.
+type redisCommandWriter struct {
+	conf *service.ParsedConfig
+	log  *service.Logger
+	name string
+
+	connMut sync.RWMutex
+	client  redis.UniversalClient
+}
+
+func (r *redisCommandWriter) Connect(ctx context.Context) error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.client != nil {
+		return nil
+	}
+
+	client, err := getClient(r.conf)
+	if err != nil {
+		return err
+	}
+	if _, err = client.Ping().Result(); err != nil {
+		_ = client.Close()
+		return err
+	}
+
+	r.log.Infof("Writing messages to Redis with %v commands", r.name)
+	r.client = client
+	return nil
+}
+
+func (r *redisCommandWriter) currentClient() redis.UniversalClient {
+	r.connMut.RLock()
+	defer r.connMut.RUnlock()
+	return r.client
+}
+
+func (r *redisCommandWriter) disconnect() error {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+	if r.client != nil {
+		err := r.client.Close()
+		r.client = nil
+		return err
+	}
+	return nil
+}
+
+func (r *redisCommandWriter) Close(context.Context) error {
+	return r.disconnect()
+}
+
+// isRedisReplyError returns whether an error was replied by the server, in
+// which case the connection remains usable.
+func isRedisReplyError(err error) bool {
+	var rErr redis.Error
+	return errors.As(err, &rErr)
+}
+
+// execPipeline sends the commands of the messages of a batch as a single
+// pipeline, where cmds[i] holds the arguments of the command of the message
+// at indexes[i]. Messages with commands that the server rejects are marked as
+// failed within batchErr, and the connection is closed when it fails.
+func (r *redisCommandWriter) execPipeline(client redis.UniversalClient, batch service.MessageBatch, batchErr *service.BatchError, indexes []int, cmds [][]any) (*service.BatchError, error) {
+	if len(cmds) == 0 {
+		return batchErr, nil
+	}
+
+	pipe := client.Pipeline()
+	results := make([]*redis.Cmd, len(cmds))
+	for i, args := range cmds {
+		results[i] = pipe.Do(args...)
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil && !isRedisReplyError(err) {
+		_ = r.disconnect()
+		r.log.Errorf("Error from redis: %v\n", err)
+		return nil, service.ErrNotConnected
+	}
+
+	for i, res := range results {
+		// A nil reply means that a conditional command was skipped.
+		if err := res.Err(); err != nil && err != redis.Nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(indexes[i], err)
+		}
+	}
+	return batchErr, nil
+}
@@ -0,0 +1,200 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rjFieldKey         = "key"
+	rjFieldPath        = "path"
+	rjFieldOperation   = "operation"
+	rjFieldCondition   = "condition"
+	rjFieldBatching    = "batching"
+	rjFieldMaxInFlight = "max_in_flight"
+)
+
+func redisJSONOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sets or merges JSON values of Redis keys with the `JSON.SET` and `JSON.MERGE` commands.").
+		Description(`
+The content of each message is written as a JSON value to the path of the field ` + "`path`" + ` within the key of the field ` + "`key`" + `, which requires the [RedisJSON](https://redis.io/docs/stack/json/) module. The operation ` + "`set`" + ` replaces the value at the path, and ` + "`merge`" + ` merges the value into the existing value at the path according to [RFC 7386](https://datatracker.ietf.org/doc/html/rfc7386), where fields with a ` + "`null`" + ` value are deleted.
+
+Messages are written as a pipeline of commands for each batch. Messages with content that isn't valid JSON, or that are rejected by the server, such as those with a path that doesn't exist within the key, fail without affecting the other messages of the batch. Messages that are skipped due to the field ` + "`condition`" + ` are acknowledged.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field ` + "`max_in_flight`" + `.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField(rjFieldKey).
+			Description("The key to write each message to.").
+			Example(`users:${! this.id }`)).
+		Field(service.NewInterpolatedStringField(rjFieldPath).
+			Description("The JSONPath within the key to write each message to, where `$` is the root of the key.").
+			Example(`$.profile`).
+			Example(`$.sessions.${! this.session_id }`).
+			Default("$")).
+		Field(service.NewStringAnnotatedEnumField(rjFieldOperation, map[string]string{
+			"set":   "Sets the value at the path with the `JSON.SET` command.",
+			"merge": "Merges the value into the value at the path with the `JSON.MERGE` command, which requires RedisJSON 2.6 or newer.",
+		}).
+			Description("The operation to write each message with.").
+			Default("set")).
+		Field(service.NewStringAnnotatedEnumField(rjFieldCondition, map[string]string{
+			"none": "Values are always set.",
+			"nx":   "Values are only set when the path doesn't exist.",
+			"xx":   "Values are only set when the path already exists.",
+		}).
+			Description("A condition of setting values, which is only supported by the operation `set`.").
+			Advanced().
+			Default("none")).
+		Field(service.NewBatchPolicyField(rjFieldBatching)).
+		Field(service.NewIntField(rjFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Document Updates",
+			"In this example partial updates of user documents are merged into a key of each user, where the fields of updates replace those of the document.",
+			`
+output:
+  redis_json:
+    url: redis://localhost:6379
+    key: users:${! this.user_id }
+    operation: merge
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"redis_json", redisJSONOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(rjFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(rjFieldBatching); err != nil {
+				return
+			}
+			out, err = newRedisJSONOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type redisJSONOutput struct {
+	redisCommandWriter
+
+	key       *service.InterpolatedString
+	path      *service.InterpolatedString
+	command   string
+	condition string
+}
+
+func newRedisJSONOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*redisJSONOutput, error) {
+	r := &redisJSONOutput{}
+
+	var err error
+	if r.key, err = conf.FieldInterpolatedString(rjFieldKey); err != nil {
+		return nil, err
+	}
+	if r.path, err = conf.FieldInterpolatedString(rjFieldPath); err != nil {
+		return nil, err
+	}
+
+	operation, err := conf.FieldString(rjFieldOperation)
+	if err != nil {
+		return nil, err
+	}
+	condition, err := conf.FieldString(rjFieldCondition)
+	if err != nil {
+		return nil, err
+	}
+
+	switch operation {
+	case "set":
+		r.command = "JSON.SET"
+		if condition != "none" {
+			r.condition = condition
+		}
+	case "merge":
+		r.command = "JSON.MERGE"
+		if condition != "none" {
+			return nil, errors.New("a condition is not supported by the operation merge")
+		}
+	}
+	r.redisCommandWriter = redisCommandWriter{conf: conf, log: log, name: r.command}
+
+	if _, err := getClient(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *redisJSONOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	client := r.currentClient()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	failed := func(index int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(index, err)
+	}
+
+	var indexes []int
+	var cmds [][]any
+	for i, msg := range batch {
+		key := batch.InterpolatedString(i, r.key)
+		if key == "" {
+			failed(i, errors.New("key resolved to an empty string"))
+			continue
+		}
+
+		value, err := msg.AsBytes()
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+		if !json.Valid(value) {
+			failed(i, errors.New("message content is not valid JSON"))
+			continue
+		}
+
+		args := []any{r.command, key, batch.InterpolatedString(i, r.path), value}
+		if r.condition != "" {
+			args = append(args, r.condition)
+		}
+		indexes = append(indexes, i)
+		cmds = append(cmds, args)
+	}
+
+	batchErr, err := r.execPipeline(client, batch, batchErr, indexes, cmds)
+	if err != nil {
+		return err
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testRedisJSONOutput(t *testing.T, conf string) *redisJSONOutput {
+	t.Helper()
+
+	pConf, err := redisJSONOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newRedisJSONOutputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() { _ = out.Close(context.Background()) })
+	return out
+}
+
+func TestRedisJSONSet(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		switch cmd[1] {
+		case "users:2":
+			// The condition of the command wasn't met.
+			return "$-1\r\n"
+		case "users:3":
+			return "-ERR new objects must be created at the root\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	out := testRedisJSONOutput(t, `
+url: `+s.url()+`
+key: users:${! this.id }
+path: $.profile
+condition: nx
+`)
+
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+		service.NewMessage([]byte(`{"id":3}`)),
+		service.NewMessage([]byte(`{"id":`)),
+	})
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{2, 3}, failed)
+
+	assert.Equal(t, [][]string{
+		{"JSON.SET", "users:1", "$.profile", `{"id":1}`, "nx"},
+		{"JSON.SET", "users:2", "$.profile", `{"id":2}`, "nx"},
+		{"JSON.SET", "users:3", "$.profile", `{"id":3}`, "nx"},
+	}, s.received())
+}
+
+func TestRedisJSONMerge(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		return "+OK\r\n"
+	})
+
+	out := testRedisJSONOutput(t, `
+url: `+s.url()+`
+key: users:${! this.id }
+operation: merge
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":null}`)),
+	}))
+	assert.Equal(t, [][]string{
+		{"JSON.MERGE", "users:1", "$", `{"id":1,"name":null}`},
+	}, s.received())
+}
+
+func TestRedisJSONConfigErrors(t *testing.T) {
+	pConf, err := redisJSONOutputSpec().ParseYAML(`
+url: redis://localhost:6379
+key: foo
+operation: merge
+condition: xx
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisJSONOutputFromParsed(pConf, service.MockResources().Logger())
+	require.Error(t, err)
+}
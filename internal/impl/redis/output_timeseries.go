@@ -0,0 +1,292 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v7"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rtsFieldKey         = "key"
+	rtsFieldTimestamp   = "timestamp"
+	rtsFieldValue       = "value"
+	rtsFieldRetention   = "retention"
+	rtsFieldOnDuplicate = "on_duplicate"
+	rtsFieldLabels      = "labels"
+	rtsFieldBatching    = "batching"
+	rtsFieldMaxInFlight = "max_in_flight"
+)
+
+func redisTimeSeriesOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Adds samples to Redis time series with the `TS.ADD` and `TS.MADD` commands.").
+		Description(`
+Each message is added as a sample with the value of the field ` + "`value`" + ` to the time series of the field ` + "`key`" + `, which requires the [RedisTimeSeries](https://redis.io/docs/stack/timeseries/) module. A time series that doesn't exist is created with the options ` + "`retention`" + `, ` + "`on_duplicate`" + ` and ` + "`labels`" + `, which have no effect on existing time series.
+
+Batches of messages are added with a single ` + "`TS.MADD`" + ` command, which can't create time series with options and is therefore replaced with a pipeline of ` + "`TS.ADD`" + ` commands when any are set. A pipeline is also used when the ` + "`kind`" + ` is ` + "`cluster`" + `, as the keys of a ` + "`TS.MADD`" + ` command must belong to the same hash slot. Samples that are rejected, such as those with a value that isn't a number or a timestamp that is older than the retention period, fail without affecting the other messages of the batch.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field ` + "`max_in_flight`" + `.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`)
+
+	for _, f := range clientFields() {
+		spec = spec.Field(f)
+	}
+
+	return spec.
+		Field(service.NewInterpolatedStringField(rtsFieldKey).
+			Description("The key of the time series to add each sample to.").
+			Example(`sensors:${! this.sensor_id }:temperature`)).
+		Field(service.NewInterpolatedStringField(rtsFieldTimestamp).
+			Description("The timestamp of each sample as unix milliseconds, where `*` uses the time of the server.").
+			Example(`${! (this.ts.ts_parse("2006-01-02T15:04:05Z07:00").ts_unix_nano() / 1000000).floor() }`).
+			Default("*")).
+		Field(service.NewInterpolatedStringField(rtsFieldValue).
+			Description("The numeric value of each sample.").
+			Example(`${! this.temperature }`).
+			Default(`${! content() }`)).
+		Field(service.NewDurationField(rtsFieldRetention).
+			Description("The maximum age of samples of created time series relative to their latest sample, where older samples are removed.").
+			Example("24h").
+			Optional()).
+		Field(service.NewStringEnumField(rtsFieldOnDuplicate, "BLOCK", "FIRST", "LAST", "MIN", "MAX", "SUM").
+			Description("The policy for samples with the timestamp of an existing sample of created time series, which defaults to the policy of the server.").
+			Advanced().
+			Optional()).
+		Field(service.NewInterpolatedStringMapField(rtsFieldLabels).
+			Description("Labels of created time series, which allow them to be queried with commands such as `TS.MRANGE`.").
+			Example(map[string]any{
+				"sensor":   `${! this.sensor_id }`,
+				"building": "north",
+			}).
+			Optional()).
+		Field(service.NewBatchPolicyField(rtsFieldBatching)).
+		Field(service.NewIntField(rtsFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Sensor Readings",
+			"In this example readings of sensors are added to a time series of each sensor, which is created with a retention period of a week and labelled with the sensor.",
+			`
+output:
+  redis_timeseries:
+    url: redis://localhost:6379
+    key: sensors:${! this.sensor_id }:temperature
+    timestamp: ${! this.ts_ms }
+    value: ${! this.temperature }
+    retention: 168h
+    labels:
+      sensor: ${! this.sensor_id }
+    batching:
+      count: 100
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"redis_timeseries", redisTimeSeriesOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(rtsFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(rtsFieldBatching); err != nil {
+				return
+			}
+			out, err = newRedisTimeSeriesOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type redisTimeSeriesOutput struct {
+	redisCommandWriter
+
+	key       *service.InterpolatedString
+	timestamp *service.InterpolatedString
+	value     *service.InterpolatedString
+	labels    map[string]*service.InterpolatedString
+	labelKeys []string
+
+	// The options of TS.ADD commands that create time series.
+	createOpts []any
+	cluster    bool
+}
+
+func newRedisTimeSeriesOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*redisTimeSeriesOutput, error) {
+	r := &redisTimeSeriesOutput{
+		redisCommandWriter: redisCommandWriter{conf: conf, log: log, name: "TS.ADD"},
+	}
+
+	var err error
+	if r.key, err = conf.FieldInterpolatedString(rtsFieldKey); err != nil {
+		return nil, err
+	}
+	if r.timestamp, err = conf.FieldInterpolatedString(rtsFieldTimestamp); err != nil {
+		return nil, err
+	}
+	if r.value, err = conf.FieldInterpolatedString(rtsFieldValue); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(rtsFieldRetention) {
+		retention, err := conf.FieldDuration(rtsFieldRetention)
+		if err != nil {
+			return nil, err
+		}
+		r.createOpts = append(r.createOpts, "RETENTION", retention.Milliseconds())
+	}
+	if conf.Contains(rtsFieldOnDuplicate) {
+		policy, err := conf.FieldString(rtsFieldOnDuplicate)
+		if err != nil {
+			return nil, err
+		}
+		r.createOpts = append(r.createOpts, "ON_DUPLICATE", policy)
+	}
+	if conf.Contains(rtsFieldLabels) {
+		if r.labels, err = conf.FieldInterpolatedStringMap(rtsFieldLabels); err != nil {
+			return nil, err
+		}
+		for k := range r.labels {
+			r.labelKeys = append(r.labelKeys, k)
+		}
+		sort.Strings(r.labelKeys)
+	}
+
+	kind, err := conf.FieldString("kind")
+	if err != nil {
+		return nil, err
+	}
+	r.cluster = kind == "cluster"
+
+	if _, err := getClient(conf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// sample returns the key, timestamp and value of the sample of a message.
+func (r *redisTimeSeriesOutput) sample(batch service.MessageBatch, index int) ([]any, error) {
+	key := batch.InterpolatedString(index, r.key)
+	if key == "" {
+		return nil, errors.New("key resolved to an empty string")
+	}
+
+	timestamp := batch.InterpolatedString(index, r.timestamp)
+	if timestamp != "*" {
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			return nil, fmt.Errorf("timestamp '%v' must be unix milliseconds or '*'", timestamp)
+		}
+	}
+	return []any{key, timestamp, batch.InterpolatedString(index, r.value)}, nil
+}
+
+func (r *redisTimeSeriesOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	client := r.currentClient()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	var batchErr *service.BatchError
+	var indexes []int
+	var samples [][]any
+	for i := range batch {
+		sample, err := r.sample(batch, i)
+		if err != nil {
+			if batchErr == nil {
+				batchErr = service.NewBatchError(batch, err)
+			}
+			batchErr.Failed(i, err)
+			continue
+		}
+		indexes = append(indexes, i)
+		samples = append(samples, sample)
+	}
+
+	var err error
+	if len(samples) > 1 && len(r.createOpts) == 0 && len(r.labels) == 0 && !r.cluster {
+		batchErr, err = r.madd(client, batch, batchErr, indexes, samples)
+	} else {
+		cmds := make([][]any, len(samples))
+		for i, sample := range samples {
+			cmds[i] = r.addArgs(batch, indexes[i], sample)
+		}
+		batchErr, err = r.execPipeline(client, batch, batchErr, indexes, cmds)
+	}
+	if err != nil {
+		return err
+	}
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (r *redisTimeSeriesOutput) addArgs(batch service.MessageBatch, index int, sample []any) []any {
+	args := append([]any{"TS.ADD"}, sample...)
+	args = append(args, r.createOpts...)
+	if len(r.labelKeys) > 0 {
+		args = append(args, "LABELS")
+		for _, k := range r.labelKeys {
+			args = append(args, k, batch.InterpolatedString(index, r.labels[k]))
+		}
+	}
+	return args
+}
+
+// madd adds the samples of a batch with a single TS.MADD command, which
+// replies with either the timestamp or an error for each sample.
+func (r *redisTimeSeriesOutput) madd(client redis.UniversalClient, batch service.MessageBatch, batchErr *service.BatchError, indexes []int, samples [][]any) (*service.BatchError, error) {
+	args := []any{"TS.MADD"}
+	for _, sample := range samples {
+		args = append(args, sample...)
+	}
+
+	res, err := client.Do(args...).Result()
+	failed := func(index int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(index, err)
+	}
+	if err != nil {
+		if !isRedisReplyError(err) {
+			_ = r.disconnect()
+			r.log.Errorf("Error from redis: %v\n", err)
+			return nil, service.ErrNotConnected
+		}
+		for _, i := range indexes {
+			failed(i, err)
+		}
+		return batchErr, nil
+	}
+
+	replies, ok := res.([]any)
+	if !ok || len(replies) != len(samples) {
+		err := fmt.Errorf("unexpected reply to TS.MADD: %v", res)
+		for _, i := range indexes {
+			failed(i, err)
+		}
+		return batchErr, nil
+	}
+	for i, reply := range replies {
+		if err, ok := reply.(error); ok {
+			failed(indexes[i], err)
+		}
+	}
+	return batchErr, nil
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testRedisTimeSeriesOutput(t *testing.T, conf string) *redisTimeSeriesOutput {
+	t.Helper()
+
+	pConf, err := redisTimeSeriesOutputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	out, err := newRedisTimeSeriesOutputFromParsed(pConf, service.MockResources().Logger())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() { _ = out.Close(context.Background()) })
+	return out
+}
+
+func testReadingsBatch() service.MessageBatch {
+	return service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","ts":1000,"v":1.5}`)),
+		service.NewMessage([]byte(`{"id":"b","ts":1000,"v":"wat"}`)),
+		service.NewMessage([]byte(`{"id":"c","ts":2000,"v":3}`)),
+	}
+}
+
+func TestRedisTimeSeriesMADD(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		return "*3\r\n:1000\r\n-ERR TSDB: invalid value\r\n:2000\r\n"
+	})
+
+	out := testRedisTimeSeriesOutput(t, `
+url: `+s.url()+`
+key: temp:${! this.id }
+timestamp: ${! this.ts }
+value: ${! this.v }
+`)
+
+	err := out.WriteBatch(context.Background(), testReadingsBatch())
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+			assert.Contains(t, err.Error(), "invalid value")
+		}
+		return true
+	})
+	assert.Equal(t, []int{1}, failed)
+
+	assert.Equal(t, [][]string{
+		{"TS.MADD", "temp:a", "1000", "1.5", "temp:b", "1000", "wat", "temp:c", "2000", "3"},
+	}, s.received())
+}
+
+func TestRedisTimeSeriesADD(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		if strings.HasPrefix(cmd[1], "temp:b") {
+			return "-ERR TSDB: invalid value\r\n"
+		}
+		return ":" + cmd[2] + "\r\n"
+	})
+
+	out := testRedisTimeSeriesOutput(t, `
+url: `+s.url()+`
+key: temp:${! this.id }
+timestamp: ${! this.ts }
+value: ${! this.v }
+retention: 1h
+on_duplicate: LAST
+labels:
+  sensor: ${! this.id }
+  building: north
+`)
+
+	err := out.WriteBatch(context.Background(), testReadingsBatch())
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr), err)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	assert.Equal(t, [][]string{
+		{"TS.ADD", "temp:a", "1000", "1.5", "RETENTION", "3600000", "ON_DUPLICATE", "LAST", "LABELS", "building", "north", "sensor", "a"},
+		{"TS.ADD", "temp:b", "1000", "wat", "RETENTION", "3600000", "ON_DUPLICATE", "LAST", "LABELS", "building", "north", "sensor", "b"},
+		{"TS.ADD", "temp:c", "2000", "3", "RETENTION", "3600000", "ON_DUPLICATE", "LAST", "LABELS", "building", "north", "sensor", "c"},
+	}, s.received())
+}
+
+func TestRedisTimeSeriesSingle(t *testing.T) {
+	s := startRESPServer(t, func(cmd []string) string {
+		return ":1700000000000\r\n"
+	})
+
+	out := testRedisTimeSeriesOutput(t, `
+url: `+s.url()+`
+key: temp
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`21.5`)),
+	}))
+
+	// Messages with an invalid timestamp aren't sent.
+	out.timestamp, _ = service.NewInterpolatedString("yesterday")
+	err := out.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`21.5`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be unix milliseconds")
+
+	assert.Equal(t, [][]string{{"TS.ADD", "temp", "*", "21.5"}}, s.received())
+}
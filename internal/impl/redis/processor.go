@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v7"
@@ -205,12 +206,12 @@ type redisOperator func(r *redisProc, key string, part *service.Message) error
 
 func newRedisKeysOperator() redisOperator {
 	return func(r *redisProc, key string, part *service.Message) error {
-		res, err := r.client.Keys(key).Result()
+		res, err := redisKeys(r.client, key)
 
 		for i := 0; i <= r.retries && err != nil; i++ {
 			r.log.Errorf("Keys command failed: %v\n", err)
 			<-time.After(r.retryPeriod)
-			res, err = r.client.Keys(key).Result()
+			res, err = redisKeys(r.client, key)
 		}
 		if err != nil {
 			return err
@@ -338,11 +339,28 @@ func (r *redisProc) execRaw(ctx context.Context, index int, inBatch service.Mess
 	command := inBatch.InterpolatedString(index, r.command)
 	args = append([]any{command}, args...)
 
-	res, err := r.client.DoContext(ctx, args...).Result()
+	do := func() (any, error) {
+		// Clusters only match the keys of the node a KEYS command is sent
+		// to, and so it's sent to every master instead.
+		if pattern, ok := args[len(args)-1].(string); ok && len(args) == 2 && strings.EqualFold(command, "keys") {
+			keys, err := redisKeys(r.client, pattern)
+			if err != nil {
+				return nil, err
+			}
+			res := make([]any, len(keys))
+			for i, k := range keys {
+				res[i] = k
+			}
+			return res, nil
+		}
+		return r.client.DoContext(ctx, args...).Result()
+	}
+
+	res, err := do()
 	for i := 0; i <= r.retries && err != nil; i++ {
 		r.log.Errorf("%v command failed: %v", command, err)
 		<-time.After(r.retryPeriod)
-		res, err = r.client.DoContext(ctx, args...).Result()
+		res, err = do()
 	}
 	if err != nil {
 		return err
@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// respServer is a fake Redis server that records the commands it receives and
+// replies to them with a handler, which returns raw RESP replies.
+type respServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mut         sync.Mutex
+	commands    [][]string
+	handler     func(cmd []string) string
+	subscribers []net.Conn
+}
+
+func startRESPServer(t *testing.T, handler func(cmd []string) string) *respServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &respServer{t: t, listener: listener, handler: handler}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *respServer) url() string {
+	return "redis://" + s.listener.Addr().String()
+}
+
+func (s *respServer) received() [][]string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+// publish sends a message with a raw RESP payload to subscribed connections.
+func (s *respServer) publish(channel, payload string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, conn := range s.subscribers {
+		_, _ = io.WriteString(conn, "*3\r\n$7\r\nmessage\r\n$"+strconv.Itoa(len(channel))+"\r\n"+channel+"\r\n"+payload)
+	}
+}
+
+// dropSubscribers closes all subscribed connections.
+func (s *respServer) dropSubscribers() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, conn := range s.subscribers {
+		_ = conn.Close()
+	}
+	s.subscribers = nil
+}
+
+func (s *respServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		if strings.EqualFold(cmd[0], "PING") {
+			_, _ = io.WriteString(conn, "+PONG\r\n")
+			continue
+		}
+
+		s.mut.Lock()
+		s.commands = append(s.commands, cmd)
+		handler := s.handler
+		if strings.EqualFold(cmd[0], "SUBSCRIBE") {
+			// Subscribed connections only receive published messages.
+			s.subscribers = append(s.subscribers, conn)
+			_, _ = io.WriteString(conn, "*3\r\n$9\r\nsubscribe\r\n$"+strconv.Itoa(len(cmd[1]))+"\r\n"+cmd[1]+"\r\n:1\r\n")
+			s.mut.Unlock()
+			continue
+		}
+		s.mut.Unlock()
+
+		if _, err := io.WriteString(conn, handler(cmd)); err != nil {
+			return
+		}
+	}
+}
+
+func readRESPLine(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != prefix {
+		return 0, fmt.Errorf("unexpected line: %q", line)
+	}
+	return strconv.Atoi(line[1 : len(line)-2])
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readRESPLine(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	cmd := make([]string, n)
+	for i := range cmd {
+		l, err := readRESPLine(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, l+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		cmd[i] = string(b[:l])
+	}
+	return cmd, nil
+}
@@ -0,0 +1,315 @@
+package rocketmq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBroker is a fake of a name server and a broker of a single broker group,
+// which serves a topic with a number of queues.
+type testBroker struct {
+	t    *testing.T
+	addr string
+
+	mut       sync.Mutex
+	topic     string
+	queues    [][]*rmqMessageExt
+	offsets   map[string]int64
+	consumers map[string]map[string]*rmqConn
+	locks     map[rmqQueue]string
+	requests  []*rmqCommand
+}
+
+func startTestBroker(t *testing.T, topic string, queues int) *testBroker {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	b := &testBroker{
+		t:         t,
+		addr:      l.Addr().String(),
+		topic:     topic,
+		queues:    make([][]*rmqMessageExt, queues),
+		offsets:   map[string]int64{},
+		consumers: map[string]map[string]*rmqConn{},
+		locks:     map[rmqQueue]string{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) serve(netConn net.Conn) {
+	c := &rmqConn{conn: netConn, onClose: func() {}, pending: map[int32]chan *rmqCommand{}}
+	defer c.close()
+
+	r := bufio.NewReader(netConn)
+	for {
+		cmd, err := readRMQFrame(r)
+		if err != nil {
+			return
+		}
+		res := b.handle(c, cmd)
+		if res == nil || cmd.Flag&rmqFlagOneway != 0 {
+			continue
+		}
+		res.Opaque, res.Flag = cmd.Opaque, rmqFlagResponse
+		_ = c.write(res)
+	}
+}
+
+func (b *testBroker) messages(queueID int) []*rmqMessageExt {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return append([]*rmqMessageExt(nil), b.queues[queueID]...)
+}
+
+func (b *testBroker) offset(group string, queueID int) (int64, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	offset, exists := b.offsets[fmt.Sprintf("%v/%v", group, queueID)]
+	return offset, exists
+}
+
+func (b *testBroker) lockHolder(queueID int) string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.locks[rmqQueue{Topic: b.topic, BrokerName: "broker-a", QueueID: queueID}]
+}
+
+func (b *testBroker) requested(code int) bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for _, cmd := range b.requests {
+		if cmd.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *testBroker) put(queueID int, tag string, body string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.queues[queueID] = append(b.queues[queueID], &rmqMessageExt{
+		Topic:       b.topic,
+		QueueID:     int32(queueID),
+		QueueOffset: int64(len(b.queues[queueID])),
+		Body:        []byte(body),
+		Properties:  map[string]string{rmqPropTags: tag},
+	})
+}
+
+// notify notifies the consumers of a group other than a client that the
+// members of the group have changed.
+func (b *testBroker) notify(group, clientID string) {
+	for id, c := range b.consumers[group] {
+		if id == clientID {
+			continue
+		}
+		cmd := newRMQRequest(rmqCodeNotifyConsumerIDs, map[string]string{}, nil)
+		cmd.Flag = rmqFlagOneway
+		_ = c.write(cmd)
+	}
+}
+
+func (b *testBroker) handle(c *rmqConn, cmd *rmqCommand) *rmqCommand {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.requests = append(b.requests, cmd)
+	success := func(fields map[string]string, body []byte) *rmqCommand {
+		return &rmqCommand{Code: rmqRespSuccess, ExtFields: fields, Body: body}
+	}
+	queueID, _ := strconv.Atoi(cmd.ExtFields["queueId"])
+	offsetKey := fmt.Sprintf("%v/%v", cmd.ExtFields["consumerGroup"], queueID)
+
+	switch cmd.Code {
+	case rmqCodeGetRouteInfoByTopic:
+		if cmd.ExtFields["topic"] != b.topic {
+			return &rmqCommand{Code: rmqRespTopicNotExist, Remark: "no route"}
+		}
+		return success(nil, []byte(fmt.Sprintf(
+			`{"brokerDatas":[{"brokerAddrs":{0:"%v"},"brokerName":"broker-a","cluster":"DefaultCluster"}],"queueDatas":[{"brokerName":"broker-a","perm":6,"readQueueNums":%v,"writeQueueNums":%v}]}`,
+			b.addr, len(b.queues), len(b.queues),
+		)))
+
+	case rmqCodeSendMessage:
+		msg := &rmqMessageExt{
+			Topic:         cmd.ExtFields["topic"],
+			QueueID:       int32(queueID),
+			QueueOffset:   int64(len(b.queues[queueID])),
+			BornTimestamp: mustAtoi64(cmd.ExtFields["bornTimestamp"]),
+			Body:          cmd.Body,
+			Properties:    decodeRMQProperties(cmd.ExtFields["properties"]),
+		}
+		b.queues[queueID] = append(b.queues[queueID], msg)
+		return success(map[string]string{
+			"msgId":       msg.Properties[rmqPropUniqueKey],
+			"queueId":     strconv.Itoa(queueID),
+			"queueOffset": strconv.FormatInt(msg.QueueOffset, 10),
+		}, nil)
+
+	case rmqCodePullMessage:
+		offset := mustAtoi64(cmd.ExtFields["queueOffset"])
+		maxMsgs := mustAtoi64(cmd.ExtFields["maxMsgNums"])
+		msgs := b.queues[queueID]
+		if offset >= int64(len(msgs)) {
+			// Pull requests are held briefly rather than for the full suspend
+			// timeout.
+			b.mut.Unlock()
+			time.Sleep(time.Millisecond * 20)
+			b.mut.Lock()
+			return &rmqCommand{Code: rmqRespPullNotFound, ExtFields: map[string]string{
+				"nextBeginOffset": strconv.FormatInt(offset, 10),
+			}}
+		}
+		end := offset + maxMsgs
+		if end > int64(len(msgs)) {
+			end = int64(len(msgs))
+		}
+		var body []byte
+		for _, m := range msgs[offset:end] {
+			body = append(body, encodeTestMessage(m)...)
+		}
+		return success(map[string]string{"nextBeginOffset": strconv.FormatInt(end, 10)}, body)
+
+	case rmqCodeQueryConsumerOffset:
+		offset, exists := b.offsets[offsetKey]
+		if !exists {
+			return &rmqCommand{Code: rmqRespQueryNotFound}
+		}
+		return success(map[string]string{"offset": strconv.FormatInt(offset, 10)}, nil)
+
+	case rmqCodeUpdateConsumerOffset:
+		b.offsets[offsetKey] = mustAtoi64(cmd.ExtFields["commitOffset"])
+		return success(nil, nil)
+
+	case rmqCodeGetMinOffset:
+		return success(map[string]string{"offset": "0"}, nil)
+
+	case rmqCodeGetMaxOffset:
+		return success(map[string]string{"offset": strconv.Itoa(len(b.queues[queueID]))}, nil)
+
+	case rmqCodeHeartbeat:
+		var hb struct {
+			ClientID        string `json:"clientID"`
+			ConsumerDataSet []struct {
+				GroupName string `json:"groupName"`
+			} `json:"consumerDataSet"`
+		}
+		if err := json.Unmarshal(cmd.Body, &hb); err != nil {
+			b.t.Error(err)
+		}
+		for _, data := range hb.ConsumerDataSet {
+			members := b.consumers[data.GroupName]
+			if members == nil {
+				members = map[string]*rmqConn{}
+				b.consumers[data.GroupName] = members
+			}
+			if _, exists := members[hb.ClientID]; !exists {
+				members[hb.ClientID] = c
+				b.notify(data.GroupName, hb.ClientID)
+			}
+		}
+		return success(nil, nil)
+
+	case rmqCodeUnregisterClient:
+		group := cmd.ExtFields["consumerGroup"]
+		delete(b.consumers[group], cmd.ExtFields["clientID"])
+		b.notify(group, "")
+		return success(nil, nil)
+
+	case rmqCodeGetConsumerListByGroup:
+		ids := []string{}
+		for id := range b.consumers[cmd.ExtFields["consumerGroup"]] {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		body, _ := json.Marshal(map[string]any{"consumerIdList": ids})
+		return success(nil, body)
+
+	case rmqCodeLockBatchMQ, rmqCodeUnlockBatchMQ:
+		var req struct {
+			ClientID string     `json:"clientId"`
+			MQSet    []rmqQueue `json:"mqSet"`
+		}
+		if err := json.Unmarshal(cmd.Body, &req); err != nil {
+			b.t.Error(err)
+		}
+		locked := []rmqQueue{}
+		for _, q := range req.MQSet {
+			holder, exists := b.locks[q]
+			if cmd.Code == rmqCodeUnlockBatchMQ {
+				if holder == req.ClientID {
+					delete(b.locks, q)
+				}
+				continue
+			}
+			if !exists || holder == req.ClientID {
+				b.locks[q] = req.ClientID
+				locked = append(locked, q)
+			}
+		}
+		body, _ := json.Marshal(map[string]any{"lockOKMQSet": locked})
+		return success(nil, body)
+	}
+	return &rmqCommand{Code: rmqRespNotSupported}
+}
+
+func mustAtoi64(s string) int64 {
+	v, _ := strconv.ParseInt(s, 10, 64)
+	return v
+}
+
+// encodeTestMessage encodes a message in the format in which messages are
+// stored by brokers.
+func encodeTestMessage(m *rmqMessageExt) []byte {
+	var b []byte
+	putInt32 := func(v int32) { b = binary.BigEndian.AppendUint32(b, uint32(v)) }
+	putInt64 := func(v int64) { b = binary.BigEndian.AppendUint64(b, uint64(v)) }
+
+	props := encodeRMQProperties(m.Properties)
+	putInt32(0) // Total size
+	putInt32(rmqMagicCodeV1)
+	putInt32(0) // Body CRC
+	putInt32(m.QueueID)
+	putInt32(0) // Flag
+	putInt64(m.QueueOffset)
+	putInt64(0) // Physical offset
+	putInt32(0) // System flag
+	putInt64(m.BornTimestamp)
+	b = append(b, 127, 0, 0, 1, 0, 0, 0, 1)
+	putInt64(m.StoreTimestamp)
+	b = append(b, 127, 0, 0, 1, 0, 0, 0, 2)
+	putInt32(m.ReconsumeTimes)
+	putInt64(0) // Prepared transaction offset
+	putInt32(int32(len(m.Body)))
+	b = append(b, m.Body...)
+	b = append(b, byte(len(m.Topic)))
+	b = append(b, m.Topic...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(props)))
+	b = append(b, props...)
+
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
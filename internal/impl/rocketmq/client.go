@@ -0,0 +1,215 @@
+package rocketmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rmqFieldNameServers    = "name_server_addresses"
+	rmqFieldAccessKey      = "access_key"
+	rmqFieldSecretKey      = "secret_key"
+	rmqFieldRequestTimeout = "request_timeout"
+
+	// The ID of the master broker of a broker group.
+	rmqMasterBrokerID = "0"
+
+	// The permissions of the queues of a topic.
+	rmqPermWrite = 1 << 1
+	rmqPermRead  = 1 << 2
+)
+
+func rmqConnectionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringListField(rmqFieldNameServers).
+			Description("A list of addresses of name servers to connect to. Each item in the list may contain multiple addresses separated by semicolons.").
+			Example([]string{"localhost:9876"}).
+			Example([]string{"ns1:9876;ns2:9876"}),
+		service.NewStringField(rmqFieldAccessKey).
+			Description("An access key to sign requests with for clusters that have ACL enabled.").
+			Default(""),
+		service.NewStringField(rmqFieldSecretKey).
+			Description("The secret key of the access key.").
+			Default(""),
+		service.NewDurationField(rmqFieldRequestTimeout).
+			Description("The maximum period of time to wait for the response of a request to a name server or broker.").
+			Advanced().
+			Default("3s"),
+	}
+}
+
+// rmqClient obtains the routes of topics from name servers and sends requests
+// to the brokers of those routes.
+type rmqClient struct {
+	nameServers []string
+	remoting    *rmqRemoting
+}
+
+func rmqClientFromParsed(conf *service.ParsedConfig, handler func(*rmqCommand) *rmqCommand) (*rmqClient, error) {
+	addrs, err := conf.FieldStringList(rmqFieldNameServers)
+	if err != nil {
+		return nil, err
+	}
+	c := &rmqClient{}
+	for _, addr := range addrs {
+		for _, splitAddr := range strings.Split(addr, ";") {
+			if splitAddr = strings.TrimSpace(splitAddr); splitAddr != "" {
+				c.nameServers = append(c.nameServers, splitAddr)
+			}
+		}
+	}
+	if len(c.nameServers) == 0 {
+		return nil, errors.New("at least one name server address must be specified")
+	}
+
+	var creds rmqCredentials
+	if creds.AccessKey, err = conf.FieldString(rmqFieldAccessKey); err != nil {
+		return nil, err
+	}
+	if creds.SecretKey, err = conf.FieldString(rmqFieldSecretKey); err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(rmqFieldRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c.remoting = newRMQRemoting(creds, timeout, handler)
+	return c, nil
+}
+
+// rmqQueue identifies a queue of a topic.
+type rmqQueue struct {
+	Topic      string `json:"topic"`
+	BrokerName string `json:"brokerName"`
+	QueueID    int    `json:"queueId"`
+}
+
+func (q rmqQueue) String() string {
+	return fmt.Sprintf("%v@%v:%v", q.Topic, q.BrokerName, q.QueueID)
+}
+
+func sortRMQQueues(queues []rmqQueue) {
+	sort.Slice(queues, func(i, j int) bool {
+		if queues[i].Topic != queues[j].Topic {
+			return queues[i].Topic < queues[j].Topic
+		}
+		if queues[i].BrokerName != queues[j].BrokerName {
+			return queues[i].BrokerName < queues[j].BrokerName
+		}
+		return queues[i].QueueID < queues[j].QueueID
+	})
+}
+
+// rmqRoute is the route of a topic, which consists of the queues of the topic
+// on each broker group and the addresses of the brokers of each group.
+type rmqRoute struct {
+	QueueDatas []struct {
+		BrokerName     string `json:"brokerName"`
+		ReadQueueNums  int    `json:"readQueueNums"`
+		WriteQueueNums int    `json:"writeQueueNums"`
+		Perm           int    `json:"perm"`
+	} `json:"queueDatas"`
+	BrokerDatas []struct {
+		BrokerName  string            `json:"brokerName"`
+		BrokerAddrs map[string]string `json:"brokerAddrs"`
+	} `json:"brokerDatas"`
+}
+
+// The routes of name servers are serialized with integer map keys that are not
+// quoted, which is not valid JSON.
+var rmqUnquotedKeyRegexp = regexp.MustCompile(`([{,])(-?\d+):`)
+
+func parseRMQRoute(body []byte) (*rmqRoute, error) {
+	route := &rmqRoute{}
+	if err := json.Unmarshal(rmqUnquotedKeyRegexp.ReplaceAll(body, []byte(`$1"$2":`)), route); err != nil {
+		return nil, err
+	}
+	return route, nil
+}
+
+// masterAddr returns the address of the master broker of a broker group.
+func (r *rmqRoute) masterAddr(brokerName string) string {
+	for _, b := range r.BrokerDatas {
+		if b.BrokerName == brokerName {
+			return b.BrokerAddrs[rmqMasterBrokerID]
+		}
+	}
+	return ""
+}
+
+// masterAddrs returns the addresses of the master brokers of all broker groups
+// of the route.
+func (r *rmqRoute) masterAddrs() []string {
+	var addrs []string
+	for _, b := range r.BrokerDatas {
+		if addr := b.BrokerAddrs[rmqMasterBrokerID]; addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// queues returns the queues of a topic that have a permission, and that are
+// of a broker group with a master broker, in a consistent order.
+func (r *rmqRoute) queues(topic string, perm int) []rmqQueue {
+	var queues []rmqQueue
+	for _, q := range r.QueueDatas {
+		if q.Perm&perm == 0 || r.masterAddr(q.BrokerName) == "" {
+			continue
+		}
+		n := q.ReadQueueNums
+		if perm == rmqPermWrite {
+			n = q.WriteQueueNums
+		}
+		for i := 0; i < n; i++ {
+			queues = append(queues, rmqQueue{Topic: topic, BrokerName: q.BrokerName, QueueID: i})
+		}
+	}
+	sortRMQQueues(queues)
+	return queues
+}
+
+// route obtains the route of a topic from the first name server that responds.
+func (c *rmqClient) route(ctx context.Context, topic string) (*rmqRoute, error) {
+	var err error
+	for _, addr := range c.nameServers {
+		var res *rmqCommand
+		if res, err = c.remoting.invoke(ctx, addr, newRMQRequest(rmqCodeGetRouteInfoByTopic, map[string]string{
+			"topic": topic,
+		}, nil)); err != nil {
+			continue
+		}
+		switch res.Code {
+		case rmqRespSuccess:
+			return parseRMQRoute(res.Body)
+		case rmqRespTopicNotExist:
+			return nil, fmt.Errorf("topic %v does not exist", topic)
+		default:
+			return nil, fmt.Errorf("failed to obtain route of topic %v: %w", topic, res.err())
+		}
+	}
+	return nil, fmt.Errorf("failed to obtain route of topic %v: %w", topic, err)
+}
+
+// cachedRMQRoute is a route that is refreshed once it becomes stale.
+type cachedRMQRoute struct {
+	route     *rmqRoute
+	fetchedAt time.Time
+}
+
+func (c *cachedRMQRoute) stale(ttl time.Duration) bool {
+	return c == nil || time.Since(c.fetchedAt) > ttl
+}
+
+func (c *rmqClient) close() {
+	c.remoting.close()
+}
@@ -0,0 +1,921 @@
+package rocketmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rmqiFieldTopics          = "topics"
+	rmqiFieldTags            = "tags"
+	rmqiFieldConsumerGroup   = "consumer_group"
+	rmqiFieldOrdered         = "ordered"
+	rmqiFieldStartFromOldest = "start_from_oldest"
+	rmqiFieldBatchSize       = "batch_size"
+	rmqiFieldCheckpointLimit = "checkpoint_limit"
+	rmqiFieldCommitPeriod    = "commit_period"
+
+	// The period of time between each heartbeat and rebalance of the queues
+	// of the consumer group, which is that of the Java client.
+	rmqRebalancePeriod = time.Second * 20
+
+	// The period of time that brokers hold pull requests for when there are
+	// no messages to pull.
+	rmqPullSuspendTimeout = time.Second * 15
+
+	// The period of time to wait after a request to a broker fails before
+	// requests to the broker are attempted again.
+	rmqRetryBackoff = time.Second
+
+	rmqPullFlagSuspend      = 1 << 1
+	rmqPullFlagSubscription = 1 << 2
+)
+
+func rmqInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes messages from Apache RocketMQ topics as a member of a consumer group.").
+		Description(`
+The queues of the topics are shared between the members of the consumer group in the same way as the default allocation strategy of the Java client, and are reassigned when members join or leave the group. Messages are pulled from each queue in batches of up to ` + "`batch_size`" + ` messages, and the offset of each queue is committed to its broker once all messages before it have been acknowledged.
+
+Messages that are rejected are reattempted in place rather than sent back to the broker, and therefore block the offsets of their queues from being committed until they are delivered. When a queue is reassigned the messages of the queue that have not been acknowledged are delivered again by its new consumer.
+
+### Ordered Consumption
+
+When ` + "`ordered`" + ` is enabled each queue that is assigned to a consumer is locked on its broker, such that only one member of the consumer group consumes it at a time, and each batch of a queue is only consumed once the previous batch of the queue has been acknowledged. Messages that are sent to the same queue, for example with the ` + "`sharding_key`" + ` field of the ` + "[`rocketmq` output](/docs/components/outputs/rocketmq)" + `, are therefore delivered in the order they were sent.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- rocketmq_topic
+- rocketmq_broker_name
+- rocketmq_queue_id
+- rocketmq_queue_offset
+- rocketmq_msg_id
+- rocketmq_tags
+- rocketmq_keys
+- rocketmq_born_timestamp
+- rocketmq_store_timestamp
+- rocketmq_reconsume_times
+- All user properties of the message
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range rmqConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewStringListField(rmqiFieldTopics).
+			Description("A list of topics to consume from.")).
+		Field(service.NewStringField(rmqiFieldTags).
+			Description("An expression of the tags of messages to consume, which is either `*` for all messages or a list of tags separated by `||`.").
+			Example("TagA || TagB").
+			Default("*")).
+		Field(service.NewStringField(rmqiFieldConsumerGroup).
+			Description("The consumer group to consume as.")).
+		Field(service.NewBoolField(rmqiFieldOrdered).
+			Description("Whether to lock the queues that are assigned to the consumer and consume the messages of each queue in order.").
+			Default(false)).
+		Field(service.NewBoolField(rmqiFieldStartFromOldest).
+			Description("Whether to consume from the oldest message of a queue when the consumer group has no committed offset for it, rather than from the newest.").
+			Default(true)).
+		Field(service.NewIntField(rmqiFieldBatchSize).
+			Description("The maximum number of messages to pull from a queue at a time, which are consumed as a batch.").
+			Default(32)).
+		Field(service.NewIntField(rmqiFieldCheckpointLimit).
+			Description("The maximum number of messages of a queue that can be processed at a given time when consumption is not ordered. Increasing this limit enables parallel processing and batching at the output level.").
+			Advanced().
+			Default(1024)).
+		Field(service.NewDurationField(rmqiFieldCommitPeriod).
+			Description("The period of time between each commit of the offsets of the queues.").
+			Advanced().
+			Default("5s")).
+		Example(
+			"Ordered Consumption",
+			"In this example the events of orders are consumed in the order they were sent for each order, excluding events that are tagged as tests.",
+			`
+input:
+  rocketmq:
+    name_server_addresses: [ localhost:9876 ]
+    topics: [ order_events ]
+    tags: created || paid || shipped
+    consumer_group: fulfilment
+    ordered: true
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"rocketmq", rmqInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newRMQInputFromParsed(conf, mgr.Logger())
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type rmqBatch struct {
+	batch   service.MessageBatch
+	release func() any
+}
+
+// rmqQueueConsumer pulls the messages of a queue that is assigned to the
+// consumer.
+type rmqQueueConsumer struct {
+	queue       rmqQueue
+	checkpoints *checkpoint.Capped
+	committed   int64
+	cancel      func()
+	done        chan struct{}
+}
+
+type rmqInput struct {
+	conf            *service.ParsedConfig
+	log             *service.Logger
+	topics          []string
+	filter          rmqTagFilter
+	group           string
+	ordered         bool
+	startFromOldest bool
+	batchSize       int
+	checkpointLimit int
+	commitPeriod    time.Duration
+	rebalancePeriod time.Duration
+	clientID        string
+	subVersion      string
+
+	batches   chan rmqBatch
+	rebalance chan struct{}
+
+	m         sync.Mutex
+	client    *rmqClient
+	routes    map[string]*rmqRoute
+	consumers map[rmqQueue]*rmqQueueConsumer
+	cancel    func()
+	done      chan struct{}
+}
+
+func newRMQInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*rmqInput, error) {
+	i := &rmqInput{
+		conf:            conf,
+		log:             log,
+		rebalancePeriod: rmqRebalancePeriod,
+		batches:         make(chan rmqBatch),
+		rebalance:       make(chan struct{}, 1),
+	}
+
+	if _, err := rmqClientFromParsed(conf, nil); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if i.topics, err = conf.FieldStringList(rmqiFieldTopics); err != nil {
+		return nil, err
+	}
+	if len(i.topics) == 0 {
+		return nil, errors.New("at least one topic must be specified")
+	}
+	tags, err := conf.FieldString(rmqiFieldTags)
+	if err != nil {
+		return nil, err
+	}
+	i.filter = newRMQTagFilter(tags)
+	if i.group, err = conf.FieldString(rmqiFieldConsumerGroup); err != nil {
+		return nil, err
+	}
+	if i.group == "" {
+		return nil, errors.New("a consumer group must be specified")
+	}
+	if i.ordered, err = conf.FieldBool(rmqiFieldOrdered); err != nil {
+		return nil, err
+	}
+	if i.startFromOldest, err = conf.FieldBool(rmqiFieldStartFromOldest); err != nil {
+		return nil, err
+	}
+	if i.batchSize, err = conf.FieldInt(rmqiFieldBatchSize); err != nil {
+		return nil, err
+	}
+	if i.batchSize < 1 {
+		return nil, fmt.Errorf("%v must be at least 1", rmqiFieldBatchSize)
+	}
+	if i.checkpointLimit, err = conf.FieldInt(rmqiFieldCheckpointLimit); err != nil {
+		return nil, err
+	}
+	if i.commitPeriod, err = conf.FieldDuration(rmqiFieldCommitPeriod); err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	i.clientID = fmt.Sprintf("%v@%v@%v", hostname, os.Getpid(), hex.EncodeToString(suffix))
+	i.subVersion = strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return i, nil
+}
+
+// handleRequest handles the requests of brokers, where a notification that the
+// members of the consumer group have changed triggers a rebalance.
+func (i *rmqInput) handleRequest(cmd *rmqCommand) *rmqCommand {
+	if cmd.Code != rmqCodeNotifyConsumerIDs {
+		return nil
+	}
+	select {
+	case i.rebalance <- struct{}{}:
+	default:
+	}
+	return &rmqCommand{Code: rmqRespSuccess}
+}
+
+func (i *rmqInput) Connect(ctx context.Context) error {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	if i.client != nil {
+		return nil
+	}
+
+	client, err := rmqClientFromParsed(i.conf, i.handleRequest)
+	if err != nil {
+		return err
+	}
+	routes := map[string]*rmqRoute{}
+	for _, topic := range i.topics {
+		if routes[topic], err = client.route(ctx, topic); err != nil {
+			client.close()
+			return err
+		}
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	i.client, i.routes, i.cancel = client, routes, cancel
+	i.consumers = map[rmqQueue]*rmqQueueConsumer{}
+	i.done = make(chan struct{})
+
+	go i.loop(loopCtx, client, i.done)
+
+	i.log.Infof("Consuming RocketMQ topics %v as consumer group %v with client ID %v", i.topics, i.group, i.clientID)
+	return nil
+}
+
+// loop sends heartbeats to the brokers of the topics, rebalances the queues of
+// the topics and commits offsets until the context is cancelled.
+func (i *rmqInput) loop(ctx context.Context, client *rmqClient, done chan struct{}) {
+	defer close(done)
+
+	commitTicker := time.NewTicker(i.commitPeriod)
+	defer commitTicker.Stop()
+
+	rebalanceTicker := time.NewTicker(i.rebalancePeriod)
+	defer rebalanceTicker.Stop()
+
+	for {
+		i.heartbeat(ctx, client)
+		i.rebalanceQueues(ctx, client)
+
+	waitLoop:
+		for {
+			select {
+			case <-commitTicker.C:
+				i.commitAll(ctx, client)
+			case <-rebalanceTicker.C:
+				break waitLoop
+			case <-i.rebalance:
+				break waitLoop
+			case <-ctx.Done():
+				i.shutdown(client)
+				return
+			}
+		}
+	}
+}
+
+func (i *rmqInput) brokerAddrs() []string {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	seen := map[string]struct{}{}
+	var addrs []string
+	for _, route := range i.routes {
+		for _, addr := range route.masterAddrs() {
+			if _, exists := seen[addr]; !exists {
+				seen[addr] = struct{}{}
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func (i *rmqInput) brokerAddr(queue rmqQueue) string {
+	i.m.Lock()
+	defer i.m.Unlock()
+
+	if route := i.routes[queue.Topic]; route != nil {
+		return route.masterAddr(queue.BrokerName)
+	}
+	return ""
+}
+
+func (i *rmqInput) heartbeat(ctx context.Context, client *rmqClient) {
+	consumeFrom := "CONSUME_FROM_LAST_OFFSET"
+	if i.startFromOldest {
+		consumeFrom = "CONSUME_FROM_FIRST_OFFSET"
+	}
+	tagsSet, codeSet := append([]string{}, i.filter.tags...), i.filter.codes()
+
+	var subs []any
+	for _, topic := range i.topics {
+		subs = append(subs, map[string]any{
+			"classFilterMode": false,
+			"topic":           topic,
+			"subString":       i.filter.expression,
+			"tagsSet":         tagsSet,
+			"codeSet":         codeSet,
+			"subVersion":      i.subVersion,
+			"expressionType":  "TAG",
+		})
+	}
+	body, err := json.Marshal(map[string]any{
+		"clientID":        i.clientID,
+		"producerDataSet": []any{},
+		"consumerDataSet": []any{map[string]any{
+			"groupName":           i.group,
+			"consumeType":         "CONSUME_PASSIVELY",
+			"messageModel":        "CLUSTERING",
+			"consumeFromWhere":    consumeFrom,
+			"subscriptionDataSet": subs,
+			"unitMode":            false,
+		}},
+	})
+	if err != nil {
+		i.log.Errorf("Failed to encode heartbeat: %v", err)
+		return
+	}
+
+	for _, addr := range i.brokerAddrs() {
+		res, err := client.remoting.invoke(ctx, addr, newRMQRequest(rmqCodeHeartbeat, nil, body))
+		if err == nil && res.Code != rmqRespSuccess {
+			err = res.err()
+		}
+		if err != nil && ctx.Err() == nil {
+			i.log.Warnf("Failed to send heartbeat to broker %v: %v", addr, err)
+		}
+	}
+}
+
+// allocateRMQQueues allocates queues to the members of a consumer group in the
+// same way as the default strategy of the Java client, where each member is
+// allocated a contiguous range of the queues, and returns the queues of a
+// member.
+func allocateRMQQueues(queues []rmqQueue, members []string, member string) []rmqQueue {
+	index := -1
+	for i, m := range members {
+		if m == member {
+			index = i
+			break
+		}
+	}
+	if index < 0 || len(queues) == 0 {
+		return nil
+	}
+
+	mod := len(queues) % len(members)
+	size := 1
+	if len(queues) > len(members) {
+		size = len(queues) / len(members)
+		if mod > 0 && index < mod {
+			size++
+		}
+	}
+	start := index*size + mod
+	if mod > 0 && index < mod {
+		start = index * size
+	}
+
+	var allocated []rmqQueue
+	for i := 0; i < size && start+i < len(queues); i++ {
+		allocated = append(allocated, queues[start+i])
+	}
+	return allocated
+}
+
+func (i *rmqInput) members(ctx context.Context, client *rmqClient, route *rmqRoute) ([]string, error) {
+	var err error
+	for _, addr := range route.masterAddrs() {
+		var res *rmqCommand
+		if res, err = client.remoting.invoke(ctx, addr, newRMQRequest(rmqCodeGetConsumerListByGroup, map[string]string{
+			"consumerGroup": i.group,
+		}, nil)); err != nil {
+			continue
+		}
+		if res.Code != rmqRespSuccess {
+			err = res.err()
+			continue
+		}
+		var list struct {
+			ConsumerIDList []string `json:"consumerIdList"`
+		}
+		if err = json.Unmarshal(res.Body, &list); err != nil {
+			continue
+		}
+		sort.Strings(list.ConsumerIDList)
+		return list.ConsumerIDList, nil
+	}
+	if err == nil {
+		err = errors.New("route has no brokers")
+	}
+	return nil, err
+}
+
+// lockQueues locks queues on their brokers for the consumer and returns the
+// queues that were locked.
+func (i *rmqInput) lockQueues(ctx context.Context, client *rmqClient, queues []rmqQueue) []rmqQueue {
+	byAddr := map[string][]rmqQueue{}
+	for _, q := range queues {
+		if addr := i.brokerAddr(q); addr != "" {
+			byAddr[addr] = append(byAddr[addr], q)
+		}
+	}
+
+	var locked []rmqQueue
+	for addr, qs := range byAddr {
+		body, _ := json.Marshal(map[string]any{
+			"consumerGroup": i.group,
+			"clientId":      i.clientID,
+			"mqSet":         qs,
+		})
+		res, err := client.remoting.invoke(ctx, addr, newRMQRequest(rmqCodeLockBatchMQ, nil, body))
+		if err == nil && res.Code != rmqRespSuccess {
+			err = res.err()
+		}
+		var lockRes struct {
+			LockOKMQSet []rmqQueue `json:"lockOKMQSet"`
+		}
+		if err == nil {
+			err = json.Unmarshal(res.Body, &lockRes)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				i.log.Warnf("Failed to lock queues on broker %v: %v", addr, err)
+			}
+			continue
+		}
+		locked = append(locked, lockRes.LockOKMQSet...)
+	}
+	return locked
+}
+
+func (i *rmqInput) unlockQueue(ctx context.Context, client *rmqClient, queue rmqQueue) {
+	addr := i.brokerAddr(queue)
+	if addr == "" {
+		return
+	}
+	body, _ := json.Marshal(map[string]any{
+		"consumerGroup": i.group,
+		"clientId":      i.clientID,
+		"mqSet":         []rmqQueue{queue},
+	})
+	if err := client.remoting.oneway(ctx, addr, newRMQRequest(rmqCodeUnlockBatchMQ, nil, body)); err != nil {
+		i.log.Warnf("Failed to unlock queue %v: %v", queue, err)
+	}
+}
+
+func (i *rmqInput) rebalanceQueues(ctx context.Context, client *rmqClient) {
+	assigned := map[rmqQueue]struct{}{}
+	var toLock []rmqQueue
+	for _, topic := range i.topics {
+		route, err := client.route(ctx, topic)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			i.log.Warnf("Failed to refresh route of topic %v, continuing with previous route: %v", topic, err)
+			i.m.Lock()
+			route = i.routes[topic]
+			i.m.Unlock()
+			if route == nil {
+				continue
+			}
+		} else {
+			i.m.Lock()
+			i.routes[topic] = route
+			i.m.Unlock()
+		}
+
+		members, err := i.members(ctx, client, route)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// The queues of the topic remain as they are until the members of
+			// the group are known.
+			i.log.Warnf("Failed to obtain members of consumer group %v: %v", i.group, err)
+			i.m.Lock()
+			for q := range i.consumers {
+				if q.Topic == topic {
+					assigned[q] = struct{}{}
+				}
+			}
+			i.m.Unlock()
+			continue
+		}
+
+		queues := allocateRMQQueues(route.queues(topic, rmqPermRead), members, i.clientID)
+		if i.ordered {
+			toLock = append(toLock, queues...)
+			continue
+		}
+		for _, q := range queues {
+			assigned[q] = struct{}{}
+		}
+	}
+	if i.ordered {
+		// Locks expire unless they are renewed, and are therefore obtained
+		// again for every queue with each rebalance.
+		for _, q := range i.lockQueues(ctx, client, toLock) {
+			assigned[q] = struct{}{}
+		}
+	}
+
+	i.m.Lock()
+	var revoked []*rmqQueueConsumer
+	for q, c := range i.consumers {
+		if _, exists := assigned[q]; !exists {
+			revoked = append(revoked, c)
+			delete(i.consumers, q)
+		}
+	}
+	var added []rmqQueue
+	for q := range assigned {
+		if _, exists := i.consumers[q]; !exists {
+			added = append(added, q)
+		}
+	}
+	i.m.Unlock()
+
+	for _, c := range revoked {
+		i.log.Debugf("Queue %v has been revoked", c.queue)
+		i.stopConsumer(ctx, client, c)
+	}
+
+	sortRMQQueues(added)
+	for _, q := range added {
+		i.log.Debugf("Queue %v has been assigned", q)
+		i.startConsumer(ctx, client, q)
+	}
+}
+
+func (i *rmqInput) startConsumer(ctx context.Context, client *rmqClient, queue rmqQueue) {
+	capacity := int64(i.checkpointLimit)
+	if i.ordered {
+		capacity = 1
+	}
+	consumerCtx, cancel := context.WithCancel(ctx)
+	c := &rmqQueueConsumer{
+		queue:       queue,
+		checkpoints: checkpoint.NewCapped(capacity),
+		committed:   -1,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+
+	i.m.Lock()
+	i.consumers[queue] = c
+	i.m.Unlock()
+
+	go i.consume(consumerCtx, client, c)
+}
+
+func (i *rmqInput) stopConsumer(ctx context.Context, client *rmqClient, c *rmqQueueConsumer) {
+	c.cancel()
+	<-c.done
+	i.commit(ctx, client, c)
+	if i.ordered {
+		i.unlockQueue(ctx, client, c.queue)
+	}
+}
+
+func (i *rmqInput) queueRequest(code int, queue rmqQueue, fields map[string]string) *rmqCommand {
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	fields["topic"] = queue.Topic
+	fields["queueId"] = strconv.Itoa(queue.QueueID)
+	return newRMQRequest(code, fields, nil)
+}
+
+func rmqOffsetField(res *rmqCommand, key string) (int64, error) {
+	if res.Code != rmqRespSuccess {
+		return 0, res.err()
+	}
+	offset, err := strconv.ParseInt(res.ExtFields[key], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %v of response: %w", key, err)
+	}
+	return offset, nil
+}
+
+// initialOffset returns the committed offset of a queue, or when there is no
+// committed offset either the oldest or newest offset of the queue.
+func (i *rmqInput) initialOffset(ctx context.Context, client *rmqClient, addr string, queue rmqQueue) (int64, error) {
+	res, err := client.remoting.invoke(ctx, addr, i.queueRequest(rmqCodeQueryConsumerOffset, queue, map[string]string{
+		"consumerGroup": i.group,
+	}))
+	if err != nil {
+		return 0, err
+	}
+	if res.Code != rmqRespQueryNotFound {
+		if offset, err := rmqOffsetField(res, "offset"); err != nil || offset >= 0 {
+			return offset, err
+		}
+	}
+
+	code := rmqCodeGetMaxOffset
+	if i.startFromOldest {
+		code = rmqCodeGetMinOffset
+	}
+	if res, err = client.remoting.invoke(ctx, addr, i.queueRequest(code, queue, nil)); err != nil {
+		return 0, err
+	}
+	return rmqOffsetField(res, "offset")
+}
+
+func (i *rmqInput) consume(ctx context.Context, client *rmqClient, c *rmqQueueConsumer) {
+	defer close(c.done)
+
+	backoff := func() bool {
+		select {
+		case <-time.After(rmqRetryBackoff):
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	offset := int64(-1)
+	for ctx.Err() == nil {
+		addr := i.brokerAddr(c.queue)
+		if addr == "" {
+			i.log.Warnf("Queue %v has no master broker", c.queue)
+			if !backoff() {
+				return
+			}
+			continue
+		}
+
+		if offset < 0 {
+			var err error
+			if offset, err = i.initialOffset(ctx, client, addr, c.queue); err != nil {
+				offset = -1
+				if ctx.Err() == nil {
+					i.log.Errorf("Failed to obtain offset of queue %v: %v", c.queue, err)
+				}
+				if !backoff() {
+					return
+				}
+				continue
+			}
+			c.committed = offset
+		}
+
+		next, batch, err := i.pull(ctx, client, addr, c.queue, offset)
+		if err != nil {
+			if ctx.Err() == nil {
+				i.log.Errorf("Failed to pull messages from queue %v: %v", c.queue, err)
+			}
+			if !backoff() {
+				return
+			}
+			continue
+		}
+		if next == offset {
+			continue
+		}
+
+		weight := int64(len(batch))
+		if i.ordered || weight == 0 {
+			weight = 1
+		}
+		release, err := c.checkpoints.Track(ctx, next, weight)
+		if err != nil {
+			return
+		}
+		offset = next
+
+		// Offsets that are skipped over without any messages to consume, as
+		// they were filtered, are resolved once those before them are.
+		if len(batch) == 0 {
+			release()
+			continue
+		}
+
+		select {
+		case i.batches <- rmqBatch{batch: batch, release: release}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pull pulls the messages of a queue from an offset, and returns the offset to
+// pull from next along with the messages that match the tag filter.
+func (i *rmqInput) pull(ctx context.Context, client *rmqClient, addr string, queue rmqQueue, offset int64) (int64, service.MessageBatch, error) {
+	ctx, done := context.WithTimeout(ctx, rmqPullSuspendTimeout+client.remoting.timeout)
+	defer done()
+
+	res, err := client.remoting.invoke(ctx, addr, i.queueRequest(rmqCodePullMessage, queue, map[string]string{
+		"consumerGroup":        i.group,
+		"queueOffset":          strconv.FormatInt(offset, 10),
+		"maxMsgNums":           strconv.Itoa(i.batchSize),
+		"sysFlag":              strconv.Itoa(rmqPullFlagSuspend | rmqPullFlagSubscription),
+		"commitOffset":         "0",
+		"suspendTimeoutMillis": strconv.FormatInt(rmqPullSuspendTimeout.Milliseconds(), 10),
+		"subscription":         i.filter.expression,
+		"subVersion":           i.subVersion,
+		"expressionType":       "TAG",
+	}))
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return offset, nil, nil
+		}
+		return 0, nil, err
+	}
+
+	switch res.Code {
+	case rmqRespSuccess, rmqRespPullNotFound, rmqRespPullRetryImmediately, rmqRespPullOffsetMoved:
+	default:
+		return 0, nil, res.err()
+	}
+	next, err := rmqOffsetField(&rmqCommand{ExtFields: res.ExtFields}, "nextBeginOffset")
+	if err != nil {
+		return 0, nil, err
+	}
+	if res.Code == rmqRespPullOffsetMoved {
+		i.log.Warnf("Offset %v of queue %v is no longer valid, consuming from offset %v", offset, queue, next)
+	}
+	if res.Code != rmqRespSuccess {
+		return next, nil, nil
+	}
+
+	msgs, err := decodeRMQMessages(res.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to decode messages: %w", err)
+	}
+
+	var batch service.MessageBatch
+	for _, m := range msgs {
+		if m.QueueOffset < offset || !i.filter.matches(m.Properties[rmqPropTags]) {
+			continue
+		}
+		batch = append(batch, rmqMessageToPart(queue, m))
+	}
+	return next, batch, nil
+}
+
+func rmqMessageToPart(queue rmqQueue, m *rmqMessageExt) *service.Message {
+	msg := service.NewMessage(m.Body)
+	for k, v := range m.Properties {
+		if _, reserved := rmqSystemProps[k]; !reserved {
+			msg.MetaSet(k, v)
+		}
+	}
+	msg.MetaSet("rocketmq_topic", m.Topic)
+	msg.MetaSet("rocketmq_broker_name", queue.BrokerName)
+	msg.MetaSet("rocketmq_queue_id", strconv.Itoa(int(m.QueueID)))
+	msg.MetaSet("rocketmq_queue_offset", strconv.FormatInt(m.QueueOffset, 10))
+	msg.MetaSet("rocketmq_msg_id", m.Properties[rmqPropUniqueKey])
+	msg.MetaSet("rocketmq_tags", m.Properties[rmqPropTags])
+	msg.MetaSet("rocketmq_keys", m.Properties[rmqPropKeys])
+	msg.MetaSet("rocketmq_born_timestamp", strconv.FormatInt(m.BornTimestamp, 10))
+	msg.MetaSet("rocketmq_store_timestamp", strconv.FormatInt(m.StoreTimestamp, 10))
+	msg.MetaSet("rocketmq_reconsume_times", strconv.Itoa(int(m.ReconsumeTimes)))
+	return msg
+}
+
+// commit commits the highest offset of a queue that all messages before have
+// been acknowledged to its broker.
+func (i *rmqInput) commit(ctx context.Context, client *rmqClient, c *rmqQueueConsumer) {
+	highest, ok := c.checkpoints.Highest().(int64)
+	if !ok || highest == c.committed {
+		return
+	}
+	addr := i.brokerAddr(c.queue)
+	if addr == "" {
+		return
+	}
+	res, err := client.remoting.invoke(ctx, addr, i.queueRequest(rmqCodeUpdateConsumerOffset, c.queue, map[string]string{
+		"consumerGroup": i.group,
+		"commitOffset":  strconv.FormatInt(highest, 10),
+	}))
+	if err == nil && res.Code != rmqRespSuccess {
+		err = res.err()
+	}
+	if err != nil {
+		i.log.Errorf("Failed to commit offset %v of queue %v: %v", highest, c.queue, err)
+		return
+	}
+	c.committed = highest
+}
+
+func (i *rmqInput) commitAll(ctx context.Context, client *rmqClient) {
+	i.m.Lock()
+	consumers := make([]*rmqQueueConsumer, 0, len(i.consumers))
+	for _, c := range i.consumers {
+		consumers = append(consumers, c)
+	}
+	i.m.Unlock()
+
+	for _, c := range consumers {
+		i.commit(ctx, client, c)
+	}
+}
+
+// shutdown stops all consumers, commits their offsets and unregisters the
+// consumer from the brokers such that its queues are reassigned immediately.
+func (i *rmqInput) shutdown(client *rmqClient) {
+	ctx, done := context.WithTimeout(context.Background(), client.remoting.timeout)
+	defer done()
+
+	i.m.Lock()
+	consumers := i.consumers
+	i.consumers = map[rmqQueue]*rmqQueueConsumer{}
+	i.m.Unlock()
+
+	for _, c := range consumers {
+		i.stopConsumer(ctx, client, c)
+	}
+	for _, addr := range i.brokerAddrs() {
+		_ = client.remoting.oneway(ctx, addr, newRMQRequest(rmqCodeUnregisterClient, map[string]string{
+			"clientID":      i.clientID,
+			"consumerGroup": i.group,
+		}, nil))
+	}
+}
+
+func (i *rmqInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	i.m.Lock()
+	done := i.done
+	i.m.Unlock()
+
+	if done == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case b := <-i.batches:
+		return b.batch, func(ctx context.Context, err error) error {
+			// Batches that are rejected are reattempted by the input and
+			// therefore only ever resolved once delivered.
+			b.release()
+			return nil
+		}, nil
+	case <-done:
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (i *rmqInput) Close(ctx context.Context) error {
+	i.m.Lock()
+	client, cancel, done := i.client, i.cancel, i.done
+	i.client, i.cancel, i.done = nil, nil, nil
+	i.m.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	client.close()
+	return nil
+}
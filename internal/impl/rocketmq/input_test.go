@@ -0,0 +1,233 @@
+package rocketmq
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testInput(t *testing.T, b *testBroker, extra string) *rmqInput {
+	t.Helper()
+	return testGroupInput(t, b, "bar", extra)
+}
+
+func testGroupInput(t *testing.T, b *testBroker, group, extra string) *rmqInput {
+	t.Helper()
+
+	conf, err := rmqInputSpec().ParseYAML(`
+name_server_addresses: [ `+b.addr+` ]
+topics: [ foo ]
+consumer_group: `+group+`
+commit_period: 10ms
+`+extra, nil)
+	require.NoError(t, err)
+
+	in, err := newRMQInputFromParsed(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	in.rebalancePeriod = time.Millisecond * 50
+	require.NoError(t, in.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = in.Close(context.Background())
+	})
+	return in
+}
+
+func readTestBatch(t *testing.T, in *rmqInput) (service.MessageBatch, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := in.ReadBatch(ctx)
+	require.NoError(t, err)
+	return batch, ackFn
+}
+
+func batchBodies(t *testing.T, batch service.MessageBatch) (bodies []string) {
+	t.Helper()
+
+	for _, msg := range batch {
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		bodies = append(bodies, string(b))
+	}
+	return
+}
+
+func TestRocketMQInput(t *testing.T) {
+	b := startTestBroker(t, "foo", 1)
+	b.put(0, "TagA", "first")
+	b.put(0, "TagB", "second")
+	b.put(0, "TagA", "third")
+	b.mut.Lock()
+	b.queues[0][0].Properties[rmqPropUniqueKey] = "ABC"
+	b.queues[0][0].Properties[rmqPropKeys] = "k1 k2"
+	b.queues[0][0].Properties["region"] = "eu"
+	b.mut.Unlock()
+
+	in := testInput(t, b, `
+tags: TagA || TagC
+batch_size: 2
+`)
+
+	batch, ackFn := readTestBatch(t, in)
+	assert.Equal(t, []string{"first"}, batchBodies(t, batch))
+
+	meta := map[string]any{}
+	require.NoError(t, batch[0].MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"rocketmq_topic":           "foo",
+		"rocketmq_broker_name":     "broker-a",
+		"rocketmq_queue_id":        "0",
+		"rocketmq_queue_offset":    "0",
+		"rocketmq_msg_id":          "ABC",
+		"rocketmq_tags":            "TagA",
+		"rocketmq_keys":            "k1 k2",
+		"rocketmq_born_timestamp":  "0",
+		"rocketmq_store_timestamp": "0",
+		"rocketmq_reconsume_times": "0",
+		"region":                   "eu",
+	}, meta)
+
+	b2, ackFn2 := readTestBatch(t, in)
+	assert.Equal(t, []string{"third"}, batchBodies(t, b2))
+
+	// The offset is only committed once all batches before it are delivered.
+	require.NoError(t, ackFn2(context.Background(), nil))
+	time.Sleep(time.Millisecond * 50)
+	_, committed := b.offset("bar", 0)
+	assert.False(t, committed)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	assert.Eventually(t, func() bool {
+		offset, _ := b.offset("bar", 0)
+		return offset == 3
+	}, time.Second*5, time.Millisecond*10)
+
+	b.put(0, "TagA", "fourth")
+	batch, ackFn = readTestBatch(t, in)
+	assert.Equal(t, []string{"fourth"}, batchBodies(t, batch))
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	require.NoError(t, in.Close(context.Background()))
+	offset, _ := b.offset("bar", 0)
+	assert.Equal(t, int64(4), offset)
+}
+
+func TestRocketMQInputStartFrom(t *testing.T) {
+	b := startTestBroker(t, "foo", 1)
+	b.put(0, "", "old")
+	b.mut.Lock()
+	b.offsets["bar/0"] = 0
+	b.mut.Unlock()
+
+	// A group with a committed offset consumes from that offset.
+	in := testInput(t, b, `
+start_from_oldest: false
+`)
+	batch, _ := readTestBatch(t, in)
+	assert.Equal(t, []string{"old"}, batchBodies(t, batch))
+
+	// A group without a committed offset consumes from the newest offset.
+	newIn := testGroupInput(t, b, "qux", `
+start_from_oldest: false
+`)
+	assert.Eventually(t, func() bool {
+		return b.requested(rmqCodeGetMaxOffset)
+	}, time.Second*5, time.Millisecond*10)
+	b.put(0, "", "new")
+	batch, _ = readTestBatch(t, newIn)
+	assert.Equal(t, []string{"new"}, batchBodies(t, batch))
+}
+
+func TestRocketMQInputOrdered(t *testing.T) {
+	b := startTestBroker(t, "foo", 1)
+	for i := 0; i < 3; i++ {
+		b.put(0, "", fmt.Sprintf("msg%v", i))
+	}
+
+	in := testInput(t, b, `
+ordered: true
+batch_size: 1
+`)
+
+	batch, ackFn := readTestBatch(t, in)
+	assert.Equal(t, []string{"msg0"}, batchBodies(t, batch))
+	assert.Equal(t, in.clientID, b.lockHolder(0))
+
+	// The next batch of the queue is only consumed once the previous batch has
+	// been delivered.
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*200)
+	_, _, err := in.ReadBatch(ctx)
+	done()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, ackFn(context.Background(), nil))
+	batch, ackFn = readTestBatch(t, in)
+	assert.Equal(t, []string{"msg1"}, batchBodies(t, batch))
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	// The queue is unlocked once the input is closed.
+	require.NoError(t, in.Close(context.Background()))
+	assert.Eventually(t, func() bool {
+		return b.lockHolder(0) == ""
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func assignedQueues(in *rmqInput) (ids []int) {
+	in.m.Lock()
+	defer in.m.Unlock()
+	for q := range in.consumers {
+		ids = append(ids, q.QueueID)
+	}
+	return
+}
+
+func TestRocketMQInputRebalance(t *testing.T) {
+	b := startTestBroker(t, "foo", 4)
+
+	first := testInput(t, b, "")
+	assert.Eventually(t, func() bool {
+		return len(assignedQueues(first)) == 4
+	}, time.Second*5, time.Millisecond*10)
+
+	second := testInput(t, b, "")
+	assert.Eventually(t, func() bool {
+		return len(assignedQueues(first)) == 2 && len(assignedQueues(second)) == 2
+	}, time.Second*5, time.Millisecond*10)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, append(assignedQueues(first), assignedQueues(second)...))
+
+	// The queues of a consumer that leaves the group are reassigned.
+	require.NoError(t, second.Close(context.Background()))
+	assert.Eventually(t, func() bool {
+		return len(assignedQueues(first)) == 4
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestRocketMQInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{conf: "name_server_addresses: [ ' ' ]\ntopics: [ foo ]\nconsumer_group: bar", errStr: "name server"},
+		{conf: "name_server_addresses: [ localhost:9876 ]\ntopics: []\nconsumer_group: bar", errStr: "topic"},
+		{conf: "name_server_addresses: [ localhost:9876 ]\ntopics: [ foo ]\nconsumer_group: ''", errStr: "consumer group"},
+		{conf: "name_server_addresses: [ localhost:9876 ]\ntopics: [ foo ]\nconsumer_group: bar\nbatch_size: 0", errStr: "batch_size"},
+	} {
+		conf, err := rmqInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newRMQInputFromParsed(conf, service.MockResources().Logger())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errStr)
+	}
+}
@@ -0,0 +1,256 @@
+package rocketmq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The names of the properties of messages that are reserved by RocketMQ.
+const (
+	rmqPropTags        = "TAGS"
+	rmqPropKeys        = "KEYS"
+	rmqPropDelayLevel  = "DELAY"
+	rmqPropWaitStore   = "WAIT"
+	rmqPropUniqueKey   = "UNIQ_KEY"
+	rmqPropShardingKey = "__SHARDINGKEY"
+)
+
+// rmqSystemProps are the properties that are reserved by RocketMQ, which are
+// not added to consumed messages as metadata.
+var rmqSystemProps = map[string]struct{}{
+	rmqPropTags: {}, rmqPropKeys: {}, rmqPropDelayLevel: {}, rmqPropWaitStore: {},
+	rmqPropUniqueKey: {}, rmqPropShardingKey: {},
+	"MIN_OFFSET": {}, "MAX_OFFSET": {}, "REAL_TOPIC": {}, "REAL_QID": {},
+	"RETRY_TOPIC": {}, "ORIGIN_MESSAGE_ID": {}, "PGROUP": {}, "TRAN_MSG": {},
+	"CONSUME_START_TIME": {}, "CLUSTER": {}, "TRACE_ON": {}, "MSG_REGION": {},
+}
+
+const (
+	rmqMagicCodeV1 = int32(-626843481)
+	rmqMagicCodeV2 = int32(-626843477)
+
+	rmqSysFlagCompressed  = 1 << 0
+	rmqSysFlagBornHostV6  = 1 << 4
+	rmqSysFlagStoreHostV6 = 1 << 5
+	rmqPropNameValueSep   = '\x01'
+	rmqPropSeparator      = '\x02'
+	rmqTagExpressionAll   = "*"
+	rmqTagExpressionOrSep = "||"
+	rmqMaxDelayLevel      = 18
+)
+
+func encodeRMQProperties(props map[string]string) string {
+	var b strings.Builder
+	for k, v := range props {
+		b.WriteString(k)
+		b.WriteByte(rmqPropNameValueSep)
+		b.WriteString(v)
+		b.WriteByte(rmqPropSeparator)
+	}
+	return b.String()
+}
+
+func decodeRMQProperties(s string) map[string]string {
+	props := map[string]string{}
+	for _, kv := range strings.Split(s, string(rmqPropSeparator)) {
+		if k, v, ok := strings.Cut(kv, string(rmqPropNameValueSep)); ok {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// rmqMessageExt is a message that has been stored by a broker.
+type rmqMessageExt struct {
+	Topic          string
+	QueueID        int32
+	QueueOffset    int64
+	ReconsumeTimes int32
+	BornTimestamp  int64
+	StoreTimestamp int64
+	Body           []byte
+	Properties     map[string]string
+}
+
+var errRMQShortMessage = errors.New("message is truncated")
+
+type rmqReader struct {
+	b   []byte
+	err error
+}
+
+func (r *rmqReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errRMQShortMessage
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *rmqReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *rmqReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *rmqReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// decodeRMQMessages decodes the messages of the body of a pull response, which
+// are in the format in which they are stored by brokers.
+func decodeRMQMessages(body []byte) ([]*rmqMessageExt, error) {
+	var msgs []*rmqMessageExt
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, errRMQShortMessage
+		}
+		size := int(binary.BigEndian.Uint32(body))
+		if size < 4 || size > len(body) {
+			return nil, errRMQShortMessage
+		}
+		msg, err := decodeRMQMessage(body[4:size])
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+		body = body[size:]
+	}
+	return msgs, nil
+}
+
+func decodeRMQMessage(b []byte) (*rmqMessageExt, error) {
+	r := &rmqReader{b: b}
+	msg := &rmqMessageExt{}
+
+	magicCode := r.int32()
+	if r.err == nil && magicCode != rmqMagicCodeV1 && magicCode != rmqMagicCodeV2 {
+		return nil, fmt.Errorf("unexpected magic code: %v", magicCode)
+	}
+	_ = r.int32() // Body CRC
+	msg.QueueID = r.int32()
+	_ = r.int32() // Flag
+	msg.QueueOffset = r.int64()
+	_ = r.int64() // Physical offset
+	sysFlag := r.int32()
+	msg.BornTimestamp = r.int64()
+	if sysFlag&rmqSysFlagBornHostV6 != 0 {
+		_ = r.next(20)
+	} else {
+		_ = r.next(8)
+	}
+	msg.StoreTimestamp = r.int64()
+	if sysFlag&rmqSysFlagStoreHostV6 != 0 {
+		_ = r.next(20)
+	} else {
+		_ = r.next(8)
+	}
+	msg.ReconsumeTimes = r.int32()
+	_ = r.int64() // Prepared transaction offset
+	msg.Body = r.next(int(r.int32()))
+
+	if magicCode == rmqMagicCodeV2 {
+		msg.Topic = string(r.next(int(r.int16())))
+	} else if l := r.next(1); l != nil {
+		msg.Topic = string(r.next(int(l[0])))
+	}
+	props := string(r.next(int(r.int16())))
+	if r.err != nil {
+		return nil, r.err
+	}
+	msg.Properties = decodeRMQProperties(props)
+
+	if sysFlag&rmqSysFlagCompressed != 0 {
+		zr, err := zlib.NewReader(bytes.NewReader(msg.Body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress body: %w", err)
+		}
+		if msg.Body, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress body: %w", err)
+		}
+	} else {
+		msg.Body = append([]byte(nil), msg.Body...)
+	}
+	return msg, nil
+}
+
+//------------------------------------------------------------------------------
+
+// rmqTagFilter matches the tags of messages with a tag expression, which is
+// either `*` or a list of tags separated by `||`.
+type rmqTagFilter struct {
+	expression string
+	tags       []string
+}
+
+func newRMQTagFilter(expression string) rmqTagFilter {
+	f := rmqTagFilter{expression: strings.TrimSpace(expression)}
+	if f.expression == "" || f.expression == rmqTagExpressionAll {
+		f.expression = rmqTagExpressionAll
+		return f
+	}
+	for _, tag := range strings.Split(f.expression, rmqTagExpressionOrSep) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			f.tags = append(f.tags, tag)
+		}
+	}
+	return f
+}
+
+func (f rmqTagFilter) matches(tag string) bool {
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, t := range f.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// codes returns the hash codes of the tags of the filter, which brokers use in
+// order to filter messages before they are pulled.
+func (f rmqTagFilter) codes() []int32 {
+	codes := make([]int32, 0, len(f.tags))
+	for _, t := range f.tags {
+		codes = append(codes, javaStringHashCode(t))
+	}
+	return codes
+}
+
+// javaStringHashCode returns the hash code of a string as calculated by Java.
+func javaStringHashCode(s string) int32 {
+	var h int32
+	for _, r := range s {
+		if r >= 0x10000 {
+			r -= 0x10000
+			h = 31*h + int32(0xD800+(r>>10))
+			h = 31*h + int32(0xDC00+(r&0x3FF))
+			continue
+		}
+		h = 31*h + r
+	}
+	return h
+}
@@ -0,0 +1,363 @@
+package rocketmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rmqoFieldTopic         = "topic"
+	rmqoFieldProducerGroup = "producer_group"
+	rmqoFieldTags          = "tags"
+	rmqoFieldKeys          = "keys"
+	rmqoFieldDelayLevel    = "delay_level"
+	rmqoFieldShardingKey   = "sharding_key"
+	rmqoFieldMetadata      = "metadata"
+	rmqoFieldMaxInFlight   = "max_in_flight"
+
+	// The period of time after which the routes of topics are obtained again.
+	rmqRouteTTL = time.Second * 30
+)
+
+func rmqOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sends messages to an Apache RocketMQ topic.").
+		Description(`
+The route of a topic is obtained from the name servers, and messages are sent to the master brokers of the route, where each message is sent to the queues of the topic in turn. When ` + "`sharding_key`" + ` is set messages that share a sharding key are sent to the same queue instead, and are therefore consumed in the order they were sent by consumers with ordered consumption enabled. In order to preserve this order it is recommended to use ` + "`max_in_flight: 1`" + `.
+
+Messages are only acknowledged once they have been stored by the master broker, and messages that are rejected by the broker, including those that the broker fails to flush or replicate in time, are reattempted.
+
+### Delayed Messages
+
+When ` + "`delay_level`" + ` resolves to a level between 1 and 18 the message is only delivered to consumers once the period of time of that level has elapsed, which with the default configuration of brokers is 1s, 5s, 10s, 30s, 1m, 2m, 3m, 4m, 5m, 6m, 7m, 8m, 9m, 10m, 20m, 30m, 1h and 2h respectively.`)
+
+	for _, f := range rmqConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewInterpolatedStringField(rmqoFieldTopic).
+			Description("The topic to send messages to.")).
+		Field(service.NewStringField(rmqoFieldProducerGroup).
+			Description("The producer group that messages are sent as.").
+			Default("benthos")).
+		Field(service.NewInterpolatedStringField(rmqoFieldTags).
+			Description("An optional tag of each message, which consumers are able to filter by.").
+			Example("TagA").
+			Example(`${! meta("kind") }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(rmqoFieldKeys).
+			Description("Optional keys of each message separated by spaces, which messages are indexed by in order to be queried.").
+			Example(`${! this.order_id }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(rmqoFieldDelayLevel).
+			Description("An optional delay level of each message between 1 and 18, where an empty string or 0 delivers the message immediately.").
+			Example("3").
+			Optional()).
+		Field(service.NewInterpolatedStringField(rmqoFieldShardingKey).
+			Description("An optional key that determines the queue that messages are sent to, where messages that share a key are sent to the same queue.").
+			Example(`${! this.customer_id }`).
+			Optional()).
+		Field(service.NewMetadataFilterField(rmqoFieldMetadata).
+			Description("Specify criteria for which metadata values are sent as user properties of messages.").
+			Optional()).
+		Field(service.NewIntField(rmqoFieldMaxInFlight).
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Ordered Orders",
+			"In this example the events of orders are sent such that the events of each order are consumed in order, tagged by the kind of event.",
+			`
+output:
+  rocketmq:
+    name_server_addresses: [ localhost:9876 ]
+    topic: order_events
+    producer_group: order_service
+    tags: ${! this.kind }
+    keys: ${! this.order_id }
+    sharding_key: ${! this.order_id }
+    max_in_flight: 1
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"rocketmq", rmqOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(rmqoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newRMQOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type rmqOutput struct {
+	conf *service.ParsedConfig
+	log  *service.Logger
+
+	topic         *service.InterpolatedString
+	topicStr      string
+	producerGroup string
+	tags          *service.InterpolatedString
+	keys          *service.InterpolatedString
+	delayLevel    *service.InterpolatedString
+	shardingKey   *service.InterpolatedString
+	metaFilter    *service.MetadataFilter
+
+	next uint32
+
+	m      sync.RWMutex
+	client *rmqClient
+	routes map[string]*cachedRMQRoute
+}
+
+func newRMQOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*rmqOutput, error) {
+	r := &rmqOutput{conf: conf, log: log}
+
+	// Parse the connection fields early in order to surface errors before
+	// connecting.
+	if _, err := rmqClientFromParsed(conf, nil); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if r.topic, err = conf.FieldInterpolatedString(rmqoFieldTopic); err != nil {
+		return nil, err
+	}
+	if r.topicStr, err = conf.FieldString(rmqoFieldTopic); err != nil {
+		return nil, err
+	}
+	if r.producerGroup, err = conf.FieldString(rmqoFieldProducerGroup); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name string
+		ptr  **service.InterpolatedString
+	}{
+		{rmqoFieldTags, &r.tags},
+		{rmqoFieldKeys, &r.keys},
+		{rmqoFieldDelayLevel, &r.delayLevel},
+		{rmqoFieldShardingKey, &r.shardingKey},
+	} {
+		if !conf.Contains(f.name) {
+			continue
+		}
+		if *f.ptr, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(rmqoFieldMetadata) {
+		if r.metaFilter, err = conf.FieldMetadataFilter(rmqoFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *rmqOutput) Connect(ctx context.Context) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.client != nil {
+		return nil
+	}
+
+	client, err := rmqClientFromParsed(r.conf, nil)
+	if err != nil {
+		return err
+	}
+
+	// Obtaining the route of the topic, when it is static, verifies that the
+	// name servers are reachable and that the topic exists.
+	routes := map[string]*cachedRMQRoute{}
+	if !strings.Contains(r.topicStr, "${!") {
+		route, err := client.route(ctx, r.topicStr)
+		if err != nil {
+			client.close()
+			return err
+		}
+		routes[r.topicStr] = &cachedRMQRoute{route: route, fetchedAt: time.Now()}
+	}
+
+	r.client, r.routes = client, routes
+	r.log.Infof("Sending RocketMQ messages to topic: %v", r.topicStr)
+	return nil
+}
+
+func (r *rmqOutput) route(ctx context.Context, topic string) (*rmqRoute, error) {
+	r.m.RLock()
+	client, cached := r.client, r.routes[topic]
+	r.m.RUnlock()
+
+	if client == nil {
+		return nil, service.ErrNotConnected
+	}
+	if !cached.stale(rmqRouteTTL) {
+		return cached.route, nil
+	}
+
+	route, err := client.route(ctx, topic)
+	if err != nil {
+		if cached != nil {
+			r.log.Warnf("Failed to refresh route of topic %v, continuing with previous route: %v", topic, err)
+			return cached.route, nil
+		}
+		return nil, err
+	}
+
+	r.m.Lock()
+	if r.routes != nil {
+		r.routes[topic] = &cachedRMQRoute{route: route, fetchedAt: time.Now()}
+	}
+	r.m.Unlock()
+	return route, nil
+}
+
+// selectQueue selects the queue that a message is sent to, which is the same
+// queue as would be selected by the hash selector of the Java client when the
+// message has a sharding key.
+func (r *rmqOutput) selectQueue(queues []rmqQueue, shardingKey string) rmqQueue {
+	if shardingKey != "" {
+		h := int(javaStringHashCode(shardingKey))
+		if h < 0 {
+			h = -h
+		}
+		return queues[h%len(queues)]
+	}
+	return queues[int(atomic.AddUint32(&r.next, 1)-1)%len(queues)]
+}
+
+func newRMQUniqueKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+func (r *rmqOutput) properties(msg *service.Message) (map[string]string, string, error) {
+	props := map[string]string{}
+	_ = r.metaFilter.Walk(msg, func(key, value string) error {
+		props[key] = value
+		return nil
+	})
+
+	var err error
+	if props[rmqPropUniqueKey], err = newRMQUniqueKey(); err != nil {
+		return nil, "", err
+	}
+	props[rmqPropWaitStore] = "true"
+
+	if r.tags != nil {
+		if v := r.tags.String(msg); v != "" {
+			props[rmqPropTags] = v
+		}
+	}
+	if r.keys != nil {
+		if v := strings.TrimSpace(r.keys.String(msg)); v != "" {
+			props[rmqPropKeys] = v
+		}
+	}
+	if r.delayLevel != nil {
+		if v := r.delayLevel.String(msg); v != "" {
+			level, err := strconv.Atoi(v)
+			if err != nil || level < 0 || level > rmqMaxDelayLevel {
+				return nil, "", fmt.Errorf("delay level must be an integer between 0 and %v, got: %v", rmqMaxDelayLevel, v)
+			}
+			if level > 0 {
+				props[rmqPropDelayLevel] = strconv.Itoa(level)
+			}
+		}
+	}
+
+	var shardingKey string
+	if r.shardingKey != nil {
+		if shardingKey = r.shardingKey.String(msg); shardingKey != "" {
+			props[rmqPropShardingKey] = shardingKey
+		}
+	}
+	return props, shardingKey, nil
+}
+
+func (r *rmqOutput) Write(ctx context.Context, msg *service.Message) error {
+	topic := r.topic.String(msg)
+	body, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	props, shardingKey, err := r.properties(msg)
+	if err != nil {
+		return err
+	}
+
+	route, err := r.route(ctx, topic)
+	if err != nil {
+		return err
+	}
+	queues := route.queues(topic, rmqPermWrite)
+	if len(queues) == 0 {
+		return fmt.Errorf("topic %v has no writable queues", topic)
+	}
+	queue := r.selectQueue(queues, shardingKey)
+
+	r.m.RLock()
+	client := r.client
+	r.m.RUnlock()
+	if client == nil {
+		return service.ErrNotConnected
+	}
+
+	res, err := client.remoting.invoke(ctx, route.masterAddr(queue.BrokerName), newRMQRequest(rmqCodeSendMessage, map[string]string{
+		"producerGroup":         r.producerGroup,
+		"topic":                 topic,
+		"defaultTopic":          "TBW102",
+		"defaultTopicQueueNums": "4",
+		"queueId":               strconv.Itoa(queue.QueueID),
+		"sysFlag":               "0",
+		"bornTimestamp":         strconv.FormatInt(time.Now().UnixMilli(), 10),
+		"flag":                  "0",
+		"properties":            encodeRMQProperties(props),
+		"reconsumeTimes":        "0",
+		"unitMode":              "false",
+		"batch":                 "false",
+	}, body))
+	if err != nil {
+		// The route of the topic is obtained again for the next message, as
+		// the broker may have been removed from it.
+		r.m.Lock()
+		delete(r.routes, topic)
+		r.m.Unlock()
+		return err
+	}
+	if res.Code != rmqRespSuccess {
+		return fmt.Errorf("failed to send message to queue %v: %w", queue, res.err())
+	}
+	return nil
+}
+
+func (r *rmqOutput) Close(ctx context.Context) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.client != nil {
+		r.client.close()
+		r.client, r.routes = nil, nil
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package rocketmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testOutput(t *testing.T, b *testBroker, extra string) *rmqOutput {
+	t.Helper()
+
+	conf, err := rmqOutputSpec().ParseYAML(`
+name_server_addresses: [ `+b.addr+` ]
+topic: foo
+`+extra, nil)
+	require.NoError(t, err)
+
+	out, err := newRMQOutputFromParsed(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = out.Close(context.Background())
+	})
+	return out
+}
+
+func TestRocketMQOutput(t *testing.T) {
+	b := startTestBroker(t, "foo", 4)
+	out := testOutput(t, b, `
+tags: ${! this.kind }
+keys: ${! this.id } ${! this.kind }
+delay_level: ${! this.delay.or("") }
+sharding_key: ${! this.id }
+metadata:
+  include_prefixes: [ app_ ]
+`)
+
+	msg := service.NewMessage([]byte(`{"id":"order-1","kind":"paid","delay":"3"}`))
+	msg.MetaSet("app_region", "eu")
+	msg.MetaSet("other", "nope")
+	require.NoError(t, out.Write(context.Background(), msg))
+	require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte(`{"id":"order-1","kind":"shipped"}`))))
+
+	// Messages that share a sharding key are sent to the queue selected by the
+	// hash selector of the Java client.
+	queueID := int(javaStringHashCode("order-1"))
+	if queueID < 0 {
+		queueID = -queueID
+	}
+	queueID %= 4
+	msgs := b.messages(queueID)
+	require.Len(t, msgs, 2)
+
+	assert.Equal(t, `{"id":"order-1","kind":"paid","delay":"3"}`, string(msgs[0].Body))
+	props := msgs[0].Properties
+	assert.Len(t, props[rmqPropUniqueKey], 32)
+	delete(props, rmqPropUniqueKey)
+	assert.Equal(t, map[string]string{
+		rmqPropTags:        "paid",
+		rmqPropKeys:        "order-1 paid",
+		rmqPropDelayLevel:  "3",
+		rmqPropShardingKey: "order-1",
+		rmqPropWaitStore:   "true",
+		"app_region":       "eu",
+	}, props)
+
+	assert.Equal(t, "shipped", msgs[1].Properties[rmqPropTags])
+	assert.NotContains(t, msgs[1].Properties, rmqPropDelayLevel)
+	assert.NotEqual(t, msgs[0].Properties[rmqPropUniqueKey], msgs[1].Properties[rmqPropUniqueKey])
+
+	err := out.Write(context.Background(), service.NewMessage([]byte(`{"id":"order-2","delay":"19"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "delay level")
+}
+
+func TestRocketMQOutputRoundRobin(t *testing.T) {
+	b := startTestBroker(t, "foo", 3)
+	out := testOutput(t, b, "")
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, out.Write(context.Background(), service.NewMessage([]byte("hello"))))
+	}
+	for i := 0; i < 3; i++ {
+		assert.Len(t, b.messages(i), 2)
+	}
+}
+
+func TestRocketMQOutputUnknownTopic(t *testing.T) {
+	b := startTestBroker(t, "foo", 1)
+
+	conf, err := rmqOutputSpec().ParseYAML(`
+name_server_addresses: [ `+b.addr+` ]
+topic: bar
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newRMQOutputFromParsed(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+
+	err = out.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "topic bar does not exist")
+}
@@ -0,0 +1,409 @@
+package rocketmq
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The request codes of the remoting protocol that are used by the components
+// of this package.
+const (
+	rmqCodeSendMessage            = 10
+	rmqCodePullMessage            = 11
+	rmqCodeQueryConsumerOffset    = 14
+	rmqCodeUpdateConsumerOffset   = 15
+	rmqCodeGetMaxOffset           = 30
+	rmqCodeGetMinOffset           = 31
+	rmqCodeHeartbeat              = 34
+	rmqCodeUnregisterClient       = 35
+	rmqCodeGetConsumerListByGroup = 38
+	rmqCodeNotifyConsumerIDs      = 40
+	rmqCodeLockBatchMQ            = 41
+	rmqCodeUnlockBatchMQ          = 42
+	rmqCodeGetRouteInfoByTopic    = 105
+)
+
+// The response codes of the remoting protocol that are used by the components
+// of this package.
+const (
+	rmqRespSuccess              = 0
+	rmqRespNotSupported         = 3
+	rmqRespTopicNotExist        = 17
+	rmqRespPullNotFound         = 19
+	rmqRespPullRetryImmediately = 20
+	rmqRespPullOffsetMoved      = 21
+	rmqRespQueryNotFound        = 22
+)
+
+const (
+	rmqFlagResponse = 1 << 0
+	rmqFlagOneway   = 1 << 1
+
+	// The version of the protocol that is reported by clients, which is that
+	// of the 4.9 releases.
+	rmqProtocolVersion = 399
+
+	// The maximum size of a frame, which is that of the brokers.
+	rmqMaxFrameSize = 16 * 1024 * 1024
+)
+
+// rmqCommand is a request or response of the remoting protocol.
+type rmqCommand struct {
+	Code      int               `json:"code"`
+	Language  string            `json:"language"`
+	Version   int               `json:"version"`
+	Opaque    int32             `json:"opaque"`
+	Flag      int               `json:"flag"`
+	Remark    string            `json:"remark,omitempty"`
+	ExtFields map[string]string `json:"extFields,omitempty"`
+	Body      []byte            `json:"-"`
+}
+
+func newRMQRequest(code int, fields map[string]string, body []byte) *rmqCommand {
+	return &rmqCommand{
+		Code:      code,
+		Language:  "GO",
+		Version:   rmqProtocolVersion,
+		ExtFields: fields,
+		Body:      body,
+	}
+}
+
+func (c *rmqCommand) isResponse() bool {
+	return c.Flag&rmqFlagResponse != 0
+}
+
+// rmqResponseError is returned for responses that have an unexpected code.
+type rmqResponseError struct {
+	Code   int
+	Remark string
+}
+
+func (e *rmqResponseError) Error() string {
+	return fmt.Sprintf("response code %v: %v", e.Code, e.Remark)
+}
+
+func (c *rmqCommand) err() error {
+	return &rmqResponseError{Code: c.Code, Remark: c.Remark}
+}
+
+// writeFrame writes a command as a frame, which is the length of the frame
+// followed by the length of the JSON encoded header, the header and the body.
+func (c *rmqCommand) writeFrame(w io.Writer) error {
+	header, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8, 8+len(header)+len(c.Body))
+	binary.BigEndian.PutUint32(frame[0:], uint32(4+len(header)+len(c.Body)))
+	// The high byte of the header length is the type of serialization, where
+	// zero is JSON.
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(header)))
+	frame = append(frame, header...)
+	frame = append(frame, c.Body...)
+	_, err = w.Write(frame)
+	return err
+}
+
+func readRMQFrame(r io.Reader) (*rmqCommand, error) {
+	var prefix [8]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(prefix[0:])
+	headerInfo := binary.BigEndian.Uint32(prefix[4:])
+	if headerInfo>>24 != 0 {
+		return nil, fmt.Errorf("unsupported header serialization type: %v", headerInfo>>24)
+	}
+	headerLen := headerInfo & 0xFFFFFF
+	if length > rmqMaxFrameSize || headerLen > length-4 {
+		return nil, fmt.Errorf("invalid frame of length %v with a header of length %v", length, headerLen)
+	}
+
+	data := make([]byte, length-4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	c := &rmqCommand{}
+	if err := json.Unmarshal(data[:headerLen], c); err != nil {
+		return nil, fmt.Errorf("failed to parse header: %w", err)
+	}
+	if len(data) > int(headerLen) {
+		c.Body = data[headerLen:]
+	}
+	return c, nil
+}
+
+// rmqCredentials sign the requests of a client for brokers and name servers
+// that have ACL enabled.
+type rmqCredentials struct {
+	AccessKey string
+	SecretKey string
+}
+
+// sign adds the access key and signature of a request to its fields, where the
+// signature is the HMAC-SHA1 of the values of all fields in the order of their
+// keys followed by the body.
+func (c rmqCredentials) sign(cmd *rmqCommand) {
+	if c.AccessKey == "" {
+		return
+	}
+	if cmd.ExtFields == nil {
+		cmd.ExtFields = map[string]string{}
+	}
+	delete(cmd.ExtFields, "Signature")
+	cmd.ExtFields["AccessKey"] = c.AccessKey
+
+	keys := make([]string, 0, len(cmd.ExtFields))
+	for k := range cmd.ExtFields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	mac := hmac.New(sha1.New, []byte(c.SecretKey))
+	for _, k := range keys {
+		_, _ = mac.Write([]byte(cmd.ExtFields[k]))
+	}
+	_, _ = mac.Write(cmd.Body)
+	cmd.ExtFields["Signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+//------------------------------------------------------------------------------
+
+var errRMQConnClosed = errors.New("connection closed")
+
+// rmqConn is a connection to a name server or broker, over which requests are
+// matched with their responses by their opaque value.
+type rmqConn struct {
+	conn    net.Conn
+	onClose func()
+	handler func(*rmqCommand) *rmqCommand
+
+	writeMut sync.Mutex
+	opaque   int32
+
+	mut     sync.Mutex
+	pending map[int32]chan *rmqCommand
+	closed  bool
+}
+
+func (c *rmqConn) readLoop() {
+	r := bufio.NewReader(c.conn)
+	for {
+		cmd, err := readRMQFrame(r)
+		if err != nil {
+			c.close()
+			return
+		}
+		if !cmd.isResponse() {
+			// Requests are sent by brokers in order to notify clients of
+			// changes or query them, and are handled without blocking the
+			// responses of the connection.
+			go c.handleRequest(cmd)
+			continue
+		}
+		c.mut.Lock()
+		resChan, exists := c.pending[cmd.Opaque]
+		delete(c.pending, cmd.Opaque)
+		c.mut.Unlock()
+		if exists {
+			resChan <- cmd
+		}
+	}
+}
+
+func (c *rmqConn) handleRequest(cmd *rmqCommand) {
+	var res *rmqCommand
+	if c.handler != nil {
+		res = c.handler(cmd)
+	}
+	if cmd.Flag&rmqFlagOneway != 0 {
+		return
+	}
+	if res == nil {
+		res = &rmqCommand{Code: rmqRespNotSupported, Remark: fmt.Sprintf("request code %v is not supported", cmd.Code)}
+	}
+	res.Language, res.Version = "GO", rmqProtocolVersion
+	res.Opaque, res.Flag = cmd.Opaque, rmqFlagResponse
+	_ = c.write(res)
+}
+
+func (c *rmqConn) write(cmd *rmqCommand) error {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+	return cmd.writeFrame(c.conn)
+}
+
+func (c *rmqConn) invoke(ctx context.Context, cmd *rmqCommand) (*rmqCommand, error) {
+	cmd.Opaque = atomic.AddInt32(&c.opaque, 1)
+	resChan := make(chan *rmqCommand, 1)
+
+	c.mut.Lock()
+	if c.closed {
+		c.mut.Unlock()
+		return nil, errRMQConnClosed
+	}
+	c.pending[cmd.Opaque] = resChan
+	c.mut.Unlock()
+
+	defer func() {
+		c.mut.Lock()
+		delete(c.pending, cmd.Opaque)
+		c.mut.Unlock()
+	}()
+
+	if err := c.write(cmd); err != nil {
+		c.close()
+		return nil, err
+	}
+
+	select {
+	case res, open := <-resChan:
+		if !open {
+			return nil, errRMQConnClosed
+		}
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *rmqConn) oneway(cmd *rmqCommand) error {
+	cmd.Opaque = atomic.AddInt32(&c.opaque, 1)
+	cmd.Flag |= rmqFlagOneway
+	if err := c.write(cmd); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+func (c *rmqConn) close() {
+	c.mut.Lock()
+	if c.closed {
+		c.mut.Unlock()
+		return
+	}
+	c.closed = true
+	for opaque, resChan := range c.pending {
+		close(resChan)
+		delete(c.pending, opaque)
+	}
+	c.mut.Unlock()
+
+	_ = c.conn.Close()
+	c.onClose()
+}
+
+//------------------------------------------------------------------------------
+
+// rmqRemoting maintains a connection to each of the name servers and brokers
+// that requests are sent to, and signs each request.
+type rmqRemoting struct {
+	creds   rmqCredentials
+	timeout time.Duration
+	handler func(*rmqCommand) *rmqCommand
+
+	mut    sync.Mutex
+	conns  map[string]*rmqConn
+	closed bool
+}
+
+func newRMQRemoting(creds rmqCredentials, timeout time.Duration, handler func(*rmqCommand) *rmqCommand) *rmqRemoting {
+	return &rmqRemoting{
+		creds:   creds,
+		timeout: timeout,
+		handler: handler,
+		conns:   map[string]*rmqConn{},
+	}
+}
+
+func (r *rmqRemoting) conn(ctx context.Context, addr string) (*rmqConn, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.closed {
+		return nil, errRMQConnClosed
+	}
+	if c, exists := r.conns[addr]; exists {
+		return c, nil
+	}
+
+	dialer := net.Dialer{Timeout: r.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &rmqConn{
+		conn:    netConn,
+		handler: r.handler,
+		pending: map[int32]chan *rmqCommand{},
+	}
+	c.onClose = func() {
+		r.mut.Lock()
+		if r.conns[addr] == c {
+			delete(r.conns, addr)
+		}
+		r.mut.Unlock()
+	}
+	r.conns[addr] = c
+	go c.readLoop()
+	return c, nil
+}
+
+// invoke sends a request to an address and returns its response, where the
+// request is abandoned once the timeout of the remoting elapses unless the
+// context has a deadline of its own.
+func (r *rmqRemoting) invoke(ctx context.Context, addr string, cmd *rmqCommand) (*rmqCommand, error) {
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var done func()
+		ctx, done = context.WithTimeout(ctx, r.timeout)
+		defer done()
+	}
+	c, err := r.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	r.creds.sign(cmd)
+	res, err := c.invoke(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", addr, err)
+	}
+	return res, nil
+}
+
+func (r *rmqRemoting) oneway(ctx context.Context, addr string, cmd *rmqCommand) error {
+	c, err := r.conn(ctx, addr)
+	if err != nil {
+		return err
+	}
+	r.creds.sign(cmd)
+	return c.oneway(cmd)
+}
+
+func (r *rmqRemoting) close() {
+	r.mut.Lock()
+	r.closed = true
+	conns := make([]*rmqConn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mut.Unlock()
+
+	for _, c := range conns {
+		c.close()
+	}
+}
@@ -0,0 +1,135 @@
+package rocketmq
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRocketMQFrameRoundTrip(t *testing.T) {
+	cmd := newRMQRequest(rmqCodeSendMessage, map[string]string{"topic": "foo"}, []byte("hello world"))
+	cmd.Opaque = 7
+
+	var buf bytes.Buffer
+	require.NoError(t, cmd.writeFrame(&buf))
+	assert.Equal(t, uint32(buf.Len()-4), binary.BigEndian.Uint32(buf.Bytes()))
+
+	res, err := readRMQFrame(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, cmd, res)
+
+	_, err = readRMQFrame(bytes.NewReader([]byte{0, 0, 0, 8, 1, 0, 0, 4, 0, 0, 0, 0}))
+	require.Error(t, err)
+}
+
+func TestRocketMQSignature(t *testing.T) {
+	cmd := newRMQRequest(rmqCodeSendMessage, map[string]string{"topic": "foo", "queueId": "1"}, []byte("bar"))
+	rmqCredentials{AccessKey: "ak", SecretKey: "sk"}.sign(cmd)
+
+	assert.Equal(t, "ak", cmd.ExtFields["AccessKey"])
+	// The HMAC-SHA1 of "ak1foobar" with the key "sk".
+	assert.Equal(t, "hlE8Sxmb3VVD6OesQOQ/gYuCicc=", cmd.ExtFields["Signature"])
+
+	unsigned := newRMQRequest(rmqCodeSendMessage, nil, nil)
+	rmqCredentials{}.sign(unsigned)
+	assert.Nil(t, unsigned.ExtFields)
+}
+
+func TestRocketMQParseRoute(t *testing.T) {
+	route, err := parseRMQRoute([]byte(`{"brokerDatas":[{"brokerAddrs":{0:"10.0.0.1:10911",1:"10.0.0.2:10911"},"brokerName":"broker-b"},{"brokerAddrs":{1:"10.0.0.3:10911"},"brokerName":"broker-a"}],"queueDatas":[{"brokerName":"broker-b","perm":6,"readQueueNums":2,"writeQueueNums":1},{"brokerName":"broker-a","perm":6,"readQueueNums":2,"writeQueueNums":2}]}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.0.0.1:10911", route.masterAddr("broker-b"))
+	assert.Equal(t, []string{"10.0.0.1:10911"}, route.masterAddrs())
+	assert.Equal(t, []rmqQueue{
+		{Topic: "foo", BrokerName: "broker-b", QueueID: 0},
+		{Topic: "foo", BrokerName: "broker-b", QueueID: 1},
+	}, route.queues("foo", rmqPermRead))
+	assert.Equal(t, []rmqQueue{
+		{Topic: "foo", BrokerName: "broker-b", QueueID: 0},
+	}, route.queues("foo", rmqPermWrite))
+}
+
+func TestRocketMQAllocateQueues(t *testing.T) {
+	var queues []rmqQueue
+	for i := 0; i < 5; i++ {
+		queues = append(queues, rmqQueue{Topic: "foo", BrokerName: "a", QueueID: i})
+	}
+	ids := func(qs []rmqQueue) (ids []int) {
+		for _, q := range qs {
+			ids = append(ids, q.QueueID)
+		}
+		return
+	}
+
+	members := []string{"c1", "c2", "c3"}
+	assert.Equal(t, []int{0, 1}, ids(allocateRMQQueues(queues, members, "c1")))
+	assert.Equal(t, []int{2, 3}, ids(allocateRMQQueues(queues, members, "c2")))
+	assert.Equal(t, []int{4}, ids(allocateRMQQueues(queues, members, "c3")))
+	assert.Empty(t, allocateRMQQueues(queues, members, "c4"))
+
+	members = []string{"c1", "c2", "c3", "c4", "c5", "c6"}
+	assert.Equal(t, []int{4}, ids(allocateRMQQueues(queues, members, "c5")))
+	assert.Empty(t, allocateRMQQueues(queues, members, "c6"))
+}
+
+func TestRocketMQTagFilter(t *testing.T) {
+	all := newRMQTagFilter(" ")
+	assert.Equal(t, "*", all.expression)
+	assert.True(t, all.matches("anything"))
+	assert.Empty(t, all.codes())
+
+	f := newRMQTagFilter("TagA || TagB")
+	assert.True(t, f.matches("TagB"))
+	assert.False(t, f.matches("TagC"))
+	assert.False(t, f.matches(""))
+	assert.Equal(t, []int32{2598919, 2598920}, f.codes())
+
+	assert.Equal(t, int32(99162322), javaStringHashCode("hello"))
+	assert.Equal(t, int32(1772899), javaStringHashCode("😀"))
+}
+
+func TestRocketMQDecodeMessages(t *testing.T) {
+	first := encodeTestMessage(&rmqMessageExt{
+		Topic:          "foo",
+		QueueID:        2,
+		QueueOffset:    10,
+		ReconsumeTimes: 1,
+		BornTimestamp:  1000,
+		StoreTimestamp: 2000,
+		Body:           []byte("first"),
+		Properties:     map[string]string{rmqPropTags: "TagA", "custom": "bar"},
+	})
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("second"))
+	require.NoError(t, zw.Close())
+	second := encodeTestMessage(&rmqMessageExt{Topic: "foo", QueueOffset: 11, Body: compressed.Bytes()})
+	// Set the flag of the compressed body.
+	binary.BigEndian.PutUint32(second[36:], rmqSysFlagCompressed)
+
+	msgs, err := decodeRMQMessages(append(first, second...))
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	assert.Equal(t, &rmqMessageExt{
+		Topic:          "foo",
+		QueueID:        2,
+		QueueOffset:    10,
+		ReconsumeTimes: 1,
+		BornTimestamp:  1000,
+		StoreTimestamp: 2000,
+		Body:           []byte("first"),
+		Properties:     map[string]string{rmqPropTags: "TagA", "custom": "bar"},
+	}, msgs[0])
+	assert.Equal(t, "second", string(msgs[1].Body))
+	assert.Equal(t, int64(11), msgs[1].QueueOffset)
+
+	_, err = decodeRMQMessages(first[:len(first)-1])
+	require.Error(t, err)
+}
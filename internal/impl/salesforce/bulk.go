@@ -0,0 +1,135 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// sfBulkQuery is a query job of the Bulk API 2.0, which is created once and
+// then polled until complete, after which its results are read one page of
+// rows at a time.
+type sfBulkQuery struct {
+	client       *sfClient
+	query        string
+	includeAll   bool
+	pollInterval time.Duration
+	pageSize     int
+
+	jobID    string
+	complete bool
+	locator  string
+	done     bool
+}
+
+// init creates the job when it has not yet been created, and then blocks until
+// it is complete.
+func (b *sfBulkQuery) init(ctx context.Context) error {
+	if b.jobID == "" {
+		operation := "query"
+		if b.includeAll {
+			operation = "queryAll"
+		}
+		var job struct {
+			ID string `json:"id"`
+		}
+		if err := b.client.doJSON(ctx, http.MethodPost, "/jobs/query", map[string]any{
+			"operation": operation,
+			"query":     b.query,
+		}, &job); err != nil {
+			return fmt.Errorf("failed to create query job: %w", err)
+		}
+		b.jobID = job.ID
+	}
+
+	for !b.complete {
+		var job struct {
+			State        string `json:"state"`
+			ErrorMessage string `json:"errorMessage"`
+		}
+		if err := b.client.doJSON(ctx, http.MethodGet, "/jobs/query/"+b.jobID, nil, &job); err != nil {
+			return fmt.Errorf("failed to obtain state of query job %v: %w", b.jobID, err)
+		}
+
+		switch job.State {
+		case "JobComplete":
+			b.complete = true
+			continue
+		case "Failed", "Aborted":
+			// The job can not be recovered and therefore a new one is created
+			// the next time the query is read.
+			jobID := b.jobID
+			b.jobID = ""
+			if job.ErrorMessage != "" {
+				return fmt.Errorf("query job %v %v: %v", jobID, job.State, job.ErrorMessage)
+			}
+			return fmt.Errorf("query job %v %v", jobID, job.State)
+		}
+
+		select {
+		case <-time.After(b.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// nextPage returns the next page of results as objects keyed by the columns of
+// the query, or io.EOF once all results have been read.
+func (b *sfBulkQuery) nextPage(ctx context.Context) ([]map[string]any, error) {
+	if b.done {
+		return nil, io.EOF
+	}
+	if err := b.init(ctx); err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("maxRecords", strconv.Itoa(b.pageSize))
+	if b.locator != "" {
+		query.Set("locator", b.locator)
+	}
+
+	res, err := b.client.do(ctx, http.MethodGet, "/jobs/query/"+b.jobID+"/results?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain results of query job %v: %w", b.jobID, err)
+	}
+	defer res.Body.Close()
+
+	records, err := csv.NewReader(res.Body).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse results of query job %v: %w", b.jobID, err)
+	}
+
+	var rows []map[string]any
+	if len(records) > 0 {
+		columns := records[0]
+		for _, record := range records[1:] {
+			if len(record) != len(columns) {
+				return nil, errors.New("results contained a row with an unexpected number of columns")
+			}
+			row := make(map[string]any, len(columns))
+			for i, c := range columns {
+				// Empty fields and null fields are indistinguishable.
+				if record[i] == "" {
+					row[c] = nil
+				} else {
+					row[c] = record[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	if b.locator = res.Header.Get("Sforce-Locator"); b.locator == "" || b.locator == "null" {
+		b.done = true
+	}
+	return rows, nil
+}
@@ -0,0 +1,268 @@
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sfFieldLoginURL             = "login_url"
+	sfFieldOAuth2               = "oauth2"
+	sfFieldOAuth2ClientID       = "client_id"
+	sfFieldOAuth2ClientSecret   = "client_secret"
+	sfFieldOAuth2RefreshToken   = "refresh_token"
+	sfFieldAPIVersion           = "api_version"
+	sfTokenPath                 = "/services/oauth2/token"
+	sfDefaultSalesforceLoginURL = "https://login.salesforce.com"
+)
+
+func sfClientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(sfFieldLoginURL).
+			Description("The URL used to obtain access tokens, which must be the [My Domain](https://help.salesforce.com/s/articleView?id=sf.domain_name_overview.htm) URL of the org when tokens are obtained with the client credentials of a connected app.").
+			Example("https://login.salesforce.com").
+			Example("https://test.salesforce.com").
+			Example("https://acme.my.salesforce.com").
+			Default(sfDefaultSalesforceLoginURL),
+		service.NewObjectField(sfFieldOAuth2,
+			service.NewStringField(sfFieldOAuth2ClientID).
+				Description("The consumer key of the connected app."),
+			service.NewStringField(sfFieldOAuth2ClientSecret).
+				Description("The consumer secret of the connected app.").
+				Default(""),
+			service.NewStringField(sfFieldOAuth2RefreshToken).
+				Description("A refresh token of a user that authorised the connected app. When empty access tokens are obtained with the client credentials flow, which must be enabled for the connected app.").
+				Default(""),
+		).Description("The credentials of a [connected app](https://help.salesforce.com/s/articleView?id=sf.connected_app_overview.htm) used to obtain access tokens."),
+		service.NewStringField(sfFieldAPIVersion).
+			Description("The version of the REST API to use.").
+			Advanced().
+			Default("57.0"),
+	}
+}
+
+// sfSession is an access token issued for an org.
+type sfSession struct {
+	accessToken string
+	instanceURL string
+	orgID       string
+}
+
+// sfClient obtains access tokens for an org and calls its REST API.
+type sfClient struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	apiVersion   string
+	http         *http.Client
+
+	mut     sync.Mutex
+	session *sfSession
+}
+
+func sfClientFromParsed(conf *service.ParsedConfig) (*sfClient, error) {
+	c := &sfClient{http: http.DefaultClient}
+
+	loginURL, err := conf.FieldString(sfFieldLoginURL)
+	if err != nil {
+		return nil, err
+	}
+	c.tokenURL = strings.TrimSuffix(loginURL, "/") + sfTokenPath
+
+	oConf := conf.Namespace(sfFieldOAuth2)
+	if c.clientID, err = oConf.FieldString(sfFieldOAuth2ClientID); err != nil {
+		return nil, err
+	}
+	if c.clientSecret, err = oConf.FieldString(sfFieldOAuth2ClientSecret); err != nil {
+		return nil, err
+	}
+	if c.refreshToken, err = oConf.FieldString(sfFieldOAuth2RefreshToken); err != nil {
+		return nil, err
+	}
+	if c.apiVersion, err = conf.FieldString(sfFieldAPIVersion); err != nil {
+		return nil, err
+	}
+	c.apiVersion = strings.TrimPrefix(c.apiVersion, "v")
+	return c, nil
+}
+
+// sfOrgIDFromIdentity extracts the org ID from an identity URL, which is of the
+// form https://login.salesforce.com/id/{org id}/{user id}.
+func sfOrgIDFromIdentity(identity string) (string, error) {
+	u, err := url.Parse(identity)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse identity URL: %w", err)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 || parts[len(parts)-3] != "id" {
+		return "", fmt.Errorf("unexpected identity URL: %v", identity)
+	}
+	return parts[len(parts)-2], nil
+}
+
+func (c *sfClient) obtainSession(ctx context.Context) (*sfSession, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, c.http)
+
+	var tok *oauth2.Token
+	var err error
+	if c.refreshToken != "" {
+		conf := &oauth2.Config{
+			ClientID:     c.clientID,
+			ClientSecret: c.clientSecret,
+			Endpoint: oauth2.Endpoint{
+				TokenURL:  c.tokenURL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		}
+		tok, err = conf.TokenSource(ctx, &oauth2.Token{RefreshToken: c.refreshToken}).Token()
+	} else {
+		conf := &clientcredentials.Config{
+			ClientID:     c.clientID,
+			ClientSecret: c.clientSecret,
+			TokenURL:     c.tokenURL,
+			AuthStyle:    oauth2.AuthStyleInParams,
+		}
+		tok, err = conf.Token(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain access token: %w", err)
+	}
+
+	s := &sfSession{accessToken: tok.AccessToken}
+	s.instanceURL, _ = tok.Extra("instance_url").(string)
+	if s.instanceURL == "" {
+		return nil, errors.New("token response did not contain an instance URL")
+	}
+	s.instanceURL = strings.TrimSuffix(s.instanceURL, "/")
+
+	identity, _ := tok.Extra("id").(string)
+	if s.orgID, err = sfOrgIDFromIdentity(identity); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// getSession returns the current session, or obtains a new one when there is
+// no current session or the current session has been rejected. Salesforce
+// does not state when access tokens expire, and therefore sessions are only
+// replaced once rejected.
+func (c *sfClient) getSession(ctx context.Context, rejected *sfSession) (*sfSession, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.session != nil && c.session != rejected {
+		return c.session, nil
+	}
+	s, err := c.obtainSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.session = s
+	return s, nil
+}
+
+// sfError is an error returned by the REST API.
+type sfError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *sfError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("request failed with status %v", e.StatusCode)
+	}
+	return fmt.Sprintf("request failed with status %v: %v: %v", e.StatusCode, e.Code, e.Message)
+}
+
+func sfErrorFromResponse(res *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	err := &sfError{StatusCode: res.StatusCode}
+
+	var errs []struct {
+		ErrorCode string `json:"errorCode"`
+		Message   string `json:"message"`
+	}
+	if jErr := json.Unmarshal(body, &errs); jErr == nil && len(errs) > 0 {
+		err.Code, err.Message = errs[0].ErrorCode, errs[0].Message
+	}
+	return err
+}
+
+// do makes a request to the REST API of the org, where the path is relative to
+// the data services of the API version, and returns the response when it is
+// successful. A new session is obtained and the request retried once when the
+// session is rejected.
+func (c *sfClient) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var rejected *sfSession
+	for {
+		s, err := c.getSession(ctx, rejected)
+		if err != nil {
+			return nil, err
+		}
+
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, s.instanceURL+"/services/data/v"+c.apiVersion+path, bodyReader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+s.accessToken)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return res, nil
+		}
+
+		err = sfErrorFromResponse(res)
+		res.Body.Close()
+		if res.StatusCode == http.StatusUnauthorized && rejected == nil {
+			rejected = s
+			continue
+		}
+		return nil, err
+	}
+}
+
+func (c *sfClient) doJSON(ctx context.Context, method, path string, reqBody, resBody any) error {
+	var body []byte
+	if reqBody != nil {
+		var err error
+		if body, err = json.Marshal(reqBody); err != nil {
+			return err
+		}
+	}
+
+	res, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if resBody == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(resBody)
+}
@@ -0,0 +1,614 @@
+package salesforce
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/linkedin/goavro/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sfiFieldBulk               = "bulk"
+	sfiFieldBulkQuery          = "query"
+	sfiFieldBulkIncludeDeleted = "include_deleted"
+	sfiFieldBulkPollInterval   = "poll_interval"
+	sfiFieldBulkPageSize       = "page_size"
+	sfiFieldTopic              = "topic"
+	sfiFieldReplayPreset       = "replay_preset"
+	sfiFieldCache              = "cache"
+	sfiFieldCacheKey           = "cache_key"
+	sfiFieldBatchSize          = "batch_size"
+	sfiFieldCheckpointLimit    = "checkpoint_limit"
+	sfiFieldPubSubEndpoint     = "pubsub_endpoint"
+)
+
+func salesforceInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes records of a Salesforce org with the Bulk API 2.0 and change events with the Pub/Sub API.").
+		Description(`
+This input performs an initial load of records with a [Bulk API 2.0](https://developer.salesforce.com/docs/atlas.en-us.api_asynch.meta/api_asynch/queries.htm) query when ` + "`bulk.query`" + ` is set, and subscribes to a [Change Data Capture](https://developer.salesforce.com/docs/atlas.en-us.change_data_capture.meta/change_data_capture/cdc_intro.htm) or platform event channel of the [Pub/Sub API](https://developer.salesforce.com/docs/platform/pub-sub-api/overview) when ` + "`topic`" + ` is set. When both are set the channel is subscribed to once all records of the query have been consumed. When only a query is set the input shuts down once all records have been consumed.
+
+Each record of a query is consumed as a JSON object keyed by the fields selected, where all values are strings and empty fields are null. Each event is decoded from Avro with the schema of the event into a JSON object.
+
+### Replay IDs
+
+When ` + "`cache`" + ` is set the replay ID of the latest event that has been delivered, along with all events before it, is stored within the cache, and the subscription resumes from the stored replay ID when the input is restarted. Whilst a replay ID is stored the query of ` + "`bulk.query`" + ` is not run, as the records have already been consumed. Salesforce retains events for 72 hours, and therefore replay IDs that are older can not be resumed from.
+
+When there is no stored replay ID the subscription starts from ` + "`replay_preset`" + `. However, when a query is set the subscription instead starts from the earliest event retained in order that changes made whilst the query runs are not missed, which means changes already included within the records of the query may also be consumed as events.
+
+### Credentials
+
+Access tokens are obtained with the credentials of a [connected app](https://help.salesforce.com/s/articleView?id=sf.connected_app_overview.htm), which requires the OAuth scope ` + "`api`" + `, and also the scope ` + "`refresh_token`" + ` when ` + "`oauth2.refresh_token`" + ` is set.
+
+### Metadata
+
+This input adds the following metadata fields to each record of a query:
+
+` + "```" + `
+- salesforce_bulk_job_id
+` + "```" + `
+
+And the following metadata fields to each event:
+
+` + "```" + `
+- salesforce_topic
+- salesforce_event_id
+- salesforce_schema_id
+- salesforce_replay_id
+- salesforce_entity_name (change events only)
+- salesforce_change_type (change events only)
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range sfClientFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewObjectField(sfiFieldBulk,
+			service.NewStringField(sfiFieldBulkQuery).
+				Description("A SOQL query of the records to consume. When empty no records are queried.").
+				Example("SELECT Id, Name, Industry FROM Account").
+				Default(""),
+			service.NewBoolField(sfiFieldBulkIncludeDeleted).
+				Description("Whether to include records that have been deleted or archived.").
+				Default(false),
+			service.NewDurationField(sfiFieldBulkPollInterval).
+				Description("The period of time between each poll for the state of the query job.").
+				Advanced().
+				Default("5s"),
+			service.NewIntField(sfiFieldBulkPageSize).
+				Description("The maximum number of records to obtain with each request for the results of the query job.").
+				Advanced().
+				Default(10000),
+		).Description("Options for an initial load of records with a query job of the Bulk API 2.0.")).
+		Field(service.NewStringField(sfiFieldTopic).
+			Description("The channel to subscribe to. When empty no channel is subscribed to.").
+			Example("/data/ChangeEvents").
+			Example("/data/AccountChangeEvent").
+			Example("/event/Order_Placed__e").
+			Default("")).
+		Field(service.NewStringEnumField(sfiFieldReplayPreset, "latest", "earliest").
+			Description("The event to start the subscription from when there is no stored replay ID, where `latest` consumes only new events, and `earliest` consumes all events retained.").
+			Default("latest")).
+		Field(service.NewStringField(sfiFieldCache).
+			Description("A [cache resource](/docs/components/caches/about) to store replay IDs within. When empty replay IDs are not stored.").
+			Default("")).
+		Field(service.NewStringField(sfiFieldCacheKey).
+			Description("The key to store replay IDs at within the cache. When empty the key is the topic.").
+			Advanced().
+			Default("")).
+		Field(service.NewIntField(sfiFieldBatchSize).
+			Description("The maximum number of events to request from the subscription at a time.").
+			Advanced().
+			Default(100)).
+		Field(service.NewIntField(sfiFieldCheckpointLimit).
+			Description("The maximum number of events that can be in flight at a time. Events can be delivered out of order, but the stored replay ID only advances once all events before it have been delivered.").
+			Advanced().
+			Default(1024)).
+		Field(service.NewStringField(sfiFieldPubSubEndpoint).
+			Description("The address of the Pub/Sub API.").
+			Advanced().
+			Default("api.pubsub.salesforce.com:7443")).
+		Example(
+			"Account Sync",
+			"In this example all accounts are loaded and then changes to accounts are consumed as they are made, where the replay ID of the latest change consumed is stored within a Redis cache.",
+			`
+input:
+  salesforce:
+    login_url: https://acme.my.salesforce.com
+    oauth2:
+      client_id: "${SF_CLIENT_ID}"
+      client_secret: "${SF_CLIENT_SECRET}"
+    bulk:
+      query: SELECT Id, Name, Industry, LastModifiedDate FROM Account
+    topic: /data/AccountChangeEvent
+    cache: replay_ids
+
+cache_resources:
+  - label: replay_ids
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"salesforce", salesforceInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newSalesforceInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacks(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sfRecv struct {
+	res *sfFetchResponse
+	err error
+}
+
+type salesforceInput struct {
+	client    *sfClient
+	bulk      *sfBulkQuery
+	topic     string
+	preset    sfReplayPreset
+	cache     string
+	cacheKey  string
+	batchSize int
+	endpoint  string
+	dialOpts  []grpc.DialOption
+	mgr       *service.Resources
+	log       *service.Logger
+
+	checkpoints *checkpoint.Capped
+	commitMut   sync.Mutex
+
+	mut          sync.Mutex
+	connected    bool
+	replayLoaded bool
+	replayID     []byte
+	rows         []map[string]any
+	conn         *grpc.ClientConn
+	session      *sfSession
+	rejected     *sfSession
+	md           metadata.MD
+	sub          *sfSubscription
+	subCancel    context.CancelFunc
+	recvChan     chan sfRecv
+	events       []sfConsumerEvent
+	pending      int32
+	codecs       map[string]*goavro.Codec
+}
+
+func newSalesforceInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*salesforceInput, error) {
+	i := &salesforceInput{
+		mgr:    mgr,
+		log:    mgr.Logger(),
+		codecs: map[string]*goavro.Codec{},
+	}
+
+	var err error
+	if i.client, err = sfClientFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	bConf := conf.Namespace(sfiFieldBulk)
+	bulk := &sfBulkQuery{client: i.client}
+	if bulk.query, err = bConf.FieldString(sfiFieldBulkQuery); err != nil {
+		return nil, err
+	}
+	if bulk.includeAll, err = bConf.FieldBool(sfiFieldBulkIncludeDeleted); err != nil {
+		return nil, err
+	}
+	if bulk.pollInterval, err = bConf.FieldDuration(sfiFieldBulkPollInterval); err != nil {
+		return nil, err
+	}
+	if bulk.pageSize, err = bConf.FieldInt(sfiFieldBulkPageSize); err != nil {
+		return nil, err
+	}
+	if bulk.query != "" {
+		i.bulk = bulk
+	}
+
+	if i.topic, err = conf.FieldString(sfiFieldTopic); err != nil {
+		return nil, err
+	}
+	if i.topic == "" && i.bulk == nil {
+		return nil, fmt.Errorf("at least one of %v.%v and %v must be set", sfiFieldBulk, sfiFieldBulkQuery, sfiFieldTopic)
+	}
+
+	preset, err := conf.FieldString(sfiFieldReplayPreset)
+	if err != nil {
+		return nil, err
+	}
+	if preset == "earliest" {
+		i.preset = sfReplayEarliest
+	}
+
+	if i.cache, err = conf.FieldString(sfiFieldCache); err != nil {
+		return nil, err
+	}
+	if i.cache != "" && !mgr.HasCache(i.cache) {
+		return nil, fmt.Errorf("cache resource %v was not found", i.cache)
+	}
+	if i.cacheKey, err = conf.FieldString(sfiFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if i.cacheKey == "" {
+		i.cacheKey = i.topic
+	}
+
+	if i.batchSize, err = conf.FieldInt(sfiFieldBatchSize); err != nil {
+		return nil, err
+	}
+	if i.batchSize < 1 {
+		return nil, fmt.Errorf("%v must be greater than 0", sfiFieldBatchSize)
+	}
+
+	checkpointLimit, err := conf.FieldInt(sfiFieldCheckpointLimit)
+	if err != nil {
+		return nil, err
+	}
+	i.checkpoints = checkpoint.NewCapped(int64(checkpointLimit))
+
+	if i.endpoint, err = conf.FieldString(sfiFieldPubSubEndpoint); err != nil {
+		return nil, err
+	}
+	i.dialOpts = []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})),
+	}
+	return i, nil
+}
+
+// loadReplayID obtains the stored replay ID, if any, the first time the input
+// connects.
+func (i *salesforceInput) loadReplayID(ctx context.Context) error {
+	if i.replayLoaded || i.cache == "" || i.topic == "" {
+		return nil
+	}
+
+	var replayID []byte
+	var cErr error
+	if err := i.mgr.AccessCache(ctx, i.cache, func(c service.Cache) {
+		replayID, cErr = c.Get(ctx, i.cacheKey)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil && !errors.Is(cErr, service.ErrKeyNotFound) {
+		return fmt.Errorf("failed to obtain stored replay ID: %w", cErr)
+	}
+
+	if len(replayID) > 0 {
+		i.replayID = append([]byte(nil), replayID...)
+		if i.bulk != nil {
+			i.log.Infof("Skipping query as a replay ID is stored for topic %v", i.topic)
+			i.bulk = nil
+		}
+	}
+	i.replayLoaded = true
+	return nil
+}
+
+func (i *salesforceInput) subscribe(ctx context.Context) error {
+	s, err := i.client.getSession(ctx, i.rejected)
+	if err != nil {
+		return err
+	}
+	i.session, i.rejected = s, nil
+	i.md = metadata.Pairs(
+		"accesstoken", s.accessToken,
+		"instanceurl", s.instanceURL,
+		"tenantid", s.orgID,
+	)
+
+	if i.conn == nil {
+		if i.conn, err = grpc.DialContext(ctx, i.endpoint, i.dialOpts...); err != nil {
+			return fmt.Errorf("failed to dial pub/sub API: %w", err)
+		}
+	}
+
+	subCtx, subCancel := context.WithCancel(metadata.NewOutgoingContext(context.Background(), i.md))
+	sub, err := sfSubscribe(subCtx, i.conn)
+	if err != nil {
+		subCancel()
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	req := &sfFetchRequest{
+		TopicName:    i.topic,
+		ReplayPreset: i.preset,
+		NumRequested: int32(i.batchSize),
+	}
+	switch {
+	case len(i.replayID) > 0:
+		req.ReplayPreset = sfReplayCustom
+		req.ReplayID = i.replayID
+	case i.bulk != nil:
+		req.ReplayPreset = sfReplayEarliest
+	}
+	if err := sub.send(req); err != nil {
+		subCancel()
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	recvChan := make(chan sfRecv)
+	go func() {
+		for {
+			res, err := sub.recv()
+			select {
+			case recvChan <- sfRecv{res: res, err: err}:
+			case <-subCtx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	i.sub, i.subCancel, i.recvChan = sub, subCancel, recvChan
+	i.events, i.pending = nil, int32(i.batchSize)
+	return nil
+}
+
+func (i *salesforceInput) unsubscribe() {
+	if i.sub == nil {
+		return
+	}
+	_ = i.sub.close()
+	i.subCancel()
+	i.sub, i.subCancel, i.recvChan = nil, nil, nil
+	i.events = nil
+}
+
+func (i *salesforceInput) Connect(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.connected {
+		return nil
+	}
+	if err := i.loadReplayID(ctx); err != nil {
+		return err
+	}
+	if i.bulk != nil {
+		// The subscription is made once the query is complete.
+		if _, err := i.client.getSession(ctx, nil); err != nil {
+			return err
+		}
+	} else if err := i.subscribe(ctx); err != nil {
+		return err
+	}
+	i.connected = true
+	return nil
+}
+
+func (i *salesforceInput) readRow(ctx context.Context) (*service.Message, error) {
+	for len(i.rows) == 0 {
+		rows, err := i.bulk.nextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		i.rows = rows
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(i.rows[0])
+	msg.MetaSetMut("salesforce_bulk_job_id", i.bulk.jobID)
+	i.rows = i.rows[1:]
+	return msg, nil
+}
+
+func (i *salesforceInput) codec(ctx context.Context, schemaID string) (*goavro.Codec, error) {
+	if c, exists := i.codecs[schemaID]; exists {
+		return c, nil
+	}
+
+	schema, err := sfGetSchema(metadata.NewOutgoingContext(ctx, i.md), i.conn, schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain schema %v: %w", schemaID, err)
+	}
+	c, err := goavro.NewCodecForStandardJSONFull(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %v: %w", schemaID, err)
+	}
+	i.codecs[schemaID] = c
+	return c, nil
+}
+
+func (i *salesforceInput) eventToMessage(c *goavro.Codec, e *sfConsumerEvent) (*service.Message, error) {
+	native, _, err := c.NativeFromBinary(e.Event.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode event %v: %w", e.Event.ID, err)
+	}
+	jBytes, err := c.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode event %v: %w", e.Event.ID, err)
+	}
+
+	msg := service.NewMessage(jBytes)
+	msg.MetaSetMut("salesforce_topic", i.topic)
+	msg.MetaSetMut("salesforce_event_id", e.Event.ID)
+	msg.MetaSetMut("salesforce_schema_id", e.Event.SchemaID)
+	msg.MetaSetMut("salesforce_replay_id", base64.StdEncoding.EncodeToString(e.ReplayID))
+	if obj, ok := native.(map[string]any); ok {
+		if header, ok := obj["ChangeEventHeader"].(map[string]any); ok {
+			if v, ok := header["entityName"].(string); ok {
+				msg.MetaSetMut("salesforce_entity_name", v)
+			}
+			if v, ok := header["changeType"].(string); ok {
+				msg.MetaSetMut("salesforce_change_type", v)
+			}
+		}
+	}
+	return msg, nil
+}
+
+// nextEvent blocks until an event is received from the subscription, and
+// requests more events once all requested events have been received.
+func (i *salesforceInput) nextEvent(ctx context.Context) (*sfConsumerEvent, error) {
+	for len(i.events) == 0 {
+		if i.pending <= 0 {
+			if err := i.sub.send(&sfFetchRequest{
+				TopicName:    i.topic,
+				NumRequested: int32(i.batchSize),
+			}); err != nil {
+				return nil, i.subscriptionFailed(err)
+			}
+			i.pending = int32(i.batchSize)
+		}
+
+		var r sfRecv
+		select {
+		case r = <-i.recvChan:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if r.err != nil {
+			return nil, i.subscriptionFailed(r.err)
+		}
+		// Responses without events are sent periodically in order to keep the
+		// subscription alive.
+		i.events = r.res.Events
+		i.pending = r.res.PendingNumRequested
+	}
+
+	e := &i.events[0]
+	i.events = i.events[1:]
+	return e, nil
+}
+
+func (i *salesforceInput) subscriptionFailed(err error) error {
+	var sErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &sErr) && sErr.GRPCStatus().Code() == codes.Unauthenticated {
+		i.rejected = i.session
+	}
+	i.unsubscribe()
+	i.connected = false
+	if err == io.EOF {
+		i.log.Warnf("Subscription to topic %v was closed", i.topic)
+	} else {
+		i.log.Errorf("Subscription to topic %v failed: %v", i.topic, err)
+	}
+	return service.ErrNotConnected
+}
+
+func (i *salesforceInput) storeReplayID(ctx context.Context, replayID []byte) error {
+	var cErr error
+	if err := i.mgr.AccessCache(ctx, i.cache, func(c service.Cache) {
+		cErr = c.Set(ctx, i.cacheKey, replayID, nil)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil {
+		return fmt.Errorf("failed to store replay ID: %w", cErr)
+	}
+	return nil
+}
+
+func (i *salesforceInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if !i.connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if i.bulk != nil {
+		msg, err := i.readRow(ctx)
+		if err == nil {
+			return msg, func(context.Context, error) error { return nil }, nil
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, nil, err
+		}
+		if i.topic == "" {
+			return nil, nil, service.ErrEndOfInput
+		}
+		if err := i.subscribe(ctx); err != nil {
+			return nil, nil, err
+		}
+		// The query is only cleared once subscribed so that the subscription
+		// starts from the earliest event.
+		i.bulk = nil
+	}
+
+	for {
+		if i.sub == nil {
+			i.connected = false
+			return nil, nil, service.ErrNotConnected
+		}
+
+		e, err := i.nextEvent(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Failing to obtain a schema is likely transient, and the event is
+		// received again once subscribed as the subscription resumes from the
+		// last event read.
+		c, err := i.codec(ctx, e.Event.SchemaID)
+		if err != nil {
+			return nil, nil, i.subscriptionFailed(err)
+		}
+
+		msg, err := i.eventToMessage(c, e)
+		if err != nil {
+			i.log.Errorf("Skipping event: %v", err)
+			continue
+		}
+
+		release, err := i.checkpoints.Track(ctx, e.ReplayID, 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		i.replayID = e.ReplayID
+
+		return msg, func(ctx context.Context, err error) error {
+			i.commitMut.Lock()
+			defer i.commitMut.Unlock()
+
+			highest := release()
+			if highest == nil || i.cache == "" {
+				return nil
+			}
+			return i.storeReplayID(ctx, highest.([]byte))
+		}, nil
+	}
+}
+
+func (i *salesforceInput) Close(ctx context.Context) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	i.unsubscribe()
+	i.connected = false
+	if i.conn != nil {
+		err := i.conn.Close()
+		i.conn = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,470 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testSchema = `{
+  "type": "record",
+  "name": "AccountChangeEvent",
+  "fields": [
+    {
+      "name": "ChangeEventHeader",
+      "type": {
+        "type": "record",
+        "name": "ChangeEventHeader",
+        "fields": [
+          {"name": "entityName", "type": "string"},
+          {"name": "changeType", "type": {"type": "enum", "name": "ChangeType", "symbols": ["CREATE", "UPDATE", "DELETE"]}}
+        ]
+      }
+    },
+    {"name": "Name", "type": ["null", "string"], "default": null}
+  ]
+}`
+
+// testOrg is a fake of the token endpoint and the Bulk API 2.0 of an org,
+// which issues a new access token for each token request and only accepts the
+// access token most recently issued.
+type testOrg struct {
+	t   *testing.T
+	url string
+
+	mut     sync.Mutex
+	tokens  int
+	polls   int
+	queries []string
+	pages   [][]string
+}
+
+func startTestOrg(t *testing.T) *testOrg {
+	t.Helper()
+
+	o := &testOrg{t: t}
+	srv := httptest.NewServer(o)
+	t.Cleanup(srv.Close)
+	o.url = srv.URL
+	return o
+}
+
+func (o *testOrg) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if r.URL.Path == "/services/oauth2/token" {
+		require.NoError(o.t, r.ParseForm())
+		assert.Equal(o.t, "fooclient", r.Form.Get("client_id"))
+		o.tokens++
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token%v", o.tokens),
+			"token_type":   "Bearer",
+			"instance_url": o.url,
+			"id":           o.url + "/id/00Dorg/005user",
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token%v", o.tokens) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`[{"errorCode":"INVALID_SESSION_ID","message":"Session expired or invalid"}]`))
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/services/data/v57.0")
+	switch {
+	case r.Method == "POST" && path == "/jobs/query":
+		var body map[string]string
+		require.NoError(o.t, json.NewDecoder(r.Body).Decode(&body))
+		o.queries = append(o.queries, body["operation"]+": "+body["query"])
+		_, _ = w.Write([]byte(`{"id":"750job","state":"UploadComplete"}`))
+	case r.Method == "GET" && path == "/jobs/query/750job":
+		o.polls++
+		state := "InProgress"
+		if o.polls > 1 {
+			state = "JobComplete"
+		}
+		_, _ = fmt.Fprintf(w, `{"id":"750job","state":%q}`, state)
+	case r.Method == "GET" && path == "/jobs/query/750job/results":
+		assert.Equal(o.t, "2", r.URL.Query().Get("maxRecords"))
+		index := 0
+		if locator := r.URL.Query().Get("locator"); locator != "" {
+			_, _ = fmt.Sscanf(locator, "page%d", &index)
+		}
+		locator := "null"
+		if index+1 < len(o.pages) {
+			locator = fmt.Sprintf("page%d", index+1)
+		}
+		w.Header().Set("Sforce-Locator", locator)
+		_, _ = w.Write([]byte(strings.Join(o.pages[index], "\n") + "\n"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// testPubSub is a fake of the Pub/Sub API that serves events of a single
+// schema from a fixed log.
+type testPubSub struct {
+	t    *testing.T
+	addr string
+
+	mut      sync.Mutex
+	events   []sfConsumerEvent
+	requests []*sfFetchRequest
+	md       []metadata.MD
+}
+
+func startTestPubSub(t *testing.T, payloads ...string) *testPubSub {
+	t.Helper()
+
+	codec, err := goavro.NewCodecForStandardJSONFull(testSchema)
+	require.NoError(t, err)
+
+	p := &testPubSub{t: t}
+	for i, v := range payloads {
+		native, _, err := codec.NativeFromTextual([]byte(v))
+		require.NoError(t, err)
+		payload, err := codec.BinaryFromNative(nil, native)
+		require.NoError(t, err)
+
+		p.events = append(p.events, sfConsumerEvent{
+			Event: sfProducerEvent{
+				ID:       fmt.Sprintf("event%v", i),
+				SchemaID: "schema1",
+				Payload:  payload,
+			},
+			ReplayID: []byte{byte(i + 1)},
+		})
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	p.addr = lis.Addr().String()
+
+	srv := grpc.NewServer(grpc.ForceServerCodec(sfCodec{}))
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: sfPubSubService,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "GetSchema",
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				req := &sfSchemaRequest{}
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				if req.SchemaID != "schema1" {
+					return nil, errors.New("schema not found")
+				}
+				return &sfSchemaInfo{SchemaJSON: testSchema, SchemaID: req.SchemaID}, nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Subscribe",
+			Handler:       func(_ any, stream grpc.ServerStream) error { return p.subscribe(stream) },
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, p)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+	return p
+}
+
+func (p *testPubSub) subscribe(stream grpc.ServerStream) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	index := -1
+	for {
+		req := &sfFetchRequest{}
+		if err := stream.RecvMsg(req); err != nil {
+			return nil
+		}
+
+		p.mut.Lock()
+		if index == -1 {
+			p.md = append(p.md, md)
+			switch req.ReplayPreset {
+			case sfReplayEarliest:
+				index = 0
+			case sfReplayLatest:
+				index = len(p.events)
+			case sfReplayCustom:
+				for i, e := range p.events {
+					if string(e.ReplayID) == string(req.ReplayID) {
+						index = i + 1
+					}
+				}
+			}
+		}
+		p.requests = append(p.requests, req)
+
+		res := &sfFetchResponse{RPCID: "rpc"}
+		for len(res.Events) < int(req.NumRequested) && index < len(p.events) {
+			res.Events = append(res.Events, p.events[index])
+			index++
+		}
+		res.PendingNumRequested = req.NumRequested - int32(len(res.Events))
+		if len(p.events) > 0 {
+			res.LatestReplayID = p.events[len(p.events)-1].ReplayID
+		}
+		p.mut.Unlock()
+
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+}
+
+func testInput(t *testing.T, org *testOrg, pubsub *testPubSub, mgr *service.Resources, extra string) *salesforceInput {
+	t.Helper()
+
+	conf, err := salesforceInputSpec().ParseYAML(fmt.Sprintf(`
+login_url: %v
+oauth2:
+  client_id: fooclient
+  client_secret: foosecret
+pubsub_endpoint: %v
+bulk:
+  page_size: 2
+  poll_interval: 1ms
+`, org.url, pubsub.addr)+extra, nil)
+	require.NoError(t, err)
+
+	in, err := newSalesforceInputFromParsed(conf, mgr)
+	require.NoError(t, err)
+	in.dialOpts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	return in
+}
+
+func readTestMessage(t *testing.T, in *salesforceInput) (map[string]any, *service.Message, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	return v.(map[string]any), msg, ackFn
+}
+
+func storedReplayID(t *testing.T, mgr *service.Resources) []byte {
+	t.Helper()
+
+	var v []byte
+	var cErr error
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		v, cErr = c.Get(context.Background(), "/data/AccountChangeEvent")
+	}))
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return nil
+	}
+	require.NoError(t, cErr)
+	return v
+}
+
+func TestSalesforceInputQueryThenSubscribe(t *testing.T) {
+	org := startTestOrg(t)
+	org.pages = [][]string{
+		{"Id,Name", "001a,Acme", "001b,"},
+		{"Id,Name", `001c,"Foo, Inc"`},
+	}
+	pubsub := startTestPubSub(t,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE"},"Name":"Bar"}`,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"UPDATE"},"Name":null}`,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"DELETE"},"Name":null}`,
+	)
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	in := testInput(t, org, pubsub, mgr, `
+  query: SELECT Id, Name FROM Account
+topic: /data/AccountChangeEvent
+cache: foocache
+batch_size: 2
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	// Access tokens that are rejected are replaced.
+	org.mut.Lock()
+	org.tokens++
+	org.mut.Unlock()
+
+	var rows []map[string]any
+	for i := 0; i < 3; i++ {
+		v, msg, ackFn := readTestMessage(t, in)
+		rows = append(rows, v)
+		jobID, _ := msg.MetaGet("salesforce_bulk_job_id")
+		assert.Equal(t, "750job", jobID)
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	assert.Equal(t, []map[string]any{
+		{"Id": "001a", "Name": "Acme"},
+		{"Id": "001b", "Name": nil},
+		{"Id": "001c", "Name": "Foo, Inc"},
+	}, rows)
+
+	var events []map[string]any
+	var changeTypes []string
+	for i := 0; i < 3; i++ {
+		v, msg, ackFn := readTestMessage(t, in)
+		events = append(events, v)
+
+		changeType, _ := msg.MetaGet("salesforce_change_type")
+		changeTypes = append(changeTypes, changeType)
+		entity, _ := msg.MetaGet("salesforce_entity_name")
+		assert.Equal(t, "Account", entity)
+		eventID, _ := msg.MetaGet("salesforce_event_id")
+		assert.Equal(t, fmt.Sprintf("event%v", i), eventID)
+
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	assert.Equal(t, "Bar", events[0]["Name"])
+	assert.Nil(t, events[1]["Name"])
+	assert.Equal(t, []string{"CREATE", "UPDATE", "DELETE"}, changeTypes)
+	assert.Equal(t, []byte{3}, storedReplayID(t, mgr))
+
+	require.NoError(t, in.Close(context.Background()))
+
+	org.mut.Lock()
+	assert.Equal(t, []string{"query: SELECT Id, Name FROM Account"}, org.queries)
+	org.mut.Unlock()
+
+	pubsub.mut.Lock()
+	defer pubsub.mut.Unlock()
+	require.Len(t, pubsub.requests, 2)
+	assert.Equal(t, sfReplayEarliest, pubsub.requests[0].ReplayPreset)
+	assert.Equal(t, int32(2), pubsub.requests[0].NumRequested)
+	assert.Equal(t, "/data/AccountChangeEvent", pubsub.requests[1].TopicName)
+	require.Len(t, pubsub.md, 1)
+	assert.Equal(t, []string{"token3"}, pubsub.md[0].Get("accesstoken"))
+	assert.Equal(t, []string{org.url}, pubsub.md[0].Get("instanceurl"))
+	assert.Equal(t, []string{"00Dorg"}, pubsub.md[0].Get("tenantid"))
+}
+
+func TestSalesforceInputResume(t *testing.T) {
+	org := startTestOrg(t)
+	pubsub := startTestPubSub(t,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE"},"Name":"a"}`,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE"},"Name":"b"}`,
+		`{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE"},"Name":"c"}`,
+	)
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "/data/AccountChangeEvent", []byte{1}, nil))
+	}))
+
+	in := testInput(t, org, pubsub, mgr, `
+  query: SELECT Id FROM Account
+topic: /data/AccountChangeEvent
+cache: foocache
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	// The query is skipped as a replay ID is stored.
+	b, _, ackB := readTestMessage(t, in)
+	c, _, ackC := readTestMessage(t, in)
+	assert.Equal(t, "b", b["Name"])
+	assert.Equal(t, "c", c["Name"])
+
+	// The replay ID only advances once all events before it are delivered.
+	require.NoError(t, ackC(context.Background(), nil))
+	assert.Equal(t, []byte{1}, storedReplayID(t, mgr))
+	require.NoError(t, ackB(context.Background(), nil))
+	assert.Equal(t, []byte{3}, storedReplayID(t, mgr))
+
+	// When the subscription fails it resumes from the last event read.
+	in.mut.Lock()
+	_ = in.subscriptionFailed(errors.New("nope"))
+	in.mut.Unlock()
+
+	require.NoError(t, in.Connect(context.Background()))
+	require.Eventually(t, func() bool {
+		pubsub.mut.Lock()
+		defer pubsub.mut.Unlock()
+		return len(pubsub.requests) == 2
+	}, time.Second*5, time.Millisecond*10)
+	require.NoError(t, in.Close(context.Background()))
+
+	org.mut.Lock()
+	assert.Empty(t, org.queries)
+	org.mut.Unlock()
+
+	pubsub.mut.Lock()
+	defer pubsub.mut.Unlock()
+	require.Len(t, pubsub.requests, 2)
+	assert.Equal(t, sfReplayCustom, pubsub.requests[0].ReplayPreset)
+	assert.Equal(t, []byte{1}, pubsub.requests[0].ReplayID)
+	assert.Equal(t, sfReplayCustom, pubsub.requests[1].ReplayPreset)
+	assert.Equal(t, []byte{3}, pubsub.requests[1].ReplayID)
+}
+
+func TestSalesforceInputQueryOnly(t *testing.T) {
+	org := startTestOrg(t)
+	org.pages = [][]string{{"Id", "001a"}}
+
+	in := testInput(t, org, &testPubSub{}, service.MockResources(), `
+  query: SELECT Id FROM Account
+  include_deleted: true
+`)
+	require.NoError(t, in.Connect(context.Background()))
+
+	v, _, _ := readTestMessage(t, in)
+	assert.Equal(t, map[string]any{"Id": "001a"}, v)
+
+	_, _, err := in.Read(context.Background())
+	assert.True(t, errors.Is(err, service.ErrEndOfInput), err)
+
+	org.mut.Lock()
+	defer org.mut.Unlock()
+	assert.Equal(t, []string{"queryAll: SELECT Id FROM Account"}, org.queries)
+}
+
+func TestSalesforceInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{conf: `oauth2: { client_id: foo }`, errStr: "at least one of"},
+		{conf: "oauth2: { client_id: foo }\ntopic: /data/ChangeEvents\ncache: nope", errStr: "cache resource nope"},
+		{conf: "oauth2: { client_id: foo }\ntopic: /data/ChangeEvents\nbatch_size: 0", errStr: "batch_size"},
+	} {
+		conf, err := salesforceInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newSalesforceInputFromParsed(conf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errStr)
+	}
+}
+
+func TestSalesforceOrgIDFromIdentity(t *testing.T) {
+	orgID, err := sfOrgIDFromIdentity("https://login.salesforce.com/id/00Dxx0000001gPL/005xx000001Sv6e")
+	require.NoError(t, err)
+	assert.Equal(t, "00Dxx0000001gPL", orgID)
+
+	_, err = sfOrgIDFromIdentity("https://login.salesforce.com/services/oauth2/userinfo")
+	require.Error(t, err)
+}
@@ -0,0 +1,345 @@
+package salesforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages and methods of the Salesforce Pub/Sub API that are used, which
+// are defined at https://github.com/developerforce/pub-sub-api. The messages
+// are encoded by hand in order to avoid generating code for the whole API.
+const (
+	sfPubSubService   = "eventbus.v1.PubSub"
+	sfSubscribeMethod = "/" + sfPubSubService + "/Subscribe"
+	sfSchemaMethod    = "/" + sfPubSubService + "/GetSchema"
+)
+
+type sfReplayPreset int32
+
+const (
+	sfReplayLatest   sfReplayPreset = 0
+	sfReplayEarliest sfReplayPreset = 1
+	sfReplayCustom   sfReplayPreset = 2
+)
+
+type sfProtoMessage interface {
+	marshalProto() []byte
+	unmarshalProto(b []byte) error
+}
+
+// sfCodec encodes the hand written messages of the Pub/Sub API.
+type sfCodec struct{}
+
+func (sfCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(sfProtoMessage)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+	return m.marshalProto(), nil
+}
+
+func (sfCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(sfProtoMessage)
+	if !ok {
+		return fmt.Errorf("unsupported message type %T", v)
+	}
+	return m.unmarshalProto(data)
+}
+
+func (sfCodec) Name() string {
+	return "proto"
+}
+
+var _ encoding.Codec = sfCodec{}
+
+// sfRangeFields calls fn for each field of an encoded message.
+func sfRangeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var value []byte
+		var varint uint64
+		switch typ {
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, value, varint); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sfAppendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func sfAppendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func sfAppendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+type sfFetchRequest struct {
+	TopicName    string
+	ReplayPreset sfReplayPreset
+	ReplayID     []byte
+	NumRequested int32
+}
+
+func (r *sfFetchRequest) marshalProto() []byte {
+	var b []byte
+	b = sfAppendString(b, 1, r.TopicName)
+	b = sfAppendVarint(b, 2, uint64(r.ReplayPreset))
+	b = sfAppendBytes(b, 3, r.ReplayID)
+	b = sfAppendVarint(b, 4, uint64(r.NumRequested))
+	return b
+}
+
+func (r *sfFetchRequest) unmarshalProto(b []byte) error {
+	*r = sfFetchRequest{}
+	return sfRangeFields(b, func(num protowire.Number, _ protowire.Type, value []byte, varint uint64) error {
+		switch num {
+		case 1:
+			r.TopicName = string(value)
+		case 2:
+			r.ReplayPreset = sfReplayPreset(varint)
+		case 3:
+			r.ReplayID = append([]byte(nil), value...)
+		case 4:
+			r.NumRequested = int32(varint)
+		}
+		return nil
+	})
+}
+
+type sfEventHeader struct {
+	Key   string
+	Value []byte
+}
+
+type sfProducerEvent struct {
+	ID       string
+	SchemaID string
+	Payload  []byte
+	Headers  []sfEventHeader
+}
+
+func (e *sfProducerEvent) marshalProto() []byte {
+	var b []byte
+	b = sfAppendString(b, 1, e.ID)
+	b = sfAppendString(b, 2, e.SchemaID)
+	b = sfAppendBytes(b, 3, e.Payload)
+	for _, h := range e.Headers {
+		var hb []byte
+		hb = sfAppendString(hb, 1, h.Key)
+		hb = sfAppendBytes(hb, 2, h.Value)
+		b = protowire.AppendTag(b, 4, protowire.BytesType)
+		b = protowire.AppendBytes(b, hb)
+	}
+	return b
+}
+
+func (e *sfProducerEvent) unmarshalProto(b []byte) error {
+	*e = sfProducerEvent{}
+	return sfRangeFields(b, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64) error {
+		switch num {
+		case 1:
+			e.ID = string(value)
+		case 2:
+			e.SchemaID = string(value)
+		case 3:
+			e.Payload = append([]byte(nil), value...)
+		case 4:
+			var h sfEventHeader
+			if err := sfRangeFields(value, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64) error {
+				switch num {
+				case 1:
+					h.Key = string(value)
+				case 2:
+					h.Value = append([]byte(nil), value...)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			e.Headers = append(e.Headers, h)
+		}
+		return nil
+	})
+}
+
+type sfConsumerEvent struct {
+	Event    sfProducerEvent
+	ReplayID []byte
+}
+
+type sfFetchResponse struct {
+	Events              []sfConsumerEvent
+	LatestReplayID      []byte
+	RPCID               string
+	PendingNumRequested int32
+}
+
+func (r *sfFetchResponse) marshalProto() []byte {
+	var b []byte
+	for _, e := range r.Events {
+		var eb []byte
+		eb = sfAppendBytes(eb, 1, e.Event.marshalProto())
+		eb = sfAppendBytes(eb, 2, e.ReplayID)
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, eb)
+	}
+	b = sfAppendBytes(b, 2, r.LatestReplayID)
+	b = sfAppendString(b, 3, r.RPCID)
+	b = sfAppendVarint(b, 4, uint64(r.PendingNumRequested))
+	return b
+}
+
+func (r *sfFetchResponse) unmarshalProto(b []byte) error {
+	*r = sfFetchResponse{}
+	return sfRangeFields(b, func(num protowire.Number, _ protowire.Type, value []byte, varint uint64) error {
+		switch num {
+		case 1:
+			var e sfConsumerEvent
+			if err := sfRangeFields(value, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64) error {
+				switch num {
+				case 1:
+					return e.Event.unmarshalProto(value)
+				case 2:
+					e.ReplayID = append([]byte(nil), value...)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			r.Events = append(r.Events, e)
+		case 2:
+			r.LatestReplayID = append([]byte(nil), value...)
+		case 3:
+			r.RPCID = string(value)
+		case 4:
+			r.PendingNumRequested = int32(varint)
+		}
+		return nil
+	})
+}
+
+type sfSchemaRequest struct {
+	SchemaID string
+}
+
+func (r *sfSchemaRequest) marshalProto() []byte {
+	return sfAppendString(nil, 1, r.SchemaID)
+}
+
+func (r *sfSchemaRequest) unmarshalProto(b []byte) error {
+	*r = sfSchemaRequest{}
+	return sfRangeFields(b, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64) error {
+		if num == 1 {
+			r.SchemaID = string(value)
+		}
+		return nil
+	})
+}
+
+type sfSchemaInfo struct {
+	SchemaJSON string
+	SchemaID   string
+}
+
+func (s *sfSchemaInfo) marshalProto() []byte {
+	var b []byte
+	b = sfAppendString(b, 1, s.SchemaJSON)
+	b = sfAppendString(b, 2, s.SchemaID)
+	return b
+}
+
+func (s *sfSchemaInfo) unmarshalProto(b []byte) error {
+	*s = sfSchemaInfo{}
+	return sfRangeFields(b, func(num protowire.Number, _ protowire.Type, value []byte, _ uint64) error {
+		switch num {
+		case 1:
+			s.SchemaJSON = string(value)
+		case 2:
+			s.SchemaID = string(value)
+		}
+		return nil
+	})
+}
+
+// sfSubscription is a bidirectional stream of the Subscribe method.
+type sfSubscription struct {
+	stream grpc.ClientStream
+}
+
+func sfSubscribe(ctx context.Context, conn *grpc.ClientConn) (*sfSubscription, error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{
+		StreamName:    "Subscribe",
+		ServerStreams: true,
+		ClientStreams: true,
+	}, sfSubscribeMethod, grpc.ForceCodec(sfCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	return &sfSubscription{stream: stream}, nil
+}
+
+func (s *sfSubscription) send(req *sfFetchRequest) error {
+	return s.stream.SendMsg(req)
+}
+
+func (s *sfSubscription) recv() (*sfFetchResponse, error) {
+	res := &sfFetchResponse{}
+	if err := s.stream.RecvMsg(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (s *sfSubscription) close() error {
+	return s.stream.CloseSend()
+}
+
+func sfGetSchema(ctx context.Context, conn *grpc.ClientConn, schemaID string) (string, error) {
+	res := &sfSchemaInfo{}
+	if err := conn.Invoke(ctx, sfSchemaMethod, &sfSchemaRequest{SchemaID: schemaID}, res, grpc.ForceCodec(sfCodec{})); err != nil {
+		return "", err
+	}
+	if res.SchemaJSON == "" {
+		return "", errors.New("schema response was empty")
+	}
+	return res.SchemaJSON, nil
+}
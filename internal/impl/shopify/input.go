@@ -0,0 +1,558 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	shiFieldShop         = "shop"
+	shiFieldAccessToken  = "access_token"
+	shiFieldResource     = "resource"
+	shiFieldAPIVersion   = "api_version"
+	shiFieldStartTime    = "start_time"
+	shiFieldPollInterval = "poll_interval"
+	shiFieldPageSize     = "page_size"
+	shiFieldCache        = "cache"
+	shiFieldCacheKey     = "cache_key"
+	shiFieldRateLimit    = "rate_limit"
+	shiFieldAPIURL       = "api_url"
+
+	// The maximum number of pages that can be in flight before reads block.
+	shopifyCheckpointLimit = 64
+
+	// The rate at which the request bucket of a shop leaks, which is the
+	// lowest rate of all plans.
+	shopifyLeakRate = 2
+)
+
+func shopifyInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes the records of a resource of a Shopify shop in the order that they were updated.").
+		Description(`
+Pages through the records of a resource with the [REST Admin API](https://shopify.dev/docs/api/admin-rest) in the order that they were updated from a cursor, which is the time that the latest record consumed was updated, and consumes each page of records as a batch. Once all records have been consumed the input polls for records that have been updated every `+"`poll_interval`"+`. A record is consumed again each time that it is updated, and deleted records are not consumed.
+
+When `+"`cache`"+` is set the cursor is stored within the cache once each page of records has been delivered, along with all pages before it, and the input resumes from the stored cursor when restarted. Otherwise, or when no cursor is stored, the input starts from `+"`start_time`"+`.
+
+### Rate Limits
+
+Requests are paced such that the [request bucket](https://shopify.dev/docs/api/usage/rate-limits) of the shop remains less than half full, in order to leave room for other clients of the shop, and requests that are rate limited are retried once the period of the `+"`Retry-After`"+` header has elapsed. Requests can be paced further with a `+"[`rate_limit`](/docs/components/rate_limits/about)"+` resource.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- shopify_resource
+- shopify_id
+- shopify_updated_at
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(shiFieldShop).
+			Description("The name of the shop, which is the subdomain of its `myshopify.com` domain.").
+			Example("acme")).
+		Field(service.NewStringField(shiFieldAccessToken).
+			Description("An access token of a custom app with read access to the resource.")).
+		Field(service.NewStringEnumField(shiFieldResource, "orders", "products", "customers", "draft_orders").
+			Description("The resource to consume the records of. Orders of any status are consumed, including closed and cancelled orders.")).
+		Field(service.NewStringField(shiFieldAPIVersion).
+			Description("The version of the REST Admin API to use.").
+			Advanced().
+			Default("2023-01")).
+		Field(service.NewStringField(shiFieldStartTime).
+			Description("An RFC 3339 timestamp of the time to consume records updated from when there is no stored cursor. When empty all records are consumed.").
+			Example("2023-01-01T00:00:00Z").
+			Default("")).
+		Field(service.NewDurationField(shiFieldPollInterval).
+			Description("The period of time between each poll for updated records once all records have been consumed.").
+			Default("1m")).
+		Field(service.NewIntField(shiFieldPageSize).
+			Description("The maximum number of records of each page, between 1 and 250.").
+			Advanced().
+			Default(250)).
+		Field(service.NewStringField(shiFieldCache).
+			Description("A [cache resource](/docs/components/caches/about) to store the cursor within. When empty the cursor is not stored.").
+			Default("")).
+		Field(service.NewStringField(shiFieldCacheKey).
+			Description("The key to store the cursor at within the cache. When empty the key is `shopify_` followed by the resource.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField(shiFieldRateLimit).
+			Description("An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by.").
+			Default("")).
+		Field(service.NewStringField(shiFieldAPIURL).
+			Description("The URL of the shop. When empty the URL is the `myshopify.com` domain of the shop.").
+			Advanced().
+			Default("")).
+		Example(
+			"Order Sync",
+			"In this example orders are consumed as they are updated, where the cursor is stored within a Redis cache.",
+			`
+input:
+  shopify:
+    shop: acme
+    access_token: "${SHOPIFY_ACCESS_TOKEN}"
+    resource: orders
+    start_time: 2023-01-01T00:00:00Z
+    cache: cursors
+
+cache_resources:
+  - label: cursors
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"shopify", shopifyInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newShopifyInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// shopifyCursor is the time that the latest record consumed was updated, along
+// with the IDs of all records consumed that were updated at that time, as
+// records are obtained from the time of the cursor inclusively.
+type shopifyCursor struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	IDs       []int64   `json:"ids,omitempty"`
+}
+
+func (c shopifyCursor) consumed(id int64, updatedAt time.Time) bool {
+	if updatedAt.Before(c.UpdatedAt) {
+		return true
+	}
+	if updatedAt.Equal(c.UpdatedAt) {
+		for _, v := range c.IDs {
+			if v == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c shopifyCursor) advance(id int64, updatedAt time.Time) shopifyCursor {
+	if updatedAt.Equal(c.UpdatedAt) {
+		return shopifyCursor{UpdatedAt: c.UpdatedAt, IDs: append(append([]int64(nil), c.IDs...), id)}
+	}
+	return shopifyCursor{UpdatedAt: updatedAt, IDs: []int64{id}}
+}
+
+type shopifyRecord struct {
+	ID        int64     `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	raw       []byte
+}
+
+type shopifyInput struct {
+	apiURL       string
+	accessToken  string
+	resource     string
+	apiVersion   string
+	startTime    time.Time
+	pollInterval time.Duration
+	pageSize     int
+	cache        string
+	cacheKey     string
+	rateLimit    string
+	http         *http.Client
+	mgr          *service.Resources
+	log          *service.Logger
+
+	checkpoints *checkpoint.Capped
+	commitMut   sync.Mutex
+
+	mut          sync.Mutex
+	connected    bool
+	cursorLoaded bool
+	cursor       shopifyCursor
+	nextLink     string
+	caughtUp     bool
+	lastPoll     time.Time
+}
+
+func newShopifyInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*shopifyInput, error) {
+	s := &shopifyInput{
+		http:        http.DefaultClient,
+		mgr:         mgr,
+		log:         mgr.Logger(),
+		checkpoints: checkpoint.NewCapped(shopifyCheckpointLimit),
+	}
+
+	shop, err := conf.FieldString(shiFieldShop)
+	if err != nil {
+		return nil, err
+	}
+	shop = strings.TrimSuffix(shop, ".myshopify.com")
+	if s.accessToken, err = conf.FieldString(shiFieldAccessToken); err != nil {
+		return nil, err
+	}
+	if s.resource, err = conf.FieldString(shiFieldResource); err != nil {
+		return nil, err
+	}
+	if s.apiVersion, err = conf.FieldString(shiFieldAPIVersion); err != nil {
+		return nil, err
+	}
+
+	startTime, err := conf.FieldString(shiFieldStartTime)
+	if err != nil {
+		return nil, err
+	}
+	if startTime != "" {
+		if s.startTime, err = time.Parse(time.RFC3339, startTime); err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %w", shiFieldStartTime, err)
+		}
+	}
+
+	if s.pollInterval, err = conf.FieldDuration(shiFieldPollInterval); err != nil {
+		return nil, err
+	}
+	if s.pageSize, err = conf.FieldInt(shiFieldPageSize); err != nil {
+		return nil, err
+	}
+	if s.pageSize < 1 || s.pageSize > 250 {
+		return nil, fmt.Errorf("%v must be between 1 and 250", shiFieldPageSize)
+	}
+	if s.cache, err = conf.FieldString(shiFieldCache); err != nil {
+		return nil, err
+	}
+	if s.cache != "" && !mgr.HasCache(s.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", s.cache)
+	}
+	if s.cacheKey, err = conf.FieldString(shiFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if s.cacheKey == "" {
+		s.cacheKey = "shopify_" + s.resource
+	}
+	if s.rateLimit, err = conf.FieldString(shiFieldRateLimit); err != nil {
+		return nil, err
+	}
+	if s.rateLimit != "" && !mgr.HasRateLimit(s.rateLimit) {
+		return nil, fmt.Errorf("rate limit resource '%v' was not found", s.rateLimit)
+	}
+	if s.apiURL, err = conf.FieldString(shiFieldAPIURL); err != nil {
+		return nil, err
+	}
+	if s.apiURL == "" {
+		s.apiURL = "https://" + shop + ".myshopify.com"
+	}
+	s.apiURL = strings.TrimSuffix(s.apiURL, "/")
+	return s, nil
+}
+
+func (s *shopifyInput) wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *shopifyInput) waitForAccess(ctx context.Context) error {
+	if s.rateLimit == "" {
+		return nil
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := s.mgr.AccessRateLimit(ctx, s.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			s.log.Errorf("Rate limit error: %v\n", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		if err := s.wait(ctx, period); err != nil {
+			return err
+		}
+	}
+}
+
+// bucketDelay returns the period of time to wait before the next request in
+// order for the request bucket to leak until it is half full, which is
+// reported as the number of requests used of the size of the bucket.
+func bucketDelay(callLimit string) time.Duration {
+	usedStr, sizeStr, ok := strings.Cut(callLimit, "/")
+	if !ok {
+		return 0
+	}
+	used, err := strconv.Atoi(usedStr)
+	if err != nil {
+		return 0
+	}
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil {
+		return 0
+	}
+	if excess := used - size/2; excess > 0 {
+		return time.Duration(excess) * time.Second / shopifyLeakRate
+	}
+	return 0
+}
+
+var linkNextRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// listRecords obtains a page of records from a URL, and returns the URL of the
+// next page when there is one.
+func (s *shopifyInput) listRecords(ctx context.Context, pageURL string) ([]*shopifyRecord, string, error) {
+	for {
+		if err := s.waitForAccess(ctx); err != nil {
+			return nil, "", err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("X-Shopify-Access-Token", s.accessToken)
+
+		res, err := s.http.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			res.Body.Close()
+			wait := time.Second
+			if secs, err := strconv.ParseFloat(res.Header.Get("Retry-After"), 64); err == nil {
+				wait = time.Duration(secs * float64(time.Second))
+			}
+			s.log.Debugf("Request rate limited, retrying in %v", wait)
+			if err := s.wait(ctx, wait); err != nil {
+				return nil, "", err
+			}
+			continue
+		}
+
+		var body map[string]json.RawMessage
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			if errs, exists := body["errors"]; exists {
+				return nil, "", fmt.Errorf("request failed with status %v: %s", res.StatusCode, errs)
+			}
+			return nil, "", fmt.Errorf("request failed with status %v", res.StatusCode)
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decode records: %w", err)
+		}
+
+		var raws []json.RawMessage
+		if err := json.Unmarshal(body[s.resource], &raws); err != nil {
+			return nil, "", fmt.Errorf("failed to decode records: %w", err)
+		}
+		records := make([]*shopifyRecord, 0, len(raws))
+		for _, raw := range raws {
+			r := &shopifyRecord{raw: raw}
+			if err := json.Unmarshal(raw, r); err != nil {
+				return nil, "", fmt.Errorf("failed to decode record: %w", err)
+			}
+			records = append(records, r)
+		}
+
+		var next string
+		if m := linkNextRegexp.FindStringSubmatch(res.Header.Get("Link")); m != nil {
+			next = m[1]
+		}
+
+		// Pacing is applied after the response so that the first request of a
+		// poll is never delayed.
+		if err := s.wait(ctx, bucketDelay(res.Header.Get("X-Shopify-Shop-Api-Call-Limit"))); err != nil {
+			return nil, "", err
+		}
+		return records, next, nil
+	}
+}
+
+func (s *shopifyInput) loadCursor(ctx context.Context) error {
+	if s.cursorLoaded {
+		return nil
+	}
+	s.cursor = shopifyCursor{UpdatedAt: s.startTime}
+	if s.cache != "" {
+		var cursor []byte
+		var cErr error
+		if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+			cursor, cErr = c.Get(ctx, s.cacheKey)
+		}); err != nil {
+			return err
+		}
+		if cErr != nil && !errors.Is(cErr, service.ErrKeyNotFound) {
+			return fmt.Errorf("failed to obtain stored cursor: %w", cErr)
+		}
+		if cErr == nil {
+			if err := json.Unmarshal(cursor, &s.cursor); err != nil {
+				return fmt.Errorf("failed to decode stored cursor: %w", err)
+			}
+		}
+	}
+	s.cursorLoaded = true
+	return nil
+}
+
+func (s *shopifyInput) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.connected {
+		return nil
+	}
+	if err := s.loadCursor(ctx); err != nil {
+		return err
+	}
+	s.connected = true
+	return nil
+}
+
+// pollURL returns the URL of the first page of records updated from the time
+// of the cursor.
+func (s *shopifyInput) pollURL() string {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(s.pageSize))
+	query.Set("order", "updated_at asc")
+	if !s.cursor.UpdatedAt.IsZero() {
+		query.Set("updated_at_min", s.cursor.UpdatedAt.Format(time.RFC3339))
+	}
+	if s.resource == "orders" {
+		query.Set("status", "any")
+	}
+	return s.apiURL + "/admin/api/" + s.apiVersion + "/" + s.resource + ".json?" + query.Encode()
+}
+
+// nextPage obtains the next records after the cursor, and blocks until it is
+// time to poll when all records have been consumed.
+func (s *shopifyInput) nextPage(ctx context.Context) ([]*shopifyRecord, shopifyCursor, error) {
+	for {
+		pageURL := s.nextLink
+		if pageURL == "" {
+			if s.caughtUp {
+				if err := s.wait(ctx, time.Until(s.lastPoll.Add(s.pollInterval))); err != nil {
+					return nil, shopifyCursor{}, err
+				}
+			}
+			pageURL = s.pollURL()
+		}
+
+		records, next, err := s.listRecords(ctx, pageURL)
+		if err != nil {
+			return nil, shopifyCursor{}, err
+		}
+		if s.nextLink = next; next == "" {
+			s.caughtUp, s.lastPoll = true, time.Now()
+		}
+
+		cursor := s.cursor
+		var unconsumed []*shopifyRecord
+		for _, r := range records {
+			if cursor.consumed(r.ID, r.UpdatedAt) {
+				continue
+			}
+			unconsumed = append(unconsumed, r)
+			cursor = cursor.advance(r.ID, r.UpdatedAt)
+		}
+		if len(unconsumed) > 0 {
+			return unconsumed, cursor, nil
+		}
+	}
+}
+
+func (s *shopifyInput) storeCursor(ctx context.Context, cursor shopifyCursor) error {
+	cursorBytes, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+
+	var cErr error
+	if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+		cErr = c.Set(ctx, s.cacheKey, cursorBytes, nil)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil {
+		return fmt.Errorf("failed to store cursor: %w", cErr)
+	}
+	return nil
+}
+
+func (s *shopifyInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if !s.connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	records, cursor, err := s.nextPage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(records))
+	for _, r := range records {
+		msg := service.NewMessage(r.raw)
+		msg.MetaSetMut("shopify_resource", s.resource)
+		msg.MetaSetMut("shopify_id", strconv.FormatInt(r.ID, 10))
+		msg.MetaSetMut("shopify_updated_at", r.UpdatedAt.Format(time.RFC3339))
+		batch = append(batch, msg)
+	}
+
+	release, err := s.checkpoints.Track(ctx, cursor, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cursor = cursor
+
+	return batch, func(ctx context.Context, err error) error {
+		s.commitMut.Lock()
+		defer s.commitMut.Unlock()
+
+		highest := release()
+		if highest == nil || s.cache == "" {
+			return nil
+		}
+		return s.storeCursor(ctx, highest.(shopifyCursor))
+	}, nil
+}
+
+func (s *shopifyInput) Close(ctx context.Context) error {
+	s.mut.Lock()
+	s.connected = false
+	s.mut.Unlock()
+	return nil
+}
@@ -0,0 +1,214 @@
+package shopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testRecord struct {
+	ID        int64  `json:"id"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// testShop is a fake of the REST Admin API of a shop, which rate limits the
+// first request.
+type testShop struct {
+	t   *testing.T
+	url string
+
+	mut      sync.Mutex
+	records  []testRecord
+	requests int
+	queries  []string
+}
+
+func startTestShop(t *testing.T) *testShop {
+	t.Helper()
+
+	s := &testShop{t: t}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+	return s
+}
+
+func (s *testShop) update(id int64, updatedAt string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for i, r := range s.records {
+		if r.ID == id {
+			s.records = append(s.records[:i], s.records[i+1:]...)
+			break
+		}
+	}
+	s.records = append(s.records, testRecord{ID: id, UpdatedAt: updatedAt})
+}
+
+func (s *testShop) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	assert.Equal(s.t, "shpat_foo", r.Header.Get("X-Shopify-Access-Token"))
+	assert.Equal(s.t, "/admin/api/2023-01/orders.json", r.URL.Path)
+
+	if s.requests++; s.requests == 1 {
+		w.Header().Set("Retry-After", "0.0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"errors":"Exceeded 2 calls per second for api client. Reduce request rates to resume uninterrupted service."}`))
+		return
+	}
+
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+
+	// The page info is the index of the next record followed by the minimum
+	// update time of the first page, which must not be combined with filters.
+	var index int
+	var min string
+	if pageInfo := q.Get("page_info"); pageInfo != "" {
+		assert.Empty(s.t, q.Get("updated_at_min"))
+		_, _ = fmt.Sscanf(pageInfo, "%d_%s", &index, &min)
+	} else {
+		assert.Equal(s.t, "updated_at asc", q.Get("order"))
+		assert.Equal(s.t, "any", q.Get("status"))
+		min = q.Get("updated_at_min")
+		s.queries = append(s.queries, min)
+	}
+
+	var matched []testRecord
+	for _, rec := range s.records {
+		if min == "" || rec.UpdatedAt >= min {
+			matched = append(matched, rec)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].UpdatedAt < matched[j].UpdatedAt
+	})
+
+	end := index + limit
+	if end < len(matched) {
+		w.Header().Set("Link", fmt.Sprintf(`<%v%v?limit=%v&page_info=%v_%v>; rel="next"`, s.url, r.URL.Path, limit, end, min))
+	} else {
+		end = len(matched)
+	}
+	w.Header().Set("X-Shopify-Shop-Api-Call-Limit", "1/40")
+	_ = json.NewEncoder(w).Encode(map[string]any{"orders": matched[index:end]})
+}
+
+func readTestBatch(t *testing.T, in *shopifyInput) []int64 {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := in.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	var ids []int64
+	for _, msg := range batch {
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		id, err := v.(map[string]any)["id"].(json.Number).Int64()
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func storedCursor(t *testing.T, mgr *service.Resources) string {
+	t.Helper()
+
+	var v []byte
+	var cErr error
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		v, cErr = c.Get(context.Background(), "shopify_orders")
+	}))
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return ""
+	}
+	require.NoError(t, cErr)
+	return string(v)
+}
+
+func TestShopifyInput(t *testing.T) {
+	s := startTestShop(t)
+	s.update(1, "2023-01-01T10:00:00Z")
+	s.update(2, "2023-01-01T11:00:00Z")
+	s.update(3, "2023-01-01T12:00:00Z")
+	s.update(4, "2023-01-01T12:00:00Z")
+	s.update(5, "2023-01-01T12:00:00Z")
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	conf, err := shopifyInputSpec().ParseYAML(`
+shop: acme.myshopify.com
+access_token: shpat_foo
+resource: orders
+api_url: `+s.url+`
+start_time: 2023-01-01T10:30:00Z
+page_size: 2
+poll_interval: 10ms
+cache: foocache
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newShopifyInputFromParsed(conf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+	defer in.Close(context.Background())
+
+	assert.Equal(t, []int64{2, 3}, readTestBatch(t, in))
+	assert.Equal(t, []int64{4, 5}, readTestBatch(t, in))
+	assert.Equal(t, `{"updated_at":"2023-01-01T12:00:00Z","ids":[3,4,5]}`, storedCursor(t, mgr))
+
+	// Records that have been consumed are not consumed again when polled,
+	// whereas records that are updated are.
+	s.update(6, "2023-01-01T12:00:00Z")
+	s.update(1, "2023-01-02T09:00:00Z")
+	assert.Equal(t, []int64{6}, readTestBatch(t, in))
+	assert.Equal(t, []int64{1}, readTestBatch(t, in))
+	assert.Equal(t, `{"updated_at":"2023-01-02T09:00:00Z","ids":[1]}`, storedCursor(t, mgr))
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	assert.Equal(t, []string{"2023-01-01T10:30:00Z", "2023-01-01T12:00:00Z"}, s.queries[:2])
+}
+
+func TestShopifyBucketDelay(t *testing.T) {
+	for input, exp := range map[string]time.Duration{
+		"1/40":  0,
+		"20/40": 0,
+		"30/40": time.Second * 5,
+		"nope":  0,
+	} {
+		assert.Equal(t, exp, bucketDelay(input), input)
+	}
+}
+
+func TestShopifyCursor(t *testing.T) {
+	at := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := shopifyCursor{}.advance(1, at).advance(2, at)
+	assert.Equal(t, []int64{1, 2}, c.IDs)
+
+	assert.True(t, c.consumed(2, at))
+	assert.True(t, c.consumed(3, at.Add(-time.Second)))
+	assert.False(t, c.consumed(3, at))
+	assert.False(t, c.consumed(1, at.Add(time.Second)))
+
+	c = c.advance(1, at.Add(time.Second))
+	assert.Equal(t, shopifyCursor{UpdatedAt: at.Add(time.Second), IDs: []int64{1}}, c)
+}
@@ -0,0 +1,386 @@
+package splunk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hecFieldURL         = "url"
+	hecFieldToken       = "token"
+	hecFieldIndex       = "index"
+	hecFieldSourceType  = "sourcetype"
+	hecFieldSource      = "source"
+	hecFieldHost        = "host"
+	hecFieldGzip        = "gzip"
+	hecFieldTLS         = "tls"
+	hecFieldAck         = "indexer_ack"
+	hecFieldAckEnabled  = "enabled"
+	hecFieldAckChannel  = "channel"
+	hecFieldAckInterval = "poll_interval"
+	hecFieldAckTimeout  = "timeout"
+	hecFieldBatching    = "batching"
+	hecFieldMaxInFlight = "max_in_flight"
+)
+
+func hecOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Writes events to a [Splunk HTTP Event Collector](https://docs.splunk.com/Documentation/Splunk/latest/Data/UsetheHTTPEventCollector).").
+		Description(`
+Each message of a batch is sent as an event within a single request to the event endpoint of the collector. Messages that contain valid JSON are sent as structured events, and all other messages are sent as strings. The time of each event is the time at which it was written.
+
+### Indexer Acknowledgement
+
+When `+"`indexer_ack.enabled`"+` is set to `+"`true`"+` messages are only acknowledged once the collector reports that the events of the request have been indexed, rather than as soon as the collector receives them. The collector must have indexer acknowledgement enabled for the token, and the acknowledgement status of each request is polled every `+"`indexer_ack.poll_interval`"+`. When the events are not indexed within `+"`indexer_ack.timeout`"+` the batch is considered failed and is sent again, which may result in duplicate events.
+
+### Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).`).
+		Field(service.NewStringField(hecFieldURL).
+			Description("The base URL of the HTTP Event Collector.").
+			Example("https://localhost:8088")).
+		Field(service.NewStringField(hecFieldToken).
+			Description("The HTTP Event Collector token to authenticate with.")).
+		Field(service.NewInterpolatedStringField(hecFieldIndex).
+			Description("An optional index to write events to, when omitted the default index of the token is used.").
+			Example("main").
+			Example(`${! meta("splunk_index") }`).
+			Optional()).
+		Field(service.NewInterpolatedStringField(hecFieldSourceType).
+			Description("An optional source type of each event.").
+			Example("_json").
+			Optional()).
+		Field(service.NewInterpolatedStringField(hecFieldSource).
+			Description("An optional source of each event.").
+			Optional()).
+		Field(service.NewInterpolatedStringField(hecFieldHost).
+			Description("An optional host of each event.").
+			Example(`${! hostname() }`).
+			Optional()).
+		Field(service.NewBoolField(hecFieldGzip).
+			Description("Whether to compress request bodies with gzip.").
+			Advanced().
+			Default(false)).
+		Field(service.NewTLSToggledField(hecFieldTLS)).
+		Field(service.NewObjectField(hecFieldAck,
+			service.NewBoolField(hecFieldAckEnabled).
+				Description("Whether to wait for events to be indexed before acknowledging messages.").
+				Default(false),
+			service.NewStringField(hecFieldAckChannel).
+				Description("The channel to send requests and poll acknowledgements with, when empty a random channel is generated.").
+				Default(""),
+			service.NewDurationField(hecFieldAckInterval).
+				Description("The period to wait between polls of the acknowledgement status.").
+				Default("1s"),
+			service.NewDurationField(hecFieldAckTimeout).
+				Description("The maximum period to wait for events to be indexed before the batch is considered failed.").
+				Default("1m"),
+		).
+			Description("Indexer acknowledgement settings, see [indexer acknowledgement](#indexer-acknowledgement).").
+			Advanced()).
+		Field(service.NewBatchPolicyField(hecFieldBatching)).
+		Field(service.NewIntField(hecFieldMaxInFlight).
+			Description("The maximum number of message batches to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Durable Delivery",
+			"In this example events are batched and only acknowledged once they have been indexed.",
+			`
+output:
+  splunk_hec:
+    url: https://splunk.example.com:8088
+    token: "${SPLUNK_HEC_TOKEN}"
+    index: main
+    sourcetype: _json
+    indexer_ack:
+      enabled: true
+    batching:
+      count: 500
+      period: 1s
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"splunk_hec", hecOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(hecFieldMaxInFlight); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(hecFieldBatching); err != nil {
+				return
+			}
+			out, err = newHECOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type hecOutput struct {
+	log *service.Logger
+
+	url   string
+	token string
+	gzip  bool
+
+	index      *service.InterpolatedString
+	sourceType *service.InterpolatedString
+	source     *service.InterpolatedString
+	host       *service.InterpolatedString
+
+	ackEnabled  bool
+	ackChannel  string
+	ackInterval time.Duration
+	ackTimeout  time.Duration
+
+	client *http.Client
+}
+
+func newHECOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*hecOutput, error) {
+	h := &hecOutput{log: mgr.Logger()}
+
+	var err error
+	if h.url, err = conf.FieldString(hecFieldURL); err != nil {
+		return nil, err
+	}
+	h.url = strings.TrimSuffix(h.url, "/")
+	if h.token, err = conf.FieldString(hecFieldToken); err != nil {
+		return nil, err
+	}
+	if h.gzip, err = conf.FieldBool(hecFieldGzip); err != nil {
+		return nil, err
+	}
+
+	for _, f := range []struct {
+		name  string
+		field **service.InterpolatedString
+	}{
+		{hecFieldIndex, &h.index},
+		{hecFieldSourceType, &h.sourceType},
+		{hecFieldSource, &h.source},
+		{hecFieldHost, &h.host},
+	} {
+		if !conf.Contains(f.name) {
+			continue
+		}
+		if *f.field, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+
+	ackConf := conf.Namespace(hecFieldAck)
+	if h.ackEnabled, err = ackConf.FieldBool(hecFieldAckEnabled); err != nil {
+		return nil, err
+	}
+	if h.ackChannel, err = ackConf.FieldString(hecFieldAckChannel); err != nil {
+		return nil, err
+	}
+	if h.ackChannel == "" {
+		channel, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		h.ackChannel = channel.String()
+	}
+	if h.ackInterval, err = ackConf.FieldDuration(hecFieldAckInterval); err != nil {
+		return nil, err
+	}
+	if h.ackTimeout, err = ackConf.FieldDuration(hecFieldAckTimeout); err != nil {
+		return nil, err
+	}
+
+	h.client = &http.Client{}
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(hecFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		if t, ok := http.DefaultTransport.(*http.Transport); ok {
+			cloned := t.Clone()
+			cloned.TLSClientConfig = tlsConf
+			h.client.Transport = cloned
+		} else {
+			h.client.Transport = &http.Transport{TLSClientConfig: tlsConf}
+		}
+	}
+	return h, nil
+}
+
+func (h *hecOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+type hecEvent struct {
+	Time       float64 `json:"time"`
+	Host       string  `json:"host,omitempty"`
+	Source     string  `json:"source,omitempty"`
+	SourceType string  `json:"sourcetype,omitempty"`
+	Index      string  `json:"index,omitempty"`
+	Event      any     `json:"event"`
+}
+
+func (h *hecOutput) encodeBatch(batch service.MessageBatch, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	ts := float64(now.UnixMilli()) / 1000
+
+	optStr := func(i *service.InterpolatedString, msg *service.Message) string {
+		if i == nil {
+			return ""
+		}
+		return i.String(msg)
+	}
+
+	for _, msg := range batch {
+		e := hecEvent{
+			Time:       ts,
+			Host:       optStr(h.host, msg),
+			Source:     optStr(h.source, msg),
+			SourceType: optStr(h.sourceType, msg),
+			Index:      optStr(h.index, msg),
+		}
+		if structured, err := msg.AsStructured(); err == nil {
+			e.Event = structured
+		} else {
+			mBytes, err := msg.AsBytes()
+			if err != nil {
+				return nil, err
+			}
+			e.Event = string(mBytes)
+		}
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if !h.gzip {
+		return buf.Bytes(), nil
+	}
+	var zBuf bytes.Buffer
+	zw := gzip.NewWriter(&zBuf)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return zBuf.Bytes(), nil
+}
+
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+func (h *hecOutput) do(ctx context.Context, path string, body []byte, compressed bool, res any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+h.token)
+	req.Header.Set("Content-Type", "application/json")
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if h.ackEnabled {
+		req.Header.Set("X-Splunk-Request-Channel", h.ackChannel)
+	}
+
+	hRes, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer hRes.Body.Close()
+
+	resBytes, err := io.ReadAll(hRes.Body)
+	if err != nil {
+		return err
+	}
+	if hRes.StatusCode < 200 || hRes.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code %v: %s", hRes.StatusCode, bytes.TrimSpace(resBytes))
+	}
+	if res == nil || len(resBytes) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resBytes, res)
+}
+
+func (h *hecOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	body, err := h.encodeBatch(batch, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var res hecResponse
+	if err := h.do(ctx, "/services/collector/event", body, h.gzip, &res); err != nil {
+		return err
+	}
+	if res.Code != 0 {
+		return fmt.Errorf("collector returned code %v: %v", res.Code, res.Text)
+	}
+	if !h.ackEnabled {
+		return nil
+	}
+	if res.AckID == nil {
+		return errors.New("collector did not return an ackId, indexer acknowledgement may not be enabled for the token")
+	}
+	return h.waitForAck(ctx, *res.AckID)
+}
+
+func (h *hecOutput) waitForAck(ctx context.Context, ackID int64) error {
+	ctx, done := context.WithTimeout(ctx, h.ackTimeout)
+	defer done()
+
+	body, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return err
+	}
+	idStr := fmt.Sprintf("%v", ackID)
+
+	for {
+		var res struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		err := h.do(ctx, "/services/collector/ack?channel="+url.QueryEscape(h.ackChannel), body, false, &res)
+		if err == nil && res.Acks[idStr] {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			h.log.Warnf("Failed to poll indexer acknowledgement: %v", err)
+		}
+
+		select {
+		case <-time.After(h.ackInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("events were not indexed within the acknowledgement timeout: %w", ctx.Err())
+		}
+	}
+}
+
+func (h *hecOutput) Close(ctx context.Context) error {
+	h.client.CloseIdleConnections()
+	return nil
+}
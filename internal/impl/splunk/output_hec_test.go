@@ -0,0 +1,160 @@
+package splunk
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type testHEC struct {
+	mut         sync.Mutex
+	events      []map[string]any
+	ackPolls    int
+	ackAfter    int
+	channels    []string
+	authHeaders []string
+}
+
+func (h *testHEC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	h.authHeaders = append(h.authHeaders, r.Header.Get("Authorization"))
+	h.channels = append(h.channels, r.Header.Get("X-Splunk-Request-Channel"))
+
+	switch r.URL.Path {
+	case "/services/collector/event":
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		dec := json.NewDecoder(body)
+		for dec.More() {
+			var e map[string]any
+			if err := dec.Decode(&e); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			h.events = append(h.events, e)
+		}
+		_, _ = w.Write([]byte(`{"text":"Success","code":0,"ackId":7}`))
+	case "/services/collector/ack":
+		h.ackPolls++
+		if h.ackPolls > h.ackAfter {
+			_, _ = w.Write([]byte(`{"acks":{"7":true}}`))
+		} else {
+			_, _ = w.Write([]byte(`{"acks":{"7":false}}`))
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func testHECOutput(t *testing.T, confStr string) *hecOutput {
+	t.Helper()
+	conf, err := hecOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+	out, err := newHECOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = out.Close(context.Background())
+	})
+	return out
+}
+
+func TestHECOutputWrite(t *testing.T) {
+	hec := &testHEC{}
+	ts := httptest.NewServer(hec)
+	defer ts.Close()
+
+	out := testHECOutput(t, `
+url: `+ts.URL+`
+token: foo
+index: ${! meta("index").or("") }
+sourcetype: _json
+gzip: true
+`)
+
+	msgA := service.NewMessage([]byte(`{"id":"a"}`))
+	msgA.MetaSet("index", "first")
+	msgB := service.NewMessage([]byte(`not json`))
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB}))
+
+	hec.mut.Lock()
+	defer hec.mut.Unlock()
+
+	require.Len(t, hec.events, 2)
+	assert.Equal(t, map[string]any{"id": "a"}, hec.events[0]["event"])
+	assert.Equal(t, "first", hec.events[0]["index"])
+	assert.Equal(t, "_json", hec.events[0]["sourcetype"])
+	assert.Equal(t, "not json", hec.events[1]["event"])
+	assert.NotContains(t, hec.events[1], "index")
+	assert.Contains(t, hec.events[1], "time")
+	assert.Equal(t, []string{"Splunk foo"}, hec.authHeaders)
+}
+
+func TestHECOutputIndexerAck(t *testing.T) {
+	hec := &testHEC{ackAfter: 2}
+	ts := httptest.NewServer(hec)
+	defer ts.Close()
+
+	out := testHECOutput(t, `
+url: `+ts.URL+`
+token: foo
+indexer_ack:
+  enabled: true
+  channel: bar
+  poll_interval: 1ms
+`)
+
+	require.NoError(t, out.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`hello`))}))
+
+	hec.mut.Lock()
+	assert.Equal(t, 3, hec.ackPolls)
+	assert.Equal(t, []string{"bar", "bar", "bar", "bar"}, hec.channels)
+	hec.mut.Unlock()
+
+	// The events of the second batch are never indexed.
+	hec.mut.Lock()
+	hec.ackPolls, hec.ackAfter = 0, 1000
+	hec.mut.Unlock()
+
+	out.ackTimeout = time.Millisecond * 20
+	err := out.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`hello`))})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "not indexed"), err.Error())
+}
+
+func TestHECOutputErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"text":"Server is busy","code":9}`, http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	out := testHECOutput(t, `
+url: `+ts.URL+`
+token: foo
+`)
+	err := out.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`hello`))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+}
@@ -0,0 +1,147 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// bulkInserter writes rows using the native bulk insert path of a driver,
+// which for large batches is significantly faster than a multi-row insert
+// statement. When transaction is true the rows are inserted within a
+// transaction that is rolled back if any row fails to be inserted.
+type bulkInserter interface {
+	insert(ctx context.Context, db *sql.DB, rows [][]any, transaction bool) error
+}
+
+func newBulkInserter(driver, table string, columns []string) (bulkInserter, error) {
+	switch driver {
+	case "mssql":
+		return &mssqlBulkInserter{query: mssql.CopyIn(table, mssql.BulkOptions{}, columns...)}, nil
+	case "oracle":
+		placeholders := make([]string, len(columns))
+		for i := range columns {
+			placeholders[i] = ":" + strconv.Itoa(i+1)
+		}
+		return &oracleBulkInserter{
+			query:   "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")",
+			columns: len(columns),
+		}, nil
+	}
+	return nil, fmt.Errorf("bulk inserts are not supported by the %v driver", driver)
+}
+
+//------------------------------------------------------------------------------
+
+// mssqlBulkInserter uses the bulk copy protocol of SQL Server, where rows are
+// buffered by the statement and sent once it is executed without arguments.
+type mssqlBulkInserter struct {
+	query string
+}
+
+func (m *mssqlBulkInserter) insert(ctx context.Context, db *sql.DB, rows [][]any, transaction bool) error {
+	if !transaction {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		stmt, err := conn.PrepareContext(ctx, m.query)
+		if err != nil {
+			return err
+		}
+		return m.exec(ctx, stmt, rows)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, m.query)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = m.exec(ctx, stmt, rows); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// exec buffers each row with the statement before sending them, and closes the
+// statement.
+func (m *mssqlBulkInserter) exec(ctx context.Context, stmt *sql.Stmt, rows [][]any) (err error) {
+	for _, args := range rows {
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		_, err = stmt.ExecContext(ctx)
+	}
+	if cerr := stmt.Close(); err == nil {
+		err = cerr
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// oracleBulkInserter uses array binds, where the values of each column for all
+// rows are bound to a single execution of the insert statement.
+type oracleBulkInserter struct {
+	query   string
+	columns int
+}
+
+func (o *oracleBulkInserter) insert(ctx context.Context, db *sql.DB, rows [][]any, transaction bool) error {
+	values := make([][]driver.Value, o.columns)
+	for i := range values {
+		values[i] = make([]driver.Value, len(rows))
+	}
+	for i, args := range rows {
+		if len(args) != o.columns {
+			return fmt.Errorf("mapping returned %v values for %v columns", len(args), o.columns)
+		}
+		for j, v := range args {
+			values[j][i] = v
+		}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		oraConn, ok := driverConn.(*go_ora.Connection)
+		if !ok {
+			return errors.New("connection does not support array binds")
+		}
+		if !transaction {
+			_, err := oraConn.BulkInsert(o.query, len(rows), values...)
+			return err
+		}
+
+		tx, err := oraConn.BeginTx(ctx, driver.TxOptions{})
+		if err != nil {
+			return err
+		}
+		if _, err = oraConn.BulkInsert(o.query, len(rows), values...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
@@ -0,0 +1,21 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInserterQueries(t *testing.T) {
+	b, err := newBulkInserter("oracle", "footable", []string{"id", "name"})
+	require.NoError(t, err)
+	assert.Equal(t, "INSERT INTO footable (id, name) VALUES (:1, :2)", b.(*oracleBulkInserter).query)
+
+	b, err = newBulkInserter("mssql", "footable", []string{"id", "name"})
+	require.NoError(t, err)
+	assert.Contains(t, b.(*mssqlBulkInserter).query, "footable")
+
+	_, err = newBulkInserter("postgres", "footable", []string{"id", "name"})
+	require.EqualError(t, err, "bulk inserts are not supported by the postgres driver")
+}
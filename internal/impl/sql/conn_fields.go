@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -52,6 +53,13 @@ func connFields() []*service.ConfigField {
 			Description(`An optional maximum number of open connections to the database. If conn_max_idle is greater than 0 and the new conn_max_open is less than conn_max_idle, then conn_max_idle will be reduced to match the new conn_max_open limit. If value <= 0, then there is no limit on the number of open connections. The default is 0 (unlimited).`).
 			Optional().
 			Advanced(),
+		service.NewStringField("shared_pool").
+			Description("An optional name of a connection pool to share with all other SQL components of the process that specify the same name, instead of opening a pool dedicated to this component. Components sharing a pool must use the same `driver`, `dsn` and connection settings, otherwise they fail to connect.").
+			Example("primary_db").
+			Optional().
+			Advanced().
+			Version("4.9.0"),
+		kerberosField(),
 	}
 }
 
@@ -64,7 +72,7 @@ func rawQueryField() *service.ConfigField {
 ` + "| `clickhouse` | Dollar sign |" + `
 ` + "| `mysql` | Question mark |" + `
 ` + "| `postgres` | Dollar sign |" + `
-` + "| `mssql` | Question mark |" + `
+` + "| `mssql` | Question mark, which is translated into `@p1` style parameters by the driver |" + `
 ` + "| `sqlite` | Question mark |" + `
 ` + "| `oracle` | Colon |" + `
 `)
@@ -75,6 +83,8 @@ type connSettings struct {
 	connMaxIdleTime time.Duration
 	maxIdleConns    int
 	maxOpenConns    int
+	sharedPool      string
+	kerberos        kerberos.ToggledConfig
 }
 
 func (c connSettings) apply(db *sql.DB) {
@@ -108,10 +118,29 @@ func connSettingsFromParsed(conf *service.ParsedConfig) (c connSettings, err err
 			return
 		}
 	}
+
+	if conf.Contains("shared_pool") {
+		if c.sharedPool, err = conf.FieldString("shared_pool"); err != nil {
+			return
+		}
+	}
+
+	if conf.Contains("kerberos") {
+		var v any
+		if v, err = conf.FieldAny("kerberos"); err != nil {
+			return
+		}
+		if c.kerberos, err = kerberos.ToggledConfigFromAny(v, ""); err != nil {
+			return
+		}
+	}
 	return
 }
 
-func sqlOpenWithReworks(logger *service.Logger, driver, dsn string) (*sql.DB, error) {
+func sqlOpenWithReworks(logger *service.Logger, driver, dsn string, krbConf kerberos.ToggledConfig) (*sql.DB, error) {
+	if krbConf.Enabled {
+		return openKerberosDB(driver, dsn, krbConf.Config)
+	}
 	if driver == "clickhouse" && strings.HasPrefix(dsn, "tcp") {
 		u, err := url.Parse(dsn)
 		if err != nil {
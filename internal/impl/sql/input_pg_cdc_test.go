@@ -0,0 +1,252 @@
+package sql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgLSN(t *testing.T) {
+	lsn, err := parsePgLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, pgLSN(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", lsn.String())
+
+	lsn, err = parsePgLSN("0/0")
+	require.NoError(t, err)
+	assert.Equal(t, pgLSN(0), lsn)
+
+	for _, bad := range []string{"", "16", "16/", "G/1", "1/100000000"} {
+		_, err := parsePgLSN(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPgCDCConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{
+			conf: `
+dsn: postgres://localhost/foo
+slot: foo
+`,
+			errStr: "a publication must be specified when using the pgoutput plugin",
+		},
+		{
+			conf: `
+dsn: postgres://localhost/foo
+slot: ""
+plugin: wal2json
+`,
+			errStr: "a slot must be specified",
+		},
+		{
+			conf: `
+dsn: postgres://localhost/foo
+slot: foo
+plugin: wal2json
+max_changes: 0
+`,
+			errStr: "max_changes must be greater than zero",
+		},
+	} {
+		conf, err := pgCDCInputConfig().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newPgCDCInputFromConfig(conf, nil)
+		require.EqualError(t, err, test.errStr)
+	}
+}
+
+func TestWal2JSONAssembler(t *testing.T) {
+	a := &wal2jsonAssembler{}
+
+	rows := []string{
+		`{"action":"B"}`,
+		`{"action":"I","schema":"public","table":"users","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"foo"}]}`,
+		`{"action":"U","schema":"public","table":"users","columns":[{"name":"id","type":"integer","value":1},{"name":"name","type":"text","value":"bar"}],"identity":[{"name":"id","type":"integer","value":1}]}`,
+		`{"action":"D","schema":"public","table":"users","identity":[{"name":"id","type":"integer","value":1}]}`,
+		`{"action":"M","transactional":true,"prefix":"foo","content":"bar"}`,
+		`{"action":"C"}`,
+	}
+
+	var tx *pgTransaction
+	for i, row := range rows {
+		var err error
+		tx, err = a.add(pgLSN(i+1), "500", []byte(row))
+		require.NoError(t, err)
+		if i < len(rows)-1 {
+			require.Nil(t, tx)
+		}
+	}
+	require.NotNil(t, tx)
+
+	assert.Equal(t, "500", tx.xid)
+	assert.Equal(t, pgLSN(6), tx.commitLSN)
+	assert.Equal(t, 6, tx.rows)
+	assert.Equal(t, []pgChange{
+		{
+			lsn: 2, operation: "insert", schema: "public", table: "users",
+			data: map[string]any{"id": json.Number("1"), "name": "foo"},
+		},
+		{
+			lsn: 3, operation: "update", schema: "public", table: "users",
+			data: map[string]any{"id": json.Number("1"), "name": "bar"},
+			old:  map[string]any{"id": json.Number("1")},
+		},
+		{
+			lsn: 4, operation: "delete", schema: "public", table: "users",
+			old: map[string]any{"id": json.Number("1")},
+		},
+	}, tx.changes)
+
+	// Rows of a transaction that was started before a reset are ignored.
+	_, err := a.add(7, "501", []byte(`{"action":"B"}`))
+	require.NoError(t, err)
+	a.reset()
+	tx, err = a.add(8, "501", []byte(`{"action":"C"}`))
+	require.NoError(t, err)
+	assert.Nil(t, tx)
+
+	_, err = a.add(9, "502", []byte(`not json`))
+	require.Error(t, err)
+}
+
+// pgoutputMsg builds pgoutput protocol messages for tests.
+type pgoutputMsg []byte
+
+func (m pgoutputMsg) byte(b byte) pgoutputMsg {
+	return append(m, b)
+}
+
+func (m pgoutputMsg) uint16(v uint16) pgoutputMsg {
+	return binary.BigEndian.AppendUint16(m, v)
+}
+
+func (m pgoutputMsg) uint32(v uint32) pgoutputMsg {
+	return binary.BigEndian.AppendUint32(m, v)
+}
+
+func (m pgoutputMsg) uint64(v uint64) pgoutputMsg {
+	return binary.BigEndian.AppendUint64(m, v)
+}
+
+func (m pgoutputMsg) string(s string) pgoutputMsg {
+	return append(append(m, s...), 0)
+}
+
+func (m pgoutputMsg) text(s string) pgoutputMsg {
+	return append(m.byte('t').uint32(uint32(len(s))), s...)
+}
+
+func TestPgoutputAssembler(t *testing.T) {
+	a := newPgoutputAssembler()
+
+	begin := pgoutputMsg{}.byte('B').uint64(100).uint64(0).uint32(500)
+	relation := pgoutputMsg{}.byte('R').uint32(42).string("public").string("users").byte('d').uint16(5).
+		byte(1).string("id").uint32(pgOIDInt4).uint32(0).
+		byte(0).string("name").uint32(25).uint32(0).
+		byte(0).string("active").uint32(pgOIDBool).uint32(0).
+		byte(0).string("doc").uint32(pgOIDJSONB).uint32(0).
+		byte(0).string("big").uint32(25).uint32(0)
+	insert := pgoutputMsg{}.byte('I').uint32(42).byte('N').uint16(5).
+		text("1").text("foo").text("t").text(`{"a":[1,2]}`).byte('n')
+	update := pgoutputMsg{}.byte('U').uint32(42).byte('K').uint16(5).
+		text("1").byte('n').byte('n').byte('n').byte('n').
+		byte('N').uint16(5).
+		text("1").text("bar").text("f").text(`null`).byte('u')
+	del := pgoutputMsg{}.byte('D').uint32(42).byte('K').uint16(5).
+		text("1").byte('n').byte('n').byte('n').byte('n')
+	truncate := pgoutputMsg{}.byte('T').uint32(1).byte(0).uint32(42)
+	commit := pgoutputMsg{}.byte('C').byte(0).uint64(106).uint64(0x1000000107).uint64(0)
+
+	msgs := []pgoutputMsg{begin, relation, insert, update, del, truncate, commit}
+
+	var tx *pgTransaction
+	for i, msg := range msgs {
+		var err error
+		tx, err = a.add(pgLSN(100+i), "500", msg)
+		require.NoError(t, err, i)
+		if i < len(msgs)-1 {
+			require.Nil(t, tx, i)
+		}
+	}
+	require.NotNil(t, tx)
+
+	assert.Equal(t, pgLSN(0x1000000107), tx.commitLSN)
+	assert.Equal(t, 7, tx.rows)
+	assert.Equal(t, []pgChange{
+		{
+			lsn: 102, operation: "insert", schema: "public", table: "users",
+			data: map[string]any{
+				"id": int64(1), "name": "foo", "active": true,
+				"doc": map[string]any{"a": []any{json.Number("1"), json.Number("2")}},
+				"big": nil,
+			},
+		},
+		{
+			lsn: 103, operation: "update", schema: "public", table: "users",
+			data: map[string]any{"id": int64(1), "name": "bar", "active": false, "doc": nil},
+			old:  map[string]any{"id": int64(1), "name": nil, "active": nil, "doc": nil, "big": nil},
+		},
+		{
+			lsn: 104, operation: "delete", schema: "public", table: "users",
+			old: map[string]any{"id": int64(1), "name": nil, "active": nil, "doc": nil, "big": nil},
+		},
+		{lsn: 105, operation: "truncate", schema: "public", table: "users"},
+	}, tx.changes)
+
+	// Relations are kept between transactions.
+	_, err := a.add(200, "501", begin)
+	require.NoError(t, err)
+	_, err = a.add(201, "501", insert)
+	require.NoError(t, err)
+
+	_, err = a.add(202, "501", pgoutputMsg{}.byte('I').uint32(43).byte('N').uint16(0))
+	require.EqualError(t, err, "change refers to unknown relation 43")
+
+	_, err = a.add(203, "501", insert[:10])
+	require.Error(t, err)
+}
+
+func TestPgTextValue(t *testing.T) {
+	assert.Equal(t, int64(-5), pgTextValue(pgOIDInt8, "-5"))
+	assert.Equal(t, 1.5, pgTextValue(pgOIDFloat8, "1.5"))
+	assert.Equal(t, json.Number("12.340"), pgTextValue(pgOIDNumeric, "12.340"))
+	assert.Equal(t, json.Number("1e400"), pgTextValue(pgOIDNumeric, "1e400"))
+	assert.Equal(t, "NaN", pgTextValue(pgOIDNumeric, "NaN"))
+	assert.Equal(t, "Infinity", pgTextValue(pgOIDFloat8, "Infinity"))
+	assert.Equal(t, "2022-01-01", pgTextValue(1082, "2022-01-01"))
+	assert.Equal(t, "{bad", pgTextValue(pgOIDJSON, "{bad"))
+}
+
+func TestPgCDCRelease(t *testing.T) {
+	p := &pgCDCInput{}
+
+	first := &pgTrackedTx{commitLSN: 10, remaining: 2, rows: 4}
+	empty := &pgTrackedTx{commitLSN: 20, remaining: 0, rows: 2}
+	last := &pgTrackedTx{commitLSN: 30, remaining: 1, rows: 3}
+	p.txs = []*pgTrackedTx{first, empty, last}
+	p.pendingRows = 9
+
+	// Acknowledging a later transaction doesn't release earlier ones.
+	last.remaining--
+	p.releaseLocked()
+	assert.Equal(t, pgLSN(0), p.advanceTo)
+	assert.Len(t, p.txs, 3)
+
+	first.remaining--
+	p.releaseLocked()
+	assert.Equal(t, pgLSN(0), p.advanceTo)
+
+	first.remaining--
+	p.releaseLocked()
+	assert.Equal(t, pgLSN(30), p.advanceTo)
+	assert.Empty(t, p.txs)
+	assert.Equal(t, 0, p.pendingRows)
+}
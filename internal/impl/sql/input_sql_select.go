@@ -3,11 +3,17 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/Masterminds/squirrel"
 
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/partition"
 	"github.com/benthosdev/benthos/v4/internal/shutdown"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -18,7 +24,15 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 		// Stable(). TODO
 		Categories("Services").
 		Summary("Executes a select query and creates a message for each row received.").
-		Description(`Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).`).
+		Description(`
+Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+## Sharing a Table Between Instances
+
+The rows of a table can be shared amongst multiple instances of Benthos running the same config with the field ` + "[`partitioning`](#partitioning)" + `, where the query of each instance only selects the rows that belong to the partitions it holds. A row belongs to the partition given by the absolute value of the integer column ` + "`partitioning.column`" + ` modulo ` + "`partitioning.count`" + `.
+
+Partitions are only checked when the query is executed, and therefore instances should be started at roughly the same time in order to share a table. An instance that holds no partitions when it connects selects no rows.
+`).
 		Field(driverField).
 		Field(dsnField).
 		Field(service.NewStringField("table").
@@ -44,7 +58,8 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 		Field(service.NewStringField("suffix").
 			Description("An optional suffix to append to the select query.").
 			Optional().
-			Advanced())
+			Advanced()).
+		Field(sqlSelectPartitioningField())
 
 	for _, f := range connFields() {
 		spec = spec.Field(f)
@@ -76,7 +91,7 @@ func init() {
 	err := service.RegisterInput(
 		"sql_select", sqlSelectInputConfig(),
 		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
-			i, err := newSQLSelectInputFromConfig(conf, mgr.Logger())
+			i, err := newSQLSelectInputFromConfig(conf, mgr)
 			if err != nil {
 				return nil, err
 			}
@@ -87,6 +102,46 @@ func init() {
 	}
 }
 
+func sqlSelectPartitioningField() *service.ConfigField {
+	spec := partition.FieldSpec()
+	spec.Children = append(spec.Children, docs.FieldString(
+		"column", "The integer column that determines which partition a row belongs to, such as an auto-incrementing primary key. This field is required when partitioning is enabled.",
+	).HasDefault(""))
+	return service.NewInternalField(spec)
+}
+
+func sqlSelectPartitioningFromParsed(conf *service.ParsedConfig) (pConf partition.Config, column string, err error) {
+	conf = conf.Namespace("partitioning")
+	if pConf.Cache, err = conf.FieldString("cache"); err != nil {
+		return
+	}
+	if pConf.KeyPrefix, err = conf.FieldString("key_prefix"); err != nil {
+		return
+	}
+	if pConf.Count, err = conf.FieldInt("count"); err != nil {
+		return
+	}
+	if pConf.LeaseTTL, err = conf.FieldString("lease_ttl"); err != nil {
+		return
+	}
+	column, err = conf.FieldString("column")
+	return
+}
+
+// partitionClause returns a where clause that selects the rows of a table
+// that belong to a list of partitions.
+func partitionClause(driver, column string, count int, partitions []int) string {
+	ids := make([]string, len(partitions))
+	for i, p := range partitions {
+		ids[i] = strconv.Itoa(p)
+	}
+	expr := fmt.Sprintf("ABS(%v) %% %v", column, count)
+	if driver == "oracle" {
+		expr = fmt.Sprintf("MOD(ABS(%v), %v)", column, count)
+	}
+	return expr + " IN (" + strings.Join(ids, ", ") + ")"
+}
+
 //------------------------------------------------------------------------------
 
 type sqlSelectInput struct {
@@ -102,13 +157,16 @@ type sqlSelectInput struct {
 
 	connSettings connSettings
 
+	partitions      *partition.Coordinator
+	partitionColumn string
+
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
 }
 
-func newSQLSelectInputFromConfig(conf *service.ParsedConfig, logger *service.Logger) (*sqlSelectInput, error) {
+func newSQLSelectInputFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*sqlSelectInput, error) {
 	s := &sqlSelectInput{
-		logger:  logger,
+		logger:  mgr.Logger(),
 		shutSig: shutdown.NewSignaller(),
 	}
 
@@ -170,9 +228,42 @@ func newSQLSelectInputFromConfig(conf *service.ParsedConfig, logger *service.Log
 	if s.connSettings, err = connSettingsFromParsed(conf); err != nil {
 		return nil, err
 	}
+
+	pConf, pColumn, err := sqlSelectPartitioningFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	if pConf.Enabled() {
+		if pColumn == "" {
+			return nil, errors.New("a partitioning column must be specified when partitioning is enabled")
+		}
+		s.partitionColumn = pColumn
+
+		nm := mgr.XUnwrapper().(interface {
+			Unwrap() bundle.NewManagement
+		}).Unwrap()
+		if s.partitions, err = partition.New(pConf, mgr.Label(), nm, nm.Logger()); err != nil {
+			return nil, fmt.Errorf("failed to create partition coordinator: %w", err)
+		}
+	}
 	return s, nil
 }
 
+// heldPartitions returns the partitions currently held by the instance once
+// the first attempt to claim them has finished.
+func (s *sqlSelectInput) heldPartitions(ctx context.Context) ([]int, error) {
+	if err := s.partitions.WaitForSync(ctx); err != nil {
+		return nil, err
+	}
+	var held []int
+	for p := 0; p < s.partitions.Count(); p++ {
+		if s.partitions.Owns(p) {
+			held = append(held, p)
+		}
+	}
+	return held, nil
+}
+
 func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	s.dbMut.Lock()
 	defer s.dbMut.Unlock()
@@ -182,17 +273,15 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	}
 
 	var db *sql.DB
-	if db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if db, err = openDB(s.logger, s.driver, s.dsn, s.connSettings); err != nil {
 		return
 	}
 	defer func() {
 		if err != nil {
-			_ = db.Close()
+			_ = closeDB(db, s.connSettings)
 		}
 	}()
 
-	s.connSettings.apply(db)
-
 	var args []any
 	if s.argsMapping != nil {
 		var iargs any
@@ -211,9 +300,26 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	if s.where != "" {
 		queryBuilder = queryBuilder.Where(s.where, args...)
 	}
+
+	runQuery := true
+	if s.partitions != nil {
+		var held []int
+		if held, err = s.heldPartitions(ctx); err != nil {
+			return
+		}
+		if len(held) == 0 {
+			s.logger.Info("No partitions are held, and therefore no rows will be selected")
+			runQuery = false
+		} else {
+			queryBuilder = queryBuilder.Where(partitionClause(s.driver, s.partitionColumn, s.partitions.Count(), held))
+		}
+	}
+
 	var rows *sql.Rows
-	if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
-		return
+	if runQuery {
+		if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
+			return
+		}
 	}
 
 	s.db = db
@@ -228,7 +334,7 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 			s.rows = nil
 		}
 		if s.db != nil {
-			_ = s.db.Close()
+			_ = closeDB(s.db, s.connSettings)
 		}
 		s.dbMut.Unlock()
 
@@ -277,6 +383,11 @@ func (s *sqlSelectInput) Read(ctx context.Context) (*service.Message, service.Ac
 
 func (s *sqlSelectInput) Close(ctx context.Context) error {
 	s.shutSig.CloseNow()
+	if s.partitions != nil {
+		if err := s.partitions.Close(ctx); err != nil {
+			return err
+		}
+	}
 	s.dbMut.Lock()
 	isNil := s.db == nil
 	s.dbMut.Unlock()
@@ -2,9 +2,16 @@ package sql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -25,7 +32,87 @@ args_mapping: 'root = [ this.id ]'
 	selectConfig, err := spec.ParseYAML(conf, env)
 	require.NoError(t, err)
 
-	selectInput, err := newSQLSelectInputFromConfig(selectConfig, nil)
+	selectInput, err := newSQLSelectInputFromConfig(selectConfig, service.MockResources())
 	require.NoError(t, err)
 	require.NoError(t, selectInput.Close(context.Background()))
 }
+
+func TestSQLSelectPartitionClause(t *testing.T) {
+	assert.Equal(t, "ABS(id) % 16 IN (0, 3, 4)", partitionClause("postgres", "id", 16, []int{0, 3, 4}))
+	assert.Equal(t, "MOD(ABS(id), 4) IN (1)", partitionClause("oracle", "id", 4, []int{1}))
+}
+
+func TestSQLSelectPartitioningNoColumn(t *testing.T) {
+	selectConfig, err := sqlSelectInputConfig().ParseYAML(`
+driver: sqlite
+dsn: woof
+table: quack
+columns: [ id ]
+partitioning:
+  cache: foo
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newSQLSelectInputFromConfig(selectConfig, service.MockResources(service.MockResourcesOptAddCache("foo")))
+	require.EqualError(t, err, "a partitioning column must be specified when partitioning is enabled")
+}
+
+func TestSQLSelectPartitioning(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE footable (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+	for i := -10; i < 10; i++ {
+		_, err = db.Exec("INSERT INTO footable (id, name) VALUES (?, ?)", i, fmt.Sprintf("row %v", i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	res := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	// Another instance is registered as a member and holds the first two
+	// partitions, leaving this instance with the remaining two.
+	require.NoError(t, res.AccessCache(ctx, "foo", func(c service.Cache) {
+		for _, k := range []string{"bar_member_0", "bar_partition_0", "bar_partition_1"} {
+			require.NoError(t, c.Set(ctx, k, []byte("other"), nil))
+		}
+	}))
+
+	selectConfig, err := sqlSelectInputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: footable
+columns: [ id ]
+suffix: ORDER BY id
+partitioning:
+  cache: foo
+  key_prefix: bar
+  count: 4
+  column: id
+`, dsn), nil)
+	require.NoError(t, err)
+
+	selectInput, err := newSQLSelectInputFromConfig(selectConfig, res)
+	require.NoError(t, err)
+	require.NoError(t, selectInput.Connect(ctx))
+
+	var ids []int64
+	for {
+		msg, _, err := selectInput.Read(ctx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			break
+		}
+		require.NoError(t, err)
+
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		ids = append(ids, v.(map[string]any)["id"].(int64))
+	}
+	assert.Equal(t, []int64{-10, -7, -6, -3, -2, 2, 3, 6, 7}, ids)
+
+	require.NoError(t, selectInput.Close(ctx))
+}
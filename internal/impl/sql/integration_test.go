@@ -430,6 +430,145 @@ processors:
 	})
 }
 
+func testPostgresCopyOutput(t *testing.T, driver, dsn, table string) {
+	t.Run("postgres_copy_output", func(t *testing.T) {
+		outputConf := fmt.Sprintf(`
+postgres_copy:
+  dsn: %v
+  table: %v
+  columns: [ foo, bar, baz ]
+  args_mapping: 'root = [ this.foo, this.bar, this.baz ]'
+`, dsn, table)
+
+		streamInBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamInBuilder.SetLoggerYAML(`level: OFF`))
+		require.NoError(t, streamInBuilder.AddOutputYAML(outputConf))
+
+		inFn, err := streamInBuilder.AddBatchProducerFunc()
+		require.NoError(t, err)
+
+		streamIn, err := streamInBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			assert.NoError(t, streamIn.Run(context.Background()))
+		}()
+
+		var insertBatch service.MessageBatch
+		for i := 0; i < 10; i++ {
+			insertBatch = append(insertBatch, service.NewMessage([]byte(fmt.Sprintf(`{
+	"foo": "doc-%d",
+	"bar": %d,
+	"baz": "and\tthis"
+}`, i, i))))
+		}
+		require.NoError(t, inFn(context.Background(), insertBatch))
+		require.NoError(t, streamIn.StopWithin(15*time.Second))
+
+		db, err := sql.Open(driver, dsn)
+		require.NoError(t, err)
+		defer db.Close()
+
+		rows, err := db.Query(fmt.Sprintf(`select "foo", "bar", "baz" from %v order by "bar" asc`, table))
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var results []string
+		for rows.Next() {
+			var foo, baz string
+			var bar int
+			require.NoError(t, rows.Scan(&foo, &bar, &baz))
+			results = append(results, fmt.Sprintf("%v %v %v", foo, bar, baz))
+		}
+		require.NoError(t, rows.Err())
+		require.Len(t, results, 10)
+		assert.Equal(t, "doc-0 0 and\tthis", results[0])
+		assert.Equal(t, "doc-9 9 and\tthis", results[9])
+	})
+}
+
+func testPgCDCInput(t *testing.T, driver, dsn, table string) {
+	t.Run("pg_cdc_input", func(t *testing.T) {
+		db, err := sql.Open(driver, dsn)
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = db.Exec(fmt.Sprintf(`create publication %v_pub for table %v`, table, table))
+		require.NoError(t, err)
+
+		inputConf := fmt.Sprintf(`
+pg_cdc:
+  dsn: %v
+  slot: %v_slot
+  publication: %v_pub
+  create_slot: true
+  poll_interval: 100ms
+`, dsn, table, table)
+
+		streamOutBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamOutBuilder.SetLoggerYAML(`level: OFF`))
+		require.NoError(t, streamOutBuilder.AddInputYAML(inputConf))
+
+		var outMut sync.Mutex
+		var results []string
+		require.NoError(t, streamOutBuilder.AddConsumerFunc(func(_ context.Context, m *service.Message) error {
+			b, err := m.AsBytes()
+			if err != nil {
+				return err
+			}
+			op, _ := m.MetaGet("pg_cdc_operation")
+			outMut.Lock()
+			results = append(results, op+" "+string(b))
+			outMut.Unlock()
+			return nil
+		}))
+
+		streamOut, err := streamOutBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			assert.NoError(t, streamOut.Run(context.Background()))
+		}()
+
+		// Wait for the slot to be created before making changes.
+		require.Eventually(t, func() bool {
+			var n int
+			return db.QueryRow(`select count(*) from pg_replication_slots where slot_name = $1`, table+"_slot").Scan(&n) == nil && n == 1
+		}, time.Second*30, time.Millisecond*100)
+
+		_, err = db.Exec(fmt.Sprintf(`insert into %v ("foo", "bar", "baz") values ('doc-0', 0, 'first'), ('doc-1', 1, 'second')`, table))
+		require.NoError(t, err)
+		_, err = db.Exec(fmt.Sprintf(`update %v set "baz" = 'updated' where "foo" = 'doc-1'`, table))
+		require.NoError(t, err)
+		_, err = db.Exec(fmt.Sprintf(`delete from %v where "foo" = 'doc-0'`, table))
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			outMut.Lock()
+			defer outMut.Unlock()
+			return len(results) == 4
+		}, time.Second*30, time.Millisecond*100)
+		require.NoError(t, streamOut.StopWithin(15*time.Second))
+
+		outMut.Lock()
+		defer outMut.Unlock()
+		assert.Equal(t, []string{
+			fmt.Sprintf(`insert {"data":{"bar":0,"baz":"first","foo":"doc-0"},"operation":"insert","schema":"public","table":"%v"}`, table),
+			fmt.Sprintf(`insert {"data":{"bar":1,"baz":"second","foo":"doc-1"},"operation":"insert","schema":"public","table":"%v"}`, table),
+			fmt.Sprintf(`update {"data":{"bar":1,"baz":"updated","foo":"doc-1"},"operation":"update","schema":"public","table":"%v"}`, table),
+			fmt.Sprintf(`delete {"old":{"bar":null,"baz":null,"foo":"doc-0"},"operation":"delete","schema":"public","table":"%v"}`, table),
+		}, results)
+
+		// The slot has been advanced past the acknowledged changes.
+		var pending int
+		require.NoError(t, db.QueryRow(
+			`select count(*) from pg_logical_slot_peek_binary_changes($1, NULL, NULL, 'proto_version', '1', 'publication_names', $2)`,
+			table+"_slot", table+"_pub",
+		).Scan(&pending))
+		assert.Equal(t, 0, pending)
+	})
+}
+
 func testBatchInputOutputRaw(t *testing.T, driver, dsn, table string) {
 	t.Run("raw_input_output", func(t *testing.T) {
 		confReplacer := strings.NewReplacer(
@@ -680,6 +819,7 @@ func postgresIntegration(t *testing.T) {
 			"POSTGRES_PASSWORD=testpass",
 			"POSTGRES_DB=testdb",
 		},
+		Cmd: []string{"postgres", "-c", "wal_level=logical"},
 	})
 	require.NoError(t, err)
 
@@ -721,6 +861,16 @@ func postgresIntegration(t *testing.T) {
 	}))
 
 	testSuite(t, "postgres", dsn, createTable)
+
+	tableName, err := gonanoid.Generate("abcdefghijklmnopqrstuvwxyz", 40)
+	require.NoError(t, err)
+	require.NoError(t, createTable(tableName))
+	testPostgresCopyOutput(t, "postgres", dsn, tableName)
+
+	tableName, err = gonanoid.Generate("abcdefghijklmnopqrstuvwxyz", 40)
+	require.NoError(t, err)
+	require.NoError(t, createTable(tableName))
+	testPgCDCInput(t, "postgres", dsn, tableName)
 }
 
 func mySQLIntegration(t *testing.T) {
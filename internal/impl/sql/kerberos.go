@@ -0,0 +1,98 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/lib/pq"
+
+	"github.com/benthosdev/benthos/v4/internal/kerberos"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func kerberosField() *service.ConfigField {
+	spec := kerberos.FieldSpec("")
+	spec.Description += " Kerberos is only supported by the `postgres` driver, where the service principal is formed from the DSN parameter `krbsrvname` (defaulting to `postgres`) and the host, or is specified in full with the DSN parameter `krbspn`."
+	return service.NewInternalField(spec)
+}
+
+// The GSS provider of the postgres driver is global, and therefore connections
+// are opened one at a time with the client of their pool made current.
+var (
+	krbConnectMut sync.Mutex
+	krbCurrent    *client.Client
+)
+
+func init() {
+	pq.RegisterGSSProvider(func() (pq.GSS, error) {
+		if krbCurrent == nil {
+			return nil, errors.New("kerberos is not enabled for this connection")
+		}
+		return krbGSS{cl: krbCurrent}, nil
+	})
+}
+
+type krbGSS struct {
+	cl *client.Client
+}
+
+func (k krbGSS) GetInitToken(host, service string) ([]byte, error) {
+	return kerberos.InitToken(k.cl, service+"/"+host)
+}
+
+func (k krbGSS) GetInitTokenFromSpn(spn string) ([]byte, error) {
+	return kerberos.InitToken(k.cl, spn)
+}
+
+func (k krbGSS) Continue(inToken []byte) (done bool, outToken []byte, err error) {
+	// Mutual authentication isn't requested and so no further tokens are
+	// exchanged.
+	return true, nil, nil
+}
+
+// krbConnector opens postgres connections that authenticate with Kerberos.
+type krbConnector struct {
+	cl    *client.Client
+	inner driver.Connector
+}
+
+func (k *krbConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	krbConnectMut.Lock()
+	defer krbConnectMut.Unlock()
+
+	krbCurrent = k.cl
+	defer func() {
+		krbCurrent = nil
+	}()
+	return k.inner.Connect(ctx)
+}
+
+func (k *krbConnector) Driver() driver.Driver {
+	return k.inner.Driver()
+}
+
+// Close is called when the pool of the connector is closed.
+func (k *krbConnector) Close() error {
+	k.cl.Destroy()
+	return nil
+}
+
+func openKerberosDB(driverName, dsn string, conf kerberos.Config) (*sql.DB, error) {
+	if driverName != "postgres" {
+		return nil, fmt.Errorf("kerberos is not supported by the %v driver", driverName)
+	}
+	inner, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	cl, err := conf.Client()
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&krbConnector{cl: cl, inner: inner}), nil
+}
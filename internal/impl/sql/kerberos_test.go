@@ -0,0 +1,44 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKerberosConnSettings(t *testing.T) {
+	spec := service.NewConfigSpec()
+	for _, f := range connFields() {
+		spec = spec.Field(f)
+	}
+
+	parsed, err := spec.ParseYAML(`
+kerberos:
+  enabled: true
+  username: benthos
+  realm: EXAMPLE.COM
+  keytab_file: /etc/security/benthos.keytab
+`, nil)
+	require.NoError(t, err)
+
+	conn, err := connSettingsFromParsed(parsed)
+	require.NoError(t, err)
+	assert.True(t, conn.kerberos.Enabled)
+	assert.Equal(t, "benthos", conn.kerberos.Username)
+	assert.Equal(t, "EXAMPLE.COM", conn.kerberos.Realm)
+	assert.Equal(t, "/etc/security/benthos.keytab", conn.kerberos.KeytabFile)
+	assert.Equal(t, "/etc/krb5.conf", conn.kerberos.ConfigFile)
+
+	_, err = openDB(nil, "sqlite", "file::memory:", conn)
+	require.EqualError(t, err, "kerberos is not supported by the sqlite driver")
+
+	parsed, err = spec.ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	conn, err = connSettingsFromParsed(parsed)
+	require.NoError(t, err)
+	assert.False(t, conn.kerberos.Enabled)
+}
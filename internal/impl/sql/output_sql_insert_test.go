@@ -2,9 +2,16 @@ package sql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
 
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -28,3 +35,119 @@ args_mapping: 'root = [ this.id ]'
 	require.NoError(t, err)
 	require.NoError(t, insertOutput.Close(context.Background()))
 }
+
+func TestSQLInsertOutputTransactionRollback(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE footable (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	spec := sqlInsertOutputConfig()
+	insertConfig, err := spec.ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: footable
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+transaction: true
+upsert:
+  conflict_columns: [ id ]
+`, dsn), service.NewEnvironment())
+	require.NoError(t, err)
+
+	insertOutput, err := newSQLInsertOutputFromConfig(insertConfig, nil)
+	require.NoError(t, err)
+	require.NoError(t, insertOutput.Connect(ctx))
+	t.Cleanup(func() {
+		_ = insertOutput.Close(context.Background())
+	})
+
+	rows := func() (names []string) {
+		r, err := db.Query("SELECT name FROM footable ORDER BY id")
+		require.NoError(t, err)
+		defer r.Close()
+		for r.Next() {
+			var name string
+			require.NoError(t, r.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, r.Err())
+		return
+	}
+
+	require.NoError(t, insertOutput.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"foo"}`)),
+		service.NewMessage([]byte(`{"id":2,"name":"bar"}`)),
+	}))
+	assert.Equal(t, []string{"foo", "bar"}, rows())
+
+	err = insertOutput.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"baz"}`)),
+		service.NewMessage([]byte(`{"id":3,"name":null}`)),
+		service.NewMessage([]byte(`{"id":4,"name":"buz"}`)),
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, rows())
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+	assert.Equal(t, 3, bErr.IndexedErrors())
+
+	var errs []error
+	bErr.WalkMessages(func(_ int, _ *service.Message, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	assert.ErrorIs(t, errs[0], errTransactionRolledBack)
+	assert.Contains(t, errs[1].Error(), "NOT NULL")
+	assert.Contains(t, errs[2].Error(), "message 1 of batch failed")
+
+	require.NoError(t, insertOutput.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"baz"}`)),
+		service.NewMessage([]byte(`{"id":3,"name":"buz"}`)),
+	}))
+	assert.Equal(t, []string{"baz", "bar", "buz"}, rows())
+}
+
+func TestSQLInsertOutputIdentityInsert(t *testing.T) {
+	newOutput := func(driver, extra string) (*sqlInsertOutput, error) {
+		t.Helper()
+		insertConfig, err := sqlInsertOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: %v
+dsn: woof
+table: footable
+columns: [ id, name ]
+args_mapping: 'root = [ this.id, this.name ]'
+identity_insert: true
+%v
+`, driver, extra), service.NewEnvironment())
+		require.NoError(t, err)
+		return newSQLInsertOutputFromConfig(insertConfig, nil)
+	}
+
+	out, err := newOutput("mssql", "")
+	require.NoError(t, err)
+	query, _, err := out.builder.Values(1, "foo").ToSql()
+	require.NoError(t, err)
+	assert.Equal(t, "SET IDENTITY_INSERT footable ON; INSERT INTO footable (id,name) VALUES (?,?)", query)
+	require.NoError(t, out.Close(context.Background()))
+
+	out, err = newOutput("mssql", "transaction: true")
+	require.NoError(t, err)
+	assert.Equal(t, "SET IDENTITY_INSERT footable ON; INSERT INTO footable (id,name) VALUES (?,?)", out.rowQuery)
+	require.NoError(t, out.Close(context.Background()))
+
+	_, err = newOutput("mssql", "bulk_insert: true")
+	require.Error(t, err)
+
+	_, err = newOutput("postgres", "")
+	require.EqualError(t, err, "identity_insert is not supported by the postgres driver")
+}
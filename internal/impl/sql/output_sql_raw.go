@@ -30,6 +30,16 @@ func sqlRawOutputConfig() *service.ConfigSpec {
 			Example("root = [ this.cat.meow, this.doc.woofs[0] ]").
 			Example(`root = [ meta("user.id") ]`).
 			Optional()).
+		Field(service.NewBoolField("transaction").
+			Description("Whether to execute the queries of each batch within a transaction. When enabled a failure to execute the query of any message of a batch causes the transaction to be rolled back and all messages of the batch are reattempted.").
+			Advanced().
+			Default(false).
+			Version("4.9.0")).
+		Field(service.NewBoolField("prepared_statement").
+			Description("Whether to prepare the query once and reuse the prepared statement across batches. This option has no effect when `unsafe_dynamic_query` is enabled, and must not be used with queries containing multiple statements for drivers that do not support preparing them.").
+			Advanced().
+			Default(false).
+			Version("4.9.0")).
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of inserts to run in parallel.").
 			Default(64))
@@ -91,6 +101,10 @@ type sqlRawOutput struct {
 
 	argsMapping *bloblang.Executor
 
+	transaction bool
+	prepare     bool
+	stmt        *sql.Stmt
+
 	connSettings connSettings
 
 	logger  *service.Logger
@@ -133,7 +147,16 @@ func newSQLRawOutputFromConfig(conf *service.ParsedConfig, logger *service.Logge
 	if err != nil {
 		return nil, err
 	}
-	return newSQLRawOutput(logger, driverStr, dsnStr, queryStatic, queryDyn, argsMapping, connSettings), nil
+
+	s := newSQLRawOutput(logger, driverStr, dsnStr, queryStatic, queryDyn, argsMapping, connSettings)
+	if s.transaction, err = conf.FieldBool("transaction"); err != nil {
+		return nil, err
+	}
+	if s.prepare, err = conf.FieldBool("prepared_statement"); err != nil {
+		return nil, err
+	}
+	s.prepare = s.prepare && queryDyn == nil
+	return s, nil
 }
 
 func newSQLRawOutput(
@@ -165,17 +188,26 @@ func (s *sqlRawOutput) Connect(ctx context.Context) error {
 	}
 
 	var err error
-	if s.db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if s.db, err = openDB(s.logger, s.driver, s.dsn, s.connSettings); err != nil {
 		return err
 	}
 
-	s.connSettings.apply(s.db)
+	if s.prepare {
+		if s.stmt, err = s.db.PrepareContext(ctx, s.queryStatic); err != nil {
+			_ = closeDB(s.db, s.connSettings)
+			s.db = nil
+			return fmt.Errorf("failed to prepare statement: %w", err)
+		}
+	}
 
 	go func() {
 		<-s.shutSig.CloseNowChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		if s.stmt != nil {
+			_ = s.stmt.Close()
+		}
+		_ = closeDB(s.db, s.connSettings)
 		s.dbMut.Unlock()
 
 		s.shutSig.ShutdownComplete()
@@ -187,33 +219,46 @@ func (s *sqlRawOutput) WriteBatch(ctx context.Context, batch service.MessageBatc
 	s.dbMut.RLock()
 	defer s.dbMut.RUnlock()
 
-	for i := range batch {
-		var args []any
-		if s.argsMapping != nil {
-			resMsg, err := batch.BloblangQuery(i, s.argsMapping)
-			if err != nil {
-				return err
-			}
+	var execer interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	} = s.db
+	stmt := s.stmt
 
-			iargs, err := resMsg.AsStructured()
-			if err != nil {
-				return err
-			}
+	var tx *sql.Tx
+	if s.transaction {
+		var err error
+		if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		execer = tx
+		if stmt != nil {
+			stmt = tx.StmtContext(ctx, stmt)
+		}
+	}
 
-			var ok bool
-			if args, ok = iargs.([]any); !ok {
-				return fmt.Errorf("mapping returned non-array result: %T", iargs)
+	for i := range batch {
+		args, err := argsFromMapping(batch, i, s.argsMapping)
+		if err == nil {
+			if stmt != nil {
+				_, err = stmt.ExecContext(ctx, args...)
+			} else {
+				queryStr := s.queryStatic
+				if s.queryDyn != nil {
+					queryStr = batch.InterpolatedString(i, s.queryDyn)
+				}
+				_, err = execer.ExecContext(ctx, queryStr, args...)
 			}
 		}
-
-		queryStr := s.queryStatic
-		if s.queryDyn != nil {
-			queryStr = batch.InterpolatedString(i, s.queryDyn)
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+			return messageWriteErr(batch, i, err, tx != nil)
 		}
+	}
 
-		if _, err := s.db.ExecContext(ctx, queryStr, args...); err != nil {
-			return err
-		}
+	if tx != nil {
+		return tx.Commit()
 	}
 	return nil
 }
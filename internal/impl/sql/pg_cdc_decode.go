@@ -0,0 +1,441 @@
+package sql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// pgLSN is a position within the write-ahead log of PostgreSQL.
+type pgLSN uint64
+
+// parsePgLSN parses the text form of an LSN, which consists of two hexadecimal
+// numbers separated by a slash.
+func parsePgLSN(s string) (pgLSN, error) {
+	hi, lo, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid lsn '%v'", s)
+	}
+	hiN, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn '%v': %w", s, err)
+	}
+	loN, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid lsn '%v': %w", s, err)
+	}
+	return pgLSN(hiN<<32 | loN), nil
+}
+
+func (l pgLSN) String() string {
+	return fmt.Sprintf("%X/%X", uint32(l>>32), uint32(l))
+}
+
+// pgChange is a single row level change decoded from a replication slot.
+type pgChange struct {
+	lsn       pgLSN
+	operation string
+	schema    string
+	table     string
+	data      map[string]any
+	old       map[string]any
+}
+
+// pgTransaction is a committed transaction and the changes it contains.
+type pgTransaction struct {
+	xid       string
+	commitLSN pgLSN
+	changes   []pgChange
+
+	// The number of rows of the slot the transaction was decoded from.
+	rows int
+}
+
+// pgTxAssembler assembles the rows produced by an output plugin of a
+// replication slot into transactions.
+type pgTxAssembler interface {
+	// reset discards any partially assembled transaction, which is called
+	// before the changes of a slot are read from the start again.
+	reset()
+
+	// add decodes a row of the slot and returns a transaction when the row
+	// completes one.
+	add(lsn pgLSN, xid string, data []byte) (*pgTransaction, error)
+}
+
+//------------------------------------------------------------------------------
+
+// wal2jsonAssembler decodes the rows of the wal2json plugin with format
+// version 2, which produces a JSON object for each change along with objects
+// marking the beginning and end of each transaction.
+type wal2jsonAssembler struct {
+	current *pgTransaction
+}
+
+func (w *wal2jsonAssembler) reset() {
+	w.current = nil
+}
+
+type wal2jsonColumn struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+type wal2jsonRow struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+func wal2jsonValues(cols []wal2jsonColumn) map[string]any {
+	if cols == nil {
+		return nil
+	}
+	values := make(map[string]any, len(cols))
+	for _, c := range cols {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+func (w *wal2jsonAssembler) add(lsn pgLSN, xid string, data []byte) (*pgTransaction, error) {
+	var row wal2jsonRow
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&row); err != nil {
+		return nil, fmt.Errorf("failed to parse wal2json row: %w", err)
+	}
+
+	if row.Action == "B" {
+		w.current = &pgTransaction{xid: xid, rows: 1}
+		return nil, nil
+	}
+	if w.current == nil {
+		// Rows of a transaction whose beginning was not seen are ignored,
+		// which can only happen when a read starts mid transaction.
+		return nil, nil
+	}
+	w.current.rows++
+
+	change := pgChange{lsn: lsn, schema: row.Schema, table: row.Table}
+	switch row.Action {
+	case "I":
+		change.operation = "insert"
+		change.data = wal2jsonValues(row.Columns)
+	case "U":
+		change.operation = "update"
+		change.data = wal2jsonValues(row.Columns)
+		change.old = wal2jsonValues(row.Identity)
+	case "D":
+		change.operation = "delete"
+		change.old = wal2jsonValues(row.Identity)
+	case "T":
+		change.operation = "truncate"
+	case "C":
+		tx := w.current
+		tx.commitLSN = lsn
+		w.current = nil
+		return tx, nil
+	default:
+		return nil, nil
+	}
+	w.current.changes = append(w.current.changes, change)
+	return nil, nil
+}
+
+//------------------------------------------------------------------------------
+
+type pgRelationColumn struct {
+	name string
+	oid  uint32
+}
+
+type pgRelation struct {
+	schema  string
+	table   string
+	columns []pgRelationColumn
+}
+
+// pgoutputAssembler decodes the binary messages of version 1 of the logical
+// replication protocol produced by the pgoutput plugin, where column values
+// are in their text format.
+type pgoutputAssembler struct {
+	// Relations are described before their first change within each
+	// decoding session, and are kept for as long as the input is running.
+	relations map[uint32]pgRelation
+	current   *pgTransaction
+}
+
+func newPgoutputAssembler() *pgoutputAssembler {
+	return &pgoutputAssembler{relations: map[uint32]pgRelation{}}
+}
+
+func (p *pgoutputAssembler) reset() {
+	p.current = nil
+}
+
+var errPgoutputShort = errors.New("message is too short")
+
+// pgoutputReader reads the fields of a pgoutput message.
+type pgoutputReader struct {
+	b   []byte
+	err error
+}
+
+func (r *pgoutputReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errPgoutputShort
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *pgoutputReader) uint8() byte {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *pgoutputReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		r.err = errPgoutputShort
+		return ""
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
+
+// tuple reads tuple data, where columns with unchanged TOAST values are
+// omitted as their values are not sent.
+func (r *pgoutputReader) tuple(rel pgRelation) (map[string]any, error) {
+	n := int(r.uint16())
+	values := make(map[string]any, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		kind := r.uint8()
+		var col pgRelationColumn
+		if i < len(rel.columns) {
+			col = rel.columns[i]
+		} else {
+			col.name = strconv.Itoa(i)
+		}
+		switch kind {
+		case 'n':
+			values[col.name] = nil
+		case 'u':
+		case 't':
+			text := r.take(int(r.uint32()))
+			if r.err == nil {
+				values[col.name] = pgTextValue(col.oid, string(text))
+			}
+		default:
+			return nil, fmt.Errorf("unexpected tuple column kind '%c'", kind)
+		}
+	}
+	return values, r.err
+}
+
+// Type OIDs of PostgreSQL that are converted from their text format.
+const (
+	pgOIDBool    = 16
+	pgOIDInt8    = 20
+	pgOIDInt2    = 21
+	pgOIDInt4    = 23
+	pgOIDJSON    = 114
+	pgOIDFloat4  = 700
+	pgOIDFloat8  = 701
+	pgOIDNumeric = 1700
+	pgOIDJSONB   = 3802
+)
+
+// pgTextValue converts the text format of a value to a structured value for
+// common types, values of other types are kept as strings.
+func pgTextValue(oid uint32, text string) any {
+	switch oid {
+	case pgOIDBool:
+		return text == "t"
+	case pgOIDInt2, pgOIDInt4, pgOIDInt8:
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+	case pgOIDFloat4, pgOIDFloat8:
+		// NaN and infinite values are not valid JSON numbers.
+		if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case pgOIDNumeric:
+		// Numerics are kept as numbers without losing precision.
+		switch text {
+		case "NaN", "Infinity", "-Infinity":
+		default:
+			return json.Number(text)
+		}
+	case pgOIDJSON, pgOIDJSONB:
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err == nil {
+			return v
+		}
+	}
+	return text
+}
+
+func (p *pgoutputAssembler) relation(r *pgoutputReader) (pgRelation, error) {
+	id := r.uint32()
+	if r.err != nil {
+		return pgRelation{}, r.err
+	}
+	rel, exists := p.relations[id]
+	if !exists {
+		return pgRelation{}, fmt.Errorf("change refers to unknown relation %v", id)
+	}
+	return rel, nil
+}
+
+func (p *pgoutputAssembler) add(lsn pgLSN, xid string, data []byte) (*pgTransaction, error) {
+	r := &pgoutputReader{b: data}
+	msgType := r.uint8()
+
+	switch msgType {
+	case 'B':
+		p.current = &pgTransaction{xid: xid, rows: 1}
+		return nil, r.err
+	case 'R':
+		id := r.uint32()
+		rel := pgRelation{schema: r.string(), table: r.string()}
+		_ = r.uint8() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			_ = r.uint8() // Flags
+			col := pgRelationColumn{name: r.string(), oid: r.uint32()}
+			_ = r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to decode relation message: %w", r.err)
+		}
+		p.relations[id] = rel
+		if p.current != nil {
+			p.current.rows++
+		}
+		return nil, nil
+	}
+
+	if p.current == nil {
+		// Messages of a transaction whose beginning was not seen are
+		// ignored, which can only happen when a read starts mid transaction.
+		return nil, nil
+	}
+	p.current.rows++
+
+	var changes []pgChange
+	switch msgType {
+	case 'I':
+		rel, err := p.relation(r)
+		if err != nil {
+			return nil, err
+		}
+		if kind := r.uint8(); r.err == nil && kind != 'N' {
+			return nil, fmt.Errorf("unexpected insert tuple kind '%c'", kind)
+		}
+		data, err := r.tuple(rel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode insert message: %w", err)
+		}
+		changes = append(changes, pgChange{lsn: lsn, operation: "insert", schema: rel.schema, table: rel.table, data: data})
+	case 'U':
+		rel, err := p.relation(r)
+		if err != nil {
+			return nil, err
+		}
+		change := pgChange{lsn: lsn, operation: "update", schema: rel.schema, table: rel.table}
+		kind := r.uint8()
+		if kind == 'K' || kind == 'O' {
+			if change.old, err = r.tuple(rel); err != nil {
+				return nil, fmt.Errorf("failed to decode update message: %w", err)
+			}
+			kind = r.uint8()
+		}
+		if r.err == nil && kind != 'N' {
+			return nil, fmt.Errorf("unexpected update tuple kind '%c'", kind)
+		}
+		if change.data, err = r.tuple(rel); err != nil {
+			return nil, fmt.Errorf("failed to decode update message: %w", err)
+		}
+		changes = append(changes, change)
+	case 'D':
+		rel, err := p.relation(r)
+		if err != nil {
+			return nil, err
+		}
+		if kind := r.uint8(); r.err == nil && kind != 'K' && kind != 'O' {
+			return nil, fmt.Errorf("unexpected delete tuple kind '%c'", kind)
+		}
+		old, err := r.tuple(rel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode delete message: %w", err)
+		}
+		changes = append(changes, pgChange{lsn: lsn, operation: "delete", schema: rel.schema, table: rel.table, old: old})
+	case 'T':
+		n := int(r.uint32())
+		_ = r.uint8() // Options
+		for i := 0; i < n && r.err == nil; i++ {
+			rel, err := p.relation(r)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, pgChange{lsn: lsn, operation: "truncate", schema: rel.schema, table: rel.table})
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to decode truncate message: %w", r.err)
+		}
+	case 'C':
+		// The end of the commit record is the position that the slot is
+		// advanced to once the transaction is delivered.
+		_ = r.uint8() // Flags
+		_ = r.take(8) // Commit LSN
+		endLSN := r.take(8)
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to decode commit message: %w", r.err)
+		}
+		tx := p.current
+		tx.commitLSN = pgLSN(binary.BigEndian.Uint64(endLSN))
+		p.current = nil
+		return tx, nil
+	}
+
+	p.current.changes = append(p.current.changes, changes...)
+	return nil, nil
+}
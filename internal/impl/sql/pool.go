@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type sharedPool struct {
+	driver string
+	dsn    string
+	conn   connSettings
+	db     *sql.DB
+	refs   int
+}
+
+var (
+	sharedPoolsMut sync.Mutex
+	sharedPools    = map[string]*sharedPool{}
+)
+
+// openDB opens a connection pool to a database and applies the connection
+// settings to it. When the connection settings specify a shared pool the pool
+// is shared with all other components of the process that reference the same
+// name, which must also specify the same driver, dsn and connection settings.
+func openDB(logger *service.Logger, driver, dsn string, conn connSettings) (*sql.DB, error) {
+	if conn.sharedPool == "" {
+		db, err := sqlOpenWithReworks(logger, driver, dsn, conn.kerberos)
+		if err != nil {
+			return nil, err
+		}
+		conn.apply(db)
+		return db, nil
+	}
+
+	sharedPoolsMut.Lock()
+	defer sharedPoolsMut.Unlock()
+
+	if p, exists := sharedPools[conn.sharedPool]; exists {
+		if p.driver != driver || p.dsn != dsn {
+			return nil, fmt.Errorf("shared pool %v is already open with a different driver or dsn", conn.sharedPool)
+		}
+		if p.conn != conn {
+			return nil, fmt.Errorf("shared pool %v is already open with different connection settings", conn.sharedPool)
+		}
+		p.refs++
+		return p.db, nil
+	}
+
+	db, err := sqlOpenWithReworks(logger, driver, dsn, conn.kerberos)
+	if err != nil {
+		return nil, err
+	}
+	conn.apply(db)
+
+	sharedPools[conn.sharedPool] = &sharedPool{
+		driver: driver,
+		dsn:    dsn,
+		conn:   conn,
+		db:     db,
+		refs:   1,
+	}
+	return db, nil
+}
+
+// closeDB closes a connection pool opened with openDB. Shared pools are only
+// closed once all components that opened them have closed them.
+func closeDB(db *sql.DB, conn connSettings) error {
+	if conn.sharedPool == "" {
+		return db.Close()
+	}
+
+	sharedPoolsMut.Lock()
+	defer sharedPoolsMut.Unlock()
+
+	p, exists := sharedPools[conn.sharedPool]
+	if !exists || p.db != db {
+		return db.Close()
+	}
+	if p.refs--; p.refs > 0 {
+		return nil
+	}
+	delete(sharedPools, conn.sharedPool)
+	return db.Close()
+}
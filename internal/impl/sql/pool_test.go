@@ -0,0 +1,43 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestSharedPool(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+	conn := connSettings{sharedPool: "foo"}
+
+	dbA, err := openDB(nil, "sqlite", dsn, conn)
+	require.NoError(t, err)
+
+	dbB, err := openDB(nil, "sqlite", dsn, conn)
+	require.NoError(t, err)
+	assert.Same(t, dbA, dbB)
+
+	_, err = openDB(nil, "sqlite", dsn+"?mode=ro", conn)
+	require.Error(t, err)
+
+	_, err = openDB(nil, "sqlite", dsn, connSettings{sharedPool: "foo", maxOpenConns: 10})
+	require.Error(t, err)
+
+	dbC, err := openDB(nil, "sqlite", dsn, connSettings{})
+	require.NoError(t, err)
+	assert.NotSame(t, dbA, dbC)
+	require.NoError(t, closeDB(dbC, connSettings{}))
+
+	require.NoError(t, closeDB(dbA, conn))
+	require.NoError(t, dbB.Ping())
+
+	require.NoError(t, closeDB(dbB, conn))
+	require.Error(t, dbB.Ping())
+
+	sharedPoolsMut.Lock()
+	assert.Empty(t, sharedPools)
+	sharedPoolsMut.Unlock()
+}
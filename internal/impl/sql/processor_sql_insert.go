@@ -46,6 +46,7 @@ If the insert fails to execute then the message will still remain unchanged and
 	for _, f := range connFields() {
 		spec = spec.Field(f)
 	}
+	spec = spec.Field(service.NewCircuitBreakerToggledField("circuit_breaker").Version("4.9.0"))
 
 	spec = spec.Version("3.59.0").
 		Example("Table Insert (MySQL)",
@@ -90,6 +91,7 @@ type sqlInsertProcessor struct {
 
 	useTxStmt   bool
 	argsMapping *bloblang.Executor
+	breaker     *service.CircuitBreaker
 
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
@@ -166,22 +168,24 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 		s.builder = s.builder.Suffix(suffixStr)
 	}
 
+	if s.breaker, err = conf.FieldCircuitBreakerToggled("circuit_breaker"); err != nil {
+		return nil, err
+	}
+
 	connSettings, err := connSettingsFromParsed(conf)
 	if err != nil {
 		return nil, err
 	}
 
-	if s.db, err = sqlOpenWithReworks(logger, driverStr, dsnStr); err != nil {
+	if s.db, err = openDB(logger, driverStr, dsnStr, connSettings); err != nil {
 		return nil, err
 	}
 
-	connSettings.apply(s.db)
-
 	go func() {
 		<-s.shutSig.CloseNowChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = closeDB(s.db, connSettings)
 		s.dbMut.Unlock()
 
 		s.shutSig.ShutdownComplete()
@@ -190,6 +194,15 @@ func NewSQLInsertProcessorFromConfig(conf *service.ParsedConfig, logger *service
 }
 
 func (s *sqlInsertProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if err := s.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	res, err := s.processBatch(ctx, batch)
+	s.breaker.Done(err)
+	return res, err
+}
+
+func (s *sqlInsertProcessor) processBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
 	s.dbMut.RLock()
 	defer s.dbMut.RUnlock()
 
@@ -40,6 +40,7 @@ If the query fails to execute then the message will remain unchanged and the err
 	for _, f := range connFields() {
 		spec = spec.Field(f)
 	}
+	spec = spec.Field(service.NewCircuitBreakerToggledField("circuit_breaker").Version("4.9.0"))
 
 	spec = spec.Version("3.65.0").
 		Example(
@@ -97,6 +98,7 @@ type sqlRawProcessor struct {
 	onlyExec    bool
 
 	argsMapping *bloblang.Executor
+	breaker     *service.CircuitBreaker
 
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
@@ -141,11 +143,22 @@ func NewSQLRawProcessorFromConfig(conf *service.ParsedConfig, logger *service.Lo
 		}
 	}
 
+	breaker, err := conf.FieldCircuitBreakerToggled("circuit_breaker")
+	if err != nil {
+		return nil, err
+	}
+
 	connSettings, err := connSettingsFromParsed(conf)
 	if err != nil {
 		return nil, err
 	}
-	return newSQLRawProcessor(logger, driverStr, dsnStr, queryStatic, queryDyn, onlyExec, argsMapping, connSettings)
+
+	s, err := newSQLRawProcessor(logger, driverStr, dsnStr, queryStatic, queryDyn, onlyExec, argsMapping, connSettings)
+	if err != nil {
+		return nil, err
+	}
+	s.breaker = breaker
+	return s, nil
 }
 
 func newSQLRawProcessor(
@@ -167,16 +180,15 @@ func newSQLRawProcessor(
 	}
 
 	var err error
-	if s.db, err = sqlOpenWithReworks(logger, driverStr, dsnStr); err != nil {
+	if s.db, err = openDB(logger, driverStr, dsnStr, connSettings); err != nil {
 		return nil, err
 	}
-	connSettings.apply(s.db)
 
 	go func() {
 		<-s.shutSig.CloseNowChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = closeDB(s.db, connSettings)
 		s.dbMut.Unlock()
 
 		s.shutSig.ShutdownComplete()
@@ -219,14 +231,28 @@ func (s *sqlRawProcessor) ProcessBatch(ctx context.Context, batch service.Messag
 			queryStr = batch.InterpolatedString(i, s.queryDyn)
 		}
 
+		if s.breaker != nil {
+			if err := s.breaker.Allow(); err != nil {
+				msg.SetError(err)
+				continue
+			}
+		}
+
 		if s.onlyExec {
-			if _, err := s.db.ExecContext(ctx, queryStr, args...); err != nil {
+			_, err := s.db.ExecContext(ctx, queryStr, args...)
+			if s.breaker != nil {
+				s.breaker.Done(err)
+			}
+			if err != nil {
 				s.logger.Debugf("Failed to run query: %v", err)
 				msg.SetError(err)
 				continue
 			}
 		} else {
 			rows, err := s.db.QueryContext(ctx, queryStr, args...)
+			if s.breaker != nil {
+				s.breaker.Done(err)
+			}
 			if err != nil {
 				s.logger.Debugf("Failed to run query: %v", err)
 				msg.SetError(err)
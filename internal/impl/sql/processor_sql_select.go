@@ -52,6 +52,7 @@ If the query fails to execute then the message will remain unchanged and the err
 	for _, f := range connFields() {
 		spec = spec.Field(f)
 	}
+	spec = spec.Field(service.NewCircuitBreakerToggledField("circuit_breaker").Version("4.9.0"))
 
 	spec = spec.Version("3.59.0").
 		Example("Table Query (PostgreSQL)",
@@ -98,6 +99,7 @@ type sqlSelectProcessor struct {
 
 	where       string
 	argsMapping *bloblang.Executor
+	breaker     *service.CircuitBreaker
 
 	logger  *service.Logger
 	shutSig *shutdown.Signaller
@@ -166,21 +168,24 @@ func NewSQLSelectProcessorFromConfig(conf *service.ParsedConfig, logger *service
 		s.builder = s.builder.Suffix(suffixStr)
 	}
 
+	if s.breaker, err = conf.FieldCircuitBreakerToggled("circuit_breaker"); err != nil {
+		return nil, err
+	}
+
 	connSettings, err := connSettingsFromParsed(conf)
 	if err != nil {
 		return nil, err
 	}
 
-	if s.db, err = sqlOpenWithReworks(logger, driverStr, dsnStr); err != nil {
+	if s.db, err = openDB(logger, driverStr, dsnStr, connSettings); err != nil {
 		return nil, err
 	}
-	connSettings.apply(s.db)
 
 	go func() {
 		<-s.shutSig.CloseNowChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = closeDB(s.db, connSettings)
 		s.dbMut.Unlock()
 
 		s.shutSig.ShutdownComplete()
@@ -223,7 +228,13 @@ func (s *sqlSelectProcessor) ProcessBatch(ctx context.Context, batch service.Mes
 			queryBuilder = queryBuilder.Where(s.where, args...)
 		}
 
+		if err := s.breaker.Allow(); err != nil {
+			msg.SetError(err)
+			continue
+		}
+
 		rows, err := queryBuilder.RunWith(s.db).QueryContext(ctx)
+		s.breaker.Done(err)
 		if err != nil {
 			s.logger.Debugf("Failed to run query: %v", err)
 			msg.SetError(err)
@@ -0,0 +1,160 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func upsertField() *service.ConfigField {
+	return service.NewObjectField("upsert",
+		service.NewStringListField("conflict_columns").
+			Description("The columns that identify an existing row, which must be covered by a unique constraint or primary key for the `mysql`, `postgres` and `sqlite` drivers.").
+			Example([]string{"id"}),
+		service.NewStringListField("update_columns").
+			Description("The columns to update when a row already exists. When empty all columns that are not conflict columns are updated.").
+			Default([]any{}),
+	).Description(`
+Optionally insert rows as upserts, where rows that conflict with an existing row are updated instead. The statement generated depends on the driver:
+
+| Driver | Statement |
+|---|---|
+` + "| `mysql` | `INSERT ... ON DUPLICATE KEY UPDATE` |" + `
+` + "| `postgres` | `INSERT ... ON CONFLICT (...) DO UPDATE` |" + `
+` + "| `sqlite` | `INSERT ... ON CONFLICT (...) DO UPDATE` |" + `
+` + "| `mssql` | `MERGE` |" + `
+` + "| `oracle` | `MERGE` |" + `
+
+Upserts are not supported by the ` + "`clickhouse`" + ` driver. Drivers that use a ` + "`MERGE`" + ` statement execute one statement per message.`).
+		Optional().
+		Advanced().
+		Version("4.9.0")
+}
+
+type upsertConfig struct {
+	conflictColumns []string
+	updateColumns   []string
+}
+
+func upsertConfigFromParsed(conf *service.ParsedConfig, columns []string) (*upsertConfig, error) {
+	if !conf.Contains("upsert", "conflict_columns") {
+		return nil, nil
+	}
+
+	conf = conf.Namespace("upsert")
+
+	u := &upsertConfig{}
+
+	var err error
+	if u.conflictColumns, err = conf.FieldStringList("conflict_columns"); err != nil {
+		return nil, err
+	}
+	if len(u.conflictColumns) == 0 {
+		return nil, nil
+	}
+	if u.updateColumns, err = conf.FieldStringList("update_columns"); err != nil {
+		return nil, err
+	}
+	if len(u.updateColumns) == 0 {
+		conflicts := map[string]struct{}{}
+		for _, c := range u.conflictColumns {
+			conflicts[c] = struct{}{}
+		}
+		for _, c := range columns {
+			if _, exists := conflicts[c]; !exists {
+				u.updateColumns = append(u.updateColumns, c)
+			}
+		}
+	}
+	return u, nil
+}
+
+// usesMerge returns true if the driver requires a MERGE statement to be
+// executed for each row rather than an insert suffix.
+func (u *upsertConfig) usesMerge(driver string) bool {
+	return driver == "mssql" || driver == "oracle"
+}
+
+// insertSuffix returns the upsert clause to append to an insert statement for
+// drivers that support one.
+func (u *upsertConfig) insertSuffix(driver string) (string, error) {
+	switch driver {
+	case "postgres", "sqlite":
+		clause := "ON CONFLICT (" + strings.Join(u.conflictColumns, ", ") + ") DO "
+		if len(u.updateColumns) == 0 {
+			return clause + "NOTHING", nil
+		}
+		sets := make([]string, len(u.updateColumns))
+		for i, c := range u.updateColumns {
+			sets[i] = c + " = EXCLUDED." + c
+		}
+		return clause + "UPDATE SET " + strings.Join(sets, ", "), nil
+	case "mysql":
+		updates := u.updateColumns
+		if len(updates) == 0 {
+			// MySQL has no DO NOTHING equivalent, a no-op update of a conflict
+			// column has the same effect.
+			updates = u.conflictColumns[:1]
+		}
+		sets := make([]string, len(updates))
+		for i, c := range updates {
+			sets[i] = c + " = VALUES(" + c + ")"
+		}
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+	}
+	return "", fmt.Errorf("upsert is not supported by the %v driver", driver)
+}
+
+// mergeStatement returns a MERGE statement that upserts a single row of the
+// given columns, using the placeholder style of the driver.
+func (u *upsertConfig) mergeStatement(driver, table string, columns []string) (string, error) {
+	selects := make([]string, len(columns))
+	for i, c := range columns {
+		placeholder := "?"
+		if driver == "oracle" {
+			placeholder = ":" + strconv.Itoa(i+1)
+		}
+		selects[i] = placeholder + " AS " + c
+	}
+
+	var source string
+	switch driver {
+	case "mssql":
+		source = "(SELECT " + strings.Join(selects, ", ") + ") AS src"
+		table += " AS tgt"
+	case "oracle":
+		source = "(SELECT " + strings.Join(selects, ", ") + " FROM dual) src"
+		table += " tgt"
+	default:
+		return "", fmt.Errorf("merge upserts are not supported by the %v driver", driver)
+	}
+
+	ons := make([]string, len(u.conflictColumns))
+	for i, c := range u.conflictColumns {
+		ons[i] = "tgt." + c + " = src." + c
+	}
+
+	var b strings.Builder
+	b.WriteString("MERGE INTO " + table + " USING " + source + " ON (" + strings.Join(ons, " AND ") + ")")
+	if len(u.updateColumns) > 0 {
+		sets := make([]string, len(u.updateColumns))
+		for i, c := range u.updateColumns {
+			sets[i] = "tgt." + c + " = src." + c
+		}
+		b.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", "))
+	}
+
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		values[i] = "src." + c
+	}
+	b.WriteString(" WHEN NOT MATCHED THEN INSERT (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ")")
+
+	if driver == "mssql" {
+		// SQL Server requires MERGE statements to be terminated.
+		b.WriteString(";")
+	}
+	return b.String(), nil
+}
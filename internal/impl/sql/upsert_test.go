@@ -0,0 +1,64 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertInsertSuffix(t *testing.T) {
+	u := &upsertConfig{
+		conflictColumns: []string{"id"},
+		updateColumns:   []string{"name", "age"},
+	}
+
+	for _, test := range []struct {
+		driver string
+		output string
+		errStr string
+	}{
+		{driver: "postgres", output: "ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age"},
+		{driver: "sqlite", output: "ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age"},
+		{driver: "mysql", output: "ON DUPLICATE KEY UPDATE name = VALUES(name), age = VALUES(age)"},
+		{driver: "clickhouse", errStr: "upsert is not supported by the clickhouse driver"},
+	} {
+		res, err := u.insertSuffix(test.driver)
+		if test.errStr != "" {
+			assert.EqualError(t, err, test.errStr, test.driver)
+			continue
+		}
+		require.NoError(t, err, test.driver)
+		assert.Equal(t, test.output, res, test.driver)
+	}
+
+	noUpdates := &upsertConfig{conflictColumns: []string{"id"}}
+
+	res, err := noUpdates.insertSuffix("postgres")
+	require.NoError(t, err)
+	assert.Equal(t, "ON CONFLICT (id) DO NOTHING", res)
+
+	res, err = noUpdates.insertSuffix("mysql")
+	require.NoError(t, err)
+	assert.Equal(t, "ON DUPLICATE KEY UPDATE id = VALUES(id)", res)
+}
+
+func TestUpsertMergeStatement(t *testing.T) {
+	u := &upsertConfig{
+		conflictColumns: []string{"id"},
+		updateColumns:   []string{"name"},
+	}
+
+	res, err := u.mergeStatement("mssql", "foo", []string{"id", "name"})
+	require.NoError(t, err)
+	assert.Equal(t, "MERGE INTO foo AS tgt USING (SELECT ? AS id, ? AS name) AS src ON (tgt.id = src.id) WHEN MATCHED THEN UPDATE SET tgt.name = src.name WHEN NOT MATCHED THEN INSERT (id, name) VALUES (src.id, src.name);", res)
+
+	res, err = u.mergeStatement("oracle", "foo", []string{"id", "name"})
+	require.NoError(t, err)
+	assert.Equal(t, "MERGE INTO foo tgt USING (SELECT :1 AS id, :2 AS name FROM dual) src ON (tgt.id = src.id) WHEN MATCHED THEN UPDATE SET tgt.name = src.name WHEN NOT MATCHED THEN INSERT (id, name) VALUES (src.id, src.name)", res)
+
+	noUpdates := &upsertConfig{conflictColumns: []string{"id"}}
+	res, err = noUpdates.mergeStatement("oracle", "foo", []string{"id"})
+	require.NoError(t, err)
+	assert.Equal(t, "MERGE INTO foo tgt USING (SELECT :1 AS id FROM dual) src ON (tgt.id = src.id) WHEN NOT MATCHED THEN INSERT (id) VALUES (src.id)", res)
+}
@@ -2,8 +2,58 @@ package sql
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+// errTransactionRolledBack is the error given to messages of a batch that were
+// written successfully but discarded when the transaction was rolled back.
+var errTransactionRolledBack = errors.New("transaction rolled back due to a failed message")
+
+func argsFromMapping(batch service.MessageBatch, i int, argsMapping *bloblang.Executor) ([]any, error) {
+	if argsMapping == nil {
+		return nil, nil
+	}
+
+	resMsg, err := batch.BloblangQuery(i, argsMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	iargs, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, err
+	}
+
+	args, ok := iargs.([]any)
+	if !ok {
+		return nil, fmt.Errorf("mapping returned non-array result: %T", iargs)
+	}
+	return args, nil
+}
+
+// messageWriteErr returns a batch error for a write that failed at message
+// index i. When the batch was written within a transaction that has been
+// rolled back all messages are failed, otherwise messages prior to i are
+// considered delivered and the remaining messages are failed.
+func messageWriteErr(batch service.MessageBatch, i int, err error, rolledBack bool) error {
+	bErr := service.NewBatchError(batch, fmt.Errorf("failed to write message %v: %w", i, err))
+	for j := range batch {
+		switch {
+		case j == i:
+			bErr.Failed(j, err)
+		case j > i:
+			bErr.Failed(j, fmt.Errorf("message %v of batch failed: %w", i, err))
+		case rolledBack:
+			bErr.Failed(j, errTransactionRolledBack)
+		}
+	}
+	return bErr
+}
+
 func sqlRowsToArray(rows *sql.Rows) ([]any, error) {
 	columnNames, err := rows.Columns()
 	if err != nil {
@@ -0,0 +1,209 @@
+package stomp
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+)
+
+type testSubscription struct {
+	id string
+	w  *testBrokerConn
+}
+
+type testBrokerConn struct {
+	mut sync.Mutex
+	w   *bufio.Writer
+}
+
+func (c *testBrokerConn) send(f *stFrame) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	_ = f.writeTo(c.w)
+}
+
+// testBroker is a fake of a STOMP broker with queues, where the messages of a
+// queue are delivered to its subscriptions in turn and rejected messages are
+// delivered again.
+type testBroker struct {
+	t    *testing.T
+	addr string
+
+	mut       sync.Mutex
+	queues    map[string][]*stFrame
+	subs      map[string][]*testSubscription
+	pending   map[string]*stFrame
+	acked     []string
+	nacked    []string
+	frames    []*stFrame
+	nextID    int
+	heartbeat string
+}
+
+func startTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	b := &testBroker{
+		t:         t,
+		addr:      l.Addr().String(),
+		queues:    map[string][]*stFrame{},
+		subs:      map[string][]*testSubscription{},
+		pending:   map[string]*stFrame{},
+		heartbeat: "0,0",
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	c := &testBrokerConn{w: bufio.NewWriter(conn)}
+	defer b.unsubscribe(c)
+
+	for {
+		f, err := readSTFrame(r)
+		if err != nil {
+			return
+		}
+		b.mut.Lock()
+		b.frames = append(b.frames, f)
+		b.mut.Unlock()
+
+		switch f.command {
+		case "CONNECT":
+			if f.value("login") == "bad" {
+				c.send(newSTFrame("ERROR", "message", "access denied"))
+				return
+			}
+			b.mut.Lock()
+			heartbeat := b.heartbeat
+			b.mut.Unlock()
+			c.send(newSTFrame("CONNECTED", "version", "1.2", "heart-beat", heartbeat))
+			continue
+		case "SUBSCRIBE":
+			if f.value("destination") == "/queue/forbidden" {
+				c.send(newSTFrame("ERROR", "message", "not authorized", "receipt-id", f.value("receipt")))
+				return
+			}
+			b.mut.Lock()
+			b.subs[f.value("destination")] = append(b.subs[f.value("destination")], &testSubscription{id: f.value("id"), w: c})
+			b.mut.Unlock()
+		case "SEND":
+			b.mut.Lock()
+			b.queues[f.value("destination")] = append(b.queues[f.value("destination")], f)
+			b.mut.Unlock()
+		case "ACK", "NACK":
+			b.mut.Lock()
+			msg := b.pending[f.value("id")]
+			delete(b.pending, f.value("id"))
+			if f.command == "ACK" {
+				b.acked = append(b.acked, f.value("id"))
+			} else {
+				b.nacked = append(b.nacked, f.value("id"))
+				if msg != nil {
+					dest := msg.value("destination")
+					b.queues[dest] = append([]*stFrame{msg}, b.queues[dest]...)
+				}
+			}
+			b.mut.Unlock()
+		}
+
+		if receipt, exists := f.get("receipt"); exists {
+			c.send(newSTFrame("RECEIPT", "receipt-id", receipt))
+		}
+		if f.command == "DISCONNECT" {
+			return
+		}
+		b.deliver()
+	}
+}
+
+func (b *testBroker) unsubscribe(c *testBrokerConn) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for dest, subs := range b.subs {
+		var remaining []*testSubscription
+		for _, s := range subs {
+			if s.w != c {
+				remaining = append(remaining, s)
+			}
+		}
+		b.subs[dest] = remaining
+	}
+}
+
+// put adds a message to a queue and delivers it to a subscription.
+func (b *testBroker) put(dest string, body string, headers ...string) {
+	f := newSTFrame("SEND", append([]string{"destination", dest}, headers...)...)
+	f.body = []byte(body)
+	b.mut.Lock()
+	b.queues[dest] = append(b.queues[dest], f)
+	b.mut.Unlock()
+	b.deliver()
+}
+
+func (b *testBroker) deliver() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	for dest, msgs := range b.queues {
+		subs := b.subs[dest]
+		if len(subs) == 0 {
+			continue
+		}
+		for _, msg := range msgs {
+			b.nextID++
+			id := fmt.Sprintf("msg-%v", b.nextID)
+			sub := subs[b.nextID%len(subs)]
+
+			out := newSTFrame("MESSAGE", "subscription", sub.id, "message-id", id, "ack", id)
+			out.headers = append(out.headers, msg.headers...)
+			out.body = msg.body
+			b.pending[id] = msg
+			sub.w.send(out)
+		}
+		b.queues[dest] = nil
+	}
+}
+
+func (b *testBroker) messages(dest string) []*stFrame {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return append([]*stFrame(nil), b.queues[dest]...)
+}
+
+func (b *testBroker) acks() (acked, nacked []string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return append([]string(nil), b.acked...), append([]string(nil), b.nacked...)
+}
+
+func (b *testBroker) received(command string) []*stFrame {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	var frames []*stFrame
+	for _, f := range b.frames {
+		if f.command == command {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
@@ -0,0 +1,252 @@
+package stomp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	stiFieldDestination = "destination"
+	stiFieldPrefetch    = "prefetch_count"
+	stiFieldHeaders     = "headers"
+
+	// The ID of the subscription of the input, of which there is only one per
+	// connection.
+	stSubscriptionID = "0"
+)
+
+func stInputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes messages from a destination of a broker over STOMP 1.2.").
+		Description(`
+Subscribes to a destination with the ` + "`client-individual`" + ` acknowledgement mode, where each message is acknowledged with an ` + "`ACK`" + ` frame once it has been delivered, and otherwise rejected with a ` + "`NACK`" + ` frame, which causes the broker to either deliver the message again or move it to a dead-letter queue depending on its configuration. Messages that have not been acknowledged when the connection is lost are delivered again by the broker.
+
+The naming of destinations depends on the broker, for example ActiveMQ Classic uses the prefixes ` + "`/queue/`" + ` and ` + "`/topic/`" + ` and ActiveMQ Artemis uses the names of addresses and queues directly.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "```" + `
+- stomp_destination
+- stomp_message_id
+- stomp_subscription
+- stomp_content_type
+- All other headers of the message
+` + "```" + `
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`)
+
+	for _, f := range stConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewStringField(stiFieldDestination).
+			Description("The destination to subscribe to.").
+			Example("/queue/orders").
+			Example("orders::fulfilment")).
+		Field(service.NewIntField(stiFieldPrefetch).
+			Description("The maximum number of messages that the broker sends before they are acknowledged, which is set with the `activemq.prefetchSize` header for ActiveMQ Classic and the `consumer-window-size` header for ActiveMQ Artemis, where the latter is in bytes. Set to `0` in order to use the default of the broker.").
+			Advanced().
+			Default(0)).
+		Field(service.NewStringMapField(stiFieldHeaders).
+			Description("Additional headers of the subscription, which are specific to each broker.").
+			Example(map[string]any{"selector": "region = 'eu'"}).
+			Advanced().
+			Default(map[string]any{})).
+		Example(
+			"ActiveMQ Queue",
+			"In this example the messages of an ActiveMQ queue are consumed with a selector.",
+			`
+input:
+  stomp:
+    address: localhost:61613
+    login: benthos
+    passcode: "${ACTIVEMQ_PASSWORD}"
+    destination: /queue/orders
+    headers:
+      selector: "region = 'eu'"
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"stomp", stInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newSTInputFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type stInput struct {
+	conn        stConnDetails
+	log         *service.Logger
+	destination string
+	prefetch    int
+	headers     map[string]string
+
+	m        sync.RWMutex
+	sc       *stConn
+	messages chan *stFrame
+}
+
+func newSTInputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*stInput, error) {
+	s := &stInput{log: log}
+
+	var err error
+	if s.conn, err = stConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if s.destination, err = conf.FieldString(stiFieldDestination); err != nil {
+		return nil, err
+	}
+	if s.destination == "" {
+		return nil, errors.New("a destination must be specified")
+	}
+	if s.prefetch, err = conf.FieldInt(stiFieldPrefetch); err != nil {
+		return nil, err
+	}
+	if s.headers, err = conf.FieldStringMap(stiFieldHeaders); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *stInput) subscribeFrame() *stFrame {
+	f := newSTFrame("SUBSCRIBE",
+		"id", stSubscriptionID,
+		"destination", s.destination,
+		"ack", "client-individual",
+	)
+	if s.prefetch > 0 {
+		f.add("activemq.prefetchSize", fmt.Sprint(s.prefetch))
+		f.add("consumer-window-size", fmt.Sprint(s.prefetch))
+	}
+	keys := make([]string, 0, len(s.headers))
+	for k := range s.headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f.add(k, s.headers[k])
+	}
+	return f
+}
+
+func (s *stInput) Connect(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.sc != nil {
+		return nil
+	}
+
+	messages := make(chan *stFrame)
+	sc, err := dialST(ctx, s.conn, messages)
+	if err != nil {
+		return err
+	}
+	// The subscription is confirmed with a receipt in order to surface errors,
+	// such as a lack of permission, before messages are read.
+	if err := sc.writeWithReceipt(ctx, s.subscribeFrame()); err != nil {
+		sc.close(ctx)
+		return fmt.Errorf("failed to subscribe to %v: %w", s.destination, err)
+	}
+
+	s.sc, s.messages = sc, messages
+	s.log.Infof("Consuming STOMP messages from destination: %v", s.destination)
+	return nil
+}
+
+func stFrameToMessage(f *stFrame) *service.Message {
+	msg := service.NewMessage(f.body)
+	// The headers are walked in reverse such that the first occurrence of a
+	// repeated header takes precedence.
+	for i := len(f.headers) - 1; i >= 0; i-- {
+		k, v := f.headers[i][0], f.headers[i][1]
+		switch k {
+		case "destination":
+			msg.MetaSetMut("stomp_destination", v)
+		case "message-id":
+			msg.MetaSetMut("stomp_message_id", v)
+		case "subscription":
+			msg.MetaSetMut("stomp_subscription", v)
+		case "content-type":
+			msg.MetaSetMut("stomp_content_type", v)
+		case "ack", "content-length":
+		default:
+			msg.MetaSetMut(k, v)
+		}
+	}
+	return msg
+}
+
+func (s *stInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	s.m.RLock()
+	sc, messages := s.sc, s.messages
+	s.m.RUnlock()
+
+	if sc == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var f *stFrame
+	select {
+	case f = <-messages:
+	case <-sc.closed:
+		if err := sc.failure(); !errors.Is(err, errSTConnClosed) {
+			s.log.Errorf("Lost connection due to: %v", err)
+		}
+		s.disconnect(context.Background(), sc)
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	ackID, hasAck := f.get("ack")
+	return stFrameToMessage(f), func(ctx context.Context, res error) error {
+		if !hasAck {
+			return nil
+		}
+		command := "ACK"
+		if res != nil {
+			command = "NACK"
+		}
+		if err := sc.write(newSTFrame(command, "id", ackID)); err != nil {
+			return fmt.Errorf("failed to send %v: %w", command, err)
+		}
+		return nil
+	}, nil
+}
+
+func (s *stInput) disconnect(ctx context.Context, sc *stConn) {
+	s.m.Lock()
+	if s.sc == sc {
+		s.sc, s.messages = nil, nil
+	}
+	s.m.Unlock()
+
+	sc.close(ctx)
+}
+
+func (s *stInput) Close(ctx context.Context) error {
+	s.m.RLock()
+	sc := s.sc
+	s.m.RUnlock()
+
+	if sc != nil {
+		s.disconnect(ctx, sc)
+	}
+	return nil
+}
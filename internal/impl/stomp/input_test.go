@@ -0,0 +1,136 @@
+package stomp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testInput(t *testing.T, b *testBroker, extra string) *stInput {
+	t.Helper()
+
+	conf, err := stInputSpec().ParseYAML(`
+address: `+b.addr+`
+destination: /queue/orders
+`+extra, nil)
+	require.NoError(t, err)
+
+	in, err := newSTInputFromParsed(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	return in
+}
+
+func readTestMessage(t *testing.T, in *stInput) (*service.Message, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+	return msg, ackFn
+}
+
+func TestSTOMPInput(t *testing.T) {
+	b := startTestBroker(t)
+	in := testInput(t, b, `
+login: foo
+passcode: bar
+prefetch_count: 10
+headers:
+  selector: "region = 'eu'"
+`)
+	require.NoError(t, in.Connect(context.Background()))
+	defer in.Close(context.Background())
+
+	subs := b.received("SUBSCRIBE")
+	require.Len(t, subs, 1)
+	assert.Equal(t, "client-individual", subs[0].value("ack"))
+	assert.Equal(t, "10", subs[0].value("activemq.prefetchSize"))
+	assert.Equal(t, "region = 'eu'", subs[0].value("selector"))
+	connects := b.received("CONNECT")
+	require.Len(t, connects, 1)
+	assert.Equal(t, "127.0.0.1", connects[0].value("host"))
+	assert.Equal(t, "foo", connects[0].value("login"))
+
+	b.put("/queue/orders", "first", "content-type", "text/plain", "priority", "4")
+	b.put("/queue/orders", "second")
+
+	msg, ackFn := readTestMessage(t, in)
+	body, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(body))
+
+	meta := map[string]any{}
+	require.NoError(t, msg.MetaWalkMut(func(k string, v any) error {
+		meta[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"stomp_destination":  "/queue/orders",
+		"stomp_message_id":   "msg-1",
+		"stomp_subscription": "0",
+		"stomp_content_type": "text/plain",
+		"priority":           "4",
+	}, meta)
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	// A rejected message is delivered again.
+	msg, ackFn = readTestMessage(t, in)
+	body, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(body))
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+
+	msg, ackFn = readTestMessage(t, in)
+	body, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(body))
+	require.NoError(t, ackFn(context.Background(), nil))
+
+	assert.Eventually(t, func() bool {
+		acked, nacked := b.acks()
+		return assert.ObjectsAreEqual([]string{"msg-1", "msg-3"}, acked) &&
+			assert.ObjectsAreEqual([]string{"msg-2"}, nacked)
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, in.Close(context.Background()))
+	assert.Len(t, b.received("DISCONNECT"), 1)
+}
+
+func TestSTOMPInputErrors(t *testing.T) {
+	b := startTestBroker(t)
+
+	in := testInput(t, b, "login: bad")
+	err := in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+
+	in = testInput(t, b, "")
+	in.destination = "/queue/forbidden"
+	err = in.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not authorized")
+}
+
+func TestSTOMPInputHeartbeatTimeout(t *testing.T) {
+	b := startTestBroker(t)
+	b.heartbeat = "20,0"
+
+	in := testInput(t, b, "heartbeat: 20ms")
+	require.NoError(t, in.Connect(context.Background()))
+	defer in.Close(context.Background())
+
+	// The broker never sends heartbeats, and the connection is therefore
+	// considered lost.
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	_, _, err := in.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+}
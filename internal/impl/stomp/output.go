@@ -0,0 +1,205 @@
+package stomp
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	stoFieldDestination = "destination"
+	stoFieldContentType = "content_type"
+	stoFieldHeaders     = "headers"
+	stoFieldMetadata    = "metadata"
+	stoFieldMaxInFlight = "max_in_flight"
+)
+
+func stOutputSpec() *service.ConfigSpec {
+	spec := service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Sends messages to a destination of a broker over STOMP 1.2.").
+		Description(`
+Each message is sent with a ` + "`SEND`" + ` frame that requests a receipt, and is only acknowledged once the broker has responded with the receipt.`)
+
+	for _, f := range stConnectionFields() {
+		spec = spec.Field(f)
+	}
+	return spec.
+		Field(service.NewInterpolatedStringField(stoFieldDestination).
+			Description("The destination to send messages to.").
+			Example("/queue/orders").
+			Example(`/topic/${! meta("kind") }`)).
+		Field(service.NewInterpolatedStringField(stoFieldContentType).
+			Description("The content type of each message.").
+			Default("application/octet-stream")).
+		Field(service.NewInterpolatedStringMapField(stoFieldHeaders).
+			Description("Additional headers of each message.").
+			Example(map[string]any{"persistent": "true", "JMSXGroupID": "${! this.customer_id }"}).
+			Default(map[string]any{})).
+		Field(service.NewMetadataFilterField(stoFieldMetadata).
+			Description("Specify criteria for which metadata values are sent as headers of messages.").
+			Optional()).
+		Field(service.NewIntField(stoFieldMaxInFlight).
+			Description("The maximum number of messages to have in flight at a given time.").
+			Default(64)).
+		Example(
+			"Persistent Messages",
+			"In this example messages are sent to an ActiveMQ queue as persistent messages, which are grouped by customer such that the messages of a customer are consumed in order.",
+			`
+output:
+  stomp:
+    address: localhost:61613
+    destination: /queue/orders
+    content_type: application/json
+    headers:
+      persistent: "true"
+      JMSXGroupID: ${! this.customer_id }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"stomp", stOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(stoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newSTOutputFromParsed(conf, mgr.Logger())
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type stOutput struct {
+	conn stConnDetails
+	log  *service.Logger
+
+	destination *service.InterpolatedString
+	contentType *service.InterpolatedString
+	headers     map[string]*service.InterpolatedString
+	headerKeys  []string
+	metaFilter  *service.MetadataFilter
+
+	m  sync.RWMutex
+	sc *stConn
+}
+
+func newSTOutputFromParsed(conf *service.ParsedConfig, log *service.Logger) (*stOutput, error) {
+	s := &stOutput{log: log}
+
+	var err error
+	if s.conn, err = stConnDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if s.destination, err = conf.FieldInterpolatedString(stoFieldDestination); err != nil {
+		return nil, err
+	}
+	if s.contentType, err = conf.FieldInterpolatedString(stoFieldContentType); err != nil {
+		return nil, err
+	}
+	if s.headers, err = conf.FieldInterpolatedStringMap(stoFieldHeaders); err != nil {
+		return nil, err
+	}
+	for k := range s.headers {
+		s.headerKeys = append(s.headerKeys, k)
+	}
+	sort.Strings(s.headerKeys)
+	if conf.Contains(stoFieldMetadata) {
+		if s.metaFilter, err = conf.FieldMetadataFilter(stoFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *stOutput) Connect(ctx context.Context) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.sc != nil {
+		return nil
+	}
+
+	sc, err := dialST(ctx, s.conn, nil)
+	if err != nil {
+		return err
+	}
+	s.sc = sc
+	s.log.Infof("Sending STOMP messages to address: %v", s.conn.address)
+	return nil
+}
+
+func (s *stOutput) sendFrame(msg *service.Message) (*stFrame, error) {
+	body, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Headers that are set explicitly are added first such that they take
+	// precedence over those of metadata.
+	f := newSTFrame("SEND",
+		"destination", s.destination.String(msg),
+		"content-type", s.contentType.String(msg),
+	)
+	for _, k := range s.headerKeys {
+		f.add(k, s.headers[k].String(msg))
+	}
+	_ = s.metaFilter.Walk(msg, func(key, value string) error {
+		f.add(key, value)
+		return nil
+	})
+	f.body = body
+	return f, nil
+}
+
+func (s *stOutput) Write(ctx context.Context, msg *service.Message) error {
+	s.m.RLock()
+	sc := s.sc
+	s.m.RUnlock()
+
+	if sc == nil {
+		return service.ErrNotConnected
+	}
+
+	f, err := s.sendFrame(msg)
+	if err != nil {
+		return err
+	}
+	if err := sc.writeWithReceipt(ctx, f); err != nil {
+		if sc.failure() != nil {
+			s.log.Errorf("Lost connection due to: %v", sc.failure())
+			s.disconnect(ctx, sc)
+			return service.ErrNotConnected
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *stOutput) disconnect(ctx context.Context, sc *stConn) {
+	s.m.Lock()
+	if s.sc == sc {
+		s.sc = nil
+	}
+	s.m.Unlock()
+
+	sc.close(ctx)
+}
+
+func (s *stOutput) Close(ctx context.Context) error {
+	s.m.RLock()
+	sc := s.sc
+	s.m.RUnlock()
+
+	if sc != nil {
+		s.disconnect(ctx, sc)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package stomp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSTOMPOutput(t *testing.T) {
+	b := startTestBroker(t)
+
+	conf, err := stOutputSpec().ParseYAML(`
+address: `+b.addr+`
+host: vhost
+destination: /queue/${! meta("kind") }
+content_type: application/json
+headers:
+  persistent: "true"
+  JMSXGroupID: ${! this.customer }
+metadata:
+  include_prefixes: [ app_ ]
+`, nil)
+	require.NoError(t, err)
+
+	out, err := newSTOutputFromParsed(conf, service.MockResources().Logger())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(context.Background()))
+	defer out.Close(context.Background())
+
+	msg := service.NewMessage([]byte(`{"customer":"alice"}`))
+	msg.MetaSetMut("kind", "orders")
+	msg.MetaSetMut("app_region", "eu:west")
+	require.NoError(t, out.Write(context.Background(), msg))
+
+	// The message is stored by the broker by the time the write returns.
+	msgs := b.messages("/queue/orders")
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"customer":"alice"}`, string(msgs[0].body))
+	assert.Equal(t, [][2]string{
+		{"destination", "/queue/orders"},
+		{"content-type", "application/json"},
+		{"JMSXGroupID", "alice"},
+		{"persistent", "true"},
+		{"app_region", "eu:west"},
+		{"receipt", "1"},
+		{"content-length", "20"},
+	}, msgs[0].headers)
+
+	connects := b.received("CONNECT")
+	require.Len(t, connects, 1)
+	assert.Equal(t, "vhost", connects[0].value("host"))
+	assert.Equal(t, "1.2", connects[0].value("accept-version"))
+
+	require.NoError(t, out.Close(context.Background()))
+	assert.Len(t, b.received("DISCONNECT"), 1)
+	require.ErrorIs(t, out.Write(context.Background(), msg), service.ErrNotConnected)
+}
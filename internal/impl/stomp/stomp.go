@@ -0,0 +1,523 @@
+package stomp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	stFieldAddress   = "address"
+	stFieldHost      = "host"
+	stFieldLogin     = "login"
+	stFieldPasscode  = "passcode"
+	stFieldHeartbeat = "heartbeat"
+	stFieldTimeout   = "timeout"
+	stFieldTLS       = "tls"
+
+	// The maximum size of the body of a frame that is received.
+	stMaxBodySize = 64 * 1024 * 1024
+)
+
+func stConnectionFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringField(stFieldAddress).
+			Description("The address of the broker to connect to.").
+			Example("localhost:61613"),
+		service.NewStringField(stFieldHost).
+			Description("The name of the virtual host to connect to. When empty the host of the address is used.").
+			Advanced().
+			Default(""),
+		service.NewStringField(stFieldLogin).
+			Description("An optional user to authenticate as.").
+			Default(""),
+		service.NewStringField(stFieldPasscode).
+			Description("The password of the user.").
+			Default(""),
+		service.NewDurationField(stFieldHeartbeat).
+			Description("The period of time between heartbeats that are sent to and expected from the broker, where the connection is considered lost once no frames have been received for twice the period negotiated with the broker. Set to `0s` in order to disable heartbeats.").
+			Advanced().
+			Default("10s"),
+		service.NewDurationField(stFieldTimeout).
+			Description("The maximum period of time to wait for the broker to accept a connection or respond with a receipt.").
+			Advanced().
+			Default("10s"),
+		service.NewTLSToggledField(stFieldTLS),
+	}
+}
+
+type stConnDetails struct {
+	address   string
+	host      string
+	login     string
+	passcode  string
+	heartbeat time.Duration
+	timeout   time.Duration
+	tlsConf   *tls.Config
+}
+
+func stConnDetailsFromParsed(conf *service.ParsedConfig) (d stConnDetails, err error) {
+	if d.address, err = conf.FieldString(stFieldAddress); err != nil {
+		return
+	}
+	if d.address == "" {
+		err = errors.New("an address must be specified")
+		return
+	}
+	if d.host, err = conf.FieldString(stFieldHost); err != nil {
+		return
+	}
+	if d.host == "" {
+		if d.host, _, err = net.SplitHostPort(d.address); err != nil {
+			err = fmt.Errorf("failed to parse address: %w", err)
+			return
+		}
+	}
+	if d.login, err = conf.FieldString(stFieldLogin); err != nil {
+		return
+	}
+	if d.passcode, err = conf.FieldString(stFieldPasscode); err != nil {
+		return
+	}
+	if d.heartbeat, err = conf.FieldDuration(stFieldHeartbeat); err != nil {
+		return
+	}
+	if d.timeout, err = conf.FieldDuration(stFieldTimeout); err != nil {
+		return
+	}
+	var tlsEnabled bool
+	if d.tlsConf, tlsEnabled, err = conf.FieldTLSToggled(stFieldTLS); err != nil {
+		return
+	}
+	if !tlsEnabled {
+		d.tlsConf = nil
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// stFrame is a frame of the STOMP protocol, where the headers are kept in the
+// order in which they were received as the first of a repeated header takes
+// precedence.
+type stFrame struct {
+	command string
+	headers [][2]string
+	body    []byte
+}
+
+func newSTFrame(command string, headers ...string) *stFrame {
+	f := &stFrame{command: command}
+	for i := 0; i+1 < len(headers); i += 2 {
+		f.add(headers[i], headers[i+1])
+	}
+	return f
+}
+
+func (f *stFrame) add(key, value string) {
+	f.headers = append(f.headers, [2]string{key, value})
+}
+
+func (f *stFrame) get(key string) (string, bool) {
+	for _, kv := range f.headers {
+		if kv[0] == key {
+			return kv[1], true
+		}
+	}
+	return "", false
+}
+
+func (f *stFrame) value(key string) string {
+	v, _ := f.get(key)
+	return v
+}
+
+// The values of headers are escaped in all frames other than the CONNECT and
+// CONNECTED frames.
+var (
+	stHeaderEscaper   = strings.NewReplacer(`\`, `\\`, "\r", `\r`, "\n", `\n`, ":", `\c`)
+	stHeaderUnescaper = strings.NewReplacer(`\\`, `\`, `\r`, "\r", `\n`, "\n", `\c`, ":")
+)
+
+func stEscapes(command string) bool {
+	return command != "CONNECT" && command != "CONNECTED"
+}
+
+func (f *stFrame) writeTo(w *bufio.Writer) error {
+	escape := stEscapes(f.command)
+	_, _ = w.WriteString(f.command)
+	_ = w.WriteByte('\n')
+	for _, kv := range f.headers {
+		k, v := kv[0], kv[1]
+		if escape {
+			k, v = stHeaderEscaper.Replace(k), stHeaderEscaper.Replace(v)
+		}
+		_, _ = w.WriteString(k)
+		_ = w.WriteByte(':')
+		_, _ = w.WriteString(v)
+		_ = w.WriteByte('\n')
+	}
+	if len(f.body) > 0 {
+		if _, exists := f.get("content-length"); !exists {
+			_, _ = w.WriteString("content-length:" + strconv.Itoa(len(f.body)) + "\n")
+		}
+	}
+	_ = w.WriteByte('\n')
+	_, _ = w.Write(f.body)
+	_ = w.WriteByte(0)
+	return w.Flush()
+}
+
+func stReadLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readSTFrame reads the next frame, skipping over the empty lines that are
+// sent as heartbeats.
+func readSTFrame(r *bufio.Reader) (*stFrame, error) {
+	var command string
+	for command == "" {
+		var err error
+		if command, err = stReadLine(r); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &stFrame{command: command}
+	escaped := stEscapes(command)
+	for {
+		line, err := stReadLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header: %q", line)
+		}
+		if escaped {
+			k, v = stHeaderUnescaper.Replace(k), stHeaderUnescaper.Replace(v)
+		}
+		f.add(k, v)
+	}
+
+	if lengthStr, exists := f.get("content-length"); exists {
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length < 0 || length > stMaxBodySize {
+			return nil, fmt.Errorf("invalid content length: %v", lengthStr)
+		}
+		f.body = make([]byte, length)
+		if _, err := io.ReadFull(r, f.body); err != nil {
+			return nil, err
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0 {
+			return nil, errors.New("frame body is not terminated by a null octet")
+		}
+		return f, nil
+	}
+
+	body, err := r.ReadBytes(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > stMaxBodySize {
+		return nil, errors.New("frame body exceeds maximum size")
+	}
+	f.body = body[:len(body)-1]
+	return f, nil
+}
+
+//------------------------------------------------------------------------------
+
+// stBrokerError is the error of an ERROR frame that is sent by a broker.
+type stBrokerError struct {
+	message string
+	body    []byte
+}
+
+func (e *stBrokerError) Error() string {
+	if len(e.body) > 0 {
+		return fmt.Sprintf("broker error: %v: %s", e.message, bytes.TrimSpace(e.body))
+	}
+	return fmt.Sprintf("broker error: %v", e.message)
+}
+
+var errSTConnClosed = errors.New("connection closed")
+
+// stConn is a connection to a broker, which sends and expects heartbeats at
+// the negotiated intervals and matches receipts with the frames that request
+// them.
+type stConn struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	timeout  time.Duration
+	messages chan *stFrame
+
+	writeMut sync.Mutex
+	w        *bufio.Writer
+
+	receiptID int64
+
+	mut      sync.Mutex
+	receipts map[string]chan struct{}
+	err      error
+	closed   chan struct{}
+	closing  chan struct{}
+	once     sync.Once
+}
+
+// stHeartbeats returns the intervals at which heartbeats are sent and expected
+// as negotiated with the heart-beat header of a CONNECTED frame.
+func stHeartbeats(desired time.Duration, header string) (send, receive time.Duration) {
+	if desired <= 0 || header == "" {
+		return 0, 0
+	}
+	sx, sy, _ := strings.Cut(header, ",")
+	serverSend, _ := strconv.Atoi(strings.TrimSpace(sx))
+	serverReceive, _ := strconv.Atoi(strings.TrimSpace(sy))
+
+	if serverReceive > 0 {
+		if send = time.Duration(serverReceive) * time.Millisecond; send < desired {
+			send = desired
+		}
+	}
+	if serverSend > 0 {
+		if receive = time.Duration(serverSend) * time.Millisecond; receive < desired {
+			receive = desired
+		}
+	}
+	return
+}
+
+// dialST connects to a broker, where any MESSAGE frames that are received are
+// sent to the provided channel.
+func dialST(ctx context.Context, d stConnDetails, messages chan *stFrame) (*stConn, error) {
+	dialer := &net.Dialer{Timeout: d.timeout}
+	var netConn net.Conn
+	var err error
+	if d.tlsConf != nil {
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: d.tlsConf}).DialContext(ctx, "tcp", d.address)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", d.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &stConn{
+		conn:     netConn,
+		r:        bufio.NewReader(netConn),
+		w:        bufio.NewWriter(netConn),
+		timeout:  d.timeout,
+		messages: messages,
+		receipts: map[string]chan struct{}{},
+		closed:   make(chan struct{}),
+		closing:  make(chan struct{}),
+	}
+
+	heartbeatMillis := strconv.FormatInt(d.heartbeat.Milliseconds(), 10)
+	connect := newSTFrame("CONNECT",
+		"accept-version", "1.2",
+		"host", d.host,
+		"heart-beat", heartbeatMillis+","+heartbeatMillis,
+	)
+	if d.login != "" {
+		connect.add("login", d.login)
+		connect.add("passcode", d.passcode)
+	}
+
+	_ = netConn.SetDeadline(time.Now().Add(d.timeout))
+	if err := connect.writeTo(c.w); err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	res, err := readSTFrame(c.r)
+	if err != nil {
+		_ = netConn.Close()
+		return nil, err
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	switch res.command {
+	case "CONNECTED":
+	case "ERROR":
+		_ = netConn.Close()
+		return nil, &stBrokerError{message: res.value("message"), body: res.body}
+	default:
+		_ = netConn.Close()
+		return nil, fmt.Errorf("unexpected response to connect: %v", res.command)
+	}
+	if v := res.value("version"); v != "1.2" {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("broker does not support STOMP 1.2, negotiated version: %q", v)
+	}
+
+	send, receive := stHeartbeats(d.heartbeat, res.value("heart-beat"))
+	go c.readLoop(receive * 2)
+	if send > 0 {
+		go c.heartbeatLoop(send)
+	}
+	return c, nil
+}
+
+func (c *stConn) readLoop(readTimeout time.Duration) {
+	for {
+		if readTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(readTimeout))
+		}
+		f, err := readSTFrame(c.r)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		switch f.command {
+		case "MESSAGE":
+			if c.messages == nil {
+				continue
+			}
+			// Messages that are received once the connection is closing are
+			// dropped, and are delivered again by the broker as they are never
+			// acknowledged.
+			select {
+			case c.messages <- f:
+			case <-c.closing:
+			case <-c.closed:
+				return
+			}
+		case "RECEIPT":
+			id := f.value("receipt-id")
+			c.mut.Lock()
+			if ch, exists := c.receipts[id]; exists {
+				delete(c.receipts, id)
+				close(ch)
+			}
+			c.mut.Unlock()
+		case "ERROR":
+			c.fail(&stBrokerError{message: f.value("message"), body: f.body})
+			return
+		}
+	}
+}
+
+func (c *stConn) heartbeatLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeMut.Lock()
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+			_ = c.w.WriteByte('\n')
+			err := c.w.Flush()
+			c.writeMut.Unlock()
+			if err != nil {
+				c.fail(err)
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// fail closes the connection with the error that caused it to close, which is
+// returned by all subsequent writes.
+func (c *stConn) fail(err error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.closed)
+	_ = c.conn.Close()
+}
+
+func (c *stConn) failure() error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.err
+}
+
+func (c *stConn) write(f *stFrame) error {
+	if err := c.failure(); err != nil {
+		return err
+	}
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	if err := f.writeTo(c.w); err != nil {
+		c.fail(err)
+		return err
+	}
+	return nil
+}
+
+// writeWithReceipt writes a frame and waits for the broker to confirm that it
+// has been processed with a receipt.
+func (c *stConn) writeWithReceipt(ctx context.Context, f *stFrame) error {
+	id := strconv.FormatInt(atomic.AddInt64(&c.receiptID, 1), 10)
+	f.add("receipt", id)
+
+	ch := make(chan struct{})
+	c.mut.Lock()
+	c.receipts[id] = ch
+	c.mut.Unlock()
+	defer func() {
+		c.mut.Lock()
+		delete(c.receipts, id)
+		c.mut.Unlock()
+	}()
+
+	if err := c.write(f); err != nil {
+		return err
+	}
+
+	timeout := time.NewTimer(c.timeout)
+	defer timeout.Stop()
+	select {
+	case <-ch:
+		return nil
+	case <-c.closed:
+		return c.failure()
+	case <-timeout.C:
+		return fmt.Errorf("timed out waiting for receipt of %v frame", f.command)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close disconnects gracefully by waiting for the receipt of a DISCONNECT
+// frame, which ensures that all frames sent before it have been processed.
+func (c *stConn) close(ctx context.Context) {
+	c.once.Do(func() { close(c.closing) })
+	if c.failure() == nil {
+		_ = c.writeWithReceipt(ctx, newSTFrame("DISCONNECT"))
+	}
+	c.fail(errSTConnClosed)
+}
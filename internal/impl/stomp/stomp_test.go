@@ -0,0 +1,77 @@
+package stomp
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSTOMPFrameRoundTrip(t *testing.T) {
+	f := newSTFrame("SEND", "destination", "/queue/a:b", "note", "line\none\\two")
+	f.body = []byte("hello\x00world")
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	require.NoError(t, f.writeTo(w))
+	assert.Equal(t, "SEND\ndestination:/queue/a\\cb\nnote:line\\none\\\\two\ncontent-length:11\n\nhello\x00world\x00", buf.String())
+
+	// Heartbeats between frames are skipped.
+	r := bufio.NewReader(strings.NewReader("\n\r\n" + buf.String() + "\nMESSAGE\nfoo:bar\nfoo:baz\n\nno length\x00"))
+	res, err := readSTFrame(r)
+	require.NoError(t, err)
+	assert.Equal(t, "SEND", res.command)
+	assert.Equal(t, "/queue/a:b", res.value("destination"))
+	assert.Equal(t, "line\none\\two", res.value("note"))
+	assert.Equal(t, "hello\x00world", string(res.body))
+
+	res, err = readSTFrame(r)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE", res.command)
+	assert.Equal(t, "bar", res.value("foo"))
+	assert.Equal(t, "no length", string(res.body))
+}
+
+func TestSTOMPConnectFrameNotEscaped(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newSTFrame("CONNECT", "passcode", `a:b\c`).writeTo(bufio.NewWriter(&buf)))
+	assert.Equal(t, "CONNECT\npasscode:a:b\\c\n\n\x00", buf.String())
+
+	res, err := readSTFrame(bufio.NewReader(strings.NewReader("CONNECTED\nserver:a\\cb\n\n\x00")))
+	require.NoError(t, err)
+	assert.Equal(t, `a\cb`, res.value("server"))
+}
+
+func TestSTOMPFrameErrors(t *testing.T) {
+	for _, input := range []string{
+		"SEND\nnocolon\n\n\x00",
+		"SEND\ncontent-length:nope\n\n\x00",
+		"SEND\ncontent-length:2\n\nabc\x00",
+		"SEND\n\nunterminated",
+	} {
+		_, err := readSTFrame(bufio.NewReader(strings.NewReader(input)))
+		require.Error(t, err, input)
+	}
+}
+
+func TestSTOMPHeartbeats(t *testing.T) {
+	for _, test := range []struct {
+		desired       time.Duration
+		header        string
+		send, receive time.Duration
+	}{
+		{desired: time.Second, header: "0,0"},
+		{desired: 0, header: "1000,1000"},
+		{desired: time.Second, header: ""},
+		{desired: time.Second, header: "500,5000", send: time.Second * 5, receive: time.Second},
+		{desired: time.Second * 10, header: "2000,0", receive: time.Second * 10},
+	} {
+		send, receive := stHeartbeats(test.desired, test.header)
+		assert.Equal(t, test.send, send, test.header)
+		assert.Equal(t, test.receive, receive, test.header)
+	}
+}
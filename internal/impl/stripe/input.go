@@ -0,0 +1,495 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/checkpoint"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	siFieldAPIKey       = "api_key"
+	siFieldEventTypes   = "event_types"
+	siFieldStartFrom    = "start_from"
+	siFieldPollInterval = "poll_interval"
+	siFieldPageSize     = "page_size"
+	siFieldCache        = "cache"
+	siFieldCacheKey     = "cache_key"
+	siFieldRateLimit    = "rate_limit"
+	siFieldAPIURL       = "api_url"
+
+	// The maximum number of pages that can be in flight before reads block.
+	stripeCheckpointLimit = 64
+
+	// The maximum period of time to wait between retries of requests that are
+	// rate limited without a Retry-After header.
+	stripeMaxBackoff = time.Second * 30
+)
+
+func stripeInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Services").
+		Summary("Consumes the events of a Stripe account in the order that they were created.").
+		Description(`
+Pages through the [events](https://stripe.com/docs/api/events) of an account from a cursor, which is the ID of the latest event consumed, and consumes each page of events as a batch. Once all events have been consumed the input polls for new events every `+"`poll_interval`"+`.
+
+When `+"`cache`"+` is set the cursor is stored within the cache once each page of events has been delivered, along with all pages before it, and the input resumes from the stored cursor when restarted. Otherwise, or when no cursor is stored, the input starts from `+"`start_from`"+`. Stripe retains events for 30 days, and therefore the input can not resume from a cursor that is older.
+
+### Rate Limits
+
+Requests that are rate limited by Stripe are retried once the period of the `+"`Retry-After`"+` header has elapsed, or with an exponential backoff when there is no header. Requests can be paced ahead of the rate limits of Stripe with a `+"[`rate_limit`](/docs/components/rate_limits/about)"+` resource, which is useful when other clients share the API key.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- stripe_event_id
+- stripe_event_type
+- stripe_created (unix timestamp)
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(siFieldAPIKey).
+			Description("A secret or restricted API key with read access to events.")).
+		Field(service.NewStringListField(siFieldEventTypes).
+			Description("The types of events to consume, of which there can be up to 20. When a single type is specified `*` can be used as a wildcard for any part of the type. When empty all events are consumed.").
+			Example([]string{"charge.succeeded", "charge.refunded"}).
+			Example([]string{"customer.subscription.*"}).
+			Default([]string{})).
+		Field(service.NewStringEnumField(siFieldStartFrom, "latest", "earliest").
+			Description("The event to start from when there is no stored cursor, where `latest` consumes only events created after the input starts, and `earliest` consumes all events retained.").
+			Default("latest")).
+		Field(service.NewDurationField(siFieldPollInterval).
+			Description("The period of time between each poll for new events once all events have been consumed.").
+			Default("1m")).
+		Field(service.NewIntField(siFieldPageSize).
+			Description("The maximum number of events of each page, between 1 and 100.").
+			Advanced().
+			Default(100)).
+		Field(service.NewStringField(siFieldCache).
+			Description("A [cache resource](/docs/components/caches/about) to store the cursor within. When empty the cursor is not stored.").
+			Default("")).
+		Field(service.NewStringField(siFieldCacheKey).
+			Description("The key to store the cursor at within the cache.").
+			Advanced().
+			Default("stripe_events_cursor")).
+		Field(service.NewStringField(siFieldRateLimit).
+			Description("An optional [rate limit](/docs/components/rate_limits/about) to throttle requests by.").
+			Default("")).
+		Field(service.NewStringField(siFieldAPIURL).
+			Description("The URL of the Stripe API.").
+			Advanced().
+			Default("https://api.stripe.com")).
+		Example(
+			"Invoice Events",
+			"In this example the invoice events of an account are consumed from the earliest event retained, where the cursor is stored within a Redis cache.",
+			`
+input:
+  stripe:
+    api_key: "${STRIPE_API_KEY}"
+    event_types: [ "invoice.*" ]
+    start_from: earliest
+    cache: cursors
+
+cache_resources:
+  - label: cursors
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput(
+		"stripe", stripeInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newStripeInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatched(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	raw     []byte
+}
+
+type stripeInput struct {
+	apiKey       string
+	apiURL       string
+	eventTypes   []string
+	earliest     bool
+	pollInterval time.Duration
+	pageSize     int
+	cache        string
+	cacheKey     string
+	rateLimit    string
+	http         *http.Client
+	mgr          *service.Resources
+	log          *service.Logger
+
+	checkpoints *checkpoint.Capped
+	commitMut   sync.Mutex
+
+	mut          sync.Mutex
+	connected    bool
+	cursorLoaded bool
+	cursor       string
+	caughtUp     bool
+	lastPoll     time.Time
+}
+
+func newStripeInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*stripeInput, error) {
+	s := &stripeInput{
+		http:        http.DefaultClient,
+		mgr:         mgr,
+		log:         mgr.Logger(),
+		checkpoints: checkpoint.NewCapped(stripeCheckpointLimit),
+	}
+
+	var err error
+	if s.apiKey, err = conf.FieldString(siFieldAPIKey); err != nil {
+		return nil, err
+	}
+	if s.eventTypes, err = conf.FieldStringList(siFieldEventTypes); err != nil {
+		return nil, err
+	}
+	if len(s.eventTypes) > 20 {
+		return nil, fmt.Errorf("%v must not contain more than 20 types", siFieldEventTypes)
+	}
+	startFrom, err := conf.FieldString(siFieldStartFrom)
+	if err != nil {
+		return nil, err
+	}
+	s.earliest = startFrom == "earliest"
+	if s.pollInterval, err = conf.FieldDuration(siFieldPollInterval); err != nil {
+		return nil, err
+	}
+	if s.pageSize, err = conf.FieldInt(siFieldPageSize); err != nil {
+		return nil, err
+	}
+	if s.pageSize < 1 || s.pageSize > 100 {
+		return nil, fmt.Errorf("%v must be between 1 and 100", siFieldPageSize)
+	}
+	if s.cache, err = conf.FieldString(siFieldCache); err != nil {
+		return nil, err
+	}
+	if s.cache != "" && !mgr.HasCache(s.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", s.cache)
+	}
+	if s.cacheKey, err = conf.FieldString(siFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if s.rateLimit, err = conf.FieldString(siFieldRateLimit); err != nil {
+		return nil, err
+	}
+	if s.rateLimit != "" && !mgr.HasRateLimit(s.rateLimit) {
+		return nil, fmt.Errorf("rate limit resource '%v' was not found", s.rateLimit)
+	}
+	if s.apiURL, err = conf.FieldString(siFieldAPIURL); err != nil {
+		return nil, err
+	}
+	s.apiURL = strings.TrimSuffix(s.apiURL, "/")
+	return s, nil
+}
+
+func (s *stripeInput) waitForAccess(ctx context.Context) error {
+	if s.rateLimit == "" {
+		return nil
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := s.mgr.AccessRateLimit(ctx, s.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			s.log.Errorf("Rate limit error: %v\n", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// listEvents obtains a page of events, which are ordered from the newest to
+// the oldest, and whether there are more events beyond the page.
+func (s *stripeInput) listEvents(ctx context.Context, query url.Values) ([]*stripeEvent, bool, error) {
+	query.Set("limit", strconv.Itoa(s.pageSize))
+	if len(s.eventTypes) == 1 {
+		query.Set("type", s.eventTypes[0])
+	} else {
+		for _, t := range s.eventTypes {
+			query.Add("types[]", t)
+		}
+	}
+
+	backoff := time.Second
+	for {
+		if err := s.waitForAccess(ctx); err != nil {
+			return nil, false, err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.apiURL+"/v1/events?"+query.Encode(), nil)
+		if err != nil {
+			return nil, false, err
+		}
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+		res, err := s.http.Do(req)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			res.Body.Close()
+			wait := backoff
+			if secs, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+				wait = time.Duration(secs) * time.Second
+			} else if backoff *= 2; backoff > stripeMaxBackoff {
+				backoff = stripeMaxBackoff
+			}
+			s.log.Debugf("Request rate limited, retrying in %v", wait)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return nil, false, ctx.Err()
+			}
+			continue
+		}
+
+		var list struct {
+			Data    []json.RawMessage `json:"data"`
+			HasMore bool              `json:"has_more"`
+			Error   *struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&list)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			if list.Error != nil {
+				return nil, false, fmt.Errorf("request failed with status %v: %v: %v", res.StatusCode, list.Error.Type, list.Error.Message)
+			}
+			return nil, false, fmt.Errorf("request failed with status %v", res.StatusCode)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode events: %w", err)
+		}
+
+		events := make([]*stripeEvent, 0, len(list.Data))
+		for _, raw := range list.Data {
+			e := &stripeEvent{raw: raw}
+			if err := json.Unmarshal(raw, e); err != nil {
+				return nil, false, fmt.Errorf("failed to decode event: %w", err)
+			}
+			events = append(events, e)
+		}
+		return events, list.HasMore, nil
+	}
+}
+
+// oldestEvent pages through all events in order to find the oldest event, or
+// returns nil when there are no events.
+func (s *stripeInput) oldestEvent(ctx context.Context) (*stripeEvent, error) {
+	var oldest *stripeEvent
+	for {
+		query := url.Values{}
+		if oldest != nil {
+			query.Set("starting_after", oldest.ID)
+		}
+		events, hasMore, err := s.listEvents(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			oldest = events[len(events)-1]
+		}
+		if !hasMore {
+			return oldest, nil
+		}
+	}
+}
+
+func (s *stripeInput) loadCursor(ctx context.Context) error {
+	if s.cursorLoaded {
+		return nil
+	}
+	if s.cache != "" {
+		var cursor []byte
+		var cErr error
+		if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+			cursor, cErr = c.Get(ctx, s.cacheKey)
+		}); err != nil {
+			return err
+		}
+		if cErr != nil && !errors.Is(cErr, service.ErrKeyNotFound) {
+			return fmt.Errorf("failed to obtain stored cursor: %w", cErr)
+		}
+		s.cursor = string(cursor)
+	}
+
+	if s.cursor == "" && !s.earliest {
+		events, _, err := s.listEvents(ctx, url.Values{})
+		if err != nil {
+			return fmt.Errorf("failed to obtain latest event: %w", err)
+		}
+		if len(events) > 0 {
+			s.cursor = events[0].ID
+		} else {
+			// Any event created from now on is new, including the first.
+			s.earliest = true
+		}
+	}
+	s.cursorLoaded = true
+	return nil
+}
+
+func (s *stripeInput) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.connected {
+		return nil
+	}
+	if err := s.loadCursor(ctx); err != nil {
+		return err
+	}
+	s.connected = true
+	return nil
+}
+
+// nextPage obtains the next events after the cursor from the oldest to the
+// newest, and blocks until it is time to poll when all events have been
+// consumed.
+func (s *stripeInput) nextPage(ctx context.Context) ([]*stripeEvent, error) {
+	for {
+		if s.caughtUp {
+			if wait := time.Until(s.lastPoll.Add(s.pollInterval)); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+
+		var events []*stripeEvent
+		var hasMore bool
+		if s.cursor == "" {
+			oldest, err := s.oldestEvent(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if oldest != nil {
+				events, hasMore = []*stripeEvent{oldest}, true
+			}
+		} else {
+			var err error
+			if events, hasMore, err = s.listEvents(ctx, url.Values{"ending_before": []string{s.cursor}}); err != nil {
+				return nil, err
+			}
+		}
+
+		if s.caughtUp = !hasMore; s.caughtUp {
+			s.lastPoll = time.Now()
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		for l, r := 0, len(events)-1; l < r; l, r = l+1, r-1 {
+			events[l], events[r] = events[r], events[l]
+		}
+		return events, nil
+	}
+}
+
+func (s *stripeInput) storeCursor(ctx context.Context, cursor string) error {
+	var cErr error
+	if err := s.mgr.AccessCache(ctx, s.cache, func(c service.Cache) {
+		cErr = c.Set(ctx, s.cacheKey, []byte(cursor), nil)
+	}); err != nil {
+		return err
+	}
+	if cErr != nil {
+		return fmt.Errorf("failed to store cursor: %w", cErr)
+	}
+	return nil
+}
+
+func (s *stripeInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if !s.connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	events, err := s.nextPage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batch := make(service.MessageBatch, 0, len(events))
+	for _, e := range events {
+		msg := service.NewMessage(e.raw)
+		msg.MetaSetMut("stripe_event_id", e.ID)
+		msg.MetaSetMut("stripe_event_type", e.Type)
+		msg.MetaSetMut("stripe_created", strconv.FormatInt(e.Created, 10))
+		batch = append(batch, msg)
+	}
+
+	cursor := events[len(events)-1].ID
+	release, err := s.checkpoints.Track(ctx, cursor, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.cursor = cursor
+
+	return batch, func(ctx context.Context, err error) error {
+		s.commitMut.Lock()
+		defer s.commitMut.Unlock()
+
+		highest := release()
+		if highest == nil || s.cache == "" {
+			return nil
+		}
+		return s.storeCursor(ctx, highest.(string))
+	}, nil
+}
+
+func (s *stripeInput) Close(ctx context.Context) error {
+	s.mut.Lock()
+	s.connected = false
+	s.mut.Unlock()
+	return nil
+}
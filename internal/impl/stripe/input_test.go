@@ -0,0 +1,275 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// testStripe is a fake of the events API of Stripe, which rate limits the
+// first request.
+type testStripe struct {
+	t   *testing.T
+	url string
+
+	mut         sync.Mutex
+	events      []*stripeEvent
+	requests    int
+	typeFilters []string
+}
+
+func startTestStripe(t *testing.T) *testStripe {
+	t.Helper()
+
+	s := &testStripe{t: t}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.url = srv.URL
+	return s
+}
+
+func (s *testStripe) addEvents(eventType string, n int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for i := 0; i < n; i++ {
+		s.events = append(s.events, &stripeEvent{
+			ID:      fmt.Sprintf("evt_%02d", len(s.events)+1),
+			Type:    eventType,
+			Created: int64(1700000000 + len(s.events)),
+		})
+	}
+}
+
+func (s *testStripe) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	assert.Equal(s.t, "Bearer sk_test", r.Header.Get("Authorization"))
+	assert.Equal(s.t, "/v1/events", r.URL.Path)
+
+	if s.requests++; s.requests == 1 {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"type":"rate_limit_error"}}`))
+		return
+	}
+
+	q := r.URL.Query()
+	s.typeFilters = append(s.typeFilters, q.Get("type")+fmt.Sprint(q["types[]"]))
+
+	var list []*stripeEvent
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if q.Get("type") == "" || q.Get("type") == s.events[i].Type {
+			list = append(list, s.events[i])
+		}
+	}
+	indexOf := func(id string) int {
+		for i, e := range list {
+			if e.ID == id {
+				return i
+			}
+		}
+		s.t.Errorf("event %v not found", id)
+		return 0
+	}
+
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	start, end := 0, len(list)
+	switch {
+	case q.Get("starting_after") != "":
+		start = indexOf(q.Get("starting_after")) + 1
+	case q.Get("ending_before") != "":
+		end = indexOf(q.Get("ending_before"))
+		if start = end - limit; start < 0 {
+			start = 0
+		}
+	}
+
+	var page []*stripeEvent
+	hasMore := false
+	if q.Get("ending_before") != "" {
+		page, hasMore = list[start:end], start > 0
+	} else {
+		if start+limit < end {
+			end, hasMore = start+limit, true
+		}
+		page = list[start:end]
+	}
+
+	data := []any{}
+	for _, e := range page {
+		data = append(data, map[string]any{"id": e.ID, "object": "event", "type": e.Type, "created": e.Created})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"object": "list", "data": data, "has_more": hasMore})
+}
+
+func testInput(t *testing.T, s *testStripe, mgr *service.Resources, extra string) *stripeInput {
+	t.Helper()
+
+	conf, err := stripeInputSpec().ParseYAML(`
+api_key: sk_test
+api_url: `+s.url+`
+page_size: 2
+poll_interval: 10ms
+`+extra, nil)
+	require.NoError(t, err)
+
+	in, err := newStripeInputFromParsed(conf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, in.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = in.Close(context.Background())
+	})
+	return in
+}
+
+func readTestBatch(t *testing.T, in *stripeInput) ([]string, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	batch, ackFn, err := in.ReadBatch(ctx)
+	require.NoError(t, err)
+
+	var ids []string
+	for _, msg := range batch {
+		id, _ := msg.MetaGet("stripe_event_id")
+		ids = append(ids, id)
+	}
+	return ids, ackFn
+}
+
+func storedCursor(t *testing.T, mgr *service.Resources) string {
+	t.Helper()
+
+	var v []byte
+	var cErr error
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		v, cErr = c.Get(context.Background(), "stripe_events_cursor")
+	}))
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return ""
+	}
+	require.NoError(t, cErr)
+	return string(v)
+}
+
+func TestStripeInputEarliest(t *testing.T) {
+	s := startTestStripe(t)
+	s.addEvents("invoice.paid", 5)
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+
+	in := testInput(t, s, mgr, `
+start_from: earliest
+cache: foocache
+`)
+
+	var batches [][]string
+	for i := 0; i < 3; i++ {
+		ids, ackFn := readTestBatch(t, in)
+		batches = append(batches, ids)
+		require.NoError(t, ackFn(context.Background(), nil))
+	}
+	assert.Equal(t, [][]string{
+		{"evt_01"},
+		{"evt_02", "evt_03"},
+		{"evt_04", "evt_05"},
+	}, batches)
+	assert.Equal(t, "evt_05", storedCursor(t, mgr))
+
+	// New events are consumed once polled.
+	s.addEvents("invoice.paid", 1)
+	ids, ackFn := readTestBatch(t, in)
+	assert.Equal(t, []string{"evt_06"}, ids)
+	require.NoError(t, ackFn(context.Background(), nil))
+	assert.Equal(t, "evt_06", storedCursor(t, mgr))
+}
+
+func TestStripeInputLatest(t *testing.T) {
+	s := startTestStripe(t)
+	s.addEvents("invoice.paid", 2)
+	s.addEvents("charge.succeeded", 1)
+
+	in := testInput(t, s, service.MockResources(), `
+event_types: [ invoice.paid ]
+`)
+
+	s.addEvents("charge.succeeded", 1)
+	s.addEvents("invoice.paid", 1)
+
+	batch, _, err := in.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	b, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"created":1700000004,"id":"evt_05","object":"event","type":"invoice.paid"}`, string(b))
+	eventType, _ := batch[0].MetaGet("stripe_event_type")
+	assert.Equal(t, "invoice.paid", eventType)
+	created, _ := batch[0].MetaGet("stripe_created")
+	assert.Equal(t, "1700000004", created)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	assert.Equal(t, []string{"invoice.paid[]", "invoice.paid[]"}, s.typeFilters)
+}
+
+func TestStripeInputResume(t *testing.T) {
+	s := startTestStripe(t)
+	s.addEvents("invoice.paid", 6)
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "stripe_events_cursor", []byte("evt_02"), nil))
+	}))
+
+	in := testInput(t, s, mgr, `
+cache: foocache
+event_types: [ invoice.paid, invoice.voided ]
+`)
+
+	first, ackFirst := readTestBatch(t, in)
+	second, ackSecond := readTestBatch(t, in)
+	assert.Equal(t, []string{"evt_03", "evt_04"}, first)
+	assert.Equal(t, []string{"evt_05", "evt_06"}, second)
+
+	// The cursor only advances once all pages before it are delivered.
+	require.NoError(t, ackSecond(context.Background(), nil))
+	assert.Equal(t, "evt_02", storedCursor(t, mgr))
+	require.NoError(t, ackFirst(context.Background(), nil))
+	assert.Equal(t, "evt_06", storedCursor(t, mgr))
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	assert.Equal(t, "[invoice.paid invoice.voided]", s.typeFilters[0])
+}
+
+func TestStripeInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{conf: "api_key: foo\npage_size: 101", errStr: "page_size"},
+		{conf: "api_key: foo\ncache: nope", errStr: "cache resource 'nope'"},
+		{conf: "api_key: foo\nrate_limit: nope", errStr: "rate limit resource 'nope'"},
+	} {
+		conf, err := stripeInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newStripeInputFromParsed(conf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errStr)
+	}
+}
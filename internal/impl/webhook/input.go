@@ -0,0 +1,412 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	whiFieldProvider  = "provider"
+	whiFieldSecret    = "secret"
+	whiFieldAddress   = "address"
+	whiFieldPath      = "path"
+	whiFieldTolerance = "timestamp_tolerance"
+	whiFieldCache     = "cache"
+	whiFieldCacheTTL  = "cache_ttl"
+	whiFieldTimeout   = "timeout"
+	whiFieldMaxBody   = "max_body_bytes"
+	whiFieldCertFile  = "cert_file"
+	whiFieldKeyFile   = "key_file"
+)
+
+func webhookInputSpec() *service.ConfigSpec {
+	var providers []string
+	for k := range whProviders {
+		providers = append(providers, k)
+	}
+	sort.Strings(providers)
+
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.9.0").
+		Categories("Network").
+		Summary("Receives webhooks of a provider over HTTP(S), and consumes the events of requests that are verified to have been sent by the provider.").
+		Description(`
+Each request is verified with the scheme of the provider and the `+"`secret`"+` of the webhook, and requests that fail verification are rejected with a 401 response. The body of each verified request is consumed as a message, and a 200 response is returned once the message has been delivered. When delivery fails, or does not complete within the `+"`timeout`"+`, a 500 response is returned in order that the provider retries the request.
+
+### Providers
+
+| Provider | Verification | Event type | Delivery ID |
+|---|---|---|---|
+| `+"`github`"+` | HMAC SHA256 signature of `+"`X-Hub-Signature-256`"+` | `+"`X-GitHub-Event`"+` header | `+"`X-GitHub-Delivery`"+` header |
+| `+"`gitlab`"+` | Secret token of `+"`X-Gitlab-Token`"+` | `+"`X-Gitlab-Event`"+` header | `+"`X-Gitlab-Event-UUID`"+` header |
+| `+"`jira`"+` | HMAC SHA256 signature of `+"`X-Hub-Signature`"+` | `+"`webhookEvent`"+` field | `+"`X-Atlassian-Webhook-Identifier`"+` header |
+| `+"`slack`"+` | HMAC SHA256 signature and timestamp of `+"`X-Slack-Signature`"+` | `+"`event.type`"+` or `+"`type`"+` field | `+"`event_id`"+` field |
+| `+"`stripe`"+` | HMAC SHA256 signatures and timestamp of `+"`Stripe-Signature`"+` | `+"`type`"+` field | `+"`id`"+` field |
+
+The [URL verification](https://api.slack.com/events/url_verification) requests of Slack are answered with their challenge and are not consumed.
+
+### Replays
+
+Requests of providers that sign a timestamp are rejected when the timestamp differs from the current time by more than `+"`timestamp_tolerance`"+`. When `+"`cache`"+` is set the delivery ID of each request is added to the cache, and requests with a delivery ID that already exists within the cache are answered with a 200 response but are not consumed, which prevents both replayed requests and retries of requests that were delivered from being consumed more than once. The delivery ID is removed from the cache when delivery fails in order that the retry of the request is consumed.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```"+`
+- webhook_provider
+- webhook_event_type
+- webhook_delivery_id
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringEnumField(whiFieldProvider, providers...).
+			Description("The provider that sends requests.")).
+		Field(service.NewStringField(whiFieldSecret).
+			Description("The secret of the webhook, which is the signing secret for Slack and Stripe, and the secret token for GitLab.")).
+		Field(service.NewStringField(whiFieldAddress).
+			Description("The address to listen on.").
+			Default("0.0.0.0:4196")).
+		Field(service.NewStringField(whiFieldPath).
+			Description("The path to receive requests at.").
+			Default("/webhook")).
+		Field(service.NewDurationField(whiFieldTolerance).
+			Description("The maximum difference between the timestamp of a request and the current time, which only applies to providers that sign a timestamp.").
+			Default("5m")).
+		Field(service.NewStringField(whiFieldCache).
+			Description("A [cache resource](/docs/components/caches/about) to store the delivery IDs of requests within. When empty requests are not deduplicated.").
+			Default("")).
+		Field(service.NewDurationField(whiFieldCacheTTL).
+			Description("The period of time to store delivery IDs for, which should exceed the period of time that the provider retries requests for.").
+			Default("72h")).
+		Field(service.NewDurationField(whiFieldTimeout).
+			Description("The maximum period of time to wait for a message to be delivered before a request is answered with a 500 response, which should be less than the period of time that the provider waits for a response.").
+			Default("5s")).
+		Field(service.NewIntField(whiFieldMaxBody).
+			Description("The maximum size of the body of a request, where requests that are larger are rejected with a 413 response.").
+			Advanced().
+			Default(25*1024*1024)).
+		Field(service.NewStringField(whiFieldCertFile).
+			Description("Enable TLS by specifying a certificate and key file. The certificate is reloaded when either file is modified.").
+			Advanced().
+			Default("")).
+		Field(service.NewStringField(whiFieldKeyFile).
+			Description("Enable TLS by specifying a certificate and key file.").
+			Advanced().
+			Default("")).
+		Example(
+			"GitHub Pushes",
+			"In this example the pushes of a GitHub webhook are consumed, where retries of deliveries are ignored for a day.",
+			`
+input:
+  webhook:
+    provider: github
+    secret: "${GITHUB_WEBHOOK_SECRET}"
+    path: /github
+    cache: deliveries
+    cache_ttl: 24h
+  processors:
+    - mapping: |
+        root = if meta("webhook_event_type") != "push" { deleted() }
+
+cache_resources:
+  - label: deliveries
+    redis:
+      url: tcp://localhost:6379
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"webhook", webhookInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newWebhookInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type whRequest struct {
+	msg     *service.Message
+	resChan chan error
+}
+
+type webhookInput struct {
+	providerName string
+	provider     whProvider
+	secret       []byte
+	address      string
+	path         string
+	tolerance    time.Duration
+	cache        string
+	cacheTTL     time.Duration
+	timeout      time.Duration
+	maxBody      int64
+	certFile     string
+	keyFile      string
+	mgr          *service.Resources
+	log          *service.Logger
+
+	reqChan chan whRequest
+
+	mut      sync.Mutex
+	server   *http.Server
+	listener net.Listener
+	closed   chan struct{}
+}
+
+func newWebhookInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*webhookInput, error) {
+	w := &webhookInput{
+		mgr:     mgr,
+		log:     mgr.Logger(),
+		reqChan: make(chan whRequest),
+		closed:  make(chan struct{}),
+	}
+
+	var err error
+	if w.providerName, err = conf.FieldString(whiFieldProvider); err != nil {
+		return nil, err
+	}
+	w.provider = whProviders[w.providerName]
+
+	secret, err := conf.FieldString(whiFieldSecret)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, errors.New("a secret must be provided")
+	}
+	w.secret = []byte(secret)
+
+	if w.address, err = conf.FieldString(whiFieldAddress); err != nil {
+		return nil, err
+	}
+	if w.path, err = conf.FieldString(whiFieldPath); err != nil {
+		return nil, err
+	}
+	if w.tolerance, err = conf.FieldDuration(whiFieldTolerance); err != nil {
+		return nil, err
+	}
+	if w.cache, err = conf.FieldString(whiFieldCache); err != nil {
+		return nil, err
+	}
+	if w.cache != "" && !mgr.HasCache(w.cache) {
+		return nil, errors.New("cache resource " + w.cache + " was not found")
+	}
+	if w.cacheTTL, err = conf.FieldDuration(whiFieldCacheTTL); err != nil {
+		return nil, err
+	}
+	if w.timeout, err = conf.FieldDuration(whiFieldTimeout); err != nil {
+		return nil, err
+	}
+	maxBody, err := conf.FieldInt(whiFieldMaxBody)
+	if err != nil {
+		return nil, err
+	}
+	w.maxBody = int64(maxBody)
+	if w.certFile, err = conf.FieldString(whiFieldCertFile); err != nil {
+		return nil, err
+	}
+	if w.keyFile, err = conf.FieldString(whiFieldKeyFile); err != nil {
+		return nil, err
+	}
+	if (w.certFile == "") != (w.keyFile == "") {
+		return nil, errors.New("both a cert_file and key_file must be specified in order to enable TLS")
+	}
+	return w, nil
+}
+
+// claim adds the delivery ID of a request to the cache, and returns false when
+// it already exists.
+func (w *webhookInput) claim(ctx context.Context, key string) (bool, error) {
+	var cErr error
+	if err := w.mgr.AccessCache(ctx, w.cache, func(c service.Cache) {
+		cErr = c.Add(ctx, key, []byte("t"), &w.cacheTTL)
+	}); err != nil {
+		return false, err
+	}
+	if errors.Is(cErr, service.ErrKeyAlreadyExists) {
+		return false, nil
+	}
+	return cErr == nil, cErr
+}
+
+func (w *webhookInput) unclaim(key string) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	var cErr error
+	if err := w.mgr.AccessCache(ctx, w.cache, func(c service.Cache) {
+		cErr = c.Delete(ctx, key)
+	}); err != nil {
+		cErr = err
+	}
+	if cErr != nil {
+		w.log.Errorf("Failed to remove delivery ID %v from cache: %v", key, cErr)
+	}
+}
+
+// deliver consumes the message of a request and waits for it to be delivered.
+func (w *webhookInput) deliver(ctx context.Context, msg *service.Message) error {
+	ctx, done := context.WithTimeout(ctx, w.timeout)
+	defer done()
+
+	resChan := make(chan error, 1)
+	select {
+	case w.reqChan <- whRequest{msg: msg, resChan: resChan}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.closed:
+		return service.ErrNotConnected
+	}
+
+	select {
+	case err := <-resChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *webhookInput) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, w.maxBody+1))
+	if err != nil {
+		http.Error(rw, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > w.maxBody {
+		http.Error(rw, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := w.provider.verify(r.Header, body, w.secret, time.Now(), w.tolerance); err != nil {
+		w.log.Debugf("Rejecting request from %v: %v", r.RemoteAddr, err)
+		http.Error(rw, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	eventType, deliveryID := w.provider.describe(r.Header, body)
+	if w.providerName == "slack" && eventType == "url_verification" {
+		rw.Header().Set("Content-Type", "text/plain")
+		_, _ = rw.Write([]byte(whJSONFields(body, "challenge")[0]))
+		return
+	}
+
+	var key string
+	if w.cache != "" && deliveryID != "" {
+		key = w.providerName + ":" + deliveryID
+		claimed, err := w.claim(r.Context(), key)
+		if err != nil {
+			w.log.Errorf("Failed to add delivery ID %v to cache: %v", key, err)
+			http.Error(rw, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			w.log.Debugf("Ignoring delivery %v as it has already been received", key)
+			return
+		}
+	}
+
+	msg := service.NewMessage(body)
+	msg.MetaSetMut("webhook_provider", w.providerName)
+	msg.MetaSetMut("webhook_event_type", eventType)
+	msg.MetaSetMut("webhook_delivery_id", deliveryID)
+
+	if err := w.deliver(r.Context(), msg); err != nil {
+		if key != "" {
+			w.unclaim(key)
+		}
+		http.Error(rw, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (w *webhookInput) Connect(ctx context.Context) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.server != nil {
+		return nil
+	}
+
+	server := &http.Server{}
+	if w.certFile != "" {
+		reloader, err := btls.NewCertReloader(w.certFile, w.keyFile, "")
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = reloader.ServerConfig()
+	}
+
+	listener, err := net.Listen("tcp", w.address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(w.path, w)
+	server.Handler = mux
+
+	go func() {
+		var err error
+		if w.certFile != "" {
+			err = server.ServeTLS(listener, "", "")
+		} else {
+			err = server.Serve(listener)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			w.log.Errorf("Server error: %v", err)
+		}
+	}()
+
+	w.server, w.listener = server, listener
+	return nil
+}
+
+func (w *webhookInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case req := <-w.reqChan:
+		return req.msg, func(ctx context.Context, err error) error {
+			req.resChan <- err
+			return nil
+		}, nil
+	case <-w.closed:
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (w *webhookInput) Close(ctx context.Context) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	select {
+	case <-w.closed:
+	default:
+		close(w.closed)
+	}
+	if w.server == nil {
+		return nil
+	}
+	err := w.server.Shutdown(ctx)
+	w.server = nil
+	return err
+}
@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func startTestInput(t *testing.T, mgr *service.Resources, conf string) (*webhookInput, string) {
+	t.Helper()
+
+	pConf, err := webhookInputSpec().ParseYAML(`
+address: 127.0.0.1:0
+timeout: 1s
+`+conf, nil)
+	require.NoError(t, err)
+
+	w, err := newWebhookInputFromParsed(pConf, mgr)
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+	return w, "http://" + w.listener.Addr().String() + "/webhook"
+}
+
+func testPost(t *testing.T, url, body string, headers map[string]string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest("POST", url, strings.NewReader(body))
+	require.NoError(t, err)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(resBody)
+}
+
+type testResult struct {
+	status int
+	body   string
+}
+
+func testPostAsync(t *testing.T, url, body string, headers map[string]string) <-chan testResult {
+	resChan := make(chan testResult, 1)
+	go func() {
+		status, resBody := testPost(t, url, body, headers)
+		resChan <- testResult{status: status, body: resBody}
+	}()
+	return resChan
+}
+
+func readTestMessage(t *testing.T, w *webhookInput) (*service.Message, service.AckFunc) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	msg, ackFn, err := w.Read(ctx)
+	require.NoError(t, err)
+	return msg, ackFn
+}
+
+func TestWebhookInputDeliveries(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foocache"))
+	w, url := startTestInput(t, mgr, `
+provider: github
+secret: foo
+cache: foocache
+`)
+
+	body := `{"ref":"refs/heads/main"}`
+	headers := map[string]string{
+		"X-Hub-Signature-256": "sha256=" + testSign("foo", body),
+		"X-GitHub-Event":      "push",
+		"X-GitHub-Delivery":   "abc",
+	}
+
+	// A delivery that fails is answered with a 500 response, and is then
+	// consumed again when retried.
+	resChan := testPostAsync(t, url, body, headers)
+	_, ackFn := readTestMessage(t, w)
+	require.NoError(t, ackFn(context.Background(), errors.New("nope")))
+	assert.Equal(t, http.StatusInternalServerError, (<-resChan).status)
+
+	resChan = testPostAsync(t, url, body, headers)
+	msg, ackFn := readTestMessage(t, w)
+	require.NoError(t, ackFn(context.Background(), nil))
+	assert.Equal(t, http.StatusOK, (<-resChan).status)
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, body, string(b))
+	for k, v := range map[string]string{
+		"webhook_provider":    "github",
+		"webhook_event_type":  "push",
+		"webhook_delivery_id": "abc",
+	} {
+		actual, _ := msg.MetaGet(k)
+		assert.Equal(t, v, actual, k)
+	}
+
+	// Deliveries that have already been consumed are not consumed again.
+	status, _ := testPost(t, url, body, headers)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Requests that are not signed by the secret are rejected.
+	headers["X-Hub-Signature-256"] = "sha256=" + testSign("bar", body)
+	headers["X-GitHub-Delivery"] = "def"
+	status, _ = testPost(t, url, body, headers)
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	_, _, err = w.Read(ctx)
+	assert.Error(t, err)
+}
+
+func TestWebhookInputSlackChallenge(t *testing.T) {
+	_, url := startTestInput(t, service.MockResources(), `
+provider: slack
+secret: foo
+`)
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	body := `{"type":"url_verification","challenge":"barchallenge"}`
+	status, resBody := testPost(t, url, body, map[string]string{
+		"X-Slack-Request-Timestamp": ts,
+		"X-Slack-Signature":         "v0=" + testSign("foo", "v0:"+ts+":", body),
+	})
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "barchallenge", resBody)
+}
+
+func TestWebhookInputLimits(t *testing.T) {
+	w, url := startTestInput(t, service.MockResources(), `
+provider: gitlab
+secret: foo
+max_body_bytes: 10
+timeout: 10ms
+`)
+
+	status, _ := testPost(t, url, strings.Repeat("x", 11), map[string]string{"X-Gitlab-Token": "foo"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+
+	// Deliveries that are not consumed within the timeout fail.
+	status, _ = testPost(t, url, "{}", map[string]string{"X-Gitlab-Token": "foo"})
+	assert.Equal(t, http.StatusInternalServerError, status)
+
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	require.NoError(t, w.Close(context.Background()))
+	_, _, err = w.Read(context.Background())
+	assert.Equal(t, service.ErrNotConnected, err)
+}
+
+func TestWebhookInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf   string
+		errStr string
+	}{
+		{conf: "provider: github\nsecret: ''", errStr: "secret"},
+		{conf: "provider: github\nsecret: foo\ncache: nope", errStr: "cache resource nope"},
+		{conf: "provider: github\nsecret: foo\ncert_file: foo.pem", errStr: "key_file"},
+	} {
+		conf, err := webhookInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newWebhookInputFromParsed(conf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errStr)
+	}
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errSignatureMissing = errors.New("request is not signed")
+	errSignatureInvalid = errors.New("request signature is invalid")
+)
+
+// whProvider verifies and describes the requests of a webhook provider.
+type whProvider interface {
+	// verify returns an error when the request was not sent by the provider,
+	// or was sent outside of the tolerance of the current time.
+	verify(h http.Header, body, secret []byte, now time.Time, tolerance time.Duration) error
+
+	// describe returns the type of event of a verified request, and the unique
+	// ID of its delivery when there is one.
+	describe(h http.Header, body []byte) (eventType, deliveryID string)
+}
+
+var whProviders = map[string]whProvider{
+	"github": githubProvider{},
+	"gitlab": gitlabProvider{},
+	"jira":   jiraProvider{},
+	"slack":  slackProvider{},
+	"stripe": stripeProvider{},
+}
+
+func whHMAC(secret []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	for _, p := range parts {
+		_, _ = mac.Write(p)
+	}
+	return mac.Sum(nil)
+}
+
+// whCheckHexSignature compares a hex encoded signature with an expected
+// signature in constant time.
+func whCheckHexSignature(signature string, expected []byte) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, expected) {
+		return errSignatureInvalid
+	}
+	return nil
+}
+
+func whCheckTimestamp(timestamp string, now time.Time, tolerance time.Duration) error {
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("request timestamp is invalid: %w", err)
+	}
+	if delta := now.Sub(time.Unix(secs, 0)); delta > tolerance || delta < -tolerance {
+		return fmt.Errorf("request timestamp is outside of the tolerance by %v", delta)
+	}
+	return nil
+}
+
+// whJSONFields extracts string fields of a JSON body by path, where fields
+// of nested objects are addressed as parent.child.
+func whJSONFields(body []byte, paths ...string) []string {
+	var obj map[string]any
+	_ = json.Unmarshal(body, &obj)
+
+	values := make([]string, len(paths))
+	for i, p := range paths {
+		var v any = obj
+		for _, k := range strings.Split(p, ".") {
+			m, _ := v.(map[string]any)
+			v = m[k]
+		}
+		values[i], _ = v.(string)
+	}
+	return values
+}
+
+// githubProvider verifies the X-Hub-Signature-256 header, which is an HMAC
+// SHA256 signature of the body.
+type githubProvider struct{}
+
+func (githubProvider) verify(h http.Header, body, secret []byte, _ time.Time, _ time.Duration) error {
+	sig := h.Get("X-Hub-Signature-256")
+	if sig == "" {
+		return errSignatureMissing
+	}
+	return whCheckHexSignature(strings.TrimPrefix(sig, "sha256="), whHMAC(secret, body))
+}
+
+func (githubProvider) describe(h http.Header, _ []byte) (eventType, deliveryID string) {
+	return h.Get("X-GitHub-Event"), h.Get("X-GitHub-Delivery")
+}
+
+// gitlabProvider verifies the X-Gitlab-Token header, which is the secret token
+// of the webhook.
+type gitlabProvider struct{}
+
+func (gitlabProvider) verify(h http.Header, _, secret []byte, _ time.Time, _ time.Duration) error {
+	token := h.Get("X-Gitlab-Token")
+	if token == "" {
+		return errSignatureMissing
+	}
+	if subtle.ConstantTimeCompare([]byte(token), secret) != 1 {
+		return errSignatureInvalid
+	}
+	return nil
+}
+
+func (gitlabProvider) describe(h http.Header, _ []byte) (eventType, deliveryID string) {
+	return h.Get("X-Gitlab-Event"), h.Get("X-Gitlab-Event-UUID")
+}
+
+// jiraProvider verifies the X-Hub-Signature header, which is an HMAC SHA256
+// signature of the body.
+type jiraProvider struct{}
+
+func (jiraProvider) verify(h http.Header, body, secret []byte, _ time.Time, _ time.Duration) error {
+	sig := h.Get("X-Hub-Signature")
+	if sig == "" {
+		return errSignatureMissing
+	}
+	if !strings.HasPrefix(sig, "sha256=") {
+		return errSignatureInvalid
+	}
+	return whCheckHexSignature(strings.TrimPrefix(sig, "sha256="), whHMAC(secret, body))
+}
+
+func (jiraProvider) describe(h http.Header, body []byte) (eventType, deliveryID string) {
+	return whJSONFields(body, "webhookEvent")[0], h.Get("X-Atlassian-Webhook-Identifier")
+}
+
+// slackProvider verifies the X-Slack-Signature header, which is an HMAC SHA256
+// signature of the version, the X-Slack-Request-Timestamp header and the body.
+type slackProvider struct{}
+
+func (slackProvider) verify(h http.Header, body, secret []byte, now time.Time, tolerance time.Duration) error {
+	sig, timestamp := h.Get("X-Slack-Signature"), h.Get("X-Slack-Request-Timestamp")
+	if sig == "" || timestamp == "" {
+		return errSignatureMissing
+	}
+	if err := whCheckTimestamp(timestamp, now, tolerance); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(sig, "v0=") {
+		return errSignatureInvalid
+	}
+	return whCheckHexSignature(strings.TrimPrefix(sig, "v0="), whHMAC(secret, []byte("v0:"+timestamp+":"), body))
+}
+
+func (slackProvider) describe(_ http.Header, body []byte) (eventType, deliveryID string) {
+	fields := whJSONFields(body, "type", "event.type", "event_id")
+	if eventType = fields[1]; eventType == "" {
+		eventType = fields[0]
+	}
+	return eventType, fields[2]
+}
+
+// stripeProvider verifies the Stripe-Signature header, which contains a
+// timestamp and one or more HMAC SHA256 signatures of the timestamp and the
+// body.
+type stripeProvider struct{}
+
+func (stripeProvider) verify(h http.Header, body, secret []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var sigs []string
+	for _, kv := range strings.Split(h.Get("Stripe-Signature"), ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(kv), "=")
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if timestamp == "" || len(sigs) == 0 {
+		return errSignatureMissing
+	}
+	if err := whCheckTimestamp(timestamp, now, tolerance); err != nil {
+		return err
+	}
+
+	// Multiple signatures are sent whilst a secret is being rolled.
+	expected := whHMAC(secret, []byte(timestamp+"."), body)
+	for _, sig := range sigs {
+		if whCheckHexSignature(sig, expected) == nil {
+			return nil
+		}
+	}
+	return errSignatureInvalid
+}
+
+func (stripeProvider) describe(_ http.Header, body []byte) (eventType, deliveryID string) {
+	fields := whJSONFields(body, "type", "id")
+	return fields[0], fields[1]
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSign(secret string, parts ...string) string {
+	var b [][]byte
+	for _, p := range parts {
+		b = append(b, []byte(p))
+	}
+	return hex.EncodeToString(whHMAC([]byte(secret), b...))
+}
+
+func TestProviderVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ts := fmt.Sprint(now.Unix())
+	staleTS := fmt.Sprint(now.Add(-time.Hour).Unix())
+	body := `{"id":"evt_1","type":"invoice.paid"}`
+
+	for _, test := range []struct {
+		name     string
+		provider string
+		headers  map[string]string
+		errStr   string
+	}{
+		{
+			name:     "github valid",
+			provider: "github",
+			headers:  map[string]string{"X-Hub-Signature-256": "sha256=" + testSign("foo", body)},
+		},
+		{
+			name:     "github wrong secret",
+			provider: "github",
+			headers:  map[string]string{"X-Hub-Signature-256": "sha256=" + testSign("bar", body)},
+			errStr:   "invalid",
+		},
+		{
+			name:     "github unsigned",
+			provider: "github",
+			errStr:   "not signed",
+		},
+		{
+			name:     "gitlab valid",
+			provider: "gitlab",
+			headers:  map[string]string{"X-Gitlab-Token": "foo"},
+		},
+		{
+			name:     "gitlab invalid",
+			provider: "gitlab",
+			headers:  map[string]string{"X-Gitlab-Token": "fo"},
+			errStr:   "invalid",
+		},
+		{
+			name:     "jira valid",
+			provider: "jira",
+			headers:  map[string]string{"X-Hub-Signature": "sha256=" + testSign("foo", body)},
+		},
+		{
+			name:     "jira other algorithm",
+			provider: "jira",
+			headers:  map[string]string{"X-Hub-Signature": "sha1=" + testSign("foo", body)},
+			errStr:   "invalid",
+		},
+		{
+			name:     "slack valid",
+			provider: "slack",
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": ts,
+				"X-Slack-Signature":         "v0=" + testSign("foo", "v0:"+ts+":", body),
+			},
+		},
+		{
+			name:     "slack stale",
+			provider: "slack",
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": staleTS,
+				"X-Slack-Signature":         "v0=" + testSign("foo", "v0:"+staleTS+":", body),
+			},
+			errStr: "tolerance",
+		},
+		{
+			name:     "slack timestamp not signed",
+			provider: "slack",
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": ts,
+				"X-Slack-Signature":         "v0=" + testSign("foo", "v0:"+staleTS+":", body),
+			},
+			errStr: "invalid",
+		},
+		{
+			name:     "stripe valid with rolled secret",
+			provider: "stripe",
+			headers: map[string]string{
+				"Stripe-Signature": "t=" + ts + ",v1=" + testSign("old", ts+".", body) + ",v1=" + testSign("foo", ts+".", body) + ",v0=nope",
+			},
+		},
+		{
+			name:     "stripe stale",
+			provider: "stripe",
+			headers: map[string]string{
+				"Stripe-Signature": "t=" + staleTS + ",v1=" + testSign("foo", staleTS+".", body),
+			},
+			errStr: "tolerance",
+		},
+		{
+			name:     "stripe invalid",
+			provider: "stripe",
+			headers: map[string]string{
+				"Stripe-Signature": "t=" + ts + ",v1=nothex",
+			},
+			errStr: "invalid",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range test.headers {
+				h.Set(k, v)
+			}
+			err := whProviders[test.provider].verify(h, []byte(body), []byte("foo"), now, time.Minute*5)
+			if test.errStr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errStr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestProviderDescribe(t *testing.T) {
+	for _, test := range []struct {
+		provider   string
+		headers    map[string]string
+		body       string
+		eventType  string
+		deliveryID string
+	}{
+		{
+			provider:   "github",
+			headers:    map[string]string{"X-GitHub-Event": "push", "X-GitHub-Delivery": "abc"},
+			eventType:  "push",
+			deliveryID: "abc",
+		},
+		{
+			provider:   "gitlab",
+			headers:    map[string]string{"X-Gitlab-Event": "Merge Request Hook", "X-Gitlab-Event-UUID": "abc"},
+			eventType:  "Merge Request Hook",
+			deliveryID: "abc",
+		},
+		{
+			provider:   "jira",
+			headers:    map[string]string{"X-Atlassian-Webhook-Identifier": "abc"},
+			body:       `{"webhookEvent":"jira:issue_updated"}`,
+			eventType:  "jira:issue_updated",
+			deliveryID: "abc",
+		},
+		{
+			provider:   "slack",
+			body:       `{"type":"event_callback","event_id":"Ev1","event":{"type":"app_mention"}}`,
+			eventType:  "app_mention",
+			deliveryID: "Ev1",
+		},
+		{
+			provider:  "slack",
+			body:      `{"type":"url_verification","challenge":"foo"}`,
+			eventType: "url_verification",
+		},
+		{
+			provider:   "stripe",
+			body:       `{"id":"evt_1","type":"invoice.paid"}`,
+			eventType:  "invoice.paid",
+			deliveryID: "evt_1",
+		},
+		{
+			provider: "stripe",
+			body:     `not json`,
+		},
+	} {
+		h := http.Header{}
+		for k, v := range test.headers {
+			h.Set(k, v)
+		}
+		eventType, deliveryID := whProviders[test.provider].describe(h, []byte(test.body))
+		assert.Equal(t, test.eventType, eventType, test.provider)
+		assert.Equal(t, test.deliveryID, deliveryID, test.provider)
+	}
+}
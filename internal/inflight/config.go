@@ -0,0 +1,25 @@
+package inflight
+
+// Config contains configuration parameters for in-flight accounting.
+type Config struct {
+	HighWatermarkMessages int `json:"high_watermark_messages" yaml:"high_watermark_messages"`
+	LowWatermarkMessages  int `json:"low_watermark_messages" yaml:"low_watermark_messages"`
+	HighWatermarkBytes    int `json:"high_watermark_bytes" yaml:"high_watermark_bytes"`
+	LowWatermarkBytes     int `json:"low_watermark_bytes" yaml:"low_watermark_bytes"`
+}
+
+// NewConfig creates an in-flight config with default values, which disables
+// accounting.
+func NewConfig() Config {
+	return Config{
+		HighWatermarkMessages: 0,
+		LowWatermarkMessages:  0,
+		HighWatermarkBytes:    0,
+		LowWatermarkBytes:     0,
+	}
+}
+
+// IsNoop returns true if this config does not apply any limits.
+func (c Config) IsNoop() bool {
+	return c.HighWatermarkMessages <= 0 && c.HighWatermarkBytes <= 0
+}
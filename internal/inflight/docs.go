@@ -0,0 +1,17 @@
+package inflight
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// FieldSpec returns a spec for a common in_flight field.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject("in_flight", `
+Allows you to limit the number of messages and bytes that are in flight, meaning they have been read but not yet acknowledged. Once either high watermark is reached back pressure is applied upstream until the number of messages and bytes in flight fall to their respective low watermarks.
+
+The current number of messages and bytes in flight are exposed with the gauge metrics `+"`in_flight_messages`"+` and `+"`in_flight_bytes`"+`, and the counter `+"`in_flight_saturated`"+` tracks the number of times back pressure was applied.`,
+	).WithChildren(
+		docs.FieldInt("high_watermark_messages", "The number of messages in flight at which back pressure is applied. Set to `0` to disable message based accounting.").HasDefault(0),
+		docs.FieldInt("low_watermark_messages", "The number of messages in flight at which back pressure is released once it has been applied. When set to `0` back pressure is released as soon as the number of messages in flight falls below the high watermark.").HasDefault(0),
+		docs.FieldInt("high_watermark_bytes", "The number of bytes in flight at which back pressure is applied. Set to `0` to disable byte based accounting.").HasDefault(0),
+		docs.FieldInt("low_watermark_bytes", "The number of bytes in flight at which back pressure is released once it has been applied. When set to `0` back pressure is released as soon as the number of bytes in flight falls below the high watermark.").HasDefault(0),
+	).Advanced().ChildDefaultAndTypesFromStruct(NewConfig())
+}
@@ -0,0 +1,199 @@
+package inflight
+
+import (
+	"context"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+// Gate is a stream layer that consumes transactions and forwards them
+// downstream whilst counting the messages and bytes that have not yet been
+// acknowledged. Once a high watermark is reached the gate stops consuming new
+// transactions until the counts fall to their low watermarks, which propagates
+// back pressure upstream.
+type Gate struct {
+	highMessages, lowMessages int64
+	highBytes, lowBytes       int64
+
+	mut       sync.Mutex
+	messages  int64
+	bytes     int64
+	saturated bool
+	notify    chan struct{}
+
+	mMessages  metrics.StatGauge
+	mBytes     metrics.StatGauge
+	mSaturated metrics.StatCounter
+
+	transactionsIn  <-chan message.Transaction
+	transactionsOut chan message.Transaction
+
+	shutSig *shutdown.Signaller
+}
+
+// NewGate creates a new in-flight gate from a config.
+func NewGate(conf Config, stats metrics.Type) *Gate {
+	g := &Gate{
+		highMessages:    int64(conf.HighWatermarkMessages),
+		lowMessages:     int64(conf.LowWatermarkMessages),
+		highBytes:       int64(conf.HighWatermarkBytes),
+		lowBytes:        int64(conf.LowWatermarkBytes),
+		notify:          make(chan struct{}, 1),
+		mMessages:       stats.GetGauge("in_flight_messages"),
+		mBytes:          stats.GetGauge("in_flight_bytes"),
+		mSaturated:      stats.GetCounter("in_flight_saturated"),
+		transactionsOut: make(chan message.Transaction),
+		shutSig:         shutdown.NewSignaller(),
+	}
+	if g.lowMessages <= 0 || g.lowMessages >= g.highMessages {
+		g.lowMessages = g.highMessages - 1
+	}
+	if g.lowBytes <= 0 || g.lowBytes >= g.highBytes {
+		g.lowBytes = g.highBytes - 1
+	}
+	return g
+}
+
+// InFlight returns the current number of messages and bytes in flight.
+func (g *Gate) InFlight() (messages, bytes int64) {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	return g.messages, g.bytes
+}
+
+func batchBytes(b message.Batch) (n int64) {
+	for _, p := range b {
+		n += int64(len(p.AsBytes()))
+	}
+	return
+}
+
+// isBlocked returns true if new transactions should not be admitted, and must
+// be called with the mutex held.
+func (g *Gate) isBlocked() bool {
+	if !g.saturated {
+		if (g.highMessages > 0 && g.messages >= g.highMessages) ||
+			(g.highBytes > 0 && g.bytes >= g.highBytes) {
+			g.saturated = true
+			g.mSaturated.Incr(1)
+		}
+		return g.saturated
+	}
+	if (g.highMessages <= 0 || g.messages <= g.lowMessages) &&
+		(g.highBytes <= 0 || g.bytes <= g.lowBytes) {
+		g.saturated = false
+	}
+	return g.saturated
+}
+
+// waitForCapacity blocks until a new transaction can be admitted, returns false
+// if the gate was closed whilst waiting.
+func (g *Gate) waitForCapacity() bool {
+	for {
+		g.mut.Lock()
+		blocked := g.isBlocked()
+		g.mut.Unlock()
+		if !blocked {
+			return true
+		}
+		select {
+		case <-g.notify:
+		case <-g.shutSig.CloseNowChan():
+			return false
+		}
+	}
+}
+
+func (g *Gate) add(messages, bytes int64) {
+	g.mut.Lock()
+	g.messages += messages
+	g.bytes += bytes
+	g.mMessages.Set(g.messages)
+	g.mBytes.Set(g.bytes)
+	g.mut.Unlock()
+
+	if messages < 0 {
+		select {
+		case g.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (g *Gate) loop() {
+	defer func() {
+		close(g.transactionsOut)
+		g.shutSig.ShutdownComplete()
+	}()
+
+	for {
+		if !g.waitForCapacity() {
+			return
+		}
+
+		var ts message.Transaction
+		var open bool
+		select {
+		case ts, open = <-g.transactionsIn:
+			if !open {
+				return
+			}
+		case <-g.shutSig.CloseNowChan():
+			return
+		}
+
+		messages, bytes := int64(ts.Payload.Len()), batchBytes(ts.Payload)
+		g.add(messages, bytes)
+
+		var once sync.Once
+		tracked := message.NewTransactionFunc(ts.Payload, func(ctx context.Context, err error) error {
+			once.Do(func() {
+				g.add(-messages, -bytes)
+			})
+			return ts.Ack(ctx, err)
+		})
+
+		select {
+		case g.transactionsOut <- *tracked.WithContext(ts.Context()):
+		case <-g.shutSig.CloseNowChan():
+			return
+		}
+	}
+}
+
+// Consume starts the gate consuming transactions from a channel.
+func (g *Gate) Consume(msgs <-chan message.Transaction) error {
+	if g.transactionsIn != nil {
+		return component.ErrAlreadyStarted
+	}
+	g.transactionsIn = msgs
+	go g.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming transactions from
+// this gate.
+func (g *Gate) TransactionChan() <-chan message.Transaction {
+	return g.transactionsOut
+}
+
+// TriggerCloseNow signals that the gate should close immediately, even if it
+// is currently blocked waiting on acknowledgements.
+func (g *Gate) TriggerCloseNow() {
+	g.shutSig.CloseNow()
+}
+
+// WaitForClose blocks until the gate has closed down or the context is
+// cancelled.
+func (g *Gate) WaitForClose(ctx context.Context) error {
+	select {
+	case <-g.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package inflight
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func sendTran(t *testing.T, tChan chan<- message.Transaction, content string) <-chan error {
+	t.Helper()
+
+	resChan := make(chan error, 1)
+	select {
+	case tChan <- message.NewTransactionFunc(message.QuickBatch([][]byte{[]byte(content)}), func(ctx context.Context, err error) error {
+		resChan <- err
+		return nil
+	}):
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return resChan
+}
+
+func readTran(t *testing.T, tChan <-chan message.Transaction) message.Transaction {
+	t.Helper()
+
+	select {
+	case ts, open := <-tChan:
+		require.True(t, open)
+		return ts
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+	return message.Transaction{}
+}
+
+func TestGateMessageWatermarks(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewConfig()
+	conf.HighWatermarkMessages = 3
+	conf.LowWatermarkMessages = 1
+
+	stats := metrics.NewLocal()
+	g := NewGate(conf, stats)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, g.Consume(tChan))
+
+	var resChans []<-chan error
+	var received []message.Transaction
+	for i := 0; i < 3; i++ {
+		resChans = append(resChans, sendTran(t, tChan, "foo"))
+		received = append(received, readTran(t, g.TransactionChan()))
+	}
+
+	msgs, _ := g.InFlight()
+	assert.Equal(t, int64(3), msgs)
+
+	// The gate is saturated and so the next transaction is not consumed.
+	select {
+	case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("bar")}), make(chan error, 1)):
+		t.Fatal("expected back pressure")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// Dropping to two in flight is above the low watermark.
+	require.NoError(t, received[0].Ack(ctx, nil))
+	require.NoError(t, <-resChans[0])
+	select {
+	case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("bar")}), make(chan error, 1)):
+		t.Fatal("expected back pressure")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// Dropping to one in flight releases back pressure.
+	require.NoError(t, received[1].Ack(ctx, nil))
+	require.NoError(t, <-resChans[1])
+
+	resChans = append(resChans, sendTran(t, tChan, "bar"))
+	received = append(received, readTran(t, g.TransactionChan()))
+	assert.Equal(t, "bar", string(received[3].Payload.Get(0).AsBytes()))
+
+	// Acknowledging twice only decrements once.
+	require.NoError(t, received[2].Ack(ctx, nil))
+	require.NoError(t, <-resChans[2])
+	require.NoError(t, received[2].Ack(ctx, nil))
+	require.NoError(t, <-resChans[2])
+	require.NoError(t, received[3].Ack(ctx, nil))
+	<-resChans[3]
+
+	msgs, _ = g.InFlight()
+	assert.Equal(t, int64(0), msgs)
+	assert.Equal(t, int64(1), stats.GetCounters()["in_flight_saturated"])
+
+	close(tChan)
+	require.NoError(t, g.WaitForClose(ctx))
+}
+
+func TestGateByteWatermarks(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewConfig()
+	conf.HighWatermarkBytes = 10
+
+	g := NewGate(conf, metrics.Noop())
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, g.Consume(tChan))
+
+	resChan := sendTran(t, tChan, "0123456789")
+	ts := readTran(t, g.TransactionChan())
+
+	_, bytes := g.InFlight()
+	assert.Equal(t, int64(10), bytes)
+
+	select {
+	case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("bar")}), make(chan error, 1)):
+		t.Fatal("expected back pressure")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	require.NoError(t, ts.Ack(ctx, nil))
+	require.NoError(t, <-resChan)
+
+	_ = sendTran(t, tChan, "bar")
+	_ = readTran(t, g.TransactionChan())
+
+	g.TriggerCloseNow()
+	require.NoError(t, g.WaitForClose(ctx))
+}
+
+func TestGateCloseWhileSaturated(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	conf := NewConfig()
+	conf.HighWatermarkMessages = 1
+
+	g := NewGate(conf, metrics.Noop())
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, g.Consume(tChan))
+
+	_ = sendTran(t, tChan, "foo")
+	_ = readTran(t, g.TransactionChan())
+
+	g.TriggerCloseNow()
+	require.NoError(t, g.WaitForClose(ctx))
+
+	_, open := <-g.TransactionChan()
+	assert.False(t, open)
+}
@@ -0,0 +1,4 @@
+// Package inflight implements accounting of the messages and bytes that are in
+// flight (read but not yet acknowledged) through a segment of a stream, and
+// applies back pressure upstream once configured high watermarks are reached.
+package inflight
@@ -0,0 +1,128 @@
+package kerberos
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"gopkg.in/yaml.v3"
+)
+
+// Config contains configuration fields for authenticating with Kerberos,
+// either with the keys of a keytab or the tickets of a credential cache.
+type Config struct {
+	Username        string `json:"username" yaml:"username"`
+	Realm           string `json:"realm" yaml:"realm"`
+	KeytabFile      string `json:"keytab_file" yaml:"keytab_file"`
+	CCacheFile      string `json:"ccache_file" yaml:"ccache_file"`
+	ConfigFile      string `json:"config_file" yaml:"config_file"`
+	ServiceName     string `json:"service_name" yaml:"service_name"`
+	DisablePAFXFAST bool   `json:"disable_pafxfast" yaml:"disable_pafxfast"`
+}
+
+const defaultConfigFile = "/etc/krb5.conf"
+
+// NewConfig creates a new Config with default values, where the service name
+// is the default of the service principals being authenticated with.
+func NewConfig(serviceName string) Config {
+	return Config{
+		Username:        "",
+		Realm:           "",
+		KeytabFile:      "",
+		CCacheFile:      "",
+		ConfigFile:      defaultConfigFile,
+		ServiceName:     serviceName,
+		DisablePAFXFAST: false,
+	}
+}
+
+// ToggledConfig contains the fields of a Config along with a field `enabled`,
+// as defined by FieldSpec.
+type ToggledConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	Config  `json:",inline" yaml:",inline"`
+}
+
+// NewToggledConfig creates a new ToggledConfig with default values.
+func NewToggledConfig(serviceName string) ToggledConfig {
+	return ToggledConfig{
+		Enabled: false,
+		Config:  NewConfig(serviceName),
+	}
+}
+
+// ConfigFromAny decodes a Config from the value of a field defined with
+// NonToggledFieldSpec, such as that returned from the FieldAny method of a
+// parsed config.
+func ConfigFromAny(v any, serviceName string) (Config, error) {
+	conf := NewConfig(serviceName)
+	err := decodeAny(v, &conf)
+	return conf, err
+}
+
+// ToggledConfigFromAny decodes a ToggledConfig from the value of a field
+// defined with FieldSpec, such as that returned from the FieldAny method of a
+// parsed config.
+func ToggledConfigFromAny(v any, serviceName string) (ToggledConfig, error) {
+	conf := NewToggledConfig(serviceName)
+	err := decodeAny(v, &conf)
+	return conf, err
+}
+
+func decodeAny(v, conf any) error {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return err
+	}
+	return node.Decode(conf)
+}
+
+//------------------------------------------------------------------------------
+
+// CCachePath returns the path of the credential cache to obtain tickets from,
+// which is the configured path, or otherwise that of the environment.
+func (c Config) CCachePath() string {
+	if c.CCacheFile != "" {
+		return c.CCacheFile
+	}
+	if p := os.Getenv("KRB5CCNAME"); p != "" {
+		return strings.TrimPrefix(p, "FILE:")
+	}
+	return fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+}
+
+// Client creates a Kerberos client from the config, which obtains tickets
+// either with the keys of a keytab, or from a credential cache.
+func (c Config) Client() (*client.Client, error) {
+	krbConf, err := krb5config.Load(c.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kerberos config file: %w", err)
+	}
+	settings := client.DisablePAFXFAST(c.DisablePAFXFAST)
+
+	if c.KeytabFile != "" {
+		if c.Username == "" || c.Realm == "" {
+			return nil, errors.New("a username and realm must be specified in order to authenticate with a keytab")
+		}
+		kt, err := keytab.Load(c.KeytabFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keytab: %w", err)
+		}
+		return client.NewWithKeytab(c.Username, c.Realm, kt, krbConf, settings), nil
+	}
+
+	ccache, err := credentials.LoadCCache(c.CCachePath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credential cache: %w", err)
+	}
+	cl, err := client.NewFromCCache(ccache, krbConf, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credential cache: %w", err)
+	}
+	return cl, nil
+}
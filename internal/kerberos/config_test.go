@@ -0,0 +1,78 @@
+package kerberos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromAny(t *testing.T) {
+	conf, err := ConfigFromAny(map[string]any{
+		"username":    "benthos",
+		"realm":       "EXAMPLE.COM",
+		"keytab_file": "/etc/security/benthos.keytab",
+	}, "kafka")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Username:    "benthos",
+		Realm:       "EXAMPLE.COM",
+		KeytabFile:  "/etc/security/benthos.keytab",
+		ConfigFile:  "/etc/krb5.conf",
+		ServiceName: "kafka",
+	}, conf)
+
+	tConf, err := ToggledConfigFromAny(map[string]any{
+		"enabled":      true,
+		"service_name": "HTTPS",
+	}, "HTTP")
+	require.NoError(t, err)
+	assert.True(t, tConf.Enabled)
+	assert.Equal(t, "HTTPS", tConf.ServiceName)
+	assert.Equal(t, "/etc/krb5.conf", tConf.ConfigFile)
+}
+
+func TestConfigCCachePath(t *testing.T) {
+	t.Setenv("KRB5CCNAME", "FILE:/tmp/foo")
+
+	conf := NewConfig("")
+	assert.Equal(t, "/tmp/foo", conf.CCachePath())
+
+	conf.CCacheFile = "/tmp/bar"
+	assert.Equal(t, "/tmp/bar", conf.CCachePath())
+}
+
+func TestConfigClientErrors(t *testing.T) {
+	dir := t.TempDir()
+	krbConfFile := filepath.Join(dir, "krb5.conf")
+	require.NoError(t, os.WriteFile(krbConfFile, []byte(`
+[libdefaults]
+  default_realm = EXAMPLE.COM
+
+[realms]
+  EXAMPLE.COM = {
+    kdc = localhost:88
+  }
+`), 0o600))
+
+	conf := NewConfig("")
+	conf.ConfigFile = filepath.Join(dir, "nope.conf")
+	_, err := conf.Client()
+	require.ErrorContains(t, err, "failed to load kerberos config file")
+
+	conf.ConfigFile = krbConfFile
+	conf.CCacheFile = filepath.Join(dir, "nope")
+	_, err = conf.Client()
+	require.ErrorContains(t, err, "failed to load credential cache")
+
+	conf.KeytabFile = filepath.Join(dir, "nope.keytab")
+	_, err = conf.Client()
+	require.EqualError(t, err, "a username and realm must be specified in order to authenticate with a keytab")
+
+	conf.Username = "benthos"
+	conf.Realm = "EXAMPLE.COM"
+	_, err = conf.Client()
+	require.ErrorContains(t, err, "failed to load keytab")
+}
@@ -0,0 +1,52 @@
+package kerberos
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+func fieldSpecs(serviceName string) docs.FieldSpecs {
+	specs := docs.FieldSpecs{
+		docs.FieldString(
+			"username", "The principal name to authenticate as when a `keytab_file` is specified, which is otherwise obtained from the credential cache.", "benthos",
+		).HasDefault(""),
+		docs.FieldString(
+			"realm", "The realm of the principal to authenticate as when a `keytab_file` is specified.", "EXAMPLE.COM",
+		).HasDefault(""),
+		docs.FieldString(
+			"keytab_file", "The path of a keytab file containing the keys of the principal, which are used to obtain tickets. When empty the tickets of a credential cache are used instead.", "/etc/security/benthos.keytab",
+		).HasDefault(""),
+		docs.FieldString(
+			"ccache_file", "The path of a credential cache file to obtain tickets from when a `keytab_file` isn't specified, such as one populated with `kinit`. When empty the cache of the environment variable `KRB5CCNAME` is used, or otherwise `/tmp/krb5cc_<uid>`.", "/tmp/krb5cc_1000",
+		).HasDefault(""),
+		docs.FieldString(
+			"config_file", "The path of the Kerberos configuration file, which lists the realms and their key distribution centers.",
+		).HasDefault(defaultConfigFile),
+	}
+	if serviceName != "" {
+		specs = append(specs, docs.FieldString(
+			"service_name", "The service name of the principals of servers, which is combined with the hostname of each server in order to form the principal to obtain tickets for.",
+		).HasDefault(serviceName))
+	}
+	return append(specs, docs.FieldBool(
+		"disable_pafxfast", "Whether to disable the PA-FX-FAST pre-authentication, which isn't supported by some key distribution centers such as those of Active Directory.",
+	).HasDefault(false))
+}
+
+// FieldSpec returns a spec for a common Kerberos field, where the service name
+// is the default of the field `service_name`. When the service name is empty
+// the field `service_name` is omitted, which is for components where it's
+// specified by other means.
+func FieldSpec(serviceName string) docs.FieldSpec {
+	return docs.FieldObject(
+		"kerberos", "Allows you to authenticate with Kerberos, where tickets are obtained either with the keys of a keytab or from a credential cache.",
+	).WithChildren(append(docs.FieldSpecs{
+		docs.FieldBool("enabled", "Whether to authenticate with Kerberos.").HasDefault(false),
+	}, fieldSpecs(serviceName)...)...).AtVersion("4.9.0").Advanced()
+}
+
+// NonToggledFieldSpec returns a spec for a common Kerberos field without an
+// `enabled` field, which is for components where Kerberos is selected by other
+// means, such as a SASL mechanism.
+func NonToggledFieldSpec(serviceName string) docs.FieldSpec {
+	return docs.FieldObject(
+		"kerberos", "Contains fields for authenticating with Kerberos when the mechanism `GSSAPI` is used.",
+	).WithChildren(fieldSpecs(serviceName)...).AtVersion("4.9.0").Advanced().Optional()
+}
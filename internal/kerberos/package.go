@@ -0,0 +1,3 @@
+// Package kerberos provides a common config for authenticating with Kerberos
+// along with the tokens used by GSSAPI based protocols.
+package kerberos
@@ -0,0 +1,103 @@
+package kerberos
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// NegotiateToken returns an initial SPNEGO token that authenticates the client
+// with a service principal, such as HTTP/example.com, as sent with the HTTP
+// Negotiate scheme.
+func NegotiateToken(cl *client.Client, spn string) ([]byte, error) {
+	mechToken, err := InitToken(cl, spn)
+	if err != nil {
+		return nil, err
+	}
+	return negTokenInit(mechToken)
+}
+
+// InitToken returns an initial Kerberos GSSAPI token that authenticates the
+// client with a service principal, such as postgres/example.com, as sent with
+// the GSSAPI authentication of databases. Mutual authentication isn't
+// requested, and therefore the token is all that's required by servers.
+func InitToken(cl *client.Client, spn string) ([]byte, error) {
+	if err := cl.AffirmLogin(); err != nil {
+		return nil, fmt.Errorf("failed to login: %w", err)
+	}
+	tkt, key, err := cl.GetServiceTicket(spn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain service ticket for %v: %w", spn, err)
+	}
+
+	auth, err := types.NewAuthenticator(cl.Credentials.Domain(), cl.Credentials.CName())
+	if err != nil {
+		return nil, err
+	}
+	auth.Cksum = types.Checksum{
+		CksumType: chksumtype.GSSAPI,
+		Checksum:  authenticatorChecksum(gssapi.ContextFlagInteg | gssapi.ContextFlagConf),
+	}
+	apReq, err := messages.NewAPReq(tkt, key, auth)
+	if err != nil {
+		return nil, err
+	}
+	apReqBytes, err := apReq.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return mechToken(apReqBytes)
+}
+
+// authenticatorChecksum returns the checksum of an authenticator for GSSAPI as
+// specified by RFC 4121, which contains the context flags being requested.
+func authenticatorChecksum(flags uint32) []byte {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint32(b[:4], 16)
+	binary.LittleEndian.PutUint32(b[20:24], flags)
+	return b
+}
+
+type marshalNegTokenInit struct {
+	MechTypes []asn1.ObjectIdentifier `asn1:"explicit,tag:0"`
+	MechToken []byte                  `asn1:"explicit,optional,omitempty,tag:2"`
+}
+
+// mechToken wraps an AP-REQ message within a Kerberos mechanism token as
+// specified by RFC 1964.
+func mechToken(apReq []byte) ([]byte, error) {
+	krb5OID, err := asn1.Marshal(gssapi.OIDKRB5.OID())
+	if err != nil {
+		return nil, err
+	}
+	b := append(krb5OID, 0x01, 0x00)
+	return asn1tools.AddASNAppTag(append(b, apReq...), 0), nil
+}
+
+// negTokenInit wraps a Kerberos mechanism token within an SPNEGO NegTokenInit
+// as specified by RFC 4178.
+func negTokenInit(mechToken []byte) ([]byte, error) {
+	b, err := asn1.Marshal(marshalNegTokenInit{
+		MechTypes: []asn1.ObjectIdentifier{gssapi.OIDKRB5.OID()},
+		MechToken: mechToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if b, err = asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: b}); err != nil {
+		return nil, err
+	}
+
+	spnegoOID, err := asn1.Marshal(gssapi.OIDSPNEGO.OID())
+	if err != nil {
+		return nil, err
+	}
+	return asn1tools.AddASNAppTag(append(spnegoOID, b...), 0), nil
+}
@@ -0,0 +1,60 @@
+package kerberos
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegTokenInit(t *testing.T) {
+	apReq := []byte("not really an AP-REQ")
+
+	mech, err := mechToken(apReq)
+	require.NoError(t, err)
+
+	var mechRaw asn1.RawValue
+	_, err = asn1.Unmarshal(mech, &mechRaw)
+	require.NoError(t, err)
+	assert.Equal(t, asn1.ClassApplication, mechRaw.Class)
+
+	var oid asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(mechRaw.Bytes, &oid)
+	require.NoError(t, err)
+	assert.True(t, oid.Equal(gssapi.OIDKRB5.OID()))
+	assert.Equal(t, append([]byte{0x01, 0x00}, apReq...), rest)
+
+	token, err := negTokenInit(mech)
+	require.NoError(t, err)
+
+	var tokenRaw asn1.RawValue
+	_, err = asn1.Unmarshal(token, &tokenRaw)
+	require.NoError(t, err)
+	assert.Equal(t, asn1.ClassApplication, tokenRaw.Class)
+
+	rest, err = asn1.Unmarshal(tokenRaw.Bytes, &oid)
+	require.NoError(t, err)
+	assert.True(t, oid.Equal(gssapi.OIDSPNEGO.OID()))
+
+	var initRaw asn1.RawValue
+	_, err = asn1.Unmarshal(rest, &initRaw)
+	require.NoError(t, err)
+	assert.Equal(t, asn1.ClassContextSpecific, initRaw.Class)
+
+	var init marshalNegTokenInit
+	_, err = asn1.Unmarshal(initRaw.Bytes, &init)
+	require.NoError(t, err)
+	require.Len(t, init.MechTypes, 1)
+	assert.True(t, init.MechTypes[0].Equal(gssapi.OIDKRB5.OID()))
+	assert.Equal(t, mech, init.MechToken)
+}
+
+func TestAuthenticatorChecksum(t *testing.T) {
+	assert.Equal(t, []byte{
+		0x10, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0x30, 0, 0, 0,
+	}, authenticatorChecksum(gssapi.ContextFlagInteg|gssapi.ContextFlagConf))
+}
@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// ErrResourceVersionExpired is returned when watching from a resource version
+// that is too old, in which case the resources must be listed again.
+var ErrResourceVersionExpired = errors.New("resource version expired")
+
+// Client is a minimal client of the Kubernetes API, which supports the few
+// requests that are needed for watching resources and reporting their status.
+type Client struct {
+	baseURL   string
+	token     string
+	tokenFile string
+	http      *http.Client
+}
+
+// NewClient creates a client of the Kubernetes API at a base URL, which
+// authenticates with a bearer token when it is not empty.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    httpClient,
+	}
+}
+
+// NewInClusterClient creates a client of the Kubernetes API from the
+// environment and service account of the pod that it runs in.
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running within a Kubernetes cluster, KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	caBytes, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account certificate authority: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("failed to parse service account certificate authority")
+	}
+
+	c := NewClient("https://"+net.JoinHostPort(host, port), "", &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	})
+	// Service account tokens are rotated, and so the token is read for each
+	// request.
+	c.tokenFile = serviceAccountDir + "/token"
+	if _, err := c.bearerToken(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// InClusterNamespace returns the namespace of the pod that the process runs
+// in, or an empty string when it can not be determined.
+func InClusterNamespace() string {
+	ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(ns))
+}
+
+func (c *Client) bearerToken() (string, error) {
+	if c.tokenFile == "" {
+		return c.token, nil
+	}
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// statusError is the body of a failed request.
+type statusError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (s *statusError) Error() string {
+	return fmt.Sprintf("request failed (%v %v): %v", s.Code, s.Reason, s.Message)
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	token, err := c.bearerToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		resBytes, _ := io.ReadAll(res.Body)
+		sErr := &statusError{Code: res.StatusCode}
+		if jErr := json.Unmarshal(resBytes, sErr); jErr != nil || sErr.Message == "" {
+			sErr.Reason = http.StatusText(res.StatusCode)
+			sErr.Message = strings.TrimSpace(string(resBytes))
+		}
+		if sErr.Code == http.StatusGone {
+			return nil, ErrResourceVersionExpired
+		}
+		return nil, sErr
+	}
+	return res, nil
+}
+
+// Get obtains a resource or a list of resources at a path of the API, and
+// decodes it into a value.
+func (c *Client) Get(ctx context.Context, path string, v any) error {
+	res, err := c.do(ctx, http.MethodGet, path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// MergePatch applies a JSON merge patch to a resource at a path of the API.
+func (c *Client) MergePatch(ctx context.Context, path string, patch any) error {
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	res, err := c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", patchBytes)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return res.Body.Close()
+}
+
+// WatchEvent is an event of a watch, describing a change to a resource.
+type WatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// Watch watches the resources at a path of the API for changes that were made
+// after a resource version, calling a closure for each event until either the
+// watch is closed by the server, the context is cancelled, or the closure
+// returns an error.
+func (c *Client) Watch(ctx context.Context, path, resourceVersion string, fn func(WatchEvent) error) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	path += sep + "watch=true&allowWatchBookmarks=true&resourceVersion=" + resourceVersion
+
+	res, err := c.do(ctx, http.MethodGet, path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(res.Body))
+	for {
+		var event WatchEvent
+		if err := dec.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if event.Type == "ERROR" {
+			sErr := &statusError{}
+			if err := json.Unmarshal(event.Object, sErr); err != nil {
+				return err
+			}
+			if sErr.Code == http.StatusGone {
+				return ErrResourceVersionExpired
+			}
+			return sErr
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/config"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+	"github.com/benthosdev/benthos/v4/internal/stream"
+	strmmgr "github.com/benthosdev/benthos/v4/internal/stream/manager"
+)
+
+// The group, version and plural name of the StreamConfig custom resource.
+const (
+	StreamConfigGroup   = "benthos.dev"
+	StreamConfigVersion = "v1alpha1"
+	StreamConfigPlural  = "streamconfigs"
+)
+
+// Phases of streams that are reported within the status of StreamConfig
+// resources.
+const (
+	PhaseRunning = "Running"
+	PhaseStopped = "Stopped"
+	PhaseFailed  = "Failed"
+)
+
+// StreamsConfig determines where the definitions of streams are watched from.
+type StreamsConfig struct {
+	// ConfigMap is the name of a ConfigMap where each key is the ID of a
+	// stream and each value is its config.
+	ConfigMap string
+
+	// CustomResources enables watching StreamConfig custom resources, where
+	// the name of each resource is the ID of a stream and its spec is the
+	// config.
+	CustomResources bool
+
+	// Namespace is the namespace of the resources, and defaults to the
+	// namespace of the pod.
+	Namespace string
+
+	// DrainTimeout is the maximum period of time to wait for a stream to
+	// drain before it is replaced or removed.
+	DrainTimeout time.Duration
+
+	// StatusInterval is the period of time between checks of whether streams
+	// have stopped, which are reported within the status of StreamConfig
+	// resources.
+	StatusInterval time.Duration
+}
+
+// Enabled returns true when the config watches streams from Kubernetes.
+func (c StreamsConfig) Enabled() bool {
+	return c.ConfigMap != "" || c.CustomResources
+}
+
+// streamDefinition is the desired state of a stream.
+type streamDefinition struct {
+	conf  stream.Config
+	lints []string
+	err   error
+
+	// The generation of the resource that the definition was obtained from.
+	generation int64
+}
+
+type streamStatus struct {
+	Phase              string `json:"phase"`
+	Message            string `json:"message,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+}
+
+// StreamsWatcher watches Kubernetes for the definitions of streams and applies
+// them to a stream manager, one stream at a time such that each stream that
+// is changed or removed is drained before the next change is applied. When
+// watching StreamConfig resources the phase of each stream is reported within
+// the status subresource of its resource.
+type StreamsWatcher struct {
+	conf    StreamsConfig
+	client  *Client
+	streams *strmmgr.Type
+	log     log.Modular
+
+	retryInterval time.Duration
+
+	// Only accessed by the loop.
+	objects  map[string]map[string]streamDefinition
+	applied  map[string]stream.Config
+	reported map[string]streamStatus
+
+	shutSig *shutdown.Signaller
+}
+
+// NewStreamsWatcher creates a watcher that applies the streams defined within
+// Kubernetes to a stream manager. The watcher does nothing until Start is
+// called.
+func NewStreamsWatcher(conf StreamsConfig, client *Client, streams *strmmgr.Type, logger log.Modular) (*StreamsWatcher, error) {
+	if conf.ConfigMap != "" && conf.CustomResources {
+		return nil, errors.New("streams can be watched from either a ConfigMap or custom resources, but not both")
+	}
+	if !conf.Enabled() {
+		return nil, errors.New("either a ConfigMap or custom resources must be watched")
+	}
+	if conf.Namespace == "" {
+		return nil, errors.New("a namespace must be specified when not running within a cluster")
+	}
+	if conf.DrainTimeout <= 0 {
+		conf.DrainTimeout = time.Second * 30
+	}
+	if conf.StatusInterval <= 0 {
+		conf.StatusInterval = time.Second * 10
+	}
+	return &StreamsWatcher{
+		conf:          conf,
+		client:        client,
+		streams:       streams,
+		log:           logger,
+		retryInterval: time.Second * 5,
+		objects:       map[string]map[string]streamDefinition{},
+		applied:       map[string]stream.Config{},
+		reported:      map[string]streamStatus{},
+		shutSig:       shutdown.NewSignaller(),
+	}, nil
+}
+
+// Start begins watching for the definitions of streams in the background.
+func (w *StreamsWatcher) Start() {
+	go w.loop()
+}
+
+// Close stops watching for the definitions of streams, the streams that were
+// created remain within the stream manager.
+func (w *StreamsWatcher) Close(ctx context.Context) error {
+	w.shutSig.CloseNow()
+	select {
+	case <-w.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (w *StreamsWatcher) collectionPath() string {
+	ns := url.PathEscape(w.conf.Namespace)
+	if w.conf.CustomResources {
+		return fmt.Sprintf("/apis/%v/%v/namespaces/%v/%v", StreamConfigGroup, StreamConfigVersion, ns, StreamConfigPlural)
+	}
+	return fmt.Sprintf("/api/v1/namespaces/%v/configmaps?fieldSelector=%v", ns, url.QueryEscape("metadata.name="+w.conf.ConfigMap))
+}
+
+func (w *StreamsWatcher) statusPath(name string) string {
+	return fmt.Sprintf("/apis/%v/%v/namespaces/%v/%v/%v/status",
+		StreamConfigGroup, StreamConfigVersion, url.PathEscape(w.conf.Namespace), StreamConfigPlural, url.PathEscape(name))
+}
+
+func (w *StreamsWatcher) loop() {
+	defer w.shutSig.ShutdownComplete()
+
+	ctx, done := w.shutSig.CloseNowCtx(context.Background())
+	defer done()
+
+	// Statuses are checked periodically in the background of watches in
+	// order to report streams that have stopped by themselves, which is
+	// signalled to the loop as it owns the state.
+	statusTicker := time.NewTicker(w.conf.StatusInterval)
+	defer statusTicker.Stop()
+
+	for {
+		err := w.listAndWatch(ctx, statusTicker.C)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !errors.Is(err, ErrResourceVersionExpired) {
+			w.log.Errorf("Failed to watch stream configs: %v\n", err)
+			select {
+			case <-time.After(w.retryInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+type objectMeta struct {
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+	Generation      int64  `json:"generation"`
+}
+
+type object struct {
+	Metadata objectMeta        `json:"metadata"`
+	Data     map[string]string `json:"data"`
+	Spec     json.RawMessage   `json:"spec"`
+}
+
+type objectList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []object `json:"items"`
+}
+
+func (w *StreamsWatcher) listAndWatch(ctx context.Context, statusTick <-chan time.Time) error {
+	var list objectList
+	if err := w.client.Get(ctx, w.collectionPath(), &list); err != nil {
+		return fmt.Errorf("failed to list stream configs: %w", err)
+	}
+
+	w.objects = map[string]map[string]streamDefinition{}
+	for _, obj := range list.Items {
+		w.objects[obj.Metadata.Name] = w.definitions(obj)
+	}
+	w.reconcile(ctx)
+
+	events := make(chan WatchEvent)
+	watchErr := make(chan error, 1)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		watchErr <- w.client.Watch(watchCtx, w.collectionPath(), list.Metadata.ResourceVersion, func(e WatchEvent) error {
+			select {
+			case events <- e:
+				return nil
+			case <-watchCtx.Done():
+				return watchCtx.Err()
+			}
+		})
+	}()
+
+	for {
+		select {
+		case e := <-events:
+			var obj object
+			if err := json.Unmarshal(e.Object, &obj); err != nil {
+				return fmt.Errorf("failed to decode watch event: %w", err)
+			}
+			switch e.Type {
+			case "ADDED", "MODIFIED":
+				w.objects[obj.Metadata.Name] = w.definitions(obj)
+			case "DELETED":
+				delete(w.objects, obj.Metadata.Name)
+			default:
+				continue
+			}
+			w.reconcile(ctx)
+		case <-statusTick:
+			w.reportStatuses(ctx)
+		case err := <-watchErr:
+			return err
+		}
+	}
+}
+
+// definitions parses the definitions of the streams within a resource.
+func (w *StreamsWatcher) definitions(obj object) map[string]streamDefinition {
+	defs := map[string]streamDefinition{}
+	parse := func(id, source string, confBytes []byte) {
+		def := streamDefinition{generation: obj.Metadata.Generation}
+		def.conf, def.lints, def.err = config.ParseStreamConfig(source, config.ReplaceEnvVariables(confBytes))
+		defs[id] = def
+	}
+
+	if w.conf.CustomResources {
+		// The spec is JSON, which is also valid YAML.
+		parse(obj.Metadata.Name, "streamconfigs/"+obj.Metadata.Name, obj.Spec)
+		return defs
+	}
+
+	for key, value := range obj.Data {
+		id := strings.TrimSuffix(strings.TrimSuffix(key, ".yaml"), ".yml")
+		parse(id, "configmaps/"+obj.Metadata.Name+"/"+key, []byte(value))
+	}
+	return defs
+}
+
+// reconcile applies the definitions of all resources to the stream manager,
+// one stream at a time.
+func (w *StreamsWatcher) reconcile(ctx context.Context) {
+	desired := map[string]streamDefinition{}
+	for _, defs := range w.objects {
+		for id, def := range defs {
+			desired[id] = def
+		}
+	}
+
+	var removed []string
+	for id := range w.applied {
+		if _, exists := desired[id]; !exists {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, id := range removed {
+		if ctx.Err() != nil {
+			return
+		}
+		w.log.Infof("Removing stream %v as its config was deleted\n", id)
+		if err := w.drain(ctx, func(ctx context.Context) error {
+			return w.streams.Delete(ctx, id)
+		}); err != nil && !errors.Is(err, strmmgr.ErrStreamDoesNotExist) {
+			w.log.Errorf("Failed to remove stream %v: %v\n", id, err)
+			continue
+		}
+		delete(w.applied, id)
+		delete(w.reported, id)
+	}
+
+	ids := make([]string, 0, len(desired))
+	for id := range desired {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		def := desired[id]
+		if def.err != nil {
+			// The stream keeps running with its previous config.
+			w.log.Errorf("Failed to parse config of stream %v: %v\n", id, def.err)
+			w.report(ctx, id, streamStatus{Phase: PhaseFailed, Message: def.err.Error(), ObservedGeneration: def.generation})
+			continue
+		}
+
+		if applied, exists := w.applied[id]; !exists || !reflect.DeepEqual(applied, def.conf) {
+			for _, lint := range def.lints {
+				w.log.Warnf("Config lint error: %v\n", lint)
+			}
+			if err := w.apply(ctx, id, def.conf, exists); err != nil {
+				w.log.Errorf("Failed to apply config of stream %v: %v\n", id, err)
+				w.report(ctx, id, streamStatus{Phase: PhaseFailed, Message: err.Error(), ObservedGeneration: def.generation})
+				continue
+			}
+			w.applied[id] = def.conf
+		}
+		w.report(ctx, id, w.currentStatus(id, def.generation))
+	}
+}
+
+func (w *StreamsWatcher) apply(ctx context.Context, id string, conf stream.Config, exists bool) error {
+	if !exists {
+		err := w.streams.Create(id, conf)
+		if errors.Is(err, strmmgr.ErrStreamExists) {
+			// The stream was created by other means, such as the API.
+			exists = true
+		} else {
+			if err == nil {
+				w.log.Infof("Created stream %v\n", id)
+			}
+			return err
+		}
+	}
+
+	err := w.drain(ctx, func(ctx context.Context) error {
+		err := w.streams.Update(ctx, id, conf)
+		if errors.Is(err, strmmgr.ErrStreamDoesNotExist) {
+			err = w.streams.Create(id, conf)
+		}
+		return err
+	})
+	if err == nil {
+		w.log.Infof("Updated stream %v\n", id)
+	}
+	return err
+}
+
+func (w *StreamsWatcher) drain(ctx context.Context, fn func(context.Context) error) error {
+	drainCtx, done := context.WithTimeout(ctx, w.conf.DrainTimeout)
+	defer done()
+	return fn(drainCtx)
+}
+
+func (w *StreamsWatcher) currentStatus(id string, generation int64) streamStatus {
+	status := streamStatus{Phase: PhaseRunning, ObservedGeneration: generation}
+	if s, err := w.streams.Read(id); err != nil {
+		status.Phase, status.Message = PhaseFailed, err.Error()
+	} else if !s.IsRunning() {
+		status.Phase = PhaseStopped
+	}
+	return status
+}
+
+// reportStatuses reports the phases of streams that have changed since they
+// were last reported, such as streams that have stopped by themselves.
+func (w *StreamsWatcher) reportStatuses(ctx context.Context) {
+	for id, reported := range w.reported {
+		if reported.Phase == PhaseFailed {
+			continue
+		}
+		w.report(ctx, id, w.currentStatus(id, reported.ObservedGeneration))
+	}
+}
+
+// report writes the status of a stream into the status subresource of its
+// StreamConfig resource when it differs from what was last reported.
+func (w *StreamsWatcher) report(ctx context.Context, id string, status streamStatus) {
+	if !w.conf.CustomResources {
+		return
+	}
+	if last, exists := w.reported[id]; exists && last == status {
+		return
+	}
+	if err := w.client.MergePatch(ctx, w.statusPath(id), map[string]any{"status": status}); err != nil {
+		w.log.Errorf("Failed to report status of stream %v: %v\n", id, err)
+		return
+	}
+	w.reported[id] = status
+}
@@ -0,0 +1,282 @@
+package kubernetes_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/kubernetes"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	bmanager "github.com/benthosdev/benthos/v4/internal/manager"
+	strmmgr "github.com/benthosdev/benthos/v4/internal/stream/manager"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+type streamStatus struct {
+	Phase              string `json:"phase"`
+	Message            string `json:"message"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+}
+
+type fakeAPIServer struct {
+	t *testing.T
+
+	mut      sync.Mutex
+	list     string
+	expireRV bool
+	events   chan string
+	patches  map[string][]streamStatus
+}
+
+func newFakeAPIServer(t *testing.T, list string) (*fakeAPIServer, *kubernetes.Client) {
+	t.Helper()
+
+	f := &fakeAPIServer{
+		t:       t,
+		list:    list,
+		events:  make(chan string),
+		patches: map[string][]streamStatus{},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(srv.Close)
+	return f, kubernetes.NewClient(srv.URL, "footoken", nil)
+}
+
+func (f *fakeAPIServer) handle(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(f.t, "Bearer footoken", r.Header.Get("Authorization"))
+
+	if r.Method == http.MethodPatch {
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/apis/benthos.dev/v1alpha1/namespaces/foons/streamconfigs/"), "/status")
+		assert.Equal(f.t, "application/merge-patch+json", r.Header.Get("Content-Type"))
+
+		var patch struct {
+			Status streamStatus `json:"status"`
+		}
+		require.NoError(f.t, json.NewDecoder(r.Body).Decode(&patch))
+
+		f.mut.Lock()
+		f.patches[name] = append(f.patches[name], patch.Status)
+		f.mut.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+		return
+	}
+
+	if r.URL.Query().Get("watch") != "true" {
+		f.mut.Lock()
+		list := f.list
+		f.mut.Unlock()
+		_, _ = w.Write([]byte(list))
+		return
+	}
+
+	f.mut.Lock()
+	expire := f.expireRV
+	f.expireRV = false
+	f.mut.Unlock()
+	if expire {
+		_, _ = w.Write([]byte(`{"type":"ERROR","object":{"kind":"Status","code":410,"reason":"Expired","message":"too old resource version"}}`))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	for {
+		select {
+		case e := <-f.events:
+			_, _ = w.Write([]byte(e + "\n"))
+			w.(http.Flusher).Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (f *fakeAPIServer) sendEvent(t *testing.T, eventType, obj string) {
+	t.Helper()
+	select {
+	case f.events <- `{"type":"` + eventType + `","object":` + obj + `}`:
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out sending watch event")
+	}
+}
+
+func (f *fakeAPIServer) lastStatus(name string) (streamStatus, bool) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	patches := f.patches[name]
+	if len(patches) == 0 {
+		return streamStatus{}, false
+	}
+	return patches[len(patches)-1], true
+}
+
+func testStreamsWatcher(t *testing.T, conf kubernetes.StreamsConfig, client *kubernetes.Client) *strmmgr.Type {
+	t.Helper()
+
+	res, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	streams := strmmgr.New(res)
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+		defer done()
+		_ = streams.Stop(ctx)
+	})
+
+	conf.Namespace = "foons"
+	conf.StatusInterval = time.Millisecond * 50
+	w, err := kubernetes.NewStreamsWatcher(conf, client, streams, log.Noop())
+	require.NoError(t, err)
+	w.Start()
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+		defer done()
+		require.NoError(t, w.Close(ctx))
+	})
+	return streams
+}
+
+func generateMapping(t *testing.T, streams *strmmgr.Type, id string) string {
+	t.Helper()
+	s, err := streams.Read(id)
+	if err != nil {
+		return ""
+	}
+	return s.Config().Input.Generate.Mapping
+}
+
+func TestStreamsWatcherConfigErrors(t *testing.T) {
+	client := kubernetes.NewClient("http://localhost:1234", "", nil)
+
+	_, err := kubernetes.NewStreamsWatcher(kubernetes.StreamsConfig{Namespace: "foo"}, client, nil, log.Noop())
+	require.Error(t, err)
+
+	_, err = kubernetes.NewStreamsWatcher(kubernetes.StreamsConfig{ConfigMap: "foo", CustomResources: true, Namespace: "foo"}, client, nil, log.Noop())
+	require.Error(t, err)
+
+	_, err = kubernetes.NewStreamsWatcher(kubernetes.StreamsConfig{ConfigMap: "foo"}, client, nil, log.Noop())
+	require.Error(t, err)
+}
+
+func configMapJSON(t *testing.T, data map[string]string) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{"name": "foomap", "resourceVersion": "2"},
+		"data":     data,
+	})
+	require.NoError(t, err)
+	return string(b)
+}
+
+func streamYAML(mapping string) string {
+	return `
+input:
+  generate:
+    interval: 1s
+    mapping: '` + mapping + `'
+output:
+  drop: {}
+`
+}
+
+func TestStreamsWatcherConfigMap(t *testing.T) {
+	f, client := newFakeAPIServer(t, "")
+	f.list = `{"metadata":{"resourceVersion":"1"},"items":[` + configMapJSON(t, map[string]string{
+		"foo.yaml": streamYAML("root = \"foo\""),
+		"bar":      streamYAML("root = \"bar\""),
+	}) + `]}`
+
+	streams := testStreamsWatcher(t, kubernetes.StreamsConfig{ConfigMap: "foomap"}, client)
+
+	require.Eventually(t, func() bool {
+		return generateMapping(t, streams, "foo") == `root = "foo"` &&
+			generateMapping(t, streams, "bar") == `root = "bar"`
+	}, time.Second*5, time.Millisecond*10)
+
+	f.sendEvent(t, "MODIFIED", configMapJSON(t, map[string]string{
+		"foo.yaml": streamYAML("root = \"foo2\""),
+	}))
+
+	require.Eventually(t, func() bool {
+		return generateMapping(t, streams, "foo") == `root = "foo2"` &&
+			generateMapping(t, streams, "bar") == ""
+	}, time.Second*5, time.Millisecond*10)
+
+	// An invalid config leaves the stream running with its previous config.
+	f.sendEvent(t, "MODIFIED", configMapJSON(t, map[string]string{
+		"foo.yaml": "input: [ nope",
+		"baz.yml":  streamYAML("root = \"baz\""),
+	}))
+
+	require.Eventually(t, func() bool {
+		return generateMapping(t, streams, "baz") == `root = "baz"`
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, `root = "foo2"`, generateMapping(t, streams, "foo"))
+
+	f.sendEvent(t, "DELETED", configMapJSON(t, nil))
+
+	require.Eventually(t, func() bool {
+		return generateMapping(t, streams, "foo") == "" &&
+			generateMapping(t, streams, "baz") == ""
+	}, time.Second*5, time.Millisecond*10)
+
+	f.mut.Lock()
+	assert.Empty(t, f.patches)
+	f.mut.Unlock()
+}
+
+func streamConfigJSON(name string, generation int, spec string) string {
+	return fmt.Sprintf(`{"metadata":{"name":%q,"resourceVersion":"3","generation":%v},"spec":%v}`, name, generation, spec)
+}
+
+func TestStreamsWatcherCustomResources(t *testing.T) {
+	f, client := newFakeAPIServer(t, `{"metadata":{"resourceVersion":"1"},"items":[`+
+		streamConfigJSON("foo", 1, `{"input":{"generate":{"interval":"1s","mapping":"root = \"foo\""}},"output":{"drop":{}}}`)+
+		`]}`)
+	f.expireRV = true
+
+	streams := testStreamsWatcher(t, kubernetes.StreamsConfig{CustomResources: true}, client)
+
+	require.Eventually(t, func() bool {
+		s, ok := f.lastStatus("foo")
+		return ok && s == streamStatus{Phase: kubernetes.PhaseRunning, ObservedGeneration: 1}
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, `root = "foo"`, generateMapping(t, streams, "foo"))
+
+	f.sendEvent(t, "ADDED", streamConfigJSON("bar", 1, `{"input":{"nope":{}},"output":{"drop":{}}}`))
+
+	require.Eventually(t, func() bool {
+		s, ok := f.lastStatus("bar")
+		return ok && s.Phase == kubernetes.PhaseFailed && s.Message != "" && s.ObservedGeneration == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	f.sendEvent(t, "MODIFIED", streamConfigJSON("foo", 2, `{"input":{"generate":{"count":1,"interval":"","mapping":"root = \"foo2\""}},"output":{"drop":{}}}`))
+
+	require.Eventually(t, func() bool {
+		return generateMapping(t, streams, "foo") == `root = "foo2"`
+	}, time.Second*5, time.Millisecond*10)
+
+	// The stream stops by itself once its single message is generated, which
+	// is reported by the periodic status check.
+	require.Eventually(t, func() bool {
+		s, ok := f.lastStatus("foo")
+		return ok && s == streamStatus{Phase: kubernetes.PhaseStopped, ObservedGeneration: 2}
+	}, time.Second*5, time.Millisecond*10)
+
+	f.sendEvent(t, "DELETED", streamConfigJSON("foo", 2, `{}`))
+
+	require.Eventually(t, func() bool {
+		_, err := streams.Read("foo")
+		return err == strmmgr.ErrStreamDoesNotExist
+	}, time.Second*5, time.Millisecond*10)
+}
@@ -0,0 +1,50 @@
+// Package leader provides mechanisms for electing a leader amongst multiple
+// instances of Benthos, which is used in order to run singleton inputs on only
+// one instance at a time.
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// Election is a campaign for the leadership of a single key, where at most one
+// participant of an election is the leader at any given time.
+type Election interface {
+	// Campaign blocks until either leadership is acquired, the context is
+	// cancelled or an attempt fails with an error. When leadership is acquired
+	// a channel is returned that is closed when leadership is lost.
+	Campaign(ctx context.Context) (<-chan struct{}, error)
+
+	// Resign gives up leadership when it is held, allowing other participants
+	// to become the leader.
+	Resign(ctx context.Context) error
+
+	// Close resigns and releases all resources of the election.
+	Close(ctx context.Context) error
+}
+
+// New creates an election of a key from a leader election config.
+func New(conf Config, key string, logger log.Modular) (Election, error) {
+	pollInterval, err := time.ParseDuration(conf.PollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse poll_interval: %w", err)
+	}
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("poll_interval must be greater than zero")
+	}
+
+	key = conf.KeyPrefix + key
+	switch conf.Type {
+	case "sql":
+		return newSQLElection(conf.SQL, key, pollInterval, logger)
+	case "kubernetes":
+		return newKubernetesElection(conf.Kubernetes, key, pollInterval, logger)
+	case "", "none":
+		return nil, fmt.Errorf("leader election is not configured")
+	}
+	return nil, fmt.Errorf("leader election type '%v' was not recognised", conf.Type)
+}
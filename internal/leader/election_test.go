@@ -0,0 +1,88 @@
+package leader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+func TestNewElectionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   func(c *Config)
+		errStr string
+	}{
+		{
+			name:   "not configured",
+			conf:   func(c *Config) {},
+			errStr: "leader election is not configured",
+		},
+		{
+			name:   "unknown type",
+			conf:   func(c *Config) { c.Type = "zookeeper" },
+			errStr: "leader election type 'zookeeper' was not recognised",
+		},
+		{
+			name: "bad poll interval",
+			conf: func(c *Config) {
+				c.Type = "sql"
+				c.PollInterval = "0s"
+			},
+			errStr: "poll_interval must be greater than zero",
+		},
+		{
+			name: "unsupported driver",
+			conf: func(c *Config) {
+				c.Type = "sql"
+				c.SQL.Driver = "sqlite"
+			},
+			errStr: "sql driver 'sqlite' does not support leader election",
+		},
+		{
+			name:   "no dsn",
+			conf:   func(c *Config) { c.Type = "sql" },
+			errStr: "a dsn must be specified for sql leader election",
+		},
+		{
+			name:   "kubernetes outside of a cluster",
+			conf:   func(c *Config) { c.Type = "kubernetes" },
+			errStr: "kubernetes leader election must run within a cluster, but KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set",
+		},
+	}
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := NewConfig()
+			test.conf(&conf)
+
+			_, err := New(conf, "foo", log.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestSQLLockKeys(t *testing.T) {
+	assert.Equal(t, postgresLockID("benthos_foo"), postgresLockID("benthos_foo"))
+	assert.NotEqual(t, postgresLockID("benthos_foo"), postgresLockID("benthos_bar"))
+
+	classID, objID := postgresLockParts(-1)
+	assert.Equal(t, int64(0xffffffff), classID)
+	assert.Equal(t, int64(0xffffffff), objID)
+
+	classID, objID = postgresLockParts(1<<32 + 5)
+	assert.Equal(t, int64(1), classID)
+	assert.Equal(t, int64(5), objID)
+
+	assert.Equal(t, "benthos_foo", mysqlLockName("benthos_foo"))
+
+	long := strings.Repeat("a", mysqlMaxLockName+1)
+	assert.Len(t, mysqlLockName(long), mysqlMaxLockName)
+	assert.NotEqual(t, mysqlLockName(long), mysqlLockName(long+"b"))
+}
@@ -0,0 +1,431 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// The directory that the credentials of the service account of a pod are
+// mounted to.
+const kubernetesServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// The format of the MicroTime fields of a lease.
+const kubernetesMicroTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// The maximum length of lease names, which is limited to that of a label in
+// order to be valid for any purpose.
+const kubernetesMaxLeaseName = 63
+
+type kubernetesObjectMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type kubernetesLeaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity,omitempty"`
+	LeaseDurationSeconds int64  `json:"leaseDurationSeconds,omitempty"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int64  `json:"leaseTransitions,omitempty"`
+}
+
+type kubernetesLease struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Metadata   kubernetesObjectMeta `json:"metadata"`
+	Spec       kubernetesLeaseSpec  `json:"spec"`
+}
+
+// kubernetesLeaseName converts an election key into a valid lease name, where
+// keys that had to be altered are suffixed with a hash in order to keep them
+// distinct.
+func kubernetesLeaseName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(key))
+	name = strings.Trim(name, "-.")
+	if name == key && len(name) <= kubernetesMaxLeaseName {
+		return name
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	suffix := fmt.Sprintf("%08x", h.Sum32())
+	if maxLen := kubernetesMaxLeaseName - len(suffix) - 1; len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], "-.")
+	}
+	if name == "" {
+		return suffix
+	}
+	return name + "-" + suffix
+}
+
+// kubernetesElection elects leaders with a Lease object of the coordination
+// API, which is held for as long as the leader keeps renewing it. Leases are
+// updated with the resource version they were read at, and therefore only one
+// of the instances that attempt to take over a lease at the same time
+// succeeds.
+type kubernetesElection struct {
+	client        *http.Client
+	baseURL       string
+	tokenFn       func() (string, error)
+	namespace     string
+	name          string
+	identity      string
+	leaseDuration time.Duration
+	pollInterval  time.Duration
+	log           log.Modular
+
+	// The time at which the lease of another holder was last seen to change,
+	// which is measured with our own clock in order to not depend on the
+	// clocks of instances being synchronised.
+	observedVersion string
+	observedAt      time.Time
+
+	mut     sync.Mutex
+	leading bool
+	lost    chan struct{}
+	stopHB  chan struct{}
+	hbDone  chan struct{}
+}
+
+func newKubernetesElection(conf KubernetesConfig, key string, pollInterval time.Duration, logger log.Modular) (*kubernetesElection, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes leader election must run within a cluster, but KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+	}
+
+	caBytes, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("service account certificate contains no valid certificates")
+	}
+
+	namespace := conf.Namespace
+	if namespace == "" {
+		nsBytes, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read service account namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(nsBytes))
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	// Tokens of service accounts are rotated, and so they're read for each
+	// request.
+	tokenFn := func() (string, error) {
+		tokenBytes, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "token"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return strings.TrimSpace(string(tokenBytes)), nil
+	}
+
+	return newKubernetesElectionWithClient(conf, key, pollInterval, logger, client, "https://"+net.JoinHostPort(host, port), namespace, tokenFn)
+}
+
+func newKubernetesElectionWithClient(
+	conf KubernetesConfig,
+	key string,
+	pollInterval time.Duration,
+	logger log.Modular,
+	client *http.Client,
+	baseURL, namespace string,
+	tokenFn func() (string, error),
+) (*kubernetesElection, error) {
+	leaseDuration, err := time.ParseDuration(conf.LeaseDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubernetes.lease_duration: %w", err)
+	}
+	if leaseDuration < time.Second {
+		return nil, errors.New("kubernetes.lease_duration must be at least one second")
+	}
+	if pollInterval >= leaseDuration {
+		return nil, errors.New("poll_interval must be less than kubernetes.lease_duration")
+	}
+	if namespace == "" {
+		return nil, errors.New("a namespace must be specified for kubernetes leader election")
+	}
+
+	hostname, _ := os.Hostname()
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubernetesElection{
+		client:        client,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		tokenFn:       tokenFn,
+		namespace:     namespace,
+		name:          kubernetesLeaseName(key),
+		identity:      hostname + "_" + id.String(),
+		leaseDuration: leaseDuration,
+		pollInterval:  pollInterval,
+		log:           logger,
+	}, nil
+}
+
+func (e *kubernetesElection) leasesPath() string {
+	return "/apis/coordination.k8s.io/v1/namespaces/" + e.namespace + "/leases"
+}
+
+// do performs a request against the API server and returns the status code of
+// the response, decoding the body into out when it's successful.
+func (e *kubernetesElection) do(ctx context.Context, method, path string, body, out any) (int, error) {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+path, reqBody)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token, err := e.tokenFn()
+	if err != nil {
+		return 0, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		if out != nil {
+			if err := json.Unmarshal(resBytes, out); err != nil {
+				return res.StatusCode, fmt.Errorf("failed to decode lease: %w", err)
+			}
+		}
+	case res.StatusCode == http.StatusNotFound, res.StatusCode == http.StatusConflict:
+	default:
+		return res.StatusCode, fmt.Errorf("%v request of lease %v failed with status %v: %s", method, e.name, res.StatusCode, bytes.TrimSpace(resBytes))
+	}
+	return res.StatusCode, nil
+}
+
+// tryAcquireOrRenew takes the lease when it's free or has expired and renews
+// it when it's already held, returning whether it's held by this instance.
+func (e *kubernetesElection) tryAcquireOrRenew(ctx context.Context) (bool, error) {
+	now := time.Now()
+	nowStr := now.UTC().Format(kubernetesMicroTime)
+
+	var lease kubernetesLease
+	status, err := e.do(ctx, http.MethodGet, e.leasesPath()+"/"+e.name, nil, &lease)
+	if err != nil {
+		return false, err
+	}
+
+	if status == http.StatusNotFound {
+		lease = kubernetesLease{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   kubernetesObjectMeta{Name: e.name, Namespace: e.namespace},
+			Spec: kubernetesLeaseSpec{
+				HolderIdentity:       e.identity,
+				LeaseDurationSeconds: int64(e.leaseDuration / time.Second),
+				AcquireTime:          nowStr,
+				RenewTime:            nowStr,
+			},
+		}
+		if status, err = e.do(ctx, http.MethodPost, e.leasesPath(), &lease, nil); err != nil {
+			return false, err
+		}
+		// A conflict means another instance created the lease first.
+		return status != http.StatusConflict, nil
+	}
+
+	if holder := lease.Spec.HolderIdentity; holder != "" && holder != e.identity {
+		if lease.Metadata.ResourceVersion != e.observedVersion {
+			e.observedVersion = lease.Metadata.ResourceVersion
+			e.observedAt = now
+		}
+		duration := time.Duration(lease.Spec.LeaseDurationSeconds) * time.Second
+		if duration <= 0 {
+			duration = e.leaseDuration
+		}
+		if now.Sub(e.observedAt) < duration {
+			return false, nil
+		}
+	}
+
+	if lease.Spec.HolderIdentity != e.identity {
+		lease.Spec.AcquireTime = nowStr
+		lease.Spec.LeaseTransitions++
+	}
+	lease.Spec.HolderIdentity = e.identity
+	lease.Spec.LeaseDurationSeconds = int64(e.leaseDuration / time.Second)
+	lease.Spec.RenewTime = nowStr
+
+	// The lease is updated at the resource version it was read at, and so a
+	// conflict means another instance updated it in the meantime.
+	if status, err = e.do(ctx, http.MethodPut, e.leasesPath()+"/"+e.name, &lease, nil); err != nil {
+		return false, err
+	}
+	return status != http.StatusConflict && status != http.StatusNotFound, nil
+}
+
+// Campaign attempts to take the lease of the election every poll interval
+// until it succeeds.
+func (e *kubernetesElection) Campaign(ctx context.Context) (<-chan struct{}, error) {
+	e.mut.Lock()
+	if e.leading {
+		lost := e.lost
+		e.mut.Unlock()
+		return lost, nil
+	}
+	e.mut.Unlock()
+
+	for {
+		acquired, err := e.tryAcquireOrRenew(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return e.lead(), nil
+		}
+		select {
+		case <-time.After(e.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// lead renews the lease every poll interval, and gives up leadership when the
+// lease is taken by another instance or when it could not be renewed within
+// its duration.
+func (e *kubernetesElection) lead() <-chan struct{} {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	e.leading = true
+	e.lost = make(chan struct{})
+	e.stopHB = make(chan struct{})
+	e.hbDone = make(chan struct{})
+
+	go func(lost, stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(e.pollInterval)
+		defer ticker.Stop()
+
+		renewedAt := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), e.pollInterval)
+			held, err := e.tryAcquireOrRenew(ctx)
+			cancel()
+			if err == nil && held {
+				renewedAt = time.Now()
+				continue
+			}
+
+			if err == nil {
+				e.log.Errorf("Lost leadership of '%v' as its lease was taken by another instance\n", e.name)
+			} else if time.Since(renewedAt) >= e.leaseDuration {
+				e.log.Errorf("Lost leadership of '%v' as its lease could not be renewed: %v\n", e.name, err)
+			} else {
+				e.log.Warnf("Failed to renew lease '%v': %v\n", e.name, err)
+				continue
+			}
+
+			e.mut.Lock()
+			e.leading = false
+			e.mut.Unlock()
+			close(lost)
+			return
+		}
+	}(e.lost, e.stopHB, e.hbDone)
+
+	return e.lost
+}
+
+// Resign frees the lease of the election when it's held, allowing other
+// instances to take it immediately.
+func (e *kubernetesElection) Resign(ctx context.Context) error {
+	e.mut.Lock()
+	leading, stop, done := e.leading, e.stopHB, e.hbDone
+	e.leading, e.stopHB, e.hbDone = false, nil, nil
+	e.mut.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if !leading {
+		return nil
+	}
+
+	var lease kubernetesLease
+	status, err := e.do(ctx, http.MethodGet, e.leasesPath()+"/"+e.name, nil, &lease)
+	if err != nil || status == http.StatusNotFound || lease.Spec.HolderIdentity != e.identity {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = ""
+	lease.Spec.LeaseDurationSeconds = 1
+	lease.Spec.RenewTime = time.Now().UTC().Format(kubernetesMicroTime)
+	_, err = e.do(ctx, http.MethodPut, e.leasesPath()+"/"+e.name, &lease, nil)
+	return err
+}
+
+// Close resigns the election.
+func (e *kubernetesElection) Close(ctx context.Context) error {
+	err := e.Resign(ctx)
+	e.client.CloseIdleConnections()
+	return err
+}
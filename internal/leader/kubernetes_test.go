@@ -0,0 +1,211 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// fakeLeaseServer mimics the lease endpoints of the coordination API,
+// including the rejection of updates at stale resource versions.
+type fakeLeaseServer struct {
+	mut     sync.Mutex
+	version int
+	leases  map[string]kubernetesLease
+}
+
+func (f *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer footoken" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	const prefix = "/apis/coordination.k8s.io/v1/namespaces/foons/leases"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	var lease kubernetesLease
+	if r.Method != http.MethodGet {
+		if err := json.NewDecoder(r.Body).Decode(&lease); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		existing, exists := f.leases[name]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(existing)
+		return
+	case http.MethodPost:
+		name = lease.Metadata.Name
+		if _, exists := f.leases[name]; exists {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	case http.MethodPut:
+		existing, exists := f.leases[name]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if existing.Metadata.ResourceVersion != lease.Metadata.ResourceVersion {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+
+	f.version++
+	lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	f.leases[name] = lease
+	_ = json.NewEncoder(w).Encode(lease)
+}
+
+func (f *fakeLeaseServer) holder(name string) string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.leases[name].Spec.HolderIdentity
+}
+
+func (f *fakeLeaseServer) setHolder(name, holder string, durationSeconds int64) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.version++
+	lease := f.leases[name]
+	lease.Metadata.Name = name
+	lease.Metadata.ResourceVersion = strconv.Itoa(f.version)
+	lease.Spec.HolderIdentity = holder
+	lease.Spec.LeaseDurationSeconds = durationSeconds
+	f.leases[name] = lease
+}
+
+func testKubernetesElection(t *testing.T, srv *httptest.Server) *kubernetesElection {
+	t.Helper()
+
+	e, err := newKubernetesElectionWithClient(KubernetesConfig{
+		LeaseDuration: "1s",
+	}, "benthos_foo", time.Millisecond*10, log.Noop(), srv.Client(), srv.URL, "foons", func() (string, error) {
+		return "footoken", nil
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = e.Close(context.Background())
+	})
+	return e
+}
+
+func TestKubernetesLeaseName(t *testing.T) {
+	assert.Equal(t, "foo-bar.baz", kubernetesLeaseName("foo-bar.baz"))
+
+	name := kubernetesLeaseName("benthos_foo")
+	assert.True(t, strings.HasPrefix(name, "benthos-foo-"), name)
+	assert.NotEqual(t, name, kubernetesLeaseName("benthos-foo"))
+	assert.NotEqual(t, name, kubernetesLeaseName("Benthos_foo"))
+
+	long := kubernetesLeaseName(strings.Repeat("a", 100))
+	assert.Len(t, long, kubernetesMaxLeaseName)
+	assert.NotEqual(t, long, kubernetesLeaseName(strings.Repeat("a", 101)))
+
+	assert.Len(t, kubernetesLeaseName("___"), 8)
+}
+
+func TestKubernetesElectionConfigErrors(t *testing.T) {
+	tokenFn := func() (string, error) { return "", nil }
+
+	_, err := newKubernetesElectionWithClient(KubernetesConfig{LeaseDuration: "1s"}, "foo", time.Second, log.Noop(), http.DefaultClient, "http://localhost", "foons", tokenFn)
+	require.EqualError(t, err, "poll_interval must be less than kubernetes.lease_duration")
+
+	_, err = newKubernetesElectionWithClient(KubernetesConfig{LeaseDuration: "100ms"}, "foo", time.Millisecond, log.Noop(), http.DefaultClient, "http://localhost", "foons", tokenFn)
+	require.EqualError(t, err, "kubernetes.lease_duration must be at least one second")
+
+	_, err = newKubernetesElectionWithClient(KubernetesConfig{LeaseDuration: "1s"}, "foo", time.Millisecond, log.Noop(), http.DefaultClient, "http://localhost", "", tokenFn)
+	require.EqualError(t, err, "a namespace must be specified for kubernetes leader election")
+}
+
+func TestKubernetesElectionFailover(t *testing.T) {
+	fake := &fakeLeaseServer{leases: map[string]kubernetesLease{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	a, b := testKubernetesElection(t, srv), testKubernetesElection(t, srv)
+
+	lostA, err := a.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, a.identity, fake.holder(a.name))
+
+	// The lease is renewed by the leader, and so no other instance can take
+	// it over.
+	shortCtx, shortDone := context.WithTimeout(ctx, time.Millisecond*1500)
+	_, err = b.Campaign(shortCtx)
+	shortDone()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, a.Resign(ctx))
+	select {
+	case <-lostA:
+		t.Fatal("resigning should not signal a loss of leadership")
+	default:
+	}
+
+	lostB, err := b.Campaign(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, b.identity, fake.holder(b.name))
+
+	// Leadership is lost when another instance takes the lease.
+	fake.setHolder(b.name, "other", 15)
+	select {
+	case <-lostB:
+	case <-ctx.Done():
+		t.Fatal("leadership was not lost")
+	}
+}
+
+func TestKubernetesElectionExpiredLease(t *testing.T) {
+	fake := &fakeLeaseServer{leases: map[string]kubernetesLease{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	e := testKubernetesElection(t, srv)
+
+	// The lease of an instance that stopped without resigning is taken over
+	// once it hasn't changed for its duration.
+	fake.setHolder(e.name, "crashed", 1)
+
+	start := time.Now()
+	_, err := e.Campaign(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, e.identity, fake.holder(e.name))
+
+	fake.mut.Lock()
+	assert.Equal(t, int64(1), fake.leases[e.name].Spec.LeaseTransitions)
+	fake.mut.Unlock()
+}
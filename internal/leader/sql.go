@@ -0,0 +1,252 @@
+package leader
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// The maximum length of the names of MySQL locks.
+const mysqlMaxLockName = 64
+
+// sqlElection elects leaders with session level advisory locks, which are held
+// for as long as the connection that acquired them remains open. The drivers
+// are registered by the SQL components, and are therefore available whenever
+// those components are part of the build.
+type sqlElection struct {
+	db           *sql.DB
+	driver       string
+	key          string
+	pollInterval time.Duration
+	log          log.Modular
+
+	mut    sync.Mutex
+	conn   *sql.Conn
+	lost   chan struct{}
+	stopHB chan struct{}
+	hbDone chan struct{}
+}
+
+func newSQLElection(conf SQLConfig, key string, pollInterval time.Duration, logger log.Modular) (*sqlElection, error) {
+	switch conf.Driver {
+	case "postgres", "mysql":
+	default:
+		return nil, fmt.Errorf("sql driver '%v' does not support leader election", conf.Driver)
+	}
+	if conf.DSN == "" {
+		return nil, errors.New("a dsn must be specified for sql leader election")
+	}
+
+	db, err := sql.Open(conf.Driver, conf.DSN)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlElection{
+		db:           db,
+		driver:       conf.Driver,
+		key:          key,
+		pollInterval: pollInterval,
+		log:          logger,
+	}, nil
+}
+
+// postgresLockID returns the key of a postgres advisory lock, which is a 64 bit
+// integer.
+func postgresLockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// mysqlLockName returns the name of a MySQL lock, where keys that are too long
+// are hashed.
+func mysqlLockName(key string) string {
+	if len(key) <= mysqlMaxLockName {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// postgresLockParts returns the values of the classid and objid columns of
+// pg_locks for an advisory lock of a 64 bit key, which are its high and low
+// 32 bits respectively.
+func postgresLockParts(id int64) (classID, objID int64) {
+	return int64(uint64(id) >> 32), int64(uint32(id))
+}
+
+// held returns whether the session of a connection still holds the lock of
+// the election, as a healthy connection does not guarantee that the lock
+// hasn't been released, for example by an administrator.
+func (e *sqlElection) held(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var held bool
+	switch e.driver {
+	case "postgres":
+		classID, objID := postgresLockParts(postgresLockID(e.key))
+		err := conn.QueryRowContext(ctx, `SELECT EXISTS (
+  SELECT 1 FROM pg_locks WHERE locktype = 'advisory' AND granted AND pid = pg_backend_pid()
+  AND classid::bigint = $1 AND objid::bigint = $2 AND objsubid = 1
+)`, classID, objID).Scan(&held)
+		if err != nil {
+			return false, err
+		}
+	case "mysql":
+		var res sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", mysqlLockName(e.key)).Scan(&res); err != nil {
+			return false, err
+		}
+		held = res.Valid && res.Int64 == 1
+	}
+	return held, nil
+}
+
+func (e *sqlElection) tryAcquire(ctx context.Context) (*sql.Conn, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	switch e.driver {
+	case "postgres":
+		err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", postgresLockID(e.key)).Scan(&acquired)
+	case "mysql":
+		var res sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", mysqlLockName(e.key)).Scan(&res)
+		acquired = res.Valid && res.Int64 == 1
+	}
+	if err != nil || !acquired {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (e *sqlElection) release(ctx context.Context, conn *sql.Conn) error {
+	var err error
+	switch e.driver {
+	case "postgres":
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", postgresLockID(e.key))
+	case "mysql":
+		_, err = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", mysqlLockName(e.key))
+	}
+
+	// The lock is released by the database when the connection closes, and
+	// so the error of releasing it explicitly isn't critical.
+	_ = conn.Close()
+	return err
+}
+
+// Campaign attempts to acquire the lock of the election every poll interval
+// until it succeeds.
+func (e *sqlElection) Campaign(ctx context.Context) (<-chan struct{}, error) {
+	e.mut.Lock()
+	if e.conn != nil {
+		lost := e.lost
+		e.mut.Unlock()
+		return lost, nil
+	}
+	e.mut.Unlock()
+
+	for {
+		conn, err := e.tryAcquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			return e.lead(conn), nil
+		}
+		select {
+		case <-time.After(e.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// lead holds the connection of an acquired lock and checks that it still
+// holds the lock every poll interval, as the lock is released when the
+// connection fails.
+func (e *sqlElection) lead(conn *sql.Conn) <-chan struct{} {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	e.conn = conn
+	e.lost = make(chan struct{})
+	e.stopHB = make(chan struct{})
+	e.hbDone = make(chan struct{})
+
+	go func(lost, stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(e.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), e.pollInterval)
+			held, err := e.held(ctx, conn)
+			cancel()
+			if err == nil && held {
+				continue
+			}
+
+			if err != nil {
+				e.log.Errorf("Lost leadership of '%v' due to a failed connection: %v\n", e.key, err)
+			} else {
+				e.log.Errorf("Lost leadership of '%v' as its lock is no longer held\n", e.key)
+			}
+			e.mut.Lock()
+			if e.conn == conn {
+				_ = conn.Close()
+				e.conn = nil
+			}
+			e.mut.Unlock()
+			close(lost)
+			return
+		}
+	}(e.lost, e.stopHB, e.hbDone)
+
+	return e.lost
+}
+
+// Resign releases the lock of the election when it's held.
+func (e *sqlElection) Resign(ctx context.Context) error {
+	e.mut.Lock()
+	conn, stop, done := e.conn, e.stopHB, e.hbDone
+	e.conn, e.stopHB, e.hbDone = nil, nil, nil
+	e.mut.Unlock()
+
+	// The heartbeat is stopped before releasing the lock, as it would
+	// otherwise race with it over the connection.
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	if conn == nil {
+		return nil
+	}
+	return e.release(ctx, conn)
+}
+
+// Close resigns the election and closes the database.
+func (e *sqlElection) Close(ctx context.Context) error {
+	err := e.Resign(ctx)
+	if cErr := e.db.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package lineage
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// Config contains configuration parameters for lineage metadata.
+type Config struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
+
+// NewConfig creates a lineage config with default values, which disables
+// lineage metadata.
+func NewConfig() Config {
+	return Config{
+		Enabled: false,
+	}
+}
+
+// FieldSpec returns a spec for a common lineage field.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject("lineage", `
+Attach standard lineage metadata to each message as it is consumed from the input:
+
+- `+"`"+MetaSource+"`"+`: The label or path of the input the message was consumed from.
+- `+"`"+MetaSourceID+"`"+`: An identifier of the position of the message within its source, derived from the metadata of inputs such as `+"`kafka`, `aws_sqs` and `aws_s3`"+`.
+- `+"`"+MetaIngestTimestamp+"`"+`: The time at which the message was consumed, in RFC 3339 format.
+- `+"`"+MetaBatchIndex+"`"+`: The index of the message within the batch it was consumed in.
+- `+"`"+MetaRetryCount+"`"+`: The number of times the message has been rejected and redelivered, which is only known for messages with a source identifier.
+
+Metadata is carried along with messages through processors, and messages that already contain lineage metadata, such as those bridged from another stream, keep their original values.`,
+	).WithChildren(
+		docs.FieldBool("enabled", "Whether lineage metadata should be added to messages.").HasDefault(false),
+	).Advanced().AtVersion("4.9.0").ChildDefaultAndTypesFromStruct(NewConfig())
+}
@@ -0,0 +1,192 @@
+package lineage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+// Standard lineage metadata keys.
+const (
+	MetaSource          = "benthos_lineage_source"
+	MetaSourceID        = "benthos_lineage_source_id"
+	MetaIngestTimestamp = "benthos_lineage_ingest_timestamp"
+	MetaBatchIndex      = "benthos_lineage_batch_index"
+	MetaRetryCount      = "benthos_lineage_retry_count"
+)
+
+// maxTrackedRetries is the maximum number of rejected source identifiers that
+// are remembered in order to count redeliveries, beyond which the oldest
+// counts are forgotten.
+const maxTrackedRetries = 1024
+
+// Layer is a stream layer that consumes transactions from an input and adds
+// lineage metadata to each message before forwarding them downstream.
+type Layer struct {
+	source string
+	nowFn  func() time.Time
+
+	retriesMut   sync.Mutex
+	retries      map[string]int
+	retriesOrder []string
+
+	transactionsIn  <-chan message.Transaction
+	transactionsOut chan message.Transaction
+
+	shutSig *shutdown.Signaller
+}
+
+// NewLayer creates a new lineage layer where the source is the label or path
+// of the input that transactions are consumed from.
+func NewLayer(source string) *Layer {
+	return &Layer{
+		source:          source,
+		nowFn:           time.Now,
+		retries:         map[string]int{},
+		transactionsOut: make(chan message.Transaction),
+		shutSig:         shutdown.NewSignaller(),
+	}
+}
+
+func (l *Layer) retryCount(id string) int {
+	l.retriesMut.Lock()
+	defer l.retriesMut.Unlock()
+	return l.retries[id]
+}
+
+func (l *Layer) resolveRetries(ids []string, err error) {
+	l.retriesMut.Lock()
+	defer l.retriesMut.Unlock()
+
+	for _, id := range ids {
+		if err == nil {
+			delete(l.retries, id)
+			continue
+		}
+		if _, exists := l.retries[id]; !exists {
+			l.retriesOrder = append(l.retriesOrder, id)
+		}
+		l.retries[id]++
+	}
+
+	// Forget the oldest counts once we exceed our limit, ids that have since
+	// been acknowledged may still be present in the order and are skipped.
+	for len(l.retries) > maxTrackedRetries && len(l.retriesOrder) > 0 {
+		delete(l.retries, l.retriesOrder[0])
+		l.retriesOrder = l.retriesOrder[1:]
+	}
+	if len(l.retries) == 0 {
+		l.retriesOrder = nil
+	} else if len(l.retriesOrder) > 2*maxTrackedRetries {
+		order := make([]string, 0, len(l.retries))
+		for _, id := range l.retriesOrder {
+			if _, exists := l.retries[id]; exists {
+				order = append(order, id)
+			}
+		}
+		l.retriesOrder = order
+	}
+}
+
+// annotate adds lineage metadata to each message of a batch and returns the
+// unique source identifiers of the messages that were annotated, such that a
+// rejected batch counts as a single delivery attempt of each source position
+// regardless of how many of its messages share it.
+func (l *Layer) annotate(batch message.Batch) (ids []string) {
+	ingestedAt := l.nowFn().Format(time.RFC3339Nano)
+	seen := map[string]struct{}{}
+	for i, p := range batch {
+		if p.MetaGet(MetaSource) != "" {
+			continue
+		}
+		p.MetaSet(MetaSource, l.source)
+		p.MetaSet(MetaIngestTimestamp, ingestedAt)
+		p.MetaSet(MetaBatchIndex, strconv.Itoa(i))
+		id, exists := SourceID(func(key string) (string, bool) {
+			v := p.MetaGet(key)
+			return v, v != ""
+		})
+		if !exists {
+			continue
+		}
+		p.MetaSet(MetaSourceID, id)
+		p.MetaSet(MetaRetryCount, strconv.Itoa(l.retryCount(id)))
+		if _, exists := seen[id]; !exists {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
+func (l *Layer) loop() {
+	defer func() {
+		close(l.transactionsOut)
+		l.shutSig.ShutdownComplete()
+	}()
+
+	for {
+		var ts message.Transaction
+		var open bool
+		select {
+		case ts, open = <-l.transactionsIn:
+			if !open {
+				return
+			}
+		case <-l.shutSig.CloseNowChan():
+			return
+		}
+
+		outTs := ts
+		if ids := l.annotate(ts.Payload); len(ids) > 0 {
+			tracked := message.NewTransactionFunc(ts.Payload, func(ctx context.Context, err error) error {
+				l.resolveRetries(ids, err)
+				return ts.Ack(ctx, err)
+			})
+			outTs = *tracked.WithContext(ts.Context())
+		}
+
+		select {
+		case l.transactionsOut <- outTs:
+		case <-l.shutSig.CloseNowChan():
+			return
+		}
+	}
+}
+
+// Consume starts the layer consuming transactions from a channel.
+func (l *Layer) Consume(msgs <-chan message.Transaction) error {
+	if l.transactionsIn != nil {
+		return component.ErrAlreadyStarted
+	}
+	l.transactionsIn = msgs
+	go l.loop()
+	return nil
+}
+
+// TransactionChan returns the channel used for consuming transactions from
+// this layer.
+func (l *Layer) TransactionChan() <-chan message.Transaction {
+	return l.transactionsOut
+}
+
+// TriggerCloseNow signals that the layer should close immediately.
+func (l *Layer) TriggerCloseNow() {
+	l.shutSig.CloseNow()
+}
+
+// WaitForClose blocks until the layer has closed down or the context is
+// cancelled.
+func (l *Layer) WaitForClose(ctx context.Context) error {
+	select {
+	case <-l.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package lineage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func TestLayerMetadata(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	l := NewLayer("foo")
+	l.nowFn = func() time.Time {
+		return time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, l.Consume(tChan))
+
+	newBatch := func() message.Batch {
+		b := message.QuickBatch([][]byte{[]byte("a"), []byte("b")})
+		b.Get(0).MetaSet("sqs_message_id", "abc")
+		return b
+	}
+
+	send := func(b message.Batch) (message.Transaction, <-chan error) {
+		resChan := make(chan error, 1)
+		select {
+		case tChan <- message.NewTransaction(b, resChan):
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+		select {
+		case ts := <-l.TransactionChan():
+			return ts, resChan
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+		return message.Transaction{}, nil
+	}
+
+	ts, resChan := send(newBatch())
+	assert.Equal(t, "foo", ts.Payload.Get(0).MetaGet(MetaSource))
+	assert.Equal(t, "2022-10-01T12:00:00Z", ts.Payload.Get(0).MetaGet(MetaIngestTimestamp))
+	assert.Equal(t, "0", ts.Payload.Get(0).MetaGet(MetaBatchIndex))
+	assert.Equal(t, "sqs:abc", ts.Payload.Get(0).MetaGet(MetaSourceID))
+	assert.Equal(t, "0", ts.Payload.Get(0).MetaGet(MetaRetryCount))
+
+	assert.Equal(t, "foo", ts.Payload.Get(1).MetaGet(MetaSource))
+	assert.Equal(t, "1", ts.Payload.Get(1).MetaGet(MetaBatchIndex))
+	assert.Equal(t, "", ts.Payload.Get(1).MetaGet(MetaSourceID))
+	assert.Equal(t, "", ts.Payload.Get(1).MetaGet(MetaRetryCount))
+
+	// A rejected message increments the retry count when redelivered.
+	require.NoError(t, ts.Ack(ctx, errors.New("nope")))
+	require.Error(t, <-resChan)
+
+	ts, resChan = send(newBatch())
+	assert.Equal(t, "1", ts.Payload.Get(0).MetaGet(MetaRetryCount))
+
+	require.NoError(t, ts.Ack(ctx, nil))
+	require.NoError(t, <-resChan)
+
+	ts, _ = send(newBatch())
+	assert.Equal(t, "0", ts.Payload.Get(0).MetaGet(MetaRetryCount))
+
+	// Messages sharing a source position count as a single delivery attempt.
+	newSharedBatch := func() message.Batch {
+		b := message.QuickBatch([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		b.Get(0).MetaSet("sqs_message_id", "abc")
+		b.Get(1).MetaSet("sqs_message_id", "def")
+		b.Get(2).MetaSet("sqs_message_id", "def")
+		return b
+	}
+	ts, resChan = send(newSharedBatch())
+	require.NoError(t, ts.Ack(ctx, errors.New("nope")))
+	require.Error(t, <-resChan)
+
+	ts, resChan = send(newSharedBatch())
+	assert.Equal(t, "1", ts.Payload.Get(1).MetaGet(MetaRetryCount))
+	assert.Equal(t, "1", ts.Payload.Get(2).MetaGet(MetaRetryCount))
+	require.NoError(t, ts.Ack(ctx, nil))
+	require.NoError(t, <-resChan)
+
+	// Existing lineage metadata is preserved.
+	b := newBatch()
+	b.Get(0).MetaSet(MetaSource, "bar")
+	ts, _ = send(b)
+	assert.Equal(t, "bar", ts.Payload.Get(0).MetaGet(MetaSource))
+	assert.Equal(t, "", ts.Payload.Get(0).MetaGet(MetaIngestTimestamp))
+
+	close(tChan)
+	require.NoError(t, l.WaitForClose(ctx))
+}
+
+func TestLayerRetriesBounded(t *testing.T) {
+	l := NewLayer("foo")
+
+	var ids []string
+	for i := 0; i < maxTrackedRetries+10; i++ {
+		ids = append(ids, string(rune('a'+i%26))+string(rune(i)))
+	}
+	l.resolveRetries(ids, errors.New("nope"))
+	assert.Len(t, l.retries, maxTrackedRetries)
+	assert.Equal(t, 0, l.retryCount(ids[0]))
+	assert.Equal(t, 1, l.retryCount(ids[len(ids)-1]))
+
+	l.resolveRetries(ids, nil)
+	assert.Len(t, l.retries, 0)
+	assert.Len(t, l.retriesOrder, 0)
+}
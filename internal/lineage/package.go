@@ -0,0 +1,3 @@
+// Package lineage implements the standard lineage metadata that can be attached
+// to messages as they enter a stream, describing where each message came from.
+package lineage
@@ -0,0 +1,33 @@
+package lineage
+
+// SourceID attempts to derive an identifier of the position of a message within
+// its source from the metadata added by the input it was consumed from, using
+// a function that returns the value of a metadata key and whether it exists.
+func SourceID(metaGet func(key string) (string, bool)) (string, bool) {
+	if topic, exists := metaGet("kafka_topic"); exists {
+		partition, pExists := metaGet("kafka_partition")
+		offset, oExists := metaGet("kafka_offset")
+		if pExists && oExists {
+			return "kafka:" + topic + ":" + partition + ":" + offset, true
+		}
+	}
+	if id, exists := metaGet("sqs_message_id"); exists {
+		return "sqs:" + id, true
+	}
+	// Objects can be split into any number of messages by a codec, and
+	// therefore an identifier is only derived when the index of the message
+	// within its object is also known.
+	for _, k := range [][4]string{
+		{"s3", "s3_bucket", "s3_key", "s3_message_index"},
+		{"gcs", "gcs_bucket", "gcs_key", "gcs_message_index"},
+		{"blob_storage", "blob_storage_container", "blob_storage_key", "blob_storage_message_index"},
+	} {
+		bucket, bExists := metaGet(k[1])
+		key, kExists := metaGet(k[2])
+		index, iExists := metaGet(k[3])
+		if bExists && kExists && iExists {
+			return k[0] + ":" + bucket + "/" + key + ":" + index, true
+		}
+	}
+	return "", false
+}
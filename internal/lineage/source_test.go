@@ -0,0 +1,72 @@
+package lineage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceID(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     map[string]string
+		expected string
+	}{
+		{
+			name: "kafka",
+			meta: map[string]string{
+				"kafka_topic":     "foo",
+				"kafka_partition": "1",
+				"kafka_offset":    "23",
+			},
+			expected: "kafka:foo:1:23",
+		},
+		{
+			name: "kafka missing offset",
+			meta: map[string]string{
+				"kafka_topic":     "foo",
+				"kafka_partition": "1",
+			},
+		},
+		{
+			name:     "sqs",
+			meta:     map[string]string{"sqs_message_id": "abc"},
+			expected: "sqs:abc",
+		},
+		{
+			name:     "s3",
+			meta:     map[string]string{"s3_bucket": "foo", "s3_key": "bar/baz.json", "s3_message_index": "3"},
+			expected: "s3:foo/bar/baz.json:3",
+		},
+		{
+			name: "s3 missing index",
+			meta: map[string]string{"s3_bucket": "foo", "s3_key": "bar/baz.json"},
+		},
+		{
+			name:     "gcs",
+			meta:     map[string]string{"gcs_bucket": "foo", "gcs_key": "bar", "gcs_message_index": "0"},
+			expected: "gcs:foo/bar:0",
+		},
+		{
+			name:     "blob storage",
+			meta:     map[string]string{"blob_storage_container": "foo", "blob_storage_key": "bar", "blob_storage_message_index": "1"},
+			expected: "blob_storage:foo/bar:1",
+		},
+		{
+			name: "none",
+			meta: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			key, exists := SourceID(func(k string) (string, bool) {
+				v, exists := test.meta[k]
+				return v, exists
+			})
+			assert.Equal(t, test.expected != "", exists)
+			assert.Equal(t, test.expected, key)
+		})
+	}
+}
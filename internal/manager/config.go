@@ -1,21 +1,51 @@
 package manager
 
 import (
+	"errors"
+
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
+	"github.com/benthosdev/benthos/v4/internal/leader"
 )
 
 // ResourceConfig contains fields for specifying resource components at the root
 // of a Benthos config.
 type ResourceConfig struct {
-	ResourceInputs     []input.Config     `json:"input_resources,omitempty" yaml:"input_resources,omitempty"`
-	ResourceProcessors []processor.Config `json:"processor_resources,omitempty" yaml:"processor_resources,omitempty"`
-	ResourceOutputs    []output.Config    `json:"output_resources,omitempty" yaml:"output_resources,omitempty"`
-	ResourceCaches     []cache.Config     `json:"cache_resources,omitempty" yaml:"cache_resources,omitempty"`
-	ResourceRateLimits []ratelimit.Config `json:"rate_limit_resources,omitempty" yaml:"rate_limit_resources,omitempty"`
+	ResourceInputs     []input.Config      `json:"input_resources,omitempty" yaml:"input_resources,omitempty"`
+	ResourceProcessors []processor.Config  `json:"processor_resources,omitempty" yaml:"processor_resources,omitempty"`
+	ResourceOutputs    []output.Config     `json:"output_resources,omitempty" yaml:"output_resources,omitempty"`
+	ResourceCaches     []cache.Config      `json:"cache_resources,omitempty" yaml:"cache_resources,omitempty"`
+	ResourceRateLimits []ratelimit.Config  `json:"rate_limit_resources,omitempty" yaml:"rate_limit_resources,omitempty"`
+	ResourceMappings   []MappingConfig     `json:"mapping_resources,omitempty" yaml:"mapping_resources,omitempty"`
+	LeaderElection     leader.Config       `json:"leader_election" yaml:"leader_election"`
+	Interpolation      InterpolationConfig `json:"interpolation" yaml:"interpolation"`
+}
+
+// InterpolationConfig contains fields that control how interpolated fields are
+// parsed and observed.
+type InterpolationConfig struct {
+	LatencyMetrics    bool `json:"latency_metrics" yaml:"latency_metrics"`
+	MaxInterpolations int  `json:"max_interpolations" yaml:"max_interpolations"`
+	MaxLength         int  `json:"max_length" yaml:"max_length"`
+}
+
+// NewInterpolationConfig creates an InterpolationConfig with default values.
+func NewInterpolationConfig() InterpolationConfig {
+	return InterpolationConfig{
+		LatencyMetrics:    false,
+		MaxInterpolations: 0,
+		MaxLength:         0,
+	}
+}
+
+// MappingConfig contains fields for specifying a Bloblang mapping resource,
+// which is compiled once and can be referenced by name from processors.
+type MappingConfig struct {
+	Label   string `json:"label" yaml:"label"`
+	Mapping string `json:"mapping" yaml:"mapping"`
 }
 
 // NewResourceConfig creates a ResourceConfig with default values.
@@ -26,6 +56,9 @@ func NewResourceConfig() ResourceConfig {
 		ResourceOutputs:    []output.Config{},
 		ResourceCaches:     []cache.Config{},
 		ResourceRateLimits: []ratelimit.Config{},
+		ResourceMappings:   []MappingConfig{},
+		LeaderElection:     leader.NewConfig(),
+		Interpolation:      NewInterpolationConfig(),
 	}
 }
 
@@ -37,5 +70,18 @@ func (r *ResourceConfig) AddFrom(extra *ResourceConfig) error {
 	r.ResourceOutputs = append(r.ResourceOutputs, extra.ResourceOutputs...)
 	r.ResourceCaches = append(r.ResourceCaches, extra.ResourceCaches...)
 	r.ResourceRateLimits = append(r.ResourceRateLimits, extra.ResourceRateLimits...)
+	r.ResourceMappings = append(r.ResourceMappings, extra.ResourceMappings...)
+	if extra.LeaderElection.Enabled() {
+		if r.LeaderElection.Enabled() {
+			return errors.New("leader_election is configured more than once")
+		}
+		r.LeaderElection = extra.LeaderElection
+	}
+	if extra.Interpolation != NewInterpolationConfig() {
+		if r.Interpolation != NewInterpolationConfig() {
+			return errors.New("interpolation is configured more than once")
+		}
+		r.Interpolation = extra.Interpolation
+	}
 	return nil
 }
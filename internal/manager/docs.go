@@ -4,6 +4,7 @@ import (
 	"github.com/Jeffail/gabs/v2"
 
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/leader"
 )
 
 func lintResource(ctx docs.LintContext, line, col int, v any) []docs.Lint {
@@ -42,5 +43,22 @@ func Spec() docs.FieldSpecs {
 		docs.FieldRateLimit(
 			"rate_limit_resources", "A list of rate limit resources, each must have a unique label.",
 		).Array().LinterFunc(lintResource).HasDefault([]any{}),
+
+		docs.FieldObject(
+			"mapping_resources", "A list of Bloblang mapping resources, each must have a unique label. Mapping resources are compiled once and can be referenced from the `mapping` and `mutation` processors with the value `resource:<label>`.",
+		).WithChildren(
+			docs.FieldString("label", "The unique label of the mapping.").HasDefault(""),
+			docs.FieldBloblang("mapping", "The Bloblang mapping.").HasDefault(""),
+		).Array().LinterFunc(lintResource).HasDefault([]any{}).AtVersion("4.9.0"),
+
+		leader.FieldSpec(),
+
+		docs.FieldObject(
+			"interpolation", "Controls how [interpolated fields](/docs/configuration/interpolation#bloblang-queries) of components are parsed and observed.",
+		).WithChildren(
+			docs.FieldBool("latency_metrics", "Whether interpolated fields record a sample of their evaluation latencies to the timer metric `interpolation_latency_ns`, labelled by the path of their component.").HasDefault(false),
+			docs.FieldInt("max_interpolations", "The maximum number of interpolation functions that a field may contain, beyond which the config fails to start. Set to `0` in order to disable the limit.").HasDefault(0),
+			docs.FieldInt("max_length", "The maximum length in bytes of a field that contains interpolation functions, beyond which the config fails to start. Set to `0` in order to disable the limit.").HasDefault(0),
+		).Advanced().AtVersion("4.9.0"),
 	}
 }
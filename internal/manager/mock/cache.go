@@ -28,6 +28,17 @@ func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
 	return []byte(i.Value), nil
 }
 
+// GetMulti obtains multiple mock cache items.
+func (c *Cache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.Values[k]; ok {
+			values[k] = []byte(i.Value)
+		}
+	}
+	return values, nil
+}
+
 // Set a mock cache item.
 func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	c.Values[key] = CacheItem{
@@ -60,6 +71,17 @@ func (c *Cache) Add(ctx context.Context, key string, value []byte, ttl *time.Dur
 	return nil
 }
 
+// AddMulti adds multiple mock cache items.
+func (c *Cache) AddMulti(ctx context.Context, kvs map[string]cache.TTLItem) map[string]error {
+	errs := map[string]error{}
+	for k, v := range kvs {
+		if err := c.Add(ctx, k, v.Value, v.TTL); err != nil {
+			errs[k] = err
+		}
+	}
+	return errs
+}
+
 // Delete a mock cache item.
 func (c *Cache) Delete(ctx context.Context, key string) error {
 	delete(c.Values, key)
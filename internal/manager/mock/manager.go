@@ -7,6 +7,7 @@ import (
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/buffer"
@@ -28,6 +29,7 @@ type Manager struct {
 	RateLimits map[string]RateLimit
 	Outputs    map[string]OutputWriter
 	Processors map[string]Processor
+	Mappings   map[string]*mapping.Executor
 	Pipes      map[string]<-chan message.Transaction
 
 	// OnRegisterEndpoint can be set in order to intercept endpoints registered
@@ -47,6 +49,7 @@ func NewManager() *Manager {
 		RateLimits: map[string]RateLimit{},
 		Outputs:    map[string]OutputWriter{},
 		Processors: map[string]Processor{},
+		Mappings:   map[string]*mapping.Executor{},
 		Pipes:      map[string]<-chan message.Transaction{},
 		M:          metrics.Noop(),
 		L:          log.Noop(),
@@ -178,6 +181,33 @@ func (m *Manager) AccessRateLimit(ctx context.Context, name string, fn func(rate
 	return nil
 }
 
+// ProbeMapping returns true if a mapping resource exists under the provided
+// name.
+func (m *Manager) ProbeMapping(name string) bool {
+	_, exists := m.Mappings[name]
+	return exists
+}
+
+// AccessMapping executes a closure on a mapping resource.
+func (m *Manager) AccessMapping(ctx context.Context, name string, fn func(*mapping.Executor)) error {
+	e, ok := m.Mappings[name]
+	if !ok {
+		return component.ErrMappingNotFound
+	}
+	fn(e)
+	return nil
+}
+
+// StoreMapping compiles a mapping and stores it as a resource.
+func (m *Manager) StoreMapping(ctx context.Context, name, blobl string) error {
+	e, err := m.BloblEnvironment().NewMapping(blobl)
+	if err != nil {
+		return err
+	}
+	m.Mappings[name] = e
+	return nil
+}
+
 // ProbeInput returns true if an input resource exists under the provided name.
 func (m *Manager) ProbeInput(name string) bool {
 	_, exists := m.Inputs[name]
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+)
+
+// State is a snapshot of the runtime state held within the memory of the
+// resources of a manager, which can be imported by the manager of another
+// instance in order to migrate the state during a planned replacement.
+type State struct {
+	// Caches contains the items of each cache resource that holds its items
+	// in memory, by the label of the resource.
+	Caches map[string][]StateCacheItem `json:"caches"`
+}
+
+// StateCacheItem is an item of a cache within a state snapshot.
+type StateCacheItem struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+
+	// TTL is the remaining lifetime of the item as a duration string, and is
+	// empty when the item does not expire.
+	TTL string `json:"ttl,omitempty"`
+}
+
+// ExportState returns a snapshot of the items of all cache resources that hold
+// their items in memory. Caches of other types are omitted as their items are
+// held by an external service, and therefore already survive the replacement
+// of an instance.
+func (t *Type) ExportState(ctx context.Context) (*State, error) {
+	t.resourceLock.RLock()
+	defer t.resourceLock.RUnlock()
+
+	state := &State{Caches: map[string][]StateCacheItem{}}
+	for label, c := range t.caches {
+		s, ok := c.(cache.Snapshotter)
+		if !ok {
+			continue
+		}
+
+		items, err := s.ExportItems(ctx)
+		if errors.Is(err, cache.ErrSnapshotNotSupported) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to export items of cache '%v': %w", label, err)
+		}
+
+		exported := make([]StateCacheItem, 0, len(items))
+		for k, v := range items {
+			item := StateCacheItem{Key: k, Value: v.Value}
+			if v.TTL != nil {
+				item.TTL = v.TTL.String()
+			}
+			exported = append(exported, item)
+		}
+		sort.Slice(exported, func(i, j int) bool {
+			return exported[i].Key < exported[j].Key
+		})
+		state.Caches[label] = exported
+	}
+	return state, nil
+}
+
+// ImportState writes the items of a snapshot into the cache resources of the
+// same labels, overwriting any existing items of the same keys. Items without
+// a TTL are written with the default TTL of the cache.
+func (t *Type) ImportState(ctx context.Context, state *State) error {
+	t.resourceLock.RLock()
+	defer t.resourceLock.RUnlock()
+
+	// Check that all caches exist before writing to any of them.
+	for label := range state.Caches {
+		if c := t.caches[label]; c == nil {
+			return ErrResourceNotFound(label)
+		}
+	}
+
+	for label, items := range state.Caches {
+		if len(items) == 0 {
+			continue
+		}
+		ttlItems := make(map[string]cache.TTLItem, len(items))
+		for _, item := range items {
+			ttlItem := cache.TTLItem{Value: item.Value}
+			if item.TTL != "" {
+				ttl, err := time.ParseDuration(item.TTL)
+				if err != nil {
+					return fmt.Errorf("failed to parse ttl of item '%v' of cache '%v': %w", item.Key, label, err)
+				}
+				ttlItem.TTL = &ttl
+			}
+			ttlItems[item.Key] = ttlItem
+		}
+		if err := t.caches[label].SetMulti(ctx, ttlItems); err != nil {
+			return fmt.Errorf("failed to import items of cache '%v': %w", label, err)
+		}
+	}
+	return nil
+}
+
+// RegisterStateEndpoints adds the endpoints for exporting and importing the
+// state of resources to the API of the manager.
+func (t *Type) RegisterStateEndpoints() {
+	t.apiReg.RegisterEndpoint(
+		"/state",
+		"GET: Returns a snapshot of the runtime state held in memory by resources, such as the items of memory caches."+
+			" POST: Imports a snapshot obtained from another instance.",
+		t.handleState,
+	)
+}
+
+func (t *Type) handleState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		state, err := t.ExportState(r.Context())
+		if err != nil {
+			t.logger.Errorf("State export error: %v\n", err)
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+			return
+		}
+		resBytes, err := json.Marshal(state)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(resBytes)
+	case "POST":
+		reqBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+			return
+		}
+		var state State
+		if err := json.Unmarshal(reqBytes, &state); err != nil {
+			http.Error(w, fmt.Sprintf("Error: failed to parse state: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := t.ImportState(r.Context(), &state); err != nil {
+			t.logger.Errorf("State import error: %v\n", err)
+			http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Error: method not supported", http.StatusMethodNotAllowed)
+	}
+}
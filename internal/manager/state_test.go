@@ -0,0 +1,143 @@
+package manager_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func stateTestManager(t *testing.T) (*manager.Type, http.HandlerFunc) {
+	t.Helper()
+
+	conf := manager.NewResourceConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`
+cache_resources:
+  - label: foo
+    memory:
+      default_ttl: 5m
+  - label: bar
+    memory:
+      compaction_interval: ""
+  - label: baz
+    multilevel: [ foo, bar ]
+`), &conf))
+
+	var handler http.HandlerFunc
+	apiReg := mock.NewManager()
+	apiReg.OnRegisterEndpoint = func(path string, h http.HandlerFunc) {
+		if path == "/state" {
+			handler = h
+		}
+	}
+
+	mgr, err := manager.New(conf, manager.OptSetAPIReg(apiReg))
+	require.NoError(t, err)
+	mgr.RegisterStateEndpoints()
+	require.NotNil(t, handler)
+	return mgr, handler
+}
+
+func TestManagerStateExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	from, _ := stateTestManager(t)
+	ttl := time.Minute
+	require.NoError(t, from.AccessCache(ctx, "foo", func(c cache.V1) {
+		require.NoError(t, c.Set(ctx, "a", []byte("first"), &ttl))
+		require.NoError(t, c.Set(ctx, "b", []byte("second"), nil))
+	}))
+	require.NoError(t, from.AccessCache(ctx, "bar", func(c cache.V1) {
+		require.NoError(t, c.Set(ctx, "c", []byte("third"), nil))
+	}))
+
+	state, err := from.ExportState(ctx)
+	require.NoError(t, err)
+
+	// The multilevel cache does not hold items of its own.
+	require.Len(t, state.Caches, 2)
+	require.Len(t, state.Caches["foo"], 2)
+	assert.Equal(t, "a", state.Caches["foo"][0].Key)
+	assert.Equal(t, []byte("first"), state.Caches["foo"][0].Value)
+	exportedTTL, err := time.ParseDuration(state.Caches["foo"][0].TTL)
+	require.NoError(t, err)
+	assert.True(t, exportedTTL > 0 && exportedTTL <= time.Minute, exportedTTL)
+	assert.Equal(t, "b", state.Caches["foo"][1].Key)
+	assert.Equal(t, []manager.StateCacheItem{
+		{Key: "c", Value: []byte("third")},
+	}, state.Caches["bar"])
+
+	to, _ := stateTestManager(t)
+	require.NoError(t, to.ImportState(ctx, state))
+
+	for label, items := range map[string]map[string]string{
+		"foo": {"a": "first", "b": "second"},
+		"bar": {"c": "third"},
+	} {
+		require.NoError(t, to.AccessCache(ctx, label, func(c cache.V1) {
+			for k, v := range items {
+				b, err := c.Get(ctx, k)
+				require.NoError(t, err)
+				assert.Equal(t, v, string(b))
+			}
+		}))
+	}
+
+	err = to.ImportState(ctx, &manager.State{
+		Caches: map[string][]manager.StateCacheItem{
+			"nope": {{Key: "a", Value: []byte("b")}},
+		},
+	})
+	require.EqualError(t, err, "unable to locate resource: nope")
+}
+
+func TestManagerStateEndpoint(t *testing.T) {
+	ctx := context.Background()
+
+	from, fromHandler := stateTestManager(t)
+	require.NoError(t, from.AccessCache(ctx, "bar", func(c cache.V1) {
+		require.NoError(t, c.Set(ctx, "c", []byte("third"), nil))
+	}))
+
+	res := httptest.NewRecorder()
+	fromHandler(res, httptest.NewRequest("GET", "/state", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+
+	var state manager.State
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &state))
+	assert.Equal(t, []manager.StateCacheItem{
+		{Key: "c", Value: []byte("third")},
+	}, state.Caches["bar"])
+
+	to, toHandler := stateTestManager(t)
+
+	res = httptest.NewRecorder()
+	toHandler(res, httptest.NewRequest("POST", "/state", strings.NewReader(`{"caches":{"bar":[{"key":"c","value":"dGhpcmQ="}]}}`)))
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+	require.NoError(t, to.AccessCache(ctx, "bar", func(c cache.V1) {
+		b, err := c.Get(ctx, "c")
+		require.NoError(t, err)
+		assert.Equal(t, "third", string(b))
+	}))
+
+	res = httptest.NewRecorder()
+	toHandler(res, httptest.NewRequest("POST", "/state", strings.NewReader(`not json`)))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = httptest.NewRecorder()
+	toHandler(res, httptest.NewRequest("DELETE", "/state", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+}
@@ -2,6 +2,7 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -10,6 +11,8 @@ import (
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
@@ -21,6 +24,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/leader"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -73,16 +77,24 @@ type Type struct {
 	processors   map[string]processor.V1
 	outputs      map[string]*outputWrapper
 	rateLimits   map[string]ratelimit.V1
+	mappings     map[string]*mapping.Executor
 	resourceLock *sync.RWMutex
 
 	// Collections of component constructors
 	env      *bundle.Environment
 	bloblEnv *bloblang.Environment
 
+	// The Bloblang environment provided to components, which differs from
+	// bloblEnv in that interpolated fields are timed with stats when enabled.
+	fieldBloblEnv        *bloblang.Environment
+	interpolationMetrics bool
+
 	logger log.Modular
 	stats  *metrics.Namespaced
 	tracer trace.TracerProvider
 
+	leaderElection leader.Config
+
 	pipes    map[string]<-chan message.Transaction
 	pipeLock *sync.RWMutex
 }
@@ -168,6 +180,7 @@ func New(conf ResourceConfig, opts ...OptFunc) (*Type, error) {
 		processors:   map[string]processor.V1{},
 		outputs:      map[string]*outputWrapper{},
 		rateLimits:   map[string]ratelimit.V1{},
+		mappings:     map[string]*mapping.Executor{},
 		resourceLock: &sync.RWMutex{},
 
 		// Environment defaults to global (everything that was imported).
@@ -186,6 +199,17 @@ func New(conf ResourceConfig, opts ...OptFunc) (*Type, error) {
 		opt(t)
 	}
 
+	t.leaderElection = conf.LeaderElection
+
+	if conf.Interpolation.MaxInterpolations > 0 || conf.Interpolation.MaxLength > 0 {
+		t.bloblEnv = t.bloblEnv.WithFieldLimits(bloblang.FieldLimits{
+			MaxInterpolations: conf.Interpolation.MaxInterpolations,
+			MaxLength:         conf.Interpolation.MaxLength,
+		})
+	}
+	t.interpolationMetrics = conf.Interpolation.LatencyMetrics
+	t.setStats(t.stats)
+
 	seen := map[string]struct{}{}
 
 	checkLabel := func(typeStr, label string) error {
@@ -234,8 +258,20 @@ func New(conf ResourceConfig, opts ...OptFunc) (*Type, error) {
 		}
 		t.rateLimits[c.Label] = nil
 	}
+	for _, c := range conf.ResourceMappings {
+		if err := checkLabel("mapping", c.Label); err != nil {
+			return nil, err
+		}
+		t.mappings[c.Label] = nil
+	}
 
 	// Labels validated, begin construction
+	for _, conf := range conf.ResourceMappings {
+		if err := t.StoreMapping(context.Background(), conf.Label, conf.Mapping); err != nil {
+			return nil, err
+		}
+	}
+
 	for _, conf := range conf.ResourceRateLimits {
 		if err := t.StoreRateLimit(context.Background(), conf.Label, conf); err != nil {
 			return nil, err
@@ -284,7 +320,7 @@ func (t *Type) forStream(id string) *Type {
 	newT.logger = t.logger.WithFields(map[string]string{
 		"stream": id,
 	})
-	newT.stats = t.stats.WithLabels("stream", id)
+	newT.setStats(t.stats.WithLabels("stream", id))
 	return &newT
 }
 
@@ -294,7 +330,7 @@ func (t *Type) forLabel(name string) *Type {
 	newT.logger = t.logger.WithFields(map[string]string{
 		"label": name,
 	})
-	newT.stats = t.stats.WithLabels("label", name)
+	newT.setStats(t.stats.WithLabels("label", name))
 	return &newT
 }
 
@@ -316,7 +352,7 @@ func (t *Type) intoPath(segments ...string) *Type {
 	newT.logger = t.logger.WithFields(map[string]string{
 		"path": pathStr,
 	})
-	newT.stats = t.stats.WithLabels("path", pathStr)
+	newT.setStats(t.stats.WithLabels("path", pathStr))
 	return &newT
 }
 
@@ -334,7 +370,7 @@ func (t *Type) Label() string {
 // registered to both the current metrics target as well as the provided one.
 func (t *Type) WithAddedMetrics(m metrics.Type) bundle.NewManagement {
 	newT := *t
-	newT.stats = newT.stats.WithStats(metrics.Combine(newT.stats.Child(), m))
+	newT.setStats(newT.stats.WithStats(metrics.Combine(newT.stats.Child(), m)))
 	return &newT
 }
 
@@ -383,7 +419,7 @@ func (t *Type) UnsetPipe(name string, tran <-chan message.Transaction) {
 // with a mapping.
 func (t *Type) WithMetricsMapping(m *metrics.Mapping) *Type {
 	newT := *t
-	newT.stats = t.stats.WithMapping(m)
+	newT.setStats(t.stats.WithMapping(m))
 	return &newT
 }
 
@@ -408,10 +444,26 @@ func (t *Type) Environment() *bundle.Environment {
 	return t.env
 }
 
-// BloblEnvironment returns a Bloblang environment used by the manager. This is
-// for internal use only.
+// setStats sets the metrics exporter of the manager, and therefore the timer
+// that interpolated fields created from its Bloblang environment record their
+// evaluation latencies to when latency metrics are enabled.
+func (t *Type) setStats(stats *metrics.Namespaced) {
+	t.stats = stats
+	t.fieldBloblEnv = t.bloblEnv
+	if t.interpolationMetrics {
+		t.fieldBloblEnv = t.bloblEnv.WithFieldEvaluationTimers(func() field.EvaluationTimer {
+			return stats.GetTimer("interpolation_latency_ns")
+		})
+	}
+}
+
+// BloblEnvironment returns a Bloblang environment used by the manager. When
+// interpolation latency metrics are enabled dynamic field expressions record a
+// sample of their evaluation latencies to the timer metric
+// interpolation_latency_ns, labelled by the component path. This is for
+// internal use only.
 func (t *Type) BloblEnvironment() *bloblang.Environment {
-	return t.bloblEnv
+	return t.fieldBloblEnv
 }
 
 //------------------------------------------------------------------------------
@@ -518,9 +570,38 @@ func (t *Type) AccessInput(ctx context.Context, name string, fn func(input.Strea
 
 // NewInput attempts to create a new input component from a config.
 func (t *Type) NewInput(conf input.Config) (input.Streamed, error) {
+	if conf.Singleton {
+		return t.newSingletonInput(conf)
+	}
 	return t.env.InputInit(conf, t.forLabel(conf.Label))
 }
 
+// newSingletonInput creates an input that is only created and consumed from
+// whilst this instance is the leader of its election, which is keyed by the
+// label of the input or, when it has no label, its path.
+func (t *Type) newSingletonInput(conf input.Config) (input.Streamed, error) {
+	if !t.leaderElection.Enabled() {
+		return nil, errors.New("input is a singleton but leader_election is not configured")
+	}
+
+	key := conf.Label
+	if key == "" {
+		key = query.SliceToDotPath(t.componentPath...)
+	}
+	if t.stream != "" {
+		key = t.stream + "." + key
+	}
+
+	mgr := t.forLabel(conf.Label)
+	election, err := leader.New(t.leaderElection, key, mgr.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader election: %w", err)
+	}
+	return input.NewSingleton(func() (input.Streamed, error) {
+		return t.env.InputInit(conf, mgr)
+	}, election, mgr.logger), nil
+}
+
 // StoreInput attempts to store a new input resource. If an existing resource
 // has the same name it is closed and removed _before_ the new one is
 // initialized in order to avoid duplicate connections.
@@ -751,6 +832,48 @@ func (t *Type) StoreRateLimit(ctx context.Context, name string, conf ratelimit.C
 
 //------------------------------------------------------------------------------
 
+// ProbeMapping returns true if a mapping resource exists under the provided
+// name.
+func (t *Type) ProbeMapping(name string) bool {
+	_, exists := t.mappings[name]
+	return exists
+}
+
+// AccessMapping attempts to access a mapping resource by a unique identifier
+// and executes a closure function with the mapping as an argument. Returns an
+// error if the mapping does not exist (or is otherwise inaccessible).
+//
+// During the execution of the provided closure it is guaranteed that the
+// resource will not be replaced. However, it is possible for the resource to be
+// accessed by any number of components in parallel.
+func (t *Type) AccessMapping(ctx context.Context, name string, fn func(*mapping.Executor)) error {
+	t.resourceLock.RLock()
+	defer t.resourceLock.RUnlock()
+	m, ok := t.mappings[name]
+	if !ok || m == nil {
+		return ErrResourceNotFound(name)
+	}
+	fn(m)
+	return nil
+}
+
+// StoreMapping attempts to compile and store a new mapping resource. If an
+// existing resource has the same name it is only replaced once the new mapping
+// has been compiled successfully.
+func (t *Type) StoreMapping(ctx context.Context, name, blobl string) error {
+	exec, err := t.bloblEnv.NewMapping(blobl)
+	if err != nil {
+		return fmt.Errorf("failed to parse mapping resource '%v': %w", name, err)
+	}
+
+	t.resourceLock.Lock()
+	t.mappings[name] = exec
+	t.resourceLock.Unlock()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
 // TriggerStopConsuming instructs the manager to stop resource inputs and
 // outputs from consuming data. This call does not block.
 func (t *Type) TriggerStopConsuming() {
@@ -6,11 +6,13 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
@@ -66,6 +68,56 @@ func TestManagerProcessorLabels(t *testing.T) {
 	}
 }
 
+func TestManagerFieldEvaluationMetrics(t *testing.T) {
+	stats := metrics.NewLocal()
+	mgr, err := manager.New(manager.NewResourceConfig(), manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	require.NoError(t, err)
+
+	e, err := mgr.IntoPath("foo").BloblEnvironment().NewField(`${! content().uppercase() }`)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", e.String(0, message.QuickBatch([][]byte{[]byte("hello")})))
+	assert.Empty(t, stats.GetTimings())
+
+	conf := manager.NewResourceConfig()
+	conf.Interpolation.LatencyMetrics = true
+	mgr, err = manager.New(conf, manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	require.NoError(t, err)
+
+	e, err = mgr.IntoPath("foo").BloblEnvironment().NewField(`${! content().uppercase() }`)
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", e.String(0, message.QuickBatch([][]byte{[]byte("hello")})))
+
+	timings := stats.GetTimings()
+	require.Len(t, timings, 1)
+	for k := range timings {
+		assert.Contains(t, k, "interpolation_latency_ns")
+		assert.Contains(t, k, "root.foo")
+		assert.NotContains(t, k, "content()")
+	}
+}
+
+func TestManagerFieldLimits(t *testing.T) {
+	conf := manager.NewResourceConfig()
+	conf.Interpolation.MaxInterpolations = 2
+	conf.Interpolation.MaxLength = 35
+	mgr, err := manager.New(conf)
+	require.NoError(t, err)
+
+	env := mgr.IntoPath("foo").BloblEnvironment()
+
+	_, err = env.NewField(`${! content() } ${! meta("foo") }`)
+	require.NoError(t, err)
+
+	_, err = env.NewField(`this is a static field that is longer than the limit`)
+	require.NoError(t, err)
+
+	_, err = env.NewField(`${! content() } ${! meta("foo") } ${! meta("bar") }`)
+	require.EqualError(t, err, "field contains 3 interpolation functions, which exceeds the limit of 2")
+
+	_, err = env.NewField(`${! content().uppercase().lowercase() }`)
+	require.EqualError(t, err, "field with interpolation functions is 39 bytes long, which exceeds the limit of 35")
+}
+
 func TestManagerCache(t *testing.T) {
 	conf := manager.NewResourceConfig()
 
@@ -334,6 +386,28 @@ func TestManagerInputListErrors(t *testing.T) {
 	require.EqualError(t, err, "input resource has an empty label")
 }
 
+func TestManagerSingletonInputErrors(t *testing.T) {
+	mgr, err := manager.New(manager.NewResourceConfig())
+	require.NoError(t, err)
+
+	conf := input.NewConfig()
+	conf.Type = "generate"
+	conf.Singleton = true
+
+	_, err = mgr.NewInput(conf)
+	require.EqualError(t, err, "input is a singleton but leader_election is not configured")
+
+	rConf := manager.NewResourceConfig()
+	rConf.LeaderElection.Type = "sql"
+	rConf.LeaderElection.SQL.Driver = "sqlite"
+
+	mgr, err = manager.New(rConf)
+	require.NoError(t, err)
+
+	_, err = mgr.NewInput(conf)
+	require.EqualError(t, err, "failed to create leader election: sql driver 'sqlite' does not support leader election")
+}
+
 func TestManagerOutputList(t *testing.T) {
 	cFoo := output.NewConfig()
 	cFoo.Type = "drop"
@@ -460,3 +534,75 @@ func TestManagerPipeGetSet(t *testing.T) {
 }
 
 //------------------------------------------------------------------------------
+
+func TestManagerMappingResource(t *testing.T) {
+	conf := manager.NewResourceConfig()
+	conf.ResourceMappings = append(conf.ResourceMappings, manager.MappingConfig{
+		Label:   "foo",
+		Mapping: `root = content().uppercase()`,
+	})
+
+	mgr, err := manager.New(conf)
+	require.NoError(t, err)
+
+	require.True(t, mgr.ProbeMapping("foo"))
+	require.False(t, mgr.ProbeMapping("bar"))
+
+	var procConfs []processor.Config
+	for _, procType := range []string{"mapping", "mutation"} {
+		procConf := processor.NewConfig()
+		require.NoError(t, yaml.Unmarshal([]byte(procType+`: resource:foo`), &procConf))
+		procConfs = append(procConfs, procConf)
+	}
+
+	var procs []processor.V1
+	for _, procConf := range procConfs {
+		proc, err := mgr.NewProcessor(procConf)
+		require.NoError(t, err)
+		procs = append(procs, proc)
+	}
+
+	for _, proc := range procs {
+		res, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("hello")}))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, "HELLO", string(res[0].Get(0).AsBytes()))
+	}
+
+	// An invalid mapping must not replace the existing one.
+	require.Error(t, mgr.StoreMapping(context.Background(), "foo", `root = nope(`))
+
+	require.NoError(t, mgr.StoreMapping(context.Background(), "foo", `root = content().string().reverse()`))
+	for _, proc := range procs {
+		res, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("hello")}))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		assert.Equal(t, "olleh", string(res[0].Get(0).AsBytes()))
+	}
+
+	badConf := processor.NewConfig()
+	require.NoError(t, yaml.Unmarshal([]byte(`mapping: resource:bar`), &badConf))
+	_, err = mgr.NewProcessor(badConf)
+	require.Error(t, err)
+}
+
+func TestManagerMappingResourceErrors(t *testing.T) {
+	conf := manager.NewResourceConfig()
+	conf.ResourceMappings = append(conf.ResourceMappings, manager.MappingConfig{
+		Label:   "foo",
+		Mapping: `root = `,
+	})
+	_, err := manager.New(conf)
+	require.Error(t, err)
+
+	conf = manager.NewResourceConfig()
+	conf.ResourceMappings = append(conf.ResourceMappings, manager.MappingConfig{
+		Label:   "foo",
+		Mapping: `root = this`,
+	})
+	fooCache := cache.NewConfig()
+	fooCache.Label = "foo"
+	conf.ResourceCaches = append(conf.ResourceCaches, fooCache)
+	_, err = manager.New(conf)
+	require.Error(t, err)
+}
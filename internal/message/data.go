@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -16,9 +17,16 @@ type messageData struct {
 	readOnlyStructured bool
 	structured         any // Sometimes mutable
 
+	// Caches a failure to parse the raw bytes so that repeated structured
+	// access of an unstructured message doesn't parse it each time.
+	structuredErr error
+
+	// Formats other than JSON that the raw bytes are detected and parsed as.
+	formats []StructuredFormat
+
 	// Mutable when readOnlyMeta = false
 	readOnlyMeta bool
-	metadata     map[string]string
+	metadata     map[string]any
 }
 
 func newMessageBytes(content []byte) *messageData {
@@ -32,25 +40,23 @@ func newMessageBytes(content []byte) *messageData {
 func (m *messageData) SetBytes(d []byte) {
 	m.rawBytes = d
 	m.structured = nil
+	m.structuredErr = nil
 }
 
 func (m *messageData) AsBytes() []byte {
 	if len(m.rawBytes) == 0 && m.structured != nil {
-		var buf bytes.Buffer
-		enc := json.NewEncoder(&buf)
-		enc.SetEscapeHTML(false)
-		if err := enc.Encode(m.structured); err != nil {
+		b, err := marshalJSON(m.structured)
+		if err != nil {
 			return nil
 		}
-		if buf.Len() > 1 {
-			m.rawBytes = buf.Bytes()[:buf.Len()-1]
-		}
+		m.rawBytes = b
 	}
 	return m.rawBytes
 }
 
 func (m *messageData) SetStructured(jObj any) {
 	m.rawBytes = nil
+	m.structuredErr = nil
 	if jObj == nil {
 		m.rawBytes = []byte(`null`)
 		return
@@ -70,16 +76,40 @@ func (m *messageData) AsStructured() (any, error) {
 		return m.structured, nil
 	}
 
+	if m.structuredErr != nil {
+		return nil, m.structuredErr
+	}
+
 	if len(m.rawBytes) == 0 {
 		return nil, ErrMessagePartNotExist // TODO: Need this?
 	}
 
+	if f, ok := detectFormat(m.formats, m.rawBytes); ok {
+		v, err := f.Parse(m.rawBytes)
+		if err != nil {
+			m.structuredErr = fmt.Errorf("failed to parse %v: %w", f.Name, err)
+			return nil, m.structuredErr
+		}
+		m.structured = v
+		return v, nil
+	}
+
+	v, err := m.parseJSON()
+	if err != nil {
+		m.structuredErr = err
+		return nil, err
+	}
+	return v, nil
+}
+
+func (m *messageData) parseJSON() (any, error) {
 	dec := json.NewDecoder(bytes.NewReader(m.rawBytes))
 	if useNumber {
 		dec.UseNumber()
 	}
 
 	if err := dec.Decode(&m.structured); err != nil {
+		m.structured = nil
 		return nil, err
 	}
 
@@ -104,7 +134,7 @@ func (m *messageData) AsStructuredMut() (any, error) {
 				return nil, err
 			}
 		}
-		m.readOnlyStructured = true
+		m.readOnlyStructured = false
 	}
 	v, err := m.AsStructured()
 	if err != nil {
@@ -119,12 +149,19 @@ func (m *messageData) AsStructuredMut() (any, error) {
 // ShallowCopy returns a copy of the message data that can be mutated without
 // mutating the original message contents (metadata and structured data).
 func (m *messageData) ShallowCopy() *messageData {
-	return &messageData{
+	c := m.shallowCopy()
+	return &c
+}
+
+func (m *messageData) shallowCopy() messageData {
+	return messageData{
 		rawBytes: m.rawBytes,
 		err:      m.err,
 
 		readOnlyStructured: true,
 		structured:         m.structured,
+		structuredErr:      m.structuredErr,
+		formats:            m.formats,
 
 		readOnlyMeta: true,
 		metadata:     m.metadata,
@@ -139,11 +176,16 @@ func (m *messageData) ShallowCopy() *messageData {
 // This is worth doing on values persisted outside of the lifetime of a
 // transaction unless some other strategy is used for persistence.
 func (m *messageData) DeepCopy() *messageData {
-	var clonedMeta map[string]string
+	c := m.deepCopy()
+	return &c
+}
+
+func (m *messageData) deepCopy() messageData {
+	var clonedMeta map[string]any
 	if m.metadata != nil {
-		clonedMeta = make(map[string]string, len(m.metadata))
+		clonedMeta = make(map[string]any, len(m.metadata))
 		for k, v := range m.metadata {
-			clonedMeta[k] = v
+			clonedMeta[k] = deepCopyMetaValue(v)
 		}
 	}
 
@@ -158,14 +200,22 @@ func (m *messageData) DeepCopy() *messageData {
 		structuredCopy, _ = CopyJSON(m.structured)
 	}
 
-	return &messageData{
+	return messageData{
 		rawBytes:   bytesCopy,
 		err:        m.err,
 		structured: structuredCopy,
+		formats:    m.formats,
 		metadata:   clonedMeta,
 	}
 }
 
+func (m *messageData) SetStructuredFormats(formats []StructuredFormat) {
+	m.formats = formats
+	if m.structured == nil {
+		m.structuredErr = nil
+	}
+}
+
 func (m *messageData) IsEmpty() bool {
 	return len(m.rawBytes) == 0 && m.structured == nil
 }
@@ -175,9 +225,9 @@ func (m *messageData) writeableMeta() {
 		return
 	}
 
-	var clonedMeta map[string]string
+	var clonedMeta map[string]any
 	if m.metadata != nil {
-		clonedMeta = make(map[string]string, len(m.metadata))
+		clonedMeta = make(map[string]any, len(m.metadata))
 		for k, v := range m.metadata {
 			clonedMeta[k] = v
 		}
@@ -187,18 +237,26 @@ func (m *messageData) writeableMeta() {
 	m.readOnlyMeta = false
 }
 
-func (m *messageData) MetaGet(key string) (string, bool) {
+func (m *messageData) MetaGetMut(key string) (any, bool) {
 	if m.metadata == nil {
+		return nil, false
+	}
+	v, exists := m.metadata[key]
+	return v, exists
+}
+
+func (m *messageData) MetaGet(key string) (string, bool) {
+	v, exists := m.MetaGetMut(key)
+	if !exists {
 		return "", false
 	}
-	s, exists := m.metadata[key]
-	return s, exists
+	return metaToString(v), true
 }
 
-func (m *messageData) MetaSet(key, value string) {
+func (m *messageData) MetaSetMut(key string, value any) {
 	m.writeableMeta()
 	if m.metadata == nil {
-		m.metadata = map[string]string{
+		m.metadata = map[string]any{
 			key: value,
 		}
 		return
@@ -206,12 +264,16 @@ func (m *messageData) MetaSet(key, value string) {
 	m.metadata[key] = value
 }
 
+func (m *messageData) MetaSet(key, value string) {
+	m.MetaSetMut(key, value)
+}
+
 func (m *messageData) MetaDelete(key string) {
 	m.writeableMeta()
 	delete(m.metadata, key)
 }
 
-func (m *messageData) MetaIter(f func(k, v string) error) error {
+func (m *messageData) MetaIterMut(f func(k string, v any) error) error {
 	for ak, av := range m.metadata {
 		if err := f(ak, av); err != nil {
 			return err
@@ -220,6 +282,15 @@ func (m *messageData) MetaIter(f func(k, v string) error) error {
 	return nil
 }
 
+func (m *messageData) MetaIter(f func(k, v string) error) error {
+	for ak, av := range m.metadata {
+		if err := f(ak, metaToString(av)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *messageData) ErrorGet() error {
 	return m.err
 }
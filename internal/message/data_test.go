@@ -9,6 +9,33 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestStructuredMutAfterShallowCopy(t *testing.T) {
+	source := newMessageBytes(nil)
+	source.SetStructured(map[string]any{
+		"foo": "bar",
+	})
+
+	local := source.ShallowCopy()
+
+	first, err := local.AsStructuredMut()
+	require.NoError(t, err)
+	first.(map[string]any)["foo"] = "baz"
+
+	// Subsequent mutable access returns the same copy rather than cloning it
+	// again, and therefore mutations made through earlier references are kept.
+	second, err := local.AsStructuredMut()
+	require.NoError(t, err)
+	first.(map[string]any)["bar"] = "qux"
+
+	assert.Equal(t, map[string]any{"foo": "baz", "bar": "qux"}, second)
+	assert.Equal(t, `{"bar":"qux","foo":"baz"}`, string(local.AsBytes()))
+
+	v, err := source.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, v)
+	assert.Equal(t, `{"foo":"bar"}`, string(source.AsBytes()))
+}
+
 func TestConcurrentMutationsFromNil(t *testing.T) {
 	source := newMessageBytes(nil)
 	kickOffChan := make(chan struct{})
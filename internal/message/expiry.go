@@ -0,0 +1,65 @@
+package message
+
+import (
+	"context"
+	"time"
+)
+
+type expiryKey struct{}
+
+// WithExpiry returns a message part with a time at which it expires, after
+// which components should no longer attempt to deliver it. A zero time removes
+// any expiry from the part.
+func WithExpiry(p *Part, t time.Time) *Part {
+	return p.WithContext(ContextWithExpiry(p.GetContext(), t))
+}
+
+// ContextWithExpiry returns a context that, when attached to a message part,
+// sets the time at which the part expires. A zero time removes any expiry.
+func ContextWithExpiry(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, expiryKey{}, t)
+}
+
+// GetExpiry returns the time at which a message part expires, and false if the
+// part has no expiry.
+func GetExpiry(p *Part) (time.Time, bool) {
+	t, _ := p.GetContext().Value(expiryKey{}).(time.Time)
+	return t, !t.IsZero()
+}
+
+// IsExpired returns true if a message part has an expiry that is not after a
+// given time.
+func IsExpired(p *Part, now time.Time) bool {
+	t, ok := GetExpiry(p)
+	return ok && !t.After(now)
+}
+
+// WithoutExpired returns a batch containing only the messages of a batch that
+// have not expired at a given time, along with the index of each remaining
+// message within the original batch. When no messages have expired the batch
+// itself is returned along with nil indexes.
+func WithoutExpired(b Batch, now time.Time) (Batch, []int) {
+	var live Batch
+	var indexes []int
+	for i, p := range b {
+		if !IsExpired(p, now) {
+			if indexes != nil {
+				live = append(live, p)
+				indexes = append(indexes, i)
+			}
+			continue
+		}
+		if indexes == nil {
+			live = make(Batch, i, len(b))
+			copy(live, b[:i])
+			indexes = make([]int, i, len(b))
+			for j := range indexes {
+				indexes[j] = j
+			}
+		}
+	}
+	if indexes == nil {
+		return b, nil
+	}
+	return live, indexes
+}
@@ -0,0 +1,53 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartExpiry(t *testing.T) {
+	now := time.Now()
+
+	p := NewPart([]byte("foo"))
+	_, ok := GetExpiry(p)
+	assert.False(t, ok)
+	assert.False(t, IsExpired(p, now))
+
+	p = WithExpiry(p, now)
+	exp, ok := GetExpiry(p)
+	assert.True(t, ok)
+	assert.Equal(t, now, exp)
+	assert.True(t, IsExpired(p, now))
+	assert.False(t, IsExpired(p, now.Add(-time.Second)))
+	assert.True(t, IsExpired(p.ShallowCopy(), now))
+
+	p = WithExpiry(p, time.Time{})
+	_, ok = GetExpiry(p)
+	assert.False(t, ok)
+}
+
+func TestBatchWithoutExpired(t *testing.T) {
+	now := time.Now()
+
+	b := QuickBatch([][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")})
+	live, indexes := WithoutExpired(b, now)
+	assert.Equal(t, b, live)
+	assert.Nil(t, indexes)
+
+	b[1] = WithExpiry(b[1], now.Add(-time.Second))
+	b[2] = WithExpiry(b[2], now.Add(time.Hour))
+	b[3] = WithExpiry(b[3], now)
+
+	live, indexes = WithoutExpired(b, now)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("c")}, GetAllBytes(live))
+	assert.Equal(t, []int{0, 2}, indexes)
+
+	b[0] = WithExpiry(b[0], now)
+	b[2] = WithExpiry(b[2], now)
+	live, indexes = WithoutExpired(b, now)
+	assert.Empty(t, live)
+	assert.Empty(t, indexes)
+	assert.NotNil(t, indexes)
+}
@@ -0,0 +1,76 @@
+package message
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StructuredFormat describes a format of raw message contents, other than
+// JSON, that can be detected and parsed into a structured value the first time
+// the structured form of a message is accessed.
+type StructuredFormat struct {
+	// Name of the format, which is used to enable it.
+	Name string
+
+	// Detect returns true if the raw contents of a message appear to be
+	// encoded in this format. Detection should be cheap and strict enough to
+	// not match JSON documents.
+	Detect func(b []byte) bool
+
+	// Parse the raw contents of a message into a structured value.
+	Parse func(b []byte) (any, error)
+}
+
+var (
+	registeredFormats = map[string]StructuredFormat{}
+	formatsMut        sync.RWMutex
+)
+
+// RegisterStructuredFormat adds a format that can be detected and parsed
+// lazily when the structured form of a message is accessed. Formats are only
+// detected for messages where they've been enabled with SetStructuredFormats,
+// which inputs do for the formats listed in their field `detect_formats`.
+func RegisterStructuredFormat(f StructuredFormat) {
+	formatsMut.Lock()
+	registeredFormats[f.Name] = f
+	formatsMut.Unlock()
+}
+
+// GetStructuredFormats returns the registered formats of the provided names,
+// in the same order, or an error if any of them haven't been registered.
+func GetStructuredFormats(names ...string) ([]StructuredFormat, error) {
+	formatsMut.RLock()
+	defer formatsMut.RUnlock()
+
+	formats := make([]StructuredFormat, 0, len(names))
+	for _, name := range names {
+		f, exists := registeredFormats[name]
+		if !exists {
+			return nil, fmt.Errorf("format '%v' was not recognised", name)
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// detectFormat returns the first of a list of formats that matches raw
+// contents which can't be a JSON document.
+func detectFormat(formats []StructuredFormat, b []byte) (StructuredFormat, bool) {
+	if len(formats) == 0 || len(b) == 0 || mightBeJSON(b[0]) {
+		return StructuredFormat{}, false
+	}
+	for _, f := range formats {
+		if f.Detect(b) {
+			return f, true
+		}
+	}
+	return StructuredFormat{}, false
+}
+
+func mightBeJSON(c byte) bool {
+	switch c {
+	case '{', '[', '"', 't', 'f', 'n', '-', ' ', '\t', '\r', '\n':
+		return true
+	}
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredFormatDetection(t *testing.T) {
+	var parses int
+	RegisterStructuredFormat(StructuredFormat{
+		Name: "test_format",
+		Detect: func(b []byte) bool {
+			return b[0] == '~'
+		},
+		Parse: func(b []byte) (any, error) {
+			parses++
+			if string(b) == "~bad" {
+				return nil, errors.New("nope")
+			}
+			return string(b[1:]), nil
+		},
+	})
+
+	_, err := GetStructuredFormats("test_format", "nope")
+	require.EqualError(t, err, "format 'nope' was not recognised")
+
+	formats, err := GetStructuredFormats("test_format")
+	require.NoError(t, err)
+
+	part := NewPart([]byte("~foo"))
+	_, err = part.AsStructured()
+	require.Error(t, err, "formats are disabled by default")
+	assert.Equal(t, 0, parses)
+
+	// Enabling formats discards the cached failure to parse.
+	part.SetStructuredFormats(formats)
+	v, err := part.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+
+	// The parsed result is cached, including across copies.
+	v, err = part.ShallowCopy().AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", v)
+	assert.Equal(t, 1, parses)
+
+	part = NewPart([]byte("~bad"))
+	part.SetStructuredFormats(formats)
+	_, err = part.AsStructured()
+	require.EqualError(t, err, "failed to parse test_format: nope")
+	_, err = part.ShallowCopy().AsStructured()
+	require.Error(t, err)
+	assert.Equal(t, 2, parses)
+
+	// Formats remain enabled when the contents are replaced.
+	part.SetBytes([]byte(`{"foo":"bar"}`))
+	v, err = part.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"foo": "bar"}, v)
+
+	part.SetBytes([]byte("~baz"))
+	v, err = part.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "baz", v)
+	assert.Equal(t, 3, parses)
+}
@@ -0,0 +1,64 @@
+package message
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// metaToString converts a metadata value into a string, which is necessary at
+// boundaries that only support string metadata such as protocol headers. The
+// conversion matches that of Bloblang when coercing values into strings.
+func metaToString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case []byte:
+		return string(t)
+	case int:
+		return strconv.Itoa(t)
+	case int32:
+		return strconv.FormatInt(int64(t), 10)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case uint:
+		return strconv.FormatUint(uint64(t), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(t), 10)
+	case uint64:
+		return strconv.FormatUint(t, 10)
+	case float32:
+		return strconv.FormatFloat(float64(t), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case nil:
+		return "null"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// deepCopyMetaValue returns a copy of a metadata value where reference types
+// are copied in order to eliminate parental ownership.
+func deepCopyMetaValue(v any) any {
+	switch t := v.(type) {
+	case []byte:
+		return append([]byte(nil), t...)
+	case map[string]any, []any:
+		c, err := CopyJSON(t)
+		if err != nil {
+			return v
+		}
+		return c
+	}
+	return v
+}
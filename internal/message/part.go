@@ -23,28 +23,19 @@ type Part struct {
 
 // NewPart initializes a new message part.
 func NewPart(data []byte) *Part {
-	return &Part{
-		data: newMessageBytes(data),
-		ctx:  context.Background(),
-	}
+	return newPartWithData(context.Background(), messageData{rawBytes: data})
 }
 
 //------------------------------------------------------------------------------
 
 // ShallowCopy creates a shallow copy of the message part.
 func (p *Part) ShallowCopy() *Part {
-	return &Part{
-		data: p.data.ShallowCopy(),
-		ctx:  p.ctx,
-	}
+	return newPartWithData(p.ctx, p.data.shallowCopy())
 }
 
 // DeepCopy creates a new deep copy of the message part.
 func (p *Part) DeepCopy() *Part {
-	return &Part{
-		data: p.data.DeepCopy(),
-		ctx:  p.ctx,
-	}
+	return newPartWithData(p.ctx, p.data.deepCopy())
 }
 
 //------------------------------------------------------------------------------
@@ -127,19 +118,40 @@ func (p *Part) SetStructured(jObj any) {
 	p.data.SetStructured(jObj)
 }
 
+// SetStructuredFormats enables the detection of formats other than JSON when
+// the structured form of the message is accessed and it hasn't already been
+// set. Formats are detected in the order provided.
+func (p *Part) SetStructuredFormats(formats []StructuredFormat) {
+	p.data.SetStructuredFormats(formats)
+}
+
 //------------------------------------------------------------------------------
 
 // MetaGet returns a metadata value if a key exists, otherwise an empty string.
+// Values that are not strings are converted into a string.
 func (p *Part) MetaGet(key string) string {
 	v, _ := p.data.MetaGet(key)
 	return v
 }
 
+// MetaGetMut returns a metadata value if a key exists with its type preserved.
+// The value returned should not be mutated unless it is set again with
+// MetaSetMut.
+func (p *Part) MetaGetMut(key string) (any, bool) {
+	return p.data.MetaGetMut(key)
+}
+
 // MetaSet sets the value of a metadata key.
 func (p *Part) MetaSet(key, value string) {
 	p.data.MetaSet(key, value)
 }
 
+// MetaSetMut sets the value of a metadata key to a value of any type, which is
+// preserved until it reaches a boundary that requires strings.
+func (p *Part) MetaSetMut(key string, value any) {
+	p.data.MetaSetMut(key, value)
+}
+
 // MetaDelete removes the value of a metadata key.
 func (p *Part) MetaDelete(key string) {
 	p.data.MetaDelete(key)
@@ -150,6 +162,12 @@ func (p *Part) MetaIter(f func(k, v string) error) error {
 	return p.data.MetaIter(f)
 }
 
+// MetaIterMut iterates each metadata key/value pair with the types of values
+// preserved.
+func (p *Part) MetaIterMut(f func(k string, v any) error) error {
+	return p.data.MetaIterMut(f)
+}
+
 //------------------------------------------------------------------------------
 
 // IsEmpty returns true if the message part is empty.
@@ -3,6 +3,7 @@ package message
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -142,3 +143,58 @@ func TestPartJSONMarshal(t *testing.T) {
 		t.Errorf("Wrong marshalled json: %v != %v", act, exp)
 	}
 }
+
+func TestPartTypedMetadata(t *testing.T) {
+	tstamp := time.Date(2022, 10, 3, 12, 30, 0, 0, time.UTC)
+
+	p := NewPart(nil)
+	p.MetaSetMut("int", int64(42))
+	p.MetaSetMut("float", 1.5)
+	p.MetaSetMut("bool", true)
+	p.MetaSetMut("time", tstamp)
+	p.MetaSetMut("bytes", []byte("foo"))
+	p.MetaSetMut("obj", map[string]any{"bar": "baz"})
+	p.MetaSet("str", "buz")
+
+	v, exists := p.MetaGetMut("int")
+	require.True(t, exists)
+	assert.Equal(t, int64(42), v)
+
+	_, exists = p.MetaGetMut("nope")
+	assert.False(t, exists)
+
+	assert.Equal(t, "42", p.MetaGet("int"))
+	assert.Equal(t, "1.5", p.MetaGet("float"))
+	assert.Equal(t, "true", p.MetaGet("bool"))
+	assert.Equal(t, "2022-10-03T12:30:00Z", p.MetaGet("time"))
+	assert.Equal(t, "foo", p.MetaGet("bytes"))
+	assert.Equal(t, `{"bar":"baz"}`, p.MetaGet("obj"))
+	assert.Equal(t, "buz", p.MetaGet("str"))
+
+	strs := map[string]string{}
+	_ = p.MetaIter(func(k, v string) error {
+		strs[k] = v
+		return nil
+	})
+	assert.Equal(t, "42", strs["int"])
+
+	typed := map[string]any{}
+	_ = p.MetaIterMut(func(k string, v any) error {
+		typed[k] = v
+		return nil
+	})
+	assert.Equal(t, tstamp, typed["time"])
+	assert.Equal(t, true, typed["bool"])
+
+	// Copies retain types, and deep copies don't share reference types.
+	sCopy := p.ShallowCopy()
+	sCopy.MetaSetMut("int", int64(10))
+	v, _ = p.MetaGetMut("int")
+	assert.Equal(t, int64(42), v)
+
+	dCopy := p.DeepCopy()
+	dBytes, _ := dCopy.MetaGetMut("bytes")
+	dBytes.([]byte)[0] = 'g'
+	assert.Equal(t, "foo", p.MetaGet("bytes"))
+	assert.Equal(t, "goo", dCopy.MetaGet("bytes"))
+}
@@ -0,0 +1,70 @@
+package message
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBufferSize is the largest buffer capacity that is returned to the
+// pool, larger buffers are left to the garbage collector so that an occasional
+// huge message doesn't pin memory indefinitely.
+const maxPooledBufferSize = 1 << 20
+
+// jsonEncoder is a buffer paired with an encoder writing to it, both of which
+// are reused across messages in order to avoid allocating a new encoder and
+// growing a fresh buffer for each message within high throughput pipelines.
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var encoderPool = sync.Pool{
+	New: func() any {
+		e := &jsonEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		e.enc.SetEscapeHTML(false)
+		return e
+	},
+}
+
+// marshalJSON serialises a structured value with a pooled encoder and returns a
+// copy of exactly the size of the result.
+func marshalJSON(v any) ([]byte, error) {
+	e, _ := encoderPool.Get().(*jsonEncoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledBufferSize {
+			e.buf.Reset()
+			encoderPool.Put(e)
+		}
+	}()
+
+	if err := e.enc.Encode(v); err != nil {
+		e.buf.Reset()
+		return nil, err
+	}
+
+	// Trim the newline appended by the encoder.
+	b := e.buf.Bytes()[:e.buf.Len()-1]
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out, nil
+}
+
+//------------------------------------------------------------------------------
+
+// partWithData allocates a message part alongside its data, which halves the
+// allocations required for each new, copied or fanned out message. Copies of a
+// part still share underlying contents until they're written to.
+type partWithData struct {
+	part Part
+	data messageData
+}
+
+func newPartWithData(ctx context.Context, data messageData) *Part {
+	pd := &partWithData{data: data}
+	pd.part.data = &pd.data
+	pd.part.ctx = ctx
+	return &pd.part
+}
@@ -0,0 +1,136 @@
+package message
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledSerialisation(t *testing.T) {
+	a := NewPart(nil)
+	a.SetStructuredMut(map[string]any{"foo": "<bar>"})
+
+	b := NewPart(nil)
+	b.SetStructuredMut([]any{"baz", 10})
+
+	aBytes, bBytes := a.AsBytes(), b.AsBytes()
+	assert.Equal(t, `{"foo":"<bar>"}`, string(aBytes))
+	assert.Equal(t, `["baz",10]`, string(bBytes))
+	assert.Equal(t, len(aBytes), cap(aBytes))
+
+	// Serialising further messages must not modify previous results that
+	// shared a pooled buffer.
+	for i := 0; i < 10; i++ {
+		c := NewPart(nil)
+		c.SetStructuredMut(map[string]any{"nope": i})
+		_ = c.AsBytes()
+	}
+	assert.Equal(t, `{"foo":"<bar>"}`, string(aBytes))
+	assert.Equal(t, `["baz",10]`, string(bBytes))
+}
+
+func TestPartCopiesIsolated(t *testing.T) {
+	source := NewPart([]byte(`{"foo":"bar"}`))
+	source.MetaSetMut("foo", "bar")
+
+	shallow, deep := source.ShallowCopy(), source.DeepCopy()
+
+	v, err := shallow.AsStructuredMut()
+	require.NoError(t, err)
+	v.(map[string]any)["foo"] = "shallow"
+	shallow.MetaSetMut("foo", "shallow")
+
+	deep.SetBytes([]byte(`deep`))
+	deep.MetaSetMut("foo", "deep")
+
+	assert.Equal(t, `{"foo":"bar"}`, string(source.AsBytes()))
+	assert.Equal(t, "bar", source.MetaGet("foo"))
+	assert.Equal(t, `{"foo":"shallow"}`, string(shallow.AsBytes()))
+	assert.Equal(t, "shallow", shallow.MetaGet("foo"))
+	assert.Equal(t, `deep`, string(deep.AsBytes()))
+	assert.Equal(t, "deep", deep.MetaGet("foo"))
+}
+
+func BenchmarkPartShallowCopySerialise(b *testing.B) {
+	source := NewPart(nil)
+	source.SetStructuredMut(map[string]any{
+		"id":   "c3b2a1",
+		"tags": []any{"foo", "bar", "baz"},
+		"nested": map[string]any{
+			"value": 10.5,
+		},
+	})
+	source.MetaSetMut("topic", "events")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		p := source.ShallowCopy()
+		p.MetaSetMut("index", i)
+		_ = p.AsBytes()
+	}
+}
+
+func benchStructured() any {
+	return map[string]any{
+		"id":   "c3b2a1",
+		"tags": []any{"foo", "bar", "baz"},
+		"nested": map[string]any{
+			"value": 10.5,
+			"text":  "the quick brown fox jumps over the lazy dog",
+		},
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	v := benchStructured()
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := marshalJSON(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Mirrors the serialisation performed before encoders were pooled.
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(v); err != nil {
+				b.Fatal(err)
+			}
+			_ = buf.Bytes()[:buf.Len()-1]
+		}
+	})
+}
+
+var benchPartSink *Part
+
+func BenchmarkPartShallowCopy(b *testing.B) {
+	source := NewPart([]byte(`{"id":"c3b2a1"}`))
+	source.MetaSetMut("topic", "events")
+
+	b.Run("single allocation", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchPartSink = source.ShallowCopy()
+		}
+	})
+
+	// Mirrors the copy performed before parts were allocated with their data.
+	b.Run("separate allocations", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchPartSink = &Part{data: source.data.ShallowCopy(), ctx: source.ctx}
+		}
+	})
+}
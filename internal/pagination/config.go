@@ -0,0 +1,19 @@
+package pagination
+
+// Config contains configuration parameters for pagination.
+type Config struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Cursor   string `json:"cursor" yaml:"cursor"`
+	Check    string `json:"check" yaml:"check"`
+	MaxPages int    `json:"max_pages" yaml:"max_pages"`
+}
+
+// NewConfig creates a pagination config with default values.
+func NewConfig() Config {
+	return Config{
+		Enabled:  false,
+		Cursor:   "",
+		Check:    "",
+		MaxPages: 0,
+	}
+}
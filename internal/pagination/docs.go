@@ -0,0 +1,24 @@
+package pagination
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// FieldSpec returns a spec for a common pagination field.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject("pagination", `
+Allows you to configure requests to be repeated for each page of a paginated API. Following each response a cursor is extracted from the response with a mapping, and if one is found another request is made with the cursor added to the request message as the metadata field `+"`"+CursorMetaKey+"`"+`, which can be referenced within the `+"`url`"+` and `+"`headers`"+` fields. Pages are requested until the cursor is empty, the check fails, or the maximum number of pages is reached.`,
+	).WithChildren(
+		docs.FieldBool("enabled", "Whether pagination is enabled.").HasDefault(false),
+		docs.FieldBloblang(
+			"cursor",
+			"A [Bloblang mapping](/docs/guides/bloblang/about) executed against the last message of each response that should result in the cursor of the next page, where a `null` or empty result, or deleting the root, indicates that there are no more pages. Response headers can be referenced as metadata when extracted with the field `extract_headers`.",
+			`root = this.next_cursor`,
+			`root = meta("Link").re_find_all_submatch("<([^>]+)>; rel=\"next\"").index(0).index(1).catch(null)`,
+		).HasDefault(""),
+		docs.FieldBloblang(
+			"check",
+			"An optional [Bloblang query](/docs/guides/bloblang/about) executed against the last message of each response that should return a boolean indicating whether the next page should be requested.",
+			`this.has_more`,
+		).HasDefault(""),
+		docs.FieldInt("max_pages", "An optional maximum number of pages to request, where zero means no limit.").HasDefault(0),
+	).Advanced().AtVersion("4.9.0").ChildDefaultAndTypesFromStruct(NewConfig())
+}
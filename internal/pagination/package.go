@@ -0,0 +1,5 @@
+// Package pagination provides a generic mechanism for components that request
+// pages of results from a service, where a cursor is extracted from each page
+// with a Bloblang mapping and used in order to request the next page until a
+// termination condition is met.
+package pagination
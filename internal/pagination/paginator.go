@@ -0,0 +1,134 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// CursorMetaKey is the metadata key that the cursor of the next page is stored
+// within for each request following the first.
+const CursorMetaKey = "http_pagination_cursor"
+
+// Paginator determines the cursor of the next page following each page of
+// results.
+type Paginator struct {
+	cursor   *mapping.Executor
+	check    *mapping.Executor
+	maxPages int
+}
+
+// New creates a paginator from a config, returns nil if pagination is not
+// enabled.
+func New(conf Config, mgr interface {
+	BloblEnvironment() *bloblang.Environment
+}) (*Paginator, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+	if conf.Cursor == "" {
+		return nil, errors.New("a pagination cursor mapping is required")
+	}
+	if conf.MaxPages < 0 {
+		return nil, fmt.Errorf("pagination max_pages must not be negative, got %v", conf.MaxPages)
+	}
+
+	p := &Paginator{maxPages: conf.MaxPages}
+
+	var err error
+	if p.cursor, err = mgr.BloblEnvironment().NewMapping(conf.Cursor); err != nil {
+		return nil, fmt.Errorf("failed to parse pagination cursor mapping: %w", err)
+	}
+	if conf.Check != "" {
+		if p.check, err = mgr.BloblEnvironment().NewMapping(conf.Check); err != nil {
+			return nil, fmt.Errorf("failed to parse pagination check: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// Next returns the cursor of the page following a page of results, where pages
+// is the number of pages obtained so far. Returns false if there are no more
+// pages to request.
+func (p *Paginator) Next(page message.Batch, pages int) (string, bool, error) {
+	if page.Len() == 0 || (p.maxPages > 0 && pages >= p.maxPages) {
+		return "", false, nil
+	}
+	index := page.Len() - 1
+
+	if p.check != nil {
+		more, err := p.check.QueryPart(index, page)
+		if err != nil {
+			return "", false, fmt.Errorf("pagination check failed: %w", err)
+		}
+		if !more {
+			return "", false, nil
+		}
+	}
+
+	v, err := p.cursor.Exec(query.FunctionContext{
+		Vars:     map[string]any{},
+		Index:    index,
+		MsgBatch: page,
+		NewMeta:  page.Get(index).ShallowCopy(),
+	}.WithValueFunc(func() *any {
+		if jObj, err := page.Get(index).AsStructured(); err == nil {
+			return &jObj
+		}
+		return nil
+	}))
+	if err != nil {
+		return "", false, fmt.Errorf("pagination cursor mapping failed: %w", err)
+	}
+	switch v.(type) {
+	case nil, query.Nothing, query.Delete:
+		return "", false, nil
+	}
+	cursor := query.IToString(v)
+	return cursor, cursor != "", nil
+}
+
+// Paginate sends a request, and then sends further requests for each following
+// page with the cursor of the page added to each message of the request as
+// metadata. The messages of all pages are returned in order.
+func (p *Paginator) Paginate(ctx context.Context, req message.Batch, send func(context.Context, message.Batch) (message.Batch, error)) (message.Batch, error) {
+	var result message.Batch
+	for pages := 0; ; {
+		page, err := send(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, page...)
+		pages++
+
+		cursor, more, err := p.Next(page, pages)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return result, nil
+		}
+		req = WithCursor(req, cursor)
+	}
+}
+
+// WithCursor returns a shallow copy of a batch where each message has a cursor
+// set as metadata.
+func WithCursor(batch message.Batch, cursor string) message.Batch {
+	newBatch := make(message.Batch, len(batch))
+	for i, part := range batch {
+		newBatch[i] = part.ShallowCopy()
+		newBatch[i].MetaSet(CursorMetaKey, cursor)
+	}
+	if len(newBatch) == 0 {
+		part := message.NewPart(nil)
+		part.MetaSet(CursorMetaKey, cursor)
+		newBatch = append(newBatch, part)
+	}
+	return newBatch
+}
@@ -0,0 +1,98 @@
+package pagination
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+type testEnv struct{}
+
+func (testEnv) BloblEnvironment() *bloblang.Environment {
+	return bloblang.GlobalEnvironment()
+}
+
+func testConf(cursor, check string, maxPages int) Config {
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Cursor = cursor
+	conf.Check = check
+	conf.MaxPages = maxPages
+	return conf
+}
+
+func TestPaginatorDisabled(t *testing.T) {
+	p, err := New(NewConfig(), testEnv{})
+	require.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestPaginatorBadConfig(t *testing.T) {
+	_, err := New(testConf("", "", 0), testEnv{})
+	require.Error(t, err)
+
+	_, err = New(testConf("root = this.", "", 0), testEnv{})
+	require.Error(t, err)
+
+	_, err = New(testConf("root = this.next", "", -1), testEnv{})
+	require.Error(t, err)
+}
+
+func TestPaginatorNext(t *testing.T) {
+	p, err := New(testConf(`root = this.next`, `this.more`, 3), testEnv{})
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		name   string
+		page   string
+		pages  int
+		cursor string
+		more   bool
+	}{
+		{name: "string cursor", page: `{"next":"foo","more":true}`, pages: 1, cursor: "foo", more: true},
+		{name: "number cursor", page: `{"next":10,"more":true}`, pages: 1, cursor: "10", more: true},
+		{name: "null cursor", page: `{"next":null,"more":true}`, pages: 1},
+		{name: "empty cursor", page: `{"next":"","more":true}`, pages: 1},
+		{name: "check fails", page: `{"next":"foo","more":false}`, pages: 1},
+		{name: "max pages", page: `{"next":"foo","more":true}`, pages: 3},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cursor, more, err := p.Next(message.QuickBatch([][]byte{[]byte(test.page)}), test.pages)
+			require.NoError(t, err)
+			assert.Equal(t, test.cursor, cursor)
+			assert.Equal(t, test.more, more)
+		})
+	}
+
+	_, _, err = p.Next(message.QuickBatch([][]byte{[]byte(`not json`)}), 1)
+	require.Error(t, err)
+}
+
+func TestPaginatorPaginate(t *testing.T) {
+	p, err := New(testConf(`root = if this.page < 3 { (this.page + 1).string() } else { null }`, "", 0), testEnv{})
+	require.NoError(t, err)
+
+	var cursors []string
+	res, err := p.Paginate(context.Background(), message.QuickBatch([][]byte{[]byte("req")}), func(ctx context.Context, req message.Batch) (message.Batch, error) {
+		cursor := req.Get(0).MetaGet(CursorMetaKey)
+		cursors = append(cursors, cursor)
+		if cursor == "" {
+			cursor = "1"
+		}
+		return message.QuickBatch([][]byte{[]byte(`{"page":` + cursor + `}`)}), nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "2", "3"}, cursors)
+	assert.Equal(t, [][]byte{
+		[]byte(`{"page":1}`),
+		[]byte(`{"page":2}`),
+		[]byte(`{"page":3}`),
+	}, message.GetAllBytes(res))
+}
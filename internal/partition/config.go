@@ -0,0 +1,42 @@
+package partition
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+// Config contains fields for sharding the work of an input amongst multiple
+// instances of Benthos running the same config.
+type Config struct {
+	Cache     string `json:"cache" yaml:"cache"`
+	KeyPrefix string `json:"key_prefix" yaml:"key_prefix"`
+	Count     int    `json:"count" yaml:"count"`
+	LeaseTTL  string `json:"lease_ttl" yaml:"lease_ttl"`
+}
+
+// NewConfig creates a partitioning config with default values.
+func NewConfig() Config {
+	return Config{
+		Cache:     "",
+		KeyPrefix: "",
+		Count:     16,
+		LeaseTTL:  "30s",
+	}
+}
+
+// Enabled returns whether partitioning is configured.
+func (c Config) Enabled() bool {
+	return c.Cache != ""
+}
+
+// FieldSpec returns a field spec for the partitioning configuration of an
+// input.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject(
+		"partitioning", "Shards the work of this input amongst multiple instances of Benthos running the same config, by dividing it into a fixed number of partitions that instances claim by writing leases to a cache resource. Partitions are balanced evenly amongst the instances that are running, and the partitions of an instance that stops are claimed by the others once their leases expire.",
+	).WithChildren(
+		docs.FieldString("cache", "The [cache resource](/docs/components/caches/about) that claims are written to, which must be shared by all instances, such as a `redis` or `memcached` cache. Partitioning is disabled when this field is empty.").HasDefault(""),
+		docs.FieldString("key_prefix", "A prefix for the keys of claims, which must be unique to the input within the cache. When empty the label of the input is used.").HasDefault(""),
+		docs.FieldInt("count", "The number of partitions to divide the work into, which must be the same for all instances and limits the number of instances that can share the work.").HasDefault(16),
+		docs.FieldString("lease_ttl", "The period of time after which the claims of an instance expire unless renewed, which determines how long it takes for the partitions of an instance that stops unexpectedly to be claimed by others. Claims are renewed every third of this period.").HasDefault("30s"),
+	).Advanced().AtVersion("4.9.0")
+}
@@ -0,0 +1,396 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/shutdown"
+)
+
+// CacheAccessor provides access to the cache resources that claims are
+// written to.
+type CacheAccessor interface {
+	AccessCache(ctx context.Context, name string, fn func(cache.V1)) error
+}
+
+// Coordinator claims partitions of work on behalf of an instance, balancing
+// them evenly with the other instances that share the same cache and keys.
+//
+// Instances register themselves by claiming one of a number of member slots
+// equal to the number of partitions, which allows them to count the members
+// of the fleet without listing keys, and each instance then claims its share
+// of the partitions, releasing any that exceed it. Claims are written with
+// the Add method of the cache, and therefore only one instance can hold a
+// given partition at any time, unless a claim expires before it is renewed.
+type Coordinator struct {
+	cache    string
+	prefix   string
+	count    int
+	ttl      time.Duration
+	id       string
+	accessor CacheAccessor
+	log      log.Modular
+
+	// Only accessed by the loop.
+	slot int
+
+	mut       sync.Mutex
+	owned     map[int]struct{}
+	renewedAt time.Time
+	changed   chan struct{}
+	synced    chan struct{}
+
+	shutSig *shutdown.Signaller
+}
+
+// New creates a coordinator that claims partitions by writing to a cache
+// resource. The name is used as the prefix of claim keys when the config does
+// not specify one, and would usually be the label of the input.
+func New(conf Config, name string, accessor CacheAccessor, logger log.Modular) (*Coordinator, error) {
+	if !conf.Enabled() {
+		return nil, errors.New("partitioning is not configured")
+	}
+	if conf.Count <= 0 {
+		return nil, errors.New("partition count must be greater than zero")
+	}
+
+	prefix := conf.KeyPrefix
+	if prefix == "" {
+		prefix = name
+	}
+	if prefix == "" {
+		return nil, errors.New("partitioning requires either a key_prefix or an input label")
+	}
+
+	ttl, err := time.ParseDuration(conf.LeaseTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lease_ttl: %w", err)
+	}
+	if ttl <= 0 {
+		return nil, errors.New("lease_ttl must be greater than zero")
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coordinator{
+		cache:    conf.Cache,
+		prefix:   prefix,
+		count:    conf.Count,
+		ttl:      ttl,
+		id:       id.String(),
+		accessor: accessor,
+		log:      logger,
+		slot:     -1,
+		owned:    map[int]struct{}{},
+		changed:  make(chan struct{}),
+		synced:   make(chan struct{}),
+		shutSig:  shutdown.NewSignaller(),
+	}
+	go c.loop()
+	return c, nil
+}
+
+// Count returns the total number of partitions.
+func (c *Coordinator) Count() int {
+	return c.count
+}
+
+// PartitionOf returns the partition that a key belongs to.
+func (c *Coordinator) PartitionOf(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(c.count))
+}
+
+// Owns returns whether the instance currently holds the claim of a partition.
+// Claims that could not be renewed before they expire are no longer
+// considered held, as another instance might have claimed them since.
+func (c *Coordinator) Owns(partition int) bool {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if time.Since(c.renewedAt) >= c.ttl {
+		return false
+	}
+	_, exists := c.owned[partition]
+	return exists
+}
+
+// OwnsKey returns whether the instance currently holds the claim of the
+// partition that a key belongs to.
+func (c *Coordinator) OwnsKey(key string) bool {
+	return c.Owns(c.PartitionOf(key))
+}
+
+// Changed returns a channel that is closed the next time the partitions held
+// by the instance change.
+func (c *Coordinator) Changed() <-chan struct{} {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.changed
+}
+
+// WaitForSync blocks until the first attempt to claim partitions has finished,
+// which avoids consuming with no partitions whilst starting up.
+func (c *Coordinator) WaitForSync(ctx context.Context) error {
+	select {
+	case <-c.synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close releases the claims held by the instance so that others can take them
+// over immediately, rather than once they expire.
+func (c *Coordinator) Close(ctx context.Context) error {
+	c.shutSig.CloseNow()
+	select {
+	case <-c.shutSig.HasClosedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func (c *Coordinator) memberKey(slot int) string {
+	return c.prefix + "_member_" + strconv.Itoa(slot)
+}
+
+func (c *Coordinator) claimKey(partition int) string {
+	return c.prefix + "_partition_" + strconv.Itoa(partition)
+}
+
+func (c *Coordinator) loop() {
+	defer c.shutSig.ShutdownComplete()
+
+	ctx, done := c.shutSig.CloseNowCtx(context.Background())
+	defer done()
+
+	var syncOnce sync.Once
+	ticker := time.NewTicker(c.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := c.withCache(ctx, c.rebalance); err != nil && ctx.Err() == nil {
+			c.log.Errorf("Failed to claim partitions: %v\n", err)
+		}
+		syncOnce.Do(func() { close(c.synced) })
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.Background(), c.ttl)
+			if err := c.withCache(releaseCtx, c.releaseAll); err != nil {
+				c.log.Errorf("Failed to release partitions: %v\n", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+func (c *Coordinator) withCache(ctx context.Context, fn func(context.Context, cache.V1) error) error {
+	var err error
+	if aErr := c.accessor.AccessCache(ctx, c.cache, func(ca cache.V1) {
+		err = fn(ctx, ca)
+	}); aErr != nil {
+		return aErr
+	}
+	return err
+}
+
+// renew extends the lease of a key when it's held by this instance, and
+// returns false when it's not.
+func (c *Coordinator) renew(ctx context.Context, ca cache.V1, key string) (bool, error) {
+	v, err := ca.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, component.ErrKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if string(v) != c.id {
+		return false, nil
+	}
+	return true, ca.Set(ctx, key, []byte(c.id), &c.ttl)
+}
+
+// claim attempts to take a key that isn't held by any instance.
+func (c *Coordinator) claim(ctx context.Context, ca cache.V1, key string) (bool, error) {
+	err := ca.Add(ctx, key, []byte(c.id), &c.ttl)
+	if err != nil {
+		if errors.Is(err, component.ErrKeyAlreadyExists) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Coordinator) rebalance(ctx context.Context, ca cache.V1) error {
+	// Keep hold of a member slot, or claim the first free one.
+	if c.slot >= 0 {
+		held, err := c.renew(ctx, ca, c.memberKey(c.slot))
+		if err != nil {
+			return err
+		}
+		if !held {
+			c.slot = -1
+		}
+	}
+	for s := 0; c.slot < 0 && s < c.count; s++ {
+		claimed, err := c.claim(ctx, ca, c.memberKey(s))
+		if err != nil {
+			return err
+		}
+		if claimed {
+			c.slot = s
+		}
+	}
+
+	// Count the members and our rank amongst them, which determines our
+	// share of the partitions such that all of them are covered.
+	var members, rank int
+	for s := 0; s < c.count; s++ {
+		if _, err := ca.Get(ctx, c.memberKey(s)); err != nil {
+			if errors.Is(err, component.ErrKeyNotFound) {
+				continue
+			}
+			return err
+		}
+		members++
+		if s < c.slot {
+			rank++
+		}
+	}
+
+	target := 0
+	if c.slot >= 0 && members > 0 {
+		target = c.count / members
+		if rank < c.count%members {
+			target++
+		}
+	}
+
+	c.mut.Lock()
+	owned := make(map[int]struct{}, len(c.owned))
+	for p := range c.owned {
+		owned[p] = struct{}{}
+	}
+	c.mut.Unlock()
+
+	for p := range owned {
+		held, err := c.renew(ctx, ca, c.claimKey(p))
+		if err != nil {
+			return err
+		}
+		if !held {
+			c.log.Warnf("Lost claim of partition %v\n", p)
+			delete(owned, p)
+		}
+	}
+	renewedAt := time.Now()
+
+	if len(owned) > target {
+		held := make([]int, 0, len(owned))
+		for p := range owned {
+			held = append(held, p)
+		}
+		sort.Ints(held)
+		for _, p := range held[target:] {
+			if err := ca.Delete(ctx, c.claimKey(p)); err != nil && !errors.Is(err, component.ErrKeyNotFound) {
+				return err
+			}
+			delete(owned, p)
+		}
+	}
+
+	// Start looking for free partitions from a position that differs between
+	// members in order to reduce contention.
+	start := 0
+	if members > 0 && c.slot >= 0 {
+		start = rank * (c.count / members)
+	}
+	for i := 0; i < c.count && len(owned) < target; i++ {
+		p := (start + i) % c.count
+		if _, exists := owned[p]; exists {
+			continue
+		}
+		claimed, err := c.claim(ctx, ca, c.claimKey(p))
+		if err != nil {
+			return err
+		}
+		if claimed {
+			owned[p] = struct{}{}
+		}
+	}
+
+	c.setOwned(owned, renewedAt)
+	return nil
+}
+
+func (c *Coordinator) releaseAll(ctx context.Context, ca cache.V1) error {
+	c.mut.Lock()
+	owned := c.owned
+	c.mut.Unlock()
+
+	var err error
+	for p := range owned {
+		if dErr := ca.Delete(ctx, c.claimKey(p)); dErr != nil && !errors.Is(dErr, component.ErrKeyNotFound) {
+			err = dErr
+		}
+	}
+	if c.slot >= 0 {
+		if dErr := ca.Delete(ctx, c.memberKey(c.slot)); dErr != nil && !errors.Is(dErr, component.ErrKeyNotFound) {
+			err = dErr
+		}
+		c.slot = -1
+	}
+	c.setOwned(map[int]struct{}{}, time.Time{})
+	return err
+}
+
+func (c *Coordinator) setOwned(owned map[int]struct{}, renewedAt time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.renewedAt = renewedAt
+
+	changed := len(owned) != len(c.owned)
+	for p := range owned {
+		if _, exists := c.owned[p]; !exists {
+			changed = true
+		}
+	}
+	c.owned = owned
+	if !changed {
+		return
+	}
+
+	held := make([]int, 0, len(owned))
+	for p := range owned {
+		held = append(held, p)
+	}
+	sort.Ints(held)
+	c.log.Infof("Holding partitions %v of %v\n", held, c.count)
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
@@ -0,0 +1,249 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// lockedCache is a cache shared between coordinators running concurrently,
+// where accessing it locks it for the duration of the closure.
+type lockedCache struct {
+	mut    sync.Mutex
+	values map[string]string
+}
+
+func (l *lockedCache) AccessCache(ctx context.Context, name string, fn func(cache.V1)) error {
+	if name != "foo" {
+		return component.ErrCacheNotFound
+	}
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	fn(l)
+	return nil
+}
+
+func (l *lockedCache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, exists := l.values[key]
+	if !exists {
+		return nil, component.ErrKeyNotFound
+	}
+	return []byte(v), nil
+}
+
+func (l *lockedCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	l.values[key] = string(value)
+	return nil
+}
+
+func (l *lockedCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	if _, exists := l.values[key]; exists {
+		return component.ErrKeyAlreadyExists
+	}
+	l.values[key] = string(value)
+	return nil
+}
+
+func (l *lockedCache) Delete(ctx context.Context, key string) error {
+	delete(l.values, key)
+	return nil
+}
+
+func (l *lockedCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (l *lockedCache) SetMulti(ctx context.Context, items map[string]cache.TTLItem) error {
+	return errors.New("not implemented")
+}
+
+func (l *lockedCache) AddMulti(ctx context.Context, items map[string]cache.TTLItem) map[string]error {
+	return nil
+}
+
+func (l *lockedCache) Close(ctx context.Context) error {
+	return nil
+}
+
+func testConfig() Config {
+	conf := NewConfig()
+	conf.Cache = "foo"
+	conf.Count = 8
+	conf.LeaseTTL = "60ms"
+	return conf
+}
+
+func ownedBy(c *Coordinator) []int {
+	var owned []int
+	for p := 0; p < c.Count(); p++ {
+		if c.Owns(p) {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+func TestNewCoordinatorErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   func(c *Config)
+		label  string
+		errStr string
+	}{
+		{
+			name:   "not configured",
+			conf:   func(c *Config) { c.Cache = "" },
+			label:  "bar",
+			errStr: "partitioning is not configured",
+		},
+		{
+			name:   "bad count",
+			conf:   func(c *Config) { c.Count = 0 },
+			label:  "bar",
+			errStr: "partition count must be greater than zero",
+		},
+		{
+			name:   "no prefix",
+			conf:   func(c *Config) {},
+			errStr: "partitioning requires either a key_prefix or an input label",
+		},
+		{
+			name:   "bad ttl",
+			conf:   func(c *Config) { c.LeaseTTL = "0s" },
+			label:  "bar",
+			errStr: "lease_ttl must be greater than zero",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf := testConfig()
+			test.conf(&conf)
+
+			_, err := New(conf, test.label, &lockedCache{values: map[string]string{}}, log.Noop())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestCoordinatorSingle(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	caches := &lockedCache{values: map[string]string{}}
+
+	c, err := New(testConfig(), "bar", caches, log.Noop())
+	require.NoError(t, err)
+	require.NoError(t, c.WaitForSync(ctx))
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, ownedBy(c))
+	assert.True(t, c.OwnsKey("some key"))
+
+	caches.mut.Lock()
+	assert.Equal(t, c.id, caches.values["bar_member_0"])
+	assert.Equal(t, c.id, caches.values["bar_partition_3"])
+	caches.mut.Unlock()
+
+	require.NoError(t, c.Close(ctx))
+	assert.Empty(t, ownedBy(c))
+
+	caches.mut.Lock()
+	assert.Empty(t, caches.values)
+	caches.mut.Unlock()
+}
+
+func TestCoordinatorBalancing(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	caches := &lockedCache{values: map[string]string{}}
+	conf := testConfig()
+	conf.KeyPrefix = "baz"
+
+	var coordinators []*Coordinator
+	for i := 0; i < 3; i++ {
+		c, err := New(conf, "", caches, log.Noop())
+		require.NoError(t, err)
+		coordinators = append(coordinators, c)
+	}
+
+	balanced := func(cs []*Coordinator) bool {
+		seen := map[int]bool{}
+		for _, c := range cs {
+			owned := ownedBy(c)
+			if n := len(owned); n < conf.Count/len(cs) || n > conf.Count/len(cs)+1 {
+				return false
+			}
+			for _, p := range owned {
+				if seen[p] {
+					return false
+				}
+				seen[p] = true
+			}
+		}
+		return len(seen) == conf.Count
+	}
+
+	assert.Eventually(t, func() bool { return balanced(coordinators) }, time.Second*2, time.Millisecond*5)
+
+	changed := coordinators[0].Changed()
+	require.NoError(t, coordinators[2].Close(ctx))
+	coordinators = coordinators[:2]
+
+	select {
+	case <-changed:
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+	assert.Eventually(t, func() bool { return balanced(coordinators) }, time.Second*2, time.Millisecond*5)
+
+	for _, c := range coordinators {
+		require.NoError(t, c.Close(ctx))
+	}
+}
+
+func TestCoordinatorLostClaim(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	caches := &lockedCache{values: map[string]string{}}
+	conf := testConfig()
+	conf.Count = 2
+
+	c, err := New(conf, "bar", caches, log.Noop())
+	require.NoError(t, err)
+	defer c.Close(ctx)
+	require.NoError(t, c.WaitForSync(ctx))
+	require.True(t, c.Owns(1))
+
+	// Another instance takes over the claim after it expired.
+	caches.mut.Lock()
+	caches.values["bar_partition_1"] = "someone else"
+	caches.mut.Unlock()
+
+	assert.Eventually(t, func() bool { return !c.Owns(1) }, time.Second*2, time.Millisecond*5)
+	assert.True(t, c.Owns(0))
+}
+
+func TestPartitionOf(t *testing.T) {
+	c := &Coordinator{count: 16}
+	assert.Equal(t, c.PartitionOf("foo"), c.PartitionOf("foo"))
+
+	seen := map[int]bool{}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		p := c.PartitionOf(k)
+		assert.True(t, p >= 0 && p < 16)
+		seen[p] = true
+	}
+	assert.Greater(t, len(seen), 1)
+}
@@ -1,10 +1,14 @@
 package pipeline
 
 import (
+	"runtime"
 	"strconv"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
+	"github.com/benthosdev/benthos/v4/internal/lineage"
 )
 
 // Config is a configuration struct for creating parallel processing pipelines.
@@ -18,6 +22,10 @@ import (
 type Config struct {
 	Threads    int                `json:"threads" yaml:"threads"`
 	Processors []processor.Config `json:"processors" yaml:"processors"`
+	InFlight   inflight.Config    `json:"in_flight" yaml:"in_flight"`
+	Lineage    lineage.Config     `json:"lineage" yaml:"lineage"`
+	Parallel   ParallelConfig     `json:"parallel" yaml:"parallel"`
+	Autoscale  autoscale.Config   `json:"autoscale" yaml:"autoscale"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -25,6 +33,10 @@ func NewConfig() Config {
 	return Config{
 		Threads:    -1,
 		Processors: []processor.Config{},
+		InFlight:   inflight.NewConfig(),
+		Lineage:    lineage.NewConfig(),
+		Parallel:   NewParallelConfig(),
+		Autoscale:  autoscale.NewConfig(),
 	}
 }
 
@@ -41,8 +53,24 @@ func New(conf Config, mgr bundle.NewManagement) (processor.Pipeline, error) {
 			return nil, err
 		}
 	}
-	if conf.Threads == 1 {
-		return NewProcessor(processors...), nil
+
+	var parallel *parallelExecutor
+	if conf.Parallel.Workers != 0 {
+		parallel = newParallelExecutor(conf.Parallel.Workers)
+	}
+
+	var scaler *autoscale.Scaler
+	if conf.Autoscale.Enabled {
+		initial := conf.Threads
+		if initial <= 0 {
+			initial = runtime.NumCPU()
+		}
+		var err error
+		if scaler, err = autoscale.New(conf.Autoscale, initial, mgr.IntoPath("autoscale").Metrics()); err != nil {
+			return nil, err
+		}
+	} else if conf.Threads == 1 {
+		return newProcessor(parallel, processors...), nil
 	}
-	return NewPool(conf.Threads, mgr.Logger(), processors...)
+	return newPool(conf.Threads, parallel, scaler, mgr.Logger(), processors...)
 }
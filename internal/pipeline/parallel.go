@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// ParallelConfig contains configuration parameters for executing processors
+// across the parts of a batch in parallel.
+type ParallelConfig struct {
+	Workers int `json:"workers" yaml:"workers"`
+}
+
+// NewParallelConfig creates a parallel config with default values, which
+// disables parallel execution.
+func NewParallelConfig() ParallelConfig {
+	return ParallelConfig{
+		Workers: 0,
+	}
+}
+
+// ParallelFieldSpec returns a spec for the parallel field of a pipeline.
+func ParallelFieldSpec() docs.FieldSpec {
+	return docs.FieldObject("parallel", `
+Distributes the messages of each batch across a bounded pool of workers, where the processors of the pipeline are executed on each message individually and the results are re-assembled into a single batch in their original order. This is useful for CPU heavy processors such as compression, encryption or schema validation when batches are large and there are fewer batches in flight than CPU cores.
+
+Since each message is processed as a batch of one, processors that operate on whole batches such as `+"`archive`"+`, or functions that access other messages of a batch, should not be used when parallel execution is enabled.`,
+	).WithChildren(
+		docs.FieldInt("workers", "The maximum number of messages to process in parallel, which is shared across all pipeline threads. Set to `0` to disable parallel execution, or a negative number to match the number of logical CPUs.").HasDefault(0),
+	).Advanced().ChildDefaultAndTypesFromStruct(NewParallelConfig())
+}
+
+//------------------------------------------------------------------------------
+
+// parallelExecutor executes processors across the parts of a batch, bounded by
+// a pool of workers that may be shared by multiple pipelines.
+type parallelExecutor struct {
+	sem chan struct{}
+}
+
+func newParallelExecutor(workers int) *parallelExecutor {
+	if workers < 0 {
+		workers = runtime.NumCPU()
+	}
+	return &parallelExecutor{
+		sem: make(chan struct{}, workers),
+	}
+}
+
+func (e *parallelExecutor) execute(ctx context.Context, procs []processor.V1, msg message.Batch) ([]message.Batch, error) {
+	if len(msg) <= 1 {
+		return processor.ExecuteAll(ctx, procs, msg)
+	}
+
+	return processor.ExecutePerPart(ctx, e.sem, msg, func(ctx context.Context, b message.Batch) ([]message.Batch, error) {
+		return processor.ExecuteAll(ctx, procs, b)
+	})
+}
@@ -0,0 +1,142 @@
+package pipeline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/pipeline"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+func TestPipelineParallelOrdering(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	sleepConf := processor.NewConfig()
+	sleepConf.Type = "sleep"
+	sleepConf.Sleep.Duration = `${! 500 - (content().length() * 100) }ms`
+
+	blobConf := processor.NewConfig()
+	blobConf.Type = "bloblang"
+	blobConf.Bloblang = `root = if content() == "drop" { deleted() } else { content().uppercase() }`
+
+	conf := pipeline.NewConfig()
+	conf.Threads = 1
+	conf.Parallel.Workers = 4
+	conf.Processors = append(conf.Processors, sleepConf, blobConf)
+
+	proc, err := pipeline.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan message.Transaction), make(chan error)
+	require.NoError(t, proc.Consume(tChan))
+
+	// Messages sleep for less time the longer they are, and therefore finish
+	// processing in reverse order.
+	inputs := [][]byte{
+		[]byte("a"),
+		[]byte("bb"),
+		[]byte("drop"),
+		[]byte("cccc"),
+	}
+
+	start := time.Now()
+	select {
+	case tChan <- message.NewTransaction(message.QuickBatch(inputs), resChan):
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	var tran message.Transaction
+	select {
+	case tran = <-proc.TransactionChan():
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	// Running sequentially would take at least a second.
+	assert.Less(t, time.Since(start), time.Millisecond*800)
+	assert.Equal(t, [][]byte{
+		[]byte("A"),
+		[]byte("BB"),
+		[]byte("CCCC"),
+	}, message.GetAllBytes(tran.Payload))
+
+	go func() {
+		require.NoError(t, tran.Ack(ctx, nil))
+	}()
+	select {
+	case err := <-resChan:
+		assert.NoError(t, err)
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	proc.TriggerCloseNow()
+	require.NoError(t, proc.WaitForClose(ctx))
+}
+
+func TestPipelineParallelSingleWorker(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	blobConf := processor.NewConfig()
+	blobConf.Type = "bloblang"
+	blobConf.Bloblang = `root = content().uppercase()`
+
+	conf := pipeline.NewConfig()
+	conf.Threads = 2
+	conf.Parallel.Workers = 1
+	conf.Processors = append(conf.Processors, blobConf)
+
+	proc, err := pipeline.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan message.Transaction), make(chan error)
+	require.NoError(t, proc.Consume(tChan))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case tChan <- message.NewTransaction(message.QuickBatch([][]byte{
+			[]byte("foo"), []byte("bar"), []byte("baz"),
+		}), resChan):
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		var tran message.Transaction
+		select {
+		case tran = <-proc.TransactionChan():
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+		assert.Equal(t, [][]byte{
+			[]byte("FOO"), []byte("BAR"), []byte("BAZ"),
+		}, message.GetAllBytes(tran.Payload))
+		go func() {
+			require.NoError(t, tran.Ack(ctx, nil))
+		}()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-resChan:
+			assert.NoError(t, err)
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	proc.TriggerCloseNow()
+	require.NoError(t, proc.WaitForClose(ctx))
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -18,6 +19,7 @@ import (
 // response channel in the transaction.
 type Pool struct {
 	workers []processor.Pipeline
+	scaler  *autoscale.Scaler
 
 	log log.Modular
 
@@ -29,19 +31,31 @@ type Pool struct {
 
 // NewPool creates a new processing pool.
 func NewPool(threads int, log log.Modular, msgProcessors ...processor.V1) (*Pool, error) {
+	return newPool(threads, nil, nil, log, msgProcessors...)
+}
+
+func newPool(threads int, parallel *parallelExecutor, scaler *autoscale.Scaler, log log.Modular, msgProcessors ...processor.V1) (*Pool, error) {
 	if threads <= 0 {
 		threads = runtime.NumCPU()
 	}
+	if scaler != nil {
+		// A thread is created for the maximum number of workers, and the
+		// scaler limits how many of them are active.
+		threads = scaler.Max()
+	}
 
 	p := &Pool{
 		workers:     make([]processor.Pipeline, threads),
+		scaler:      scaler,
 		log:         log,
 		messagesOut: make(chan message.Transaction),
 		shutSig:     shutdown.NewSignaller(),
 	}
 
 	for i := range p.workers {
-		p.workers[i] = NewProcessor(msgProcessors...)
+		w := newProcessor(parallel, msgProcessors...)
+		w.scaler, w.index = scaler, i
+		p.workers[i] = w
 	}
 
 	return p, nil
@@ -69,6 +83,12 @@ func (p *Pool) loop() {
 		p.shutSig.ShutdownComplete()
 	}()
 
+	if p.scaler != nil {
+		stopScaling := make(chan struct{})
+		defer close(stopScaling)
+		go p.scaler.Loop(stopScaling)
+	}
+
 	internalMessages := make(chan message.Transaction)
 	remainingWorkers := int64(len(p.workers))
 
@@ -276,3 +276,66 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 	close(tChan)
 	require.NoError(t, proc.WaitForClose(context.Background()))
 }
+
+func TestPoolAutoscaleNaturalClose(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	blobConf := processor.NewConfig()
+	blobConf.Type = "bloblang"
+	blobConf.Bloblang = `root = content().uppercase()`
+
+	conf := pipeline.NewConfig()
+	conf.Threads = 1
+	conf.Autoscale.Enabled = true
+	conf.Autoscale.Min = 1
+	conf.Autoscale.Max = 4
+	conf.Processors = append(conf.Processors, blobConf)
+
+	proc, err := pipeline.New(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan message.Transaction), make(chan error)
+	require.NoError(t, proc.Consume(tChan))
+
+	for i := 0; i < 5; i++ {
+		select {
+		case tChan <- message.NewTransaction(message.QuickBatch([][]byte{[]byte("foo")}), resChan):
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+
+		var tran message.Transaction
+		select {
+		case tran = <-proc.TransactionChan():
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+		assert.Equal(t, [][]byte{[]byte("FOO")}, message.GetAllBytes(tran.Payload))
+		go func() {
+			require.NoError(t, tran.Ack(ctx, nil))
+		}()
+
+		select {
+		case err := <-resChan:
+			assert.NoError(t, err)
+		case <-ctx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	// Inactive threads must also shut down once the input is closed.
+	close(tChan)
+	require.NoError(t, proc.WaitForClose(ctx))
+}
+
+func TestPoolAutoscaleBadConfig(t *testing.T) {
+	conf := pipeline.NewConfig()
+	conf.Autoscale.Enabled = true
+	conf.Autoscale.Min = 5
+	conf.Autoscale.Max = 2
+	conf.Processors = append(conf.Processors, processor.NewConfig())
+
+	_, err := pipeline.New(conf, mock.NewManager())
+	require.Error(t, err)
+}
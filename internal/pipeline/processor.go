@@ -3,7 +3,9 @@ package pipeline
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -16,6 +18,12 @@ import (
 // either propagate a new message or drop it.
 type Processor struct {
 	msgProcessors []processor.V1
+	parallel      *parallelExecutor
+
+	// When autoscaling the processor only consumes messages while its index
+	// is within the limit of active threads.
+	scaler *autoscale.Scaler
+	index  int
 
 	messagesOut chan message.Transaction
 	responsesIn chan error
@@ -27,8 +35,13 @@ type Processor struct {
 
 // NewProcessor returns a new message processing pipeline.
 func NewProcessor(msgProcessors ...processor.V1) *Processor {
+	return newProcessor(nil, msgProcessors...)
+}
+
+func newProcessor(parallel *parallelExecutor, msgProcessors ...processor.V1) *Processor {
 	return &Processor{
 		msgProcessors: msgProcessors,
+		parallel:      parallel,
 		messagesOut:   make(chan message.Transaction),
 		responsesIn:   make(chan error),
 		shutSig:       shutdown.NewSignaller(),
@@ -56,17 +69,36 @@ func (p *Processor) loop() {
 
 	var open bool
 	for !p.shutSig.ShouldCloseAtLeisure() {
+		if p.scaler != nil && !p.scaler.WaitActive(p.index, p.shutSig.CloseAtLeisureChan()) {
+			return
+		}
+
 		var tran message.Transaction
 		select {
 		case tran, open = <-p.messagesIn:
 			if !open {
+				if p.scaler != nil {
+					// Wake inactive threads so that they also observe the
+					// closed channel.
+					p.scaler.Release()
+				}
 				return
 			}
 		case <-p.shutSig.CloseNowChan():
 			return
 		}
 
-		resultMsgs, resultRes := processor.ExecuteAll(closeNowCtx, p.msgProcessors, tran.Payload)
+		var resultMsgs []message.Batch
+		var resultRes error
+		t0 := time.Now()
+		if p.parallel != nil {
+			resultMsgs, resultRes = p.parallel.execute(closeNowCtx, p.msgProcessors, tran.Payload)
+		} else {
+			resultMsgs, resultRes = processor.ExecuteAll(closeNowCtx, p.msgProcessors, tran.Payload)
+		}
+		if p.scaler != nil {
+			p.scaler.Record(time.Since(t0))
+		}
 		if len(resultMsgs) == 0 {
 			if err := tran.Ack(closeNowCtx, resultRes); err != nil && closeNowCtx.Err() != nil {
 				return
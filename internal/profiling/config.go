@@ -0,0 +1,59 @@
+package profiling
+
+import "github.com/benthosdev/benthos/v4/internal/docs"
+
+// Profile types that can be collected.
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+	ProfileMutex     = "mutex"
+	ProfileBlock     = "block"
+)
+
+// Config contains configuration parameters for pushing profiles to a
+// continuous profiling server.
+type Config struct {
+	Enabled         bool              `json:"enabled" yaml:"enabled"`
+	URL             string            `json:"url" yaml:"url"`
+	ApplicationName string            `json:"application_name" yaml:"application_name"`
+	Interval        string            `json:"interval" yaml:"interval"`
+	ProfileTypes    []string          `json:"profile_types" yaml:"profile_types"`
+	Labels          map[string]string `json:"labels" yaml:"labels"`
+	Headers         map[string]string `json:"headers" yaml:"headers"`
+}
+
+// NewConfig creates a profiling config with default values, which disables
+// pushing profiles.
+func NewConfig() Config {
+	return Config{
+		Enabled:         false,
+		URL:             "",
+		ApplicationName: "benthos",
+		Interval:        "15s",
+		ProfileTypes:    []string{ProfileCPU, ProfileHeap},
+		Labels:          map[string]string{},
+		Headers:         map[string]string{},
+	}
+}
+
+// FieldSpec returns a spec for the profiler section of a config.
+func FieldSpec() docs.FieldSpec {
+	return docs.FieldObject("profiler", `
+Continuously collects runtime profiles and pushes them to a server implementing the Pyroscope ingestion API. Components of each stream are executed with the profiler labels `+"`component`"+`, which is one of `+"`input`"+`, `+"`buffer`"+`, `+"`pipeline`"+` or `+"`output`"+`, and `+"`stream`"+` when running in streams mode, allowing CPU hotspots to be attributed to specific stages of a pipeline.
+
+Profilers that scrape profiles, such as Parca, can instead be pointed at the `+"`/debug/pprof`"+` endpoints registered when `+"`http.debug_endpoints`"+` is enabled.`,
+	).WithChildren(
+		docs.FieldBool("enabled", "Whether to push profiles.").HasDefault(false),
+		docs.FieldString("url", "The base URL of the profiling server.", "http://localhost:4040").HasDefault(""),
+		docs.FieldString("application_name", "The application name that profiles are pushed under.").HasDefault("benthos"),
+		docs.FieldString("interval", "The period of time covered by each CPU profile, which is also how often profiles are pushed.").HasDefault("15s"),
+		docs.FieldString("profile_types", "The types of profile to collect.").Array().HasOptions(
+			ProfileCPU, ProfileHeap, ProfileGoroutine, ProfileMutex, ProfileBlock,
+		).HasDefault([]any{ProfileCPU, ProfileHeap}),
+		docs.FieldString("labels", "Static labels to add to all profiles, such as the environment or region.").Map().Advanced().HasDefault(map[string]any{}),
+		docs.FieldString("headers", "A map of headers to add to each push request, which can be used for authentication.", map[string]any{
+			"Authorization": "Bearer ${PYROSCOPE_TOKEN}",
+		}).Map().Advanced().HasDefault(map[string]any{}),
+	).Advanced().AtVersion("4.9.0").ChildDefaultAndTypesFromStruct(NewConfig())
+}
@@ -0,0 +1,4 @@
+// Package profiling implements the continuous collection of runtime profiles
+// and pushing them to a profiling server, enabling production hotspots to be
+// attributed to the streams and components that caused them.
+package profiling
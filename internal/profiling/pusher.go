@@ -0,0 +1,230 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// Pusher collects profiles of the running process each interval and pushes
+// them to a profiling server.
+type Pusher struct {
+	ingestURL string
+	name      string
+	interval  time.Duration
+	headers   map[string]string
+
+	cpu       bool
+	snapshots []string
+
+	client *http.Client
+	log    log.Modular
+
+	shutSig chan struct{}
+	closed  chan struct{}
+}
+
+// NewPusher creates a profile pusher from a config. The pusher does not begin
+// collecting profiles until Start is called.
+func NewPusher(conf Config, logger log.Modular) (*Pusher, error) {
+	if conf.URL == "" {
+		return nil, errors.New("a profiler url must be specified")
+	}
+	baseURL, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profiler url: %w", err)
+	}
+	baseURL.Path = strings.TrimSuffix(baseURL.Path, "/") + "/ingest"
+
+	if conf.ApplicationName == "" {
+		return nil, errors.New("a profiler application_name must be specified")
+	}
+
+	interval, err := time.ParseDuration(conf.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profiler interval: %w", err)
+	}
+	if interval <= 0 {
+		return nil, errors.New("profiler interval must be greater than zero")
+	}
+
+	p := &Pusher{
+		ingestURL: baseURL.String(),
+		name:      conf.ApplicationName + formatLabels(conf.Labels),
+		interval:  interval,
+		headers:   conf.Headers,
+		client:    &http.Client{Timeout: interval},
+		log:       logger,
+		shutSig:   make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+
+	for _, t := range conf.ProfileTypes {
+		switch t {
+		case ProfileCPU:
+			p.cpu = true
+		case ProfileHeap, ProfileGoroutine, ProfileMutex, ProfileBlock:
+			p.snapshots = append(p.snapshots, t)
+		default:
+			return nil, fmt.Errorf("profile type not recognised: %v", t)
+		}
+	}
+	if !p.cpu && len(p.snapshots) == 0 {
+		return nil, errors.New("at least one profile type must be specified")
+	}
+	return p, nil
+}
+
+// formatLabels returns labels in the form {key=value,...} sorted by key, which
+// is how the ingestion API expects them to be appended to the application
+// name.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Start collecting and pushing profiles in the background.
+func (p *Pusher) Start() {
+	go p.loop()
+}
+
+func (p *Pusher) loop() {
+	defer close(p.closed)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		from := time.Now()
+
+		var cpuBuf bytes.Buffer
+		cpuStarted := false
+		if p.cpu {
+			if err := pprof.StartCPUProfile(&cpuBuf); err != nil {
+				// Most likely a profile has been requested via the debug
+				// endpoints, so we skip this interval.
+				p.log.Debugf("Skipping CPU profile: %v\n", err)
+			} else {
+				cpuStarted = true
+			}
+		}
+
+		stopped := false
+		select {
+		case <-ticker.C:
+		case <-p.shutSig:
+			stopped = true
+		}
+
+		if cpuStarted {
+			pprof.StopCPUProfile()
+		}
+		until := time.Now()
+
+		ctx, done := context.WithTimeout(context.Background(), p.interval)
+		if cpuStarted {
+			if err := p.push(ctx, ProfileCPU, from, until, cpuBuf.Bytes()); err != nil {
+				p.log.Errorf("Failed to push CPU profile: %v\n", err)
+			}
+		}
+		for _, t := range p.snapshots {
+			var buf bytes.Buffer
+			if err := pprof.Lookup(t).WriteTo(&buf, 0); err != nil {
+				p.log.Errorf("Failed to collect %v profile: %v\n", t, err)
+				continue
+			}
+			if err := p.push(ctx, t, from, until, buf.Bytes()); err != nil {
+				p.log.Errorf("Failed to push %v profile: %v\n", t, err)
+			}
+		}
+		done()
+
+		if stopped {
+			return
+		}
+	}
+}
+
+func (p *Pusher) push(ctx context.Context, profileType string, from, until time.Time, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	query := url.Values{}
+	query.Set("name", p.name)
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("format", "pprof")
+	query.Set("spyName", "gospy")
+	if profileType == ProfileCPU {
+		query.Set("sampleRate", "100")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.ingestURL+"?"+query.Encode(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, body)
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// Close stops collecting profiles, pushing any profiles collected so far, and
+// blocks until complete or the context is cancelled.
+func (p *Pusher) Close(ctx context.Context) error {
+	select {
+	case <-p.shutSig:
+	default:
+		close(p.shutSig)
+	}
+	select {
+	case <-p.closed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+func TestPusherBadConfig(t *testing.T) {
+	conf := NewConfig()
+	_, err := NewPusher(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.URL = "http://localhost:4040"
+	conf.Interval = "nope"
+	_, err = NewPusher(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.Interval = "1s"
+	conf.ProfileTypes = []string{"nope"}
+	_, err = NewPusher(conf, log.Noop())
+	require.Error(t, err)
+
+	conf.ProfileTypes = nil
+	_, err = NewPusher(conf, log.Noop())
+	require.Error(t, err)
+}
+
+func TestPusherPushes(t *testing.T) {
+	var mut sync.Mutex
+	var names []string
+	var auth []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.NotEmpty(t, body)
+		assert.Equal(t, "/foo/ingest", r.URL.Path)
+		assert.Equal(t, "pprof", r.URL.Query().Get("format"))
+
+		mut.Lock()
+		names = append(names, r.URL.Query().Get("name"))
+		auth = append(auth, r.Header.Get("Authorization"))
+		mut.Unlock()
+	}))
+	defer server.Close()
+
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.URL = server.URL + "/foo/"
+	conf.ApplicationName = "test"
+	conf.Interval = "50ms"
+	conf.ProfileTypes = []string{ProfileCPU, ProfileGoroutine}
+	conf.Labels = map[string]string{"region": "eu", "env": "prod"}
+	conf.Headers = map[string]string{"Authorization": "Bearer nope"}
+
+	p, err := NewPusher(conf, log.Noop())
+	require.NoError(t, err)
+	p.Start()
+
+	assert.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(names) >= 2
+	}, time.Second*5, time.Millisecond*10)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+	require.NoError(t, p.Close(ctx))
+
+	mut.Lock()
+	defer mut.Unlock()
+	for i, name := range names {
+		assert.Equal(t, "test{env=prod,region=eu}", name)
+		assert.Equal(t, "Bearer nope", auth[i])
+	}
+}
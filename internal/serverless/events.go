@@ -0,0 +1,109 @@
+package serverless
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// eventRecord is a record of an event batch that was sent by an event source
+// mapping, such as an SQS queue or a Kinesis stream.
+type eventRecord struct {
+	id   string
+	part *message.Part
+}
+
+// eventRecordParsers converts records of an event source into messages,
+// returning the identifier of the record that is reported when it fails.
+var eventRecordParsers = map[string]func(record map[string]any) (eventRecord, error){
+	"aws:sqs": func(record map[string]any) (eventRecord, error) {
+		id, _ := record["messageId"].(string)
+		body, _ := record["body"].(string)
+
+		part := message.NewPart([]byte(body))
+		if attrs, ok := record["messageAttributes"].(map[string]any); ok {
+			for k, v := range attrs {
+				attr, _ := v.(map[string]any)
+				if str, ok := attr["stringValue"].(string); ok {
+					part.MetaSetMut(k, str)
+				}
+			}
+		}
+		part.MetaSetMut("sqs_message_id", id)
+		return eventRecord{id: id, part: part}, nil
+	},
+	"aws:kinesis": func(record map[string]any) (eventRecord, error) {
+		kinesis, _ := record["kinesis"].(map[string]any)
+		id, _ := kinesis["sequenceNumber"].(string)
+		encoded, _ := kinesis["data"].(string)
+
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return eventRecord{}, fmt.Errorf("failed to decode data of record %v: %w", id, err)
+		}
+
+		part := message.NewPart(data)
+		if key, ok := kinesis["partitionKey"].(string); ok {
+			part.MetaSetMut("kinesis_partition_key", key)
+		}
+		part.MetaSetMut("kinesis_sequence_number", id)
+		return eventRecord{id: id, part: part}, nil
+	},
+	"aws:dynamodb": func(record map[string]any) (eventRecord, error) {
+		dynamodb, _ := record["dynamodb"].(map[string]any)
+		id, _ := dynamodb["SequenceNumber"].(string)
+
+		part := message.NewPart(nil)
+		part.SetStructuredMut(dynamodb)
+		if name, ok := record["eventName"].(string); ok {
+			part.MetaSetMut("dynamodb_event_name", name)
+		}
+		part.MetaSetMut("dynamodb_sequence_number", id)
+		return eventRecord{id: id, part: part}, nil
+	},
+}
+
+// parseEventRecords attempts to parse an invocation payload as the event batch
+// of an event source mapping, and returns false when the payload is not
+// recognised as one.
+func parseEventRecords(obj any) ([]eventRecord, bool, error) {
+	event, ok := obj.(map[string]any)
+	if !ok {
+		return nil, false, nil
+	}
+	rawRecords, ok := event["Records"].([]any)
+	if !ok || len(rawRecords) == 0 {
+		return nil, false, nil
+	}
+
+	records := make([]eventRecord, 0, len(rawRecords))
+	for _, v := range rawRecords {
+		rawRecord, ok := v.(map[string]any)
+		if !ok {
+			return nil, false, nil
+		}
+
+		source, _ := rawRecord["eventSource"].(string)
+		parser, exists := eventRecordParsers[source]
+		if !exists {
+			return nil, false, nil
+		}
+
+		record, err := parser(rawRecord)
+		if err != nil {
+			return nil, true, err
+		}
+		if record.id == "" {
+			return nil, true, errors.New("event record is missing an identifier")
+		}
+
+		record.part.MetaSetMut("lambda_event_source", source)
+		if arn, ok := rawRecord["eventSourceARN"].(string); ok {
+			record.part.MetaSetMut("lambda_event_source_arn", arn)
+		}
+		records = append(records, record)
+	}
+	return records, true, nil
+}
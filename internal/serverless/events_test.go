@@ -0,0 +1,86 @@
+package serverless
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestEvent(t *testing.T, event string) any {
+	t.Helper()
+	var obj any
+	require.NoError(t, json.Unmarshal([]byte(event), &obj))
+	return obj
+}
+
+func TestParseEventRecordsSQS(t *testing.T) {
+	records, isEvent, err := parseEventRecords(parseTestEvent(t, `{"Records":[
+  {"messageId":"a","body":"first","eventSource":"aws:sqs","eventSourceARN":"arn:aws:sqs:us-east-1:123:foo","messageAttributes":{"bar":{"stringValue":"baz","dataType":"String"}}},
+  {"messageId":"b","body":"second","eventSource":"aws:sqs"}
+]}`))
+	require.NoError(t, err)
+	require.True(t, isEvent)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "a", records[0].id)
+	assert.Equal(t, "first", string(records[0].part.AsBytes()))
+	assert.Equal(t, "baz", records[0].part.MetaGet("bar"))
+	assert.Equal(t, "a", records[0].part.MetaGet("sqs_message_id"))
+	assert.Equal(t, "aws:sqs", records[0].part.MetaGet("lambda_event_source"))
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123:foo", records[0].part.MetaGet("lambda_event_source_arn"))
+
+	assert.Equal(t, "b", records[1].id)
+	assert.Equal(t, "second", string(records[1].part.AsBytes()))
+}
+
+func TestParseEventRecordsKinesis(t *testing.T) {
+	records, isEvent, err := parseEventRecords(parseTestEvent(t, `{"Records":[
+  {"kinesis":{"partitionKey":"foo","sequenceNumber":"123","data":"aGVsbG8="},"eventSource":"aws:kinesis"}
+]}`))
+	require.NoError(t, err)
+	require.True(t, isEvent)
+	require.Len(t, records, 1)
+
+	assert.Equal(t, "123", records[0].id)
+	assert.Equal(t, "hello", string(records[0].part.AsBytes()))
+	assert.Equal(t, "foo", records[0].part.MetaGet("kinesis_partition_key"))
+
+	_, isEvent, err = parseEventRecords(parseTestEvent(t, `{"Records":[
+  {"kinesis":{"sequenceNumber":"123","data":"not base64!"},"eventSource":"aws:kinesis"}
+]}`))
+	require.True(t, isEvent)
+	require.Error(t, err)
+}
+
+func TestParseEventRecordsDynamoDB(t *testing.T) {
+	records, isEvent, err := parseEventRecords(parseTestEvent(t, `{"Records":[
+  {"eventName":"INSERT","dynamodb":{"Keys":{"id":{"S":"foo"}},"SequenceNumber":"111"},"eventSource":"aws:dynamodb"}
+]}`))
+	require.NoError(t, err)
+	require.True(t, isEvent)
+	require.Len(t, records, 1)
+
+	assert.Equal(t, "111", records[0].id)
+	assert.Equal(t, "INSERT", records[0].part.MetaGet("dynamodb_event_name"))
+	assert.Equal(t, `{"Keys":{"id":{"S":"foo"}},"SequenceNumber":"111"}`, string(records[0].part.AsBytes()))
+}
+
+func TestParseEventRecordsNotEvents(t *testing.T) {
+	for _, event := range []string{
+		`{"foo":"bar"}`,
+		`[{"eventSource":"aws:sqs"}]`,
+		`{"Records":[]}`,
+		`{"Records":[{"eventSource":"aws:s3"}]}`,
+		`{"Records":[{"messageId":"a","eventSource":"aws:sqs"},{"eventSource":"aws:s3"}]}`,
+	} {
+		_, isEvent, err := parseEventRecords(parseTestEvent(t, event))
+		require.NoError(t, err, event)
+		assert.False(t, isEvent, event)
+	}
+
+	_, isEvent, err := parseEventRecords(parseTestEvent(t, `{"Records":[{"body":"a","eventSource":"aws:sqs"}]}`))
+	assert.True(t, isEvent)
+	require.Error(t, err)
+}
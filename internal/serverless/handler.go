@@ -9,6 +9,7 @@ import (
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	ioutput "github.com/benthosdev/benthos/v4/internal/component/output"
@@ -29,10 +30,21 @@ const ServerlessResponseType = "sync_response"
 // Handler contains a live Benthos pipeline and wraps it within an invoke
 // handler.
 type Handler struct {
+	batchEvents     bool
 	transactionChan chan message.Transaction
 	done            func(exitTimeout time.Duration) error
 }
 
+// OptSetEventBatching sets whether the record batches of event source mappings
+// (SQS, Kinesis and DynamoDB streams) are consumed as batches with a message
+// per record, where the response of an invocation reports the records that
+// failed as batch item failures.
+func OptSetEventBatching(b bool) func(*Handler) {
+	return func(h *Handler) {
+		h.batchEvents = b
+	}
+}
+
 // Close shuts down the underlying pipeline. If the shut down takes longer than
 // the specified timeout it is aborted and an error is returned.
 func (h *Handler) Close(tout time.Duration) error {
@@ -42,6 +54,16 @@ func (h *Handler) Close(tout time.Duration) error {
 // Handle is a request/response func that injects a payload into the underlying
 // Benthos pipeline and returns a result.
 func (h *Handler) Handle(ctx context.Context, obj any) (any, error) {
+	if h.batchEvents {
+		records, isEvent, err := parseEventRecords(obj)
+		if err != nil {
+			return nil, err
+		}
+		if isEvent {
+			return h.handleEventRecords(ctx, records)
+		}
+	}
+
 	part := message.NewPart(nil)
 	part.SetStructuredMut(obj)
 	msg := message.Batch{part}
@@ -100,8 +122,67 @@ func (h *Handler) Handle(ctx context.Context, obj any) (any, error) {
 	return genBatchOfBatches, nil
 }
 
+// handleEventRecords injects the records of an event batch into the pipeline
+// as a single batch, and returns a partial batch response listing the
+// identifiers of records that failed.
+func (h *Handler) handleEventRecords(ctx context.Context, records []eventRecord) (any, error) {
+	msg := make(message.Batch, len(records))
+	for i, r := range records {
+		msg[i] = r.part
+	}
+	sortGroup, msg := message.NewSortGroup(msg)
+	transaction.AddResultStore(msg, transaction.NewResultStore())
+
+	resChan := make(chan error, 1)
+
+	select {
+	case h.transactionChan <- message.NewTransaction(msg, resChan):
+	case <-ctx.Done():
+		return nil, errors.New("request cancelled")
+	}
+
+	var res error
+	select {
+	case res = <-resChan:
+	case <-ctx.Done():
+		return nil, errors.New("request cancelled")
+	}
+
+	failures := []any{}
+	if res == nil {
+		return map[string]any{"batchItemFailures": failures}, nil
+	}
+
+	failed := make([]bool, len(records))
+	allFailed := true
+	if walkable, ok := res.(batch.WalkableError); ok {
+		walkable.WalkParts(func(_ int, p *message.Part, err error) bool {
+			if err == nil {
+				return true
+			}
+			if i := sortGroup.GetIndex(p); i >= 0 {
+				failed[i] = true
+				allFailed = false
+				return true
+			}
+
+			// If we couldn't link the errored part back to a record then all
+			// records need to be retried.
+			allFailed = true
+			return false
+		})
+	}
+
+	for i, r := range records {
+		if allFailed || failed[i] {
+			failures = append(failures, map[string]any{"itemIdentifier": r.id})
+		}
+	}
+	return map[string]any{"batchItemFailures": failures}, nil
+}
+
 // NewHandler returns a Handler by creating a Benthos pipeline.
-func NewHandler(conf config.Type) (*Handler, error) {
+func NewHandler(conf config.Type, opts ...func(*Handler)) (*Handler, error) {
 	// Logging and stats aggregation.
 	logger, err := log.NewV2(os.Stdout, conf.Logger)
 	if err != nil {
@@ -159,7 +240,7 @@ func NewHandler(conf config.Type) (*Handler, error) {
 		return nil, fmt.Errorf("failed to create resource: %v", err)
 	}
 
-	return &Handler{
+	h := &Handler{
 		transactionChan: transactionChan,
 		done: func(exitTimeout time.Duration) error {
 			close(transactionChan)
@@ -193,7 +274,11 @@ func NewHandler(conf config.Type) (*Handler, error) {
 			}
 			return nil
 		},
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
 }
 
 //------------------------------------------------------------------------------
@@ -2,6 +2,7 @@ package serverless
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -10,6 +11,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/config"
@@ -202,3 +206,131 @@ func TestHandlerCombined(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func testEventBatchHandler(t *testing.T, mapping string) *Handler {
+	t.Helper()
+
+	conf := config.New()
+	conf.Output.Type = "switch"
+	conf.Output.Switch.RetryUntilSuccess = false
+
+	errorCase := output.NewSwitchConfigCase()
+	errorCase.Check = "errored()"
+	errorCase.Output.Type = "reject"
+	errorCase.Output.Reject = "processing failed due to: ${! error() }"
+
+	responseCase := output.NewSwitchConfigCase()
+	responseCase.Output.Type = ServerlessResponseType
+
+	conf.Output.Switch.Cases = append(conf.Output.Switch.Cases, errorCase, responseCase)
+
+	pConf := processor.NewConfig()
+	pConf.Type = "bloblang"
+	pConf.Bloblang = mapping
+	conf.Pipeline.Processors = append(conf.Pipeline.Processors, pConf)
+
+	h, err := NewHandler(conf, OptSetEventBatching(true))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, h.Close(time.Second*10))
+	})
+	return h
+}
+
+func TestHandlerEventBatchPartialFailures(t *testing.T) {
+	h := testEventBatchHandler(t, `root = if content() == "fail" { throw("nope") } else { content().uppercase() }`)
+
+	var event any
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[
+  {"messageId":"a","body":"first","eventSource":"aws:sqs"},
+  {"messageId":"b","body":"fail","eventSource":"aws:sqs"},
+  {"messageId":"c","body":"third","eventSource":"aws:sqs"},
+  {"messageId":"d","body":"fail","eventSource":"aws:sqs"}
+]}`), &event))
+
+	res, err := h.Handle(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"batchItemFailures": []any{
+			map[string]any{"itemIdentifier": "b"},
+			map[string]any{"itemIdentifier": "d"},
+		},
+	}, res)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[
+  {"kinesis":{"sequenceNumber":"1","data":"Zmlyc3Q="},"eventSource":"aws:kinesis"},
+  {"kinesis":{"sequenceNumber":"2","data":"c2Vjb25k"},"eventSource":"aws:kinesis"}
+]}`), &event))
+
+	res, err = h.Handle(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"batchItemFailures": []any{}}, res)
+}
+
+func TestHandlerEventBatchFilteredAndSplit(t *testing.T) {
+	h := testEventBatchHandler(t, `root = match content().string() {
+  "drop" => deleted()
+  "fail" => throw("nope")
+  _ => content()
+}`)
+
+	var event any
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[
+  {"messageId":"a","body":"drop","eventSource":"aws:sqs"},
+  {"messageId":"b","body":"keep","eventSource":"aws:sqs"},
+  {"messageId":"c","body":"fail","eventSource":"aws:sqs"}
+]}`), &event))
+
+	res, err := h.Handle(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"batchItemFailures": []any{
+			map[string]any{"itemIdentifier": "c"},
+		},
+	}, res)
+}
+
+func TestHandlerEventBatchOutputFailure(t *testing.T) {
+	conf := config.New()
+	conf.Output.Type = "reject"
+	conf.Output.Reject = "nope"
+
+	h, err := NewHandler(conf, OptSetEventBatching(true))
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, h.Close(time.Second*10))
+	}()
+
+	var event any
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[
+  {"messageId":"a","body":"first","eventSource":"aws:sqs"},
+  {"messageId":"b","body":"second","eventSource":"aws:sqs"}
+]}`), &event))
+
+	res, err := h.Handle(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"batchItemFailures": []any{
+			map[string]any{"itemIdentifier": "a"},
+			map[string]any{"itemIdentifier": "b"},
+		},
+	}, res)
+}
+
+func TestHandlerEventBatchingDisabled(t *testing.T) {
+	conf := config.New()
+	conf.Output.Type = ServerlessResponseType
+
+	h, err := NewHandler(conf)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, h.Close(time.Second*10))
+	}()
+
+	event := map[string]any{"Records": []any{
+		map[string]any{"messageId": "a", "body": "first", "eventSource": "aws:sqs"},
+	}}
+	res, err := h.Handle(context.Background(), event)
+	require.NoError(t, err)
+	assert.Equal(t, event, res)
+}
@@ -3,6 +3,7 @@ package lambda
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
@@ -17,6 +18,9 @@ var handler *serverless.Handler
 
 // Run executes Benthos as an AWS Lambda function. Configuration can be stored
 // within the environment variable BENTHOS_CONFIG.
+//
+// The config is parsed and the pipeline is created once during the init phase
+// of an execution environment, and is reused by all invocations of it.
 func Run() {
 	// A list of default config paths to check for if not explicitly defined
 	defaultPaths := []string{
@@ -57,7 +61,13 @@ func Run() {
 		// Iterate default config paths
 		for _, path := range defaultPaths {
 			if _, err := os.Stat(path); err == nil {
-				if _, err = config.ReadFileLinted(path, config.LintOptions{}, &conf); err != nil {
+				// Linting the config is skipped as it adds to the duration of
+				// cold starts and lints are not reported.
+				confBytes, _, err := config.ReadFileEnvSwap(path)
+				if err == nil {
+					err = yaml.Unmarshal(confBytes, &conf)
+				}
+				if err != nil {
 					fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
 					os.Exit(1)
 				}
@@ -67,7 +77,8 @@ func Run() {
 	}
 
 	var err error
-	if handler, err = serverless.NewHandler(conf); err != nil {
+	batchEvents, _ := strconv.ParseBool(os.Getenv("BENTHOS_BATCH_EVENTS"))
+	if handler, err = serverless.NewHandler(conf, serverless.OptSetEventBatching(batchEvents)); err != nil {
 		fmt.Fprintf(os.Stderr, "Initialisation error: %v\n", err)
 		os.Exit(1)
 	}
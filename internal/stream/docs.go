@@ -1,7 +1,11 @@
 package stream
 
 import (
+	"github.com/benthosdev/benthos/v4/internal/autoscale"
 	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
+	"github.com/benthosdev/benthos/v4/internal/lineage"
+	"github.com/benthosdev/benthos/v4/internal/pipeline"
 )
 
 // Spec returns a docs.FieldSpec for a stream configuration.
@@ -12,6 +16,10 @@ func Spec() docs.FieldSpecs {
 		docs.FieldObject("pipeline", "Describes optional processing pipelines used for mutating messages.").WithChildren(
 			docs.FieldInt("threads", "The number of threads to execute processing pipelines across.").HasDefault(-1),
 			docs.FieldProcessor("processors", "A list of processors to apply to messages.").Array().HasDefault([]any{}),
+			inflight.FieldSpec().AtVersion("4.9.0"),
+			lineage.FieldSpec(),
+			pipeline.ParallelFieldSpec().AtVersion("4.9.0"),
+			autoscale.FieldSpec("Adjust the number of processing threads within the bounds `min` and `max`, starting from `threads`, based on how saturated the threads are and the latency of processing each batch. Time spent waiting for the output to accept processed messages does not count towards utilisation, and therefore a slow output does not cause threads to be added."),
 		),
 		docs.FieldOutput("output", "An output to sink messages to.").Optional(),
 	}
@@ -0,0 +1,196 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+// ShutdownConfig describes the deadlines of each phase of a graceful stream
+// shutdown. Phases without a deadline share whatever time remains of the
+// overall shutdown timeout.
+type ShutdownConfig struct {
+	StopConsuming string `json:"stop_consuming" yaml:"stop_consuming"`
+	DrainPipeline string `json:"drain_pipeline" yaml:"drain_pipeline"`
+	FlushOutputs  string `json:"flush_outputs" yaml:"flush_outputs"`
+}
+
+// NewShutdownConfig returns a ShutdownConfig with default values.
+func NewShutdownConfig() ShutdownConfig {
+	return ShutdownConfig{
+		StopConsuming: "",
+		DrainPipeline: "",
+		FlushOutputs:  "",
+	}
+}
+
+// IsNoop returns true if the config does not specify any phase deadlines.
+func (c ShutdownConfig) IsNoop() bool {
+	return c.StopConsuming == "" && c.DrainPipeline == "" && c.FlushOutputs == ""
+}
+
+// ShutdownFieldSpec returns a field spec for the shutdown phases of a stream.
+func ShutdownFieldSpec() docs.FieldSpec {
+	return docs.FieldObject("shutdown_phases", `
+Optional deadlines for each phase of a graceful shutdown. A shutdown begins by instructing inputs to stop consuming, then waits for buffers and processors to drain, and then waits for outputs to flush pending messages. If a phase exceeds its deadline the remaining phases are skipped and the stream is abandoned, where all components are closed immediately within what remains of `+"`shutdown_timeout`"+`.
+
+A structured log is emitted at the end of each shutdown reporting the phase reached, the time spent within each phase and, when `+"`pipeline.in_flight`"+` is configured, the number of messages and bytes that were still in flight when the stream was abandoned.`,
+	).WithChildren(
+		docs.FieldString("stop_consuming", "The maximum period of time to wait for inputs to stop consuming and close. When empty the phase shares the remaining shutdown timeout.", "5s").HasDefault(""),
+		docs.FieldString("drain_pipeline", "The maximum period of time to wait for buffers and processors to finish with pending messages. When empty the phase shares the remaining shutdown timeout.", "10s").HasDefault(""),
+		docs.FieldString("flush_outputs", "The maximum period of time to wait for outputs to flush pending messages and close. When empty the phase shares the remaining shutdown timeout.", "5s").HasDefault(""),
+	).Advanced().AtVersion("4.9.0").ChildDefaultAndTypesFromStruct(NewShutdownConfig())
+}
+
+// Phases of a stream shutdown, in the order they are performed.
+const (
+	ShutdownPhaseStopConsuming = "stop_consuming"
+	ShutdownPhaseDrainPipeline = "drain_pipeline"
+	ShutdownPhaseFlushOutputs  = "flush_outputs"
+	ShutdownPhaseAbandon       = "abandon"
+)
+
+// ShutdownReport summarises the outcome of a stream shutdown.
+type ShutdownReport struct {
+	// Phase is the last phase that was entered, which is
+	// ShutdownPhaseAbandon when the graceful phases failed to complete.
+	Phase string
+
+	// Graceful is true when all phases completed within their deadlines.
+	Graceful bool
+
+	// Durations contains the time spent within each phase that was entered.
+	Durations map[string]time.Duration
+
+	// AbandonedMessages and AbandonedBytes are the number of messages and
+	// bytes that were in flight when the stream was abandoned, and are only
+	// known when in flight accounting is enabled on the pipeline.
+	AbandonedMessages int64
+	AbandonedBytes    int64
+
+	// Err is the error that caused the graceful phases to fail, if any.
+	Err error
+}
+
+func (r *ShutdownReport) keyValues() []any {
+	kvs := []any{"phase", r.Phase, "graceful", r.Graceful}
+	for _, phase := range []string{
+		ShutdownPhaseStopConsuming,
+		ShutdownPhaseDrainPipeline,
+		ShutdownPhaseFlushOutputs,
+		ShutdownPhaseAbandon,
+	} {
+		if d, exists := r.Durations[phase]; exists {
+			kvs = append(kvs, phase+"_duration", d.String())
+		}
+	}
+	if r.AbandonedMessages > 0 || r.AbandonedBytes > 0 {
+		kvs = append(kvs, "abandoned_messages", r.AbandonedMessages, "abandoned_bytes", r.AbandonedBytes)
+	}
+	return kvs
+}
+
+type shutdownDeadlines struct {
+	stopConsuming time.Duration
+	drainPipeline time.Duration
+	flushOutputs  time.Duration
+}
+
+func (c ShutdownConfig) deadlines() (d shutdownDeadlines, err error) {
+	for _, f := range []struct {
+		name string
+		str  string
+		dur  *time.Duration
+	}{
+		{name: ShutdownPhaseStopConsuming, str: c.StopConsuming, dur: &d.stopConsuming},
+		{name: ShutdownPhaseDrainPipeline, str: c.DrainPipeline, dur: &d.drainPipeline},
+		{name: ShutdownPhaseFlushOutputs, str: c.FlushOutputs, dur: &d.flushOutputs},
+	} {
+		if f.str == "" {
+			continue
+		}
+		if *f.dur, err = time.ParseDuration(f.str); err != nil {
+			return d, fmt.Errorf("failed to parse shutdown phase %v duration: %w", f.name, err)
+		}
+	}
+	return
+}
+
+func phaseContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// StopPhased attempts to close the stream gracefully, where each phase of the
+// shutdown is given its own deadline. If any phase fails to complete in time
+// the stream is abandoned by closing all remaining components within the
+// provided context. A report of the shutdown is returned along with an error
+// if the stream failed to close at all.
+func (t *Type) StopPhased(ctx context.Context) (ShutdownReport, error) {
+	report := ShutdownReport{
+		Durations: map[string]time.Duration{},
+	}
+
+	phase := func(name string, d time.Duration, fn func(ctx context.Context) error) error {
+		report.Phase = name
+		pCtx, done := phaseContext(ctx, d)
+		defer done()
+
+		started := time.Now()
+		err := fn(pCtx)
+		report.Durations[name] = time.Since(started)
+		return err
+	}
+
+	err := phase(ShutdownPhaseStopConsuming, t.deadlines.stopConsuming, func(ctx context.Context) error {
+		t.inputLayer.TriggerStopConsuming()
+		if err := t.inputLayer.WaitForClose(ctx); err != nil {
+			return err
+		}
+		if t.lineageLayer != nil {
+			return t.lineageLayer.WaitForClose(ctx)
+		}
+		return nil
+	})
+	if err == nil {
+		err = phase(ShutdownPhaseDrainPipeline, t.deadlines.drainPipeline, func(ctx context.Context) error {
+			if t.bufferLayer != nil {
+				t.bufferLayer.TriggerStopConsuming()
+				if err := t.bufferLayer.WaitForClose(ctx); err != nil {
+					return err
+				}
+			}
+			if t.inFlightLayer != nil {
+				if err := t.inFlightLayer.WaitForClose(ctx); err != nil {
+					return err
+				}
+			}
+			if t.pipelineLayer != nil {
+				return t.pipelineLayer.WaitForClose(ctx)
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = phase(ShutdownPhaseFlushOutputs, t.deadlines.flushOutputs, t.outputLayer.WaitForClose)
+	}
+	if err == nil {
+		report.Graceful = true
+		t.manager.Logger().With(report.keyValues()...).Infoln("Stream shut down gracefully")
+		return report, nil
+	}
+
+	report.Err = err
+	if t.inFlightLayer != nil {
+		report.AbandonedMessages, report.AbandonedBytes = t.inFlightLayer.InFlight()
+	}
+
+	failedPhase := report.Phase
+	err = phase(ShutdownPhaseAbandon, 0, t.StopUnordered)
+	t.manager.Logger().With(report.keyValues()...).Warnf("Stream shutdown phase %v failed to complete, stream was abandoned: %v\n", failedPhase, report.Err)
+	return report, err
+}
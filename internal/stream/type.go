@@ -14,6 +14,8 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/inflight"
+	"github.com/benthosdev/benthos/v4/internal/lineage"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/pipeline"
 )
@@ -23,11 +25,17 @@ type Type struct {
 	conf Config
 
 	inputLayer    input.Streamed
+	lineageLayer  *lineage.Layer
 	bufferLayer   buffer.Streamed
+	inFlightLayer *inflight.Gate
 	pipelineLayer processor.Pipeline
 	outputLayer   output.Streamed
 
-	manager bundle.NewManagement
+	manager  bundle.NewManagement
+	streamID string
+
+	shutdownConf ShutdownConfig
+	deadlines    shutdownDeadlines
 
 	onClose func()
 	closed  uint32
@@ -44,7 +52,11 @@ func New(conf Config, mgr bundle.NewManagement, opts ...func(*Type)) (*Type, err
 	for _, opt := range opts {
 		opt(t)
 	}
-	if err := t.start(); err != nil {
+	var err error
+	if t.deadlines, err = t.shutdownConf.deadlines(); err != nil {
+		return nil, err
+	}
+	if err = t.start(); err != nil {
 		return nil, err
 	}
 
@@ -87,8 +99,38 @@ func OptOnClose(onClose func()) func(*Type) {
 	}
 }
 
+// OptSetShutdownPhases sets the deadlines of each phase of a graceful shutdown,
+// which are then used by Stop in place of its default behaviour.
+func OptSetShutdownPhases(conf ShutdownConfig) func(*Type) {
+	return func(t *Type) {
+		t.shutdownConf = conf
+	}
+}
+
+// OptSetStreamID sets an identifier of the stream, which is added as the label
+// `stream` to profiles of its components.
+func OptSetStreamID(id string) func(*Type) {
+	return func(t *Type) {
+		t.streamID = id
+	}
+}
+
 //------------------------------------------------------------------------------
 
+// withProfilerLabels executes a closure with profiler labels identifying the
+// stream and one of its components, which are inherited by any goroutines
+// started by the closure.
+func (t *Type) withProfilerLabels(component string, fn func() error) (err error) {
+	labels := []string{"component", component}
+	if t.streamID != "" {
+		labels = append(labels, "stream", t.streamID)
+	}
+	pprof.Do(context.Background(), pprof.Labels(labels...), func(context.Context) {
+		err = fn()
+	})
+	return
+}
+
 // IsReady returns a boolean indicating whether both the input and output layers
 // of the stream are connected.
 func (t *Type) IsReady() bool {
@@ -96,25 +138,50 @@ func (t *Type) IsReady() bool {
 }
 
 func (t *Type) start() (err error) {
-	// Constructors
-	iMgr := t.manager.IntoPath("input")
-	if t.inputLayer, err = iMgr.NewInput(t.conf.Input); err != nil {
+	// Components are constructed and begin consuming with profiler labels, so
+	// that goroutines started by each layer are attributed to it.
+	if err = t.withProfilerLabels("input", func() (err error) {
+		iMgr := t.manager.IntoPath("input")
+		if t.inputLayer, err = iMgr.NewInput(t.conf.Input); err != nil {
+			return
+		}
+		if t.conf.Pipeline.Lineage.Enabled {
+			source := t.conf.Input.Label
+			if source == "" {
+				source = "input"
+			}
+			t.lineageLayer = lineage.NewLayer(source)
+		}
+		return
+	}); err != nil {
 		return
 	}
 	if t.conf.Buffer.Type != "none" {
-		bMgr := t.manager.IntoPath("buffer")
-		if t.bufferLayer, err = bMgr.NewBuffer(t.conf.Buffer); err != nil {
+		if err = t.withProfilerLabels("buffer", func() (err error) {
+			bMgr := t.manager.IntoPath("buffer")
+			t.bufferLayer, err = bMgr.NewBuffer(t.conf.Buffer)
+			return
+		}); err != nil {
 			return
 		}
 	}
-	if tLen := len(t.conf.Pipeline.Processors); tLen > 0 {
-		pMgr := t.manager.IntoPath("pipeline")
-		if t.pipelineLayer, err = pipeline.New(t.conf.Pipeline, pMgr); err != nil {
-			return
+	if err = t.withProfilerLabels("pipeline", func() (err error) {
+		if !t.conf.Pipeline.InFlight.IsNoop() {
+			t.inFlightLayer = inflight.NewGate(t.conf.Pipeline.InFlight, t.manager.IntoPath("pipeline", "in_flight").Metrics())
+		}
+		if tLen := len(t.conf.Pipeline.Processors); tLen > 0 {
+			pMgr := t.manager.IntoPath("pipeline")
+			t.pipelineLayer, err = pipeline.New(t.conf.Pipeline, pMgr)
 		}
+		return
+	}); err != nil {
+		return
 	}
-	oMgr := t.manager.IntoPath("output")
-	if t.outputLayer, err = oMgr.NewOutput(t.conf.Output); err != nil {
+	if err = t.withProfilerLabels("output", func() (err error) {
+		oMgr := t.manager.IntoPath("output")
+		t.outputLayer, err = oMgr.NewOutput(t.conf.Output)
+		return
+	}); err != nil {
 		return
 	}
 
@@ -122,19 +189,41 @@ func (t *Type) start() (err error) {
 	var nextTranChan <-chan message.Transaction
 
 	nextTranChan = t.inputLayer.TransactionChan()
+	if t.lineageLayer != nil {
+		if err = t.withProfilerLabels("input", func() error {
+			return t.lineageLayer.Consume(nextTranChan)
+		}); err != nil {
+			return
+		}
+		nextTranChan = t.lineageLayer.TransactionChan()
+	}
 	if t.bufferLayer != nil {
-		if err = t.bufferLayer.Consume(nextTranChan); err != nil {
+		if err = t.withProfilerLabels("buffer", func() error {
+			return t.bufferLayer.Consume(nextTranChan)
+		}); err != nil {
 			return
 		}
 		nextTranChan = t.bufferLayer.TransactionChan()
 	}
+	if t.inFlightLayer != nil {
+		if err = t.withProfilerLabels("pipeline", func() error {
+			return t.inFlightLayer.Consume(nextTranChan)
+		}); err != nil {
+			return
+		}
+		nextTranChan = t.inFlightLayer.TransactionChan()
+	}
 	if t.pipelineLayer != nil {
-		if err = t.pipelineLayer.Consume(nextTranChan); err != nil {
+		if err = t.withProfilerLabels("pipeline", func() error {
+			return t.pipelineLayer.Consume(nextTranChan)
+		}); err != nil {
 			return
 		}
 		nextTranChan = t.pipelineLayer.TransactionChan()
 	}
-	if err = t.outputLayer.Consume(nextTranChan); err != nil {
+	if err = t.withProfilerLabels("output", func() error {
+		return t.outputLayer.Consume(nextTranChan)
+	}); err != nil {
 		return
 	}
 
@@ -160,6 +249,11 @@ func (t *Type) StopGracefully(ctx context.Context) (err error) {
 	if err = t.inputLayer.WaitForClose(ctx); err != nil {
 		return
 	}
+	if t.lineageLayer != nil {
+		if err = t.lineageLayer.WaitForClose(ctx); err != nil {
+			return
+		}
+	}
 
 	// If we have a buffer then wait right here. We want to try and allow the
 	// buffer to empty out before prompting the other layers to shut down.
@@ -171,6 +265,12 @@ func (t *Type) StopGracefully(ctx context.Context) (err error) {
 	}
 
 	// After this point we can start closing the remaining components.
+	if t.inFlightLayer != nil {
+		if err = t.inFlightLayer.WaitForClose(ctx); err != nil {
+			return
+		}
+	}
+
 	if t.pipelineLayer != nil {
 		if err = t.pipelineLayer.WaitForClose(ctx); err != nil {
 			return
@@ -188,9 +288,15 @@ func (t *Type) StopGracefully(ctx context.Context) (err error) {
 // should only be attempted if both stopGracefully and stopOrdered failed.
 func (t *Type) StopUnordered(ctx context.Context) (err error) {
 	t.inputLayer.TriggerCloseNow()
+	if t.lineageLayer != nil {
+		t.lineageLayer.TriggerCloseNow()
+	}
 	if t.bufferLayer != nil {
 		t.bufferLayer.TriggerCloseNow()
 	}
+	if t.inFlightLayer != nil {
+		t.inFlightLayer.TriggerCloseNow()
+	}
 	if t.pipelineLayer != nil {
 		t.pipelineLayer.TriggerCloseNow()
 	}
@@ -200,12 +306,24 @@ func (t *Type) StopUnordered(ctx context.Context) (err error) {
 		return
 	}
 
+	if t.lineageLayer != nil {
+		if err = t.lineageLayer.WaitForClose(ctx); err != nil {
+			return
+		}
+	}
+
 	if t.bufferLayer != nil {
 		if err = t.bufferLayer.WaitForClose(ctx); err != nil {
 			return
 		}
 	}
 
+	if t.inFlightLayer != nil {
+		if err = t.inFlightLayer.WaitForClose(ctx); err != nil {
+			return
+		}
+	}
+
 	if t.pipelineLayer != nil {
 		if err = t.pipelineLayer.WaitForClose(ctx); err != nil {
 			return
@@ -224,7 +342,15 @@ func (t *Type) StopUnordered(ctx context.Context) (err error) {
 //
 // If the context is cancelled an error is returned _after_ asynchronously
 // instructing the remaining stream components to terminate ungracefully.
+//
+// When shutdown phases have been configured the stream is instead closed with
+// StopPhased.
 func (t *Type) Stop(ctx context.Context) error {
+	if !t.shutdownConf.IsNoop() {
+		_, err := t.StopPhased(ctx)
+		return err
+	}
+
 	ctxCloseGraceful := ctx
 
 	// If the provided context has a known deadline then we calculate a period
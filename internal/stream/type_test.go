@@ -1,10 +1,12 @@
 package stream_test
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"runtime/pprof"
 	"testing"
 	"time"
 
@@ -46,6 +48,31 @@ func TestTypeConstruction(t *testing.T) {
 	require.NoError(t, strm.Stop(ctx))
 }
 
+func TestTypeProfilerLabels(t *testing.T) {
+	conf := stream.NewConfig()
+	conf.Input.Type = "generate"
+	conf.Input.Generate.Mapping = "root = {}"
+	conf.Buffer.Type = "memory"
+	conf.Output.Type = "drop"
+
+	newMgr, err := manager.New(manager.NewResourceConfig())
+	require.NoError(t, err)
+
+	strm, err := stream.New(conf, newMgr, stream.OptSetStreamID("label_test_stream"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+	for _, component := range []string{"input", "buffer", "output"} {
+		assert.Contains(t, buf.String(), `"component":"`+component+`"`)
+	}
+	assert.Contains(t, buf.String(), `"stream":"label_test_stream"`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+	require.NoError(t, strm.Stop(ctx))
+}
+
 func TestStreamCloseUngraceful(t *testing.T) {
 	t.Parallel()
 
@@ -142,6 +169,75 @@ func TestTypeCloseUnordered(t *testing.T) {
 	assert.NoError(t, strm.StopUnordered(ctx))
 }
 
+func TestTypeClosePhased(t *testing.T) {
+	conf := stream.NewConfig()
+	conf.Input.Type = "generate"
+	conf.Input.Generate.Mapping = "root = {}"
+	conf.Buffer.Type = "memory"
+	conf.Output.Type = "drop"
+
+	newMgr, err := manager.New(manager.NewResourceConfig())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	shutConf := stream.NewShutdownConfig()
+	shutConf.StopConsuming = "10s"
+	shutConf.FlushOutputs = "10s"
+
+	strm, err := stream.New(conf, newMgr, stream.OptSetShutdownPhases(shutConf))
+	require.NoError(t, err)
+
+	report, err := strm.StopPhased(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Graceful)
+	assert.Equal(t, stream.ShutdownPhaseFlushOutputs, report.Phase)
+	assert.Len(t, report.Durations, 3)
+
+	shutConf.DrainPipeline = "nope"
+	_, err = stream.New(conf, newMgr, stream.OptSetShutdownPhases(shutConf))
+	require.Error(t, err)
+}
+
+func TestTypeClosePhasedAbandon(t *testing.T) {
+	conf := stream.NewConfig()
+	conf.Input.Type = "generate"
+	conf.Input.Generate.Mapping = `root = "hello world"`
+	conf.Input.Generate.Interval = ""
+	conf.Pipeline.InFlight.HighWatermarkMessages = 10
+	conf.Output.Type = "inproc"
+	conf.Output.Inproc = "foo"
+
+	newMgr, err := manager.New(manager.NewResourceConfig())
+	require.NoError(t, err)
+
+	shutConf := stream.NewShutdownConfig()
+	shutConf.StopConsuming = "100ms"
+
+	strm, err := stream.New(conf, newMgr, stream.OptSetShutdownPhases(shutConf))
+	require.NoError(t, err)
+
+	tChan, err := newMgr.GetPipe("foo")
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	select {
+	case <-tChan:
+	case <-ctx.Done():
+		t.Fatal(ctx.Err())
+	}
+
+	report, _ := strm.StopPhased(ctx)
+	assert.False(t, report.Graceful)
+	assert.Equal(t, stream.ShutdownPhaseAbandon, report.Phase)
+	assert.Error(t, report.Err)
+	assert.Equal(t, int64(1), report.AbandonedMessages)
+	assert.Equal(t, int64(len("hello world")), report.AbandonedBytes)
+}
+
 type mockAPIReg struct {
 	server *httptest.Server
 }
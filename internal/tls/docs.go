@@ -24,11 +24,11 @@ func FieldSpec() docs.FieldSpec {
 		).HasDefault(""),
 
 		docs.FieldString(
-			"root_cas_file", "An optional path of a root certificate authority file to use. This is a file, often with a .pem extension, containing a certificate chain from the parent trusted root certificate, to possible intermediate signing certificates, to the host certificate.", "./root_cas.pem",
+			"root_cas_file", "An optional path of a root certificate authority file to use. This is a file, often with a .pem extension, containing a certificate chain from the parent trusted root certificate, to possible intermediate signing certificates, to the host certificate. The file is reloaded when it's modified.", "./root_cas.pem",
 		).HasDefault(""),
 
 		docs.FieldObject(
-			"client_certs", "A list of client certificates to use. For each certificate either the fields `cert` and `key`, or `cert_file` and `key_file` should be specified, but not both.",
+			"client_certs", "A list of client certificates to use. For each certificate either the fields `cert` and `key`, or `cert_file` and `key_file` should be specified, but not both. Certificates loaded from files are reloaded when either file is modified.",
 			[]any{
 				map[string]any{
 					"cert": "foo",
@@ -48,5 +48,21 @@ func FieldSpec() docs.FieldSpec {
 			docs.FieldString("key_file", "The path of a certificate key to use.").HasDefault(""),
 			docs.FieldString("password", "A plain text password for when the private key is a password encrypted PEM block according to RFC 1423. Warning: Since it does not authenticate the ciphertext, it is vulnerable to padding oracle attacks that can let an attacker recover the plaintext.", "foo", "${KEY_PASSWORD}").HasDefault(""),
 		),
+
+		docs.FieldString(
+			"min_version", "The minimum TLS version to accept, one of `1.0`, `1.1`, `1.2` or `1.3`. When empty the minimum version is `1.2`.", "1.3",
+		).AtVersion("4.9.0").Advanced().HasDefault(""),
+
+		docs.FieldString(
+			"cipher_suites", "A list of cipher suites to enable, by their names as listed by [the Go crypto/tls package](https://pkg.go.dev/crypto/tls#pkg-constants). When empty a default list of secure cipher suites is used. Cipher suites are not configurable with TLS 1.3, and therefore this list only applies to connections of earlier versions.", []string{"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+		).Array().AtVersion("4.9.0").Advanced().HasDefault([]any{}),
+
+		docs.FieldObject(
+			"spiffe", "Obtains the certificate of the workload and the trust bundle used to verify servers from a [SPIFFE Workload API](https://spiffe.io/docs/latest/spiffe-about/spiffe-concepts/#spiffe-workload-api), such as the agent of [SPIRE](https://spiffe.io/docs/latest/spire-about/). The certificate is rotated whenever a new X509-SVID is issued. Servers are verified by their SPIFFE ID rather than their hostname, and therefore the fields `root_cas`, `root_cas_file` and `client_certs` cannot be specified when enabled.",
+		).WithChildren(
+			docs.FieldBool("enabled", "Whether to obtain certificates from the Workload API.").HasDefault(false),
+			docs.FieldString("socket_path", "The address of the Workload API, either the path of a unix socket or a `tcp://` address. When empty the environment variable `SPIFFE_ENDPOINT_SOCKET` is used.", "/run/spire/sockets/agent.sock", "unix:///tmp/agent.sock").HasDefault(""),
+			docs.FieldString("allowed_ids", "A list of SPIFFE IDs of servers to accept. When empty any server with a certificate issued by the trust bundle is accepted.", []string{"spiffe://example.org/service/db"}).Array().HasDefault([]any{}),
+		).AtVersion("4.9.0").Advanced(),
 	).Advanced()
 }
@@ -0,0 +1,179 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertCheckPeriod is the minimum period between checks of whether reloaded
+// files have been modified.
+const CertCheckPeriod = time.Second
+
+// fileReloader reads a set of files whenever any of them are modified, which is
+// checked at most once per CertCheckPeriod.
+type fileReloader struct {
+	paths []string
+	load  func(contents [][]byte) error
+
+	mut       sync.Mutex
+	mods      []time.Time
+	loaded    bool
+	checkedAt time.Time
+}
+
+func newFileReloader(load func(contents [][]byte) error, paths ...string) (*fileReloader, error) {
+	r := &fileReloader{paths: paths, load: load}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *fileReloader) reload() error {
+	mods := make([]time.Time, len(r.paths))
+	for i, p := range r.paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		mods[i] = info.ModTime()
+	}
+	if r.loaded {
+		modified := false
+		for i, m := range mods {
+			if !m.Equal(r.mods[i]) {
+				modified = true
+			}
+		}
+		if !modified {
+			return nil
+		}
+	}
+
+	contents := make([][]byte, len(r.paths))
+	for i, p := range r.paths {
+		var err error
+		if contents[i], err = os.ReadFile(p); err != nil {
+			return err
+		}
+	}
+	if err := r.load(contents); err != nil {
+		return err
+	}
+	r.mods, r.loaded = mods, true
+	return nil
+}
+
+// check reloads the files if they have been modified since they were last
+// loaded. Files that cannot be reloaded are ignored in favour of the contents
+// that were previously loaded.
+func (r *fileReloader) check() {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if time.Since(r.checkedAt) >= CertCheckPeriod {
+		r.checkedAt = time.Now()
+		_ = r.reload()
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// CertReloader provides a certificate from a pair of files, reloading it
+// whenever either file is modified so that certificates can be rotated without
+// a restart.
+type CertReloader struct {
+	files *fileReloader
+
+	mut  sync.Mutex
+	cert *tls.Certificate
+}
+
+// NewCertReloader loads a certificate from a pair of files, where the key is
+// decrypted with a password when it's encrypted.
+func NewCertReloader(certFile, keyFile, password string) (*CertReloader, error) {
+	r := &CertReloader{}
+	files, err := newFileReloader(func(contents [][]byte) error {
+		cert, err := loadKeyPair(contents[0], contents[1], password)
+		if err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+		r.mut.Lock()
+		r.cert = &cert
+		r.mut.Unlock()
+		return nil
+	}, certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	r.files = files
+	return r, nil
+}
+
+// Certificate returns the current certificate. When the certificate files
+// cannot be reloaded the previous certificate continues to be provided.
+func (r *CertReloader) Certificate() *tls.Certificate {
+	r.files.check()
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.cert
+}
+
+// GetCertificate implements the GetCertificate func of a tls.Config.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// ServerConfig returns a tls.Config for servers that provides the current
+// certificate to each handshake, and therefore picks up modifications to the
+// certificate files without restarting the server.
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{GetCertificate: r.GetCertificate}
+}
+
+//------------------------------------------------------------------------------
+
+// poolReloader provides a pool of root certificate authorities from a file,
+// reloading it whenever the file is modified.
+type poolReloader struct {
+	files *fileReloader
+
+	mut  sync.Mutex
+	pool *x509.CertPool
+}
+
+func newPoolReloader(path string) (*poolReloader, error) {
+	r := &poolReloader{}
+	files, err := newFileReloader(func(contents [][]byte) error {
+		pool := x509.NewCertPool()
+		parsed := pool.AppendCertsFromPEM(contents[0])
+
+		r.mut.Lock()
+		defer r.mut.Unlock()
+		if !parsed && r.pool != nil {
+			// A file that is being rewritten is ignored until it's valid.
+			return errors.New("failed to parse any certificates from root_cas_file")
+		}
+		r.pool = pool
+		return nil
+	}, path)
+	if err != nil {
+		return nil, err
+	}
+	r.files = files
+	return r, nil
+}
+
+func (r *poolReloader) current() *x509.CertPool {
+	r.files.check()
+
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.pool
+}
@@ -0,0 +1,231 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func touchTestFiles(t *testing.T, paths ...string) {
+	t.Helper()
+
+	later := time.Now().Add(time.Minute)
+	for _, p := range paths {
+		require.NoError(t, os.Chtimes(p, later, later))
+	}
+}
+
+func certCommonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	r, err := NewCertReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", certCommonName(t, cert))
+
+	writeTestCert(t, certFile, keyFile, "second")
+	touchTestFiles(t, certFile, keyFile)
+
+	// A modified certificate is picked up once the check period has passed.
+	r.files.checkedAt = time.Time{}
+	cert, err = r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", certCommonName(t, cert))
+
+	// An invalid certificate is ignored in favour of the previous one.
+	require.NoError(t, os.WriteFile(certFile, []byte("nope"), 0o600))
+	r.files.checkedAt = time.Time{}
+	cert, err = r.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "second", certCommonName(t, cert))
+
+	_, err = NewCertReloader(filepath.Join(dir, "nope.pem"), keyFile, "")
+	require.Error(t, err)
+}
+
+func TestPoolReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	r, err := newPoolReloader(certFile)
+	require.NoError(t, err)
+	first := r.current()
+
+	writeTestCert(t, certFile, keyFile, "second")
+	touchTestFiles(t, certFile)
+	r.files.checkedAt = time.Time{}
+	second := r.current()
+	assert.NotSame(t, first, second)
+
+	// A file without certificates is ignored once a pool has been loaded.
+	require.NoError(t, os.WriteFile(certFile, []byte("nope"), 0o600))
+	touchTestFiles(t, certFile)
+	r.files.checkedAt = time.Time{}
+	assert.Same(t, second, r.current())
+}
+
+func TestConfigClientCertReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	conf := NewConfig()
+	conf.ClientCertificates = []ClientCertConfig{{CertFile: certFile, KeyFile: keyFile}}
+
+	tlsConf, err := conf.GetNonToggled()
+	require.NoError(t, err)
+	require.Len(t, tlsConf.Certificates, 1)
+	require.NotNil(t, tlsConf.GetClientCertificate)
+
+	cri := &tls.CertificateRequestInfo{
+		Version:          tls.VersionTLS13,
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+	}
+	cert, err := tlsConf.GetClientCertificate(cri)
+	require.NoError(t, err)
+	assert.Equal(t, "first", certCommonName(t, cert))
+
+	writeTestCert(t, certFile, keyFile, "second")
+	touchTestFiles(t, certFile, keyFile)
+	time.Sleep(CertCheckPeriod)
+
+	cert, err = tlsConf.GetClientCertificate(cri)
+	require.NoError(t, err)
+	assert.Equal(t, "second", certCommonName(t, cert))
+
+	cert, err = tlsConf.GetCertificate(&tls.ClientHelloInfo{SupportedVersions: []uint16{tls.VersionTLS13}})
+	require.NoError(t, err)
+	assert.Equal(t, "second", certCommonName(t, cert))
+}
+
+func TestConfigRootCAsFileReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile, "first")
+
+	server, err := NewCertReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+	// Keep the initial certificate of the server.
+	server.files.checkedAt = time.Now().Add(time.Hour)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server.ServerConfig())
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	caDir := t.TempDir()
+	caFile := filepath.Join(caDir, "ca.pem")
+	writeTestCert(t, caFile, filepath.Join(caDir, "key.pem"), "other")
+
+	conf := NewConfig()
+	conf.RootCAsFile = caFile
+	tlsConf, err := conf.GetNonToggled()
+	require.NoError(t, err)
+	tlsConf.ServerName = "localhost"
+
+	dial := func() error {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), tlsConf)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	require.Error(t, dial())
+
+	// The certificate of the server is trusted once the roots are reloaded.
+	certPem, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(caFile, certPem, 0o600))
+	touchTestFiles(t, caFile)
+	time.Sleep(CertCheckPeriod)
+
+	require.NoError(t, dial())
+
+	tlsConf.ServerName = "nope"
+	require.Error(t, dial())
+}
+
+func TestConfigVersionAndCipherSuites(t *testing.T) {
+	conf := NewConfig()
+	tlsConf, err := conf.GetNonToggled()
+	require.NoError(t, err)
+	assert.Nil(t, tlsConf)
+
+	conf.MinVersion = "1.3"
+	conf.CipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA"}
+	tlsConf, err = conf.GetNonToggled()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConf.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_RC4_128_SHA}, tlsConf.CipherSuites)
+
+	conf.MinVersion = "1.4"
+	_, err = conf.GetNonToggled()
+	require.EqualError(t, err, "min_version 1.4 is not recognised, expected one of 1.0, 1.1, 1.2 or 1.3")
+
+	conf.MinVersion = ""
+	conf.CipherSuites = []string{"nope"}
+	_, err = conf.GetNonToggled()
+	require.EqualError(t, err, "cipher suite nope is not recognised")
+}
@@ -0,0 +1,312 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SPIFFEConfig contains config fields for obtaining certificates from the
+// SPIFFE Workload API.
+type SPIFFEConfig struct {
+	Enabled    bool     `json:"enabled" yaml:"enabled"`
+	SocketPath string   `json:"socket_path" yaml:"socket_path"`
+	AllowedIDs []string `json:"allowed_ids" yaml:"allowed_ids"`
+}
+
+// NewSPIFFEConfig creates a new SPIFFEConfig with default values.
+func NewSPIFFEConfig() SPIFFEConfig {
+	return SPIFFEConfig{
+		Enabled:    false,
+		SocketPath: "",
+		AllowedIDs: []string{},
+	}
+}
+
+const (
+	spiffeSocketEnv     = "SPIFFE_ENDPOINT_SOCKET"
+	spiffeFetchX509SVID = "/SpiffeWorkloadAPI/FetchX509SVID"
+
+	// The maximum time to wait for the first SVID of a source when verifying
+	// a connection, as verification does not provide a context.
+	spiffeWaitTimeout = time.Second * 10
+)
+
+// spiffeSVID is an X509-SVID along with the bundle of its trust domain.
+type spiffeSVID struct {
+	id     string
+	cert   tls.Certificate
+	bundle *x509.CertPool
+}
+
+// spiffeSource maintains the latest X509-SVID of the workload from a stream of
+// the Workload API.
+type spiffeSource struct {
+	target string
+
+	mut   sync.Mutex
+	svid  *spiffeSVID
+	err   error
+	ready chan struct{}
+}
+
+var (
+	spiffeSourcesMut sync.Mutex
+	spiffeSources    = map[string]*spiffeSource{}
+)
+
+// getSPIFFESource returns the source of an endpoint socket, which is shared by
+// all TLS configs of the process. A tls.Config doesn't have a lifecycle, and
+// therefore sources are kept updated for the lifetime of the process.
+func getSPIFFESource(socket string) (*spiffeSource, error) {
+	if socket == "" {
+		if socket = os.Getenv(spiffeSocketEnv); socket == "" {
+			return nil, fmt.Errorf("a spiffe socket_path must be specified when the environment variable %v is not set", spiffeSocketEnv)
+		}
+	}
+	target, err := spiffeTarget(socket)
+	if err != nil {
+		return nil, err
+	}
+
+	spiffeSourcesMut.Lock()
+	defer spiffeSourcesMut.Unlock()
+
+	s, exists := spiffeSources[target]
+	if !exists {
+		s = &spiffeSource{target: target, ready: make(chan struct{})}
+		spiffeSources[target] = s
+		go s.loop()
+	}
+	return s, nil
+}
+
+// spiffeTarget returns the gRPC target of an endpoint socket address, which is
+// either a unix socket or a tcp address.
+func spiffeTarget(socket string) (string, error) {
+	if !strings.Contains(socket, "://") && !strings.HasPrefix(socket, "unix:") {
+		return "unix://" + socket, nil
+	}
+	u, err := url.Parse(socket)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse spiffe socket: %w", err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		return "unix://" + path, nil
+	case "tcp":
+		return u.Host, nil
+	}
+	return "", fmt.Errorf("spiffe socket scheme %v is not supported", u.Scheme)
+}
+
+func (s *spiffeSource) loop() {
+	backOff := time.Second
+	for {
+		received, err := s.watch()
+		if received {
+			backOff = time.Second
+		}
+
+		s.mut.Lock()
+		s.err = err
+		s.mut.Unlock()
+
+		time.Sleep(backOff)
+		if backOff *= 2; backOff > time.Second*30 {
+			backOff = time.Second * 30
+		}
+	}
+}
+
+// watch streams SVIDs until the stream fails, and returns whether any were
+// received.
+func (s *spiffeSource) watch() (received bool, err error) {
+	conn, err := grpc.Dial(s.target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+	ctx = metadata.AppendToOutgoingContext(ctx, "workload.spiffe.io", "true")
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, spiffeFetchX509SVID, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return false, err
+	}
+	req := []byte{}
+	if err := stream.SendMsg(&req); err != nil {
+		return false, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return false, err
+	}
+
+	for {
+		var res []byte
+		if err := stream.RecvMsg(&res); err != nil {
+			return received, err
+		}
+		svid, err := parseSPIFFEResponse(res)
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		s.mut.Lock()
+		first := s.svid == nil
+		s.svid, s.err = svid, nil
+		s.mut.Unlock()
+		if first {
+			close(s.ready)
+		}
+	}
+}
+
+// current returns the latest SVID, waiting for the first one to be received.
+func (s *spiffeSource) current(ctx context.Context) (*spiffeSVID, error) {
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		s.mut.Lock()
+		err := s.err
+		s.mut.Unlock()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to obtain spiffe svid: %w", err)
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.svid, nil
+}
+
+// parseSPIFFEResponse parses the first SVID of an X509SVIDResponse message.
+func parseSPIFFEResponse(b []byte) (*spiffeSVID, error) {
+	var svid []byte
+	for len(b) > 0 && svid == nil {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num == 1 && typ == protowire.BytesType {
+			if svid, n = protowire.ConsumeBytes(b); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+		} else if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	if svid == nil {
+		return nil, errors.New("workload api response contains no svids")
+	}
+
+	var id string
+	var certsDER, keyDER, bundleDER []byte
+	for len(svid) > 0 {
+		num, typ, n := protowire.ConsumeTag(svid)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		svid = svid[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, svid); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			svid = svid[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(svid)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		svid = svid[n:]
+		switch num {
+		case 1:
+			id = string(v)
+		case 2:
+			certsDER = v
+		case 3:
+			keyDER = v
+		case 4:
+			bundleDER = v
+		}
+	}
+
+	certs, err := x509.ParseCertificates(certsDER)
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("failed to parse svid certificates: %v", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse svid key: %w", err)
+	}
+	bundle, err := x509.ParseCertificates(bundleDER)
+	if err != nil || len(bundle) == 0 {
+		return nil, fmt.Errorf("failed to parse svid bundle: %v", err)
+	}
+
+	s := &spiffeSVID{id: id, bundle: x509.NewCertPool()}
+	s.cert.PrivateKey, s.cert.Leaf = key, certs[0]
+	for _, c := range certs {
+		s.cert.Certificate = append(s.cert.Certificate, c.Raw)
+	}
+	for _, c := range bundle {
+		s.bundle.AddCert(c)
+	}
+	return s, nil
+}
+
+// spiffeID returns the SPIFFE ID of a certificate, which is its only URI SAN.
+func spiffeID(cert *x509.Certificate) (string, error) {
+	if len(cert.URIs) != 1 || cert.URIs[0].Scheme != "spiffe" {
+		return "", errors.New("certificate does not contain a single spiffe id")
+	}
+	return cert.URIs[0].String(), nil
+}
+
+// rawCodec passes the messages of gRPC streams as raw bytes, which allows
+// the Workload API to be used without generated protobuf code.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected message type: %T", v)
+	}
+	return *b, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type: %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,239 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type testSPIFFECA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestSPIFFECA(t *testing.T) *testSPIFFECA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example.org"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testSPIFFECA{cert: cert, key: key}
+}
+
+// svid issues an X509-SVID, returning its certificate and PKCS8 key.
+func (c *testSPIFFECA) svid(t *testing.T, id string) (tls.Certificate, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	u, err := url.Parse(id)
+	require.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{u},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, c.cert, &key.PublicKey, c.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, keyDER
+}
+
+// response encodes an X509SVIDResponse message containing a single SVID.
+func (c *testSPIFFECA) response(t *testing.T, id string) []byte {
+	t.Helper()
+
+	cert, keyDER := c.svid(t, id)
+
+	var svid []byte
+	svid = protowire.AppendTag(svid, 1, protowire.BytesType)
+	svid = protowire.AppendString(svid, id)
+	svid = protowire.AppendTag(svid, 2, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, cert.Certificate[0])
+	svid = protowire.AppendTag(svid, 3, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, keyDER)
+	svid = protowire.AppendTag(svid, 4, protowire.BytesType)
+	svid = protowire.AppendBytes(svid, c.cert.Raw)
+
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+	return protowire.AppendBytes(res, svid)
+}
+
+// startTestWorkloadAPI serves a fake Workload API on a unix socket, where the
+// responses sent to the channel are streamed to every client.
+func startTestWorkloadAPI(t *testing.T) (string, chan<- []byte) {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	resChan := make(chan []byte, 10)
+	server := grpc.NewServer(
+		grpc.ForceServerCodec(rawCodec{}),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			if method != spiffeFetchX509SVID {
+				return status.Errorf(codes.Unimplemented, "unknown method %v", method)
+			}
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			if v := md.Get("workload.spiffe.io"); len(v) != 1 || v[0] != "true" {
+				return status.Error(codes.InvalidArgument, "missing security header")
+			}
+
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			for {
+				select {
+				case res := <-resChan:
+					if err := stream.SendMsg(&res); err != nil {
+						return err
+					}
+				case <-stream.Context().Done():
+					return nil
+				}
+			}
+		}),
+	)
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	t.Cleanup(server.Stop)
+
+	return socket, resChan
+}
+
+func TestSPIFFEConfig(t *testing.T) {
+	ca := newTestSPIFFECA(t)
+	socket, resChan := startTestWorkloadAPI(t)
+
+	// The server requires client certificates issued by the trust domain, and
+	// reports the SPIFFE ID of each client.
+	serverCert, _ := ca.svid(t, "spiffe://example.org/server")
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	idChan := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			tConn := conn.(*tls.Conn)
+			if tConn.Handshake() == nil {
+				if id, err := spiffeID(tConn.ConnectionState().PeerCertificates[0]); err == nil {
+					idChan <- id
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	dial := func(conf Config) error {
+		tlsConf, err := conf.GetNonToggled()
+		if err != nil {
+			return err
+		}
+		conn, err := tls.Dial("tcp", ln.Addr().String(), tlsConf)
+		if err != nil {
+			return err
+		}
+		// Ensure that the server has accepted the client certificate.
+		_, _ = conn.Read(make([]byte, 1))
+		return conn.Close()
+	}
+
+	conf := NewConfig()
+	conf.SPIFFE.Enabled = true
+	conf.SPIFFE.SocketPath = socket
+	conf.SPIFFE.AllowedIDs = []string{"spiffe://example.org/server"}
+
+	resChan <- ca.response(t, "spiffe://example.org/first")
+	require.NoError(t, dial(conf))
+	assert.Equal(t, "spiffe://example.org/first", <-idChan)
+
+	// A new SVID is used once it's received.
+	resChan <- ca.response(t, "spiffe://example.org/second")
+	require.Eventually(t, func() bool {
+		require.NoError(t, dial(conf))
+		return <-idChan == "spiffe://example.org/second"
+	}, time.Second*5, time.Millisecond*10)
+
+	conf.SPIFFE.AllowedIDs = nil
+	require.NoError(t, dial(conf))
+	assert.Equal(t, "spiffe://example.org/second", <-idChan)
+
+	conf.SPIFFE.AllowedIDs = []string{"spiffe://example.org/other"}
+	require.Error(t, dial(conf))
+
+	conf.SPIFFE.AllowedIDs = nil
+	conf.RootCAsFile = "./nope.pem"
+	require.Error(t, dial(conf))
+}
+
+func TestSPIFFETarget(t *testing.T) {
+	for _, test := range []struct {
+		socket, target string
+	}{
+		{socket: "/run/agent.sock", target: "unix:///run/agent.sock"},
+		{socket: "unix:///run/agent.sock", target: "unix:///run/agent.sock"},
+		{socket: "unix:/run/agent.sock", target: "unix:///run/agent.sock"},
+		{socket: "tcp://127.0.0.1:8081", target: "127.0.0.1:8081"},
+	} {
+		target, err := spiffeTarget(test.socket)
+		require.NoError(t, err, test.socket)
+		assert.Equal(t, test.target, target, test.socket)
+	}
+
+	_, err := spiffeTarget("http://127.0.0.1:8081")
+	require.Error(t, err)
+}
@@ -1,10 +1,12 @@
 package tls
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"os"
 )
 
@@ -25,6 +27,9 @@ type Config struct {
 	InsecureSkipVerify  bool               `json:"skip_cert_verify" yaml:"skip_cert_verify"`
 	ClientCertificates  []ClientCertConfig `json:"client_certs" yaml:"client_certs"`
 	EnableRenegotiation bool               `json:"enable_renegotiation" yaml:"enable_renegotiation"`
+	MinVersion          string             `json:"min_version" yaml:"min_version"`
+	CipherSuites        []string           `json:"cipher_suites" yaml:"cipher_suites"`
+	SPIFFE              SPIFFEConfig       `json:"spiffe" yaml:"spiffe"`
 }
 
 // NewConfig creates a new Config with default values.
@@ -36,6 +41,9 @@ func NewConfig() Config {
 		InsecureSkipVerify:  false,
 		ClientCertificates:  []ClientCertConfig{},
 		EnableRenegotiation: false,
+		MinVersion:          "",
+		CipherSuites:        []string{},
+		SPIFFE:              NewSPIFFEConfig(),
 	}
 }
 
@@ -63,14 +71,21 @@ func (c *Config) GetNonToggled() (*tls.Config, error) {
 		return nil, errors.New("only one field between root_cas and root_cas_file can be specified")
 	}
 
+	if c.SPIFFE.Enabled && (len(c.RootCAs) > 0 || len(c.RootCAsFile) > 0 || len(c.ClientCertificates) > 0) {
+		return nil, errors.New("the fields root_cas, root_cas_file and client_certs cannot be specified when spiffe is enabled")
+	}
+
+	var roots func() (*x509.CertPool, error)
 	if len(c.RootCAsFile) > 0 {
-		caCert, err := os.ReadFile(c.RootCAsFile)
+		pool, err := newPoolReloader(c.RootCAsFile)
 		if err != nil {
 			return nil, err
 		}
 		initConf()
-		tlsConf.RootCAs = x509.NewCertPool()
-		tlsConf.RootCAs.AppendCertsFromPEM(caCert)
+		tlsConf.RootCAs = pool.current()
+		roots = func() (*x509.CertPool, error) {
+			return pool.current(), nil
+		}
 	}
 
 	if len(c.RootCAs) > 0 {
@@ -79,13 +94,106 @@ func (c *Config) GetNonToggled() (*tls.Config, error) {
 		tlsConf.RootCAs.AppendCertsFromPEM([]byte(c.RootCAs))
 	}
 
+	var reloaders []*CertReloader
+	var reloading bool
 	for _, conf := range c.ClientCertificates {
-		cert, err := conf.Load()
+		var cert tls.Certificate
+		if conf.CertFile != "" || conf.KeyFile != "" {
+			if conf.CertFile == "" {
+				return nil, errors.New("missing cert_file field in client certificate config")
+			}
+			if conf.KeyFile == "" {
+				return nil, errors.New("missing key_file field in client certificate config")
+			}
+			r, err := NewCertReloader(conf.CertFile, conf.KeyFile, conf.Password)
+			if err != nil {
+				return nil, err
+			}
+			reloaders = append(reloaders, r)
+			reloading = true
+			cert = *r.Certificate()
+		} else {
+			var err error
+			if cert, err = conf.Load(); err != nil {
+				return nil, err
+			}
+			reloaders = append(reloaders, nil)
+		}
+		initConf()
+		tlsConf.Certificates = append(tlsConf.Certificates, cert)
+	}
+	if reloading {
+		certs := tlsConf.Certificates
+		current := func() []tls.Certificate {
+			current := make([]tls.Certificate, len(certs))
+			for i, r := range reloaders {
+				if current[i] = certs[i]; r != nil {
+					current[i] = *r.Certificate()
+				}
+			}
+			return current
+		}
+		tlsConf.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			// Mirrors the selection of certificates when GetClientCertificate
+			// isn't set, where no certificate is sent when none are supported.
+			for _, cert := range current() {
+				if err := cri.SupportsCertificate(&cert); err == nil {
+					return &cert, nil
+				}
+			}
+			return &tls.Certificate{}, nil
+		}
+		tlsConf.GetCertificate = func(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			certs := current()
+			for _, cert := range certs {
+				if err := chi.SupportsCertificate(&cert); err == nil {
+					return &cert, nil
+				}
+			}
+			return &certs[0], nil
+		}
+	}
+
+	if c.SPIFFE.Enabled {
+		source, err := getSPIFFESource(c.SPIFFE.SocketPath)
 		if err != nil {
 			return nil, err
 		}
 		initConf()
-		tlsConf.Certificates = append(tlsConf.Certificates, cert)
+		tlsConf.GetClientCertificate = func(cri *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			svid, err := source.current(cri.Context())
+			if err != nil {
+				return nil, err
+			}
+			return &svid.cert, nil
+		}
+		roots = func() (*x509.CertPool, error) {
+			ctx, done := context.WithTimeout(context.Background(), spiffeWaitTimeout)
+			defer done()
+			svid, err := source.current(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return svid.bundle, nil
+		}
+	}
+
+	if len(c.MinVersion) > 0 {
+		v, err := parseVersion(c.MinVersion)
+		if err != nil {
+			return nil, err
+		}
+		initConf()
+		tlsConf.MinVersion = v
+	}
+
+	if len(c.CipherSuites) > 0 {
+		suites, err := parseCipherSuites(c.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		initConf()
+		tlsConf.CipherSuites = suites
 	}
 
 	if c.EnableRenegotiation {
@@ -96,6 +204,12 @@ func (c *Config) GetNonToggled() (*tls.Config, error) {
 	if c.InsecureSkipVerify {
 		initConf()
 		tlsConf.InsecureSkipVerify = true
+	} else if roots != nil {
+		// The standard verification of the server certificate only supports
+		// a fixed pool of root certificates, and so it's replaced with one
+		// that verifies it against the current pool.
+		tlsConf.InsecureSkipVerify = true
+		tlsConf.VerifyConnection = verifyConnection(roots, c.SPIFFE.Enabled, c.SPIFFE.AllowedIDs)
 	}
 
 	return tlsConf, nil
@@ -179,3 +293,87 @@ func decryptKey(key *pem.Block, password string) ([]byte, error) {
 	decyptedKey := pem.EncodeToMemory(&pem.Block{Type: key.Type, Bytes: decryptedKey})
 	return decyptedKey, nil
 }
+
+func parseVersion(v string) (uint16, error) {
+	switch v {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("min_version %v is not recognised, expected one of 1.0, 1.1, 1.2 or 1.3", v)
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	ids := map[string]uint16{}
+	for _, s := range tls.CipherSuites() {
+		ids[s.Name] = s.ID
+	}
+	for _, s := range tls.InsecureCipherSuites() {
+		ids[s.Name] = s.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, n := range names {
+		id, exists := ids[n]
+		if !exists {
+			return nil, fmt.Errorf("cipher suite %v is not recognised", n)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// verifyConnection returns a func that verifies the certificate chain of a
+// server against the current pool of roots, which either verifies the server
+// name of the connection or, for SPIFFE, the SPIFFE ID of the certificate.
+func verifyConnection(roots func() (*x509.CertPool, error), spiffe bool, allowedIDs []string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("tls: server did not provide a certificate")
+		}
+
+		pool, err := roots()
+		if err != nil {
+			return err
+		}
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+
+		if !spiffe {
+			if cs.ServerName == "" {
+				return errors.New("tls: a server name is required in order to verify the certificate of the server")
+			}
+			opts.DNSName = cs.ServerName
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			return err
+		}
+		id, err := spiffeID(cs.PeerCertificates[0])
+		if err != nil {
+			return err
+		}
+		if len(allowedIDs) == 0 {
+			return nil
+		}
+		for _, allowed := range allowedIDs {
+			if id == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("tls: spiffe id %v of the server is not allowed", id)
+	}
+}
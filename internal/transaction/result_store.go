@@ -81,6 +81,43 @@ func NewResultStore() ResultStore {
 
 //------------------------------------------------------------------------------
 
+// StreamedResultStore is a ResultStore that signals each time a message batch
+// is added, allowing the origin of a message to forward responses as they
+// arrive rather than once the message has been fully delivered.
+type StreamedResultStore interface {
+	ResultStore
+
+	// AddedChan returns a channel that receives a signal whenever one or more
+	// message batches have been added to the store since the last signal.
+	AddedChan() <-chan struct{}
+}
+
+type streamedResultStoreImpl struct {
+	resultStoreImpl
+	addedChan chan struct{}
+}
+
+func (r *streamedResultStoreImpl) Add(msg message.Batch) {
+	r.resultStoreImpl.Add(msg)
+	select {
+	case r.addedChan <- struct{}{}:
+	default:
+	}
+}
+
+func (r *streamedResultStoreImpl) AddedChan() <-chan struct{} {
+	return r.addedChan
+}
+
+// NewStreamedResultStore returns an implementation of StreamedResultStore.
+func NewStreamedResultStore() StreamedResultStore {
+	return &streamedResultStoreImpl{
+		addedChan: make(chan struct{}, 1),
+	}
+}
+
+//------------------------------------------------------------------------------
+
 // AddResultStore sets a result store within the context of the provided message
 // that allows a roundtrip.Writer or any other component to propagate a
 // resulting message back to the origin.
@@ -38,3 +38,35 @@ func TestResultStore(t *testing.T) {
 		t.Errorf("Unexpected count of stored messages: %v != %v", act, exp)
 	}
 }
+
+func TestStreamedResultStore(t *testing.T) {
+	store := NewStreamedResultStore()
+
+	select {
+	case <-store.AddedChan():
+		t.Fatal("Unexpected signal from empty store")
+	default:
+	}
+
+	store.Add(message.QuickBatch([][]byte{[]byte("foo")}))
+	store.Add(message.QuickBatch([][]byte{[]byte("bar")}))
+
+	select {
+	case <-store.AddedChan():
+	default:
+		t.Fatal("Expected signal from store")
+	}
+	select {
+	case <-store.AddedChan():
+		t.Fatal("Unexpected second signal from store")
+	default:
+	}
+
+	results := store.Get()
+	if exp, act := 2, len(results); exp != act {
+		t.Fatalf("Wrong count of result batches: %v != %v", act, exp)
+	}
+	if exp, act := "bar", string(results[1].Get(0).AsBytes()); exp != act {
+		t.Errorf("Wrong message contents: %v != %v", act, exp)
+	}
+}
@@ -14,3 +14,11 @@ func (e executorUnwrapper) Unwrap() *mapping.Executor {
 func (e *Executor) XUnwrapper() any {
 	return executorUnwrapper{child: e.exec}
 }
+
+// XWrapExecutor is for internal use only, do not use this.
+func XWrapExecutor(v any) *Executor {
+	if exec, ok := v.(*mapping.Executor); ok {
+		return newExecutor(exec)
+	}
+	return nil
+}
@@ -12,30 +12,52 @@ import (
 	_ "github.com/benthosdev/benthos/v4/public/components/azure"
 	_ "github.com/benthosdev/benthos/v4/public/components/beanstalkd"
 	_ "github.com/benthosdev/benthos/v4/public/components/cassandra"
+	_ "github.com/benthosdev/benthos/v4/public/components/coap"
 	_ "github.com/benthosdev/benthos/v4/public/components/confluent"
+	_ "github.com/benthosdev/benthos/v4/public/components/currency"
+	_ "github.com/benthosdev/benthos/v4/public/components/datadog"
 	_ "github.com/benthosdev/benthos/v4/public/components/dgraph"
 	_ "github.com/benthosdev/benthos/v4/public/components/elasticsearch"
+	_ "github.com/benthosdev/benthos/v4/public/components/ftp"
 	_ "github.com/benthosdev/benthos/v4/public/components/gcp"
+	_ "github.com/benthosdev/benthos/v4/public/components/gdrive"
+	_ "github.com/benthosdev/benthos/v4/public/components/grpc"
 	_ "github.com/benthosdev/benthos/v4/public/components/hdfs"
+	_ "github.com/benthosdev/benthos/v4/public/components/iceberg"
 	_ "github.com/benthosdev/benthos/v4/public/components/influxdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/io"
 	_ "github.com/benthosdev/benthos/v4/public/components/jaeger"
 	_ "github.com/benthosdev/benthos/v4/public/components/kafka"
+	_ "github.com/benthosdev/benthos/v4/public/components/ldap"
+	_ "github.com/benthosdev/benthos/v4/public/components/loki"
 	_ "github.com/benthosdev/benthos/v4/public/components/maxmind"
 	_ "github.com/benthosdev/benthos/v4/public/components/memcached"
+	_ "github.com/benthosdev/benthos/v4/public/components/metricsrelay"
+	_ "github.com/benthosdev/benthos/v4/public/components/modbus"
 	_ "github.com/benthosdev/benthos/v4/public/components/mongodb"
 	_ "github.com/benthosdev/benthos/v4/public/components/mqtt"
+	_ "github.com/benthosdev/benthos/v4/public/components/msgraph"
 	_ "github.com/benthosdev/benthos/v4/public/components/nanomsg"
 	_ "github.com/benthosdev/benthos/v4/public/components/nats"
+	_ "github.com/benthosdev/benthos/v4/public/components/neo4j"
+	_ "github.com/benthosdev/benthos/v4/public/components/netflow"
 	_ "github.com/benthosdev/benthos/v4/public/components/nsq"
+	_ "github.com/benthosdev/benthos/v4/public/components/opcua"
 	_ "github.com/benthosdev/benthos/v4/public/components/otlp"
 	_ "github.com/benthosdev/benthos/v4/public/components/prometheus"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure/extended"
 	_ "github.com/benthosdev/benthos/v4/public/components/pusher"
 	_ "github.com/benthosdev/benthos/v4/public/components/redis"
+	_ "github.com/benthosdev/benthos/v4/public/components/rocketmq"
+	_ "github.com/benthosdev/benthos/v4/public/components/salesforce"
 	_ "github.com/benthosdev/benthos/v4/public/components/sftp"
+	_ "github.com/benthosdev/benthos/v4/public/components/shopify"
 	_ "github.com/benthosdev/benthos/v4/public/components/snowflake"
+	_ "github.com/benthosdev/benthos/v4/public/components/splunk"
 	_ "github.com/benthosdev/benthos/v4/public/components/sql"
 	_ "github.com/benthosdev/benthos/v4/public/components/statsd"
+	_ "github.com/benthosdev/benthos/v4/public/components/stomp"
+	_ "github.com/benthosdev/benthos/v4/public/components/stripe"
+	_ "github.com/benthosdev/benthos/v4/public/components/webhook"
 )
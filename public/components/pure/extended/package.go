@@ -10,6 +10,7 @@ package extended
 
 import (
 	// Import pure but larger packages.
+	_ "github.com/benthosdev/benthos/v4/internal/impl/arrow"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/awk"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/jsonpath"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/lang"
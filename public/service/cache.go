@@ -55,20 +55,73 @@ type batchedCache interface {
 	SetMulti(ctx context.Context, keyValues ...CacheItem) error
 }
 
+// batchedGetCache represents a cache where the underlying implementation is
+// able to benefit from batched get requests. This interface is optional for
+// caches and when implemented will automatically be utilised where possible.
+type batchedGetCache interface {
+	// GetMulti attempts to obtain multiple cache items in as few requests as
+	// possible. Keys that do not exist should be omitted from the result.
+	GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error)
+}
+
+// batchedAddCache represents a cache where the underlying implementation is
+// able to benefit from batched add requests. This interface is optional for
+// caches and when implemented will automatically be utilised where possible.
+type batchedAddCache interface {
+	// AddMulti attempts to add multiple cache items in as few requests as
+	// possible, returning an error for each key that was not added, which
+	// should be ErrKeyAlreadyExists for keys that already exist.
+	AddMulti(ctx context.Context, keyValues ...CacheItem) map[string]error
+}
+
+// snapshotCache represents a cache that holds its items within the memory of
+// the process. This interface is optional for caches and when implemented
+// allows the items of the cache to be exported in order to migrate them to
+// another instance.
+type snapshotCache interface {
+	// ExportItems returns a copy of all items that have not expired, along
+	// with the remaining TTL of each item that expires.
+	ExportItems(ctx context.Context) ([]CacheItem, error)
+}
+
 //------------------------------------------------------------------------------
 
 // Implements types.Cache.
 type airGapCache struct {
-	c  Cache
-	cm batchedCache
+	c   Cache
+	cm  batchedCache
+	cmg batchedGetCache
+	cma batchedAddCache
+	cs  snapshotCache
 }
 
 func newAirGapCache(c Cache, stats metrics.Type) cache.V1 {
 	ag := &airGapCache{c: c, cm: nil}
 	ag.cm, _ = c.(batchedCache)
+	ag.cmg, _ = c.(batchedGetCache)
+	ag.cma, _ = c.(batchedAddCache)
+	ag.cs, _ = c.(snapshotCache)
 	return cache.MetricsForCache(ag, stats)
 }
 
+func (a *airGapCache) ExportItems(ctx context.Context) (map[string]cache.TTLItem, error) {
+	if a.cs == nil {
+		return nil, cache.ErrSnapshotNotSupported
+	}
+	items, err := a.cs.ExportItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exported := make(map[string]cache.TTLItem, len(items))
+	for _, item := range items {
+		exported[item.Key] = cache.TTLItem{
+			Value: item.Value,
+			TTL:   item.TTL,
+		}
+	}
+	return exported, nil
+}
+
 func (a *airGapCache) Get(ctx context.Context, key string) ([]byte, error) {
 	b, err := a.c.Get(ctx, key)
 	if errors.Is(err, ErrKeyNotFound) {
@@ -77,6 +130,24 @@ func (a *airGapCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return b, err
 }
 
+func (a *airGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if a.cmg != nil {
+		return a.cmg.GetMulti(ctx, keys...)
+	}
+	values := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		b, err := a.c.Get(ctx, k)
+		if err != nil {
+			if errors.Is(err, ErrKeyNotFound) || errors.Is(err, component.ErrKeyNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		values[k] = b
+	}
+	return values, nil
+}
+
 func (a *airGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return a.c.Set(ctx, key, value, ttl)
 }
@@ -109,6 +180,34 @@ func (a *airGapCache) Add(ctx context.Context, key string, value []byte, ttl *ti
 	return err
 }
 
+func (a *airGapCache) AddMulti(ctx context.Context, keyValues map[string]cache.TTLItem) map[string]error {
+	var errs map[string]error
+	if a.cma != nil {
+		items := make([]CacheItem, 0, len(keyValues))
+		for k, v := range keyValues {
+			items = append(items, CacheItem{
+				Key:   k,
+				Value: v.Value,
+				TTL:   v.TTL,
+			})
+		}
+		errs = a.cma.AddMulti(ctx, items...)
+	} else {
+		errs = map[string]error{}
+		for k, v := range keyValues {
+			if err := a.c.Add(ctx, k, v.Value, v.TTL); err != nil {
+				errs[k] = err
+			}
+		}
+	}
+	for k, err := range errs {
+		if errors.Is(err, ErrKeyAlreadyExists) {
+			errs[k] = component.ErrKeyAlreadyExists
+		}
+	}
+	return errs
+}
+
 func (a *airGapCache) Delete(ctx context.Context, key string) error {
 	return a.c.Delete(ctx, key)
 }
@@ -136,10 +235,25 @@ func (r *reverseAirGapCache) Get(ctx context.Context, key string) ([]byte, error
 	return b, err
 }
 
+func (r *reverseAirGapCache) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	return r.c.GetMulti(ctx, keys...)
+}
+
 func (r *reverseAirGapCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
 	return r.c.Set(ctx, key, value, ttl)
 }
 
+func (r *reverseAirGapCache) SetMulti(ctx context.Context, keyValues ...CacheItem) error {
+	items := make(map[string]cache.TTLItem, len(keyValues))
+	for _, kv := range keyValues {
+		items[kv.Key] = cache.TTLItem{
+			Value: kv.Value,
+			TTL:   kv.TTL,
+		}
+	}
+	return r.c.SetMulti(ctx, items)
+}
+
 func (r *reverseAirGapCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) (err error) {
 	if err = r.c.Add(ctx, key, value, ttl); errors.Is(err, component.ErrKeyAlreadyExists) {
 		err = ErrKeyAlreadyExists
@@ -283,6 +283,50 @@ func TestCacheAirGapAddWithTTL(t *testing.T) {
 	assert.EqualError(t, err, "key already exists")
 }
 
+func TestCacheAirGapGetMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+			"baz": {b: []byte("buz")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	values, err := agrl.GetMulti(ctx, "foo", "baz", "not exist")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"foo": []byte("bar"),
+		"baz": []byte("buz"),
+	}, values)
+
+	rl.err = errors.New("nope")
+	_, err = agrl.GetMulti(ctx, "foo")
+	assert.EqualError(t, err, "nope")
+}
+
+func TestCacheAirGapAddMulti(t *testing.T) {
+	ctx := context.Background()
+	rl := &closableCache{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+		},
+	}
+	agrl := newAirGapCache(rl, metrics.Noop())
+
+	errs := agrl.AddMulti(ctx, map[string]cache.TTLItem{
+		"foo": {Value: []byte("baz")},
+		"buz": {Value: []byte("bev")},
+	})
+	assert.Equal(t, map[string]error{
+		"foo": component.ErrKeyAlreadyExists,
+	}, errs)
+	assert.Equal(t, map[string]testCacheItem{
+		"foo": {b: []byte("bar")},
+		"buz": {b: []byte("bev")},
+	}, rl.m)
+}
+
 func TestCacheAirGapDelete(t *testing.T) {
 	ctx := context.Background()
 	rl := &closableCache{
@@ -326,8 +370,33 @@ func (c *closableCacheType) Set(ctx context.Context, key string, value []byte, t
 	return nil
 }
 
+func (c *closableCacheType) GetMulti(ctx context.Context, keys ...string) (map[string][]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	values := map[string][]byte{}
+	for _, k := range keys {
+		if i, ok := c.m[k]; ok {
+			values[k] = i.b
+		}
+	}
+	return values, nil
+}
+
 func (c *closableCacheType) SetMulti(ctx context.Context, items map[string]cache.TTLItem) error {
-	return errors.New("not implemented")
+	if c.err != nil {
+		return c.err
+	}
+	for k, v := range items {
+		c.m[k] = testCacheItem{
+			b: v.Value, ttl: v.TTL,
+		}
+	}
+	return nil
+}
+
+func (c *closableCacheType) AddMulti(ctx context.Context, items map[string]cache.TTLItem) map[string]error {
+	return map[string]error{"": errors.New("not implemented")}
 }
 
 func (c *closableCacheType) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
@@ -409,6 +478,38 @@ func TestCacheReverseAirGapSet(t *testing.T) {
 	}, rl.m)
 }
 
+func TestCacheReverseAirGapGetMulti(t *testing.T) {
+	rl := &closableCacheType{
+		m: map[string]testCacheItem{
+			"foo": {b: []byte("bar")},
+			"baz": {b: []byte("buz")},
+		},
+	}
+	agrl := newReverseAirGapCache(rl)
+
+	values, err := agrl.GetMulti(context.Background(), "foo", "not exist")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"foo": []byte("bar")}, values)
+}
+
+func TestCacheReverseAirGapSetMulti(t *testing.T) {
+	rl := &closableCacheType{
+		m: map[string]testCacheItem{},
+	}
+	agrl := newReverseAirGapCache(rl)
+
+	ttl := time.Second
+	err := agrl.SetMulti(context.Background(),
+		CacheItem{Key: "foo", Value: []byte("bar")},
+		CacheItem{Key: "baz", Value: []byte("buz"), TTL: &ttl},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]testCacheItem{
+		"foo": {b: []byte("bar")},
+		"baz": {b: []byte("buz"), ttl: &ttl},
+	}, rl.m)
+}
+
 func TestCacheReverseAirGapSetWithTTL(t *testing.T) {
 	rl := &closableCacheType{
 		m: map[string]testCacheItem{},
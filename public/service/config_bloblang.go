@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
@@ -19,6 +21,9 @@ func NewBloblangField(name string) *ConfigField {
 // FieldBloblang accesses a field from a parsed config that was defined with
 // NewBloblangField and returns either a *bloblang.Executor or an error if the
 // mapping was invalid.
+//
+// A field value of the form `resource:<label>` returns the mapping resource of
+// that label as it exists at the time of calling.
 func (p *ParsedConfig) FieldBloblang(path ...string) (*bloblang.Executor, error) {
 	v, exists := p.field(path...)
 	if !exists {
@@ -30,6 +35,16 @@ func (p *ParsedConfig) FieldBloblang(path ...string) (*bloblang.Executor, error)
 		return nil, fmt.Errorf("expected field '%v' to be a string, got %T", strings.Join(path, "."), v)
 	}
 
+	if name, isResource := docs.MappingResourceName(str); isResource {
+		var exec *bloblang.Executor
+		if err := p.mgr.AccessMapping(context.Background(), name, func(e *mapping.Executor) {
+			exec = bloblang.XWrapExecutor(e)
+		}); err != nil {
+			return nil, fmt.Errorf("failed to access mapping resource '%v' for field '%v': %w", name, strings.Join(path, "."), err)
+		}
+		return exec, nil
+	}
+
 	exec, err := bloblang.XWrapEnvironment(p.mgr.BloblEnvironment()).Parse(str)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse bloblang mapping '%v': %v", strings.Join(path, "."), err)
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/circuitbreaker"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+// ErrCircuitBreakerOpen is returned by CircuitBreaker.Allow when a request
+// should not be attempted because the circuit is open.
+var ErrCircuitBreakerOpen = circuitbreaker.ErrOpen
+
+// CircuitBreaker tracks the outcome of requests made to a downstream service
+// and determines whether subsequent requests should be attempted, opening the
+// circuit once an error rate threshold is breached.
+type CircuitBreaker struct {
+	b *circuitbreaker.Breaker
+}
+
+// Allow returns ErrCircuitBreakerOpen if a request should not be attempted,
+// otherwise nil is returned and the caller must call Done with the outcome of
+// the request.
+func (c *CircuitBreaker) Allow() error {
+	return c.b.Allow()
+}
+
+// Done reports the outcome of a request that was permitted by Allow.
+func (c *CircuitBreaker) Done(err error) {
+	c.b.Done(err)
+}
+
+// NewCircuitBreakerField defines a new object type config field that describes
+// a circuit breaker policy. It is then possible to extract a *CircuitBreaker
+// from the resulting parsed config with the method FieldCircuitBreaker.
+func NewCircuitBreakerField(name string) *ConfigField {
+	cf := circuitbreaker.FieldSpec()
+	cf.Name = name
+	var newChildren []docs.FieldSpec
+	for _, f := range cf.Children {
+		if f.Name != "enabled" {
+			newChildren = append(newChildren, f)
+		}
+	}
+	cf.Children = newChildren
+	return &ConfigField{field: cf}
+}
+
+// FieldCircuitBreaker accesses a field from a parsed config that was defined
+// with NewCircuitBreakerField and returns a *CircuitBreaker, or an error if the
+// configuration was invalid. The state of the circuit breaker is exposed as
+// metrics of the component being created, labelled with the path of the field.
+func (p *ParsedConfig) FieldCircuitBreaker(path ...string) (*CircuitBreaker, error) {
+	conf, err := p.circuitBreakerConf(path...)
+	if err != nil {
+		return nil, err
+	}
+	conf.Enabled = true
+
+	b, err := circuitbreaker.New(conf, p.mgr.IntoPath(path...).Metrics())
+	if err != nil {
+		return nil, err
+	}
+	return &CircuitBreaker{b: b}, nil
+}
+
+// NewCircuitBreakerToggledField defines a new object type config field that
+// describes a circuit breaker policy. This field differs from a standard
+// CircuitBreakerField as it includes a boolean field `enabled` which is `false`
+// by default.
+//
+// A *CircuitBreaker can be extracted from the resulting parsed config with the
+// method FieldCircuitBreakerToggled.
+func NewCircuitBreakerToggledField(name string) *ConfigField {
+	cf := circuitbreaker.FieldSpec()
+	cf.Name = name
+	return &ConfigField{field: cf}
+}
+
+// FieldCircuitBreakerToggled accesses a field from a parsed config that was
+// defined with NewCircuitBreakerToggledField and returns a *CircuitBreaker, or
+// an error if the configuration was invalid. When the circuit breaker is not
+// enabled the resulting *CircuitBreaker permits all requests and no metrics are
+// registered for it.
+func (p *ParsedConfig) FieldCircuitBreakerToggled(path ...string) (*CircuitBreaker, error) {
+	conf, err := p.circuitBreakerConf(path...)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := circuitbreaker.New(conf, p.mgr.IntoPath(path...).Metrics())
+	if err != nil {
+		return nil, err
+	}
+	return &CircuitBreaker{b: b}, nil
+}
+
+func (p *ParsedConfig) circuitBreakerConf(path ...string) (conf circuitbreaker.Config, err error) {
+	v, exists := p.field(path...)
+	if !exists {
+		err = fmt.Errorf("field '%v' was not found in the config", strings.Join(path, "."))
+		return
+	}
+
+	var node yaml.Node
+	if err = node.Encode(v); err != nil {
+		return
+	}
+
+	conf = circuitbreaker.NewConfig()
+	err = node.Decode(&conf)
+	return
+}
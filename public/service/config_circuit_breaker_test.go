@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+)
+
+func TestConfigCircuitBreaker(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewCircuitBreakerField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  min_requests: 2
+  error_threshold: 1
+`, nil)
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldCircuitBreaker("b")
+	require.Error(t, err)
+
+	cb, err := parsedConfig.FieldCircuitBreaker("a")
+	require.NoError(t, err)
+
+	errBad := errors.New("bad")
+	require.NoError(t, cb.Allow())
+	cb.Done(errBad)
+	require.NoError(t, cb.Allow())
+	cb.Done(errBad)
+
+	assert.Equal(t, ErrCircuitBreakerOpen, cb.Allow())
+}
+
+func TestConfigCircuitBreakerToggled(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewCircuitBreakerToggledField("a"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  min_requests: 1
+`, nil)
+	require.NoError(t, err)
+
+	cb, err := parsedConfig.FieldCircuitBreakerToggled("a")
+	require.NoError(t, err)
+
+	errBad := errors.New("bad")
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cb.Allow())
+		cb.Done(errBad)
+	}
+
+	parsedConfig, err = spec.ParseYAML(`
+a:
+  enabled: true
+  min_requests: 1
+`, nil)
+	require.NoError(t, err)
+
+	cb, err = parsedConfig.FieldCircuitBreakerToggled("a")
+	require.NoError(t, err)
+
+	require.NoError(t, cb.Allow())
+	cb.Done(errBad)
+	assert.Equal(t, ErrCircuitBreakerOpen, cb.Allow())
+}
+
+func TestConfigCircuitBreakerMetrics(t *testing.T) {
+	spec := NewConfigSpec().
+		Field(NewCircuitBreakerField("a")).
+		Field(NewCircuitBreakerField("b")).
+		Field(NewCircuitBreakerToggledField("c"))
+
+	parsedConfig, err := spec.ParseYAML(`
+a:
+  min_requests: 1
+  error_threshold: 1
+b:
+  min_requests: 1
+  error_threshold: 1
+`, nil)
+	require.NoError(t, err)
+
+	stats := metrics.NewLocal()
+	mgr, err := manager.New(manager.NewResourceConfig(), manager.OptSetMetrics(metrics.NewNamespaced(stats)))
+	require.NoError(t, err)
+	parsedConfig.mgr = mgr.IntoPath("foo")
+
+	cbA, err := parsedConfig.FieldCircuitBreaker("a")
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldCircuitBreaker("b")
+	require.NoError(t, err)
+
+	_, err = parsedConfig.FieldCircuitBreakerToggled("c")
+	require.NoError(t, err)
+
+	require.NoError(t, cbA.Allow())
+	cbA.Done(errors.New("bad"))
+
+	assert.Equal(t, map[string]int64{
+		`circuit_breaker_state{path="root.foo.a"}`:    2,
+		`circuit_breaker_opened{path="root.foo.a"}`:   1,
+		`circuit_breaker_rejected{path="root.foo.a"}`: 0,
+		`circuit_breaker_state{path="root.foo.b"}`:    0,
+		`circuit_breaker_opened{path="root.foo.b"}`:   0,
+		`circuit_breaker_rejected{path="root.foo.b"}`: 0,
+	}, stats.GetCounters())
+}
@@ -192,6 +192,23 @@ func (m *Message) MetaGet(key string) (string, bool) {
 	return v, len(v) > 0
 }
 
+// MetaGetMut attempts to find a metadata key from the message and returns its
+// value with the type preserved, and a boolean indicating whether it was found.
+// The value returned should not be mutated unless it is set again with
+// MetaSetMut.
+func (m *Message) MetaGetMut(key string) (any, bool) {
+	return m.part.MetaGetMut(key)
+}
+
+// MetaSetMut sets the value of a metadata key to a value of any type, such as a
+// number, boolean, timestamp or byte slice. The type is preserved as the
+// message travels through the pipeline, and values are only converted into
+// strings by components that require them, such as when writing headers of a
+// protocol.
+func (m *Message) MetaSetMut(key string, value any) {
+	m.part.MetaSetMut(key, value)
+}
+
 // MetaSet sets the value of a metadata key. If the value is an empty string the
 // metadata key is deleted.
 func (m *Message) MetaSet(key, value string) {
@@ -214,6 +231,14 @@ func (m *Message) MetaWalk(fn func(string, string) error) error {
 	return m.part.MetaIter(fn)
 }
 
+// MetaWalkMut iterates each metadata key/value pair with the types of values
+// preserved, and executes a provided closure on each iteration. To stop
+// iterating, return an error from the closure. An error returned by the closure
+// will be returned by this function.
+func (m *Message) MetaWalkMut(fn func(key string, value any) error) error {
+	return m.part.MetaIterMut(fn)
+}
+
 //------------------------------------------------------------------------------
 
 // BloblangQuery executes a parsed Bloblang mapping on a message and returns a
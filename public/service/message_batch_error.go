@@ -0,0 +1,60 @@
+package service
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// BatchError is an error type that can be returned by a batched output in order
+// to describe which messages of a batch failed to be delivered. When returned
+// the messages that did not fail are acknowledged and only those that did are
+// reattempted.
+type BatchError struct {
+	wrapped *batch.Error
+}
+
+// NewBatchError creates a new batch-wide error, where it's possible to add
+// granular errors for individual messages of the batch with Failed. If Failed
+// is never called then all messages of the batch are considered failed.
+func NewBatchError(b MessageBatch, headline error) *BatchError {
+	ib := make(message.Batch, len(b))
+	for i, m := range b {
+		ib[i] = m.part
+	}
+	return &BatchError{wrapped: batch.NewError(ib, headline)}
+}
+
+// Failed stores an error state for a particular message of the batch. Returns
+// a pointer to the underlying error, allowing the method to be chained.
+//
+// Once Failed has been called at least once all message indexes that aren't
+// explicitly failed are assumed to have been delivered successfully.
+func (err *BatchError) Failed(i int, merr error) *BatchError {
+	err.wrapped.Failed(i, merr)
+	return err
+}
+
+// IndexedErrors returns the number of messages of the batch that have been
+// explicitly failed.
+func (err *BatchError) IndexedErrors() int {
+	return err.wrapped.IndexedErrors()
+}
+
+// WalkMessages applies a closure to each message of the batch along with its
+// individual error, which is nil if the message was delivered successfully. The
+// closure returns a bool which indicates whether the iteration should continue.
+func (err *BatchError) WalkMessages(fn func(int, *Message, error) bool) {
+	err.wrapped.WalkParts(func(i int, p *message.Part, merr error) bool {
+		return fn(i, newMessageFromPart(p), merr)
+	})
+}
+
+// Error implements the common error interface.
+func (err *BatchError) Error() string {
+	return err.wrapped.Error()
+}
+
+// Unwrap returns the headline error of the batch.
+func (err *BatchError) Unwrap() error {
+	return err.wrapped.Unwrap()
+}
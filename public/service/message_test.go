@@ -240,6 +240,62 @@ func TestMessageMapping(t *testing.T) {
 	}, resI)
 }
 
+func TestMessageTypedMetadata(t *testing.T) {
+	part := NewMessage(nil)
+	part.MetaSetMut("count", int64(5))
+	part.MetaSetMut("enabled", true)
+	part.MetaSet("name", "foo")
+
+	blobl, err := bloblang.Parse(`
+root.count = metadata("count") + 1
+root.count_str = meta("count")
+root.enabled = metadata("enabled")
+meta doubled = metadata("count") * 2
+meta tags = ["a", "b"]
+`)
+	require.NoError(t, err)
+
+	res, err := part.BloblangQuery(blobl)
+	require.NoError(t, err)
+
+	resI, err := res.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"count":     int64(6),
+		"count_str": "5",
+		"enabled":   true,
+	}, resI)
+
+	v, exists := res.MetaGetMut("doubled")
+	require.True(t, exists)
+	assert.Equal(t, int64(10), v)
+
+	v, exists = res.MetaGetMut("tags")
+	require.True(t, exists)
+	assert.Equal(t, []any{"a", "b"}, v)
+
+	str, exists := res.MetaGet("tags")
+	require.True(t, exists)
+	assert.Equal(t, `["a","b"]`, str)
+
+	v, exists = res.MetaGetMut("count")
+	require.True(t, exists)
+	assert.Equal(t, int64(5), v)
+
+	seen := map[string]any{}
+	require.NoError(t, res.MetaWalkMut(func(k string, v any) error {
+		seen[k] = v
+		return nil
+	}))
+	assert.Equal(t, map[string]any{
+		"count":   int64(5),
+		"enabled": true,
+		"name":    "foo",
+		"doubled": int64(10),
+		"tags":    []any{"a", "b"},
+	}, seen)
+}
+
 func TestMessageBatchMapping(t *testing.T) {
 	partOne := NewMessage(nil)
 	partOne.SetStructured(map[string]any{
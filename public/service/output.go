@@ -121,6 +121,10 @@ func (a *airGapBatchWriter) WriteBatch(ctx context.Context, msg message.Batch) e
 	if err != nil && errors.Is(err, ErrNotConnected) {
 		err = component.ErrNotConnected
 	}
+	var bErr *BatchError
+	if errors.As(err, &bErr) {
+		err = bErr.wrapped
+	}
 	return err
 }
 
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/batch"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/message"
 )
@@ -171,3 +173,33 @@ func TestBatchOutputAirGapHappy(t *testing.T) {
 
 	assert.Equal(t, "hello world", wroteMsg)
 }
+
+func TestBatchOutputAirGapBatchError(t *testing.T) {
+	o := &fnBatchOutput{
+		connect: func() error {
+			return nil
+		},
+		writeBatch: func(m MessageBatch) error {
+			return NewBatchError(m, errors.New("bad write")).Failed(1, errors.New("bad message"))
+		},
+	}
+	agi := newAirGapBatchWriter(o)
+
+	inMsg := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar")})
+
+	err := agi.WriteBatch(context.Background(), inMsg)
+	assert.EqualError(t, err, "bad write")
+
+	var bErr *batch.Error
+	require.True(t, errors.As(err, &bErr))
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	var results []string
+	bErr.WalkParts(func(i int, p *message.Part, err error) bool {
+		if err != nil {
+			results = append(results, string(p.AsBytes())+": "+err.Error())
+		}
+		return true
+	})
+	assert.Equal(t, []string{"bar: bad message"}, results)
+}
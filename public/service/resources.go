@@ -6,12 +6,14 @@ import (
 
 	"go.opentelemetry.io/otel/trace"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
 
 // Resources provides access to service-wide resources.
@@ -55,6 +57,19 @@ func MockResourcesOptAddRateLimit(name string, fn func(context.Context) (time.Du
 	}
 }
 
+type resourcesUnwrapper struct {
+	mgr bundle.NewManagement
+}
+
+func (r resourcesUnwrapper) Unwrap() bundle.NewManagement {
+	return r.mgr
+}
+
+// XUnwrapper is for internal use only, do not use this.
+func (r *Resources) XUnwrapper() any {
+	return resourcesUnwrapper{mgr: r.mgr}
+}
+
 // Label returns a label that identifies the component instantiation. This could
 // be an explicit label set in config, or is otherwise a generated label based
 // on the position of the component within a config.
@@ -140,3 +155,19 @@ func (r *Resources) AccessRateLimit(ctx context.Context, name string, fn func(r
 func (r *Resources) HasRateLimit(name string) bool {
 	return r.mgr.ProbeRateLimit(name)
 }
+
+// AccessBloblangMapping attempts to access a Bloblang mapping resource by name.
+// Mapping resources can be replaced while a service is running, and therefore
+// the mapping should be accessed each time it is needed rather than stored.
+func (r *Resources) AccessBloblangMapping(ctx context.Context, name string, fn func(e *bloblang.Executor)) error {
+	return r.mgr.AccessMapping(ctx, name, func(e *mapping.Executor) {
+		fn(bloblang.XWrapExecutor(e))
+	})
+}
+
+// HasBloblangMapping confirms whether a Bloblang mapping with a given name has
+// been registered as a resource. This method is useful during component
+// initialisation as it is defensive against ordering.
+func (r *Resources) HasBloblangMapping(name string) bool {
+	return r.mgr.ProbeMapping(name)
+}
@@ -103,6 +103,7 @@ module.exports = {
         'guides/monitoring',
         'guides/performance_tuning',
         'guides/sync_responses',
+        'guides/bundles',
         {
           type: 'category',
           label: 'Cloud Credentials',
@@ -126,6 +127,7 @@ module.exports = {
             'guides/streams_mode/about',
             'guides/streams_mode/using_config_files',
             'guides/streams_mode/using_rest_api',
+            'guides/streams_mode/kubernetes',
             'guides/streams_mode/streams_api',
           ],
         },